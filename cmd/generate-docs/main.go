@@ -80,11 +80,24 @@ func generateDirectoryStructure() string {
 
 	// Header
 	buf.WriteString("# Multiclaude Directory Structure\n\n")
-	buf.WriteString("This document describes the directory structure used by multiclaude in `~/.multiclaude/`.\n")
+	buf.WriteString("This document describes the directory structure multiclaude uses for its state, clones and worktrees.\n")
 	buf.WriteString("It is intended to help with debugging and understanding how multiclaude organizes its data.\n\n")
 	buf.WriteString("> **Note**: This file is auto-generated from code constants in `pkg/config/doc.go`.\n")
 	buf.WriteString("> Do not edit manually. Run `go generate ./pkg/config/...` to regenerate.\n\n")
 
+	// Layouts
+	buf.WriteString("## Layouts\n\n")
+	buf.WriteString("The paths below are shown under `~/.multiclaude/`, where everything lives in the legacy layout.\n")
+	buf.WriteString("multiclaude picks the first layout that applies:\n\n")
+	buf.WriteString("| Layout | When | Where |\n")
+	buf.WriteString("|--------|------|-------|\n")
+	buf.WriteString("| custom | `$MULTICLAUDE_HOME` is set | Everything under `$MULTICLAUDE_HOME/` |\n")
+	buf.WriteString("| legacy | `~/.multiclaude/` exists | Everything under `~/.multiclaude/` |\n")
+	buf.WriteString("| xdg | Otherwise | `repos/` and `wts/` under `$XDG_DATA_HOME/multiclaude/` (default `~/.local/share/multiclaude/`); ")
+	buf.WriteString("`daemon.pid` and `daemon.sock` under `$XDG_RUNTIME_DIR/multiclaude/` when it is set; ")
+	buf.WriteString("everything else under `$XDG_STATE_HOME/multiclaude/` (default `~/.local/state/multiclaude/`) |\n\n")
+	buf.WriteString("`multiclaude migrate-paths` moves a legacy `~/.multiclaude/` into the XDG layout.\n\n")
+
 	// Directory layout
 	buf.WriteString("## Directory Layout\n\n")
 	buf.WriteString("```\n")
@@ -101,6 +114,7 @@ func generateDirectoryStructure() string {
 	buf.WriteString("│   └── <repo-name>/\n")
 	buf.WriteString("│       ├── supervisor/     # Supervisor's worktree\n")
 	buf.WriteString("│       ├── merge-queue/    # Merge queue's worktree\n")
+	buf.WriteString("│       ├── <observer-name>/  # Observers' detached worktrees\n")
 	buf.WriteString("│       └── <worker-name>/  # Worker worktrees\n")
 	buf.WriteString("│\n")
 	buf.WriteString("├── messages/           # Inter-agent messages\n")
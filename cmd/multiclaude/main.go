@@ -9,17 +9,19 @@ import (
 )
 
 func main() {
-	if err := run(); err != nil {
-		fmt.Fprintln(os.Stderr, errors.Format(err))
+	c, err := cli.New()
+	if err == nil {
+		err = c.Execute(os.Args[1:])
+	}
+	if err != nil && c != nil && c.JSONOutput() {
+		fmt.Fprintln(os.Stderr, errors.FormatJSON(err))
 		os.Exit(1)
 	}
-}
-
-func run() error {
-	c, err := cli.New()
 	if err != nil {
-		return err
+		fmt.Fprintln(os.Stderr, errors.Format(err))
+		if c != nil && c.TraceID() != "" {
+			fmt.Fprintf(os.Stderr, "\nTrace this failure: multiclaude daemon logs --trace %s\n", c.TraceID())
+		}
+		os.Exit(1)
 	}
-
-	return c.Execute(os.Args[1:])
 }
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -15,9 +17,14 @@ type Definition struct {
 	// Name is the agent name, derived from the filename (without .md extension)
 	Name string
 
-	// Content is the full markdown content of the agent definition
+	// Content is the markdown content of the agent definition, without its
+	// front matter
 	Content string
 
+	// FrontMatter holds the "key: value" settings from the front matter
+	// block at the top of the file, if any. Keys are lowercase.
+	FrontMatter map[string]string
+
 	// SourcePath is the absolute path to the source file
 	SourcePath string
 
@@ -109,12 +116,13 @@ func MergeDefinitions(local, repo []Definition) []Definition {
 	// For repo definitions: append to local if exists, otherwise add as new
 	for _, repoDef := range repo {
 		if localDef, exists := merged[repoDef.Name]; exists {
-			// Append repo content to local base template
+			// Append repo content to local base template; repo settings win
 			merged[repoDef.Name] = Definition{
-				Name:       repoDef.Name,
-				Content:    mergeContent(localDef.Content, repoDef.Content),
-				SourcePath: localDef.SourcePath, // Keep local path as primary
-				Source:     SourceMerged,
+				Name:        repoDef.Name,
+				Content:     mergeContent(localDef.Content, repoDef.Content),
+				FrontMatter: mergeFrontMatter(localDef.FrontMatter, repoDef.FrontMatter),
+				SourcePath:  localDef.SourcePath, // Keep local path as primary
+				Source:      SourceMerged,
 			}
 		} else {
 			// New repo-only definition, add as-is
@@ -144,6 +152,21 @@ func mergeContent(base, custom string) string {
 	return base + "\n\n---\n\n## Custom Instructions\n\n" + custom
 }
 
+// mergeFrontMatter combines two definitions' settings, custom ones winning
+func mergeFrontMatter(base, custom map[string]string) map[string]string {
+	if len(base) == 0 && len(custom) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(custom))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range custom {
+		merged[key] = value
+	}
+	return merged
+}
+
 // readDefinitionsFromDir reads all .md files from a directory and returns them as definitions.
 // Returns an empty slice (not an error) if the directory doesn't exist.
 func readDefinitionsFromDir(dir string, source DefinitionSource) ([]Definition, error) {
@@ -191,18 +214,88 @@ func readDefinitionsFromDir(dir string, source DefinitionSource) ([]Definition,
 
 		// Extract name from filename (without .md extension)
 		name := strings.TrimSuffix(entry.Name(), ".md")
+		frontMatter, body := ParseFrontMatter(string(content))
 
 		definitions = append(definitions, Definition{
-			Name:       name,
-			Content:    string(content),
-			SourcePath: filePath,
-			Source:     source,
+			Name:        name,
+			Content:     body,
+			FrontMatter: frontMatter,
+			SourcePath:  filePath,
+			Source:      source,
 		})
 	}
 
 	return definitions, nil
 }
 
+// ParseFrontMatter splits a definition into its front matter settings and
+// the rest of its content. Front matter is a block of "key: value" lines
+// between two "---" lines at the very top of the file:
+//
+//	---
+//	max-instances: 3
+//	---
+//
+// Keys are lowercased; blank lines and lines starting with # are skipped.
+// Content without front matter is returned whole, with nil settings.
+func ParseFrontMatter(content string) (map[string]string, string) {
+	rest, ok := cutLine(content, "---")
+	if !ok {
+		return nil, content
+	}
+	fields := make(map[string]string)
+	for rest != "" {
+		line := rest
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		line = strings.TrimSpace(line)
+		if line == "---" {
+			return fields, strings.TrimLeft(rest, "\r\n")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !found || !frontMatterKeyRe.MatchString(key) {
+			// Markdown after a horizontal rule, not front matter
+			return nil, content
+		}
+		fields[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	// No closing line: it was a horizontal rule, not front matter
+	return nil, content
+}
+
+// frontMatterKeyRe matches a front matter key, such as "max-instances"
+var frontMatterKeyRe = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// cutLine returns what follows content's first line if that line is want
+func cutLine(content, want string) (string, bool) {
+	first, rest, _ := strings.Cut(content, "\n")
+	if strings.TrimSpace(first) != want {
+		return "", false
+	}
+	return rest, true
+}
+
+// MaxInstancesKey is the front matter setting that limits how many agents
+// from a definition may run at once in a repository
+const MaxInstancesKey = "max-instances"
+
+// MaxInstances returns how many agents from the definition may run at once,
+// or 0 when it sets no limit (or an unusable one)
+func (d *Definition) MaxInstances() int {
+	n, err := strconv.Atoi(d.FrontMatter[MaxInstancesKey])
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
 // ParseTitle extracts the title from a markdown definition.
 // It looks for the first H1 heading (# Title) in the content.
 // Returns the name as-is if no H1 heading is found.
@@ -252,3 +345,76 @@ func (d *Definition) ParseDescription() string {
 
 	return strings.Join(descLines, " ")
 }
+
+// capabilitiesRe matches a capabilities declaration in a definition, e.g.
+// "Capabilities: review-go, write-sql-migrations" (optionally in bold)
+var capabilitiesRe = regexp.MustCompile(`(?i)^[*_]*capabilities[*_]*:[*_]*(.*)$`)
+
+// ParseCapabilities extracts the capabilities a definition declares.
+// See ParseCapabilities (package function) for the format.
+func (d *Definition) ParseCapabilities() []string {
+	return ParseCapabilities(d.Content)
+}
+
+// ParseCapabilities extracts declared capabilities from agent prompt content.
+// Capabilities are declared on a single line outside code blocks:
+//
+//	Capabilities: review-go, write-sql-migrations, deploy
+//
+// Each capability is normalized with NormalizeCapability; duplicates are dropped.
+// Returns nil if the content declares none.
+func ParseCapabilities(content string) []string {
+	inCode := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		m := capabilitiesRe.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		var caps []string
+		for _, field := range strings.Split(m[1], ",") {
+			capability := NormalizeCapability(field)
+			if capability != "" && !HasCapability(caps, capability) {
+				caps = append(caps, capability)
+			}
+		}
+		return caps
+	}
+	return nil
+}
+
+// NormalizeCapability turns a capability into its canonical form: lowercase,
+// with words joined by hyphens ("Review Go" becomes "review-go")
+func NormalizeCapability(capability string) string {
+	capability = strings.Trim(strings.TrimSpace(capability), "`*_.")
+	return strings.ToLower(strings.Join(strings.Fields(capability), "-"))
+}
+
+// HasCapability reports whether caps contains a capability
+func HasCapability(caps []string, capability string) bool {
+	capability = NormalizeCapability(capability)
+	for _, c := range caps {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByCapability returns the definitions that declare a capability, in order
+func FindByCapability(defs []Definition, capability string) []Definition {
+	var found []Definition
+	for _, def := range defs {
+		if HasCapability(def.ParseCapabilities(), capability) {
+			found = append(found, def)
+		}
+	}
+	return found
+}
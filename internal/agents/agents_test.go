@@ -3,6 +3,7 @@ package agents
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -421,3 +422,83 @@ func TestEmptyRepoPath(t *testing.T) {
 		t.Fatalf("expected 0 definitions, got %d", len(defs))
 	}
 }
+
+func TestParseCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"plain", "# SQL Bot\n\nCapabilities: write-sql-migrations, review-go\n", []string{"write-sql-migrations", "review-go"}},
+		{"bold and spaced", "# Reviewer\n\n**Capabilities:** Review Go, `deploy`, review-go\n", []string{"review-go", "deploy"}},
+		{"none", "# Worker\n\nDoes tasks.\n", nil},
+		{"code block only", "# Worker\n\n```\nCapabilities: deploy\n```\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := Definition{Content: tt.content}
+			if got := def.ParseCapabilities(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCapabilities() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindByCapability(t *testing.T) {
+	defs := []Definition{
+		{Name: "reviewer", Content: "Capabilities: review-go"},
+		{Name: "worker", Content: "# Worker"},
+		{Name: "go-expert", Content: "Capabilities: write-go, review-go"},
+	}
+	found := FindByCapability(defs, "Review Go")
+	if len(found) != 2 || found[0].Name != "reviewer" || found[1].Name != "go-expert" {
+		t.Errorf("FindByCapability() = %v, want reviewer and go-expert", found)
+	}
+	if found := FindByCapability(defs, "deploy"); len(found) != 0 {
+		t.Errorf("FindByCapability(deploy) = %v, want none", found)
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		body    string
+	}{
+		{"settings", "---\nmax-instances: 3\n# a comment\nOwner: \"alice\"\n---\n\n# Reviewer\n", map[string]string{"max-instances": "3", "owner": "alice"}, "# Reviewer\n"},
+		{"none", "# Worker\n---\nmore\n", nil, "# Worker\n---\nmore\n"},
+		{"unclosed", "---\nmax-instances: 3\n", nil, "---\nmax-instances: 3\n"},
+		{"horizontal rule", "---\nSome intro: with prose\n\nand more\n---\n", nil, "---\nSome intro: with prose\n\nand more\n---\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, body := ParseFrontMatter(tt.content)
+			if !reflect.DeepEqual(got, tt.want) || body != tt.body {
+				t.Errorf("ParseFrontMatter() = %q, %q; want %q, %q", got, body, tt.want, tt.body)
+			}
+		})
+	}
+}
+
+func TestMaxInstances(t *testing.T) {
+	for value, want := range map[string]int{"3": 3, "": 0, "0": 0, "-1": 0, "many": 0} {
+		def := Definition{FrontMatter: map[string]string{MaxInstancesKey: value}}
+		if got := def.MaxInstances(); got != want {
+			t.Errorf("MaxInstances() with %q = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestMergeDefinitionsFrontMatter(t *testing.T) {
+	local := []Definition{{Name: "reviewer", Content: "# Reviewer", FrontMatter: map[string]string{"max-instances": "3", "owner": "alice"}}}
+	repo := []Definition{{Name: "reviewer", Content: "Be strict.", FrontMatter: map[string]string{"max-instances": "1"}}}
+	merged := MergeDefinitions(local, repo)
+	want := map[string]string{"max-instances": "1", "owner": "alice"}
+	if len(merged) != 1 || !reflect.DeepEqual(merged[0].FrontMatter, want) {
+		t.Errorf("merged front matter = %v, want %v", merged[0].FrontMatter, want)
+	}
+	if strings.Contains(merged[0].Content, "max-instances") {
+		t.Errorf("merged content has front matter: %q", merged[0].Content)
+	}
+}
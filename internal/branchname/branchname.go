@@ -0,0 +1,236 @@
+// Package branchname renders worker branch names from per-repository templates
+// such as "mc/{agent}/{task-slug}" or "{user}/{date}-{agent}".
+package branchname
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultTemplate is the historical fixed scheme, used when a repository has no template
+const DefaultTemplate = "work/{agent}"
+
+// Placeholders supported in templates
+const (
+	varAgent    = "{agent}"
+	varTaskSlug = "{task-slug}"
+	varDate     = "{date}"
+	varUser     = "{user}"
+)
+
+// dateFormat is how {date} is rendered
+const dateFormat = "20060102"
+
+// maxSlugLen caps {task-slug} so branch names stay readable
+const maxSlugLen = 40
+
+// Vars holds the values substituted into a template
+type Vars struct {
+	Agent string
+	Task  string
+	Date  time.Time
+	// User defaults to the current OS user when empty
+	User string
+}
+
+var placeholderRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Validate checks that a template only uses known placeholders, includes {agent}
+// (so every worker gets a unique branch) and yields a valid git branch name.
+func Validate(tmpl string) error {
+	if tmpl == "" {
+		return fmt.Errorf("branch template must not be empty")
+	}
+	if !strings.Contains(tmpl, varAgent) {
+		return fmt.Errorf("branch template %q must contain %s", tmpl, varAgent)
+	}
+	for _, p := range placeholderRe.FindAllString(tmpl, -1) {
+		switch p {
+		case varAgent, varTaskSlug, varDate, varUser:
+		default:
+			return fmt.Errorf("unknown placeholder %s in branch template (use %s, %s, %s or %s)", p, varAgent, varTaskSlug, varDate, varUser)
+		}
+	}
+	sample := placeholderRe.ReplaceAllString(tmpl, "x")
+	if strings.ContainsAny(sample, "{}") {
+		return fmt.Errorf("unbalanced braces in branch template %q", tmpl)
+	}
+	return checkRefName(sample)
+}
+
+// Render builds a branch name from a template. An empty template means DefaultTemplate.
+func Render(tmpl string, vars Vars) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	if err := Validate(tmpl); err != nil {
+		return "", err
+	}
+	if vars.Date.IsZero() {
+		vars.Date = time.Now()
+	}
+
+	r := strings.NewReplacer(
+		varAgent, vars.Agent,
+		varTaskSlug, Slugify(vars.Task),
+		varDate, vars.Date.Format(dateFormat),
+		varUser, userOrCurrent(vars.User),
+	)
+	branch := r.Replace(tmpl)
+	if err := checkRefName(branch); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// Prefix returns the fixed part of a template before the first per-worker
+// placeholder, with {user} filled in. It is empty if the template starts with
+// {agent}, {task-slug} or {date}.
+func Prefix(tmpl string) string {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	tmpl = strings.ReplaceAll(tmpl, varUser, userOrCurrent(""))
+	if i := strings.Index(tmpl, "{"); i >= 0 {
+		return tmpl[:i]
+	}
+	return tmpl
+}
+
+// Glob returns a shell-style pattern matching branches made from a template,
+// e.g. "mc/*/*" for "mc/{agent}/{task-slug}"
+func Glob(tmpl string) string {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	tmpl = strings.ReplaceAll(tmpl, varUser, userOrCurrent(""))
+	return placeholderRe.ReplaceAllString(tmpl, "*")
+}
+
+// Matches reports whether branch could have been produced by a template
+func Matches(tmpl, branch string) bool {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	rest := tmpl
+	for {
+		loc := placeholderRe.FindStringIndex(rest)
+		if loc == nil {
+			pattern.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:loc[0]]))
+		switch rest[loc[0]:loc[1]] {
+		case varDate:
+			pattern.WriteString(`\d{8}`)
+		case varUser:
+			pattern.WriteString(regexp.QuoteMeta(userOrCurrent("")))
+		default:
+			pattern.WriteString(`[^/]+`)
+		}
+		rest = rest[loc[1]:]
+	}
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(branch)
+}
+
+// managedPrefixes are branch prefixes owned entirely by multiclaude
+var managedPrefixes = []string{"multiclaude/", "work/"}
+
+// CleanupPrefixes returns the branch prefixes that merged-branch cleanup scans:
+// the built-in multiclaude prefixes plus the fixed prefix of tmpl, if it adds one.
+func CleanupPrefixes(tmpl string) []string {
+	prefixes := append([]string(nil), managedPrefixes...)
+	if tmpl == "" {
+		return prefixes
+	}
+	prefix := Prefix(tmpl)
+	for _, p := range managedPrefixes {
+		if strings.HasPrefix(prefix, p) {
+			return prefixes
+		}
+	}
+	return append(prefixes, prefix)
+}
+
+// CleanupFilter returns the branch filter for a prefix from CleanupPrefixes.
+// Built-in prefixes need none; a template prefix may be empty or shared with
+// human branches, so it only accepts names the template could have produced.
+func CleanupFilter(prefix, tmpl string) func(string) bool {
+	for _, p := range managedPrefixes {
+		if prefix == p {
+			return nil
+		}
+	}
+	return func(branch string) bool { return Matches(tmpl, branch) }
+}
+
+// Slugify turns a task description into a short, branch-safe slug
+func Slugify(task string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(task) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := sb.String()
+	if len(slug) > maxSlugLen {
+		slug = slug[:maxSlugLen]
+	}
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "task"
+	}
+	return slug
+}
+
+// userOrCurrent returns name, or the current OS user's login name if name is empty
+func userOrCurrent(name string) string {
+	if name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	} else {
+		name = os.Getenv("USER")
+	}
+	if name == "" {
+		return "user"
+	}
+	return Slugify(name)
+}
+
+// checkRefName applies the git branch naming rules that templates can break
+func checkRefName(branch string) error {
+	switch {
+	case strings.HasPrefix(branch, "/"), strings.HasSuffix(branch, "/"):
+		return fmt.Errorf("branch name %q must not start or end with '/'", branch)
+	case strings.HasPrefix(branch, "-"):
+		return fmt.Errorf("branch name %q must not start with '-'", branch)
+	case strings.HasSuffix(branch, ".lock"), strings.HasSuffix(branch, "."):
+		return fmt.Errorf("branch name %q must not end with '.' or '.lock'", branch)
+	case strings.Contains(branch, ".."), strings.Contains(branch, "//"), strings.Contains(branch, "@{"):
+		return fmt.Errorf("branch name %q contains '..', '//' or '@{'", branch)
+	case strings.ContainsAny(branch, " ~^:?*[\\\t"):
+		return fmt.Errorf("branch name %q contains characters git does not allow", branch)
+	}
+	return nil
+}
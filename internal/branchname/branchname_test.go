@@ -0,0 +1,182 @@
+package branchname
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr bool
+	}{
+		{"work/{agent}", false},
+		{"mc/{agent}/{task-slug}", false},
+		{"{user}/{date}-{agent}", false},
+		{"", true},
+		{"mc/{task-slug}", true},              // no {agent}
+		{"mc/{agent}/{ticket}", true},         // unknown placeholder
+		{"mc/{agent", true},                   // unbalanced
+		{"mc/{agent}/", true},                 // trailing slash
+		{"mc..x/{agent}", true},               // ".."
+		{"mc/{agent} {task-slug}", true},      // space
+		{"-bad/{agent}", true},                // leading dash
+		{"release/{agent}.lock", true},        // .lock suffix
+		{"team-a/{user}/{agent}", false},      // fixed user prefix
+		{"feature/{date}/{agent}-wip", false}, // trailing literal
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.tmpl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	vars := Vars{
+		Agent: "happy-eagle",
+		Task:  "Fix the login bug (#123) in auth/session.go",
+		Date:  time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+		User:  "alice",
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"", "work/happy-eagle"},
+		{"work/{agent}", "work/happy-eagle"},
+		{"mc/{agent}/{task-slug}", "mc/happy-eagle/fix-the-login-bug-123-in-auth-session-go"},
+		{"{user}/{date}-{agent}", "alice/20261016-happy-eagle"},
+	}
+
+	for _, tt := range tests {
+		got, err := Render(tt.tmpl, vars)
+		if err != nil {
+			t.Errorf("Render(%q) failed: %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+		if !Matches(tt.tmpl, got) && !strings.Contains(tt.tmpl, "{user}") {
+			t.Errorf("Matches(%q, %q) = false for a rendered branch", tt.tmpl, got)
+		}
+	}
+
+	if _, err := Render("mc/{nope}", vars); err == nil {
+		t.Error("Render() with an invalid template should fail")
+	}
+	if _, err := Render("work/{agent}", Vars{Agent: "bad name"}); err == nil {
+		t.Error("Render() producing an invalid branch should fail")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Add OAuth support", "add-oauth-support"},
+		{"  --Weird__spacing!!  ", "weird-spacing"},
+		{"!!!", "task"},
+		{"", "task"},
+		{strings.Repeat("a", 50), strings.Repeat("a", maxSlugLen)},
+		{strings.Repeat("abc ", 20), "abc-abc-abc-abc-abc-abc-abc-abc-abc-abc"},
+	}
+
+	for _, tt := range tests {
+		got := Slugify(tt.in)
+		if got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if len(got) > maxSlugLen {
+			t.Errorf("Slugify(%q) length %d exceeds %d", tt.in, len(got), maxSlugLen)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		tmpl   string
+		branch string
+		want   bool
+	}{
+		{"", "work/happy-eagle", true},
+		{"work/{agent}", "work/happy-eagle", true},
+		{"work/{agent}", "work/a/b", false},
+		{"work/{agent}", "feature/x", false},
+		{"mc/{agent}/{task-slug}", "mc/happy-eagle/fix-bug", true},
+		{"mc/{agent}/{task-slug}", "mc/happy-eagle", false},
+		{"feature/{date}-{agent}", "feature/20261016-happy-eagle", true},
+		{"feature/{date}-{agent}", "feature/today-happy-eagle", false},
+	}
+
+	for _, tt := range tests {
+		if got := Matches(tt.tmpl, tt.branch); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.tmpl, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixAndGlob(t *testing.T) {
+	tests := []struct {
+		tmpl       string
+		wantPrefix string
+		wantGlob   string
+	}{
+		{"", "work/", "work/*"},
+		{"mc/{agent}/{task-slug}", "mc/", "mc/*/*"},
+		{"{date}-{agent}", "", "*-*"},
+	}
+
+	for _, tt := range tests {
+		if got := Prefix(tt.tmpl); got != tt.wantPrefix {
+			t.Errorf("Prefix(%q) = %q, want %q", tt.tmpl, got, tt.wantPrefix)
+		}
+		if got := Glob(tt.tmpl); got != tt.wantGlob {
+			t.Errorf("Glob(%q) = %q, want %q", tt.tmpl, got, tt.wantGlob)
+		}
+	}
+
+	// {user} is filled in, so it doesn't end the prefix
+	if got := Prefix("{user}/{agent}"); got == "" || !strings.HasSuffix(got, "/") {
+		t.Errorf("Prefix({user}/{agent}) = %q, want the current user followed by '/'", got)
+	}
+}
+
+func TestCleanupPrefixes(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want []string
+	}{
+		{"", []string{"multiclaude/", "work/"}},
+		{"work/{agent}/{task-slug}", []string{"multiclaude/", "work/"}},
+		{"mc/{agent}", []string{"multiclaude/", "work/", "mc/"}},
+		{"{date}-{agent}", []string{"multiclaude/", "work/", ""}},
+	}
+
+	for _, tt := range tests {
+		got := CleanupPrefixes(tt.tmpl)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("CleanupPrefixes(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	if CleanupFilter("work/", "mc/{agent}") != nil {
+		t.Error("CleanupFilter() for a built-in prefix should be nil")
+	}
+	filter := CleanupFilter("", "{date}-{agent}")
+	if filter == nil {
+		t.Fatal("CleanupFilter() for a template prefix should not be nil")
+	}
+	if !filter("20261016-happy-eagle") {
+		t.Error("filter rejected a branch made by the template")
+	}
+	if filter("feature/login") {
+		t.Error("filter accepted a human branch")
+	}
+}
@@ -0,0 +1,269 @@
+package bugreport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
+)
+
+const (
+	// bundleLogLines is how many daemon log lines go into a bundle
+	bundleLogLines = 500
+	// bundlePaneLines is how much scrollback is captured per agent window
+	bundlePaneLines = 200
+	// commandTimeout bounds each external command run while bundling
+	commandTimeout = 5 * time.Second
+)
+
+// sanitizedState mirrors state.json with identifying information redacted
+type sanitizedState struct {
+	Repos       map[string]*state.Repository `json:"repos"`
+	CurrentRepo string                       `json:"current_repo,omitempty"`
+	Projects    map[string]*state.Project    `json:"projects,omitempty"`
+}
+
+// bundleFile is a single file to write into the bundle
+type bundleFile struct {
+	name    string
+	content []byte
+}
+
+// WriteBundle writes a tar.gz bundle with the report, version information, a
+// daemon log excerpt, a sanitized state.json and a snapshot of every agent's
+// tmux window. All text is redacted and scrubbed of secrets before writing.
+func (c *Collector) WriteBundle(report *Report, outputPath string) error {
+	files := []bundleFile{
+		{name: "report.md", content: []byte(FormatMarkdown(report))},
+		{name: "versions.txt", content: []byte(c.collectVersions(report))},
+	}
+
+	// Sanitize state first so repo names are registered with the redactor
+	// before logs and pane snapshots are redacted
+	st, err := state.Load(c.paths.StateFile)
+	if err != nil {
+		files = append(files, bundleFile{name: "state.json", content: []byte(fmt.Sprintf("(failed to load state: %v)\n", err))})
+	} else {
+		stateData, err := json.MarshalIndent(c.sanitizeState(st), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal sanitized state: %w", err)
+		}
+		// Fields are already redacted individually; only scrub stray secrets here
+		// since free-text rewriting could break the JSON
+		files = append(files, bundleFile{name: "state.json", content: []byte(c.redactor.Secrets(string(stateData)))})
+		files = append(files, c.capturePanes(st)...)
+	}
+
+	files = append(files, bundleFile{name: "daemon.log", content: []byte(c.tailDaemonLog(bundleLogLines))})
+
+	return writeTarGz(outputPath, files)
+}
+
+// collectVersions renders version information for every relevant tool
+func (c *Collector) collectVersions(report *Report) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("multiclaude: %s\n", report.Version))
+	sb.WriteString(fmt.Sprintf("go: %s\n", report.GoVersion))
+	sb.WriteString(fmt.Sprintf("os/arch: %s/%s\n", report.OS, report.Arch))
+	sb.WriteString(fmt.Sprintf("tmux: %s\n", report.TmuxVersion))
+	sb.WriteString(fmt.Sprintf("git: %s\n", report.GitVersion))
+
+	claudeVersion := "not found"
+	if report.ClaudeExists {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		if output, err := exec.CommandContext(ctx, "claude", "--version").Output(); err == nil {
+			claudeVersion = strings.TrimSpace(string(output))
+		} else {
+			claudeVersion = "installed (version unavailable)"
+		}
+	}
+	sb.WriteString(fmt.Sprintf("claude: %s\n", claudeVersion))
+	return sb.String()
+}
+
+// sanitizeState returns a copy of state with names, URLs, paths and free text redacted
+func (c *Collector) sanitizeState(st *state.State) *sanitizedState {
+	repos := st.GetAllRepos()
+
+	// Register every repo name up front so free text redacts consistently
+	repoNames := make([]string, 0, len(repos))
+	for name := range repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+	for _, name := range repoNames {
+		c.redactor.RepoName(name)
+	}
+
+	out := &sanitizedState{Repos: make(map[string]*state.Repository, len(repos))}
+	for _, name := range repoNames {
+		repo := repos[name]
+		redactedName := c.redactor.RepoName(name)
+
+		repo.GithubURL = c.redactor.GitHubURL(repo.GithubURL)
+		repo.TmuxSession = c.redactor.Text(repo.TmuxSession)
+		repo.ForkConfig.UpstreamURL = c.redactor.GitHubURL(repo.ForkConfig.UpstreamURL)
+		if repo.ForkConfig.UpstreamOwner != "" {
+			repo.ForkConfig.UpstreamOwner = "<owner>"
+		}
+		if repo.ForkConfig.UpstreamRepo != "" {
+			repo.ForkConfig.UpstreamRepo = "<repo>"
+		}
+		repo.NotifyConfig.To = redactAll(repo.NotifyConfig.To)
+		repo.NotifyConfig.From = redactIfSet(repo.NotifyConfig.From)
+		repo.NotifyConfig.SMTPUsername = redactIfSet(repo.NotifyConfig.SMTPUsername)
+
+		agents := make(map[string]state.Agent, len(repo.Agents))
+		for agentName, agent := range repo.Agents {
+			redactedAgent := c.redactor.AgentName(agentName, string(agent.Type))
+			agent.WorktreePath = c.redactor.Path(agent.WorktreePath)
+			if agent.TmuxWindow != "" {
+				agent.TmuxWindow = redactedAgent
+			}
+			agent.Task = c.redactor.Text(agent.Task)
+			agent.Summary = c.redactor.Text(agent.Summary)
+			agent.FailureReason = c.redactor.Text(agent.FailureReason)
+			agents[redactedAgent] = agent
+		}
+		repo.Agents = agents
+
+		for i := range repo.TaskHistory {
+			entry := &repo.TaskHistory[i]
+			entry.Task = c.redactor.Text(entry.Task)
+			entry.Summary = c.redactor.Text(entry.Summary)
+			entry.FailureReason = c.redactor.Text(entry.FailureReason)
+			entry.PRURL = c.redactor.GitHubURL(entry.PRURL)
+		}
+
+		out.Repos[redactedName] = repo
+	}
+
+	if current := st.GetCurrentRepo(); current != "" {
+		out.CurrentRepo = c.redactor.RepoName(current)
+	}
+
+	for _, projectName := range st.ListProjects() {
+		project, ok := st.GetProject(projectName)
+		if !ok {
+			continue
+		}
+		for i, repoName := range project.Repos {
+			project.Repos[i] = c.redactor.RepoName(repoName)
+		}
+		if out.Projects == nil {
+			out.Projects = make(map[string]*state.Project)
+		}
+		out.Projects[fmt.Sprintf("project-%d", len(out.Projects)+1)] = &project
+	}
+
+	return out
+}
+
+// capturePanes snapshots each agent's tmux window
+func (c *Collector) capturePanes(st *state.State) []bundleFile {
+	client := tmux.NewClient()
+	if !client.IsTmuxAvailable() {
+		return nil
+	}
+
+	var files []bundleFile
+	repos := st.GetAllRepos()
+	for repoName, repo := range repos {
+		for agentName, agent := range repo.Agents {
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			content, err := client.CapturePane(ctx, repo.TmuxSession, agent.TmuxWindow, bundlePaneLines)
+			cancel()
+			if err != nil {
+				content = fmt.Sprintf("(capture failed: %v)\n", err)
+			}
+
+			name := path.Join("panes", c.redactor.RepoName(repoName), c.redactor.AgentName(agentName, string(agent.Type))+".txt")
+			files = append(files, bundleFile{name: name, content: []byte(c.redactor.Text(content))})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+// tailDaemonLog returns the last n lines of the daemon log, redacted
+func (c *Collector) tailDaemonLog(n int) string {
+	data, err := os.ReadFile(c.paths.DaemonLog)
+	if err != nil {
+		return "(no log file found)"
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return c.redactor.Text(strings.Join(lines, "\n"))
+}
+
+// writeTarGz writes files into a gzip-compressed tar archive under a common top-level directory
+func writeTarGz(outputPath string, files []bundleFile) error {
+	out, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	root := strings.TrimSuffix(path.Base(outputPath), ".tar.gz")
+	modTime := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    path.Join(root, f.name),
+			Mode:    0600,
+			Size:    int64(len(f.content)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write %s to bundle: %w", f.name, err)
+		}
+		if _, err := tw.Write(f.content); err != nil {
+			return fmt.Errorf("failed to write %s to bundle: %w", f.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	return out.Close()
+}
+
+// redactIfSet replaces a non-empty value with a placeholder
+func redactIfSet(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<redacted>"
+}
+
+// redactAll replaces every value with a placeholder
+func redactAll(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	out := make([]string, len(values))
+	for i := range values {
+		out[i] = "<redacted>"
+	}
+	return out
+}
@@ -1,15 +1,18 @@
 package bugreport
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
 
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
 	"github.com/micheal-at/multiclaude/internal/redact"
 	"github.com/micheal-at/multiclaude/internal/state"
 	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/retry"
 )
 
 // Report contains all collected diagnostic information
@@ -63,11 +66,14 @@ type Collector struct {
 	version  string
 }
 
-// NewCollector creates a new diagnostic collector
+// NewCollector creates a new diagnostic collector. Secrets matching the
+// patterns in the redact file are scrubbed along with the built-in ones; a
+// redact file that can't be used leaves only the built-in patterns.
 func NewCollector(paths *config.Paths, version string) *Collector {
+	redactor, _ := redact.NewFromFile(paths.RedactFile())
 	return &Collector{
 		paths:    paths,
-		redactor: redact.New(),
+		redactor: redactor,
 		version:  version,
 	}
 }
@@ -104,8 +110,9 @@ func (c *Collector) Collect(description string, verbose bool) (*Report, error) {
 
 // getTmuxVersion returns the tmux version or an error message
 func (c *Collector) getTmuxVersion() string {
-	cmd := exec.Command("tmux", "-V")
-	output, err := cmd.Output()
+	output, err := retry.Tmux.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "tmux", "-V")
+	})
 	if err != nil {
 		return "not installed"
 	}
@@ -114,12 +121,11 @@ func (c *Collector) getTmuxVersion() string {
 
 // getGitVersion returns the git version or an error message
 func (c *Collector) getGitVersion() string {
-	cmd := exec.Command("git", "--version")
-	output, err := cmd.Output()
+	version, err := gitcmd.Run(context.Background(), "", "--version")
 	if err != nil {
 		return "not installed"
 	}
-	return strings.TrimSpace(string(output))
+	return version
 }
 
 // checkClaudeExists checks if the claude CLI is available
@@ -0,0 +1,79 @@
+// Package cache provides a small in-memory TTL cache for expensive lookups,
+// such as gh and git queries, that several callers would otherwise repeat.
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache holds values by key for a fixed time-to-live. Failed lookups are not
+// cached. It is safe for concurrent use.
+type Cache[V any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry[V]
+}
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// New creates a cache whose entries expire after ttl
+func New[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]entry[V]),
+	}
+}
+
+// Get returns the cached value for key, calling fetch to fill the cache when
+// the entry is missing or expired
+func (c *Cache[V]) Get(key string, fetch func() (V, error)) (V, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return e.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry[V]{value: value, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// Invalidate drops the entry for key
+func (c *Cache[V]) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// InvalidatePrefix drops every entry whose key starts with prefix
+func (c *Cache[V]) InvalidatePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet replaced
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
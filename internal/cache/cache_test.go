@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheGet(t *testing.T) {
+	now := time.Now()
+	c := New[int](time.Minute)
+	c.now = func() time.Time { return now }
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	if v, err := c.Get("a", fetch); err != nil || v != 1 {
+		t.Fatalf("Get() = %d, %v; want 1", v, err)
+	}
+	if v, _ := c.Get("a", fetch); v != 1 || calls != 1 {
+		t.Errorf("second Get() = %d after %d fetches; want cached 1", v, calls)
+	}
+
+	// Expired entries are fetched again
+	now = now.Add(time.Minute + time.Second)
+	if v, _ := c.Get("a", fetch); v != 2 {
+		t.Errorf("Get() after expiry = %d, want 2", v)
+	}
+}
+
+func TestCacheErrorsNotCached(t *testing.T) {
+	c := New[string](time.Minute)
+	boom := errors.New("boom")
+
+	if _, err := c.Get("k", func() (string, error) { return "", boom }); err != boom {
+		t.Fatalf("Get() error = %v, want boom", err)
+	}
+	if c.Len() != 0 {
+		t.Error("failed lookup should not be cached")
+	}
+	if v, err := c.Get("k", func() (string, error) { return "ok", nil }); err != nil || v != "ok" {
+		t.Errorf("Get() = %q, %v; want ok", v, err)
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	c := New[int](time.Minute)
+	for _, key := range []string{"repo-a:x", "repo-a:y", "repo-b:x"} {
+		c.Get(key, func() (int, error) { return 1, nil })
+	}
+
+	c.Invalidate("repo-b:x")
+	if c.Len() != 2 {
+		t.Errorf("Len() after Invalidate = %d, want 2", c.Len())
+	}
+
+	c.InvalidatePrefix("repo-a:")
+	if c.Len() != 0 {
+		t.Errorf("Len() after InvalidatePrefix = %d, want 0", c.Len())
+	}
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+)
+
+// bulkFlags are the flags of the commands that act on many workers at once
+var bulkFlags = []Flag{
+	{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+	{Name: "all", Type: FlagBool, Description: "Every worker, or every one the filters match"},
+	{Name: "filter", Value: "<key>=<value>", Description: "Only workers matching status=<status>[,...] or name=<glob>[,...]; may be repeated"},
+	{Name: "ready-for-cleanup", Type: FlagBool, Description: "Only workers that have completed (status=completed)"},
+	{Name: "idle", Type: FlagBool, Description: "Only running workers that have gone quiet (status=idle)"},
+	{Name: "dry-run", Type: FlagBool, Description: "List the workers that match without doing anything"},
+	{Name: "yes", Type: FlagBool, Description: "Don't ask for confirmation"},
+	{Name: "json", Type: FlagBool, Description: "Print the matched workers and results as JSON"},
+}
+
+// bulkVerbs describe the bulk actions in confirmations and results
+var bulkVerbs = map[string][2]string{
+	"rm":       {"Remove", "removed"},
+	"nudge":    {"Nudge", "nudged"},
+	"complete": {"Complete", "completed"},
+}
+
+// bulkSelectors are the flags that make worker rm act on the workers they
+// select rather than one named worker
+var bulkSelectors = map[string]bool{"all": true, "filter": true, "ready-for-cleanup": true, "idle": true, "dry-run": true, "yes": true}
+
+// isBulkRemove reports whether worker rm's arguments select workers by
+// filter rather than by name
+func isBulkRemove(args []string) bool {
+	for _, arg := range args {
+		if name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "="); strings.HasPrefix(arg, "-") && bulkSelectors[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkWorkers runs a bulk action on the workers of ctx.Repo that match the
+// filters, in one daemon request. It lists the workers first and asks before
+// acting, unless --yes; with --dry-run it stops after the list. Names given
+// as arguments select workers by name.
+func (c *CLI) bulkWorkers(ctx CommandContext, action string, args []string) error {
+	filters, args, err := cutRepeatedFlag(args, "filter")
+	if err != nil {
+		return err
+	}
+	flags, err := ParseFlagSpecs(bulkFlags, args)
+	if err != nil {
+		return err
+	}
+	if flags.Bool("ready-for-cleanup") {
+		filters = append(filters, "status=completed")
+	}
+	if flags.Bool("idle") {
+		filters = append(filters, "status=idle")
+	}
+	if len(flags.Args()) > 0 {
+		filters = append(filters, "name="+strings.Join(flags.Args(), ","))
+	}
+	if len(filters) == 0 && !flags.Bool("all") {
+		return errors.InvalidUsage(fmt.Sprintf("select workers with --all, --filter <key>=<value>, --ready-for-cleanup or --idle (e.g. multiclaude worker %s --all --ready-for-cleanup)", action))
+	}
+
+	reqArgs := map[string]interface{}{
+		"repo":    ctx.Repo,
+		"action":  action,
+		"filters": filters,
+		"all":     flags.Bool("all"),
+		"dry_run": true,
+	}
+	resp, err := c.sendDaemonRequest("bulk_workers", reqArgs)
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	matched, _ := data["matched"].([]interface{})
+	if len(matched) == 0 {
+		if flags.Bool("json") {
+			return printJSON(data)
+		}
+		fmt.Printf("No workers in %s match\n", ctx.Repo)
+		return nil
+	}
+
+	verb := bulkVerbs[action]
+	if !flags.Bool("json") {
+		table := format.NewColoredTable("NAME", "STATUS", "PR", "TASK").Flexible("TASK", 20)
+		for _, m := range matched {
+			worker, _ := m.(map[string]interface{})
+			name, _ := worker["name"].(string)
+			status, _ := worker["status"].(string)
+			prStatus, _ := worker["pr_status"].(string)
+			if prStatus == "" {
+				prStatus = "-"
+			}
+			task, _ := worker["task"].(string)
+			table.AddRow(format.Cell(name), formatAgentStatusCell(status), format.Cell(prStatus), format.Cell(task))
+		}
+		table.Print()
+	}
+	if flags.Bool("dry-run") {
+		if flags.Bool("json") {
+			return printJSON(data)
+		}
+		fmt.Printf("\nDry run: %d workers would be %s\n", len(matched), verb[1])
+		return nil
+	}
+
+	if !flags.Bool("yes") {
+		if !isTerminal(os.Stdin) {
+			return errors.InvalidUsage(fmt.Sprintf("%s %d workers? Confirm with --yes, or list them with --dry-run", strings.ToLower(verb[0]), len(matched)))
+		}
+		fmt.Printf("\n%s these %d workers? [y/N]: ", verb[0], len(matched))
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	// Only the workers that were listed, in case more match by now
+	names := make([]string, 0, len(matched))
+	for _, m := range matched {
+		if worker, ok := m.(map[string]interface{}); ok {
+			name, _ := worker["name"].(string)
+			names = append(names, name)
+		}
+	}
+	reqArgs["dry_run"] = false
+	reqArgs["agents"] = names
+	if resp, err = c.sendDaemonRequest("bulk_workers", reqArgs); err != nil {
+		return err
+	}
+	data, _ = resp.Data.(map[string]interface{})
+	results, _ := data["results"].([]interface{})
+	if flags.Bool("json") {
+		if err := printJSON(data); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		result, _ := r.(map[string]interface{})
+		name, _ := result["name"].(string)
+		if ok, _ := result["ok"].(bool); ok {
+			if !flags.Bool("json") {
+				fmt.Printf("%s %s %s\n", format.Green.Sprint("✓"), name, verb[1])
+			}
+			continue
+		}
+		failed++
+		if !flags.Bool("json") {
+			reason, _ := result["error"].(string)
+			fmt.Printf("%s %s: %s\n", format.Red.Sprint("✗"), name, reason)
+		}
+	}
+	if failed > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d of %d workers could not be %s", failed, len(results), verb[1]))
+	}
+	return nil
+}
+
+// printJSON prints v indented, for --json
+func printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
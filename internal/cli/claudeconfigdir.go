@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/pkg/claude"
+)
+
+// claudeConfigDir returns the Claude config directory a repository's agents
+// run with, or "" for ~/.claude. It's checked each time an agent starts, since
+// the credentials can go away after the directory was configured.
+func (c *CLI) claudeConfigDir(repoName string) (string, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return "", err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists || repo.ClaudeConfigDir == "" {
+		return "", nil
+	}
+	if err := claude.ValidateConfigDir(repo.ClaudeConfigDir); err != nil {
+		return "", errors.Wrap(errors.CategoryConfig, "cannot start Claude for "+repoName, err).
+			WithSuggestion("log in there, or go back to ~/.claude: multiclaude config " + repoName + " --claude-config-dir=default")
+	}
+	return repo.ClaudeConfigDir, nil
+}
+
+// claudeProjectsDir returns where Claude keeps the session history of a
+// repository's agents. History is read whether or not the directory still
+// holds credentials.
+func (c *CLI) claudeProjectsDir(repoName string) (string, error) {
+	configDir := ""
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetRepo(repoName); exists {
+			configDir = repo.ClaudeConfigDir
+		}
+	}
+	return claude.ProjectsDir(configDir)
+}
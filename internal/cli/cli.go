@@ -5,30 +5,62 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/branchname"
 	"github.com/micheal-at/multiclaude/internal/bugreport"
+	"github.com/micheal-at/multiclaude/internal/clone"
 	"github.com/micheal-at/multiclaude/internal/daemon"
+	"github.com/micheal-at/multiclaude/internal/digest"
 	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/events"
+	"github.com/micheal-at/multiclaude/internal/export"
+	"github.com/micheal-at/multiclaude/internal/federation"
 	"github.com/micheal-at/multiclaude/internal/fork"
 	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
 	"github.com/micheal-at/multiclaude/internal/hooks"
+	"github.com/micheal-at/multiclaude/internal/logging"
 	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/mirror"
 	"github.com/micheal-at/multiclaude/internal/names"
+	"github.com/micheal-at/multiclaude/internal/notify"
+	"github.com/micheal-at/multiclaude/internal/outputs"
+	"github.com/micheal-at/multiclaude/internal/presets"
 	"github.com/micheal-at/multiclaude/internal/prompts"
+	"github.com/micheal-at/multiclaude/internal/prompts/commands"
+	"github.com/micheal-at/multiclaude/internal/reconcile"
+	"github.com/micheal-at/multiclaude/internal/redact"
+	"github.com/micheal-at/multiclaude/internal/repoconfig"
+	"github.com/micheal-at/multiclaude/internal/routing"
+	"github.com/micheal-at/multiclaude/internal/scaffold"
+	"github.com/micheal-at/multiclaude/internal/service"
+	"github.com/micheal-at/multiclaude/internal/simagent"
 	"github.com/micheal-at/multiclaude/internal/socket"
 	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/tasks"
 	"github.com/micheal-at/multiclaude/internal/templates"
+	"github.com/micheal-at/multiclaude/internal/train"
+	"github.com/micheal-at/multiclaude/internal/trash"
+	"github.com/micheal-at/multiclaude/internal/workhours"
 	"github.com/micheal-at/multiclaude/internal/worktree"
+	"github.com/micheal-at/multiclaude/internal/wsl"
 	"github.com/micheal-at/multiclaude/pkg/claude"
 	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/retry"
 	"github.com/micheal-at/multiclaude/pkg/tmux"
 )
 
@@ -77,6 +109,11 @@ type Command struct {
 	Usage       string
 	Run         func(args []string) error
 	Subcommands map[string]*Command
+
+	// Flags declares the command's flags; they are listed in its usage and
+	// help, and RunFlags gets them parsed and validated
+	Flags    []Flag
+	RunFlags func(flags *FlagSet) error
 }
 
 // CLI manages the command-line interface
@@ -84,6 +121,16 @@ type CLI struct {
 	rootCmd       *Command
 	paths         *config.Paths
 	documentation string // Auto-generated CLI documentation for prompts
+
+	// traceID correlates the daemon requests, log lines and events of one
+	// invocation; traced records whether anything was tagged with it
+	traceID  string
+	traced   bool
+	traceLog *logging.Logger
+
+	// jsonOutput records whether the last Execute was asked for --json, so
+	// its error is printed as JSON too
+	jsonOutput bool
 }
 
 // New creates a new CLI
@@ -102,6 +149,12 @@ func New() (*CLI, error) {
 		},
 	}
 
+	// Repositories may keep their worktrees under a base directory of their
+	// own; the daemon sets this up itself when it shares the paths
+	if paths.WorktreeBase == nil {
+		paths.WorktreeBase = cli.worktreeBase
+	}
+
 	cli.registerCommands()
 
 	// Generate documentation after commands are registered
@@ -121,6 +174,12 @@ func NewWithPaths(paths *config.Paths) *CLI {
 		},
 	}
 
+	// Repositories may keep their worktrees under a base directory of their
+	// own; the daemon sets this up itself when it shares the paths
+	if paths.WorktreeBase == nil {
+		paths.WorktreeBase = cli.worktreeBase
+	}
+
 	cli.registerCommands()
 
 	// Generate documentation after commands are registered
@@ -131,6 +190,10 @@ func NewWithPaths(paths *config.Paths) *CLI {
 
 // getClaudeBinary resolves the claude binary path
 func (c *CLI) getClaudeBinary() (string, error) {
+	// The simulator stands in for Claude, and it's multiclaude itself
+	if simagent.Enabled() {
+		return os.Executable()
+	}
 	binaryPath, err := exec.LookPath("claude")
 	if err != nil {
 		return "", errors.ClaudeNotFound(err)
@@ -150,7 +213,7 @@ func (c *CLI) loadState() (*state.State, error) {
 // sendDaemonRequest sends a request to the daemon and handles common error cases.
 // It returns the response if successful, or an error if communication fails or the daemon returns an error.
 func (c *CLI) sendDaemonRequest(command string, args map[string]interface{}) (*socket.Response, error) {
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: command,
 		Args:    args,
@@ -159,11 +222,17 @@ func (c *CLI) sendDaemonRequest(command string, args map[string]interface{}) (*s
 		return nil, errors.DaemonCommunicationFailed(command, err)
 	}
 	if !resp.Success {
-		return nil, fmt.Errorf("%s failed: %s", command, resp.Error)
+		return nil, daemonError(command+" failed", resp)
 	}
 	return resp, nil
 }
 
+// daemonError describes a failed daemon response, keeping the code and
+// remediation the daemon gave
+func daemonError(message string, resp *socket.Response) *errors.CLIError {
+	return errors.FromDaemon(message, resp.Error, errors.Code(resp.Code), resp.Remediation)
+}
+
 // removeDirectoryIfExists removes a directory and prints status messages.
 // It prints a warning if removal fails, or a success message if it succeeds.
 // If the directory doesn't exist, it does nothing.
@@ -201,6 +270,8 @@ func sanitizeTmuxSessionName(repoName string) string {
 
 // Execute executes the CLI with the given arguments
 func (c *CLI) Execute(args []string) error {
+	args, plain := stripPlainFlag(args)
+	format.SetPlain(plain)
 	if len(args) == 0 {
 		return c.showHelp()
 	}
@@ -210,7 +281,103 @@ func (c *CLI) Execute(args []string) error {
 		return c.showVersion()
 	}
 
-	return c.executeCommand(c.rootCmd, args)
+	c.traceID, c.traced = logging.NewTraceID(), false
+	c.jsonOutput = false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--json" {
+			c.jsonOutput = true
+		}
+	}
+	if c.traceLog != nil {
+		c.traceLog.Close()
+		c.traceLog = nil
+	}
+
+	start := time.Now()
+	err := c.executeCommand(c.rootCmd, args)
+	c.recordUsage(args, time.Since(start), err)
+	if err != nil && c.traced {
+		c.tracef("%s failed: %v", args[0], err)
+	}
+	return err
+}
+
+// stripPlainFlag removes the global --plain flag, which any command takes
+// to print without color or status icons, from args before the command
+// parses them
+func stripPlainFlag(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	plain := false
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == "--plain" {
+			plain = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, plain
+}
+
+// TraceID returns the ID the last Execute tagged its daemon requests and log
+// lines with, or "" if it didn't talk to the daemon
+func (c *CLI) TraceID() string {
+	if !c.traced {
+		return ""
+	}
+	return c.traceID
+}
+
+// JSONOutput reports whether the last Execute was run with --json, in which
+// case its error should be printed with errors.FormatJSON
+func (c *CLI) JSONOutput() bool {
+	return c.jsonOutput
+}
+
+// daemonClient returns a client for the daemon socket whose requests carry
+// this invocation's trace ID
+func (c *CLI) daemonClient() *socket.Client {
+	client := socket.NewClient(c.paths.DaemonSock)
+	if c.traceID != "" {
+		client.SetTraceID(c.traceID)
+		c.traced = true
+	}
+	return client
+}
+
+// redactor returns a Redactor with the built-in secret patterns and those in
+// the redact file. A broken redact file is reported and otherwise ignored.
+func (c *CLI) redactor() *redact.Redactor {
+	r, err := redact.NewFromFile(c.paths.RedactFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring extra secret patterns: %v\n", err)
+	}
+	return r
+}
+
+// tracef records a step of this invocation in the daemon log, tagged with its
+// trace ID, so the steps the CLI takes itself (worktrees, tmux, starting
+// Claude) sit alongside the daemon's. Tracing is best effort.
+func (c *CLI) tracef(format string, args ...interface{}) {
+	if c.traceID == "" {
+		return
+	}
+	if c.traceLog == nil {
+		l, err := logging.NewFile(c.paths.DaemonLog)
+		if err != nil {
+			return
+		}
+		l.SetScrubber(c.redactor().Secrets)
+		c.traceLog = l.WithTrace(c.traceID)
+	}
+	c.traced = true
+	c.traceLog.Info("cli: "+format, args...)
 }
 
 // showVersion displays the version information
@@ -241,11 +408,92 @@ func (c *CLI) versionCommand(args []string) error {
 	return nil
 }
 
+// localPath translates a Windows path given on the command line (C:\..., \\wsl$\...)
+// to its location inside WSL. Other paths, and all paths outside WSL, are unchanged.
+func localPath(p string) string {
+	if wsl.IsWindowsPath(p) && wsl.Detect() {
+		return wsl.ToLinux(p)
+	}
+	return p
+}
+
+// wslStatus shows whether multiclaude is running under WSL, how the daemon
+// listens, and where Windows tools find multiclaude's files
+func (c *CLI) wslStatus(args []string) error {
+	if !wsl.Detect() {
+		fmt.Println("Not running under WSL.")
+		return nil
+	}
+	distro := wsl.Distro()
+	if distro == "" {
+		distro = "(unknown: WSL_DISTRO_NAME is not set)"
+	}
+	fmt.Println("Running under WSL")
+	fmt.Printf("  Distribution: %s\n", distro)
+
+	transport, err := daemon.Transport()
+	if err != nil {
+		return errors.Wrap(errors.CategoryConfig, "invalid daemon transport", err)
+	}
+	fmt.Printf("  Daemon transport: %s", transport)
+	if transport == socket.TransportAuto {
+		fmt.Print(" (Unix socket, TCP loopback if the socket can't be created)")
+	}
+	fmt.Println()
+	if address, _, err := socket.ReadTCPAddress(c.paths.DaemonSock); err == nil {
+		fmt.Printf("  Daemon listening on: %s (TCP loopback)\n", address)
+	}
+
+	fmt.Println("\nPaths for Windows tools:")
+	for _, p := range []struct{ name, path string }{
+		{"Root", c.paths.Root},
+		{"Daemon log", c.paths.DaemonLog},
+		{"Repositories", c.paths.ReposDir},
+		{"Worktrees", c.paths.WorktreesDir},
+	} {
+		fmt.Printf("  %-13s %s\n", p.name+":", wsl.ToWindows(p.path, wsl.Distro()))
+	}
+	fmt.Println("\nTranslate other paths with: multiclaude wsl path <path>")
+	return nil
+}
+
+// wslPath translates a path between its WSL and Windows forms. Without
+// --windows or --linux, Windows paths become WSL paths and vice versa.
+func (c *CLI) wslPath(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude wsl path <path> [--windows|--linux]")
+	}
+	p := posArgs[0]
+
+	toWindows := !wsl.IsWindowsPath(p)
+	if flags["windows"] == "true" {
+		toWindows = true
+	} else if flags["linux"] == "true" {
+		toWindows = false
+	}
+
+	if !toWindows {
+		fmt.Println(wsl.ToLinux(p))
+		return nil
+	}
+	if wsl.IsWindowsPath(p) {
+		fmt.Println(p)
+		return nil
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return errors.Wrap(errors.CategoryUsage, "invalid path", err)
+	}
+	fmt.Println(wsl.ToWindows(abs, wsl.Distro()))
+	return nil
+}
+
 // executeCommand recursively executes commands and subcommands
 func (c *CLI) executeCommand(cmd *Command, args []string) error {
 	if len(args) == 0 {
-		if cmd.Run != nil {
-			return cmd.Run([]string{})
+		if cmd.Run != nil || cmd.RunFlags != nil {
+			return c.runCommand(cmd, []string{})
 		}
 		return c.showCommandHelp(cmd)
 	}
@@ -261,13 +509,25 @@ func (c *CLI) executeCommand(cmd *Command, args []string) error {
 	}
 
 	// No subcommand found, run this command with args
-	if cmd.Run != nil {
-		return cmd.Run(args)
+	if cmd.Run != nil || cmd.RunFlags != nil {
+		return c.runCommand(cmd, args)
 	}
 
 	return errors.UnknownCommand(args[0])
 }
 
+// runCommand runs a command, parsing its declared flags first if it has them
+func (c *CLI) runCommand(cmd *Command, args []string) error {
+	if cmd.RunFlags == nil {
+		return cmd.Run(args)
+	}
+	flags, err := cmd.parseFlags(args)
+	if err != nil {
+		return err
+	}
+	return cmd.RunFlags(flags)
+}
+
 // showHelp shows the main help message
 func (c *CLI) showHelp() error {
 	fmt.Println("multiclaude - repo-centric orchestrator for Claude Code")
@@ -280,6 +540,10 @@ func (c *CLI) showHelp() error {
 		fmt.Printf("  %-15s %s\n", name, cmd.Description)
 	}
 
+	fmt.Println()
+	fmt.Println("Global flags:")
+	fmt.Printf("  %-15s %s\n", "--json", "Print errors (and, where supported, output) as JSON")
+	fmt.Printf("  %-15s %s\n", "--plain", "Print without color or status icons (NO_COLOR does the same for color)")
 	fmt.Println()
 	fmt.Println("Use 'multiclaude <command> --help' for more information about a command.")
 	return nil
@@ -289,8 +553,16 @@ func (c *CLI) showHelp() error {
 func (c *CLI) showCommandHelp(cmd *Command) error {
 	fmt.Printf("%s - %s\n", cmd.Name, cmd.Description)
 	fmt.Println()
-	if cmd.Usage != "" {
-		fmt.Printf("Usage: %s\n", cmd.Usage)
+	if usage := cmd.usage(); usage != "" {
+		fmt.Printf("Usage: %s\n", usage)
+		fmt.Println()
+	}
+
+	if len(cmd.Flags) > 0 {
+		fmt.Println("Flags:")
+		for _, f := range cmd.Flags {
+			fmt.Printf("  %-24s %s\n", f.placeholder(), f.details())
+		}
 		fmt.Println()
 	}
 
@@ -343,17 +615,82 @@ func (c *CLI) registerCommands() {
 	daemonCmd.Subcommands["status"] = &Command{
 		Name:        "status",
 		Description: "Show daemon status",
-		Usage:       "multiclaude daemon status",
-		Run:         c.daemonStatus,
+		Usage:       "multiclaude daemon status [--verbose]",
+		Flags: []Flag{
+			{Name: "verbose", Type: FlagBool, Description: "Also show each loop's last run and duration, queue depths and error counts"},
+		},
+		RunFlags: c.daemonStatus,
+	}
+
+	daemonCmd.Subcommands["standby"] = &Command{
+		Name:        "standby",
+		Description: "Enter low-power standby until resumed",
+		Usage:       "multiclaude daemon standby",
+		Run:         c.daemonStandby,
+	}
+
+	daemonCmd.Subcommands["resume"] = &Command{
+		Name:        "resume",
+		Description: "Leave standby and resume paused agents",
+		Usage:       "multiclaude daemon resume",
+		Run:         c.daemonResume,
+	}
+
+	daemonCmd.Subcommands["reload"] = &Command{
+		Name:        "reload",
+		Description: "Reread daemon.yaml without restarting agents",
+		Usage:       "multiclaude daemon reload",
+		Run:         c.daemonReload,
+	}
+
+	daemonCmd.Subcommands["debug"] = &Command{
+		Name:        "debug",
+		Description: "Show goroutines, loop timings, queue depths and recent errors of the running daemon",
+		Usage:       "multiclaude daemon debug [--pprof] [--json]",
+		Flags: []Flag{
+			{Name: "pprof", Type: FlagBool, Description: "Also write goroutine and heap profiles and print their paths"},
+			{Name: "json", Type: FlagBool, Description: "Print the report as JSON"},
+		},
+		RunFlags: c.daemonDebug,
 	}
 
 	daemonCmd.Subcommands["logs"] = &Command{
 		Name:        "logs",
 		Description: "View daemon logs",
-		Usage:       "multiclaude daemon logs [-f|--follow] [-n <lines>]",
+		Usage:       "multiclaude daemon logs [-f|--follow] [-n <lines>] [--trace <id>]",
 		Run:         c.daemonLogs,
 	}
 
+	daemonCmd.Subcommands["install-service"] = &Command{
+		Name:        "install-service",
+		Description: "Run the daemon as a user service (systemd or launchd) that restarts after crashes and reboots",
+		Usage:       "multiclaude daemon install-service [--systemd|--launchd] [--print]",
+		Flags: []Flag{
+			{Name: "systemd", Type: FlagBool, Description: "Install a systemd user unit (the default on Linux)"},
+			{Name: "launchd", Type: FlagBool, Description: "Install a launchd agent (the default on macOS)"},
+			{Name: "print", Type: FlagBool, Description: "Print the unit or plist instead of installing it"},
+		},
+		Run: c.installDaemonService,
+	}
+
+	daemonCmd.Subcommands["uninstall-service"] = &Command{
+		Name:        "uninstall-service",
+		Description: "Remove the daemon's user service",
+		Usage:       "multiclaude daemon uninstall-service [--systemd|--launchd]",
+		Flags: []Flag{
+			{Name: "systemd", Type: FlagBool, Description: "Remove the systemd user unit"},
+			{Name: "launchd", Type: FlagBool, Description: "Remove the launchd agent"},
+		},
+		Run: c.uninstallDaemonService,
+	}
+
+	daemonCmd.Subcommands["watch"] = &Command{
+		Name:        "watch",
+		Description: "Keep the daemon running: restart it if it dies or stops responding",
+		Usage:       "multiclaude daemon watch [--interval <duration>]",
+		Run:         c.watchDaemon,
+	}
+
 	daemonCmd.Subcommands["_run"] = &Command{
 		Name:        "_run",
 		Description: "Internal: run daemon in foreground (used by daemon start)",
@@ -370,6 +707,16 @@ func (c *CLI) registerCommands() {
 		Run:         c.stopAll,
 	}
 
+	c.rootCmd.Subcommands["migrate-paths"] = &Command{
+		Name:        "migrate-paths",
+		Description: "Move ~/.multiclaude into the XDG base directories",
+		Usage:       "multiclaude migrate-paths",
+		Flags: []Flag{
+			{Name: "dry-run", Type: FlagBool, Description: "Show what would move without moving anything"},
+		},
+		RunFlags: c.migratePaths,
+	}
+
 	// Repository commands (repo subcommand)
 	repoCmd := &Command{
 		Name:        "repo",
@@ -380,10 +727,17 @@ func (c *CLI) registerCommands() {
 	repoCmd.Subcommands["init"] = &Command{
 		Name:        "init",
 		Description: "Initialize a repository",
-		Usage:       "multiclaude repo init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned]",
+		Usage:       "multiclaude repo init <github-url> [name] | --org <org> [--concurrency <n>] [--no-merge-queue] [--mq-track=all|author|assigned] [--no-presets] [--skip-agents] [--depth <n>] [--filter <spec>] [--restart-clone] [--dry-run]",
 		Run:         c.initRepo,
 	}
 
+	repoCmd.Subcommands["reinit"] = &Command{
+		Name:        "reinit",
+		Description: "Repair a repository in place: clone remote, agent definitions, tmux session, supervisor and merge queue",
+		Usage:       "multiclaude repo reinit [name] [--url <github-url>]",
+		Run:         c.withOptionalRepo(c.reinitRepo),
+	}
+
 	repoCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List tracked repositories",
@@ -422,46 +776,285 @@ func (c *CLI) registerCommands() {
 	repoCmd.Subcommands["history"] = &Command{
 		Name:        "history",
 		Description: "Show task history for a repository",
-		Usage:       "multiclaude repo history [--repo <repo>] [-n <count>] [--status <status>] [--search <query>] [--full]",
-		Run:         c.showHistory,
+		Usage:       "multiclaude repo history",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "n", Type: FlagInt, Value: "<count>", Default: "10", Description: "Number of tasks to show"},
+			{Name: "status", Enum: []string{"merged", "open", "draft", "closed", "failed", "no-pr"}, Description: "Only show tasks with this PR status"},
+			{Name: "search", Value: "<query>", Description: "Only show tasks whose description matches"},
+			{Name: "full", Type: FlagBool, Description: "Show full task descriptions and summaries of workers' sessions"},
+		},
+		RunFlags: c.withRepoFlags(c.showHistory),
+	}
+
+	repoCmd.Subcommands["migrate-worktrees"] = &Command{
+		Name:        "migrate-worktrees",
+		Description: "Move agent worktrees into the repository's worktree directory after changing it",
+		Usage:       "multiclaude repo migrate-worktrees",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "dry-run", Type: FlagBool, Description: "Show what would move without moving anything"},
+		},
+		RunFlags: c.withRepoFlags(c.migrateWorktrees),
 	}
 
 	c.rootCmd.Subcommands["repo"] = repoCmd
 
+	// Project commands (groups of repositories under a project supervisor)
+	projectCmd := &Command{
+		Name:        "project",
+		Description: "Manage projects spanning multiple repositories",
+		Subcommands: make(map[string]*Command),
+	}
+
+	projectCmd.Subcommands["create"] = &Command{
+		Name:        "create",
+		Description: "Create a project and start its project supervisor",
+		Usage:       "multiclaude project create <name> --repos <repo1,repo2,...>",
+		Run:         c.createProject,
+	}
+
+	projectCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List projects",
+		Usage:       "multiclaude project list",
+		Run:         c.listProjects,
+	}
+
+	projectCmd.Subcommands["status"] = &Command{
+		Name:        "status",
+		Description: "Show status of a project's repositories",
+		Usage:       "multiclaude project status <name>",
+		Run:         c.projectStatus,
+	}
+
+	projectCmd.Subcommands["rm"] = &Command{
+		Name:        "rm",
+		Description: "Remove a project (member repositories are kept)",
+		Usage:       "multiclaude project rm <name>",
+		Run:         c.removeProject,
+	}
+
+	c.rootCmd.Subcommands["project"] = projectCmd
+
+	// State snapshot commands
+	stateCmd := &Command{
+		Name:        "state",
+		Description: "Inspect and roll back state snapshots",
+		Subcommands: make(map[string]*Command),
+	}
+
+	stateCmd.Subcommands["snapshots"] = &Command{
+		Name:        "snapshots",
+		Description: "List state snapshots",
+		Usage:       "multiclaude state snapshots",
+		Run:         c.listSnapshots,
+	}
+
+	stateCmd.Subcommands["diff"] = &Command{
+		Name:        "diff",
+		Description: "Compare two snapshots, or a snapshot with the current state",
+		Usage:       "multiclaude state diff [<from>] [<to>]",
+		Run:         c.diffSnapshots,
+	}
+
+	stateCmd.Subcommands["rollback"] = &Command{
+		Name:        "rollback",
+		Description: "Restore state from a snapshot",
+		Usage:       "multiclaude state rollback --to <snapshot-id|timestamp>",
+		Run:         c.rollbackState,
+	}
+
+	c.rootCmd.Subcommands["state"] = stateCmd
+
 	// Backward compatibility aliases for root-level repo commands
 	c.rootCmd.Subcommands["init"] = repoCmd.Subcommands["init"]
 	c.rootCmd.Subcommands["list"] = repoCmd.Subcommands["list"]
 	c.rootCmd.Subcommands["history"] = repoCmd.Subcommands["history"]
 
+	c.rootCmd.Subcommands["digest"] = &Command{
+		Name:        "digest",
+		Description: "Summarize what every agent did (commits, PRs, messages, failures)",
+		Usage:       "multiclaude digest [--repo <repo>] [--since <duration>] [--post-to workspace|slack]",
+		Run:         c.withRepo(c.showDigest),
+	}
+
+	c.rootCmd.Subcommands["ask"] = &Command{
+		Name:        "ask",
+		Description: "Ask another agent a question and wait for the answer",
+		Usage:       "multiclaude ask <agent> <question> [--timeout <duration>] [--no-wait] [--agent <name>] | multiclaude ask --ticket <id>",
+		Run:         c.withOptionalAgent(c.ask),
+	}
+
+	c.rootCmd.Subcommands["answer"] = &Command{
+		Name:        "answer",
+		Description: "Answer a question asked with 'multiclaude ask'",
+		Usage:       "multiclaude answer <ticket> <answer> [--agent <name>]",
+		Run:         c.answer,
+	}
+
+	// Task queue commands
+	taskCmd := &Command{
+		Name:        "task",
+		Description: "Queue tasks that start workers once the tasks they depend on are done",
+		Subcommands: make(map[string]*Command),
+	}
+
+	taskCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Queue a task; it starts when every task it comes after is merged or completed",
+		Usage:       "multiclaude task add <task description>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "after", Value: "<task-id,...>", Description: "Tasks that must be merged or completed first"},
+		},
+		RunFlags: c.withRepoFlags(c.addTask),
+	}
+
+	taskCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List queued, running and finished tasks",
+		Usage:       "multiclaude task list",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+		},
+		RunFlags: c.withRepoFlags(c.listTasks),
+	}
+
+	taskCmd.Subcommands["graph"] = &Command{
+		Name:        "graph",
+		Description: "Show tasks and their dependencies as a graph",
+		Usage:       "multiclaude task graph",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "dot", Type: FlagBool, Description: "Print Graphviz DOT instead of a tree"},
+		},
+		RunFlags: c.withRepoFlags(c.taskGraph),
+	}
+
+	taskCmd.Subcommands["cancel"] = &Command{
+		Name:        "cancel",
+		Description: "Cancel a task that hasn't started",
+		Usage:       "multiclaude task cancel <task-id>",
+		Run:         c.cancelTask,
+	}
+
+	c.rootCmd.Subcommands["task"] = taskCmd
+
+	c.rootCmd.Subcommands["train"] = &Command{
+		Name:        "train",
+		Description: "Show the merge train: open PRs simulated on top of each other before merging",
+		Usage:       "multiclaude train",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+		},
+		RunFlags: c.withRepoFlags(c.showTrain),
+	}
+
 	// Worker commands
 	workerCmd := &Command{
 		Name:        "worker",
 		Description: "Manage worker agents",
-		Usage:       "multiclaude worker [<task>] [--repo <repo>] [--branch <branch>] [--push-to <branch>]",
+		Usage:       "multiclaude worker [<task>] [--repo <repo>] [--base <branch>] [--branch <branch>] [--push-to <branch>] (no arguments in a terminal starts a wizard)",
 		Subcommands: make(map[string]*Command),
 	}
 
-	workerCmd.Run = c.createWorker // Default action for 'worker' command (same as 'worker create')
+	workerCmd.Run = c.withOptionalRepo(c.createWorker) // Default action for 'worker' command (same as 'worker create')
 
 	workerCmd.Subcommands["create"] = &Command{
 		Name:        "create",
 		Description: "Create a new worker agent",
-		Usage:       "multiclaude worker create <task> [--repo <repo>] [--branch <branch>] [--push-to <branch>]",
-		Run:         c.createWorker,
+		Usage:       "multiclaude worker create <task> [--repo <repo>] [--base <branch>] [--branch <branch>] [--push-to <branch>] [--capability <capability>|--definition <name>] [--criteria <text>]... [--criteria-file <file>] [--criteria-issue <number>] [--new]",
+		Run:         c.withOptionalRepo(c.createWorker),
 	}
 
 	workerCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List active workers",
-		Usage:       "multiclaude worker list [--repo <repo>]",
-		Run:         c.listWorkers,
+		Usage:       "multiclaude worker list [--repo <repo> | --all] [--wide]",
+		Run:         c.withOptionalRepo(c.listWorkers),
 	}
 
 	workerCmd.Subcommands["rm"] = &Command{
 		Name:        "rm",
-		Description: "Remove a worker",
-		Usage:       "multiclaude worker rm <worker-name>",
-		Run:         c.removeWorker,
+		Description: "Remove a worker, keeping it in the trash for a week",
+		Usage:       "multiclaude worker rm <worker-name> [--purge] | multiclaude worker rm --all [--ready-for-cleanup] [--filter <key>=<value>]... [--dry-run] [--yes]",
+		Run:         c.withRepo(c.removeWorker),
+	}
+
+	workerCmd.Subcommands["nudge"] = &Command{
+		Name:        "nudge",
+		Description: "Ask the workers that match for a progress update",
+		Usage:       "multiclaude worker nudge [<worker-name>...] [--all] [--idle] [--filter <key>=<value>]... [--dry-run] [--yes]",
+		Flags:       bulkFlags,
+		Run:         c.withRepo(func(ctx CommandContext, args []string) error { return c.bulkWorkers(ctx, "nudge", args) }),
+	}
+
+	workerCmd.Subcommands["complete"] = &Command{
+		Name:        "complete",
+		Description: "Mark the workers that match as completed, e.g. those whose PR merged",
+		Usage:       "multiclaude worker complete [<worker-name>...] [--all] [--filter <key>=<value>]... [--dry-run] [--yes]",
+		Flags:       bulkFlags,
+		Run:         c.withRepo(func(ctx CommandContext, args []string) error { return c.bulkWorkers(ctx, "complete", args) }),
+	}
+
+	workerCmd.Subcommands["undelete"] = &Command{
+		Name:        "undelete",
+		Description: "Restore a removed worker from the trash",
+		Usage:       "multiclaude worker undelete [<worker-name>] [--repo <repo>]",
+		Run:         c.withRepo(c.undeleteWorker),
+	}
+
+	workerCmd.Subcommands["retry"] = &Command{
+		Name:        "retry",
+		Description: "Retry a task from the history with a new worker",
+		Usage:       "multiclaude worker retry <history-id|worker-name> [--repo <repo>] [--name <name>]",
+		Run:         c.withRepo(c.retryWorker),
+	}
+
+	workerCmd.Subcommands["refresh"] = &Command{
+		Name:        "refresh",
+		Description: "Set how the daemon keeps a worker's branch up to date, overriding the repository's config",
+		Usage:       "multiclaude worker refresh <worker-name>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "strategy", Enum: []string{"rebase", "merge", "fetch", "off"}, Description: "Rebase onto, merge in, only report, or ignore the default branch"},
+			{Name: "only-clean", Type: FlagBool, Description: "Skip the refresh while the worktree has uncommitted changes"},
+			{Name: "pause-active", Type: FlagBool, Description: "Skip the refresh while the worker is producing output or editing files"},
+			{Name: "reset", Type: FlagBool, Description: "Drop the override and use the repository's config"},
+		},
+		RunFlags: c.withRepoFlags(c.setWorkerRefresh),
+	}
+
+	workerCmd.Subcommands["ready"] = &Command{
+		Name:        "ready",
+		Description: "Take a worker's draft PR out of draft and hand it to the merge queue",
+		Usage:       "multiclaude worker ready <worker-name>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+		},
+		RunFlags: c.withRepoFlags(c.workerReady),
+	}
+
+	workerCmd.Subcommands["check"] = &Command{
+		Name:        "check",
+		Description: "Check a worker's branch against the repository's push checks before pushing",
+		Usage:       "multiclaude worker check <worker-name>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+		},
+		RunFlags: c.withRepoFlags(c.workerCheck),
+	}
+
+	workerCmd.Subcommands["describe"] = &Command{
+		Name:        "describe",
+		Description: "Write a worker's PR description from its task, criteria, commits and a summary of its diff",
+		Usage:       "multiclaude worker describe <worker-name>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "dry-run", Type: FlagBool, Description: "Print the description without setting it on the PR"},
+		},
+		RunFlags: c.withRepoFlags(c.describeWorkerPR),
 	}
 
 	c.rootCmd.Subcommands["worker"] = workerCmd
@@ -469,6 +1062,32 @@ func (c *CLI) registerCommands() {
 	// 'work' is an alias for 'worker' (backward compatibility)
 	c.rootCmd.Subcommands["work"] = workerCmd
 
+	c.rootCmd.Subcommands["solo"] = &Command{
+		Name:        "solo",
+		Description: "Start a standalone agent for a one-off task in the current directory",
+		Usage:       "multiclaude solo \"<task>\"",
+		Flags: []Flag{
+			{Name: "name", Description: "Agent name (default: generated)"},
+			{Name: "worktree", Type: FlagBool, Description: "Work in a new worktree on branch solo/<name> instead of the current directory"},
+		},
+		RunFlags: c.soloAgent,
+	}
+
+	c.rootCmd.Subcommands["adopt"] = &Command{
+		Name:        "adopt",
+		Description: "Register a Claude session already running in tmux as an agent",
+		Usage:       "multiclaude adopt",
+		Flags: []Flag{
+			{Name: "session", Required: true, Description: "tmux session the window is in"},
+			{Name: "window", Required: true, Description: "tmux window Claude is running in"},
+			{Name: "repo", Description: "Repository to register the agent with"},
+			{Name: "as", Value: "<type>", Default: "worker", Enum: adoptableTypes, Description: "Agent type"},
+			{Name: "name", Description: "Agent name (default: the window name)"},
+			{Name: "task", Value: "\"<task>\"", Description: "Task the agent is working on"},
+		},
+		RunFlags: c.withRepoFlags(c.adoptAgent),
+	}
+
 	// Workspace commands
 	workspaceCmd := &Command{
 		Name:        "workspace",
@@ -477,34 +1096,34 @@ func (c *CLI) registerCommands() {
 		Subcommands: make(map[string]*Command),
 	}
 
-	workspaceCmd.Run = c.workspaceDefault // Default action: list or connect
+	workspaceCmd.Run = c.withRepo(c.workspaceDefault) // Default action: list or connect
 
 	workspaceCmd.Subcommands["add"] = &Command{
 		Name:        "add",
 		Description: "Add a new workspace",
 		Usage:       "multiclaude workspace add <name> [--branch <branch>]",
-		Run:         c.addWorkspace,
+		Run:         c.withRepo(c.addWorkspace),
 	}
 
 	workspaceCmd.Subcommands["rm"] = &Command{
 		Name:        "rm",
 		Description: "Remove a workspace",
 		Usage:       "multiclaude workspace rm <name>",
-		Run:         c.removeWorkspace,
+		Run:         c.withRepo(c.removeWorkspace),
 	}
 
 	workspaceCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List workspaces",
 		Usage:       "multiclaude workspace list",
-		Run:         c.listWorkspaces,
+		Run:         c.withRepo(c.listWorkspaces),
 	}
 
 	workspaceCmd.Subcommands["connect"] = &Command{
 		Name:        "connect",
-		Description: "Connect to a workspace",
-		Usage:       "multiclaude workspace connect <name>",
-		Run:         c.connectWorkspace,
+		Description: "Connect to a workspace, or open its worktree in an editor",
+		Usage:       "multiclaude workspace connect <name> [--editor vscode|jetbrains] [--remote <ssh-host>]",
+		Run:         c.withRepo(c.connectWorkspace),
 	}
 
 	c.rootCmd.Subcommands["workspace"] = workspaceCmd
@@ -521,50 +1140,81 @@ func (c *CLI) registerCommands() {
 	agentCmd.Subcommands["send-message"] = &Command{
 		Name:        "send-message",
 		Description: "Send a message to another agent (alias for 'message send')",
-		Usage:       "multiclaude agent send-message <recipient> <message>",
-		Run:         c.sendMessage,
+		Usage:       "multiclaude agent send-message [--idempotency-key=<key>] [--agent <name>] <recipient> <message>",
+		Run:         c.withAgent(c.sendMessage),
 	}
 
 	agentCmd.Subcommands["list-messages"] = &Command{
 		Name:        "list-messages",
 		Description: "List pending messages (alias for 'message list')",
-		Usage:       "multiclaude agent list-messages",
-		Run:         c.listMessages,
+		Usage:       "multiclaude agent list-messages [--repo <repo>] [--agent <name>]",
+		Run:         c.withAgent(c.listMessages),
 	}
 
 	agentCmd.Subcommands["read-message"] = &Command{
 		Name:        "read-message",
 		Description: "Read a specific message (alias for 'message read')",
-		Usage:       "multiclaude agent read-message <message-id>",
-		Run:         c.readMessage,
+		Usage:       "multiclaude agent read-message <message-id> [--agent <name>]",
+		Run:         c.withAgent(c.readMessage),
 	}
 
 	agentCmd.Subcommands["ack-message"] = &Command{
 		Name:        "ack-message",
 		Description: "Acknowledge a message (alias for 'message ack')",
-		Usage:       "multiclaude agent ack-message <message-id>",
-		Run:         c.ackMessage,
+		Usage:       "multiclaude agent ack-message <message-id> [--agent <name>]",
+		Run:         c.withAgent(c.ackMessage),
 	}
 
 	agentCmd.Subcommands["complete"] = &Command{
 		Name:        "complete",
 		Description: "Signal worker completion",
-		Usage:       "multiclaude agent complete [--summary <text>] [--failure <reason>]",
-		Run:         c.completeWorker,
+		Usage:       "multiclaude agent complete [--summary <text>] [--failure <reason>] [--criterion <n>=met|unmet[:note]]... [--agent <name>]",
+		Run:         c.withAgent(c.completeWorker),
+	}
+
+	agentCmd.Subcommands["_simulate"] = &Command{
+		Name:        "_simulate",
+		Description: "Internal: play an agent in its window (used when MULTICLAUDE_TEST_MODE=sim)",
+		Run:         c.withAgent(c.simulateAgent),
 	}
 
 	agentCmd.Subcommands["restart"] = &Command{
 		Name:        "restart",
 		Description: "Restart a crashed or exited agent",
 		Usage:       "multiclaude agent restart <name> [--repo <repo>] [--force]",
-		Run:         c.restartAgentCmd,
+		Run:         c.withRepo(c.restartAgentCmd),
+	}
+
+	agentCmd.Subcommands["interrupt"] = &Command{
+		Name:        "interrupt",
+		Description: "Stop an agent mid-turn, as pressing Escape in its window would",
+		Usage:       "multiclaude agent interrupt <name>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "key", Default: "escape", Enum: []string{"escape", "ctrl-c"}, Description: "Key sent to the agent's window"},
+			{Name: "signal", Type: FlagBool, Description: "Also send SIGINT to the agent's Claude process"},
+			{Name: "message", Value: "<text>", Description: "Message to send the agent once it has stopped, e.g. what to do instead"},
+		},
+		RunFlags: c.withRepoFlags(c.interruptAgent),
 	}
 
 	agentCmd.Subcommands["attach"] = &Command{
 		Name:        "attach",
 		Description: "Attach to an agent's tmux window",
 		Usage:       "multiclaude agent attach <agent-name> [--read-only]",
-		Run:         c.attachAgent,
+		Run:         c.withRepo(c.attachAgent),
+	}
+
+	agentCmd.Subcommands["export"] = &Command{
+		Name:        "export",
+		Description: "Export an agent's conversation, messages and changes for review",
+		Usage:       "multiclaude agent export <name>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "format", Default: string(export.FormatMarkdown), Enum: export.Formats, Description: "Output format"},
+			{Name: "output", Value: "<file>", Description: "Write to a file instead of stdout"},
+		},
+		RunFlags: c.withRepoFlags(c.exportAgent),
 	}
 
 	c.rootCmd.Subcommands["agent"] = agentCmd
@@ -580,29 +1230,39 @@ func (c *CLI) registerCommands() {
 	messageCmd.Subcommands["send"] = &Command{
 		Name:        "send",
 		Description: "Send a message to another agent",
-		Usage:       "multiclaude message send <recipient> <message>",
-		Run:         c.sendMessage,
+		Usage:       "multiclaude message send [--idempotency-key=<key>] [--agent <name>] <recipient> <message> | <recipient> --template <name> [--var <name>=<value>...] [note]",
+		Run:         c.withAgent(c.sendMessage),
+	}
+
+	messageCmd.Subcommands["templates"] = &Command{
+		Name:        "templates",
+		Description: "List the repository's message templates, or show one",
+		Usage:       "multiclaude message templates [<name>] [--repo <repo>]",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository whose templates to list"},
+		},
+		RunFlags: c.withRepoFlags(c.listMessageTemplates),
 	}
 
 	messageCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List pending messages",
-		Usage:       "multiclaude message list",
-		Run:         c.listMessages,
+		Usage:       "multiclaude message list [--repo <repo>] [--agent <name>]",
+		Run:         c.withAgent(c.listMessages),
 	}
 
 	messageCmd.Subcommands["read"] = &Command{
 		Name:        "read",
 		Description: "Read a specific message",
-		Usage:       "multiclaude message read <message-id>",
-		Run:         c.readMessage,
+		Usage:       "multiclaude message read <message-id> [--agent <name>]",
+		Run:         c.withAgent(c.readMessage),
 	}
 
 	messageCmd.Subcommands["ack"] = &Command{
 		Name:        "ack",
 		Description: "Acknowledge a message",
-		Usage:       "multiclaude message ack <message-id>",
-		Run:         c.ackMessage,
+		Usage:       "multiclaude message ack <message-id> [--agent <name>]",
+		Run:         c.withAgent(c.ackMessage),
 	}
 
 	c.rootCmd.Subcommands["message"] = messageCmd
@@ -610,18 +1270,32 @@ func (c *CLI) registerCommands() {
 	// 'attach' is an alias for 'agent attach' (backward compatibility)
 	c.rootCmd.Subcommands["attach"] = agentCmd.Subcommands["attach"]
 
+	c.rootCmd.Subcommands["whoami"] = &Command{
+		Name:        "whoami",
+		Description: "Print the calling agent's name, type, repo, branch, task, session, pending messages and teammates as JSON",
+		Usage:       "multiclaude whoami [--agent <name>] [--repo <repo>]",
+		Run:         c.withAgent(c.whoami),
+	}
+
+	c.rootCmd.Subcommands["sync"] = &Command{
+		Name:        "sync",
+		Description: "Bring the calling worker's branch up to date with the default branch now, as the repo's refresh config says",
+		Usage:       "multiclaude sync [--strategy rebase|merge|fetch] [--json] [--agent <name>] [--repo <repo>]",
+		Run:         c.withAgent(c.syncWorktree),
+	}
+
 	// Maintenance commands
 	c.rootCmd.Subcommands["cleanup"] = &Command{
 		Name:        "cleanup",
 		Description: "Clean up orphaned resources",
-		Usage:       "multiclaude cleanup [--dry-run] [--verbose] [--merged]",
+		Usage:       "multiclaude cleanup [--dry-run] [--verbose] [--merged] [--outputs [--older-than <duration>]]",
 		Run:         c.cleanup,
 	}
 
 	c.rootCmd.Subcommands["repair"] = &Command{
 		Name:        "repair",
-		Description: "Repair state after crash",
-		Usage:       "multiclaude repair [--verbose]",
+		Description: "Reconcile state with tmux, worktrees and messages after a crash",
+		Usage:       "multiclaude repair [--auto] [--dry-run] [--verbose]",
 		Run:         c.repair,
 	}
 
@@ -629,8 +1303,8 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["claude"] = &Command{
 		Name:        "claude",
 		Description: "Restart Claude in current agent context",
-		Usage:       "multiclaude claude",
-		Run:         c.restartClaude,
+		Usage:       "multiclaude claude [--agent <name>]",
+		Run:         c.withAgent(c.restartClaude),
 	}
 
 	// Debug command
@@ -642,13 +1316,30 @@ func (c *CLI) registerCommands() {
 	}
 
 	// Review command
-	c.rootCmd.Subcommands["review"] = &Command{
+	reviewCmd := &Command{
 		Name:        "review",
-		Description: "Spawn a review agent for a PR",
-		Usage:       "multiclaude review <pr-url>",
+		Description: "Review a PR: hand it to a running review agent, or spawn one",
+		Usage:       "multiclaude review <pr-url> [--new] [--repo <repo>]",
 		Run:         c.reviewPR,
+		Subcommands: make(map[string]*Command),
+	}
+
+	reviewCmd.Subcommands["done"] = &Command{
+		Name:        "done",
+		Description: "Take a finished review off the review queue (run by review agents)",
+		Usage:       "multiclaude review done <pr-number>",
+		Run:         c.withRepo(c.reviewDone),
 	}
 
+	reviewCmd.Subcommands["queue"] = &Command{
+		Name:        "queue",
+		Description: "Show the queued reviews and each reviewer's queue depth",
+		Usage:       "multiclaude review queue [--repo <repo>]",
+		Run:         c.withRepo(c.reviewQueue),
+	}
+
+	c.rootCmd.Subcommands["review"] = reviewCmd
+
 	// Logs commands
 	logsCmd := &Command{
 		Name:        "logs",
@@ -682,83 +1373,331 @@ func (c *CLI) registerCommands() {
 
 	c.rootCmd.Subcommands["logs"] = logsCmd
 
+	c.rootCmd.Subcommands["watch"] = &Command{
+		Name:        "watch",
+		Description: "Follow agent lifecycle changes, message deliveries and task completions live",
+		Usage:       "multiclaude watch",
+		Flags: []Flag{
+			{Name: "repo", Description: "Only show events for this repository"},
+			{Name: "stream", Type: FlagBool, Description: "Print one line per event instead of a live view"},
+			{Name: "json", Type: FlagBool, Description: "Print one JSON object per event (implies --stream)"},
+			{Name: "count", Type: FlagInt, Value: "<n>", Description: "Exit after n events"},
+		},
+		RunFlags: c.watch,
+	}
+
 	// Config command
 	c.rootCmd.Subcommands["config"] = &Command{
 		Name:        "config",
 		Description: "View or modify repository configuration",
-		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned] [--ps-enabled=true|false] [--ps-track=all|author|assigned]",
+		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned] [--mq-test=<command>|off] [--ps-enabled=true|false] [--ps-track=all|author|assigned] [--default-branch=<branch>] [--branch-template=<template>|default] [--notify-enabled=true|false] [--notify-to=<addr,...>] [--notify-method=sendmail|smtp] [--notify-smtp=host:port] [--notify-smtp-user=<user>] [--notify-from=<addr>] [--notify-digest=<minutes>] [--notify-human=tmux,desktop,webhook|off] [--notify-webhook=<url>|off] [--federation=off|git|<dir>] [--federation-branch=<branch>] [--federation-peer=<id>] [--zombie=on|off] [--zombie-stall=<minutes>] [--zombie-stalled=<action>] [--zombie-looping=<action>] [--zombie-prompt=<action>] [--worktree-lfs=true|false] [--worktree-submodules=true|false] [--worktree-mirror=true|false] [--worktree-dir=<dir>|default] [--refresh=rebase|merge|fetch|off] [--refresh-only-clean=true|false] [--refresh-pause-active=true|false] [--roster=prompt|file|off] [--draft-prs=true|false] [--pr-descriptions=true|false] [--pr-template=<path>|default] [--push-rebased=true|false] [--push-signed=true|false] [--push-message=<regex>|off] [--review-dispatch=round-robin|least-loaded] [--max-workers=<n>|off] [--claude-config-dir=<dir>|default] [--comment-commands=<login,...>|off] [--pre-spawn=<command>|off] [--post-spawn=<command>|off] [--prompt-budget=<tokens>] [--prompt-budget-base=<tokens>] [--prompt-budget-docs=<tokens>] [--prompt-budget-commands=<tokens>] [--prompt-budget-custom=<tokens>] [--work-hours=HH:MM-HH:MM|off] [--work-days=<days>|all] [--timezone=<zone>|local]",
 		Run:         c.configRepo,
+		Subcommands: make(map[string]*Command),
 	}
 
-	// Bug report command
-	c.rootCmd.Subcommands["bug"] = &Command{
-		Name:        "bug",
-		Description: "Generate a diagnostic bug report",
-		Usage:       "multiclaude bug [--output <file>] [--verbose] [description]",
-		Run:         c.bugReport,
+	c.rootCmd.Subcommands["config"].Subcommands["validate"] = &Command{
+		Name:        "validate",
+		Description: "Check .multiclaude/config.yaml against the config schema",
+		Usage:       "multiclaude config validate [path]",
+		Run:         c.validateRepoConfig,
 	}
 
-	// Version command
-	c.rootCmd.Subcommands["version"] = &Command{
-		Name:        "version",
-		Description: "Show version information",
-		Usage:       "multiclaude version [--json]",
-		Run:         c.versionCommand,
+	c.rootCmd.Subcommands["config"].Subcommands["schema"] = &Command{
+		Name:        "schema",
+		Description: "Print the JSON Schema for .multiclaude/config.yaml",
+		Usage:       "multiclaude config schema",
+		Run:         c.printRepoConfigSchema,
 	}
 
-	// Agents command - for managing agent definitions
-	agentsCmd := &Command{
-		Name:        "agents",
-		Description: "Manage agent definitions",
+	// Work hours commands
+	hoursCmd := &Command{
+		Name:        "hours",
+		Description: "Show a repository's work hours, or start or stop its agents' work now",
+		Usage:       "multiclaude hours [--repo <repo>]",
+		Run:         c.withRepo(c.hoursStatus),
 		Subcommands: make(map[string]*Command),
 	}
 
-	agentsCmd.Subcommands["list"] = &Command{
-		Name:        "list",
-		Description: "List available agent definitions for a repository",
-		Usage:       "multiclaude agents list [--repo <repo>]",
-		Run:         c.listAgentDefinitions,
+	hoursCmd.Subcommands["on"] = &Command{
+		Name:        "on",
+		Description: "Work now, outside the work hours, until they start anyway or for a while",
+		Usage:       "multiclaude hours on [--repo <repo>] [--for <duration>]",
+		Run:         c.hoursOverride(workhours.On),
 	}
 
-	agentsCmd.Subcommands["spawn"] = &Command{
-		Name:        "spawn",
-		Description: "Spawn an agent from a prompt file",
-		Usage:       "multiclaude agents spawn --name <name> --class <class> --prompt-file <file> [--repo <repo>] [--task <task>]",
-		Run:         c.spawnAgentFromFile,
+	hoursCmd.Subcommands["off"] = &Command{
+		Name:        "off",
+		Description: "Stop work now, inside the work hours, until they end anyway or for a while",
+		Usage:       "multiclaude hours off [--repo <repo>] [--for <duration>]",
+		Run:         c.hoursOverride(workhours.Off),
 	}
 
-	agentsCmd.Subcommands["reset"] = &Command{
-		Name:        "reset",
-		Description: "Reset agent definitions to defaults (re-copy from templates)",
-		Usage:       "multiclaude agents reset [--repo <repo>]",
-		Run:         c.resetAgentDefinitions,
+	hoursCmd.Subcommands["auto"] = &Command{
+		Name:        "auto",
+		Description: "Clear an override and follow the work hours again",
+		Usage:       "multiclaude hours auto [--repo <repo>]",
+		Run:         c.hoursOverride("auto"),
 	}
 
-	c.rootCmd.Subcommands["agents"] = agentsCmd
-}
-
-// Daemon command implementations
+	c.rootCmd.Subcommands["hours"] = hoursCmd
 
-func (c *CLI) startDaemon(args []string) error {
-	return daemon.RunDetached()
-}
+	// Message routing rules commands
+	routingCmd := &Command{
+		Name:        "routing",
+		Description: "List a repository's message routing rules",
+		Usage:       "multiclaude routing [--repo <repo>]",
+		Run:         c.withRepo(c.listRoutingRules),
+		Subcommands: make(map[string]*Command),
+	}
 
-func (c *CLI) runDaemon(args []string) error {
-	return daemon.Run()
-}
+	routingCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Add a rule that copies or prioritizes the messages it matches",
+		Usage:       "multiclaude routing add [--repo <repo>] [--contains <text>] [--from <agent|type>] [--to <agent>] [--cc <agent,...>] [--priority high|normal|low]",
+		Run:         c.withRepo(c.addRoutingRule),
+	}
 
-func (c *CLI) stopDaemon(args []string) error {
-	_, err := c.sendDaemonRequest("stop", nil)
-	if err != nil {
-		return err
+	routingCmd.Subcommands["remove"] = &Command{
+		Name:        "remove",
+		Description: "Remove a routing rule by its number, or all of them",
+		Usage:       "multiclaude routing remove <number>|all [--repo <repo>]",
+		Run:         c.withRepo(c.removeRoutingRule),
 	}
 
-	fmt.Println("Daemon stopped successfully")
-	return nil
-}
+	c.rootCmd.Subcommands["routing"] = routingCmd
 
-func (c *CLI) daemonStatus(args []string) error {
-	// Check PID file first
+	// Agent memory commands
+	memoryCmd := &Command{
+		Name:        "memory",
+		Description: "Show an agent's persistent notes, which survive restarts",
+		Usage:       "multiclaude memory [--agent <name>] [--repo <repo>]",
+		Run:         c.withAgent(c.memoryShow),
+		Subcommands: make(map[string]*Command),
+	}
+
+	memoryCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Save a note to an agent's memory; its prompt carries the newest notes",
+		Usage:       "multiclaude memory add <note> [--agent <name>] [--repo <repo>]",
+		Run:         c.withAgent(c.memoryAdd),
+	}
+
+	memoryCmd.Subcommands["clear"] = &Command{
+		Name:        "clear",
+		Description: "Forget everything in an agent's memory",
+		Usage:       "multiclaude memory clear [--agent <name>] [--repo <repo>]",
+		Run:         c.withAgent(c.memoryClear),
+	}
+
+	c.rootCmd.Subcommands["memory"] = memoryCmd
+
+	c.rootCmd.Subcommands["scaffold"] = &Command{
+		Name:        "scaffold",
+		Description: "Generate a starter .multiclaude directory in a repository",
+		Usage:       "multiclaude scaffold [path]",
+		Flags: []Flag{
+			{Name: "minimal", Type: FlagBool, Description: "Only a short config.yaml and the worker definition"},
+			{Name: "full", Type: FlagBool, Description: "Every config setting, commented, and example hooks"},
+			{Name: "force", Type: FlagBool, Description: "Overwrite files that already exist"},
+		},
+		RunFlags: c.scaffoldRepo,
+	}
+
+	// Federation commands
+	federationCmd := &Command{
+		Name:        "federation",
+		Description: "Share repo status and messages with teammates' daemons",
+		Subcommands: make(map[string]*Command),
+	}
+
+	federationCmd.Subcommands["status"] = &Command{
+		Name:        "status",
+		Description: "Show teammates' workers on a federated repository",
+		Usage:       "multiclaude federation status [--repo <repo>]",
+		Run:         c.withRepo(c.federationStatus),
+	}
+
+	c.rootCmd.Subcommands["federation"] = federationCmd
+
+	// Bug report command
+	c.rootCmd.Subcommands["bug"] = &Command{
+		Name:        "bug",
+		Description: "Generate a diagnostic bug report",
+		Usage:       "multiclaude bug [--output <file>] [--verbose] [--bundle] [description]",
+		Run:         c.bugReport,
+	}
+
+	// WSL command
+	wslCmd := &Command{
+		Name:        "wsl",
+		Description: "Show how multiclaude runs under WSL and translate paths for Windows tools",
+		Usage:       "multiclaude wsl",
+		Run:         c.wslStatus,
+		Subcommands: make(map[string]*Command),
+	}
+
+	wslCmd.Subcommands["path"] = &Command{
+		Name:        "path",
+		Description: "Translate a path between WSL and Windows forms",
+		Usage:       "multiclaude wsl path <path> [--windows|--linux]",
+		Run:         c.wslPath,
+	}
+
+	c.rootCmd.Subcommands["wsl"] = wslCmd
+
+	// Version command
+	c.rootCmd.Subcommands["selftest"] = &Command{
+		Name:        "selftest",
+		Description: "Check the installation end to end in a disposable sandbox (init, work, message, complete, cleanup)",
+		Usage:       "multiclaude selftest [--keep]",
+		Flags: []Flag{
+			{Name: "keep", Type: FlagBool, Description: "Keep the sandbox directory even when the test passes"},
+		},
+		RunFlags: c.selftest,
+	}
+
+	statsCmd := &Command{
+		Name:        "stats",
+		Description: "Show how often each command runs, how long it takes and how often it fails (opt-in, local only)",
+		Usage:       "multiclaude stats [--since <duration>] [--json]",
+		Run:         c.showStats,
+		Subcommands: make(map[string]*Command),
+	}
+
+	statsCmd.Subcommands["enable"] = &Command{
+		Name:        "enable",
+		Description: "Start recording command usage locally",
+		Usage:       "multiclaude stats enable",
+		Run:         c.enableStats,
+	}
+
+	statsCmd.Subcommands["disable"] = &Command{
+		Name:        "disable",
+		Description: "Stop recording command usage and delete what was recorded",
+		Usage:       "multiclaude stats disable",
+		Run:         c.disableStats,
+	}
+
+	c.rootCmd.Subcommands["stats"] = statsCmd
+
+	showCmd := &Command{
+		Name:        "show",
+		Description: "Show how things relate: a PR's worker, task, messages and merge queue status",
+		Subcommands: make(map[string]*Command),
+	}
+
+	showCmd.Subcommands["pr"] = &Command{
+		Name:        "pr",
+		Description: "Show a PR's worker, task, related messages and merge queue status",
+		Usage:       "multiclaude show pr <number>",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository (default: inferred from the current directory)"},
+			{Name: "json", Type: FlagBool, Description: "Print the cross-references as JSON"},
+		},
+		RunFlags: c.withRepoFlags(c.showPR),
+	}
+
+	c.rootCmd.Subcommands["show"] = showCmd
+
+	c.rootCmd.Subcommands["version"] = &Command{
+		Name:        "version",
+		Description: "Show version information",
+		Usage:       "multiclaude version [--json]",
+		Run:         c.versionCommand,
+	}
+
+	// Agents command - for managing agent definitions
+	agentsCmd := &Command{
+		Name:        "agents",
+		Description: "Manage agent definitions",
+		Subcommands: make(map[string]*Command),
+	}
+
+	agentsCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List available agent definitions for a repository, or every running agent with --all",
+		Usage:       "multiclaude agents list [--repo <repo>] [--all]",
+		Run:         c.withOptionalRepo(c.listAgentDefinitions),
+	}
+
+	agentsCmd.Subcommands["find"] = &Command{
+		Name:        "find",
+		Description: "Find agent definitions and running agents with a capability",
+		Usage:       "multiclaude agents find --capability <capability> [--repo <repo>]",
+		Run:         c.withRepo(c.findAgentsByCapability),
+	}
+
+	agentsCmd.Subcommands["spawn"] = &Command{
+		Name:        "spawn",
+		Description: "Spawn an agent from a prompt file or agent definition",
+		Usage:       "multiclaude agents spawn --name <name> --class <persistent|ephemeral|observer> [--prompt-file <file>] [--definition <name>] [--repo <repo>] [--task <task>]",
+		Run:         c.withRepo(c.spawnAgentFromFile),
+	}
+
+	agentsCmd.Subcommands["reset"] = &Command{
+		Name:        "reset",
+		Description: "Reset agent definitions to defaults (re-copy from templates)",
+		Usage:       "multiclaude agents reset [--repo <repo>]",
+		Run:         c.withRepo(c.resetAgentDefinitions),
+	}
+
+	agentsCmd.Subcommands["test"] = &Command{
+		Name:        "test",
+		Description: "Render every agent prompt and check it still has the instructions agents need",
+		Usage:       "multiclaude agents test",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository whose agent definitions to test (default: inferred from the current directory)"},
+			{Name: "builtin", Type: FlagBool, Description: "Test the prompts multiclaude ships instead of a repository's"},
+			{Name: "golden", Value: "<dir>", Description: "Also compare each prompt with <dir>/<name>.golden.md"},
+			{Name: "update", Type: FlagBool, Description: "Rewrite the golden files instead of comparing"},
+		},
+		RunFlags: c.withOptionalRepoFlags(c.testAgentPrompts),
+	}
+
+	c.rootCmd.Subcommands["agents"] = agentsCmd
+
+	// Commands command - for inspecting slash commands
+	commandsCmd := &Command{
+		Name:        "commands",
+		Description: "Inspect the slash commands agents get",
+		Subcommands: make(map[string]*Command),
+	}
+
+	commandsCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List the effective slash commands for a repository, built-in and repo-defined",
+		Usage:       "multiclaude commands list",
+		Flags: []Flag{
+			{Name: "repo", Description: "Repository to list commands for"},
+		},
+		RunFlags: c.withRepoFlags(c.listSlashCommands),
+	}
+
+	c.rootCmd.Subcommands["commands"] = commandsCmd
+}
+
+// Daemon command implementations
+
+func (c *CLI) startDaemon(args []string) error {
+	return daemon.RunDetached()
+}
+
+func (c *CLI) runDaemon(args []string) error {
+	return daemon.Run()
+}
+
+func (c *CLI) stopDaemon(args []string) error {
+	_, err := c.sendDaemonRequest("stop", nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon stopped successfully")
+	return nil
+}
+
+func (c *CLI) daemonStatus(flags *FlagSet) error {
+	// Check PID file first
 	pidFile := daemon.NewPIDFile(c.paths.DaemonPID)
 	running, pid, err := pidFile.IsRunning()
 	if err != nil {
@@ -771,17 +1710,19 @@ func (c *CLI) daemonStatus(args []string) error {
 	}
 
 	// Try to connect to daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "status",
-	})
+	client := c.daemonClient()
+	req := socket.Request{Command: "status"}
+	if flags.Bool("verbose") {
+		req.Args = map[string]interface{}{"verbose": true}
+	}
+	resp, err := client.Send(req)
 	if err != nil {
 		fmt.Printf("Daemon PID file exists (PID: %d) but daemon is not responding\n", pid)
 		return nil
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("status check failed: %s", resp.Error)
+		return daemonError("status check failed", resp)
 	}
 
 	// Pretty print status
@@ -792,6 +1733,58 @@ func (c *CLI) daemonStatus(args []string) error {
 		fmt.Printf("  Repos: %v\n", statusMap["repos"])
 		fmt.Printf("  Agents: %v\n", statusMap["agents"])
 		fmt.Printf("  Socket: %v\n", statusMap["socket_path"])
+		if address, _ := statusMap["address"].(string); strings.HasPrefix(address, "tcp:") {
+			fmt.Printf("  Listening: %s (TCP loopback)\n", strings.TrimPrefix(address, "tcp:"))
+		}
+		if count, _ := statusMap["inconsistencies"].(float64); count > 0 {
+			fmt.Printf("  Inconsistencies: %.0f (review with: multiclaude repair --dry-run)\n", count)
+		}
+		if github, ok := statusMap["github"].(map[string]interface{}); ok {
+			if available, _ := github["available"].(bool); available {
+				fmt.Printf("  GitHub: available\n")
+			} else {
+				fmt.Printf("  GitHub: %s (%v)\n", format.Red.Sprint("unavailable"), github["problem"])
+				if disabled, ok := github["disabled"].([]interface{}); ok && len(disabled) > 0 {
+					names := make([]string, 0, len(disabled))
+					for _, name := range disabled {
+						names = append(names, fmt.Sprint(name))
+					}
+					fmt.Printf("    Off: %s\n", strings.Join(names, ", "))
+				}
+				fmt.Printf("    Fix: %v, then: multiclaude daemon reload\n", github["fix"])
+			}
+		}
+		if standby, _ := statusMap["standby"].(bool); standby {
+			fmt.Printf("  Standby: yes (%v)\n", statusMap["standby_reason"])
+		} else {
+			fmt.Printf("  Standby: no\n")
+		}
+		if conns, ok := statusMap["connections"].(map[string]interface{}); ok {
+			fmt.Printf("  Connections: %v active, %v served", conns["active"], conns["accepted"])
+			rejected, _ := conns["rejected"].(float64)
+			timedOut, _ := conns["timed_out"].(float64)
+			tooLarge, _ := conns["too_large"].(float64)
+			if rejected+timedOut+tooLarge > 0 {
+				fmt.Printf(" (%.0f rejected, %.0f timed out, %.0f too large)", rejected, timedOut, tooLarge)
+			}
+			fmt.Println()
+		}
+		if stalled, _ := statusMap["stalled"].(map[string]interface{}); len(stalled) > 0 {
+			names := make([]string, 0, len(stalled))
+			for name := range stalled {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("  Stalled: %s (%v)\n", format.Red.Sprint(name), stalled[name])
+			}
+			if !flags.Bool("verbose") {
+				format.Dimmed("  Details: multiclaude daemon status --verbose")
+			}
+		}
+		if flags.Bool("verbose") {
+			printDaemonLoops(statusMap)
+		}
 	} else {
 		// Fallback: print as JSON
 		jsonData, _ := json.MarshalIndent(resp.Data, "  ", "  ")
@@ -801,14 +1794,249 @@ func (c *CLI) daemonStatus(args []string) error {
 	return nil
 }
 
+// daemonStandby puts the daemon into standby: periodic loops slow down and
+// the merge queue, PR shepherd and custom persistent agents are paused
+func (c *CLI) daemonStandby(args []string) error {
+	if _, err := c.sendDaemonRequest("standby", map[string]interface{}{"enabled": true}); err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon is in standby")
+	format.Dimmed("Loops run less often and the merge queue, PR shepherd and custom persistent agents are paused.")
+	format.Dimmed("Resume with: multiclaude daemon resume")
+	return nil
+}
+
+// daemonResume takes the daemon out of standby
+func (c *CLI) daemonResume(args []string) error {
+	if _, err := c.sendDaemonRequest("standby", map[string]interface{}{"enabled": false}); err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon resumed; paused agents are running again")
+	return nil
+}
+
+// daemonReload asks the daemon to reread daemon.yaml, like sending it SIGHUP,
+// and lists the settings that changed
+func (c *CLI) daemonReload(args []string) error {
+	resp, err := c.sendDaemonRequest("reload_config", nil)
+	if err != nil {
+		return err
+	}
+
+	changes, _ := resp.Data.([]interface{})
+	if len(changes) == 0 {
+		fmt.Printf("Daemon settings reloaded from %s; nothing changed\n", c.paths.DaemonConfigFile())
+		return nil
+	}
+	fmt.Printf("Daemon settings reloaded from %s:\n", c.paths.DaemonConfigFile())
+	for _, raw := range changes {
+		change, _ := raw.(map[string]interface{})
+		fmt.Printf("  %v: %v -> %v\n", change["key"], change["old"], change["new"])
+	}
+	return nil
+}
+
+// daemonService returns the user service definition for manager, or for
+// this platform's service manager when it's empty
+func (c *CLI) daemonService(manager string) (*service.Service, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	if manager == "" {
+		if manager, err = service.DefaultManager(runtime.GOOS); err != nil {
+			return nil, errors.New(errors.CategoryConfig, err.Error())
+		}
+	}
+
+	// The service manager doesn't see the shell's environment, so pass on
+	// the variables that decide where the daemon keeps its files, and a PATH
+	// that finds this binary and the tools the daemon runs
+	var env []string
+	for _, key := range config.LayoutEnv {
+		if value := os.Getenv(key); value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	dirs := []string{filepath.Dir(executable)}
+	for _, tool := range []string{"tmux", "git", "gh", "claude"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+
+	svc, err := service.ForManager(manager, home, service.Options{
+		Executable: executable,
+		LogFile:    c.paths.DaemonLog,
+		PATH:       service.SearchPath(os.Getenv("PATH"), dirs...),
+		Env:        env,
+	})
+	if err != nil {
+		return nil, errors.New(errors.CategoryConfig, err.Error())
+	}
+	return svc, nil
+}
+
+// serviceManagerFlag returns the service manager chosen with --systemd or
+// --launchd, or "" for this platform's
+func serviceManagerFlag(flags map[string]string) (string, error) {
+	systemd, launchd := flags[service.Systemd] == "true", flags[service.Launchd] == "true"
+	switch {
+	case systemd && launchd:
+		return "", errors.InvalidUsage("--systemd and --launchd can't be used together")
+	case systemd:
+		return service.Systemd, nil
+	case launchd:
+		return service.Launchd, nil
+	}
+	return "", nil
+}
+
+// installDaemonService installs and starts the daemon as a user service
+func (c *CLI) installDaemonService(args []string) error {
+	flags, _ := ParseFlags(args)
+	manager, err := serviceManagerFlag(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := c.daemonService(manager)
+	if err != nil {
+		return err
+	}
+	if flags["print"] == "true" {
+		fmt.Printf("# %s\n%s", svc.Path, svc.Content)
+		return nil
+	}
+	if err := svc.Available(); err != nil {
+		return errors.New(errors.CategoryConfig, err.Error()).
+			WithSuggestion("multiclaude daemon install-service --print, to install the definition by hand")
+	}
+
+	// The service manager starts its own daemon, so stop one started by hand
+	if running, _, _ := daemon.NewPIDFile(c.paths.DaemonPID).IsRunning(); running {
+		fmt.Println("Stopping the running daemon so the service can take over...")
+		if _, err := c.sendDaemonRequest("stop", nil); err != nil {
+			return err
+		}
+		if !c.waitForDaemonExit(10 * time.Second) {
+			return errors.New(errors.CategoryRuntime, "daemon did not stop in time").
+				WithSuggestion("multiclaude daemon stop, then run install-service again")
+		}
+	}
+
+	if err := svc.Install(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to install service", err)
+	}
+	fmt.Printf("✓ Installed %s\n", svc.Path)
+
+	// A unit that installs fine can still fail to start the daemon, such as
+	// when the binary moved or the environment is missing something
+	if !c.waitForDaemonStart(15 * time.Second) {
+		return errors.New(errors.CategoryRuntime, "the service is installed but the daemon did not start").
+			WithSuggestion(fmt.Sprintf("%s, and multiclaude daemon logs", strings.Join(svc.Status, " ")))
+	}
+	fmt.Println("✓ Daemon started by the service")
+
+	fmt.Println("The daemon starts at login and restarts if it crashes; agents are restored on restart.")
+	if svc.Manager == service.Systemd {
+		format.Dimmed("To start it at boot without logging in: loginctl enable-linger $USER")
+		format.Dimmed("Environment like %s goes in: systemctl --user edit %s", notify.SMTPPasswordEnv, service.SystemdUnit)
+	}
+	format.Dimmed("'multiclaude daemon stop' stops it until the next login; remove it with: multiclaude daemon uninstall-service")
+	return nil
+}
+
+// uninstallDaemonService stops and removes the daemon's user service
+func (c *CLI) uninstallDaemonService(args []string) error {
+	flags, _ := ParseFlags(args)
+	manager, err := serviceManagerFlag(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := c.daemonService(manager)
+	if err != nil {
+		return err
+	}
+	if !svc.Installed() {
+		fmt.Printf("The daemon's %s service is not installed\n", svc.Manager)
+		return nil
+	}
+	if err := svc.Uninstall(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to uninstall service", err)
+	}
+
+	fmt.Printf("✓ Removed %s; the daemon is stopped\n", svc.Path)
+	format.Dimmed("Start it by hand with: multiclaude start")
+	return nil
+}
+
+// waitForDaemonStart waits for the daemon to answer a ping
+func (c *CLI) waitForDaemonStart(timeout time.Duration) bool {
+	client := c.daemonClient()
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(500 * time.Millisecond) {
+		if _, err := client.Send(socket.Request{Command: "ping"}); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForDaemonExit waits for the daemon's PID file to go stale
+func (c *CLI) waitForDaemonExit(timeout time.Duration) bool {
+	pidFile := daemon.NewPIDFile(c.paths.DaemonPID)
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if running, _, _ := pidFile.IsRunning(); !running {
+			return true
+		}
+	}
+	return false
+}
+
+// watchDaemon runs a watchdog in the foreground until interrupted
+func (c *CLI) watchDaemon(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	w := daemon.NewWatchdog(c.paths)
+	if s, ok := flags["interval"]; ok {
+		interval, err := parseInterval(s)
+		if err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --interval %q (e.g., 30s, 5m)", s))
+		}
+		w.Interval = interval
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Watching the daemon every %s (Ctrl-C to stop)\n", w.Interval)
+	return w.Run(ctx)
+}
+
 func (c *CLI) daemonLogs(args []string) error {
 	flags, _ := ParseFlags(args)
 
+	// --trace shows the lines of one CLI invocation, wherever they are
+	if id := flags["trace"]; id != "" {
+		return c.daemonLogsForTrace(id)
+	}
+
 	// Check if we should follow logs
 	follow := flags["follow"] == "true" || flags["f"] == "true"
 
 	if follow {
 		// Use tail -f to follow logs
+		// Following runs until interrupted, so it has no timeout
 		cmd := exec.Command("tail", "-f", c.paths.DaemonLog)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -827,6 +2055,33 @@ func (c *CLI) daemonLogs(args []string) error {
 	return cmd.Run()
 }
 
+// daemonLogsForTrace prints the daemon log lines tagged with a trace ID
+func (c *CLI) daemonLogsForTrace(id string) error {
+	f, err := os.Open(c.paths.DaemonLog)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read daemon log", err)
+	}
+	defer f.Close()
+
+	tag := logging.TraceTag(id)
+	found := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), tag) {
+			fmt.Println(scanner.Text())
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read daemon log", err)
+	}
+	if !found {
+		fmt.Printf("No log lines for trace %s\n", id)
+	}
+	return nil
+}
+
 func (c *CLI) stopAll(args []string) error {
 	flags, _ := ParseFlags(args)
 	clean := flags["clean"] == "true"
@@ -834,7 +2089,7 @@ func (c *CLI) stopAll(args []string) error {
 
 	// Get list of repos (try daemon first, then state file)
 	var repos []string
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{Command: "list_repos"})
 	if err == nil && resp.Success {
 		// Daemon is running, get repos from it
@@ -1022,16 +2277,231 @@ func (c *CLI) stopAll(args []string) error {
 	return nil
 }
 
-func (c *CLI) initRepo(args []string) error {
-	flags, posArgs := ParseFlags(args)
-
-	if len(posArgs) < 1 {
-		return errors.InvalidUsage("usage: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned]")
-	}
+// initPlan is what init will do, worked out before anything is touched so it
+// can be shown first, or instead with --dry-run
+type initPlan struct {
+	Repo        string
+	URL         string
+	RepoPath    string
+	AgentsDir   string
+	TmuxSession string
+	MergeQueue  state.MergeQueueConfig
+	SkipAgents  bool
+	Agents      []initPlanAgent
+	Clone       clone.Options
+	// ResumeClone is an interrupted clone at RepoPath that init continues
+	ResumeClone *clone.Pending
+	// Presets are the settings the repository's topics and labels picked,
+	// and PresetMatches the presets they came from
+	Presets       presets.Settings
+	PresetMatches []presets.Match
+}
 
-	githubURL := strings.TrimRight(posArgs[0], "/")
+// initPlanAgent is an agent init will start
+type initPlanAgent struct {
+	Name   string
+	Type   state.AgentType
+	Dir    string
+	Branch string // Only for agents in their own worktree
+}
 
-	// Parse repository name from URL if not provided
+// planInit works out the paths, session and agents for initializing a repo.
+// Whether the repo is a fork is only known after cloning; a fork gets a
+// pr-shepherd in place of the merge queue.
+func (c *CLI) planInit(repoName, githubURL string, mqConfig state.MergeQueueConfig, skipAgents bool) initPlan {
+	repoPath := c.paths.RepoDir(repoName)
+	plan := initPlan{
+		Repo:        repoName,
+		URL:         githubURL,
+		RepoPath:    repoPath,
+		AgentsDir:   c.paths.RepoAgentsDir(repoName),
+		TmuxSession: sanitizeTmuxSessionName(repoName),
+		MergeQueue:  mqConfig,
+		SkipAgents:  skipAgents,
+	}
+	plan.ResumeClone, _ = clone.Interrupted(repoPath)
+	if skipAgents {
+		return plan
+	}
+
+	plan.Agents = append(plan.Agents, initPlanAgent{Name: "supervisor", Type: state.AgentTypeSupervisor, Dir: repoPath})
+	if mqConfig.Enabled {
+		plan.Agents = append(plan.Agents, initPlanAgent{Name: "merge-queue", Type: state.AgentTypeMergeQueue, Dir: repoPath})
+	}
+	plan.Agents = append(plan.Agents, initPlanAgent{
+		Name:   "default",
+		Type:   state.AgentTypeWorkspace,
+		Dir:    c.paths.AgentWorktree(repoName, "default"),
+		Branch: "workspace/default",
+	})
+	return plan
+}
+
+// printInitPlan prints the steps of an init plan
+func printInitPlan(plan initPlan) {
+	fmt.Printf("  Clone %s\n    to %s\n", plan.URL, plan.RepoPath)
+	if plan.ResumeClone != nil {
+		fmt.Printf("    continuing the clone interrupted after starting %s\n", plan.ResumeClone.StartedAt.Format("2006-01-02 15:04"))
+	} else {
+		if plan.Clone.Depth > 0 {
+			fmt.Printf("    keeping only the latest %d commits\n", plan.Clone.Depth)
+		}
+		if plan.Clone.Filter != "" {
+			fmt.Printf("    as a partial clone (--filter=%s)\n", plan.Clone.Filter)
+		}
+	}
+	fmt.Printf("  Copy agent definitions to %s\n", plan.AgentsDir)
+	fmt.Printf("  Create tmux session %s\n", plan.TmuxSession)
+	if plan.MergeQueue.Enabled {
+		fmt.Printf("  Register %s with the merge queue enabled (tracking: %s)\n", plan.Repo, plan.MergeQueue.TrackMode)
+	} else {
+		fmt.Printf("  Register %s with the merge queue disabled\n", plan.Repo)
+	}
+	if len(plan.PresetMatches) > 0 {
+		var applied []string
+		for _, match := range plan.PresetMatches {
+			applied = append(applied, fmt.Sprintf("%s (%s)", match.Preset, match.Reason))
+		}
+		fmt.Printf("  Apply presets: %s\n", strings.Join(applied, ", "))
+		if plan.Presets.TestCommand != "" {
+			fmt.Printf("    merge train test command: %s\n", plan.Presets.TestCommand)
+		}
+		if plan.Presets.PreSpawn != "" {
+			fmt.Printf("    pre-spawn hook: %s\n", plan.Presets.PreSpawn)
+		}
+	}
+
+	if plan.SkipAgents {
+		fmt.Println("  Start no agents (--skip-agents)")
+		return
+	}
+	fmt.Println("  Start agents:")
+	for _, agent := range plan.Agents {
+		fmt.Printf("    %-12s %-12s window %s:%s\n", agent.Name, agent.Type, plan.TmuxSession, agent.Name)
+		if agent.Branch != "" {
+			fmt.Printf("      in a new worktree %s on %s\n", agent.Dir, agent.Branch)
+		} else {
+			fmt.Printf("      in %s\n", agent.Dir)
+		}
+	}
+	if plan.MergeQueue.Enabled {
+		fmt.Println("  If the repository is a fork, a pr-shepherd replaces the merge queue.")
+	}
+}
+
+// detectPresets looks up a GitHub repository's topics and labels and returns
+// the presets they pick. Repositories not on GitHub get none.
+func detectPresets(githubURL string) (presets.Settings, []presets.Match) {
+	owner, repo, err := fork.ParseGitHubURL(githubURL)
+	if err != nil {
+		return presets.Settings{}, nil
+	}
+	topics, labels, err := presets.Fetch(context.Background(), owner, repo)
+	if err != nil {
+		fmt.Printf("Warning: could not read topics and labels for presets: %v\n", err)
+		return presets.Settings{}, nil
+	}
+	return presets.Detect(topics, labels)
+}
+
+// addPresetArgs adds the preset settings add_repo takes to its arguments
+func addPresetArgs(args map[string]interface{}, settings presets.Settings) {
+	if settings.TestCommand != "" {
+		args["mq_test_command"] = settings.TestCommand
+	}
+	if settings.PreSpawn != "" {
+		args["pre_spawn"] = settings.PreSpawn
+	}
+}
+
+// checkInitPlan returns the reasons carrying out an init plan would fail
+func (c *CLI) checkInitPlan(plan initPlan) []string {
+	var problems []string
+
+	resp, err := c.daemonClient().Send(socket.Request{Command: "list_repos"})
+	if err != nil {
+		problems = append(problems, "the daemon is not running (start it with: multiclaude start)")
+	} else if repos, ok := resp.Data.([]interface{}); ok {
+		for _, r := range repos {
+			if name, _ := r.(string); name == plan.Repo {
+				problems = append(problems, fmt.Sprintf("repository %q is already tracked", plan.Repo))
+			}
+		}
+	}
+
+	if plan.ResumeClone != nil && plan.ResumeClone.URL != plan.URL {
+		problems = append(problems, fmt.Sprintf("%s holds an interrupted clone of %s (start over with --restart-clone)", plan.RepoPath, plan.ResumeClone.URL))
+	} else if _, err := os.Stat(plan.RepoPath); err == nil && plan.ResumeClone == nil {
+		problems = append(problems, fmt.Sprintf("%s already exists", plan.RepoPath))
+	}
+	if plan.TmuxSession == "mc-" {
+		problems = append(problems, "the repository name gives an empty tmux session name")
+	} else if exists, err := tmux.NewClient().HasSession(context.Background(), plan.TmuxSession); err == nil && exists {
+		problems = append(problems, fmt.Sprintf("tmux session %s already exists", plan.TmuxSession))
+	}
+	return problems
+}
+
+// registerRepoWithoutAgents finishes an init with --skip-agents: it creates
+// the repo's tmux session, so the daemon doesn't restore agents into it, and
+// registers the repo without starting Claude
+func (c *CLI) registerRepoWithoutAgents(client *socket.Client, plan initPlan, forkConfig state.ForkConfig, psConfig state.PRShepherdConfig, defaultBranch string) error {
+	if _, err := tmuxCommand("new-session", "-d", "-s", plan.TmuxSession, "-n", "shell", "-c", plan.RepoPath); err != nil {
+		return errors.TmuxOperationFailed("create session", err)
+	}
+
+	args := map[string]interface{}{
+		"name":          plan.Repo,
+		"github_url":    plan.URL,
+		"tmux_session":  plan.TmuxSession,
+		"mq_enabled":    plan.MergeQueue.Enabled && !forkConfig.IsFork,
+		"mq_track_mode": string(plan.MergeQueue.TrackMode),
+		"ps_enabled":    psConfig.Enabled,
+		"ps_track_mode": string(psConfig.TrackMode),
+		"is_fork":       forkConfig.IsFork,
+		"target_branch": defaultBranch,
+	}
+	addPresetArgs(args, plan.Presets)
+	if forkConfig.IsFork {
+		args["upstream_url"] = forkConfig.UpstreamURL
+		args["upstream_owner"] = forkConfig.UpstreamOwner
+		args["upstream_repo"] = forkConfig.UpstreamRepo
+	}
+	resp, err := client.Send(socket.Request{Command: "add_repo", Args: args})
+	if err != nil {
+		return fmt.Errorf("failed to register repository with daemon: %w", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to register repository", resp)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Repository registered without agents")
+	fmt.Printf("  Tmux session: %s\n", plan.TmuxSession)
+	fmt.Println("\nStart agents when you're ready:")
+	fmt.Printf("  multiclaude workspace add <name> --repo %s\n", plan.Repo)
+	fmt.Printf("  multiclaude work \"<task>\" --repo %s\n", plan.Repo)
+	return nil
+}
+
+func (c *CLI) initRepo(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// --org sets up a whole organization's repositories, with --filter choosing which
+	if org := flags["org"]; org != "" {
+		if len(posArgs) > 0 {
+			return errors.InvalidUsage("usage: multiclaude init --org <org> [--filter <query>] [--concurrency <n>] (no URL with --org)")
+		}
+		return c.initOrg(org, flags)
+	}
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned] [--no-presets] [--skip-agents] [--depth <n>] [--filter <spec>] [--restart-clone] [--dry-run]")
+	}
+
+	githubURL := strings.TrimRight(posArgs[0], "/")
+
+	// Parse repository name from URL if not provided
 	var repoName string
 	if len(posArgs) >= 2 {
 		repoName = posArgs[1]
@@ -1069,30 +2539,76 @@ func (c *CLI) initRepo(args []string) error {
 		TrackMode: mqTrackMode,
 	}
 
-	fmt.Printf("Initializing repository: %s\n", repoName)
-	fmt.Printf("GitHub URL: %s\n", githubURL)
-	if mqEnabled {
-		fmt.Printf("Merge queue: enabled (tracking: %s)\n", mqTrackMode)
-	} else {
-		fmt.Printf("Merge queue: disabled\n")
+	// The repository's topics and labels pick presets; --mq-track wins over them
+	var presetSettings presets.Settings
+	var presetMatches []presets.Match
+	if flags["no-presets"] != "true" {
+		presetSettings, presetMatches = detectPresets(githubURL)
+		if _, explicit := flags["mq-track"]; !explicit && presetSettings.TrackMode != "" {
+			mqConfig.TrackMode = presetSettings.TrackMode
+		}
+	}
+
+	// --depth and --filter shrink what a large repository's clone downloads
+	var cloneOpts clone.Options
+	if depth, ok := flags["depth"]; ok {
+		n, err := strconv.Atoi(depth)
+		if err != nil || n < 1 {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --depth value: %s (must be a positive number of commits)", depth))
+		}
+		cloneOpts.Depth = n
+	}
+	cloneOpts.Filter = flags["filter"]
+
+	skipAgents := flags["skip-agents"] == "true" || flags["no-agents"] == "true"
+	plan := c.planInit(repoName, githubURL, mqConfig, skipAgents)
+	plan.Clone = cloneOpts
+	plan.Presets, plan.PresetMatches = presetSettings, presetMatches
+	if flags["restart-clone"] == "true" && plan.ResumeClone != nil {
+		if flags["dry-run"] != "true" {
+			fmt.Printf("Removing the interrupted clone in %s\n", plan.RepoPath)
+			if err := os.RemoveAll(plan.RepoPath); err != nil {
+				return fmt.Errorf("failed to remove the interrupted clone: %w", err)
+			}
+		}
+		plan.ResumeClone = nil
+	}
+
+	if flags["dry-run"] == "true" {
+		fmt.Printf("Dry run: initializing %s would\n\n", repoName)
+		printInitPlan(plan)
+		if problems := c.checkInitPlan(plan); len(problems) > 0 {
+			fmt.Println("\nIt would fail:")
+			for _, problem := range problems {
+				fmt.Printf("  ✗ %s\n", problem)
+			}
+		}
+		fmt.Println("\nNothing was changed. Run again without --dry-run to initialize.")
+		return nil
 	}
 
+	fmt.Printf("Initializing repository: %s\n\n", repoName)
+	printInitPlan(plan)
+	fmt.Println()
+
 	// Check if daemon is running
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	_, err := client.Send(socket.Request{Command: "ping"})
 	if err != nil {
 		return errors.DaemonNotRunning()
 	}
 
-	// Clone repository
+	// Clone repository. An interrupted clone keeps what it fetched and is
+	// continued by running init again.
 	repoPath := c.paths.RepoDir(repoName)
-	fmt.Printf("Cloning to: %s\n", repoPath)
-
-	cmd := exec.Command("git", "clone", githubURL, repoPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return errors.GitOperationFailed("clone", err)
+	if plan.ResumeClone != nil {
+		fmt.Printf("Continuing the interrupted clone in: %s\n", repoPath)
+	} else {
+		fmt.Printf("Cloning to: %s\n", repoPath)
+	}
+	if err := clone.Clone(githubURL, repoPath, plan.Clone, os.Stderr); err != nil {
+		return errors.GitOperationFailed("clone", err).WithSuggestion(
+			"run the same init command again to continue the clone where it stopped, or add --restart-clone to start over")
 	}
 
 	// Detect if this is a fork
@@ -1126,6 +2642,18 @@ func (c *CLI) initRepo(args []string) error {
 		mqEnabled = false
 	}
 
+	// Detect the default branch from the remote PRs target (upstream for forks)
+	defaultBranch := "main"
+	branchWt := worktree.NewManager(repoPath)
+	if remote, err := branchWt.GetUpstreamRemote(); err == nil {
+		if branch, err := branchWt.DetectRemoteDefaultBranch(remote); err == nil {
+			defaultBranch = branch
+		} else {
+			fmt.Printf("Warning: Failed to detect default branch, assuming %q: %v\n", defaultBranch, err)
+		}
+	}
+	fmt.Printf("Default branch: %s\n", defaultBranch)
+
 	// PR Shepherd config (used in fork mode)
 	psConfig := state.DefaultPRShepherdConfig()
 	psEnabled := forkInfo.IsFork && psConfig.Enabled
@@ -1145,21 +2673,22 @@ func (c *CLI) initRepo(args []string) error {
 
 	fmt.Printf("Creating tmux session: %s\n", tmuxSession)
 
+	if skipAgents {
+		return c.registerRepoWithoutAgents(client, plan, forkConfig, psConfig, defaultBranch)
+	}
+
 	// Create session with supervisor window
-	cmd = exec.Command("tmux", "new-session", "-d", "-s", tmuxSession, "-n", "supervisor", "-c", repoPath)
-	if err := cmd.Run(); err != nil {
+	if _, err := tmuxCommand("new-session", "-d", "-s", tmuxSession, "-n", "supervisor", "-c", repoPath); err != nil {
 		return errors.TmuxOperationFailed("create session", err)
 	}
 
 	// Create merge-queue or pr-shepherd window based on mode
 	if mqEnabled {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "merge-queue", "-c", repoPath)
-		if err := cmd.Run(); err != nil {
+		if _, err := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", "merge-queue", "-c", repoPath); err != nil {
 			return errors.TmuxOperationFailed("create merge-queue window", err)
 		}
 	} else if psEnabled {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "pr-shepherd", "-c", repoPath)
-		if err := cmd.Run(); err != nil {
+		if _, err := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", "pr-shepherd", "-c", repoPath); err != nil {
 			return errors.TmuxOperationFailed("create pr-shepherd window", err)
 		}
 	}
@@ -1209,7 +2738,7 @@ func (c *CLI) initRepo(args []string) error {
 
 	// Start Claude in supervisor window (skip in test mode)
 	var supervisorPID, mergeQueuePID, prShepherdPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+	if !simagent.Skip() {
 		// Resolve claude binary
 		claudeBinary, err := c.getClaudeBinary()
 		if err != nil {
@@ -1266,7 +2795,9 @@ func (c *CLI) initRepo(args []string) error {
 		"ps_enabled":    psConfig.Enabled,
 		"ps_track_mode": string(psConfig.TrackMode),
 		"is_fork":       forkConfig.IsFork,
+		"target_branch": defaultBranch,
 	}
+	addPresetArgs(addRepoArgs, plan.Presets)
 	if forkConfig.IsFork {
 		addRepoArgs["upstream_url"] = forkConfig.UpstreamURL
 		addRepoArgs["upstream_owner"] = forkConfig.UpstreamOwner
@@ -1280,7 +2811,7 @@ func (c *CLI) initRepo(args []string) error {
 		return fmt.Errorf("failed to register repository with daemon: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to register repository: %s", resp.Error)
+		return daemonError("failed to register repository", resp)
 	}
 
 	// Add supervisor agent
@@ -1300,7 +2831,7 @@ func (c *CLI) initRepo(args []string) error {
 		return fmt.Errorf("failed to register supervisor: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to register supervisor: %s", resp.Error)
+		return daemonError("failed to register supervisor", resp)
 	}
 
 	// Add merge-queue agent only if enabled (non-fork mode)
@@ -1321,7 +2852,7 @@ func (c *CLI) initRepo(args []string) error {
 			return fmt.Errorf("failed to register merge-queue: %w", err)
 		}
 		if !resp.Success {
-			return fmt.Errorf("failed to register merge-queue: %s", resp.Error)
+			return daemonError("failed to register merge-queue", resp)
 		}
 	}
 
@@ -1343,7 +2874,7 @@ func (c *CLI) initRepo(args []string) error {
 			return fmt.Errorf("failed to register pr-shepherd: %w", err)
 		}
 		if !resp.Success {
-			return fmt.Errorf("failed to register pr-shepherd: %s", resp.Error)
+			return daemonError("failed to register pr-shepherd", resp)
 		}
 	}
 
@@ -1373,8 +2904,7 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Create default workspace tmux window (detached so it doesn't switch focus)
-	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "default", "-c", workspacePath)
-	if err := cmd.Run(); err != nil {
+	if _, err := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", "default", "-c", workspacePath); err != nil {
 		return fmt.Errorf("failed to create workspace window: %w", err)
 	}
 
@@ -1397,7 +2927,7 @@ func (c *CLI) initRepo(args []string) error {
 
 	// Start Claude in default workspace window (skip in test mode)
 	var workspacePID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+	if !simagent.Skip() {
 		// Resolve claude binary
 		claudeBinary, err := c.getClaudeBinary()
 		if err != nil {
@@ -1434,7 +2964,7 @@ func (c *CLI) initRepo(args []string) error {
 		return fmt.Errorf("failed to register default workspace: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to register default workspace: %s", resp.Error)
+		return daemonError("failed to register default workspace", resp)
 	}
 
 	fmt.Println()
@@ -1451,6 +2981,53 @@ func (c *CLI) initRepo(args []string) error {
 	return nil
 }
 
+// reinitRepo repairs a tracked repository without removing it, keeping its
+// worktrees, workers and history
+func (c *CLI) reinitRepo(ctx CommandContext, args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	repoName := ctx.Repo
+	if len(posArgs) > 0 {
+		repoName = posArgs[0]
+	} else if repoName == "" {
+		return errors.NotInRepo()
+	}
+
+	reqArgs := map[string]interface{}{"name": repoName}
+	if url := strings.TrimRight(flags["url"], "/"); url != "" {
+		reqArgs["github_url"] = url
+	}
+
+	// Agents started by the daemon read the CLI reference from this file
+	c.cliDocsReference(repoName)
+
+	fmt.Printf("Reinitializing repository: %s\n", repoName)
+	resp, err := c.sendDaemonRequest("reinit_repo", reqArgs)
+	if err != nil {
+		return err
+	}
+
+	var actions []string
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		if list, ok := data["actions"].([]interface{}); ok {
+			for _, a := range list {
+				if action, ok := a.(string); ok {
+					actions = append(actions, action)
+				}
+			}
+		}
+	}
+	if len(actions) == 0 {
+		fmt.Println("✓ Nothing to repair: the clone, session and agents are in order")
+		return nil
+	}
+	for _, action := range actions {
+		fmt.Printf("  ✓ %s\n", action)
+	}
+	fmt.Printf("\n✓ Repository %s reinitialized\n", repoName)
+	return nil
+}
+
 func (c *CLI) listRepos(args []string) error {
 	resp, err := c.sendDaemonRequest("list_repos", map[string]interface{}{
 		"rich": true,
@@ -1495,7 +3072,10 @@ func (c *CLI) listRepos(args []string) error {
 
 			// Format mode string
 			var modeStr string
-			if isFork {
+			if solo, _ := repoMap["solo"].(bool); solo {
+				path, _ := repoMap["path"].(string)
+				modeStr = "solo in " + path
+			} else if isFork {
 				modeStr = fmt.Sprintf("fork of %s/%s", upstreamOwner, upstreamRepo)
 			} else {
 				modeStr = "upstream"
@@ -1535,7 +3115,7 @@ func (c *CLI) removeRepo(args []string) error {
 		repoName = args[0]
 	} else {
 		// Interactive selection - list repos
-		client := socket.NewClient(c.paths.DaemonSock)
+		client := c.daemonClient()
 		resp, err := client.Send(socket.Request{
 			Command: "list_repos",
 			Args: map[string]interface{}{
@@ -1546,7 +3126,7 @@ func (c *CLI) removeRepo(args []string) error {
 			return errors.DaemonCommunicationFailed("listing repositories", err)
 		}
 		if !resp.Success {
-			return errors.Wrap(errors.CategoryRuntime, "failed to list repos", fmt.Errorf("%s", resp.Error))
+			return daemonError("failed to list repos", resp)
 		}
 
 		repos, _ := resp.Data.([]interface{})
@@ -1568,7 +3148,7 @@ func (c *CLI) removeRepo(args []string) error {
 	fmt.Printf("Removing repository '%s'...\n", repoName)
 
 	// Get repo info from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
@@ -1579,7 +3159,7 @@ func (c *CLI) removeRepo(args []string) error {
 		return errors.DaemonCommunicationFailed("getting repo info", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to get repo info", resp)
 	}
 
 	// Get list of agents
@@ -1622,14 +3202,25 @@ func (c *CLI) removeRepo(args []string) error {
 		}
 	}
 
-	// Remove worktrees for all agents
+	// Remove worktrees for all agents. Solo repositories branch worktrees from
+	// the user's directory, and their agents may work in it directly.
 	repoPath := c.paths.RepoDir(repoName)
+	solo := false
+	if st, err := c.loadState(); err == nil {
+		if repo, ok := st.GetRepo(repoName); ok && repo.Solo {
+			repoPath, solo = repo.Path, true
+		}
+	}
 	wt := worktree.NewManager(repoPath)
 	for _, agent := range agents {
 		if agentMap, ok := agent.(map[string]interface{}); ok {
 			wtPath, _ := agentMap["worktree_path"].(string)
 			agentName, _ := agentMap["name"].(string)
-			if wtPath != "" && wtPath != repoPath {
+			if hasPathPrefix(wtPath, c.paths.MirrorWorktreeDir(repoName)) {
+				// Removed with the mirror below
+				continue
+			}
+			if wtPath != "" && wtPath != repoPath && (!solo || hasPathPrefix(wtPath, c.paths.WorktreeDir(repoName))) {
 				fmt.Printf("Removing worktree for '%s': %s\n", agentName, wtPath)
 				if err := wt.Remove(wtPath, true); err != nil {
 					fmt.Printf("Warning: failed to remove worktree: %v\n", err)
@@ -1647,6 +3238,16 @@ func (c *CLI) removeRepo(args []string) error {
 		}
 	}
 
+	// Remove the mirror and the worktrees checked out from it
+	for _, dir := range []string{c.paths.MirrorWorktreeDir(repoName), c.paths.MirrorDir(repoName)} {
+		if _, err := os.Stat(dir); err == nil {
+			fmt.Printf("Removing mirror: %s\n", dir)
+			if err := os.RemoveAll(dir); err != nil {
+				fmt.Printf("Warning: failed to remove mirror: %v\n", err)
+			}
+		}
+	}
+
 	// Clean up messages directory for this repo
 	msgDir := filepath.Join(c.paths.MessagesDir, repoName)
 	if _, err := os.Stat(msgDir); err == nil {
@@ -1667,508 +3268,2636 @@ func (c *CLI) removeRepo(args []string) error {
 		return errors.DaemonCommunicationFailed("removing repo", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to remove repo from state", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to remove repo from state", resp)
 	}
 
 	fmt.Println("✓ Repository removed successfully")
-	fmt.Printf("\nNote: The cloned repository at '%s' was NOT deleted.\n", repoPath)
-	fmt.Println("Delete it manually if you no longer need it.")
+	if !solo {
+		fmt.Printf("\nNote: The cloned repository at '%s' was NOT deleted.\n", repoPath)
+		fmt.Println("Delete it manually if you no longer need it.")
+	}
 	return nil
 }
 
-func (c *CLI) setCurrentRepo(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude repo use <name>")
+func (c *CLI) createProject(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 || flags["repos"] == "" {
+		return errors.InvalidUsage("usage: multiclaude project create <name> --repos <repo1,repo2,...>")
+	}
+	name := posArgs[0]
+	if err := state.ValidateProjectName(name); err != nil {
+		return errors.InvalidUsage(err.Error())
 	}
 
-	repoName := args[0]
+	var repos []interface{}
+	for _, r := range strings.Split(flags["repos"], ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			repos = append(repos, r)
+		}
+	}
+	if len(repos) == 0 {
+		return errors.InvalidUsage("--repos must list at least one repository")
+	}
 
-	_, err := c.sendDaemonRequest("set_current_repo", map[string]interface{}{
-		"name": repoName,
+	resp, err := c.sendDaemonRequest("create_project", map[string]interface{}{
+		"name":  name,
+		"repos": repos,
 	})
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Current repository set to: %s\n", repoName)
+	tmuxSession := fmt.Sprintf("mc-project-%s", name)
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		if session, ok := data["tmux_session"].(string); ok {
+			tmuxSession = session
+		}
+	}
+
+	fmt.Printf("Project '%s' created with %d repositories\n", name, len(repos))
+	fmt.Printf("Project supervisor running in tmux session: %s\n", tmuxSession)
+	format.Dimmed("\nAgents in member repositories can reach it with: multiclaude message send @%s <message>", name)
 	return nil
 }
 
-func (c *CLI) getCurrentRepo(args []string) error {
-	resp, err := c.sendDaemonRequest("get_current_repo", nil)
+func (c *CLI) listProjects(args []string) error {
+	resp, err := c.sendDaemonRequest("list_projects", nil)
 	if err != nil {
 		return err
 	}
 
-	currentRepo, _ := resp.Data.(string)
-	if currentRepo == "" {
-		fmt.Println("No current repository set")
-		fmt.Println("\nUse 'multiclaude repo use <name>' to set one")
-	} else {
-		fmt.Printf("Current repository: %s\n", currentRepo)
+	projects, ok := resp.Data.([]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	if len(projects) == 0 {
+		fmt.Println("No projects")
+		format.Dimmed("\nCreate one with: multiclaude project create <name> --repos <repo1,repo2,...>")
+		return nil
+	}
+
+	format.Header("Projects (%d):", len(projects))
+	fmt.Println()
+
+	table := format.NewColoredTable("PROJECT", "REPOS", "SESSION")
+	for _, project := range projects {
+		projectMap, ok := project.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := projectMap["name"].(string)
+		tmuxSession, _ := projectMap["tmux_session"].(string)
+		table.AddRow(
+			format.Cell(name),
+			format.Cell(strings.Join(interfaceSliceToStrings(projectMap["repos"]), ", ")),
+			format.ColorCell(tmuxSession, format.Dim),
+		)
 	}
+	table.Print()
+
 	return nil
 }
 
-func (c *CLI) clearCurrentRepo(args []string) error {
-	_, err := c.sendDaemonRequest("clear_current_repo", nil)
+func (c *CLI) listSnapshots(args []string) error {
+	snapshots, err := state.ListSnapshots(c.paths.SnapshotsDir())
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Current repository cleared")
-	return nil
-}
+	if len(snapshots) == 0 {
+		fmt.Println("No state snapshots yet")
+		format.Dimmed("\nThe daemon snapshots state.json every health check when it has changed.")
+		return nil
+	}
 
-func (c *CLI) configRepo(args []string) error {
-	flags, posArgs := ParseFlags(args)
+	format.Header("State snapshots (%d):", len(snapshots))
+	fmt.Println()
 
-	// Determine repository
-	var repoName string
-	if len(posArgs) >= 1 {
-		repoName = posArgs[0]
-	} else {
-		// Try to infer from current directory
-		cwd, err := os.Getwd()
+	table := format.NewColoredTable("ID", "TIME", "REPOS", "AGENTS", "REASON")
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		info := snapshots[i]
+		snap, err := state.LoadSnapshot(info.Path)
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
-
-		// Check if we're in a tracked repo
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoPath := c.paths.RepoDir(repo)
-			if strings.HasPrefix(cwd, repoPath) {
-				repoName = repo
-				break
-			}
+			table.AddRow(format.Cell(info.ID), format.Cell(info.Time.Format("2006-01-02 15:04:05")), format.Cell("-"), format.Cell("-"), format.ColorCell("unreadable", format.Red))
+			continue
 		}
-
-		if repoName == "" {
-			// If only one repo exists, use it
-			if len(repos) == 1 {
-				repoName = repos[0]
-			} else {
-				return fmt.Errorf("please specify a repository name or run from within a tracked repository")
+		repoCount, agentCount := 0, 0
+		if st, err := snap.ParseState(); err == nil {
+			repoCount = len(st.Repos)
+			for _, repo := range st.Repos {
+				agentCount += len(repo.Agents)
 			}
 		}
+		table.AddRow(
+			format.Cell(info.ID),
+			format.Cell(info.Time.Format("2006-01-02 15:04:05")),
+			format.Cell(strconv.Itoa(repoCount)),
+			format.Cell(strconv.Itoa(agentCount)),
+			format.ColorCell(snap.Reason, format.Dim),
+		)
 	}
+	table.Print()
 
-	// Check if any config flags are provided
-	hasMqEnabled := flags["mq-enabled"] != ""
-	hasMqTrack := flags["mq-track"] != ""
-	hasPsEnabled := flags["ps-enabled"] != ""
-	hasPsTrack := flags["ps-track"] != ""
+	return nil
+}
 
-	if !hasMqEnabled && !hasMqTrack && !hasPsEnabled && !hasPsTrack {
-		// No flags - just show current config
-		return c.showRepoConfig(repoName)
+func (c *CLI) diffSnapshots(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) > 2 {
+		return errors.InvalidUsage("usage: multiclaude state diff [<from>] [<to>]")
 	}
 
-	// Apply config changes
-	return c.updateRepoConfig(repoName, flags)
-}
+	dir := c.paths.SnapshotsDir()
+	var from, to *state.Snapshot
+	var fromLabel, toLabel string
 
-func (c *CLI) showRepoConfig(repoName string) error {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "get_repo_config",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to get repo config: %w (is daemon running?)", err)
+	if len(posArgs) == 0 {
+		snapshots, err := state.ListSnapshots(dir)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			return errors.New(errors.CategoryRuntime, "no state snapshots found")
+		}
+		posArgs = []string{snapshots[len(snapshots)-1].ID}
 	}
 
-	if !resp.Success {
-		return fmt.Errorf("failed to get repo config: %s", resp.Error)
+	info, err := state.FindSnapshot(dir, posArgs[0])
+	if err != nil {
+		return err
 	}
-
-	// Parse response
-	configMap, ok := resp.Data.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected response format")
+	if from, err = state.LoadSnapshot(info.Path); err != nil {
+		return err
 	}
+	fromLabel = info.ID
 
-	fmt.Printf("Configuration for repository: %s\n\n", repoName)
-
-	// Show fork info if this is a fork
-	isFork, _ := configMap["is_fork"].(bool)
-	if isFork {
-		upstreamOwner, _ := configMap["upstream_owner"].(string)
-		upstreamRepo, _ := configMap["upstream_repo"].(string)
-		fmt.Printf("Fork Mode: Yes (fork of %s/%s)\n\n", upstreamOwner, upstreamRepo)
+	if len(posArgs) == 2 {
+		info, err := state.FindSnapshot(dir, posArgs[1])
+		if err != nil {
+			return err
+		}
+		if to, err = state.LoadSnapshot(info.Path); err != nil {
+			return err
+		}
+		toLabel = info.ID
 	} else {
-		fmt.Println("Fork Mode: No (upstream/direct repository)")
-		fmt.Println()
+		if to, err = c.currentSnapshot(); err != nil {
+			return err
+		}
+		toLabel = "current"
 	}
 
-	// Show merge queue config
-	fmt.Println("Merge Queue:")
-	mqEnabled := true
-	if enabled, ok := configMap["mq_enabled"].(bool); ok {
-		mqEnabled = enabled
+	lines, err := state.DiffSnapshots(from, to)
+	if err != nil {
+		return err
 	}
-	mqTrackMode := "all"
-	if trackMode, ok := configMap["mq_track_mode"].(string); ok {
-		mqTrackMode = trackMode
+
+	format.Header("State diff %s -> %s:", fromLabel, toLabel)
+	if len(lines) == 0 {
+		fmt.Println("  No differences")
+		return nil
 	}
-	if mqEnabled {
-		fmt.Printf("  Enabled: true\n")
-		fmt.Printf("  Track mode: %s\n", mqTrackMode)
-	} else {
-		fmt.Printf("  Enabled: false\n")
+	for _, line := range lines {
+		switch line[0] {
+		case '+':
+			format.Green.Printf("  %s\n", line)
+		case '-':
+			format.Red.Printf("  %s\n", line)
+		default:
+			format.Yellow.Printf("  %s\n", line)
+		}
 	}
+	return nil
+}
 
-	// Show PR shepherd config
-	fmt.Println("\nPR Shepherd:")
-	psEnabled := true
-	if enabled, ok := configMap["ps_enabled"].(bool); ok {
-		psEnabled = enabled
+func (c *CLI) rollbackState(args []string) error {
+	flags, _ := ParseFlags(args)
+	to, ok := flags["to"]
+	if !ok || to == "" || to == "true" {
+		return errors.InvalidUsage("usage: multiclaude state rollback --to <snapshot-id|timestamp>")
 	}
-	psTrackMode := "author"
-	if trackMode, ok := configMap["ps_track_mode"].(string); ok {
-		psTrackMode = trackMode
+
+	client := c.daemonClient()
+	if _, err := client.Send(socket.Request{Command: "ping"}); err != nil {
+		return c.localRollback(to)
 	}
-	if psEnabled {
-		fmt.Printf("  Enabled: true\n")
-		fmt.Printf("  Track mode: %s\n", psTrackMode)
-	} else {
-		fmt.Printf("  Enabled: false\n")
+
+	resp, err := c.sendDaemonRequest("state_rollback", map[string]interface{}{"to": to})
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("\nTo modify:")
-	fmt.Printf("  multiclaude config %s --mq-enabled=true|false\n", repoName)
-	fmt.Printf("  multiclaude config %s --mq-track=all|author|assigned\n", repoName)
-	fmt.Printf("  multiclaude config %s --ps-enabled=true|false\n", repoName)
-	fmt.Printf("  multiclaude config %s --ps-track=all|author|assigned\n", repoName)
+	data, _ := resp.Data.(map[string]interface{})
+	id, _ := data["id"].(string)
+	fmt.Printf("✓ State rolled back to snapshot %s\n", id)
+	if recreated, _ := data["recreated"].(float64); recreated > 0 {
+		fmt.Printf("  Recreated %d missing window(s)/worktree(s)\n", int(recreated))
+	}
+	if failures := interfaceSliceToStrings(data["failures"]); len(failures) > 0 {
+		fmt.Printf("  Could not recreate %d resource(s):\n", len(failures))
+		for _, f := range failures {
+			fmt.Printf("    - %s\n", f)
+		}
+		format.Dimmed("  Run 'multiclaude repair' to resolve the rest.")
+	}
+	format.Dimmed("Message files are not rolled back. The previous state was snapshotted first.")
+	return nil
+}
+
+// localRollback restores a snapshot while the daemon is stopped
+func (c *CLI) localRollback(to string) error {
+	dir := c.paths.SnapshotsDir()
+	info, err := state.FindSnapshot(dir, to)
+	if err != nil {
+		return err
+	}
+	snap, err := state.LoadSnapshot(info.Path)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.currentSnapshot()
+	if err != nil {
+		return err
+	}
+	current.Reason = "before rollback"
+	if _, err := state.WriteSnapshot(dir, current); err != nil {
+		return fmt.Errorf("failed to snapshot current state: %w", err)
+	}
+
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	if err := st.Restore(snap); err != nil {
+		return fmt.Errorf("failed to restore snapshot %s: %w", info.ID, err)
+	}
 
+	fmt.Printf("✓ State rolled back to snapshot %s\n", info.ID)
+	format.Dimmed("Daemon is not running. Start it and run 'multiclaude repair' to recreate windows for restored agents.")
 	return nil
 }
 
-func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
-	// Build update args
-	updateArgs := map[string]interface{}{
+// currentSnapshot captures the on-disk state and message index without writing it
+func (c *CLI) currentSnapshot() (*state.Snapshot, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	msgIndex := make(map[string][]state.MessageIndexEntry)
+	all, err := messages.NewManager(c.paths.MessagesDir).ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for inbox, msgs := range all {
+		for _, msg := range msgs {
+			msgIndex[inbox] = append(msgIndex[inbox], state.MessageIndexEntry{ID: msg.ID, From: msg.From, Status: string(msg.Status)})
+		}
+	}
+	return st.TakeSnapshot("manual", msgIndex)
+}
+
+func (c *CLI) projectStatus(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude project status <name>")
+	}
+	name := args[0]
+
+	resp, err := c.sendDaemonRequest("project_status", map[string]interface{}{
+		"name": name,
+	})
+	if err != nil {
+		return err
+	}
+
+	status, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	supervisorHealthy, _ := status["supervisor_healthy"].(bool)
+	tmuxSession, _ := status["tmux_session"].(string)
+
+	format.Header("Project: %s", name)
+	supervisorStatus := format.StatusHealthy
+	if !supervisorHealthy {
+		supervisorStatus = format.StatusError
+	}
+	fmt.Printf("Project supervisor: %s ", format.ColoredStatus(supervisorStatus))
+	format.Dimmed("(%s)", tmuxSession)
+	fmt.Println()
+
+	repos, _ := status["repos"].([]interface{})
+	table := format.NewColoredTable("REPO", "AGENTS", "WORKERS", "COMPLETED", "STATUS")
+	for _, repo := range repos {
+		repoMap, ok := repo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		repoName, _ := repoMap["name"].(string)
+		totalAgents, _ := repoMap["total_agents"].(float64)
+		workerCount, _ := repoMap["worker_count"].(float64)
+		completedCount, _ := repoMap["completed_count"].(float64)
+		sessionHealthy, _ := repoMap["session_healthy"].(bool)
+
+		var statusCell format.ColoredCell
+		if sessionHealthy {
+			statusCell = format.ColorCell(format.ColoredStatus(format.StatusHealthy), nil)
+		} else {
+			statusCell = format.ColorCell(format.ColoredStatus(format.StatusError), nil)
+		}
+
+		table.AddRow(
+			format.Cell(repoName),
+			format.Cell(fmt.Sprintf("%d", int(totalAgents))),
+			format.Cell(fmt.Sprintf("%d", int(workerCount))),
+			format.Cell(fmt.Sprintf("%d", int(completedCount))),
+			statusCell,
+		)
+	}
+	table.Print()
+
+	return nil
+}
+
+func (c *CLI) removeProject(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude project rm <name>")
+	}
+	name := args[0]
+
+	if _, err := c.sendDaemonRequest("remove_project", map[string]interface{}{
+		"name": name,
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Project '%s' removed\n", name)
+	return nil
+}
+
+func (c *CLI) setCurrentRepo(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude repo use <name>")
+	}
+
+	repoName := args[0]
+
+	_, err := c.sendDaemonRequest("set_current_repo", map[string]interface{}{
 		"name": repoName,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current repository set to: %s\n", repoName)
+	return nil
+}
+
+func (c *CLI) getCurrentRepo(args []string) error {
+	resp, err := c.sendDaemonRequest("get_current_repo", nil)
+	if err != nil {
+		return err
+	}
+
+	currentRepo, _ := resp.Data.(string)
+	if currentRepo == "" {
+		fmt.Println("No current repository set")
+		fmt.Println("\nUse 'multiclaude repo use <name>' to set one")
+	} else {
+		fmt.Printf("Current repository: %s\n", currentRepo)
+	}
+	return nil
+}
+
+func (c *CLI) clearCurrentRepo(args []string) error {
+	_, err := c.sendDaemonRequest("clear_current_repo", nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Current repository cleared")
+	return nil
+}
+
+func (c *CLI) configRepo(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// Determine repository
+	var repoName string
+	if len(posArgs) >= 1 {
+		repoName = posArgs[0]
+	} else {
+		// Try to infer from current directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		// Check if we're in a tracked repo
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoPath := c.paths.RepoDir(repo)
+			if strings.HasPrefix(cwd, repoPath) {
+				repoName = repo
+				break
+			}
+		}
+
+		if repoName == "" {
+			// If only one repo exists, use it
+			if len(repos) == 1 {
+				repoName = repos[0]
+			} else {
+				return fmt.Errorf("please specify a repository name or run from within a tracked repository")
+			}
+		}
+	}
+
+	// Check if any config flags are provided
+	hasMqEnabled := flags["mq-enabled"] != ""
+	hasMqTrack := flags["mq-track"] != ""
+	hasMqTest := flags["mq-test"] != ""
+	hasPsEnabled := flags["ps-enabled"] != ""
+	hasPsTrack := flags["ps-track"] != ""
+	hasDefaultBranch := flags["default-branch"] != ""
+	hasBranchTemplate := flags["branch-template"] != ""
+	hasNotify := false
+	for _, flag := range []string{"notify-enabled", "notify-to", "notify-from", "notify-method", "notify-smtp", "notify-smtp-user", "notify-digest", "notify-human", "notify-webhook"} {
+		if flags[flag] != "" {
+			hasNotify = true
+		}
+	}
+
+	hasFederation := false
+	for _, flag := range []string{"federation", "federation-branch", "federation-peer"} {
+		if flags[flag] != "" {
+			hasFederation = true
+		}
+	}
+
+	hasZombie := false
+	for _, flag := range []string{"zombie", "zombie-stall", "zombie-stalled", "zombie-looping", "zombie-prompt"} {
+		if flags[flag] != "" {
+			hasZombie = true
+		}
+	}
+
+	hasWorktree := flags["worktree-lfs"] != "" || flags["worktree-submodules"] != "" || flags["worktree-mirror"] != "" || flags["worktree-dir"] != ""
+
+	hasRefresh := flags["refresh"] != "" || flags["refresh-only-clean"] != "" || flags["refresh-pause-active"] != ""
+
+	hasRoster := flags["roster"] != ""
+
+	hasDraftPRs := flags["draft-prs"] != ""
+
+	hasPRDescriptions := flags["pr-descriptions"] != "" || flags["pr-template"] != ""
+
+	hasPushChecks := flags["push-rebased"] != "" || flags["push-signed"] != "" || flags["push-message"] != ""
+
+	hasReviewDispatch := flags["review-dispatch"] != ""
+
+	hasMaxWorkers := flags["max-workers"] != ""
+
+	hasClaudeConfigDir := flags["claude-config-dir"] != ""
+
+	hasCommentCommands := flags["comment-commands"] != ""
+
+	hasSpawnHooks := flags["pre-spawn"] != "" || flags["post-spawn"] != ""
+
+	hasPromptBudget := false
+	for flag := range promptBudgetFlags {
+		if flags[flag] != "" {
+			hasPromptBudget = true
+		}
+	}
+
+	hasWorkHours := flags["work-hours"] != "" || flags["work-days"] != "" || flags["timezone"] != ""
+
+	if !hasMqEnabled && !hasMqTrack && !hasMqTest && !hasPsEnabled && !hasPsTrack && !hasDefaultBranch && !hasBranchTemplate && !hasNotify && !hasFederation && !hasZombie && !hasWorktree && !hasRefresh && !hasRoster && !hasDraftPRs && !hasPRDescriptions && !hasPushChecks && !hasReviewDispatch && !hasMaxWorkers && !hasClaudeConfigDir && !hasCommentCommands && !hasSpawnHooks && !hasPromptBudget && !hasWorkHours {
+		// No flags - just show current config
+		return c.showRepoConfig(repoName)
+	}
+
+	// Apply config changes
+	return c.updateRepoConfig(repoName, flags)
+}
+
+// promptBudgetFlags maps the config command's prompt budget flags to their
+// update_repo_config arguments
+var promptBudgetFlags = map[string]string{
+	"prompt-budget":          "prompt_budget_total",
+	"prompt-budget-base":     "prompt_budget_base",
+	"prompt-budget-docs":     "prompt_budget_docs",
+	"prompt-budget-commands": "prompt_budget_commands",
+	"prompt-budget-custom":   "prompt_budget_custom",
+}
+
+func (c *CLI) showRepoConfig(repoName string) error {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "get_repo_config",
+		Args: map[string]interface{}{
+			"name": repoName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get repo config: %w (is daemon running?)", err)
+	}
+
+	if !resp.Success {
+		return daemonError("failed to get repo config", resp)
+	}
+
+	// Parse response
+	configMap, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response format")
+	}
+
+	fmt.Printf("Configuration for repository: %s\n\n", repoName)
+
+	// Show fork info if this is a fork
+	isFork, _ := configMap["is_fork"].(bool)
+	if isFork {
+		upstreamOwner, _ := configMap["upstream_owner"].(string)
+		upstreamRepo, _ := configMap["upstream_repo"].(string)
+		fmt.Printf("Fork Mode: Yes (fork of %s/%s)\n\n", upstreamOwner, upstreamRepo)
+	} else {
+		fmt.Println("Fork Mode: No (upstream/direct repository)")
+		fmt.Println()
+	}
+
+	targetBranch, _ := configMap["target_branch"].(string)
+	if targetBranch == "" {
+		targetBranch = "(not recorded, detected on use)"
+	}
+	fmt.Printf("Default Branch: %s\n", targetBranch)
+
+	branchTemplate, _ := configMap["branch_template"].(string)
+	if branchTemplate == "" {
+		branchTemplate = branchname.DefaultTemplate + " (default)"
+	}
+	fmt.Printf("Worker Branch Template: %s\n\n", branchTemplate)
+
+	// Show merge queue config
+	fmt.Println("Merge Queue:")
+	mqEnabled := true
+	if enabled, ok := configMap["mq_enabled"].(bool); ok {
+		mqEnabled = enabled
+	}
+	mqTrackMode := "all"
+	if trackMode, ok := configMap["mq_track_mode"].(string); ok {
+		mqTrackMode = trackMode
+	}
+	if mqEnabled {
+		fmt.Printf("  Enabled: true\n")
+		fmt.Printf("  Track mode: %s\n", mqTrackMode)
+		if testCommand, _ := configMap["mq_test_command"].(string); testCommand != "" {
+			fmt.Printf("  Test command: %s\n", testCommand)
+		} else {
+			fmt.Printf("  Test command: none (no merge train)\n")
+		}
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+
+	// Show PR shepherd config
+	fmt.Println("\nPR Shepherd:")
+	psEnabled := true
+	if enabled, ok := configMap["ps_enabled"].(bool); ok {
+		psEnabled = enabled
+	}
+	psTrackMode := "author"
+	if trackMode, ok := configMap["ps_track_mode"].(string); ok {
+		psTrackMode = trackMode
+	}
+	if psEnabled {
+		fmt.Printf("  Enabled: true\n")
+		fmt.Printf("  Track mode: %s\n", psTrackMode)
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+
+	// Show notification config
+	fmt.Println("\nEmail Notifications:")
+	notifyEnabled, _ := configMap["notify_enabled"].(bool)
+	if notifyEnabled {
+		notifyMethod, _ := configMap["notify_method"].(string)
+		notifyFrom, _ := configMap["notify_from"].(string)
+		fmt.Printf("  Enabled: true\n")
+		fmt.Printf("  Method: %s\n", notifyMethod)
+		fmt.Printf("  To: %s\n", strings.Join(interfaceSliceToStrings(configMap["notify_to"]), ", "))
+		if notifyFrom != "" {
+			fmt.Printf("  From: %s\n", notifyFrom)
+		}
+		if notifyMethod == string(state.NotifyMethodSMTP) {
+			smtpAddr, _ := configMap["notify_smtp_addr"].(string)
+			smtpUser, _ := configMap["notify_smtp_username"].(string)
+			fmt.Printf("  SMTP server: %s\n", smtpAddr)
+			if smtpUser != "" {
+				fmt.Printf("  SMTP user: %s (password from $%s)\n", smtpUser, notify.SMTPPasswordEnv)
+			}
+		}
+		digestMinutes, _ := configMap["notify_digest_minutes"].(float64)
+		if digestMinutes > 0 {
+			fmt.Printf("  Digest: every %d minutes\n", int(digestMinutes))
+		} else {
+			fmt.Printf("  Digest: off (send immediately)\n")
+		}
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+
+	fmt.Println("\nAgents Waiting on a Human:")
+	if notifiers := interfaceSliceToStrings(configMap["notify_needs_human"]); len(notifiers) > 0 {
+		fmt.Printf("  Notifiers: %s\n", strings.Join(notifiers, ", "))
+	} else {
+		fmt.Printf("  Notifiers: none (needs_human events only)\n")
+	}
+	if webhook, _ := configMap["notify_webhook"].(string); webhook != "" {
+		fmt.Printf("  Webhook: %s\n", webhook)
+	}
+
+	// Show federation config
+	fmt.Println("\nFederation:")
+	fedEnabled, _ := configMap["federation_enabled"].(bool)
+	if fedEnabled {
+		fedRelay, _ := configMap["federation_relay"].(string)
+		fedBranch, _ := configMap["federation_branch"].(string)
+		fedPeer, _ := configMap["federation_peer_id"].(string)
+		fmt.Printf("  Enabled: true\n")
+		if fedRelay == federation.RelayGit {
+			if fedBranch == "" {
+				fedBranch = federation.DefaultBranch
+			}
+			fmt.Printf("  Relay: git branch %s on origin\n", fedBranch)
+		} else {
+			fmt.Printf("  Relay: %s\n", fedRelay)
+		}
+		fmt.Printf("  Peer ID: %s\n", fedPeer)
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+
+	// Show zombie detection config
+	fmt.Println("\nZombie Detection:")
+	zombieEnabled, _ := configMap["zombie_enabled"].(bool)
+	if zombieEnabled {
+		stallMinutes, _ := configMap["zombie_stall_minutes"].(float64)
+		stalled, _ := configMap["zombie_stalled"].(string)
+		looping, _ := configMap["zombie_looping"].(string)
+		prompt, _ := configMap["zombie_prompt"].(string)
+		fmt.Printf("  Enabled: true\n")
+		fmt.Printf("  Stalled (no output for %d minutes): %s\n", int(stallMinutes), stalled)
+		fmt.Printf("  Looping (repeating output): %s\n", looping)
+		fmt.Printf("  Permission prompt: %s\n", prompt)
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+
+	// Show worktree setup config
+	fmt.Println("\nWorktree Setup:")
+	worktreeLFS, _ := configMap["worktree_lfs"].(bool)
+	worktreeSubmodules, _ := configMap["worktree_submodules"].(bool)
+	fmt.Printf("  Git LFS pull: %v\n", worktreeLFS)
+	fmt.Printf("  Submodules: %v\n", worktreeSubmodules)
+	worktreeMirror, _ := configMap["worktree_mirror"].(bool)
+	fmt.Printf("  Mirror for reviewers and observers: %v\n", worktreeMirror)
+	if worktreeDir, _ := configMap["worktree_dir"].(string); worktreeDir != "" {
+		fmt.Printf("  Directory: %s\n", filepath.Join(worktreeDir, repoName))
+	} else {
+		fmt.Printf("  Directory: %s (default)\n", filepath.Join(c.paths.WorktreesDir, repoName))
+	}
+
+	// Show worktree refresh config
+	fmt.Println("\nWorktree Refresh:")
+	refreshStrategy, _ := configMap["refresh_strategy"].(string)
+	refreshOnlyClean, _ := configMap["refresh_only_clean"].(bool)
+	refreshPauseActive, _ := configMap["refresh_pause_active"].(bool)
+	fmt.Printf("  Strategy: %s\n", refreshStrategy)
+	fmt.Printf("  Only when clean: %v\n", refreshOnlyClean)
+	fmt.Printf("  Pause while agent is active: %v\n", refreshPauseActive)
+
+	// Show how agents learn their teammates
+	fmt.Println("\nTeammate Roster:")
+	roster, _ := configMap["roster"].(string)
+	fmt.Printf("  Mode: %s\n", roster)
+
+	// Show whether workers open draft PRs
+	fmt.Println("\nDraft PRs:")
+	draftPRs, _ := configMap["draft_prs"].(bool)
+	if draftPRs {
+		fmt.Printf("  Enabled: true (workers open drafts early; multiclaude worker ready <name> hands them to the merge queue)\n")
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+
+	// Show whether the daemon writes workers' PR descriptions
+	fmt.Println("\nPR Descriptions:")
+	prDescriptions, _ := configMap["pr_descriptions"].(bool)
+	fmt.Printf("  Enabled: %v\n", prDescriptions)
+	if prTemplate, _ := configMap["pr_template"].(string); prTemplate != "" {
+		fmt.Printf("  Template: %s\n", prTemplate)
+	} else {
+		fmt.Printf("  Template: (built-in)\n")
+	}
+
+	// Show what workers' branches must pass before they're pushed
+	fmt.Println("\nPush Checks:")
+	pushRebased, _ := configMap["push_rebased"].(bool)
+	pushSigned, _ := configMap["push_signed"].(bool)
+	fmt.Printf("  Rebased: %v\n", pushRebased)
+	fmt.Printf("  Signed: %v\n", pushSigned)
+	if pattern, _ := configMap["push_message_pattern"].(string); pattern != "" {
+		fmt.Printf("  Message pattern: %s\n", pattern)
+	} else {
+		fmt.Printf("  Message pattern: (any)\n")
+	}
+
+	// Show how review requests are shared among reviewers
+	fmt.Println("\nReviews:")
+	reviewDispatch, _ := configMap["review_dispatch"].(string)
+	fmt.Printf("  Dispatch: %s\n", reviewDispatch)
+
+	// Show how many workers may run at once
+	fmt.Println("\nWorkers:")
+	if maxWorkers, _ := configMap["max_workers"].(float64); maxWorkers > 0 {
+		fmt.Printf("  Limit: %d at once\n", int(maxWorkers))
+	} else {
+		fmt.Printf("  Limit: none\n")
+	}
+
+	// Show which Claude account the agents use
+	fmt.Println("\nClaude:")
+	if configDir, _ := configMap["claude_config_dir"].(string); configDir != "" {
+		fmt.Printf("  Config directory: %s\n", configDir)
+	} else {
+		fmt.Printf("  Config directory: ~/.claude (default)\n")
+	}
+
+	// Show who may steer workers from GitHub comments
+	fmt.Println("\nComment Commands:")
+	if allow := interfaceSliceToStrings(configMap["comment_commands_allow"]); len(allow) > 0 {
+		fmt.Printf("  Allowed: @%s\n", strings.Join(allow, ", @"))
+	} else {
+		fmt.Printf("  Allowed: (off)\n")
+	}
+
+	// Show the commands run around starting workers
+	fmt.Println("\nSpawn Hooks:")
+	for _, hook := range []struct{ label, key string }{{"Pre-spawn", "pre_spawn"}, {"Post-spawn", "post_spawn"}} {
+		command, _ := configMap[hook.key].(string)
+		if command == "" {
+			command = "(none)"
+		}
+		fmt.Printf("  %s: %s\n", hook.label, command)
+	}
+
+	// Show prompt budget, marking limits left at their default
+	fmt.Println("\nPrompt Budget (estimated tokens):")
+	budget := prompts.ResolveBudget(state.PromptBudget{})
+	for _, limit := range []struct {
+		label, key string
+		fallback   int
+	}{
+		{"Total", "prompt_budget_total", budget.Total},
+		{"Base prompt", "prompt_budget_base", budget.Base},
+		{"CLI docs", "prompt_budget_docs", budget.Docs},
+		{"Slash commands", "prompt_budget_commands", budget.Commands},
+		{"Custom instructions", "prompt_budget_custom", budget.Custom},
+	} {
+		if tokens, _ := configMap[limit.key].(float64); tokens > 0 {
+			fmt.Printf("  %s: %d\n", limit.label, int(tokens))
+		} else {
+			fmt.Printf("  %s: %d (default)\n", limit.label, limit.fallback)
+		}
+	}
+
+	// Show when agents are kept busy
+	fmt.Println("\nWork Hours:")
+	hours := workHoursFromConfig(configMap)
+	fmt.Printf("  Hours: %s\n", workhours.Describe(hours))
+	if working, _ := configMap["working"].(bool); working {
+		fmt.Printf("  Now: working\n")
+	} else {
+		fmt.Printf("  Now: off hours (no nudges, queued tasks or new workers)\n")
+	}
+	if hours.Override != "" {
+		fmt.Printf("  Override: %s (multiclaude hours auto --repo %s to clear)\n", hours.Override, repoName)
+	}
+
+	// Show message routing rules
+	fmt.Println("\nMessage Routing:")
+	if rules := routingRulesFromConfig(configMap); len(rules) > 0 {
+		for i, rule := range rules {
+			fmt.Printf("  %d. %s\n", i+1, routing.Describe(rule))
+		}
+	} else {
+		fmt.Printf("  No rules\n")
+	}
+
+	fmt.Println("\nTo modify:")
+	fmt.Printf("  multiclaude config %s --mq-enabled=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --mq-track=all|author|assigned\n", repoName)
+	fmt.Printf("  multiclaude config %s --mq-test=<command>|off  (simulate PRs on a merge train first)\n", repoName)
+	fmt.Printf("  multiclaude config %s --ps-enabled=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --ps-track=all|author|assigned\n", repoName)
+	fmt.Printf("  multiclaude config %s --default-branch=<branch>\n", repoName)
+	fmt.Printf("  multiclaude config %s --branch-template=<template>|default  (placeholders: {agent} {task-slug} {date} {user})\n", repoName)
+	fmt.Printf("  multiclaude config %s --notify-enabled=true --notify-to=<addr,...> [--notify-method=sendmail|smtp] [--notify-smtp=host:port] [--notify-digest=<minutes>]\n", repoName)
+	fmt.Printf("  multiclaude config %s --notify-human=tmux,desktop,webhook|off [--notify-webhook=<url>|off]\n", repoName)
+	fmt.Printf("  multiclaude config %s --federation=off|git|<shared-dir> [--federation-branch=<branch>] [--federation-peer=<id>]\n", repoName)
+	fmt.Printf("  multiclaude config %s --zombie=on|off [--zombie-stall=<minutes>] [--zombie-stalled|--zombie-looping|--zombie-prompt=nudge|restart|escalate|ignore|default]\n", repoName)
+	fmt.Printf("  multiclaude config %s --worktree-lfs=true|false --worktree-submodules=true|false --worktree-mirror=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --worktree-dir=<dir>|default  (then move existing worktrees: multiclaude repo migrate-worktrees)\n", repoName)
+	fmt.Printf("  multiclaude config %s --refresh=rebase|merge|fetch|off [--refresh-only-clean=true|false] [--refresh-pause-active=true|false]\n", repoName)
+	fmt.Printf("  multiclaude config %s --roster=prompt|file|off  (list teammates in prompts, only in a file, or not at all)\n", repoName)
+	fmt.Printf("  multiclaude config %s --draft-prs=true|false  (workers open draft PRs the merge queue ignores until they're ready)\n", repoName)
+	fmt.Printf("  multiclaude config %s --pr-descriptions=true|false [--pr-template=<path>|default]  (write workers' PR descriptions when they finish)\n", repoName)
+	fmt.Printf("  multiclaude config %s --push-rebased=true|false --push-signed=true|false --push-message=<regex>|off  (checked by worker check, worker ready and agent complete)\n", repoName)
+	fmt.Printf("  multiclaude config %s --review-dispatch=round-robin|least-loaded  (how multiclaude review shares PRs among running reviewers)\n", repoName)
+	fmt.Printf("  multiclaude config %s --max-workers=<n>|off  (most workers running at once; queued tasks wait for a place)\n", repoName)
+	fmt.Printf("  multiclaude config %s --claude-config-dir=<dir>|default  (run agents with another Claude account's CLAUDE_CONFIG_DIR)\n", repoName)
+	fmt.Printf("  multiclaude config %s --comment-commands=<login,...>|off  (who may use /multiclaude revise|abandon|restart in PR and issue comments)\n", repoName)
+	fmt.Printf("  multiclaude config %s --pre-spawn=<command>|off --post-spawn=<command>|off  (run around starting each worker)\n", repoName)
+	fmt.Printf("  multiclaude config %s --prompt-budget=<tokens>|default [--prompt-budget-base|--prompt-budget-docs|--prompt-budget-commands|--prompt-budget-custom=<tokens>|default]\n", repoName)
+	fmt.Printf("  multiclaude config %s --work-hours=07:00-22:00|off [--work-days=mon-fri|all] [--timezone=Europe/Berlin|local]\n", repoName)
+	fmt.Printf("  multiclaude routing add --repo %s [--contains <text>] [--from <agent|type>] [--to <agent>] [--cc <agents>] [--priority high|low]\n", repoName)
+
+	return nil
+}
+
+// validateRepoConfig checks a repository config file and prints each problem
+// as path:line:column so CI logs and editors can point at it
+func (c *CLI) validateRepoConfig(args []string) error {
+	_, posArgs := ParseFlags(args)
+
+	var path string
+	if len(posArgs) > 0 {
+		path = localPath(posArgs[0])
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		path = findRepoConfig(cwd)
+		if path == "" {
+			return errors.New(errors.CategoryNotFound, fmt.Sprintf("no %s found in this repository", repoconfig.Path)).
+				WithSuggestion("multiclaude config validate <path>")
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(errors.CategoryConfig, "failed to read config file", err)
+	}
+
+	issues := repoconfig.Validate(data)
+	if len(issues) == 0 {
+		fmt.Printf("✓ %s is valid\n", path)
+		return nil
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s:%s\n", path, issue)
+	}
+	return errors.New(errors.CategoryConfig, fmt.Sprintf("%s has %d problem(s)", path, len(issues))).
+		WithSuggestion("multiclaude config schema")
+}
+
+// findRepoConfig looks for the repository config file in dir and its parents,
+// stopping at the repository root
+func findRepoConfig(dir string) string {
+	for {
+		candidate := filepath.Join(dir, repoconfig.Path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (c *CLI) printRepoConfigSchema(args []string) error {
+	_, err := os.Stdout.Write(repoconfig.Schema())
+	return err
+}
+
+// scaffoldRepo writes a starter .multiclaude directory at the root of the
+// repository containing the given path (default: the current directory)
+func (c *CLI) scaffoldRepo(flags *FlagSet) error {
+	if flags.Bool("minimal") && flags.Bool("full") {
+		return errors.InvalidUsage("--minimal and --full can't be used together")
+	}
+	variant := scaffold.Standard
+	if flags.Bool("minimal") {
+		variant = scaffold.Minimal
+	} else if flags.Bool("full") {
+		variant = scaffold.Full
+	}
+
+	dir := "."
+	if args := flags.Args(); len(args) > 0 {
+		dir = localPath(args[0])
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("%s is not a directory", dir))
+	}
+	root := findRepoRoot(dir)
+	if root == "" {
+		fmt.Printf("Note: %s is not in a git repository\n", dir)
+		root = dir
+	}
+
+	result, err := scaffold.Write(root, variant, flags.Bool("force"))
+	if result != nil {
+		for _, path := range result.Written {
+			fmt.Printf("  created  %s\n", path)
+		}
+		for _, path := range result.Skipped {
+			fmt.Printf("  exists   %s\n", path)
+		}
+	}
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to scaffold "+scaffold.Dir, err)
+	}
+
+	fmt.Printf("\n✓ Scaffolded %s (%s) in %s\n", scaffold.Dir, variant, root)
+	if len(result.Skipped) > 0 {
+		fmt.Printf("Left %d existing file(s) alone; use --force to overwrite them.\n", len(result.Skipped))
+	}
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Edit the files for your project")
+	fmt.Println("  2. Check the config: multiclaude config validate")
+	fmt.Println("  3. Commit .multiclaude/ so everyone gets the same setup")
+	return nil
+}
+
+// findRepoRoot returns the nearest directory at or above dir that holds a
+// .git entry, or "" if there is none
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
+	// Build update args
+	updateArgs := map[string]interface{}{
+		"name": repoName,
+	}
+
+	// Parse and validate flags
+	if mqEnabled, ok := flags["mq-enabled"]; ok {
+		switch mqEnabled {
+		case "true":
+			updateArgs["mq_enabled"] = true
+		case "false":
+			updateArgs["mq_enabled"] = false
+		default:
+			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
+		}
+	}
+
+	if mqTrack, ok := flags["mq-track"]; ok {
+		switch mqTrack {
+		case "all", "author", "assigned":
+			updateArgs["mq_track_mode"] = mqTrack
+		default:
+			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
+		}
+	}
+
+	if mqTest, ok := flags["mq-test"]; ok {
+		if mqTest == "off" {
+			mqTest = ""
+		}
+		updateArgs["mq_test_command"] = mqTest
+	}
+
+	// Parse PR shepherd flags
+	if psEnabled, ok := flags["ps-enabled"]; ok {
+		switch psEnabled {
+		case "true":
+			updateArgs["ps_enabled"] = true
+		case "false":
+			updateArgs["ps_enabled"] = false
+		default:
+			return fmt.Errorf("invalid --ps-enabled value: %s (must be 'true' or 'false')", psEnabled)
+		}
+	}
+
+	if psTrack, ok := flags["ps-track"]; ok {
+		switch psTrack {
+		case "all", "author", "assigned":
+			updateArgs["ps_track_mode"] = psTrack
+		default:
+			return fmt.Errorf("invalid --ps-track value: %s (must be 'all', 'author', or 'assigned')", psTrack)
+		}
+	}
+
+	if branch, ok := flags["default-branch"]; ok {
+		if branch == "" || branch == "true" || strings.ContainsAny(branch, " \t~^:?*[\\") {
+			return fmt.Errorf("invalid --default-branch value: %q (must be a branch name)", branch)
+		}
+		updateArgs["target_branch"] = branch
+	}
+
+	if tmpl, ok := flags["branch-template"]; ok {
+		if tmpl == "default" {
+			tmpl = ""
+		} else if err := branchname.Validate(tmpl); err != nil {
+			return fmt.Errorf("invalid --branch-template value: %w", err)
+		}
+		updateArgs["branch_template"] = tmpl
+	}
+
+	// Parse notification flags
+	if notifyEnabled, ok := flags["notify-enabled"]; ok {
+		switch notifyEnabled {
+		case "true":
+			updateArgs["notify_enabled"] = true
+		case "false":
+			updateArgs["notify_enabled"] = false
+		default:
+			return fmt.Errorf("invalid --notify-enabled value: %s (must be 'true' or 'false')", notifyEnabled)
+		}
+	}
+
+	if notifyMethod, ok := flags["notify-method"]; ok {
+		if _, err := state.ParseNotifyMethod(notifyMethod); err != nil {
+			return fmt.Errorf("invalid --notify-method value: %s (must be 'sendmail' or 'smtp')", notifyMethod)
+		}
+		updateArgs["notify_method"] = notifyMethod
+	}
+
+	if notifyTo, ok := flags["notify-to"]; ok {
+		var recipients []interface{}
+		for _, addr := range strings.Split(notifyTo, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				recipients = append(recipients, addr)
+			}
+		}
+		updateArgs["notify_to"] = recipients
+	}
+
+	if notifyFrom, ok := flags["notify-from"]; ok {
+		updateArgs["notify_from"] = notifyFrom
+	}
+
+	if smtpAddr, ok := flags["notify-smtp"]; ok {
+		updateArgs["notify_smtp_addr"] = smtpAddr
+	}
+
+	if smtpUser, ok := flags["notify-smtp-user"]; ok {
+		updateArgs["notify_smtp_username"] = smtpUser
+	}
+
+	if digest, ok := flags["notify-digest"]; ok {
+		minutes, err := strconv.Atoi(digest)
+		if err != nil || minutes < 0 {
+			return fmt.Errorf("invalid --notify-digest value: %s (must be a number of minutes, 0 to disable)", digest)
+		}
+		updateArgs["notify_digest_minutes"] = minutes
+	}
+
+	if human, ok := flags["notify-human"]; ok {
+		notifiers := []string{}
+		if human != "off" {
+			for _, name := range strings.Split(human, ",") {
+				name = strings.TrimSpace(name)
+				if _, err := state.ParseHumanNotifier(name); err != nil {
+					return fmt.Errorf("invalid --notify-human value: %s (must be 'off' or a list of tmux, desktop and webhook)", human)
+				}
+				notifiers = append(notifiers, name)
+			}
+		}
+		updateArgs["notify_needs_human"] = notifiers
+	}
+
+	if webhook, ok := flags["notify-webhook"]; ok {
+		if webhook == "off" {
+			webhook = ""
+		}
+		updateArgs["notify_webhook"] = webhook
+	}
+
+	// Parse federation flags
+	if relay, ok := flags["federation"]; ok {
+		switch {
+		case relay == "off" || relay == "false":
+			updateArgs["federation_enabled"] = false
+		case relay == federation.RelayGit:
+			updateArgs["federation_enabled"] = true
+			updateArgs["federation_relay"] = relay
+		case filepath.IsAbs(relay):
+			updateArgs["federation_enabled"] = true
+			updateArgs["federation_relay"] = filepath.Clean(relay)
+		default:
+			return fmt.Errorf("invalid --federation value: %s (must be 'off', 'git', or an absolute directory path)", relay)
+		}
+	}
+
+	if branch, ok := flags["federation-branch"]; ok {
+		if branch == "default" {
+			branch = ""
+		}
+		updateArgs["federation_branch"] = branch
+	}
+
+	if peerID, ok := flags["federation-peer"]; ok {
+		if peerID == "default" {
+			peerID = ""
+		} else if err := federation.ValidatePeerID(peerID); err != nil {
+			return fmt.Errorf("invalid --federation-peer value: %w", err)
+		}
+		updateArgs["federation_peer_id"] = peerID
+	}
+
+	// Parse zombie detection flags
+	if zombieFlag, ok := flags["zombie"]; ok {
+		switch zombieFlag {
+		case "on", "true":
+			updateArgs["zombie_enabled"] = true
+		case "off", "false":
+			updateArgs["zombie_enabled"] = false
+		default:
+			return fmt.Errorf("invalid --zombie value: %s (must be 'on' or 'off')", zombieFlag)
+		}
+	}
+
+	if stall, ok := flags["zombie-stall"]; ok {
+		minutes, err := strconv.Atoi(stall)
+		if err != nil || minutes < 0 {
+			return fmt.Errorf("invalid --zombie-stall value: %s (must be a number of minutes, 0 for the default)", stall)
+		}
+		updateArgs["zombie_stall_minutes"] = minutes
+	}
+
+	for _, kind := range []string{"stalled", "looping", "prompt"} {
+		value, ok := flags["zombie-"+kind]
+		if !ok {
+			continue
+		}
+		if value == "default" {
+			value = ""
+		} else if _, err := state.ParseZombieAction(value); err != nil {
+			return fmt.Errorf("invalid --zombie-%s value: %s (must be 'nudge', 'restart', 'escalate', 'ignore' or 'default')", kind, value)
+		}
+		updateArgs["zombie_"+kind] = value
+	}
+
+	// Parse worktree setup flags
+	for _, step := range []string{"lfs", "submodules", "mirror"} {
+		value, ok := flags["worktree-"+step]
+		if !ok {
+			continue
+		}
+		switch value {
+		case "true":
+			updateArgs["worktree_"+step] = true
+		case "false":
+			updateArgs["worktree_"+step] = false
+		default:
+			return fmt.Errorf("invalid --worktree-%s value: %s (must be 'true' or 'false')", step, value)
+		}
+	}
+	if value, ok := flags["worktree-dir"]; ok {
+		dir, err := worktreeBaseArg(value)
+		if err != nil {
+			return err
+		}
+		updateArgs["worktree_dir"] = dir
+	}
+
+	// Parse worktree refresh flags
+	if value, ok := flags["refresh"]; ok {
+		if _, err := state.ParseRefreshStrategy(value); err != nil {
+			return fmt.Errorf("invalid --refresh value: %s (must be 'rebase', 'merge', 'fetch' or 'off')", value)
+		}
+		updateArgs["refresh_strategy"] = value
+	}
+	for _, option := range []string{"only-clean", "pause-active"} {
+		value, ok := flags["refresh-"+option]
+		if !ok {
+			continue
+		}
+		key := "refresh_" + strings.ReplaceAll(option, "-", "_")
+		switch value {
+		case "true":
+			updateArgs[key] = true
+		case "false":
+			updateArgs[key] = false
+		default:
+			return fmt.Errorf("invalid --refresh-%s value: %s (must be 'true' or 'false')", option, value)
+		}
+	}
+
+	// Parse roster flag
+	if value, ok := flags["roster"]; ok {
+		if _, err := state.ParseRosterMode(value); err != nil {
+			return fmt.Errorf("invalid --roster value: %s (must be 'prompt', 'file' or 'off')", value)
+		}
+		updateArgs["roster"] = value
+	}
+
+	// Parse draft PRs flag
+	if value, ok := flags["draft-prs"]; ok {
+		switch value {
+		case "true":
+			updateArgs["draft_prs"] = true
+		case "false":
+			updateArgs["draft_prs"] = false
+		default:
+			return fmt.Errorf("invalid --draft-prs value: %s (must be 'true' or 'false')", value)
+		}
+	}
+
+	// Parse PR description flags; "default" is the built-in template
+	if value, ok := flags["pr-descriptions"]; ok {
+		switch value {
+		case "true":
+			updateArgs["pr_descriptions"] = true
+		case "false":
+			updateArgs["pr_descriptions"] = false
+		default:
+			return fmt.Errorf("invalid --pr-descriptions value: %s (must be 'true' or 'false')", value)
+		}
+	}
+	if value, ok := flags["pr-template"]; ok {
+		if value == "default" {
+			value = ""
+		}
+		updateArgs["pr_template"] = value
+	}
+
+	// Parse push check flags; "off" allows any commit message
+	for flag, key := range map[string]string{"push-rebased": "push_rebased", "push-signed": "push_signed"} {
+		if value, ok := flags[flag]; ok {
+			switch value {
+			case "true":
+				updateArgs[key] = true
+			case "false":
+				updateArgs[key] = false
+			default:
+				return fmt.Errorf("invalid --%s value: %s (must be 'true' or 'false')", flag, value)
+			}
+		}
+	}
+	if value, ok := flags["push-message"]; ok {
+		if value == "off" {
+			value = ""
+		}
+		updateArgs["push_message_pattern"] = value
+	}
+
+	// Parse review dispatch flag
+	if value, ok := flags["review-dispatch"]; ok {
+		if _, err := state.ParseReviewDispatch(value); err != nil {
+			return fmt.Errorf("invalid --review-dispatch value: %s (must be 'round-robin' or 'least-loaded')", value)
+		}
+		updateArgs["review_dispatch"] = value
+	}
+
+	// Parse the worker limit; "off" means no limit
+	if value, ok := flags["max-workers"]; ok {
+		limit := 0
+		if value != "off" {
+			var err error
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				return fmt.Errorf("invalid --max-workers value: %s (must be a positive number, or 'off')", value)
+			}
+		}
+		updateArgs["max_workers"] = limit
+	}
+
+	// Parse the Claude config directory; "default" is ~/.claude
+	if value, ok := flags["claude-config-dir"]; ok {
+		dir, err := dirArg("claude-config-dir", value)
+		if err != nil {
+			return err
+		}
+		updateArgs["claude_config_dir"] = dir
+	}
+
+	// Parse the comment command allowlist; "off" empties it
+	if value, ok := flags["comment-commands"]; ok {
+		allow := []interface{}{}
+		if value != "off" {
+			for _, login := range strings.Split(value, ",") {
+				if login = strings.TrimPrefix(strings.TrimSpace(login), "@"); login != "" {
+					allow = append(allow, login)
+				}
+			}
+		}
+		updateArgs["comment_commands_allow"] = allow
+	}
+
+	// Parse spawn hook flags; "off" removes a hook
+	for flag, key := range map[string]string{"pre-spawn": "pre_spawn", "post-spawn": "post_spawn"} {
+		if value, ok := flags[flag]; ok {
+			if value == "off" {
+				value = ""
+			}
+			updateArgs[key] = value
+		}
+	}
+
+	// Parse prompt budget flags: --prompt-budget is the total, the others limit one section
+	for flag, key := range promptBudgetFlags {
+		value, ok := flags[flag]
+		if !ok {
+			continue
+		}
+		if value == "default" {
+			value = "0"
+		}
+		tokens, err := strconv.Atoi(value)
+		if err != nil || tokens < 0 {
+			return fmt.Errorf("invalid --%s value: %s (must be a number of tokens, or 'default')", flag, value)
+		}
+		updateArgs[key] = tokens
+	}
+
+	// Parse work hours flags; "off" works around the clock again
+	if value, ok := flags["work-hours"]; ok {
+		start, end := "", ""
+		if value != "off" {
+			var err error
+			if start, end, err = workhours.ParseHours(value); err != nil {
+				return fmt.Errorf("invalid --work-hours value: %w", err)
+			}
+		}
+		updateArgs["work_hours_start"] = start
+		updateArgs["work_hours_end"] = end
+	}
+	if value, ok := flags["work-days"]; ok {
+		days := []string{}
+		if value != "all" {
+			parsed, err := workhours.ParseDays(value)
+			if err != nil {
+				return fmt.Errorf("invalid --work-days value: %w", err)
+			}
+			days = parsed
+		}
+		updateArgs["work_hours_days"] = days
+	}
+	if value, ok := flags["timezone"]; ok {
+		if value == "local" {
+			value = ""
+		}
+		updateArgs["work_hours_timezone"] = value
+	}
+
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "update_repo_config",
+		Args:    updateArgs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+	}
+
+	if !resp.Success {
+		return daemonError("failed to update repo config", resp)
+	}
+
+	fmt.Printf("Configuration updated for repository: %s\n", repoName)
+
+	// Show the updated config
+	return c.showRepoConfig(repoName)
+}
+
+func (c *CLI) createWorker(ctx CommandContext, args []string) error {
+	// With no arguments in a terminal, ask for what the flags would say
+	if len(args) == 0 && isTerminal(os.Stdin) {
+		return c.workerWizard(ctx, os.Stdin, os.Stdout)
+	}
+
+	// --criteria may be repeated, so pull it out before parsing the other flags
+	criteria, args, err := cutRepeatedFlag(args, "criteria")
+	if err != nil {
+		return err
+	}
+	flags, posArgs := ParseFlags(args)
+
+	// Get task description
+	task := strings.Join(posArgs, " ")
+	if task == "" && flags["retry-of"] == "" && flags["undelete"] == "" {
+		return errors.InvalidUsage("usage: multiclaude worker create <task description>")
+	}
+	if ctx.Repo == "" {
+		return errors.NotInRepo()
+	}
+	repoName := ctx.Repo
+
+	// Outside work hours the daemon isn't spawning workers, and neither is this
+	if err := c.checkWorkHours(repoName); err != nil {
+		return err
+	}
+
+	// A task that was already attempted is usually better retried with what
+	// the last worker learned than handed out again blind
+	if flags["retry-of"] == "" && flags["undelete"] == "" && flags["new"] != "true" {
+		entry, err := c.checkDuplicateTask(repoName, task)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			flags["retry-of"] = entry.ID()
+		}
+	}
+
+	// --retry-of re-runs a task from the history, briefing the worker on the previous attempt
+	var retryOf, previousAttempt string
+	if ref := flags["retry-of"]; ref != "" {
+		entry, err := c.findTaskHistory(repoName, ref)
+		if err != nil {
+			return err
+		}
+		if task == "" {
+			task = entry.Task
+		}
+		retryOf = entry.ID()
+		previousAttempt = c.previousAttemptContext(repoName, entry)
+		if len(criteria) == 0 && flags["criteria-file"] == "" && flags["criteria-issue"] == "" {
+			for _, criterion := range entry.Criteria {
+				criteria = append(criteria, criterion.Text)
+			}
+		}
+	}
+
+	// --undelete restores a removed worker from the trash with its name, task
+	// and branch, re-applying the changes it hadn't committed
+	var tomb *trash.Tombstone
+	if name := flags["undelete"]; name != "" {
+		tomb, err = c.loadTombstone(repoName, name)
+		if err != nil {
+			return err
+		}
+		task, retryOf = tomb.Agent.Task, tomb.Agent.RetryOf
+		for _, criterion := range tomb.Agent.Criteria {
+			criteria = append(criteria, criterion.Text)
+		}
+		previousAttempt = restoredWorkerContext(tomb)
+		flags["name"] = tomb.Name
+		if flags["base"] == "" {
+			flags["base"] = tomb.Agent.BaseBranch
+		}
+	}
+
+	// --base puts the worker on another branch than the default, such as a
+	// release branch for a hotfix: it starts from the base, its PR targets
+	// it and the daemon keeps it rebased onto it
+	base := strings.TrimPrefix(flags["base"], "origin/")
+
+	// Acceptance criteria can also come from a checklist file or GitHub issue
+	if path := flags["criteria-file"]; path != "" {
+		data, err := os.ReadFile(localPath(path))
+		if err != nil {
+			return errors.Wrap(errors.CategoryConfig, "failed to read criteria file", err)
+		}
+		criteria = append(criteria, parseCriteria(string(data))...)
+	}
+	if issue := flags["criteria-issue"]; issue != "" {
+		issueCriteria, err := c.issueCriteria(repoName, issue)
+		if err != nil {
+			return err
+		}
+		criteria = append(criteria, issueCriteria...)
+	}
+
+	// --capability routes the task to the agent definition that declares it;
+	// --definition names the definition directly
+	definition := "worker"
+	if name := flags["definition"]; name != "" {
+		if flags["capability"] != "" {
+			return errors.InvalidUsage("use --definition or --capability, not both")
+		}
+		def, err := c.namedDefinition(repoName, name)
+		if err != nil {
+			return err
+		}
+		definition = def.Name
+	}
+	if capability := flags["capability"]; capability != "" {
+		def, err := c.capableDefinition(repoName, capability)
+		if err != nil {
+			return err
+		}
+		definition = def.Name
+	}
+	if err := c.checkAgentQuota(repoName, state.AgentTypeWorker, definition); err != nil {
+		return err
+	}
+
+	// Generate worker name (Docker-style)
+	workerName := names.Generate()
+	if name, ok := flags["name"]; ok {
+		workerName = name
+	}
+
+	// Check for --push-to flag (for iterating on existing PRs)
+	pushTo, hasPushTo := flags["push-to"]
+	if hasPushTo {
+		// --push-to requires --branch to specify the remote branch to start from
+		if _, hasBranch := flags["branch"]; !hasBranch {
+			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/work/jolly-hawk --push-to work/jolly-hawk)")
+		}
+	}
+
+	// Get repository path
+	repoPath := c.paths.RepoDir(repoName)
+
+	// Fetch latest from origin before creating worktree
+	// This ensures workers start from the latest code, not stale local refs
+	// Note: We fetch without a refspec (not "main:main") because the latter
+	// fails when the default branch is checked out in the main clone with:
+	// "fatal: refusing to fetch into branch 'refs/heads/main' checked out at ..."
+	fmt.Println("Fetching latest from origin...")
+	if _, err := retry.GitRemote.CombinedOutput(context.Background(), func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "git", "fetch", "origin")
+		cmd.Dir = repoPath
+		return cmd
+	}); err != nil {
+		// Best effort - don't fail if offline or fetch fails
+		fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
+	}
+
+	// Determine branch to start from
+	// Prefer origin/<default branch> if it exists (updated by fetch), otherwise fall back to HEAD
+	// This handles both normal repos and test repos without remotes
+	startBranch := "HEAD"
+	originDefault := "origin/" + c.repoDefaultBranch(repoName)
+	if _, err := gitcmd.Run(context.Background(), repoPath, "rev-parse", "--verify", originDefault); err == nil {
+		startBranch = originDefault
+	}
+	if base != "" {
+		if startBranch, err = baseStartPoint(repoPath, base); err != nil {
+			return err
+		}
+	}
+	if tomb != nil {
+		fmt.Printf("Restoring worker '%s' in repo '%s' on branch '%s'\n", workerName, repoName, tomb.Branch)
+	} else if branch, ok := flags["branch"]; ok {
+		startBranch = branch
+		if hasPushTo {
+			fmt.Printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'\n", workerName, repoName, pushTo)
+		} else {
+			fmt.Printf("Creating worker '%s' in repo '%s' from branch '%s'\n", workerName, repoName, branch)
+		}
+	} else if base != "" {
+		fmt.Printf("Creating worker '%s' in repo '%s' on base branch '%s'\n", workerName, repoName, base)
+	} else {
+		fmt.Printf("Creating worker '%s' in repo '%s'\n", workerName, repoName)
+	}
+	fmt.Printf("Task: %s\n", task)
+	if definition != "worker" {
+		fmt.Printf("Definition: %s\n", definition)
+	}
+	for i, criterion := range criteria {
+		fmt.Printf("  %d. %s\n", i+1, criterion)
+	}
+
+	// Warn when a teammate's daemon already has a worker on something similar
+	if self, peers, err := c.federationPeers(repoName); err == nil {
+		for _, s := range federation.FindSimilar(task, peers, self) {
+			fmt.Printf("Warning: %s already has worker '%s' on a similar task: %s\n", s.Peer, s.Worker.Name, format.Truncate(s.Worker.Task, 80))
+		}
+	}
+
+	// Create worktree
+	wt := c.worktreeManager(repoName)
+	wtPath := c.paths.AgentWorktree(repoName, workerName)
+
+	var branchName string
+	if tomb != nil {
+		// The branch is kept in the trash, but may have been deleted since
+		branchName = tomb.Branch
+		branchExists, err := wt.BranchExists(branchName)
+		if err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+		fmt.Printf("Creating worktree at: %s\n", wtPath)
+		if branchExists {
+			err = wt.Create(wtPath, branchName)
+		} else {
+			fmt.Printf("Branch '%s' is gone, recreating it at %s\n", branchName, tomb.Head)
+			err = wt.CreateNewBranch(wtPath, branchName, tomb.Head)
+		}
+		if err := worktreeSetupWarning(err); err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+		if err := tomb.Restore(wtPath); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	} else if hasPushTo {
+		// When --push-to is specified, we're iterating on an existing PR branch
+		// Create a worktree that checks out the remote branch into a local branch
+		branchName = pushTo
+		fmt.Printf("Creating worktree at: %s (checking out %s)\n", wtPath, startBranch)
+
+		// Check if the local branch already exists
+		branchExists, err := wt.BranchExists(branchName)
+		if err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+
+		if branchExists {
+			// Branch exists locally, check it out
+			if err := worktreeSetupWarning(wt.Create(wtPath, branchName)); err != nil {
+				return errors.WorktreeCreationFailed(err)
+			}
+		} else {
+			// Branch doesn't exist, create it from the start point
+			if err := worktreeSetupWarning(wt.CreateNewBranch(wtPath, branchName, startBranch)); err != nil {
+				return errors.WorktreeCreationFailed(err)
+			}
+		}
+	} else {
+		// Normal case: create a new branch for this worker, named by the repo's template
+		branchName, err = branchname.Render(c.repoBranchTemplate(repoName), branchname.Vars{Agent: workerName, Task: task})
+		if err != nil {
+			return errors.Wrap(errors.CategoryConfig, "failed to name worker branch", err)
+		}
+		fmt.Printf("Creating worktree at: %s\n", wtPath)
+		if err := worktreeSetupWarning(wt.CreateNewBranch(wtPath, branchName, startBranch)); err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+	}
+	c.tracef("created worktree %s on %s from %s for worker %s/%s", wtPath, branchName, startBranch, repoName, workerName)
+
+	// Get repository info to determine tmux session
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting repo info", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to get repo info", resp)
+	}
+
+	// The daemon runs the repository's pre_spawn hook, if any, in the new worktree
+	resp, err = client.Send(socket.Request{
+		Command: "run_spawn_hook",
+		Args: map[string]interface{}{
+			"repo":          repoName,
+			"agent":         workerName,
+			"hook":          "pre_spawn",
+			"worktree_path": wtPath,
+			"branch":        branchName,
+			"task":          task,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("running the pre_spawn hook", err)
+	}
+	if !resp.Success {
+		if err := wt.Remove(wtPath, true); err != nil {
+			fmt.Printf("Warning: failed to remove worktree %s: %v\n", wtPath, err)
+		}
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("worker not started: %s", resp.Error)).
+			WithSuggestion(fmt.Sprintf("fix the hook (multiclaude config %s) and see the daemon log for its output", repoName))
+	}
+
+	// Get tmux session name (it's mc-<reponame>)
+	tmuxSession := sanitizeTmuxSessionName(repoName)
+
+	// Ensure tmux session exists before creating window
+	// This handles cases where the session was killed or daemon didn't restore it
+	tmuxClient := tmux.NewClient()
+	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
+	if err != nil {
+		return errors.TmuxOperationFailed("check session", err)
+	}
+	if !hasSession {
+		fmt.Printf("Tmux session '%s' not found, creating it...\n", tmuxSession)
+		if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+			return errors.TmuxOperationFailed("create session", err)
+		}
+	}
+
+	// Create tmux window for worker (detached so it doesn't switch focus)
+	fmt.Printf("Creating tmux window: %s\n", workerName)
+	if _, err := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", workerName, "-c", wtPath); err != nil {
+		return errors.TmuxOperationFailed("create window", err)
+	}
+	c.tracef("created tmux window %s:%s", tmuxSession, workerName)
+
+	// Generate session ID for worker
+	workerSessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate worker session ID: %w", err)
+	}
+
+	// Get fork config and draft PR mode from daemon to include in worker prompt
+	var forkConfig state.ForkConfig
+	draftPRs, prDescriptions := false, false
+	var pushChecks state.PushChecks
+	configResp, err := client.Send(socket.Request{
+		Command: "get_repo_config",
+		Args: map[string]interface{}{
+			"name": repoName,
+		},
+	})
+	if err == nil && configResp.Success {
+		if configMap, ok := configResp.Data.(map[string]interface{}); ok {
+			if isFork, ok := configMap["is_fork"].(bool); ok && isFork {
+				forkConfig.IsFork = true
+				forkConfig.UpstreamURL, _ = configMap["upstream_url"].(string)
+				forkConfig.UpstreamOwner, _ = configMap["upstream_owner"].(string)
+				forkConfig.UpstreamRepo, _ = configMap["upstream_repo"].(string)
+			}
+			draftPRs, _ = configMap["draft_prs"].(bool)
+			prDescriptions, _ = configMap["pr_descriptions"].(bool)
+			pushChecks.Rebased, _ = configMap["push_rebased"].(bool)
+			pushChecks.Signed, _ = configMap["push_signed"].(bool)
+			pushChecks.MessagePattern, _ = configMap["push_message_pattern"].(string)
+		}
+	}
+
+	// Without gh, the worker can't open its PR and shouldn't try
+	githubProblem := ""
+	if statusResp, err := client.Send(socket.Request{Command: "status"}); err == nil && statusResp.Success {
+		if statusMap, ok := statusResp.Data.(map[string]interface{}); ok {
+			if github, ok := statusMap["github"].(map[string]interface{}); ok {
+				githubProblem, _ = github["problem"].(string)
+			}
+		}
+	}
+
+	// Write prompt file for worker (with push-to config and fork config if applicable)
+	workerConfig := WorkerConfig{
+		ForkConfig:      forkConfig,
+		DraftPRs:        draftPRs,
+		PRDescriptions:  prDescriptions,
+		PushChecks:      pushChecks,
+		GitHubProblem:   githubProblem,
+		PreviousAttempt: previousAttempt,
+		Criteria:        criteria,
+		Definition:      definition,
+		BaseBranch:      base,
+	}
+	if hasPushTo {
+		workerConfig.PushToBranch = pushTo
+	}
+	workerPromptFile, capabilities, err := c.writeWorkerPromptFile(repoPath, workerName, workerConfig)
+	if err != nil {
+		return fmt.Errorf("failed to write worker prompt: %w", err)
+	}
+
+	// Copy hooks configuration if it exists
+	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
+		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	}
+
+	// Start Claude in worker window with initial task (skip in test mode)
+	var workerPID int
+	if !simagent.Skip() {
+		// Resolve claude binary
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code in worker window...")
+		initialMessage := fmt.Sprintf("Task: %s", task)
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workerName, wtPath, workerSessionID, workerPromptFile, repoName, initialMessage)
+		if err != nil {
+			return fmt.Errorf("failed to start worker Claude: %w", err)
+		}
+		workerPID = pid
+		c.tracef("started claude in %s:%s (pid %d, session %s)", tmuxSession, workerName, pid, workerSessionID)
+
+		// Set up output capture for worker
+		if err := c.setupOutputCapture(tmuxSession, workerName, repoName, workerName, "worker"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for worker: %v\n", err)
+		}
+	}
+
+	// Register worker with daemon
+	resp, err = client.Send(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          repoName,
+			"agent":         workerName,
+			"type":          "worker",
+			"worktree_path": wtPath,
+			"tmux_window":   workerName,
+			"task":          task,
+			"branch":        branchName,
+			"retry_of":      retryOf,
+			"criteria":      criteria,
+			"capabilities":  capabilities,
+			"definition":    definition,
+			"base_branch":   base,
+			"session_id":    workerSessionID,
+			"pid":           workerPID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+	if !resp.Success {
+		c.abandonAgent(repoName, tmuxSession, workerName, wtPath)
+		return daemonError("failed to register worker", resp)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Worker created successfully!")
+	fmt.Printf("  Name: %s\n", workerName)
+	fmt.Printf("  Branch: %s\n", branchName)
+	if base != "" {
+		fmt.Printf("  Base: %s\n", base)
+	}
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	if hasPushTo {
+		fmt.Printf("  Mode: Push to existing PR branch (%s)\n", pushTo)
+	}
+	if retryOf != "" {
+		fmt.Printf("  Retry of: %s\n", retryOf)
+	}
+	if len(criteria) > 0 {
+		fmt.Printf("  Acceptance criteria: %d\n", len(criteria))
+	}
+	if tomb != nil {
+		if err := trash.Remove(c.paths, repoName, tomb.Name); err != nil {
+			fmt.Printf("Warning: failed to remove worker from the trash: %v\n", err)
+		}
+		fmt.Println("  Restored from the trash")
+	}
+	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
+
+	return nil
+}
+
+// baseStartPoint returns where a worker on base starts from: the remote's
+// copy of the branch if there is one, else the local branch
+func baseStartPoint(repoPath, base string) (string, error) {
+	for _, ref := range []string{"origin/" + base, base} {
+		if _, err := gitcmd.Run(context.Background(), repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			return ref, nil
+		}
+	}
+	return "", errors.New(errors.CategoryNotFound, fmt.Sprintf("base branch '%s' not found on origin or locally", base)).
+		WithSuggestion("check the name with: git branch -r")
+}
+
+// cutRepeatedFlag removes every --<name> <value> and --<name>=<value> from
+// args, returning the values in order and the remaining args
+func cutRepeatedFlag(args []string, name string) (values, rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			values = append(values, value)
+			continue
+		}
+		if arg == "--"+name {
+			if i+1 >= len(args) {
+				return nil, nil, errors.InvalidUsage(fmt.Sprintf("--%s requires a value", name))
+			}
+			values = append(values, args[i+1])
+			i++
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return values, rest, nil
+}
+
+// checklistItemRe matches a markdown checklist item: "- [ ] text" or "* [x] text"
+var checklistItemRe = regexp.MustCompile(`^\s*[-*+]\s+\[[ xX]\]\s+(.+)$`)
+
+// listItemRe strips list markers ("- ", "* ", "1. ") from a line
+var listItemRe = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+
+// parseCriteria extracts acceptance criteria from text. If the text contains a
+// markdown checklist, its items are the criteria; otherwise every non-empty,
+// non-heading line is one.
+func parseCriteria(text string) []string {
+	lines := strings.Split(text, "\n")
+	if items := checklistItems(lines); len(items) > 0 {
+		return items
+	}
+	var criteria []string
+	for _, line := range lines {
+		line = strings.TrimSpace(listItemRe.ReplaceAllString(line, ""))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		criteria = append(criteria, line)
+	}
+	return criteria
+}
+
+func checklistItems(lines []string) []string {
+	var items []string
+	for _, line := range lines {
+		if m := checklistItemRe.FindStringSubmatch(line); m != nil {
+			items = append(items, strings.TrimSpace(m[1]))
+		}
+	}
+	return items
+}
+
+// issueCriteria reads acceptance criteria from the checklist in a GitHub issue
+func (c *CLI) issueCriteria(repoName, issue string) ([]string, error) {
+	issue = strings.TrimPrefix(issue, "#")
+	output, err := retry.GitHub.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "gh", "issue", "view", issue, "--json", "body", "--jq", ".body")
+		cmd.Dir = c.paths.RepoDir(repoName)
+		return cmd
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to read issue #%s", issue), err).
+			WithSuggestion("check that gh is installed and authenticated: gh auth status")
+	}
+	items := checklistItems(strings.Split(string(output), "\n"))
+	if len(items) == 0 {
+		return nil, errors.New(errors.CategoryUsage, fmt.Sprintf("issue #%s has no checklist items to use as acceptance criteria", issue)).
+			WithSuggestion("add \"- [ ] <criterion>\" lines to the issue, or pass --criteria")
+	}
+	return items, nil
+}
+
+// retryWorker re-creates a worker for a task from the history
+// soloAgent starts an agent for a one-off task in the current directory: no
+// clone, supervisor or merge queue, just a tmux window, messaging and logs.
+// Solo agents started in the same directory share a solo repository, which
+// the daemon removes once its last agent is gone.
+func (c *CLI) soloAgent(flags *FlagSet) error {
+	task := strings.Join(flags.Args(), " ")
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude solo \"<task>\" [--name <name>] [--worktree]")
+	}
+
+	client := c.daemonClient()
+	if _, err := client.Send(socket.Request{Command: "ping"}); err != nil {
+		return errors.DaemonNotRunning()
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	// --worktree branches from the repository rather than working in it
+	useWorktree := flags.Bool("worktree")
+	if useWorktree {
+		toplevel, err := gitcmd.Run(context.Background(), dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return errors.New(errors.CategoryUsage, "--worktree needs a git repository").
+				WithSuggestion("run multiclaude solo without --worktree to work in this directory directly")
+		}
+		dir = toplevel
+	}
+
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repoName, exists := soloRepoName(st, dir)
+
+	agentName := names.Generate()
+	if flags.IsSet("name") {
+		agentName = flags.String("name")
+	}
+	if _, taken := st.GetAgent(repoName, agentName); taken {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("solo agent '%s' is already running in %s", agentName, dir)).
+			WithSuggestion("pick another name with --name")
+	}
+
+	fmt.Printf("Starting solo agent '%s' in %s\n", agentName, dir)
+	fmt.Printf("Task: %s\n", task)
+
+	workDir := dir
+	var branchName string
+	if useWorktree {
+		workDir = c.paths.AgentWorktree(repoName, agentName)
+		branchName = "solo/" + agentName
+		fmt.Printf("Creating worktree at: %s\n", workDir)
+		if err := worktreeSetupWarning(worktree.NewManager(dir).CreateNewBranch(workDir, branchName, "HEAD")); err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+		if err := hooks.CopyConfig(dir, workDir); err != nil {
+			fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+		}
+	}
+
+	// Solo agents in the same directory share a tmux session
+	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxClient := tmux.NewClient()
+	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
+	if err != nil {
+		return errors.TmuxOperationFailed("check session", err)
+	}
+	tmuxArgs := []string{"new-session", "-d", "-s", tmuxSession, "-n", agentName, "-c", workDir}
+	if hasSession {
+		tmuxArgs = []string{"new-window", "-d", "-t", tmuxSession, "-n", agentName, "-c", workDir}
+	}
+	if _, err := tmuxCommand(tmuxArgs...); err != nil {
+		return errors.TmuxOperationFailed("create window", err)
+	}
+
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate solo session ID: %w", err)
+	}
+
+	promptText, report, err := prompts.GetPromptWithBudget(workDir, state.AgentTypeSolo, c.cliDocsReference(repoName), c.repoPromptBudget(repoName))
+	if err != nil {
+		return fmt.Errorf("failed to get solo prompt: %w", err)
+	}
+	reportPromptTrim(report)
+	promptFile, err := c.savePromptToFile(agentName, prompts.ExpandTemplateVars(promptText, prompts.TemplateVars{}))
+	if err != nil {
+		return fmt.Errorf("failed to write solo prompt: %w", err)
+	}
+
+	// Start Claude with the task (skip in test mode)
+	var pid int
+	if !simagent.Skip() {
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code...")
+		pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, agentName, workDir, sessionID, promptFile, repoName, "Task: "+task)
+		if err != nil {
+			return fmt.Errorf("failed to start solo Claude: %w", err)
+		}
+
+		if err := c.setupOutputCapture(tmuxSession, agentName, repoName, agentName, "solo"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture: %v\n", err)
+		}
+	}
+
+	if !exists {
+		if _, err := c.sendDaemonRequest("add_repo", map[string]interface{}{
+			"name":         repoName,
+			"tmux_session": tmuxSession,
+			"solo":         true,
+			"path":         dir,
+		}); err != nil {
+			return err
+		}
+	}
+	if _, err := c.sendDaemonRequest("add_agent", map[string]interface{}{
+		"repo":          repoName,
+		"agent":         agentName,
+		"type":          string(state.AgentTypeSolo),
+		"worktree_path": workDir,
+		"tmux_window":   agentName,
+		"task":          task,
+		"branch":        branchName,
+		"session_id":    sessionID,
+		"pid":           pid,
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Solo agent started!")
+	fmt.Printf("  Name: %s\n", agentName)
+	fmt.Printf("  Directory: %s\n", workDir)
+	if branchName != "" {
+		fmt.Printf("  Branch: %s\n", branchName)
+	}
+	fmt.Printf("\nAttach: tmux attach -t %s:%s\n", tmuxSession, agentName)
+	fmt.Printf("Logs: multiclaude logs %s --repo %s\n", agentName, repoName)
+	return nil
+}
+
+// soloRepoName returns the solo repository for a directory and whether it
+// already exists. New ones are named after the directory (solo-<dir>), with a
+// number added when another directory has the same name.
+func soloRepoName(st *state.State, dir string) (string, bool) {
+	base := "solo-" + strings.Trim(soloNameRe.ReplaceAllString(strings.ToLower(filepath.Base(dir)), "-"), "-")
+	if base == "solo-" {
+		base = "solo-root"
+	}
+	repos := st.GetAllRepos()
+	name := base
+	for i := 2; ; i++ {
+		repo, ok := repos[name]
+		if !ok {
+			return name, false
+		}
+		if repo.Solo && repo.Path == dir {
+			return name, true
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// soloNameRe matches runs of characters not allowed in solo repository names
+var soloNameRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// soloAgentContext finds the solo agent running in the current tmux window.
+// Solo agents started without --worktree work in the user's own directory,
+// so the directory alone can't identify them.
+func (c *CLI) soloAgentContext(dir string) (repoName, agentName string, ok bool) {
+	if os.Getenv("TMUX_PANE") == "" {
+		return "", "", false
+	}
+	output, err := tmuxCommand("display-message", "-p", "#{session_name}\t#{window_name}")
+	if err != nil {
+		return "", "", false
+	}
+	session, window, _ := strings.Cut(strings.TrimSpace(string(output)), "\t")
+
+	st, err := c.loadState()
+	if err != nil {
+		return "", "", false
+	}
+	for name, repo := range st.GetAllRepos() {
+		if !repo.Solo || repo.TmuxSession != session {
+			continue
+		}
+		for agentName, agent := range repo.Agents {
+			if agent.TmuxWindow == window && hasPathPrefix(dir, agent.WorktreePath) {
+				return name, agentName, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// adoptableTypes are the agent types an existing session can be adopted as
+var adoptableTypes = []string{
+	string(state.AgentTypeWorker),
+	string(state.AgentTypeReview),
+	string(state.AgentTypeWorkspace),
+	string(state.AgentTypeGenericPersistent),
+	string(state.AgentTypeObserver),
+	string(state.AgentTypeSupervisor),
+	string(state.AgentTypeMergeQueue),
+	string(state.AgentTypePRShepherd),
+}
+
+// adoptAgent registers a Claude session the user started themselves as an
+// agent. The window stays where it is; when it lives in another tmux session
+// it is linked into the repository's session so messages and health checks
+// reach it. Adopted agents are never killed or have their directories removed.
+func (c *CLI) adoptAgent(ctx CommandContext, flags *FlagSet) error {
+	session, window := flags.String("session"), flags.String("window")
+	agentType := state.AgentType(flags.String("as"))
+
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(ctx.Repo)
+	if !exists {
+		return errors.RepoNotFound(ctx.Repo)
 	}
 
-	// Parse and validate flags
-	if mqEnabled, ok := flags["mq-enabled"]; ok {
-		switch mqEnabled {
-		case "true":
-			updateArgs["mq_enabled"] = true
-		case "false":
-			updateArgs["mq_enabled"] = false
-		default:
-			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
-		}
+	agentName := window
+	if flags.IsSet("name") {
+		agentName = flags.String("name")
+	}
+	if _, taken := st.GetAgent(ctx.Repo, agentName); taken {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("agent '%s' already exists in %s", agentName, ctx.Repo)).
+			WithSuggestion("pick another name with --name")
 	}
 
-	if mqTrack, ok := flags["mq-track"]; ok {
-		switch mqTrack {
-		case "all", "author", "assigned":
-			updateArgs["mq_track_mode"] = mqTrack
-		default:
-			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
+	tmuxCtx := context.Background()
+	tmuxClient := tmux.NewClient()
+	hasWindow, err := tmuxClient.HasWindow(tmuxCtx, session, window)
+	if err != nil || !hasWindow {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("tmux window '%s:%s' not found", session, window)).
+			WithSuggestion("list windows with: tmux list-windows -t " + session)
+	}
+	if session != repo.TmuxSession || agentName != window {
+		if taken, _ := tmuxClient.HasWindow(tmuxCtx, repo.TmuxSession, agentName); taken {
+			return errors.New(errors.CategoryUsage, fmt.Sprintf("session '%s' already has a window named '%s'", repo.TmuxSession, agentName)).
+				WithSuggestion("pick another name with --name")
 		}
 	}
 
-	// Parse PR shepherd flags
-	if psEnabled, ok := flags["ps-enabled"]; ok {
-		switch psEnabled {
-		case "true":
-			updateArgs["ps_enabled"] = true
-		case "false":
-			updateArgs["ps_enabled"] = false
-		default:
-			return fmt.Errorf("invalid --ps-enabled value: %s (must be 'true' or 'false')", psEnabled)
-		}
+	panePID, err := tmuxClient.GetPanePID(tmuxCtx, session, window)
+	if err != nil {
+		return errors.TmuxOperationFailed("get pane PID", err)
+	}
+	workDir, err := tmuxClient.GetPanePath(tmuxCtx, session, window)
+	if err != nil {
+		return errors.TmuxOperationFailed("get pane directory", err)
 	}
 
-	if psTrack, ok := flags["ps-track"]; ok {
-		switch psTrack {
-		case "all", "author", "assigned":
-			updateArgs["ps_track_mode"] = psTrack
-		default:
-			return fmt.Errorf("invalid --ps-track value: %s (must be 'all', 'author', or 'assigned')", psTrack)
+	// Prefer the session ID Claude was started with, then the newest
+	// session recorded for the directory
+	pid := panePID
+	var sessionID string
+	if claudePID, claudeArgs, err := claude.FindProcess(panePID); err == nil {
+		pid = claudePID
+		sessionID = claude.SessionIDFromArgs(claudeArgs)
+	}
+	if sessionID == "" {
+		if projectsDir, err := c.claudeProjectsDir(ctx.Repo); err == nil {
+			sessionID = claude.LatestSessionID(projectsDir, workDir)
 		}
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "update_repo_config",
-		Args:    updateArgs,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+	// Name the window after the agent, and make it reachable from the repo session
+	if agentName != window {
+		if err := tmuxClient.RenameWindow(tmuxCtx, session, window, agentName); err != nil {
+			return errors.TmuxOperationFailed("rename window", err)
+		}
+	}
+	if session != repo.TmuxSession {
+		if err := tmuxClient.LinkWindow(tmuxCtx, session, agentName, repo.TmuxSession); err != nil {
+			return errors.TmuxOperationFailed("link window", err)
+		}
 	}
 
-	if !resp.Success {
-		return fmt.Errorf("failed to update repo config: %s", resp.Error)
+	if err := c.setupOutputCapture(repo.TmuxSession, agentName, ctx.Repo, agentName, string(agentType)); err != nil {
+		fmt.Printf("Warning: failed to setup output capture: %v\n", err)
 	}
 
-	fmt.Printf("Configuration updated for repository: %s\n", repoName)
+	if _, err := c.sendDaemonRequest("add_agent", map[string]interface{}{
+		"repo":          ctx.Repo,
+		"agent":         agentName,
+		"type":          string(agentType),
+		"worktree_path": workDir,
+		"tmux_window":   agentName,
+		"task":          flags.String("task"),
+		"session_id":    sessionID,
+		"pid":           pid,
+		"adopted":       true,
+	}); err != nil {
+		return err
+	}
 
-	// Show the updated config
-	return c.showRepoConfig(repoName)
+	fmt.Printf("✓ Adopted %s:%s as %s '%s' in %s\n", session, window, agentType, agentName, ctx.Repo)
+	fmt.Printf("  Directory: %s\n", workDir)
+	fmt.Printf("  PID: %d\n", pid)
+	if sessionID != "" {
+		fmt.Printf("  Session ID: %s\n", sessionID)
+	} else {
+		fmt.Println("  Session ID: unknown (multiclaude won't be able to resume this session)")
+	}
+	if session != repo.TmuxSession {
+		fmt.Printf("  Linked into tmux session: %s\n", repo.TmuxSession)
+	}
+	return nil
 }
 
-func (c *CLI) createWorker(args []string) error {
+func (c *CLI) retryWorker(ctx CommandContext, args []string) error {
 	flags, posArgs := ParseFlags(args)
-
-	// Get task description
-	task := strings.Join(posArgs, " ")
-	if task == "" {
-		return errors.InvalidUsage("usage: multiclaude worker create <task description>")
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude worker retry <history-id|worker-name> [--repo <repo>]")
 	}
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+	entry, err := c.findTaskHistory(ctx.Repo, posArgs[0])
 	if err != nil {
-		return errors.NotInRepo()
+		return err
+	}
+	if entry.Task == "" {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("task %s has no recorded task description to retry", entry.ID()))
+	}
+	if entry.RetriedBy != "" {
+		fmt.Printf("Note: %s was already retried by %s\n", entry.ID(), entry.RetriedBy)
 	}
 
-	// Generate worker name (Docker-style)
-	workerName := names.Generate()
+	createArgs := []string{"--retry-of", entry.ID()}
 	if name, ok := flags["name"]; ok {
-		workerName = name
+		createArgs = append(createArgs, "--name", name)
 	}
+	return c.createWorker(ctx, createArgs)
+}
 
-	// Check for --push-to flag (for iterating on existing PRs)
-	pushTo, hasPushTo := flags["push-to"]
-	if hasPushTo {
-		// --push-to requires --branch to specify the remote branch to start from
-		if _, hasBranch := flags["branch"]; !hasBranch {
-			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/work/jolly-hawk --push-to work/jolly-hawk)")
+// setWorkerRefresh sets a worker's own worktree refresh config. With no
+// options it just shows the config in effect.
+func (c *CLI) setWorkerRefresh(ctx CommandContext, flags *FlagSet) error {
+	if len(flags.Args()) != 1 {
+		return errors.InvalidUsage("usage: multiclaude worker refresh <worker-name> [--strategy=rebase|merge|fetch|off] [--only-clean[=false]] [--pause-active[=false]] [--reset]")
+	}
+	args := map[string]interface{}{"repo": ctx.Repo, "agent": flags.Args()[0]}
+	if flags.IsSet("strategy") {
+		args["strategy"] = flags.String("strategy")
+	}
+	for _, option := range []string{"only-clean", "pause-active", "reset"} {
+		if flags.IsSet(option) {
+			args[strings.ReplaceAll(option, "-", "_")] = flags.Bool(option)
 		}
 	}
+	if len(args) == 2 {
+		// Nothing to change: show the config in effect from state
+		st, err := c.loadState()
+		if err != nil {
+			return err
+		}
+		agent, exists := st.GetAgent(ctx.Repo, flags.Args()[0])
+		if !exists {
+			return errors.AgentNotFound("worker", flags.Args()[0], ctx.Repo)
+		}
+		repo, _ := st.GetRepo(ctx.Repo)
+		cfg := repo.RefreshConfig
+		source := "repository config"
+		if agent.Refresh != nil {
+			cfg = *agent.Refresh
+			source = "worker override"
+		}
+		printRefreshConfig(flags.Args()[0], string(cfg.EffectiveStrategy()), cfg.OnlyClean, cfg.PauseActive, source)
+		return nil
+	}
 
-	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
-
-	// Fetch latest from origin before creating worktree
-	// This ensures workers start from the latest code, not stale local refs
-	// Note: We use "git fetch origin main" (not "main:main") because the latter
-	// fails when main is checked out in the bare repo with:
-	// "fatal: refusing to fetch into branch 'refs/heads/main' checked out at ..."
-	fmt.Println("Fetching latest from origin...")
-	fetchCmd := exec.Command("git", "fetch", "origin")
-	fetchCmd.Dir = repoPath
-	if err := fetchCmd.Run(); err != nil {
-		// Best effort - don't fail if offline or fetch fails
-		fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
+	resp, err := c.daemonClient().Send(socket.Request{Command: "set_agent_refresh", Args: args})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("setting the worker's refresh config", err)
 	}
+	if !resp.Success {
+		return daemonError("failed to set the worker's refresh config", resp)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	strategy, _ := data["strategy"].(string)
+	onlyClean, _ := data["only_clean"].(bool)
+	pauseActive, _ := data["pause_active"].(bool)
+	source := "repository config"
+	if override, _ := data["override"].(bool); override {
+		source = "worker override"
+	}
+	printRefreshConfig(flags.Args()[0], strategy, onlyClean, pauseActive, source)
+	return nil
+}
 
-	// Determine branch to start from
-	// Prefer origin/main if it exists (updated by fetch), otherwise fall back to HEAD
-	// This handles both normal repos and test repos without remotes
-	startBranch := "HEAD"
-	checkOriginCmd := exec.Command("git", "rev-parse", "--verify", "origin/main")
-	checkOriginCmd.Dir = repoPath
-	if err := checkOriginCmd.Run(); err == nil {
-		startBranch = "origin/main"
+// workerReady marks a worker's draft PR ready for review; the daemon tells the
+// merge queue, which leaves drafts alone
+func (c *CLI) workerReady(ctx CommandContext, flags *FlagSet) error {
+	if len(flags.Args()) != 1 {
+		return errors.InvalidUsage("usage: multiclaude worker ready <worker-name> [--repo <repo>]")
 	}
-	if branch, ok := flags["branch"]; ok {
-		startBranch = branch
-		if hasPushTo {
-			fmt.Printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'\n", workerName, repoName, pushTo)
-		} else {
-			fmt.Printf("Creating worker '%s' in repo '%s' from branch '%s'\n", workerName, repoName, branch)
-		}
+	resp, err := c.sendDaemonRequest("pr_ready", map[string]interface{}{"repo": ctx.Repo, "agent": flags.Args()[0]})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	number, _ := data["pr_number"].(float64)
+	url, _ := data["pr_url"].(string)
+	if wasDraft, _ := data["was_draft"].(bool); wasDraft {
+		fmt.Printf("%s PR #%d marked ready for review: %s\n", format.Green.Sprint("✓"), int(number), url)
 	} else {
-		fmt.Printf("Creating worker '%s' in repo '%s'\n", workerName, repoName)
+		fmt.Printf("PR #%d was already ready for review: %s\n", int(number), url)
 	}
-	fmt.Printf("Task: %s\n", task)
-
-	// Create worktree
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, workerName)
+	if notified, _ := data["notified"].(bool); notified {
+		fmt.Println("Merge queue notified")
+	} else {
+		fmt.Println(format.Dim.Sprint("No merge queue agent to notify"))
+	}
+	return nil
+}
 
-	var branchName string
-	if hasPushTo {
-		// When --push-to is specified, we're iterating on an existing PR branch
-		// Create a worktree that checks out the remote branch into a local branch
-		branchName = pushTo
-		fmt.Printf("Creating worktree at: %s (checking out %s)\n", wtPath, startBranch)
+// workerCheck runs a worker's push checks and lists what fails, with how to
+// fix it
+func (c *CLI) workerCheck(ctx CommandContext, flags *FlagSet) error {
+	if len(flags.Args()) != 1 {
+		return errors.InvalidUsage("usage: multiclaude worker check <worker-name> [--repo <repo>]")
+	}
+	resp, err := c.sendDaemonRequest("push_check", map[string]interface{}{"repo": ctx.Repo, "agent": flags.Args()[0]})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if enabled, _ := data["enabled"].(bool); !enabled {
+		fmt.Printf("%s has no push checks (set them with: multiclaude config %s --push-rebased=true)\n", ctx.Repo, ctx.Repo)
+		return nil
+	}
+	failures, _ := data["failures"].([]interface{})
+	if len(failures) == 0 {
+		fmt.Printf("%s Push checks passed\n", format.Green.Sprint("✓"))
+		return nil
+	}
 
-		// Check if the local branch already exists
-		branchExists, err := wt.BranchExists(branchName)
-		if err != nil {
-			return errors.WorktreeCreationFailed(err)
+	fixes := make(map[string]bool)
+	var fixList []string
+	for _, f := range failures {
+		failure, _ := f.(map[string]interface{})
+		check, _ := failure["check"].(string)
+		commit, _ := failure["commit"].(string)
+		message, _ := failure["message"].(string)
+		if commit != "" {
+			check += " " + commit
 		}
-
-		if branchExists {
-			// Branch exists locally, check it out
-			if err := wt.Create(wtPath, branchName); err != nil {
-				return errors.WorktreeCreationFailed(err)
-			}
-		} else {
-			// Branch doesn't exist, create it from the start point
-			if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-				return errors.WorktreeCreationFailed(err)
-			}
+		fmt.Printf("%s %s: %s\n", format.Red.Sprint("✗"), check, message)
+		if fix, _ := failure["fix"].(string); fix != "" && !fixes[fix] {
+			fixes[fix] = true
+			fixList = append(fixList, fix)
 		}
-	} else {
-		// Normal case: create a new branch for this worker
-		branchName = fmt.Sprintf("work/%s", workerName)
-		fmt.Printf("Creating worktree at: %s\n", wtPath)
-		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+	}
+	if len(fixList) > 0 {
+		fmt.Println("\nTo fix:")
+		for _, fix := range fixList {
+			fmt.Printf("  %s\n", fix)
 		}
 	}
+	return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d push check(s) failed", len(failures)))
+}
 
-	// Get repository info to determine tmux session
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
+// describeWorkerPR writes a worker's PR description now, or with --dry-run
+// prints it, whether or not the repository has PR descriptions on
+func (c *CLI) describeWorkerPR(ctx CommandContext, flags *FlagSet) error {
+	if len(flags.Args()) != 1 {
+		return errors.InvalidUsage("usage: multiclaude worker describe <worker-name> [--repo <repo>] [--dry-run]")
+	}
+	dryRun := flags.Bool("dry-run")
+	resp, err := c.sendDaemonRequest("describe_pr", map[string]interface{}{
+		"repo":    ctx.Repo,
+		"agent":   flags.Args()[0],
+		"dry_run": dryRun,
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting repo info", err)
+		return err
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+	data, _ := resp.Data.(map[string]interface{})
+	number, _ := data["pr_number"].(float64)
+	url, _ := data["pr_url"].(string)
+	body, _ := data["body"].(string)
+	if dryRun {
+		fmt.Print(body)
+		return nil
 	}
+	fmt.Printf("%s Wrote the description of PR #%d: %s\n", format.Green.Sprint("✓"), int(number), url)
+	if summarized, _ := data["summarized"].(bool); !summarized {
+		fmt.Println(format.Dim.Sprint("Written without a summary of the diff (see the daemon log)"))
+	}
+	return nil
+}
 
-	// Get tmux session name (it's mc-<reponame>)
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+// printRefreshConfig prints the worktree refresh config a worker runs with
+func printRefreshConfig(worker, strategy string, onlyClean, pauseActive bool, source string) {
+	fmt.Printf("Worktree refresh for %s (%s):\n", worker, source)
+	fmt.Printf("  Strategy: %s\n", strategy)
+	fmt.Printf("  Only when clean: %v\n", onlyClean)
+	fmt.Printf("  Pause while agent is active: %v\n", pauseActive)
+}
 
-	// Ensure tmux session exists before creating window
-	// This handles cases where the session was killed or daemon didn't restore it
-	tmuxClient := tmux.NewClient()
-	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
+// findTaskHistory looks up a task history entry by history ID or worker name
+func (c *CLI) findTaskHistory(repoName, ref string) (state.TaskHistoryEntry, error) {
+	st, err := c.loadState()
 	if err != nil {
-		return errors.TmuxOperationFailed("check session", err)
+		return state.TaskHistoryEntry{}, err
 	}
-	if !hasSession {
-		fmt.Printf("Tmux session '%s' not found, creating it...\n", tmuxSession)
-		if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
-			return errors.TmuxOperationFailed("create session", err)
-		}
+	entry, err := st.FindTaskHistory(repoName, ref)
+	if err != nil {
+		return state.TaskHistoryEntry{}, errors.New(errors.CategoryNotFound, err.Error()).
+			WithSuggestion(fmt.Sprintf("multiclaude history --repo %s", repoName))
 	}
+	return entry, nil
+}
 
-	// Create tmux window for worker (detached so it doesn't switch focus)
-	fmt.Printf("Creating tmux window: %s\n", workerName)
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return errors.TmuxOperationFailed("create window", err)
-	}
+// maxRetryDiffLines caps how much of a previous attempt's diff goes into a retry prompt
+const maxRetryDiffLines = 300
 
-	// Generate session ID for worker
-	workerSessionID, err := claude.GenerateSessionID()
-	if err != nil {
-		return fmt.Errorf("failed to generate worker session ID: %w", err)
-	}
+// maxRetryMessages is how many of a previous attempt's final messages a retry sees
+const maxRetryMessages = 5
 
-	// Get fork config from daemon to include in worker prompt
-	var forkConfig state.ForkConfig
-	configResp, err := client.Send(socket.Request{
-		Command: "get_repo_config",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
-	if err == nil && configResp.Success {
-		if configMap, ok := configResp.Data.(map[string]interface{}); ok {
-			if isFork, ok := configMap["is_fork"].(bool); ok && isFork {
-				forkConfig.IsFork = true
-				forkConfig.UpstreamURL, _ = configMap["upstream_url"].(string)
-				forkConfig.UpstreamOwner, _ = configMap["upstream_owner"].(string)
-				forkConfig.UpstreamRepo, _ = configMap["upstream_repo"].(string)
-			}
-		}
+// previousAttemptContext summarizes a previous attempt at a task for the worker
+// retrying it: outcome, failure reason, the diff it left on its branch and the
+// last messages it sent. Missing pieces (deleted branch, acked messages) are skipped.
+func (c *CLI) previousAttemptContext(repoName string, entry state.TaskHistoryEntry) string {
+	var sb strings.Builder
+	sb.WriteString("## Previous Attempt\n\n")
+	sb.WriteString("**This task was attempted before. Learn from that attempt instead of repeating it.**\n\n")
+	sb.WriteString(fmt.Sprintf("- Worker: %s (history ID %s)\n", entry.Name, entry.ID()))
+	if entry.Status != "" {
+		sb.WriteString(fmt.Sprintf("- Outcome: %s\n", entry.Status))
 	}
-
-	// Write prompt file for worker (with push-to config and fork config if applicable)
-	workerConfig := WorkerConfig{
-		ForkConfig: forkConfig,
+	if entry.Branch != "" {
+		sb.WriteString(fmt.Sprintf("- Branch: %s\n", entry.Branch))
 	}
-	if hasPushTo {
-		workerConfig.PushToBranch = pushTo
+	if entry.PRURL != "" {
+		sb.WriteString(fmt.Sprintf("- PR: %s\n", entry.PRURL))
 	}
-	workerPromptFile, err := c.writeWorkerPromptFile(repoPath, workerName, workerConfig)
-	if err != nil {
-		return fmt.Errorf("failed to write worker prompt: %w", err)
+	if entry.FailureReason != "" {
+		sb.WriteString(fmt.Sprintf("- Failure reason: %s\n", entry.FailureReason))
+	}
+	if entry.Summary != "" {
+		sb.WriteString(fmt.Sprintf("- Summary: %s\n", entry.Summary))
 	}
 
-	// Copy hooks configuration if it exists
-	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	if diff := c.previousAttemptDiff(repoName, entry.Branch); diff != "" {
+		sb.WriteString("\n### Changes on the previous branch\n\n```diff\n")
+		sb.WriteString(diff)
+		sb.WriteString("\n```\n")
 	}
 
-	// Start Claude in worker window with initial task (skip in test mode)
-	var workerPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
+	if msgs := c.previousAttemptMessages(repoName, entry.Name); len(msgs) > 0 {
+		sb.WriteString("\n### Final messages from the previous worker\n\n")
+		for _, msg := range msgs {
+			sb.WriteString(fmt.Sprintf("- to %s (%s): %s\n", msg.To, msg.Timestamp.Format(time.RFC3339), msg.Body))
 		}
+	}
 
-		fmt.Println("Starting Claude Code in worker window...")
-		initialMessage := fmt.Sprintf("Task: %s", task)
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workerName, wtPath, workerSessionID, workerPromptFile, repoName, initialMessage)
-		if err != nil {
-			return fmt.Errorf("failed to start worker Claude: %w", err)
-		}
-		workerPID = pid
+	sb.WriteString("\n---\n\n")
+	return sb.String()
+}
 
-		// Set up output capture for worker
-		if err := c.setupOutputCapture(tmuxSession, workerName, repoName, workerName, "worker"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for worker: %v\n", err)
+// previousAttemptDiff returns the changes a branch made relative to the default
+// branch, truncated to maxRetryDiffLines, or "" if the branch is gone
+func (c *CLI) previousAttemptDiff(repoName, branch string) string {
+	if branch == "" {
+		return ""
+	}
+	repoPath := c.paths.RepoDir(repoName)
+
+	ref := ""
+	for _, candidate := range []string{branch, "origin/" + branch} {
+		if _, err := gitcmd.Run(context.Background(), repoPath, "rev-parse", "--verify", "--quiet", candidate); err == nil {
+			ref = candidate
+			break
 		}
 	}
+	if ref == "" {
+		return ""
+	}
+
+	base := c.repoDefaultBranch(repoName)
+	if _, err := gitcmd.Run(context.Background(), repoPath, "rev-parse", "--verify", "--quiet", "origin/"+base); err == nil {
+		base = "origin/" + base
+	}
 
-	// Register worker with daemon
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workerName,
-			"type":          "worker",
-			"worktree_path": wtPath,
-			"tmux_window":   workerName,
-			"task":          task,
-			"session_id":    workerSessionID,
-			"pid":           workerPID,
-		},
-	})
+	output, err := gitcmd.Output(context.Background(), repoPath, "diff", "--stat", "--patch", base+"..."+ref)
 	if err != nil {
-		return fmt.Errorf("failed to register worker: %w", err)
+		return ""
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register worker: %s", resp.Error)
+
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > maxRetryDiffLines {
+		omitted := len(lines) - maxRetryDiffLines
+		lines = append(lines[:maxRetryDiffLines], fmt.Sprintf("... (%d more lines, see git diff %s...%s)", omitted, base, ref))
 	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
 
-	fmt.Println()
-	fmt.Println("✓ Worker created successfully!")
-	fmt.Printf("  Name: %s\n", workerName)
-	fmt.Printf("  Branch: %s\n", branchName)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	if hasPushTo {
-		fmt.Printf("  Mode: Push to existing PR branch (%s)\n", pushTo)
+// previousAttemptMessages returns the last messages a worker sent, oldest first
+func (c *CLI) previousAttemptMessages(repoName, workerName string) []*messages.Message {
+	all, err := messages.NewManager(c.paths.MessagesDir).ListAll()
+	if err != nil {
+		return nil
 	}
-	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
-	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
 
-	return nil
+	var sent []*messages.Message
+	for key, msgs := range all {
+		if !strings.HasPrefix(key, repoName+"/") {
+			continue
+		}
+		for _, msg := range msgs {
+			if msg.From == workerName {
+				sent = append(sent, msg)
+			}
+		}
+	}
+
+	sort.Slice(sent, func(i, j int) bool { return sent[i].Timestamp.Before(sent[j].Timestamp) })
+	if len(sent) > maxRetryMessages {
+		sent = sent[len(sent)-maxRetryMessages:]
+	}
+	return sent
 }
 
-func (c *CLI) listWorkers(args []string) error {
+func (c *CLI) listWorkers(ctx CommandContext, args []string) error {
 	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
+	if flags["all"] == "true" {
+		return c.listAllWorkers(flags["wide"] == "true")
+	}
+	if ctx.Repo == "" {
 		return errors.NotInRepo()
 	}
 
 	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
-		"repo": repoName,
-		"rich": true,
+		"repo":      ctx.Repo,
+		"rich":      true,
+		"pr_status": true,
 	})
 	if err != nil {
 		return err
@@ -2195,7 +5924,7 @@ func (c *CLI) listWorkers(args []string) error {
 
 	// Show workspace first if it exists
 	if workspace != nil {
-		format.Header("Workspace in '%s':", repoName)
+		format.Header("Workspace in '%s':", ctx.Repo)
 		status, _ := workspace["status"].(string)
 		statusCell := formatAgentStatusCell(status)
 		fmt.Printf("  workspace ")
@@ -2205,50 +5934,394 @@ func (c *CLI) listWorkers(args []string) error {
 	}
 
 	if len(workers) == 0 {
-		fmt.Printf("No workers in repository '%s'\n", repoName)
+		fmt.Printf("No workers in repository '%s'\n", ctx.Repo)
 		format.Dimmed("\nCreate a worker with: multiclaude worker create <task>")
 		return nil
 	}
 
-	format.Header("Workers in '%s' (%d):", repoName, len(workers))
+	format.Header("Workers in '%s' (%d):", ctx.Repo, len(workers))
+	fmt.Println()
+
+	// --wide adds the CPU and memory the daemon last sampled
+	wide := flags["wide"] == "true"
+	headers := []string{"NAME", "STATUS", "BRANCH", "GIT", "PR", "MSGS"}
+	if wide {
+		headers = append(headers, "CPU", "AVG CPU", "MEM", "PEAK MEM")
+	}
+	table := format.NewColoredTable(append(headers, "TASK")...).Flexible("TASK", 20)
+	for _, worker := range workers {
+		table.AddRow(workerCells(worker, wide)...)
+	}
+	table.Print()
+
+	return nil
+}
+
+// listAllWorkers lists the workers of every repository in one table, from a
+// single list_agents request
+func (c *CLI) listAllWorkers(wide bool) error {
+	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
+		"all":       true,
+		"rich":      true,
+		"pr_status": true,
+	})
+	if err != nil {
+		return err
+	}
+	agents, ok := resp.Data.([]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	var workers []map[string]interface{}
+	repos := make(map[string]bool)
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok && agentMap["type"] == "worker" {
+			workers = append(workers, agentMap)
+			repo, _ := agentMap["repo"].(string)
+			repos[repo] = true
+		}
+	}
+
+	if len(workers) == 0 {
+		fmt.Println("No workers in any repository")
+		format.Dimmed("\nCreate a worker with: multiclaude worker create <task> --repo <repo>")
+		return nil
+	}
+
+	format.Header("Workers in all repositories (%d in %d):", len(workers), len(repos))
 	fmt.Println()
 
-	table := format.NewColoredTable("NAME", "STATUS", "BRANCH", "MSGS", "TASK")
+	headers := []string{"REPO", "NAME", "STATUS", "BRANCH", "GIT", "PR", "MSGS"}
+	if wide {
+		headers = append(headers, "CPU", "AVG CPU", "MEM", "PEAK MEM")
+	}
+	table := format.NewColoredTable(append(headers, "TASK")...).Flexible("TASK", 20)
 	for _, worker := range workers {
-		name, _ := worker["name"].(string)
-		task, _ := worker["task"].(string)
-		status, _ := worker["status"].(string)
-		branch, _ := worker["branch"].(string)
-		msgsTotal := 0
-		if v, ok := worker["messages_total"].(float64); ok {
-			msgsTotal = int(v)
+		repo, _ := worker["repo"].(string)
+		table.AddRow(append([]format.ColoredCell{format.Cell(repo)}, workerCells(worker, wide)...)...)
+	}
+	table.Print()
+
+	return nil
+}
+
+// workerCells formats a worker from list_agents as a worker list row: name,
+// status, branch, git status, PR, messages, the resource columns if wide, and task
+func workerCells(worker map[string]interface{}, wide bool) []format.ColoredCell {
+	name, _ := worker["name"].(string)
+	task, _ := worker["task"].(string)
+	status, _ := worker["status"].(string)
+	branch, _ := worker["branch"].(string)
+	msgsTotal := 0
+	if v, ok := worker["messages_total"].(float64); ok {
+		msgsTotal = int(v)
+	}
+	msgsPending := 0
+	if v, ok := worker["messages_pending"].(float64); ok {
+		msgsPending = int(v)
+	}
+
+	// Format status with color
+	statusCell := formatAgentStatusCell(status)
+
+	// Format branch, with the base it targets when that isn't the default
+	branchCell := format.ColorCell(branch, format.Cyan)
+	if base, _ := worker["base_branch"].(string); base != "" && branch != "" {
+		branchCell = format.ColorCell(branch+" → "+base, format.Cyan)
+	}
+	if branch == "" {
+		branchCell = format.ColorCell("-", format.Dim)
+	}
+
+	// Format PR status (absent when gh isn't available)
+	prCell := format.ColorCell("-", format.Dim)
+	if prStatus, _ := worker["pr_status"].(string); prStatus != "" && prStatus != "no-pr" {
+		prNumber, _ := worker["pr_number"].(float64)
+		prCell = format.Cell(fmt.Sprintf("#%d %s", int(prNumber), prStatus))
+	}
+
+	// Format message count
+	msgStr := format.MessageBadge(msgsPending, msgsTotal)
+
+	cells := []format.ColoredCell{
+		format.Cell(name),
+		statusCell,
+		branchCell,
+		gitStatusCell(worker["worktree_status"]),
+		prCell,
+		format.Cell(msgStr),
+	}
+	if wide {
+		cells = append(cells, resourceCells(worker["resources"])...)
+	}
+	return append(cells, format.Cell(task))
+}
+
+// gitStatusCell formats a worktree's git status compactly: +N commits to push,
+// -N to pull, ~N changed files and !N conflicted files, "clean" if none
+func gitStatusCell(data interface{}) format.ColoredCell {
+	status, ok := data.(map[string]interface{})
+	if !ok {
+		return format.ColorCell("-", format.Dim)
+	}
+	num := func(key string) int {
+		v, _ := status[key].(float64)
+		return int(v)
+	}
+
+	var parts []string
+	if ahead := num("ahead"); ahead > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", ahead))
+	}
+	if behind := num("behind"); behind > 0 {
+		parts = append(parts, fmt.Sprintf("-%d", behind))
+	}
+	if changed := num("staged") + num("unstaged") + num("untracked"); changed > 0 {
+		parts = append(parts, fmt.Sprintf("~%d", changed))
+	}
+	if conflicted := num("conflicted"); conflicted > 0 {
+		return format.ColorCell(strings.Join(append(parts, fmt.Sprintf("!%d", conflicted)), " "), format.Red)
+	}
+	if len(parts) == 0 {
+		return format.ColorCell("clean", format.Dim)
+	}
+	return format.Cell(strings.Join(parts, " "))
+}
+
+// resourceCells formats an agent's sampled resource stats as CPU, average
+// CPU, memory and peak memory cells, dimmed dashes if it hasn't been sampled
+func resourceCells(data interface{}) []format.ColoredCell {
+	stats, ok := data.(map[string]interface{})
+	if !ok {
+		dash := format.ColorCell("-", format.Dim)
+		return []format.ColoredCell{dash, dash, dash, dash}
+	}
+	num := func(key string) float64 {
+		v, _ := stats[key].(float64)
+		return v
+	}
+	cpuCell := format.Cell(fmt.Sprintf("%.0f%%", num("cpu")))
+	if hot, _ := stats["hot_samples"].(float64); hot > 0 {
+		cpuCell = format.ColorCell(cpuCell.Text, format.Red)
+	}
+	return []format.ColoredCell{
+		cpuCell,
+		format.Cell(fmt.Sprintf("%.0f%%", num("avg_cpu"))),
+		format.Cell(format.Size(int64(num("rss")))),
+		format.Cell(format.Size(int64(num("peak_rss")))),
+	}
+}
+
+// federationStatus lists teammates' workers on a federated repository
+func (c *CLI) federationStatus(ctx CommandContext, args []string) error {
+	resp, err := c.sendDaemonRequest("federation_status", map[string]interface{}{
+		"repo": ctx.Repo,
+	})
+	if err != nil {
+		return err
+	}
+
+	var status federationStatusData
+	if err := remarshal(resp.Data, &status); err != nil {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	if !status.Enabled {
+		fmt.Printf("Federation is not enabled for repository '%s'\n", ctx.Repo)
+		format.Dimmed("\nEnable it with: multiclaude config %s --federation=git", ctx.Repo)
+		return nil
+	}
+
+	format.Header("Federation for '%s' (this daemon: %s):", ctx.Repo, status.PeerID)
+	fmt.Println()
+
+	if len(status.Peers) == 0 {
+		fmt.Println("No teammates have published yet")
+		return nil
+	}
+
+	now := time.Now()
+	table := format.NewColoredTable("PEER", "WORKER", "BRANCH", "TASK").Flexible("TASK", 20)
+	for _, peer := range status.Peers {
+		peerCell := format.Cell(peer.Peer)
+		if peer.Stale(now) {
+			peerCell = format.ColorCell(fmt.Sprintf("%s (offline %s)", peer.Peer, format.TimeAgo(peer.UpdatedAt)), format.Dim)
+		}
+		if len(peer.Workers) == 0 {
+			table.AddRow(peerCell, format.ColorCell("-", format.Dim), format.ColorCell("-", format.Dim), format.Cell(""))
+			continue
 		}
-		msgsPending := 0
-		if v, ok := worker["messages_pending"].(float64); ok {
-			msgsPending = int(v)
+		for _, w := range peer.Workers {
+			branchCell := format.ColorCell(w.Branch, format.Cyan)
+			if w.Branch == "" {
+				branchCell = format.ColorCell("-", format.Dim)
+			}
+			table.AddRow(peerCell, format.Cell(w.Name), branchCell, format.Cell(w.Task))
 		}
+	}
+	table.Print()
 
-		// Format status with color
-		statusCell := formatAgentStatusCell(status)
+	return nil
+}
 
-		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
-			branchCell = format.ColorCell("-", format.Dim)
+// federationStatusData is the federation_status response
+type federationStatusData struct {
+	Enabled bool                    `json:"enabled"`
+	PeerID  string                  `json:"peer_id"`
+	Peers   []federation.PeerStatus `json:"peers"`
+}
+
+// federationPeers returns this daemon's peer ID and its teammates' statuses
+// from the last federation sync. It fails when federation is off or the
+// daemon can't be reached.
+func (c *CLI) federationPeers(repoName string) (string, []federation.PeerStatus, error) {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "federation_status",
+		Args:    map[string]interface{}{"repo": repoName},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if !resp.Success {
+		return "", nil, fmt.Errorf("%s", resp.Error)
+	}
+
+	var status federationStatusData
+	if err := remarshal(resp.Data, &status); err != nil {
+		return "", nil, err
+	}
+	if !status.Enabled {
+		return "", nil, fmt.Errorf("federation is not enabled")
+	}
+	return status.PeerID, status.Peers, nil
+}
+
+// remarshal converts generic socket response data into a typed value
+func remarshal(data interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// listAgentDefinitions lists available agent definitions for a repository
+func (c *CLI) listAgentDefinitions(ctx CommandContext, args []string) error {
+	flags, _ := ParseFlags(args)
+	if flags["all"] == "true" {
+		return c.listAllAgents()
+	}
+	if ctx.Repo == "" {
+		return errors.NotInRepo()
+	}
+
+	// Get paths to agent definition directories
+	localAgentsDir := c.paths.RepoAgentsDir(ctx.Repo)
+	repoPath := c.paths.RepoDir(ctx.Repo)
+
+	// Read and merge agent definitions
+	reader := agents.NewReader(localAgentsDir, repoPath)
+	defs, err := reader.ReadAllDefinitions()
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
+	}
+
+	if len(defs) == 0 {
+		fmt.Println("No agent definitions found.")
+		fmt.Printf("\nAgent definitions are stored in:\n")
+		fmt.Printf("  Local: %s\n", localAgentsDir)
+		fmt.Printf("  Repo:  %s/.multiclaude/agents/\n", repoPath)
+		return nil
+	}
+
+	fmt.Printf("Agent definitions for %s:\n\n", ctx.Repo)
+
+	// Count the agents running from each definition, for their limits
+	running := make(map[string]int)
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetRepo(ctx.Repo); exists {
+			for _, agent := range repo.Agents {
+				if agent.Definition != "" {
+					running[agent.Definition]++
+				}
+			}
+		}
+	}
+
+	// Create colored table
+	table := format.NewColoredTable("Name", "Source", "Title", "Description", "Capabilities", "Running").
+		Flexible("Title", 20).Flexible("Description", 20)
+
+	for _, def := range defs {
+		source := string(def.Source)
+		title := def.ParseTitle()
+		desc := def.ParseDescription()
+
+		// Color the source based on type
+		sourceCell := format.Cell(source)
+		if def.Source == agents.SourceRepo {
+			sourceCell = format.ColorCell(source, format.Green)
 		}
 
-		// Format message count
-		msgStr := format.MessageBadge(msgsPending, msgsTotal)
+		table.AddRow(
+			format.Cell(def.Name),
+			sourceCell,
+			format.Cell(title),
+			format.Cell(desc),
+			format.Cell(strings.Join(def.ParseCapabilities(), ", ")),
+			format.Cell(definitionUsage(def, running)),
+		)
+	}
+
+	table.Print()
+
+	return nil
+}
+
+// listAllAgents lists the running agents of every repository, from a single
+// list_agents request, so one command gives the picture across repositories
+func (c *CLI) listAllAgents() error {
+	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
+		"all":  true,
+		"rich": true,
+	})
+	if err != nil {
+		return err
+	}
+	agents, ok := resp.Data.([]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+	if len(agents) == 0 {
+		fmt.Println("No agents in any repository")
+		return nil
+	}
 
-		// Truncate task
-		truncTask := format.Truncate(task, 40)
+	format.Header("Agents in all repositories (%d):", len(agents))
+	fmt.Println()
 
+	table := format.NewColoredTable("REPO", "NAME", "TYPE", "STATUS", "MSGS", "TASK").Flexible("TASK", 20)
+	for _, agent := range agents {
+		agentMap, ok := agent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		repo, _ := agentMap["repo"].(string)
+		name, _ := agentMap["name"].(string)
+		agentType, _ := agentMap["type"].(string)
+		status, _ := agentMap["status"].(string)
+		task, _ := agentMap["task"].(string)
+		msgsTotal, _ := agentMap["messages_total"].(float64)
+		msgsPending, _ := agentMap["messages_pending"].(float64)
 		table.AddRow(
+			format.Cell(repo),
 			format.Cell(name),
-			statusCell,
-			branchCell,
-			format.Cell(msgStr),
-			format.Cell(truncTask),
+			format.Cell(agentType),
+			formatAgentStatusCell(status),
+			format.Cell(format.MessageBadge(int(msgsPending), int(msgsTotal))),
+			format.Cell(task),
 		)
 	}
 	table.Print()
@@ -2256,70 +6329,117 @@ func (c *CLI) listWorkers(args []string) error {
 	return nil
 }
 
-// listAgentDefinitions lists available agent definitions for a repository
-func (c *CLI) listAgentDefinitions(args []string) error {
-	flags, _ := ParseFlags(args)
+// listSlashCommands shows the slash commands a repository's agents get: the
+// built-ins merged with the repository's .multiclaude/commands
+func (c *CLI) listSlashCommands(ctx CommandContext, flags *FlagSet) error {
+	repoPath := c.paths.RepoDir(ctx.Repo)
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+	cmds, warnings := commands.Effective(repoPath)
+
+	fmt.Printf("Slash commands for %s:\n\n", ctx.Repo)
+
+	table := format.NewColoredTable("Name", "Source", "Description").Flexible("Description", 20)
+	for _, cmd := range cmds {
+		sourceCell := format.Cell(string(cmd.Source))
+		switch cmd.Source {
+		case commands.SourceRepo:
+			sourceCell = format.ColorCell(string(cmd.Source), format.Green)
+		case commands.SourceOverride:
+			sourceCell = format.ColorCell(string(cmd.Source), format.Yellow)
+		}
+		table.AddRow(
+			format.Cell("/"+cmd.Name),
+			sourceCell,
+			format.Cell(cmd.Description),
+		)
 	}
+	table.Print()
 
-	// Get paths to agent definition directories
-	localAgentsDir := c.paths.RepoAgentsDir(repoName)
-	repoPath := c.paths.RepoDir(repoName)
+	if len(warnings) > 0 {
+		fmt.Println("\nSkipped:")
+		for _, w := range warnings {
+			fmt.Printf("  %s\n", w)
+		}
+	}
+
+	fmt.Printf("\nAdd or override commands in %s/<name>.md\n", filepath.Join(repoPath, commands.RepoCommandsDir))
+	return nil
+}
+
+// findAgentsByCapability lists the agent definitions that declare a capability
+// and the running agents that have it
+func (c *CLI) findAgentsByCapability(ctx CommandContext, args []string) error {
+	flags, _ := ParseFlags(args)
+
+	capability := agents.NormalizeCapability(flags["capability"])
+	if capability == "" || capability == "true" {
+		return errors.InvalidUsage("usage: multiclaude agents find --capability <capability>")
+	}
 
-	// Read and merge agent definitions
-	reader := agents.NewReader(localAgentsDir, repoPath)
+	reader := agents.NewReader(c.paths.RepoAgentsDir(ctx.Repo), c.paths.RepoDir(ctx.Repo))
 	defs, err := reader.ReadAllDefinitions()
 	if err != nil {
 		return errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
 	}
+	found := agents.FindByCapability(defs, capability)
 
-	if len(defs) == 0 {
-		fmt.Println("No agent definitions found.")
-		fmt.Printf("\nAgent definitions are stored in:\n")
-		fmt.Printf("  Local: %s\n", localAgentsDir)
-		fmt.Printf("  Repo:  %s/.multiclaude/agents/\n", repoPath)
-		return nil
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": ctx.Repo,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing agents", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to list agents", resp)
+	}
+	var running []struct {
+		Name         string   `json:"name"`
+		Type         string   `json:"type"`
+		Task         string   `json:"task"`
+		Capabilities []string `json:"capabilities"`
+	}
+	if err := remarshal(resp.Data, &running); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to parse agents", err)
 	}
 
-	fmt.Printf("Agent definitions for %s:\n\n", repoName)
-
-	// Create colored table
-	table := format.NewColoredTable("Name", "Source", "Title", "Description")
-
-	for _, def := range defs {
-		source := string(def.Source)
-		title := def.ParseTitle()
-		desc := def.ParseDescription()
+	if len(found) == 0 {
+		fmt.Printf("No agent definitions declare capability '%s'.\n", capability)
+		fmt.Println("Declare one with a \"Capabilities: <capability>, ...\" line in a definition (see: multiclaude agents list)")
+		return nil
+	}
 
-		// Truncate description if too long
-		desc = format.Truncate(desc, 50)
+	fmt.Printf("Definitions with capability '%s':\n", capability)
+	for _, def := range found {
+		fmt.Printf("  %s (%s)\n", def.Name, def.Source)
+	}
 
-		// Color the source based on type
-		sourceCell := format.Cell(source)
-		if def.Source == agents.SourceRepo {
-			sourceCell = format.ColorCell(source, format.Green)
+	fmt.Println("\nRunning agents:")
+	matched := 0
+	for _, agent := range running {
+		if !agents.HasCapability(agent.Capabilities, capability) {
+			continue
+		}
+		matched++
+		if agent.Task != "" {
+			fmt.Printf("  %s (%s): %s\n", agent.Name, agent.Type, format.Truncate(agent.Task, 60))
+		} else {
+			fmt.Printf("  %s (%s)\n", agent.Name, agent.Type)
 		}
-
-		table.AddRow(
-			format.Cell(def.Name),
-			sourceCell,
-			format.Cell(title),
-			format.Cell(desc),
-		)
 	}
-
-	table.Print()
-
+	if matched == 0 {
+		fmt.Println("  none")
+	}
+	fmt.Printf("\nAssign a task: multiclaude worker create \"<task>\" --capability %s\n", capability)
 	return nil
 }
 
 // spawnAgentFromFile spawns an agent using a prompt file and the daemon's spawn_agent handler.
 // This is the CLI command that connects supervisor orchestration with daemon agent spawning.
-func (c *CLI) spawnAgentFromFile(args []string) error {
+func (c *CLI) spawnAgentFromFile(ctx CommandContext, args []string) error {
 	flags, _ := ParseFlags(args)
 
 	// Get required parameters
@@ -2330,36 +6450,46 @@ func (c *CLI) spawnAgentFromFile(args []string) error {
 
 	agentClass, ok := flags["class"]
 	if !ok || agentClass == "" {
-		return errors.InvalidUsage("--class is required (persistent or ephemeral)")
+		return errors.InvalidUsage("--class is required (persistent, ephemeral or observer)")
 	}
-	if agentClass != "persistent" && agentClass != "ephemeral" {
-		return errors.InvalidUsage("--class must be 'persistent' or 'ephemeral'")
+	if agentClass != "persistent" && agentClass != "ephemeral" && agentClass != "observer" {
+		return errors.InvalidUsage("--class must be 'persistent', 'ephemeral' or 'observer'")
 	}
 
-	promptFile, ok := flags["prompt-file"]
-	if !ok || promptFile == "" {
-		return errors.InvalidUsage("--prompt-file is required")
+	promptFile, definition := flags["prompt-file"], flags["definition"]
+	if promptFile == "" && definition == "" {
+		return errors.InvalidUsage("--prompt-file or --definition is required")
 	}
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+	// Read the prompt from the file, or else the (merged) definition. The
+	// agent counts towards the definition's max-instances limit: the one
+	// named, or the one the prompt file is.
+	var promptContent []byte
+	if definition != "" {
+		def, err := c.namedDefinition(ctx.Repo, definition)
+		if err != nil {
+			return err
+		}
+		promptContent = []byte(def.Content)
 	}
-
-	// Read prompt from file
-	promptContent, err := os.ReadFile(promptFile)
-	if err != nil {
-		return errors.Wrap(errors.CategoryRuntime, "failed to read prompt file", err)
+	if promptFile != "" {
+		var err error
+		promptContent, err = os.ReadFile(localPath(promptFile))
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read prompt file", err)
+		}
+		if definition == "" {
+			definition = c.promptFileDefinition(ctx.Repo, localPath(promptFile))
+		}
 	}
 
 	// Get optional task parameter
 	task := flags["task"]
 
 	// Send spawn_agent request to daemon
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	reqArgs := map[string]interface{}{
-		"repo":   repoName,
+		"repo":   ctx.Repo,
 		"name":   agentName,
 		"class":  agentClass,
 		"prompt": string(promptContent),
@@ -2367,6 +6497,9 @@ func (c *CLI) spawnAgentFromFile(args []string) error {
 	if task != "" {
 		reqArgs["task"] = task
 	}
+	if definition != "" {
+		reqArgs["definition"] = definition
+	}
 
 	resp, err := client.Send(socket.Request{
 		Command: "spawn_agent",
@@ -2376,7 +6509,7 @@ func (c *CLI) spawnAgentFromFile(args []string) error {
 		return errors.DaemonCommunicationFailed("spawning agent", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to spawn agent", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to spawn agent", resp)
 	}
 
 	fmt.Printf("Agent '%s' spawned successfully (class: %s)\n", agentName, agentClass)
@@ -2384,17 +6517,9 @@ func (c *CLI) spawnAgentFromFile(args []string) error {
 }
 
 // resetAgentDefinitions deletes the local agent definitions and re-copies from templates.
-func (c *CLI) resetAgentDefinitions(args []string) error {
-	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
-	}
-
+func (c *CLI) resetAgentDefinitions(ctx CommandContext, args []string) error {
 	// Get agents directory path
-	agentsDir := c.paths.RepoAgentsDir(repoName)
+	agentsDir := c.paths.RepoAgentsDir(ctx.Repo)
 
 	// Check if directory exists
 	if _, err := os.Stat(agentsDir); os.IsNotExist(err) {
@@ -2429,35 +6554,16 @@ func (c *CLI) resetAgentDefinitions(args []string) error {
 	return nil
 }
 
-func (c *CLI) showHistory(args []string) error {
-	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
-	}
-
-	// Get limit from flags (default 10)
-	limit := 10
-	if n, ok := flags["n"]; ok {
-		if v, err := strconv.Atoi(n); err == nil && v > 0 {
-			limit = v
-		}
+func (c *CLI) showHistory(ctx CommandContext, flags *FlagSet) error {
+	limit := flags.Int("n")
+	if limit <= 0 {
+		return errors.InvalidArgument("-n", flags.String("n"), "a positive count")
 	}
 
 	// Get filter options
-	statusFilter := flags["status"] // Filter by status (merged, open, closed, failed, no-pr)
-	searchQuery := flags["search"]  // Search in task descriptions
-	showFull := flags["full"] == "true"
-
-	// Validate status filter if provided
-	validStatuses := map[string]bool{
-		"merged": true, "open": true, "closed": true, "failed": true, "no-pr": true,
-	}
-	if statusFilter != "" && !validStatuses[statusFilter] {
-		return errors.InvalidUsage(fmt.Sprintf("invalid status filter: %s (valid values: merged, open, closed, failed, no-pr)", statusFilter))
-	}
+	statusFilter := flags.String("status")
+	searchQuery := flags.String("search")
+	showFull := flags.Bool("full")
 
 	// When filtering, fetch more history to ensure we get enough results
 	fetchLimit := limit
@@ -2469,11 +6575,11 @@ func (c *CLI) showHistory(args []string) error {
 	}
 
 	// Get task history from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "task_history",
 		Args: map[string]interface{}{
-			"repo":  repoName,
+			"repo":  ctx.Repo,
 			"limit": fetchLimit,
 		},
 	})
@@ -2481,21 +6587,31 @@ func (c *CLI) showHistory(args []string) error {
 		return errors.DaemonCommunicationFailed("getting task history", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get task history", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to get task history", resp)
 	}
 
 	history, ok := resp.Data.([]interface{})
 	if !ok || len(history) == 0 {
-		fmt.Printf("No task history for repository '%s'\n", repoName)
+		fmt.Printf("No task history for repository '%s'\n", ctx.Repo)
 		format.Dimmed("\nCreate workers with: multiclaude worker create <task>")
 		return nil
 	}
 
-	// Query GitHub for PR status for each task with a branch
-	repoPath := c.paths.RepoDir(repoName)
+	// Query GitHub for PR status for each task with a branch, through the
+	// daemon's cache when possible
+	repoPath := c.paths.RepoDir(ctx.Repo)
+	var branches []string
+	for _, item := range history {
+		if entry, ok := item.(map[string]interface{}); ok {
+			if branch, _ := entry["branch"].(string); branch != "" {
+				branches = append(branches, branch)
+			}
+		}
+	}
+	prStatuses := c.daemonPRStatuses(ctx.Repo, branches)
 
 	// Build filtered header
-	headerParts := []string{fmt.Sprintf("Task History for '%s'", repoName)}
+	headerParts := []string{fmt.Sprintf("Task History for '%s'", ctx.Repo)}
 	if statusFilter != "" {
 		headerParts = append(headerParts, fmt.Sprintf("status=%s", statusFilter))
 	}
@@ -2508,12 +6624,20 @@ func (c *CLI) showHistory(args []string) error {
 	// First pass: collect entries with details to show after table
 	type entryDetails struct {
 		name          string
+		id            string
 		summary       string
+		conversation  string
 		failureReason string
+		retryOf       string
+		retriedBy     string
+		criteria      []interface{}
 	}
 	var detailsToShow []entryDetails
 
 	table := format.NewColoredTable("NAME", "STATUS", "PR", "COMPLETED", "TASK")
+	if !showFull {
+		table.Flexible("TASK", 20)
+	}
 	displayedCount := 0
 	for _, item := range history {
 		// Stop once we've displayed enough
@@ -2532,11 +6656,24 @@ func (c *CLI) showHistory(args []string) error {
 		prURL, _ := entry["pr_url"].(string)
 		completedAt, _ := entry["completed_at"].(string)
 		summary, _ := entry["summary"].(string)
+		conversation, _ := entry["conversation"].(string)
+		if !showFull {
+			conversation = ""
+		}
 		failureReason, _ := entry["failure_reason"].(string)
 		storedStatus, _ := entry["status"].(string)
+		id, _ := entry["id"].(string)
+		retryOf, _ := entry["retry_of"].(string)
+		retriedBy, _ := entry["retried_by"].(string)
+		criteria, _ := entry["criteria"].([]interface{})
 
 		// Try to get PR status from GitHub if we have a branch
-		prStatus, prLink := c.getPRStatusForBranch(repoPath, branch, prURL)
+		var prStatus, prLink string
+		if pr, ok := prStatuses[branch]; ok && prURL == "" {
+			prStatus, prLink = pr.status, pr.link
+		} else {
+			prStatus, prLink = c.getPRStatusForBranch(repoPath, branch, prURL)
+		}
 
 		// Use stored status if it indicates failure
 		if storedStatus == "failed" {
@@ -2567,11 +6704,16 @@ func (c *CLI) showHistory(args []string) error {
 		displayedCount++
 
 		// Collect entries with summary or failure for detailed display
-		if summary != "" || failureReason != "" {
+		if summary != "" || conversation != "" || failureReason != "" || retryOf != "" || retriedBy != "" || len(criteria) > 0 {
 			detailsToShow = append(detailsToShow, entryDetails{
 				name:          name,
+				id:            id,
 				summary:       summary,
+				conversation:  conversation,
 				failureReason: failureReason,
+				retryOf:       retryOf,
+				retriedBy:     retriedBy,
+				criteria:      criteria,
 			})
 		}
 
@@ -2582,6 +6724,8 @@ func (c *CLI) showHistory(args []string) error {
 			statusCell = format.ColorCell("merged", format.Green)
 		case "open":
 			statusCell = format.ColorCell("open", format.Yellow)
+		case "draft":
+			statusCell = format.ColorCell("draft", format.Dim)
 		case "closed":
 			statusCell = format.ColorCell("closed", format.Red)
 		case "failed":
@@ -2605,47 +6749,490 @@ func (c *CLI) showHistory(args []string) error {
 			}
 		}
 
-		// Format task - show full or truncate
-		displayTask := task
-		if !showFull {
-			displayTask = format.Truncate(task, 50)
-		}
-
 		table.AddRow(
 			format.Cell(name),
 			statusCell,
 			prCell,
 			completedCell,
-			format.Cell(displayTask),
+			format.Cell(task),
 		)
 	}
 
-	// Show message if no results after filtering
-	if displayedCount == 0 {
-		if statusFilter != "" || searchQuery != "" {
-			fmt.Printf("No tasks match the filter criteria\n")
-		}
+	// Show message if no results after filtering
+	if displayedCount == 0 {
+		if statusFilter != "" || searchQuery != "" {
+			fmt.Printf("No tasks match the filter criteria\n")
+		}
+		return nil
+	}
+
+	table.Print()
+
+	// Print detailed summary/failure section if any entries have them
+	if len(detailsToShow) > 0 {
+		fmt.Println()
+		format.Header("Details:")
+		for _, d := range detailsToShow {
+			format.Bold.Printf("\n%s:\n", d.name)
+			if d.summary != "" {
+				format.Dimmed("  Summary: %s", d.summary)
+			}
+			if d.conversation != "" {
+				fmt.Println("  Session:")
+				for _, line := range strings.Split(d.conversation, "\n") {
+					fmt.Printf("    %s\n", line)
+				}
+			}
+			if d.failureReason != "" {
+				format.Red.Printf("  Failure: %s\n", d.failureReason)
+			}
+			if len(d.criteria) > 0 {
+				printCriteria(d.criteria)
+			}
+			if d.retryOf != "" {
+				format.Dimmed("  Retry of: %s", d.retryOf)
+			}
+			if d.retriedBy != "" {
+				format.Dimmed("  Retried by: %s", d.retriedBy)
+			} else if d.failureReason != "" && d.id != "" {
+				format.Dimmed("  Retry with: multiclaude work retry %s", d.id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// watchRecent is how many events the live view of watch shows, and
+// maxWatchWait how long each events request in a stream waits for news
+const (
+	watchRecent  = 15
+	maxWatchWait = 25 * time.Second
+)
+
+// watch follows the daemon's lifecycle events by long-polling the events
+// command, printing them as they arrive or redrawing a live view
+func (c *CLI) watch(flags *FlagSet) error {
+	repoFilter := flags.String("repo")
+	asJSON := flags.Bool("json")
+	stream := asJSON || flags.Bool("stream")
+	limit := flags.Int("count")
+
+	// Start from the latest event
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{Command: "events"})
+	if err != nil {
+		return errors.DaemonNotRunning()
+	}
+	if !resp.Success {
+		return daemonError("failed to read events", resp)
+	}
+	_, seq, err := decodeEvents(resp.Data)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The live view redraws every few seconds even when nothing happens
+	wait := maxWatchWait
+	if !stream {
+		wait = 5 * time.Second
+	}
+
+	var recent []events.Event
+	seen := 0
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		if !stream {
+			c.renderWatch(repoFilter, recent)
+		}
+
+		type result struct {
+			resp *socket.Response
+			err  error
+		}
+		done := make(chan result, 1)
+		go func() {
+			resp, err := client.Send(socket.Request{
+				Command: "events",
+				Args: map[string]interface{}{
+					"since":        seq,
+					"wait_seconds": wait.Seconds(),
+					"repo":         repoFilter,
+				},
+			})
+			done <- result{resp, err}
+		}()
+
+		var r result
+		select {
+		case <-ctx.Done():
+			return nil
+		case r = <-done:
+		}
+		if r.err != nil {
+			return errors.DaemonCommunicationFailed("watching events", r.err)
+		}
+		if !r.resp.Success {
+			return daemonError("failed to read events", r.resp)
+		}
+		evs, next, err := decodeEvents(r.resp.Data)
+		if err != nil {
+			return err
+		}
+		seq = next
+
+		for _, e := range evs {
+			switch {
+			case asJSON:
+				if err := encoder.Encode(e); err != nil {
+					return err
+				}
+			case stream:
+				fmt.Println(formatEvent(e))
+			default:
+				recent = append(recent, e)
+				if len(recent) > watchRecent {
+					recent = recent[len(recent)-watchRecent:]
+				}
+			}
+			seen++
+			if limit > 0 && seen >= limit {
+				if !stream {
+					c.renderWatch(repoFilter, recent)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// decodeEvents reads the events and latest sequence number from an events response
+func decodeEvents(data interface{}) ([]events.Event, uint64, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read events: %w", err)
+	}
+	var batch struct {
+		Events []events.Event `json:"events"`
+		Seq    uint64         `json:"seq"`
+	}
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		return nil, 0, fmt.Errorf("failed to read events: %w", err)
+	}
+	return batch.Events, batch.Seq, nil
+}
+
+// formatEvent renders an event as one line: time, type, agent and details
+func formatEvent(e events.Event) string {
+	who := e.Repo
+	if e.Agent != "" {
+		who += "/" + e.Agent
+	}
+
+	var detail string
+	switch e.Type {
+	case events.EventAgentStarted:
+		detail = e.Data["type"]
+		if task := e.Data["task"]; task != "" {
+			detail += ": " + task
+		}
+	case events.EventAgentDied, events.EventResourceAlert:
+		detail = e.Data["detail"]
+	case events.EventAgentRestarted:
+		detail = "PID " + e.Data["pid"]
+	case events.EventAgentInterrupted:
+		detail = e.Data["key"]
+		if e.Data["signal"] != "" {
+			detail += " + " + e.Data["signal"]
+		}
+		if by := e.Data["by"]; by != "" {
+			detail += " by " + by
+		}
+	case events.EventTaskCompleted:
+		detail = e.Data["summary"]
+		if detail == "" {
+			detail = e.Data["task"]
+		}
+	case events.EventTaskFailed:
+		detail = e.Data["reason"]
+	case events.EventTaskStarted:
+		detail = e.Data["id"] + ": " + e.Data["task"]
+	case events.EventTaskFinished:
+		detail = e.Data["id"] + " " + e.Data["status"]
+		if reason := e.Data["reason"]; reason != "" {
+			detail += ": " + reason
+		}
+	case events.EventTrainPassed:
+		detail = "#" + e.Data["pr"] + " " + e.Data["branch"]
+	case events.EventTrainFailed:
+		detail = "#" + e.Data["pr"] + ": " + e.Data["reason"]
+	case events.EventCommentCommand:
+		detail = e.Data["verb"] + " by @" + e.Data["author"] + " on #" + e.Data["number"]
+	case events.EventMessageDelivered:
+		detail = fmt.Sprintf("from %s (%s)", e.Data["from"], e.Data["id"])
+	case events.EventConfigChanged:
+		detail = strings.ReplaceAll(e.Data["changed"], ",", ", ")
+	case events.EventConfigReloaded:
+		var changed []string
+		for key, change := range e.Data {
+			if key != "source" {
+				changed = append(changed, key+": "+change)
+			}
+		}
+		sort.Strings(changed)
+		detail = "by " + e.Data["source"]
+		if len(changed) > 0 {
+			detail += "; " + strings.Join(changed, ", ")
+		}
+	}
+
+	line := fmt.Sprintf("%s  %-17s  %s", e.Time.Local().Format("15:04:05"), e.Type, who)
+	if detail != "" {
+		line += "  " + detail
+	}
+	return line
+}
+
+// renderWatch redraws the live view of watch: the agents in each repository
+// and the most recent events
+func (c *CLI) renderWatch(repoFilter string, recent []events.Event) {
+	fmt.Print("\033[H\033[2J")
+	format.Header("multiclaude watch — %s", time.Now().Format("15:04:05"))
+	format.Dimmed("Ctrl-C to quit")
+	fmt.Println()
+
+	if st, err := c.loadState(); err == nil {
+		repos := st.GetAllRepos()
+		names := make([]string, 0, len(repos))
+		for name := range repos {
+			if repoFilter == "" || name == repoFilter {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			repo := repos[name]
+			fmt.Printf("%s (%d agents)\n", name, len(repo.Agents))
+			agentNames := make([]string, 0, len(repo.Agents))
+			for agentName := range repo.Agents {
+				agentNames = append(agentNames, agentName)
+			}
+			sort.Strings(agentNames)
+			for _, agentName := range agentNames {
+				agent := repo.Agents[agentName]
+				fmt.Printf("  %-20s %-18s %s\n", agentName, agent.Type, format.Truncate(agent.Task, 60))
+			}
+			fmt.Println()
+		}
+	}
+
+	fmt.Println("Recent events:")
+	if len(recent) == 0 {
+		format.Dimmed("  Waiting for events...")
+	}
+	for _, e := range recent {
+		fmt.Println("  " + formatEvent(e))
+	}
+}
+
+// showDigest prints (or posts) a summary of what every agent in a repository
+// did: commits from their worktrees and branches, finished tasks and their
+// PRs, messages sent and failures
+func (c *CLI) showDigest(ctx CommandContext, args []string) error {
+	flags, _ := ParseFlags(args)
+
+	period := 24 * time.Hour
+	if s, ok := flags["since"]; ok {
+		var err error
+		if period, err = parseDuration(s); err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --since duration %q: %v (e.g., 24h, 7d)", s, err))
+		}
+	}
+	postTo := flags["post-to"]
+	if postTo != "" && postTo != "workspace" && postTo != "slack" {
+		return errors.InvalidUsage(fmt.Sprintf("invalid --post-to target: %s (valid values: workspace, slack)", postTo))
+	}
+
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(ctx.Repo)
+	if !exists {
+		return errors.RepoNotFound(ctx.Repo)
+	}
+
+	in := digest.Input{
+		Repo:    ctx.Repo,
+		Since:   time.Now().Add(-period),
+		Agents:  repo.Agents,
+		History: repo.TaskHistory,
+		Commits: make(map[string][]digest.Commit),
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	inboxes, _ := msgMgr.Inboxes(ctx.Repo)
+	for _, inbox := range inboxes {
+		msgs, err := msgMgr.List(ctx.Repo, inbox)
+		if err != nil {
+			continue
+		}
+		in.Messages = append(in.Messages, msgs...)
+	}
+
+	// Only count commits that aren't on the default branch yet, so agents
+	// working in the main checkout aren't credited with everything that landed
+	repoPath := c.paths.RepoDir(ctx.Repo)
+	base := c.repoDefaultBranch(ctx.Repo)
+	if _, err := gitcmd.Run(context.Background(), repoPath, "rev-parse", "--verify", "--quiet", "origin/"+base); err == nil {
+		base = "origin/" + base
+	}
+	for name, agent := range repo.Agents {
+		if agent.WorktreePath == "" || agent.WorktreePath == repoPath {
+			continue
+		}
+		if commits, err := digest.Commits(agent.WorktreePath, base, "HEAD", in.Since); err == nil {
+			in.Commits[name] = commits
+		}
+	}
+	var branches []string
+	for _, entry := range repo.TaskHistory {
+		if entry.Branch == "" || entry.CompletedAt.Before(in.Since) {
+			continue
+		}
+		branches = append(branches, entry.Branch)
+		if _, running := repo.Agents[entry.Name]; running {
+			continue
+		}
+		// Finished workers' worktrees are gone, but their branches may remain
+		if commits, err := digest.Commits(repoPath, base, entry.Branch, in.Since); err == nil {
+			in.Commits[entry.Name] = append(in.Commits[entry.Name], commits...)
+		}
+	}
+	if statuses := c.daemonPRStatuses(ctx.Repo, branches); statuses != nil {
+		in.PRStatus = make(map[string]string, len(statuses))
+		for branch, pr := range statuses {
+			in.PRStatus[branch] = pr.status
+		}
+	}
+
+	text := digest.Build(in).String()
+	fmt.Print(text)
+
+	switch postTo {
+	case "workspace":
+		var workspaces []string
+		for name, agent := range repo.Agents {
+			if agent.Type == state.AgentTypeWorkspace {
+				workspaces = append(workspaces, name)
+			}
+		}
+		if len(workspaces) == 0 {
+			return errors.New(errors.CategoryNotFound, fmt.Sprintf("repository '%s' has no workspace to post to", ctx.Repo)).
+				WithSuggestion("multiclaude workspace add <name>")
+		}
+		sort.Strings(workspaces)
+		for _, name := range workspaces {
+			if _, err := msgMgr.Send(ctx.Repo, "digest", name, text); err != nil {
+				return fmt.Errorf("failed to post digest to %s: %w", name, err)
+			}
+		}
+		client := c.daemonClient()
+		_, _ = client.Send(socket.Request{Command: "route_messages"})
+		fmt.Printf("\nPosted to %s\n", strings.Join(workspaces, ", "))
+
+	case "slack":
+		webhook := os.Getenv(digest.SlackWebhookEnv)
+		if webhook == "" {
+			return errors.New(errors.CategoryConfig, digest.SlackWebhookEnv+" is not set").
+				WithSuggestion("export " + digest.SlackWebhookEnv + "=<Slack incoming webhook URL>")
+		}
+		if err := digest.PostSlack(webhook, text); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to post digest", err)
+		}
+		fmt.Println("\nPosted to Slack")
+	}
+
+	return nil
+}
+
+// printCriteria prints acceptance criteria from a task_history response with
+// the worker's report on each
+func printCriteria(criteria []interface{}) {
+	met := 0
+	for _, item := range criteria {
+		if c, _ := item.(map[string]interface{}); c["status"] == string(state.CriterionMet) {
+			met++
+		}
+	}
+	fmt.Printf("  Criteria: %d/%d met\n", met, len(criteria))
+	for _, item := range criteria {
+		c, _ := item.(map[string]interface{})
+		text, _ := c["text"].(string)
+		note, _ := c["note"].(string)
+		if note != "" {
+			text += " - " + note
+		}
+		switch c["status"] {
+		case string(state.CriterionMet):
+			format.Green.Printf("    ✓ %s\n", text)
+		case string(state.CriterionUnmet):
+			format.Red.Printf("    ✗ %s\n", text)
+		default:
+			format.Dimmed("    ? %s (not reported)", text)
+		}
+	}
+}
+
+// branchPRStatus is a branch's PR status and short link (e.g. "#42")
+type branchPRStatus struct {
+	status string
+	link   string
+}
+
+// daemonPRStatuses looks up the PR status of many branches with one daemon
+// request, which the daemon answers from a single cached gh query. It returns
+// nil if the daemon can't answer, so callers fall back to querying gh directly.
+func (c *CLI) daemonPRStatuses(repoName string, branches []string) map[string]branchPRStatus {
+	if len(branches) == 0 {
+		return nil
+	}
+
+	branchArgs := make([]interface{}, len(branches))
+	for i, b := range branches {
+		branchArgs[i] = b
+	}
+
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "pr_status",
+		Args: map[string]interface{}{
+			"repo":     repoName,
+			"branches": branchArgs,
+		},
+	})
+	if err != nil || !resp.Success {
 		return nil
 	}
 
-	table.Print()
-
-	// Print detailed summary/failure section if any entries have them
-	if len(detailsToShow) > 0 {
-		fmt.Println()
-		format.Header("Details:")
-		for _, d := range detailsToShow {
-			format.Bold.Printf("\n%s:\n", d.name)
-			if d.summary != "" {
-				format.Dimmed("  Summary: %s", d.summary)
-			}
-			if d.failureReason != "" {
-				format.Red.Printf("  Failure: %s\n", d.failureReason)
-			}
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	statuses := make(map[string]branchPRStatus, len(data))
+	for branch, v := range data {
+		pr, _ := v.(map[string]interface{})
+		status, _ := pr["status"].(string)
+		link := ""
+		if number, ok := pr["number"].(float64); ok && number > 0 {
+			link = fmt.Sprintf("#%d", int(number))
 		}
+		statuses[branch] = branchPRStatus{status: status, link: link}
 	}
-
-	return nil
+	return statuses
 }
 
 // getPRStatusForBranch queries GitHub for the PR status of a branch
@@ -2667,9 +7254,11 @@ func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (stat
 	}
 
 	// Query GitHub for PR associated with this branch using gh CLI
-	cmd := exec.Command("gh", "pr", "list", "--head", branch, "--state", "all", "--json", "number,state,url", "--limit", "1")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
+	output, err := retry.GitHub.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "gh", "pr", "list", "--head", branch, "--state", "all", "--json", "number,state,url", "--limit", "1")
+		cmd.Dir = repoPath
+		return cmd
+	})
 	if err != nil {
 		return "no-pr", ""
 	}
@@ -2699,28 +7288,25 @@ func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (stat
 	}
 }
 
-func (c *CLI) removeWorker(args []string) error {
-	flags, remainingArgs := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+func (c *CLI) removeWorker(ctx CommandContext, args []string) error {
+	if isBulkRemove(args) {
+		return c.bulkWorkers(ctx, "rm", args)
 	}
+	flags, remainingArgs := ParseFlags(args)
 
 	// Get worker info
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo": ctx.Repo,
 		},
 	})
 	if err != nil {
 		return errors.DaemonCommunicationFailed("getting worker info", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get worker info", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to get worker info", resp)
 	}
 
 	agents, _ := resp.Data.([]interface{})
@@ -2733,7 +7319,7 @@ func (c *CLI) removeWorker(args []string) error {
 		// Interactive selection
 		items := agentsToSelectableItems(agents, []string{"worker"})
 		if len(items) == 0 {
-			return errors.NoWorkersFound(repoName)
+			return errors.NoWorkersFound(ctx.Repo)
 		}
 		selected, err := SelectFromList("Select worker to remove:", items)
 		if err != nil {
@@ -2746,7 +7332,7 @@ func (c *CLI) removeWorker(args []string) error {
 		workerName = selected
 	}
 
-	fmt.Printf("Removing worker '%s' from repo '%s'\n", workerName, repoName)
+	fmt.Printf("Removing worker '%s' from repo '%s'\n", workerName, ctx.Repo)
 
 	// Find worker
 	var workerInfo map[string]interface{}
@@ -2760,49 +7346,59 @@ func (c *CLI) removeWorker(args []string) error {
 	}
 
 	if workerInfo == nil {
-		return errors.AgentNotFound("worker", workerName, repoName)
+		return errors.AgentNotFound("worker", workerName, ctx.Repo)
 	}
 
 	// Get worktree path
 	wtPath := workerInfo["worktree_path"].(string)
 
-	// Check for uncommitted changes
-	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
-	if err != nil {
-		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
-	} else if hasUncommitted {
-		fmt.Println("\nWarning: Worker has uncommitted changes!")
-		fmt.Println("Files may be lost if you continue with cleanup.")
-		fmt.Print("Continue with cleanup? [y/N]: ")
-
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
-			fmt.Println("Cleanup cancelled")
-			return nil
+	// Unless purged, the worker goes to the trash: its branch is kept and a
+	// tombstone saves its uncommitted changes, so nothing needs confirming
+	var tomb *trash.Tombstone
+	if flags["purge"] != "true" {
+		if tomb, err = c.trashWorker(ctx.Repo, workerName); err != nil {
+			fmt.Printf("Warning: failed to move worker to the trash, it will be deleted: %v\n", err)
 		}
 	}
 
-	// Check for unpushed commits
-	if err := checkUnpushedCommits(wtPath, "Worker", "cleanup"); err != nil {
-		return nil
+	if tomb == nil {
+		// Check for uncommitted changes
+		hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+		if err != nil {
+			fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
+		} else if hasUncommitted {
+			fmt.Println("\nWarning: Worker has uncommitted changes!")
+			fmt.Println("Files may be lost if you continue with cleanup.")
+			fmt.Print("Continue with cleanup? [y/N]: ")
+
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cleanup cancelled")
+				return nil
+			}
+		}
+
+		// Check for unpushed commits
+		if err := checkUnpushedCommits(wtPath, "Worker", "cleanup"); err != nil {
+			return nil
+		}
 	}
 
 	// Kill tmux window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := sanitizeTmuxSessionName(ctx.Repo)
 	tmuxWindow := workerInfo["tmux_window"].(string)
 	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
-	if err := cmd.Run(); err != nil {
+	if _, err := tmuxCommand("kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)); err != nil {
 		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
 	}
 
 	// Remove worktree
-	repoPath := c.paths.RepoDir(repoName)
+	repoPath := c.paths.RepoDir(ctx.Repo)
 	wt := worktree.NewManager(repoPath)
 
 	fmt.Printf("Removing worktree: %s\n", wtPath)
-	if err := wt.Remove(wtPath, false); err != nil {
+	if err := wt.Remove(wtPath, tomb != nil); err != nil {
 		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
 	}
 
@@ -2810,7 +7406,7 @@ func (c *CLI) removeWorker(args []string) error {
 	resp, err = client.Send(socket.Request{
 		Command: "remove_agent",
 		Args: map[string]interface{}{
-			"repo":  repoName,
+			"repo":  ctx.Repo,
 			"agent": workerName,
 		},
 	})
@@ -2818,33 +7414,131 @@ func (c *CLI) removeWorker(args []string) error {
 		return fmt.Errorf("failed to unregister worker: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to unregister worker: %s", resp.Error)
+		return daemonError("failed to unregister worker", resp)
 	}
 
+	if tomb != nil {
+		fmt.Printf("✓ Worker moved to the trash until %s\n", tomb.ExpiresAt.Format("2006-01-02 15:04"))
+		fmt.Printf("Restore it with: multiclaude worker undelete %s --repo %s\n", workerName, ctx.Repo)
+		return nil
+	}
 	fmt.Println("✓ Worker removed successfully")
 	return nil
 }
 
+// trashWorker saves a tombstone of a worker that is about to be removed
+func (c *CLI) trashWorker(repoName, workerName string) (*trash.Tombstone, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return nil, err
+	}
+	agent, exists := st.GetAgent(repoName, workerName)
+	if !exists {
+		return nil, errors.AgentNotFound("worker", workerName, repoName)
+	}
+	tomb, err := trash.Capture(export.Source{
+		Repo:     repoName,
+		Name:     workerName,
+		Agent:    agent,
+		BaseRef:  "origin/" + c.repoDefaultBranch(repoName),
+		Messages: messages.NewManager(c.paths.MessagesDir),
+		Redactor: c.redactor(),
+	}, trash.DefaultRetention, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := trash.Save(c.paths, tomb); err != nil {
+		return nil, err
+	}
+	return tomb, nil
+}
+
+// undeleteWorker restores a removed worker from the trash
+func (c *CLI) undeleteWorker(ctx CommandContext, args []string) error {
+	_, posArgs := ParseFlags(args)
+	var workerName string
+	if len(posArgs) > 0 {
+		workerName = posArgs[0]
+	} else {
+		tombstones, err := trash.List(c.paths, ctx.Repo)
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read the trash", err)
+		}
+		if len(tombstones) == 0 {
+			fmt.Printf("No removed workers in the trash of %s\n", ctx.Repo)
+			return nil
+		}
+		var items []SelectableItem
+		for _, t := range tombstones {
+			items = append(items, SelectableItem{
+				Name:        t.Name,
+				Description: fmt.Sprintf("removed %s: %s", format.TimeAgo(t.DeletedAt), format.Truncate(t.Agent.Task, 40)),
+			})
+		}
+		selected, err := SelectFromList("Select worker to restore:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		workerName = selected
+	}
+
+	return c.createWorker(ctx, []string{"--undelete", workerName})
+}
+
+// loadTombstone reads a removed worker's tombstone, checking that its name
+// is free to be used again
+func (c *CLI) loadTombstone(repoName, workerName string) (*trash.Tombstone, error) {
+	tomb, err := trash.Load(c.paths, repoName, workerName)
+	if err != nil {
+		return nil, errors.New(errors.CategoryNotFound, err.Error()).
+			WithSuggestion(fmt.Sprintf("multiclaude worker undelete --repo %s", repoName))
+	}
+	if st, err := c.loadState(); err == nil {
+		if _, exists := st.GetAgent(repoName, workerName); exists {
+			return nil, errors.New(errors.CategoryUsage, fmt.Sprintf("an agent named '%s' already exists in repo '%s'", workerName, repoName))
+		}
+	}
+	return tomb, nil
+}
+
+// restoredWorkerContext tells a worker restored from the trash where it left off
+func restoredWorkerContext(t *trash.Tombstone) string {
+	var sb strings.Builder
+	sb.WriteString("## Restored Worker\n\n")
+	sb.WriteString(fmt.Sprintf("**You were removed on %s and have been restored from the trash.**\n\n", t.DeletedAt.Format("2006-01-02 15:04")))
+	sb.WriteString(fmt.Sprintf("Your branch %s still has the commits you made. ", t.Branch))
+	if t.Changes != "" {
+		sb.WriteString("The changes you hadn't committed were re-applied to the worktree. ")
+	}
+	sb.WriteString("Check `git status` and `git log` to see where you left off before continuing.\n")
+	sb.WriteString("\n---\n\n")
+	return sb.String()
+}
+
 // Workspace command implementations
 
 // workspaceDefault handles `multiclaude workspace` with no subcommand or `multiclaude workspace <name>`
-func (c *CLI) workspaceDefault(args []string) error {
+func (c *CLI) workspaceDefault(ctx CommandContext, args []string) error {
 	// If no args, list workspaces
 	if len(args) == 0 {
-		return c.listWorkspaces(args)
+		return c.listWorkspaces(ctx, args)
 	}
 
 	// If first arg looks like a workspace name (not a flag), treat as connect
 	if !strings.HasPrefix(args[0], "-") {
-		return c.connectWorkspace(args)
+		return c.connectWorkspace(ctx, args)
 	}
 
 	// Otherwise list with flags
-	return c.listWorkspaces(args)
+	return c.listWorkspaces(ctx, args)
 }
 
 // addWorkspace creates a new workspace
-func (c *CLI) addWorkspace(args []string) error {
+func (c *CLI) addWorkspace(ctx CommandContext, args []string) error {
 	flags, posArgs := ParseFlags(args)
 
 	if len(posArgs) < 1 {
@@ -2858,34 +7552,28 @@ func (c *CLI) addWorkspace(args []string) error {
 		return err
 	}
 
-	// Determine repository using standard resolution chain
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return err
-	}
-
 	// Determine branch to start from
 	startBranch := "HEAD" // Default to current branch/HEAD
 	if branch, ok := flags["branch"]; ok {
 		startBranch = branch
-		fmt.Printf("Creating workspace '%s' in repo '%s' from branch '%s'\n", workspaceName, repoName, branch)
+		fmt.Printf("Creating workspace '%s' in repo '%s' from branch '%s'\n", workspaceName, ctx.Repo, branch)
 	} else {
-		fmt.Printf("Creating workspace '%s' in repo '%s'\n", workspaceName, repoName)
+		fmt.Printf("Creating workspace '%s' in repo '%s'\n", workspaceName, ctx.Repo)
 	}
 
 	// Check if workspace already exists
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo": ctx.Repo,
 		},
 	})
 	if err != nil {
 		return errors.DaemonCommunicationFailed("checking existing workspaces", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to check existing workspaces", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to check existing workspaces", resp)
 	}
 
 	agents, _ := resp.Data.([]interface{})
@@ -2894,31 +7582,30 @@ func (c *CLI) addWorkspace(args []string) error {
 			agentType, _ := agentMap["type"].(string)
 			name, _ := agentMap["name"].(string)
 			if agentType == "workspace" && name == workspaceName {
-				return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, repoName)
+				return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, ctx.Repo)
 			}
 		}
 	}
 
 	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
+	repoPath := c.paths.RepoDir(ctx.Repo)
 
 	// Create worktree
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, workspaceName)
+	wt := c.worktreeManager(ctx.Repo)
+	wtPath := c.paths.AgentWorktree(ctx.Repo, workspaceName)
 	branchName := fmt.Sprintf("workspace/%s", workspaceName)
 
 	fmt.Printf("Creating worktree at: %s\n", wtPath)
-	if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
+	if err := worktreeSetupWarning(wt.CreateNewBranch(wtPath, branchName, startBranch)); err != nil {
 		return errors.WorktreeCreationFailed(err)
 	}
 
 	// Get tmux session name
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := sanitizeTmuxSessionName(ctx.Repo)
 
 	// Create tmux window for workspace (detached so it doesn't switch focus)
 	fmt.Printf("Creating tmux window: %s\n", workspaceName)
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workspaceName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
+	if _, err := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", workspaceName, "-c", wtPath); err != nil {
 		return errors.TmuxOperationFailed("create window", err)
 	}
 
@@ -2941,7 +7628,7 @@ func (c *CLI) addWorkspace(args []string) error {
 
 	// Start Claude in workspace window (skip in test mode)
 	var workspacePID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+	if !simagent.Skip() {
 		// Resolve claude binary
 		claudeBinary, err := c.getClaudeBinary()
 		if err != nil {
@@ -2949,14 +7636,14 @@ func (c *CLI) addWorkspace(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, "")
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, ctx.Repo, "")
 		if err != nil {
 			return fmt.Errorf("failed to start workspace Claude: %w", err)
 		}
 		workspacePID = pid
 
 		// Set up output capture for workspace
-		if err := c.setupOutputCapture(tmuxSession, workspaceName, repoName, workspaceName, "workspace"); err != nil {
+		if err := c.setupOutputCapture(tmuxSession, workspaceName, ctx.Repo, workspaceName, "workspace"); err != nil {
 			fmt.Printf("Warning: failed to setup output capture for workspace: %v\n", err)
 		}
 	}
@@ -2965,7 +7652,7 @@ func (c *CLI) addWorkspace(args []string) error {
 	resp, err = client.Send(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
-			"repo":          repoName,
+			"repo":          ctx.Repo,
 			"agent":         workspaceName,
 			"type":          "workspace",
 			"worktree_path": wtPath,
@@ -2975,46 +7662,252 @@ func (c *CLI) addWorkspace(args []string) error {
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to register workspace: %w", err)
+		return fmt.Errorf("failed to register workspace: %w", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to register workspace", resp)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Workspace created successfully!")
+	fmt.Printf("  Name: %s\n", workspaceName)
+	fmt.Printf("  Branch: %s\n", branchName)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
+
+	return nil
+}
+
+// removeWorkspace removes a workspace
+func (c *CLI) removeWorkspace(ctx CommandContext, args []string) error {
+	_, remainingArgs := ParseFlags(args)
+
+	// Get workspace info
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": ctx.Repo,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting workspace info", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to get workspace info", resp)
+	}
+
+	agents, _ := resp.Data.([]interface{})
+
+	// Determine workspace name - from args or interactive selection
+	var workspaceName string
+	if len(remainingArgs) > 0 {
+		workspaceName = remainingArgs[0]
+	} else {
+		// Interactive selection
+		items := agentsToSelectableItems(agents, []string{"workspace"})
+		if len(items) == 0 {
+			return errors.NoWorkspacesFound(ctx.Repo)
+		}
+		selected, err := SelectFromList("Select workspace to remove:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		workspaceName = selected
+	}
+
+	fmt.Printf("Removing workspace '%s' from repo '%s'\n", workspaceName, ctx.Repo)
+
+	// Find workspace
+	var workspaceInfo map[string]interface{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			name, _ := agentMap["name"].(string)
+			if agentType == "workspace" && name == workspaceName {
+				workspaceInfo = agentMap
+				break
+			}
+		}
+	}
+
+	if workspaceInfo == nil {
+		return errors.AgentNotFound("workspace", workspaceName, ctx.Repo)
+	}
+
+	// Get worktree path
+	wtPath := workspaceInfo["worktree_path"].(string)
+
+	// Check for uncommitted changes
+	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	if err != nil {
+		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
+	} else if hasUncommitted {
+		fmt.Println("\nWarning: Workspace has uncommitted changes!")
+		fmt.Println("Files may be lost if you continue with removal.")
+		fmt.Print("Continue with removal? [y/N]: ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Removal cancelled")
+			return nil
+		}
+	}
+
+	// Check for unpushed commits
+	if err := checkUnpushedCommits(wtPath, "Workspace", "removal"); err != nil {
+		return nil
+	}
+
+	// Check for an editor still open on the worktree
+	if editor := editorLabel(workspaceInfo); editor != "" {
+		fmt.Printf("\nWarning: Workspace is open in %s!\n", editor)
+		fmt.Println("Unsaved editor changes will be lost, and the editor will be left on a deleted directory.")
+		fmt.Print("Continue with removal? [y/N]: ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Removal cancelled")
+			return nil
+		}
+	}
+
+	// Kill tmux window
+	tmuxSession := sanitizeTmuxSessionName(ctx.Repo)
+	tmuxWindow := workspaceInfo["tmux_window"].(string)
+	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
+	if _, err := tmuxCommand("kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)); err != nil {
+		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
+	}
+
+	// Remove worktree
+	repoPath := c.paths.RepoDir(ctx.Repo)
+	wt := worktree.NewManager(repoPath)
+
+	fmt.Printf("Removing worktree: %s\n", wtPath)
+	if err := wt.Remove(wtPath, false); err != nil {
+		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
+	}
+
+	// Unregister from daemon
+	resp, err = client.Send(socket.Request{
+		Command: "remove_agent",
+		Args: map[string]interface{}{
+			"repo":  ctx.Repo,
+			"agent": workspaceName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unregister workspace: %w", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to unregister workspace", resp)
+	}
+
+	fmt.Println("✓ Workspace removed successfully")
+	return nil
+}
+
+// listWorkspaces lists all workspaces in a repository
+func (c *CLI) listWorkspaces(ctx CommandContext, args []string) error {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": ctx.Repo,
+			"rich": true,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing workspaces", err)
 	}
+
 	if !resp.Success {
-		return fmt.Errorf("failed to register workspace: %s", resp.Error)
+		return daemonError("failed to list workspaces", resp)
+	}
+
+	agents, ok := resp.Data.([]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	// Filter for workspaces
+	workspaces := []map[string]interface{}{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			if agentType == "workspace" {
+				workspaces = append(workspaces, agentMap)
+			}
+		}
+	}
+
+	if len(workspaces) == 0 {
+		fmt.Printf("No workspaces in repository '%s'\n", ctx.Repo)
+		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
+		return nil
 	}
 
+	format.Header("Workspaces in '%s' (%d):", ctx.Repo, len(workspaces))
 	fmt.Println()
-	fmt.Println("✓ Workspace created successfully!")
-	fmt.Printf("  Name: %s\n", workspaceName)
-	fmt.Printf("  Branch: %s\n", branchName)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
-	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
+
+	table := format.NewColoredTable("NAME", "BRANCH", "STATUS", "EDITOR")
+	for _, ws := range workspaces {
+		name, _ := ws["name"].(string)
+		status, _ := ws["status"].(string)
+		branch, _ := ws["branch"].(string)
+
+		// Format status with color
+		statusCell := formatAgentStatusCell(status)
+
+		// Format branch
+		branchCell := format.ColorCell(branch, format.Cyan)
+		if branch == "" {
+			branchCell = format.ColorCell("-", format.Dim)
+		}
+
+		// Where the worktree is open, if anywhere
+		editorCell := format.ColorCell("-", format.Dim)
+		if editor := editorLabel(ws); editor != "" {
+			editorCell = format.ColorCell(editor, format.Green)
+		}
+
+		table.AddRow(
+			format.Cell(name),
+			branchCell,
+			statusCell,
+			editorCell,
+		)
+	}
+	table.Print()
 
 	return nil
 }
 
-// removeWorkspace removes a workspace
-func (c *CLI) removeWorkspace(args []string) error {
+// connectWorkspace attaches to a workspace
+func (c *CLI) connectWorkspace(ctx CommandContext, args []string) error {
 	flags, remainingArgs := ParseFlags(args)
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
-	}
-
 	// Get workspace info
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo": ctx.Repo,
 		},
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting workspace info", err)
+		return fmt.Errorf("failed to get workspace info: %w (is daemon running?)", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get workspace info", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to get workspace info", resp)
 	}
 
 	agents, _ := resp.Data.([]interface{})
@@ -3027,9 +7920,9 @@ func (c *CLI) removeWorkspace(args []string) error {
 		// Interactive selection
 		items := agentsToSelectableItems(agents, []string{"workspace"})
 		if len(items) == 0 {
-			return errors.NoWorkspacesFound(repoName)
+			return errors.NoWorkspacesFound(ctx.Repo)
 		}
-		selected, err := SelectFromList("Select workspace to remove:", items)
+		selected, err := SelectFromList("Select workspace to connect:", items)
 		if err != nil {
 			return err
 		}
@@ -3040,8 +7933,6 @@ func (c *CLI) removeWorkspace(args []string) error {
 		workspaceName = selected
 	}
 
-	fmt.Printf("Removing workspace '%s' from repo '%s'\n", workspaceName, repoName)
-
 	// Find workspace
 	var workspaceInfo map[string]interface{}
 	for _, agent := range agents {
@@ -3056,338 +7947,788 @@ func (c *CLI) removeWorkspace(args []string) error {
 	}
 
 	if workspaceInfo == nil {
-		return errors.AgentNotFound("workspace", workspaceName, repoName)
+		return errors.WorkspaceNotFound(workspaceName, ctx.Repo)
 	}
 
-	// Get worktree path
-	wtPath := workspaceInfo["worktree_path"].(string)
+	// Open the worktree in an editor instead of attaching
+	if editor := flags["editor"]; editor != "" {
+		return c.openWorkspaceInEditor(ctx.Repo, workspaceName, workspaceInfo, editor, flags["remote"])
+	}
 
-	// Check for uncommitted changes
-	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
-	if err != nil {
-		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
-	} else if hasUncommitted {
-		fmt.Println("\nWarning: Workspace has uncommitted changes!")
-		fmt.Println("Files may be lost if you continue with removal.")
-		fmt.Print("Continue with removal? [y/N]: ")
+	// Get tmux session and window
+	tmuxSession := sanitizeTmuxSessionName(ctx.Repo)
+	tmuxWindow := workspaceInfo["tmux_window"].(string)
 
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
-			fmt.Println("Removal cancelled")
-			return nil
-		}
+	// Attach to tmux
+	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
+
+	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
+	tmuxArgs := []string{"attach", "-t", target}
+	if readOnly {
+		tmuxArgs = append(tmuxArgs, "-r")
 	}
 
-	// Check for unpushed commits
-	if err := checkUnpushedCommits(wtPath, "Workspace", "removal"); err != nil {
-		return nil
+	// Attaching is interactive and lasts as long as the user stays, so it
+	// runs without a timeout or retries
+	cmd := exec.Command("tmux", tmuxArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// validateWorkspaceName validates that a workspace name follows branch name restrictions
+func validateWorkspaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("workspace name cannot be empty")
 	}
 
-	// Kill tmux window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
-	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
+	// Git branch name restrictions
+	// - Cannot start with . or -
+	// - Cannot contain consecutive dots ..
+	// - Cannot contain \ or any of these characters: ~ ^ : ? * [ @ { } space
+	// - Cannot end with . or /
+	// - Cannot be "." or ".."
+
+	if name == "." || name == ".." {
+		return fmt.Errorf("workspace name cannot be '.' or '..'")
 	}
 
-	// Remove worktree
-	repoPath := c.paths.RepoDir(repoName)
-	wt := worktree.NewManager(repoPath)
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("workspace name cannot start with '.' or '-'")
+	}
 
-	fmt.Printf("Removing worktree: %s\n", wtPath)
-	if err := wt.Remove(wtPath, false); err != nil {
-		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
+		return fmt.Errorf("workspace name cannot end with '.' or '/'")
 	}
 
-	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": workspaceName,
-		},
-	})
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("workspace name cannot contain '..'")
+	}
+
+	invalidChars := []string{"\\", "~", "^", ":", "?", "*", "[", "@", "{", "}", " ", "\t", "\n"}
+	for _, char := range invalidChars {
+		if strings.Contains(name, char) {
+			return fmt.Errorf("workspace name cannot contain '%s'", char)
+		}
+	}
+
+	return nil
+}
+
+// getReposList is a helper to get the list of repos
+func (c *CLI) getReposList() []string {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{Command: "list_repos"})
 	if err != nil {
-		return fmt.Errorf("failed to unregister workspace: %w", err)
+		return []string{}
 	}
+
 	if !resp.Success {
-		return fmt.Errorf("failed to unregister workspace: %s", resp.Error)
+		return []string{}
 	}
 
-	fmt.Println("✓ Workspace removed successfully")
-	return nil
+	repos, ok := resp.Data.([]interface{})
+	if !ok {
+		return []string{}
+	}
+
+	result := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		if repoStr, ok := repo.(string); ok {
+			result = append(result, repoStr)
+		}
+	}
+
+	return result
 }
 
-// listWorkspaces lists all workspaces in a repository
-func (c *CLI) listWorkspaces(args []string) error {
-	flags, _ := ParseFlags(args)
+func (c *CLI) sendMessage(ctx CommandContext, args []string) error {
+	// Flags are matched exactly (--idempotency-key only in the --flag=value
+	// form) so message bodies can contain dashes
+	var idempotencyKey, templateName string
+	vars := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if key, ok := strings.CutPrefix(arg, "--idempotency-key="); ok {
+			idempotencyKey = key
+			continue
+		}
+		if (arg == "--template" || arg == "--var") && i+1 < len(args) {
+			i++
+			arg += "=" + args[i]
+		}
+		if name, ok := strings.CutPrefix(arg, "--template="); ok {
+			templateName = name
+			continue
+		}
+		if v, ok := strings.CutPrefix(arg, "--var="); ok {
+			key, value, found := strings.Cut(v, "=")
+			if !found || key == "" {
+				return errors.InvalidUsage(fmt.Sprintf("invalid --var %q: use --var name=value", v))
+			}
+			vars[key] = value
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	args = rest
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+	if len(args) < 2 && (templateName == "" || len(args) < 1) {
+		return errors.InvalidUsage("usage: multiclaude message send <to> <message> | <to> --template <name> [--var name=value...] [note]")
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-			"rich": true,
-		},
-	})
+	to := args[0]
+	body := strings.Join(args[1:], " ")
+	repoName, agentName := ctx.Repo, ctx.Agent
+
+	if templateName != "" {
+		rendered, err := renderMessageTemplate(c.paths.RepoDir(repoName), templateName, vars, map[string]string{"to": to, "from": agentName, "repo": repoName})
+		if err != nil {
+			return err
+		}
+		// Text after the template goes underneath it as a note
+		if body != "" {
+			rendered += "\n\n" + body
+		}
+		body = rendered
+	}
+
+	// <agent>@<peer> goes to an agent on a teammate's daemon via the federation relay
+	if !strings.HasPrefix(to, "@") && strings.Contains(to, "@") {
+		return c.sendFederatedMessage(repoName, agentName, to, body)
+	}
+
+	// Resolve cross-repo (<repo>/<agent>) and project (@<project>) recipients
+	st, err := c.loadState()
 	if err != nil {
-		return errors.DaemonCommunicationFailed("listing workspaces", err)
+		return err
+	}
+	toRepo, toAgent, err := resolveMessageRecipient(st, repoName, to)
+	if err != nil {
+		return err
+	}
+	from := agentName
+	if toRepo != repoName {
+		from = messageSenderAddress(repoName, agentName)
 	}
 
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+	// Create message manager
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+
+	// Send message; a retry with the same --idempotency-key returns the
+	// original. Without one every send is a new message.
+	msg, err := msgMgr.SendIdempotent(toRepo, from, toAgent, body, idempotencyKey)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
 	}
 
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	// Trigger immediate routing (best-effort, polling is fallback)
+	client := c.daemonClient()
+	_, _ = client.Send(socket.Request{Command: "route_messages"})
+	// Ignore errors - 2-minute polling fallback will catch it
+
+	fmt.Printf("Message sent to %s (ID: %s)\n", to, msg.ID)
+	return nil
+}
+
+// renderMessageTemplate fills in one of the repository's message templates.
+// to, from and repo are always available, but --var values take precedence.
+func renderMessageTemplate(repoPath, name string, vars, builtins map[string]string) (string, error) {
+	tmpl, err := messages.LoadTemplate(repoPath, name)
+	if err != nil {
+		return "", errors.New(errors.CategoryNotFound, err.Error()).
+			WithSuggestion("multiclaude message templates")
+	}
+	for key, value := range builtins {
+		if _, ok := vars[key]; !ok {
+			vars[key] = value
+		}
+	}
+	body, err := tmpl.Render(vars)
+	if err != nil {
+		return "", errors.InvalidUsage(err.Error()).
+			WithSuggestion(fmt.Sprintf("multiclaude message templates %s", name))
 	}
+	return body, nil
+}
 
-	// Filter for workspaces
-	workspaces := []map[string]interface{}{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "workspace" {
-				workspaces = append(workspaces, agentMap)
-			}
+// listMessageTemplates lists a repository's message templates, or shows one
+func (c *CLI) listMessageTemplates(ctx CommandContext, flags *FlagSet) error {
+	repoPath := c.paths.RepoDir(ctx.Repo)
+
+	if len(flags.Args()) > 0 {
+		tmpl, err := messages.LoadTemplate(repoPath, flags.Args()[0])
+		if err != nil {
+			return errors.New(errors.CategoryNotFound, err.Error()).
+				WithSuggestion("multiclaude message templates")
+		}
+		fmt.Printf("Template: %s\n", tmpl.Name)
+		fmt.Printf("File: %s\n", tmpl.Path)
+		if vars := tmpl.Vars(); len(vars) > 0 {
+			fmt.Printf("Variables: %s\n", strings.Join(vars, ", "))
+		}
+		fmt.Printf("\n%s\n", tmpl.Body)
+		return nil
+	}
+
+	templates, warnings := messages.ListTemplates(repoPath)
+	dir := filepath.Join(repoPath, messages.RepoTemplatesDir)
+	if len(templates) == 0 {
+		fmt.Printf("No message templates for %s. Add them as %s/<name>.md\n", ctx.Repo, dir)
+	} else {
+		fmt.Printf("Message templates for %s:\n\n", ctx.Repo)
+		table := format.NewColoredTable("Name", "Variables", "Description").Flexible("Description", 20)
+		for _, tmpl := range templates {
+			table.AddRow(
+				format.Cell(tmpl.Name),
+				format.Cell(strings.Join(tmpl.Vars(), ", ")),
+				format.Cell(tmpl.Description),
+			)
 		}
+		table.Print()
+		fmt.Printf("\nSend one with: multiclaude message send <to> --template <name> --var <name>=<value>\n")
 	}
 
-	if len(workspaces) == 0 {
-		fmt.Printf("No workspaces in repository '%s'\n", repoName)
-		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
-		return nil
+	if len(warnings) > 0 {
+		fmt.Println("\nSkipped:")
+		for _, w := range warnings {
+			fmt.Printf("  %s\n", w)
+		}
 	}
+	return nil
+}
 
-	format.Header("Workspaces in '%s' (%d):", repoName, len(workspaces))
-	fmt.Println()
+// askTimeout is how long 'multiclaude ask' waits for an answer by default
+const askTimeout = 10 * time.Minute
 
-	table := format.NewColoredTable("NAME", "BRANCH", "STATUS")
-	for _, ws := range workspaces {
-		name, _ := ws["name"].(string)
-		status, _ := ws["status"].(string)
-		branch, _ := ws["branch"].(string)
+// askPollInterval is how often a waiting 'multiclaude ask' checks its ticket
+var askPollInterval = 2 * time.Second
 
-		// Format status with color
-		statusCell := formatAgentStatusCell(status)
+// ask sends a question to another agent through the daemon, which tracks it
+// as a ticket, and waits for the answer unless --no-wait is given
+func (c *CLI) ask(ctx CommandContext, args []string) error {
+	flags, posArgs := ParseFlags(args)
+	client := c.daemonClient()
 
-		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
-			branchCell = format.ColorCell("-", format.Dim)
+	if id := flags["ticket"]; id != "" {
+		ticket, err := c.getTicket(client, id)
+		if err != nil {
+			return err
 		}
-
-		table.AddRow(
-			format.Cell(name),
-			branchCell,
-			statusCell,
-		)
+		return printTicket(ticket)
 	}
-	table.Print()
 
-	return nil
-}
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude ask <agent> <question> [--timeout <duration>] [--no-wait]")
+	}
+	to := posArgs[0]
+	question := strings.Join(posArgs[1:], " ")
 
-// connectWorkspace attaches to a workspace
-func (c *CLI) connectWorkspace(args []string) error {
-	flags, remainingArgs := ParseFlags(args)
+	timeout := askTimeout
+	if s, ok := flags["timeout"]; ok {
+		var err error
+		if timeout, err = parseInterval(s); err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --timeout %q (e.g., 90s, 10m, 1h)", s))
+		}
+	}
+	wait := flags["no-wait"] != "true"
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
+	// Agents ask as themselves; from a plain terminal you ask as the human
+	repoName, from := ctx.Repo, ctx.Agent
+	if repoName == "" {
 		return errors.NotInRepo()
 	}
+	if from == "" {
+		from = notify.HumanRecipient
+	}
 
-	// Get workspace info
-	client := socket.NewClient(c.paths.DaemonSock)
 	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
+		Command: "ask",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo":            repoName,
+			"from":            from,
+			"to":              to,
+			"question":        question,
+			"timeout_seconds": timeout.Seconds(),
+			"wait":            wait,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get workspace info: %w (is daemon running?)", err)
+		return errors.DaemonCommunicationFailed("asking question", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to get workspace info: %s", resp.Error)
+		return daemonError(fmt.Sprintf("failed to ask %s", to), resp)
 	}
+	data, _ := resp.Data.(map[string]interface{})
+	id, _ := data["ticket"].(string)
 
-	agents, _ := resp.Data.([]interface{})
+	if !wait {
+		fmt.Printf("Asked %s (ticket %s). The answer will arrive as a message.\n", to, id)
+		return nil
+	}
 
-	// Determine workspace name - from args or interactive selection
-	var workspaceName string
-	if len(remainingArgs) > 0 {
-		workspaceName = remainingArgs[0]
-	} else {
-		// Interactive selection
-		items := agentsToSelectableItems(agents, []string{"workspace"})
-		if len(items) == 0 {
-			return errors.NoWorkspacesFound(repoName)
-		}
-		selected, err := SelectFromList("Select workspace to connect:", items)
+	fmt.Fprintf(os.Stderr, "Asked %s (ticket %s); waiting up to %s for an answer...\n", to, id, timeout)
+	deadline := time.Now().Add(timeout)
+	for {
+		ticket, err := c.getTicket(client, id)
 		if err != nil {
 			return err
 		}
-		if selected == "" {
-			fmt.Println("Cancelled")
-			return nil
+		if status, _ := ticket["status"].(string); status != "pending" {
+			return printTicket(ticket)
 		}
-		workspaceName = selected
+		if time.Now().After(deadline) {
+			return noAnswerError(to, id)
+		}
+		time.Sleep(askPollInterval)
 	}
+}
 
-	// Find workspace
-	var workspaceInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				workspaceInfo = agentMap
-				break
-			}
-		}
+// getTicket fetches a ticket from the daemon
+func (c *CLI) getTicket(client *socket.Client, id string) (map[string]interface{}, error) {
+	resp, err := client.Send(socket.Request{
+		Command: "get_ticket",
+		Args:    map[string]interface{}{"ticket": id},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("checking ticket", err)
 	}
+	if !resp.Success {
+		return nil, errors.New(errors.CategoryNotFound, resp.Error)
+	}
+	ticket, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response format")
+	}
+	return ticket, nil
+}
 
-	if workspaceInfo == nil {
-		return errors.WorkspaceNotFound(workspaceName, repoName)
+// printTicket prints a ticket's answer, or returns an error if it has none
+func printTicket(ticket map[string]interface{}) error {
+	id, _ := ticket["ticket"].(string)
+	to, _ := ticket["to"].(string)
+	switch status, _ := ticket["status"].(string); status {
+	case "answered":
+		by, _ := ticket["answered_by"].(string)
+		answer, _ := ticket["answer"].(string)
+		fmt.Printf("Answer from %s (ticket %s):\n%s\n", by, id, answer)
+		return nil
+	case "pending":
+		fmt.Printf("Ticket %s: waiting for %s to answer\n", id, to)
+		return nil
+	default:
+		return noAnswerError(to, id)
 	}
+}
 
-	// Get tmux session and window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
+func noAnswerError(to, id string) error {
+	return errors.New(errors.CategoryRuntime, fmt.Sprintf("no answer from %s before the deadline (ticket %s)", to, id)).
+		WithSuggestion("a late answer arrives as a message: multiclaude message list")
+}
 
-	// Attach to tmux
-	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
+// answer answers a ticket. Everything after the ticket ID is the answer, so
+// it may contain dashes.
+func (c *CLI) answer(args []string) error {
+	flags, args := cutContextFlags(args)
+	if len(args) < 2 {
+		return errors.InvalidUsage("usage: multiclaude answer <ticket> <answer>")
+	}
+	id := args[0]
+	text := strings.Join(args[1:], " ")
 
-	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
-	tmuxArgs := []string{"attach", "-t", target}
-	if readOnly {
-		tmuxArgs = append(tmuxArgs, "-r")
+	answerer, err := c.resolveAgent(flags)
+	from := answerer.Agent
+	if err != nil || from == "" {
+		from = notify.HumanRecipient
 	}
 
-	cmd := exec.Command("tmux", tmuxArgs...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "answer",
+		Args: map[string]interface{}{
+			"ticket": id,
+			"answer": text,
+			"from":   from,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("answering ticket", err)
+	}
+	if !resp.Success {
+		return daemonError(fmt.Sprintf("failed to answer %s", id), resp)
+	}
 
-	return cmd.Run()
+	data, _ := resp.Data.(map[string]interface{})
+	asker, _ := data["from"].(string)
+	if late, _ := data["late"].(bool); late {
+		fmt.Printf("Answered %s late; %s stopped waiting, so the answer was sent as a message\n", id, asker)
+	} else {
+		fmt.Printf("Answered %s for %s\n", id, asker)
+	}
+	return nil
 }
 
-// validateWorkspaceName validates that a workspace name follows branch name restrictions
-func validateWorkspaceName(name string) error {
-	if name == "" {
-		return fmt.Errorf("workspace name cannot be empty")
-	}
+// queuedTask is a task as list_tasks returns it
+type queuedTask struct {
+	tasks.Task
+	Pending  []string `json:"pending"`
+	Blocking []string `json:"blocking"`
+}
 
-	// Git branch name restrictions
-	// - Cannot start with . or -
-	// - Cannot contain consecutive dots ..
-	// - Cannot contain \ or any of these characters: ~ ^ : ? * [ @ { } space
-	// - Cannot end with . or /
-	// - Cannot be "." or ".."
+// fetchTasks returns a repository's tasks from the daemon
+func (c *CLI) fetchTasks(repoName string) ([]queuedTask, error) {
+	resp, err := c.daemonClient().Send(socket.Request{
+		Command: "list_tasks",
+		Args:    map[string]interface{}{"repo": repoName},
+	})
+	if err != nil {
+		return nil, errors.DaemonCommunicationFailed("listing tasks", err)
+	}
+	if !resp.Success {
+		return nil, daemonError("failed to list tasks", resp)
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		return nil, err
+	}
+	var list []queuedTask
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.Wrap(errors.CategoryRuntime, "unexpected task list from daemon", err)
+	}
+	return list, nil
+}
 
-	if name == "." || name == ".." {
-		return fmt.Errorf("workspace name cannot be '.' or '..'")
+// addTask queues a task, optionally after other tasks
+func (c *CLI) addTask(ctx CommandContext, flags *FlagSet) error {
+	task := strings.Join(flags.Args(), " ")
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude task add <task description> [--after <task-id,...>]")
+	}
+	var after []string
+	for _, id := range strings.Split(flags.String("after"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			after = append(after, id)
+		}
 	}
 
-	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
-		return fmt.Errorf("workspace name cannot start with '.' or '-'")
+	resp, err := c.daemonClient().Send(socket.Request{
+		Command: "add_task",
+		Args: map[string]interface{}{
+			"repo":  ctx.Repo,
+			"task":  task,
+			"after": after,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("adding task", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to add task", resp).
+			WithSuggestion("multiclaude task list")
 	}
 
-	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
-		return fmt.Errorf("workspace name cannot end with '.' or '/'")
+	data, _ := resp.Data.(map[string]interface{})
+	id, _ := data["id"].(string)
+	if worker, _ := data["worker"].(string); data["status"] == string(tasks.StatusRunning) {
+		fmt.Printf("Task %s started as worker %s\n", id, worker)
+		return nil
 	}
+	pending, _ := data["pending"].([]interface{})
+	if len(pending) == 0 {
+		fmt.Printf("Task %s queued; it starts when %s's work hours begin\n", id, ctx.Repo)
+		format.Dimmed("Start it now with: multiclaude hours on --repo %s", ctx.Repo)
+		return nil
+	}
+	verb := "is"
+	if len(pending) > 1 {
+		verb = "are"
+	}
+	fmt.Printf("Task %s queued; it starts once %s %s merged or completed\n", id, strings.Join(interfaceSliceToStrings(pending), ", "), verb)
+	format.Dimmed("See the queue with: multiclaude task graph")
+	return nil
+}
 
-	if strings.Contains(name, "..") {
-		return fmt.Errorf("workspace name cannot contain '..'")
+// listTasks lists a repository's tasks with what each is waiting on
+func (c *CLI) listTasks(ctx CommandContext, flags *FlagSet) error {
+	list, err := c.fetchTasks(ctx.Repo)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Printf("No tasks for repository '%s'\n", ctx.Repo)
+		format.Dimmed("\nQueue one with: multiclaude task add <task> [--after <task-id>]")
+		return nil
 	}
 
-	invalidChars := []string{"\\", "~", "^", ":", "?", "*", "[", "@", "{", "}", " ", "\t", "\n"}
-	for _, char := range invalidChars {
-		if strings.Contains(name, char) {
-			return fmt.Errorf("workspace name cannot contain '%s'", char)
+	format.Header("Tasks for '%s':", ctx.Repo)
+	fmt.Println()
+	table := format.NewColoredTable("ID", "STATUS", "WORKER", "WAITING ON", "TASK").Flexible("TASK", 20)
+	for _, t := range list {
+		waiting := strings.Join(t.Pending, ", ")
+		if len(t.Blocking) > 0 {
+			waiting = "blocked by " + strings.Join(t.Blocking, ", ")
 		}
+		table.AddRow(
+			format.Cell(t.ID),
+			format.ColorCell(string(t.Status), format.StatusColor(taskFormatStatus(t.Status))),
+			format.Cell(t.Worker),
+			format.Cell(waiting),
+			format.Cell(t.Description),
+		)
 	}
+	table.Print()
 
+	fmt.Println()
+	for _, t := range list {
+		if t.Error != "" {
+			fmt.Printf("%s %s: %s\n", t.ID, t.Status, t.Error)
+		}
+	}
+	for _, t := range list {
+		if len(t.Blocking) > 0 {
+			format.Dimmed("%s is blocked for good: queue the failed work again with 'multiclaude task add', or cancel it with 'multiclaude task cancel %s'", t.ID, t.ID)
+		}
+	}
 	return nil
 }
 
-// getReposList is a helper to get the list of repos
-func (c *CLI) getReposList() []string {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{Command: "list_repos"})
+// taskGraph prints a repository's tasks as a tree of dependencies, or as DOT
+func (c *CLI) taskGraph(ctx CommandContext, flags *FlagSet) error {
+	list, err := c.fetchTasks(ctx.Repo)
 	if err != nil {
-		return []string{}
+		return err
+	}
+	graph := make([]*tasks.Task, len(list))
+	for i := range list {
+		graph[i] = &list[i].Task
+	}
+
+	if flags.Bool("dot") {
+		fmt.Print(tasks.DOT(graph))
+		return nil
+	}
+	if len(graph) == 0 {
+		fmt.Printf("No tasks for repository '%s'\n", ctx.Repo)
+		return nil
 	}
+	fmt.Print(tasks.Render(graph))
+	return nil
+}
+
+// cancelTask cancels a task that hasn't started
+func (c *CLI) cancelTask(args []string) error {
+	if len(args) != 1 {
+		return errors.InvalidUsage("usage: multiclaude task cancel <task-id>")
+	}
+	if _, err := c.sendDaemonRequest("cancel_task", map[string]interface{}{"id": args[0]}); err != nil {
+		return err
+	}
+	fmt.Printf("Cancelled task %s\n", args[0])
+	return nil
+}
 
+// showTrain lists the PRs the daemon has simulated on a repository's merge train
+func (c *CLI) showTrain(ctx CommandContext, flags *FlagSet) error {
+	resp, err := c.daemonClient().Send(socket.Request{
+		Command: "train_status",
+		Args:    map[string]interface{}{"repo": ctx.Repo},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting the merge train", err)
+	}
 	if !resp.Success {
-		return []string{}
+		return daemonError("failed to get the merge train", resp)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	raw, err := json.Marshal(data["entries"])
+	if err != nil {
+		return err
+	}
+	var entries []train.Entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "unexpected merge train from daemon", err)
 	}
 
-	repos, ok := resp.Data.([]interface{})
-	if !ok {
-		return []string{}
+	testCommand, _ := data["test_command"].(string)
+	if testCommand == "" {
+		fmt.Printf("No merge train for repository '%s': no test command is configured\n", ctx.Repo)
+		format.Dimmed("\nSet one with: multiclaude config %s --mq-test='make test'", ctx.Repo)
+		return nil
+	}
+	format.Header("Merge train for '%s':", ctx.Repo)
+	format.Dimmed("Test command: %s", testCommand)
+	fmt.Println()
+	if len(entries) == 0 {
+		fmt.Println("No PRs simulated yet")
+		return nil
 	}
 
-	result := make([]string, 0, len(repos))
-	for _, repo := range repos {
-		if repoStr, ok := repo.(string); ok {
-			result = append(result, repoStr)
+	table := format.NewColoredTable("PR", "STATUS", "BRANCH", "MERGED ONTO", "TESTED")
+	for _, e := range entries {
+		status := format.StatusCompleted
+		if e.Status == train.StatusFailed {
+			status = format.StatusError
+		}
+		onto := "base"
+		if len(e.Ahead) > 0 {
+			onto = "base + " + formatPRList(e.Ahead)
 		}
+		table.AddRow(
+			format.Cell(fmt.Sprintf("#%d", e.PR)),
+			format.ColorCell(string(e.Status), format.StatusColor(status)),
+			format.Cell(e.Branch),
+			format.Cell(onto),
+			format.Cell(format.TimeAgo(e.TestedAt)),
+		)
 	}
+	table.Print()
 
-	return result
+	fmt.Println()
+	var order []int
+	for _, e := range entries {
+		if e.Status == train.StatusPassed {
+			order = append(order, e.PR)
+		}
+	}
+	if len(order) > 0 {
+		fmt.Printf("Merge in this order: %s\n", formatPRList(order))
+	}
+	for _, e := range entries {
+		if e.Status == train.StatusFailed {
+			fmt.Printf("#%d failed: %s\n", e.PR, e.Reason)
+			if e.Log != "" {
+				format.Dimmed("  Log: %s", e.Log)
+			}
+		}
+	}
+	return nil
 }
 
-func (c *CLI) sendMessage(args []string) error {
-	if len(args) < 2 {
-		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message>")
+// formatPRList formats PR numbers as "#1, #2"
+func formatPRList(numbers []int) string {
+	list := make([]string, len(numbers))
+	for i, n := range numbers {
+		list[i] = fmt.Sprintf("#%d", n)
 	}
+	return strings.Join(list, ", ")
+}
 
-	to := args[0]
-	body := strings.Join(args[1:], " ")
+// taskFormatStatus returns the display status a task status is colored as
+func taskFormatStatus(status tasks.Status) format.Status {
+	switch status {
+	case tasks.StatusMerged, tasks.StatusCompleted:
+		return format.StatusCompleted
+	case tasks.StatusRunning, tasks.StatusReview:
+		return format.StatusRunning
+	case tasks.StatusFailed:
+		return format.StatusError
+	case tasks.StatusCancelled:
+		return format.StatusIdle
+	default:
+		return format.StatusPending
+	}
+}
 
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
+// parseInterval parses a Go duration (30s, 1h30m) or a parseDuration one (7d)
+func parseInterval(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		return err
+		d, err = parseDuration(s)
 	}
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return d, nil
+}
 
-	// Create message manager
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
+// sendFederatedMessage queues a message for an agent on a teammate's daemon
+func (c *CLI) sendFederatedMessage(repoName, agentName, to, body string) error {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "federation_send",
+		Args: map[string]interface{}{
+			"repo": repoName,
+			"from": agentName,
+			"to":   to,
+			"body": body,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("sending federated message", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to send message", resp)
+	}
 
-	// Send message
-	msg, err := msgMgr.Send(repoName, agentName, to, body)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+	fmt.Printf("Message queued for %s (ID: %v); it is delivered on the next federation sync\n", to, resp.Data)
+	return nil
+}
+
+// resolveMessageRecipient resolves a message recipient to the repository key and
+// agent name it should be delivered to. Recipients may be:
+//   - <agent>: an agent in the sender's repository
+//   - <repo>/<agent>: an agent in a repository sharing a project with the sender
+//   - @<project>: the project supervisor of a project containing the sender's repository
+//
+// fromRepo is the sender's repository, or a project key for a project supervisor.
+func resolveMessageRecipient(st *state.State, fromRepo, to string) (repoKey, agentName string, err error) {
+	fromProject, fromIsProject := state.ParseProjectKey(fromRepo)
+
+	if projectName, ok := state.ParseProjectKey(to); ok {
+		project, exists := st.GetProject(projectName)
+		if !exists {
+			return "", "", fmt.Errorf("project %q not found", projectName)
+		}
+		if !project.HasRepo(fromRepo) && fromProject != projectName {
+			return "", "", fmt.Errorf("repository %q is not a member of project %q", fromRepo, projectName)
+		}
+		return to, "supervisor", nil
 	}
 
-	// Trigger immediate routing (best-effort, polling is fallback)
-	client := socket.NewClient(c.paths.DaemonSock)
-	_, _ = client.Send(socket.Request{Command: "route_messages"})
-	// Ignore errors - 2-minute polling fallback will catch it
+	targetRepo, targetAgent, crossRepo := strings.Cut(to, "/")
+	if !crossRepo {
+		if fromIsProject {
+			return "", "", errors.InvalidUsage("project supervisors must address agents as <repo>/<agent>")
+		}
+		return fromRepo, to, nil
+	}
+	if targetRepo == "" || targetAgent == "" {
+		return "", "", errors.InvalidUsage(fmt.Sprintf("invalid recipient %q: expected <repo>/<agent>", to))
+	}
+	if targetRepo == fromRepo {
+		return fromRepo, targetAgent, nil
+	}
 
-	fmt.Printf("Message sent to %s (ID: %s)\n", to, msg.ID)
-	return nil
+	if fromIsProject {
+		project, exists := st.GetProject(fromProject)
+		if !exists || !project.HasRepo(targetRepo) {
+			return "", "", fmt.Errorf("repository %q is not a member of project %q", targetRepo, fromProject)
+		}
+		return targetRepo, targetAgent, nil
+	}
+	if !st.ReposShareProject(fromRepo, targetRepo) {
+		return "", "", fmt.Errorf("repositories %q and %q do not share a project; see 'multiclaude project create'", fromRepo, targetRepo)
+	}
+	return targetRepo, targetAgent, nil
 }
 
-func (c *CLI) listMessages(args []string) error {
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
-	if err != nil {
-		return err
+// messageSenderAddress returns the address recipients in other repositories
+// can reply to: <repo>/<agent>, or @<project> for a project supervisor
+func messageSenderAddress(repoKey, agentName string) string {
+	if _, ok := state.ParseProjectKey(repoKey); ok {
+		return repoKey
 	}
+	return repoKey + "/" + agentName
+}
+
+func (c *CLI) listMessages(ctx CommandContext, args []string) error {
+	repoName, agentName := ctx.Repo, ctx.Agent
 
 	msgMgr := messages.NewManager(c.paths.MessagesDir)
 
@@ -3408,10 +8749,17 @@ func (c *CLI) listMessages(args []string) error {
 		if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
 			status = messages.Status(fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt)))
 		}
+		from := msg.From
+		if msg.CCOf != "" {
+			from += " (cc of " + msg.CCOf + ")"
+		}
+		if msg.Priority != "" && msg.Priority != messages.PriorityNormal {
+			status = messages.Status(fmt.Sprintf("%s, %s priority", status, msg.Priority))
+		}
 		fmt.Printf("  [%s] %s - From: %s - %s - %s\n",
 			msg.ID,
 			formatTime(msg.Timestamp),
-			msg.From,
+			from,
 			status,
 			truncateString(msg.Body, 60))
 	}
@@ -3419,18 +8767,13 @@ func (c *CLI) listMessages(args []string) error {
 	return nil
 }
 
-func (c *CLI) readMessage(args []string) error {
+func (c *CLI) readMessage(ctx CommandContext, args []string) error {
 	if len(args) < 1 {
 		return errors.InvalidUsage("usage: multiclaude agent read-message <message-id>")
 	}
 
 	messageID := args[0]
-
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
-	if err != nil {
-		return err
-	}
+	repoName, agentName := ctx.Repo, ctx.Agent
 
 	msgMgr := messages.NewManager(c.paths.MessagesDir)
 
@@ -3462,18 +8805,13 @@ func (c *CLI) readMessage(args []string) error {
 	return nil
 }
 
-func (c *CLI) ackMessage(args []string) error {
+func (c *CLI) ackMessage(ctx CommandContext, args []string) error {
 	if len(args) < 1 {
 		return errors.InvalidUsage("usage: multiclaude agent ack-message <message-id>")
 	}
 
 	messageID := args[0]
-
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
-	if err != nil {
-		return err
-	}
+	repoName, agentName := ctx.Repo, ctx.Agent
 
 	msgMgr := messages.NewManager(c.paths.MessagesDir)
 
@@ -3609,13 +8947,11 @@ func normalizeGitHubURL(url string) string {
 // and tries to match it against known repositories in state.
 func (c *CLI) findRepoFromGitRemote() (string, error) {
 	// Run git remote get-url origin
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+	remoteURL, err := gitcmd.Run(context.Background(), "", "remote", "get-url", "origin")
 	if err != nil {
 		return "", fmt.Errorf("failed to get git remote: %w", err)
 	}
 
-	remoteURL := strings.TrimSpace(string(output))
 	if remoteURL == "" {
 		return "", fmt.Errorf("git remote URL is empty")
 	}
@@ -3649,27 +8985,33 @@ func (c *CLI) findRepoFromGitRemote() (string, error) {
 
 // resolveRepo determines the repository to use based on:
 // 1. Explicit --repo flag (highest priority)
-// 2. Git remote URL matching (if in a git repo with origin pointing to a tracked repo)
-// 3. Current working directory (if in a multiclaude directory)
-// 4. Current repo set via 'multiclaude repo use' (lowest priority)
+// 2. The MULTICLAUDE_REPO environment variable
+// 3. Git remote URL matching (if in a git repo with origin pointing to a tracked repo)
+// 4. Current working directory (if in a multiclaude directory)
+// 5. Current repo set via 'multiclaude repo use' (lowest priority)
 func (c *CLI) resolveRepo(flags map[string]string) (string, error) {
 	// 1. Check explicit --repo flag
 	if r, ok := flags["repo"]; ok {
 		return r, nil
 	}
 
-	// 2. Try to infer from git remote URL
+	// 2. Check the environment
+	if r := os.Getenv(EnvRepo); r != "" {
+		return r, nil
+	}
+
+	// 3. Try to infer from git remote URL
 	if repoName, err := c.findRepoFromGitRemote(); err == nil {
 		return repoName, nil
 	}
 
-	// 3. Try to infer from current working directory
+	// 4. Try to infer from current working directory
 	if inferred, err := c.inferRepoFromCwd(); err == nil {
 		return inferred, nil
 	}
 
-	// 4. Check current repo from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
+	// 5. Check current repo from daemon
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "get_current_repo",
 	})
@@ -3712,6 +9054,17 @@ func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
 		}
 	}
 
+	// Check if we're in a project supervisor directory
+	// Path format: ~/.multiclaude/projects/<project>
+	projectsDir := c.paths.ProjectDir("")
+	if hasPathPrefix(cwd, projectsDir) {
+		rel, err := filepath.Rel(projectsDir, cwd)
+		if err == nil && rel != "." {
+			projectName := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+			return state.ProjectKey(projectName), "supervisor", nil
+		}
+	}
+
 	// Check if we're in a main repo path
 	// Path format: ~/.multiclaude/repos/<repo>
 	if hasPathPrefix(cwd, c.paths.ReposDir) {
@@ -3724,8 +9077,7 @@ func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
 				tmuxWindow := os.Getenv("TMUX_PANE")
 				if tmuxWindow != "" {
 					// Get window name from tmux
-					cmd := exec.Command("tmux", "display-message", "-p", "#{window_name}")
-					output, err := cmd.Output()
+					output, err := tmuxCommand("display-message", "-p", "#{window_name}")
 					if err == nil {
 						windowName := strings.TrimSpace(string(output))
 						return parts[0], windowName, nil
@@ -3738,6 +9090,11 @@ func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
 		}
 	}
 
+	// Check if we're a solo agent working in the user's directory
+	if repoName, agentName, ok := c.soloAgentContext(cwd); ok {
+		return repoName, agentName, nil
+	}
+
 	return "", "", errors.NotInAgentContext()
 }
 
@@ -3769,6 +9126,19 @@ func formatTime(t time.Time) string {
 	return t.Format("Jan 02 15:04")
 }
 
+// interfaceSliceToStrings converts a JSON-decoded []interface{} into a []string,
+// skipping non-string elements
+func interfaceSliceToStrings(v interface{}) []string {
+	items, _ := v.([]interface{})
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -3811,16 +9181,25 @@ func checkUnpushedCommits(wtPath, entityType, action string) error {
 	return nil
 }
 
-func (c *CLI) completeWorker(args []string) error {
-	// Parse flags for optional summary and failure reason
-	flags, _ := ParseFlags(args)
-
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
+func (c *CLI) completeWorker(ctx CommandContext, args []string) error {
+	// --criterion may be repeated, once per acceptance criterion
+	criterionArgs, args, err := cutRepeatedFlag(args, "criterion")
 	if err != nil {
-		return fmt.Errorf("failed to determine agent context: %w", err)
+		return err
+	}
+	var criteria []interface{}
+	for _, arg := range criterionArgs {
+		report, err := parseCriterionReport(arg)
+		if err != nil {
+			return err
+		}
+		criteria = append(criteria, report)
 	}
 
+	// Parse flags for optional summary and failure reason
+	flags, _ := ParseFlags(args)
+	repoName, agentName := ctx.Repo, ctx.Agent
+
 	fmt.Printf("Marking agent '%s' as complete...\n", agentName)
 
 	// Build request args
@@ -3841,7 +9220,11 @@ func (c *CLI) completeWorker(args []string) error {
 		fmt.Printf("Failure reason: %s\n", failureReason)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
+	if len(criteria) > 0 {
+		reqArgs["criteria"] = criteria
+	}
+
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "complete_agent",
 		Args:    reqArgs,
@@ -3850,7 +9233,7 @@ func (c *CLI) completeWorker(args []string) error {
 		return errors.DaemonCommunicationFailed("marking agent complete", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to mark agent complete", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to mark agent complete", resp)
 	}
 
 	fmt.Println("✓ Agent marked as complete")
@@ -3858,7 +9241,30 @@ func (c *CLI) completeWorker(args []string) error {
 	return nil
 }
 
-func (c *CLI) restartAgentCmd(args []string) error {
+// parseCriterionReport parses a --criterion value: <n>=met|unmet[:note]
+func parseCriterionReport(arg string) (map[string]interface{}, error) {
+	usage := fmt.Sprintf("invalid --criterion %q: expected <n>=met|unmet[:note], e.g. --criterion \"2=unmet:no Windows CI\"", arg)
+	indexStr, rest, ok := strings.Cut(arg, "=")
+	if !ok {
+		return nil, errors.InvalidUsage(usage)
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(indexStr))
+	if err != nil || index < 1 {
+		return nil, errors.InvalidUsage(usage)
+	}
+	status, note, _ := strings.Cut(rest, ":")
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != string(state.CriterionMet) && status != string(state.CriterionUnmet) {
+		return nil, errors.InvalidUsage(usage)
+	}
+	return map[string]interface{}{
+		"index":  index,
+		"status": status,
+		"note":   strings.TrimSpace(note),
+	}, nil
+}
+
+func (c *CLI) restartAgentCmd(ctx CommandContext, args []string) error {
 	// Parse flags
 	flags, remaining := ParseFlags(args)
 
@@ -3868,26 +9274,15 @@ func (c *CLI) restartAgentCmd(args []string) error {
 	}
 	agentName := remaining[0]
 
-	// Get repo from flag or infer from cwd
-	repoName := flags["repo"]
-	if repoName == "" {
-		// Try to infer from cwd
-		inferred, err := c.inferRepoFromCwd()
-		if err != nil {
-			return errors.InvalidUsage("could not determine repository - use --repo flag or run from within a multiclaude worktree")
-		}
-		repoName = inferred
-	}
-
 	force := flags["force"] == "true"
 
-	fmt.Printf("Restarting agent '%s' in repository '%s'...\n", agentName, repoName)
+	fmt.Printf("Restarting agent '%s' in repository '%s'...\n", agentName, ctx.Repo)
 
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
-			"repo":  repoName,
+			"repo":  ctx.Repo,
 			"agent": agentName,
 			"force": force,
 		},
@@ -3896,7 +9291,7 @@ func (c *CLI) restartAgentCmd(args []string) error {
 		return errors.DaemonCommunicationFailed("restarting agent", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to restart agent", fmt.Errorf("%s", resp.Error))
+		return daemonError("failed to restart agent", resp)
 	}
 
 	// Extract PID from response
@@ -3913,6 +9308,42 @@ func (c *CLI) restartAgentCmd(args []string) error {
 	return nil
 }
 
+// interruptAgent stops an agent mid-turn. When run by another agent, such as
+// the supervisor, the interruption and any message are recorded as coming
+// from it.
+func (c *CLI) interruptAgent(ctx CommandContext, flags *FlagSet) error {
+	if len(flags.Args()) != 1 {
+		return errors.InvalidUsage("usage: multiclaude agent interrupt <name> [--repo <repo>] [--key=escape|ctrl-c] [--signal] [--message <text>]")
+	}
+	agentName := flags.Args()[0]
+	args := map[string]interface{}{
+		"repo":    ctx.Repo,
+		"agent":   agentName,
+		"key":     flags.String("key"),
+		"signal":  flags.Bool("signal"),
+		"message": flags.String("message"),
+	}
+	if callerRepo, caller, err := c.inferAgentContext(); err == nil && callerRepo == ctx.Repo && caller != "" {
+		args["by"] = caller
+	}
+
+	resp, err := c.daemonClient().Send(socket.Request{Command: "interrupt_agent", Args: args})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("interrupting agent", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to interrupt agent", resp)
+	}
+
+	fmt.Printf("✓ Interrupted '%s'\n", agentName)
+	if flags.String("message") != "" {
+		fmt.Println("  Message queued for delivery")
+	} else {
+		fmt.Printf("  It is waiting for instructions: multiclaude message send %s \"<what to do instead>\"\n", agentName)
+	}
+	return nil
+}
+
 func (c *CLI) reviewPR(args []string) error {
 	if len(args) < 1 {
 		return errors.InvalidUsage("usage: multiclaude review <pr-url>")
@@ -3962,6 +9393,40 @@ func (c *CLI) reviewPR(args []string) error {
 		}
 	}
 
+	number, err := strconv.Atoi(prNumber)
+	if err != nil || number <= 0 {
+		return errors.InvalidPRURL()
+	}
+	reviewArgs := map[string]interface{}{
+		"repo":      repoName,
+		"pr_url":    fmt.Sprintf("https://github.com/%s/%s/pull/%s", parts[1], parts[2], prNumber),
+		"pr_number": number,
+	}
+
+	// Running reviewers take the PR in turn, unless a new one is asked for
+	if flags["new"] != "true" {
+		resp, err := c.sendDaemonRequest("request_review", reviewArgs)
+		if err != nil {
+			return err
+		}
+		data, _ := resp.Data.(map[string]interface{})
+		if assigned, _ := data["assigned"].(bool); assigned {
+			reviewer, _ := data["reviewer"].(string)
+			depth, _ := data["queue_depth"].(float64)
+			if queued, _ := data["already_queued"].(bool); queued {
+				fmt.Printf("PR #%s is already queued for review by %s (%d in its queue)\n", prNumber, reviewer, int(depth))
+			} else {
+				fmt.Printf("✓ Sent PR #%s to review agent %s (%d in its queue)\n", prNumber, reviewer, int(depth))
+			}
+			fmt.Println("Start a dedicated reviewer instead with: multiclaude review " + args[0] + " --new")
+			return nil
+		}
+	}
+
+	if err := c.checkAgentQuota(repoName, state.AgentTypeReview, reviewerDefinition); err != nil {
+		return err
+	}
+
 	// Generate review agent name
 	reviewerName := fmt.Sprintf("review-%s", prNumber)
 
@@ -3974,22 +9439,35 @@ func (c *CLI) reviewPR(args []string) error {
 	// The refs/pull/<number>/head ref always exists and points to the PR's head commit
 	fmt.Printf("Fetching PR #%s...\n", prNumber)
 	prRef := fmt.Sprintf("refs/pull/%s/head", prNumber)
-	localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
-	cmd := exec.Command("git", "fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
-	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
-			WithSuggestion("ensure the PR exists and you have access to the repository")
-	}
 
-	// Create worktree for review
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, reviewerName)
-	reviewBranch := fmt.Sprintf("review/%s", reviewerName)
+	// Review from the repository's mirror when it has one, so the reviewer's
+	// git commands stay out of the way of the workers in the main clone
+	reviewBranch := "none (PR head checked out from the mirror)"
+	wtPath, err := c.createMirrorReviewWorktree(repoName, reviewerName, prRef)
+	if err != nil {
+		fmt.Printf("Warning: %v; reviewing from the main clone\n", err)
+	}
+	if wtPath == "" {
+		localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
+		output, err := retry.GitRemote.CombinedOutput(context.Background(), func(ctx context.Context) *exec.Cmd {
+			cmd := exec.CommandContext(ctx, "git", "fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
+			cmd.Dir = repoPath
+			return cmd
+		})
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
+				WithSuggestion("ensure the PR exists and you have access to the repository")
+		}
 
-	fmt.Printf("Creating worktree at: %s\n", wtPath)
-	if err := wt.CreateNewBranch(wtPath, reviewBranch, localRef); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+		// Create worktree for review
+		wt := c.worktreeManager(repoName)
+		wtPath = c.paths.AgentWorktree(repoName, reviewerName)
+		reviewBranch = fmt.Sprintf("review/%s", reviewerName)
+
+		fmt.Printf("Creating worktree at: %s\n", wtPath)
+		if err := worktreeSetupWarning(wt.CreateNewBranch(wtPath, reviewBranch, localRef)); err != nil {
+			return fmt.Errorf("failed to create worktree: %w", err)
+		}
 	}
 
 	// Get tmux session name
@@ -3997,8 +9475,7 @@ func (c *CLI) reviewPR(args []string) error {
 
 	// Create tmux window for reviewer (detached so it doesn't switch focus)
 	fmt.Printf("Creating tmux window: %s\n", reviewerName)
-	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
+	if _, err := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath); err != nil {
 		return fmt.Errorf("failed to create tmux window: %w", err)
 	}
 
@@ -4021,7 +9498,7 @@ func (c *CLI) reviewPR(args []string) error {
 
 	// Start Claude in reviewer window with initial task (skip in test mode)
 	var reviewerPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+	if !simagent.Skip() {
 		// Resolve claude binary
 		claudeBinary, err := c.getClaudeBinary()
 		if err != nil {
@@ -4043,7 +9520,7 @@ func (c *CLI) reviewPR(args []string) error {
 	}
 
 	// Register reviewer with daemon
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
@@ -4053,6 +9530,7 @@ func (c *CLI) reviewPR(args []string) error {
 			"worktree_path": wtPath,
 			"tmux_window":   reviewerName,
 			"task":          fmt.Sprintf("Review PR #%s", prNumber),
+			"definition":    reviewerDefinition,
 			"session_id":    reviewerSessionID,
 			"pid":           reviewerPID,
 		},
@@ -4061,7 +9539,14 @@ func (c *CLI) reviewPR(args []string) error {
 		return fmt.Errorf("failed to register reviewer: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to register reviewer: %s", resp.Error)
+		c.abandonAgent(repoName, tmuxSession, reviewerName, wtPath)
+		return daemonError("failed to register reviewer", resp)
+	}
+
+	// The review is the new reviewer's, so others aren't sent it too
+	reviewArgs["reviewer"] = reviewerName
+	if _, err := c.sendDaemonRequest("request_review", reviewArgs); err != nil {
+		fmt.Printf("Warning: failed to queue the review: %v\n", err)
 	}
 
 	fmt.Println()
@@ -4075,6 +9560,100 @@ func (c *CLI) reviewPR(args []string) error {
 	return nil
 }
 
+// reviewDone takes a finished review off the review queue, so the reviewer
+// isn't counted as busy with it
+func (c *CLI) reviewDone(ctx CommandContext, args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude review done <pr-number>")
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil || number <= 0 {
+		return errors.InvalidUsage(fmt.Sprintf("invalid PR number: %s", args[0]))
+	}
+	resp, err := c.sendDaemonRequest("review_done", map[string]interface{}{
+		"repo":      ctx.Repo,
+		"pr_number": number,
+	})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	remaining, _ := data["remaining"].(float64)
+	fmt.Printf("✓ Review of PR #%d done; %d left in the queue\n", number, int(remaining))
+	return nil
+}
+
+// reviewQueue shows a repository's queued reviews and how deep each
+// reviewer's queue is
+func (c *CLI) reviewQueue(ctx CommandContext, args []string) error {
+	resp, err := c.sendDaemonRequest("review_queue", map[string]interface{}{"repo": ctx.Repo})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	dispatch, _ := data["dispatch"].(string)
+	reviewers, _ := data["reviewers"].([]interface{})
+	reviews, _ := data["reviews"].([]interface{})
+
+	fmt.Printf("Reviewers in %s (dispatch: %s):\n", ctx.Repo, dispatch)
+	if len(reviewers) == 0 {
+		fmt.Println(format.Dim.Sprint("  none running; multiclaude review <pr-url> starts one"))
+	}
+	for _, r := range reviewers {
+		reviewer, _ := r.(map[string]interface{})
+		name, _ := reviewer["name"].(string)
+		depth, _ := reviewer["depth"].(float64)
+		line := fmt.Sprintf("  %s: %d queued", name, int(depth))
+		if available, _ := reviewer["available"].(bool); !available {
+			line += format.Dim.Sprint(" (paused, crashed or done; its reviews move to others)")
+		}
+		fmt.Println(line)
+	}
+
+	if len(reviews) == 0 {
+		fmt.Println("\nNo reviews queued")
+		return nil
+	}
+	fmt.Println()
+	table := format.NewColoredTable("PR", "REVIEWER", "URL").Flexible("URL", 20)
+	for _, r := range reviews {
+		review, _ := r.(map[string]interface{})
+		number, _ := review["pr_number"].(float64)
+		reviewer, _ := review["reviewer"].(string)
+		if reviewer == "" {
+			reviewer = "(waiting)"
+		}
+		url, _ := review["pr_url"].(string)
+		table.AddRow(format.Cell(fmt.Sprintf("#%d", int(number))), format.Cell(reviewer), format.Cell(url))
+	}
+	table.Print()
+	return nil
+}
+
+// createMirrorReviewWorktree checks a PR out from the repository's mirror
+// for a reviewer. It returns "" without an error when the repository isn't
+// mirrored or the daemon hasn't cloned the mirror yet.
+func (c *CLI) createMirrorReviewWorktree(repoName, reviewerName, prRef string) (string, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return "", nil
+	}
+	cfg, err := st.GetWorktreeConfig(repoName)
+	mirrorDir := c.paths.MirrorDir(repoName)
+	if err != nil || !cfg.Mirror || !mirror.Exists(mirrorDir) {
+		return "", nil
+	}
+	if err := mirror.FetchRef(mirrorDir, prRef); err != nil {
+		return "", err
+	}
+	wtPath := c.paths.MirrorWorktree(repoName, reviewerName)
+	fmt.Printf("Creating worktree from the mirror at: %s\n", wtPath)
+	if err := mirror.AddWorktree(mirrorDir, wtPath, prRef); err != nil {
+		return "", err
+	}
+	return wtPath, nil
+}
+
 // Logs command implementations
 
 func (c *CLI) viewLogs(args []string) error {
@@ -4117,6 +9696,7 @@ func (c *CLI) viewLogs(args []string) error {
 	// Check for --follow flag
 	if _, ok := flags["follow"]; ok {
 		// Use tail -f
+		// Following runs until interrupted, so it has no timeout
 		cmd := exec.Command("tail", "-f", logFile)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -4349,29 +9929,80 @@ func parseDuration(s string) (time.Duration, error) {
 	}
 }
 
-func (c *CLI) attachAgent(args []string) error {
-	flags, remainingArgs := ParseFlags(args)
-	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
+// exportAgent writes an agent's Claude transcript, messages and worktree diff
+// as one file that can be shared for review or debugging
+func (c *CLI) exportAgent(ctx CommandContext, flags *FlagSet) error {
+	args := flags.Args()
+	if len(args) != 1 {
+		return errors.InvalidUsage("usage: multiclaude agent export <name> [--format jsonl|markdown] [--output <file>]")
+	}
+	agentName := args[0]
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+	st, err := c.loadState()
 	if err != nil {
-		return errors.NotInRepo()
+		return err
 	}
+	agent, exists := st.GetAgent(ctx.Repo, agentName)
+	if !exists {
+		return errors.AgentNotFound("agent", agentName, ctx.Repo)
+	}
+
+	src := export.Source{
+		Repo:     ctx.Repo,
+		Name:     agentName,
+		Agent:    agent,
+		LogFile:  c.paths.AgentLogFile(ctx.Repo, agentName, agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview),
+		BaseRef:  "origin/" + c.repoDefaultBranch(ctx.Repo),
+		Messages: messages.NewManager(c.paths.MessagesDir),
+		Redactor: c.redactor(),
+	}
+	if projectsDir, err := c.claudeProjectsDir(ctx.Repo); err == nil {
+		src.ClaudeProjectsDir = projectsDir
+	}
+
+	e, err := export.Collect(src)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to export agent", err)
+	}
+
+	format := export.Format(flags.String("format"))
+	output := flags.String("output")
+	if output == "" {
+		return e.Write(os.Stdout, format)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to create export file", err)
+	}
+	if err := e.Write(f, format); err != nil {
+		f.Close()
+		return errors.Wrap(errors.CategoryRuntime, "failed to write export", err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to write export", err)
+	}
+	fmt.Printf("Exported %s/%s to %s\n", ctx.Repo, agentName, output)
+	return nil
+}
+
+func (c *CLI) attachAgent(ctx CommandContext, args []string) error {
+	flags, remainingArgs := ParseFlags(args)
+	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
 
 	// Get agent info to find tmux session and window
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo": ctx.Repo,
 		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to get agent info: %w (is daemon running?)", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to get agent info: %s", resp.Error)
+		return daemonError("failed to get agent info", resp)
 	}
 
 	agents, _ := resp.Data.([]interface{})
@@ -4384,7 +10015,7 @@ func (c *CLI) attachAgent(args []string) error {
 		// Interactive selection - all agent types
 		items := agentsToSelectableItems(agents, nil)
 		if len(items) == 0 {
-			return errors.NoAgentsFound(repoName)
+			return errors.NoAgentsFound(ctx.Repo)
 		}
 		selected, err := SelectFromList("Select agent to attach:", items)
 		if err != nil {
@@ -4409,11 +10040,11 @@ func (c *CLI) attachAgent(args []string) error {
 	}
 
 	if agentInfo == nil {
-		return errors.AgentNotFound("agent", agentName, repoName)
+		return errors.AgentNotFound("agent", agentName, ctx.Repo)
 	}
 
 	// Get tmux session and window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := sanitizeTmuxSessionName(ctx.Repo)
 	tmuxWindow := agentInfo["tmux_window"].(string)
 
 	// Attach to tmux
@@ -4424,6 +10055,8 @@ func (c *CLI) attachAgent(args []string) error {
 		tmuxArgs = append(tmuxArgs, "-r")
 	}
 
+	// Attaching is interactive and lasts as long as the user stays, so it
+	// runs without a timeout or retries
 	cmd := exec.Command("tmux", tmuxArgs...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -4449,7 +10082,11 @@ func (c *CLI) cleanup(args []string) error {
 		return c.cleanupMergedBranches(dryRun, verbose)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
+	if flags["outputs"] == "true" {
+		return c.cleanupOutputs(flags["older-than"], dryRun, verbose)
+	}
+
+	client := c.daemonClient()
 
 	// Check if daemon is running
 	_, err := client.Send(socket.Request{Command: "ping"})
@@ -4469,13 +10106,108 @@ func (c *CLI) cleanup(args []string) error {
 		return fmt.Errorf("failed to trigger cleanup: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("cleanup failed: %s", resp.Error)
+		return daemonError("cleanup failed", resp)
 	}
 
 	fmt.Println("Cleanup completed")
 	return nil
 }
 
+// cleanupOutputs archives the logs of agents that no longer exist, deletes
+// archived and rotated logs older than olderThan (default: the daemon's
+// retention period) and reports how much space the output directory uses
+func (c *CLI) cleanupOutputs(olderThan string, dryRun, verbose bool) error {
+	retention := outputs.DefaultRetention
+	if olderThan != "" {
+		d, err := parseDuration(olderThan)
+		if err != nil || d <= 0 {
+			return errors.InvalidArgument("--older-than", olderThan, "a duration like 7d, 24h or 30m")
+		}
+		retention = d
+	}
+
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	result, err := outputs.Clean(c.paths, outputs.Options{
+		OlderThan: retention,
+		Exists: func(repoName, agentName string) bool {
+			_, exists := st.GetAgent(repoName, agentName)
+			return exists
+		},
+		DryRun: dryRun,
+	})
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to clean output directory", err)
+	}
+
+	if len(result.Files) == 0 {
+		fmt.Println("\nNo agent output to clean up")
+		return nil
+	}
+
+	// Usage per repository before cleaning
+	type usage struct{ live, rotated, archived int64 }
+	byRepo := make(map[string]*usage)
+	var repos []string
+	for _, f := range result.Files {
+		u, ok := byRepo[f.Repo]
+		if !ok {
+			u = &usage{}
+			byRepo[f.Repo] = u
+			repos = append(repos, f.Repo)
+		}
+		switch f.Kind {
+		case outputs.KindLive:
+			u.live += f.Size
+		case outputs.KindRotated:
+			u.rotated += f.Size
+		case outputs.KindArchived:
+			u.archived += f.Size
+		}
+	}
+	fmt.Println()
+	table := format.NewColoredTable("REPO", "LIVE", "ROTATED", "ARCHIVED", "TOTAL")
+	var total int64
+	for _, repo := range repos {
+		u := byRepo[repo]
+		total += u.live + u.rotated + u.archived
+		table.AddRow(format.Cell(repo), format.Cell(format.Size(u.live)), format.Cell(format.Size(u.rotated)),
+			format.Cell(format.Size(u.archived)), format.Cell(format.Size(u.live+u.rotated+u.archived)))
+	}
+	table.Print()
+
+	if verbose {
+		for _, f := range result.Archived {
+			fmt.Printf("  archive %s (%s)\n", f.Path, format.Size(f.Size))
+		}
+		for _, f := range result.Deleted {
+			fmt.Printf("  delete  %s (%s)\n", f.Path, format.Size(f.Size))
+		}
+	}
+
+	verb, archivedVerb := "Deleted", "archived"
+	if dryRun {
+		verb, archivedVerb = "Would delete", "would archive"
+	}
+	fmt.Printf("\n%s %d log(s) older than %s, freeing %s of %s; %s %d log(s) of removed agents\n",
+		verb, len(result.Deleted), olderThanLabel(retention), format.Size(result.Freed()), format.Size(total), archivedVerb, len(result.Archived))
+	return nil
+}
+
+// olderThanLabel formats a retention period the way --older-than takes it
+func olderThanLabel(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
 // cleanupMergedBranches cleans up branches that have been merged upstream
 func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 	fmt.Println("\nChecking for branches merged upstream...")
@@ -4512,9 +10244,13 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 		}
 
 		wt := worktree.NewManager(repoPath)
+		if branch, err := st.GetTargetBranch(repoName); err == nil {
+			wt.SetDefaultBranch(branch)
+		}
 
-		// Check for merged branches with common prefixes
-		for _, prefix := range []string{"multiclaude/", "work/"} {
+		// Check for merged branches with common prefixes and the repo's branch template
+		tmpl, _ := st.GetBranchTemplate(repoName)
+		for _, prefix := range branchname.CleanupPrefixes(tmpl) {
 			mergedBranches, err := wt.FindMergedUpstreamBranches(prefix)
 			if err != nil {
 				if verbose {
@@ -4522,6 +10258,15 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 				}
 				continue
 			}
+			if filter := branchname.CleanupFilter(prefix, tmpl); filter != nil {
+				var matched []string
+				for _, branch := range mergedBranches {
+					if filter(branch) {
+						matched = append(matched, branch)
+					}
+				}
+				mergedBranches = matched
+			}
 
 			if len(mergedBranches) == 0 {
 				if verbose {
@@ -4605,6 +10350,23 @@ func (c *CLI) cleanupOrphanedBranchesWithPrefix(wt *worktree.Manager, branchPref
 		return 0, 0
 	}
 
+	// Branches of workers in the trash are kept so they can be restored
+	if tombstones, err := trash.List(c.paths, repoName); err == nil && len(tombstones) > 0 {
+		trashed := make(map[string]bool)
+		for _, t := range tombstones {
+			trashed[t.Branch] = true
+		}
+		kept := orphanedBranches[:0]
+		for _, branch := range orphanedBranches {
+			if !trashed[branch] {
+				kept = append(kept, branch)
+			} else if verbose {
+				fmt.Printf("  Keeping %s (worker is in the trash)\n", branch)
+			}
+		}
+		orphanedBranches = kept
+	}
+
 	if len(orphanedBranches) == 0 {
 		if verbose {
 			branchType := "work"
@@ -4787,42 +10549,34 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 		}
 	}
 
-	// Check for orphaned message directories
-	msgEntries, err := os.ReadDir(c.paths.MessagesDir)
-	if err != nil && !os.IsNotExist(err) {
-		fmt.Printf("Warning: failed to read messages directory: %v\n", err)
-	} else if err == nil {
-		for _, entry := range msgEntries {
-			if !entry.IsDir() {
-				continue
-			}
-
-			repoName := entry.Name()
-			validAgents, _ := st.ListAgents(repoName)
-
-			msgMgr := messages.NewManager(c.paths.MessagesDir)
+	// Check for orphaned message inboxes
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msgRepos, err := msgMgr.Repos()
+	if err != nil {
+		fmt.Printf("Warning: failed to read messages: %v\n", err)
+	}
+	for _, repoName := range msgRepos {
+		validAgents, _ := st.ListAgents(repoName)
 
-			if !dryRun {
-				count, err := msgMgr.CleanupOrphaned(repoName, validAgents)
-				if err != nil && verbose {
-					fmt.Printf("Warning: failed to cleanup messages for %s: %v\n", repoName, err)
-				} else if count > 0 {
-					fmt.Printf("Cleaned up %d orphaned message dir(s) for %s\n", count, repoName)
-					totalRemoved += count
-				}
-			} else {
-				// Dry run check
-				repoDir := filepath.Join(c.paths.MessagesDir, repoName)
-				agentEntries, _ := os.ReadDir(repoDir)
-				validAgentMap := make(map[string]bool)
-				for _, a := range validAgents {
-					validAgentMap[a] = true
-				}
-				for _, ae := range agentEntries {
-					if ae.IsDir() && !validAgentMap[ae.Name()] {
-						fmt.Printf("Would remove orphaned message dir: %s/%s\n", repoName, ae.Name())
-						totalIssues++
-					}
+		if !dryRun {
+			count, err := msgMgr.CleanupOrphaned(repoName, validAgents)
+			if err != nil && verbose {
+				fmt.Printf("Warning: failed to cleanup messages for %s: %v\n", repoName, err)
+			} else if count > 0 {
+				fmt.Printf("Cleaned up %d orphaned message dir(s) for %s\n", count, repoName)
+				totalRemoved += count
+			}
+		} else {
+			// Dry run check
+			inboxes, _ := msgMgr.Inboxes(repoName)
+			validAgentMap := make(map[string]bool)
+			for _, a := range validAgents {
+				validAgentMap[a] = true
+			}
+			for _, agentName := range inboxes {
+				if !validAgentMap[agentName] {
+					fmt.Printf("Would remove orphaned message dir: %s/%s\n", repoName, agentName)
+					totalIssues++
 				}
 			}
 		}
@@ -4878,10 +10632,14 @@ func (c *CLI) repair(args []string) error {
 	flags, _ := ParseFlags(args)
 	verbose := flags["verbose"] == "true" || flags["v"] == "true"
 
+	if flags["auto"] != "true" {
+		return c.interactiveRepair(flags["dry-run"] == "true")
+	}
+
 	fmt.Println("Repairing state...")
 
 	// Check if daemon is running
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	_, err := client.Send(socket.Request{Command: "ping"})
 	if err != nil {
 		// Daemon not running - do local repair
@@ -4897,7 +10655,7 @@ func (c *CLI) repair(args []string) error {
 		return fmt.Errorf("failed to trigger repair: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("repair failed: %s", resp.Error)
+		return daemonError("repair failed", resp)
 	}
 
 	fmt.Println("✓ State repaired successfully")
@@ -4913,6 +10671,205 @@ func (c *CLI) repair(args []string) error {
 	return nil
 }
 
+// repairChoice is a discrepancy paired with the action chosen for it
+type repairChoice struct {
+	discrepancy reconcile.Discrepancy
+	action      reconcile.Action
+}
+
+// interactiveRepair shows the reconciliation diff and lets the user resolve each discrepancy
+func (c *CLI) interactiveRepair(dryRun bool) error {
+	client := c.daemonClient()
+	_, err := client.Send(socket.Request{Command: "ping"})
+	daemonRunning := err == nil
+
+	var plan []reconcile.Discrepancy
+	var firstSeen map[string]time.Time
+	var env reconcile.Env
+	if daemonRunning {
+		// The daemon checks integrity periodically; refreshing returns what is
+		// wrong now along with how long each discrepancy has been there
+		resp, err := client.Send(socket.Request{
+			Command: "get_inconsistencies",
+			Args:    map[string]interface{}{"refresh": true, "all": true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get repair plan: %w", err)
+		}
+		if !resp.Success {
+			return daemonError("failed to get repair plan", resp)
+		}
+		inconsistencies, err := inconsistenciesFromResponse(resp.Data)
+		if err != nil {
+			return fmt.Errorf("failed to get repair plan: %w", err)
+		}
+		firstSeen = make(map[string]time.Time, len(inconsistencies))
+		for _, inc := range inconsistencies {
+			plan = append(plan, inc.Discrepancy)
+			firstSeen[inc.Repo+"/"+inc.Agent+"/"+string(inc.Kind)] = inc.FirstSeen
+		}
+	} else {
+		st, err := c.loadState()
+		if err != nil {
+			return err
+		}
+		env = reconcile.Env{State: st, Tmux: tmux.NewClient(), Paths: c.paths}
+		plan, err = reconcile.Plan(context.Background(), env)
+		if err != nil {
+			return fmt.Errorf("failed to build repair plan: %w", err)
+		}
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("✓ State matches tmux, worktrees and messages - nothing to repair")
+		return nil
+	}
+
+	format.Header("Reconciliation diff (%d discrepancies):", len(plan))
+	table := format.NewColoredTable("REPO", "AGENT", "ISSUE", "SINCE", "DETAIL")
+	for _, disc := range plan {
+		since := "-"
+		if seen, ok := firstSeen[disc.Repo+"/"+disc.Agent+"/"+string(disc.Kind)]; ok {
+			since = format.TimeAgo(seen)
+		}
+		table.AddRow(
+			format.Cell(disc.Repo),
+			format.Cell(disc.Agent),
+			format.ColorCell(string(disc.Kind), format.Yellow),
+			format.Cell(since),
+			format.Cell(disc.Detail),
+		)
+	}
+	table.Print()
+
+	if dryRun {
+		return nil
+	}
+	if !daemonRunning {
+		format.Dimmed("Daemon is not running: agents can be deleted or adopted, but not recreated.")
+	}
+
+	fmt.Println()
+	choices, err := chooseRepairActions(os.Stdin, os.Stdout, plan)
+	if err != nil {
+		return err
+	}
+	if len(choices) == 0 {
+		fmt.Println("No changes made.")
+		return nil
+	}
+
+	if !daemonRunning {
+		applied := 0
+		for _, choice := range choices {
+			if err := reconcile.Apply(context.Background(), env, choice.discrepancy, choice.action); err != nil {
+				fmt.Printf("  ✗ %s %s/%s: %v\n", choice.action, choice.discrepancy.Repo, choice.discrepancy.Agent, err)
+				continue
+			}
+			applied++
+		}
+		fmt.Printf("\n✓ Applied %d of %d change(s)\n", applied, len(choices))
+		return nil
+	}
+
+	actions := make([]interface{}, 0, len(choices))
+	for _, choice := range choices {
+		actions = append(actions, map[string]interface{}{
+			"repo":   choice.discrepancy.Repo,
+			"agent":  choice.discrepancy.Agent,
+			"kind":   string(choice.discrepancy.Kind),
+			"action": string(choice.action),
+		})
+	}
+	resp, err := client.Send(socket.Request{
+		Command: "repair_apply",
+		Args:    map[string]interface{}{"actions": actions},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply repairs: %w", err)
+	}
+	if !resp.Success {
+		return daemonError("failed to apply repairs", resp)
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	results, _ := data["results"].([]interface{})
+	for _, raw := range results {
+		result, _ := raw.(map[string]interface{})
+		if errMsg, ok := result["error"].(string); ok {
+			fmt.Printf("  ✗ %s %s/%s: %s\n", result["action"], result["repo"], result["agent"], errMsg)
+		}
+	}
+	applied, _ := data["applied"].(float64)
+	fmt.Printf("\n✓ Applied %d of %d change(s)\n", int(applied), len(choices))
+	return nil
+}
+
+// chooseRepairActions prompts for an action per discrepancy. Skipped
+// discrepancies are omitted from the result; end of input skips the rest.
+func chooseRepairActions(in io.Reader, out io.Writer, plan []reconcile.Discrepancy) ([]repairChoice, error) {
+	reader := bufio.NewReader(in)
+	var choices []repairChoice
+
+	for _, disc := range plan {
+		options := make([]string, 0, len(disc.Actions)+1)
+		for _, a := range disc.Actions {
+			options = append(options, fmt.Sprintf("[%c]%s", a[0], a[1:]))
+		}
+		options = append(options, "[s]kip")
+
+		for {
+			fmt.Fprintf(out, "%s/%s: %s\n  %s (default skip): ", disc.Repo, disc.Agent, disc.Detail, strings.Join(options, ", "))
+			input, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("failed to read input: %w", err)
+			}
+			action, ok := parseRepairAction(strings.TrimSpace(input), disc)
+			if ok {
+				if action != reconcile.ActionSkip {
+					choices = append(choices, repairChoice{discrepancy: disc, action: action})
+				}
+				break
+			}
+			if err == io.EOF {
+				fmt.Fprintln(out)
+				return choices, nil
+			}
+			fmt.Fprintf(out, "  Invalid choice %q\n", strings.TrimSpace(input))
+		}
+	}
+	return choices, nil
+}
+
+// parseRepairAction maps user input (full name or first letter) to an allowed action
+func parseRepairAction(input string, disc reconcile.Discrepancy) (reconcile.Action, bool) {
+	input = strings.ToLower(input)
+	if input == "" {
+		return reconcile.ActionSkip, true
+	}
+	for _, a := range append([]reconcile.Action{reconcile.ActionSkip}, disc.Actions...) {
+		if input == string(a) || input == string(a[0]) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// inconsistenciesFromResponse decodes a get_inconsistencies response
+func inconsistenciesFromResponse(data interface{}) ([]reconcile.Inconsistency, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Inconsistencies []reconcile.Inconsistency `json:"inconsistencies"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+	return resp.Inconsistencies, nil
+}
+
 // localRepair performs state repair without the daemon running
 func (c *CLI) localRepair(verbose bool) error {
 	// Load state from disk
@@ -5079,12 +11036,8 @@ func (c *CLI) localRepair(verbose bool) error {
 
 // restartClaude restarts Claude in the current agent context.
 // It auto-detects whether to use --resume or --session-id based on session history.
-func (c *CLI) restartClaude(args []string) error {
-	// Infer agent context from cwd
-	repoName, agentName, err := c.inferAgentContext()
-	if err != nil {
-		return fmt.Errorf("cannot determine agent context: %w\n\nRun this command from within a multiclaude agent tmux window", err)
-	}
+func (c *CLI) restartClaude(ctx CommandContext, args []string) error {
+	repoName, agentName := ctx.Repo, ctx.Agent
 
 	// Load state to get session ID
 	st, err := state.Load(c.paths.StateFile)
@@ -5104,9 +11057,18 @@ func (c *CLI) restartClaude(args []string) error {
 	// Get the prompt file path (stored as ~/.multiclaude/prompts/<agent-name>.md)
 	promptFile := filepath.Join(c.paths.Root, "prompts", agentName+".md")
 
+	configDir, err := c.claudeConfigDir(repoName)
+	if err != nil {
+		return err
+	}
+
 	// Check if the session has history by looking for the .jsonl file
-	// Claude stores sessions in ~/.claude/projects/<encoded-path>/<session-id>.jsonl
-	claudeProjectsDir := filepath.Join(os.Getenv("HOME"), ".claude", "projects")
+	// Claude stores sessions in ~/.claude/projects/<encoded-path>/<session-id>.jsonl,
+	// or under the repository's Claude config directory
+	claudeProjectsDir, err := claude.ProjectsDir(configDir)
+	if err != nil {
+		return err
+	}
 	hasHistory := false
 
 	// The path encoding replaces / with - and prefixes with -
@@ -5134,7 +11096,11 @@ func (c *CLI) restartClaude(args []string) error {
 	}
 
 	// Add common flags
-	cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
+	if agent.Type.IsReadOnly() {
+		cmdArgs = append(cmdArgs, claude.ReadOnlyArgs()...)
+	} else {
+		cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
+	}
 	if _, err := os.Stat(promptFile); err == nil {
 		cmdArgs = append(cmdArgs, "--append-system-prompt-file", promptFile)
 	}
@@ -5150,6 +11116,9 @@ func (c *CLI) restartClaude(args []string) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Dir = agent.WorktreePath
+	if configDir != "" {
+		cmd.Env = append(os.Environ(), claude.ConfigDirEnv+"="+configDir)
+	}
 
 	return cmd.Run()
 }
@@ -5187,8 +11156,17 @@ func (c *CLI) generateCommandDocs(sb *strings.Builder, name string, cmd *Command
 	}
 
 	// Usage
-	if cmd.Usage != "" {
-		sb.WriteString(fmt.Sprintf("**Usage:** `%s`\n\n", cmd.Usage))
+	if usage := cmd.usage(); usage != "" {
+		sb.WriteString(fmt.Sprintf("**Usage:** `%s`\n\n", usage))
+	}
+
+	// Flags
+	if len(cmd.Flags) > 0 {
+		sb.WriteString("**Flags:**\n\n")
+		for _, f := range cmd.Flags {
+			sb.WriteString(fmt.Sprintf("- `%s` - %s\n", f.placeholder(), f.details()))
+		}
+		sb.WriteString("\n")
 	}
 
 	// Subcommands
@@ -5304,29 +11282,173 @@ func (c *CLI) getAgentDefinition(repoName, repoPath, agentDefName string) (strin
 	return "", fmt.Errorf("no %s agent definition found", agentDefName)
 }
 
-// appendDocsAndSlashCommands adds CLI documentation and slash commands to prompt text.
-func (c *CLI) appendDocsAndSlashCommands(promptText string) string {
-	if c.documentation != "" {
-		promptText += fmt.Sprintf("\n\n---\n\n%s", c.documentation)
+// capableDefinition returns the agent definition that declares a capability.
+// The worker definition wins if it declares the capability itself; otherwise
+// the first matching definition by name is used.
+func (c *CLI) capableDefinition(repoName, capability string) (agents.Definition, error) {
+	reader := agents.NewReader(c.paths.RepoAgentsDir(repoName), c.paths.RepoDir(repoName))
+	defs, err := reader.ReadAllDefinitions()
+	if err != nil {
+		return agents.Definition{}, errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
+	}
+	found := agents.FindByCapability(defs, capability)
+	if len(found) == 0 {
+		return agents.Definition{}, errors.New(errors.CategoryNotFound, fmt.Sprintf("no agent definition declares capability '%s'", agents.NormalizeCapability(capability))).
+			WithSuggestion("add a \"Capabilities: <capability>, ...\" line to a definition, then check: multiclaude agents list")
+	}
+	for _, def := range found {
+		if def.Name == "worker" {
+			return def, nil
+		}
+	}
+	return found[0], nil
+}
+
+// namedDefinition returns the repository's agent definition with the given name
+func (c *CLI) namedDefinition(repoName, name string) (agents.Definition, error) {
+	reader := agents.NewReader(c.paths.RepoAgentsDir(repoName), c.paths.RepoDir(repoName))
+	defs, err := reader.ReadAllDefinitions()
+	if err != nil {
+		return agents.Definition{}, errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
+	}
+	for _, def := range defs {
+		if def.Name == name {
+			return def, nil
+		}
 	}
+	return agents.Definition{}, errors.New(errors.CategoryNotFound, fmt.Sprintf("no agent definition named '%s'", name)).
+		WithSuggestion("multiclaude agents list")
+}
 
-	slashCommands := prompts.GetSlashCommandsPrompt()
-	if slashCommands != "" {
-		promptText += fmt.Sprintf("\n\n---\n\n%s", slashCommands)
+// cliDocsReference writes the CLI reference to the repository's docs file and
+// returns the prompt section pointing to it, so prompts stay small and pick up
+// new docs without being regenerated. If the file can't be written the
+// reference is returned inline instead.
+func (c *CLI) cliDocsReference(repoName string) string {
+	path := c.paths.CLIDocsFile(repoName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return c.documentation
 	}
+	if err := os.WriteFile(path, []byte(c.documentation), 0644); err != nil {
+		return c.documentation
+	}
+	return prompts.CLIDocsReference(path)
+}
 
+// appendDocsAndSlashCommands adds the CLI reference and slash commands to prompt text,
+// holding the result to the repository's prompt budget.
+func (c *CLI) appendDocsAndSlashCommands(repoName, promptText string) string {
+	promptText, report := prompts.Assemble([]prompts.Section{
+		{Name: prompts.SectionBase, Text: promptText},
+		{Name: prompts.SectionDocs, Text: c.cliDocsReference(repoName)},
+		{Name: prompts.SectionCommands, Text: prompts.GetRepoSlashCommandsPrompt(c.paths.RepoDir(repoName))},
+	}, c.repoPromptBudget(repoName))
+	reportPromptTrim(report)
 	return promptText
 }
 
+// repoPromptBudget returns the prompt budget configured for a repository
+// (zero limits, i.e. the defaults, if none is or it can't be read)
+func (c *CLI) repoPromptBudget(repoName string) state.PromptBudget {
+	st, err := c.loadState()
+	if err != nil {
+		return state.PromptBudget{}
+	}
+	budget, _ := st.GetPromptBudget(repoName)
+	return budget
+}
+
+// reportPromptTrim tells the user what was cut from a prompt to fit its budget
+func reportPromptTrim(report prompts.Report) {
+	if report.Trimmed() {
+		fmt.Printf("Note: %s\n", report)
+	}
+}
+
+// repoDefaultBranch returns the default branch recorded for a repository, detecting
+// it from the clone if nothing is recorded yet (e.g. during init)
+func (c *CLI) repoDefaultBranch(repoName string) string {
+	if st, err := c.loadState(); err == nil {
+		if branch, err := st.GetTargetBranch(repoName); err == nil && branch != "" {
+			return branch
+		}
+	}
+
+	wt := worktree.NewManager(c.paths.RepoDir(repoName))
+	if remote, err := wt.GetUpstreamRemote(); err == nil {
+		if branch, err := wt.DetectRemoteDefaultBranch(remote); err == nil {
+			return branch
+		}
+	}
+	return "main"
+}
+
+// worktreeManager returns a worktree manager for a repository that runs the
+// repository's configured setup steps (LFS, submodules) in new worktrees,
+// printing their progress
+func (c *CLI) worktreeManager(repoName string) *worktree.Manager {
+	wt := worktree.NewManager(c.paths.RepoDir(repoName))
+	if st, err := c.loadState(); err == nil {
+		if cfg, err := st.GetWorktreeConfig(repoName); err == nil {
+			wt.SetSetup(worktree.Setup{
+				LFS:        cfg.LFS,
+				Submodules: cfg.Submodules,
+				Progress:   func(msg string) { fmt.Println(msg) },
+			})
+		}
+	}
+	return wt
+}
+
+// worktreeSetupWarning turns a failed worktree setup step into a warning: the
+// worktree exists, so the agent can start and finish the setup itself
+func worktreeSetupWarning(err error) error {
+	if worktree.IsSetupError(err) {
+		fmt.Printf("Warning: %v\n", err)
+		return nil
+	}
+	return err
+}
+
+// repoBranchTemplate returns the repository's worker branch template, or "" for the default
+func (c *CLI) repoBranchTemplate(repoName string) string {
+	st, err := c.loadState()
+	if err != nil {
+		return ""
+	}
+	tmpl, _ := st.GetBranchTemplate(repoName)
+	return tmpl
+}
+
+// savePromptForRepo fills in the repository's template variables, adds the
+// teammates and memory sections and saves the prompt
+func (c *CLI) savePromptForRepo(repoName, agentName, promptText string) (string, error) {
+	vars := prompts.TemplateVars{
+		DefaultBranch: c.repoDefaultBranch(repoName),
+		BranchPattern: branchname.Glob(c.repoBranchTemplate(repoName)),
+	}
+	promptText = prompts.ExpandTemplateVars(promptText, vars)
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetAllRepos()[repoName]; exists {
+			promptText = prompts.SetRosterSection(promptText, prompts.RosterSection(repo.Roster, repo.Agents, agentName, c.paths.RosterFile(repoName)))
+		}
+	}
+	promptText = prompts.SetMemorySection(promptText, c.memorySection(repoName, agentName))
+	return c.savePromptToFile(agentName, promptText)
+}
+
 // writePromptFile writes the agent prompt to a temporary file and returns the path
 func (c *CLI) writePromptFile(repoPath string, agentType state.AgentType, agentName string) (string, error) {
+	repoName := filepath.Base(repoPath)
+
 	// Get the complete prompt (default + custom + CLI docs)
-	promptText, err := prompts.GetPrompt(repoPath, agentType, c.documentation)
+	promptText, report, err := prompts.GetPromptWithBudget(repoPath, agentType, c.cliDocsReference(repoName), c.repoPromptBudget(repoName))
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
+	reportPromptTrim(report)
 
-	return c.savePromptToFile(agentName, promptText)
+	return c.savePromptForRepo(repoName, agentName, promptText)
 }
 
 // writeMergeQueuePromptFile writes a merge-queue prompt file with tracking mode configuration.
@@ -5340,13 +11462,17 @@ func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqCon
 	}
 
 	// Add CLI documentation and slash commands
-	promptText = c.appendDocsAndSlashCommands(promptText)
+	promptText = c.appendDocsAndSlashCommands(repoName, promptText)
 
-	// Add tracking mode configuration to the prompt
-	trackingConfig := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode))
-	promptText = trackingConfig + "\n\n" + promptText
+	// Add tracking mode configuration to the prompt, from an include file the
+	// daemon rewrites when the mode changes
+	fragments, err := prompts.WriteFragments(c.paths.PromptFragmentsDir(agentName), prompts.MergeQueueFragments(string(mqConfig.TrackMode)))
+	if err != nil {
+		return "", err
+	}
+	promptText = fragments + "\n\n" + promptText
 
-	return c.savePromptToFile(agentName, promptText)
+	return c.savePromptForRepo(repoName, agentName, promptText)
 }
 
 // writePRShepherdPromptFile writes a pr-shepherd prompt file with fork context.
@@ -5360,37 +11486,58 @@ func (c *CLI) writePRShepherdPromptFile(repoPath string, agentName string, psCon
 	}
 
 	// Add CLI documentation and slash commands
-	promptText = c.appendDocsAndSlashCommands(promptText)
+	promptText = c.appendDocsAndSlashCommands(repoName, promptText)
 
-	// Add fork workflow context
-	forkContext := prompts.GenerateForkWorkflowPrompt(forkConfig.UpstreamOwner, forkConfig.UpstreamRepo, forkConfig.UpstreamOwner)
-	promptText = forkContext + "\n\n" + promptText
-
-	// Add tracking mode configuration to the prompt
-	trackingConfig := prompts.GenerateTrackingModePrompt(string(psConfig.TrackMode))
-	promptText = trackingConfig + "\n\n" + promptText
+	// Add tracking mode configuration and fork workflow context, from include
+	// files the daemon rewrites when the config changes
+	fragments, err := prompts.WriteFragments(c.paths.PromptFragmentsDir(agentName),
+		prompts.PRShepherdFragments(string(psConfig.TrackMode), forkConfig.UpstreamOwner, forkConfig.UpstreamRepo))
+	if err != nil {
+		return "", err
+	}
+	promptText = fragments + "\n\n" + promptText
 
-	return c.savePromptToFile(agentName, promptText)
+	return c.savePromptForRepo(repoName, agentName, promptText)
 }
 
 // WorkerConfig holds configuration for creating worker prompts
 type WorkerConfig struct {
-	PushToBranch string           // Branch to push to instead of creating a new PR (for iterating on existing PRs)
-	ForkConfig   state.ForkConfig // Fork configuration (if working in a fork)
+	PushToBranch    string           // Branch to push to instead of creating a new PR (for iterating on existing PRs)
+	ForkConfig      state.ForkConfig // Fork configuration (if working in a fork)
+	PreviousAttempt string           // Summary of an earlier attempt at the task (for retries)
+	Criteria        []string         // Acceptance criteria the worker must report on when completing
+	Definition      string           // Agent definition to specialize the worker with ("" or "worker" for none)
+	BaseBranch      string           // Branch the worker's PR targets, when not the default branch
+	DraftPRs        bool             // Open a draft PR early and mark it ready with `worker ready` when done
+	PRDescriptions  bool             // The daemon writes the PR's description when the worker finishes
+	PushChecks      state.PushChecks // What the worker's branch must pass before it's pushed
+	GitHubProblem   string           // Why gh can't be used, if it can't: the worker pushes without opening a PR
 }
 
 // writeWorkerPromptFile writes a worker prompt file with optional configuration.
 // It reads the worker prompt from agent definitions (configurable agent system).
-func (c *CLI) writeWorkerPromptFile(repoPath string, agentName string, config WorkerConfig) (string, error) {
+// It also returns the capabilities the worker's definition declares.
+func (c *CLI) writeWorkerPromptFile(repoPath string, agentName string, config WorkerConfig) (string, []string, error) {
 	repoName := filepath.Base(repoPath)
 
 	promptText, err := c.getAgentDefinition(repoName, repoPath, "worker")
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	capabilities := agents.ParseCapabilities(promptText)
+
+	// A specialized worker follows the worker instructions plus its own definition
+	if config.Definition != "" && config.Definition != "worker" {
+		specialization, err := c.getAgentDefinition(repoName, repoPath, config.Definition)
+		if err != nil {
+			return "", nil, err
+		}
+		capabilities = agents.ParseCapabilities(specialization)
+		promptText = strings.TrimRight(promptText, "\n") + "\n\n---\n\n## Specialization: " + config.Definition + "\n\n" + specialization
 	}
 
 	// Add CLI documentation and slash commands
-	promptText = c.appendDocsAndSlashCommands(promptText)
+	promptText = c.appendDocsAndSlashCommands(repoName, promptText)
 
 	// Add fork workflow context if working in a fork
 	if config.ForkConfig.IsFork {
@@ -5404,6 +11551,32 @@ func (c *CLI) writeWorkerPromptFile(repoPath string, agentName string, config Wo
 		promptText = forkWorkflow + "\n---\n\n" + promptText
 	}
 
+	// Open a draft PR early, unless iterating on an existing PR
+	opensPR := config.PushToBranch == "" && config.GitHubProblem == ""
+	if config.DraftPRs && opensPR {
+		promptText = draftPRPrompt(agentName) + promptText
+	}
+
+	// The daemon writes the PR's description, so the worker needn't
+	if config.PRDescriptions && opensPR {
+		promptText = prDescriptionsPrompt + promptText
+	}
+
+	// The branch must pass the push checks before it's pushed
+	if config.PushChecks.Enabled() {
+		promptText = pushChecksPrompt(agentName, config.PushChecks) + promptText
+	}
+
+	// PRs against a base other than the default branch must say so
+	if config.BaseBranch != "" {
+		promptText = baseBranchPrompt(config.BaseBranch, opensPR) + promptText
+	}
+
+	// Without gh there's no PR to open; push and say so
+	if config.GitHubProblem != "" {
+		promptText = githubUnavailablePrompt(config.GitHubProblem) + promptText
+	}
+
 	// Add push-to configuration if specified
 	if config.PushToBranch != "" {
 		pushToConfig := fmt.Sprintf(`## PR Iteration Mode
@@ -5425,7 +11598,137 @@ Do NOT create a new PR. The existing PR will be updated automatically when you p
 		promptText = pushToConfig + promptText
 	}
 
-	return c.savePromptToFile(agentName, promptText)
+	// List acceptance criteria; agent complete is rejected until each is reported
+	if len(config.Criteria) > 0 {
+		promptText = acceptanceCriteriaPrompt(config.Criteria) + promptText
+	}
+
+	// Brief retries on the previous attempt
+	if config.PreviousAttempt != "" {
+		promptText = config.PreviousAttempt + promptText
+	}
+
+	promptPath, err := c.savePromptForRepo(repoName, agentName, promptText)
+	return promptPath, capabilities, err
+}
+
+// baseBranchPrompt tells a worker its work is against a base branch other
+// than the default, so it syncs with and opens its PR against that branch
+func baseBranchPrompt(base string, opensPR bool) string {
+	prompt := `## Base Branch
+
+Your branch was created from ` + "`" + base + "`" + `, not the default branch, and that is where your work goes. ` + "`multiclaude sync`" + ` and the daemon's refreshes keep you up to date with ` + "`origin/" + base + "`" + `; compare and rebase against it, never the default branch.
+`
+	if opensPR {
+		prompt += `
+Open your PR against it: ` + "`gh pr create --base " + base + "`" + `. A PR against the default branch would merge your work into the wrong place.
+`
+	}
+	return prompt + `
+---
+
+`
+}
+
+// prDescriptionsPrompt tells a worker its PR's description is written for it
+const prDescriptionsPrompt = `## PR Descriptions
+
+multiclaude writes your PR's description when you complete (or run ` + "`multiclaude worker ready`" + `), from your task, your acceptance criteria reports, your commits and a summary of your diff. Open the PR with a one-line body, and make your commit messages and ` + "`agent complete --summary`" + ` say what changed and why: they are what reviewers will read.
+
+---
+
+`
+
+// draftPRPrompt tells a worker to open its PR as a draft once it has something
+// to show, so humans can follow along, and to mark it ready when done
+func draftPRPrompt(agentName string) string {
+	return `## Draft PRs
+
+This repository uses draft PRs. As soon as you have a first commit, push your branch and open the PR as a draft (` + "`gh pr create --draft`" + `), then keep pushing to it. Humans can follow your work there; the merge queue ignores drafts.
+
+When your work is done, mark the PR ready before completing:
+
+` + "```" + `bash
+multiclaude worker ready ` + agentName + `
+multiclaude agent complete
+` + "```" + `
+
+` + "`worker ready`" + ` takes the PR out of draft and tells the merge queue. Don't mark it ready with gh directly.
+
+---
+
+`
+}
+
+// pushChecksPrompt tells a worker what its branch must pass before it
+// pushes, and how to check
+func pushChecksPrompt(agentName string, checks state.PushChecks) string {
+	var rules []string
+	if checks.Rebased {
+		rules = append(rules, "- Rebased on the latest base branch: rebase, don't merge it in")
+	}
+	if checks.Signed {
+		rules = append(rules, "- Every commit signed (`git commit -S`)")
+	}
+	if checks.MessagePattern != "" {
+		rules = append(rules, "- Every commit's subject line matching `"+checks.MessagePattern+"`")
+	}
+	return `## Push Checks
+
+This repository checks your branch before it goes anywhere. It must be:
+
+` + strings.Join(rules, "\n") + `
+
+Run this before every push, and fix whatever it lists:
+
+` + "```" + `bash
+multiclaude worker check ` + agentName + `
+` + "```" + `
+
+` + "`worker ready`" + ` and ` + "`agent complete`" + ` run the same checks and refuse a branch that fails them.
+
+---
+
+`
+}
+
+// githubUnavailablePrompt tells a worker gh can't be used, so it pushes its
+// branch and leaves opening the PR to a human
+func githubUnavailablePrompt(problem string) string {
+	return `## GitHub Is Unavailable
+
+The GitHub CLI can't be used on this machine (` + problem + `), so don't run ` + "`gh`" + ` or ` + "`multiclaude worker ready`" + `: they will fail. Everything else works as usual. When your work is done, push your branch (` + "`git push -u origin HEAD`" + `) and complete with a summary that says the branch is pushed and still needs a PR:
+
+` + "```" + `bash
+multiclaude agent complete --summary "<what changed>. Branch pushed; no PR opened (GitHub unavailable)."
+` + "```" + `
+
+---
+
+`
+}
+
+// acceptanceCriteriaPrompt tells a worker what its task must satisfy and how to
+// report on it when completing
+func acceptanceCriteriaPrompt(criteria []string) string {
+	var sb strings.Builder
+	sb.WriteString("## Acceptance Criteria\n\nYour task is done when all of these hold:\n\n")
+	for i, criterion := range criteria {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, criterion))
+	}
+	sb.WriteString(`
+When you finish, report on every criterion as you complete:
+
+` + "```" + `bash
+multiclaude agent complete --criterion 1=met --criterion "2=unmet:<why not>"
+` + "```" + `
+
+` + "`agent complete`" + ` is rejected until each criterion is reported. Only report "met" for criteria you have verified; an unmet criterion with a reason is more useful than a wrong "met".
+
+---
+
+`)
+	return sb.String()
 }
 
 // setupOutputCapture sets up tmux pipe-pane to capture agent output to a log file.
@@ -5452,10 +11755,24 @@ func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, a
 }
 
 // startClaudeInTmux starts Claude Code in a tmux window with the given configuration
-// Returns the PID of the Claude process
+// Returns the PID of the Claude process. With MULTICLAUDE_TEST_MODE=sim the
+// window runs the simulator instead, which reads its task from state.
 func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, sessionID, promptFile, repoName string, initialMessage string) (int, error) {
-	// Build Claude command - uses global ~/.claude/ for auth and slash commands are embedded in prompts
-	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions", binaryPath, sessionID)
+	if simagent.Enabled() {
+		return c.startSimAgentInTmux(binaryPath, tmuxSession, tmuxWindow, repoName)
+	}
+
+	// Build Claude command - uses ~/.claude/ for auth unless the repository has
+	// its own Claude config directory; slash commands are embedded in prompts
+	configDir, err := c.claudeConfigDir(repoName)
+	if err != nil {
+		return 0, err
+	}
+	claudeCmd := ""
+	if configDir != "" {
+		claudeCmd = fmt.Sprintf("%s=%q ", claude.ConfigDirEnv, configDir)
+	}
+	claudeCmd += fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions", binaryPath, sessionID)
 
 	// Add prompt file if provided
 	if promptFile != "" {
@@ -5464,8 +11781,7 @@ func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, se
 
 	// Send command to tmux window
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
-	cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-	if err := cmd.Run(); err != nil {
+	if _, err := tmuxCommand("send-keys", "-t", target, claudeCmd, "C-m"); err != nil {
 		return 0, fmt.Errorf("failed to start Claude in tmux: %w", err)
 	}
 
@@ -5516,11 +11832,28 @@ func (c *CLI) bugReport(args []string) error {
 		return fmt.Errorf("failed to collect diagnostic information: %w", err)
 	}
 
+	// A bundle adds pane snapshots, logs and sanitized state to the report
+	if flags["bundle"] == "true" {
+		outputFile, ok := flags["output"]
+		if ok {
+			outputFile = localPath(outputFile)
+		} else {
+			outputFile = fmt.Sprintf("multiclaude-bug-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+		if err := collector.WriteBundle(report, outputFile); err != nil {
+			return fmt.Errorf("failed to write bug bundle: %w", err)
+		}
+		fmt.Printf("Bug bundle written to: %s\n", outputFile)
+		format.Dimmed("Review the bundle before sharing; secrets and names are scrubbed on a best-effort basis.")
+		return nil
+	}
+
 	// Format as Markdown
 	markdown := bugreport.FormatMarkdown(report)
 
 	// Check if output file specified
 	if outputFile, ok := flags["output"]; ok {
+		outputFile = localPath(outputFile)
 		if err := os.WriteFile(outputFile, []byte(markdown), 0644); err != nil {
 			return fmt.Errorf("failed to write report to %s: %w", outputFile, err)
 		}
@@ -5533,11 +11866,18 @@ func (c *CLI) bugReport(args []string) error {
 	return nil
 }
 
+// tmuxCommand runs a tmux command the tmux client has no method for, such as
+// one that sets a new window's directory, under retry.Tmux, and returns its
+// standard output
+func tmuxCommand(args ...string) ([]byte, error) {
+	return retry.Tmux.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "tmux", args...)
+	})
+}
+
 // listBranchesWithPrefix returns all local branches with the given prefix
 func (c *CLI) listBranchesWithPrefix(repoPath, prefix string) ([]string, error) {
-	cmd := exec.Command("git", "branch", "--list", prefix+"*")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), repoPath, "branch", "--list", prefix+"*")
 	if err != nil {
 		return nil, err
 	}
@@ -5555,22 +11895,18 @@ func (c *CLI) listBranchesWithPrefix(repoPath, prefix string) ([]string, error)
 
 // deleteBranch deletes a local git branch
 func (c *CLI) deleteBranch(repoPath, branch string) error {
-	cmd := exec.Command("git", "branch", "-D", branch)
-	cmd.Dir = repoPath
-	return cmd.Run()
+	_, err := gitcmd.Run(context.Background(), repoPath, "branch", "-D", branch)
+	return err
 }
 
 // extractOwnerFromGitHubURL extracts the owner from a repository's origin URL.
 // It first tries to get the origin URL from git remote, then parses it.
 func (c *CLI) extractOwnerFromGitHubURL(repoPath string) string {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
+	originURL, err := gitcmd.Run(context.Background(), repoPath, "remote", "get-url", "origin")
 	if err != nil {
 		return ""
 	}
 
-	originURL := strings.TrimSpace(string(output))
 	owner, _, err := fork.ParseGitHubURL(originURL)
 	if err != nil {
 		return ""
@@ -1,19 +1,33 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/clone"
 	"github.com/micheal-at/multiclaude/internal/daemon"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/events"
 	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/reconcile"
 	"github.com/micheal-at/multiclaude/internal/socket"
 	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/tasks"
+	"github.com/micheal-at/multiclaude/internal/telemetry"
+	"github.com/micheal-at/multiclaude/internal/tickets"
 	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/daemontest"
 	"github.com/micheal-at/multiclaude/pkg/tmux"
 )
 
@@ -284,6 +298,15 @@ func TestGenerateDocumentation(t *testing.T) {
 	}
 }
 
+// setupFakeDaemon creates a CLI talking to an in-memory daemon, for tests
+// that only need the socket API
+func setupFakeDaemon(t *testing.T) (*CLI, *daemontest.Daemon) {
+	t.Helper()
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	d := daemontest.New(t)
+	return NewWithPaths(d.Paths()), d
+}
+
 // setupTestEnvironment creates a test environment with daemon and paths
 func setupTestEnvironment(t *testing.T) (*CLI, *daemon.Daemon, func()) {
 	t.Helper()
@@ -378,8 +401,7 @@ func setupTestRepo(t *testing.T, repoPath string) {
 }
 
 func TestCLIListReposEmpty(t *testing.T) {
-	cli, _, cleanup := setupTestEnvironment(t)
-	defer cleanup()
+	cli, _ := setupFakeDaemon(t)
 
 	// List repos when empty - should not error
 	err := cli.Execute([]string{"list"})
@@ -389,29 +411,23 @@ func TestCLIListReposEmpty(t *testing.T) {
 }
 
 func TestCLIDaemonStatus(t *testing.T) {
-	cli, _, cleanup := setupTestEnvironment(t)
-	defer cleanup()
+	cli, d := setupFakeDaemon(t)
 
 	// Check daemon status
 	err := cli.Execute([]string{"daemon", "status"})
 	if err != nil {
 		t.Errorf("daemon status failed: %v", err)
 	}
+	if requests := d.Requests(); len(requests) != 1 || requests[0].Command != "status" {
+		t.Errorf("daemon status sent %+v, want one status request", requests)
+	}
 }
 
 func TestCLIWorkListEmpty(t *testing.T) {
-	cli, d, cleanup := setupTestEnvironment(t)
-	defer cleanup()
+	cli, d := setupFakeDaemon(t)
 
 	// Add a repo first via daemon so we can list workers
-	repo := &state.Repository{
-		GithubURL:   "https://github.com/test/repo",
-		TmuxSession: "mc-test-repo",
-		Agents:      make(map[string]state.Agent),
-	}
-	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
-		t.Fatalf("Failed to add repo: %v", err)
-	}
+	d.AddRepo("test-repo", daemontest.Repo{GithubURL: "https://github.com/test/repo", TmuxSession: "mc-test-repo"})
 
 	// List workers - should work even when empty
 	err := cli.Execute([]string{"work", "list", "--repo", "test-repo"})
@@ -421,29 +437,17 @@ func TestCLIWorkListEmpty(t *testing.T) {
 }
 
 func TestCLIWorkListWithWorkers(t *testing.T) {
-	cli, d, cleanup := setupTestEnvironment(t)
-	defer cleanup()
+	cli, d := setupFakeDaemon(t)
 
 	// Add a repo and worker via daemon
-	repo := &state.Repository{
-		GithubURL:   "https://github.com/test/repo",
-		TmuxSession: "mc-test-repo",
-		Agents:      make(map[string]state.Agent),
-	}
-	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
-		t.Fatalf("Failed to add repo: %v", err)
-	}
-
-	agent := state.Agent{
-		Type:         state.AgentTypeWorker,
+	d.AddRepo("test-repo", daemontest.Repo{GithubURL: "https://github.com/test/repo", TmuxSession: "mc-test-repo"})
+	d.AddAgent("test-repo", "test-worker", daemontest.Agent{
+		Type:         "worker",
 		WorktreePath: "/tmp/test",
 		TmuxWindow:   "test-worker",
 		Task:         "Test task description",
 		CreatedAt:    time.Now(),
-	}
-	if err := d.GetState().AddAgent("test-repo", "test-worker", agent); err != nil {
-		t.Fatalf("Failed to add agent: %v", err)
-	}
+	})
 
 	// List workers - should show the worker
 	err := cli.Execute([]string{"work", "list", "--repo", "test-repo"})
@@ -452,6 +456,21 @@ func TestCLIWorkListWithWorkers(t *testing.T) {
 	}
 }
 
+func TestCLIWorkListAllRepos(t *testing.T) {
+	cli, d := setupFakeDaemon(t)
+
+	for _, repoName := range []string{"api", "web"} {
+		d.AddRepo(repoName, daemontest.Repo{TmuxSession: "mc-" + repoName})
+		d.AddAgent(repoName, "worker-"+repoName, daemontest.Agent{Type: "worker", TmuxWindow: "worker-" + repoName, Task: "task in " + repoName})
+	}
+
+	for _, args := range [][]string{{"work", "list", "--all"}, {"agents", "list", "--all"}} {
+		if err := cli.Execute(args); err != nil {
+			t.Errorf("%v failed: %v", args, err)
+		}
+	}
+}
+
 func TestCLIAgentMessaging(t *testing.T) {
 	_, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -601,7 +620,7 @@ func TestCLISendMessageTriggersImmediateRouting(t *testing.T) {
 	// 1. Message is created successfully
 	// 2. Socket call doesn't cause errors (it's ignored if it fails)
 
-	err := cli.sendMessage([]string{"supervisor", "Test message for immediate routing"})
+	err := cli.Execute([]string{"message", "send", "supervisor", "Test message for immediate routing"})
 	if err != nil {
 		t.Fatalf("sendMessage failed: %v", err)
 	}
@@ -621,6 +640,9 @@ func TestCLISendMessageTriggersImmediateRouting(t *testing.T) {
 	if msgs[0].Body != "Test message for immediate routing" {
 		t.Errorf("Message body = %s, want 'Test message for immediate routing'", msgs[0].Body)
 	}
+	if msgs[0].IdempotencyKey != "" {
+		t.Errorf("Message idempotency key = %q, want none without --idempotency-key", msgs[0].IdempotencyKey)
+	}
 
 	// Verify the route_messages socket command works (daemon should be running)
 	client := socket.NewClient(paths.DaemonSock)
@@ -699,7 +721,7 @@ func TestCLISendMessageFallbackWhenDaemonUnavailable(t *testing.T) {
 
 	// Send message - should succeed even though daemon is not running
 	// The socket call will fail silently (best-effort)
-	err = cli.sendMessage([]string{"supervisor", "Fallback test message"})
+	err = cli.Execute([]string{"message", "send", "supervisor", "Fallback test message"})
 	if err != nil {
 		t.Fatalf("sendMessage failed when daemon unavailable: %v", err)
 	}
@@ -718,6 +740,82 @@ func TestCLISendMessageFallbackWhenDaemonUnavailable(t *testing.T) {
 	}
 }
 
+func TestCLISendMessageIdempotencyKey(t *testing.T) {
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve symlinks: %v", err)
+	}
+	paths := &config.Paths{
+		Root:            tmpDir,
+		DaemonPID:       filepath.Join(tmpDir, "daemon.pid"),
+		DaemonSock:      filepath.Join(tmpDir, "nonexistent.sock"),
+		DaemonLog:       filepath.Join(tmpDir, "daemon.log"),
+		StateFile:       filepath.Join(tmpDir, "state.json"),
+		ReposDir:        filepath.Join(tmpDir, "repos"),
+		WorktreesDir:    filepath.Join(tmpDir, "wts"),
+		MessagesDir:     filepath.Join(tmpDir, "messages"),
+		OutputDir:       filepath.Join(tmpDir, "output"),
+		ClaudeConfigDir: filepath.Join(tmpDir, "claude-config"),
+	}
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	repoName := "idempotent-repo"
+	st := state.New(paths.StateFile)
+	if err := st.AddRepo(repoName, &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-idempotent-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	worktreeDir := filepath.Join(paths.WorktreesDir, repoName, "sender-agent")
+	if err := os.MkdirAll(worktreeDir, 0755); err != nil {
+		t.Fatalf("Failed to create worktree dir: %v", err)
+	}
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	if err := os.Chdir(worktreeDir); err != nil {
+		t.Fatalf("Failed to change to worktree: %v", err)
+	}
+
+	cli := NewWithPaths(paths)
+	msgMgr := messages.NewManager(paths.MessagesDir)
+
+	// A retried send with the same key stores one message
+	for i := 0; i < 2; i++ {
+		if err := cli.Execute([]string{"message", "send", "--idempotency-key=retry-1", "supervisor", "Retried message"}); err != nil {
+			t.Fatalf("send %d failed: %v", i+1, err)
+		}
+	}
+	msgs, err := msgMgr.List(repoName, "supervisor")
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Expected 1 message after sending twice with the same key, got %d", len(msgs))
+	}
+	if msgs[0].IdempotencyKey != "retry-1" {
+		t.Errorf("Message idempotency key = %q, want retry-1", msgs[0].IdempotencyKey)
+	}
+
+	// Without a key, the same message sent twice is two messages
+	for i := 0; i < 2; i++ {
+		if err := cli.Execute([]string{"message", "send", "supervisor", "Repeated message"}); err != nil {
+			t.Fatalf("send %d failed: %v", i+1, err)
+		}
+	}
+	msgs, err = msgMgr.List(repoName, "supervisor")
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Errorf("Expected 3 messages, got %d", len(msgs))
+	}
+}
+
 func TestCLISocketCommunication(t *testing.T) {
 	_, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -953,6 +1051,111 @@ func TestCLIWorkCreateWithRealTmux(t *testing.T) {
 	}
 }
 
+func TestCLIWorkRetry(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	paths := d.GetPaths()
+	repoName := "test-repo"
+	repoPath := paths.RepoDir(repoName)
+	setupTestRepo(t, repoPath)
+
+	// Leave a branch behind from the failed attempt
+	out, err := exec.Command("git", "-C", repoPath, "branch", "--show-current").Output()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+	defaultBranch := strings.TrimSpace(string(out))
+	for _, cmdArgs := range [][]string{
+		{"git", "checkout", "-b", "work/old-worker"},
+		{"sh", "-c", "echo 'half-done fix' > login.go && git add login.go && git commit -m 'WIP login fix'"},
+		{"git", "checkout", defaultBranch},
+	} {
+		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Failed to run %v: %v\n%s", cmdArgs, err, output)
+		}
+	}
+
+	tmuxSession := "mc-test-repo"
+	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	if err := d.GetState().AddRepo(repoName, &state.Repository{
+		GithubURL:    "https://github.com/test/repo",
+		TmuxSession:  tmuxSession,
+		Agents:       make(map[string]state.Agent),
+		TargetBranch: defaultBranch,
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	failed := state.TaskHistoryEntry{
+		Name:          "old-worker",
+		Task:          "Fix the login bug",
+		Branch:        "work/old-worker",
+		Status:        state.TaskStatusFailed,
+		FailureReason: "could not reproduce the bug",
+		CompletedAt:   time.Now().Add(-time.Hour),
+		Criteria:      []state.Criterion{{Text: "login works with SSO", Status: state.CriterionUnmet}},
+	}
+	if err := d.GetState().AddTaskHistory(repoName, failed); err != nil {
+		t.Fatalf("Failed to add task history: %v", err)
+	}
+	if _, err := messages.NewManager(paths.MessagesDir).Send(repoName, "old-worker", "supervisor", "Giving up: login works for me locally"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if err := cli.Execute([]string{"work", "retry", failed.ID(), "--name", "retry-worker", "--repo", repoName}); err != nil {
+		t.Fatalf("work retry failed: %v", err)
+	}
+
+	agent, exists := d.GetState().GetAgent(repoName, "retry-worker")
+	if !exists {
+		t.Fatal("Retry worker should exist in state")
+	}
+	if agent.Task != "Fix the login bug" {
+		t.Errorf("Agent task = %q, want the original task", agent.Task)
+	}
+	if agent.RetryOf != failed.ID() {
+		t.Errorf("Agent RetryOf = %q, want %q", agent.RetryOf, failed.ID())
+	}
+	if len(agent.Criteria) != 1 || agent.Criteria[0].Text != "login works with SSO" || agent.Criteria[0].Status != state.CriterionPending {
+		t.Errorf("Agent Criteria = %+v, want the original criteria, pending", agent.Criteria)
+	}
+
+	entry, err := d.GetState().FindTaskHistory(repoName, failed.ID())
+	if err != nil {
+		t.Fatalf("FindTaskHistory() failed: %v", err)
+	}
+	if entry.RetriedBy != "retry-worker" {
+		t.Errorf("RetriedBy = %q, want retry-worker", entry.RetriedBy)
+	}
+
+	prompt, err := os.ReadFile(filepath.Join(paths.Root, "prompts", "retry-worker.md"))
+	if err != nil {
+		t.Fatalf("Failed to read worker prompt: %v", err)
+	}
+	for _, want := range []string{"## Previous Attempt", "could not reproduce the bug", "+half-done fix", "Giving up: login works for me locally", "## Acceptance Criteria", "1. login works with SSO"} {
+		if !strings.Contains(string(prompt), want) {
+			t.Errorf("worker prompt missing %q", want)
+		}
+	}
+
+	// Unknown history IDs are rejected
+	if err := cli.Execute([]string{"work", "retry", "no-such-task", "--repo", repoName}); err == nil {
+		t.Error("work retry with unknown ID should fail")
+	}
+}
+
 func TestCLICleanupCommand(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -1354,6 +1557,7 @@ func TestInferAgentContext(t *testing.T) {
 		filepath.Join(worktreesDir, "myrepo", "workspace"),
 		filepath.Join(worktreesDir, "myrepo"), // Just repo level
 		filepath.Join(reposDir, "myrepo"),
+		filepath.Join(tmpDir, "projects", "platform"),
 	}
 	for _, d := range testDirs {
 		if err := os.MkdirAll(d, 0755); err != nil {
@@ -1405,6 +1609,13 @@ func TestInferAgentContext(t *testing.T) {
 			wantAgent: "",
 			wantError: true, // Can't determine agent
 		},
+		{
+			name:      "in project supervisor dir",
+			cwd:       filepath.Join(tmpDir, "projects", "platform"),
+			wantRepo:  "@platform",
+			wantAgent: "supervisor",
+			wantError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1786,6 +1997,116 @@ func TestCLIConfigRepoUpdateViaSocket(t *testing.T) {
 	}
 }
 
+func TestCLIConfigWorktreeDir(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := d.GetState().AddRepo("test-repo", &state.Repository{TmuxSession: "mc-test-repo", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	base := filepath.Join(d.GetPaths().Root, "scratch")
+	if err := cli.Execute([]string{"config", "test-repo", "--worktree-dir=" + base}); err != nil {
+		t.Fatalf("config --worktree-dir failed: %v", err)
+	}
+	if got, want := cli.paths.AgentWorktree("test-repo", "calm-owl"), filepath.Join(base, "test-repo", "calm-owl"); got != want {
+		t.Errorf("AgentWorktree() = %q, want %q", got, want)
+	}
+
+	if err := cli.Execute([]string{"config", "test-repo", "--worktree-dir=default"}); err != nil {
+		t.Fatalf("config --worktree-dir=default failed: %v", err)
+	}
+	if got, want := cli.paths.WorktreeDir("test-repo"), filepath.Join(d.GetPaths().WorktreesDir, "test-repo"); got != want {
+		t.Errorf("WorktreeDir() after reset = %q, want %q", got, want)
+	}
+}
+
+func TestWorktreeBaseArg(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	cwd, _ := os.Getwd()
+	tests := map[string]string{
+		"default":      "",
+		"/mnt/scratch": "/mnt/scratch",
+		"~/wts":        filepath.Join(home, "wts"),
+		"scratch":      filepath.Join(cwd, "scratch"),
+	}
+	for value, want := range tests {
+		if got, err := worktreeBaseArg(value); err != nil || got != want {
+			t.Errorf("worktreeBaseArg(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+}
+
+func TestCLIMigrateWorktrees(t *testing.T) {
+	cli, d := setupFakeDaemon(t)
+	d.AddRepo("test-repo", daemontest.Repo{TmuxSession: "mc-test-repo"})
+
+	results := []map[string]interface{}{
+		{"agent": "calm-owl", "from": "/old/test-repo/calm-owl", "to": "/new/test-repo/calm-owl", "status": "moved"},
+		{"agent": "workspace", "from": "/old/test-repo/workspace", "to": "/new/test-repo/workspace", "status": "skipped", "reason": "agent is running"},
+	}
+	d.Handle("migrate_worktrees", func(args map[string]interface{}) (interface{}, error) {
+		return results, nil
+	})
+
+	if err := cli.Execute([]string{"repo", "migrate-worktrees", "--repo", "test-repo", "--dry-run"}); err != nil {
+		t.Fatalf("migrate-worktrees failed: %v", err)
+	}
+	requests := d.Requests()
+	if last := requests[len(requests)-1]; last.Args["repo"] != "test-repo" || last.Args["dry_run"] != true {
+		t.Errorf("migrate_worktrees args = %v", last.Args)
+	}
+
+	results = append(results, map[string]interface{}{"agent": "brave-fox", "status": "failed", "reason": "permission denied"})
+	if err := cli.Execute([]string{"repo", "migrate-worktrees", "--repo", "test-repo"}); err == nil {
+		t.Error("migrate-worktrees should fail when a worktree couldn't be moved")
+	}
+}
+
+func TestCLIMigratePaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range config.LayoutEnv {
+		t.Setenv(env, "")
+	}
+
+	legacy := config.LegacyPaths(home)
+	if err := legacy.EnsureDirectories(); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.New(legacy.StateFile).Save(); err != nil {
+		t.Fatal(err)
+	}
+	cli := NewWithPaths(legacy)
+
+	if err := cli.Execute([]string{"migrate-paths", "--dry-run"}); err != nil {
+		t.Fatalf("migrate-paths --dry-run failed: %v", err)
+	}
+	if _, err := os.Stat(legacy.StateFile); err != nil {
+		t.Fatalf("dry run moved the state file: %v", err)
+	}
+
+	if err := cli.Execute([]string{"migrate-paths"}); err != nil {
+		t.Fatalf("migrate-paths failed: %v", err)
+	}
+	xdg := config.XDGPaths(home)
+	if _, err := os.Stat(xdg.StateFile); err != nil {
+		t.Errorf("state file not moved to %s: %v", xdg.StateFile, err)
+	}
+	if _, err := os.Stat(legacy.Root); !os.IsNotExist(err) {
+		t.Errorf("legacy root still exists: %v", err)
+	}
+
+	if err := NewWithPaths(xdg).Execute([]string{"migrate-paths"}); err != nil {
+		t.Errorf("migrate-paths on the XDG layout should do nothing, got %v", err)
+	}
+	custom := config.RootPaths(home)
+	custom.Layout = config.LayoutCustom
+	if err := NewWithPaths(custom).Execute([]string{"migrate-paths"}); err == nil {
+		t.Error("migrate-paths should fail with MULTICLAUDE_HOME set")
+	}
+}
+
 func TestCLIConfigRepoNonexistent(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -1797,58 +2118,286 @@ func TestCLIConfigRepoNonexistent(t *testing.T) {
 	}
 }
 
-func TestCLIRemoveWorkerNonexistent(t *testing.T) {
+func TestCLIConfigValidate(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(valid, []byte("merge_queue:\n  track: author\n"), 0644)
+	os.WriteFile(invalid, []byte("merge_queue:\n  track: everyone\n"), 0644)
+
+	if err := cli.Execute([]string{"config", "validate", valid}); err != nil {
+		t.Errorf("config validate on a valid file failed: %v", err)
+	}
+	if err := cli.Execute([]string{"config", "validate", invalid}); err == nil {
+		t.Error("config validate on an invalid file should fail")
+	}
+
+	// Without a path, the file is found from the repository root
+	repoDir := filepath.Join(dir, "repo")
+	os.MkdirAll(filepath.Join(repoDir, ".git"), 0755)
+	os.MkdirAll(filepath.Join(repoDir, ".multiclaude"), 0755)
+	os.MkdirAll(filepath.Join(repoDir, "sub", "dir"), 0755)
+	os.WriteFile(filepath.Join(repoDir, ".multiclaude", "config.yaml"), []byte("default_branch: main\n"), 0644)
+	if got := findRepoConfig(filepath.Join(repoDir, "sub", "dir")); got != filepath.Join(repoDir, ".multiclaude", "config.yaml") {
+		t.Errorf("findRepoConfig = %q, want the repo's config file", got)
+	}
+	if got := findRepoConfig(dir); got != "" {
+		t.Errorf("findRepoConfig outside a repo = %q, want none", got)
+	}
+}
+
+func TestCLIDigest(t *testing.T) {
 	cli, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	// Add a repo
 	repo := &state.Repository{
 		GithubURL:   "https://github.com/test/repo",
 		TmuxSession: "mc-test-repo",
-		Agents:      make(map[string]state.Agent),
+		Agents: map[string]state.Agent{
+			"default": {Type: state.AgentTypeWorkspace},
+		},
+		TaskHistory: []state.TaskHistoryEntry{
+			{Name: "quiet-elk", Task: "migrate config", Status: state.TaskStatusFailed, FailureReason: "tests time out", CompletedAt: time.Now()},
+		},
 	}
 	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
 		t.Fatalf("Failed to add repo: %v", err)
 	}
 
-	// Remove nonexistent worker should fail
-	err := cli.Execute([]string{"work", "rm", "nonexistent-worker", "--repo", "test-repo"})
-	if err == nil {
-		t.Error("removing nonexistent worker should fail")
+	if err := cli.Execute([]string{"digest", "--repo", "test-repo", "--since", "2h"}); err != nil {
+		t.Errorf("digest failed: %v", err)
 	}
-}
-
-func TestCLIRemoveWorkerWithRealTmux(t *testing.T) {
-	tmuxClient := tmux.NewClient()
-	if !tmuxClient.IsTmuxAvailable() {
-		t.Fatal("tmux is required for this test but not available")
+	if err := cli.Execute([]string{"digest", "--repo", "test-repo", "--since", "soon"}); err == nil {
+		t.Error("digest with an invalid --since should fail")
+	}
+	if err := cli.Execute([]string{"digest", "--repo", "test-repo", "--post-to", "email"}); err == nil {
+		t.Error("digest with an unknown --post-to target should fail")
 	}
 
-	cli, d, cleanup := setupTestEnvironment(t)
-	defer cleanup()
-
-	paths := d.GetPaths()
-	repoName := "test-repo"
-
-	// Create tmux session
-	tmuxSession := "mc-test-rm"
-	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
-		t.Fatalf("Failed to create tmux session: %v", err)
+	t.Setenv("MULTICLAUDE_SLACK_WEBHOOK", "")
+	if err := cli.Execute([]string{"digest", "--repo", "test-repo", "--post-to", "slack"}); err == nil {
+		t.Error("posting to Slack without a webhook should fail")
 	}
-	defer tmuxClient.KillSession(context.Background(), tmuxSession)
 
-	// Create worker window
-	if err := tmuxClient.CreateWindow(context.Background(), tmuxSession, "test-worker"); err != nil {
-		t.Fatalf("Failed to create window: %v", err)
+	if err := cli.Execute([]string{"digest", "--repo", "test-repo", "--post-to", "workspace"}); err != nil {
+		t.Fatalf("digest --post-to workspace failed: %v", err)
+	}
+	msgs, err := messages.NewManager(d.GetPaths().MessagesDir).List("test-repo", "default")
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("workspace inbox = %v, %v; want one digest", msgs, err)
 	}
+	if msgs[0].From != "digest" || !strings.Contains(msgs[0].Body, "FAILED: migrate config - tests time out") {
+		t.Errorf("posted digest = %+v", msgs[0])
+	}
+}
+
+func TestCLITaskQueue(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
 
-	// Add repo to daemon
 	repo := &state.Repository{
 		GithubURL:   "https://github.com/test/repo",
-		TmuxSession: tmuxSession,
-		Agents:      make(map[string]state.Agent),
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"calm-owl": {Type: state.AgentTypeWorker, TmuxWindow: "calm-owl"},
+		},
 	}
-	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// A task already running on calm-owl, so nothing new gets a worker
+	store, _ := tasks.Load(d.GetPaths().TasksFile())
+	first, _ := store.Add("test-repo", "Add the schema", nil, time.Now())
+	first.Status, first.Worker = tasks.StatusRunning, "calm-owl"
+	if err := store.Save(); err != nil {
+		t.Fatalf("Failed to save tasks: %v", err)
+	}
+
+	if err := cli.Execute([]string{"task", "add", "Build", "the", "API", "--repo", "test-repo", "--after", first.ID}); err != nil {
+		t.Fatalf("task add failed: %v", err)
+	}
+	store, _ = tasks.Load(d.GetPaths().TasksFile())
+	list := store.List("test-repo")
+	if len(list) != 2 || list[1].Description != "Build the API" || list[1].Status != tasks.StatusWaiting || list[1].After[0] != first.ID {
+		t.Fatalf("tasks = %+v, want the new task waiting on the first", list)
+	}
+	second := list[1].ID
+
+	if err := cli.Execute([]string{"task", "add", "Orphan", "--repo", "test-repo", "--after", "t-missing"}); err == nil {
+		t.Error("task add should fail with an unknown prerequisite")
+	}
+	for _, args := range [][]string{
+		{"task", "list", "--repo", "test-repo"},
+		{"task", "graph", "--repo", "test-repo"},
+		{"task", "graph", "--repo", "test-repo", "--dot"},
+	} {
+		if err := cli.Execute(args); err != nil {
+			t.Errorf("%v failed: %v", args, err)
+		}
+	}
+
+	if err := cli.Execute([]string{"task", "cancel", second}); err != nil {
+		t.Errorf("task cancel failed: %v", err)
+	}
+	if err := cli.Execute([]string{"task", "cancel", first.ID}); err == nil {
+		t.Error("task cancel should fail for a running task")
+	}
+	store, _ = tasks.Load(d.GetPaths().TasksFile())
+	if got := store.Tasks[second].Status; got != tasks.StatusCancelled {
+		t.Errorf("cancelled task status = %s", got)
+	}
+}
+
+func TestCLIAskAnswer(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"calm-owl": {Type: state.AgentTypeWorker, TmuxWindow: "calm-owl"},
+		},
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	defer func(interval time.Duration) { askPollInterval = interval }(askPollInterval)
+	askPollInterval = 10 * time.Millisecond
+
+	// openTicket waits for the daemon to record a question and returns its ID
+	openTicket := func(question string) string {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			store, err := tickets.Load(d.GetPaths().TicketsFile())
+			if err != nil {
+				t.Fatalf("Failed to load tickets: %v", err)
+			}
+			for id, ticket := range store.Tickets {
+				if ticket.Question == question && ticket.AnsweredAt == nil {
+					return id
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("no ticket opened for %q", question)
+		return ""
+	}
+
+	// A waiting ask returns once the question is answered
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.Execute([]string{"ask", "calm-owl", "Which DB driver?", "--repo", "test-repo", "--timeout", "30s"})
+	}()
+	id := openTicket("Which DB driver?")
+	if err := cli.Execute([]string{"answer", id, "pgx", "--", "not", "sqlx"}); err != nil {
+		t.Fatalf("answer failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ask failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ask did not return after the answer")
+	}
+	if err := cli.Execute([]string{"ask", "--ticket", id}); err != nil {
+		t.Errorf("ask --ticket failed: %v", err)
+	}
+	store, _ := tickets.Load(d.GetPaths().TicketsFile())
+	if ticket := store.Tickets[id]; ticket.Answer != "pgx -- not sqlx" || ticket.From != "human" {
+		t.Errorf("ticket = %+v, want the full answer to the human's question", ticket)
+	}
+
+	// Unanswered questions time out
+	if err := cli.Execute([]string{"ask", "calm-owl", "Still there?", "--repo", "test-repo", "--timeout", "50ms"}); err == nil {
+		t.Error("ask should fail when nobody answers before the timeout")
+	}
+
+	if err := cli.Execute([]string{"ask", "calm-owl", "Any blockers?", "--repo", "test-repo", "--no-wait"}); err != nil {
+		t.Errorf("ask --no-wait failed: %v", err)
+	}
+	if err := cli.Execute([]string{"ask", "nobody", "Hello?", "--repo", "test-repo", "--no-wait"}); err == nil {
+		t.Error("asking an unknown agent should fail")
+	}
+	if err := cli.Execute([]string{"answer", "tk-missing", "hi"}); err == nil {
+		t.Error("answering an unknown ticket should fail")
+	}
+}
+
+func TestCLIDaemonService(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// --print only shows the definition; nothing is installed
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		if err := cli.Execute([]string{"daemon", "install-service", "--print"}); err != nil {
+			t.Errorf("daemon install-service --print failed: %v", err)
+		}
+	}
+
+	if err := cli.Execute([]string{"daemon", "watch", "--interval", "soon"}); err == nil {
+		t.Error("daemon watch with an invalid interval should fail")
+	}
+}
+
+func TestCLIRemoveWorkerNonexistent(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Add a repo
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Remove nonexistent worker should fail
+	err := cli.Execute([]string{"work", "rm", "nonexistent-worker", "--repo", "test-repo"})
+	if err == nil {
+		t.Error("removing nonexistent worker should fail")
+	}
+}
+
+func TestCLIRemoveWorkerWithRealTmux(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	paths := d.GetPaths()
+	repoName := "test-repo"
+
+	// Create tmux session
+	tmuxSession := "mc-test-rm"
+	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	// Create worker window
+	if err := tmuxClient.CreateWindow(context.Background(), tmuxSession, "test-worker"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	// Add repo to daemon
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: tmuxSession,
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
 		t.Fatalf("Failed to add repo: %v", err)
 	}
 
@@ -1956,8 +2505,7 @@ func TestCLIReviewInvalidURL(t *testing.T) {
 }
 
 func TestCLIGetReposList(t *testing.T) {
-	cli, d, cleanup := setupTestEnvironment(t)
-	defer cleanup()
+	cli, d := setupFakeDaemon(t)
 
 	// Initially empty
 	repos := cli.getReposList()
@@ -1967,14 +2515,7 @@ func TestCLIGetReposList(t *testing.T) {
 
 	// Add some repos
 	for _, name := range []string{"repo1", "repo2", "repo3"} {
-		repo := &state.Repository{
-			GithubURL:   "https://github.com/test/" + name,
-			TmuxSession: "mc-" + name,
-			Agents:      make(map[string]state.Agent),
-		}
-		if err := d.GetState().AddRepo(name, repo); err != nil {
-			t.Fatalf("Failed to add repo: %v", err)
-		}
+		d.AddRepo(name, daemontest.Repo{GithubURL: "https://github.com/test/" + name, TmuxSession: "mc-" + name})
 	}
 
 	// Should now have 3 repos
@@ -2710,6 +3251,51 @@ func TestIsDevVersion(t *testing.T) {
 	}
 }
 
+func TestCLIAgentsFind(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"calm-owl": {Type: state.AgentTypeWorker, TmuxWindow: "calm-owl", Capabilities: []string{"review-go"}},
+		},
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	agentsDir := cli.paths.RepoAgentsDir("test-repo")
+	if err := os.MkdirAll(agentsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"worker.md":     "# Worker\n\nDoes tasks.\n",
+		"go-expert.md":  "# Go Expert\n\nCapabilities: review-go, write-go\n",
+		"sql-writer.md": "# SQL Writer\n\nCapabilities: write-sql-migrations\n",
+	} {
+		if err := os.WriteFile(filepath.Join(agentsDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cli.Execute([]string{"agents", "find", "--capability", "Review Go", "--repo", "test-repo"}); err != nil {
+		t.Errorf("agents find failed: %v", err)
+	}
+	if err := cli.Execute([]string{"agents", "find", "--repo", "test-repo"}); err == nil {
+		t.Error("agents find without --capability should fail")
+	}
+
+	def, err := cli.capableDefinition("test-repo", "write-sql-migrations")
+	if err != nil || def.Name != "sql-writer" {
+		t.Errorf("capableDefinition() = %q, %v; want sql-writer", def.Name, err)
+	}
+	if _, err := cli.capableDefinition("test-repo", "deploy"); err == nil {
+		t.Error("capableDefinition() should fail when no definition declares the capability")
+	}
+}
+
 func TestListAgentDefinitions(t *testing.T) {
 	// Create temp directory structure
 	tmpDir, err := os.MkdirTemp("", "cli-agents-test-*")
@@ -2793,7 +3379,7 @@ A team-specific bot.
 	}
 
 	// Run list agents definitions (this doesn't require daemon)
-	err = cli.listAgentDefinitions([]string{"--repo", repoName})
+	err = cli.listAgentDefinitions(CommandContext{Repo: repoName}, nil)
 	if err != nil {
 		t.Errorf("listAgentDefinitions failed: %v", err)
 	}
@@ -2881,6 +3467,45 @@ func TestExecuteUnknownCommand(t *testing.T) {
 	}
 }
 
+func TestStripPlainFlag(t *testing.T) {
+	args, plain := stripPlainFlag([]string{"--plain", "worker", "list", "--", "--plain"})
+	if !plain {
+		t.Error("stripPlainFlag() didn't find --plain")
+	}
+	if want := []string{"worker", "list", "--", "--plain"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("stripPlainFlag() = %q, want %q", args, want)
+	}
+	if _, plain := stripPlainFlag([]string{"worker", "list"}); plain {
+		t.Error("stripPlainFlag() found --plain where there isn't one")
+	}
+}
+
+func TestIsBulkRemove(t *testing.T) {
+	tests := map[string]bool{
+		"calm-owl --purge":                false,
+		"calm-owl --repo web":             false,
+		"--all --ready-for-cleanup":       true,
+		"--filter=status=pr-merged --yes": true,
+		"--filter status=pr-merged":       true,
+		"--dry-run --repo web --idle":     true,
+	}
+	for args, want := range tests {
+		if got := isBulkRemove(strings.Fields(args)); got != want {
+			t.Errorf("isBulkRemove(%q) = %v, want %v", args, got, want)
+		}
+	}
+}
+
+func TestBulkWorkersRequiresSelection(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	err := cli.Execute([]string{"worker", "nudge", "--repo", "test-repo"})
+	if err == nil || !strings.Contains(err.Error(), "select workers with --all") {
+		t.Errorf("worker nudge without a selection = %v, want a usage error", err)
+	}
+}
+
 func TestSpawnAgentFromFile(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -2898,14 +3523,14 @@ func TestSpawnAgentFromFile(t *testing.T) {
 			wantError: "--class is required",
 		},
 		{
-			name:      "missing prompt-file flag",
+			name:      "missing prompt-file and definition flags",
 			args:      []string{"--name", "test-agent", "--class", "ephemeral"},
-			wantError: "--prompt-file is required",
+			wantError: "--prompt-file or --definition is required",
 		},
 		{
 			name:      "invalid class value",
 			args:      []string{"--name", "test-agent", "--class", "invalid", "--prompt-file", "/tmp/prompt.md"},
-			wantError: "--class must be 'persistent' or 'ephemeral'",
+			wantError: "--class must be 'persistent', 'ephemeral' or 'observer'",
 		},
 	}
 
@@ -2914,7 +3539,7 @@ func TestSpawnAgentFromFile(t *testing.T) {
 			cli, _, cleanup := setupTestEnvironment(t)
 			defer cleanup()
 
-			err := cli.spawnAgentFromFile(tt.args)
+			err := cli.spawnAgentFromFile(CommandContext{}, tt.args)
 			if err == nil {
 				t.Fatalf("spawnAgentFromFile() should fail with error containing %q", tt.wantError)
 			}
@@ -2950,10 +3575,9 @@ func TestSpawnAgentFromFilePromptNotFound(t *testing.T) {
 		"--name", "test-agent",
 		"--class", "ephemeral",
 		"--prompt-file", nonExistentPath,
-		"--repo", repoName,
 	}
 
-	err := cli.spawnAgentFromFile(args)
+	err := cli.spawnAgentFromFile(CommandContext{Repo: repoName}, args)
 	if err == nil {
 		t.Fatal("spawnAgentFromFile() should fail when prompt file doesn't exist")
 	}
@@ -2987,7 +3611,7 @@ func TestResetAgentDefinitions(t *testing.T) {
 		os.RemoveAll(agentsDir)
 
 		// Run reset
-		err := cli.resetAgentDefinitions([]string{"--repo", repoName})
+		err := cli.resetAgentDefinitions(CommandContext{Repo: repoName}, nil)
 		if err != nil {
 			t.Fatalf("resetAgentDefinitions() error = %v", err)
 		}
@@ -3020,7 +3644,7 @@ func TestResetAgentDefinitions(t *testing.T) {
 		}
 
 		// Run reset
-		err := cli.resetAgentDefinitions([]string{"--repo", repoName})
+		err := cli.resetAgentDefinitions(CommandContext{Repo: repoName}, nil)
 		if err != nil {
 			t.Fatalf("resetAgentDefinitions() error = %v", err)
 		}
@@ -3203,14 +3827,14 @@ func TestCLIAddWorkspace(t *testing.T) {
 	repoName := "workspace-test-repo"
 
 	t.Run("returns error for invalid workspace name", func(t *testing.T) {
-		err := cli.addWorkspace([]string{"invalid name with spaces", "--repo", repoName})
+		err := cli.addWorkspace(CommandContext{Repo: repoName}, []string{"invalid name with spaces"})
 		if err == nil {
 			t.Error("addWorkspace() should return error for invalid name")
 		}
 	})
 
 	t.Run("returns error when no name provided", func(t *testing.T) {
-		err := cli.addWorkspace([]string{"--repo", repoName})
+		err := cli.addWorkspace(CommandContext{Repo: repoName}, nil)
 		if err == nil {
 			t.Error("addWorkspace() should return error when no name provided")
 		}
@@ -3228,14 +3852,14 @@ func TestCLIRemoveWorkspace(t *testing.T) {
 	repoName := "remove-workspace-test"
 
 	t.Run("returns error when no name provided", func(t *testing.T) {
-		err := cli.removeWorkspace([]string{"--repo", repoName})
+		err := cli.removeWorkspace(CommandContext{Repo: repoName}, nil)
 		if err == nil {
 			t.Error("removeWorkspace() should return error when no name provided")
 		}
 	})
 
 	t.Run("returns error for nonexistent workspace", func(t *testing.T) {
-		err := cli.removeWorkspace([]string{"nonexistent-workspace", "--repo", repoName})
+		err := cli.removeWorkspace(CommandContext{Repo: repoName}, []string{"nonexistent-workspace"})
 		if err == nil {
 			t.Error("removeWorkspace() should return error for nonexistent workspace")
 		}
@@ -3253,7 +3877,7 @@ func TestCLIShowHistory(t *testing.T) {
 	repoName := "history-test-repo"
 
 	t.Run("returns error for invalid status filter", func(t *testing.T) {
-		err := cli.showHistory([]string{"--repo", repoName, "--status", "invalid"})
+		err := cli.Execute([]string{"repo", "history", "--repo", repoName, "--status", "invalid"})
 		if err == nil {
 			t.Error("showHistory() should return error for invalid status filter")
 		}
@@ -3438,7 +4062,7 @@ func TestCLIListMessages(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err := cli.listMessages([]string{})
+		err := cli.Execute([]string{"message", "list"})
 		if err != nil {
 			t.Errorf("listMessages() unexpected error: %v", err)
 		}
@@ -3456,7 +4080,7 @@ func TestCLIListMessages(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err = cli.listMessages([]string{})
+		err = cli.Execute([]string{"message", "list"})
 		if err != nil {
 			t.Errorf("listMessages() unexpected error: %v", err)
 		}
@@ -3507,7 +4131,7 @@ func TestCLIReadMessage(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err := cli.readMessage([]string{})
+		err := cli.Execute([]string{"message", "read"})
 		if err == nil {
 			t.Error("readMessage() should return error without message ID")
 		}
@@ -3525,7 +4149,7 @@ func TestCLIReadMessage(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err = cli.readMessage([]string{msg.ID})
+		err = cli.Execute([]string{"message", "read", msg.ID})
 		if err != nil {
 			t.Errorf("readMessage() unexpected error: %v", err)
 		}
@@ -3542,7 +4166,7 @@ func TestCLIReadMessage(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err := cli.readMessage([]string{"nonexistent-msg-id"})
+		err := cli.Execute([]string{"message", "read", "nonexistent-msg-id"})
 		if err == nil {
 			t.Error("readMessage() should return error for nonexistent message")
 		}
@@ -3593,7 +4217,7 @@ func TestCLIAckMessage(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err := cli.ackMessage([]string{})
+		err := cli.Execute([]string{"message", "ack"})
 		if err == nil {
 			t.Error("ackMessage() should return error without message ID")
 		}
@@ -3611,7 +4235,7 @@ func TestCLIAckMessage(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err = cli.ackMessage([]string{msg.ID})
+		err = cli.Execute([]string{"message", "ack", msg.ID})
 		if err != nil {
 			t.Errorf("ackMessage() unexpected error: %v", err)
 		}
@@ -3628,7 +4252,7 @@ func TestCLIAckMessage(t *testing.T) {
 			t.Fatalf("Failed to change to worktree: %v", err)
 		}
 
-		err := cli.ackMessage([]string{"nonexistent-msg-id"})
+		err := cli.Execute([]string{"message", "ack", "nonexistent-msg-id"})
 		if err == nil {
 			t.Error("ackMessage() should return error for nonexistent message")
 		}
@@ -3672,3 +4296,987 @@ func TestLoadStateFunction(t *testing.T) {
 		}
 	})
 }
+
+func TestResolveMessageRecipient(t *testing.T) {
+	st := state.New(filepath.Join(t.TempDir(), "state.json"))
+	for _, name := range []string{"api", "web", "infra"} {
+		if err := st.AddRepo(name, &state.Repository{TmuxSession: "mc-" + name, Agents: make(map[string]state.Agent)}); err != nil {
+			t.Fatalf("AddRepo(%s) failed: %v", name, err)
+		}
+	}
+	if err := st.AddProject("platform", &state.Project{Repos: []string{"api", "web"}}); err != nil {
+		t.Fatalf("AddProject() failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		fromRepo  string
+		to        string
+		wantRepo  string
+		wantAgent string
+		wantErr   bool
+	}{
+		{name: "same repo", fromRepo: "api", to: "supervisor", wantRepo: "api", wantAgent: "supervisor"},
+		{name: "explicit same repo", fromRepo: "api", to: "api/merge-queue", wantRepo: "api", wantAgent: "merge-queue"},
+		{name: "cross repo in project", fromRepo: "api", to: "web/supervisor", wantRepo: "web", wantAgent: "supervisor"},
+		{name: "cross repo outside project", fromRepo: "api", to: "infra/supervisor", wantErr: true},
+		{name: "to project supervisor", fromRepo: "web", to: "@platform", wantRepo: "@platform", wantAgent: "supervisor"},
+		{name: "to project supervisor from non-member", fromRepo: "infra", to: "@platform", wantErr: true},
+		{name: "to unknown project", fromRepo: "api", to: "@nope", wantErr: true},
+		{name: "project supervisor to member", fromRepo: "@platform", to: "web/supervisor", wantRepo: "web", wantAgent: "supervisor"},
+		{name: "project supervisor to non-member", fromRepo: "@platform", to: "infra/supervisor", wantErr: true},
+		{name: "project supervisor without repo", fromRepo: "@platform", to: "supervisor", wantErr: true},
+		{name: "malformed recipient", fromRepo: "api", to: "web/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRepo, gotAgent, err := resolveMessageRecipient(st, tt.fromRepo, tt.to)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveMessageRecipient() = %q, %q, want error", gotRepo, gotAgent)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveMessageRecipient() error = %v", err)
+			}
+			if gotRepo != tt.wantRepo || gotAgent != tt.wantAgent {
+				t.Errorf("resolveMessageRecipient() = %q, %q, want %q, %q", gotRepo, gotAgent, tt.wantRepo, tt.wantAgent)
+			}
+		})
+	}
+}
+
+func TestChooseRepairActions(t *testing.T) {
+	plan := []reconcile.Discrepancy{
+		{Repo: "repo", Agent: "gone-owl", Kind: reconcile.KindMissingWindow, Actions: []reconcile.Action{reconcile.ActionRecreate, reconcile.ActionDelete}},
+		{Repo: "repo", Agent: "stray-cat", Kind: reconcile.KindOrphanWindow, Actions: []reconcile.Action{reconcile.ActionAdopt, reconcile.ActionDelete}},
+		{Repo: "repo", Agent: "old-bee", Kind: reconcile.KindOrphanMessages, Actions: []reconcile.Action{reconcile.ActionDelete}},
+		{Repo: "repo", Agent: "keep-me", Kind: reconcile.KindOrphanMessages, Actions: []reconcile.Action{reconcile.ActionDelete}},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "letters and full names",
+			input: "r\nadopt\ns\nd\n",
+			want:  []string{"gone-owl:recreate", "stray-cat:adopt", "keep-me:delete"},
+		},
+		{
+			name:  "invalid choice reprompts",
+			input: "a\nd\n\n\n\n",
+			want:  []string{"gone-owl:delete"},
+		},
+		{
+			name:  "end of input skips the rest",
+			input: "delete\n",
+			want:  []string{"gone-owl:delete"},
+		},
+		{
+			name:  "empty input skips all",
+			input: "",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			choices, err := chooseRepairActions(strings.NewReader(tt.input), &out, plan)
+			if err != nil {
+				t.Fatalf("chooseRepairActions() failed: %v", err)
+			}
+			var got []string
+			for _, c := range choices {
+				got = append(got, c.discrepancy.Agent+":"+string(c.action))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("choices = %v, want %v\noutput:\n%s", got, tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestCLIRepairInteractiveDryRun(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// An agent whose session doesn't exist shows up in the diff but is kept
+	if err := d.GetState().AddRepo("repair-repo", &state.Repository{
+		TmuxSession: "mc-repair-repo-nonexistent",
+		Agents: map[string]state.Agent{
+			"keep-me": {Type: state.AgentTypeWorker, TmuxWindow: "keep-me", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := cli.Execute([]string{"repair", "--dry-run"}); err != nil {
+		t.Fatalf("repair --dry-run failed: %v", err)
+	}
+	if _, ok := d.GetState().GetAgent("repair-repo", "keep-me"); !ok {
+		t.Error("dry-run repair should not remove agents")
+	}
+
+	if err := cli.Execute([]string{"repair", "--auto"}); err != nil {
+		t.Fatalf("repair --auto failed: %v", err)
+	}
+	if _, ok := d.GetState().GetAgent("repair-repo", "keep-me"); ok {
+		t.Error("auto repair should remove agents whose session is gone")
+	}
+}
+
+func TestCutRepeatedFlag(t *testing.T) {
+	values, rest, err := cutRepeatedFlag([]string{"add dark mode", "--criteria", "toggle works", "--repo", "r", "--criteria=contrast -- AA"}, "criteria")
+	if err != nil {
+		t.Fatalf("cutRepeatedFlag() error: %v", err)
+	}
+	if strings.Join(values, "|") != "toggle works|contrast -- AA" {
+		t.Errorf("values = %q", values)
+	}
+	if strings.Join(rest, " ") != "add dark mode --repo r" {
+		t.Errorf("rest = %q", rest)
+	}
+	if _, _, err := cutRepeatedFlag([]string{"task", "--criteria"}, "criteria"); err == nil {
+		t.Error("cutRepeatedFlag() should fail when the flag has no value")
+	}
+}
+
+func TestParseCriteria(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "checklist items only",
+			text: "## Done when\nSome prose.\n- [ ] tests pass\n- [x] docs updated\n* [ ] changelog entry\n",
+			want: []string{"tests pass", "docs updated", "changelog entry"},
+		},
+		{
+			name: "plain lines and list items",
+			text: "# Criteria\n\n- tests pass\n2. docs updated\nno regressions\n",
+			want: []string{"tests pass", "docs updated", "no regressions"},
+		},
+		{
+			name: "empty",
+			text: "\n\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCriteria(tt.text); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("parseCriteria() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCriterionReport(t *testing.T) {
+	report, err := parseCriterionReport("2=Unmet: no Windows CI")
+	if err != nil {
+		t.Fatalf("parseCriterionReport() error: %v", err)
+	}
+	if report["index"] != 2 || report["status"] != "unmet" || report["note"] != "no Windows CI" {
+		t.Errorf("report = %v", report)
+	}
+	for _, bad := range []string{"met", "0=met", "x=met", "1=done", "1="} {
+		if _, err := parseCriterionReport(bad); err == nil {
+			t.Errorf("parseCriterionReport(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAcceptanceCriteriaPrompt(t *testing.T) {
+	prompt := acceptanceCriteriaPrompt([]string{"tests pass", "docs updated"})
+	for _, want := range []string{"## Acceptance Criteria", "1. tests pass\n2. docs updated\n", "multiclaude agent complete --criterion 1=met"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestDraftPRPrompt(t *testing.T) {
+	prompt := draftPRPrompt("calm-owl")
+	for _, want := range []string{"## Draft PRs", "gh pr create --draft", "multiclaude worker ready calm-owl\nmulticlaude agent complete"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestBaseBranchPrompt(t *testing.T) {
+	prompt := baseBranchPrompt("release/1.2", true)
+	for _, want := range []string{"## Base Branch", "origin/release/1.2", "gh pr create --base release/1.2"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if prompt := baseBranchPrompt("release/1.2", false); strings.Contains(prompt, "gh pr create") {
+		t.Errorf("prompt for a worker that opens no PR tells it to open one:\n%s", prompt)
+	}
+}
+
+func TestPushChecksPrompt(t *testing.T) {
+	prompt := pushChecksPrompt("clever-fox", state.PushChecks{Rebased: true, MessagePattern: "^feat: "})
+	for _, want := range []string{"## Push Checks", "Rebased on the latest base branch", "`^feat: `", "multiclaude worker check clever-fox"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "signed") {
+		t.Errorf("prompt asks for signed commits, which aren't checked:\n%s", prompt)
+	}
+}
+
+func TestBaseStartPoint(t *testing.T) {
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"branch", "release/1.2"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	// Without a remote copy, the local branch
+	if start, err := baseStartPoint(repo, "release/1.2"); err != nil || start != "release/1.2" {
+		t.Errorf("baseStartPoint() = %q, %v, want release/1.2", start, err)
+	}
+	if _, err := baseStartPoint(repo, "release/9.9"); err == nil {
+		t.Error("baseStartPoint() found a branch that doesn't exist")
+	}
+}
+
+func TestGitHubUnavailablePrompt(t *testing.T) {
+	prompt := githubUnavailablePrompt("gh is not installed")
+	for _, want := range []string{"## GitHub Is Unavailable", "(gh is not installed)", "git push -u origin HEAD", "no PR opened"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestPromptFileDefinition(t *testing.T) {
+	cli := NewWithPaths(&config.Paths{Root: "/mc", ReposDir: "/mc/repos"})
+	tests := map[string]string{
+		filepath.Join(cli.paths.RepoAgentsDir("app"), "reviewer.md"):                "reviewer",
+		filepath.Join(cli.paths.RepoDir("app"), ".multiclaude", "agents", "sql.md"): "sql",
+		filepath.Join(cli.paths.RepoAgentsDir("other"), "reviewer.md"):              "",
+		"/tmp/reviewer.md": "",
+		filepath.Join(cli.paths.RepoAgentsDir("app"), "notes.txt"): "",
+	}
+	for path, want := range tests {
+		if got := cli.promptFileDefinition("app", path); got != want {
+			t.Errorf("promptFileDefinition(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDefinitionUsage(t *testing.T) {
+	running := map[string]int{"reviewer": 2}
+	limited := agents.Definition{Name: "reviewer", FrontMatter: map[string]string{agents.MaxInstancesKey: "3"}}
+	if got := definitionUsage(limited, running); got != "2/3" {
+		t.Errorf("definitionUsage() = %q, want 2/3", got)
+	}
+	if got := definitionUsage(agents.Definition{Name: "worker"}, running); got != "-" {
+		t.Errorf("definitionUsage() without a limit = %q, want -", got)
+	}
+}
+
+func TestCLIWSL(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := cli.Execute([]string{"wsl"}); err != nil {
+		t.Errorf("wsl failed: %v", err)
+	}
+	if err := cli.Execute([]string{"wsl", "path", `C:\Users\me\task.md`}); err != nil {
+		t.Errorf("wsl path failed: %v", err)
+	}
+	if err := cli.Execute([]string{"wsl", "path"}); err == nil {
+		t.Error("wsl path without a path should fail")
+	}
+	if got := localPath("task.md"); got != "task.md" {
+		t.Errorf("localPath() = %q, want relative paths unchanged", got)
+	}
+}
+
+func TestCLISolo(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	dir := filepath.Join(cli.paths.Root, "My Notes")
+	setupTestRepo(t, dir)
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer exec.Command("tmux", "kill-session", "-t", "mc-solo-my-notes").Run()
+
+	if err := cli.Execute([]string{"solo"}); err == nil {
+		t.Error("solo without a task should fail")
+	}
+	if err := cli.Execute([]string{"solo", "tidy the README", "--name", "calm-owl"}); err != nil {
+		t.Fatalf("solo failed: %v", err)
+	}
+	if err := cli.Execute([]string{"solo", "fix the typo", "--name", "keen-fox", "--worktree"}); err != nil {
+		t.Fatalf("solo --worktree failed: %v", err)
+	}
+	if err := cli.Execute([]string{"solo", "again", "--name", "calm-owl"}); err == nil {
+		t.Error("solo should refuse a name that's already running")
+	}
+
+	repo, ok := d.GetState().GetRepo("solo-my-notes")
+	if !ok || !repo.Solo || repo.Path != dir {
+		t.Fatalf("solo repo = %+v, want solo at %s", repo, dir)
+	}
+	if agent := repo.Agents["calm-owl"]; agent.Type != state.AgentTypeSolo || agent.WorktreePath != dir || agent.Task != "tidy the README" {
+		t.Errorf("calm-owl = %+v, want a solo agent working in %s", agent, dir)
+	}
+	wtPath := cli.paths.AgentWorktree("solo-my-notes", "keen-fox")
+	if agent := repo.Agents["keen-fox"]; agent.WorktreePath != wtPath {
+		t.Errorf("keen-fox worktree = %q, want %q", agent.WorktreePath, wtPath)
+	}
+	if out, err := exec.Command("git", "-C", wtPath, "branch", "--show-current").Output(); err != nil || strings.TrimSpace(string(out)) != "solo/keen-fox" {
+		t.Errorf("keen-fox branch = %q, %v; want solo/keen-fox", out, err)
+	}
+}
+
+func TestCLIAdopt(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repoSession := "mc-test-adopt-repo"
+	userSession := "mc-test-adopt-user"
+	if err := tmuxClient.CreateSession(ctx, repoSession, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, repoSession)
+
+	workDir, _ := filepath.EvalSymlinks(t.TempDir())
+	if err := exec.Command("tmux", "new-session", "-d", "-s", userSession, "-n", "mywin", "-c", workDir).Run(); err != nil {
+		t.Fatalf("Failed to create user session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, userSession)
+
+	if err := d.GetState().AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: repoSession,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"adopt", "--window", "mywin", "--repo", "test-repo"}); err == nil {
+		t.Error("adopt without --session should fail")
+	}
+	if err := cli.Execute([]string{"adopt", "--session", userSession, "--window", "mywin", "--repo", "test-repo", "--as", "solo"}); err == nil {
+		t.Error("adopt should refuse types that can't be adopted")
+	}
+	if err := cli.Execute([]string{"adopt", "--session", userSession, "--window", "missing", "--repo", "test-repo"}); err == nil {
+		t.Error("adopt should fail for a missing window")
+	}
+	if err := cli.Execute([]string{"adopt", "--session", userSession, "--window", "mywin", "--repo", "test-repo", "--name", "keen-fox", "--task", "Fix the flaky test"}); err != nil {
+		t.Fatalf("adopt failed: %v", err)
+	}
+
+	agent, ok := d.GetState().GetAgent("test-repo", "keen-fox")
+	if !ok {
+		t.Fatal("adopted agent should exist in state")
+	}
+	if !agent.Adopted || agent.Type != state.AgentTypeWorker || agent.TmuxWindow != "keen-fox" || agent.Task != "Fix the flaky test" {
+		t.Errorf("adopted agent = %+v", agent)
+	}
+	if agent.WorktreePath != workDir || agent.PID == 0 {
+		t.Errorf("adopted agent directory = %q, PID = %d; want %q and the pane PID", agent.WorktreePath, agent.PID, workDir)
+	}
+
+	// The window is renamed and linked into the repo session, not moved
+	for _, session := range []string{userSession, repoSession} {
+		if has, _ := tmuxClient.HasWindow(ctx, session, "keen-fox"); !has {
+			t.Errorf("session %s should have the keen-fox window", session)
+		}
+	}
+
+	if err := cli.Execute([]string{"adopt", "--session", userSession, "--window", "keen-fox", "--repo", "test-repo"}); err == nil {
+		t.Error("adopting an already adopted agent should fail")
+	}
+}
+
+func TestCLIWatch(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := d.GetState().AddRepo("test-repo", &state.Repository{TmuxSession: "mc-test-repo", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"watch", "--count", "many"}); err == nil {
+		t.Error("watch should reject a non-numeric --count")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cli.Execute([]string{"watch", "--json", "--count", "1", "--repo", "test-repo"}) }()
+
+	// Keep adding agents until watch has seen one (it starts from the latest event)
+	deadline := time.After(5 * time.Second)
+	for i := 0; ; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("watch failed: %v", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("watch didn't return after an event")
+		case <-time.After(100 * time.Millisecond):
+			name := "agent-" + strconv.Itoa(i)
+			if _, err := cli.sendDaemonRequest("add_agent", map[string]interface{}{
+				"repo": "test-repo", "agent": name, "type": "worker", "worktree_path": "/tmp/" + name, "tmux_window": name,
+			}); err != nil {
+				t.Fatalf("add_agent failed: %v", err)
+			}
+		}
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		event events.Event
+		want  string
+	}{
+		{events.Event{Time: at, Type: events.EventAgentStarted, Repo: "api", Agent: "calm-owl", Data: map[string]string{"type": "worker", "task": "Fix the build"}},
+			"10:30:00  agent_started      api/calm-owl  worker: Fix the build"},
+		{events.Event{Time: at, Type: events.EventMessageDelivered, Repo: "api", Agent: "supervisor", Data: map[string]string{"from": "calm-owl", "id": "msg-1"}},
+			"10:30:00  message_delivered  api/supervisor  from calm-owl (msg-1)"},
+		{events.Event{Time: at, Type: events.EventTaskCompleted, Repo: "api", Agent: "calm-owl", Data: map[string]string{"task": "Fix the build"}},
+			"10:30:00  task_completed     api/calm-owl  Fix the build"},
+		{events.Event{Time: at, Type: events.EventResourceAlert, Repo: "api", Agent: "calm-owl", Data: map[string]string{"detail": "using 790% CPU"}},
+			"10:30:00  resource_alert     api/calm-owl  using 790% CPU"},
+		{events.Event{Time: at, Type: events.EventRepoRemoved, Repo: "api"},
+			"10:30:00  repo_removed       api"},
+	}
+	for _, tt := range tests {
+		if got := formatEvent(tt.event); got != tt.want {
+			t.Errorf("formatEvent(%s) = %q, want %q", tt.event.Type, got, tt.want)
+		}
+	}
+}
+
+func TestCLIDocsReference(t *testing.T) {
+	cli := NewWithPaths(config.NewTestPaths(t.TempDir()))
+
+	ref := cli.cliDocsReference("my-repo")
+	path := cli.paths.CLIDocsFile("my-repo")
+	if !strings.Contains(ref, path) || strings.Contains(ref, "multiclaude worker create") {
+		t.Errorf("reference = %q, want a pointer to %s rather than the docs", ref, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("CLI docs file not written: %v", err)
+	}
+	if string(data) != cli.documentation {
+		t.Error("CLI docs file doesn't hold the generated documentation")
+	}
+
+	// The worker prompt carries the reference, not the docs
+	prompt := cli.appendDocsAndSlashCommands("my-repo", "You are a worker.")
+	if !strings.Contains(prompt, path) || strings.Contains(prompt, cli.documentation) || !strings.Contains(prompt, "/cli") {
+		t.Errorf("prompt should point to the docs file:\n%s", prompt)
+	}
+}
+
+func TestCLICommandsList(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	commandsDir := filepath.Join(paths.RepoDir("my-repo"), ".multiclaude", "commands")
+	if err := os.MkdirAll(commandsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(commandsDir, "deploy.md"), []byte("# /deploy - Deploy to staging\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := NewWithPaths(paths)
+	if err := cli.Execute([]string{"commands", "list", "--repo", "my-repo"}); err != nil {
+		t.Errorf("commands list failed: %v", err)
+	}
+
+	// The repo's command reaches its agents' prompts
+	prompt := cli.appendDocsAndSlashCommands("my-repo", "You are a worker.")
+	if !strings.Contains(prompt, "# /deploy - Deploy to staging") {
+		t.Error("worker prompt is missing the repository's slash command")
+	}
+}
+
+func TestCLIAgentExport(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	st := state.New(paths.StateFile)
+	if err := st.AddRepo("my-repo", &state.Repository{TmuxSession: "mc-my-repo", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddAgent("my-repo", "calm-owl", state.Agent{Type: state.AgentTypeWorker, Task: "Fix the build"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := messages.NewManager(paths.MessagesDir).Send("my-repo", "supervisor", "calm-owl", "How is it going?"); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := NewWithPaths(paths)
+	output := filepath.Join(t.TempDir(), "calm-owl.md")
+	if err := cli.Execute([]string{"agent", "export", "calm-owl", "--repo", "my-repo", "--output", output}); err != nil {
+		t.Fatalf("agent export failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("export file not written: %v", err)
+	}
+	for _, want := range []string{"# my-repo/calm-owl", "**Task:** Fix the build", "How is it going?"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("export is missing %q:\n%s", want, data)
+		}
+	}
+
+	if err := cli.Execute([]string{"agent", "export", "ghost", "--repo", "my-repo"}); err == nil {
+		t.Error("exporting an unknown agent should fail")
+	}
+	if err := cli.Execute([]string{"agent", "export", "calm-owl", "--repo", "my-repo", "--format", "html"}); err == nil {
+		t.Error("an unknown format should be rejected")
+	}
+}
+
+func TestCLICleanupOutputs(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	st := state.New(paths.StateFile)
+	if err := st.AddRepo("my-repo", &state.Repository{TmuxSession: "mc-my-repo", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	goneLog := paths.AgentLogFile("my-repo", "gone-owl", true)
+	expired := filepath.Join(paths.ArchivedOutputDir("my-repo"), "old-fox.log.20260901-120000")
+	for _, path := range []string{goneLog, expired} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("output"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cli := NewWithPaths(paths)
+	if err := cli.Execute([]string{"cleanup", "--outputs", "--dry-run"}); err != nil {
+		t.Fatalf("cleanup --outputs --dry-run failed: %v", err)
+	}
+	if _, err := os.Stat(expired); err != nil {
+		t.Error("dry run deleted an archived log")
+	}
+
+	if err := cli.Execute([]string{"cleanup", "--outputs", "--older-than", "14d"}); err != nil {
+		t.Fatalf("cleanup --outputs failed: %v", err)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Error("expired archive was not deleted")
+	}
+	if _, err := os.Stat(goneLog); !os.IsNotExist(err) {
+		t.Error("removed agent's log was not archived")
+	}
+
+	if err := cli.Execute([]string{"cleanup", "--outputs", "--older-than", "soon"}); err == nil {
+		t.Error("an invalid --older-than should be rejected")
+	}
+}
+
+func TestCLITraceID(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	cli := NewWithPaths(paths)
+
+	if err := cli.Execute([]string{"version"}); err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if id := cli.TraceID(); id != "" {
+		t.Errorf("TraceID() = %q after a command that didn't use the daemon", id)
+	}
+
+	// No daemon is running, so this fails after tagging its request
+	if err := cli.Execute([]string{"repo", "list"}); err == nil {
+		t.Fatal("repo list succeeded without a daemon")
+	}
+	id := cli.TraceID()
+	if id == "" {
+		t.Fatal("TraceID() is empty after a daemon request")
+	}
+	data, err := os.ReadFile(paths.DaemonLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[trace=" + id + "] cli: repo failed"; !strings.Contains(string(data), want) {
+		t.Errorf("daemon log is missing %q:\n%s", want, data)
+	}
+
+	if err := cli.Execute([]string{"daemon", "logs", "--trace", id}); err != nil {
+		t.Errorf("daemon logs --trace failed: %v", err)
+	}
+	if cli.TraceID() == id {
+		t.Error("Execute() reused the previous trace ID")
+	}
+}
+
+func TestCLIInitDryRun(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	paths := d.GetPaths()
+
+	if err := cli.Execute([]string{"init", "https://github.com/user/app", "--dry-run"}); err != nil {
+		t.Fatalf("init --dry-run failed: %v", err)
+	}
+	if _, err := os.Stat(paths.RepoDir("app")); !os.IsNotExist(err) {
+		t.Error("dry run cloned the repository")
+	}
+	if _, exists := d.GetState().GetRepo("app"); exists {
+		t.Error("dry run registered the repository")
+	}
+
+	plan := cli.planInit("app", "https://github.com/user/app", state.DefaultMergeQueueConfig(), false)
+	var names []string
+	for _, agent := range plan.Agents {
+		names = append(names, agent.Name)
+	}
+	if got := strings.Join(names, ","); got != "supervisor,merge-queue,default" {
+		t.Errorf("planned agents = %s", got)
+	}
+	if plan := cli.planInit("app", "", state.MergeQueueConfig{}, true); len(plan.Agents) != 0 {
+		t.Errorf("--skip-agents planned %d agents", len(plan.Agents))
+	}
+
+	// A leftover clone and an already tracked repo are reported
+	if err := os.MkdirAll(paths.RepoDir("app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.GetState().AddRepo("app", &state.Repository{TmuxSession: "mc-app-test-dry-run", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatal(err)
+	}
+	problems := strings.Join(cli.checkInitPlan(plan), "\n")
+	for _, want := range []string{"already tracked", "already exists"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("checkInitPlan() = %q, want it to mention %q", problems, want)
+		}
+	}
+
+	// An interrupted clone is continued instead of being in the way, unless
+	// it's of another URL
+	os.RemoveAll(paths.RepoDir("app"))
+	missingURL := filepath.Join(t.TempDir(), "missing")
+	if err := clone.Clone(missingURL, paths.RepoDir("app"), clone.Options{}, nil); err == nil {
+		t.Fatal("clone of a missing repository succeeded")
+	}
+	plan = cli.planInit("app", missingURL, state.DefaultMergeQueueConfig(), false)
+	if plan.ResumeClone == nil {
+		t.Fatal("planInit() did not find the interrupted clone")
+	}
+	if problems := strings.Join(cli.checkInitPlan(plan), "\n"); strings.Contains(problems, "already exists") {
+		t.Errorf("checkInitPlan() = %q, want the interrupted clone continued", problems)
+	}
+	plan = cli.planInit("app", "https://github.com/user/other", state.DefaultMergeQueueConfig(), false)
+	if problems := strings.Join(cli.checkInitPlan(plan), "\n"); !strings.Contains(problems, "interrupted clone of "+missingURL) {
+		t.Errorf("checkInitPlan() = %q, want the other URL's clone reported", problems)
+	}
+}
+
+func TestCLIRepoReinitUnknownRepo(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	err := cli.Execute([]string{"repo", "reinit", "missing"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("repo reinit of an untracked repo = %v, want a not found error", err)
+	}
+	// The daemon's code and remediation reach the CLI's error
+	if code := errors.CodeOf(err); code != errors.CodeRepoNotFound {
+		t.Errorf("error code = %s, want %s", code, errors.CodeRepoNotFound)
+	}
+	if cli.JSONOutput() {
+		t.Error("JSONOutput() without --json = true")
+	}
+
+	err = cli.Execute([]string{"repo", "reinit", "missing", "--json"})
+	if !cli.JSONOutput() || !strings.Contains(errors.FormatJSON(err), `"code":"repo_not_found"`) {
+		t.Errorf("with --json: JSONOutput() = %v, error %s", cli.JSONOutput(), errors.FormatJSON(err))
+	}
+}
+
+func TestCLIScaffold(t *testing.T) {
+	cli := NewWithPaths(config.NewTestPaths(t.TempDir()))
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "src", "pkg")
+	for _, dir := range []string{filepath.Join(repo, ".git"), sub} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Run from a subdirectory, the scaffold goes to the repository root
+	if err := cli.Execute([]string{"scaffold", sub, "--minimal"}); err != nil {
+		t.Fatalf("scaffold --minimal failed: %v", err)
+	}
+	for _, path := range []string{".multiclaude/config.yaml", ".multiclaude/agents/worker.md"} {
+		if _, err := os.Stat(filepath.Join(repo, path)); err != nil {
+			t.Errorf("%s was not created: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".multiclaude", "hooks.json")); !os.IsNotExist(err) {
+		t.Error("--minimal created hooks.json")
+	}
+
+	// Running again with --full adds the rest
+	if err := cli.Execute([]string{"scaffold", repo, "--full"}); err != nil {
+		t.Fatalf("scaffold --full failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".multiclaude", "hooks.json")); err != nil {
+		t.Errorf("--full did not create hooks.json: %v", err)
+	}
+
+	if err := cli.Execute([]string{"scaffold", repo, "--minimal", "--full"}); err == nil {
+		t.Error("scaffold accepted --minimal with --full")
+	}
+}
+
+func TestWorkerWizardPlan(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+
+	for _, name := range []string{"web", "api"} {
+		if err := d.GetState().AddRepo(name, &state.Repository{TmuxSession: "mc-" + name, Agents: make(map[string]state.Agent)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Pick the second repo (web), a two-line task, another base branch, and
+	// an out-of-range worker count before a valid one
+	input := "2\nFix the login bug\nand add a test\n\norigin/dev\n9\n2\n"
+	var out bytes.Buffer
+	plan, err := cli.planWorkers(CommandContext{}, &wizard{in: bufio.NewReader(strings.NewReader(input)), out: &out})
+	if err != nil {
+		t.Fatalf("planWorkers() failed: %v\n%s", err, out.String())
+	}
+	want := workerPlan{Repo: "web", Task: "Fix the login bug\nand add a test", Branch: "origin/dev", Count: 2}
+	if *plan != want {
+		t.Errorf("plan = %+v, want %+v", *plan, want)
+	}
+	if got := plan.command(); got != "multiclaude work 'Fix the login bug\nand add a test' --repo web --branch origin/dev" {
+		t.Errorf("command() = %q", got)
+	}
+	if !strings.Contains(out.String(), "Enter a number from 1 to 5") {
+		t.Errorf("invalid count was not rejected:\n%s", out.String())
+	}
+
+	// Running out of input cancels
+	if _, err := cli.planWorkers(CommandContext{}, &wizard{in: bufio.NewReader(strings.NewReader("1\n")), out: &out}); err == nil {
+		t.Error("planWorkers() succeeded without a task")
+	}
+}
+
+func TestResolveDuplicateTask(t *testing.T) {
+	matches := []state.TaskHistoryEntry{
+		{Name: "swift-elk", Task: "Fix the flaky login test", Status: state.TaskStatusClosed, PRNumber: 7, CompletedAt: time.Now()},
+		{Name: "calm-owl", Task: "Fix the flaky login test", Status: state.TaskStatusFailed, FailureReason: "blocked on auth mock", CompletedAt: time.Now().Add(-time.Hour)},
+	}
+	finalMessages := func(entry state.TaskHistoryEntry) []*messages.Message {
+		return []*messages.Message{{To: "supervisor", Body: entry.Name + " is blocked"}}
+	}
+	ask := func(input string) (*state.TaskHistoryEntry, string, error) {
+		var out bytes.Buffer
+		entry, err := resolveDuplicateTask(&wizard{in: bufio.NewReader(strings.NewReader(input)), out: &out}, matches, finalMessages)
+		return entry, out.String(), err
+	}
+
+	// View the second attempt, pick one out of range, then retry the second
+	entry, out, err := ask("v2\nr5\nr2\n")
+	if err != nil {
+		t.Fatalf("resolveDuplicateTask() failed: %v", err)
+	}
+	if entry == nil || entry.Name != "calm-owl" {
+		t.Errorf("retried entry = %+v, want calm-owl", entry)
+	}
+	for _, want := range []string{"Failure reason: blocked on auth mock", "to supervisor: calm-owl is blocked", "Pick an attempt from 1 to 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// An empty answer creates a new worker anyway
+	if entry, _, err := ask("\n"); err != nil || entry != nil {
+		t.Errorf("resolveDuplicateTask() with the default = %+v, %v, want to continue", entry, err)
+	}
+	if _, _, err := ask("a\n"); err == nil {
+		t.Error("resolveDuplicateTask() did not abort")
+	}
+}
+
+func TestGitStatusCell(t *testing.T) {
+	tests := []struct {
+		status interface{}
+		want   string
+	}{
+		{nil, "-"},
+		{map[string]interface{}{"branch": "work/owl", "ahead": 0.0, "behind": 0.0}, "clean"},
+		{map[string]interface{}{"ahead": 2.0, "behind": 1.0, "staged": 1.0, "unstaged": 1.0, "untracked": 1.0}, "+2 -1 ~3"},
+		{map[string]interface{}{"behind": 4.0, "conflicted": 2.0}, "-4 !2"},
+	}
+	for _, tt := range tests {
+		if got := gitStatusCell(tt.status).Text; got != tt.want {
+			t.Errorf("gitStatusCell(%v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMessageTemplate(t *testing.T) {
+	repo := t.TempDir()
+	dir := filepath.Join(repo, messages.RepoTemplatesDir)
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "request-changes.md"), []byte("# request-changes - Ask for changes\n\n{{to}}: PR #{{pr}} needs changes. - {{from}}"), 0644)
+
+	builtins := map[string]string{"to": "calm-owl", "from": "supervisor", "repo": "my-repo"}
+	body, err := renderMessageTemplate(repo, "request-changes", map[string]string{"pr": "42", "from": "the supervisor"}, builtins)
+	if err != nil {
+		t.Fatalf("renderMessageTemplate() error: %v", err)
+	}
+	if body != "calm-owl: PR #42 needs changes. - the supervisor" {
+		t.Errorf("renderMessageTemplate() = %q, want builtins filled in and --var taking precedence", body)
+	}
+
+	if _, err := renderMessageTemplate(repo, "request-changes", map[string]string{}, builtins); err == nil || !strings.Contains(err.Error(), "pr") {
+		t.Errorf("renderMessageTemplate() without pr error = %v", err)
+	}
+	if _, err := renderMessageTemplate(repo, "nope", map[string]string{}, builtins); err == nil {
+		t.Error("renderMessageTemplate() of a missing template should fail")
+	}
+}
+
+func TestSelftest(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	testMode := os.Getenv("MULTICLAUDE_TEST_MODE")
+	cli := NewWithPaths(config.RootPaths(t.TempDir()))
+	if err := cli.Execute([]string{"selftest"}); err != nil {
+		t.Fatalf("selftest failed: %v", err)
+	}
+	if got := os.Getenv("MULTICLAUDE_TEST_MODE"); got != testMode {
+		t.Errorf("selftest left MULTICLAUDE_TEST_MODE = %q, want %q", got, testMode)
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	cli := NewWithPaths(config.RootPaths(t.TempDir()))
+	path := cli.paths.TelemetryFile()
+
+	// Nothing is recorded until the user opts in
+	cli.Execute([]string{"version"})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("telemetry file exists before stats enable: %v", err)
+	}
+
+	if err := cli.Execute([]string{"stats", "enable"}); err != nil {
+		t.Fatalf("stats enable failed: %v", err)
+	}
+	cli.Execute([]string{"version", "--json"})
+	cli.Execute([]string{"config"})
+	cli.Execute([]string{"no-such-command"})
+	cli.Execute([]string{"daemon", "_run", "--help"})
+
+	events, err := telemetry.Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Command)
+	}
+	want := []string{"stats enable", "version", "config"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("recorded %v, want %v (no unknown or internal commands)", names, want)
+	}
+	if events[2].Failed != true {
+		t.Errorf("config outside a repository should be recorded as failed: %+v", events[2])
+	}
+
+	if err := cli.Execute([]string{"stats", "disable"}); err != nil {
+		t.Fatalf("stats disable failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("stats disable left the telemetry file: %v", err)
+	}
+}
+
+func TestEditorLaunch(t *testing.T) {
+	tests := []struct {
+		editor, remote string
+		binary         string
+		args           []string
+	}{
+		{"vscode", "", "code", []string{"--new-window", "--wait", "/wts/app/ws"}},
+		{"vscode", "devbox", "code", []string{"--new-window", "--wait", "--remote", "ssh-remote+devbox", "/wts/app/ws"}},
+		{"jetbrains", "", "idea", []string{"/wts/app/ws"}},
+		{"jetbrains", "me@devbox", "gateway", []string{"jetbrains-gateway://connect#type=ssh&deploy=false&host=me%40devbox&projectPath=%2Fwts%2Fapp%2Fws"}},
+	}
+	for _, tt := range tests {
+		binary, args := editors[tt.editor]("/wts/app/ws", tt.remote)
+		if binary != tt.binary || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s (remote %q) = %s %v, want %s %v", tt.editor, tt.remote, binary, args, tt.binary, tt.args)
+		}
+	}
+
+	if _, err := openInEditor("emacs", "/tmp", ""); err == nil || !strings.Contains(err.Error(), "jetbrains or vscode") {
+		t.Errorf("openInEditor(emacs) = %v, want the supported editors listed", err)
+	}
+	if got := editorLabel(map[string]interface{}{"editor": map[string]interface{}{"editor": "vscode", "remote": "devbox"}}); got != "vscode via devbox" {
+		t.Errorf("editorLabel() = %q", got)
+	}
+}
+
+func TestServiceManagerFlag(t *testing.T) {
+	if m, err := serviceManagerFlag(map[string]string{}); err != nil || m != "" {
+		t.Errorf("serviceManagerFlag() = %q, %v; want the platform's", m, err)
+	}
+	if m, err := serviceManagerFlag(map[string]string{"launchd": "true"}); err != nil || m != "launchd" {
+		t.Errorf("serviceManagerFlag(--launchd) = %q, %v", m, err)
+	}
+	if _, err := serviceManagerFlag(map[string]string{"systemd": "true", "launchd": "true"}); err == nil {
+		t.Error("serviceManagerFlag(--systemd --launchd) should fail")
+	}
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+)
+
+// Environment variables that name the repository and agent a command runs
+// as. They take precedence over the working directory, and are overridden by
+// --repo and --agent.
+const (
+	EnvRepo  = "MULTICLAUDE_REPO"
+	EnvAgent = "MULTICLAUDE_AGENT"
+)
+
+// CommandContext is the repository, and for agent commands the agent, a
+// command runs against
+type CommandContext struct {
+	Repo  string
+	Agent string
+}
+
+// withRepo wraps a command that runs against a repository. The repository
+// comes from --repo, MULTICLAUDE_REPO, or the working directory (see
+// resolveRepo), and the flag is removed from the arguments run sees.
+func (c *CLI) withRepo(run func(ctx CommandContext, args []string) error) func(args []string) error {
+	return func(args []string) error {
+		flags, rest := cutContextFlags(args, "repo")
+		repoName, err := c.resolveRepo(flags)
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		return run(CommandContext{Repo: repoName}, rest)
+	}
+}
+
+// withOptionalRepo is withRepo for commands that can run without a
+// repository, or can be given one another way: ctx.Repo is empty when none
+// is found, and the command decides whether that's an error.
+func (c *CLI) withOptionalRepo(run func(ctx CommandContext, args []string) error) func(args []string) error {
+	return func(args []string) error {
+		flags, rest := cutContextFlags(args, "repo")
+		repoName, _ := c.resolveRepo(flags)
+		return run(CommandContext{Repo: repoName}, rest)
+	}
+}
+
+// withRepoFlags is withRepo for commands with declared flags. They declare
+// --repo themselves, so it's listed in their usage and left in the flags.
+func (c *CLI) withRepoFlags(run func(ctx CommandContext, flags *FlagSet) error) func(flags *FlagSet) error {
+	return func(flags *FlagSet) error {
+		repoName, err := c.resolveRepo(flags.Map())
+		if err != nil {
+			return errors.NotInRepo()
+		}
+		return run(CommandContext{Repo: repoName}, flags)
+	}
+}
+
+// withOptionalRepoFlags is withOptionalRepo for commands with declared flags
+func (c *CLI) withOptionalRepoFlags(run func(ctx CommandContext, flags *FlagSet) error) func(flags *FlagSet) error {
+	return func(flags *FlagSet) error {
+		repoName, _ := c.resolveRepo(flags.Map())
+		return run(CommandContext{Repo: repoName}, flags)
+	}
+}
+
+// withAgent wraps a command that runs as an agent. The agent and its
+// repository come from --agent/--repo, MULTICLAUDE_AGENT/MULTICLAUDE_REPO, or
+// the working directory (see resolveAgent), and the flags are removed from the
+// arguments run sees.
+func (c *CLI) withAgent(run func(ctx CommandContext, args []string) error) func(args []string) error {
+	return func(args []string) error {
+		flags, rest := cutContextFlags(args, "repo", "agent")
+		ctx, err := c.resolveAgent(flags)
+		if err != nil {
+			return err
+		}
+		return run(ctx, rest)
+	}
+}
+
+// withOptionalAgent wraps a command that runs as an agent when it's run by
+// one, and otherwise as the human: ctx.Agent is empty outside an agent's
+// context, and ctx.Repo is empty too when no repository is found either.
+func (c *CLI) withOptionalAgent(run func(ctx CommandContext, args []string) error) func(args []string) error {
+	return func(args []string) error {
+		flags, rest := cutContextFlags(args, "repo", "agent")
+		ctx, err := c.resolveAgent(flags)
+		if err != nil || ctx.Agent == "" {
+			repoName, _ := c.resolveRepo(flags)
+			ctx = CommandContext{Repo: repoName}
+		}
+		return run(ctx, rest)
+	}
+}
+
+// resolveAgent determines the agent a command runs as, and its repository.
+// An agent named by --agent or MULTICLAUDE_AGENT belongs to the repository
+// resolveRepo finds; otherwise both are inferred from the working directory,
+// and an explicit repository must agree with it.
+func (c *CLI) resolveAgent(flags map[string]string) (CommandContext, error) {
+	agentName := flags["agent"]
+	if agentName == "" {
+		agentName = os.Getenv(EnvAgent)
+	}
+	if agentName != "" {
+		repoName, err := c.resolveRepo(flags)
+		if err != nil {
+			return CommandContext{}, errors.NotInRepo()
+		}
+		return CommandContext{Repo: repoName, Agent: agentName}, nil
+	}
+
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return CommandContext{}, err
+	}
+	explicitRepo := flags["repo"]
+	if explicitRepo == "" {
+		explicitRepo = os.Getenv(EnvRepo)
+	}
+	if explicitRepo != "" && explicitRepo != repoName {
+		return CommandContext{}, errors.New(errors.CategoryUsage,
+			fmt.Sprintf("this directory belongs to agent '%s' in '%s', not '%s'", agentName, repoName, explicitRepo)).
+			WithSuggestion("name the agent too: --agent <name>")
+	}
+	return CommandContext{Repo: repoName, Agent: agentName}, nil
+}
+
+// cutContextFlags takes the named flags, --repo and --agent, (as --flag value
+// or --flag=value) out of args. Other arguments are left exactly as given,
+// so commands with their own parsing, like message bodies, are unaffected.
+func cutContextFlags(args []string, names ...string) (map[string]string, []string) {
+	flags := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(args[i], "--"), "=")
+		if !strings.HasPrefix(args[i], "--") || !isContextFlag(name, names) {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if value != "" {
+			flags[name] = value
+		}
+	}
+	return flags, rest
+}
+
+func isContextFlag(name string, names []string) bool {
+	for _, f := range names {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
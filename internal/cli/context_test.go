@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+func TestCutContextFlags(t *testing.T) {
+	flags, rest := cutContextFlags([]string{"--repo", "api", "supervisor", "--agent=calm-owl", "ship --repo fixes", "--idempotency-key=k1"}, "repo", "agent")
+	if flags["repo"] != "api" || flags["agent"] != "calm-owl" {
+		t.Errorf("flags = %v, want repo=api agent=calm-owl", flags)
+	}
+	if got := strings.Join(rest, "|"); got != "supervisor|ship --repo fixes|--idempotency-key=k1" {
+		t.Errorf("rest = %q, want other arguments untouched", got)
+	}
+}
+
+func TestResolveAgent(t *testing.T) {
+	tmpDir, _ := filepath.EvalSymlinks(t.TempDir())
+	worktreesDir := filepath.Join(tmpDir, "wts")
+	workerDir := filepath.Join(worktreesDir, "myrepo", "worker1")
+	if err := os.MkdirAll(workerDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cli := &CLI{paths: &config.Paths{
+		Root:         tmpDir,
+		WorktreesDir: worktreesDir,
+		ReposDir:     filepath.Join(tmpDir, "repos"),
+		DaemonSock:   filepath.Join(tmpDir, "missing.sock"),
+	}}
+
+	origWd, _ := os.Getwd()
+	defer os.Chdir(origWd)
+	if err := os.Chdir(workerDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvRepo, "")
+	t.Setenv(EnvAgent, "")
+
+	check := func(name string, flags map[string]string, want CommandContext) {
+		t.Helper()
+		got, err := cli.resolveAgent(flags)
+		if err != nil || got != want {
+			t.Errorf("%s: resolveAgent() = %+v, %v; want %+v", name, got, err, want)
+		}
+	}
+
+	check("working directory", nil, CommandContext{Repo: "myrepo", Agent: "worker1"})
+	check("matching --repo", map[string]string{"repo": "myrepo"}, CommandContext{Repo: "myrepo", Agent: "worker1"})
+
+	t.Setenv(EnvRepo, "other")
+	t.Setenv(EnvAgent, "supervisor")
+	check("environment", nil, CommandContext{Repo: "other", Agent: "supervisor"})
+	check("flags override the environment", map[string]string{"repo": "api", "agent": "merge-queue"}, CommandContext{Repo: "api", Agent: "merge-queue"})
+
+	// A repository that disagrees with the directory's agent is a mistake
+	t.Setenv(EnvAgent, "")
+	_, err := cli.resolveAgent(nil)
+	if cliErr, ok := err.(*errors.CLIError); !ok || cliErr.Category != errors.CategoryUsage {
+		t.Errorf("resolveAgent() with a conflicting repo error = %v, want a usage error", err)
+	}
+
+	// Outside an agent directory with nothing named, there's no agent
+	t.Setenv(EnvRepo, "")
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.resolveAgent(nil); err == nil {
+		t.Error("resolveAgent() outside an agent directory should fail")
+	}
+
+	// The wrappers hand the resolved context to the command without the flags
+	var gotCtx CommandContext
+	var gotArgs []string
+	run := func(ctx CommandContext, args []string) error {
+		gotCtx, gotArgs = ctx, args
+		return nil
+	}
+	if err := cli.withAgent(run)([]string{"msg-1", "--agent", "worker2", "--repo=myrepo"}); err != nil {
+		t.Fatalf("withAgent() error: %v", err)
+	}
+	if gotCtx != (CommandContext{Repo: "myrepo", Agent: "worker2"}) || len(gotArgs) != 1 || gotArgs[0] != "msg-1" {
+		t.Errorf("withAgent() ran with %+v, %v", gotCtx, gotArgs)
+	}
+	t.Setenv(EnvRepo, "api")
+	if err := cli.withRepo(run)(nil); err != nil || gotCtx.Repo != "api" {
+		t.Errorf("withRepo() ran with %+v, %v; want the MULTICLAUDE_REPO repository", gotCtx, err)
+	}
+	// --agent is a flag of its own for commands that only need a repository
+	if err := cli.withRepo(run)([]string{"--agent", "reviewer"}); err != nil || strings.Join(gotArgs, " ") != "--agent reviewer" {
+		t.Errorf("withRepo() ran with %v, %v; want --agent left in the arguments", gotArgs, err)
+	}
+
+	// Without a repository the optional wrappers still run the command
+	t.Setenv(EnvRepo, "")
+	if err := cli.withOptionalRepo(run)(nil); err != nil || gotCtx != (CommandContext{}) {
+		t.Errorf("withOptionalRepo() ran with %+v, %v; want an empty context", gotCtx, err)
+	}
+	if err := cli.withRepo(run)(nil); err == nil {
+		t.Error("withRepo() outside a repository should fail")
+	}
+}
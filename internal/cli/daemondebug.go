@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/format"
+)
+
+// daemonDebug prints the running daemon's goroutine count, loop timings,
+// queue depths and last errors, for looking into a sluggish daemon without
+// restarting it
+func (c *CLI) daemonDebug(flags *FlagSet) error {
+	var args map[string]interface{}
+	if flags.Bool("pprof") {
+		args = map[string]interface{}{"pprof": true}
+	}
+	resp, err := c.sendDaemonRequest("debug", args)
+	if err != nil {
+		return err
+	}
+
+	if flags.Bool("json") {
+		data, _ := json.MarshalIndent(resp.Data, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+
+	info, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("Goroutines: %v\n", info["goroutines"])
+
+	fmt.Println("\nLoops:")
+	loops, _ := info["loops"].([]interface{})
+	if len(loops) == 0 {
+		fmt.Println("  (no ticks yet)")
+	}
+	for _, raw := range loops {
+		loop, _ := raw.(map[string]interface{})
+		line := fmt.Sprintf("  %-18v %4v ticks, last %v, max %v", loop["name"], loop["ticks"], loop["last_duration"], loop["max_duration"])
+		if reason, ok := loop["stalled"].(string); ok {
+			line += format.Red.Sprintf(" (stalled: %s)", reason)
+		} else if running, _ := loop["running"].(bool); running {
+			line += format.Yellow.Sprintf(" (running for %v)", loop["running_for"])
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println("\nQueues:")
+	queues, _ := info["queues"].(map[string]interface{})
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-18s %v\n", name, queues[name])
+	}
+
+	fmt.Println("\nLast errors:")
+	errs, _ := info["errors"].([]interface{})
+	if len(errs) == 0 {
+		fmt.Println("  " + format.Dim.Sprint("none"))
+	}
+	for _, raw := range errs {
+		e, _ := raw.(map[string]interface{})
+		fmt.Printf("  %-18v %s %v %s\n", e["subsystem"], format.Dim.Sprint(e["at"]), format.Red.Sprint(e["error"]), format.Dim.Sprintf("(%v so far)", e["count"]))
+	}
+
+	if files, ok := info["pprof"].([]interface{}); ok {
+		fmt.Println("\nProfiles:")
+		for _, file := range files {
+			fmt.Printf("  %v\n", file)
+		}
+	}
+	return nil
+}
+
+// printDaemonLoops prints the loop timings, queue depths and error counts of
+// a verbose daemon status
+func printDaemonLoops(status map[string]interface{}) {
+	fmt.Println("\nLoops:")
+	loops, _ := status["loops"].([]interface{})
+	if len(loops) == 0 {
+		fmt.Println("  (no ticks yet)")
+	} else {
+		table := format.NewColoredTable("LOOP", "LAST RUN", "TOOK", "MAX", "EVERY", "ERRORS", "STATE")
+		errorCounts := map[string]interface{}{}
+		errs, _ := status["errors"].([]interface{})
+		for _, raw := range errs {
+			e, _ := raw.(map[string]interface{})
+			errorCounts[fmt.Sprint(e["subsystem"])] = e["count"]
+		}
+		for _, raw := range loops {
+			loop, _ := raw.(map[string]interface{})
+			name := fmt.Sprint(loop["name"])
+			lastRun := "never"
+			if start, err := time.Parse(time.RFC3339, fmt.Sprint(loop["last_start"])); err == nil {
+				lastRun = format.TimeAgo(start)
+			}
+			every, _ := loop["interval"].(string)
+			if every == "" {
+				every = "-"
+			}
+			errCount := "0"
+			if n, ok := errorCounts[name]; ok {
+				errCount = fmt.Sprint(n)
+			}
+			state := format.ColorCell("ok", format.Green)
+			if reason, ok := loop["stalled"].(string); ok {
+				state = format.ColorCell("stalled: "+reason, format.Red)
+			} else if running, _ := loop["running"].(bool); running {
+				state = format.ColorCell(fmt.Sprintf("running for %v", loop["running_for"]), format.Yellow)
+			}
+			table.AddRow(format.Cell(name), format.Cell(lastRun), format.Cell(fmt.Sprint(loop["last_duration"])),
+				format.Cell(fmt.Sprint(loop["max_duration"])), format.Cell(every), format.Cell(errCount), state)
+		}
+		table.Print()
+	}
+
+	fmt.Println("\nQueues:")
+	queues, _ := status["queues"].(map[string]interface{})
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-18s %v\n", name, queues[name])
+	}
+
+	fmt.Println("\nErrors:")
+	errs, _ := status["errors"].([]interface{})
+	if len(errs) == 0 {
+		fmt.Println("  " + format.Dim.Sprint("none"))
+	}
+	for _, raw := range errs {
+		e, _ := raw.(map[string]interface{})
+		fmt.Printf("  %-18v %v, last %s %v\n", e["subsystem"], e["count"], format.Dim.Sprint(e["at"]), format.Red.Sprint(e["error"]))
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// duplicateTaskWindow is how far back `worker create` looks in the task
+// history for an earlier attempt at the same task
+const duplicateTaskWindow = 30 * 24 * time.Hour
+
+// maxDuplicateTasks caps how many earlier attempts are listed
+const maxDuplicateTasks = 3
+
+// checkDuplicateTask warns when task looks like one the repository's history
+// already finished or gave up on. On a terminal it offers to show how the
+// earlier attempt went or to retry it instead, and returns the entry to
+// retry, if any.
+func (c *CLI) checkDuplicateTask(repoName, task string) (*state.TaskHistoryEntry, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return nil, nil // The history is only advice; creating the worker doesn't need it
+	}
+	matches := st.SimilarTaskHistory(repoName, task, time.Now().Add(-duplicateTaskWindow))
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	if len(matches) > maxDuplicateTasks {
+		matches = matches[:maxDuplicateTasks]
+	}
+
+	printDuplicateTasks(os.Stdout, matches)
+	if !isTerminal(os.Stdin) {
+		fmt.Printf("Retry it instead with: multiclaude worker retry %s --repo %s\n", matches[0].ID(), repoName)
+		fmt.Printf("Skip this check with: multiclaude worker create <task> --new\n")
+		return nil, nil
+	}
+
+	w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	return resolveDuplicateTask(w, matches, func(entry state.TaskHistoryEntry) []*messages.Message {
+		return c.previousAttemptMessages(repoName, entry.Name)
+	})
+}
+
+// printDuplicateTasks lists earlier attempts at a task, most recent first
+func printDuplicateTasks(out io.Writer, matches []state.TaskHistoryEntry) {
+	fmt.Fprintln(out, "Warning: this looks like a task that was already attempted:")
+	for i, entry := range matches {
+		fmt.Fprintf(out, "  [%d] %s (%s, %s): %s\n", i+1, entry.ID(), attemptOutcome(entry),
+			format.TimeAgo(entry.CompletedAt), format.Truncate(entry.Task, 60))
+		if entry.FailureReason != "" {
+			fmt.Fprintf(out, "      Failure reason: %s\n", format.Truncate(entry.FailureReason, 100))
+		}
+	}
+}
+
+// attemptOutcome describes how a task in the history ended
+func attemptOutcome(entry state.TaskHistoryEntry) string {
+	switch entry.Status {
+	case state.TaskStatusMerged:
+		return fmt.Sprintf("merged in PR #%d", entry.PRNumber)
+	case state.TaskStatusOpen:
+		return fmt.Sprintf("PR #%d still open", entry.PRNumber)
+	case state.TaskStatusClosed:
+		return fmt.Sprintf("PR #%d closed unmerged", entry.PRNumber)
+	case state.TaskStatusNoPR:
+		return "finished without a PR"
+	case state.TaskStatusFailed:
+		return "failed"
+	default:
+		return "outcome unknown"
+	}
+}
+
+// resolveDuplicateTask asks whether to go ahead with a task that was already
+// attempted: view an earlier attempt, retry one instead, continue with a new
+// worker or abort. It returns the entry to retry, or nil to continue.
+func resolveDuplicateTask(w *wizard, matches []state.TaskHistoryEntry, finalMessages func(state.TaskHistoryEntry) []*messages.Message) (*state.TaskHistoryEntry, error) {
+	pick := func(answer string) (state.TaskHistoryEntry, bool) {
+		if len(matches) == 1 && len(answer) == 1 {
+			return matches[0], true
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(answer[1:]))
+		if err != nil || n < 1 || n > len(matches) {
+			fmt.Fprintf(w.out, "Pick an attempt from 1 to %d, e.g. %c1\n", len(matches), answer[0])
+			return state.TaskHistoryEntry{}, false
+		}
+		return matches[n-1], true
+	}
+
+	prompt := "[v]iew, [r]etry it instead, [c]reate a new worker anyway or [a]bort"
+	if len(matches) > 1 {
+		prompt = "[v<n>] view, [r<n>] retry attempt n instead, [c]reate a new worker anyway or [a]bort"
+	}
+	for {
+		answer, err := w.ask(prompt, "c")
+		if err != nil {
+			return nil, err
+		}
+		answer = strings.ToLower(answer)
+		switch answer[0] {
+		case 'c':
+			return nil, nil
+		case 'a':
+			return nil, errors.New(errors.CategoryUsage, "cancelled")
+		case 'v':
+			if entry, ok := pick(answer); ok {
+				printAttempt(w.out, entry, finalMessages(entry))
+			}
+		case 'r':
+			if entry, ok := pick(answer); ok {
+				return &entry, nil
+			}
+		default:
+			fmt.Fprintln(w.out, "Answer v, r, c or a")
+		}
+	}
+}
+
+// printAttempt shows what an earlier attempt at a task did and how it ended
+func printAttempt(out io.Writer, entry state.TaskHistoryEntry, finalMessages []*messages.Message) {
+	fmt.Fprintf(out, "\n%s\n", entry.ID())
+	fmt.Fprintf(out, "  Task:    %s\n", entry.Task)
+	fmt.Fprintf(out, "  Outcome: %s\n", attemptOutcome(entry))
+	if entry.Branch != "" {
+		fmt.Fprintf(out, "  Branch:  %s\n", entry.Branch)
+	}
+	if entry.PRURL != "" {
+		fmt.Fprintf(out, "  PR:      %s\n", entry.PRURL)
+	}
+	if entry.FailureReason != "" {
+		fmt.Fprintf(out, "  Failure reason: %s\n", entry.FailureReason)
+	}
+	if entry.Summary != "" {
+		fmt.Fprintf(out, "  Summary: %s\n", entry.Summary)
+	}
+	for _, criterion := range entry.Criteria {
+		if criterion.Note != "" {
+			fmt.Fprintf(out, "  [%s] %s - %s\n", criterion.Status, criterion.Text, criterion.Note)
+		} else {
+			fmt.Fprintf(out, "  [%s] %s\n", criterion.Status, criterion.Text)
+		}
+	}
+	if len(finalMessages) > 0 {
+		fmt.Fprintln(out, "  Final messages:")
+		for _, msg := range finalMessages {
+			fmt.Fprintf(out, "    to %s: %s\n", msg.To, msg.Body)
+		}
+	}
+	fmt.Fprintln(out)
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+)
+
+// editorLaunch is how to open a directory in an editor: the binary and its
+// arguments. remote is an SSH host the editor should connect through, or "".
+type editorLaunch func(path, remote string) (binary string, args []string)
+
+// editors are the editors `workspace connect --editor` opens worktrees in.
+// Each is started so that its process lasts as long as the window, which is
+// how `workspace list` and `workspace rm` tell whether it's still open.
+var editors = map[string]editorLaunch{
+	// VS Code's --wait keeps the CLI running until the window is closed
+	"vscode": func(path, remote string) (string, []string) {
+		args := []string{"--new-window", "--wait"}
+		if remote != "" {
+			args = append(args, "--remote", "ssh-remote+"+remote)
+		}
+		return "code", append(args, path)
+	},
+	// JetBrains Gateway for remote hosts, the IDE itself locally
+	"jetbrains": func(path, remote string) (string, []string) {
+		if remote != "" {
+			return "gateway", []string{"jetbrains-gateway://connect#type=ssh&deploy=false&host=" +
+				url.QueryEscape(remote) + "&projectPath=" + url.QueryEscape(path)}
+		}
+		return "idea", []string{path}
+	},
+}
+
+// editorNames returns the supported editors, sorted
+func editorNames() []string {
+	names := make([]string, 0, len(editors))
+	for name := range editors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openInEditor starts an editor on a directory, detached from the CLI so it
+// outlives it, and returns the editor's PID
+func openInEditor(editor, path, remote string) (int, error) {
+	launch, ok := editors[editor]
+	if !ok {
+		return 0, errors.InvalidArgument("--editor", editor, strings.Join(editorNames(), " or "))
+	}
+	binary, args := launch(path, remote)
+	binaryPath, err := exec.LookPath(binary)
+	if err != nil {
+		return 0, errors.New(errors.CategoryConfig, fmt.Sprintf("%s not found in PATH", binary)).
+			WithSuggestion(fmt.Sprintf("install the %s command-line launcher, or open %s yourself", binary, path))
+	}
+
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Dir = path
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+	pid := cmd.Process.Pid
+	// Reap it when it exits, so it doesn't linger as a zombie while we run
+	go cmd.Wait()
+	return pid, nil
+}
+
+// editorLabel describes an open editor from list_agents for display, such as
+// "vscode via devbox", or "" if there is none
+func editorLabel(info map[string]interface{}) string {
+	editor, ok := info["editor"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := editor["editor"].(string)
+	if remote, _ := editor["remote"].(string); remote != "" {
+		return name + " via " + remote
+	}
+	return name
+}
+
+// openWorkspaceInEditor opens a workspace's worktree in an editor and
+// records it on the workspace, for `workspace connect --editor`
+func (c *CLI) openWorkspaceInEditor(repoName, workspaceName string, workspaceInfo map[string]interface{}, editor, remote string) error {
+	wtPath, _ := workspaceInfo["worktree_path"].(string)
+	pid, err := openInEditor(editor, wtPath, remote)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.sendDaemonRequest("set_agent_editor", map[string]interface{}{
+		"repo":   repoName,
+		"agent":  workspaceName,
+		"editor": editor,
+		"pid":    pid,
+		"remote": remote,
+	}); err != nil {
+		fmt.Printf("Warning: failed to record the editor: %v\n", err)
+	}
+
+	fmt.Printf("%s Opened workspace '%s' in %s (PID %d)\n", format.Green.Sprint("✓"), workspaceName, editor, pid)
+	fmt.Println(format.Dim.Sprintf("Claude keeps running in the workspace window: multiclaude workspace connect %s", workspaceName))
+	return nil
+}
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+)
+
+// FlagType is the kind of value a flag takes
+type FlagType int
+
+const (
+	// FlagString takes any value
+	FlagString FlagType = iota
+	// FlagBool takes no value (--full), or an explicit one (--full=false)
+	FlagBool
+	// FlagInt takes an integer
+	FlagInt
+	// FlagDuration takes a Go duration (30s, 1h30m) or a day count (7d)
+	FlagDuration
+)
+
+// Flag declares a command flag. Commands that declare their flags get them
+// parsed, defaulted and validated before RunFlags is called, and get the
+// flags listed in their usage and help.
+type Flag struct {
+	// Name is the flag without dashes; it may be given as --name or -name
+	Name string
+	Type FlagType
+	// Value is the placeholder shown in usage, e.g. "<repo>" (default: <name>)
+	Value       string
+	Description string
+	Required    bool
+	// Default is used when the flag isn't given
+	Default string
+	// Enum restricts the flag to these values
+	Enum []string
+}
+
+// flag returns the flag as written: -n for single letters, --name otherwise
+func (f Flag) flag() string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
+
+// placeholder returns the flag as shown in a usage line
+func (f Flag) placeholder() string {
+	if f.Type == FlagBool {
+		return f.flag()
+	}
+	value := f.Value
+	if value == "" {
+		value = "<" + f.Name + ">"
+	}
+	return f.flag() + " " + value
+}
+
+// details returns the flag's description with its constraints
+func (f Flag) details() string {
+	var notes []string
+	if len(f.Enum) > 0 {
+		notes = append(notes, "one of: "+strings.Join(f.Enum, ", "))
+	}
+	if f.Required {
+		notes = append(notes, "required")
+	}
+	if f.Default != "" {
+		notes = append(notes, "default: "+f.Default)
+	}
+	if len(notes) == 0 {
+		return f.Description
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (%s)", f.Description, strings.Join(notes, "; ")))
+}
+
+// validate checks a value against the flag's type and enum
+func (f Flag) validate(value string) error {
+	switch f.Type {
+	case FlagBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.InvalidArgument(f.flag(), value, "true or false")
+		}
+	case FlagInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return errors.InvalidArgument(f.flag(), value, "an integer")
+		}
+	case FlagDuration:
+		if _, err := parseInterval(value); err != nil {
+			return errors.InvalidArgument(f.flag(), value, "a duration like 30s, 10m or 7d")
+		}
+	}
+	if len(f.Enum) > 0 {
+		for _, allowed := range f.Enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return errors.InvalidArgument(f.flag(), value, "one of: "+strings.Join(f.Enum, ", "))
+	}
+	return nil
+}
+
+// FlagSet holds a command's arguments, parsed against its declared flags
+type FlagSet struct {
+	specs  map[string]Flag
+	values map[string]string
+	args   []string
+}
+
+// ParseFlagSpecs parses args against declared flags. It accepts --flag value,
+// --flag=value and bare boolean flags, fills in defaults, and rejects unknown
+// flags, missing required ones and values of the wrong type. Everything after
+// "--" is a positional argument, even when it starts with a dash.
+func ParseFlagSpecs(specs []Flag, args []string) (*FlagSet, error) {
+	fs := &FlagSet{
+		specs:  make(map[string]Flag, len(specs)),
+		values: make(map[string]string),
+	}
+	for _, spec := range specs {
+		fs.specs[spec.Name] = spec
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			fs.args = append(fs.args, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			fs.args = append(fs.args, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		spec, known := fs.specs[name]
+		if !known {
+			return nil, errors.New(errors.CategoryUsage, fmt.Sprintf("unknown flag: %s", arg))
+		}
+		if !hasValue {
+			if spec.Type == FlagBool {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, errors.MissingArgument(spec.flag(), "value")
+			}
+		}
+		if err := spec.validate(value); err != nil {
+			return nil, err
+		}
+		fs.values[name] = value
+	}
+
+	for _, spec := range specs {
+		if _, set := fs.values[spec.Name]; set {
+			continue
+		}
+		if spec.Required {
+			return nil, errors.MissingArgument(spec.flag(), "")
+		}
+		if spec.Default != "" {
+			fs.values[spec.Name] = spec.Default
+		}
+	}
+	return fs, nil
+}
+
+// Args returns the positional arguments
+func (fs *FlagSet) Args() []string {
+	return fs.args
+}
+
+// IsSet reports whether a flag was given or has a default
+func (fs *FlagSet) IsSet(name string) bool {
+	_, ok := fs.values[name]
+	return ok
+}
+
+// String returns a flag's value, or "" if it isn't set
+func (fs *FlagSet) String(name string) string {
+	return fs.values[name]
+}
+
+// Bool returns a boolean flag's value
+func (fs *FlagSet) Bool(name string) bool {
+	v, _ := strconv.ParseBool(fs.values[name])
+	return v
+}
+
+// Int returns an integer flag's value, or 0 if it isn't set
+func (fs *FlagSet) Int(name string) int {
+	v, _ := strconv.Atoi(fs.values[name])
+	return v
+}
+
+// Duration returns a duration flag's value, or 0 if it isn't set
+func (fs *FlagSet) Duration(name string) time.Duration {
+	v, _ := parseInterval(fs.values[name])
+	return v
+}
+
+// Map returns the flag values in the form ParseFlags returns, for helpers
+// such as resolveRepo that take one
+func (fs *FlagSet) Map() map[string]string {
+	m := make(map[string]string, len(fs.values))
+	for name, value := range fs.values {
+		m[name] = value
+	}
+	return m
+}
+
+// usage returns the command's usage line, with its declared flags appended
+func (cmd *Command) usage() string {
+	parts := []string{cmd.Usage}
+	for _, f := range cmd.Flags {
+		if f.Required {
+			parts = append(parts, f.placeholder())
+		} else {
+			parts = append(parts, "["+f.placeholder()+"]")
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// parseFlags parses args against the command's declared flags, pointing
+// usage errors at the command's usage line
+func (cmd *Command) parseFlags(args []string) (*FlagSet, error) {
+	fs, err := ParseFlagSpecs(cmd.Flags, args)
+	if err != nil {
+		if cliErr, ok := err.(*errors.CLIError); ok {
+			return nil, cliErr.WithSuggestion("usage: " + cmd.usage())
+		}
+		return nil, err
+	}
+	return fs, nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+)
+
+var testFlags = []Flag{
+	{Name: "repo", Description: "Repository"},
+	{Name: "session", Required: true, Description: "tmux session"},
+	{Name: "n", Type: FlagInt, Value: "<count>", Default: "10", Description: "Number of tasks"},
+	{Name: "status", Enum: []string{"open", "merged"}, Description: "PR status"},
+	{Name: "timeout", Type: FlagDuration, Description: "How long to wait"},
+	{Name: "full", Type: FlagBool, Description: "Show everything"},
+}
+
+func TestParseFlagSpecs(t *testing.T) {
+	fs, err := ParseFlagSpecs(testFlags, []string{"fix", "--session=dev", "--full", "the bug", "-n", "5", "--timeout", "2m", "--status", "open"})
+	if err != nil {
+		t.Fatalf("ParseFlagSpecs() error: %v", err)
+	}
+	if got := strings.Join(fs.Args(), "|"); got != "fix|the bug" {
+		t.Errorf("Args() = %q, want the positional arguments", got)
+	}
+	if fs.String("session") != "dev" || fs.Int("n") != 5 || fs.Duration("timeout") != 2*time.Minute || !fs.Bool("full") || fs.String("status") != "open" {
+		t.Errorf("parsed flags = %v", fs.Map())
+	}
+	if fs.IsSet("repo") {
+		t.Error("IsSet() = true for a flag that wasn't given")
+	}
+
+	// Defaults fill in, and a boolean flag doesn't swallow the next argument
+	fs, err = ParseFlagSpecs(testFlags, []string{"--session", "dev", "--full", "task"})
+	if err != nil {
+		t.Fatalf("ParseFlagSpecs() error: %v", err)
+	}
+	if fs.Int("n") != 10 || fs.Map()["n"] != "10" {
+		t.Errorf("-n = %d, want the default 10", fs.Int("n"))
+	}
+	if len(fs.Args()) != 1 || fs.Args()[0] != "task" {
+		t.Errorf("Args() = %v, want [task]", fs.Args())
+	}
+	if fs, err := ParseFlagSpecs(testFlags, []string{"--session", "dev", "--full=false"}); err != nil || fs.Bool("full") {
+		t.Errorf("--full=false parsed as %v, %v", fs, err)
+	}
+
+	// "--" ends the flags: what follows is positional, dashes and all
+	fs, err = ParseFlagSpecs(testFlags, []string{"--session", "dev", "--", "--full", "-n", "--unknown"})
+	if err != nil {
+		t.Fatalf("ParseFlagSpecs() after -- error: %v", err)
+	}
+	if got := strings.Join(fs.Args(), "|"); got != "--full|-n|--unknown" {
+		t.Errorf("Args() = %q, want everything after -- as positional", got)
+	}
+	if fs.Bool("full") {
+		t.Error("--full after -- should not set the flag")
+	}
+
+	for _, args := range [][]string{
+		{},                                      // missing required flag
+		{"--session", "dev", "--bogus"},         // unknown flag
+		{"--session", "dev", "-n", "ten"},       // not an integer
+		{"--session", "dev", "--status", "wip"}, // not in the enum
+		{"--session", "dev", "--timeout", "-"},  // not a duration
+		{"--session", "dev", "--full=maybe"},    // not a boolean
+		{"--session"},                           // no value
+	} {
+		_, err := ParseFlagSpecs(testFlags, args)
+		if cliErr, ok := err.(*errors.CLIError); !ok || cliErr.Category != errors.CategoryUsage {
+			t.Errorf("ParseFlagSpecs(%q) error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	cmd := &Command{Name: "history", Usage: "multiclaude repo history", Flags: testFlags}
+	want := "multiclaude repo history [--repo <repo>] --session <session> [-n <count>] [--status <status>] [--timeout <timeout>] [--full]"
+	if got := cmd.usage(); got != want {
+		t.Errorf("usage() = %q, want %q", got, want)
+	}
+
+	_, err := cmd.parseFlags([]string{"--status", "wip", "--session", "dev"})
+	cliErr, ok := err.(*errors.CLIError)
+	if !ok || !strings.Contains(cliErr.Suggestion, cmd.usage()) || !strings.Contains(cliErr.Message, "open, merged") {
+		t.Errorf("parseFlags() error = %#v, want the allowed values and the usage line", err)
+	}
+
+	if got := testFlags[2].details(); got != "Number of tasks (default: 10)" {
+		t.Errorf("details() = %q", got)
+	}
+	if got := testFlags[1].details(); got != "tmux session (required)" {
+		t.Errorf("details() = %q", got)
+	}
+}
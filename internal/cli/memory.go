@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/memory"
+	"github.com/micheal-at/multiclaude/internal/prompts"
+)
+
+// memoryShow prints an agent's memory, oldest entry first
+func (c *CLI) memoryShow(ctx CommandContext, args []string) error {
+	resp, err := c.sendDaemonRequest("get_memory", map[string]interface{}{"repo": ctx.Repo, "agent": ctx.Agent})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	path, _ := data["path"].(string)
+
+	// Round-trip the entries through JSON to get them back as memory.Entry
+	raw, _ := json.Marshal(data["entries"])
+	var entries []memory.Entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read memory", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("%s has no memory yet.\n", ctx.Agent)
+		format.Dimmed("Add a note with: multiclaude memory add \"<note>\" --agent %s", ctx.Agent)
+		return nil
+	}
+	fmt.Printf("Memory of %s (%d entries):\n\n", ctx.Agent, len(entries))
+	for _, e := range entries {
+		label := e.Source
+		if e.About != "" {
+			label += " " + e.About
+		}
+		fmt.Printf("%s %s\n", format.Dim.Sprint(e.Time.Local().Format("2006-01-02 15:04")), format.Yellow.Sprint(label))
+		fmt.Printf("  %s\n\n", strings.ReplaceAll(e.Text, "\n", "\n  "))
+	}
+	format.Dimmed("Kept in %s", path)
+	return nil
+}
+
+// memoryAdd saves a note to an agent's memory
+func (c *CLI) memoryAdd(ctx CommandContext, args []string) error {
+	_, positional := ParseFlags(args)
+	note := strings.TrimSpace(strings.Join(positional, " "))
+	if note == "" {
+		return errors.MissingArgument("note", "what to remember")
+	}
+	if len(note) > memory.MaxEntryBytes {
+		return errors.InvalidArgument("note", fmt.Sprintf("%d bytes", len(note)), fmt.Sprintf("at most %d bytes; save the details in a file and note where", memory.MaxEntryBytes))
+	}
+
+	if _, err := c.sendDaemonRequest("add_memory", map[string]interface{}{"repo": ctx.Repo, "agent": ctx.Agent, "note": note}); err != nil {
+		return err
+	}
+	fmt.Printf("Remembered for %s.\n", ctx.Agent)
+	return nil
+}
+
+// memoryClear deletes an agent's memory
+func (c *CLI) memoryClear(ctx CommandContext, args []string) error {
+	if _, err := c.sendDaemonRequest("clear_memory", map[string]interface{}{"repo": ctx.Repo, "agent": ctx.Agent}); err != nil {
+		return err
+	}
+	fmt.Printf("Cleared the memory of %s.\n", ctx.Agent)
+	return nil
+}
+
+// memorySection returns the memory section of an agent's prompt
+func (c *CLI) memorySection(repoName, agentName string) string {
+	path := c.paths.MemoryFile(repoName, agentName)
+	entries, err := memory.Load(path)
+	if err != nil {
+		fmt.Printf("Warning: failed to read the memory of %s: %v\n", agentName, err)
+	}
+	return prompts.MemorySection(entries, path)
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/micheal-at/multiclaude/internal/daemon"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/layout"
+	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
+)
+
+// migratePaths moves the legacy ~/.multiclaude directory into the XDG layout
+func (c *CLI) migratePaths(flags *FlagSet) error {
+	dryRun := flags.Bool("dry-run")
+
+	switch c.paths.Layout {
+	case config.LayoutCustom:
+		return errors.New(errors.CategoryConfig, fmt.Sprintf("%s is set, so multiclaude keeps everything in %s", config.HomeEnv, c.paths.Root)).
+			WithSuggestion("unset " + config.HomeEnv + " to use the legacy or XDG layout")
+	case config.LayoutLegacy:
+	default:
+		fmt.Printf("Nothing to migrate: multiclaude already uses the XDG layout (state in %s)\n", c.paths.Root)
+		return nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	to := config.XDGPaths(home)
+
+	moves, err := layout.Plan(c.paths, to)
+	if err != nil {
+		return err
+	}
+	if err := layout.Check(moves); err != nil {
+		return errors.New(errors.CategoryConfig, "can't migrate: "+err.Error()).
+			WithSuggestion("move it out of the way and run 'multiclaude migrate-paths' again")
+	}
+
+	if dryRun {
+		fmt.Printf("Moves from %s to the XDG layout:\n", c.paths.Root)
+		for _, m := range moves {
+			fmt.Printf("  %s %s -> %s\n", format.Dim.Sprint("→"), m.From, m.To)
+		}
+		return nil
+	}
+
+	if running, pid, _ := daemon.NewPIDFile(c.paths.DaemonPID).IsRunning(); running {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("the daemon is running (PID %d)", pid)).
+			WithSuggestion("multiclaude stop-all")
+	}
+	if st, err := c.loadState(); err == nil {
+		tmuxClient := tmux.NewClient()
+		for _, name := range st.ListRepos() {
+			repo, _ := st.GetRepo(name)
+			if exists, err := tmuxClient.HasSession(context.Background(), repo.TmuxSession); err == nil && exists {
+				return errors.New(errors.CategoryRuntime, fmt.Sprintf("agents of %s are still running in tmux session %s", name, repo.TmuxSession)).
+					WithSuggestion("multiclaude stop-all")
+			}
+		}
+	}
+
+	result, err := layout.Migrate(c.paths, to)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "migration failed", err)
+	}
+	fmt.Printf("Moved %s to the XDG layout:\n", c.paths.Root)
+	for _, m := range result.Moves {
+		fmt.Printf("  %s %s -> %s\n", format.Green.Sprint("✓"), m.From, m.To)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Printf("  %s %s\n", format.Yellow.Sprint("!"), warning)
+	}
+
+	fmt.Println()
+	fmt.Printf("State and logs: %s\n", to.Root)
+	fmt.Printf("Repositories:   %s\n", to.ReposDir)
+	fmt.Printf("Worktrees:      %s\n", to.WorktreesDir)
+	fmt.Printf("Daemon socket:  %s\n", to.DaemonSock)
+	format.Dimmed("Claude sessions are kept per directory, so agents restored in a moved worktree start a fresh conversation.")
+
+	if svc, err := c.daemonService(""); err == nil && svc.Installed() {
+		format.Dimmed("The daemon service still logs to the old location; run 'multiclaude daemon install-service' to update it.")
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/pkg/retry"
+)
+
+// defaultOrgInitConcurrency is how many repositories `init --org` sets up at once
+const defaultOrgInitConcurrency = 4
+
+// orgRepoLimit caps how many repositories one gh query lists
+const orgRepoLimit = 1000
+
+// orgRepo is a repository `gh repo list` returned for an organization
+type orgRepo struct {
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	IsFork          bool   `json:"isFork"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics []struct {
+		Name string `json:"name"`
+	} `json:"repositoryTopics"`
+}
+
+// orgRepoFilter selects repositories by the terms of an --org query: every
+// term has to match
+type orgRepoFilter struct {
+	terms []func(orgRepo) bool
+}
+
+// parseOrgRepoFilter parses an --org query such as "topic:backend
+// language:go api". Terms are topic:, language:, fork:true|false, or a word
+// the repository name has to contain.
+func parseOrgRepoFilter(query string) (orgRepoFilter, error) {
+	var filter orgRepoFilter
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		key, value, qualified := strings.Cut(term, ":")
+		if !qualified {
+			word := term
+			filter.terms = append(filter.terms, func(r orgRepo) bool {
+				return strings.Contains(strings.ToLower(r.Name), word)
+			})
+			continue
+		}
+		switch key {
+		case "topic":
+			filter.terms = append(filter.terms, func(r orgRepo) bool {
+				for _, topic := range r.RepositoryTopics {
+					if strings.ToLower(topic.Name) == value {
+						return true
+					}
+				}
+				return false
+			})
+		case "language":
+			filter.terms = append(filter.terms, func(r orgRepo) bool {
+				return r.PrimaryLanguage != nil && strings.ToLower(r.PrimaryLanguage.Name) == value
+			})
+		case "fork":
+			fork, err := strconv.ParseBool(value)
+			if err != nil {
+				return orgRepoFilter{}, fmt.Errorf("invalid filter %q: fork: takes true or false", term)
+			}
+			filter.terms = append(filter.terms, func(r orgRepo) bool { return r.IsFork == fork })
+		default:
+			return orgRepoFilter{}, fmt.Errorf("invalid filter %q: use topic:, language:, fork: or part of a name", term)
+		}
+	}
+	return filter, nil
+}
+
+// match reports whether r matches every term of the filter
+func (f orgRepoFilter) match(r orgRepo) bool {
+	for _, term := range f.terms {
+		if !term(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// listOrgRepos lists an organization's repositories that aren't archived
+func listOrgRepos(org string) ([]orgRepo, error) {
+	var stderr bytes.Buffer
+	output, err := retry.GitHub.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		stderr.Reset()
+		cmd := exec.CommandContext(ctx, "gh", "repo", "list", org, "--no-archived", "--limit", strconv.Itoa(orgRepoLimit),
+			"--json", "name,url,isFork,primaryLanguage,repositoryTopics")
+		cmd.Stderr = &stderr
+		return cmd
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	var repos []orgRepo
+	if err := json.Unmarshal(output, &repos); err != nil {
+		return nil, fmt.Errorf("unexpected gh output: %w", err)
+	}
+	return repos, nil
+}
+
+// orgInitResult is how setting up one repository of a bulk init went
+type orgInitResult struct {
+	Repo   string
+	Status string // "initialized", "skipped" or "failed"
+	Detail string
+}
+
+// initOrg initializes every repository of an organization that matches
+// --filter, a few at a time, and sums up how each went. Repositories that
+// are already tracked are skipped.
+func (c *CLI) initOrg(org string, flags map[string]string) error {
+	filter, err := parseOrgRepoFilter(flags["filter"])
+	if err != nil {
+		return errors.InvalidUsage(err.Error())
+	}
+	concurrency := defaultOrgInitConcurrency
+	if value, ok := flags["concurrency"]; ok {
+		if concurrency, err = strconv.Atoi(value); err != nil || concurrency < 1 {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --concurrency value: %s (must be a positive number)", value))
+		}
+	}
+
+	all, err := listOrgRepos(org)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to list the repositories of %s", org), err).
+			WithSuggestion("check that gh is installed and logged in: gh auth status")
+	}
+	tracked := make(map[string]bool)
+	if st, err := c.loadState(); err == nil {
+		for _, name := range st.ListRepos() {
+			tracked[name] = true
+		}
+	}
+
+	var todo []orgRepo
+	var results []orgInitResult
+	for _, repo := range all {
+		if !filter.match(repo) {
+			continue
+		}
+		if tracked[repo.Name] {
+			results = append(results, orgInitResult{Repo: repo.Name, Status: "skipped", Detail: "already tracked"})
+			continue
+		}
+		todo = append(todo, repo)
+	}
+	if len(todo) == 0 && len(results) == 0 {
+		fmt.Printf("No repositories in %s match %q\n", org, flags["filter"])
+		return nil
+	}
+
+	if flags["dry-run"] == "true" {
+		fmt.Printf("Dry run: init --org %s would initialize %d repositories:\n", org, len(todo))
+		for _, repo := range todo {
+			fmt.Printf("  %s (%s)\n", repo.Name, repo.URL)
+		}
+		for _, result := range results {
+			fmt.Printf("  %s: %s, %s\n", result.Repo, result.Status, result.Detail)
+		}
+		fmt.Println("\nNothing was changed. Run again without --dry-run to initialize.")
+		return nil
+	}
+
+	if _, err := c.daemonClient().Send(socket.Request{Command: "ping"}); err != nil {
+		return errors.DaemonNotRunning()
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	initArgs := orgInitArgs(flags)
+	fmt.Printf("Initializing %d repositories from %s, %d at a time\n", len(todo), org, concurrency)
+	results = append(results, runOrgInit(todo, concurrency, func(repo orgRepo) error {
+		args := append([]string{"repo", "init", repo.URL, repo.Name}, initArgs...)
+		out, err := exec.Command(executable, args...).CombinedOutput()
+		if err != nil {
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			return fmt.Errorf("%s", lines[len(lines)-1])
+		}
+		return nil
+	})...)
+
+	return printOrgInitSummary(results)
+}
+
+// orgInitArgs returns the flags each repository's init gets from the bulk init
+func orgInitArgs(flags map[string]string) []string {
+	var args []string
+	for _, flag := range []string{"no-merge-queue", "no-presets", "skip-agents", "no-agents"} {
+		if flags[flag] == "true" {
+			args = append(args, "--"+flag)
+		}
+	}
+	for _, flag := range []string{"mq-track", "depth"} {
+		if value, ok := flags[flag]; ok {
+			args = append(args, "--"+flag, value)
+		}
+	}
+	return args
+}
+
+// runOrgInit sets up repos with at most concurrency at once, printing each
+// outcome as it comes in. Results are in the order of repos.
+func runOrgInit(repos []orgRepo, concurrency int, initRepo func(orgRepo) error) []orgInitResult {
+	results := make([]orgInitResult, len(repos))
+	sem := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(i int, repo orgRepo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			err := initRepo(repo)
+			<-sem
+
+			result := orgInitResult{Repo: repo.Name, Status: "initialized"}
+			if err != nil {
+				result.Status, result.Detail = "failed", err.Error()
+			}
+			results[i] = result
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if err != nil {
+				fmt.Printf("  ✗ %s (%d/%d): %v\n", repo.Name, done, len(repos), err)
+			} else {
+				fmt.Printf("  ✓ %s (%d/%d)\n", repo.Name, done, len(repos))
+			}
+		}(i, repo)
+	}
+	wg.Wait()
+	return results
+}
+
+// printOrgInitSummary prints a table of bulk init results. It fails if any
+// repository did, so scripts notice.
+func printOrgInitSummary(results []orgInitResult) error {
+	counts := make(map[string]int)
+	table := format.NewColoredTable("REPO", "RESULT", "DETAIL").Flexible("DETAIL", 20)
+	for _, result := range results {
+		counts[result.Status]++
+		status := format.ColorCell(result.Status, format.Green)
+		switch result.Status {
+		case "skipped":
+			status = format.ColorCell(result.Status, format.Dim)
+		case "failed":
+			status = format.ColorCell(result.Status, format.Red)
+		}
+		table.AddRow(format.Cell(result.Repo), status, format.Cell(result.Detail))
+	}
+	fmt.Println()
+	table.Print()
+	fmt.Printf("\n%d initialized, %d skipped, %d failed\n", counts["initialized"], counts["skipped"], counts["failed"])
+
+	if counts["failed"] > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d of %d repositories failed to initialize", counts["failed"], len(results))).
+			WithSuggestion("run `multiclaude init --org` again to retry them; interrupted clones continue where they stopped")
+	}
+	return nil
+}
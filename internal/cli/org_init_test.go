@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseOrgRepoFilter(t *testing.T) {
+	var repos []orgRepo
+	if err := json.Unmarshal([]byte(`[
+		{"name": "billing-api", "url": "https://github.com/acme/billing-api", "primaryLanguage": {"name": "Go"}, "repositoryTopics": [{"name": "backend"}]},
+		{"name": "web", "url": "https://github.com/acme/web", "primaryLanguage": {"name": "TypeScript"}, "repositoryTopics": [{"name": "frontend"}]},
+		{"name": "auth-api", "url": "https://github.com/acme/auth-api", "isFork": true, "primaryLanguage": null, "repositoryTopics": [{"name": "Backend"}]}
+	]`), &repos); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "billing-api,web,auth-api"},
+		{"topic:backend", "billing-api,auth-api"},
+		{"topic:backend language:go", "billing-api"},
+		{"TOPIC:BACKEND fork:true", "auth-api"},
+		{"api", "billing-api,auth-api"},
+		{"topic:missing", ""},
+	}
+	for _, tt := range tests {
+		filter, err := parseOrgRepoFilter(tt.query)
+		if err != nil {
+			t.Errorf("parseOrgRepoFilter(%q) error: %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, repo := range repos {
+			if filter.match(repo) {
+				got = append(got, repo.Name)
+			}
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("filter %q matched %v, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"stars:>10", "fork:maybe"} {
+		if _, err := parseOrgRepoFilter(query); err == nil {
+			t.Errorf("parseOrgRepoFilter(%q) succeeded", query)
+		}
+	}
+}
+
+func TestRunOrgInit(t *testing.T) {
+	var repos []orgRepo
+	for i := 0; i < 6; i++ {
+		repos = append(repos, orgRepo{Name: fmt.Sprintf("repo-%d", i)})
+	}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	results := runOrgInit(repos, 2, func(repo orgRepo) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if repo.Name == "repo-3" {
+			return fmt.Errorf("clone failed")
+		}
+		return nil
+	})
+
+	if peak > 2 {
+		t.Errorf("%d repositories were set up at once, want at most 2", peak)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want 6", len(results))
+	}
+	for i, result := range results {
+		want := "initialized"
+		if i == 3 {
+			want = "failed"
+		}
+		if result.Repo != repos[i].Name || result.Status != want {
+			t.Errorf("results[%d] = %+v, want %s %s", i, result, repos[i].Name, want)
+		}
+	}
+	if results[3].Detail != "clone failed" {
+		t.Errorf("failed result detail = %q", results[3].Detail)
+	}
+
+	if err := printOrgInitSummary(results); err == nil || !strings.Contains(err.Error(), "1 of 6") {
+		t.Errorf("printOrgInitSummary() error = %v, want the failure counted", err)
+	}
+}
+
+func TestOrgInitArgs(t *testing.T) {
+	flags, _ := ParseFlags([]string{"--org", "acme", "--filter", "topic:backend", "--no-agents", "--depth", "50", "--concurrency", "8"})
+	if got := strings.Join(orgInitArgs(flags), " "); got != "--no-agents --depth 50" {
+		t.Errorf("orgInitArgs() = %q, want only the per-repository flags", got)
+	}
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/prompttest"
+)
+
+// testAgentPrompts renders the prompt of every agent type and definition of a
+// repository (or the ones multiclaude ships, with --builtin) and checks each
+// for its required sections. With --golden it also compares them with golden
+// files in that directory, which --update rewrites.
+func (c *CLI) testAgentPrompts(ctx CommandContext, flags *FlagSet) error {
+	goldenDir := flags.String("golden")
+	update := flags.Bool("update")
+	if update && goldenDir == "" {
+		return errors.InvalidUsage("--update needs --golden <dir> to know where to write the golden files")
+	}
+
+	var defs []agents.Definition
+	repoPath, target, targetFlag := "", "built-in prompts", "--builtin"
+	if flags.Bool("builtin") {
+		var err error
+		if defs, err = prompttest.BuiltinDefinitions(); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read built-in agent definitions", err)
+		}
+	} else {
+		if ctx.Repo == "" {
+			return errors.NotInRepo().WithSuggestion("test the prompts multiclaude ships with: multiclaude agents test --builtin")
+		}
+		repoPath, target, targetFlag = c.paths.RepoDir(ctx.Repo), ctx.Repo, "--repo "+ctx.Repo
+		var err error
+		defs, err = agents.NewReader(c.paths.RepoAgentsDir(ctx.Repo), repoPath).ReadAllDefinitions()
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
+		}
+	}
+
+	results, err := prompttest.Run(prompttest.Cases(defs), repoPath, goldenDir, update)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to render prompts", err)
+	}
+
+	fmt.Printf("Prompt tests for %s:\n", target)
+	failed := 0
+	for _, r := range results {
+		if r.OK() {
+			line := fmt.Sprintf("  %s %s", format.Green.Sprint("✓"), r.Case.Name)
+			if r.Golden == prompttest.GoldenUpdated {
+				line += format.Dim.Sprint(" (golden file updated)")
+			}
+			fmt.Println(line)
+			continue
+		}
+		failed++
+		var problems []string
+		if len(r.Missing) > 0 {
+			problems = append(problems, "missing "+strings.Join(r.Missing, ", "))
+		}
+		switch r.Golden {
+		case prompttest.GoldenMissing:
+			problems = append(problems, "no golden file")
+		case prompttest.GoldenDiffers:
+			problems = append(problems, "differs from golden file at "+r.Diff)
+		}
+		fmt.Printf("  %s %s: %s\n", format.Red.Sprint("✗"), r.Case.Name, strings.Join(problems, "; "))
+	}
+
+	if failed > 0 {
+		err := errors.New(errors.CategoryConfig, fmt.Sprintf("%d of %d prompt(s) failed", failed, len(results)))
+		if goldenDir != "" {
+			err = err.WithSuggestion(fmt.Sprintf("if the prompt changes are intended, accept them with: multiclaude agents test %s --golden %s --update", targetFlag, goldenDir))
+		}
+		return err
+	}
+	fmt.Printf("%d prompt(s) passed\n", len(results))
+	return nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/worktree"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
+)
+
+// reviewerDefinition is the agent definition review agents count against:
+// multiclaude review doesn't take its prompt from it, but it describes them
+const reviewerDefinition = "reviewer"
+
+// checkAgentQuota asks the daemon whether another agent of a type may start
+// from a definition, before anything is created for it. The error names the
+// definition at its max-instances limit, or the repository at its worker
+// limit, and the agents running.
+func (c *CLI) checkAgentQuota(repoName string, agentType state.AgentType, definition string) error {
+	resp, err := c.daemonClient().Send(socket.Request{
+		Command: "check_agent_quota",
+		Args:    map[string]interface{}{"repo": repoName, "type": string(agentType), "definition": definition},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("checking agent limits", err)
+	}
+	if !resp.Success {
+		return daemonError("cannot start another agent", resp)
+	}
+	return nil
+}
+
+// abandonAgent undoes starting an agent the daemon refused to register, as it
+// does when another took the definition's last place in the meantime: it
+// closes the agent's window and removes its worktree
+func (c *CLI) abandonAgent(repoName, tmuxSession, window, wtPath string) {
+	if err := tmux.NewClient().KillWindow(context.Background(), tmuxSession, window); err != nil {
+		fmt.Printf("Warning: failed to close window %s: %v\n", window, err)
+	}
+	gitDir := c.paths.RepoDir(repoName)
+	if strings.HasPrefix(wtPath, c.paths.MirrorWorktreeDir(repoName)+string(filepath.Separator)) {
+		gitDir = c.paths.MirrorDir(repoName)
+	}
+	if err := worktree.NewManager(gitDir).Remove(wtPath, true); err != nil {
+		fmt.Printf("Warning: failed to remove worktree %s: %v\n", wtPath, err)
+	}
+}
+
+// promptFileDefinition returns the name of the repository's agent definition
+// whose file path is, or "" when it's some other file
+func (c *CLI) promptFileDefinition(repoName, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	name, isMarkdown := strings.CutSuffix(filepath.Base(abs), ".md")
+	if !isMarkdown {
+		return ""
+	}
+	dir := filepath.Dir(abs)
+	if dir != filepath.Clean(c.paths.RepoAgentsDir(repoName)) && dir != filepath.Join(c.paths.RepoDir(repoName), ".multiclaude", "agents") {
+		return ""
+	}
+	return name
+}
+
+// definitionUsage returns "running/limit" for a definition with a
+// max-instances limit, counting the repository's agents started from it, or
+// "-" without one
+func definitionUsage(def agents.Definition, running map[string]int) string {
+	limit := def.MaxInstances()
+	if limit == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", running[def.Name], limit)
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/routing"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// listRoutingRules shows a repository's message routing rules
+func (c *CLI) listRoutingRules(ctx CommandContext, args []string) error {
+	rules, err := c.routingRules(ctx.Repo)
+	if err != nil {
+		return err
+	}
+	printRoutingRules(ctx.Repo, rules)
+	return nil
+}
+
+// addRoutingRule appends a rule to a repository's routing rules
+func (c *CLI) addRoutingRule(ctx CommandContext, args []string) error {
+	flags, _ := ParseFlags(args)
+	rule := state.RoutingRule{
+		Contains: flags["contains"],
+		From:     flags["from"],
+		To:       flags["to"],
+		Priority: flags["priority"],
+	}
+	for _, name := range strings.Split(flags["cc"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			rule.CC = append(rule.CC, name)
+		}
+	}
+	if err := routing.Validate([]state.RoutingRule{rule}); err != nil {
+		return errors.InvalidUsage(strings.TrimPrefix(err.Error(), "routing rule 1: ")).
+			WithSuggestion("multiclaude routing add --contains URGENT --cc workspace")
+	}
+
+	rules, err := c.routingRules(ctx.Repo)
+	if err != nil {
+		return err
+	}
+	rules = append(rules, rule)
+	if err := c.updateRoutingRules(ctx.Repo, rules); err != nil {
+		return err
+	}
+	fmt.Printf("Added routing rule %d: %s\n", len(rules), routing.Describe(rule))
+	return nil
+}
+
+// removeRoutingRule removes one routing rule by its number, or all of them
+func (c *CLI) removeRoutingRule(ctx CommandContext, args []string) error {
+	_, positional := ParseFlags(args)
+	if len(positional) < 1 {
+		return errors.MissingArgument("number", "rule number from `multiclaude routing`, or all")
+	}
+	rules, err := c.routingRules(ctx.Repo)
+	if err != nil {
+		return err
+	}
+	if positional[0] == "all" {
+		if err := c.updateRoutingRules(ctx.Repo, []state.RoutingRule{}); err != nil {
+			return err
+		}
+		fmt.Printf("Removed all %d routing rule(s) from %s\n", len(rules), ctx.Repo)
+		return nil
+	}
+
+	n, err := strconv.Atoi(positional[0])
+	if err != nil || n < 1 || n > len(rules) {
+		return errors.InvalidArgument("number", positional[0], fmt.Sprintf("a rule number from 1 to %d, or all", len(rules)))
+	}
+	removed := rules[n-1]
+	rules = append(rules[:n-1], rules[n:]...)
+	if err := c.updateRoutingRules(ctx.Repo, rules); err != nil {
+		return err
+	}
+	fmt.Printf("Removed routing rule %d: %s\n", n, routing.Describe(removed))
+	return nil
+}
+
+// routingRules fetches a repository's routing rules from the daemon
+func (c *CLI) routingRules(repoName string) ([]state.RoutingRule, error) {
+	resp, err := c.sendDaemonRequest("get_repo_config", map[string]interface{}{"name": repoName})
+	if err != nil {
+		return nil, err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	return routingRulesFromConfig(data), nil
+}
+
+// updateRoutingRules replaces a repository's routing rules
+func (c *CLI) updateRoutingRules(repoName string, rules []state.RoutingRule) error {
+	_, err := c.sendDaemonRequest("update_repo_config", map[string]interface{}{
+		"name":          repoName,
+		"routing_rules": rules,
+	})
+	return err
+}
+
+// routingRulesFromConfig reads routing rules from get_repo_config's response
+func routingRulesFromConfig(data map[string]interface{}) []state.RoutingRule {
+	var rules []state.RoutingRule
+	raw, err := json.Marshal(data["routing_rules"])
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil
+	}
+	return rules
+}
+
+// printRoutingRules lists routing rules by the numbers `routing remove` takes
+func printRoutingRules(repoName string, rules []state.RoutingRule) {
+	if len(rules) == 0 {
+		fmt.Printf("No routing rules for %s\n", repoName)
+		format.Dimmed("Add one with: multiclaude routing add --contains URGENT --cc workspace")
+		return
+	}
+	fmt.Printf("Routing rules for %s (%d):\n", repoName, len(rules))
+	for i, rule := range rules {
+		fmt.Printf("  %d. %s\n", i+1, routing.Describe(rule))
+	}
+	format.Dimmed("Rules apply to each message once, in order, when the daemon routes it.")
+}
@@ -89,16 +89,20 @@ func SelectFromList(prompt string, items []SelectableItem) (string, error) {
 
 // formatAgentStatusCell returns a colored cell for an agent status string.
 // This is a common helper to reduce duplication across list commands.
+// Agents that stopped or failed show in red and paused ones in yellow, so
+// they stand out among the running ones.
 func formatAgentStatusCell(status string) format.ColoredCell {
 	switch status {
 	case "running":
-		return format.ColorCell(format.ColoredStatus(format.StatusRunning), nil)
+		return format.Cell(format.StatusLabel(format.StatusRunning, status))
 	case "completed":
-		return format.ColorCell(format.ColoredStatus(format.StatusCompleted), nil)
-	case "stopped":
-		return format.ColorCell(format.ColoredStatus(format.StatusError), nil)
+		return format.Cell(format.StatusLabel(format.StatusCompleted, status))
+	case "stopped", "failed":
+		return format.Cell(format.StatusLabel(format.StatusError, status))
+	case "paused":
+		return format.Cell(format.StatusLabel(format.StatusWarning, status))
 	default:
-		return format.ColorCell(format.ColoredStatus(format.StatusIdle), nil)
+		return format.Cell(format.ColoredStatus(format.StatusIdle))
 	}
 }
 
@@ -3,6 +3,8 @@ package cli
 import (
 	"strings"
 	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/format"
 )
 
 func TestSelectableItem(t *testing.T) {
@@ -374,6 +376,9 @@ func TestFormatAgentStatusCell(t *testing.T) {
 			if cell.Text == "" {
 				t.Errorf("formatAgentStatusCell(%q) returned empty text", tt.status)
 			}
+			if text := format.StripANSI(cell.Text); !strings.Contains(text, tt.wantText) {
+				t.Errorf("formatAgentStatusCell(%q) = %q, want it to show %q", tt.status, text, tt.wantText)
+			}
 		})
 	}
 }
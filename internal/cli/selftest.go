@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/daemon"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
+)
+
+// selftestWorker is the worker the self-test creates
+const selftestWorker = "selftest-worker"
+
+// selftestStep is one stage of the self-test. Each stage runs only if the
+// ones before it passed.
+type selftestStep struct {
+	name string
+	run  func() error
+}
+
+// selftest runs the init → work → message → complete → cleanup flow against
+// a disposable root, fake repository and real tmux session, with agents
+// simulated as in MULTICLAUDE_TEST_MODE, to check that the installation works
+// before it is trusted with real repositories
+func (c *CLI) selftest(flags *FlagSet) error {
+	keep := flags.Bool("keep")
+
+	root, err := os.MkdirTemp("", "mc-selftest-")
+	if err != nil {
+		return fmt.Errorf("failed to create self-test directory: %w", err)
+	}
+	// Resolve symlinks (macOS /tmp -> /private/tmp) so worktree paths match
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	paths := config.RootPaths(root)
+	repoName := "selftest-" + filepath.Base(root)[len("mc-selftest-"):]
+	tmuxSession := "mc-" + repoName
+	tmuxClient := tmux.NewClient()
+
+	// Agents' windows run a shell instead of Claude
+	restoreEnv := setEnv("MULTICLAUDE_TEST_MODE", "1")
+	defer restoreEnv()
+
+	var d *daemon.Daemon
+	var mc *CLI
+	steps := []selftestStep{
+		{"prerequisites", func() error {
+			if _, err := exec.LookPath("git"); err != nil {
+				return fmt.Errorf("git not found in PATH")
+			}
+			if !tmuxClient.IsTmuxAvailable() {
+				return fmt.Errorf("tmux not found in PATH")
+			}
+			return paths.EnsureDirectories()
+		}},
+		{"fake repository", func() error {
+			return createSelftestRemote(filepath.Join(root, "source"), filepath.Join(root, "remote.git"))
+		}},
+		{"daemon start", func() error {
+			var err error
+			if d, err = daemon.New(paths); err != nil {
+				return err
+			}
+			if err := d.Start(); err != nil {
+				d = nil
+				return err
+			}
+			mc = NewWithPaths(paths)
+			_, err = mc.sendDaemonRequest("ping", nil)
+			return err
+		}},
+		{"init", func() error {
+			if err := mc.Execute([]string{"init", filepath.Join(root, "remote.git"), repoName}); err != nil {
+				return err
+			}
+			if exists, err := tmuxClient.HasSession(context.Background(), tmuxSession); err != nil || !exists {
+				return fmt.Errorf("tmux session %s was not created (%v)", tmuxSession, err)
+			}
+			return nil
+		}},
+		{"work", func() error {
+			if err := mc.Execute([]string{"work", "Self-test task", "--name", selftestWorker, "--repo", repoName}); err != nil {
+				return err
+			}
+			agent, exists := d.GetState().GetAgent(repoName, selftestWorker)
+			if !exists {
+				return fmt.Errorf("worker missing from state")
+			}
+			if _, err := os.Stat(agent.WorktreePath); err != nil {
+				return fmt.Errorf("worker worktree missing: %w", err)
+			}
+			return nil
+		}},
+		{"message", func() error {
+			if err := mc.Execute([]string{"message", "send", "supervisor", "Self-test message", "--repo", repoName, "--agent", selftestWorker}); err != nil {
+				return err
+			}
+			inbox, err := messages.NewManager(paths.MessagesDir).List(repoName, "supervisor")
+			if err != nil {
+				return err
+			}
+			for _, msg := range inbox {
+				if msg.From == selftestWorker {
+					return nil
+				}
+			}
+			return fmt.Errorf("message not in the supervisor's inbox")
+		}},
+		{"complete", func() error {
+			if err := mc.Execute([]string{"agent", "complete", "--summary", "Self-test done", "--repo", repoName, "--agent", selftestWorker}); err != nil {
+				return err
+			}
+			if agent, _ := d.GetState().GetAgent(repoName, selftestWorker); !agent.ReadyForCleanup {
+				return fmt.Errorf("worker not marked ready for cleanup")
+			}
+			return nil
+		}},
+		{"cleanup", func() error {
+			worktreePath := paths.AgentWorktree(repoName, selftestWorker)
+			d.TriggerHealthCheck()
+			if _, exists := d.GetState().GetAgent(repoName, selftestWorker); exists {
+				return fmt.Errorf("daemon did not remove the completed worker")
+			}
+			if _, err := os.Stat(worktreePath); !os.IsNotExist(err) {
+				return fmt.Errorf("worker worktree %s was not removed", worktreePath)
+			}
+			return mc.Execute([]string{"repo", "rm", repoName})
+		}},
+	}
+
+	failed := ""
+	results := make([]string, 0, len(steps))
+	for _, step := range steps {
+		fmt.Println(format.Dim.Sprintf("==> %s", step.name))
+		start := time.Now()
+		err := step.run()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed = step.name
+			results = append(results, fmt.Sprintf("%s %s (%s): %v", format.Red.Sprint("✗"), step.name, elapsed, err))
+			break
+		}
+		results = append(results, fmt.Sprintf("%s %s (%s)", format.Green.Sprint("✓"), step.name, elapsed))
+	}
+
+	// Leave nothing running, whatever happened
+	if d != nil {
+		d.Stop()
+	}
+	tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	fmt.Println("\nSelf-test results:")
+	for _, result := range results {
+		fmt.Println("  " + result)
+	}
+	for _, step := range steps[len(results):] {
+		fmt.Printf("  %s %s (not run)\n", format.Dim.Sprint("-"), step.name)
+	}
+
+	if failed == "" && !keep {
+		os.RemoveAll(root)
+	} else {
+		fmt.Printf("\nSelf-test files kept in %s (daemon log: %s)\n", root, paths.DaemonLog)
+	}
+
+	if failed != "" {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("self-test failed at %s", failed)).
+			WithSuggestion("check the daemon log: " + paths.DaemonLog)
+	}
+	fmt.Println(format.Green.Sprint("\nSelf-test passed"))
+	return nil
+}
+
+// createSelftestRemote creates a bare repository with one commit on main, for
+// the self-test to init from
+func createSelftestRemote(source, remote string) error {
+	cmds := [][]string{
+		{"git", "-c", "init.defaultBranch=main", "init", "-q", source},
+		{"git", "-C", source, "-c", "user.name=multiclaude", "-c", "user.email=selftest@multiclaude.invalid", "commit", "-q", "--allow-empty", "-m", "Initial commit"},
+		{"git", "clone", "-q", "--bare", source, remote},
+	}
+	for _, args := range cmds {
+		if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%v: %v: %s", args, err, output)
+		}
+	}
+	return nil
+}
+
+// setEnv sets an environment variable and returns a function that restores
+// its previous value
+func setEnv(key, value string) func() {
+	previous, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if had {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
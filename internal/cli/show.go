@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/xref"
+)
+
+// prView is the show_pr response
+type prView struct {
+	Number     int               `json:"number"`
+	URL        string            `json:"url"`
+	State      string            `json:"state"`
+	StateError string            `json:"state_error"`
+	Branch     string            `json:"branch"`
+	Agent      string            `json:"agent"`
+	Task       string            `json:"task"`
+	HistoryID  string            `json:"history_id"`
+	Messages   []xref.MessageRef `json:"messages"`
+	Worker     *struct {
+		ReadyForCleanup bool              `json:"ready_for_cleanup"`
+		CreatedAt       time.Time         `json:"created_at"`
+		Criteria        []state.Criterion `json:"criteria"`
+	} `json:"worker"`
+	History *struct {
+		Status        string            `json:"status"`
+		Summary       string            `json:"summary"`
+		FailureReason string            `json:"failure_reason"`
+		CompletedAt   time.Time         `json:"completed_at"`
+		Criteria      []state.Criterion `json:"criteria"`
+	} `json:"history"`
+	Train *struct {
+		Status   string    `json:"status"`
+		Position int       `json:"position"`
+		Ahead    []int     `json:"ahead"`
+		Reason   string    `json:"reason"`
+		TestedAt time.Time `json:"tested_at"`
+	} `json:"train"`
+}
+
+// showPR prints a PR's cross-references: the worker whose branch it's from,
+// its task and how it went, the messages about it and where the merge queue
+// has it
+func (c *CLI) showPR(ctx CommandContext, flags *FlagSet) error {
+	if len(flags.Args()) != 1 {
+		return errors.InvalidUsage("usage: multiclaude show pr <number> [--repo <repo>] [--json]")
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(flags.Args()[0], "#"))
+	if err != nil || number <= 0 {
+		return errors.InvalidArgument("PR number", flags.Args()[0], "a positive number, such as 42")
+	}
+	resp, err := c.sendDaemonRequest("show_pr", map[string]interface{}{"repo": ctx.Repo, "number": number})
+	if err != nil {
+		return err
+	}
+	if flags.Bool("json") {
+		out, _ := json.MarshalIndent(resp.Data, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+	raw, err := json.Marshal(resp.Data)
+	if err != nil {
+		return err
+	}
+	var pr prView
+	if err := json.Unmarshal(raw, &pr); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "unexpected PR details from daemon", err)
+	}
+	printPRView(pr)
+	return nil
+}
+
+// printPRView prints a PR's cross-references
+func printPRView(pr prView) {
+	format.Header("PR #%d (%s)", pr.Number, pr.State)
+	if pr.URL != "" {
+		fmt.Printf("  %s\n", pr.URL)
+	}
+	if pr.StateError != "" {
+		fmt.Println(format.Dim.Sprintf("  Couldn't get the PR's state: %s", pr.StateError))
+	}
+	if pr.Branch != "" {
+		fmt.Printf("  Branch: %s\n", pr.Branch)
+	}
+
+	fmt.Println()
+	switch {
+	case pr.Agent == "":
+		fmt.Println("Not from a multiclaude worker")
+	case pr.Worker != nil:
+		status := "working"
+		if pr.Worker.ReadyForCleanup {
+			status = "completed, awaiting cleanup"
+		}
+		fmt.Printf("Worker: %s (%s, started %s)\n", pr.Agent, status, format.TimeAgo(pr.Worker.CreatedAt))
+		printPRTask(pr.Task, pr.Worker.Criteria)
+	case pr.History != nil:
+		fmt.Printf("Worker: %s (finished %s: %s)\n", pr.Agent, format.TimeAgo(pr.History.CompletedAt), pr.History.Status)
+		printPRTask(pr.Task, pr.History.Criteria)
+		if pr.History.Summary != "" {
+			fmt.Printf("  Summary: %s\n", pr.History.Summary)
+		}
+		if pr.History.FailureReason != "" {
+			fmt.Printf("  Failure: %s\n", format.Red.Sprint(pr.History.FailureReason))
+		}
+		fmt.Println(format.Dim.Sprintf("  History: %s", pr.HistoryID))
+	default:
+		fmt.Printf("Worker: %s (gone)\n", pr.Agent)
+		printPRTask(pr.Task, nil)
+	}
+
+	fmt.Println()
+	fmt.Println("Merge queue:")
+	switch {
+	case pr.State == "draft":
+		fmt.Println("  Draft: the merge queue waits for it to be marked ready")
+	case pr.State == "merged" || pr.State == "closed":
+		fmt.Printf("  Done: the PR is %s\n", pr.State)
+	case pr.Train == nil:
+		fmt.Println("  Not in the merge train")
+	case pr.Train.Status == "passed":
+		onto := "the base branch"
+		if len(pr.Train.Ahead) > 0 {
+			onto = formatPRList(pr.Train.Ahead)
+		}
+		fmt.Printf("  Train: %s, number %d to merge (tested on top of %s %s)\n",
+			format.Green.Sprint("passed"), pr.Train.Position, onto, format.TimeAgo(pr.Train.TestedAt))
+	default:
+		fmt.Printf("  Train: %s %s: %s\n", format.Red.Sprint(pr.Train.Status), format.TimeAgo(pr.Train.TestedAt), pr.Train.Reason)
+	}
+
+	fmt.Println()
+	if len(pr.Messages) == 0 {
+		fmt.Println("No related messages")
+		return
+	}
+	fmt.Printf("Messages (%d):\n", len(pr.Messages))
+	for _, m := range pr.Messages {
+		body := strings.SplitN(strings.TrimSpace(m.Body), "\n", 2)[0]
+		fmt.Printf("  %s  %s → %s: %s\n", format.Dim.Sprint(format.TimeAgo(m.At)), m.From, m.To, truncateString(body, 100))
+	}
+}
+
+// printPRTask prints a PR's task and its acceptance criteria
+func printPRTask(task string, criteria []state.Criterion) {
+	if task != "" {
+		fmt.Printf("  Task: %s\n", task)
+	}
+	for _, cr := range criteria {
+		fmt.Printf("  [%s] %s\n", cr.Status, cr.Text)
+	}
+}
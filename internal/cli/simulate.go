@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/simagent"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
+)
+
+// simulateAgent plays an agent in its window in place of Claude, under
+// MULTICLAUDE_TEST_MODE=sim. It runs until the window is killed.
+func (c *CLI) simulateAgent(ctx CommandContext, args []string) error {
+	sim := &simagent.Simulator{
+		Repo:  ctx.Repo,
+		Agent: ctx.Agent,
+		Lookup: func() (state.Agent, bool) {
+			st, err := c.loadState()
+			if err != nil {
+				return state.Agent{}, false
+			}
+			return st.GetAgent(ctx.Repo, ctx.Agent)
+		},
+		Inbox: messages.NewManager(c.paths.MessagesDir),
+		Complete: func(summary string) error {
+			_, err := c.sendDaemonRequest("complete_agent", map[string]interface{}{
+				"repo":    ctx.Repo,
+				"agent":   ctx.Agent,
+				"summary": summary,
+			})
+			return err
+		},
+		Input:           os.Stdin,
+		Out:             os.Stdout,
+		Poll:            time.Second,
+		RegisterTimeout: 30 * time.Second,
+	}
+
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	return sim.Run(runCtx)
+}
+
+// startSimAgentInTmux starts the simulator as an agent in its tmux window and
+// returns the pane's PID
+func (c *CLI) startSimAgentInTmux(binaryPath, tmuxSession, agentName, repoName string) (int, error) {
+	target := fmt.Sprintf("%s:%s", tmuxSession, agentName)
+	simCmd := simagent.Command(binaryPath, c.paths, repoName, agentName)
+	if _, err := tmuxCommand("send-keys", "-t", target, simCmd, "C-m"); err != nil {
+		return 0, fmt.Errorf("failed to start simulated agent in tmux: %w", err)
+	}
+
+	pid, err := tmux.NewClient().GetPanePID(context.Background(), tmuxSession, agentName)
+	if err != nil {
+		fmt.Printf("Warning: failed to get simulated agent PID: %v\n", err)
+		return 0, nil
+	}
+	return pid, nil
+}
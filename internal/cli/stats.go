@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/telemetry"
+)
+
+// commandName returns the command args run as its path of subcommand names,
+// such as "worker create", or "" if args don't name one or it's internal
+func (c *CLI) commandName(args []string) string {
+	var path []string
+	cmd := c.rootCmd
+	for _, arg := range args {
+		sub, ok := cmd.Subcommands[arg]
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(arg, "_") {
+			return ""
+		}
+		path = append(path, arg)
+		cmd = sub
+	}
+	return strings.Join(path, " ")
+}
+
+// recordUsage notes a command run for `multiclaude stats`, if the user opted
+// in. Telemetry never gets in the way of the command, so failures are ignored.
+func (c *CLI) recordUsage(args []string, duration time.Duration, err error) {
+	name := c.commandName(args)
+	if name == "" {
+		return
+	}
+	telemetry.Record(c.paths.TelemetryFile(), name, duration, err != nil, time.Now())
+}
+
+// showStats summarizes the recorded command runs: how often each command
+// ran, how long it took and how often it failed
+func (c *CLI) showStats(args []string) error {
+	flags, _ := ParseFlags(args)
+	path := c.paths.TelemetryFile()
+	if !telemetry.Enabled(path) {
+		fmt.Println("Command telemetry is off.")
+		fmt.Println(format.Dim.Sprint("Turn it on with: multiclaude stats enable (recorded locally; no arguments or output are kept)"))
+		return nil
+	}
+
+	var since time.Time
+	if s, ok := flags["since"]; ok {
+		period, err := parseDuration(s)
+		if err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --since duration %q: %v (e.g., 24h, 7d)", s, err))
+		}
+		since = time.Now().Add(-period)
+	}
+
+	events, err := telemetry.Load(path)
+	if err != nil {
+		return err
+	}
+	stats := telemetry.Summarize(events, since)
+
+	if flags["json"] == "true" {
+		out, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+
+	if len(stats) == 0 {
+		fmt.Println("No commands recorded yet")
+		return nil
+	}
+	runs := 0
+	for _, s := range stats {
+		runs += s.Runs
+	}
+	first := events[0].At
+	if !since.IsZero() && since.After(first) {
+		first = since
+	}
+	format.Header("Command usage since %s (%d runs)", first.Local().Format("2006-01-02 15:04"), runs)
+
+	table := format.NewColoredTable("COMMAND", "RUNS", "FAILED", "MEAN", "MAX", "LAST RUN")
+	for _, s := range stats {
+		failed := format.Cell("-")
+		if s.Failures > 0 {
+			failed = format.ColorCell(fmt.Sprintf("%d (%.0f%%)", s.Failures, s.FailureRate()*100), format.Red)
+		}
+		table.AddRow(
+			format.Cell(s.Command),
+			format.Cell(strconv.Itoa(s.Runs)),
+			failed,
+			format.Cell(s.Mean.Round(time.Millisecond).String()),
+			format.Cell(s.Max.Round(time.Millisecond).String()),
+			format.Cell(format.TimeAgo(s.LastRun)),
+		)
+	}
+	table.Print()
+	return nil
+}
+
+// enableStats turns on command telemetry
+func (c *CLI) enableStats(args []string) error {
+	if err := telemetry.Enable(c.paths.TelemetryFile()); err != nil {
+		return err
+	}
+	fmt.Printf("%s Command telemetry on; see it with: multiclaude stats\n", format.Green.Sprint("✓"))
+	fmt.Println(format.Dim.Sprintf("Each run's command name, duration and outcome go to %s; nothing leaves this machine.", c.paths.TelemetryFile()))
+	return nil
+}
+
+// disableStats turns off command telemetry, deleting what was recorded
+func (c *CLI) disableStats(args []string) error {
+	if err := telemetry.Disable(c.paths.TelemetryFile()); err != nil {
+		return err
+	}
+	fmt.Printf("%s Command telemetry off; recorded runs deleted\n", format.Green.Sprint("✓"))
+	return nil
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+)
+
+// syncWorktree brings the calling worker's worktree up to date with the
+// default branch now, the way the daemon's refresh would, instead of waiting
+// for its next tick. It fails if the rebase or merge hit conflicts, which
+// leave the worktree as it was; --json lists the files for scripts and agents.
+func (c *CLI) syncWorktree(ctx CommandContext, args []string) error {
+	flags, _ := ParseFlags(args)
+	reqArgs := map[string]interface{}{"repo": ctx.Repo, "agent": ctx.Agent}
+	if strategy := flags["strategy"]; strategy != "" {
+		reqArgs["strategy"] = strategy
+	}
+
+	resp, err := c.sendDaemonRequest("sync_agent", reqArgs)
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	onto, _ := data["onto"].(string)
+	status, _ := data["status"].(string)
+	conflicts := interfaceSliceToStrings(data["conflict_files"])
+
+	if flags["json"] == "true" {
+		out, _ := json.MarshalIndent(data, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		behind, _ := data["commits_behind"].(float64)
+		switch status {
+		case "synced":
+			strategy, _ := data["strategy"].(string)
+			verb := "Rebased onto"
+			if strategy == "merge" {
+				verb = "Merged"
+			}
+			fmt.Printf("%s %s %s (%d new commits)\n", format.Green.Sprint("✓"), verb, onto, int(behind))
+			if stashed, _ := data["stashed"].(bool); stashed {
+				fmt.Println("Uncommitted changes were stashed and restored")
+			}
+		case "up_to_date":
+			fmt.Printf("%s Already up to date with %s\n", format.Green.Sprint("✓"), onto)
+		case "behind":
+			strategy, _ := data["strategy"].(string)
+			fmt.Printf("%d commits behind %s; the refresh strategy is %s, so the branch was left alone\n", int(behind), onto, strategy)
+			fmt.Println(format.Dim.Sprint("Sync anyway: multiclaude sync --strategy rebase|merge"))
+		case "skipped":
+			reason, _ := data["reason"].(string)
+			fmt.Printf("%s Not synced: %s\n", format.Yellow.Sprint("!"), reason)
+		case "conflicts":
+			fmt.Printf("%s Syncing with %s hit conflicts; the worktree was left as it was\n", format.Red.Sprint("✗"), onto)
+			for _, file := range conflicts {
+				fmt.Printf("  %s\n", file)
+			}
+		}
+	}
+
+	if status == "conflicts" {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("sync with %s conflicts in %s", onto, strings.Join(conflicts, ", "))).
+			WithSuggestion(fmt.Sprintf("git rebase %s (or git merge %s) and resolve them yourself", onto, onto))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/worktree"
+)
+
+// Identity is what `multiclaude whoami` tells an agent about itself
+type Identity struct {
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	Repo            string     `json:"repo"`
+	Branch          string     `json:"branch,omitempty"`
+	Task            string     `json:"task,omitempty"`
+	SessionID       string     `json:"session_id,omitempty"`
+	Worktree        string     `json:"worktree,omitempty"`
+	PendingMessages int        `json:"pending_messages"`
+	Teammates       []Teammate `json:"teammates"`
+}
+
+// Teammate is another agent in the same repository
+type Teammate struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Task string `json:"task,omitempty"`
+}
+
+// whoami prints the calling agent's identity as JSON, so agents don't have
+// to work out who they are from their working directory
+func (c *CLI) whoami(ctx CommandContext, args []string) error {
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	id, err := buildIdentity(st, messages.NewManager(c.paths.MessagesDir), ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(id)
+}
+
+// buildIdentity assembles an agent's identity from state and its mailbox
+func buildIdentity(st *state.State, msgMgr *messages.Manager, ctx CommandContext) (Identity, error) {
+	agent, exists := st.GetAgent(ctx.Repo, ctx.Agent)
+	if !exists {
+		return Identity{}, errors.AgentNotFound("agent", ctx.Agent, ctx.Repo)
+	}
+
+	id := Identity{
+		Name:      ctx.Agent,
+		Type:      string(agent.Type),
+		Repo:      ctx.Repo,
+		Branch:    agent.Branch,
+		Task:      agent.Task,
+		SessionID: agent.SessionID,
+		Worktree:  agent.WorktreePath,
+		Teammates: []Teammate{},
+	}
+	if id.Branch == "" && agent.WorktreePath != "" {
+		// Agents other than workers are on whatever their directory has checked out
+		id.Branch, _ = worktree.GetCurrentBranch(agent.WorktreePath)
+	}
+
+	unread, err := msgMgr.ListUnread(ctx.Repo, ctx.Agent)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	id.PendingMessages = len(unread)
+
+	if repo, exists := st.GetRepo(ctx.Repo); exists {
+		for name, teammate := range repo.Agents {
+			if name == ctx.Agent {
+				continue
+			}
+			id.Teammates = append(id.Teammates, Teammate{Name: name, Type: string(teammate.Type), Task: teammate.Task})
+		}
+	}
+	sort.Slice(id.Teammates, func(i, j int) bool { return id.Teammates[i].Name < id.Teammates[j].Name })
+	return id, nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestBuildIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+	st := state.New(filepath.Join(tmpDir, "state.json"))
+	st.AddRepo("myrepo", &state.Repository{
+		TmuxSession: "mc-myrepo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor},
+			"calm-owl":   {Type: state.AgentTypeWorker, Task: "add dark mode", Branch: "work/calm-owl", SessionID: "s-1"},
+			"brave-fox":  {Type: state.AgentTypeWorker, Task: "fix login"},
+		},
+	})
+	msgMgr := messages.NewManager(filepath.Join(tmpDir, "messages"))
+	msgMgr.Send("myrepo", "supervisor", "calm-owl", "how's it going?")
+	msg, _ := msgMgr.Send("myrepo", "supervisor", "calm-owl", "never mind")
+	msgMgr.Ack("myrepo", "calm-owl", msg.ID)
+
+	id, err := buildIdentity(st, msgMgr, CommandContext{Repo: "myrepo", Agent: "calm-owl"})
+	if err != nil {
+		t.Fatalf("buildIdentity() error: %v", err)
+	}
+	if id.Name != "calm-owl" || id.Type != "worker" || id.Repo != "myrepo" || id.Branch != "work/calm-owl" || id.Task != "add dark mode" || id.SessionID != "s-1" {
+		t.Errorf("buildIdentity() = %+v", id)
+	}
+	if id.PendingMessages != 1 {
+		t.Errorf("PendingMessages = %d, want the unacknowledged one", id.PendingMessages)
+	}
+	if len(id.Teammates) != 2 || id.Teammates[0].Name != "brave-fox" || id.Teammates[0].Task != "fix login" || id.Teammates[1].Name != "supervisor" {
+		t.Errorf("Teammates = %+v, want the other agents by name", id.Teammates)
+	}
+
+	if _, err := buildIdentity(st, msgMgr, CommandContext{Repo: "myrepo", Agent: "ghost"}); err == nil {
+		t.Error("buildIdentity() for an unknown agent should fail")
+	}
+}
@@ -0,0 +1,312 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/shell"
+	"github.com/micheal-at/multiclaude/internal/socket"
+)
+
+// maxWizardWorkers caps how many workers the wizard starts on one task
+const maxWizardWorkers = 5
+
+// editCommand is what a task line can be to write the task in $EDITOR instead
+const editCommand = ":e"
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// workerPlan is what the worker wizard collected
+type workerPlan struct {
+	Repo       string
+	Task       string
+	Branch     string // Start point, empty for the repository's default
+	Definition string // Agent definition, empty for the plain worker
+	Count      int
+}
+
+// args returns the `worker create` arguments for one of the plan's workers,
+// in p.Repo
+func (p workerPlan) args() []string {
+	args := []string{p.Task}
+	if p.Branch != "" {
+		args = append(args, "--branch", p.Branch)
+	}
+	if p.Definition != "" {
+		args = append(args, "--definition", p.Definition)
+	}
+	return args
+}
+
+// command returns the command line that does what the plan does, so the
+// wizard teaches the flags it stands in for
+func (p workerPlan) command() string {
+	parts := []string{"multiclaude", "work", shell.Quote(p.Task), "--repo", shell.Quote(p.Repo)}
+	for _, arg := range p.args()[1:] {
+		parts = append(parts, shell.Quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// wizard asks questions on a terminal
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// line reads one line of input. End of input cancels the wizard.
+func (w *wizard) line() (string, error) {
+	input, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || input == "") {
+		return "", errors.New(errors.CategoryUsage, "cancelled")
+	}
+	return strings.TrimRight(input, "\r\n"), nil
+}
+
+// ask asks a question, returning def for an empty answer
+func (w *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	answer, err := w.line()
+	if err != nil {
+		return "", err
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// choose asks for one of items by number, returning its name. An empty
+// answer picks def, an index into items.
+func (w *wizard) choose(question string, items []SelectableItem, def int) (string, error) {
+	fmt.Fprintf(w.out, "\n%s\n", question)
+	for i, item := range items {
+		marker := " "
+		if i == def {
+			marker = "*"
+		}
+		if item.Description != "" {
+			fmt.Fprintf(w.out, " %s[%d] %s - %s\n", marker, i+1, item.Name, item.Description)
+		} else {
+			fmt.Fprintf(w.out, " %s[%d] %s\n", marker, i+1, item.Name)
+		}
+	}
+	for {
+		answer, err := w.ask("Number", strconv.Itoa(def+1))
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(items) {
+			return items[n-1].Name, nil
+		}
+		fmt.Fprintf(w.out, "Enter a number from 1 to %d.\n", len(items))
+	}
+}
+
+// text reads lines up to an empty one. A first line of editCommand opens
+// $VISUAL or $EDITOR instead.
+func (w *wizard) text(question string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor != "" {
+		fmt.Fprintf(w.out, "%s (end with an empty line, or enter %s to use %s):\n", question, editCommand, editor)
+	} else {
+		fmt.Fprintf(w.out, "%s (end with an empty line):\n", question)
+	}
+
+	var lines []string
+	for {
+		line, err := w.line()
+		if err != nil {
+			return "", err
+		}
+		if len(lines) == 0 && strings.TrimSpace(line) == editCommand && editor != "" {
+			return editText(editor, question)
+		}
+		if strings.TrimSpace(line) == "" {
+			if len(lines) == 0 {
+				continue
+			}
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// editText has the user write text in their editor. Lines starting with #
+// are dropped.
+func editText(editor, question string) (string, error) {
+	f, err := os.CreateTemp("", "multiclaude-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a file to edit: %w", err)
+	}
+	defer os.Remove(f.Name())
+	fmt.Fprintf(f, "\n# %s\n# Lines starting with # are ignored. Save and quit when done.\n", question)
+	f.Close()
+
+	cmd := exec.Command("sh", "-c", editor+" "+shell.Quote(f.Name()))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return "", errors.New(errors.CategoryUsage, "cancelled: the task is empty")
+	}
+	return text, nil
+}
+
+// workerWizard asks for a repository, task, base branch, definition and
+// number of workers, then creates the workers. It stands in for the flags
+// of `worker create` when `work` is run with no arguments in a terminal;
+// ctx.Repo, if any, is the repository it suggests.
+func (c *CLI) workerWizard(ctx CommandContext, in io.Reader, out io.Writer) error {
+	w := &wizard{in: bufio.NewReader(in), out: out}
+	plan, err := c.planWorkers(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nEquivalent command:\n  %s\n", plan.command())
+	if plan.Count > 1 {
+		fmt.Fprintf(out, "  (run %d times)\n", plan.Count)
+	}
+	confirm, err := w.ask(fmt.Sprintf("Start %d worker(s)? [Y/n]", plan.Count), "")
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(strings.ToLower(confirm), "n") {
+		fmt.Fprintln(out, "Cancelled")
+		return nil
+	}
+
+	for i := 0; i < plan.Count; i++ {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := c.createWorker(CommandContext{Repo: plan.Repo}, plan.args()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planWorkers asks the wizard's questions, suggesting ctx.Repo
+func (c *CLI) planWorkers(ctx CommandContext, w *wizard) (*workerPlan, error) {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{Command: "list_repos", Args: map[string]interface{}{"rich": true}})
+	if err != nil {
+		return nil, errors.DaemonNotRunning()
+	}
+	if !resp.Success {
+		return nil, daemonError("failed to list repositories", resp)
+	}
+
+	// Solo repositories have no workers
+	var repos []SelectableItem
+	def := 0
+	list, _ := resp.Data.([]interface{})
+	for _, item := range list {
+		repo, _ := item.(map[string]interface{})
+		name, _ := repo["name"].(string)
+		if solo, _ := repo["solo"].(bool); name == "" || solo {
+			continue
+		}
+		workers, _ := repo["worker_count"].(float64)
+		repos = append(repos, SelectableItem{Name: name, Description: fmt.Sprintf("%d worker(s)", int(workers))})
+	}
+	if len(repos) == 0 {
+		return nil, errors.NoRepositoriesFound()
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	for i, repo := range repos {
+		if repo.Name == ctx.Repo {
+			def = i
+		}
+	}
+
+	plan := &workerPlan{}
+	if len(repos) == 1 {
+		plan.Repo = repos[0].Name
+		fmt.Fprintf(w.out, "Repository: %s\n", plan.Repo)
+	} else if plan.Repo, err = w.choose("Repository:", repos, def); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(w.out)
+	if plan.Task, err = w.text("Task"); err != nil {
+		return nil, err
+	}
+
+	base := "origin/" + c.repoDefaultBranch(plan.Repo)
+	fmt.Fprintln(w.out)
+	branch, err := w.ask("Base branch", base)
+	if err != nil {
+		return nil, err
+	}
+	if branch != base {
+		plan.Branch = branch
+	}
+
+	// Specialized definitions, those that declare capabilities, are the
+	// templates a worker can follow besides the plain worker instructions
+	templates := []SelectableItem{{Name: "worker", Description: "General-purpose worker"}}
+	defs, _ := agents.NewReader(c.paths.RepoAgentsDir(plan.Repo), c.paths.RepoDir(plan.Repo)).ReadAllDefinitions()
+	for _, d := range defs {
+		if caps := d.ParseCapabilities(); d.Name != "worker" && len(caps) > 0 {
+			templates = append(templates, SelectableItem{Name: d.Name, Description: strings.Join(caps, ", ")})
+		}
+	}
+	if len(templates) > 1 {
+		name, err := w.choose("Template:", templates, 0)
+		if err != nil {
+			return nil, err
+		}
+		if name != "worker" {
+			plan.Definition = name
+		}
+	}
+
+	fmt.Fprintln(w.out)
+	for plan.Count == 0 {
+		answer, err := w.ask("Number of workers", "1")
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(answer)
+		if err != nil || n < 1 || n > maxWizardWorkers {
+			fmt.Fprintf(w.out, "Enter a number from 1 to %d.\n", maxWizardWorkers)
+			continue
+		}
+		plan.Count = n
+	}
+	return plan, nil
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/workhours"
+)
+
+// hoursStatus shows a repository's work hours and whether its agents are
+// working right now
+func (c *CLI) hoursStatus(ctx CommandContext, args []string) error {
+	resp, err := c.sendDaemonRequest("get_repo_config", map[string]interface{}{"name": ctx.Repo})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	printWorkHours(ctx.Repo, data)
+	return nil
+}
+
+// hoursOverride returns a command that starts work now ("on"), stops it now
+// ("off") or goes back to the configured hours ("auto")
+func (c *CLI) hoursOverride(mode string) func([]string) error {
+	return c.withRepo(func(ctx CommandContext, args []string) error {
+		flags, _ := ParseFlags(args)
+		reqArgs := map[string]interface{}{"repo": ctx.Repo, "mode": mode}
+		if duration := flags["for"]; duration != "" {
+			if mode == "auto" {
+				return errors.InvalidUsage("--for only applies to `hours on` and `hours off`")
+			}
+			reqArgs["for"] = duration
+		}
+		resp, err := c.sendDaemonRequest("work_hours_override", reqArgs)
+		if err != nil {
+			return err
+		}
+		data, _ := resp.Data.(map[string]interface{})
+		printWorkHours(ctx.Repo, data)
+		return nil
+	})
+}
+
+// checkWorkHours refuses to start work in a repository outside its work hours
+func (c *CLI) checkWorkHours(repoName string) error {
+	st, err := c.loadState()
+	if err != nil {
+		return nil
+	}
+	hours, err := st.GetWorkHours(repoName)
+	if err != nil {
+		return nil
+	}
+	if working, until := workhours.Status(hours, time.Now()); !working {
+		msg := fmt.Sprintf("%s is outside its work hours (%s)", repoName, workhours.Describe(hours))
+		if !until.IsZero() {
+			msg += fmt.Sprintf(" until %s", until.Local().Format("Mon 15:04"))
+		}
+		return errors.New(errors.CategoryConfig, msg).
+			WithSuggestion(fmt.Sprintf("start working now with: multiclaude hours on --repo %s", repoName))
+	}
+	return nil
+}
+
+// printWorkHours prints work hours as get_repo_config and
+// work_hours_override return them
+func printWorkHours(repoName string, data map[string]interface{}) {
+	hours := workHoursFromConfig(data)
+	fmt.Printf("Work hours for %s: %s\n", repoName, workhours.Describe(hours))
+
+	working, _ := data["working"].(bool)
+	now := format.Green.Sprint("working")
+	if !working {
+		now = format.Yellow.Sprint("off hours")
+	}
+	if until := configTime(data, "working_until"); !until.IsZero() {
+		now += fmt.Sprintf(" until %s", until.Local().Format("Mon 15:04"))
+	}
+	fmt.Printf("  Now: %s\n", now)
+
+	if hours.Override != "" {
+		until := "further notice"
+		if !hours.OverrideUntil.IsZero() {
+			until = hours.OverrideUntil.Local().Format("Mon 15:04")
+		}
+		fmt.Printf("  Override: %s until %s (clear with: multiclaude hours auto --repo %s)\n", hours.Override, until, repoName)
+	}
+	if !working {
+		format.Dimmed("Agents aren't nudged, queued tasks wait and new workers are refused until work starts again.")
+		format.Dimmed("Start working now with: multiclaude hours on --repo %s", repoName)
+	}
+}
+
+// workHoursFromConfig reads work hours from get_repo_config's response
+func workHoursFromConfig(data map[string]interface{}) state.WorkHours {
+	var hours state.WorkHours
+	hours.Start, _ = data["work_hours_start"].(string)
+	hours.End, _ = data["work_hours_end"].(string)
+	hours.Days = interfaceSliceToStrings(data["work_hours_days"])
+	hours.Timezone, _ = data["work_hours_timezone"].(string)
+	hours.Override, _ = data["work_hours_override"].(string)
+	hours.OverrideUntil = configTime(data, "work_hours_override_until")
+	return hours
+}
+
+// configTime reads a time that the daemon sent as JSON
+func configTime(data map[string]interface{}, key string) time.Time {
+	s, _ := data[key].(string)
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/format"
+)
+
+// worktreeBase returns the worktree base directory configured for a
+// repository, or "" for the default
+func (c *CLI) worktreeBase(repoName string) string {
+	st, err := c.loadState()
+	if err != nil {
+		return ""
+	}
+	cfg, err := st.GetWorktreeConfig(repoName)
+	if err != nil {
+		return ""
+	}
+	return cfg.Base
+}
+
+// worktreeBaseArg turns the --worktree-dir value into the absolute directory
+// update_repo_config takes, with "default" clearing it
+func worktreeBaseArg(value string) (string, error) {
+	return dirArg("worktree-dir", value)
+}
+
+// dirArg turns a directory flag's value into an absolute path, expanding a
+// leading ~, with "default" giving ""
+func dirArg(flag, value string) (string, error) {
+	if value == "default" {
+		return "", nil
+	}
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("invalid --%s value: %w", flag, err)
+		}
+		value = filepath.Join(home, strings.TrimPrefix(value, "~"))
+	}
+	dir, err := filepath.Abs(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid --%s value: %w", flag, err)
+	}
+	return dir, nil
+}
+
+// migrateWorktrees moves a repository's agent worktrees into its current
+// worktree directory, for after `multiclaude config --worktree-dir`
+func (c *CLI) migrateWorktrees(ctx CommandContext, flags *FlagSet) error {
+	dryRun := flags.Bool("dry-run")
+
+	resp, err := c.sendDaemonRequest("migrate_worktrees", map[string]interface{}{
+		"repo":    ctx.Repo,
+		"dry_run": dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	results, _ := resp.Data.([]interface{})
+
+	wtDir := c.paths.WorktreeDir(ctx.Repo)
+	if len(results) == 0 {
+		fmt.Printf("All worktrees of %s are already in %s\n", ctx.Repo, wtDir)
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("Worktrees of %s that would move to %s:\n", ctx.Repo, wtDir)
+	} else {
+		fmt.Printf("Moving worktrees of %s to %s:\n", ctx.Repo, wtDir)
+	}
+	failed, skipped := 0, 0
+	for _, r := range results {
+		result, _ := r.(map[string]interface{})
+		agent, _ := result["agent"].(string)
+		from, _ := result["from"].(string)
+		reason, _ := result["reason"].(string)
+		switch result["status"] {
+		case "moved":
+			fmt.Printf("  %s %s (from %s)\n", format.Green.Sprint("✓"), agent, from)
+		case "planned":
+			fmt.Printf("  %s %s (from %s)\n", format.Dim.Sprint("→"), agent, from)
+		case "skipped":
+			skipped++
+			fmt.Printf("  %s %s: skipped, %s\n", format.Yellow.Sprint("-"), agent, reason)
+		default:
+			failed++
+			fmt.Printf("  %s %s: %s\n", format.Red.Sprint("✗"), agent, reason)
+		}
+	}
+
+	if skipped > 0 {
+		format.Dimmed("Running agents keep their worktrees; run this again once they have finished.")
+	}
+	if failed > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("%d worktree(s) could not be moved", failed)).
+			WithSuggestion("multiclaude repo migrate-worktrees --repo " + ctx.Repo + " --dry-run")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package clone clones repositories for `multiclaude init` in steps that
+// survive an interruption. `git clone` deletes everything it fetched when it
+// fails, so a large repository that loses its connection halfway starts
+// from nothing. Instead the repository is initialized first, its history
+// fetched in chunks of commits that grow as they go, and the default branch
+// checked out last. Finished chunks stay on disk and a marker file records
+// the clone until it completes, so running init again continues it.
+package clone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+)
+
+// markerFile, in the clone's .git directory, records an unfinished clone
+const markerFile = "multiclaude-clone.json"
+
+// firstChunk is how many commits the first fetch of a full clone asks for.
+// Each later chunk asks for twice as many as the one before.
+const firstChunk = 1000
+
+// Options shape the clone
+type Options struct {
+	// Depth keeps only this many commits of history; 0 fetches all of it
+	Depth int `json:"depth,omitempty"`
+	// Filter is a partial clone filter, such as blob:none, that leaves
+	// objects on the server until they are needed
+	Filter string `json:"filter,omitempty"`
+}
+
+// Pending is an unfinished clone found on disk
+type Pending struct {
+	URL       string    `json:"url"`
+	Options   Options   `json:"options"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Interrupted returns the unfinished clone at path, or nil if there is none
+func Interrupted(path string) (*Pending, error) {
+	data, err := os.ReadFile(filepath.Join(path, ".git", markerFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var pending Pending
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("unreadable clone marker in %s: %w", path, err)
+	}
+	return &pending, nil
+}
+
+// Clone clones url into path, or continues an interrupted clone of url
+// there with the options it was started with. Git's progress and a line per
+// step go to progress, which may be nil.
+func Clone(url, path string, opts Options, progress io.Writer) error {
+	if progress == nil {
+		progress = io.Discard
+	}
+
+	pending, err := Interrupted(path)
+	if err != nil {
+		return err
+	}
+	if pending == nil {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+		if _, err := gitcmd.Run(context.Background(), "", "init", "--quiet", path); err != nil {
+			return err
+		}
+		pending = &Pending{URL: url, Options: opts, StartedAt: time.Now()}
+		data, err := json.MarshalIndent(pending, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(path, ".git", markerFile), data, 0644); err != nil {
+			return fmt.Errorf("failed to write clone marker: %w", err)
+		}
+	} else if pending.URL != url {
+		return fmt.Errorf("%s holds an interrupted clone of %s, not %s", path, pending.URL, url)
+	}
+
+	// The remote may already be there if the last attempt got past this step
+	if _, err := gitcmd.Run(context.Background(), path, "remote", "get-url", "origin"); err != nil {
+		if _, err := gitcmd.Run(context.Background(), path, "remote", "add", "origin", url); err != nil {
+			return err
+		}
+	}
+	if err := fetch(path, pending.Options, progress); err != nil {
+		return err
+	}
+	if err := checkout(path, progress); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(path, ".git", markerFile))
+}
+
+// fetch fetches the remote's history. A full clone starts shallow and
+// deepens one chunk at a time until it reaches the first commit, picking up
+// from whatever an earlier attempt fetched.
+func fetch(path string, opts Options, progress io.Writer) error {
+	args := []string{"fetch", "--progress"}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+
+	if opts.Depth > 0 {
+		fmt.Fprintf(progress, "Fetching the latest %d commits\n", opts.Depth)
+		return transfer(path, progress, append(args, fmt.Sprintf("--depth=%d", opts.Depth), "origin")...)
+	}
+
+	if !hasRemoteRefs(path) {
+		fmt.Fprintf(progress, "Fetching the latest %d commits\n", firstChunk)
+		if err := transfer(path, progress, append(args, fmt.Sprintf("--depth=%d", firstChunk), "origin")...); err != nil {
+			return err
+		}
+	}
+	for chunk := firstChunk; isShallow(path); chunk *= 2 {
+		fmt.Fprintf(progress, "Fetching up to %d older commits\n", chunk)
+		if err := transfer(path, progress, append(args, fmt.Sprintf("--deepen=%d", chunk), "origin")...); err != nil {
+			return err
+		}
+	}
+
+	// Deepening only follows the branches; this brings the tags along
+	fmt.Fprintln(progress, "Fetching tags")
+	return transfer(path, progress, append(args, "--tags", "origin")...)
+}
+
+// checkout checks out the remote's default branch. An empty repository has
+// nothing to check out.
+func checkout(path string, progress io.Writer) error {
+	if !hasRemoteRefs(path) {
+		return nil
+	}
+	if _, err := gitcmd.Run(context.Background(), path, "remote", "set-head", "origin", "--auto"); err != nil {
+		return err
+	}
+	head, err := gitcmd.Run(context.Background(), path, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to find the default branch: %w", err)
+	}
+	branch := strings.TrimPrefix(head, "origin/")
+	fmt.Fprintf(progress, "Checking out %s\n", branch)
+	return transfer(path, progress, "checkout", "--quiet", "-B", branch, "--track", "origin/"+branch)
+}
+
+// transfer runs a git command that fetches the repository's history or, in
+// a partial clone, its files, streaming git's progress to progress. It takes
+// as long as the repository's size needs; an interrupted clone continues
+// from what it finished.
+func transfer(path string, progress io.Writer, args ...string) error {
+	_, err := gitcmd.Cmd{Dir: path, Args: args, Progress: progress, Unbounded: true}.Output(context.Background())
+	return err
+}
+
+// hasRemoteRefs reports whether anything has been fetched from origin yet
+func hasRemoteRefs(path string) bool {
+	out, err := gitcmd.Run(context.Background(), path, "for-each-ref", "--count=1", "refs/remotes/origin")
+	return err == nil && out != ""
+}
+
+// isShallow reports whether the repository is missing older history
+func isShallow(path string) bool {
+	out, err := gitcmd.Run(context.Background(), path, "rev-parse", "--is-shallow-repository")
+	return err == nil && out == "true"
+}
@@ -0,0 +1,109 @@
+package clone
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run runs git in dir and returns its trimmed output
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// sourceRepo creates a repository with five commits on main and a tag
+func sourceRepo(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src")
+	run(t, "", "init", "--quiet", "-b", "main", src)
+	run(t, src, "config", "user.email", "test@example.com")
+	run(t, src, "config", "user.name", "Test User")
+	for i := 1; i <= 5; i++ {
+		os.WriteFile(filepath.Join(src, "file.txt"), []byte(strings.Repeat("x", i)), 0644)
+		run(t, src, "add", ".")
+		run(t, src, "commit", "--quiet", "-m", "commit")
+	}
+	run(t, src, "tag", "v1", "HEAD~3")
+	return src
+}
+
+func TestCloneResumesInterruptedClone(t *testing.T) {
+	src := sourceRepo(t)
+	path := filepath.Join(t.TempDir(), "repo")
+
+	// The source goes missing halfway, leaving the clone unfinished
+	os.Rename(src, src+".away")
+	if err := Clone(src, path, Options{}, nil); err == nil {
+		t.Fatal("Clone() of a missing repository succeeded")
+	}
+	pending, err := Interrupted(path)
+	if err != nil || pending == nil || pending.URL != src || pending.StartedAt.IsZero() {
+		t.Fatalf("Interrupted() = %+v, %v, want the unfinished clone", pending, err)
+	}
+	if err := Clone("https://example.com/other.git", path, Options{}, nil); err == nil || !strings.Contains(err.Error(), "interrupted clone") {
+		t.Errorf("Clone() of another URL into the unfinished clone error = %v", err)
+	}
+
+	// Part of the history arrived before the interruption
+	os.Rename(src+".away", src)
+	run(t, path, "fetch", "--quiet", "--depth=2", "origin")
+
+	var progress bytes.Buffer
+	if err := Clone(src, path, Options{}, &progress); err != nil {
+		t.Fatalf("Clone() resuming failed: %v\n%s", err, progress.String())
+	}
+	if pending, _ := Interrupted(path); pending != nil {
+		t.Errorf("Interrupted() after finishing = %+v, want nil", pending)
+	}
+	if got := run(t, path, "rev-parse", "--is-shallow-repository"); got != "false" {
+		t.Errorf("resumed clone is shallow")
+	}
+	if got := run(t, path, "rev-list", "--count", "HEAD"); got != "5" {
+		t.Errorf("resumed clone has %s commits, want 5", got)
+	}
+	if got := run(t, path, "rev-parse", "--abbrev-ref", "HEAD@{upstream}"); got != "origin/main" {
+		t.Errorf("checked out branch tracks %q, want origin/main", got)
+	}
+	if got := run(t, path, "tag"); got != "v1" {
+		t.Errorf("tags = %q, want v1", got)
+	}
+	if !strings.Contains(progress.String(), "Checking out main") {
+		t.Errorf("progress is missing the checkout step:\n%s", progress.String())
+	}
+}
+
+func TestCloneOptions(t *testing.T) {
+	src := sourceRepo(t)
+	dir := t.TempDir()
+
+	shallow := filepath.Join(dir, "shallow")
+	if err := Clone(src, shallow, Options{Depth: 2}, nil); err != nil {
+		t.Fatalf("Clone() with a depth failed: %v", err)
+	}
+	if got := run(t, shallow, "rev-list", "--count", "HEAD"); got != "2" {
+		t.Errorf("shallow clone has %s commits, want 2", got)
+	}
+
+	run(t, src, "config", "uploadpack.allowFilter", "true")
+	partial := filepath.Join(dir, "partial")
+	if err := Clone("file://"+src, partial, Options{Filter: "blob:none"}, nil); err != nil {
+		t.Fatalf("Clone() with a filter failed: %v", err)
+	}
+	if got := run(t, partial, "config", "remote.origin.partialclonefilter"); got != "blob:none" {
+		t.Errorf("partial clone filter = %q, want blob:none", got)
+	}
+
+	if err := Clone(src, shallow, Options{}, nil); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Clone() into a finished clone error = %v, want already exists", err)
+	}
+}
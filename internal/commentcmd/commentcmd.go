@@ -0,0 +1,172 @@
+// Package commentcmd reads commands for multiclaude out of GitHub PR and
+// issue comments, such as
+//
+//	/multiclaude revise: address the review comments
+//	/multiclaude abandon
+//
+// so the people reviewing a worker's PR can steer it without a terminal. The
+// daemon polls each repository's new comments, keeps those from allowed
+// commenters and applies them to the worker that owns the PR or issue.
+package commentcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix starts a command line in a comment
+const Prefix = "/multiclaude"
+
+// Verb is what a command asks for
+type Verb string
+
+const (
+	// VerbRevise sends the command's text to the worker as instructions
+	VerbRevise Verb = "revise"
+	// VerbAbandon ends the worker's task as failed; the daemon cleans it up
+	VerbAbandon Verb = "abandon"
+	// VerbRestart restarts the worker's Claude session
+	VerbRestart Verb = "restart"
+)
+
+// Verbs are the verbs commands may use
+var Verbs = []Verb{VerbRevise, VerbAbandon, VerbRestart}
+
+// Command is one command from a comment
+type Command struct {
+	Verb Verb
+	Text string // What follows "verb:", if anything
+}
+
+// Comment is a PR or issue comment as listed by the GitHub API
+type Comment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	Author    string    `json:"login"`
+	IssueURL  string    `json:"issue_url"` // API URL of the PR or issue, ending in its number
+	URL       string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Number returns the number of the PR or issue the comment is on, or 0
+func (c Comment) Number() int {
+	n, _ := strconv.Atoi(c.IssueURL[strings.LastIndex(c.IssueURL, "/")+1:])
+	return n
+}
+
+// Parse returns the commands in a comment body, one per line starting with
+// Prefix. The verb is matched ignoring case; unknown verbs are returned so the
+// commenter can be told they weren't understood.
+func Parse(body string) []Command {
+	var commands []Command
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		rest, ok := strings.CutPrefix(line, Prefix)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		// The verb is the first word; a colon after it is optional
+		rest = strings.TrimSpace(rest)
+		end := strings.IndexAny(rest, " \t:")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			continue
+		}
+		text := strings.TrimPrefix(strings.TrimSpace(rest[end:]), ":")
+		commands = append(commands, Command{Verb: Verb(strings.ToLower(rest[:end])), Text: strings.TrimSpace(text)})
+	}
+	return commands
+}
+
+// Known reports whether the command's verb is one of Verbs
+func (c Command) Known() bool {
+	for _, v := range Verbs {
+		if c.Verb == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether a GitHub login is on the allowlist, ignoring case
+// and a leading "@"
+func Allowed(login string, allow []string) bool {
+	for _, a := range allow {
+		if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(a), "@"), login) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mentions reports whether text refers to issue n as "#n"
+func Mentions(text string, n int) bool {
+	return regexp.MustCompile(`(^|[^\w&])#` + strconv.Itoa(n) + `\b`).MatchString(text)
+}
+
+// Cursor is how far into a repository's comments the daemon has read
+type Cursor struct {
+	path   string
+	LastID int64     `json:"last_id"` // Comments up to this ID have been handled
+	Since  time.Time `json:"since"`   // Listing starts here; comments are created in ID order
+}
+
+// LoadCursor reads a cursor file. A missing file gives a new cursor, whose
+// IsNew is true.
+func LoadCursor(path string) (*Cursor, error) {
+	c := &Cursor{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read comment cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse comment cursor: %w", err)
+	}
+	return c, nil
+}
+
+// IsNew reports whether the cursor has never been saved
+func (c *Cursor) IsNew() bool {
+	return c.Since.IsZero()
+}
+
+// Advance moves the cursor past a comment, reporting false if it was already
+// handled or predates the cursor. Listing by time also returns older comments
+// that were edited since, and those are never new.
+func (c *Cursor) Advance(comment Comment) bool {
+	if comment.ID <= c.LastID || comment.CreatedAt.Before(c.Since) {
+		return false
+	}
+	c.LastID = comment.ID
+	if comment.CreatedAt.After(c.Since) {
+		c.Since = comment.CreatedAt
+	}
+	return true
+}
+
+// Save writes the cursor back to disk
+func (c *Cursor) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create comment cursor directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment cursor: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write comment cursor: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,85 @@
+package commentcmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		body string
+		want []Command
+	}{
+		{"/multiclaude revise: address review comments", []Command{{VerbRevise, "address review comments"}}},
+		{"/multiclaude revise fix the flaky test", []Command{{VerbRevise, "fix the flaky test"}}},
+		{"Thanks!\n\n  /multiclaude Abandon  \n", []Command{{VerbAbandon, ""}}},
+		{"/multiclaude restart\n/multiclaude revise: rebase first", []Command{{VerbRestart, ""}, {VerbRevise, "rebase first"}}},
+		{"/multiclaude dance", []Command{{"dance", ""}}},
+		{"/multiclaude", nil},
+		{"/multiclauderevise: nope", nil},
+		{"see /multiclaude revise in the docs", nil},
+		{"> quoted\nplain comment", nil},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+
+	if !(Command{Verb: VerbAbandon}).Known() || (Command{Verb: "dance"}).Known() {
+		t.Error("Known() should accept only the listed verbs")
+	}
+}
+
+func TestAllowedAndMentions(t *testing.T) {
+	allow := []string{"alice", "@Bob"}
+	for login, want := range map[string]bool{"alice": true, "ALICE": true, "bob": true, "mallory": false} {
+		if got := Allowed(login, allow); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", login, got, want)
+		}
+	}
+
+	for text, want := range map[string]bool{"Fix #42": true, "#42: crash": true, "Fix #421": false, "Fix 42": false, "see a#42": false} {
+		if got := Mentions(text, 42); got != want {
+			t.Errorf("Mentions(%q, 42) = %v, want %v", text, got, want)
+		}
+	}
+
+	c := Comment{IssueURL: "https://api.github.com/repos/acme/app/issues/17"}
+	if c.Number() != 17 {
+		t.Errorf("Number() = %d, want 17", c.Number())
+	}
+}
+
+func TestCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "comments", "app.json")
+	c, err := LoadCursor(path)
+	if err != nil || !c.IsNew() {
+		t.Fatalf("LoadCursor() = %+v, %v; want a new cursor", c, err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.Since = start
+	if !c.Advance(Comment{ID: 10, CreatedAt: start.Add(time.Minute)}) {
+		t.Error("Advance() should accept a new comment")
+	}
+	if c.Advance(Comment{ID: 10, CreatedAt: start.Add(time.Hour)}) || c.Advance(Comment{ID: 9, CreatedAt: start.Add(time.Hour)}) {
+		t.Error("Advance() should skip comments already handled")
+	}
+	if c.Advance(Comment{ID: 11, CreatedAt: start}) {
+		t.Error("Advance() should skip comments from before the cursor")
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	loaded, err := LoadCursor(path)
+	if err != nil {
+		t.Fatalf("LoadCursor() failed: %v", err)
+	}
+	if loaded.IsNew() || loaded.LastID != 10 || !loaded.Since.Equal(start.Add(time.Minute)) {
+		t.Errorf("loaded cursor = %+v, want last ID 10 since %s", loaded, start.Add(time.Minute))
+	}
+}
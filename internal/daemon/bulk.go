@@ -0,0 +1,277 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/export"
+	"github.com/micheal-at/multiclaude/internal/redact"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/trash"
+)
+
+// bulkActions are what bulk_workers does to each worker it matches
+var bulkActions = []string{"rm", "nudge", "complete"}
+
+// bulkStatuses are the values a status filter takes: the agent's own
+// status, idle for running workers that have gone quiet, or its PR's state
+var bulkStatuses = []string{"running", "completed", "paused", "stopped", "idle", "pr-open", "pr-draft", "pr-merged", "pr-closed", "pr-none"}
+
+// workerFilter is one condition of a bulk operation, from key=value. A value
+// may list alternatives separated by commas; a worker matches a filter if it
+// matches any of them, and a bulk operation if it matches every filter.
+type workerFilter struct {
+	key    string
+	values []string
+}
+
+// parseWorkerFilters parses bulk_workers' filters: status=<status>[,...] or
+// name=<glob>[,...]
+func parseWorkerFilters(raw []string) ([]workerFilter, error) {
+	var filters []workerFilter
+	for _, f := range raw {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid filter %q: want key=value, such as status=pr-merged", f)
+		}
+		filter := workerFilter{key: key, values: strings.Split(value, ",")}
+		switch key {
+		case "status":
+			for _, v := range filter.values {
+				if !containsString(bulkStatuses, v) {
+					return nil, fmt.Errorf("invalid status %q in filter %q: must be one of %s", v, f, strings.Join(bulkStatuses, ", "))
+				}
+			}
+		case "name":
+			for _, v := range filter.values {
+				if _, err := path.Match(v, ""); err != nil {
+					return nil, fmt.Errorf("invalid name pattern %q in filter %q: %v", v, f, err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid filter %q: the key must be status or name", f)
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// needsPRs reports whether any filter looks at the workers' PRs, which takes
+// a gh query
+func needsPRs(filters []workerFilter) bool {
+	for _, f := range filters {
+		for _, v := range f.values {
+			if strings.HasPrefix(v, "pr-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesFilters reports whether a worker, as list_agents details it,
+// matches every filter. idle reports whether it has gone quiet, and is only
+// asked when a filter needs it.
+func matchesFilters(detail map[string]interface{}, filters []workerFilter, idle func() bool) bool {
+	for _, f := range filters {
+		matched := false
+		for _, v := range f.values {
+			if matchesFilter(detail, f.key, v, idle) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesFilter reports whether a worker matches one alternative of a filter
+func matchesFilter(detail map[string]interface{}, key, value string, idle func() bool) bool {
+	switch key {
+	case "name":
+		name, _ := detail["name"].(string)
+		ok, _ := path.Match(value, name)
+		return ok
+	case "status":
+		status, _ := detail["status"].(string)
+		prStatus, _ := detail["pr_status"].(string)
+		switch value {
+		case "idle":
+			return status == "running" && idle()
+		case "pr-none":
+			return prStatus == "no-pr"
+		default:
+			if pr, ok := strings.CutPrefix(value, "pr-"); ok {
+				return prStatus == pr
+			}
+			return status == value
+		}
+	}
+	return false
+}
+
+// handleBulkWorkers removes, nudges or completes every worker of a
+// repository that matches the filters, in one request. With dry_run it only
+// lists them, so the CLI can ask before doing anything; agents then limits
+// the operation to the workers that were confirmed.
+func (d *Daemon) handleBulkWorkers(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	action, errResp, ok := getRequiredStringArg(req.Args, "action", "action is required: "+strings.Join(bulkActions, ", "))
+	if !ok {
+		return errResp
+	}
+	if !containsString(bulkActions, action) {
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid action %q: must be one of %s", action, strings.Join(bulkActions, ", "))}
+	}
+	if _, exists := d.state.GetRepo(repoName); !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+
+	filters, err := parseWorkerFilters(interfaceStrings(req.Args["filters"]))
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	all, _ := req.Args["all"].(bool)
+	if len(filters) == 0 && !all {
+		return socket.Response{Success: false, Error: "a filter, or all, is required to act on every worker"}
+	}
+	if needsPRs(filters) {
+		if err := d.requireGitHub(); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+	}
+
+	details, err := d.agentDetails(repoName, true, needsPRs(filters))
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	confirmed := interfaceStrings(req.Args["agents"])
+	var matched []map[string]interface{}
+	for _, detail := range details {
+		name, _ := detail["name"].(string)
+		if detail["type"] != state.AgentTypeWorker {
+			continue
+		}
+		if req.Args["agents"] != nil && !containsString(confirmed, name) {
+			continue
+		}
+		wtPath, _ := detail["worktree_path"].(string)
+		idle := func() bool { active, _ := d.agentActive(repoName, name, wtPath); return !active }
+		if matchesFilters(detail, filters, idle) {
+			matched = append(matched, map[string]interface{}{
+				"name":      name,
+				"status":    detail["status"],
+				"pr_status": detail["pr_status"],
+				"task":      detail["task"],
+			})
+		}
+	}
+
+	if dryRun, _ := req.Args["dry_run"].(bool); dryRun {
+		return socket.Response{Success: true, Data: map[string]interface{}{"action": action, "matched": matched, "dry_run": true}}
+	}
+
+	results := make([]map[string]interface{}, 0, len(matched))
+	for _, m := range matched {
+		name := m["name"].(string)
+		result := map[string]interface{}{"name": name, "ok": true}
+		if err := d.bulkApply(req.TraceID, repoName, name, action); err != nil {
+			result["ok"] = false
+			result["error"] = err.Error()
+		}
+		results = append(results, result)
+	}
+	d.logger.WithTrace(req.TraceID).Info("Bulk %s of %d workers in %s", action, len(results), repoName)
+	return socket.Response{Success: true, Data: map[string]interface{}{"action": action, "matched": matched, "results": results}}
+}
+
+// bulkApply does a bulk operation's action to one worker
+func (d *Daemon) bulkApply(traceID, repoName, name, action string) error {
+	agent, exists := d.state.GetAgent(repoName, name)
+	if !exists {
+		return fmt.Errorf("worker '%s' is gone", name)
+	}
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return fmt.Errorf("repository '%s' is gone", repoName)
+	}
+
+	switch action {
+	case "rm":
+		// Like worker rm, the worker goes to the trash, from where worker
+		// undelete brings it back; one that can't be trashed is kept
+		if err := d.trashWorker(repoName, name, agent); err != nil {
+			return fmt.Errorf("not removed, failed to move it to the trash: %w", err)
+		}
+		d.cleanupDeadAgents(map[string][]string{repoName: {name}})
+	case "nudge":
+		if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, d.text("nudge.quiet", nil)); err != nil {
+			return fmt.Errorf("failed to nudge: %w", err)
+		}
+		agent.LastNudge = time.Now()
+		if err := d.state.UpdateAgent(repoName, name, agent); err != nil {
+			return err
+		}
+	case "complete":
+		resp := d.handleCompleteAgent(socket.Request{Command: "complete_agent", TraceID: traceID, Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": name,
+		}})
+		if !resp.Success {
+			return fmt.Errorf("%s", resp.Error)
+		}
+	}
+	return nil
+}
+
+// trashWorker saves a worker's tombstone to the trash before it's removed
+func (d *Daemon) trashWorker(repoName, name string, agent state.Agent) error {
+	redactor, err := redact.NewFromFile(d.paths.RedactFile())
+	if err != nil {
+		d.logger.Warn("Ignoring extra secret patterns: %v", err)
+	}
+	tomb, err := trash.Capture(export.Source{
+		Repo:     repoName,
+		Name:     name,
+		Agent:    agent,
+		BaseRef:  "origin/" + agentBase(agent, d.repoDefaultBranch(repoName)),
+		Messages: d.getMessageManager(),
+		Redactor: redactor,
+	}, trash.DefaultRetention, time.Now())
+	if err != nil {
+		return err
+	}
+	return trash.Save(d.paths, tomb)
+}
+
+// interfaceStrings returns the strings of a JSON array argument
+func interfaceStrings(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var list []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/commentcmd"
+	"github.com/micheal-at/multiclaude/internal/daemonconfig"
+	"github.com/micheal-at/multiclaude/internal/events"
+	"github.com/micheal-at/multiclaude/internal/fork"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// commentCommandsLoop reads "/multiclaude <verb>" commands from the GitHub
+// comments of every repository that allows them
+func (d *Daemon) commentCommandsLoop() {
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.CommentCommands })
+	d.periodicLoop("comment commands", interval, nil, d.slowInStandby(interval, d.pollCommentCommands))
+}
+
+// pollCommentCommands handles the new comment commands of each repository
+// with an allowlist
+func (d *Daemon) pollCommentCommands() {
+	if d.githubProblem() != "" {
+		return
+	}
+	for repoName, repo := range d.state.GetAllRepos() {
+		if repo.Solo || len(repo.CommentCommands.Allow) == 0 {
+			continue
+		}
+		if d.ctx.Err() != nil {
+			return
+		}
+		if err := d.pollRepoComments(repoName, repo); err != nil {
+			d.subsystemWarn("comment commands", "Failed to read comment commands for %s: %v", repoName, err)
+		}
+	}
+}
+
+// commentsRepo returns the GitHub repository a repository's PRs and issues
+// are on: the upstream for forks
+func commentsRepo(repo *state.Repository) (owner, name string, err error) {
+	if repo.ForkConfig.IsFork && repo.ForkConfig.UpstreamOwner != "" {
+		return repo.ForkConfig.UpstreamOwner, repo.ForkConfig.UpstreamRepo, nil
+	}
+	return fork.ParseGitHubURL(repo.GithubURL)
+}
+
+// pollRepoComments reads the comments made since the last poll and carries
+// out the commands in those from allowed commenters. Each handled comment
+// gets a 👍 reaction, or 😕 if a command in it failed. The first poll only
+// starts the cursor, so comments from before the bridge was on are ignored.
+func (d *Daemon) pollRepoComments(repoName string, repo *state.Repository) error {
+	owner, name, err := commentsRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := commentcmd.LoadCursor(d.paths.CommentsFile(repoName))
+	if err != nil {
+		return err
+	}
+	if cursor.IsNew() {
+		cursor.Since = time.Now()
+		return cursor.Save()
+	}
+
+	comments, err := d.listComments(owner, name, cursor.Since)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if !cursor.Advance(c) {
+			continue
+		}
+		commands := commentcmd.Parse(c.Body)
+		if len(commands) == 0 {
+			continue
+		}
+		if !commentcmd.Allowed(c.Author, repo.CommentCommands.Allow) {
+			d.logger.Info("Ignoring comment commands from @%s on #%d in %s: not on the allowlist", c.Author, c.Number(), repoName)
+			continue
+		}
+
+		reaction := "+1"
+		worker := d.commentWorker(repoName, c.Number())
+		for _, cmd := range commands {
+			if err := d.runCommentCommand(repoName, worker, c, cmd); err != nil {
+				d.logger.Warn("Comment command %q from @%s on #%d in %s failed: %v", cmd.Verb, c.Author, c.Number(), repoName, err)
+				reaction = "confused"
+				continue
+			}
+			d.logger.Info("Comment command %q from @%s on #%d in %s applied to %s", cmd.Verb, c.Author, c.Number(), repoName, worker)
+			d.events.Publish(events.EventCommentCommand, repoName, worker, map[string]string{
+				"verb":   string(cmd.Verb),
+				"author": c.Author,
+				"number": strconv.Itoa(c.Number()),
+			})
+		}
+		if err := d.reactToComment(owner, name, c.ID, reaction); err != nil {
+			d.logger.Debug("Failed to react to comment %d in %s: %v", c.ID, repoName, err)
+		}
+	}
+	return cursor.Save()
+}
+
+// commentWorker returns the worker a PR or issue belongs to, or "" if none
+// does: a PR's worker works on its branch, an issue's worker is the only one
+// whose task mentions it
+func (d *Daemon) commentWorker(repoName string, number int) string {
+	for attempt := 0; attempt < 2; attempt++ {
+		prs, err := d.repoPullRequests(repoName)
+		if err != nil {
+			break
+		}
+		for branch, pr := range prs {
+			if pr.Number == number {
+				return d.branchWorker(repoName, branch)
+			}
+		}
+		// The PR may be newer than the cached list
+		d.prCache.Invalidate(repoName)
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return ""
+	}
+	worker := ""
+	for name, agent := range repo.Agents {
+		if agent.Type != state.AgentTypeWorker || !commentcmd.Mentions(agent.Task, number) {
+			continue
+		}
+		if worker != "" {
+			return ""
+		}
+		worker = name
+	}
+	return worker
+}
+
+// runCommentCommand applies one comment command to the worker that owns the
+// PR or issue the comment is on
+func (d *Daemon) runCommentCommand(repoName, worker string, c commentcmd.Comment, cmd commentcmd.Command) error {
+	if !cmd.Known() {
+		verbs := make([]string, len(commentcmd.Verbs))
+		for i, v := range commentcmd.Verbs {
+			verbs[i] = string(v)
+		}
+		return fmt.Errorf("unknown command (use %s)", strings.Join(verbs, ", "))
+	}
+	if worker == "" {
+		return fmt.Errorf("no worker owns #%d", c.Number())
+	}
+
+	switch cmd.Verb {
+	case commentcmd.VerbRevise:
+		if cmd.Text == "" {
+			return fmt.Errorf("revise needs instructions, e.g. \"/multiclaude revise: address the review comments\"")
+		}
+		body := fmt.Sprintf("💬 @%s asks on #%d: %s\n\n%s", c.Author, c.Number(), cmd.Text, c.URL)
+		if _, err := d.getMessageManager().Send(repoName, "github", worker, body); err != nil {
+			return err
+		}
+		go d.routeMessages()
+		return nil
+
+	case commentcmd.VerbAbandon:
+		agent, exists := d.state.GetAgent(repoName, worker)
+		if !exists {
+			return fmt.Errorf("worker %s is gone", worker)
+		}
+		reason := fmt.Sprintf("abandoned by @%s on #%d", c.Author, c.Number())
+		if cmd.Text != "" {
+			reason += ": " + cmd.Text
+		}
+		agent.ReadyForCleanup = true
+		agent.FailureReason = reason
+		if err := d.state.UpdateAgent(repoName, worker, agent); err != nil {
+			return err
+		}
+		d.events.Publish(events.EventTaskFailed, repoName, worker, map[string]string{"task": agent.Task, "reason": reason})
+		return nil
+
+	case commentcmd.VerbRestart:
+		resp := d.handleRestartAgent(socket.Request{Command: "restart_agent", Args: map[string]interface{}{
+			"repo": repoName, "agent": worker, "force": true,
+		}})
+		if !resp.Success {
+			return fmt.Errorf("%s", resp.Error)
+		}
+		return nil
+	}
+	return nil
+}
+
+// listRepoComments lists a GitHub repository's PR and issue comments created
+// since a time, oldest first
+func (d *Daemon) listRepoComments(owner, repo string, since time.Time) ([]commentcmd.Comment, error) {
+	endpoint := fmt.Sprintf("repos/%s/%s/issues/comments?since=%s&sort=created&direction=asc&per_page=100", owner, repo, since.UTC().Format(time.RFC3339))
+	output, err := d.github().Output(d.ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "gh", "api", "--paginate", endpoint,
+			"--jq", ".[] | {id, body, login: .user.login, issue_url, html_url, created_at}")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gh api failed: %w", err)
+	}
+
+	var comments []commentcmd.Comment
+	dec := json.NewDecoder(strings.NewReader(string(output)))
+	for {
+		var c commentcmd.Comment
+		if err := dec.Decode(&c); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse gh api output: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+// reactToCommentOnGitHub adds a reaction to a PR or issue comment
+func (d *Daemon) reactToCommentOnGitHub(owner, repo string, id int64, reaction string) error {
+	output, err := d.github().CombinedOutput(d.ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "gh", "api", "-X", "POST",
+			fmt.Sprintf("repos/%s/%s/issues/comments/%d/reactions", owner, repo, id), "-f", "content="+reaction)
+	})
+	if err != nil {
+		return fmt.Errorf("gh api failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
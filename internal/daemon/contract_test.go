@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/daemontest"
+)
+
+// contractVolatile are response fields whose values depend on the machine,
+// tmux or the clock rather than on the requests sent. Only their types are
+// compared.
+var contractVolatile = map[string]bool{
+	"socket_path":     true,
+	"created_at":      true,
+	"session_healthy": true,
+	"status":          true,
+}
+
+// TestDaemontestContract sends the same requests to pkg/daemontest's fake
+// daemon and to the real one, and checks that every field the fake answers
+// with is one the real daemon answers with too, with the same type and, for
+// fields that don't depend on the machine, the same value. A change to a
+// response shape that leaves the fake behind fails here.
+func TestDaemontestContract(t *testing.T) {
+	fake := daemontest.New(t)
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {})
+	defer cleanup()
+	if err := d.server.Start(); err != nil {
+		t.Fatalf("failed to start socket server: %v", err)
+	}
+	go d.server.Serve()
+	defer d.server.Stop()
+
+	backends := map[string]*socket.Client{
+		"fake": socket.NewClient(fake.SocketPath()),
+		"real": socket.NewClient(d.paths.DaemonSock),
+	}
+
+	// The same repositories and agents, added through the socket API
+	seed := []socket.Request{
+		{Command: "add_repo", Args: map[string]interface{}{"name": "api", "github_url": "https://github.com/acme/api", "tmux_session": "mc-contract-api"}},
+		{Command: "add_repo", Args: map[string]interface{}{"name": "web", "github_url": "https://github.com/acme/web", "tmux_session": "mc-contract-web"}},
+		{Command: "add_agent", Args: map[string]interface{}{"repo": "api", "agent": "calm-owl", "type": "worker", "worktree_path": "/tmp/calm-owl", "tmux_window": "calm-owl", "task": "Fix the flaky test"}},
+		{Command: "add_agent", Args: map[string]interface{}{"repo": "web", "agent": "supervisor", "type": "supervisor", "worktree_path": "/tmp/web", "tmux_window": "supervisor"}},
+	}
+	for name, client := range backends {
+		for _, req := range seed {
+			resp, err := client.Send(req)
+			if err != nil || !resp.Success {
+				t.Fatalf("%s: %s failed: %v %+v", name, req.Command, err, resp)
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		command   string
+		args      map[string]interface{}
+		wantError string
+	}{
+		{name: "status", command: "status"},
+		{name: "list_repos", command: "list_repos"},
+		{name: "list_repos rich", command: "list_repos", args: map[string]interface{}{"rich": true}},
+		{name: "list_agents", command: "list_agents", args: map[string]interface{}{"repo": "api"}},
+		{name: "list_agents rich", command: "list_agents", args: map[string]interface{}{"repo": "api", "rich": true}},
+		{name: "list_agents all", command: "list_agents", args: map[string]interface{}{"all": true}},
+		{name: "list_agents unknown repo", command: "list_agents", args: map[string]interface{}{"repo": "nope"}, wantError: `repository "nope" not found`},
+		{name: "list_agents without repo", command: "list_agents", args: map[string]interface{}{}, wantError: "missing 'repo'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[string]*socket.Response{}
+			for name, client := range backends {
+				resp, err := client.Send(socket.Request{Command: tt.command, Args: tt.args})
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				responses[name] = resp
+			}
+
+			for name, resp := range responses {
+				if tt.wantError == "" && !resp.Success {
+					t.Fatalf("%s: failed: %s", name, resp.Error)
+				}
+				if tt.wantError != "" && (resp.Success || !strings.Contains(resp.Error, tt.wantError)) {
+					t.Errorf("%s: = %+v, want an error containing %q", name, resp, tt.wantError)
+				}
+			}
+			if tt.wantError == "" {
+				checkContract(t, tt.command, responses["fake"].Data, responses["real"].Data)
+			}
+		})
+	}
+}
+
+// checkContract checks that fake, a decoded response from the fake daemon,
+// matches live, the real daemon's
+func checkContract(t *testing.T, path string, fake, live interface{}) {
+	t.Helper()
+	switch f := fake.(type) {
+	case map[string]interface{}:
+		r, ok := live.(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: fake is an object, real is %T", path, live)
+			return
+		}
+		for key, value := range f {
+			realValue, ok := r[key]
+			if !ok {
+				t.Errorf("%s.%s: in the fake, not in the real daemon", path, key)
+				continue
+			}
+			if contractVolatile[key] {
+				if reflect.TypeOf(value) != reflect.TypeOf(realValue) {
+					t.Errorf("%s.%s: fake is %T, real is %T", path, key, value, realValue)
+				}
+				continue
+			}
+			checkContract(t, path+"."+key, value, realValue)
+		}
+	case []interface{}:
+		r, ok := live.([]interface{})
+		if !ok {
+			t.Errorf("%s: fake is a list, real is %T", path, live)
+			return
+		}
+		if len(f) != len(r) {
+			t.Errorf("%s: fake has %d items, real has %d", path, len(f), len(r))
+			return
+		}
+		// The real daemon lists repositories in map order
+		sortItems(f)
+		sortItems(r)
+		for i := range f {
+			checkContract(t, fmt.Sprintf("%s[%d]", path, i), f[i], r[i])
+		}
+	default:
+		if !reflect.DeepEqual(fake, live) {
+			t.Errorf("%s: fake = %v, real = %v", path, fake, live)
+		}
+	}
+}
+
+// sortItems sorts a list of names, or of objects by their repo and name
+func sortItems(items []interface{}) {
+	key := func(item interface{}) string {
+		if m, ok := item.(map[string]interface{}); ok {
+			return fmt.Sprintf("%v/%v", m["repo"], m["name"])
+		}
+		return fmt.Sprint(item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+}
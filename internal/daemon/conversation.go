@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/export"
+	"github.com/micheal-at/multiclaude/internal/redact"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/claude"
+)
+
+// conversationSummaryTimeout bounds Claude's summary of one worker's session
+const conversationSummaryTimeout = 3 * time.Minute
+
+// conversationConcurrency is how many sessions are summarized at once, so
+// cleaning up a batch of workers doesn't start a Claude for each
+const conversationConcurrency = 2
+
+// maxConversationBytes caps how much of a session Claude reads: the end,
+// where the outcome is, when it's longer
+const maxConversationBytes = 200 * 1024
+
+// conversationText renders a session's turns for Claude to read. A session
+// longer than max loses its beginning, on a turn boundary.
+func conversationText(entries []claude.TranscriptEntry, max int) string {
+	var turns []string
+	size := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		turn := fmt.Sprintf("[%s]\n%s\n", entries[i].Role, entries[i].Text)
+		if size+len(turn) > max && len(turns) > 0 {
+			turns = append(turns, "[... earlier turns left out]\n")
+			break
+		}
+		turns = append(turns, turn)
+		size += len(turn)
+	}
+	for i, j := 0, len(turns)-1; i < j; i, j = i+1, j-1 {
+		turns[i], turns[j] = turns[j], turns[i]
+	}
+	return strings.Join(turns, "\n")
+}
+
+// conversationPrompt asks Claude to summarize a worker's session for the
+// people and agents who pick up after it
+func conversationPrompt(task, report, conversation string) string {
+	var b strings.Builder
+	b.WriteString("Below is the session of a coding agent that has finished its task and is about to be cleaned up. ")
+	b.WriteString("Summarize it for the team that carries on after it, so what it learned isn't lost. ")
+	b.WriteString("Use three short markdown sections, each a few bullet points: \"Done\" (what changed and where), ")
+	b.WriteString("\"Decisions\" (choices made and why, including approaches tried and dropped) and ")
+	b.WriteString("\"Follow-ups\" (what's left, risks, and anything the agent flagged). ")
+	b.WriteString("Write \"None\" under a section with nothing in it. Stay under 250 words and print only the summary.\n\n")
+	fmt.Fprintf(&b, "Task: %s\n", task)
+	if report != "" {
+		fmt.Fprintf(&b, "The agent's own completion summary: %s\n", report)
+	}
+	b.WriteString("\nSession:\n\n")
+	b.WriteString(conversation)
+	return b.String()
+}
+
+// readConversation returns the text of a worker's session: its Claude
+// transcript, or the end of its terminal log when there's none, scrubbed of
+// credentials the way exports are. It's empty when neither can be read.
+func (d *Daemon) readConversation(repoName, agentName string, agent state.Agent) string {
+	var projectsDir string
+	if configDir, err := d.claudeConfigDir(repoName); err == nil {
+		projectsDir, _ = claude.ProjectsDir(configDir)
+	}
+	redactor, err := redact.NewFromFile(d.paths.RedactFile())
+	if err != nil {
+		d.logger.Warn("Ignoring extra secret patterns: %v", err)
+	}
+	e, err := export.Collect(export.Source{
+		Repo:              repoName,
+		Name:              agentName,
+		Agent:             agent,
+		ClaudeProjectsDir: projectsDir,
+		LogFile:           d.paths.AgentLogFile(repoName, agentName, true),
+		Redactor:          redactor,
+	})
+	if err != nil {
+		return ""
+	}
+	// Only the session: it already says what the changes are, and the diff
+	// would crowd it out
+	return conversationText(e.Transcript, maxConversationBytes)
+}
+
+// summarizeConversation has Claude summarize a completed worker's session
+// before it's cleaned up: what was done, decisions made and follow-ups. The
+// session is read now, while it's where the worker left it; Claude, which
+// takes a while, runs in the background. The summary is saved on the task
+// history entry and sent to the supervisor. Failures are only logged.
+func (d *Daemon) summarizeConversation(repoName, agentName string, agent state.Agent, historyID string) {
+	conversation := d.readConversation(repoName, agentName, agent)
+	if conversation == "" {
+		d.logger.Debug("No session to summarize for %s/%s", repoName, agentName)
+		return
+	}
+	prompt := conversationPrompt(agent.Task, agent.Summary, conversation)
+
+	go func() {
+		select {
+		case d.conversationSlots <- struct{}{}:
+			defer func() { <-d.conversationSlots }()
+		case <-d.ctx.Done():
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(d.ctx, conversationSummaryTimeout)
+		defer cancel()
+		summary, err := d.summarize(ctx, d.paths.Root, prompt)
+		if err != nil {
+			d.logger.Warn("Failed to summarize the session of %s/%s: %v", repoName, agentName, err)
+			return
+		}
+		if summary == "" {
+			return
+		}
+		if err := d.state.UpdateTaskHistoryConversation(repoName, historyID, summary); err != nil {
+			d.logger.Warn("Failed to save the session summary of %s/%s: %v", repoName, agentName, err)
+		}
+		body := fmt.Sprintf("Worker '%s' has been cleaned up. Summary of its session (task: %s):\n\n%s\n\nIt is kept in the task history (%s): multiclaude history --full", agentName, agent.Task, summary, historyID)
+		if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", body); err != nil {
+			d.logger.Warn("Failed to send the session summary of %s/%s to the supervisor: %v", repoName, agentName, err)
+		} else {
+			go d.routeMessages()
+		}
+		d.logger.Info("Summarized the session of %s/%s", repoName, agentName)
+	}()
+}
@@ -2,29 +2,78 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/branchname"
+	"github.com/micheal-at/multiclaude/internal/cache"
+	"github.com/micheal-at/multiclaude/internal/commentcmd"
+	"github.com/micheal-at/multiclaude/internal/daemonconfig"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/events"
+	"github.com/micheal-at/multiclaude/internal/federation"
+	"github.com/micheal-at/multiclaude/internal/fork"
+	"github.com/micheal-at/multiclaude/internal/format"
+	"github.com/micheal-at/multiclaude/internal/ghcheck"
 	"github.com/micheal-at/multiclaude/internal/hooks"
 	"github.com/micheal-at/multiclaude/internal/logging"
+	"github.com/micheal-at/multiclaude/internal/memory"
 	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/mirror"
+	"github.com/micheal-at/multiclaude/internal/names"
+	"github.com/micheal-at/multiclaude/internal/notify"
+	"github.com/micheal-at/multiclaude/internal/outputs"
+	"github.com/micheal-at/multiclaude/internal/power"
 	"github.com/micheal-at/multiclaude/internal/prompts"
+	"github.com/micheal-at/multiclaude/internal/pushcheck"
+	"github.com/micheal-at/multiclaude/internal/reconcile"
+	"github.com/micheal-at/multiclaude/internal/redact"
+	"github.com/micheal-at/multiclaude/internal/resources"
+	"github.com/micheal-at/multiclaude/internal/routing"
+	"github.com/micheal-at/multiclaude/internal/simagent"
 	"github.com/micheal-at/multiclaude/internal/socket"
 	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/tasks"
+	"github.com/micheal-at/multiclaude/internal/templates"
+	"github.com/micheal-at/multiclaude/internal/tickets"
+	"github.com/micheal-at/multiclaude/internal/train"
+	"github.com/micheal-at/multiclaude/internal/trash"
+	"github.com/micheal-at/multiclaude/internal/workhours"
 	"github.com/micheal-at/multiclaude/internal/worktree"
+	"github.com/micheal-at/multiclaude/internal/wsl"
+	"github.com/micheal-at/multiclaude/internal/xref"
+	"github.com/micheal-at/multiclaude/internal/zombie"
 	"github.com/micheal-at/multiclaude/pkg/claude"
 	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/retry"
 	"github.com/micheal-at/multiclaude/pkg/tmux"
 )
 
+// snapshotKeep is how many state snapshots the daemon retains
+const snapshotKeep = 50
+
+// eventBacklog is how many lifecycle events the daemon keeps for watchers,
+// and maxEventWait caps how long an events request waits for a new one
+const (
+	eventBacklog = 1000
+	maxEventWait = 30 * time.Second
+)
+
 // Daemon represents the main daemon process
 type Daemon struct {
 	paths        *config.Paths
@@ -32,8 +81,124 @@ type Daemon struct {
 	tmux         *tmux.Client
 	logger       *logging.Logger
 	server       *socket.Server
+	grpcServer   interface{ Stop() } // the gRPC API, in builds with -tags grpc
+	httpServer   *http.Server
 	pidFile      *PIDFile
 	claudeRunner *claude.Runner
+	notifier     *notify.Dispatcher
+	// humanNotifiers builds the notifiers for agents waiting on a human
+	humanNotifiers func(state.NotifyConfig) ([]notify.HumanNotifier, error)
+	// tmuxRetry is the tmux client's retry policy, with the timeout from
+	// daemon.yaml, for the tmux commands the client has no method for
+	tmuxRetry retry.Policy
+
+	// settingsMu guards settings, the daemon-level config, and settingsChanged,
+	// which is closed and replaced whenever the config is reloaded so
+	// periodic loops can pick up new intervals right away
+	settingsMu      sync.RWMutex
+	settings        daemonconfig.Config
+	settingsChanged chan struct{}
+
+	// federationMu guards federationPeers and the per-repo federation store files
+	federationMu    sync.Mutex
+	federationPeers map[string][]federation.PeerStatus
+
+	// standbyMu guards the standby fields. standbyHeld is set when the user
+	// resumes while on battery, so automatic standby waits for the next unplug.
+	standbyMu     sync.Mutex
+	standby       bool
+	standbyReason string
+	standbyHeld   bool
+	onBattery     func() (bool, error)
+
+	// githubMu guards githubStatus: whether gh can be used, from checkGitHub.
+	// GitHub features are off while it can't.
+	githubMu     sync.Mutex
+	githubStatus ghcheck.Status
+	checkGitHub  func(ctx context.Context) ghcheck.Status
+
+	// quotaMu guards spawning: the agents spawn_agent and add_agent are
+	// starting, keyed by repository and name. They count towards max-instances
+	// and worker limits before they're registered.
+	quotaMu  sync.Mutex
+	spawning map[string]reservation
+
+	// Caches for gh/git lookups that listings would otherwise repeat per agent
+	prCache     *cache.Cache[map[string]pullRequest]
+	statusCache *cache.Cache[worktree.Status]
+	listPRs     func(repoPath string) ([]pullRequest, error)
+	markPRReady func(repoPath string, number int) error
+
+	// Writing workers' PR descriptions: Claude summarizes the diff, gh sets
+	// the body. Claude also summarizes completed workers' sessions.
+	summarize  func(ctx context.Context, dir, prompt string) (string, error)
+	editPRBody func(repoPath string, number int, body string) error
+	// conversationSlots bounds how many sessions are summarized at once
+	conversationSlots chan struct{}
+
+	// GitHub comment access for the comment-command bridge
+	listComments   func(owner, repo string, since time.Time) ([]commentcmd.Comment, error)
+	reactToComment func(owner, repo string, id int64, reaction string) error
+
+	// zombies tracks agents' panes between health checks to spot stuck agents
+	zombies *zombie.Tracker
+
+	// refreshMu guards refreshNotified: how far behind each fetch-only worker
+	// was when last told, so it isn't told again until it falls further behind,
+	// and refreshing
+	refreshMu       sync.Mutex
+	refreshNotified map[string]int
+	// refreshing holds the worktrees being refreshed, by the refresh loop or
+	// `multiclaude sync`, so the two don't rebase one worktree at once
+	refreshing map[string]bool
+
+	// hoursMu guards offHoursRepos, the repositories last seen outside their
+	// work hours, so the daemon logs when each stops and starts working
+	hoursMu       sync.Mutex
+	offHoursRepos map[string]bool
+
+	// memoryMu serializes changes to agents' memory files and the memory
+	// sections of their saved prompts
+	memoryMu sync.Mutex
+
+	// rosters holds each repository's roster as last written, so agents'
+	// prompts are only rewritten when it changes. Only rosterLoop uses it.
+	rosters map[string]string
+
+	// integrity tracks discrepancies between state and live resources across
+	// checks. integrityMu keeps checks from overlapping, which would count
+	// one sighting twice.
+	integrityMu sync.Mutex
+	integrity   *reconcile.Tracker
+
+	// usage turns process table snapshots into agents' CPU and memory use
+	usage     *resources.Sampler
+	machine   resources.Machine
+	processes func() (*resources.Table, error)
+
+	// ticketsMu guards the ask/answer tickets file
+	ticketsMu sync.Mutex
+
+	// tasksMu guards the queued tasks file. startWorker creates a worker on a
+	// task whose prerequisites are done.
+	tasksMu     sync.Mutex
+	startWorker func(repo, name, task string) error
+
+	// trainMu guards the merge train files. simulate runs a PR's simulation.
+	trainMu sync.Mutex
+	// xrefMu guards the cross-reference index files
+	xrefMu   sync.Mutex
+	simulate func(ctx context.Context, sim train.Simulation, ahead []train.Entry, pr train.PR) error
+
+	// events records lifecycle changes for `multiclaude watch`
+	events *events.Bus
+
+	// debug records loop timings and errors for `multiclaude daemon debug`
+	debug *debugStats
+
+	// ready is set once Start has restored agents and started the loops,
+	// for /readyz
+	ready atomic.Bool
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -52,6 +217,20 @@ func New(paths *config.Paths) (*Daemon, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
+	// Agents echo environment variables; keep their tokens out of the log
+	redactor, err := redact.NewFromFile(paths.RedactFile())
+	logger.SetScrubber(redactor.Secrets)
+	if err != nil {
+		logger.Warn("Ignoring extra secret patterns: %v", err)
+	}
+
+	settings, err := daemonconfig.Load(paths.DaemonConfigFile())
+	if err != nil {
+		logger.Error("Ignoring daemon config, using defaults: %v", err)
+		settings = daemonconfig.Default()
+	}
+	logger.SetLevel(settings.LogLevel)
+	retry.SetGitTimeouts(settings.Timeouts.Git, settings.Timeouts.GitRemote)
 
 	// Load or create state
 	st, err := state.Load(paths.StateFile)
@@ -59,26 +238,88 @@ func New(paths *config.Paths) (*Daemon, error) {
 		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
 
+	// Repositories may keep their worktrees under a base directory of their own
+	paths.WorktreeBase = func(repoName string) string {
+		cfg, _ := st.GetWorktreeConfig(repoName)
+		return cfg.Base
+	}
+
+	transport, err := Transport()
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	tmuxClient := tmux.NewClient()
+	tmuxRetry := retry.Tmux
+	tmuxRetry.Timeout = settings.Timeouts.Tmux
+	tmuxClient := tmux.NewClient(tmux.WithRetry(tmuxRetry))
 	d := &Daemon{
-		paths:        paths,
-		state:        st,
-		tmux:         tmuxClient,
-		logger:       logger,
-		pidFile:      NewPIDFile(paths.DaemonPID),
-		claudeRunner: claude.NewRunner(claude.WithTerminal(tmuxClient)),
-		ctx:          ctx,
-		cancel:       cancel,
-	}
+		paths:             paths,
+		state:             st,
+		tmux:              tmuxClient,
+		tmuxRetry:         tmuxRetry,
+		logger:            logger,
+		pidFile:           NewPIDFile(paths.DaemonPID),
+		claudeRunner:      claude.NewRunner(claude.WithTerminal(tmuxClient)),
+		notifier:          notify.NewDispatcher(),
+		humanNotifiers:    notify.HumanNotifiers,
+		simulate:          runSimulation,
+		onBattery:         power.OnBattery,
+		prCache:           cache.New[map[string]pullRequest](prStatusCacheTTL),
+		statusCache:       cache.New[worktree.Status](statusCacheTTL),
+		zombies:           zombie.NewTracker(),
+		refreshNotified:   make(map[string]int),
+		refreshing:        make(map[string]bool),
+		offHoursRepos:     make(map[string]bool),
+		rosters:           make(map[string]string),
+		conversationSlots: make(chan struct{}, conversationConcurrency),
+		integrity:         reconcile.NewTracker(),
+		usage:             resources.NewSampler(),
+		machine:           resources.DetectMachine(),
+		processes:         resources.Snapshot,
+		events:            events.NewBus(eventBacklog),
+		debug:             newDebugStats(),
+		settings:          settings,
+		settingsChanged:   make(chan struct{}),
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+	d.checkGitHub = ghcheck.Check
+	d.listPRs = d.listPullRequests
+	d.markPRReady = d.markPullRequestReady
+	d.summarize = d.summarizeWithClaude
+	d.editPRBody = d.editPullRequestBody
+	d.listComments = d.listRepoComments
+	d.reactToComment = d.reactToCommentOnGitHub
+	d.startWorker = d.createWorker
+	d.notifier.OnError = func(err error) { d.subsystemError("notify", "%v", err) }
 
 	// Create socket server
 	d.server = socket.NewServer(paths.DaemonSock, socket.HandlerFunc(d.handleRequest))
+	d.server.SetTransport(transport)
 
 	return d, nil
 }
 
+// Transport returns how the daemon listens for requests: MULTICLAUDE_TRANSPORT
+// if set, otherwise a Unix socket with a TCP loopback fallback under WSL
+// (where ~/.multiclaude may be on a Windows filesystem without Unix sockets),
+// and a Unix socket elsewhere
+func Transport() (socket.Transport, error) {
+	if env := os.Getenv("MULTICLAUDE_TRANSPORT"); env != "" {
+		transport, err := socket.ParseTransport(env)
+		if err != nil {
+			return "", fmt.Errorf("MULTICLAUDE_TRANSPORT: %w", err)
+		}
+		return transport, nil
+	}
+	if wsl.Detect() {
+		return socket.TransportAuto, nil
+	}
+	return socket.TransportUnix, nil
+}
+
 // Start starts the daemon
 func (d *Daemon) Start() error {
 	d.logger.Info("Starting daemon")
@@ -93,21 +334,44 @@ func (d *Daemon) Start() error {
 		return fmt.Errorf("failed to start socket server: %w", err)
 	}
 
-	d.logger.Info("Socket server started at %s", d.paths.DaemonSock)
+	d.logger.Info("Socket server started at %s", d.server.Address())
+
+	if err := d.startGRPC(); err != nil {
+		d.server.Stop()
+		return fmt.Errorf("failed to start gRPC API: %w", err)
+	}
+	if err := d.startHTTP(); err != nil {
+		d.server.Stop()
+		d.stopGRPC()
+		return fmt.Errorf("failed to start HTTP server: %w", err)
+	}
 
 	d.logger.Info("Daemon started successfully")
 
+	d.refreshGitHubStatus()
+
+	// Answer requests while agents are restored, which can take minutes, so
+	// the watchdog's pings don't go unanswered and restart the daemon midway
+	d.wg.Add(1)
+	go d.serverLoop()
+
 	// Restore agents for tracked repos BEFORE starting health checks
 	// This prevents race conditions where health check cleans up agents being restored
 	d.restoreTrackedRepos()
 
 	// Start core loops after restore completes
-	d.wg.Add(5)
+	d.wg.Add(10)
 	go d.healthCheckLoop()
 	go d.messageRouterLoop()
 	go d.wakeLoop()
-	go d.serverLoop()
 	go d.worktreeRefreshLoop()
+	go d.federationLoop()
+	go d.powerLoop()
+	go d.mergeTrainLoop()
+	go d.rosterLoop()
+	go d.commentCommandsLoop()
+	go d.repoConfigLoop()
+	d.ready.Store(true)
 
 	return nil
 }
@@ -156,6 +420,8 @@ func (d *Daemon) Stop() error {
 	if err := d.server.Stop(); err != nil {
 		d.logger.Error("Failed to stop socket server: %v", err)
 	}
+	d.stopGRPC()
+	d.stopHTTP()
 
 	// Save state
 	if err := d.state.Save(); err != nil {
@@ -179,30 +445,162 @@ func getRequiredStringArg(args map[string]interface{}, key, description string)
 		return "", socket.Response{
 			Success: false,
 			Error:   fmt.Sprintf("missing '%s': %s", key, description),
+			Code:    string(errors.CodeMissingArgument),
 		}, false
 	}
 	return val, socket.Response{}, true
 }
 
-// periodicLoop runs a function periodically at the specified interval.
+// errorResponse reports err as a failed response, with its code and
+// remediation so the CLI can show what to do next
+func errorResponse(err error) socket.Response {
+	details := errors.DetailsOf(err)
+	message := details.Message
+	if details.Cause != "" {
+		message += ": " + details.Cause
+	}
+	return socket.Response{Success: false, Error: message, Code: string(details.Code), Remediation: details.Remediation}
+}
+
+// currentSettings returns the daemon's settings and a channel that is closed
+// when they are next reloaded
+func (d *Daemon) currentSettings() (daemonconfig.Config, <-chan struct{}) {
+	d.settingsMu.RLock()
+	defer d.settingsMu.RUnlock()
+	return d.settings, d.settingsChanged
+}
+
+// interval returns a function giving one of the configured loop intervals
+func (d *Daemon) interval(get func(daemonconfig.Intervals) time.Duration) func() time.Duration {
+	return func() time.Duration {
+		settings, _ := d.currentSettings()
+		return get(settings.Intervals)
+	}
+}
+
+// withTimeout returns a context for one iteration of a loop's work, ended
+// when the daemon stops or after one of the configured timeouts
+func (d *Daemon) withTimeout(get func(daemonconfig.Timeouts) time.Duration) (context.Context, context.CancelFunc) {
+	settings, _ := d.currentSettings()
+	return context.WithTimeout(d.ctx, get(settings.Timeouts))
+}
+
+// github returns the retry policy for gh commands, with the timeout from
+// daemon.yaml
+func (d *Daemon) github() retry.Policy {
+	settings, _ := d.currentSettings()
+	policy := retry.GitHub
+	policy.Timeout = settings.Timeouts.GitHub
+	return policy
+}
+
+// git runs a git command under its retry policy, stopping when the daemon
+// does, and returns its combined output
+func (d *Daemon) git(args ...string) ([]byte, error) {
+	return retry.ForGit(args...).CombinedOutput(d.ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "git", args...)
+	})
+}
+
+// tmuxCommand runs a tmux command the tmux client has no method for, such as
+// one that sets a new window's directory, under the client's retry policy
+func (d *Daemon) tmuxCommand(args ...string) error {
+	out, err := d.tmuxRetry.CombinedOutput(d.ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "tmux", args...)
+	})
+	if msg := strings.TrimSpace(string(out)); err != nil && msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// notificationsMuted reports whether daemon.yaml holds back notifications
+func (d *Daemon) notificationsMuted() bool {
+	settings, _ := d.currentSettings()
+	return settings.Notify.Muted
+}
+
+// reloadConfig rereads daemon.yaml and applies it without restarting agents.
+// An invalid file is logged and the current settings are kept. source says
+// what asked for the reload, for the log and the config_reloaded event.
+func (d *Daemon) reloadConfig(source string) ([]daemonconfig.Change, error) {
+	settings, err := daemonconfig.Load(d.paths.DaemonConfigFile())
+	if err != nil {
+		d.logger.Error("Config reload (%s) failed, keeping current settings: %v", source, err)
+		return nil, err
+	}
+	if err := d.logger.SetLevel(settings.LogLevel); err != nil {
+		return nil, err
+	}
+	retry.SetGitTimeouts(settings.Timeouts.Git, settings.Timeouts.GitRemote)
+
+	d.settingsMu.Lock()
+	changes := daemonconfig.Diff(d.settings, settings)
+	d.settings = settings
+	close(d.settingsChanged)
+	d.settingsChanged = make(chan struct{})
+	d.settingsMu.Unlock()
+
+	data := map[string]string{"source": source}
+	for _, change := range changes {
+		data[change.Key] = change.Old + " -> " + change.New
+		d.logger.Info("Config reload (%s): %s", source, change)
+	}
+	if len(changes) == 0 {
+		d.logger.Info("Config reload (%s): no changes", source)
+	}
+	for _, change := range changes {
+		if strings.HasPrefix(change.Key, "grpc.") {
+			d.logger.Warn("Config reload (%s): gRPC settings take effect when the daemon restarts", source)
+			break
+		}
+	}
+	for _, change := range changes {
+		if change.Key == "http.address" {
+			d.logger.Warn("Config reload (%s): the HTTP address takes effect when the daemon restarts", source)
+		}
+	}
+	for _, change := range changes {
+		if change.Key == "timeouts.tmux" {
+			d.logger.Warn("Config reload (%s): the tmux timeout takes effect when the daemon restarts", source)
+		}
+	}
+	d.events.Publish(events.EventConfigReloaded, "", "", data)
+	// A reload is also how to tell the daemon gh has been set up
+	d.refreshGitHubStatus()
+	return changes, nil
+}
+
+// periodicLoop runs a function periodically at the interval returned by
+// interval, which is asked again whenever the daemon's settings are reloaded.
 // If onStartup is provided, it's called immediately before entering the loop.
 // The onTick function is called on each timer tick.
-func (d *Daemon) periodicLoop(name string, interval time.Duration, onStartup, onTick func()) {
+func (d *Daemon) periodicLoop(name string, interval func() time.Duration, onStartup, onTick func()) {
 	defer d.wg.Done()
 	d.logger.Info("Starting %s loop", name)
 
-	ticker := time.NewTicker(interval)
+	current := interval()
+	ticker := time.NewTicker(current)
 	defer ticker.Stop()
+	d.debug.expect(name, current, time.Now())
 
 	// Run startup tasks if provided
 	if onStartup != nil {
-		onStartup()
+		d.debug.time(name, onStartup)
 	}
 
 	for {
+		_, changed := d.currentSettings()
 		select {
 		case <-ticker.C:
-			onTick()
+			d.debug.time(name, onTick)
+		case <-changed:
+			if next := interval(); next != current {
+				d.logger.Info("%s loop now runs every %s", name, next)
+				current = next
+				ticker.Reset(current)
+				d.debug.expect(name, current, time.Now())
+			}
 		case <-d.ctx.Done():
 			d.logger.Info("%s loop stopped", name)
 			return
@@ -210,6 +608,135 @@ func (d *Daemon) periodicLoop(name string, interval time.Duration, onStartup, on
 	}
 }
 
+// standbySlowdown is how many times less often periodic loops run in standby
+const standbySlowdown = 5
+
+// powerCheckInterval is how often the power source is checked for automatic standby
+const powerCheckInterval = time.Minute
+
+// slowInStandby wraps a periodic task so that, while the daemon is in standby,
+// it runs only every standbySlowdown intervals
+func (d *Daemon) slowInStandby(interval func() time.Duration, fn func()) func() {
+	var last time.Time
+	return func() {
+		if d.inStandby() && time.Since(last) < interval()*standbySlowdown {
+			return
+		}
+		last = time.Now()
+		fn()
+	}
+}
+
+// inStandby reports whether the daemon is in standby
+func (d *Daemon) inStandby() bool {
+	d.standbyMu.Lock()
+	defer d.standbyMu.Unlock()
+	return d.standby
+}
+
+// powerLoop enters standby when the machine goes on battery and leaves it when
+// external power returns
+func (d *Daemon) powerLoop() {
+	startup := func() {
+		// Agents paused by a previous daemon would otherwise stay stopped forever
+		d.resumePausedAgents()
+		d.checkPowerSource()
+	}
+	d.periodicLoop("power", func() time.Duration { return powerCheckInterval }, startup, d.checkPowerSource)
+}
+
+// checkPowerSource applies automatic standby for the current power source
+func (d *Daemon) checkPowerSource() {
+	onBattery, err := d.onBattery()
+	if err != nil {
+		d.logger.Debug("Cannot read power source: %v", err)
+		return
+	}
+
+	d.standbyMu.Lock()
+	defer d.standbyMu.Unlock()
+
+	switch {
+	case onBattery && !d.standby && !d.standbyHeld:
+		d.enterStandbyLocked("battery")
+	case !onBattery:
+		d.standbyHeld = false
+		if d.standby && d.standbyReason == "battery" {
+			d.exitStandbyLocked()
+		}
+	}
+}
+
+// enterStandbyLocked slows the periodic loops and pauses agents that aren't
+// needed while the user is away from power. Caller holds standbyMu.
+func (d *Daemon) enterStandbyLocked(reason string) {
+	d.standby = true
+	d.standbyReason = reason
+	d.logger.Info("Entering standby (%s): slowing loops %dx and pausing non-critical agents", reason, standbySlowdown)
+
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if !pausedInStandby(agent.Type) || agent.Paused || agent.PID <= 0 || !isProcessAlive(agent.PID) {
+				continue
+			}
+			if err := signalPaneProcesses(agent.PID, syscall.SIGSTOP); err != nil {
+				d.logger.Warn("Failed to pause agent %s/%s: %v", repoName, agentName, err)
+				continue
+			}
+			agent.Paused = true
+			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+				d.logger.Error("Failed to record pause of agent %s/%s: %v", repoName, agentName, err)
+			}
+			d.logger.Info("Paused agent %s/%s for standby", repoName, agentName)
+		}
+	}
+}
+
+// exitStandbyLocked restores normal operation. Caller holds standbyMu.
+func (d *Daemon) exitStandbyLocked() {
+	d.logger.Info("Leaving standby (%s)", d.standbyReason)
+	d.standby = false
+	d.standbyReason = ""
+	d.resumePausedAgents()
+
+	// Catch up on messages held back while agents were paused
+	go d.routeMessages()
+}
+
+// resumePausedAgents continues every agent paused for standby
+func (d *Daemon) resumePausedAgents() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if !agent.Paused {
+				continue
+			}
+			if agent.PID > 0 && isProcessAlive(agent.PID) {
+				if err := signalPaneProcesses(agent.PID, syscall.SIGCONT); err != nil {
+					d.logger.Warn("Failed to resume agent %s/%s: %v", repoName, agentName, err)
+					continue
+				}
+			}
+			agent.Paused = false
+			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+				d.logger.Error("Failed to record resume of agent %s/%s: %v", repoName, agentName, err)
+			}
+			d.logger.Info("Resumed agent %s/%s", repoName, agentName)
+		}
+	}
+}
+
+// pausedInStandby reports whether an agent type is stopped during standby.
+// Supervisors, workspaces, workers and reviews keep running; the merge queue,
+// PR shepherd, observers and custom persistent agents mostly poll and can wait.
+func pausedInStandby(t state.AgentType) bool {
+	switch t {
+	case state.AgentTypeMergeQueue, state.AgentTypePRShepherd, state.AgentTypeGenericPersistent, state.AgentTypeObserver:
+		return true
+	default:
+		return false
+	}
+}
+
 // serverLoop handles socket connections
 func (d *Daemon) serverLoop() {
 	defer d.wg.Done()
@@ -224,7 +751,7 @@ func (d *Daemon) serverLoop() {
 	select {
 	case err := <-errCh:
 		if err != nil {
-			d.logger.Error("Server error: %v", err)
+			d.subsystemError("server", "Server error: %v", err)
 		}
 	case <-d.ctx.Done():
 		d.logger.Info("Server loop stopped")
@@ -233,12 +760,28 @@ func (d *Daemon) serverLoop() {
 
 // healthCheckLoop periodically checks agent health
 func (d *Daemon) healthCheckLoop() {
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.HealthCheck })
 	startup := func() {
+		// Snapshot before health checks so agents they remove can be rolled back
+		d.snapshotState("periodic")
+		d.recheckGitHub()
 		d.checkAgentHealth()
-		d.rotateLogsIfNeeded()
-		d.cleanupMergedBranches()
+		d.sampleResources()
+		d.checkIntegrity()
+		d.advanceTasks()
+		// Timed on its own, as it's the part of the health check that
+		// touches the disk and git the most
+		d.debug.expect("cleanup", interval(), time.Now())
+		d.debug.time("cleanup", func() {
+			d.rotateLogsIfNeeded()
+			d.cleanOutputs()
+			d.expireTrash()
+			d.cleanMemory()
+			d.cleanupMergedBranches()
+		})
+		d.flushNotifications()
 	}
-	d.periodicLoop("health check", 2*time.Minute, startup, startup)
+	d.periodicLoop("health check", interval, startup, d.slowInStandby(interval, startup))
 }
 
 // checkAgentHealth checks if agents are still alive
@@ -253,7 +796,19 @@ func (d *Daemon) checkAgentHealth() {
 		// Check if tmux session exists
 		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
 		if err != nil {
-			d.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
+			d.subsystemError("health check", "Failed to check session %s: %v", repo.TmuxSession, err)
+			continue
+		}
+
+		if !hasSession && repo.Solo {
+			// Solo agents are one-off: when their session is gone, so are they
+			d.logger.Info("Tmux session %s for solo repo %s is gone, cleaning up its agents", repo.TmuxSession, repoName)
+			for agentName := range repo.Agents {
+				appendToSliceMap(deadAgents, repoName, agentName)
+			}
+			if len(repo.Agents) == 0 {
+				d.removeSoloRepo(repoName, repo)
+			}
 			continue
 		}
 
@@ -261,7 +816,7 @@ func (d *Daemon) checkAgentHealth() {
 			d.logger.Warn("Tmux session %s not found for repo %s, attempting restoration", repo.TmuxSession, repoName)
 			// Try to restore the session and agents instead of cleaning up
 			if err := d.restoreRepoAgents(repoName, repo); err != nil {
-				d.logger.Error("Failed to restore repo %s: %v, marking all agents for cleanup", repoName, err)
+				d.subsystemError("health check", "Failed to restore repo %s: %v, marking all agents for cleanup", repoName, err)
 				// Only mark for cleanup if restoration failed
 				for agentName := range repo.Agents {
 					appendToSliceMap(deadAgents, repoName, agentName)
@@ -284,12 +839,13 @@ func (d *Daemon) checkAgentHealth() {
 			// Check if window exists
 			hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
 			if err != nil {
-				d.logger.Error("Failed to check window %s: %v", agent.TmuxWindow, err)
+				d.subsystemError("health check", "Failed to check window %s: %v", agent.TmuxWindow, err)
 				continue
 			}
 
 			if !hasWindow {
 				d.logger.Warn("Agent %s window not found, marking for cleanup", agentName)
+				d.notifyAgentCrash(repoName, repo, agentName, "tmux window disappeared; agent removed from state")
 				appendToSliceMap(deadAgents, repoName, agentName)
 				continue
 			}
@@ -302,15 +858,27 @@ func (d *Daemon) checkAgentHealth() {
 					// For persistent agents, attempt auto-restart
 					if agent.Type.IsPersistent() {
 						d.logger.Info("Attempting to auto-restart agent %s", agentName)
-						if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-							d.logger.Error("Failed to restart agent %s: %v", agentName, err)
+						if err := d.restartAgent("", repoName, agentName, agent, repo); err != nil {
+							d.subsystemError("health check", "Failed to restart agent %s: %v", agentName, err)
+							d.notifyAgentCrash(repoName, repo, agentName, fmt.Sprintf("process (PID %d) exited and restart failed: %v", agent.PID, err))
 						} else {
+							// Recovered on its own: an event, but no email
 							d.logger.Info("Successfully restarted agent %s", agentName)
+							d.events.Publish(events.EventAgentDied, repoName, agentName, map[string]string{"detail": fmt.Sprintf("process (PID %d) exited and was restarted", agent.PID)})
 						}
+					} else {
+						d.notifyAgentCrash(repoName, repo, agentName, fmt.Sprintf("process (PID %d) exited before the agent completed", agent.PID))
 					}
 					// For transient agents (workers, review), don't auto-restart - they complete and clean up
+					continue
 				}
 			}
+
+			// The process is alive; check that it is still making progress.
+			// Workspaces wait on the user, and paused agents are idle on purpose.
+			if agent.PID > 0 && agent.Type != state.AgentTypeWorkspace && !agent.Paused {
+				d.checkZombie(repoName, agentName, agent, repo)
+			}
 		}
 	}
 
@@ -319,1051 +887,5752 @@ func (d *Daemon) checkAgentHealth() {
 		d.cleanupDeadAgents(deadAgents)
 	}
 
+	// Project supervisors live outside any repository's session
+	d.checkProjectSupervisors()
+
 	// Clean up orphaned worktrees
 	d.cleanupOrphanedWorktrees()
-}
 
-// messageRouterLoop watches for new messages and delivers them
-func (d *Daemon) messageRouterLoop() {
-	d.periodicLoop("message router", 2*time.Minute, nil, d.routeMessages)
+	// Move reviews off reviewers that are gone, paused or crashed
+	d.rebalanceReviews()
 }
 
-// routeMessages checks for pending messages and delivers them
-func (d *Daemon) routeMessages() {
-	d.logger.Debug("Routing messages")
-
-	// Get messages manager
-	msgMgr := d.getMessageManager()
+// sampleResources records the CPU and memory use of each agent's process
+// tree and alerts when an agent's tooling is hogging the machine
+func (d *Daemon) sampleResources() {
+	table, err := d.processes()
+	if err != nil {
+		d.logger.Debug("Failed to sample agent resources: %v", err)
+		return
+	}
 
-	// Get a snapshot of repos to avoid concurrent map access
 	repos := d.state.GetAllRepos()
-
-	// Check each repository
+	roots := make(map[string]int)
 	for repoName, repo := range repos {
-		// Check each agent for messages
 		for agentName, agent := range repo.Agents {
-			// Skip workspace agent - it should only receive direct user input
-			if agent.Type == state.AgentTypeWorkspace {
-				continue
-			}
-
-			// Get unread messages (pending or delivered but not yet read)
-			unreadMsgs, err := msgMgr.ListUnread(repoName, agentName)
-			if err != nil {
-				d.logger.Error("Failed to list messages for %s/%s: %v", repoName, agentName, err)
-				continue
-			}
-
-			// Deliver each pending message
-			for _, msg := range unreadMsgs {
-				if msg.Status != messages.StatusPending {
-					// Already delivered, skip
-					continue
-				}
-
-				// Format message for delivery
-				messageText := fmt.Sprintf("📨 Message from %s: %s", msg.From, msg.Body)
-
-				// Send via tmux using atomic method to avoid race conditions
-				// where Enter might be lost between separate exec calls (issue #63)
-				if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, messageText); err != nil {
-					d.logger.Error("Failed to deliver message %s to %s/%s: %v", msg.ID, repoName, agentName, err)
-					continue
-				}
-
-				// Mark as delivered
-				if err := msgMgr.UpdateStatus(repoName, agentName, msg.ID, messages.StatusDelivered); err != nil {
-					d.logger.Error("Failed to update message %s status: %v", msg.ID, err)
-					continue
-				}
-
-				d.logger.Info("Delivered message %s from %s to %s/%s", msg.ID, msg.From, repoName, agentName)
+			if agent.PID > 0 {
+				roots[repoName+"/"+agentName] = agent.PID
 			}
 		}
 	}
-}
-
-// getMessageManager returns a message manager instance
-func (d *Daemon) getMessageManager() *messages.Manager {
-	return messages.NewManager(d.paths.MessagesDir)
-}
-
-// wakeLoop periodically wakes agents with status checks
-func (d *Daemon) wakeLoop() {
-	d.periodicLoop("wake", 2*time.Minute, nil, d.wakeAgents)
-}
-
-// wakeAgents sends periodic nudges to agents
-func (d *Daemon) wakeAgents() {
-	d.logger.Debug("Waking agents")
-
 	now := time.Now()
+	usage, cpuKnown := d.usage.Sample(table, roots, now)
 
-	// Get a snapshot of repos to avoid concurrent map access
-	repos := d.state.GetAllRepos()
 	for repoName, repo := range repos {
+		updates := make(map[string]state.ResourceStats)
 		for agentName, agent := range repo.Agents {
-			// Skip workspace agent - it should only receive direct user input
-			if agent.Type == state.AgentTypeWorkspace {
-				continue
-			}
-
-			// Skip if nudged recently (within last 2 minutes)
-			if !agent.LastNudge.IsZero() && now.Sub(agent.LastNudge) < 2*time.Minute {
+			u, ok := usage[repoName+"/"+agentName]
+			if !ok {
 				continue
 			}
-
-			// Send wake message based on agent type
-			var message string
-			switch agent.Type {
-			case state.AgentTypeSupervisor:
-				message = "Status check: Review worker progress and check merge queue."
-			case state.AgentTypeMergeQueue:
-				message = "Status check: Review open PRs and check CI status."
-			case state.AgentTypePRShepherd:
-				message = "Status check: Review PRs on upstream, check CI status, and rebase branches if needed."
-			case state.AgentTypeWorker:
-				message = "Status check: Update on your progress?"
-			case state.AgentTypeReview:
-				message = "Status check: Update on your review progress?"
-			case state.AgentTypeGenericPersistent:
-				message = "Status check: Update on your progress?"
+			var prev state.ResourceStats
+			if agent.Resources != nil {
+				prev = *agent.Resources
 			}
-
-			// Send message using atomic method to avoid race conditions (issue #63)
-			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, message); err != nil {
-				d.logger.Error("Failed to send wake message to agent %s: %v", agentName, err)
-				continue
+			stats, alert := resources.Record(prev, u, cpuKnown, d.machine, now)
+			updates[agentName] = stats
+			if alert != "" {
+				d.alertResources(repoName, agentName, alert)
 			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := d.state.UpdateAgentResources(repoName, updates); err != nil {
+			d.logger.Error("Failed to record resources for repo %s: %v", repoName, err)
+		}
+	}
+}
 
-			// Update last nudge time
-			agent.LastNudge = now
-			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
-				d.logger.Error("Failed to update agent %s last nudge: %v", agentName, err)
-			}
+// alertResources tells the human (by email when notifications are enabled)
+// and the supervisor that an agent's processes are hogging the machine
+func (d *Daemon) alertResources(repoName, agentName, detail string) {
+	d.logger.Warn("Agent %s/%s is %s", repoName, agentName, detail)
+	d.events.Publish(events.EventResourceAlert, repoName, agentName, map[string]string{"detail": detail})
 
-			d.logger.Debug("Woke agent %s in repo %s", agentName, repoName)
+	body := fmt.Sprintf("Agent %s's processes are %s. A runaway test or build may be slowing every agent down. Check on it with: multiclaude attach %s", agentName, detail, agentName)
+	msgMgr := d.getMessageManager()
+	for _, to := range []string{notify.HumanRecipient, "supervisor"} {
+		if to == agentName {
+			continue
+		}
+		if _, err := msgMgr.Send(repoName, "daemon", to, body); err != nil {
+			d.logger.Error("Failed to send resource alert for %s/%s to %s: %v", repoName, agentName, to, err)
 		}
 	}
 }
 
-// worktreeRefreshLoop periodically syncs worker worktrees with main branch
-func (d *Daemon) worktreeRefreshLoop() {
-	defer d.wg.Done()
-	d.logger.Info("Starting worktree refresh loop")
+// zombieNudges are the texts sent to stuck agents whose remediation is
+// "nudge". They start with the locale's health check marker so package
+// zombie doesn't count them as output.
+var zombieNudges = map[zombie.Kind]string{
+	zombie.Stalled: "nudge.stalled",
+	zombie.Looping: "nudge.looping",
+}
 
-	// Run every 5 minutes
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+// text returns an operator- or agent-facing text in the configured locale
+// (see templates.Text)
+func (d *Daemon) text(key string, data interface{}) string {
+	settings, _ := d.currentSettings()
+	return templates.Text(settings.Locale, key, data)
+}
 
-	// Run once after a short delay on startup (respecting context cancellation)
-	select {
-	case <-time.After(30 * time.Second):
-		d.refreshWorktrees()
-	case <-d.ctx.Done():
-		d.logger.Info("Worktree refresh loop stopped")
+// checkZombie classifies a live agent from a capture of its window. It
+// announces the agent when it starts waiting on a human and, unless zombie
+// detection is off, remediates it if it is stuck (see package zombie).
+func (d *Daemon) checkZombie(repoName, agentName string, agent state.Agent, repo *state.Repository) {
+	pane, err := d.tmux.CapturePane(d.ctx, repo.TmuxSession, agent.TmuxWindow, zombie.HistoryLines)
+	if err != nil {
+		d.logger.Debug("Failed to capture %s/%s for zombie check: %v", repoName, agentName, err)
 		return
 	}
 
-	for {
-		select {
-		case <-ticker.C:
-			d.refreshWorktrees()
-		case <-d.ctx.Done():
-			d.logger.Info("Worktree refresh loop stopped")
+	if waiting, started := d.zombies.WaitingOnHuman(repoName+"/"+agentName, pane); started {
+		d.announceNeedsHuman(repoName, agentName, agent, repo, waiting)
+	}
+	if repo.ZombieConfig.Disabled {
+		// Keep the agent's last output current for pause_active refreshes
+		d.zombies.Record(repoName+"/"+agentName, pane, time.Now())
+		return
+	}
+
+	cfg := agentZombieConfig(repo.ZombieConfig, agent.Type)
+	kind, action := d.zombies.Observe(repoName+"/"+agentName, pane, time.Now(), cfg)
+	if action == "" {
+		return
+	}
+	detail := zombie.Describe(kind, cfg)
+	d.logger.Warn("Agent %s/%s looks stuck (%s); remediation: %s", repoName, agentName, detail, action)
+
+	switch action {
+	case state.ZombieActionNudge:
+		if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, d.text(zombieNudges[kind], nil)); err != nil {
+			d.logger.Error("Failed to nudge stuck agent %s/%s: %v", repoName, agentName, err)
+		}
+	case state.ZombieActionRestart:
+		if err := stopPaneProcesses(agent.PID); err != nil {
+			d.logger.Error("Failed to stop stuck agent %s/%s: %v", repoName, agentName, err)
+			d.escalateZombie(repoName, agentName, fmt.Sprintf("%s, and stopping it failed: %v", detail, err))
+			return
+		}
+		if err := d.restartAgent("", repoName, agentName, agent, repo); err != nil {
+			d.logger.Error("Failed to restart stuck agent %s/%s: %v", repoName, agentName, err)
+			d.escalateZombie(repoName, agentName, fmt.Sprintf("%s, and restarting it failed: %v", detail, err))
 			return
 		}
+		d.logger.Info("Restarted stuck agent %s/%s", repoName, agentName)
+	case state.ZombieActionEscalate:
+		d.escalateZombie(repoName, agentName, detail)
 	}
 }
 
-// refreshWorktrees syncs worker worktrees that are behind main
-func (d *Daemon) refreshWorktrees() {
-	d.logger.Debug("Checking worker worktrees for refresh")
-
-	repos := d.state.GetAllRepos()
-	for repoName, repo := range repos {
-		repoPath := d.paths.RepoDir(repoName)
+// agentZombieConfig returns the zombie settings that apply to an agent of
+// type t. Persistent agents such as the supervisor and merge queue can sit
+// idle for hours waiting on work, so going quiet isn't a stall for them; they
+// are still remediated when they loop or stop at a permission prompt.
+func agentZombieConfig(cfg state.ZombieConfig, t state.AgentType) state.ZombieConfig {
+	if t.IsPersistent() {
+		cfg.Stalled = state.ZombieActionIgnore
+	}
+	return cfg
+}
 
-		// Check if repo path exists
-		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+// escalateZombie tells the human (by email when notifications are enabled)
+// and the supervisor that an agent is stuck
+func (d *Daemon) escalateZombie(repoName, agentName, detail string) {
+	body := fmt.Sprintf("Agent %s looks stuck: %s. Check on it with: multiclaude attach %s", agentName, detail, agentName)
+	msgMgr := d.getMessageManager()
+	for _, to := range []string{notify.HumanRecipient, "supervisor"} {
+		if to == agentName {
 			continue
 		}
-
-		wt := worktree.NewManager(repoPath)
-
-		// Get the upstream remote and default branch
-		remote, err := wt.GetUpstreamRemote()
-		if err != nil {
-			d.logger.Debug("Could not get remote for %s: %v", repoName, err)
-			continue
+		if _, err := msgMgr.Send(repoName, "daemon", to, body); err != nil {
+			d.logger.Error("Failed to escalate stuck agent %s/%s to %s: %v", repoName, agentName, to, err)
 		}
+	}
+}
 
-		mainBranch, err := wt.GetDefaultBranch(remote)
-		if err != nil {
-			d.logger.Debug("Could not get default branch for %s: %v", repoName, err)
-			continue
+// announceNeedsHuman publishes a needs_human event for an agent that started
+// waiting at a permission prompt or question, and runs the repository's
+// human notifiers. The notifiers run in the background so a slow webhook
+// doesn't hold up the health check.
+func (d *Daemon) announceNeedsHuman(repoName, agentName string, agent state.Agent, repo *state.Repository, waiting zombie.Kind) {
+	d.logger.Info("Agent %s/%s is waiting on a human (%s)", repoName, agentName, waiting)
+	d.events.Publish(events.EventNeedsHuman, repoName, agentName, map[string]string{"kind": string(waiting)})
+
+	if d.notificationsMuted() {
+		return
+	}
+
+	alert := notify.Alert{
+		Repo:    repoName,
+		Agent:   agentName,
+		Kind:    string(waiting),
+		Session: repo.TmuxSession,
+		Window:  agent.TmuxWindow,
+		Time:    time.Now(),
+	}
+	d.notifier.Notify(repo.NotifyConfig, notify.Event{Type: notify.EventNeedsHuman, Repo: repoName, Agent: agentName, Session: repo.TmuxSession, Message: alert.Body(), Time: alert.Time})
+
+	notifiers, err := d.humanNotifiers(repo.NotifyConfig)
+	if err != nil {
+		d.logger.Error("Failed to set up needs-human notifiers for %s: %v", repoName, err)
+		return
+	}
+	for _, n := range notifiers {
+		go func(n notify.HumanNotifier) {
+			if err := n.Notify(alert); err != nil {
+				d.logger.Error("Failed to announce that %s/%s needs a human: %v", repoName, agentName, err)
+			}
+		}(n)
+	}
+}
+
+// stopPaneProcesses stops the processes started from an agent's pane shell
+// (Claude itself), leaving the shell and window for the restart. It sends
+// SIGTERM, then SIGKILL to anything still running a few seconds later.
+func stopPaneProcesses(panePID int) error {
+	parent := strconv.Itoa(panePID)
+	if err := exec.Command("pkill", "-TERM", "-P", parent).Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil // Nothing was running
+		}
+		return fmt.Errorf("pkill: %w", err)
+	}
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if exec.Command("pgrep", "-P", parent).Run() != nil {
+			return nil
 		}
+	}
+	_ = exec.Command("pkill", "-KILL", "-P", parent).Run()
+	return nil
+}
+
+// interruptPaneProcesses sends SIGINT to the foreground process group of an
+// agent's pane (Claude itself), as Ctrl-C would, leaving the shell alone
+func interruptPaneProcesses(panePID int) error {
+	out, err := exec.Command("ps", "-o", "tpgid=", "-p", strconv.Itoa(panePID)).Output()
+	if err != nil {
+		return fmt.Errorf("ps: %w", err)
+	}
+	foreground, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return fmt.Errorf("unexpected ps output %q", strings.TrimSpace(string(out)))
+	}
+	shell, err := syscall.Getpgid(panePID)
+	if err != nil {
+		return fmt.Errorf("getpgid: %w", err)
+	}
+	if foreground <= 0 || foreground == shell {
+		return fmt.Errorf("nothing is running in the agent's window")
+	}
+	return syscall.Kill(-foreground, syscall.SIGINT)
+}
 
-		// Fetch from remote to have latest state
-		if err := wt.FetchRemote(remote); err != nil {
-			d.logger.Debug("Could not fetch from remote for %s: %v", repoName, err)
+// signalPaneProcesses sends sig to what runs in an agent's pane rather than
+// to the pane's shell: each process the shell started, and its process group
+// when it leads one, as jobs of an interactive shell do, so the tools Claude
+// runs are stopped and continued along with it
+func signalPaneProcesses(panePID int, sig syscall.Signal) error {
+	out, err := exec.Command("pgrep", "-P", strconv.Itoa(panePID)).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil // Nothing is running
+		}
+		return fmt.Errorf("pgrep: %w", err)
+	}
+	shell, err := syscall.Getpgid(panePID)
+	if err != nil {
+		return fmt.Errorf("getpgid: %w", err)
+	}
+	var failed []string
+	for _, field := range strings.Fields(string(out)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
 			continue
 		}
+		target := pid
+		if pgid, err := syscall.Getpgid(pid); err == nil && pgid == pid && pgid != shell {
+			target = -pgid
+		}
+		if err := syscall.Kill(target, sig); err != nil && err != syscall.ESRCH {
+			failed = append(failed, fmt.Sprintf("process %d: %v", pid, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("kill: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// messageRouterLoop watches for new messages and delivers them
+func (d *Daemon) messageRouterLoop() {
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.MessageRouting })
+	d.periodicLoop("message router", interval, nil, d.slowInStandby(interval, d.routeMessages))
+}
+
+// routeMessages checks for pending messages and delivers them
+func (d *Daemon) routeMessages() {
+	d.logger.Debug("Routing messages")
+
+	// Get messages manager
+	msgMgr := d.getMessageManager()
+
+	// Get a snapshot of repos to avoid concurrent map access
+	repos := d.state.GetAllRepos()
+
+	// Check each repository
+	for repoName, repo := range repos {
+		// Routing rules run first, so copies go out in the same pass
+		d.applyRoutingRules(msgMgr, repoName, repo)
 
-		// Check each worker agent's worktree
+		// Check each agent for messages
 		for agentName, agent := range repo.Agents {
-			// Only refresh worker worktrees
-			if agent.Type != state.AgentTypeWorker {
+			// Skip workspace agent - it should only receive direct user input
+			if agent.Type == state.AgentTypeWorkspace {
 				continue
 			}
 
-			// Skip if worktree path is empty
-			if agent.WorktreePath == "" {
+			// Paused agents get their messages once standby ends
+			if agent.Paused {
 				continue
 			}
 
-			// Check if worktree exists
-			if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
+			d.deliverPendingMessages(msgMgr, repoName, agentName, repo.TmuxSession, agent.TmuxWindow)
+		}
+
+		d.forwardEscalations(msgMgr, repoName, repo)
+	}
+
+	// Deliver messages addressed to project supervisors
+	for _, projectName := range d.state.ListProjects() {
+		project, exists := d.state.GetProject(projectName)
+		if !exists {
+			continue
+		}
+		d.deliverPendingMessages(msgMgr, state.ProjectKey(projectName), "supervisor", project.TmuxSession, "supervisor")
+	}
+}
+
+// deliverPendingMessages delivers an agent's pending messages to its tmux window.
+// repoKey is a repository name or a project key (see state.ProjectKey).
+func (d *Daemon) deliverPendingMessages(msgMgr *messages.Manager, repoKey, agentName, tmuxSession, tmuxWindow string) {
+	// Get unread messages (pending or delivered but not yet read)
+	unreadMsgs, err := msgMgr.ListUnread(repoKey, agentName)
+	if err != nil {
+		d.subsystemError("message router", "Failed to list messages for %s/%s: %v", repoKey, agentName, err)
+		return
+	}
+
+	// Deliver each pending message, most urgent first
+	messages.SortByPriority(unreadMsgs)
+	for _, msg := range unreadMsgs {
+		if msg.Status != messages.StatusPending {
+			// Already delivered, skip
+			continue
+		}
+
+		// Format message for delivery
+		messageText := formatDelivery(msg)
+
+		// Send via tmux using atomic method to avoid race conditions
+		// where Enter might be lost between separate exec calls (issue #63)
+		if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, tmuxSession, tmuxWindow, messageText); err != nil {
+			d.subsystemError("message router", "Failed to deliver message %s to %s/%s: %v", msg.ID, repoKey, agentName, err)
+			continue
+		}
+
+		// Mark as delivered
+		if err := msgMgr.UpdateStatus(repoKey, agentName, msg.ID, messages.StatusDelivered); err != nil {
+			d.subsystemError("message router", "Failed to update message %s status: %v", msg.ID, err)
+			continue
+		}
+
+		d.logger.Info("Delivered message %s from %s to %s/%s", msg.ID, msg.From, repoKey, agentName)
+		d.indexMessage(repoKey, agentName, msg)
+		d.events.Publish(events.EventMessageDelivered, repoKey, agentName, map[string]string{"id": msg.ID, "from": msg.From})
+	}
+}
+
+// formatDelivery renders a message as it is typed into the recipient's window
+func formatDelivery(msg *messages.Message) string {
+	label := "Message"
+	if msg.Priority == messages.PriorityHigh {
+		label = "High-priority message"
+	}
+	from := msg.From
+	if msg.CCOf != "" {
+		from += fmt.Sprintf(" (copy of a message to %s)", msg.CCOf)
+	}
+	return fmt.Sprintf("📨 %s from %s: %s", label, from, msg.Body)
+}
+
+// applyRoutingRules applies a repository's routing rules to every pending
+// message that hasn't been routed yet, in any inbox: it sends the copies
+// the rules ask for and sets the message's priority. Each message is routed
+// once; copies are routed already, so rules never chain.
+func (d *Daemon) applyRoutingRules(msgMgr *messages.Manager, repoName string, repo *state.Repository) {
+	if len(repo.RoutingRules) == 0 {
+		return
+	}
+
+	inboxes, err := msgMgr.Inboxes(repoName)
+	if err != nil {
+		d.logger.Error("Failed to list inboxes for %s: %v", repoName, err)
+		return
+	}
+	for _, inbox := range inboxes {
+		msgs, err := msgMgr.ListUnread(repoName, inbox)
+		if err != nil {
+			d.logger.Error("Failed to list messages for %s/%s: %v", repoName, inbox, err)
+			continue
+		}
+		for _, msg := range msgs {
+			if msg.Status != messages.StatusPending || msg.Routed {
 				continue
 			}
 
-			// Check worktree state
-			wtState, err := worktree.GetWorktreeState(agent.WorktreePath, remote, mainBranch)
-			if err != nil {
-				d.logger.Debug("Could not get worktree state for %s/%s: %v", repoName, agentName, err)
-				continue
+			senderType := ""
+			if sender, ok := repo.Agents[msg.From]; ok {
+				senderType = string(sender.Type)
+			}
+			result := routing.Apply(repo.RoutingRules, msg, senderType)
+			if result.Priority != "" {
+				msg.Priority = result.Priority
 			}
 
-			// Skip if can't refresh (detached HEAD, mid-rebase, mid-merge, on main, or up to date)
-			if !wtState.CanRefresh {
-				d.logger.Debug("Skipping refresh for %s/%s: %s", repoName, agentName, wtState.RefreshReason)
+			copied := true
+			for _, to := range result.CC {
+				if _, err := msgMgr.CC(repoName, msg, to); err != nil {
+					d.logger.Error("Failed to copy message %s to %s/%s: %v", msg.ID, repoName, to, err)
+					copied = false
+					continue
+				}
+				d.logger.Info("Copied message %s from %s to %s/%s by routing rule", msg.ID, msg.From, repoName, to)
+			}
+			if !copied {
+				// Try again next time; copies already sent are rewritten, not doubled
 				continue
 			}
+			if err := msgMgr.MarkRouted(repoName, inbox, msg.ID, result.Priority); err != nil {
+				d.logger.Error("Failed to mark message %s routed: %v", msg.ID, err)
+			}
+		}
+	}
+}
 
-			// Refresh the worktree
-			d.logger.Info("Refreshing worktree for %s/%s (%d commits behind)", repoName, agentName, wtState.CommitsBehind)
-			result := worktree.RefreshWorktree(agent.WorktreePath, remote, mainBranch)
+// forwardEscalations turns messages addressed to the human into email notifications.
+// Messages stay pending when notifications are disabled so they can still be read locally.
+func (d *Daemon) forwardEscalations(msgMgr *messages.Manager, repoName string, repo *state.Repository) {
+	if !repo.NotifyConfig.Enabled || d.notificationsMuted() {
+		return
+	}
 
-			if result.Error != nil {
-				if result.HasConflicts {
-					d.logger.Warn("Worktree refresh for %s/%s has conflicts in: %v", repoName, agentName, result.ConflictFiles)
-				} else {
-					d.logger.Error("Failed to refresh worktree for %s/%s: %v", repoName, agentName, result.Error)
-				}
-			} else if result.Skipped {
-				d.logger.Debug("Worktree refresh for %s/%s skipped: %s", repoName, agentName, result.SkipReason)
-			} else {
-				d.logger.Info("Refreshed worktree for %s/%s: rebased %d commits", repoName, agentName, result.CommitsRebased)
+	unreadMsgs, err := msgMgr.ListUnread(repoName, notify.HumanRecipient)
+	if err != nil {
+		d.logger.Error("Failed to list escalations for %s: %v", repoName, err)
+		return
+	}
 
-				// Notify the agent that their worktree was refreshed
-				msgMgr := d.getMessageManager()
-				msg := fmt.Sprintf("Your worktree has been automatically synced with main (rebased %d commits). Run 'git log --oneline -5' to see recent changes.", result.CommitsRebased)
-				if _, err := msgMgr.Send(repoName, "daemon", agentName, msg); err != nil {
-					d.logger.Debug("Could not send refresh notification to %s/%s: %v", repoName, agentName, err)
-				}
-			}
+	for _, msg := range unreadMsgs {
+		if msg.Status != messages.StatusPending {
+			continue
+		}
+
+		d.notifier.Notify(repo.NotifyConfig, notify.Event{
+			Type:    notify.EventEscalation,
+			Repo:    repoName,
+			Agent:   msg.From,
+			Session: repo.TmuxSession,
+			Message: msg.Body,
+			Time:    msg.Timestamp,
+		})
+
+		// The dispatcher owns the event now (including retries), so mark it delivered
+		if err := msgMgr.UpdateStatus(repoName, notify.HumanRecipient, msg.ID, messages.StatusDelivered); err != nil {
+			d.subsystemError("message router", "Failed to update message %s status: %v", msg.ID, err)
+			continue
 		}
+		d.logger.Info("Forwarded escalation %s from %s/%s", msg.ID, repoName, msg.From)
 	}
 }
 
-// TriggerWorktreeRefresh triggers an immediate worktree refresh (for testing)
-func (d *Daemon) TriggerWorktreeRefresh() {
-	d.refreshWorktrees()
+// notifyAgentCrash publishes an agent's death and queues a crash notification
+// for it
+func (d *Daemon) notifyAgentCrash(repoName string, repo *state.Repository, agentName, detail string) {
+	d.events.Publish(events.EventAgentDied, repoName, agentName, map[string]string{"detail": detail})
+	if d.notificationsMuted() {
+		return
+	}
+	d.notifier.Notify(repo.NotifyConfig, notify.Event{
+		Type:    notify.EventAgentCrash,
+		Repo:    repoName,
+		Agent:   agentName,
+		Session: repo.TmuxSession,
+		Message: detail,
+	})
 }
 
-// handleRequest handles incoming socket requests
-func (d *Daemon) handleRequest(req socket.Request) socket.Response {
-	d.logger.Debug("Handling request: %s", req.Command)
+// flushNotifications sends notification digests whose interval has elapsed
+func (d *Daemon) flushNotifications() {
+	if d.notificationsMuted() {
+		// Digests wait for notifications to be unmuted
+		return
+	}
+	repos := d.state.GetAllRepos()
+	configs := make(map[string]state.NotifyConfig, len(repos))
+	for repoName, repo := range repos {
+		configs[repoName] = repo.NotifyConfig
+	}
 
-	switch req.Command {
-	case "ping":
-		return socket.Response{Success: true, Data: "pong"}
+	d.notifier.FlushDue(configs, time.Now())
+}
 
-	case "status":
-		return d.handleStatus(req)
+// getMessageManager returns a message manager instance
+func (d *Daemon) getMessageManager() *messages.Manager {
+	return messages.NewManager(d.paths.MessagesDir)
+}
 
-	case "stop":
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			d.Stop()
-		}()
-		return socket.Response{Success: true, Data: "Daemon stopping"}
+// wakeLoop periodically wakes agents with status checks
+func (d *Daemon) wakeLoop() {
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.Wake })
+	d.periodicLoop("wake", interval, nil, d.slowInStandby(interval, d.wakeAgents))
+}
 
-	case "list_repos":
-		return d.handleListRepos(req)
+// wakeAgents sends periodic nudges to agents
+func (d *Daemon) wakeAgents() {
+	d.logger.Debug("Waking agents")
 
-	case "add_repo":
-		return d.handleAddRepo(req)
+	now := time.Now()
 
-	case "remove_repo":
-		return d.handleRemoveRepo(req)
+	// Get a snapshot of repos to avoid concurrent map access
+	repos := d.state.GetAllRepos()
+	for repoName, repo := range repos {
+		for agentName, agent := range repo.Agents {
+			// Skip workspace and solo agents - they should only receive direct user input
+			if agent.Type == state.AgentTypeWorkspace || agent.Type == state.AgentTypeSolo {
+				continue
+			}
 
-	case "add_agent":
-		return d.handleAddAgent(req)
+			// Skip agents paused for standby
+			if agent.Paused {
+				continue
+			}
 
-	case "remove_agent":
-		return d.handleRemoveAgent(req)
+			// Leave agents alone outside their repository's work hours
+			if d.offHours(repoName) {
+				continue
+			}
 
-	case "list_agents":
-		return d.handleListAgents(req)
+			// Skip if nudged recently (within last 2 minutes)
+			if !agent.LastNudge.IsZero() && now.Sub(agent.LastNudge) < 2*time.Minute {
+				continue
+			}
 
-	case "complete_agent":
-		return d.handleCompleteAgent(req)
+			// Send wake message based on agent type, in the operator's language
+			message := d.text("nudge.wake."+string(agent.Type), nil)
 
-	case "restart_agent":
-		return d.handleRestartAgent(req)
+			// Send message using atomic method to avoid race conditions (issue #63)
+			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, message); err != nil {
+				d.subsystemError("wake", "Failed to send wake message to agent %s: %v", agentName, err)
+				continue
+			}
 
-	case "trigger_cleanup":
-		return d.handleTriggerCleanup(req)
+			// Update last nudge time
+			agent.LastNudge = now
+			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+				d.subsystemError("wake", "Failed to update agent %s last nudge: %v", agentName, err)
+			}
 
-	case "repair_state":
-		return d.handleRepairState(req)
+			d.logger.Debug("Woke agent %s in repo %s", agentName, repoName)
+		}
+	}
+}
+
+// worktreeRefreshLoop periodically syncs worker worktrees with the default branch
+func (d *Daemon) worktreeRefreshLoop() {
+	defer d.wg.Done()
+	d.logger.Info("Starting worktree refresh loop")
+
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.WorktreeRefresh })
+	current := interval()
+	ticker := time.NewTicker(current)
+	defer ticker.Stop()
+	refresh := d.slowInStandby(interval, d.refreshWorktrees)
+	// The first refresh is 30 seconds in, which is within any interval's slack
+	d.debug.expect("worktree refresh", current, time.Now())
+
+	// Run once after a short delay on startup (respecting context cancellation)
+	select {
+	case <-time.After(30 * time.Second):
+		d.debug.time("worktree refresh", d.refreshWorktrees)
+	case <-d.ctx.Done():
+		d.logger.Info("Worktree refresh loop stopped")
+		return
+	}
+
+	for {
+		_, changed := d.currentSettings()
+		select {
+		case <-ticker.C:
+			d.debug.time("worktree refresh", refresh)
+		case <-changed:
+			if next := interval(); next != current {
+				d.logger.Info("worktree refresh loop now runs every %s", next)
+				current = next
+				ticker.Reset(current)
+				d.debug.expect("worktree refresh", current, time.Now())
+			}
+		case <-d.ctx.Done():
+			d.logger.Info("Worktree refresh loop stopped")
+			return
+		}
+	}
+}
+
+// refreshWorktrees syncs worker worktrees that are behind the default branch.
+// Each repository is fetched once, then its worktrees are checked and rebased
+// in parallel, sharing a bounded pool of slots with other repositories.
+func (d *Daemon) refreshWorktrees() {
+	d.logger.Debug("Checking worker worktrees for refresh")
+	start := time.Now()
+
+	// limits.refresh_concurrency bounds how many git fetches and worktree
+	// rebases run at once across all repositories
+	settings, _ := d.currentSettings()
+	slots := make(chan struct{}, settings.Limits.RefreshConcurrency)
+	var wg sync.WaitGroup
+	for repoName, repo := range d.state.GetAllRepos() {
+		wg.Add(1)
+		go func(repoName string, repo *state.Repository) {
+			defer wg.Done()
+			d.refreshRepoWorktrees(repoName, repo, slots)
+			d.syncMirror(repoName, repo, slots)
+		}(repoName, repo)
+	}
+	wg.Wait()
+
+	d.logger.Debug("Worktree refresh finished in %s", time.Since(start).Round(time.Millisecond))
+}
+
+// refreshRepoWorktrees fetches a repository once and refreshes its worker
+// worktrees in parallel. slots bounds concurrent git work.
+func (d *Daemon) refreshRepoWorktrees(repoName string, repo *state.Repository, slots chan struct{}) {
+	repoPath := d.paths.RepoDir(repoName)
+
+	// Check if repo path exists
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		return
+	}
+
+	wt := d.repoWorktreeManager(repoName)
+
+	// Get the upstream remote and default branch
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		d.logger.Debug("Could not get remote for %s: %v", repoName, err)
+		return
+	}
+
+	mainBranch, err := wt.GetDefaultBranch(remote)
+	if err != nil {
+		d.logger.Debug("Could not get default branch for %s: %v", repoName, err)
+		return
+	}
+
+	// Fetch from remote once to have latest state for every worktree. The
+	// fetch gets its own deadline, so a hung one holds up only this repository.
+	slots <- struct{}{}
+	ctx, cancel := d.withTimeout(refreshTimeout)
+	err = wt.FetchRemote(ctx, remote)
+	cancel()
+	<-slots
+	if err != nil {
+		d.logger.Debug("Could not fetch from remote for %s: %v", repoName, err)
+		d.noteError("worktree refresh", "Could not fetch from remote for %s: %v", repoName, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for agentName, agent := range repo.Agents {
+		// Observers' detached worktrees follow the default branch
+		if agent.Type == state.AgentTypeObserver && d.ownsWorktree(repoName, agent) {
+			ctx, cancel := d.withTimeout(refreshTimeout)
+			if err := worktree.CheckoutDetached(ctx, agent.WorktreePath, remote+"/"+mainBranch); err != nil {
+				d.logger.Warn("Could not move observer %s/%s to %s/%s: %v", repoName, agentName, remote, mainBranch, err)
+			}
+			cancel()
+			continue
+		}
+
+		// Only refresh worker worktrees, and leave adopted workers' directories alone
+		if agent.Type != state.AgentTypeWorker || agent.Adopted {
+			continue
+		}
+
+		// Skip if worktree path is empty
+		if agent.WorktreePath == "" {
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(agentName string, agent state.Agent) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d.refreshAgentWorktree(repoName, agentName, agent.WorktreePath, remote, agentBase(agent, mainBranch), agentRefreshConfig(repo, agent))
+		}(agentName, agent)
+	}
+	wg.Wait()
+}
+
+// agentBase returns the branch a worker's branch is kept up to date with: its
+// own base branch if it was given one, else the default branch
+func agentBase(agent state.Agent, defaultBranch string) string {
+	if agent.BaseBranch != "" {
+		return agent.BaseBranch
+	}
+	return defaultBranch
+}
+
+// baseBranchNote tells the merge queue that a worker's PR is against its own
+// base branch, or is "" for workers on the default branch
+func baseBranchNote(agent state.Agent) string {
+	if agent.BaseBranch == "" {
+		return ""
+	}
+	return fmt.Sprintf(" Its PR targets %s, not the default branch: merge it into %s once its CI passes. The merge train only tests PRs against the default branch.", agent.BaseBranch, agent.BaseBranch)
+}
+
+// refreshTimeout picks the timeout for refreshing a repository or worktree
+func refreshTimeout(t daemonconfig.Timeouts) time.Duration { return t.Refresh }
+
+// refreshActiveWindow is how recently an agent must have produced output or
+// edited a file for a pause_active refresh config to leave its worktree alone
+const refreshActiveWindow = 5 * time.Minute
+
+// agentRefreshConfig returns the refresh config that applies to an agent:
+// its own override, or the repository's
+func agentRefreshConfig(repo *state.Repository, agent state.Agent) state.RefreshConfig {
+	if agent.Refresh != nil {
+		return *agent.Refresh
+	}
+	return repo.RefreshConfig
+}
+
+// agentActive reports whether an agent's pane changed or a file in its
+// worktree was edited within refreshActiveWindow, with what it was doing
+func (d *Daemon) agentActive(repoName, agentName, worktreePath string) (bool, string) {
+	now := time.Now()
+	if at, ok := d.zombies.LastOutput(repoName + "/" + agentName); ok && now.Sub(at) < refreshActiveWindow {
+		return true, fmt.Sprintf("agent produced output %s ago", now.Sub(at).Round(time.Second))
+	}
+	if at, err := worktree.LastEdit(worktreePath); err == nil && now.Sub(at) < refreshActiveWindow {
+		return true, fmt.Sprintf("a file was edited %s ago", now.Sub(at).Round(time.Second))
+	}
+	return false, ""
+}
+
+// refreshAgentWorktree brings one worker worktree up to date with the fetched
+// default branch if it's behind and safe to, as its refresh config says
+func (d *Daemon) refreshAgentWorktree(repoName, agentName, worktreePath, remote, mainBranch string, cfg state.RefreshConfig) {
+	strategy := cfg.EffectiveStrategy()
+	if strategy == state.RefreshOff {
+		return
+	}
+
+	// Check if worktree exists
+	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
+		return
+	}
+
+	// Each worktree gets its own deadline, so one whose git hangs doesn't
+	// hold up the rest of the refresh
+	ctx, cancel := d.withTimeout(refreshTimeout)
+	defer cancel()
+
+	// Check worktree state
+	wtState, err := worktree.GetWorktreeState(ctx, worktreePath, remote, mainBranch)
+	if err != nil {
+		d.logger.Debug("Could not get worktree state for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+
+	if strategy == state.RefreshFetch {
+		d.notifyBehind(repoName, agentName, remote, mainBranch, wtState.CommitsBehind)
+		return
+	}
+
+	// Skip if can't refresh (detached HEAD, mid-rebase, mid-merge, on main, or up to date)
+	if !wtState.CanRefresh {
+		d.logger.Debug("Skipping refresh for %s/%s: %s", repoName, agentName, wtState.RefreshReason)
+		return
+	}
+
+	if cfg.PauseActive {
+		if active, why := d.agentActive(repoName, agentName, worktreePath); active {
+			d.logger.Debug("Skipping refresh for %s/%s: %s", repoName, agentName, why)
+			return
+		}
+	}
+
+	if !d.claimRefresh(worktreePath) {
+		d.logger.Debug("Skipping refresh for %s/%s: already being synced", repoName, agentName)
+		return
+	}
+	defer d.releaseRefresh(worktreePath)
+
+	// Refresh the worktree
+	d.logger.Info("Refreshing worktree for %s/%s (%d commits behind, %s)", repoName, agentName, wtState.CommitsBehind, strategy)
+	result := worktree.RefreshWorktreeWithOptions(ctx, worktreePath, remote, mainBranch, worktree.RefreshOptions{
+		Merge:     strategy == state.RefreshMerge,
+		OnlyClean: cfg.OnlyClean,
+		// refreshRepoWorktrees fetched once for all of the repo's worktrees
+		NoFetch: true,
+	})
+
+	if result.Error != nil {
+		if result.HasConflicts {
+			d.logger.Warn("Worktree refresh for %s/%s has conflicts in: %v", repoName, agentName, result.ConflictFiles)
+		} else {
+			d.subsystemError("worktree refresh", "Failed to refresh worktree for %s/%s: %v", repoName, agentName, result.Error)
+		}
+	} else if result.Skipped {
+		d.logger.Debug("Worktree refresh for %s/%s skipped: %s", repoName, agentName, result.SkipReason)
+	} else {
+		var msg string
+		if result.Merged {
+			d.logger.Info("Refreshed worktree for %s/%s: merged %s", repoName, agentName, mainBranch)
+			msg = fmt.Sprintf("Your worktree has been automatically synced with %s (merged %d new commits into your branch). Run 'git log --oneline -5' to see recent changes.", mainBranch, wtState.CommitsBehind)
+		} else {
+			d.logger.Info("Refreshed worktree for %s/%s: rebased %d commits", repoName, agentName, result.CommitsRebased)
+			msg = fmt.Sprintf("Your worktree has been automatically synced with %s (rebased %d commits). Run 'git log --oneline -5' to see recent changes.", mainBranch, result.CommitsRebased)
+		}
+
+		if err := d.state.UpdateAgentLastRefresh(repoName, agentName, time.Now()); err != nil {
+			d.logger.Debug("Could not record refresh of %s/%s: %v", repoName, agentName, err)
+		}
+
+		// Notify the agent that their worktree was refreshed
+		msgMgr := d.getMessageManager()
+		if _, err := msgMgr.Send(repoName, "daemon", agentName, msg); err != nil {
+			d.logger.Debug("Could not send refresh notification to %s/%s: %v", repoName, agentName, err)
+		}
+	}
+}
+
+// claimRefresh marks a worktree as being refreshed, reporting false if it
+// already is
+func (d *Daemon) claimRefresh(worktreePath string) bool {
+	d.refreshMu.Lock()
+	defer d.refreshMu.Unlock()
+	if d.refreshing[worktreePath] {
+		return false
+	}
+	d.refreshing[worktreePath] = true
+	return true
+}
+
+// releaseRefresh marks a worktree claimed with claimRefresh as done
+func (d *Daemon) releaseRefresh(worktreePath string) {
+	d.refreshMu.Lock()
+	delete(d.refreshing, worktreePath)
+	d.refreshMu.Unlock()
+}
+
+// notifyBehind tells a worker with the fetch strategy how far behind the
+// default branch it is. It is told again only when the branch falls further
+// behind, not on every refresh.
+func (d *Daemon) notifyBehind(repoName, agentName, remote, mainBranch string, behind int) {
+	key := repoName + "/" + agentName
+	d.refreshMu.Lock()
+	notified := d.refreshNotified[key]
+	if behind == 0 {
+		delete(d.refreshNotified, key)
+	} else if behind > notified {
+		d.refreshNotified[key] = behind
+	}
+	d.refreshMu.Unlock()
+	if behind <= notified {
+		return
+	}
+
+	msg := fmt.Sprintf("Your branch is %d commits behind %s/%s. The daemon doesn't sync your worktree; merge or rebase when it suits you.", behind, remote, mainBranch)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, msg); err != nil {
+		d.logger.Debug("Could not send behind notification to %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// syncMirror fetches a repository's mirror, cloning it the first time, and
+// moves its observers' worktrees to the default branch. A mirror that was
+// turned off is deleted once no agent works in it.
+func (d *Daemon) syncMirror(repoName string, repo *state.Repository, slots chan struct{}) {
+	dir := d.paths.MirrorDir(repoName)
+	if !repo.WorktreeConfig.Mirror || repo.Solo {
+		if mirror.Exists(dir) && !d.usesMirror(repoName, repo) {
+			os.RemoveAll(d.paths.MirrorWorktreeDir(repoName))
+			if err := os.RemoveAll(dir); err != nil {
+				d.logger.Warn("Failed to remove mirror of %s: %v", repoName, err)
+			} else {
+				d.logger.Info("Removed mirror of %s", repoName)
+			}
+		}
+		return
+	}
+	if repo.GithubURL == "" {
+		return
+	}
+
+	slots <- struct{}{}
+	ctx, cancel := d.withTimeout(refreshTimeout)
+	defer cancel()
+	err := mirror.Sync(ctx, dir, repo.GithubURL)
+	<-slots
+	if err != nil {
+		d.subsystemWarn("worktree refresh", "Could not sync mirror of %s: %v", repoName, err)
+		return
+	}
+
+	branch := d.repoDefaultBranch(repoName)
+	for agentName, agent := range repo.Agents {
+		if agent.Type != state.AgentTypeObserver || !d.inMirror(repoName, agent.WorktreePath) {
+			continue
+		}
+		if err := mirror.Advance(ctx, agent.WorktreePath, branch); err != nil {
+			d.logger.Warn("Could not move observer %s/%s to %s: %v", repoName, agentName, branch, err)
+		}
+	}
+}
+
+// usesMirror reports whether any of a repository's agents works in its mirror
+func (d *Daemon) usesMirror(repoName string, repo *state.Repository) bool {
+	for _, agent := range repo.Agents {
+		if d.inMirror(repoName, agent.WorktreePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// inMirror reports whether a worktree was checked out from a repository's mirror
+func (d *Daemon) inMirror(repoName, path string) bool {
+	return path != "" && strings.HasPrefix(path, d.paths.MirrorWorktreeDir(repoName)+string(filepath.Separator))
+}
+
+// TriggerWorktreeRefresh triggers an immediate worktree refresh (for testing)
+func (d *Daemon) TriggerWorktreeRefresh() {
+	d.refreshWorktrees()
+}
+
+// handleRequest handles incoming socket requests. Lines are tagged with the
+// request's trace ID, and failures logged, so one CLI invocation can be
+// followed through the log.
+func (d *Daemon) handleRequest(req socket.Request) socket.Response {
+	log := d.logger.WithTrace(req.TraceID)
+	log.Debug("Handling request: %s", req.Command)
+
+	start := time.Now()
+	resp := d.dispatch(req)
+	if !resp.Success {
+		log.Warn("Request %s failed after %s: %s", req.Command, time.Since(start).Round(time.Millisecond), resp.Error)
+	}
+	return resp
+}
+
+// dispatch routes a request to its handler
+func (d *Daemon) dispatch(req socket.Request) socket.Response {
+	switch req.Command {
+	case "ping":
+		return socket.Response{Success: true, Data: "pong"}
+
+	case "status":
+		return d.handleStatus(req)
+
+	case "stop":
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			d.Stop()
+		}()
+		return socket.Response{Success: true, Data: "Daemon stopping"}
+
+	case "list_repos":
+		return d.handleListRepos(req)
+
+	case "add_repo":
+		return d.handleAddRepo(req)
+
+	case "remove_repo":
+		return d.handleRemoveRepo(req)
+
+	case "add_agent":
+		return d.handleAddAgent(req)
+
+	case "remove_agent":
+		return d.handleRemoveAgent(req)
+
+	case "list_agents":
+		return d.handleListAgents(req)
+
+	case "complete_agent":
+		return d.handleCompleteAgent(req)
+
+	case "add_memory":
+		return d.handleAddMemory(req)
+
+	case "get_memory":
+		return d.handleGetMemory(req)
+
+	case "clear_memory":
+		return d.handleClearMemory(req)
+
+	case "restart_agent":
+		return d.handleRestartAgent(req)
+
+	case "interrupt_agent":
+		return d.handleInterruptAgent(req)
+
+	case "reinit_repo":
+		return d.handleReinitRepo(req)
+
+	case "trigger_cleanup":
+		return d.handleTriggerCleanup(req)
+
+	case "repair_state":
+		return d.handleRepairState(req)
+
+	case "list_snapshots":
+		return d.handleListSnapshots(req)
+
+	case "state_rollback":
+		return d.handleStateRollback(req)
+
+	case "repair_plan":
+		return d.handleRepairPlan(req)
+
+	case "get_inconsistencies":
+		return d.handleGetInconsistencies(req)
+
+	case "repair_apply":
+		return d.handleRepairApply(req)
+
+	case "get_repo_config":
+		return d.handleGetRepoConfig(req)
+
+	case "update_repo_config":
+		return d.handleUpdateRepoConfig(req)
+
+	case "work_hours_override":
+		return d.handleWorkHoursOverride(req)
+
+	case "migrate_worktrees":
+		return d.handleMigrateWorktrees(req)
+
+	case "set_agent_refresh":
+		return d.handleSetAgentRefresh(req)
+
+	case "sync_agent":
+		return d.handleSyncAgent(req)
+
+	case "set_agent_editor":
+		return d.handleSetAgentEditor(req)
+
+	case "set_current_repo":
+		return d.handleSetCurrentRepo(req)
+
+	case "get_current_repo":
+		return d.handleGetCurrentRepo(req)
+
+	case "clear_current_repo":
+		return d.handleClearCurrentRepo(req)
+
+	case "ask":
+		return d.handleAsk(req)
+
+	case "answer":
+		return d.handleAnswer(req)
+
+	case "get_ticket":
+		return d.handleGetTicket(req)
+
+	case "train_status":
+		return d.handleTrainStatus(req)
+
+	case "add_task":
+		return d.handleAddTask(req)
+
+	case "list_tasks":
+		return d.handleListTasks(req)
+
+	case "cancel_task":
+		return d.handleCancelTask(req)
+
+	case "route_messages":
+		go d.routeMessages()
+		return socket.Response{Success: true, Data: "Message routing triggered"}
+
+	case "task_history":
+		return d.handleTaskHistory(req)
+
+	case "run_spawn_hook":
+		return d.handleRunSpawnHook(req)
+
+	case "spawn_agent":
+		return d.handleSpawnAgent(req)
+
+	case "check_agent_quota":
+		return d.handleCheckAgentQuota(req)
+
+	case "bulk_workers":
+		return d.handleBulkWorkers(req)
+
+	case "create_project":
+		return d.handleCreateProject(req)
+
+	case "list_projects":
+		return d.handleListProjects(req)
+
+	case "project_status":
+		return d.handleProjectStatus(req)
+
+	case "remove_project":
+		return d.handleRemoveProject(req)
+
+	case "standby":
+		return d.handleStandby(req)
+
+	case "reload_config":
+		return d.handleReloadConfig(req)
+
+	case "debug":
+		return d.handleDebug(req)
+
+	case "pr_status":
+		return d.handlePRStatus(req)
+
+	case "pr_ready":
+		return d.handlePRReady(req)
+
+	case "push_check":
+		return d.handlePushCheck(req)
+
+	case "request_review":
+		return d.handleRequestReview(req)
+
+	case "review_done":
+		return d.handleReviewDone(req)
+
+	case "review_queue":
+		return d.handleReviewQueue(req)
+
+	case "describe_pr":
+		return d.handleDescribePR(req)
+
+	case "show_pr":
+		return d.handleShowPR(req)
+
+	case "federation_status":
+		return d.handleFederationStatus(req)
+
+	case "federation_send":
+		return d.handleFederationSend(req)
+
+	case "events":
+		return d.handleEvents(req)
+
+	default:
+		return socket.Response{
+			Success: false,
+			Error:   fmt.Sprintf("unknown command: %q. Run 'multiclaude --help' for available commands", req.Command),
+		}
+	}
+}
+
+// handleStatus returns daemon status
+func (d *Daemon) handleStatus(req socket.Request) socket.Response {
+	repos := d.state.ListRepos()
+	agentCount := 0
+	for _, repo := range repos {
+		agents, _ := d.state.ListAgents(repo)
+		agentCount += len(agents)
+	}
+
+	d.standbyMu.Lock()
+	standby, standbyReason := d.standby, d.standbyReason
+	d.standbyMu.Unlock()
+
+	inconsistencies, _ := d.integrity.Current(false)
+
+	now := time.Now()
+	stalled := d.stalledLoops(now)
+	data := map[string]interface{}{
+		"running":         true,
+		"pid":             os.Getpid(),
+		"repos":           len(repos),
+		"agents":          agentCount,
+		"socket_path":     d.paths.DaemonSock,
+		"address":         d.server.Address(),
+		"standby":         standby,
+		"standby_reason":  standbyReason,
+		"connections":     d.server.Stats(),
+		"inconsistencies": len(inconsistencies),
+		"github":          d.githubReport(),
+		"stalled":         stalled,
+	}
+
+	// Loop timings, queue depths and error counts, for telling which
+	// subsystem is stalled. Counting the queues reads every inbox, so
+	// only when asked.
+	if verbose, _ := req.Args["verbose"].(bool); verbose {
+		loops, errs := d.loopReport(now)
+		data["loops"] = loops
+		data["queues"] = d.queueDepths()
+		data["errors"] = errs
+	}
+
+	return socket.Response{Success: true, Data: data}
+}
+
+// handleReloadConfig rereads daemon.yaml and returns the settings that changed
+func (d *Daemon) handleReloadConfig(req socket.Request) socket.Response {
+	changes, err := d.reloadConfig("socket")
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("config not reloaded, current settings kept: %v", err)}
+	}
+	data := make([]map[string]string, 0, len(changes))
+	for _, change := range changes {
+		data = append(data, map[string]string{"key": change.Key, "old": change.Old, "new": change.New})
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// handleStandby enters or leaves standby. Resuming while on battery holds off
+// automatic standby until the machine is next unplugged.
+func (d *Daemon) handleStandby(req socket.Request) socket.Response {
+	enabled, ok := req.Args["enabled"].(bool)
+	if !ok {
+		return socket.Response{Success: false, Error: "missing required argument: enabled (bool)"}
+	}
+
+	d.standbyMu.Lock()
+	defer d.standbyMu.Unlock()
+
+	if enabled {
+		d.standbyHeld = false
+		if !d.standby {
+			d.enterStandbyLocked("manual")
+		} else {
+			d.standbyReason = "manual"
+		}
+	} else {
+		if onBattery, err := d.onBattery(); err == nil && onBattery {
+			d.standbyHeld = true
+		}
+		if d.standby {
+			d.exitStandbyLocked()
+		}
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"standby":        d.standby,
+			"standby_reason": d.standbyReason,
+		},
+	}
+}
+
+// handleListRepos lists all repositories with detailed status
+func (d *Daemon) handleListRepos(req socket.Request) socket.Response {
+	repos := d.state.GetAllRepos()
+
+	// Check if rich format is requested
+	rich, _ := req.Args["rich"].(bool)
+	if !rich {
+		// Return simple list for backward compatibility
+		repoNames := make([]string, 0, len(repos))
+		for name := range repos {
+			repoNames = append(repoNames, name)
+		}
+		return socket.Response{Success: true, Data: repoNames}
+	}
+
+	// Return detailed repo info
+	repoDetails := make([]map[string]interface{}, 0, len(repos))
+	for repoName, repo := range repos {
+		// Count agents by type
+		workerCount := 0
+		totalAgents := len(repo.Agents)
+		for _, agent := range repo.Agents {
+			if agent.Type == state.AgentTypeWorker {
+				workerCount++
+			}
+		}
+
+		// Check session health
+		sessionHealthy := false
+		if hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession); err == nil {
+			sessionHealthy = hasSession
+		}
+
+		// Determine PR management mode
+		prManagementMode := "merge-queue"
+		if repo.ForkConfig.IsFork {
+			prManagementMode = "pr-shepherd"
+		} else if repo.Solo {
+			prManagementMode = "none"
+		}
+
+		repoDetails = append(repoDetails, map[string]interface{}{
+			"name":               repoName,
+			"github_url":         repo.GithubURL,
+			"tmux_session":       repo.TmuxSession,
+			"total_agents":       totalAgents,
+			"worker_count":       workerCount,
+			"session_healthy":    sessionHealthy,
+			"is_fork":            repo.ForkConfig.IsFork,
+			"upstream_owner":     repo.ForkConfig.UpstreamOwner,
+			"upstream_repo":      repo.ForkConfig.UpstreamRepo,
+			"pr_management_mode": prManagementMode,
+			"solo":               repo.Solo,
+			"path":               repo.Path,
+		})
+	}
+
+	return socket.Response{Success: true, Data: repoDetails}
+}
+
+// handleAddRepo adds a new repository
+func (d *Daemon) handleAddRepo(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required (e.g., 'my-project')")
+	if !ok {
+		return errResp
+	}
+
+	// Solo repositories (multiclaude solo) have a working directory instead of a remote
+	solo, _ := req.Args["solo"].(bool)
+	githubURL, _ := req.Args["github_url"].(string)
+	path, _ := req.Args["path"].(string)
+	if solo {
+		if path == "" {
+			return socket.Response{Success: false, Error: "path is required for a solo repository"}
+		}
+	} else if githubURL == "" {
+		return socket.Response{Success: false, Error: "GitHub repository URL is required (e.g., 'https://github.com/owner/repo')"}
+	}
+
+	tmuxSession, errResp, ok := getRequiredStringArg(req.Args, "tmux_session", "tmux session name is required")
+	if !ok {
+		return errResp
+	}
+
+	// Parse merge queue configuration (optional, defaults to enabled with "all" tracking)
+	mqConfig := state.DefaultMergeQueueConfig()
+	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
+		mqConfig.Enabled = mqEnabled
+	}
+	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
+		mode, err := state.ParseTrackMode(mqTrackMode)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		mqConfig.TrackMode = mode
+	}
+	if testCommand, ok := req.Args["mq_test_command"].(string); ok {
+		mqConfig.TestCommand = strings.TrimSpace(testCommand)
+	}
+
+	// Spawn hooks (optional), e.g. from presets picked at init
+	var spawnHooks state.SpawnHooks
+	if preSpawn, ok := req.Args["pre_spawn"].(string); ok {
+		spawnHooks.PreSpawn = strings.TrimSpace(preSpawn)
+	}
+
+	// Parse fork configuration (optional)
+	var forkConfig state.ForkConfig
+	if isFork, ok := req.Args["is_fork"].(bool); ok {
+		forkConfig.IsFork = isFork
+	}
+	if upstreamURL, ok := req.Args["upstream_url"].(string); ok {
+		forkConfig.UpstreamURL = upstreamURL
+	}
+	if upstreamOwner, ok := req.Args["upstream_owner"].(string); ok {
+		forkConfig.UpstreamOwner = upstreamOwner
+	}
+	if upstreamRepo, ok := req.Args["upstream_repo"].(string); ok {
+		forkConfig.UpstreamRepo = upstreamRepo
+	}
+
+	// Parse PR shepherd configuration (optional, defaults for fork mode)
+	psConfig := state.DefaultPRShepherdConfig()
+	if psEnabled, ok := req.Args["ps_enabled"].(bool); ok {
+		psConfig.Enabled = psEnabled
+	}
+	if psTrackMode, ok := req.Args["ps_track_mode"].(string); ok {
+		mode, err := state.ParseTrackMode(psTrackMode)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		psConfig.TrackMode = mode
+	}
+
+	// If in fork mode, disable merge-queue and enable pr-shepherd by default
+	if forkConfig.IsFork {
+		mqConfig.Enabled = false
+		psConfig.Enabled = true
+	}
+
+	// Default branch detected at clone time (optional, detected on first use if missing)
+	targetBranch, _ := req.Args["target_branch"].(string)
+
+	repo := &state.Repository{
+		GithubURL:        githubURL,
+		TmuxSession:      tmuxSession,
+		Agents:           make(map[string]state.Agent),
+		MergeQueueConfig: mqConfig,
+		PRShepherdConfig: psConfig,
+		ForkConfig:       forkConfig,
+		TargetBranch:     targetBranch,
+		SpawnHooks:       spawnHooks,
+	}
+	if solo {
+		// Solo agents work alone: no merge queue or PR shepherd
+		repo.Solo = true
+		repo.Path = path
+		repo.MergeQueueConfig.Enabled = false
+		repo.PRShepherdConfig.Enabled = false
+	}
+
+	if err := d.state.AddRepo(name, repo); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	log := d.logger.WithTrace(req.TraceID)
+	if solo {
+		log.Info("Added solo repository: %s (%s)", name, path)
+	} else if forkConfig.IsFork {
+		log.Info("Added repository: %s (fork of %s/%s, pr-shepherd: enabled=%v)", name, forkConfig.UpstreamOwner, forkConfig.UpstreamRepo, psConfig.Enabled)
+	} else {
+		log.Info("Added repository: %s (merge queue: enabled=%v, track=%s)", name, mqConfig.Enabled, mqConfig.TrackMode)
+	}
+	// The checked-in config file applies before any agent starts
+	d.applyRepoConfigFile(name)
+	d.events.PublishTraced(req.TraceID, events.EventRepoAdded, name, "", nil)
+	return socket.Response{Success: true}
+}
+
+// handleRemoveRepo removes a repository from state
+func (d *Daemon) handleRemoveRepo(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	if err := d.state.RemoveRepo(name); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.WithTrace(req.TraceID).Info("Removed repository: %s", name)
+	d.events.PublishTraced(req.TraceID, events.EventRepoRemoved, name, "", nil)
+	return socket.Response{Success: true}
+}
+
+// handleAddAgent adds a new agent
+func (d *Daemon) handleAddAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentTypeStr, errResp, ok := getRequiredStringArg(req.Args, "type", "agent type is required (supervisor, worker, merge-queue, or reviewer)")
+	if !ok {
+		return errResp
+	}
+
+	worktreePath, errResp, ok := getRequiredStringArg(req.Args, "worktree_path", "path to the agent's git worktree is required")
+	if !ok {
+		return errResp
+	}
+
+	tmuxWindow, errResp, ok := getRequiredStringArg(req.Args, "tmux_window", "tmux window name is required")
+	if !ok {
+		return errResp
+	}
+
+	// Get session ID from args or generate one
+	sessionID, _, ok := getRequiredStringArg(req.Args, "session_id", "")
+	if !ok {
+		sessionID = fmt.Sprintf("agent-%d", time.Now().UnixNano())
+	}
+
+	// Get PID from args (optional)
+	var pid int
+	if pidFloat, ok := req.Args["pid"].(float64); ok {
+		pid = int(pidFloat)
+	} else if pidInt, ok := req.Args["pid"].(int); ok {
+		pid = pidInt
+	}
+
+	agent := state.Agent{
+		Type:         state.AgentType(agentTypeStr),
+		WorktreePath: worktreePath,
+		TmuxWindow:   tmuxWindow,
+		SessionID:    sessionID,
+		PID:          pid,
+		CreatedAt:    time.Now(),
+	}
+
+	// Optional task field for workers
+	if task, ok := req.Args["task"].(string); ok {
+		agent.Task = task
+	}
+
+	// Optional branch the worker's worktree was created on
+	if branch, ok := req.Args["branch"].(string); ok {
+		agent.Branch = branch
+	}
+
+	// Optional history ID of the task this worker retries
+	if retryOf, ok := req.Args["retry_of"].(string); ok {
+		agent.RetryOf = retryOf
+	}
+
+	// Optional acceptance criteria the worker must report on when it completes
+	if criteria, ok := req.Args["criteria"].([]interface{}); ok {
+		for _, c := range criteria {
+			if text, ok := c.(string); ok && strings.TrimSpace(text) != "" {
+				agent.Criteria = append(agent.Criteria, state.Criterion{Text: strings.TrimSpace(text), Status: state.CriterionPending})
+			}
+		}
+	}
+
+	// Adopted agents were started by hand (multiclaude adopt)
+	if adopted, ok := req.Args["adopted"].(bool); ok {
+		agent.Adopted = adopted
+	}
+
+	// Optional definition the agent was started from, counted towards its
+	// max-instances limit. Without one, core agents count towards their
+	// built-in definition.
+	if definition, ok := req.Args["definition"].(string); ok {
+		agent.Definition = definition
+	}
+	if agent.Definition == "" {
+		agent.Definition = coreDefinition(agent.Type)
+	}
+
+	// Optional base branch, for workers on something other than the default
+	// branch, such as a hotfix for a release branch
+	if base, ok := req.Args["base_branch"].(string); ok {
+		agent.BaseBranch = base
+	}
+
+	// Optional capabilities declared by the agent's definition
+	if caps, ok := req.Args["capabilities"].([]interface{}); ok {
+		for _, c := range caps {
+			if capability, ok := c.(string); ok {
+				if capability = agents.NormalizeCapability(capability); capability != "" {
+					agent.Capabilities = append(agent.Capabilities, capability)
+				}
+			}
+		}
+	}
+
+	// The limit is enforced here, where the agent is registered: the CLI's
+	// check_agent_quota beforehand only saves it starting one for nothing
+	release, err := d.reserveInstance(repoName, agentName, agent.Type, agent.Definition)
+	if err != nil {
+		return errorResponse(err)
+	}
+	defer release()
+
+	if err := d.state.AddAgent(repoName, agentName, agent); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.statusCache.Invalidate(worktreePath)
+
+	if agent.RetryOf != "" {
+		if err := d.state.MarkTaskHistoryRetried(repoName, agent.RetryOf, agentName); err != nil {
+			d.logger.Warn("Failed to link retry %s to task %s: %v", agentName, agent.RetryOf, err)
+		}
+	}
+
+	d.logger.WithTrace(req.TraceID).Info("Added agent %s to repo %s", agentName, repoName)
+	d.events.PublishTraced(req.TraceID, events.EventAgentStarted, repoName, agentName, map[string]string{"type": string(agent.Type), "task": agent.Task})
+
+	// Workers created by the CLI are running by the time they're registered
+	if agent.Type == state.AgentTypeWorker && !agent.Adopted {
+		d.runPostSpawnHook(spawnTarget{Repo: repoName, Agent: agentName, Worktree: worktreePath, Branch: agent.Branch, Task: agent.Task})
+	}
+	return socket.Response{Success: true}
+}
+
+// handleRemoveAgent removes an agent
+func (d *Daemon) handleRemoveAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	// Keep removed workers in the task history so their task can be retried
+	if agent, exists := d.state.GetAgent(repoName, agentName); exists {
+		if agent.Type == state.AgentTypeWorker && agent.Task != "" {
+			d.recordTaskHistory(repoName, agentName, agent)
+		}
+		d.statusCache.Invalidate(agent.WorktreePath)
+	}
+
+	if err := d.state.RemoveAgent(repoName, agentName); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.archiveAgentOutput(repoName, agentName)
+
+	d.logger.WithTrace(req.TraceID).Info("Removed agent %s from repo %s", agentName, repoName)
+	d.events.PublishTraced(req.TraceID, events.EventAgentRemoved, repoName, agentName, nil)
+	return socket.Response{Success: true}
+}
+
+// handleListAgents lists agents for a repository
+func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
+	// Check if rich format is requested
+	rich, _ := req.Args["rich"].(bool)
+
+	// PR status needs gh, so it's only looked up on request
+	withPRs, _ := req.Args["pr_status"].(bool)
+
+	// all lists the agents of every repository, by repository name, in one call
+	if all, _ := req.Args["all"].(bool); all {
+		repoNames := d.state.ListRepos()
+		sort.Strings(repoNames)
+		agentDetails := make([]map[string]interface{}, 0)
+		for _, repoName := range repoNames {
+			details, err := d.agentDetails(repoName, rich, withPRs)
+			if err != nil {
+				continue
+			}
+			agentDetails = append(agentDetails, details...)
+		}
+		return socket.Response{Success: true, Data: agentDetails}
+	}
+
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required (or all)")
+	if !ok {
+		return errResp
+	}
+	agentDetails, err := d.agentDetails(repoName, rich, withPRs)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: agentDetails}
+}
+
+// agentDetails describes a repository's agents for list_agents, sorted by
+// name. rich adds status, branch and message counts, and withPRs the PR of
+// each worker's branch.
+func (d *Daemon) agentDetails(repoName string, rich, withPRs bool) ([]map[string]interface{}, error) {
+	agents, err := d.state.ListAgents(repoName)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(agents)
+
+	var prs map[string]pullRequest
+	if rich && withPRs {
+		var err error
+		if prs, err = d.repoPullRequests(repoName); err != nil {
+			d.logger.Debug("Could not look up PRs for %s: %v", repoName, err)
+		}
+	}
+
+	// Get repository to check session
+	repo, repoExists := d.state.GetRepo(repoName)
+
+	// Get full agent details
+	agentDetails := make([]map[string]interface{}, 0, len(agents))
+	for _, agentName := range agents {
+		agent, exists := d.state.GetAgent(repoName, agentName)
+		if !exists {
+			continue
+		}
+
+		detail := map[string]interface{}{
+			"repo":          repoName,
+			"name":          agentName,
+			"type":          agent.Type,
+			"worktree_path": agent.WorktreePath,
+			"tmux_window":   agent.TmuxWindow,
+			"task":          agent.Task,
+			"created_at":    agent.CreatedAt,
+			"capabilities":  agent.Capabilities,
+			"adopted":       agent.Adopted,
+		}
+		if agent.BaseBranch != "" {
+			detail["base_branch"] = agent.BaseBranch
+		}
+		if editor := openEditor(agent); editor != nil {
+			detail["editor"] = editor
+		}
+
+		// Add rich status information if requested
+		if rich {
+			// Determine agent status
+			status := "unknown"
+			if agent.ReadyForCleanup {
+				status = "completed"
+			} else if repoExists {
+				// Check if window exists (means agent is running)
+				hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
+				if err == nil && hasWindow && agent.Paused {
+					status = "paused"
+				} else if err == nil && hasWindow {
+					status = "running"
+				} else {
+					status = "stopped"
+				}
+			}
+			detail["status"] = status
+
+			// Branch, ahead/behind, changed files and last commit from one git status
+			branch := ""
+			if agent.WorktreePath != "" {
+				status, err := d.statusCache.Get(agent.WorktreePath, func() (worktree.Status, error) {
+					return d.worktreeManager(repoName).Status(agent.WorktreePath)
+				})
+				if err == nil {
+					branch = status.Branch
+					detail["worktree_status"] = status
+				}
+			}
+			detail["branch"] = branch
+
+			// PR status for workers, from one cached gh query per repository
+			if withPRs && agent.Type == state.AgentTypeWorker {
+				if pr, ok := prs[branch]; ok && branch != "" {
+					detail["pr_status"] = pr.status()
+					detail["pr_number"] = pr.Number
+					detail["pr_url"] = pr.URL
+				} else if prs != nil {
+					detail["pr_status"] = "no-pr"
+				}
+			}
+
+			// Get message counts
+			msgManager := messages.NewManager(d.paths.MessagesDir)
+			allMsgs, _ := msgManager.List(repoName, agentName)
+			pendingCount := 0
+			for _, msg := range allMsgs {
+				if msg.Status == messages.StatusPending || msg.Status == messages.StatusDelivered {
+					pendingCount++
+				}
+			}
+			detail["messages_total"] = len(allMsgs)
+			detail["messages_pending"] = pendingCount
+
+			if agent.Resources != nil {
+				detail["resources"] = agent.Resources
+			}
+		}
+
+		agentDetails = append(agentDetails, detail)
+	}
+
+	return agentDetails, nil
+}
+
+// prStatusCacheTTL is how long a repository's PR list is reused before gh is queried again
+const prStatusCacheTTL = 2 * time.Minute
+
+// statusCacheTTL is how long a worktree's git status is reused
+const statusCacheTTL = 30 * time.Second
+
+// prListLimit caps how many recent PRs one gh query returns per repository
+const prListLimit = 200
+
+// pullRequest is a PR as returned by `gh pr list --json`
+type pullRequest struct {
+	Number      int    `json:"number"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+	HeadRefName string `json:"headRefName"`
+	HeadRefOid  string `json:"headRefOid"`
+	BaseRefName string `json:"baseRefName"`
+	IsDraft     bool   `json:"isDraft"`
+}
+
+// status returns the PR state as shown in listings: open, draft, merged,
+// closed or unknown. Drafts are kept out of the merge train.
+func (pr pullRequest) status() string {
+	switch s := strings.ToLower(pr.State); s {
+	case "open":
+		if pr.IsDraft {
+			return "draft"
+		}
+		return s
+	case "merged", "closed":
+		return s
+	default:
+		return "unknown"
+	}
+}
+
+// listPullRequests lists a repository's recent PRs with a single gh call
+func (d *Daemon) listPullRequests(repoPath string) ([]pullRequest, error) {
+	output, err := d.github().Output(d.ctx, func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "gh", "pr", "list", "--state", "all", "--limit", fmt.Sprint(prListLimit), "--json", "number,state,url,headRefName,headRefOid,baseRefName,isDraft")
+		cmd.Dir = repoPath
+		return cmd
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gh pr list failed: %w", err)
+	}
+
+	var prs []pullRequest
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse gh output: %w", err)
+	}
+	return prs, nil
+}
+
+// repoPullRequests returns a repository's PRs keyed by head branch, from cache
+// when fresh. gh lists newest first, so a branch maps to its latest PR.
+func (d *Daemon) repoPullRequests(repoName string) (map[string]pullRequest, error) {
+	if err := d.requireGitHub(); err != nil {
+		return nil, err
+	}
+	return d.prCache.Get(repoName, func() (map[string]pullRequest, error) {
+		prs, err := d.listPRs(d.paths.RepoDir(repoName))
+		if err != nil {
+			return nil, err
+		}
+		byBranch := make(map[string]pullRequest, len(prs))
+		for _, pr := range prs {
+			if _, seen := byBranch[pr.HeadRefName]; !seen {
+				byBranch[pr.HeadRefName] = pr
+			}
+		}
+		d.linkWorkerPRs(repoName, byBranch)
+		return byBranch, nil
+	})
+}
+
+// handlePRStatus returns the PR status of branches in a repository. All
+// branches are answered from one cached gh query; "refresh" bypasses the cache.
+func (d *Daemon) handlePRStatus(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	if _, exists := d.state.GetRepo(repoName); !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+
+	if refresh, _ := req.Args["refresh"].(bool); refresh {
+		d.prCache.Invalidate(repoName)
+	}
+
+	prs, err := d.repoPullRequests(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	branches, _ := req.Args["branches"].([]interface{})
+	result := make(map[string]interface{}, len(branches))
+	for _, b := range branches {
+		branch, ok := b.(string)
+		if !ok || branch == "" {
+			continue
+		}
+		pr, found := prs[branch]
+		if !found {
+			result[branch] = map[string]interface{}{"status": "no-pr"}
+			continue
+		}
+		result[branch] = map[string]interface{}{
+			"status": pr.status(),
+			"number": pr.Number,
+			"url":    pr.URL,
+		}
+	}
+
+	return socket.Response{Success: true, Data: result}
+}
+
+// markPullRequestReady takes a PR out of draft
+func (d *Daemon) markPullRequestReady(repoPath string, number int) error {
+	output, err := d.github().CombinedOutput(d.ctx, func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "gh", "pr", "ready", strconv.Itoa(number))
+		cmd.Dir = repoPath
+		return cmd
+	})
+	if err != nil {
+		return fmt.Errorf("gh pr ready failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// handlePRReady marks a worker's draft PR ready for review and tells the
+// merge queue, which ignores drafts, that it can take the PR. A PR that is
+// already ready is only announced again.
+func (d *Daemon) handlePRReady(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("worker", agentName, repoName))
+	}
+	pr, err := d.workerPR(repoName, agentName, agent)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	branch := pr.HeadRefName
+	if err := d.requirePushChecks(repoName, agentName, agent, true); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	wasDraft := pr.IsDraft
+	if wasDraft {
+		if err := d.markPRReady(d.paths.RepoDir(repoName), pr.Number); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.prCache.Invalidate(repoName)
+		d.logger.Info("Marked PR #%d (%s) in %s ready for review", pr.Number, branch, repoName)
+	}
+	d.events.Publish(events.EventPRReady, repoName, agentName, map[string]string{"pr": strconv.Itoa(pr.Number), "branch": branch})
+	d.describeWorkerPR(repoName, agentName, agent, pr)
+
+	notified := false
+	if _, exists := d.state.GetAgent(repoName, "merge-queue"); exists {
+		body := fmt.Sprintf("PR #%d (%s) from %s is out of draft and ready for the merge queue: %s", pr.Number, branch, agentName, pr.URL) + baseBranchNote(agent)
+		if _, err := d.getMessageManager().Send(repoName, "daemon", "merge-queue", body); err != nil {
+			d.logger.Warn("Failed to tell the merge queue about PR #%d: %v", pr.Number, err)
+		} else {
+			notified = true
+			go d.routeMessages()
+		}
+	}
+
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"pr_number": pr.Number,
+		"pr_url":    pr.URL,
+		"was_draft": wasDraft,
+		"notified":  notified,
+	}}
+}
+
+// handleCompleteAgent marks an agent as ready for cleanup
+func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+
+	// Workers with acceptance criteria must report on every one of them
+	if len(agent.Criteria) > 0 || req.Args["criteria"] != nil {
+		criteria, err := reportCriteria(agent.Criteria, req.Args["criteria"])
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		agent.Criteria = criteria
+	}
+
+	// A worker that finished its task must leave a branch that passes the
+	// push checks; one giving up with a failure reason needn't, and one
+	// whose checks can't be run isn't held up
+	if failureReason, _ := req.Args["failure_reason"].(string); failureReason == "" && agent.Type == state.AgentTypeWorker {
+		if err := d.requirePushChecks(repoName, agentName, agent, false); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+	}
+
+	// Mark as ready for cleanup
+	agent.ReadyForCleanup = true
+
+	// Optional: capture summary and failure reason for task history
+	if summary, ok := req.Args["summary"].(string); ok && summary != "" {
+		agent.Summary = summary
+	}
+	if failureReason, ok := req.Args["failure_reason"].(string); ok && failureReason != "" {
+		agent.FailureReason = failureReason
+	}
+
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.WithTrace(req.TraceID).Info("Agent %s/%s marked as ready for cleanup", repoName, agentName)
+	if agent.FailureReason != "" {
+		d.events.PublishTraced(req.TraceID, events.EventTaskFailed, repoName, agentName, map[string]string{"task": agent.Task, "reason": agent.FailureReason})
+	} else {
+		d.events.PublishTraced(req.TraceID, events.EventTaskCompleted, repoName, agentName, map[string]string{"task": agent.Task, "summary": agent.Summary})
+	}
+
+	d.rememberCompletion(repoName, agentName, agent)
+	if agent.Type == state.AgentTypeReview {
+		d.finishCurrentReview(repoName, agentName)
+	}
+
+	// A finished worker has usually just opened or updated a PR. Its
+	// description is written from the worktree, before it's cleaned up.
+	d.prCache.Invalidate(repoName)
+	if repo, exists := d.state.GetRepo(repoName); exists && repo.PRDescriptions.Enabled && agent.Type == state.AgentTypeWorker {
+		if pr, err := d.workerPR(repoName, agentName, agent); err == nil {
+			d.describeWorkerPR(repoName, agentName, agent, pr)
+		} else {
+			d.logger.Info("Not describing the PR of %s/%s: %v", repoName, agentName, err)
+		}
+	}
+	go d.advanceTasks()
+
+	// Notify supervisor and merge-queue that worker or review agent completed
+	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview {
+		msgMgr := d.getMessageManager()
+		task := agent.Task
+		if task == "" {
+			task = "unknown task"
+		}
+
+		if agent.Type == state.AgentTypeWorker {
+			// Notify supervisor
+			supervisorMessage := fmt.Sprintf("Worker '%s' has completed its task: %s", agentName, task)
+			if len(agent.Criteria) > 0 {
+				supervisorMessage += "\n\nAcceptance criteria:\n" + formatCriteria(agent.Criteria)
+			}
+			if _, err := msgMgr.Send(repoName, agentName, "supervisor", supervisorMessage); err != nil {
+				d.logger.Error("Failed to send completion message to supervisor: %v", err)
+			} else {
+				d.logger.Info("Sent completion notification to supervisor for worker %s", agentName)
+			}
+
+			// Notify merge-queue so it can process any new PRs immediately
+			mergeQueueMessage := fmt.Sprintf("Worker '%s' has completed and may have created a PR. Task: %s. Please check for new PRs to process.", agentName, task) + baseBranchNote(agent)
+			if _, err := msgMgr.Send(repoName, agentName, "merge-queue", mergeQueueMessage); err != nil {
+				d.logger.Error("Failed to send completion message to merge-queue: %v", err)
+			} else {
+				d.logger.Info("Sent completion notification to merge-queue for worker %s", agentName)
+			}
+		} else if agent.Type == state.AgentTypeReview {
+			// Review agent completed - notify merge-queue to process the review results
+			mergeQueueMessage := fmt.Sprintf("Review agent '%s' has completed its review. Task: %s. Please check the review summary and decide on next steps.", agentName, task)
+			if _, err := msgMgr.Send(repoName, agentName, "merge-queue", mergeQueueMessage); err != nil {
+				d.logger.Error("Failed to send completion message to merge-queue: %v", err)
+			} else {
+				d.logger.Info("Sent completion notification to merge-queue for review agent %s", agentName)
+			}
+		}
+
+		// Trigger immediate message delivery
+		go d.routeMessages()
+	}
+
+	// Trigger immediate cleanup check
+	go d.checkAgentHealth()
+
+	return socket.Response{Success: true}
+}
+
+// handleAddMemory adds a note to an agent's memory
+func (d *Daemon) handleAddMemory(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+	note, errResp, ok := getRequiredStringArg(req.Args, "note", "the note to remember is required")
+	if !ok {
+		return errResp
+	}
+	if _, exists := d.state.GetAgent(repoName, agentName); !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+
+	if err := d.remember(repoName, agentName, memory.Entry{Time: time.Now(), Source: memory.SourceNote, Text: note}); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.WithTrace(req.TraceID).Debug("Added a note to the memory of %s/%s", repoName, agentName)
+	return socket.Response{Success: true, Data: map[string]interface{}{"path": d.paths.MemoryFile(repoName, agentName)}}
+}
+
+// handleGetMemory returns an agent's memory entries, oldest first
+func (d *Daemon) handleGetMemory(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	path := d.paths.MemoryFile(repoName, agentName)
+	d.memoryMu.Lock()
+	entries, err := memory.Load(path)
+	d.memoryMu.Unlock()
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to read memory: %v", err)}
+	}
+	if entries == nil {
+		entries = []memory.Entry{}
+	}
+	return socket.Response{Success: true, Data: map[string]interface{}{"path": path, "entries": entries}}
+}
+
+// handleClearMemory deletes an agent's memory
+func (d *Daemon) handleClearMemory(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	d.memoryMu.Lock()
+	defer d.memoryMu.Unlock()
+	if err := os.Remove(d.paths.MemoryFile(repoName, agentName)); err != nil && !os.IsNotExist(err) {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to clear memory: %v", err)}
+	}
+	d.refreshMemoryPrompt(repoName, agentName)
+	d.logger.WithTrace(req.TraceID).Info("Cleared the memory of %s/%s", repoName, agentName)
+	return socket.Response{Success: true}
+}
+
+// remember adds an entry to an agent's memory and to its saved prompt, so
+// the agent sees it when next started or resumed
+func (d *Daemon) remember(repoName, agentName string, e memory.Entry) error {
+	d.memoryMu.Lock()
+	defer d.memoryMu.Unlock()
+	if err := memory.Append(d.paths.MemoryFile(repoName, agentName), repoName, agentName, e); err != nil {
+		return err
+	}
+	d.refreshMemoryPrompt(repoName, agentName)
+	return nil
+}
+
+// rememberCompletion records a finished task in the worker's memory and, for
+// workers, in the supervisor's, so the supervisor keeps track of long-running
+// work across restarts
+func (d *Daemon) rememberCompletion(repoName, agentName string, agent state.Agent) {
+	if agent.Type != state.AgentTypeWorker && agent.Type != state.AgentTypeReview {
+		return
+	}
+	task, _, _ := strings.Cut(strings.TrimSpace(agent.Task), "\n")
+	if r := []rune(task); len(r) > 200 {
+		task = string(r[:200]) + "..."
+	}
+	outcome := "Completed: " + task
+	if agent.FailureReason != "" {
+		outcome = "Failed: " + task + "\nReason: " + agent.FailureReason
+	} else if agent.Summary != "" {
+		outcome += "\nSummary: " + agent.Summary
+	}
+	if r := []rune(outcome); len(r) > memory.MaxEntryBytes/4 {
+		outcome = string(r[:memory.MaxEntryBytes/4]) + "..."
+	}
+
+	now := time.Now()
+	if err := d.remember(repoName, agentName, memory.Entry{Time: now, Source: memory.SourceTask, Text: outcome}); err != nil {
+		d.logger.Warn("Failed to record the task of %s/%s in its memory: %v", repoName, agentName, err)
+	}
+	if agent.Type != state.AgentTypeWorker {
+		return
+	}
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	if err := d.remember(repoName, "supervisor", memory.Entry{Time: now, Source: memory.SourceWorker, About: agentName, Text: outcome}); err != nil {
+		d.logger.Warn("Failed to record the task of %s/%s in the supervisor's memory: %v", repoName, agentName, err)
+	}
+}
+
+// memorySection returns the memory section of an agent's prompt
+func (d *Daemon) memorySection(repoName, agentName string) string {
+	path := d.paths.MemoryFile(repoName, agentName)
+	entries, err := memory.Load(path)
+	if err != nil {
+		d.logger.Warn("Failed to read the memory of %s/%s: %v", repoName, agentName, err)
+	}
+	return prompts.MemorySection(entries, path)
+}
+
+// refreshMemoryPrompt replaces the memory section of an agent's saved prompt,
+// if it has one. The caller holds memoryMu.
+func (d *Daemon) refreshMemoryPrompt(repoName, agentName string) {
+	promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return
+	}
+	updated := prompts.SetMemorySection(string(data), d.memorySection(repoName, agentName))
+	if updated == string(data) {
+		return
+	}
+	if err := os.WriteFile(promptFile, []byte(updated), 0644); err != nil {
+		d.logger.Warn("Failed to update memory in prompt for %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// cleanMemory deletes the memory of agents removed more than
+// memory.Retention ago
+func (d *Daemon) cleanMemory() {
+	d.memoryMu.Lock()
+	defer d.memoryMu.Unlock()
+	deleted, err := memory.Clean(d.paths.MemoryDir(), func(repoName, agentName string) bool {
+		_, exists := d.state.GetAgent(repoName, agentName)
+		return exists
+	}, time.Now())
+	if err != nil {
+		d.logger.Error("Failed to clean agent memory: %v", err)
+	}
+	for _, path := range deleted {
+		d.logger.Info("Deleted the memory of a removed agent: %s", path)
+	}
+}
+
+// reportCriteria applies a worker's per-criterion report to its acceptance
+// criteria. The report is a list of {"index": n, "status": "met"|"unmet",
+// "note": "..."} with 1-based indexes, and must cover every criterion.
+func reportCriteria(criteria []state.Criterion, report interface{}) ([]state.Criterion, error) {
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("agent has no acceptance criteria to report on")
+	}
+	items, ok := report.([]interface{})
+	if !ok {
+		items = nil
+	}
+
+	// Build a new slice: the agent's criteria may share a backing array with a state snapshot
+	updated := make([]state.Criterion, len(criteria))
+	copy(updated, criteria)
+	reported := make([]bool, len(criteria))
+	for _, item := range items {
+		m, _ := item.(map[string]interface{})
+		index, _ := m["index"].(float64)
+		i := int(index) - 1
+		if i < 0 || i >= len(criteria) {
+			return nil, fmt.Errorf("criterion %v does not exist (the task has %d acceptance criteria)", m["index"], len(criteria))
+		}
+		status := state.CriterionStatus(fmt.Sprint(m["status"]))
+		if status != state.CriterionMet && status != state.CriterionUnmet {
+			return nil, fmt.Errorf("criterion %d: status must be 'met' or 'unmet', got %q", i+1, status)
+		}
+		note, _ := m["note"].(string)
+		updated[i].Status = status
+		updated[i].Note = note
+		reported[i] = true
+	}
+
+	var missing []string
+	for i, ok := range reported {
+		if !ok {
+			missing = append(missing, strconv.Itoa(i+1))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("report on every acceptance criterion before completing (missing: %s): multiclaude agent complete --criterion <n>=met|unmet[:note] ...\n%s",
+			strings.Join(missing, ", "), formatCriteria(criteria))
+	}
+	return updated, nil
+}
+
+// formatCriteria lists acceptance criteria one per line with their status
+func formatCriteria(criteria []state.Criterion) string {
+	var b strings.Builder
+	for i, c := range criteria {
+		fmt.Fprintf(&b, "%d. [%s] %s", i+1, c.Status, c.Text)
+		if c.Note != "" {
+			b.WriteString(" - " + c.Note)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// interruptKeys maps interrupt_agent's key arg to the tmux key sent
+var interruptKeys = map[string]string{
+	"escape": "Escape",
+	"ctrl-c": "C-c",
+}
+
+// handleInterruptAgent stops an agent mid-turn, the way a human at its window
+// would: Escape (or Ctrl-C) is sent to its pane and, optionally, SIGINT to
+// Claude. The agent keeps its session and waits for its next instructions,
+// which can come with the interrupt as a message.
+func (d *Daemon) handleInterruptAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	keyName, _ := req.Args["key"].(string)
+	if keyName == "" {
+		keyName = "escape"
+	}
+	key, ok := interruptKeys[keyName]
+	if !ok {
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid key %q: must be 'escape' or 'ctrl-c'", keyName)}
+	}
+	signal, _ := req.Args["signal"].(bool)
+	by, _ := req.Args["by"].(string)
+	message, _ := req.Args["message"].(string)
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+
+	if err := d.tmux.SendKey(d.ctx, repo.TmuxSession, agent.TmuxWindow, key); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to interrupt agent '%s': %v", agentName, err)}
+	}
+	data := map[string]string{"key": keyName}
+	if signal {
+		if agent.PID <= 0 {
+			return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' has no recorded PID to signal", agentName)}
+		}
+		if err := interruptPaneProcesses(agent.PID); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("sent %s to agent '%s', but SIGINT failed: %v", keyName, agentName, err)}
+		}
+		data["signal"] = "sigint"
+	}
+	if by != "" {
+		data["by"] = by
+	}
+	d.logger.Info("Interrupted agent %s/%s (%s, by %q)", repoName, agentName, keyName, by)
+	d.events.Publish(events.EventAgentInterrupted, repoName, agentName, data)
+
+	if message != "" {
+		from := by
+		if from == "" {
+			from = "daemon"
+		}
+		if _, err := d.getMessageManager().Send(repoName, from, agentName, message); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("interrupted agent '%s', but failed to send the message: %v", agentName, err)}
+		}
+		go d.routeMessages()
+	}
+
+	return socket.Response{Success: true, Data: map[string]interface{}{"key": keyName, "signal": signal}}
+}
+
+// handleRestartAgent restarts an agent that has crashed or exited
+func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	force, _ := req.Args["force"].(bool)
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+
+	// Check if agent is marked for cleanup (completed)
+	if agent.ReadyForCleanup {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)}
+	}
+
+	// Check if tmux window exists
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+
+	hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agentName)
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to check tmux window: %v", err)}
+	}
+	if !hasWindow {
+		return socket.Response{Success: false, Error: fmt.Sprintf("tmux window '%s' does not exist - the agent may need to be recreated", agentName)}
+	}
+
+	// Check if agent is already running
+	if agent.PID > 0 && isProcessAlive(agent.PID) {
+		if !force {
+			return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is already running with PID %d - use --force to restart anyway", agentName, agent.PID)}
+		}
+		d.logger.WithTrace(req.TraceID).Info("Force restarting agent %s (PID %d was still running)", agentName, agent.PID)
+	}
+
+	// Restart the agent
+	if err := d.restartAgent(req.TraceID, repoName, agentName, agent, repo); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to restart agent: %v", err)}
+	}
+
+	// Get updated PID from state
+	updatedAgent, _ := d.state.GetAgent(repoName, agentName)
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"agent":   agentName,
+			"repo":    repoName,
+			"pid":     updatedAgent.PID,
+			"message": fmt.Sprintf("Agent '%s' restarted successfully", agentName),
+		},
+	}
+}
+
+// handleReinitRepo repairs a tracked repository in place. It re-clones or
+// re-points the clone, restores the agent definitions, upstream remote and
+// tmux session, and starts or restarts the supervisor and merge queue (or PR
+// shepherd). Workers, workspaces, worktrees and history are left alone.
+func (d *Daemon) handleReinitRepo(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	repo, exists := d.state.GetRepo(name)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(name).WithSuggestion("multiclaude init <github-url> " + name))
+	}
+	if repo.Solo {
+		return socket.Response{Success: false, Error: fmt.Sprintf("'%s' is a solo session, not a cloned repository", name)}
+	}
+
+	log := d.logger.WithTrace(req.TraceID)
+	var actions []string
+	did := func(format string, args ...interface{}) {
+		action := fmt.Sprintf(format, args...)
+		actions = append(actions, action)
+		log.Info("Reinit %s: %s", name, action)
+	}
+	fail := func(format string, args ...interface{}) socket.Response {
+		return socket.Response{Success: false, Error: fmt.Sprintf(format, args...), Data: map[string]interface{}{"actions": actions}}
+	}
+
+	url := repo.GithubURL
+	if newURL, _ := req.Args["github_url"].(string); newURL != "" && newURL != url {
+		if err := d.state.UpdateGithubURL(name, newURL); err != nil {
+			return fail("failed to update the repository URL: %v", err)
+		}
+		did("changed the repository URL from %s to %s", url, newURL)
+		url = newURL
+	}
+
+	// The clone
+	repoPath := d.paths.RepoDir(name)
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		if out, err := d.git("clone", url, repoPath); err != nil {
+			return fail("failed to clone %s: %v\n%s", url, err, strings.TrimSpace(string(out)))
+		}
+		did("cloned %s", url)
+	} else {
+		out, err := d.git("-C", repoPath, "remote", "get-url", "origin")
+		current := strings.TrimSpace(string(out))
+		if err != nil || current != url {
+			args := []string{"-C", repoPath, "remote", "set-url", "origin", url}
+			if err != nil {
+				args[3] = "add"
+			}
+			if out, err := d.git(args...); err != nil {
+				return fail("failed to point origin at %s: %v\n%s", url, err, strings.TrimSpace(string(out)))
+			}
+			if current != "" {
+				did("pointed origin at %s (was %s)", url, current)
+			} else {
+				did("added origin %s", url)
+			}
+		}
+	}
+	if fc := repo.ForkConfig; fc.IsFork && fc.UpstreamURL != "" && !fork.HasUpstreamRemote(repoPath) {
+		if err := fork.AddUpstreamRemote(repoPath, fc.UpstreamURL); err != nil {
+			return fail("failed to add the upstream remote: %v", err)
+		}
+		did("added the upstream remote %s", fc.UpstreamURL)
+	}
+
+	// Agent definitions are the user's to edit, so only missing ones are restored
+	agentsDir := d.paths.RepoAgentsDir(name)
+	if _, err := os.Stat(agentsDir); os.IsNotExist(err) {
+		if err := templates.CopyAgentTemplates(agentsDir); err != nil {
+			return fail("failed to restore agent definitions: %v", err)
+		}
+		did("restored agent definitions in %s", agentsDir)
+	}
+	if err := hooks.CopyConfig(repoPath, repoPath); err != nil {
+		log.Warn("Reinit %s: failed to copy hooks config: %v", name, err)
+	}
+
+	// The session and core agents
+	hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+	if err != nil {
+		return fail("failed to check tmux session: %v", err)
+	}
+	if !hasSession {
+		if err := d.tmuxCommand("new-session", "-d", "-s", repo.TmuxSession, "-n", "supervisor", "-c", repoPath); err != nil {
+			return fail("failed to create tmux session %s: %v", repo.TmuxSession, err)
+		}
+		did("created tmux session %s", repo.TmuxSession)
+	}
+
+	core := []state.AgentType{state.AgentTypeSupervisor}
+	if d.state.IsForkMode(name) {
+		if repo.PRShepherdConfig.Enabled {
+			core = append(core, state.AgentTypePRShepherd)
+		}
+	} else if repo.MergeQueueConfig.Enabled {
+		core = append(core, state.AgentTypeMergeQueue)
+	}
+	for _, agentType := range core {
+		action, err := d.reinitAgent(req.TraceID, name, repo, agentType, repoPath)
+		if err != nil {
+			return fail("failed to start %s: %v", agentType, err)
+		}
+		if action != "" {
+			did("%s", action)
+		}
+	}
+
+	return socket.Response{Success: true, Data: map[string]interface{}{"actions": actions}}
+}
+
+// reinitAgent makes sure one of a repository's core agents, named after its
+// type, is running. An untracked agent is started fresh; a tracked one whose
+// window or process is gone is restarted, resuming its session. It returns
+// what it did, or "" if the agent was already running.
+func (d *Daemon) reinitAgent(traceID, repoName string, repo *state.Repository, agentType state.AgentType, workDir string) (string, error) {
+	agentName := string(agentType)
+	hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agentName)
+	if err != nil {
+		return "", fmt.Errorf("failed to check window: %w", err)
+	}
+	agent, tracked := d.state.GetAgent(repoName, agentName)
+	if tracked && hasWindow && (agent.PID <= 0 || isProcessAlive(agent.PID)) {
+		return "", nil
+	}
+
+	if !hasWindow {
+		if err := d.tmuxCommand("new-window", "-d", "-t", repo.TmuxSession, "-n", agentName, "-c", workDir); err != nil {
+			return "", fmt.Errorf("failed to create window: %w", err)
+		}
+	}
+
+	if tracked {
+		if err := d.restartAgent(traceID, repoName, agentName, agent, repo); err != nil {
+			return "", err
+		}
+		if !hasWindow {
+			return fmt.Sprintf("restarted %s in a new window", agentName), nil
+		}
+		return fmt.Sprintf("restarted %s (its process had exited)", agentName), nil
+	}
+
+	prefix, err := prompts.WriteFragments(d.paths.PromptFragmentsDir(agentName), coreAgentFragments(repo, agentType))
+	if err != nil {
+		return "", err
+	}
+	promptFile, err := d.writePromptFileWithPrefix(repoName, agentType, agentName, prefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	if err := d.startAgentWithConfig(repoName, repo, agentStartConfig{
+		agentName:  agentName,
+		agentType:  agentType,
+		promptFile: promptFile,
+		workDir:    workDir,
+	}); err != nil {
+		return "", err
+	}
+	d.events.PublishTraced(traceID, events.EventAgentStarted, repoName, agentName, map[string]string{"type": agentName})
+	return fmt.Sprintf("started %s", agentName), nil
+}
+
+// coreAgentFragments returns the config fragments init puts ahead of the
+// merge queue's and PR shepherd's prompts
+func coreAgentFragments(repo *state.Repository, agentType state.AgentType) []prompts.Fragment {
+	switch agentType {
+	case state.AgentTypeMergeQueue:
+		return prompts.MergeQueueFragments(string(repo.MergeQueueConfig.TrackMode))
+	case state.AgentTypePRShepherd:
+		fc := repo.ForkConfig
+		return prompts.PRShepherdFragments(string(repo.PRShepherdConfig.TrackMode), fc.UpstreamOwner, fc.UpstreamRepo)
+	}
+	return nil
+}
+
+// handleTriggerCleanup manually triggers cleanup operations
+func (d *Daemon) handleTriggerCleanup(req socket.Request) socket.Response {
+	d.logger.Info("Manual cleanup triggered")
+
+	// Run health check to find dead agents
+	d.checkAgentHealth()
+
+	return socket.Response{
+		Success: true,
+		Data:    "Cleanup triggered",
+	}
+}
+
+// handleRepairState repairs state inconsistencies
+func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
+	d.logger.Info("State repair triggered")
+	d.snapshotState("before repair")
+
+	agentsRemoved := 0
+	issuesFixed := 0
+
+	// Get a snapshot of repos to avoid concurrent map access
+	repos := d.state.GetAllRepos()
+
+	// Check all agents and verify resources exist
+	for repoName, repo := range repos {
+		// Check tmux session
+		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+		if err != nil {
+			d.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
+			continue
+		}
+
+		if !hasSession {
+			d.logger.Warn("Tmux session %s not found, removing all agents for repo %s", repo.TmuxSession, repoName)
+			// Remove all agents for this repo
+			for agentName := range repo.Agents {
+				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
+					agentsRemoved++
+				}
+			}
+			issuesFixed++
+			continue
+		}
+
+		// Check each agent's resources
+		for agentName, agent := range repo.Agents {
+			hasWindow, _ := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
+			if !hasWindow {
+				d.logger.Info("Removing agent %s (window not found)", agentName)
+				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
+					agentsRemoved++
+					issuesFixed++
+				}
+				continue
+			}
+
+			// Check if worktree exists (for workers and review agents)
+			if (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) && agent.WorktreePath != "" {
+				if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
+					d.logger.Warn("Worktree missing for agent %s, but window exists - keeping agent", agentName)
+					// Don't remove - user might have manually deleted worktree
+				}
+			}
+		}
+	}
+
+	// Clean up orphaned worktrees
+	d.cleanupOrphanedWorktrees()
+
+	// Clean up orphaned message directories
+	msgMgr := d.getMessageManager()
+	repoNames := d.state.ListRepos()
+	for _, repoName := range repoNames {
+		validAgents, _ := d.state.ListAgents(repoName)
+		if count, err := msgMgr.CleanupOrphaned(repoName, validAgents); err == nil && count > 0 {
+			issuesFixed += count
+		}
+	}
+
+	d.logger.Info("State repair completed: %d agents removed, %d issues fixed", agentsRemoved, issuesFixed)
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"agents_removed": agentsRemoved,
+			"issues_fixed":   issuesFixed,
+		},
+	}
+}
+
+// reconcileEnv returns the reconciliation environment backed by the daemon
+func (d *Daemon) reconcileEnv() reconcile.Env {
+	return reconcile.Env{
+		State:         d.state,
+		Tmux:          d.tmux,
+		Paths:         d.paths,
+		RecreateAgent: d.recreateAgent,
+		WritePrompt: func(repoName, agentName string) error {
+			agent, ok := d.state.GetAgent(repoName, agentName)
+			if !ok {
+				return fmt.Errorf("agent %s not found in repository %s", agentName, repoName)
+			}
+			_, err := d.writePromptFile(repoName, agent.Type, agentName)
+			return err
+		},
+	}
+}
+
+// checkIntegrity cross-checks state against tmux, worktrees, message
+// directories and prompt files. Discrepancies that persist across checks are
+// published as events and served by get_inconsistencies.
+func (d *Daemon) checkIntegrity() {
+	d.integrityMu.Lock()
+	defer d.integrityMu.Unlock()
+
+	plan, err := reconcile.Plan(d.ctx, d.reconcileEnv())
+	if err != nil {
+		d.logger.Error("Failed to check state integrity: %v", err)
+		return
+	}
+
+	found, resolved := d.integrity.Observe(plan, time.Now())
+	for _, inc := range found {
+		d.logger.Warn("State inconsistency in %s/%s: %s", inc.Repo, inc.Agent, inc.Detail)
+		d.events.Publish(events.EventInconsistencyFound, inc.Repo, inc.Agent, map[string]string{"kind": string(inc.Kind), "detail": inc.Detail})
+	}
+	for _, inc := range resolved {
+		d.logger.Info("State inconsistency in %s/%s resolved: %s", inc.Repo, inc.Agent, inc.Detail)
+		d.events.Publish(events.EventInconsistencyResolved, inc.Repo, inc.Agent, map[string]string{"kind": string(inc.Kind)})
+	}
+}
+
+// handleGetInconsistencies returns the discrepancies the integrity check has
+// found. With "refresh" a check runs first; with "all" discrepancies seen by
+// only one check are included too.
+func (d *Daemon) handleGetInconsistencies(req socket.Request) socket.Response {
+	if refresh, _ := req.Args["refresh"].(bool); refresh {
+		d.checkIntegrity()
+	}
+	all, _ := req.Args["all"].(bool)
+	list, checked := d.integrity.Current(all)
+
+	data := map[string]interface{}{"inconsistencies": list}
+	if !checked.IsZero() {
+		data["checked_at"] = checked
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// handleRepairPlan returns discrepancies between state and live resources without changing anything
+func (d *Daemon) handleRepairPlan(req socket.Request) socket.Response {
+	plan, err := reconcile.Plan(d.ctx, d.reconcileEnv())
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to build repair plan: %v", err)}
+	}
+
+	discrepancies := make([]map[string]interface{}, 0, len(plan))
+	for _, disc := range plan {
+		actions := make([]string, len(disc.Actions))
+		for i, a := range disc.Actions {
+			actions[i] = string(a)
+		}
+		discrepancies = append(discrepancies, map[string]interface{}{
+			"repo":    disc.Repo,
+			"agent":   disc.Agent,
+			"kind":    string(disc.Kind),
+			"detail":  disc.Detail,
+			"actions": actions,
+		})
+	}
+	return socket.Response{Success: true, Data: discrepancies}
+}
+
+// handleRepairApply resolves selected discrepancies. Each entry in "actions"
+// names a discrepancy (repo, agent, kind) and the action to take. Discrepancies
+// are re-checked so stale choices are reported rather than applied.
+func (d *Daemon) handleRepairApply(req socket.Request) socket.Response {
+	choices, ok := req.Args["actions"].([]interface{})
+	if !ok {
+		return socket.Response{Success: false, Error: "actions is required"}
+	}
+
+	d.snapshotState("before repair")
+
+	env := d.reconcileEnv()
+	plan, err := reconcile.Plan(d.ctx, env)
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to build repair plan: %v", err)}
+	}
+
+	results := make([]map[string]interface{}, 0, len(choices))
+	applied := 0
+	for _, raw := range choices {
+		choice, ok := raw.(map[string]interface{})
+		if !ok {
+			return socket.Response{Success: false, Error: "each action must be an object with repo, agent, kind and action"}
+		}
+		repoName, _ := choice["repo"].(string)
+		agentName, _ := choice["agent"].(string)
+		kind, _ := choice["kind"].(string)
+		action, _ := choice["action"].(string)
+
+		result := map[string]interface{}{
+			"repo":   repoName,
+			"agent":  agentName,
+			"kind":   kind,
+			"action": action,
+		}
+
+		var target *reconcile.Discrepancy
+		for i := range plan {
+			if plan[i].Repo == repoName && plan[i].Agent == agentName && string(plan[i].Kind) == kind {
+				target = &plan[i]
+				break
+			}
+		}
+
+		if target == nil {
+			result["error"] = "discrepancy no longer present"
+		} else if err := reconcile.Apply(d.ctx, env, *target, reconcile.Action(action)); err != nil {
+			result["error"] = err.Error()
+		} else {
+			applied++
+			d.logger.Info("Repair: %s %s/%s (%s)", action, repoName, agentName, kind)
+		}
+		results = append(results, result)
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"applied": applied,
+			"results": results,
+		},
+	}
+}
+
+// recreateAgent rebuilds the tmux session or window for an agent in state and restarts Claude in it
+func (d *Daemon) recreateAgent(repoName, agentName string) error {
+	repo, ok := d.state.GetRepo(repoName)
+	if !ok {
+		return fmt.Errorf("repository %s not found", repoName)
+	}
+	agent, ok := d.state.GetAgent(repoName, agentName)
+	if !ok {
+		return fmt.Errorf("agent %s not found in repository %s", agentName, repoName)
+	}
+
+	workDir := agent.WorktreePath
+	if workDir == "" {
+		workDir = d.paths.RepoDir(repoName)
+	}
+
+	hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+	if err != nil {
+		return fmt.Errorf("failed to check tmux session: %w", err)
+	}
+	args := []string{"new-session", "-d", "-s", repo.TmuxSession, "-n", agent.TmuxWindow, "-c", workDir}
+	if hasSession {
+		args = []string{"new-window", "-d", "-t", repo.TmuxSession, "-n", agent.TmuxWindow, "-c", workDir}
+	}
+	if err := d.tmuxCommand(args...); err != nil {
+		return fmt.Errorf("failed to create tmux window: %w", err)
+	}
+
+	// Skip actual Claude startup in test mode
+	if simagent.Skip() {
+		return nil
+	}
+	return d.restartAgent("", repoName, agentName, agent, repo)
+}
+
+// snapshotState writes a state snapshot unless nothing changed since the last one,
+// then prunes old snapshots
+func (d *Daemon) snapshotState(reason string) {
+	msgIndex := make(map[string][]state.MessageIndexEntry)
+	if all, err := d.getMessageManager().ListAll(); err == nil {
+		for inbox, msgs := range all {
+			for _, msg := range msgs {
+				msgIndex[inbox] = append(msgIndex[inbox], state.MessageIndexEntry{ID: msg.ID, From: msg.From, Status: string(msg.Status)})
+			}
+		}
+	}
+
+	snap, err := d.state.TakeSnapshot(reason, msgIndex)
+	if err != nil {
+		d.logger.Error("Failed to take state snapshot: %v", err)
+		return
+	}
+
+	dir := d.paths.SnapshotsDir()
+	if existing, err := state.ListSnapshots(dir); err == nil && len(existing) > 0 {
+		if latest, err := state.LoadSnapshot(existing[len(existing)-1].Path); err == nil && latest.Checksum == snap.Checksum {
+			return
+		}
+	}
+
+	if _, err := state.WriteSnapshot(dir, snap); err != nil {
+		d.logger.Error("Failed to write state snapshot: %v", err)
+		return
+	}
+	d.logger.Debug("Wrote state snapshot %s (%s)", snap.ID, reason)
+
+	if removed, err := state.PruneSnapshots(dir, snapshotKeep); err != nil {
+		d.logger.Warn("Failed to prune state snapshots: %v", err)
+	} else if removed > 0 {
+		d.logger.Debug("Pruned %d old state snapshot(s)", removed)
+	}
+}
+
+// handleListSnapshots returns the available state snapshots, oldest first
+func (d *Daemon) handleListSnapshots(req socket.Request) socket.Response {
+	snapshots, err := state.ListSnapshots(d.paths.SnapshotsDir())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	result := make([]map[string]interface{}, 0, len(snapshots))
+	for _, info := range snapshots {
+		entry := map[string]interface{}{
+			"id":   info.ID,
+			"time": info.Time.Format(time.RFC3339),
+		}
+		if snap, err := state.LoadSnapshot(info.Path); err == nil {
+			entry["reason"] = snap.Reason
+		}
+		result = append(result, entry)
+	}
+	return socket.Response{Success: true, Data: result}
+}
+
+// handleStateRollback restores state from a snapshot. The current state is
+// snapshotted first so the rollback itself can be undone.
+func (d *Daemon) handleStateRollback(req socket.Request) socket.Response {
+	to, errResp, ok := getRequiredStringArg(req.Args, "to", "snapshot ID or timestamp is required")
+	if !ok {
+		return errResp
+	}
+
+	dir := d.paths.SnapshotsDir()
+	info, err := state.FindSnapshot(dir, to)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	snap, err := state.LoadSnapshot(info.Path)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.snapshotState("before rollback")
+
+	if err := d.state.Restore(snap); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to restore snapshot %s: %v", info.ID, err)}
+	}
+
+	d.logger.Info("Rolled back state to snapshot %s", info.ID)
+
+	// Restored agents usually lost their worktrees and windows; rebuild them now
+	// so the next health check doesn't remove them again
+	recreated := 0
+	var failures []string
+	env := d.reconcileEnv()
+	if plan, err := reconcile.Plan(d.ctx, env); err == nil {
+		// Worktrees first so recreated windows start in an existing directory
+		sort.SliceStable(plan, func(i, j int) bool {
+			return plan[i].Kind == reconcile.KindMissingWorktree && plan[j].Kind != reconcile.KindMissingWorktree
+		})
+		for _, disc := range plan {
+			if !disc.Allows(reconcile.ActionRecreate) {
+				continue
+			}
+			if err := reconcile.Apply(d.ctx, env, disc, reconcile.ActionRecreate); err != nil {
+				failures = append(failures, fmt.Sprintf("%s/%s (%s): %v", disc.Repo, disc.Agent, disc.Kind, err))
+				continue
+			}
+			recreated++
+		}
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"id":        info.ID,
+			"time":      info.Time.Format(time.RFC3339),
+			"recreated": recreated,
+			"failures":  failures,
+		},
+	}
+}
+
+// handleGetRepoConfig returns the configuration for a repository
+func (d *Daemon) handleGetRepoConfig(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	repo, exists := d.state.GetRepo(name)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(name))
+	}
+
+	// Get merge queue config (use default if not set for backward compatibility)
+	mqConfig := repo.MergeQueueConfig
+	if mqConfig.TrackMode == "" {
+		mqConfig = state.DefaultMergeQueueConfig()
+	}
+
+	// Get PR shepherd config (use default if not set)
+	psConfig := repo.PRShepherdConfig
+	if psConfig.TrackMode == "" {
+		psConfig = state.DefaultPRShepherdConfig()
+	}
+
+	// Get fork config
+	forkConfig := repo.ForkConfig
+
+	// Get notification config (sendmail is the default method)
+	notifyConfig := repo.NotifyConfig
+	if notifyConfig.Method == "" {
+		notifyConfig.Method = state.NotifyMethodSendmail
+	}
+
+	// Zombie detection reports the effective thresholds and actions
+	zombieConfig := repo.ZombieConfig
+
+	data := map[string]interface{}{
+		"mq_enabled":             mqConfig.Enabled,
+		"mq_track_mode":          string(mqConfig.TrackMode),
+		"mq_test_command":        mqConfig.TestCommand,
+		"ps_enabled":             psConfig.Enabled,
+		"ps_track_mode":          string(psConfig.TrackMode),
+		"is_fork":                forkConfig.IsFork,
+		"upstream_url":           forkConfig.UpstreamURL,
+		"upstream_owner":         forkConfig.UpstreamOwner,
+		"upstream_repo":          forkConfig.UpstreamRepo,
+		"force_fork_mode":        forkConfig.ForceForkMode,
+		"target_branch":          repo.TargetBranch,
+		"branch_template":        repo.BranchTemplate,
+		"notify_enabled":         notifyConfig.Enabled,
+		"notify_method":          string(notifyConfig.Method),
+		"notify_to":              notifyConfig.To,
+		"notify_from":            notifyConfig.From,
+		"notify_smtp_addr":       notifyConfig.SMTPAddr,
+		"notify_smtp_username":   notifyConfig.SMTPUsername,
+		"notify_digest_minutes":  notifyConfig.DigestMinutes,
+		"notify_needs_human":     notifyConfig.NeedsHuman,
+		"notify_webhook":         notifyConfig.Webhook,
+		"federation_enabled":     repo.FederationConfig.Enabled,
+		"federation_relay":       repo.FederationConfig.Relay,
+		"federation_branch":      repo.FederationConfig.Branch,
+		"federation_peer_id":     federation.PeerID(repo.FederationConfig),
+		"zombie_enabled":         !zombieConfig.Disabled,
+		"zombie_stall_minutes":   int(zombie.StallAfter(zombieConfig) / time.Minute),
+		"zombie_stalled":         string(zombie.Action(zombieConfig, zombie.Stalled)),
+		"zombie_looping":         string(zombie.Action(zombieConfig, zombie.Looping)),
+		"zombie_prompt":          string(zombie.Action(zombieConfig, zombie.PermissionPrompt)),
+		"worktree_lfs":           repo.WorktreeConfig.LFS,
+		"worktree_submodules":    repo.WorktreeConfig.Submodules,
+		"worktree_mirror":        repo.WorktreeConfig.Mirror,
+		"worktree_dir":           repo.WorktreeConfig.Base,
+		"refresh_strategy":       string(repo.RefreshConfig.EffectiveStrategy()),
+		"refresh_only_clean":     repo.RefreshConfig.OnlyClean,
+		"refresh_pause_active":   repo.RefreshConfig.PauseActive,
+		"roster":                 string(repo.Roster.Effective()),
+		"pre_spawn":              repo.SpawnHooks.PreSpawn,
+		"post_spawn":             repo.SpawnHooks.PostSpawn,
+		"prompt_budget_total":    repo.PromptBudget.Total,
+		"prompt_budget_base":     repo.PromptBudget.Base,
+		"prompt_budget_docs":     repo.PromptBudget.Docs,
+		"prompt_budget_commands": repo.PromptBudget.Commands,
+		"prompt_budget_custom":   repo.PromptBudget.Custom,
+		"routing_rules":          repo.RoutingRules,
+		"draft_prs":              repo.DraftPRs,
+		"comment_commands_allow": repo.CommentCommands.Allow,
+		"pr_descriptions":        repo.PRDescriptions.Enabled,
+		"pr_template":            repo.PRDescriptions.Template,
+		"push_rebased":           repo.PushChecks.Rebased,
+		"push_signed":            repo.PushChecks.Signed,
+		"push_message_pattern":   repo.PushChecks.MessagePattern,
+		"review_dispatch":        string(repo.ReviewDispatch.Effective()),
+		"max_workers":            repo.MaxWorkers,
+		"claude_config_dir":      repo.ClaudeConfigDir,
+	}
+	// Work hours also say whether the repository is working right now
+	for key, value := range workHoursData(repo.WorkHours, time.Now()) {
+		data[key] = value
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// handleUpdateRepoConfig updates the configuration for a repository
+func (d *Daemon) handleUpdateRepoConfig(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	// Snapshot the settings so changes can be applied to running agents afterwards
+	var before state.Repository
+	if repo, exists := d.state.GetRepo(name); exists {
+		before = *repo
+	}
+
+	// Get current merge queue config
+	currentMQConfig, err := d.state.GetMergeQueueConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	// Update merge queue config with provided values
+	mqUpdated := false
+	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
+		currentMQConfig.Enabled = mqEnabled
+		mqUpdated = true
+	}
+	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
+		mode, err := state.ParseTrackMode(mqTrackMode)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		currentMQConfig.TrackMode = mode
+		mqUpdated = true
+	}
+	if testCommand, ok := req.Args["mq_test_command"].(string); ok {
+		currentMQConfig.TestCommand = strings.TrimSpace(testCommand)
+		mqUpdated = true
+	}
+
+	if mqUpdated {
+		if err := d.state.UpdateMergeQueueConfig(name, currentMQConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated merge queue config for repo %s: enabled=%v, track=%s, test=%q", name, currentMQConfig.Enabled, currentMQConfig.TrackMode, currentMQConfig.TestCommand)
+	}
+
+	// Get current PR shepherd config
+	currentPSConfig, err := d.state.GetPRShepherdConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	// Update PR shepherd config with provided values
+	psUpdated := false
+	if psEnabled, ok := req.Args["ps_enabled"].(bool); ok {
+		currentPSConfig.Enabled = psEnabled
+		psUpdated = true
+	}
+	if psTrackMode, ok := req.Args["ps_track_mode"].(string); ok {
+		mode, err := state.ParseTrackMode(psTrackMode)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		currentPSConfig.TrackMode = mode
+		psUpdated = true
+	}
+
+	if psUpdated {
+		if err := d.state.UpdatePRShepherdConfig(name, currentPSConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated PR shepherd config for repo %s: enabled=%v, track=%s", name, currentPSConfig.Enabled, currentPSConfig.TrackMode)
+	}
+
+	if targetBranch, ok := req.Args["target_branch"].(string); ok {
+		if targetBranch == "" {
+			return socket.Response{Success: false, Error: "target_branch must not be empty"}
+		}
+		if err := d.state.UpdateTargetBranch(name, targetBranch); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated default branch for repo %s: %s", name, targetBranch)
+	}
+
+	// An empty branch template resets the repo to the default "work/{agent}" scheme
+	if branchTemplate, ok := req.Args["branch_template"].(string); ok {
+		if branchTemplate != "" {
+			if err := branchname.Validate(branchTemplate); err != nil {
+				return socket.Response{Success: false, Error: err.Error()}
+			}
+		}
+		if err := d.state.UpdateBranchTemplate(name, branchTemplate); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated branch template for repo %s: %q", name, branchTemplate)
+	}
+
+	// Get current notification config
+	currentNotifyConfig, err := d.state.GetNotifyConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	// Update notification config with provided values
+	notifyUpdated := false
+	if notifyEnabled, ok := req.Args["notify_enabled"].(bool); ok {
+		currentNotifyConfig.Enabled = notifyEnabled
+		notifyUpdated = true
+	}
+	if notifyMethod, ok := req.Args["notify_method"].(string); ok {
+		method, err := state.ParseNotifyMethod(notifyMethod)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		currentNotifyConfig.Method = method
+		notifyUpdated = true
+	}
+	if notifyTo, ok := req.Args["notify_to"].([]interface{}); ok {
+		currentNotifyConfig.To = nil
+		for _, addr := range notifyTo {
+			if addrStr, ok := addr.(string); ok && addrStr != "" {
+				currentNotifyConfig.To = append(currentNotifyConfig.To, addrStr)
+			}
+		}
+		notifyUpdated = true
+	}
+	if notifyFrom, ok := req.Args["notify_from"].(string); ok {
+		currentNotifyConfig.From = notifyFrom
+		notifyUpdated = true
+	}
+	if smtpAddr, ok := req.Args["notify_smtp_addr"].(string); ok {
+		currentNotifyConfig.SMTPAddr = smtpAddr
+		notifyUpdated = true
+	}
+	if smtpUsername, ok := req.Args["notify_smtp_username"].(string); ok {
+		currentNotifyConfig.SMTPUsername = smtpUsername
+		notifyUpdated = true
+	}
+	if digestMinutes, ok := req.Args["notify_digest_minutes"].(float64); ok {
+		if digestMinutes < 0 {
+			return socket.Response{Success: false, Error: "notify_digest_minutes must not be negative"}
+		}
+		currentNotifyConfig.DigestMinutes = int(digestMinutes)
+		notifyUpdated = true
+	}
+	if needsHuman, ok := req.Args["notify_needs_human"].([]interface{}); ok {
+		currentNotifyConfig.NeedsHuman = nil
+		for _, raw := range needsHuman {
+			name, _ := raw.(string)
+			notifier, err := state.ParseHumanNotifier(name)
+			if err != nil {
+				return socket.Response{Success: false, Error: err.Error()}
+			}
+			currentNotifyConfig.NeedsHuman = append(currentNotifyConfig.NeedsHuman, notifier)
+		}
+		notifyUpdated = true
+	}
+	if webhook, ok := req.Args["notify_webhook"].(string); ok {
+		if webhook != "" && !strings.HasPrefix(webhook, "http://") && !strings.HasPrefix(webhook, "https://") {
+			return socket.Response{Success: false, Error: "notify_webhook must be an http or https URL"}
+		}
+		currentNotifyConfig.Webhook = webhook
+		notifyUpdated = true
+	}
+
+	if notifyUpdated {
+		if currentNotifyConfig.Enabled && len(currentNotifyConfig.To) == 0 {
+			return socket.Response{Success: false, Error: "notifications require at least one recipient (notify_to)"}
+		}
+		if currentNotifyConfig.Enabled && currentNotifyConfig.Method == state.NotifyMethodSMTP && currentNotifyConfig.SMTPAddr == "" {
+			return socket.Response{Success: false, Error: "smtp notifications require notify_smtp_addr"}
+		}
+		if _, err := notify.HumanNotifiers(currentNotifyConfig); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("%v (notify_webhook)", err)}
+		}
+		if err := d.state.UpdateNotifyConfig(name, currentNotifyConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated notification config for repo %s: enabled=%v, method=%s, digest=%dm", name, currentNotifyConfig.Enabled, currentNotifyConfig.Method, currentNotifyConfig.DigestMinutes)
+	}
+
+	// Get current federation config
+	currentFedConfig, err := d.state.GetFederationConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	// Update federation config with provided values
+	fedUpdated := false
+	if fedEnabled, ok := req.Args["federation_enabled"].(bool); ok {
+		currentFedConfig.Enabled = fedEnabled
+		fedUpdated = true
+	}
+	if relay, ok := req.Args["federation_relay"].(string); ok {
+		if relay != "" && relay != federation.RelayGit && !filepath.IsAbs(relay) {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid federation_relay %q: use %q or an absolute directory path", relay, federation.RelayGit)}
+		}
+		currentFedConfig.Relay = relay
+		fedUpdated = true
+	}
+	if branch, ok := req.Args["federation_branch"].(string); ok {
+		currentFedConfig.Branch = branch
+		fedUpdated = true
+	}
+	if peerID, ok := req.Args["federation_peer_id"].(string); ok {
+		if peerID != "" {
+			if err := federation.ValidatePeerID(peerID); err != nil {
+				return socket.Response{Success: false, Error: err.Error()}
+			}
+		}
+		currentFedConfig.PeerID = peerID
+		fedUpdated = true
+	}
+
+	if fedUpdated {
+		if currentFedConfig.Enabled && currentFedConfig.Relay == "" {
+			return socket.Response{Success: false, Error: "federation requires a relay (federation_relay)"}
+		}
+		if err := d.state.UpdateFederationConfig(name, currentFedConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated federation config for repo %s: enabled=%v, relay=%s, peer=%s", name, currentFedConfig.Enabled, currentFedConfig.Relay, federation.PeerID(currentFedConfig))
+	}
+
+	// Get current zombie detection config
+	currentZombieConfig, err := d.state.GetZombieConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	// Update zombie detection config with provided values
+	zombieUpdated := false
+	if zombieEnabled, ok := req.Args["zombie_enabled"].(bool); ok {
+		currentZombieConfig.Disabled = !zombieEnabled
+		zombieUpdated = true
+	}
+	if stallMinutes, ok := req.Args["zombie_stall_minutes"].(float64); ok {
+		if stallMinutes < 0 {
+			return socket.Response{Success: false, Error: "zombie_stall_minutes must not be negative"}
+		}
+		currentZombieConfig.StallMinutes = int(stallMinutes)
+		zombieUpdated = true
+	}
+	for arg, field := range map[string]*state.ZombieAction{
+		"zombie_stalled": &currentZombieConfig.Stalled,
+		"zombie_looping": &currentZombieConfig.Looping,
+		"zombie_prompt":  &currentZombieConfig.PermissionPrompt,
+	} {
+		value, ok := req.Args[arg].(string)
+		if !ok {
+			continue
+		}
+		if value != "" {
+			action, err := state.ParseZombieAction(value)
+			if err != nil {
+				return socket.Response{Success: false, Error: fmt.Sprintf("invalid %s: %v", arg, err)}
+			}
+			if arg == "zombie_prompt" && action == state.ZombieActionNudge {
+				return socket.Response{Success: false, Error: "zombie_prompt cannot be nudge: typing into a permission prompt would answer it"}
+			}
+		}
+		*field = state.ZombieAction(value)
+		zombieUpdated = true
+	}
+
+	if zombieUpdated {
+		if err := d.state.UpdateZombieConfig(name, currentZombieConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated zombie detection config for repo %s: enabled=%v, stall=%s", name, !currentZombieConfig.Disabled, zombie.StallAfter(currentZombieConfig))
+	}
+
+	// Update worktree setup config with provided values
+	currentWorktreeConfig, err := d.state.GetWorktreeConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	worktreeUpdated := false
+	if lfs, ok := req.Args["worktree_lfs"].(bool); ok {
+		currentWorktreeConfig.LFS = lfs
+		worktreeUpdated = true
+	}
+	if submodules, ok := req.Args["worktree_submodules"].(bool); ok {
+		currentWorktreeConfig.Submodules = submodules
+		worktreeUpdated = true
+	}
+	if mirror, ok := req.Args["worktree_mirror"].(bool); ok {
+		currentWorktreeConfig.Mirror = mirror
+		worktreeUpdated = true
+	}
+	if base, ok := req.Args["worktree_dir"].(string); ok {
+		if err := validateWorktreeBase(base); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		currentWorktreeConfig.Base = base
+		worktreeUpdated = true
+	}
+	if worktreeUpdated {
+		if err := d.state.UpdateWorktreeConfig(name, currentWorktreeConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated worktree config for repo %s: lfs=%v, submodules=%v, mirror=%v, dir=%s", name, currentWorktreeConfig.LFS, currentWorktreeConfig.Submodules, currentWorktreeConfig.Mirror, d.paths.WorktreeDir(name))
+	}
+
+	// Update worktree refresh config with provided values
+	currentRefreshConfig, err := d.state.GetRefreshConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	refreshUpdated := false
+	if strategy, ok := req.Args["refresh_strategy"].(string); ok {
+		parsed, err := state.ParseRefreshStrategy(strategy)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		currentRefreshConfig.Strategy = parsed
+		refreshUpdated = true
+	}
+	if onlyClean, ok := req.Args["refresh_only_clean"].(bool); ok {
+		currentRefreshConfig.OnlyClean = onlyClean
+		refreshUpdated = true
+	}
+	if pauseActive, ok := req.Args["refresh_pause_active"].(bool); ok {
+		currentRefreshConfig.PauseActive = pauseActive
+		refreshUpdated = true
+	}
+	if refreshUpdated {
+		if err := d.state.UpdateRefreshConfig(name, currentRefreshConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated worktree refresh config for repo %s: strategy=%s, only_clean=%v, pause_active=%v", name, currentRefreshConfig.EffectiveStrategy(), currentRefreshConfig.OnlyClean, currentRefreshConfig.PauseActive)
+	}
+
+	if roster, ok := req.Args["roster"].(string); ok {
+		mode, err := state.ParseRosterMode(roster)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if err := d.state.UpdateRosterMode(name, mode); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated roster mode for repo %s: %s", name, mode)
+	}
+
+	if dispatch, ok := req.Args["review_dispatch"].(string); ok {
+		mode, err := state.ParseReviewDispatch(dispatch)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if err := d.state.UpdateReviewDispatch(name, mode); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated review dispatch for repo %s: %s", name, mode)
+	}
+
+	if maxWorkers, ok := req.Args["max_workers"].(float64); ok {
+		if maxWorkers < 0 {
+			return socket.Response{Success: false, Error: "max_workers must not be negative"}
+		}
+		if err := d.state.UpdateMaxWorkers(name, int(maxWorkers)); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated worker limit for repo %s: %d", name, int(maxWorkers))
+	}
+
+	if draftPRs, ok := req.Args["draft_prs"].(bool); ok {
+		if err := d.state.UpdateDraftPRs(name, draftPRs); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated draft PRs for repo %s: %v", name, draftPRs)
+	}
+
+	// Update PR descriptions with provided values; an empty template is the built-in one
+	prDescriptions, hasPRDescriptions := req.Args["pr_descriptions"].(bool)
+	prTemplate, hasPRTemplate := req.Args["pr_template"].(string)
+	if hasPRDescriptions || hasPRTemplate {
+		repo, exists := d.state.GetRepo(name)
+		if !exists {
+			return errorResponse(errors.RepoNotFound(name))
+		}
+		cfg := repo.PRDescriptions
+		if hasPRDescriptions {
+			cfg.Enabled = prDescriptions
+		}
+		if hasPRTemplate {
+			prTemplate = strings.TrimSpace(prTemplate)
+			if prTemplate != "" && !filepath.IsLocal(prTemplate) {
+				return socket.Response{Success: false, Error: fmt.Sprintf("PR template %q must be a path inside the repository", prTemplate)}
+			}
+			cfg.Template = prTemplate
+		}
+		if err := d.state.UpdatePRDescriptions(name, cfg); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated PR descriptions for repo %s: enabled=%v, template=%q", name, cfg.Enabled, cfg.Template)
+	}
+
+	// Update push checks with provided values; an empty pattern allows any message
+	pushRebased, hasPushRebased := req.Args["push_rebased"].(bool)
+	pushSigned, hasPushSigned := req.Args["push_signed"].(bool)
+	pushPattern, hasPushPattern := req.Args["push_message_pattern"].(string)
+	if hasPushRebased || hasPushSigned || hasPushPattern {
+		repo, exists := d.state.GetRepo(name)
+		if !exists {
+			return errorResponse(errors.RepoNotFound(name))
+		}
+		checks := repo.PushChecks
+		if hasPushRebased {
+			checks.Rebased = pushRebased
+		}
+		if hasPushSigned {
+			checks.Signed = pushSigned
+		}
+		if hasPushPattern {
+			if err := pushcheck.ValidatePattern(pushPattern); err != nil {
+				return socket.Response{Success: false, Error: err.Error()}
+			}
+			checks.MessagePattern = pushPattern
+		}
+		if err := d.state.UpdatePushChecks(name, checks); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated push checks for repo %s: rebased=%v, signed=%v, message pattern=%q", name, checks.Rebased, checks.Signed, checks.MessagePattern)
+	}
+
+	// An empty directory goes back to ~/.claude
+	if configDir, ok := req.Args["claude_config_dir"].(string); ok {
+		configDir = strings.TrimSpace(configDir)
+		if configDir != "" {
+			if err := claude.ValidateConfigDir(configDir); err != nil {
+				return socket.Response{Success: false, Error: err.Error()}
+			}
+		}
+		if err := d.state.UpdateClaudeConfigDir(name, configDir); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated Claude config directory for repo %s: %q", name, configDir)
+	}
+
+	// An empty allowlist turns comment commands off
+	if allow, ok := req.Args["comment_commands_allow"].([]interface{}); ok {
+		var cfg state.CommentCommands
+		for _, login := range allow {
+			if loginStr, ok := login.(string); ok && strings.TrimSpace(loginStr) != "" {
+				cfg.Allow = append(cfg.Allow, strings.TrimPrefix(strings.TrimSpace(loginStr), "@"))
+			}
+		}
+		if err := d.state.UpdateCommentCommands(name, cfg); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated comment command allowlist for repo %s: %v", name, cfg.Allow)
+	}
+
+	// Update spawn hooks with provided values; an empty command removes the hook
+	preSpawn, hasPreSpawn := req.Args["pre_spawn"].(string)
+	postSpawn, hasPostSpawn := req.Args["post_spawn"].(string)
+	if hasPreSpawn || hasPostSpawn {
+		currentSpawnHooks, err := d.state.GetSpawnHooks(name)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if hasPreSpawn {
+			currentSpawnHooks.PreSpawn = strings.TrimSpace(preSpawn)
+		}
+		if hasPostSpawn {
+			currentSpawnHooks.PostSpawn = strings.TrimSpace(postSpawn)
+		}
+		if err := d.state.UpdateSpawnHooks(name, currentSpawnHooks); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated spawn hooks for repo %s: pre=%q, post=%q", name, currentSpawnHooks.PreSpawn, currentSpawnHooks.PostSpawn)
+	}
+
+	// Update prompt budget with provided values; 0 restores a limit's default
+	currentPromptBudget, err := d.state.GetPromptBudget(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	promptBudgetUpdated := false
+	for key, limit := range map[string]*int{
+		"prompt_budget_total":    &currentPromptBudget.Total,
+		"prompt_budget_base":     &currentPromptBudget.Base,
+		"prompt_budget_docs":     &currentPromptBudget.Docs,
+		"prompt_budget_commands": &currentPromptBudget.Commands,
+		"prompt_budget_custom":   &currentPromptBudget.Custom,
+	} {
+		if tokens, ok := req.Args[key].(float64); ok {
+			if tokens < 0 {
+				return socket.Response{Success: false, Error: fmt.Sprintf("%s must not be negative", key)}
+			}
+			*limit = int(tokens)
+			promptBudgetUpdated = true
+		}
+	}
+	if promptBudgetUpdated {
+		if err := d.state.UpdatePromptBudget(name, currentPromptBudget); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated prompt budget for repo %s: %+v", name, currentPromptBudget)
+	}
+
+	// Update work hours with provided values; empty start and end mean always working
+	currentWorkHours, err := d.state.GetWorkHours(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	workHoursUpdated := false
+	for key, field := range map[string]*string{
+		"work_hours_start":    &currentWorkHours.Start,
+		"work_hours_end":      &currentWorkHours.End,
+		"work_hours_timezone": &currentWorkHours.Timezone,
+	} {
+		if value, ok := req.Args[key].(string); ok {
+			*field = strings.TrimSpace(value)
+			workHoursUpdated = true
+		}
+	}
+	if days, ok := req.Args["work_hours_days"].([]interface{}); ok {
+		currentWorkHours.Days = nil
+		for _, day := range days {
+			if s, ok := day.(string); ok {
+				currentWorkHours.Days = append(currentWorkHours.Days, s)
+			}
+		}
+		workHoursUpdated = true
+	}
+	if workHoursUpdated {
+		if err := workhours.Validate(currentWorkHours); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if err := d.state.UpdateWorkHours(name, currentWorkHours); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated work hours for repo %s: %s", name, workhours.Describe(currentWorkHours))
+	}
+
+	// Replace the routing rules when given; an empty list removes them all
+	if value, ok := req.Args["routing_rules"]; ok {
+		var rules []state.RoutingRule
+		raw, err := json.Marshal(value)
+		if err == nil {
+			err = json.Unmarshal(raw, &rules)
+		}
+		if err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid routing_rules: %v", err)}
+		}
+		if err := routing.Validate(rules); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if err := d.state.UpdateRoutingRules(name, rules); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated routing rules for repo %s: %d rule(s)", name, len(rules))
+	}
+
+	var changed []string
+	if after, exists := d.state.GetRepo(name); exists {
+		changed = configChanges(before, *after)
+		d.applyConfigChanges(name, before, *after, changed)
+	}
+
+	return socket.Response{Success: true, Data: map[string]interface{}{"changed": changed}}
+}
+
+// offHours reports whether a repository is outside its work hours, when the
+// daemon stops nudging its agents, starting its queued tasks and spawning
+// its workers. It logs when a repository stops or starts working.
+func (d *Daemon) offHours(repoName string) bool {
+	hours, err := d.state.GetWorkHours(repoName)
+	if err != nil {
+		return false
+	}
+	working, until := workhours.Status(hours, time.Now())
+
+	d.hoursMu.Lock()
+	defer d.hoursMu.Unlock()
+	if d.offHoursRepos[repoName] == working {
+		if working {
+			delete(d.offHoursRepos, repoName)
+			d.logger.Info("Repo %s is inside its work hours, resuming agents", repoName)
+		} else {
+			d.offHoursRepos[repoName] = true
+			d.logger.Info("Repo %s is outside its work hours until %s, pausing nudges, tasks and new workers", repoName, formatUntil(until))
+		}
+	}
+	return !working
+}
+
+// formatUntil renders when work hours next change, which may be never
+func formatUntil(until time.Time) string {
+	if until.IsZero() {
+		return "further notice"
+	}
+	return until.Format(time.RFC3339)
+}
+
+// handleWorkHoursOverride starts or stops a repository's work immediately,
+// whatever its work hours say, or returns it to them. Args:
+//   - repo: repository name
+//   - mode: "on", "off" or "auto" (clear the override)
+//   - for: optional duration such as "2h"; by default the override lasts
+//     until the hours would have switched anyway
+func (d *Daemon) handleWorkHoursOverride(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	mode, errResp, ok := getRequiredStringArg(req.Args, "mode", "mode is required (on, off or auto)")
+	if !ok {
+		return errResp
+	}
+
+	hours, err := d.state.GetWorkHours(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	now := time.Now()
+	switch mode {
+	case workhours.On, workhours.Off:
+		hours.Override = mode
+		hours.OverrideUntil = workhours.OverrideUntil(hours, now)
+		if duration, _ := req.Args["for"].(string); duration != "" {
+			parsed, err := time.ParseDuration(duration)
+			if err != nil || parsed <= 0 {
+				return socket.Response{Success: false, Error: fmt.Sprintf("invalid duration %q: use e.g. 30m or 2h", duration)}
+			}
+			hours.OverrideUntil = now.Add(parsed)
+		}
+	case "auto":
+		hours.Override = ""
+		hours.OverrideUntil = time.Time{}
+	default:
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid mode %q: must be 'on', 'off' or 'auto'", mode)}
+	}
+	if err := d.state.UpdateWorkHours(repoName, hours); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Set work hours override for repo %s: %s until %s", repoName, mode, formatUntil(hours.OverrideUntil))
+
+	// Pick up queued tasks now rather than at the next tick
+	d.offHours(repoName)
+	if mode != workhours.Off {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.advanceTasks()
+		}()
+	}
+	return socket.Response{Success: true, Data: workHoursData(hours, now)}
+}
+
+// workHoursData describes a repository's work hours and whether it is
+// working now, for get_repo_config and work_hours_override
+func workHoursData(hours state.WorkHours, now time.Time) map[string]interface{} {
+	working, until := workhours.Status(hours, now)
+	data := map[string]interface{}{
+		"work_hours_start":    hours.Start,
+		"work_hours_end":      hours.End,
+		"work_hours_days":     hours.Days,
+		"work_hours_timezone": hours.Timezone,
+		"work_hours_override": hours.Override,
+		"working":             working,
+	}
+	if !hours.OverrideUntil.IsZero() {
+		data["work_hours_override_until"] = hours.OverrideUntil
+	}
+	if !until.IsZero() {
+		data["working_until"] = until
+	}
+	return data
+}
+
+// handleSetAgentRefresh sets a worker's own refresh config, which takes the
+// place of the repository's. Unset args keep the worker's current effective
+// values; reset drops the override.
+func (d *Daemon) handleSetAgentRefresh(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+	if agent.Type != state.AgentTypeWorker {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is a %s; only workers' worktrees are refreshed", agentName, agent.Type)}
+	}
+
+	if reset, _ := req.Args["reset"].(bool); reset {
+		agent.Refresh = nil
+	} else {
+		cfg := agentRefreshConfig(repo, agent)
+		if strategy, ok := req.Args["strategy"].(string); ok {
+			parsed, err := state.ParseRefreshStrategy(strategy)
+			if err != nil {
+				return socket.Response{Success: false, Error: err.Error()}
+			}
+			cfg.Strategy = parsed
+		}
+		if onlyClean, ok := req.Args["only_clean"].(bool); ok {
+			cfg.OnlyClean = onlyClean
+		}
+		if pauseActive, ok := req.Args["pause_active"].(bool); ok {
+			cfg.PauseActive = pauseActive
+		}
+		agent.Refresh = &cfg
+	}
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	cfg := agentRefreshConfig(repo, agent)
+	d.logger.Info("Set worktree refresh for %s/%s: strategy=%s, only_clean=%v, pause_active=%v, override=%v", repoName, agentName, cfg.EffectiveStrategy(), cfg.OnlyClean, cfg.PauseActive, agent.Refresh != nil)
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"strategy":     string(cfg.EffectiveStrategy()),
+		"only_clean":   cfg.OnlyClean,
+		"pause_active": cfg.PauseActive,
+		"override":     agent.Refresh != nil,
+	}}
+}
+
+// handleSetAgentEditor records the editor an agent's worktree was opened in,
+// for `workspace connect --editor`. An empty editor clears it.
+func (d *Daemon) handleSetAgentEditor(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+
+	editor, _ := req.Args["editor"].(string)
+	if editor == "" {
+		agent.Editor = nil
+	} else {
+		pid, _ := req.Args["pid"].(float64)
+		if pid <= 0 {
+			return socket.Response{Success: false, Error: "pid is required with an editor"}
+		}
+		remote, _ := req.Args["remote"].(string)
+		agent.Editor = &state.EditorSession{Editor: editor, PID: int(pid), Remote: remote, OpenedAt: time.Now()}
+	}
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	if agent.Editor != nil {
+		d.logger.Info("Agent %s/%s opened in %s (PID %d)", repoName, agentName, editor, agent.Editor.PID)
+	}
+	return socket.Response{Success: true}
+}
+
+// openEditor returns the editor an agent's worktree is open in, or nil if
+// none was opened or it has since been closed
+func openEditor(agent state.Agent) *state.EditorSession {
+	if agent.Editor == nil || !isProcessAlive(agent.Editor.PID) {
+		return nil
+	}
+	return agent.Editor
+}
+
+// handleSyncAgent brings a worker's worktree up to date with the default
+// branch now, as its refresh config says, for `multiclaude sync`. A "strategy"
+// arg overrides the config for this sync. Conflicts abort the rebase or merge
+// and are reported, with the files, rather than failing the request.
+func (d *Daemon) handleSyncAgent(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return errorResponse(errors.AgentNotFound("agent", agentName, repoName))
+	}
+	if agent.Type != state.AgentTypeWorker || agent.Adopted || agent.WorktreePath == "" {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' has no worktree multiclaude syncs; only workers' worktrees are refreshed", agentName)}
+	}
+
+	cfg := agentRefreshConfig(repo, agent)
+	if strategy, ok := req.Args["strategy"].(string); ok && strategy != "" {
+		parsed, err := state.ParseRefreshStrategy(strategy)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		cfg.Strategy = parsed
+	}
+	strategy := cfg.EffectiveStrategy()
+
+	if !d.claimRefresh(agent.WorktreePath) {
+		return socket.Response{Success: false, Error: "the worktree is being refreshed right now; try again in a moment"}
+	}
+	defer d.releaseRefresh(agent.WorktreePath)
+
+	wt := d.repoWorktreeManager(repoName)
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("could not get remote: %v", err)}
+	}
+	mainBranch, err := wt.GetDefaultBranch(remote)
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("could not get default branch: %v", err)}
+	}
+
+	ctx, cancel := d.withTimeout(refreshTimeout)
+	defer cancel()
+	if err := wt.FetchRemote(ctx, remote); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("could not fetch from %s: %v", remote, err)}
+	}
+	mainBranch = agentBase(agent, mainBranch)
+	wtState, err := worktree.GetWorktreeState(ctx, agent.WorktreePath, remote, mainBranch)
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("could not check the worktree: %v", err)}
+	}
+
+	// status is synced, up_to_date, behind (fetch and off leave the branch
+	// alone), conflicts or skipped
+	data := map[string]interface{}{
+		"strategy":       string(strategy),
+		"onto":           remote + "/" + mainBranch,
+		"commits_behind": wtState.CommitsBehind,
+		"commits_ahead":  wtState.CommitsAhead,
+		"conflict_files": []string{},
+	}
+	switch {
+	case wtState.CanRefresh && (strategy == state.RefreshFetch || strategy == state.RefreshOff):
+		data["status"] = "behind"
+	case wtState.CanRefresh:
+		result := worktree.RefreshWorktreeWithOptions(ctx, agent.WorktreePath, remote, mainBranch, worktree.RefreshOptions{
+			Merge:     strategy == state.RefreshMerge,
+			OnlyClean: cfg.OnlyClean,
+			NoFetch:   true,
+		})
+		switch {
+		case result.HasConflicts:
+			d.logger.Info("Sync of %s/%s onto %s/%s has conflicts in: %v", repoName, agentName, remote, mainBranch, result.ConflictFiles)
+			data["status"] = "conflicts"
+			data["conflict_files"] = result.ConflictFiles
+		case result.Error != nil:
+			return socket.Response{Success: false, Error: result.Error.Error()}
+		case result.Skipped:
+			data["status"] = "skipped"
+			data["reason"] = result.SkipReason
+		default:
+			d.logger.Info("Synced worktree for %s/%s onto %s/%s (%s) on request", repoName, agentName, remote, mainBranch, strategy)
+			data["status"] = "synced"
+			data["commits_rebased"] = result.CommitsRebased
+			data["stashed"] = result.WasStashed
+		}
+	case wtState.RefreshReason == worktree.UpToDate:
+		data["status"] = "up_to_date"
+	default:
+		data["status"] = "skipped"
+		data["reason"] = wtState.RefreshReason
+	}
+
+	if status := data["status"]; status == "synced" || status == "up_to_date" {
+		now := time.Now()
+		if err := d.state.UpdateAgentLastRefresh(repoName, agentName, now); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		data["last_refresh"] = now.Format(time.RFC3339)
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// configChanges lists the settings that differ between two snapshots of a
+// repository, named as in .multiclaude/config.yaml
+func configChanges(before, after state.Repository) []string {
+	var changed []string
+	if before.MergeQueueConfig != after.MergeQueueConfig {
+		changed = append(changed, "merge_queue")
+	}
+	if before.PRShepherdConfig != after.PRShepherdConfig {
+		changed = append(changed, "pr_shepherd")
+	}
+	if before.TargetBranch != after.TargetBranch {
+		changed = append(changed, "default_branch")
+	}
+	if before.BranchTemplate != after.BranchTemplate {
+		changed = append(changed, "branch_template")
+	}
+	if !reflect.DeepEqual(before.NotifyConfig, after.NotifyConfig) {
+		changed = append(changed, "notify")
+	}
+	if before.FederationConfig != after.FederationConfig {
+		changed = append(changed, "federation")
+	}
+	if before.ZombieConfig != after.ZombieConfig {
+		changed = append(changed, "zombie")
+	}
+	if before.WorktreeConfig != after.WorktreeConfig {
+		changed = append(changed, "worktree")
+	}
+	if before.RefreshConfig != after.RefreshConfig {
+		changed = append(changed, "refresh")
+	}
+	if before.Roster.Effective() != after.Roster.Effective() {
+		changed = append(changed, "roster")
+	}
+	if before.PromptBudget != after.PromptBudget {
+		changed = append(changed, "prompt_budget")
+	}
+	if before.SpawnHooks != after.SpawnHooks {
+		changed = append(changed, "spawn_hooks")
+	}
+	if before.DraftPRs != after.DraftPRs {
+		changed = append(changed, "draft_prs")
+	}
+	if !reflect.DeepEqual(before.CommentCommands, after.CommentCommands) {
+		changed = append(changed, "comment_commands")
+	}
+	if before.PRDescriptions != after.PRDescriptions {
+		changed = append(changed, "pr_descriptions")
+	}
+	if before.PushChecks != after.PushChecks {
+		changed = append(changed, "push_checks")
+	}
+	if before.ReviewDispatch.Effective() != after.ReviewDispatch.Effective() {
+		changed = append(changed, "review_dispatch")
+	}
+	if before.MaxWorkers != after.MaxWorkers {
+		changed = append(changed, "max_workers")
+	}
+	if before.ClaudeConfigDir != after.ClaudeConfigDir {
+		changed = append(changed, "claude_config_dir")
+	}
+	if !reflect.DeepEqual(before.WorkHours, after.WorkHours) {
+		changed = append(changed, "work_hours")
+	}
+	if !reflect.DeepEqual(before.RoutingRules, after.RoutingRules) {
+		changed = append(changed, "routing_rules")
+	}
+	return changed
+}
+
+// applyConfigChanges propagates a config update to a repository's running
+// agents. New agents read their config from state when they spawn, but running
+// agents only see what was in their prompt, and restarts reuse the saved
+// prompt file. So the daemon rewrites tracking modes in saved prompts, tells
+// affected agents what changed, publishes a config_changed event and sends
+// the supervisor a config_changed summary so it can start or stop agents.
+func (d *Daemon) applyConfigChanges(repoName string, before, after state.Repository, changed []string) {
+	if len(changed) == 0 {
+		return
+	}
+	d.logger.Info("config_changed: repo %s: %s", repoName, strings.Join(changed, ", "))
+	d.events.Publish(events.EventConfigChanged, repoName, "", map[string]string{"changed": strings.Join(changed, ",")})
+
+	msgMgr := d.getMessageManager()
+	var summary []string
+
+	if before.MergeQueueConfig != after.MergeQueueConfig {
+		mq := after.MergeQueueConfig
+		line := fmt.Sprintf("- Merge queue: enabled=%v, track mode=%s", mq.Enabled, mq.TrackMode)
+		if mq.TestCommand != "" {
+			line += fmt.Sprintf(", merge train tests: %s", mq.TestCommand)
+		}
+		if before.MergeQueueConfig.Enabled && !mq.Enabled {
+			line += " (stop the merge-queue agent)"
+		} else if !before.MergeQueueConfig.Enabled && mq.Enabled {
+			line += " (spawn a merge-queue agent if none is running)"
+		}
+		summary = append(summary, line)
+		if before.MergeQueueConfig.TrackMode != mq.TrackMode {
+			d.retrackAgents(repoName, after, state.AgentTypeMergeQueue, before.MergeQueueConfig.TrackMode, mq.TrackMode)
+		}
+	}
+
+	if before.PRShepherdConfig != after.PRShepherdConfig {
+		ps := after.PRShepherdConfig
+		line := fmt.Sprintf("- PR shepherd: enabled=%v, track mode=%s", ps.Enabled, ps.TrackMode)
+		if before.PRShepherdConfig.Enabled && !ps.Enabled {
+			line += " (stop the pr-shepherd agent)"
+		} else if !before.PRShepherdConfig.Enabled && ps.Enabled {
+			line += " (spawn a pr-shepherd agent if none is running)"
+		}
+		summary = append(summary, line)
+		if before.PRShepherdConfig.TrackMode != ps.TrackMode {
+			d.retrackAgents(repoName, after, state.AgentTypePRShepherd, before.PRShepherdConfig.TrackMode, ps.TrackMode)
+		}
+	}
+
+	if before.TargetBranch != after.TargetBranch {
+		summary = append(summary, fmt.Sprintf("- Default branch: %s", after.TargetBranch))
+		notice := fmt.Sprintf("The repository's default branch is now %s. Branch from, rebase onto and target PRs at %s from now on.", after.TargetBranch, after.TargetBranch)
+		for agentName, agent := range after.Agents {
+			if agent.Type == state.AgentTypeSupervisor {
+				continue
+			}
+			if _, err := msgMgr.Send(repoName, "daemon", agentName, notice); err != nil {
+				d.logger.Warn("Failed to tell %s/%s about the default branch change: %v", repoName, agentName, err)
+			}
+		}
+	}
+
+	if before.BranchTemplate != after.BranchTemplate {
+		tmpl := after.BranchTemplate
+		if tmpl == "" {
+			tmpl = branchname.DefaultTemplate
+		}
+		summary = append(summary, fmt.Sprintf("- Worker branch template: %s (applies to new workers)", tmpl))
+	}
+
+	if before.DraftPRs != after.DraftPRs {
+		summary = append(summary, fmt.Sprintf("- Draft PRs: %v (applies to new workers)", after.DraftPRs))
+	}
+
+	if before.PRDescriptions != after.PRDescriptions {
+		tmpl := after.PRDescriptions.Template
+		if tmpl == "" {
+			tmpl = "built-in"
+		}
+		summary = append(summary, fmt.Sprintf("- PR descriptions: %v (template: %s)", after.PRDescriptions.Enabled, tmpl))
+	}
+
+	if before.PushChecks != after.PushChecks {
+		summary = append(summary, "- Push checks: "+describePushChecks(after.PushChecks))
+		notice := "The repository's push checks changed. Your branch must now pass: " + describePushChecks(after.PushChecks) + ". Check it with `multiclaude worker check <your-name>` before you push."
+		if !after.PushChecks.Enabled() {
+			notice = "The repository's push checks are off now."
+		}
+		for agentName, agent := range after.Agents {
+			if agent.Type != state.AgentTypeWorker {
+				continue
+			}
+			if _, err := msgMgr.Send(repoName, "daemon", agentName, notice); err != nil {
+				d.logger.Warn("Failed to tell %s/%s about the push checks: %v", repoName, agentName, err)
+			}
+		}
+	}
+
+	if before.ReviewDispatch.Effective() != after.ReviewDispatch.Effective() {
+		summary = append(summary, fmt.Sprintf("- Review dispatch: %s (applies to reviews requested afterwards)", after.ReviewDispatch.Effective()))
+	}
+
+	if before.MaxWorkers != after.MaxWorkers {
+		line := "- Worker limit: none"
+		if limit := after.MaxWorkers; limit > 0 {
+			line = fmt.Sprintf("- Worker limit: %d at once", limit)
+			d.quotaMu.Lock()
+			running := len(d.workerInstances(repoName))
+			d.quotaMu.Unlock()
+			if running > limit {
+				line += fmt.Sprintf(" (%d running; no new workers start until fewer than %d are left)", running, limit)
+			}
+		}
+		summary = append(summary, line)
+	}
+
+	if before.ClaudeConfigDir != after.ClaudeConfigDir {
+		dir := after.ClaudeConfigDir
+		if dir == "" {
+			dir = "~/.claude"
+		}
+		summary = append(summary, fmt.Sprintf("- Claude config directory: %s (applies to agents started or restarted afterwards)", dir))
+	}
+
+	if !reflect.DeepEqual(before.CommentCommands, after.CommentCommands) {
+		allow := "off"
+		if len(after.CommentCommands.Allow) > 0 {
+			allow = "@" + strings.Join(after.CommentCommands.Allow, ", @")
+		}
+		summary = append(summary, fmt.Sprintf("- Comment commands from: %s", allow))
+	}
+
+	if len(summary) == 0 {
+		return
+	}
+	body := "Repository configuration changed (config_changed):\n\n" + strings.Join(summary, "\n")
+	if _, err := msgMgr.Send(repoName, "daemon", "supervisor", body); err != nil {
+		d.logger.Warn("Failed to send config_changed to supervisor of %s: %v", repoName, err)
+	}
+}
+
+// retrackAgents switches running agents of one type to a new PR tracking mode:
+// their tracking fragment is rewritten, and only its copy in their saved
+// prompt replaced, so restarts keep the new mode, and each agent is sent the
+// new instructions
+func (d *Daemon) retrackAgents(repoName string, repo state.Repository, agentType state.AgentType, oldMode, newMode state.TrackMode) {
+	oldSection := prompts.GenerateTrackingModePrompt(string(oldMode))
+	newSection := prompts.GenerateTrackingModePrompt(string(newMode))
+
+	for agentName, agent := range repo.Agents {
+		if agent.Type != agentType {
+			continue
+		}
+
+		fragment := prompts.Fragment{Name: prompts.FragmentTracking, Content: newSection}
+		if err := d.updatePromptFragment(agentName, fragment, oldSection); err != nil {
+			d.logger.Warn("Failed to update prompt for %s/%s: %v", repoName, agentName, err)
+		}
+
+		path := prompts.FragmentFile(d.paths.PromptFragmentsDir(agentName), prompts.FragmentTracking)
+		body := fmt.Sprintf("Your PR tracking configuration changed. This replaces the tracking mode in your instructions (kept in %s):\n\n%s", path, newSection)
+		if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, body); err != nil {
+			d.logger.Warn("Failed to send tracking mode change to %s/%s: %v", repoName, agentName, err)
+		}
+		d.logger.Info("Switched %s/%s to track mode %s", repoName, agentName, newMode)
+	}
+}
+
+// updatePromptFragment rewrites one fragment's include file and replaces its
+// copy in the agent's saved prompt, leaving the rest of the prompt alone.
+// Prompts saved before fragments had include files get legacy, the
+// fragment's old text, replaced with the include copy instead.
+func (d *Daemon) updatePromptFragment(agentName string, fragment prompts.Fragment, legacy string) error {
+	dir := d.paths.PromptFragmentsDir(agentName)
+	if _, err := prompts.WriteFragment(dir, fragment); err != nil {
+		return err
+	}
+
+	promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return nil
+	}
+	path := prompts.FragmentFile(dir, fragment.Name)
+	updated, ok := prompts.SetInclude(string(data), path, fragment.Content)
+	if !ok && legacy != "" && strings.Contains(updated, legacy) {
+		updated = strings.Replace(updated, legacy, prompts.Include(path, fragment.Content), 1)
+	}
+	if updated == string(data) {
+		return nil
+	}
+	return os.WriteFile(promptFile, []byte(updated), 0644)
+}
+
+// refreshPromptIncludes brings the include copies in an agent's saved prompt
+// up to date with their files
+func (d *Daemon) refreshPromptIncludes(repoName, agentName string) {
+	promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return
+	}
+	updated := prompts.ExpandIncludes(string(data))
+	if updated == string(data) {
+		return
+	}
+	if err := os.WriteFile(promptFile, []byte(updated), 0644); err != nil {
+		d.logger.Warn("Failed to update prompt fragments for %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// rosterInterval is how often rosters are checked when no events arrive.
+// Not every way of adding or removing an agent publishes an event.
+const rosterInterval = time.Minute
+
+// rosterLoop keeps each repository's roster current, checking after every
+// lifecycle event
+func (d *Daemon) rosterLoop() {
+	defer d.wg.Done()
+	d.logger.Info("Starting roster loop")
+
+	seq := d.events.Seq()
+	d.debug.expect("roster", rosterInterval, time.Now())
+	for {
+		d.debug.time("roster", d.writeRosters)
+		_, seq = d.events.Wait(d.ctx, seq, rosterInterval)
+		if d.ctx.Err() != nil {
+			d.logger.Info("roster loop stopped")
+			return
+		}
+	}
+}
+
+// writeRosters writes the roster file of each repository whose agents or
+// roster mode changed, and replaces the teammates section of its agents'
+// saved prompts, so restarts and resumed sessions see current teammates
+func (d *Daemon) writeRosters() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		mode := repo.Roster.Effective()
+		roster := prompts.FormatRoster(repo.Agents, "")
+		if d.rosters[repoName] == string(mode)+"\n"+roster {
+			continue
+		}
+
+		path := d.paths.RosterFile(repoName)
+		if mode == state.RosterOff {
+			os.Remove(path)
+		} else if err := writeRosterFile(path, repoName, roster); err != nil {
+			d.subsystemWarn("roster", "Failed to write roster for %s: %v", repoName, err)
+			continue
+		}
+
+		for agentName := range repo.Agents {
+			promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
+			data, err := os.ReadFile(promptFile)
+			if err != nil {
+				continue
+			}
+			updated := prompts.SetRosterSection(string(data), prompts.RosterSection(mode, repo.Agents, agentName, path))
+			if updated == string(data) {
+				continue
+			}
+			if err := os.WriteFile(promptFile, []byte(updated), 0644); err != nil {
+				d.logger.Warn("Failed to update roster in prompt for %s/%s: %v", repoName, agentName, err)
+			}
+		}
+		d.rosters[repoName] = string(mode) + "\n" + roster
+	}
+}
+
+// writeRosterFile writes a repository's roster to path
+func writeRosterFile(path, repoName, roster string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("# Agents in %s\n\nKept current by the multiclaude daemon.\n\n%s", repoName, roster)
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// federationLoop periodically syncs federated repositories with their relays
+func (d *Daemon) federationLoop() {
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.Federation })
+	d.periodicLoop("federation", interval, d.syncFederation, d.slowInStandby(interval, d.syncFederation))
+}
+
+// syncFederation publishes each federated repository's workers and queued
+// messages, then delivers teammates' messages and caches their status
+func (d *Daemon) syncFederation() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if !repo.FederationConfig.Enabled {
+			continue
+		}
+		if err := d.syncRepoFederation(repoName, repo); err != nil {
+			d.subsystemWarn("federation", "Federation sync failed for %s: %v", repoName, err)
+		}
+	}
+}
+
+// syncRepoFederation runs one publish/fetch round for a repository
+func (d *Daemon) syncRepoFederation(repoName string, repo *state.Repository) error {
+	cfg := repo.FederationConfig
+	relay, err := federation.NewRelay(d.paths.RepoDir(repoName), federationNamespace(repoName, repo.GithubURL), cfg)
+	if err != nil {
+		return err
+	}
+	self := federation.PeerID(cfg)
+
+	d.federationMu.Lock()
+	defer d.federationMu.Unlock()
+
+	store, err := federation.LoadStore(d.paths.FederationFile(repoName))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	store.Prune(now)
+
+	status := federation.PeerStatus{Peer: self, UpdatedAt: now, Messages: store.Outbox}
+	for agentName, agent := range repo.Agents {
+		if agent.Type != state.AgentTypeWorker {
+			continue
+		}
+		status.Workers = append(status.Workers, federation.Worker{
+			Name:      agentName,
+			Task:      agent.Task,
+			Branch:    agent.Branch,
+			CreatedAt: agent.CreatedAt,
+		})
+	}
+	sort.Slice(status.Workers, func(i, j int) bool { return status.Workers[i].Name < status.Workers[j].Name })
+
+	if err := relay.Publish(status); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	peers, err := relay.Fetch()
+	if err != nil {
+		return fmt.Errorf("failed to fetch peers: %w", err)
+	}
+
+	// Deliver teammates' messages to local inboxes; replies go back to <agent>@<peer>
+	inbound, cutoffs := federation.Inbound(peers, self, store.Received)
+	msgMgr := d.getMessageManager()
+	for _, m := range inbound {
+		if _, err := msgMgr.Send(repoName, m.From+"@"+m.Peer, m.ToAgent, m.Body); err != nil {
+			d.logger.Warn("Failed to deliver federated message %s to %s/%s: %v", m.ID, repoName, m.ToAgent, err)
+		}
+	}
+	store.Received = cutoffs
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	if d.federationPeers == nil {
+		d.federationPeers = make(map[string][]federation.PeerStatus)
+	}
+	d.federationPeers[repoName] = peers
+
+	if len(inbound) > 0 {
+		d.logger.Info("Received %d federated message(s) for %s", len(inbound), repoName)
+		go d.routeMessages()
+	}
+	return nil
+}
+
+// federationNamespace keeps repositories apart in a shared relay directory. It
+// uses the GitHub owner/repo so teammates' differently named clones still meet.
+func federationNamespace(repoName, githubURL string) string {
+	if owner, repo, err := fork.ParseGitHubURL(githubURL); err == nil {
+		return owner + "/" + repo
+	}
+	return repoName
+}
+
+// handleFederationStatus returns teammates' workers for a federated repository
+// as of the last sync
+func (d *Daemon) handleFederationStatus(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	cfg, err := d.state.GetFederationConfig(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	self := federation.PeerID(cfg)
+
+	d.federationMu.Lock()
+	peers := d.federationPeers[repoName]
+	d.federationMu.Unlock()
+
+	now := time.Now()
+	peerList := []map[string]interface{}{}
+	for _, p := range peers {
+		if p.Peer == self {
+			continue
+		}
+		workers := make([]map[string]interface{}, 0, len(p.Workers))
+		for _, w := range p.Workers {
+			workers = append(workers, map[string]interface{}{
+				"name":       w.Name,
+				"task":       w.Task,
+				"branch":     w.Branch,
+				"created_at": w.CreatedAt,
+			})
+		}
+		peerList = append(peerList, map[string]interface{}{
+			"peer":       p.Peer,
+			"updated_at": p.UpdatedAt,
+			"stale":      p.Stale(now),
+			"workers":    workers,
+		})
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"enabled": cfg.Enabled,
+			"peer_id": self,
+			"relay":   cfg.Relay,
+			"peers":   peerList,
+		},
+	}
+}
+
+// handleFederationSend queues a message for an agent on a teammate's daemon.
+// It is published on the next sync.
+func (d *Daemon) handleFederationSend(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	from, errResp, ok := getRequiredStringArg(req.Args, "from", "sender agent is required")
+	if !ok {
+		return errResp
+	}
+	to, errResp, ok := getRequiredStringArg(req.Args, "to", "recipient is required (<agent>@<peer>)")
+	if !ok {
+		return errResp
+	}
+	body, errResp, ok := getRequiredStringArg(req.Args, "body", "message body is required")
+	if !ok {
+		return errResp
+	}
+
+	toAgent, toPeer, ok := federation.ParseAddress(to)
+	if !ok {
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid recipient %q: expected <agent>@<peer>", to)}
+	}
+
+	cfg, err := d.state.GetFederationConfig(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if !cfg.Enabled {
+		return socket.Response{Success: false, Error: fmt.Sprintf("federation is not enabled for %s; see 'multiclaude config %s --federation'", repoName, repoName)}
+	}
+
+	msg := federation.Message{
+		ID:      fmt.Sprintf("fed-%d", time.Now().UnixNano()),
+		From:    from,
+		ToPeer:  toPeer,
+		ToAgent: toAgent,
+		Body:    body,
+		Time:    time.Now(),
+	}
+
+	d.federationMu.Lock()
+	defer d.federationMu.Unlock()
+
+	store, err := federation.LoadStore(d.paths.FederationFile(repoName))
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	store.Queue(msg)
+	if err := store.Save(); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Queued federated message %s from %s/%s to %s", msg.ID, repoName, from, to)
+	return socket.Response{Success: true, Data: msg.ID}
+}
+
+// handleAsk opens a ticket for a question to another agent and delivers the
+// question as a message naming the ticket
+func (d *Daemon) handleAsk(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	from, errResp, ok := getRequiredStringArg(req.Args, "from", "asking agent is required")
+	if !ok {
+		return errResp
+	}
+	to, errResp, ok := getRequiredStringArg(req.Args, "to", "agent to ask is required")
+	if !ok {
+		return errResp
+	}
+	question, errResp, ok := getRequiredStringArg(req.Args, "question", "question is required")
+	if !ok {
+		return errResp
+	}
+	timeoutSeconds, _ := req.Args["timeout_seconds"].(float64)
+	if timeoutSeconds <= 0 {
+		return socket.Response{Success: false, Error: "timeout_seconds must be positive"}
+	}
+	wait, _ := req.Args["wait"].(bool)
+
+	if to == from {
+		return socket.Response{Success: false, Error: "an agent cannot ask itself"}
+	}
+	if to != notify.HumanRecipient {
+		if _, exists := d.state.GetAgent(repoName, to); !exists {
+			return errorResponse(errors.AgentNotFound("agent", to, repoName))
+		}
+	}
+
+	d.ticketsMu.Lock()
+	defer d.ticketsMu.Unlock()
+
+	store, err := tickets.Load(d.paths.TicketsFile())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	now := time.Now()
+	store.Prune(now)
+	ticket := store.Open(repoName, from, to, question, time.Duration(timeoutSeconds*float64(time.Second)), !wait, now)
+	if err := store.Save(); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
 
-	case "get_repo_config":
-		return d.handleGetRepoConfig(req)
+	body := fmt.Sprintf("❓ Question from %s (ticket %s, please answer by %s): %s\nReply with: multiclaude answer %s \"<your answer>\"",
+		from, ticket.ID, ticket.Deadline.Format("15:04"), question, ticket.ID)
+	if _, err := d.getMessageManager().Send(repoName, from, to, body); err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to deliver question: %v", err)}
+	}
+	go d.routeMessages()
 
-	case "update_repo_config":
-		return d.handleUpdateRepoConfig(req)
+	d.logger.Info("Opened ticket %s: %s/%s asked %s", ticket.ID, repoName, from, to)
+	return socket.Response{Success: true, Data: ticketData(ticket, now)}
+}
 
-	case "set_current_repo":
-		return d.handleSetCurrentRepo(req)
+// handleAnswer records the answer to a ticket. Askers that aren't waiting for
+// it, and askers whose wait already timed out, get the answer as a message.
+func (d *Daemon) handleAnswer(req socket.Request) socket.Response {
+	id, errResp, ok := getRequiredStringArg(req.Args, "ticket", "ticket ID is required")
+	if !ok {
+		return errResp
+	}
+	answer, errResp, ok := getRequiredStringArg(req.Args, "answer", "answer is required")
+	if !ok {
+		return errResp
+	}
+	from, _ := req.Args["from"].(string)
 
-	case "get_current_repo":
-		return d.handleGetCurrentRepo(req)
+	d.ticketsMu.Lock()
+	defer d.ticketsMu.Unlock()
 
-	case "clear_current_repo":
-		return d.handleClearCurrentRepo(req)
+	store, err := tickets.Load(d.paths.TicketsFile())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	now := time.Now()
+	late := false
+	if ticket, err := store.Get(id); err == nil {
+		late = ticket.Status(now) == tickets.StatusExpired
+		if from == "" {
+			from = ticket.To
+		}
+	}
+	ticket, err := store.Answer(id, answer, from, now)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if err := store.Save(); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
 
-	case "route_messages":
+	data := ticketData(ticket, now)
+	data["late"] = late
+	if ticket.Notify || late {
+		body := fmt.Sprintf("💬 Answer from %s (ticket %s): %s\n\nYou asked: %s", from, ticket.ID, answer, ticket.Question)
+		if _, err := d.getMessageManager().Send(ticket.Repo, from, ticket.From, body); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("answer recorded but not delivered: %v", err)}
+		}
 		go d.routeMessages()
-		return socket.Response{Success: true, Data: "Message routing triggered"}
+		data["delivered_as_message"] = true
+	}
 
-	case "task_history":
-		return d.handleTaskHistory(req)
+	d.logger.Info("Answered ticket %s (%s/%s -> %s, late=%v)", ticket.ID, ticket.Repo, ticket.From, from, late)
+	return socket.Response{Success: true, Data: data}
+}
 
-	case "spawn_agent":
-		return d.handleSpawnAgent(req)
+// handleEvents returns the lifecycle events after a sequence number. With
+// wait_seconds it long-polls: when there are none yet it waits for one, so
+// watchers can follow along with one request at a time.
+func (d *Daemon) handleEvents(req socket.Request) socket.Response {
+	since := d.events.Seq()
+	if s, ok := req.Args["since"].(float64); ok && s >= 0 {
+		since = uint64(s)
+	}
+	repoFilter, _ := req.Args["repo"].(string)
+
+	var evs []events.Event
+	var seq uint64
+	if w, ok := req.Args["wait_seconds"].(float64); ok && w > 0 {
+		wait := min(time.Duration(w*float64(time.Second)), maxEventWait)
+		evs, seq = d.events.Wait(d.ctx, since, wait)
+	} else {
+		evs, seq = d.events.Since(since)
+	}
 
-	default:
-		return socket.Response{
-			Success: false,
-			Error:   fmt.Sprintf("unknown command: %q. Run 'multiclaude --help' for available commands", req.Command),
+	matched := []events.Event{}
+	for _, e := range evs {
+		if repoFilter == "" || e.Repo == repoFilter {
+			matched = append(matched, e)
 		}
 	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"events": matched,
+		"seq":    seq,
+	}}
 }
 
-// handleStatus returns daemon status
-func (d *Daemon) handleStatus(req socket.Request) socket.Response {
-	repos := d.state.ListRepos()
-	agentCount := 0
-	for _, repo := range repos {
-		agents, _ := d.state.ListAgents(repo)
-		agentCount += len(agents)
+// handleGetTicket returns a ticket and its status; waiting askers poll it
+func (d *Daemon) handleGetTicket(req socket.Request) socket.Response {
+	id, errResp, ok := getRequiredStringArg(req.Args, "ticket", "ticket ID is required")
+	if !ok {
+		return errResp
 	}
 
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"running":     true,
-			"pid":         os.Getpid(),
-			"repos":       len(repos),
-			"agents":      agentCount,
-			"socket_path": d.paths.DaemonSock,
-		},
+	d.ticketsMu.Lock()
+	defer d.ticketsMu.Unlock()
+
+	store, err := tickets.Load(d.paths.TicketsFile())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	ticket, err := store.Get(id)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
 	}
+	return socket.Response{Success: true, Data: ticketData(ticket, time.Now())}
 }
 
-// handleListRepos lists all repositories with detailed status
-func (d *Daemon) handleListRepos(req socket.Request) socket.Response {
-	repos := d.state.GetAllRepos()
+// ticketData is a ticket as returned over the socket
+func ticketData(t *tickets.Ticket, now time.Time) map[string]interface{} {
+	data := map[string]interface{}{
+		"ticket":   t.ID,
+		"repo":     t.Repo,
+		"from":     t.From,
+		"to":       t.To,
+		"question": t.Question,
+		"asked_at": t.AskedAt,
+		"deadline": t.Deadline,
+		"status":   string(t.Status(now)),
+	}
+	if t.AnsweredAt != nil {
+		data["answer"] = t.Answer
+		data["answered_by"] = t.AnsweredBy
+		data["answered_at"] = *t.AnsweredAt
+	}
+	return data
+}
 
-	// Check if rich format is requested
-	rich, _ := req.Args["rich"].(bool)
-	if !rich {
-		// Return simple list for backward compatibility
-		repoNames := make([]string, 0, len(repos))
-		for name := range repos {
-			repoNames = append(repoNames, name)
+// taskStartGrace is how long a started task's worker may take to show up in
+// state before the task is considered failed
+const taskStartGrace = 10 * time.Minute
+
+// atWorkerLimit reports whether a repository can't start another worker
+// under its max_workers setting, counting extra workers about to start
+func (d *Daemon) atWorkerLimit(repoName string, extra int) bool {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists || repo.MaxWorkers == 0 {
+		return false
+	}
+	d.quotaMu.Lock()
+	running := len(d.workerInstances(repoName))
+	d.quotaMu.Unlock()
+	return running+extra >= repo.MaxWorkers
+}
+
+// advanceTasks follows started tasks through to their PRs and starts workers
+// on waiting tasks whose prerequisites are merged or completed
+func (d *Daemon) advanceTasks() {
+	d.tasksMu.Lock()
+	defer d.tasksMu.Unlock()
+
+	store, err := tasks.Load(d.paths.TasksFile())
+	if err != nil {
+		d.subsystemError("tasks", "Failed to load tasks: %v", err)
+		return
+	}
+	now := time.Now()
+	changed := false
+
+	for _, t := range store.List("") {
+		if (t.Status == tasks.StatusRunning || t.Status == tasks.StatusReview) && d.followTask(t, now) {
+			changed = true
 		}
-		return socket.Response{Success: true, Data: repoNames}
 	}
 
-	// Return detailed repo info
-	repoDetails := make([]map[string]interface{}, 0, len(repos))
-	for repoName, repo := range repos {
-		// Count agents by type
-		workerCount := 0
-		totalAgents := len(repo.Agents)
-		for _, agent := range repo.Agents {
-			if agent.Type == state.AgentTypeWorker {
-				workerCount++
+	// Workers of started tasks count towards worker limits before they
+	// show up in state
+	starting := map[string]int{}
+	for _, t := range store.List("") {
+		if t.Status != tasks.StatusRunning {
+			continue
+		}
+		if repo, exists := d.state.GetRepo(t.Repo); exists {
+			if _, registered := repo.Agents[t.Worker]; !registered {
+				starting[t.Repo]++
 			}
 		}
+	}
 
-		// Check session health
-		sessionHealthy := false
-		if hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession); err == nil {
-			sessionHealthy = hasSession
+	var start []tasks.Task
+	for _, t := range store.Ready() {
+		if _, exists := d.state.GetRepo(t.Repo); !exists {
+			d.finishTask(t, tasks.StatusFailed, fmt.Sprintf("repository '%s' is no longer tracked", t.Repo), now)
+			changed = true
+			continue
 		}
-
-		// Determine PR management mode
-		prManagementMode := "merge-queue"
-		if repo.ForkConfig.IsFork {
-			prManagementMode = "pr-shepherd"
+		// Tasks wait in the queue until their repository's work hours start
+		if d.offHours(t.Repo) {
+			continue
 		}
-
-		repoDetails = append(repoDetails, map[string]interface{}{
-			"name":               repoName,
-			"github_url":         repo.GithubURL,
-			"tmux_session":       repo.TmuxSession,
-			"total_agents":       totalAgents,
-			"worker_count":       workerCount,
-			"session_healthy":    sessionHealthy,
-			"is_fork":            repo.ForkConfig.IsFork,
-			"upstream_owner":     repo.ForkConfig.UpstreamOwner,
-			"upstream_repo":      repo.ForkConfig.UpstreamRepo,
-			"pr_management_mode": prManagementMode,
-		})
+		// and while it runs as many workers as it may
+		if d.atWorkerLimit(t.Repo, starting[t.Repo]) {
+			continue
+		}
+		starting[t.Repo]++
+		t.Status = tasks.StatusRunning
+		t.Worker = d.unusedWorkerName(t.Repo)
+		t.Branch = "work/" + t.Worker
+		t.StartedAt = &now
+		start = append(start, *t)
+		changed = true
+	}
+
+	count := len(store.Tasks)
+	store.Prune(now)
+	if !changed && len(store.Tasks) == count {
+		return
+	}
+	if err := store.Save(); err != nil {
+		d.subsystemError("tasks", "Failed to save tasks: %v", err)
+		return
 	}
 
-	return socket.Response{Success: true, Data: repoDetails}
+	// Creating a worker fetches and starts Claude, so it runs in the background
+	for _, t := range start {
+		d.logger.Info("Starting task %s in %s as worker %s", t.ID, t.Repo, t.Worker)
+		d.events.Publish(events.EventTaskStarted, t.Repo, t.Worker, map[string]string{"id": t.ID, "task": t.Description})
+		d.wg.Add(1)
+		go func(t tasks.Task) {
+			defer d.wg.Done()
+			if err := d.startWorker(t.Repo, t.Worker, t.Description); err != nil {
+				d.failTask(t.ID, fmt.Sprintf("failed to start worker: %v", err))
+			}
+		}(t)
+	}
 }
 
-// handleAddRepo adds a new repository
-func (d *Daemon) handleAddRepo(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required (e.g., 'my-project')")
-	if !ok {
-		return errResp
-	}
+// followTask updates a started task from its worker and PR, reporting
+// whether the task changed
+func (d *Daemon) followTask(t *tasks.Task, now time.Time) bool {
+	if t.Status == tasks.StatusRunning {
+		agent, exists := d.state.GetAgent(t.Repo, t.Worker)
+		if exists && !agent.ReadyForCleanup {
+			return false
+		}
 
-	githubURL, errResp, ok := getRequiredStringArg(req.Args, "github_url", "GitHub repository URL is required (e.g., 'https://github.com/owner/repo')")
-	if !ok {
-		return errResp
+		var failure string
+		if exists {
+			failure = agent.FailureReason
+			if agent.Branch != "" {
+				t.Branch = agent.Branch
+			}
+		} else {
+			entry, found := d.finishedWorker(t.Repo, t.Worker, *t.StartedAt)
+			if !found {
+				if now.Sub(*t.StartedAt) < taskStartGrace {
+					return false
+				}
+				d.finishTask(t, tasks.StatusFailed, fmt.Sprintf("worker %s is gone without finishing", t.Worker), now)
+				return true
+			}
+			failure = entry.FailureReason
+			if entry.Branch != "" {
+				t.Branch = entry.Branch
+			}
+		}
+		if failure != "" {
+			d.finishTask(t, tasks.StatusFailed, failure, now)
+			return true
+		}
 	}
 
-	tmuxSession, errResp, ok := getRequiredStringArg(req.Args, "tmux_session", "tmux session name is required")
-	if !ok {
-		return errResp
+	prs, err := d.repoPullRequests(t.Repo)
+	if err != nil {
+		d.logger.Debug("Failed to look up PR for task %s: %v", t.ID, err)
+		return false
+	}
+	pr, found := prs[t.Branch]
+	switch {
+	case !found && t.Status == tasks.StatusRunning:
+		d.finishTask(t, tasks.StatusCompleted, "", now)
+	case !found:
+		return false
+	case pr.status() == "merged":
+		t.PRURL = pr.URL
+		d.finishTask(t, tasks.StatusMerged, "", now)
+	case pr.status() == "closed":
+		t.PRURL = pr.URL
+		d.finishTask(t, tasks.StatusFailed, fmt.Sprintf("PR #%d was closed without merging", pr.Number), now)
+	case t.Status == tasks.StatusRunning:
+		t.Status = tasks.StatusReview
+		t.PRURL = pr.URL
+	default:
+		return false
 	}
+	return true
+}
 
-	// Parse merge queue configuration (optional, defaults to enabled with "all" tracking)
-	mqConfig := state.DefaultMergeQueueConfig()
-	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
-		mqConfig.Enabled = mqEnabled
+// finishedWorker returns the history entry of a worker that finished after a time
+func (d *Daemon) finishedWorker(repoName, worker string, since time.Time) (state.TaskHistoryEntry, bool) {
+	history, err := d.state.GetTaskHistory(repoName, 0)
+	if err != nil {
+		return state.TaskHistoryEntry{}, false
 	}
-	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
-		mode, err := state.ParseTrackMode(mqTrackMode)
-		if err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+	for _, entry := range history {
+		if entry.Name == worker && !entry.CompletedAt.Before(since) {
+			return entry, true
 		}
-		mqConfig.TrackMode = mode
 	}
+	return state.TaskHistoryEntry{}, false
+}
 
-	// Parse fork configuration (optional)
-	var forkConfig state.ForkConfig
-	if isFork, ok := req.Args["is_fork"].(bool); ok {
-		forkConfig.IsFork = isFork
-	}
-	if upstreamURL, ok := req.Args["upstream_url"].(string); ok {
-		forkConfig.UpstreamURL = upstreamURL
+// finishTask records a task's final status and announces it
+func (d *Daemon) finishTask(t *tasks.Task, status tasks.Status, reason string, now time.Time) {
+	t.Finish(status, reason, now)
+	d.logger.Info("Task %s in %s %s %s", t.ID, t.Repo, status, reason)
+	d.events.Publish(events.EventTaskFinished, t.Repo, t.Worker, map[string]string{"id": t.ID, "status": string(status), "reason": reason})
+}
+
+// failTask marks a started task failed, e.g. when its worker couldn't be created
+func (d *Daemon) failTask(id, reason string) {
+	d.tasksMu.Lock()
+	defer d.tasksMu.Unlock()
+
+	store, err := tasks.Load(d.paths.TasksFile())
+	if err != nil {
+		d.subsystemError("tasks", "Failed to load tasks: %v", err)
+		return
 	}
-	if upstreamOwner, ok := req.Args["upstream_owner"].(string); ok {
-		forkConfig.UpstreamOwner = upstreamOwner
+	t, err := store.Get(id)
+	if err != nil || t.Finished() {
+		return
 	}
-	if upstreamRepo, ok := req.Args["upstream_repo"].(string); ok {
-		forkConfig.UpstreamRepo = upstreamRepo
+	d.finishTask(t, tasks.StatusFailed, reason, time.Now())
+	if err := store.Save(); err != nil {
+		d.subsystemError("tasks", "Failed to save tasks: %v", err)
 	}
+}
 
-	// Parse PR shepherd configuration (optional, defaults for fork mode)
-	psConfig := state.DefaultPRShepherdConfig()
-	if psEnabled, ok := req.Args["ps_enabled"].(bool); ok {
-		psConfig.Enabled = psEnabled
-	}
-	if psTrackMode, ok := req.Args["ps_track_mode"].(string); ok {
-		mode, err := state.ParseTrackMode(psTrackMode)
-		if err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+// unusedWorkerName returns a generated worker name no agent in the repository has
+func (d *Daemon) unusedWorkerName(repoName string) string {
+	name := names.Generate()
+	for i := 0; i < 10; i++ {
+		if _, exists := d.state.GetAgent(repoName, name); !exists {
+			break
 		}
-		psConfig.TrackMode = mode
+		name = names.Generate()
 	}
+	return name
+}
 
-	// If in fork mode, disable merge-queue and enable pr-shepherd by default
-	if forkConfig.IsFork {
-		mqConfig.Enabled = false
-		psConfig.Enabled = true
+// createWorker creates a worker the way `multiclaude worker create` does,
+// so queued tasks get the same worktree, prompt and window as any other. It is
+// stopped if the daemon stops first.
+func (d *Daemon) createWorker(repo, name, task string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
 	}
-
-	repo := &state.Repository{
-		GithubURL:        githubURL,
-		TmuxSession:      tmuxSession,
-		Agents:           make(map[string]state.Agent),
-		MergeQueueConfig: mqConfig,
-		PRShepherdConfig: psConfig,
-		ForkConfig:       forkConfig,
+	out, err := exec.CommandContext(d.ctx, executable, "worker", "create", task, "--repo", repo, "--name", name).CombinedOutput()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		return fmt.Errorf("%w: %s", err, lines[len(lines)-1])
 	}
+	return nil
+}
 
-	if err := d.state.AddRepo(name, repo); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
-	}
+// Spawn hooks, named as in the repository config and the run_spawn_hook request
+const (
+	preSpawnHook  = "pre_spawn"
+	postSpawnHook = "post_spawn"
+)
 
-	if forkConfig.IsFork {
-		d.logger.Info("Added repository: %s (fork of %s/%s, pr-shepherd: enabled=%v)", name, forkConfig.UpstreamOwner, forkConfig.UpstreamRepo, psConfig.Enabled)
-	} else {
-		d.logger.Info("Added repository: %s (merge queue: enabled=%v, track=%s)", name, mqConfig.Enabled, mqConfig.TrackMode)
-	}
-	return socket.Response{Success: true}
+// spawnHookTimeout bounds how long a spawn hook may run
+const spawnHookTimeout = 10 * time.Minute
+
+// spawnTarget is the agent a spawn hook runs for
+type spawnTarget struct {
+	Repo     string
+	Agent    string
+	Worktree string
+	Branch   string
+	Task     string
 }
 
-// handleRemoveRepo removes a repository from state
-func (d *Daemon) handleRemoveRepo(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
+// runSpawnHook runs the repository's pre_spawn or post_spawn command, if it
+// has one, in the agent's worktree with the agent described in the
+// environment. The error ends with the last line of the command's output.
+func (d *Daemon) runSpawnHook(hook string, target spawnTarget) error {
+	hooks, err := d.state.GetSpawnHooks(target.Repo)
+	if err != nil {
+		return err
+	}
+	command := hooks.PreSpawn
+	if hook == postSpawnHook {
+		command = hooks.PostSpawn
+	}
+	if command == "" {
+		return nil
 	}
 
-	if err := d.state.RemoveRepo(name); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+	ctx, cancel := context.WithTimeout(d.ctx, spawnHookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = target.Worktree
+	cmd.Env = append(os.Environ(),
+		"MULTICLAUDE_HOOK="+hook,
+		"MULTICLAUDE_REPO="+target.Repo,
+		"MULTICLAUDE_AGENT="+target.Agent,
+		"MULTICLAUDE_WORKTREE="+target.Worktree,
+		"MULTICLAUDE_BRANCH="+target.Branch,
+		"MULTICLAUDE_TASK="+target.Task,
+	)
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if output != "" {
+		d.logger.Debug("%s hook output for %s/%s:\n%s", hook, target.Repo, target.Agent, output)
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", spawnHookTimeout)
+		}
+		if output != "" {
+			lines := strings.Split(output, "\n")
+			err = fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		d.logger.Error("%s hook for %s/%s failed: %v", hook, target.Repo, target.Agent, err)
+		return fmt.Errorf("%s hook failed: %w", hook, err)
 	}
+	d.logger.Info("Ran %s hook for %s/%s (%s)", hook, target.Repo, target.Agent, time.Since(start).Round(time.Millisecond))
+	return nil
+}
 
-	d.logger.Info("Removed repository: %s", name)
-	return socket.Response{Success: true}
+// runPostSpawnHook runs the post_spawn hook in the background, so a slow
+// hook doesn't hold up whoever started the agent
+func (d *Daemon) runPostSpawnHook(target spawnTarget) {
+	go d.runSpawnHook(postSpawnHook, target)
 }
 
-// handleAddAgent adds a new agent
-func (d *Daemon) handleAddAgent(req socket.Request) socket.Response {
+// handleRunSpawnHook runs a spawn hook for an agent being created. The CLI
+// asks for the pre_spawn hook once the worker's worktree exists and before
+// Claude starts, and doesn't start the worker if it fails.
+func (d *Daemon) handleRunSpawnHook(req socket.Request) socket.Response {
 	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
 	if !ok {
 		return errResp
 	}
-
 	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
 	if !ok {
 		return errResp
 	}
-
-	agentTypeStr, errResp, ok := getRequiredStringArg(req.Args, "type", "agent type is required (supervisor, worker, merge-queue, or reviewer)")
+	hook, errResp, ok := getRequiredStringArg(req.Args, "hook", "hook is required (pre_spawn or post_spawn)")
+	if !ok {
+		return errResp
+	}
+	if hook != preSpawnHook && hook != postSpawnHook {
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid hook %q: must be 'pre_spawn' or 'post_spawn'", hook)}
+	}
+	worktreePath, errResp, ok := getRequiredStringArg(req.Args, "worktree_path", "path to the agent's worktree is required")
 	if !ok {
 		return errResp
 	}
+	branch, _ := req.Args["branch"].(string)
+	task, _ := req.Args["task"].(string)
+
+	target := spawnTarget{Repo: repoName, Agent: agentName, Worktree: worktreePath, Branch: branch, Task: task}
+	if err := d.runSpawnHook(hook, target); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true}
+}
 
-	worktreePath, errResp, ok := getRequiredStringArg(req.Args, "worktree_path", "path to the agent's git worktree is required")
+// handleAddTask queues a task, optionally after other tasks, and starts it
+// right away if nothing holds it back
+func (d *Daemon) handleAddTask(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
 	if !ok {
 		return errResp
 	}
-
-	tmuxWindow, errResp, ok := getRequiredStringArg(req.Args, "tmux_window", "tmux window name is required")
+	description, errResp, ok := getRequiredStringArg(req.Args, "task", "task description is required")
 	if !ok {
 		return errResp
 	}
-
-	// Get session ID from args or generate one
-	sessionID, _, ok := getRequiredStringArg(req.Args, "session_id", "")
-	if !ok {
-		sessionID = fmt.Sprintf("agent-%d", time.Now().UnixNano())
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
 	}
-
-	// Get PID from args (optional)
-	var pid int
-	if pidFloat, ok := req.Args["pid"].(float64); ok {
-		pid = int(pidFloat)
-	} else if pidInt, ok := req.Args["pid"].(int); ok {
-		pid = pidInt
+	if repo.Solo {
+		return socket.Response{Success: false, Error: fmt.Sprintf("'%s' is a solo repository; it has no workers to run tasks", repoName)}
 	}
-
-	agent := state.Agent{
-		Type:         state.AgentType(agentTypeStr),
-		WorktreePath: worktreePath,
-		TmuxWindow:   tmuxWindow,
-		SessionID:    sessionID,
-		PID:          pid,
-		CreatedAt:    time.Now(),
+	var after []string
+	if list, ok := req.Args["after"].([]interface{}); ok {
+		for _, id := range list {
+			if id, ok := id.(string); ok && id != "" {
+				after = append(after, id)
+			}
+		}
 	}
 
-	// Optional task field for workers
-	if task, ok := req.Args["task"].(string); ok {
-		agent.Task = task
+	d.tasksMu.Lock()
+	store, err := tasks.Load(d.paths.TasksFile())
+	if err != nil {
+		d.tasksMu.Unlock()
+		return socket.Response{Success: false, Error: err.Error()}
 	}
-
-	if err := d.state.AddAgent(repoName, agentName, agent); err != nil {
+	t, err := store.Add(repoName, description, after, time.Now())
+	if err == nil {
+		err = store.Save()
+	}
+	d.tasksMu.Unlock()
+	if err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
-	d.logger.Info("Added agent %s to repo %s", agentName, repoName)
-	return socket.Response{Success: true}
+	d.logger.Info("Queued task %s in %s after %v", t.ID, repoName, t.After)
+	d.advanceTasks()
+	return d.taskResponse(t.ID)
 }
 
-// handleRemoveAgent removes an agent
-func (d *Daemon) handleRemoveAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
-	}
+// taskResponse returns a task as it is after advanceTasks
+func (d *Daemon) taskResponse(id string) socket.Response {
+	d.tasksMu.Lock()
+	defer d.tasksMu.Unlock()
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
-	if !ok {
-		return errResp
+	store, err := tasks.Load(d.paths.TasksFile())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
 	}
-
-	if err := d.state.RemoveAgent(repoName, agentName); err != nil {
+	t, err := store.Get(id)
+	if err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
+	return socket.Response{Success: true, Data: taskData(store, t)}
+}
 
-	d.logger.Info("Removed agent %s from repo %s", agentName, repoName)
-	return socket.Response{Success: true}
+// handleListTasks returns the tasks of a repository, or of every repository
+func (d *Daemon) handleListTasks(req socket.Request) socket.Response {
+	repoName, _ := req.Args["repo"].(string)
+
+	d.tasksMu.Lock()
+	defer d.tasksMu.Unlock()
+
+	store, err := tasks.Load(d.paths.TasksFile())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	list := store.List(repoName)
+	result := make([]map[string]interface{}, len(list))
+	for i, t := range list {
+		result[i] = taskData(store, t)
+	}
+	return socket.Response{Success: true, Data: result}
 }
 
-// handleListAgents lists agents for a repository
-func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+// handleCancelTask cancels a task that hasn't started
+func (d *Daemon) handleCancelTask(req socket.Request) socket.Response {
+	id, errResp, ok := getRequiredStringArg(req.Args, "id", "task ID is required")
 	if !ok {
 		return errResp
 	}
 
-	agents, err := d.state.ListAgents(repoName)
+	d.tasksMu.Lock()
+	defer d.tasksMu.Unlock()
+
+	store, err := tasks.Load(d.paths.TasksFile())
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	t, err := store.Cancel(id, time.Now())
 	if err != nil {
+		if started, _ := store.Get(id); started != nil && started.Worker != "" && !started.Finished() {
+			err = fmt.Errorf("%w; remove its worker with: multiclaude worker rm %s", err, started.Worker)
+		}
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if err := store.Save(); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
+	d.logger.Info("Cancelled task %s in %s", t.ID, t.Repo)
+	d.events.Publish(events.EventTaskFinished, t.Repo, "", map[string]string{"id": t.ID, "status": string(t.Status)})
+	return socket.Response{Success: true, Data: taskData(store, t)}
+}
 
-	// Check if rich format is requested
-	rich, _ := req.Args["rich"].(bool)
+// taskData is a task as returned over the socket, with the prerequisites
+// it is waiting on and those that keep it from ever starting
+func taskData(store *tasks.Store, t *tasks.Task) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":          t.ID,
+		"repo":        t.Repo,
+		"description": t.Description,
+		"after":       t.After,
+		"status":      string(t.Status),
+		"worker":      t.Worker,
+		"branch":      t.Branch,
+		"pr_url":      t.PRURL,
+		"error":       t.Error,
+		"created_at":  t.CreatedAt,
+	}
+	if t.StartedAt != nil {
+		data["started_at"] = *t.StartedAt
+	}
+	if t.DoneAt != nil {
+		data["done_at"] = *t.DoneAt
+	}
+	if t.Status == tasks.StatusWaiting {
+		data["pending"] = store.Pending(t)
+		data["blocking"] = store.Blocking(t)
+	}
+	return data
+}
 
-	// Get repository to check session
-	repo, repoExists := d.state.GetRepo(repoName)
+// trainTestTimeout bounds how long a simulation's test command may run
+const trainTestTimeout = 30 * time.Minute
 
-	// Get full agent details
-	agentDetails := make([]map[string]interface{}, 0, len(agents))
-	for _, agentName := range agents {
-		agent, exists := d.state.GetAgent(repoName, agentName)
-		if !exists {
+// trainLogLines is how many lines of a failed simulation's log the PR's worker gets
+const trainLogLines = 40
+
+// mergeTrainLoop simulates open PRs on top of the merge train of every
+// repository whose merge queue has a test command
+func (d *Daemon) mergeTrainLoop() {
+	interval := d.interval(func(i daemonconfig.Intervals) time.Duration { return i.MergeTrain })
+	d.periodicLoop("merge train", interval, nil, d.slowInStandby(interval, d.runMergeTrains))
+}
+
+// runMergeTrains simulates at most one PR per repository, so a slow test
+// command in one repository holds back the others by one run at most
+func (d *Daemon) runMergeTrains() {
+	if d.githubProblem() != "" {
+		return
+	}
+	for repoName, repo := range d.state.GetAllRepos() {
+		if repo.Solo || !repo.MergeQueueConfig.Enabled || repo.MergeQueueConfig.TestCommand == "" {
 			continue
 		}
+		if d.ctx.Err() != nil {
+			return
+		}
+		d.advanceTrain(repoName, repo.MergeQueueConfig.TestCommand)
+	}
+}
 
-		detail := map[string]interface{}{
-			"name":          agentName,
-			"type":          agent.Type,
-			"worktree_path": agent.WorktreePath,
-			"tmux_window":   agent.TmuxWindow,
-			"task":          agent.Task,
-			"created_at":    agent.CreatedAt,
+// trainPRs returns the open, merged and closed PRs against the repository's
+// default branch
+func (d *Daemon) trainPRs(repoName, base string) ([]train.PR, error) {
+	prs, err := d.repoPullRequests(repoName)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]train.PR, 0, len(prs))
+	for _, pr := range prs {
+		if pr.BaseRefName != "" && pr.BaseRefName != base {
+			continue
 		}
+		list = append(list, train.PR{Number: pr.Number, Branch: pr.HeadRefName, Head: pr.HeadRefOid, State: pr.status()})
+	}
+	return list, nil
+}
 
-		// Add rich status information if requested
-		if rich {
-			// Determine agent status
-			status := "unknown"
-			if agent.ReadyForCleanup {
-				status = "completed"
-			} else if repoExists {
-				// Check if window exists (means agent is running)
-				hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
-				if err == nil && hasWindow {
-					status = "running"
-				} else {
-					status = "stopped"
-				}
-			}
-			detail["status"] = status
+// advanceTrain brings a repository's train up to date with its PRs and
+// simulates the next PR that hasn't been: merged onto the default branch
+// after the PRs already in the train, then tested. PRs that pass join the
+// train and the merge queue is told; failures go to the PR's worker.
+func (d *Daemon) advanceTrain(repoName, testCommand string) {
+	base := d.repoDefaultBranch(repoName)
+	prs, err := d.trainPRs(repoName, base)
+	if err != nil {
+		d.logger.Debug("Failed to list PRs for the %s merge train: %v", repoName, err)
+		return
+	}
 
-			// Get current branch from worktree
-			branch := ""
-			if agent.WorktreePath != "" {
-				if b, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil {
-					branch = b
-				}
-			}
-			detail["branch"] = branch
+	d.trainMu.Lock()
+	t, err := train.Load(d.paths.TrainFile(repoName))
+	if err != nil {
+		d.trainMu.Unlock()
+		d.subsystemError("merge train", "Failed to load merge train for %s: %v", repoName, err)
+		return
+	}
+	for _, e := range t.Sync(prs) {
+		d.logger.Info("PR #%d in %s leaves the merge train to be tested again", e.PR, repoName)
+	}
+	next := t.Next(prs)
+	ahead := t.Passed()
+	err = t.Save()
+	d.trainMu.Unlock()
+	if err != nil {
+		d.subsystemError("merge train", "Failed to save merge train for %s: %v", repoName, err)
+		return
+	}
+	if next == nil {
+		return
+	}
 
-			// Get message counts
-			msgManager := messages.NewManager(d.paths.MessagesDir)
-			allMsgs, _ := msgManager.List(repoName, agentName)
-			pendingCount := 0
-			for _, msg := range allMsgs {
-				if msg.Status == messages.StatusPending || msg.Status == messages.StatusDelivered {
-					pendingCount++
-				}
+	logPath := d.paths.TrainLogFile(repoName, next.Number)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		d.subsystemError("merge train", "Failed to create merge train log directory: %v", err)
+		return
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		d.subsystemError("merge train", "Failed to create merge train log: %v", err)
+		return
+	}
+	d.logger.Info("Simulating PR #%d in %s on top of the merge train %v", next.Number, repoName, prNumbers(ahead))
+	sim := train.Simulation{
+		RepoDir: d.paths.RepoDir(repoName),
+		Remote:  "origin",
+		Base:    base,
+		Command: testCommand,
+		Timeout: trainTestTimeout,
+		Log:     logFile,
+	}
+	err = d.simulate(d.ctx, sim, ahead, *next)
+	logFile.Close()
+
+	if stale, ok := err.(*train.StaleError); ok {
+		d.trainMu.Lock()
+		if t, loadErr := train.Load(d.paths.TrainFile(repoName)); loadErr == nil {
+			t.Requeue(stale.PR)
+			if saveErr := t.Save(); saveErr != nil {
+				d.subsystemError("merge train", "Failed to save merge train for %s: %v", repoName, saveErr)
 			}
-			detail["messages_total"] = len(allMsgs)
-			detail["messages_pending"] = pendingCount
 		}
+		d.trainMu.Unlock()
+		d.logger.Info("%s; testing the merge train for %s again", err, repoName)
+		return
+	}
+	if err != nil && !train.IsFailure(err) {
+		d.logger.Warn("Could not simulate PR #%d in %s: %v", next.Number, repoName, err)
+		return
+	}
 
-		agentDetails = append(agentDetails, detail)
+	entry := train.Entry{
+		PR:       next.Number,
+		Branch:   next.Branch,
+		Head:     next.Head,
+		Status:   train.StatusPassed,
+		Ahead:    prNumbers(ahead),
+		Log:      logPath,
+		TestedAt: time.Now(),
+	}
+	if err != nil {
+		entry.Status = train.StatusFailed
+		entry.Reason = err.Error()
 	}
 
-	return socket.Response{Success: true, Data: agentDetails}
+	d.trainMu.Lock()
+	t, err = train.Load(d.paths.TrainFile(repoName))
+	if err == nil {
+		t.Record(entry)
+		err = t.Save()
+	}
+	d.trainMu.Unlock()
+	if err != nil {
+		d.subsystemError("merge train", "Failed to record PR #%d in the %s merge train: %v", next.Number, repoName, err)
+		return
+	}
+	d.announceTrainResult(repoName, entry)
 }
 
-// handleCompleteAgent marks an agent as ready for cleanup
-func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
+// announceTrainResult tells the merge queue about a PR that joined the train,
+// or the PR's worker (the supervisor if it has none) about one that failed
+func (d *Daemon) announceTrainResult(repoName string, e train.Entry) {
+	worker := d.branchWorker(repoName, e.Branch)
+	data := map[string]string{"pr": strconv.Itoa(e.PR), "branch": e.Branch}
+
+	var to, body string
+	if e.Status == train.StatusPassed {
+		d.logger.Info("PR #%d in %s passed the merge train simulation", e.PR, repoName)
+		d.events.Publish(events.EventTrainPassed, repoName, worker, data)
+		to = "merge-queue"
+		body = fmt.Sprintf("🚂 PR #%d passed the merge train simulation: merged onto the train's tip, the tests pass. Merge the train's PRs in order: %s (multiclaude train).",
+			e.PR, formatPRNumbers(append(e.Ahead, e.PR)))
+	} else {
+		d.logger.Info("PR #%d in %s failed the merge train simulation: %s", e.PR, repoName, e.Reason)
+		data["reason"] = e.Reason
+		d.events.Publish(events.EventTrainFailed, repoName, worker, data)
+		to = worker
+		if to == "" {
+			to = "supervisor"
+		}
+		body = fmt.Sprintf("🚂 PR #%d (%s) failed the merge train simulation: %s. It stays out of the train until %s is pushed to.\n\nLast lines of the log:\n```\n%s\n```\nFull log: %s",
+			e.PR, e.Branch, e.Reason, e.Branch, train.Tail(e.Log, trainLogLines), e.Log)
+	}
+	if _, exists := d.state.GetAgent(repoName, to); !exists {
+		return
 	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", to, body); err != nil {
+		d.logger.Error("Failed to send merge train result to %s: %v", to, err)
+		return
+	}
+	go d.routeMessages()
+}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+// branchWorker returns the agent working on a branch, or "" if there is none
+func (d *Daemon) branchWorker(repoName, branch string) string {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return ""
+	}
+	for name, agent := range repo.Agents {
+		if agent.Branch == branch || (agent.Branch == "" && branch == "work/"+name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// handleTrainStatus returns a repository's merge train
+func (d *Daemon) handleTrainStatus(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
 	if !ok {
 		return errResp
 	}
-
-	agent, exists := d.state.GetAgent(repoName, agentName)
+	repo, exists := d.state.GetRepo(repoName)
 	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude worker list --repo %s", agentName, repoName, repoName)}
+		return errorResponse(errors.RepoNotFound(repoName))
 	}
 
-	// Mark as ready for cleanup
-	agent.ReadyForCleanup = true
-
-	// Optional: capture summary and failure reason for task history
-	if summary, ok := req.Args["summary"].(string); ok && summary != "" {
-		agent.Summary = summary
+	d.trainMu.Lock()
+	defer d.trainMu.Unlock()
+	t, err := train.Load(d.paths.TrainFile(repoName))
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
 	}
-	if failureReason, ok := req.Args["failure_reason"].(string); ok && failureReason != "" {
-		agent.FailureReason = failureReason
+	entries := t.Entries
+	if entries == nil {
+		entries = []train.Entry{}
 	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"test_command": repo.MergeQueueConfig.TestCommand,
+		"entries":      entries,
+	}}
+}
 
-	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+// runSimulation runs a merge train simulation
+func runSimulation(ctx context.Context, sim train.Simulation, ahead []train.Entry, pr train.PR) error {
+	return sim.Run(ctx, ahead, pr)
+}
+
+// prNumbers returns the PR numbers of train entries
+func prNumbers(entries []train.Entry) []int {
+	numbers := make([]int, len(entries))
+	for i, e := range entries {
+		numbers[i] = e.PR
 	}
+	return numbers
+}
 
-	d.logger.Info("Agent %s/%s marked as ready for cleanup", repoName, agentName)
+// formatPRNumbers formats PR numbers as "#1, #2"
+func formatPRNumbers(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = fmt.Sprintf("#%d", n)
+	}
+	return strings.Join(parts, ", ")
+}
 
-	// Notify supervisor and merge-queue that worker or review agent completed
-	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview {
-		msgMgr := d.getMessageManager()
-		task := agent.Task
-		if task == "" {
-			task = "unknown task"
-		}
+// handleSetCurrentRepo sets the current/default repository
+func (d *Daemon) handleSetCurrentRepo(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+	if !ok {
+		return errResp
+	}
 
-		if agent.Type == state.AgentTypeWorker {
-			// Notify supervisor
-			supervisorMessage := fmt.Sprintf("Worker '%s' has completed its task: %s", agentName, task)
-			if _, err := msgMgr.Send(repoName, agentName, "supervisor", supervisorMessage); err != nil {
-				d.logger.Error("Failed to send completion message to supervisor: %v", err)
-			} else {
-				d.logger.Info("Sent completion notification to supervisor for worker %s", agentName)
-			}
+	if err := d.state.SetCurrentRepo(name); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
 
-			// Notify merge-queue so it can process any new PRs immediately
-			mergeQueueMessage := fmt.Sprintf("Worker '%s' has completed and may have created a PR. Task: %s. Please check for new PRs to process.", agentName, task)
-			if _, err := msgMgr.Send(repoName, agentName, "merge-queue", mergeQueueMessage); err != nil {
-				d.logger.Error("Failed to send completion message to merge-queue: %v", err)
-			} else {
-				d.logger.Info("Sent completion notification to merge-queue for worker %s", agentName)
-			}
-		} else if agent.Type == state.AgentTypeReview {
-			// Review agent completed - notify merge-queue to process the review results
-			mergeQueueMessage := fmt.Sprintf("Review agent '%s' has completed its review. Task: %s. Please check the review summary and decide on next steps.", agentName, task)
-			if _, err := msgMgr.Send(repoName, agentName, "merge-queue", mergeQueueMessage); err != nil {
-				d.logger.Error("Failed to send completion message to merge-queue: %v", err)
-			} else {
-				d.logger.Info("Sent completion notification to merge-queue for review agent %s", agentName)
-			}
-		}
+	d.logger.Info("Set current repository to: %s", name)
+	return socket.Response{Success: true, Data: name}
+}
 
-		// Trigger immediate message delivery
-		go d.routeMessages()
+// handleGetCurrentRepo returns the current/default repository
+func (d *Daemon) handleGetCurrentRepo(req socket.Request) socket.Response {
+	currentRepo := d.state.GetCurrentRepo()
+	if currentRepo == "" {
+		return socket.Response{Success: false, Error: "no current repository set"}
 	}
+	return socket.Response{Success: true, Data: currentRepo}
+}
 
-	// Trigger immediate cleanup check
-	go d.checkAgentHealth()
+// handleClearCurrentRepo clears the current/default repository
+func (d *Daemon) handleClearCurrentRepo(req socket.Request) socket.Response {
+	if err := d.state.ClearCurrentRepo(); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
 
+	d.logger.Info("Cleared current repository")
 	return socket.Response{Success: true}
 }
 
-// handleRestartAgent restarts an agent that has crashed or exited
-func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+// handleCreateProject creates a project grouping several repositories and
+// starts its project supervisor
+func (d *Daemon) handleCreateProject(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "project name is required")
 	if !ok {
 		return errResp
 	}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
-	if !ok {
-		return errResp
+	rawRepos, _ := req.Args["repos"].([]interface{})
+	repos := make([]string, 0, len(rawRepos))
+	for _, r := range rawRepos {
+		if repoName, ok := r.(string); ok && repoName != "" {
+			repos = append(repos, repoName)
+		}
+	}
+	if len(repos) == 0 {
+		return socket.Response{Success: false, Error: "missing 'repos': at least one repository is required"}
 	}
 
-	force, _ := req.Args["force"].(bool)
+	project := &state.Project{
+		Repos:       repos,
+		TmuxSession: fmt.Sprintf("mc-project-%s", name),
+		CreatedAt:   time.Now(),
+	}
+	if err := d.state.AddProject(name, project); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
 
-	agent, exists := d.state.GetAgent(repoName, agentName)
-	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude worker list --repo %s", agentName, repoName, repoName)}
+	if err := d.startProjectSupervisor(name, repos, project.TmuxSession); err != nil {
+		d.state.RemoveProject(name)
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to start project supervisor: %v", err)}
 	}
 
-	// Check if agent is marked for cleanup (completed)
-	if agent.ReadyForCleanup {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)}
+	d.logger.Info("Created project %s with repos %v", name, repos)
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"name":         name,
+			"repos":        repos,
+			"tmux_session": project.TmuxSession,
+		},
 	}
+}
 
-	// Check if tmux window exists
-	repo, exists := d.state.GetRepo(repoName)
-	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository '%s' not found in state", repoName)}
+// startProjectSupervisor creates the project's tmux session and starts its supervisor
+func (d *Daemon) startProjectSupervisor(name string, repos []string, tmuxSession string) error {
+	workDir := d.paths.ProjectDir(name)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create project directory: %w", err)
 	}
 
-	hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agentName)
+	promptPath, err := d.writeProjectPrompt(name, repos)
 	if err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to check tmux window: %v", err)}
-	}
-	if !hasWindow {
-		return socket.Response{Success: false, Error: fmt.Sprintf("tmux window '%s' does not exist - the agent may need to be recreated", agentName)}
+		return err
 	}
 
-	// Check if agent is already running
-	if agent.PID > 0 && isProcessAlive(agent.PID) {
-		if !force {
-			return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is already running with PID %d - use --force to restart anyway", agentName, agent.PID)}
-		}
-		d.logger.Info("Force restarting agent %s (PID %d was still running)", agentName, agent.PID)
+	if err := d.tmuxCommand("new-session", "-d", "-s", tmuxSession, "-n", "supervisor", "-c", workDir); err != nil {
+		return fmt.Errorf("failed to create tmux session: %w", err)
 	}
 
-	// Restart the agent
-	if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to restart agent: %v", err)}
+	sessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		d.tmux.KillSession(d.ctx, tmuxSession)
+		return fmt.Errorf("failed to generate session ID: %w", err)
 	}
 
-	// Get updated PID from state
-	updatedAgent, _ := d.state.GetAgent(repoName, agentName)
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"agent":   agentName,
-			"repo":    repoName,
-			"pid":     updatedAgent.PID,
-			"message": fmt.Sprintf("Agent '%s' restarted successfully", agentName),
-		},
+	pid, err := d.launchClaude("", tmuxSession, "supervisor", sessionID, promptPath, false)
+	if err != nil {
+		d.tmux.KillSession(d.ctx, tmuxSession)
+		return err
 	}
-}
-
-// handleTriggerCleanup manually triggers cleanup operations
-func (d *Daemon) handleTriggerCleanup(req socket.Request) socket.Response {
-	d.logger.Info("Manual cleanup triggered")
 
-	// Run health check to find dead agents
-	d.checkAgentHealth()
+	return d.state.UpdateProjectSupervisor(name, sessionID, pid)
+}
 
-	return socket.Response{
-		Success: true,
-		Data:    "Cleanup triggered",
+// writeProjectPrompt writes a project supervisor's prompt file and returns its path
+func (d *Daemon) writeProjectPrompt(name string, repos []string) (string, error) {
+	promptDir := filepath.Join(d.paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create prompt directory: %w", err)
 	}
+	promptPath := filepath.Join(promptDir, fmt.Sprintf("project-%s.md", name))
+	promptText := prompts.GenerateProjectSupervisorPrompt(name, repos)
+	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return promptPath, nil
 }
 
-// handleRepairState repairs state inconsistencies
-func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
-	d.logger.Info("State repair triggered")
-
-	agentsRemoved := 0
-	issuesFixed := 0
-
-	// Get a snapshot of repos to avoid concurrent map access
-	repos := d.state.GetAllRepos()
+// checkProjectSupervisors keeps project supervisors running, at startup and
+// in each health check: a project whose tmux session is gone gets a new one
+// with a new supervisor, and a supervisor whose Claude exited is restarted
+// in its window, resuming its session
+func (d *Daemon) checkProjectSupervisors() {
+	for _, name := range d.state.ListProjects() {
+		project, exists := d.state.GetProject(name)
+		if !exists {
+			continue
+		}
 
-	// Check all agents and verify resources exist
-	for repoName, repo := range repos {
-		// Check tmux session
-		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+		hasSession, err := d.tmux.HasSession(d.ctx, project.TmuxSession)
 		if err != nil {
-			d.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
+			d.subsystemError("health check", "Failed to check session %s: %v", project.TmuxSession, err)
 			continue
 		}
-
 		if !hasSession {
-			d.logger.Warn("Tmux session %s not found, removing all agents for repo %s", repo.TmuxSession, repoName)
-			// Remove all agents for this repo
-			for agentName := range repo.Agents {
-				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
-					agentsRemoved++
-				}
+			d.logger.Warn("Tmux session %s not found for project %s, restoring its supervisor", project.TmuxSession, name)
+			if err := d.startProjectSupervisor(name, project.Repos, project.TmuxSession); err != nil {
+				d.subsystemError("health check", "Failed to restore the supervisor of project %s: %v", name, err)
+			} else {
+				d.logger.Info("Restored the supervisor of project %s", name)
 			}
-			issuesFixed++
 			continue
 		}
 
-		// Check each agent's resources
-		for agentName, agent := range repo.Agents {
-			hasWindow, _ := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
-			if !hasWindow {
-				d.logger.Info("Removing agent %s (window not found)", agentName)
-				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
-					agentsRemoved++
-					issuesFixed++
-				}
-				continue
-			}
-
-			// Check if worktree exists (for workers and review agents)
-			if (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) && agent.WorktreePath != "" {
-				if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
-					d.logger.Warn("Worktree missing for agent %s, but window exists - keeping agent", agentName)
-					// Don't remove - user might have manually deleted worktree
-				}
-			}
+		if project.PID <= 0 || isProcessAlive(project.PID) {
+			continue
 		}
-	}
-
-	// Clean up orphaned worktrees
-	d.cleanupOrphanedWorktrees()
-
-	// Clean up orphaned message directories
-	msgMgr := d.getMessageManager()
-	repoNames := d.state.ListRepos()
-	for _, repoName := range repoNames {
-		validAgents, _ := d.state.ListAgents(repoName)
-		if count, err := msgMgr.CleanupOrphaned(repoName, validAgents); err == nil && count > 0 {
-			issuesFixed += count
+		d.logger.Warn("Project %s supervisor process (PID %d) not running, restarting it", name, project.PID)
+		if err := d.restartProjectSupervisor(name, project); err != nil {
+			d.subsystemError("health check", "Failed to restart the supervisor of project %s: %v", name, err)
+		} else {
+			d.logger.Info("Restarted the supervisor of project %s", name)
 		}
 	}
-
-	d.logger.Info("State repair completed: %d agents removed, %d issues fixed", agentsRemoved, issuesFixed)
-
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"agents_removed": agentsRemoved,
-			"issues_fixed":   issuesFixed,
-		},
-	}
 }
 
-// handleGetRepoConfig returns the configuration for a repository
-func (d *Daemon) handleGetRepoConfig(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
-	}
-
-	repo, exists := d.state.GetRepo(name)
-	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", name)}
+// restartProjectSupervisor restarts a project supervisor that has exited in
+// its existing window, resuming its Claude session when it has history
+func (d *Daemon) restartProjectSupervisor(name string, project state.Project) error {
+	promptPath, err := d.writeProjectPrompt(name, project.Repos)
+	if err != nil {
+		return err
 	}
 
-	// Get merge queue config (use default if not set for backward compatibility)
-	mqConfig := repo.MergeQueueConfig
-	if mqConfig.TrackMode == "" {
-		mqConfig = state.DefaultMergeQueueConfig()
+	var pid int
+	if simagent.Skip() || simagent.Enabled() {
+		if pid, err = d.launchClaude("", project.TmuxSession, "supervisor", project.SessionID, promptPath, false); err != nil {
+			return err
+		}
+	} else {
+		claudeProjectsDir, err := claude.ProjectsDir("")
+		if err != nil {
+			return err
+		}
+		encodedPath := strings.ReplaceAll(d.paths.ProjectDir(name), "/", "-")
+		hasHistory := false
+		if info, err := os.Stat(filepath.Join(claudeProjectsDir, encodedPath, project.SessionID+".jsonl")); err == nil && info.Size() > 0 {
+			hasHistory = true
+		}
+		result, err := d.claudeRunner.Start(d.ctx, project.TmuxSession, "supervisor", claude.Config{
+			SessionID:        project.SessionID,
+			Resume:           hasHistory,
+			SystemPromptFile: promptPath,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restart Claude: %w", err)
+		}
+		pid = result.PID
 	}
 
-	// Get PR shepherd config (use default if not set)
-	psConfig := repo.PRShepherdConfig
-	if psConfig.TrackMode == "" {
-		psConfig = state.DefaultPRShepherdConfig()
-	}
+	return d.state.UpdateProjectSupervisor(name, project.SessionID, pid)
+}
 
-	// Get fork config
-	forkConfig := repo.ForkConfig
+// handleListProjects lists all projects and their member repositories
+func (d *Daemon) handleListProjects(req socket.Request) socket.Response {
+	names := d.state.ListProjects()
+	sort.Strings(names)
 
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"mq_enabled":      mqConfig.Enabled,
-			"mq_track_mode":   string(mqConfig.TrackMode),
-			"ps_enabled":      psConfig.Enabled,
-			"ps_track_mode":   string(psConfig.TrackMode),
-			"is_fork":         forkConfig.IsFork,
-			"upstream_url":    forkConfig.UpstreamURL,
-			"upstream_owner":  forkConfig.UpstreamOwner,
-			"upstream_repo":   forkConfig.UpstreamRepo,
-			"force_fork_mode": forkConfig.ForceForkMode,
-		},
+	projects := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		project, exists := d.state.GetProject(name)
+		if !exists {
+			continue
+		}
+		projects = append(projects, map[string]interface{}{
+			"name":         name,
+			"repos":        project.Repos,
+			"tmux_session": project.TmuxSession,
+			"created_at":   project.CreatedAt,
+		})
 	}
+
+	return socket.Response{Success: true, Data: projects}
 }
 
-// handleUpdateRepoConfig updates the configuration for a repository
-func (d *Daemon) handleUpdateRepoConfig(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+// handleProjectStatus returns the status of a project and each of its member repositories
+func (d *Daemon) handleProjectStatus(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "project name is required")
 	if !ok {
 		return errResp
 	}
 
-	// Get current merge queue config
-	currentMQConfig, err := d.state.GetMergeQueueConfig(name)
-	if err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+	project, exists := d.state.GetProject(name)
+	if !exists {
+		return errorResponse(errors.New(errors.CategoryNotFound, fmt.Sprintf("project %q not found", name)).WithSuggestion("multiclaude project list"))
 	}
 
-	// Update merge queue config with provided values
-	mqUpdated := false
-	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
-		currentMQConfig.Enabled = mqEnabled
-		mqUpdated = true
-	}
-	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
-		mode, err := state.ParseTrackMode(mqTrackMode)
-		if err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
-		}
-		currentMQConfig.TrackMode = mode
-		mqUpdated = true
+	supervisorHealthy := false
+	if hasSession, err := d.tmux.HasSession(d.ctx, project.TmuxSession); err == nil {
+		supervisorHealthy = hasSession
 	}
 
-	if mqUpdated {
-		if err := d.state.UpdateMergeQueueConfig(name, currentMQConfig); err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+	repos := d.state.GetAllRepos()
+	repoDetails := make([]map[string]interface{}, 0, len(project.Repos))
+	for _, repoName := range project.Repos {
+		repo, exists := repos[repoName]
+		if !exists {
+			continue
 		}
-		d.logger.Info("Updated merge queue config for repo %s: enabled=%v, track=%s", name, currentMQConfig.Enabled, currentMQConfig.TrackMode)
-	}
-
-	// Get current PR shepherd config
-	currentPSConfig, err := d.state.GetPRShepherdConfig(name)
-	if err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
-	}
 
-	// Update PR shepherd config with provided values
-	psUpdated := false
-	if psEnabled, ok := req.Args["ps_enabled"].(bool); ok {
-		currentPSConfig.Enabled = psEnabled
-		psUpdated = true
-	}
-	if psTrackMode, ok := req.Args["ps_track_mode"].(string); ok {
-		mode, err := state.ParseTrackMode(psTrackMode)
-		if err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+		workerCount := 0
+		readyCount := 0
+		for _, agent := range repo.Agents {
+			if agent.Type == state.AgentTypeWorker {
+				workerCount++
+				if agent.ReadyForCleanup {
+					readyCount++
+				}
+			}
 		}
-		currentPSConfig.TrackMode = mode
-		psUpdated = true
-	}
 
-	if psUpdated {
-		if err := d.state.UpdatePRShepherdConfig(name, currentPSConfig); err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+		sessionHealthy := false
+		if hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession); err == nil {
+			sessionHealthy = hasSession
 		}
-		d.logger.Info("Updated PR shepherd config for repo %s: enabled=%v, track=%s", name, currentPSConfig.Enabled, currentPSConfig.TrackMode)
+
+		repoDetails = append(repoDetails, map[string]interface{}{
+			"name":            repoName,
+			"total_agents":    len(repo.Agents),
+			"worker_count":    workerCount,
+			"completed_count": readyCount,
+			"session_healthy": sessionHealthy,
+		})
 	}
 
-	return socket.Response{Success: true}
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"name":               name,
+			"tmux_session":       project.TmuxSession,
+			"supervisor_healthy": supervisorHealthy,
+			"repos":              repoDetails,
+		},
+	}
 }
 
-// handleSetCurrentRepo sets the current/default repository
-func (d *Daemon) handleSetCurrentRepo(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
+// handleRemoveProject stops a project's supervisor and removes the project.
+// Member repositories and their agents are left untouched.
+func (d *Daemon) handleRemoveProject(req socket.Request) socket.Response {
+	name, errResp, ok := getRequiredStringArg(req.Args, "name", "project name is required")
 	if !ok {
 		return errResp
 	}
 
-	if err := d.state.SetCurrentRepo(name); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+	project, exists := d.state.GetProject(name)
+	if !exists {
+		return errorResponse(errors.New(errors.CategoryNotFound, fmt.Sprintf("project %q not found", name)).WithSuggestion("multiclaude project list"))
 	}
 
-	d.logger.Info("Set current repository to: %s", name)
-	return socket.Response{Success: true, Data: name}
-}
-
-// handleGetCurrentRepo returns the current/default repository
-func (d *Daemon) handleGetCurrentRepo(req socket.Request) socket.Response {
-	currentRepo := d.state.GetCurrentRepo()
-	if currentRepo == "" {
-		return socket.Response{Success: false, Error: "no current repository set"}
+	if hasSession, err := d.tmux.HasSession(d.ctx, project.TmuxSession); err == nil && hasSession {
+		if err := d.tmux.KillSession(d.ctx, project.TmuxSession); err != nil {
+			d.logger.Warn("Failed to kill tmux session %s: %v", project.TmuxSession, err)
+		}
 	}
-	return socket.Response{Success: true, Data: currentRepo}
-}
 
-// handleClearCurrentRepo clears the current/default repository
-func (d *Daemon) handleClearCurrentRepo(req socket.Request) socket.Response {
-	if err := d.state.ClearCurrentRepo(); err != nil {
+	if err := d.state.RemoveProject(name); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
-	d.logger.Info("Cleared current repository")
+	d.logger.Info("Removed project: %s", name)
 	return socket.Response{Success: true}
 }
 
@@ -1372,6 +6641,7 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 	for repoName, agentNames := range deadAgents {
 		for _, agentName := range agentNames {
 			d.logger.Info("Cleaning up dead agent %s/%s", repoName, agentName)
+			d.zombies.Forget(repoName + "/" + agentName)
 
 			agent, exists := d.state.GetAgent(repoName, agentName)
 			if !exists {
@@ -1390,8 +6660,13 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 				d.recordTaskHistory(repoName, agentName, agent)
 			}
 
-			// Kill tmux window
-			if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+			if agent.Adopted {
+				// The window is the user's: take it out of the repo session if it
+				// was linked in, but leave Claude running
+				if err := d.tmux.UnlinkWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+					d.logger.Debug("Left adopted window %s in place: %v", agent.TmuxWindow, err)
+				}
+			} else if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
 				d.logger.Warn("Failed to kill tmux window %s: %v", agent.TmuxWindow, err)
 			} else {
 				d.logger.Info("Killed tmux window for agent %s: %s", agentName, agent.TmuxWindow)
@@ -1400,12 +6675,24 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 			// Remove from state
 			if err := d.state.RemoveAgent(repoName, agentName); err != nil {
 				d.logger.Error("Failed to remove agent %s/%s from state: %v", repoName, agentName, err)
+			} else {
+				d.events.Publish(events.EventAgentRemoved, repoName, agentName, nil)
+				d.archiveAgentOutput(repoName, agentName)
 			}
 
-			// Clean up worktree if it exists (workers and review agents have worktrees)
-			if agent.WorktreePath != "" && (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) {
-				repoPath := d.paths.RepoDir(repoName)
-				wt := worktree.NewManager(repoPath)
+			// Clean up worktree if it exists (workers, review agents and solo agents
+			// started with --worktree have worktrees)
+			if d.inMirror(repoName, agent.WorktreePath) {
+				if err := mirror.RemoveWorktree(d.paths.MirrorDir(repoName), agent.WorktreePath); err != nil {
+					d.logger.Warn("Failed to remove worktree %s: %v", agent.WorktreePath, err)
+				} else {
+					d.logger.Info("Removed mirror worktree for dead agent: %s", agent.WorktreePath)
+				}
+			} else if d.ownsWorktree(repoName, agent) {
+				if editor := openEditor(agent); editor != nil {
+					d.logger.Warn("Removing worktree %s while it is open in %s (PID %d)", agent.WorktreePath, editor.Editor, editor.PID)
+				}
+				wt := worktree.NewManager(d.repoPath(repoName, repo))
 				if err := wt.Remove(agent.WorktreePath, true); err != nil {
 					d.logger.Warn("Failed to remove worktree %s: %v", agent.WorktreePath, err)
 				} else {
@@ -1419,10 +6706,57 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 			if _, err := msgMgr.CleanupOrphaned(repoName, validAgents); err != nil {
 				d.logger.Warn("Failed to cleanup orphaned messages for %s: %v", repoName, err)
 			}
+
+			if repo.Solo && len(validAgents) == 0 {
+				d.removeSoloRepo(repoName, repo)
+			}
 		}
 	}
 }
 
+// ownsWorktree reports whether an agent's worktree was created for it and
+// should be removed with it
+func (d *Daemon) ownsWorktree(repoName string, agent state.Agent) bool {
+	if agent.WorktreePath == "" || agent.Adopted {
+		return false
+	}
+	switch agent.Type {
+	case state.AgentTypeWorker, state.AgentTypeReview:
+		return true
+	case state.AgentTypeSolo, state.AgentTypeObserver:
+		// Solo agents without --worktree work in the user's own directory;
+		// observers of a mirrored repository read a worktree of the mirror
+		return strings.HasPrefix(agent.WorktreePath, d.paths.WorktreeDir(repoName)+string(filepath.Separator))
+	default:
+		return false
+	}
+}
+
+// repoPath returns the git repository a repository's worktrees belong to: the
+// clone under repos/, or the directory a solo repository was started in
+func (d *Daemon) repoPath(repoName string, repo *state.Repository) string {
+	if repo != nil && repo.Solo {
+		return repo.Path
+	}
+	return d.paths.RepoDir(repoName)
+}
+
+// removeSoloRepo removes a solo repository and its tmux session once its last agent is gone
+func (d *Daemon) removeSoloRepo(repoName string, repo *state.Repository) {
+	if err := d.tmux.KillSession(d.ctx, repo.TmuxSession); err != nil {
+		d.logger.Debug("Failed to kill tmux session %s: %v", repo.TmuxSession, err)
+	}
+	if err := os.Remove(d.paths.WorktreeDir(repoName)); err != nil && !os.IsNotExist(err) {
+		d.logger.Debug("Failed to remove worktree directory for %s: %v", repoName, err)
+	}
+	if err := d.state.RemoveRepo(repoName); err != nil {
+		d.logger.Error("Failed to remove solo repo %s: %v", repoName, err)
+		return
+	}
+	d.logger.Info("Removed solo repo %s: no agents left", repoName)
+	d.events.Publish(events.EventRepoRemoved, repoName, "", nil)
+}
+
 // recordTaskHistory saves a worker's task to the history before cleanup
 func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent) {
 	// Get the branch name from the worktree if it exists
@@ -1430,6 +6764,9 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 	if agent.WorktreePath != "" {
 		if b, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil {
 			branch = b
+		} else if agent.Branch != "" {
+			// Fallback: the branch recorded when the worker was created
+			branch = agent.Branch
 		} else {
 			// Fallback: construct expected branch name
 			branch = "work/" + agentName
@@ -1451,12 +6788,21 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 		FailureReason: agent.FailureReason,
 		CreatedAt:     agent.CreatedAt,
 		CompletedAt:   time.Now(),
+		RetryOf:       agent.RetryOf,
+		Criteria:      agent.Criteria,
 	}
 
+	d.updateXref(repoName, func(x *xref.Index) bool {
+		return x.LinkHistory(agentName, branch, entry.ID()) != nil
+	})
+
 	if err := d.state.AddTaskHistory(repoName, entry); err != nil {
 		d.logger.Warn("Failed to record task history for %s: %v", agentName, err)
 	} else {
 		d.logger.Info("Recorded task history for %s (branch: %s, summary: %q)", agentName, branch, agent.Summary)
+		if agent.ReadyForCleanup {
+			d.summarizeConversation(repoName, agentName, agent, entry.ID())
+		}
 	}
 }
 
@@ -1482,6 +6828,7 @@ func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
 	result := make([]map[string]interface{}, len(history))
 	for i, entry := range history {
 		result[i] = map[string]interface{}{
+			"id":             entry.ID(),
 			"name":           entry.Name,
 			"task":           entry.Task,
 			"branch":         entry.Branch,
@@ -1489,9 +6836,13 @@ func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
 			"pr_number":      entry.PRNumber,
 			"status":         string(entry.Status),
 			"summary":        entry.Summary,
+			"conversation":   entry.Conversation,
 			"failure_reason": entry.FailureReason,
 			"created_at":     entry.CreatedAt,
 			"completed_at":   entry.CompletedAt,
+			"retry_of":       entry.RetryOf,
+			"retried_by":     entry.RetriedBy,
+			"criteria":       entry.Criteria,
 		}
 	}
 
@@ -1517,7 +6868,7 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 		return errResp
 	}
 
-	agentClass, errResp, ok := getRequiredStringArg(req.Args, "class", "agent class is required (persistent or ephemeral)")
+	agentClass, errResp, ok := getRequiredStringArg(req.Args, "class", "agent class is required (persistent, ephemeral or observer)")
 	if !ok {
 		return errResp
 	}
@@ -1528,20 +6879,24 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 	}
 
 	// Validate class
-	if agentClass != "persistent" && agentClass != "ephemeral" {
+	if agentClass != "persistent" && agentClass != "ephemeral" && agentClass != "observer" {
 		return socket.Response{
 			Success: false,
-			Error:   fmt.Sprintf("invalid agent class %q: must be 'persistent' or 'ephemeral'", agentClass),
+			Error:   fmt.Sprintf("invalid agent class %q: must be 'persistent', 'ephemeral' or 'observer'", agentClass),
 		}
 	}
 
-	// Get optional task
+	// Get optional task and the definition the prompt came from
 	task, _ := req.Args["task"].(string)
+	definition, _ := req.Args["definition"].(string)
+
+	// Prompt files can be definitions; their front matter is for multiclaude
+	_, promptText = agents.ParseFrontMatter(promptText)
 
 	// Get repository
 	repo, exists := d.state.GetRepo(repoName)
 	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+		return errorResponse(errors.RepoNotFound(repoName))
 	}
 
 	// Check if agent already exists
@@ -1551,7 +6906,10 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 
 	// Determine agent type based on class
 	var agentType state.AgentType
-	if agentClass == "persistent" {
+	if agentClass == "observer" {
+		// Observers read and summarize; they run without file-editing tools
+		agentType = state.AgentTypeObserver
+	} else if agentClass == "persistent" {
 		// For persistent agents, use specific type if known or generic persistent
 		switch agentName {
 		case "merge-queue":
@@ -1568,31 +6926,73 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 		} else {
 			agentType = state.AgentTypeWorker
 		}
+		if d.offHours(repoName) {
+			return socket.Response{Success: false, Error: fmt.Sprintf("repository %q is outside its work hours (%s); start working now with: multiclaude hours on --repo %s", repoName, workhours.Describe(repo.WorkHours), repoName)}
+		}
+	}
+
+	// Hold a place under the definition's max-instances limit while starting
+	if definition == "" {
+		definition = coreDefinition(agentType)
 	}
+	release, err := d.reserveInstance(repoName, agentName, agentType, definition)
+	if err != nil {
+		return errorResponse(err)
+	}
+	defer release()
 
 	// Create worktree for the agent
 	repoPath := d.paths.RepoDir(repoName)
 	worktreePath := d.paths.AgentWorktree(repoName, agentName)
 
-	wt := worktree.NewManager(repoPath)
+	wt := d.worktreeManager(repoName)
 
-	// Create worktree - persistent agents use repo dir, ephemeral get their own branch
-	if agentClass == "persistent" {
+	// Create worktree - persistent agents use repo dir, observers a detached worktree, ephemeral get their own branch
+	var branchName string
+	if agentClass == "observer" && repo.WorktreeConfig.Mirror && mirror.Exists(d.paths.MirrorDir(repoName)) {
+		// Observers of a mirrored repository read a worktree of the mirror,
+		// kept on the default branch by the worktree refresh
+		worktreePath = d.paths.MirrorWorktree(repoName, agentName)
+		if err := mirror.AddWorktree(d.paths.MirrorDir(repoName), worktreePath, d.repoDefaultBranch(repoName)); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+	} else if agentClass == "observer" {
+		// Other observers read a detached worktree of the clone, kept on the
+		// default branch by the worktree refresh, so they can't commit to a
+		// branch or disturb the persistent agents' checkout
+		if err := wt.CreateDetached(worktreePath, "HEAD"); worktree.IsSetupError(err) {
+			d.logger.Warn("Agent %s/%s starts with an incomplete worktree: %v", repoName, agentName, err)
+		} else if err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to create worktree: %v", err)}
+		}
+	} else if agentClass == "persistent" {
 		// Persistent agents work directly in the repo directory
 		worktreePath = repoPath
 	} else {
-		// Ephemeral agents get their own worktree with a new branch
-		branchName := fmt.Sprintf("work/%s", agentName)
-		if err := wt.CreateNewBranch(worktreePath, branchName, "HEAD"); err != nil {
+		// Ephemeral agents get their own worktree with a new branch named by the repo's template
+		tmpl, _ := d.state.GetBranchTemplate(repoName)
+		var err error
+		branchName, err = branchname.Render(tmpl, branchname.Vars{Agent: agentName, Task: task})
+		if err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to name branch: %v", err)}
+		}
+		if err := wt.CreateNewBranch(worktreePath, branchName, "HEAD"); worktree.IsSetupError(err) {
+			d.logger.Warn("Agent %s/%s starts with an incomplete worktree: %v", repoName, agentName, err)
+		} else if err != nil {
 			return socket.Response{Success: false, Error: fmt.Sprintf("failed to create worktree: %v", err)}
 		}
+		if err := d.runSpawnHook(preSpawnHook, spawnTarget{Repo: repoName, Agent: agentName, Worktree: worktreePath, Branch: branchName, Task: task}); err != nil {
+			wt.Remove(worktreePath, true)
+			return socket.Response{Success: false, Error: err.Error()}
+		}
 	}
 
 	// Create tmux window with working directory
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", agentName, "-c", worktreePath)
-	if err := cmd.Run(); err != nil {
-		// Clean up worktree on failure (only for ephemeral agents that have their own worktree)
-		if agentClass != "persistent" {
+	if err := d.tmuxCommand("new-window", "-d", "-t", repo.TmuxSession, "-n", agentName, "-c", worktreePath); err != nil {
+		// Clean up worktree on failure (only for agents that have their own worktree)
+		if d.inMirror(repoName, worktreePath) {
+			mirror.RemoveWorktree(d.paths.MirrorDir(repoName), worktreePath)
+		} else if worktreePath != repoPath {
 			wt.Remove(worktreePath, true)
 		}
 		return socket.Response{Success: false, Error: fmt.Sprintf("failed to create tmux window: %v", err)}
@@ -1625,20 +7025,31 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 	if err := d.startAgentWithConfig(repoName, repo, cfg); err != nil {
 		// Clean up on failure
 		d.tmux.KillWindow(d.ctx, repo.TmuxSession, agentName)
-		if agentClass != "persistent" {
+		if d.inMirror(repoName, worktreePath) {
+			mirror.RemoveWorktree(d.paths.MirrorDir(repoName), worktreePath)
+		} else if worktreePath != repoPath {
 			wt.Remove(worktreePath, true)
 		}
 		return socket.Response{Success: false, Error: fmt.Sprintf("failed to start agent: %v", err)}
 	}
 
-	// Update task if provided
-	if task != "" {
+	// Update task, branch, capabilities and definition if provided
+	capabilities := agents.ParseCapabilities(promptText)
+	if task != "" || branchName != "" || len(capabilities) > 0 || definition != "" {
 		agent, _ := d.state.GetAgent(repoName, agentName)
-		agent.Task = task
+		if task != "" {
+			agent.Task = task
+		}
+		agent.Branch = branchName
+		agent.Capabilities = capabilities
+		agent.Definition = definition
 		d.state.UpdateAgent(repoName, agentName, agent)
 	}
 
 	d.logger.Info("Spawned agent %s/%s (class=%s, type=%s)", repoName, agentName, agentClass, agentType)
+	if agentClass == "ephemeral" {
+		d.runPostSpawnHook(spawnTarget{Repo: repoName, Agent: agentName, Worktree: worktreePath, Branch: branchName, Task: task})
+	}
 
 	return socket.Response{
 		Success: true,
@@ -1647,15 +7058,137 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 			"class":         agentClass,
 			"type":          string(agentType),
 			"worktree_path": worktreePath,
+			"capabilities":  capabilities,
 		},
 	}
 }
 
+// worktreeManager returns a worktree manager for a repository that runs the
+// repository's configured setup steps (LFS, submodules) in new worktrees
+func (d *Daemon) worktreeManager(repoName string) *worktree.Manager {
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	if cfg, err := d.state.GetWorktreeConfig(repoName); err == nil {
+		wt.SetSetup(worktree.Setup{
+			LFS:        cfg.LFS,
+			Submodules: cfg.Submodules,
+			Progress:   func(msg string) { d.logger.Info("%s: %s", repoName, msg) },
+		})
+	}
+	return wt
+}
+
+// Worktree migration outcomes
+const (
+	migrationPlanned = "planned"
+	migrationMoved   = "moved"
+	migrationSkipped = "skipped"
+	migrationFailed  = "failed"
+)
+
+// handleMigrateWorktrees moves a repository's agent worktrees into its
+// current worktree directory, after its worktree base has changed. Agents
+// that are running are skipped, since their shell and Claude session live in
+// the old directory. With dry_run it only reports what would move.
+func (d *Daemon) handleMigrateWorktrees(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	dryRun, _ := req.Args["dry_run"].(bool)
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+	repoPath := d.repoPath(repoName, repo)
+	wtDir := d.paths.WorktreeDir(repoName)
+
+	agentNames := make([]string, 0, len(repo.Agents))
+	for name := range repo.Agents {
+		agentNames = append(agentNames, name)
+	}
+	sort.Strings(agentNames)
+
+	if !dryRun {
+		d.snapshotState("before worktree migration")
+	}
+	wt := worktree.NewManager(repoPath)
+	oldDirs := make(map[string]bool)
+	results := make([]map[string]interface{}, 0)
+	for _, agentName := range agentNames {
+		agent := repo.Agents[agentName]
+		from := agent.WorktreePath
+		if !d.isMigratableWorktree(repoName, repoPath, from) {
+			continue
+		}
+		to := filepath.Join(wtDir, filepath.Base(from))
+		result := map[string]interface{}{"agent": agentName, "from": from, "to": to}
+		results = append(results, result)
+
+		if hasWindow, _ := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); hasWindow {
+			result["status"] = migrationSkipped
+			result["reason"] = "agent is running"
+			continue
+		}
+		if dryRun {
+			result["status"] = migrationPlanned
+			continue
+		}
+
+		if err := wt.Move(from, to); err != nil {
+			result["status"] = migrationFailed
+			result["reason"] = err.Error()
+			d.logger.Error("Failed to move worktree of %s/%s to %s: %v", repoName, agentName, to, err)
+			continue
+		}
+		agent.WorktreePath = to
+		if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+			result["status"] = migrationFailed
+			result["reason"] = fmt.Sprintf("moved, but state not updated: %v", err)
+			continue
+		}
+		d.statusCache.Invalidate(from)
+		oldDirs[filepath.Dir(from)] = true
+		result["status"] = migrationMoved
+		d.logger.WithTrace(req.TraceID).Info("Moved worktree of %s/%s from %s to %s", repoName, agentName, from, to)
+	}
+
+	// Leave no empty directories behind in the old base
+	for dir := range oldDirs {
+		os.Remove(dir)
+	}
+
+	return socket.Response{Success: true, Data: results}
+}
+
+// isMigratableWorktree reports whether path is an agent worktree multiclaude
+// created under a worktree base other than the repository's current one
+func (d *Daemon) isMigratableWorktree(repoName, repoPath, path string) bool {
+	if path == "" || path == repoPath || strings.HasPrefix(path, d.paths.MirrorWorktreeDir(repoName)+string(filepath.Separator)) {
+		return false
+	}
+	parent := filepath.Dir(path)
+	return filepath.Base(parent) == repoName && parent != d.paths.WorktreeDir(repoName)
+}
+
+// validateWorktreeBase checks a worktree base directory, creating it if needed
+func validateWorktreeBase(base string) error {
+	if base == "" {
+		return nil
+	}
+	if !filepath.IsAbs(base) {
+		return fmt.Errorf("worktree directory must be an absolute path, got %q", base)
+	}
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return fmt.Errorf("worktree directory %s is not usable: %w", base, err)
+	}
+	return nil
+}
+
 // cleanupOrphanedWorktrees removes worktree directories without git tracking
 func (d *Daemon) cleanupOrphanedWorktrees() {
-	repoNames := d.state.ListRepos()
-	for _, repoName := range repoNames {
-		repoPath := d.paths.RepoDir(repoName)
+	for repoName, repo := range d.state.GetAllRepos() {
+		repoPath := d.repoPath(repoName, repo)
 		wtRootDir := d.paths.WorktreeDir(repoName)
 
 		// Check if worktree directory exists
@@ -1697,23 +7230,91 @@ func (d *Daemon) cleanupMergedBranches() {
 			continue
 		}
 
-		wt := worktree.NewManager(repoPath)
+		wt := d.repoWorktreeManager(repoName)
 
-		// Clean up merged branches with common multiclaude prefixes
-		for _, prefix := range []string{"multiclaude/", "work/"} {
-			deleted, err := wt.CleanupMergedBranches(prefix, true)
-			if err != nil {
-				d.logger.Debug("Failed to cleanup merged branches with prefix %s for %s: %v", prefix, repoName, err)
+		// Clean up merged branches with common multiclaude prefixes, plus
+		// branches named by the repo's own template, in one batched pass
+		tmpl, _ := d.state.GetBranchTemplate(repoName)
+		result, err := wt.CleanupMergedBranchesFiltered(cleanupBranchFilter(tmpl), true)
+		if err != nil {
+			d.logger.Debug("Failed to cleanup merged branches for %s: %v", repoName, err)
+			continue
+		}
+
+		if len(result.Deleted) > 0 {
+			d.logger.Info("Cleaned up %d merged branch(es) for %s (%d also deleted from origin, %d still checked out)",
+				len(result.Deleted), repoName, len(result.RemoteDeleted), len(result.CheckedOut))
+			d.logger.Debug("Deleted merged branches for %s: %s", repoName, strings.Join(result.Deleted, ", "))
+		}
+	}
+}
+
+// cleanupBranchFilter selects the branches merged-branch cleanup may delete:
+// those under a cleanup prefix that pass the prefix's template filter
+func cleanupBranchFilter(tmpl string) func(string) bool {
+	prefixes := branchname.CleanupPrefixes(tmpl)
+	return func(branch string) bool {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(branch, prefix) {
 				continue
 			}
-
-			if len(deleted) > 0 {
-				d.logger.Info("Cleaned up %d merged branch(es) for %s", len(deleted), repoName)
-				for _, branch := range deleted {
-					d.logger.Info("Deleted merged branch: %s", branch)
-				}
+			if filter := branchname.CleanupFilter(prefix, tmpl); filter == nil || filter(branch) {
+				return true
 			}
 		}
+		return false
+	}
+}
+
+// repoWorktreeManager returns a worktree manager that uses the repository's recorded
+// default branch. Repositories added before the branch was recorded have it
+// detected from the remote and saved.
+func (d *Daemon) repoWorktreeManager(repoName string) *worktree.Manager {
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	if branch, err := d.state.GetTargetBranch(repoName); err != nil || branch != "" {
+		wt.SetDefaultBranch(branch)
+		return wt
+	}
+
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		return wt
+	}
+	branch, err := wt.DetectRemoteDefaultBranch(remote)
+	if err != nil {
+		d.logger.Debug("Could not detect default branch for %s: %v", repoName, err)
+		return wt
+	}
+	if err := d.state.UpdateTargetBranch(repoName, branch); err != nil {
+		d.logger.Warn("Failed to record default branch for %s: %v", repoName, err)
+	} else {
+		d.logger.Info("Detected default branch for %s: %s", repoName, branch)
+	}
+	wt.SetDefaultBranch(branch)
+	return wt
+}
+
+// repoDefaultBranch returns the repository's default branch, or "main" if it
+// can't be determined
+func (d *Daemon) repoDefaultBranch(repoName string) string {
+	wt := d.repoWorktreeManager(repoName)
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		remote = "origin"
+	}
+	branch, err := wt.GetDefaultBranch(remote)
+	if err != nil {
+		return "main"
+	}
+	return branch
+}
+
+// repoTemplateVars returns the prompt template variables for a repository
+func (d *Daemon) repoTemplateVars(repoName string) prompts.TemplateVars {
+	tmpl, _ := d.state.GetBranchTemplate(repoName)
+	return prompts.TemplateVars{
+		DefaultBranch: d.repoDefaultBranch(repoName),
+		BranchPattern: branchname.Glob(tmpl),
 	}
 }
 
@@ -1724,6 +7325,12 @@ func (d *Daemon) restoreTrackedRepos() {
 
 	repos := d.state.GetAllRepos()
 	for repoName, repo := range repos {
+		// Pick up config file changes made while the daemon was down
+		d.applyRepoConfigFile(repoName)
+		if updated, exists := d.state.GetRepo(repoName); exists {
+			repo = updated
+		}
+
 		// Check if tmux session exists
 		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
 		if err != nil {
@@ -1738,12 +7345,19 @@ func (d *Daemon) restoreTrackedRepos() {
 			continue
 		}
 
+		// Solo sessions aren't restored; the health check cleans them up
+		if repo.Solo {
+			continue
+		}
+
 		// Session doesn't exist - restore it
 		d.logger.Info("Restoring agents for repo %s (tmux session %s was missing)", repoName, repo.TmuxSession)
 		if err := d.restoreRepoAgents(repoName, repo); err != nil {
 			d.logger.Error("Failed to restore agents for repo %s: %v", repoName, err)
 		}
 	}
+
+	d.checkProjectSupervisors()
 }
 
 // restoreDeadAgents restarts agents that have dead Claude processes but existing tmux windows.
@@ -1782,8 +7396,8 @@ func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
 
 		// For persistent agents, auto-restart. For transient agents, they will be cleaned up by health check
 		if agent.Type.IsPersistent() {
-			if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-				d.logger.Error("Failed to restart agent %s: %v", agentName, err)
+			if err := d.restartAgent("", repoName, agentName, agent, repo); err != nil {
+				d.subsystemError("health check", "Failed to restart agent %s: %v", agentName, err)
 			} else {
 				d.logger.Info("Successfully restarted agent %s with --resume", agentName)
 			}
@@ -1812,8 +7426,7 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 
 	// Create tmux session with supervisor window
 	d.logger.Info("Creating tmux session %s for repo %s", repo.TmuxSession, repoName)
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", repo.TmuxSession, "-n", "supervisor", "-c", repoPath)
-	if err := cmd.Run(); err != nil {
+	if err := d.tmuxCommand("new-session", "-d", "-s", repo.TmuxSession, "-n", "supervisor", "-c", repoPath); err != nil {
 		return fmt.Errorf("failed to create tmux session: %w", err)
 	}
 
@@ -1838,7 +7451,7 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 	if _, err := os.Stat(workspacePath); os.IsNotExist(err) {
 		// Workspace worktree doesn't exist, create it
 		d.logger.Info("Creating workspace worktree for %s", repoName)
-		wt := worktree.NewManager(repoPath)
+		wt := d.worktreeManager(repoName)
 
 		// Prune stale worktree references first - this handles the case where
 		// worktree directories were deleted but git still has references to them
@@ -1875,8 +7488,7 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 
 	// Now start the workspace agent if worktree exists
 	if _, err := os.Stat(workspacePath); err == nil {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", "workspace", "-c", workspacePath)
-		if err := cmd.Run(); err != nil {
+		if err := d.tmuxCommand("new-window", "-d", "-t", repo.TmuxSession, "-n", "workspace", "-c", workspacePath); err != nil {
 			d.logger.Error("Failed to create workspace window: %v", err)
 		} else {
 			if err := d.startAgent(repoName, repo, "workspace", state.AgentTypeWorkspace, workspacePath); err != nil {
@@ -1958,6 +7570,12 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 		}
 
 		sb.WriteString(fmt.Sprintf("--- Agent Definition %d: %s (source: %s) ---\n", i+1, def.Name, def.Source))
+		if caps := def.ParseCapabilities(); len(caps) > 0 {
+			sb.WriteString(fmt.Sprintf("Capabilities: %s\n\n", strings.Join(caps, ", ")))
+		}
+		if limit := def.MaxInstances(); limit > 0 {
+			sb.WriteString(fmt.Sprintf("Max instances: %d (further spawns from this definition are refused)\n\n", limit))
+		}
 
 		// For merge-queue, prepend the tracking mode configuration if enabled
 		if def.Name == "merge-queue" && mqConfig.Enabled {
@@ -1983,14 +7601,17 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 
 	sb.WriteString("Review these definitions and determine which agents to spawn.\n")
 	sb.WriteString("For each agent, decide:\n")
-	sb.WriteString("- Class: Is it persistent (long-running, auto-restarts) or ephemeral (task-based, cleans up)?\n")
+	sb.WriteString("- Class: Is it persistent (long-running, auto-restarts), ephemeral (task-based, cleans up) or observer (read-only, summarizes activity)?\n")
 	sb.WriteString("- Spawn now: Should this agent start immediately on repository init?\n\n")
-	sb.WriteString("To spawn an agent, save the prompt to a file and use:\n")
-	sb.WriteString(fmt.Sprintf("  multiclaude agents spawn --repo %s --name <agent-name> --class <persistent|ephemeral> --prompt-file <file>\n", repoName))
+	sb.WriteString("To spawn an agent from a definition, use (add --prompt-file <file> to give it a prompt you adapted):\n")
+	sb.WriteString(fmt.Sprintf("  multiclaude agents spawn --repo %s --name <agent-name> --class <persistent|ephemeral|observer> --definition <definition>\n", repoName))
+	sb.WriteString("\nTo route a task that needs a capability, find who has it and create a worker from the matching definition:\n")
+	sb.WriteString("  multiclaude agents find --capability <capability>\n")
+	sb.WriteString("  multiclaude worker create \"<task>\" --capability <capability>\n")
 
 	// Send message to supervisor
 	msgMgr := d.getMessageManager()
-	if _, err := msgMgr.Send(repoName, "daemon", "supervisor", sb.String()); err != nil {
+	if _, err := msgMgr.Send(repoName, "daemon", "supervisor", prompts.ExpandTemplateVars(sb.String(), d.repoTemplateVars(repoName))); err != nil {
 		return fmt.Errorf("failed to send message to supervisor: %w", err)
 	}
 
@@ -2000,6 +7621,10 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 
 // getClaudeBinaryPath resolves the claude CLI binary path
 func (d *Daemon) getClaudeBinaryPath() (string, error) {
+	// The simulator stands in for Claude, and it's multiclaude itself
+	if simagent.Enabled() {
+		return os.Executable()
+	}
 	binaryPath, err := exec.LookPath("claude")
 	if err != nil {
 		return "", fmt.Errorf("claude binary not found in PATH: %w", err)
@@ -2029,35 +7654,9 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 		d.logger.Warn("Failed to copy hooks config: %v", err)
 	}
 
-	var pid int
-
-	// Skip actual Claude startup in test mode
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary path
-		binaryPath, err := d.getClaudeBinaryPath()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		// Build CLI command
-		claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
-			binaryPath, sessionID, cfg.promptFile)
-
-		// Send command to tmux window
-		target := fmt.Sprintf("%s:%s", repo.TmuxSession, cfg.agentName)
-		cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to start Claude in tmux: %w", err)
-		}
-
-		// Wait a moment for Claude to start
-		time.Sleep(500 * time.Millisecond)
-
-		// Get PID
-		pid, err = d.tmux.GetPanePID(d.ctx, repo.TmuxSession, cfg.agentName)
-		if err != nil {
-			return fmt.Errorf("failed to get Claude PID: %w", err)
-		}
+	pid, err := d.launchClaude(repoName, repo.TmuxSession, cfg.agentName, sessionID, cfg.promptFile, cfg.agentType.IsReadOnly())
+	if err != nil {
+		return err
 	}
 
 	// Register agent with state
@@ -2078,6 +7677,76 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 	return nil
 }
 
+// launchClaude starts Claude in an existing tmux window and returns its PID.
+// In test mode Claude is not started and the returned PID is 0; with
+// MULTICLAUDE_TEST_MODE=sim a repository's agents run the simulator instead.
+func (d *Daemon) launchClaude(repoName, tmuxSession, tmuxWindow, sessionID, promptFile string, readOnly bool) (int, error) {
+	// Skip actual Claude startup in test mode. The simulator only plays
+	// agents of a repository, so project supervisors get a bare shell too.
+	if simagent.Skip() || (simagent.Enabled() && repoName == "") {
+		return 0, nil
+	}
+
+	// Resolve claude binary path
+	binaryPath, err := d.getClaudeBinaryPath()
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve claude binary: %w", err)
+	}
+
+	// Build CLI command
+	var claudeCmd string
+	if simagent.Enabled() {
+		claudeCmd = simagent.Command(binaryPath, d.paths, repoName, tmuxWindow)
+	} else {
+		configDir, err := d.claudeConfigDir(repoName)
+		if err != nil {
+			return 0, err
+		}
+		if configDir != "" {
+			claudeCmd = fmt.Sprintf("%s=%q ", claude.ConfigDirEnv, configDir)
+		}
+		claudeCmd += fmt.Sprintf("%s --session-id %s", binaryPath, sessionID)
+		if readOnly {
+			for _, arg := range claude.ReadOnlyArgs() {
+				claudeCmd += fmt.Sprintf(" %q", arg)
+			}
+		} else {
+			claudeCmd += " --dangerously-skip-permissions"
+		}
+		claudeCmd += " --append-system-prompt-file " + promptFile
+	}
+
+	// Send command to tmux window
+	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
+	if err := d.tmuxCommand("send-keys", "-t", target, claudeCmd, "C-m"); err != nil {
+		return 0, fmt.Errorf("failed to start Claude in tmux: %w", err)
+	}
+
+	// Wait a moment for Claude to start
+	time.Sleep(500 * time.Millisecond)
+
+	// Get PID
+	pid, err := d.tmux.GetPanePID(d.ctx, tmuxSession, tmuxWindow)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get Claude PID: %w", err)
+	}
+	return pid, nil
+}
+
+// claudeConfigDir returns the Claude config directory a repository's agents
+// run with, "" for ~/.claude. It's checked at every spawn, since credentials
+// can go away after the directory was configured.
+func (d *Daemon) claudeConfigDir(repoName string) (string, error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists || repo.ClaudeConfigDir == "" {
+		return "", nil
+	}
+	if err := claude.ValidateConfigDir(repo.ClaudeConfigDir); err != nil {
+		return "", fmt.Errorf("cannot start Claude for %s: %w", repoName, err)
+	}
+	return repo.ClaudeConfigDir, nil
+}
+
 // startAgent starts a Claude agent in a tmux window and registers it with state
 func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName string, agentType state.AgentType, workDir string) error {
 	promptFile, err := d.writePromptFile(repoName, agentType, agentName)
@@ -2097,17 +7766,27 @@ func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName s
 func (d *Daemon) writePromptFileWithPrefix(repoName string, agentType state.AgentType, agentName, prefix string) (string, error) {
 	repoPath := d.paths.RepoDir(repoName)
 
-	// Get the base prompt (without CLI docs since we don't have them in daemon context)
-	promptText, err := prompts.GetPrompt(repoPath, agentType, "")
+	// The daemon can't generate the CLI docs, but points to the copy the CLI last wrote
+	budget, _ := d.state.GetPromptBudget(repoName)
+	promptText, report, err := prompts.GetPromptWithBudget(repoPath, agentType, d.cliDocsReference(repoName), budget)
 	if err != nil {
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
+	if report.Trimmed() {
+		d.logger.Warn("Prompt for %s/%s: %s", repoName, agentName, report)
+	}
 
 	// Prepend prefix if provided
 	if prefix != "" {
 		promptText = prefix + "\n\n" + promptText
 	}
 
+	promptText = prompts.ExpandTemplateVars(promptText, d.repoTemplateVars(repoName))
+	if repo, exists := d.state.GetAllRepos()[repoName]; exists {
+		promptText = prompts.SetRosterSection(promptText, prompts.RosterSection(repo.Roster, repo.Agents, agentName, d.paths.RosterFile(repoName)))
+	}
+	promptText = prompts.SetMemorySection(promptText, d.memorySection(repoName, agentName))
+
 	// Create prompt file in prompts directory
 	promptDir := filepath.Join(d.paths.Root, "prompts")
 	if err := os.MkdirAll(promptDir, 0755); err != nil {
@@ -2125,14 +7804,17 @@ func (d *Daemon) writePromptFileWithPrefix(repoName string, agentType state.Agen
 // restartAgent restarts an agent that has exited.
 // It uses --resume to continue the existing session if history exists.
 // This works for all agent types: supervisor, merge-queue, workspace, workers, and review agents.
-func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, repo *state.Repository) error {
-	// Check if the session has history
-	home, err := os.UserHomeDir()
+func (d *Daemon) restartAgent(traceID, repoName, agentName string, agent state.Agent, repo *state.Repository) error {
+	configDir, err := d.claudeConfigDir(repoName)
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
 
-	claudeProjectsDir := filepath.Join(home, ".claude", "projects")
+	// Check if the session has history
+	claudeProjectsDir, err := claude.ProjectsDir(configDir)
+	if err != nil {
+		return err
+	}
 	encodedPath := strings.ReplaceAll(agent.WorktreePath, "/", "-")
 	sessionFile := filepath.Join(claudeProjectsDir, encodedPath, agent.SessionID+".jsonl")
 
@@ -2149,30 +7831,53 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 		if err != nil {
 			return fmt.Errorf("failed to regenerate prompt file: %w", err)
 		}
+	} else {
+		// Bring in what the agent has remembered since its prompt was written,
+		// and config fragments rewritten since
+		d.memoryMu.Lock()
+		d.refreshMemoryPrompt(repoName, agentName)
+		d.memoryMu.Unlock()
+		d.refreshPromptIncludes(repoName, agentName)
 	}
 
-	// Restart Claude using the runner
-	// Note: Slash commands are embedded in prompts, not via CLAUDE_CONFIG_DIR
-	result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, claude.Config{
-		SessionID:        agent.SessionID,
-		Resume:           hasHistory,
-		SystemPromptFile: promptFile,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to restart Claude: %w", err)
+	// Restart Claude using the runner, or the simulator in its place
+	var pid int
+	if simagent.Enabled() {
+		if pid, err = d.launchClaude(repoName, repo.TmuxSession, agentName, agent.SessionID, promptFile, agent.Type.IsReadOnly()); err != nil {
+			return fmt.Errorf("failed to restart simulated agent: %w", err)
+		}
+	} else {
+		result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, claude.Config{
+			SessionID:        agent.SessionID,
+			Resume:           hasHistory,
+			SystemPromptFile: promptFile,
+			ReadOnly:         agent.Type.IsReadOnly(),
+			ConfigDir:        configDir,
+			MOTD: d.text("motd", map[string]string{
+				"Agent": agentName,
+				"Type":  string(agent.Type),
+				"Repo":  repoName,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restart Claude: %w", err)
+		}
+		pid = result.PID
 	}
 
 	// Update the agent's PID in state
-	if err := d.state.UpdateAgentPID(repoName, agentName, result.PID); err != nil {
+	if err := d.state.UpdateAgentPID(repoName, agentName, pid); err != nil {
 		d.logger.Warn("Failed to update agent PID: %v", err)
 	}
 
-	d.logger.Info("Restarted agent %s with PID %d (resumed=%v)", agentName, result.PID, hasHistory)
+	d.logger.WithTrace(traceID).Info("Restarted agent %s with PID %d (resumed=%v)", agentName, pid, hasHistory)
+	d.events.PublishTraced(traceID, events.EventAgentRestarted, repoName, agentName, map[string]string{"pid": strconv.Itoa(pid)})
 
 	// For workers without history, send the task as the initial message
 	// This handles cases where workers are restarted or spawned via mechanisms
-	// that don't use the CLI's startClaudeInTmux which normally sends the task
-	if agent.Type == state.AgentTypeWorker && agent.Task != "" && !hasHistory {
+	// that don't use the CLI's startClaudeInTmux which normally sends the task.
+	// The simulator reads its task from state.
+	if agent.Type == state.AgentTypeWorker && agent.Task != "" && !hasHistory && !simagent.Enabled() {
 		// Wait a moment for Claude to fully initialize
 		time.Sleep(1500 * time.Millisecond)
 
@@ -2188,6 +7893,16 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 	return nil
 }
 
+// cliDocsReference returns the prompt section pointing to a repository's CLI
+// reference, or "" if the CLI hasn't written one yet
+func (d *Daemon) cliDocsReference(repoName string) string {
+	path := d.paths.CLIDocsFile(repoName)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return prompts.CLIDocsReference(path)
+}
+
 // writePromptFile writes the agent prompt to a file and returns the path
 func (d *Daemon) writePromptFile(repoName string, agentType state.AgentType, agentName string) (string, error) {
 	return d.writePromptFileWithPrefix(repoName, agentType, agentName, "")
@@ -2229,6 +7944,21 @@ func Run() error {
 		return fmt.Errorf("failed to start daemon: %w", err)
 	}
 
+	// SIGHUP rereads daemon.yaml
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				d.reloadConfig("SIGHUP")
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+
 	// Wait for shutdown
 	d.Wait()
 
@@ -2292,12 +8022,16 @@ func RunDetached() error {
 	return nil
 }
 
-// MaxLogFileSize is the threshold for log rotation (10MB)
+// MaxLogFileSize is the default threshold for log rotation (10MB); daemon.yaml's
+// limits.max_log_size_mb overrides it
 const MaxLogFileSize = 10 * 1024 * 1024
 
-// rotateLogsIfNeeded checks log files and rotates any that exceed MaxLogFileSize
+// rotateLogsIfNeeded checks log files and rotates any that exceed the
+// configured maximum size
 func (d *Daemon) rotateLogsIfNeeded() {
 	d.logger.Debug("Checking for log rotation")
+	settings, _ := d.currentSettings()
+	maxSize := int64(settings.Limits.MaxLogSizeMB) * 1024 * 1024
 
 	err := filepath.Walk(d.paths.OutputDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -2310,7 +8044,7 @@ func (d *Daemon) rotateLogsIfNeeded() {
 			return nil
 		}
 
-		if info.Size() > MaxLogFileSize {
+		if info.Size() > maxSize {
 			if err := d.rotateLog(path); err != nil {
 				d.logger.Error("Failed to rotate log %s: %v", path, err)
 			} else {
@@ -2342,6 +8076,49 @@ func (d *Daemon) rotateLog(logPath string) error {
 	return nil
 }
 
+// archiveAgentOutput moves a removed agent's logs to the repository's archive,
+// where cleanOutputs deletes them once the retention period has passed
+func (d *Daemon) archiveAgentOutput(repoName, agentName string) {
+	archived, err := outputs.Archive(d.paths, repoName, agentName, time.Now())
+	if err != nil {
+		d.logger.Warn("Failed to archive output of %s/%s: %v", repoName, agentName, err)
+		return
+	}
+	if len(archived) > 0 {
+		d.logger.Debug("Archived %d log(s) of %s/%s", len(archived), repoName, agentName)
+	}
+}
+
+// cleanOutputs archives the logs of agents no longer in state (removed
+// without going through the daemon) and deletes archived and rotated logs
+// older than the retention period
+func (d *Daemon) cleanOutputs() {
+	result, err := outputs.Clean(d.paths, outputs.Options{
+		Exists: func(repoName, agentName string) bool {
+			_, exists := d.state.GetAgent(repoName, agentName)
+			return exists
+		},
+	})
+	if err != nil {
+		d.logger.Error("Failed to clean output directory: %v", err)
+		return
+	}
+	if len(result.Archived) > 0 || len(result.Deleted) > 0 {
+		d.logger.Info("Cleaned output directory: archived %d log(s), deleted %d (%s)", len(result.Archived), len(result.Deleted), format.Size(result.Freed()))
+	}
+}
+
+// expireTrash deletes the tombstones of removed workers once they expire
+func (d *Daemon) expireTrash() {
+	expired, err := trash.Expire(d.paths, time.Now())
+	if err != nil {
+		d.logger.Error("Failed to expire trash: %v", err)
+	}
+	for _, t := range expired {
+		d.logger.Info("Deleted %s/%s from the trash (removed %s)", t.Repo, t.Name, t.DeletedAt.Format(time.RFC3339))
+	}
+}
+
 // isLogFile checks if a file is a log file
 func isLogFile(path string) bool {
 	base := filepath.Base(path)
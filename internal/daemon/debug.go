@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/tasks"
+)
+
+// loopStats is how one loop's ticks have gone since the daemon started
+type loopStats struct {
+	ticks        int
+	lastStart    time.Time
+	lastDuration time.Duration
+	maxDuration  time.Duration
+	running      bool
+	// interval is how often the loop should tick, 0 if it hasn't said
+	interval time.Duration
+	// since is when the loop said so, for a loop that hasn't ticked yet
+	since time.Time
+}
+
+// subsystemError is the last error a subsystem logged, and how many it has
+type subsystemError struct {
+	message string
+	at      time.Time
+	count   int
+}
+
+// overdueFactor is how many intervals a loop may go without a tick before
+// it counts as stalled
+const overdueFactor = 3
+
+// debugStats collects the loop timings and errors `multiclaude daemon debug`
+// reports, so a sluggish daemon can be looked into without restarting it
+type debugStats struct {
+	mu     sync.Mutex
+	loops  map[string]*loopStats
+	errors map[string]subsystemError
+}
+
+func newDebugStats() *debugStats {
+	return &debugStats{loops: make(map[string]*loopStats), errors: make(map[string]subsystemError)}
+}
+
+// time runs one tick of a loop and records how long it took. While the tick
+// runs the loop is reported as running, so a stuck tick shows up.
+func (s *debugStats) time(loop string, tick func()) {
+	start := time.Now()
+	s.mu.Lock()
+	stats := s.loop(loop)
+	stats.lastStart, stats.running = start, true
+	s.mu.Unlock()
+
+	defer func() {
+		elapsed := time.Since(start)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		stats.ticks++
+		stats.running = false
+		stats.lastDuration = elapsed
+		if elapsed > stats.maxDuration {
+			stats.maxDuration = elapsed
+		}
+	}()
+	tick()
+}
+
+// loop returns a loop's stats, adding them the first time. s.mu must be held.
+func (s *debugStats) loop(name string) *loopStats {
+	stats, ok := s.loops[name]
+	if !ok {
+		stats = &loopStats{}
+		s.loops[name] = stats
+	}
+	return stats
+}
+
+// expect records how often a loop should tick, so one that stops ticking
+// shows up as stalled, even if it never ticked at all
+func (s *debugStats) expect(loop string, interval time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.loop(loop)
+	if stats.since.IsZero() || stats.interval != interval {
+		stats.interval, stats.since = interval, now
+	}
+}
+
+// stalled returns why each stalled loop is: a tick that has been running
+// for longer than stuckAfter, or no tick for overdueFactor intervals, times
+// slack. Loops that are fine aren't in the map.
+func (s *debugStats) stalled(now time.Time, stuckAfter time.Duration, slack int) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reasons := make(map[string]string)
+	for name, stats := range s.loops {
+		if stats.running {
+			if running := now.Sub(stats.lastStart); running > stuckAfter {
+				reasons[name] = fmt.Sprintf("tick running for %s", running.Round(time.Second))
+			}
+			continue
+		}
+		if stats.interval == 0 {
+			continue
+		}
+		last := stats.lastStart
+		if last.Before(stats.since) {
+			last = stats.since
+		}
+		if idle := now.Sub(last); idle > stats.interval*time.Duration(overdueFactor*slack) {
+			if stats.ticks == 0 {
+				reasons[name] = fmt.Sprintf("no tick in %s, expected every %s", idle.Round(time.Second), stats.interval)
+			} else {
+				reasons[name] = fmt.Sprintf("no tick for %s, expected every %s", idle.Round(time.Second), stats.interval)
+			}
+		}
+	}
+	return reasons
+}
+
+// recordError keeps msg as the subsystem's last error and counts it
+func (s *debugStats) recordError(subsystem, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[subsystem] = subsystemError{message: msg, at: time.Now(), count: s.errors[subsystem].count + 1}
+}
+
+// report returns the loop timings and last errors, sorted by name
+func (s *debugStats) report(now time.Time) (loops, errs []map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, stats := range s.loops {
+		loop := map[string]interface{}{
+			"name":          name,
+			"ticks":         stats.ticks,
+			"last_start":    stats.lastStart.Format(time.RFC3339),
+			"last_duration": stats.lastDuration.Round(time.Millisecond).String(),
+			"max_duration":  stats.maxDuration.Round(time.Millisecond).String(),
+			"running":       stats.running,
+		}
+		if stats.ticks == 0 && !stats.running {
+			loop["last_start"] = ""
+		}
+		if stats.interval > 0 {
+			loop["interval"] = stats.interval.String()
+		}
+		if stats.running {
+			loop["running_for"] = now.Sub(stats.lastStart).Round(time.Millisecond).String()
+		}
+		loops = append(loops, loop)
+	}
+	for subsystem, e := range s.errors {
+		errs = append(errs, map[string]interface{}{
+			"subsystem": subsystem,
+			"error":     e.message,
+			"at":        e.at.Format(time.RFC3339),
+			"count":     e.count,
+		})
+	}
+	sort.Slice(loops, func(i, j int) bool { return loops[i]["name"].(string) < loops[j]["name"].(string) })
+	sort.Slice(errs, func(i, j int) bool { return errs[i]["subsystem"].(string) < errs[j]["subsystem"].(string) })
+	return loops, errs
+}
+
+// subsystemError logs an error from one of the daemon's subsystems and keeps
+// it as that subsystem's last error
+func (d *Daemon) subsystemError(subsystem, format string, args ...interface{}) {
+	d.logger.Error(format, args...)
+	d.noteError(subsystem, format, args...)
+}
+
+// subsystemWarn is subsystemError for failures logged as warnings
+func (d *Daemon) subsystemWarn(subsystem, format string, args ...interface{}) {
+	d.logger.Warn(format, args...)
+	d.noteError(subsystem, format, args...)
+}
+
+// noteError keeps an error as the subsystem's last error without logging it
+func (d *Daemon) noteError(subsystem, format string, args ...interface{}) {
+	d.debug.recordError(subsystem, d.logger.Scrub(fmt.Sprintf(format, args...)))
+}
+
+// handleDebug reports the daemon's goroutine count, loop tick timings, queue
+// depths and each subsystem's last error. With pprof set it also writes
+// goroutine and heap profiles and returns their paths.
+func (d *Daemon) handleDebug(req socket.Request) socket.Response {
+	loops, errs := d.loopReport(time.Now())
+	data := map[string]interface{}{
+		"goroutines": runtime.NumGoroutine(),
+		"loops":      loops,
+		"queues":     d.queueDepths(),
+		"errors":     errs,
+	}
+
+	if dump, _ := req.Args["pprof"].(bool); dump {
+		files, err := d.writeProfiles(time.Now())
+		if err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to write profiles: %v", err)}
+		}
+		data["pprof"] = files
+	}
+
+	return socket.Response{Success: true, Data: data}
+}
+
+// loopReport is debugStats.report with each stalled loop's reason under
+// "stalled". Loops run less often in standby, so they get longer before
+// they count as stalled.
+func (d *Daemon) loopReport(now time.Time) (loops, errs []map[string]interface{}) {
+	loops, errs = d.debug.report(now)
+	stalled := d.stalledLoops(now)
+	for _, loop := range loops {
+		if reason, ok := stalled[loop["name"].(string)]; ok {
+			loop["stalled"] = reason
+		}
+	}
+	return loops, errs
+}
+
+// stalledLoops returns the daemon's stalled loops with the reason for each
+func (d *Daemon) stalledLoops(now time.Time) map[string]string {
+	settings, _ := d.currentSettings()
+	slack := 1
+	if d.inStandby() {
+		slack = standbySlowdown
+	}
+	return d.debug.stalled(now, wedgedFactor*settings.Timeouts.Refresh, slack)
+}
+
+// queueDepths counts the work waiting on the daemon: messages not yet
+// delivered, tasks waiting to start and the events kept for watchers
+func (d *Daemon) queueDepths() map[string]int {
+	depths := map[string]int{}
+
+	if inboxes, err := d.getMessageManager().ListAll(); err == nil {
+		for _, msgs := range inboxes {
+			for _, msg := range msgs {
+				if msg.Status == messages.StatusPending {
+					depths["messages_pending"]++
+				}
+			}
+		}
+	}
+
+	d.tasksMu.Lock()
+	store, err := tasks.Load(d.paths.TasksFile())
+	d.tasksMu.Unlock()
+	if err == nil {
+		for _, t := range store.List("") {
+			if t.Status == tasks.StatusWaiting {
+				depths["tasks_waiting"]++
+			}
+		}
+		depths["tasks_ready"] = len(store.Ready())
+	}
+
+	evs, _ := d.events.Since(0)
+	depths["events"] = len(evs)
+	return depths
+}
+
+// writeProfiles writes the daemon's goroutine stacks and heap profile under
+// the debug directory and returns their paths
+func (d *Daemon) writeProfiles(now time.Time) ([]string, error) {
+	dir := d.paths.DebugDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	stamp := now.Format("20060102-150405")
+	profiles := []struct {
+		name  string
+		file  string
+		debug int
+	}{
+		// Goroutine stacks as text, readable without go tool pprof
+		{"goroutine", "goroutines-" + stamp + ".txt", 2},
+		{"heap", "heap-" + stamp + ".pprof", 0},
+	}
+
+	var files []string
+	for _, p := range profiles {
+		path := filepath.Join(dir, p.file)
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		err = pprof.Lookup(p.name).WriteTo(f, p.debug)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s profile: %w", p.name, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
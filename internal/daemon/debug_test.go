@@ -0,0 +1,165 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+)
+
+func TestDebugStats(t *testing.T) {
+	s := newDebugStats()
+
+	s.time("wake", func() {})
+	s.time("wake", func() { time.Sleep(10 * time.Millisecond) })
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.time("health check", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	s.recordError("wake", "first")
+	s.recordError("wake", "second")
+
+	loops, errs := s.report(time.Now())
+	close(release)
+
+	if len(loops) != 2 || loops[0]["name"] != "health check" || loops[1]["name"] != "wake" {
+		t.Fatalf("report() loops = %v, want health check then wake", loops)
+	}
+	if running, _ := loops[0]["running"].(bool); !running || loops[0]["running_for"] == nil {
+		t.Errorf("health check = %v, want a running tick", loops[0])
+	}
+	wake := loops[1]
+	if wake["ticks"] != 2 || wake["running"] != false {
+		t.Errorf("wake = %v, want 2 finished ticks", wake)
+	}
+	if max, _ := time.ParseDuration(wake["max_duration"].(string)); max < 10*time.Millisecond {
+		t.Errorf("wake max_duration = %v, want at least 10ms", wake["max_duration"])
+	}
+	if len(errs) != 1 || errs[0]["error"] != "second" {
+		t.Errorf("report() errors = %v, want the last wake error", errs)
+	}
+}
+
+func TestHandleDebug(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.logger.SetScrubber(func(msg string) string { return strings.ReplaceAll(msg, "hunter2", "<redacted>") })
+	d.subsystemError("worktree refresh", "fetch failed with token %s", "hunter2")
+	d.debug.time("wake", func() {})
+
+	resp := d.handleDebug(socket.Request{Command: "debug"})
+	if !resp.Success {
+		t.Fatalf("handleDebug() failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if n, _ := data["goroutines"].(int); n < 1 {
+		t.Errorf("goroutines = %v", data["goroutines"])
+	}
+	if loops := data["loops"].([]map[string]interface{}); len(loops) != 1 || loops[0]["name"] != "wake" {
+		t.Errorf("loops = %v", loops)
+	}
+	if _, ok := data["queues"].(map[string]int)["events"]; !ok {
+		t.Errorf("queues = %v, want the event backlog", data["queues"])
+	}
+	errs := data["errors"].([]map[string]interface{})
+	if len(errs) != 1 || errs[0]["error"] != "fetch failed with token <redacted>" {
+		t.Errorf("errors = %v, want the scrubbed refresh error", errs)
+	}
+	if _, ok := data["pprof"]; ok {
+		t.Error("profiles written without pprof")
+	}
+
+	resp = d.handleDebug(socket.Request{Command: "debug", Args: map[string]interface{}{"pprof": true}})
+	if !resp.Success {
+		t.Fatalf("handleDebug(pprof) failed: %s", resp.Error)
+	}
+	files, _ := resp.Data.(map[string]interface{})["pprof"].([]string)
+	if len(files) != 2 {
+		t.Fatalf("pprof = %v, want goroutine and heap profiles", files)
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+			t.Errorf("profile %s: %v", file, err)
+		}
+	}
+	if stacks, _ := os.ReadFile(files[0]); !strings.Contains(string(stacks), "goroutine") {
+		t.Errorf("goroutine dump %s has no stacks", files[0])
+	}
+}
+
+func TestDebugStatsStalled(t *testing.T) {
+	s := newDebugStats()
+	start := time.Now()
+
+	s.expect("message router", time.Minute, start)
+	s.expect("wake", time.Minute, start)
+	s.time("wake", func() {})
+	s.expect("roster", 0, start)
+
+	if got := s.stalled(start.Add(2*time.Minute), time.Hour, 1); len(got) != 0 {
+		t.Errorf("stalled() after 2 intervals = %v, want none", got)
+	}
+	got := s.stalled(start.Add(4*time.Minute), time.Hour, 1)
+	if len(got) != 2 || !strings.HasPrefix(got["message router"], "no tick in 4m") || !strings.HasPrefix(got["wake"], "no tick for 4m") {
+		t.Errorf("stalled() after 4 intervals = %v, want the router and wake", got)
+	}
+	// Standby slows loops down, so they get longer
+	if got := s.stalled(start.Add(4*time.Minute), time.Hour, standbySlowdown); len(got) != 0 {
+		t.Errorf("stalled() in standby = %v, want none", got)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.time("wake", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+	got = s.stalled(time.Now().Add(2*time.Hour), time.Hour, 100)
+	if !strings.HasPrefix(got["wake"], "tick running for 2h") {
+		t.Errorf("stalled() = %v, want the stuck wake tick", got)
+	}
+
+	s.recordError("wake", "first")
+	s.recordError("wake", "second")
+	if _, errs := s.report(time.Now()); len(errs) != 1 || errs[0]["count"] != 2 {
+		t.Errorf("report() errors = %v, want 2 wake errors", errs)
+	}
+}
+
+func TestHandleStatusVerbose(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.debug.expect("message router", time.Millisecond, time.Now().Add(-time.Second))
+	d.debug.time("wake", func() {})
+	d.subsystemWarn("wake", "nudge failed")
+
+	data := d.handleStatus(socket.Request{Command: "status"}).Data.(map[string]interface{})
+	if stalled := data["stalled"].(map[string]string); !strings.HasPrefix(stalled["message router"], "no tick in") {
+		t.Errorf("stalled = %v, want the message router", stalled)
+	}
+	if _, ok := data["loops"]; ok {
+		t.Error("status without verbose has loop timings")
+	}
+
+	data = d.handleStatus(socket.Request{Command: "status", Args: map[string]interface{}{"verbose": true}}).Data.(map[string]interface{})
+	loops := data["loops"].([]map[string]interface{})
+	if len(loops) != 2 || loops[0]["name"] != "message router" || loops[0]["stalled"] == nil || loops[1]["stalled"] != nil {
+		t.Errorf("loops = %v, want a stalled router and wake", loops)
+	}
+	if _, ok := data["queues"].(map[string]int)["events"]; !ok {
+		t.Errorf("queues = %v", data["queues"])
+	}
+	if errs := data["errors"].([]map[string]interface{}); len(errs) != 1 || errs[0]["count"] != 1 {
+		t.Errorf("errors = %v, want one wake error", errs)
+	}
+}
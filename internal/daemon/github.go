@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/daemonconfig"
+	"github.com/micheal-at/multiclaude/internal/ghcheck"
+)
+
+// refreshGitHubStatus checks whether gh can be used, logging when that
+// changes: GitHub features are turned off while it can't, and back on when
+// it can again
+func (d *Daemon) refreshGitHubStatus() ghcheck.Status {
+	ctx, cancel := d.withTimeout(func(t daemonconfig.Timeouts) time.Duration { return t.GitHub })
+	defer cancel()
+	status := d.checkGitHub(ctx)
+
+	d.githubMu.Lock()
+	previous := d.githubStatus
+	d.githubStatus = status
+	d.githubMu.Unlock()
+
+	switch {
+	case !status.Available && (previous.Available || !previous.Checked()):
+		d.subsystemWarn("github", "GitHub features are off (%s): %s; fix: %s. Agents, worktrees and messaging keep working.",
+			strings.Join(ghcheck.Features, ", "), status.Problem, status.Fix)
+	case status.Available && previous.Checked() && !previous.Available:
+		d.logger.Info("gh is usable again; GitHub features are back on")
+	}
+	return status
+}
+
+// recheckGitHub checks gh again while it can't be used, so GitHub features
+// come back on by themselves once it's installed or logged in
+func (d *Daemon) recheckGitHub() {
+	if d.githubProblem() != "" {
+		d.refreshGitHubStatus()
+	}
+}
+
+// githubProblem returns why gh can't be used, or "" when it can or hasn't
+// been checked yet
+func (d *Daemon) githubProblem() string {
+	d.githubMu.Lock()
+	defer d.githubMu.Unlock()
+	if d.githubStatus.Available || !d.githubStatus.Checked() {
+		return ""
+	}
+	return d.githubStatus.Problem
+}
+
+// requireGitHub returns an error saying what's wrong when gh can't be used,
+// in place of the error gh itself would fail with
+func (d *Daemon) requireGitHub() error {
+	if problem := d.githubProblem(); problem != "" {
+		return fmt.Errorf("GitHub features are off: %s (see: multiclaude daemon status)", problem)
+	}
+	return nil
+}
+
+// githubReport describes gh's status for the status command: whether it can
+// be used and, when it can't, why, how to fix it and what's turned off
+func (d *Daemon) githubReport() map[string]interface{} {
+	d.githubMu.Lock()
+	status := d.githubStatus
+	d.githubMu.Unlock()
+
+	report := map[string]interface{}{
+		"available": status.Available || !status.Checked(),
+		"checked":   status.Checked(),
+	}
+	if status.Checked() {
+		report["checked_at"] = status.CheckedAt
+	}
+	if status.Checked() && !status.Available {
+		report["problem"] = status.Problem
+		report["fix"] = status.Fix
+		report["disabled"] = ghcheck.Features
+	}
+	return report
+}
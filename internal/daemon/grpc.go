@@ -0,0 +1,89 @@
+//go:build grpc
+
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/micheal-at/multiclaude/internal/grpcapi"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// startGRPC serves the gRPC API when daemon.yaml enables it: on a Unix socket
+// next to the daemon's, or on a loopback TCP address with a fresh token
+// written to the token file
+func (d *Daemon) startGRPC() error {
+	settings, _ := d.currentSettings()
+	if !settings.GRPC.Enabled {
+		return nil
+	}
+
+	var listener net.Listener
+	var token string
+	if settings.GRPC.Address == "" {
+		sock := d.paths.GRPCSock()
+		os.Remove(sock)
+		l, err := net.Listen("unix", sock)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(sock, 0600); err != nil {
+			l.Close()
+			return err
+		}
+		listener = l
+	} else {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("failed to generate token: %w", err)
+		}
+		token = hex.EncodeToString(b)
+		l, err := net.Listen("tcp", settings.GRPC.Address)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(d.paths.GRPCTokenFile(), []byte(token+"\n"), 0600); err != nil {
+			l.Close()
+			return fmt.Errorf("failed to write token: %w", err)
+		}
+		listener = l
+	}
+
+	server := grpcapi.NewServer(socket.HandlerFunc(d.handleRequest), d.events, d.agentLogPath, token)
+	d.grpcServer = server
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			d.logger.Error("gRPC API stopped: %v", err)
+		}
+	}()
+	d.logger.Info("gRPC API listening at %s:%s", listener.Addr().Network(), listener.Addr().String())
+	return nil
+}
+
+// stopGRPC stops the gRPC API and removes its socket and token
+func (d *Daemon) stopGRPC() {
+	if d.grpcServer == nil {
+		return
+	}
+	d.grpcServer.Stop()
+	os.Remove(d.paths.GRPCSock())
+	os.Remove(d.paths.GRPCTokenFile())
+}
+
+// agentLogPath returns the log the gRPC API tails: the daemon's when repo
+// and agent are empty, otherwise the agent's output log
+func (d *Daemon) agentLogPath(repo, agent string) (string, error) {
+	if repo == "" && agent == "" {
+		return d.paths.DaemonLog, nil
+	}
+	a, ok := d.state.GetAgent(repo, agent)
+	if !ok {
+		return "", fmt.Errorf("agent '%s' not found in repo '%s'", agent, repo)
+	}
+	return d.paths.AgentLogFile(repo, agent, a.Type == state.AgentTypeWorker), nil
+}
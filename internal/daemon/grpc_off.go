@@ -0,0 +1,15 @@
+//go:build !grpc
+
+package daemon
+
+// startGRPC reports that the gRPC API, if daemon.yaml enables it, isn't
+// served: it is only built with -tags grpc
+func (d *Daemon) startGRPC() error {
+	if settings, _ := d.currentSettings(); settings.GRPC.Enabled {
+		d.logger.Warn("daemon.yaml enables the gRPC API, but this multiclaude was built without it; rebuild with -tags grpc to serve it")
+	}
+	return nil
+}
+
+// stopGRPC does nothing without the gRPC API
+func (d *Daemon) stopGRPC() {}
@@ -0,0 +1,23 @@
+//go:build !grpc
+
+package daemon
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGRPCAPIOff(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// Enabled in daemon.yaml, but not built in: the daemon starts without it
+	d.settings.GRPC.Enabled = true
+	if err := d.startGRPC(); err != nil || d.grpcServer != nil {
+		t.Fatalf("startGRPC() = %v, server %v; want neither without -tags grpc", err, d.grpcServer)
+	}
+	if _, err := os.Stat(d.paths.GRPCSock()); !os.IsNotExist(err) {
+		t.Errorf("gRPC socket created without the API: %v", err)
+	}
+	d.stopGRPC()
+}
@@ -0,0 +1,64 @@
+//go:build grpc
+
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestGRPCAPI(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// Off unless daemon.yaml enables it
+	if err := d.startGRPC(); err != nil || d.grpcServer != nil {
+		t.Fatalf("startGRPC() without the setting = %v, server %v", err, d.grpcServer)
+	}
+
+	d.settings.GRPC.Enabled = true
+	if err := d.startGRPC(); err != nil {
+		t.Fatalf("startGRPC() failed: %v", err)
+	}
+	if info, err := os.Stat(d.paths.GRPCSock()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("gRPC socket = %v, %v; want one only the user can use", info, err)
+	}
+	d.stopGRPC()
+	if _, err := os.Stat(d.paths.GRPCSock()); !os.IsNotExist(err) {
+		t.Errorf("gRPC socket left behind after stop: %v", err)
+	}
+
+	// On TCP calls need the token written next to the socket
+	d.settings.GRPC.Address = "127.0.0.1:0"
+	if err := d.startGRPC(); err != nil {
+		t.Fatalf("startGRPC() on TCP failed: %v", err)
+	}
+	token, err := os.ReadFile(d.paths.GRPCTokenFile())
+	if err != nil || len(strings.TrimSpace(string(token))) != 32 {
+		t.Errorf("token file = %q, %v", token, err)
+	}
+	d.stopGRPC()
+	if _, err := os.Stat(d.paths.GRPCTokenFile()); !os.IsNotExist(err) {
+		t.Errorf("token file left behind after stop: %v", err)
+	}
+
+	// Logs are tailed from the daemon log or an agent's output log
+	if err := d.state.AddRepo("test-repo", &state.Repository{TmuxSession: "test-session", Agents: map[string]state.Agent{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.state.AddAgent("test-repo", "calm-owl", state.Agent{Type: state.AgentTypeWorker}); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := d.agentLogPath("", ""); err != nil || path != d.paths.DaemonLog {
+		t.Errorf("agentLogPath() = %q, %v; want the daemon log", path, err)
+	}
+	if path, err := d.agentLogPath("test-repo", "calm-owl"); err != nil || path != d.paths.AgentLogFile("test-repo", "calm-owl", true) {
+		t.Errorf("agentLogPath(worker) = %q, %v", path, err)
+	}
+	if _, err := d.agentLogPath("test-repo", "nobody"); err == nil {
+		t.Error("agentLogPath() of an unknown agent succeeded")
+	}
+}
@@ -1,15 +1,22 @@
 package daemon
 
 import (
+	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/micheal-at/multiclaude/internal/events"
 	"github.com/micheal-at/multiclaude/internal/messages"
 	"github.com/micheal-at/multiclaude/internal/socket"
 	"github.com/micheal-at/multiclaude/internal/state"
 	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
 )
 
 // setupTestDaemonWithState creates a test daemon with a pre-configured state for testing.
@@ -801,6 +808,30 @@ func TestHandleAddRepoTableDriven(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "successful add with test command and pre-spawn hook",
+			args: map[string]interface{}{
+				"name":            "preset-repo",
+				"github_url":      "https://github.com/owner/repo",
+				"tmux_session":    "mc-preset-repo",
+				"mq_test_command": "go test ./...",
+				"pre_spawn":       "go mod download",
+			},
+			wantSuccess: true,
+			checkState: func(t *testing.T, d *Daemon) {
+				repo, exists := d.state.GetRepo("preset-repo")
+				if !exists {
+					t.Error("Repo should exist after add")
+					return
+				}
+				if repo.MergeQueueConfig.TestCommand != "go test ./..." {
+					t.Errorf("MergeQueueConfig.TestCommand = %q, want %q", repo.MergeQueueConfig.TestCommand, "go test ./...")
+				}
+				if repo.SpawnHooks.PreSpawn != "go mod download" {
+					t.Errorf("SpawnHooks.PreSpawn = %q, want %q", repo.SpawnHooks.PreSpawn, "go mod download")
+				}
+			},
+		},
 		{
 			name: "duplicate repo name fails",
 			args: map[string]interface{}{
@@ -1316,3 +1347,466 @@ func TestHandleClearCurrentRepo(t *testing.T) {
 		t.Errorf("Current repo not cleared, got: %s", d.state.GetCurrentRepo())
 	}
 }
+
+// setupProjectRepos adds the given repositories to state for project tests
+func setupProjectRepos(s *state.State, names ...string) {
+	for _, name := range names {
+		s.AddRepo(name, &state.Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Agents:      make(map[string]state.Agent),
+		})
+	}
+}
+
+// TestHandleCreateProjectValidation tests create_project argument validation
+func TestHandleCreateProjectValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantError string
+	}{
+		{
+			name:      "missing name",
+			args:      map[string]interface{}{"repos": []interface{}{"api"}},
+			wantError: "missing 'name'",
+		},
+		{
+			name:      "missing repos",
+			args:      map[string]interface{}{"name": "platform"},
+			wantError: "missing 'repos'",
+		},
+		{
+			name:      "unknown repo",
+			args:      map[string]interface{}{"name": "platform", "repos": []interface{}{"api", "nope"}},
+			wantError: `repository "nope" not found`,
+		},
+		{
+			name:      "path in name",
+			args:      map[string]interface{}{"name": "../x", "repos": []interface{}{"api"}},
+			wantError: "can only contain",
+		},
+		{
+			name:      "colon in name",
+			args:      map[string]interface{}{"name": "a:b", "repos": []interface{}{"api"}},
+			wantError: "can only contain",
+		},
+		{
+			name:      "dot in name",
+			args:      map[string]interface{}{"name": "a.b", "repos": []interface{}{"api"}},
+			wantError: "can only contain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+				setupProjectRepos(s, "api", "web")
+			})
+			defer cleanup()
+
+			resp := d.handleCreateProject(socket.Request{Command: "create_project", Args: tt.args})
+			if resp.Success {
+				t.Fatal("handleCreateProject() succeeded, want failure")
+			}
+			if !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("handleCreateProject() error = %q, want containing %q", resp.Error, tt.wantError)
+			}
+			if len(d.state.ListProjects()) != 0 {
+				t.Error("project should not be stored after a failed create")
+			}
+		})
+	}
+}
+
+// TestHandleProjectLifecycle tests list_projects, project_status and remove_project
+func TestHandleProjectLifecycle(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		setupProjectRepos(s, "api", "web", "infra")
+		s.AddAgent("api", "clever-fox", state.Agent{Type: state.AgentTypeWorker, ReadyForCleanup: true})
+		s.AddAgent("api", "supervisor", state.Agent{Type: state.AgentTypeSupervisor})
+		s.AddProject("platform", &state.Project{
+			Repos:       []string{"api", "web"},
+			TmuxSession: "mc-project-platform-test",
+			CreatedAt:   time.Now(),
+		})
+	})
+	defer cleanup()
+
+	resp := d.handleListProjects(socket.Request{Command: "list_projects"})
+	if !resp.Success {
+		t.Fatalf("handleListProjects() failed: %s", resp.Error)
+	}
+	projects, ok := resp.Data.([]map[string]interface{})
+	if !ok || len(projects) != 1 || projects[0]["name"] != "platform" {
+		t.Fatalf("handleListProjects() data = %v, want one project named platform", resp.Data)
+	}
+
+	resp = d.handleProjectStatus(socket.Request{Command: "project_status", Args: map[string]interface{}{"name": "platform"}})
+	if !resp.Success {
+		t.Fatalf("handleProjectStatus() failed: %s", resp.Error)
+	}
+	status := resp.Data.(map[string]interface{})
+	repos := status["repos"].([]map[string]interface{})
+	if len(repos) != 2 {
+		t.Fatalf("handleProjectStatus() returned %d repos, want 2", len(repos))
+	}
+	for _, repo := range repos {
+		if repo["name"] == "api" {
+			if repo["total_agents"] != 2 || repo["worker_count"] != 1 || repo["completed_count"] != 1 {
+				t.Errorf("api status = %v, want 2 agents, 1 worker, 1 completed", repo)
+			}
+		}
+	}
+
+	resp = d.handleProjectStatus(socket.Request{Command: "project_status", Args: map[string]interface{}{"name": "missing"}})
+	if resp.Success {
+		t.Error("handleProjectStatus() for unknown project should fail")
+	}
+
+	resp = d.handleRemoveProject(socket.Request{Command: "remove_project", Args: map[string]interface{}{"name": "platform"}})
+	if !resp.Success {
+		t.Fatalf("handleRemoveProject() failed: %s", resp.Error)
+	}
+	if _, exists := d.state.GetProject("platform"); exists {
+		t.Error("project still exists after remove")
+	}
+	if _, exists := d.state.GetRepo("api"); !exists {
+		t.Error("removing a project must not remove member repositories")
+	}
+}
+
+// TestRouteMessagesToProjectSupervisor verifies messages to a project supervisor are delivered
+func TestRouteMessagesToProjectSupervisor(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	os.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	defer os.Unsetenv("MULTICLAUDE_TEST_MODE")
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		setupProjectRepos(s, "api", "web")
+	})
+	defer cleanup()
+
+	resp := d.handleCreateProject(socket.Request{
+		Command: "create_project",
+		Args:    map[string]interface{}{"name": "routing-test", "repos": []interface{}{"api", "web"}},
+	})
+	if !resp.Success {
+		t.Fatalf("handleCreateProject() failed: %s", resp.Error)
+	}
+	project, _ := d.state.GetProject("routing-test")
+	defer tmuxClient.KillSession(context.Background(), project.TmuxSession)
+
+	msgMgr := d.getMessageManager()
+	msg, err := msgMgr.Send(state.ProjectKey("routing-test"), "api/supervisor", "supervisor", "API is ready")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	d.routeMessages()
+
+	got, err := msgMgr.Get(state.ProjectKey("routing-test"), "supervisor", msg.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got.Status != messages.StatusDelivered {
+		t.Errorf("message status = %s, want %s", got.Status, messages.StatusDelivered)
+	}
+}
+
+func TestCheckProjectSupervisorsRestoresSession(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		setupProjectRepos(s, "api", "web")
+	})
+	defer cleanup()
+
+	resp := d.handleCreateProject(socket.Request{
+		Command: "create_project",
+		Args:    map[string]interface{}{"name": "restore-test", "repos": []interface{}{"api", "web"}},
+	})
+	if !resp.Success {
+		t.Fatalf("handleCreateProject() failed: %s", resp.Error)
+	}
+	project, _ := d.state.GetProject("restore-test")
+	defer tmuxClient.KillSession(context.Background(), project.TmuxSession)
+
+	if err := tmuxClient.KillSession(context.Background(), project.TmuxSession); err != nil {
+		t.Fatalf("KillSession() failed: %v", err)
+	}
+
+	d.checkProjectSupervisors()
+
+	hasSession, err := tmuxClient.HasSession(context.Background(), project.TmuxSession)
+	if err != nil {
+		t.Fatalf("HasSession() failed: %v", err)
+	}
+	if !hasSession {
+		t.Errorf("session %s was not restored", project.TmuxSession)
+	}
+	if _, err := os.Stat(filepath.Join(d.paths.Root, "prompts", "project-restore-test.md")); err != nil {
+		t.Errorf("project prompt not rewritten: %v", err)
+	}
+}
+
+func TestHandleCompleteAgentCriteria(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "mc-test-repo",
+			Agents:      make(map[string]state.Agent),
+		})
+	})
+	defer cleanup()
+
+	resp := d.handleAddAgent(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          "test-repo",
+			"agent":         "calm-owl",
+			"type":          "worker",
+			"worktree_path": "/tmp/calm-owl",
+			"tmux_window":   "calm-owl",
+			"task":          "add dark mode",
+			"criteria":      []interface{}{"toggle in settings", "contrast passes WCAG AA", "  "},
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+	agent, _ := d.state.GetAgent("test-repo", "calm-owl")
+	if len(agent.Criteria) != 2 || agent.Criteria[0].Status != state.CriterionPending {
+		t.Fatalf("criteria = %+v, want two pending criteria", agent.Criteria)
+	}
+
+	complete := func(report ...interface{}) socket.Response {
+		args := map[string]interface{}{"repo": "test-repo", "agent": "calm-owl"}
+		if report != nil {
+			args["criteria"] = report
+		}
+		return d.handleCompleteAgent(socket.Request{Command: "complete_agent", Args: args})
+	}
+	met := map[string]interface{}{"index": float64(1), "status": "met"}
+	unmet := map[string]interface{}{"index": float64(2), "status": "unmet", "note": "dark grey on black fails"}
+
+	if resp := complete(); resp.Success || !strings.Contains(resp.Error, "missing: 1, 2") {
+		t.Errorf("complete without a report = %+v, want both criteria missing", resp)
+	}
+	if resp := complete(met); resp.Success || !strings.Contains(resp.Error, "missing: 2") {
+		t.Errorf("complete with a partial report = %+v, want criterion 2 missing", resp)
+	}
+	if resp := complete(met, map[string]interface{}{"index": float64(3), "status": "met"}); resp.Success {
+		t.Error("complete should reject a report on a criterion that doesn't exist")
+	}
+	if resp := complete(met, map[string]interface{}{"index": float64(2), "status": "maybe"}); resp.Success {
+		t.Error("complete should reject an unknown criterion status")
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "calm-owl"); agent.ReadyForCleanup {
+		t.Fatal("rejected completions should not mark the agent for cleanup")
+	}
+
+	if resp := complete(met, unmet); !resp.Success {
+		t.Fatalf("complete with a full report failed: %s", resp.Error)
+	}
+	agent, _ = d.state.GetAgent("test-repo", "calm-owl")
+	if agent.Criteria[0].Status != state.CriterionMet || agent.Criteria[1].Status != state.CriterionUnmet || agent.Criteria[1].Note != "dark grey on black fails" {
+		t.Errorf("criteria after complete = %+v", agent.Criteria)
+	}
+
+	msgs, _ := messages.NewManager(d.paths.MessagesDir).List("test-repo", "supervisor")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "2. [unmet] contrast passes WCAG AA - dark grey on black fails") {
+		t.Errorf("supervisor messages = %+v, want the criteria report", msgs)
+	}
+
+	d.recordTaskHistory("test-repo", "calm-owl", agent)
+	history, _ := d.state.GetTaskHistory("test-repo", 1)
+	if len(history) != 1 || len(history[0].Criteria) != 2 || history[0].Criteria[1].Status != state.CriterionUnmet {
+		t.Errorf("history = %+v, want the criteria recorded", history)
+	}
+
+	// Agents without criteria can't report on any
+	d.state.AddAgent("test-repo", "quiet-elk", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "quiet-elk"})
+	resp = d.handleCompleteAgent(socket.Request{Command: "complete_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "quiet-elk", "criteria": []interface{}{met},
+	}})
+	if resp.Success {
+		t.Error("complete should reject criteria reports from an agent without criteria")
+	}
+}
+
+func TestHandleAskAnswer(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "mc-test-repo",
+			Agents: map[string]state.Agent{
+				"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+				"calm-owl":   {Type: state.AgentTypeWorker, TmuxWindow: "calm-owl"},
+			},
+		})
+	})
+	defer cleanup()
+
+	ask := func(to string, wait bool) socket.Response {
+		return d.handleAsk(socket.Request{Command: "ask", Args: map[string]interface{}{
+			"repo":            "test-repo",
+			"from":            "supervisor",
+			"to":              to,
+			"question":        "Which DB driver are you using?",
+			"timeout_seconds": float64(600),
+			"wait":            wait,
+		}})
+	}
+	if resp := ask("nobody", true); resp.Success {
+		t.Error("asking an unknown agent should fail")
+	}
+	if resp := ask("supervisor", true); resp.Success {
+		t.Error("an agent asking itself should fail")
+	}
+
+	resp := ask("calm-owl", true)
+	if !resp.Success {
+		t.Fatalf("ask failed: %s", resp.Error)
+	}
+	ticket := resp.Data.(map[string]interface{})["ticket"].(string)
+
+	// The question reaches the target as a message naming the ticket
+	msgMgr := messages.NewManager(d.paths.MessagesDir)
+	msgs, _ := msgMgr.List("test-repo", "calm-owl")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "multiclaude answer "+ticket) || msgs[0].From != "supervisor" {
+		t.Fatalf("calm-owl messages = %+v, want the question with its ticket", msgs)
+	}
+
+	get := func(id string) socket.Response {
+		return d.handleGetTicket(socket.Request{Command: "get_ticket", Args: map[string]interface{}{"ticket": id}})
+	}
+	if data := get(ticket).Data.(map[string]interface{}); data["status"] != "pending" {
+		t.Errorf("status before answering = %v, want pending", data["status"])
+	}
+
+	answer := func(id, text string) socket.Response {
+		return d.handleAnswer(socket.Request{Command: "answer", Args: map[string]interface{}{"ticket": id, "answer": text}})
+	}
+	resp = answer(ticket, "pgx")
+	if !resp.Success {
+		t.Fatalf("answer failed: %s", resp.Error)
+	}
+	data := get(ticket).Data.(map[string]interface{})
+	if data["status"] != "answered" || data["answer"] != "pgx" || data["answered_by"] != "calm-owl" {
+		t.Errorf("ticket after answering = %v", data)
+	}
+	// The asker is waiting, so no message
+	if msgs, _ := msgMgr.List("test-repo", "supervisor"); len(msgs) != 0 {
+		t.Errorf("waiting asker got messages: %+v", msgs)
+	}
+	if resp := answer(ticket, "sqlx"); resp.Success {
+		t.Error("answering twice should fail")
+	}
+	if resp := answer("tk-missing", "x"); resp.Success || !strings.Contains(resp.Error, "not found") {
+		t.Errorf("answering an unknown ticket = %+v, want not found", resp)
+	}
+
+	// Askers that don't wait get the answer as a message
+	resp = ask("calm-owl", false)
+	ticket = resp.Data.(map[string]interface{})["ticket"].(string)
+	if resp := answer(ticket, "Yes, blocked on review"); !resp.Success || resp.Data.(map[string]interface{})["delivered_as_message"] != true {
+		t.Fatalf("answer = %+v, want delivery as a message", resp)
+	}
+	msgs, _ = msgMgr.List("test-repo", "supervisor")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "Yes, blocked on review") || msgs[0].From != "calm-owl" {
+		t.Errorf("supervisor messages = %+v, want the answer", msgs)
+	}
+}
+
+func TestHandleInterruptAgent(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+	ctx := context.Background()
+	session := "mc-test-interrupt"
+	if err := tmuxClient.CreateSession(ctx, session, true); err != nil {
+		t.Fatalf("CreateSession() error: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, session)
+	if err := tmuxClient.CreateWindow(ctx, session, "calm-owl"); err != nil {
+		t.Fatalf("CreateWindow() error: %v", err)
+	}
+	if err := tmuxClient.SendKeys(ctx, session, "calm-owl", "sleep 301"); err != nil {
+		t.Fatalf("SendKeys() error: %v", err)
+	}
+	panePID, err := tmuxClient.GetPanePID(ctx, session, "calm-owl")
+	if err != nil {
+		t.Fatalf("GetPanePID() error: %v", err)
+	}
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: session,
+			Agents: map[string]state.Agent{
+				"calm-owl": {Type: state.AgentTypeWorker, TmuxWindow: "calm-owl", PID: panePID},
+			},
+		})
+	})
+	defer cleanup()
+
+	for _, tt := range []struct {
+		args      map[string]interface{}
+		wantError string
+	}{
+		{map[string]interface{}{"repo": "test-repo", "agent": "calm-owl", "key": "tab"}, "invalid key"},
+		{map[string]interface{}{"repo": "test-repo", "agent": "sad-cat"}, "not found"},
+	} {
+		resp := d.handleInterruptAgent(socket.Request{Command: "interrupt_agent", Args: tt.args})
+		if resp.Success || !strings.Contains(resp.Error, tt.wantError) {
+			t.Errorf("handleInterruptAgent(%v) = %+v, want an error containing %q", tt.args, resp, tt.wantError)
+		}
+	}
+
+	// Wait for sleep to start, so there is something to interrupt
+	var sleepPID int
+	for i := 0; i < 50 && sleepPID == 0; i++ {
+		out, _ := exec.Command("pgrep", "-f", "^sleep 301$").Output()
+		sleepPID, _ = strconv.Atoi(strings.TrimSpace(string(out)))
+		time.Sleep(100 * time.Millisecond)
+	}
+	if sleepPID == 0 {
+		t.Fatal("sleep didn't start in the agent's window")
+	}
+
+	seq := d.events.Seq()
+	resp := d.handleInterruptAgent(socket.Request{Command: "interrupt_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "calm-owl", "signal": true, "by": "supervisor", "message": "Stop: the API is frozen",
+	}})
+	if !resp.Success {
+		t.Fatalf("handleInterruptAgent() failed: %s", resp.Error)
+	}
+
+	evs, _ := d.events.Since(seq)
+	if len(evs) != 1 || evs[0].Type != events.EventAgentInterrupted || evs[0].Data["key"] != "escape" || evs[0].Data["signal"] != "sigint" || evs[0].Data["by"] != "supervisor" {
+		t.Errorf("events = %+v, want one agent_interrupted", evs)
+	}
+	stopped := false
+	for i := 0; i < 50 && !stopped; i++ {
+		stopped = syscall.Kill(sleepPID, 0) != nil
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !stopped {
+		t.Error("SIGINT didn't stop the process in the agent's window")
+	}
+	msgs, _ := d.getMessageManager().List("test-repo", "calm-owl")
+	if len(msgs) != 1 || msgs[0].From != "supervisor" {
+		t.Errorf("messages = %+v, want the supervisor's instructions", msgs)
+	}
+}
@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// wedgedFactor is how many times the refresh timeout, the longest any of the
+// loops' commands may take, a loop tick can run before it counts as wedged
+const wedgedFactor = 3
+
+// stateCheckTimeout is how long /readyz waits for the state store
+const stateCheckTimeout = 5 * time.Second
+
+// wedged returns the loops whose current tick has been running for longer
+// than after, sorted by name
+func (s *debugStats) wedged(now time.Time, after time.Duration) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name, stats := range s.loops {
+		if stats.running && now.Sub(stats.lastStart) > after {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// startHTTP serves /healthz and /readyz when daemon.yaml sets http.address
+func (d *Daemon) startHTTP() error {
+	settings, _ := d.currentSettings()
+	if settings.HTTP.Address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", settings.HTTP.Address)
+	if err != nil {
+		return err
+	}
+
+	d.httpServer = &http.Server{Handler: d.healthHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := d.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			d.logger.Error("HTTP server stopped: %v", err)
+		}
+	}()
+	d.logger.Info("HTTP health checks listening at %s", listener.Addr().String())
+	return nil
+}
+
+// stopHTTP stops the HTTP server
+func (d *Daemon) stopHTTP() {
+	if d.httpServer == nil {
+		return
+	}
+	d.httpServer.Close()
+}
+
+// healthHandler serves the health checks. /healthz fails when the daemon is
+// wedged: a loop tick has hung, so the process is up but doing nothing.
+// /readyz fails when it can't do its work: it is starting up or shutting
+// down, or the state store can't be saved.
+func (d *Daemon) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, d.liveness(time.Now()))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, d.readiness())
+	})
+	return mux
+}
+
+// liveness returns the problems that make the daemon wedged
+func (d *Daemon) liveness(now time.Time) []string {
+	settings, _ := d.currentSettings()
+	var problems []string
+	for _, loop := range d.debug.wedged(now, wedgedFactor*settings.Timeouts.Refresh) {
+		problems = append(problems, fmt.Sprintf("%s loop is stuck", loop))
+	}
+	return problems
+}
+
+// readiness returns the problems that keep the daemon from doing its work
+func (d *Daemon) readiness() []string {
+	if d.ctx.Err() != nil {
+		return []string{"daemon is shutting down"}
+	}
+	if !d.ready.Load() {
+		return []string{"daemon is starting"}
+	}
+
+	// A wedged daemon can hold the state lock forever, so don't wait on it
+	checked := make(chan error, 1)
+	go func() { checked <- d.state.Check() }()
+	select {
+	case err := <-checked:
+		if err != nil {
+			return []string{fmt.Sprintf("state store: %v", err)}
+		}
+	case <-time.After(stateCheckTimeout):
+		return []string{"state store: lock not released within " + stateCheckTimeout.String()}
+	}
+	return nil
+}
+
+// writeHealth writes a health check's result: 200 and ok without problems,
+// 503 with them
+func writeHealth(w http.ResponseWriter, problems []string) {
+	w.Header().Set("Content-Type", "application/json")
+	body := map[string]interface{}{"status": "ok"}
+	if len(problems) > 0 {
+		body = map[string]interface{}{"status": "failing", "problems": problems}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestDebugStatsWedged(t *testing.T) {
+	s := newDebugStats()
+	s.time("wake", func() {})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.time("worktree refresh", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+
+	if got := s.wedged(time.Now(), time.Hour); len(got) != 0 {
+		t.Errorf("wedged(1h) = %v, want none", got)
+	}
+	// Only a tick still running counts, however long ago a finished one started
+	if got := s.wedged(time.Now().Add(2*time.Hour), time.Hour); len(got) != 1 || got[0] != "worktree refresh" {
+		t.Errorf("wedged() = %v, want the running refresh", got)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	get := func(path string) (int, map[string]interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		d.healthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s body %q: %v", path, rec.Body.String(), err)
+		}
+		return rec.Code, body
+	}
+
+	if code, body := get("/healthz"); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("/healthz = %d %v, want 200 ok", code, body)
+	}
+	if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before start = %d, want 503", code)
+	}
+
+	d.ready.Store(true)
+	if code, body := get("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz = %d %v, want 200", code, body)
+	}
+
+	// A state store that can't be saved isn't ready
+	saved := d.state
+	d.state = state.New(filepath.Join(d.paths.Root, "missing", "state.json"))
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || body["problems"] == nil {
+		t.Errorf("/readyz with an unwritable state store = %d %v, want 503", code, body)
+	}
+	d.state = saved
+
+	// A hung loop tick makes the daemon unhealthy
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go d.debug.time("merge train", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+	d.settings.Timeouts.Refresh = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if code, body := get("/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz with a stuck loop = %d %v, want 503", code, body)
+	}
+}
@@ -0,0 +1,245 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/prbody"
+	"github.com/micheal-at/multiclaude/internal/simagent"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// prDescriptionTimeout bounds writing one PR description, Claude's summary
+// of the diff included
+const prDescriptionTimeout = 3 * time.Minute
+
+// workerPR returns the open or draft PR for a worker's branch
+func (d *Daemon) workerPR(repoName, agentName string, agent state.Agent) (pullRequest, error) {
+	branch := agent.Branch
+	if branch == "" {
+		branch = "work/" + agentName
+	}
+
+	// The PR was likely opened or pushed to moments ago
+	d.prCache.Invalidate(repoName)
+	prs, err := d.repoPullRequests(repoName)
+	if err != nil {
+		return pullRequest{}, err
+	}
+	pr, found := prs[branch]
+	if !found {
+		return pullRequest{}, fmt.Errorf("no PR found for branch %s", branch)
+	}
+	if status := pr.status(); status != "draft" && status != "open" {
+		return pullRequest{}, fmt.Errorf("PR #%d is %s", pr.Number, status)
+	}
+	return pr, nil
+}
+
+// prDescription is a worker's PR with what its description is written from
+type prDescription struct {
+	repoName  string
+	agentName string
+	pr        pullRequest
+	head      string
+	template  string
+	data      prbody.Data
+	diff      string
+}
+
+// preparePRDescription reads what a worker's PR description is written from
+// out of its worktree: the commits and diff since the PR's base, and the
+// repository's template as of the worker's branch. It's quick, so it can be
+// done before a completed worker is cleaned up; writing the description,
+// which waits on Claude, can follow.
+func (d *Daemon) preparePRDescription(repoName, agentName string, agent state.Agent, pr pullRequest) (*prDescription, error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+	if agent.WorktreePath == "" {
+		return nil, fmt.Errorf("agent '%s' has no worktree", agentName)
+	}
+
+	remote, err := d.repoWorktreeManager(repoName).GetUpstreamRemote()
+	if err != nil {
+		return nil, fmt.Errorf("could not get remote: %w", err)
+	}
+	base := remote + "/" + pr.BaseRefName
+
+	ctx, cancel := context.WithTimeout(d.ctx, prDescriptionTimeout)
+	defer cancel()
+	changes, err := prbody.Diff(ctx, agent.WorktreePath, base)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &prDescription{
+		repoName:  repoName,
+		agentName: agentName,
+		pr:        pr,
+		head:      changes.Head,
+		diff:      changes.Diff,
+		data: prbody.Data{
+			Agent:    agentName,
+			Branch:   pr.HeadRefName,
+			Base:     pr.BaseRefName,
+			Task:     agent.Task,
+			Criteria: append([]state.Criterion(nil), agent.Criteria...),
+			Report:   agent.Summary,
+			Commits:  changes.Commits,
+			DiffStat: changes.DiffStat,
+		},
+	}
+	if path := repo.PRDescriptions.Template; path != "" {
+		tmpl, err := os.ReadFile(filepath.Join(agent.WorktreePath, path))
+		if err != nil {
+			return nil, fmt.Errorf("could not read the PR description template: %w", err)
+		}
+		desc.template = string(tmpl)
+	}
+	return desc, nil
+}
+
+// renderPRDescription summarizes the diff with Claude and renders the
+// description. A failed summary is only logged: the description is still
+// useful without it.
+func (d *Daemon) renderPRDescription(desc *prDescription) (string, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, prDescriptionTimeout)
+	defer cancel()
+	if desc.diff != "" {
+		summary, err := d.summarize(ctx, d.paths.RepoDir(desc.repoName), prbody.SummaryPrompt(desc.data.Task, desc.diff))
+		if err != nil {
+			d.logger.Warn("Failed to summarize the diff of PR #%d in %s: %v", desc.pr.Number, desc.repoName, err)
+		}
+		desc.data.Summary = summary
+	}
+	return prbody.Render(desc.template, desc.data)
+}
+
+// writePRDescription renders a prepared description and sets it on the PR
+func (d *Daemon) writePRDescription(desc *prDescription) (string, error) {
+	body, err := d.renderPRDescription(desc)
+	if err != nil {
+		return "", err
+	}
+	if err := d.editPRBody(d.paths.RepoDir(desc.repoName), desc.pr.Number, body); err != nil {
+		return "", err
+	}
+	// The worker may have been cleaned up in the meantime
+	d.state.UpdateAgentDescribedHead(desc.repoName, desc.agentName, desc.head)
+	d.logger.Info("Wrote the description of PR #%d (%s) in %s", desc.pr.Number, desc.pr.HeadRefName, desc.repoName)
+	return body, nil
+}
+
+// describeWorkerPR writes the description of a worker's PR in the
+// background, when the repository has PR descriptions on and the worker has
+// committed since its description was last written. Errors are only logged.
+func (d *Daemon) describeWorkerPR(repoName, agentName string, agent state.Agent, pr pullRequest) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists || !repo.PRDescriptions.Enabled || agent.Type != state.AgentTypeWorker {
+		return
+	}
+	desc, err := d.preparePRDescription(repoName, agentName, agent, pr)
+	if err != nil {
+		d.logger.Warn("Failed to prepare the description of PR #%d for %s/%s: %v", pr.Number, repoName, agentName, err)
+		return
+	}
+	if desc.head == agent.DescribedHead {
+		return
+	}
+	go func() {
+		if _, err := d.writePRDescription(desc); err != nil {
+			d.logger.Warn("Failed to write the description of PR #%d for %s/%s: %v", pr.Number, repoName, agentName, err)
+		}
+	}()
+}
+
+// handleDescribePR writes the description of a worker's PR now, whether or
+// not the repository has PR descriptions on. With dry_run it's only returned.
+func (d *Daemon) handleDescribePR(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+	dryRun, _ := req.Args["dry_run"].(bool)
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists || agent.Type != state.AgentTypeWorker {
+		return errorResponse(errors.AgentNotFound("worker", agentName, repoName))
+	}
+	pr, err := d.workerPR(repoName, agentName, agent)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	desc, err := d.preparePRDescription(repoName, agentName, agent, pr)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	var body string
+	if dryRun {
+		body, err = d.renderPRDescription(desc)
+	} else {
+		body, err = d.writePRDescription(desc)
+	}
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"pr_number":  pr.Number,
+		"pr_url":     pr.URL,
+		"body":       body,
+		"summarized": desc.data.Summary != "",
+		"applied":    !dryRun,
+	}}
+}
+
+// summarizeWithClaude asks Claude, non-interactively, to summarize a diff.
+// In test mode there's no Claude to ask, and descriptions go without.
+func (d *Daemon) summarizeWithClaude(ctx context.Context, dir, prompt string) (string, error) {
+	if simagent.Skip() || simagent.Enabled() {
+		return "", nil
+	}
+	binaryPath, err := d.getClaudeBinaryPath()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, binaryPath, "-p")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(prompt)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("claude -p failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// editPullRequestBody replaces a PR's description
+func (d *Daemon) editPullRequestBody(repoPath string, number int, body string) error {
+	output, err := d.github().CombinedOutput(d.ctx, func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "gh", "pr", "edit", strconv.Itoa(number), "--body-file", "-")
+		cmd.Dir = repoPath
+		cmd.Stdin = strings.NewReader(body)
+		return cmd
+	})
+	if err != nil {
+		return fmt.Errorf("gh pr edit failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/pushcheck"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// runPushChecks checks a worker's branch against its repository's push
+// checks, after fetching its base so "rebased" means rebased on the latest.
+// It returns nothing when the repository has no checks.
+func (d *Daemon) runPushChecks(repoName, agentName string, agent state.Agent) ([]pushcheck.Failure, error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+	checks := repo.PushChecks
+	if !checks.Enabled() {
+		return nil, nil
+	}
+	if agent.WorktreePath == "" {
+		return nil, fmt.Errorf("agent '%s' has no worktree", agentName)
+	}
+
+	wt := d.repoWorktreeManager(repoName)
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		return nil, fmt.Errorf("could not get remote: %w", err)
+	}
+	settings, _ := d.currentSettings()
+	ctx, cancel := context.WithTimeout(d.ctx, settings.Timeouts.Refresh)
+	defer cancel()
+	if checks.Rebased {
+		if err := wt.FetchRemote(ctx, remote); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", remote, err)
+		}
+	}
+	return pushcheck.Run(ctx, agent.WorktreePath, remote+"/"+agentBase(agent, d.repoDefaultBranch(repoName)), checks)
+}
+
+// handlePushCheck runs a worker's push checks, so it can fix its branch
+// before pushing. Failures are data, not an error: the request succeeded.
+func (d *Daemon) handlePushCheck(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
+	if !ok {
+		return errResp
+	}
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists || agent.Type != state.AgentTypeWorker {
+		return errorResponse(errors.AgentNotFound("worker", agentName, repoName))
+	}
+	repo, _ := d.state.GetRepo(repoName)
+
+	failures, err := d.runPushChecks(repoName, agentName, agent)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"enabled":  repo.PushChecks.Enabled(),
+		"passed":   len(failures) == 0,
+		"failures": pushFailureData(failures),
+	}}
+}
+
+// requirePushChecks fails when a worker's branch fails its push checks, and
+// sends the worker the failures so it fixes them itself. When the checks
+// can't be run, strict decides: fail, or only log it and let the worker by.
+func (d *Daemon) requirePushChecks(repoName, agentName string, agent state.Agent, strict bool) error {
+	failures, err := d.runPushChecks(repoName, agentName, agent)
+	if err != nil {
+		if strict {
+			return fmt.Errorf("could not run push checks: %w", err)
+		}
+		d.logger.Warn("Could not run push checks for %s/%s: %v", repoName, agentName, err)
+		return nil
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	report := pushcheck.Report(failures)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, report); err != nil {
+		d.logger.Warn("Failed to send %s/%s its push check failures: %v", repoName, agentName, err)
+	} else {
+		go d.routeMessages()
+	}
+	d.logger.Info("Push checks failed for %s/%s: %d failure(s)", repoName, agentName, len(failures))
+	return fmt.Errorf("%s", report)
+}
+
+// pushFailureData is failures as push_check returns them
+func pushFailureData(failures []pushcheck.Failure) []map[string]interface{} {
+	data := make([]map[string]interface{}, 0, len(failures))
+	for _, f := range failures {
+		data = append(data, map[string]interface{}{
+			"check":   f.Check,
+			"commit":  f.Commit,
+			"message": f.Message,
+			"fix":     f.Fix,
+		})
+	}
+	return data
+}
+
+// describePushChecks lists the checks that are on, such as "rebased on the
+// base branch, signed commits", or "off"
+func describePushChecks(checks state.PushChecks) string {
+	var parts []string
+	if checks.Rebased {
+		parts = append(parts, "rebased on the base branch")
+	}
+	if checks.Signed {
+		parts = append(parts, "signed commits")
+	}
+	if checks.MessagePattern != "" {
+		parts = append(parts, fmt.Sprintf("commit subjects matching %s", checks.MessagePattern))
+	}
+	if len(parts) == 0 {
+		return "off"
+	}
+	return strings.Join(parts, ", ")
+}
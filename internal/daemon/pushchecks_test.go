@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestHandlePushCheck(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test User", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	tmp := t.TempDir()
+	origin := filepath.Join(tmp, "origin.git")
+	git(tmp, "init", "--bare", "-b", "main", origin)
+	git(repoDir, "remote", "add", "origin", origin)
+	git(repoDir, "push", "-q", "origin", "main")
+	git(repoDir, "fetch", "-q", "origin")
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:    "https://github.com/test/repo",
+		TmuxSession:  "test-session",
+		Agents:       make(map[string]state.Agent),
+		TargetBranch: "main",
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	wtPath := filepath.Join(tmp, "calm-owl")
+	git(repoDir, "worktree", "add", "-q", "-b", "work/calm-owl", wtPath, "origin/main")
+	git(wtPath, "commit", "-q", "--allow-empty", "-m", "feat: the change")
+	if err := d.state.AddAgent("test-repo", "calm-owl", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: wtPath,
+		TmuxWindow:   "calm-owl",
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	check := func() map[string]interface{} {
+		t.Helper()
+		resp := d.handlePushCheck(socket.Request{Command: "push_check", Args: map[string]interface{}{"repo": "test-repo", "agent": "calm-owl"}})
+		if !resp.Success {
+			t.Fatalf("push_check failed: %s", resp.Error)
+		}
+		return resp.Data.(map[string]interface{})
+	}
+
+	if data := check(); data["enabled"] != false || data["passed"] != true {
+		t.Errorf("push_check without checks = %+v, want it to pass", data)
+	}
+
+	if err := d.state.UpdatePushChecks("test-repo", state.PushChecks{Rebased: true, MessagePattern: `^(feat|fix): `}); err != nil {
+		t.Fatal(err)
+	}
+	if data := check(); data["passed"] != true {
+		t.Errorf("push_check = %+v, want the branch to pass", data)
+	}
+
+	// main moves on upstream; the check fetches it and finds the branch behind
+	upstream := filepath.Join(tmp, "upstream")
+	git(tmp, "clone", "-q", origin, upstream)
+	git(upstream, "commit", "-q", "--allow-empty", "-m", "upstream change")
+	git(upstream, "push", "-q", "origin", "main")
+	git(wtPath, "commit", "-q", "--allow-empty", "-m", "wip")
+
+	data := check()
+	failures := data["failures"].([]map[string]interface{})
+	if data["passed"] != false || len(failures) != 2 || failures[0]["check"] != "rebased" || failures[1]["check"] != "message" {
+		t.Fatalf("push_check = %+v, want the branch behind and the wip subject rejected", data)
+	}
+
+	// Completing is refused and the worker is told why
+	resp := d.handleCompleteAgent(socket.Request{Command: "complete_agent", Args: map[string]interface{}{"repo": "test-repo", "agent": "calm-owl"}})
+	if resp.Success || !strings.Contains(resp.Error, "Push checks failed") {
+		t.Errorf("complete_agent = %+v, want it refused by the push checks", resp)
+	}
+	msgs, err := d.getMessageManager().List("test-repo", "calm-owl")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "git rebase origin/main") {
+		t.Errorf("worker messages = %+v, %v; want the failures and fixes", msgs, err)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "calm-owl"); agent.ReadyForCleanup {
+		t.Error("worker was marked ready for cleanup")
+	}
+
+	// Giving up isn't held up by the checks
+	resp = d.handleCompleteAgent(socket.Request{Command: "complete_agent", Args: map[string]interface{}{"repo": "test-repo", "agent": "calm-owl", "failure_reason": "blocked"}})
+	if !resp.Success {
+		t.Errorf("complete_agent with a failure reason failed: %s", resp.Error)
+	}
+}
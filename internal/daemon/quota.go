@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// definitionLimit returns the max-instances limit of a repository's agent
+// definition, 0 when it has none or there's no such definition
+func (d *Daemon) definitionLimit(repoName, definition string) (int, error) {
+	defs, err := agents.NewReader(d.paths.RepoAgentsDir(repoName), d.paths.RepoDir(repoName)).ReadAllDefinitions()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read agent definitions: %w", err)
+	}
+	for _, def := range defs {
+		if def.Name == definition {
+			return def.MaxInstances(), nil
+		}
+	}
+	return 0, nil
+}
+
+// definitionInstances returns the agents of a repository started from a
+// definition, including those spawn_agent is still starting, sorted
+func (d *Daemon) definitionInstances(repoName, definition string) []string {
+	var names []string
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		for name, agent := range repo.Agents {
+			if agent.Definition == definition {
+				names = append(names, name)
+			}
+		}
+	}
+	for key, r := range d.spawning {
+		if name, ok := strings.CutPrefix(key, repoName+"/"); ok && r.definition == definition {
+			names = append(names, name+" (starting)")
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workerInstances returns a repository's workers, including those still
+// starting, sorted
+func (d *Daemon) workerInstances(repoName string) []string {
+	var names []string
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		for name, agent := range repo.Agents {
+			if agent.Type == state.AgentTypeWorker {
+				names = append(names, name)
+			}
+		}
+	}
+	for key, r := range d.spawning {
+		if name, ok := strings.CutPrefix(key, repoName+"/"); ok && r.agentType == state.AgentTypeWorker {
+			names = append(names, name+" (starting)")
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkWorkerLimit returns a WorkerLimitReached error when a repository
+// already runs as many workers as its max_workers setting allows. The caller
+// holds quotaMu.
+func (d *Daemon) checkWorkerLimit(repoName string) error {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists || repo.MaxWorkers == 0 {
+		return nil
+	}
+	if running := d.workerInstances(repoName); len(running) >= repo.MaxWorkers {
+		return errors.WorkerLimitReached(repoName, repo.MaxWorkers, running)
+	}
+	return nil
+}
+
+// coreDefinition returns the built-in definition an agent of a type started
+// without one counts towards, so the merge queue and PR shepherd the CLI
+// starts are held to a max-instances limit too. Supervisors and workspaces
+// have no definition.
+func coreDefinition(t state.AgentType) string {
+	switch t {
+	case state.AgentTypeMergeQueue, state.AgentTypePRShepherd, state.AgentTypeWorker, state.AgentTypeObserver:
+		return string(t)
+	case state.AgentTypeReview:
+		return "reviewer"
+	default:
+		return ""
+	}
+}
+
+// checkDefinitionQuota returns a DefinitionAtLimit error when a repository
+// already runs as many agents from a definition as its limit allows
+func (d *Daemon) checkDefinitionQuota(repoName, definition string) error {
+	if definition == "" {
+		return nil
+	}
+	limit, err := d.definitionLimit(repoName, definition)
+	if err != nil || limit == 0 {
+		return err
+	}
+	d.quotaMu.Lock()
+	defer d.quotaMu.Unlock()
+	if running := d.definitionInstances(repoName, definition); len(running) >= limit {
+		return errors.DefinitionAtLimit(definition, limit, running)
+	}
+	return nil
+}
+
+// reservation is an agent spawn_agent or add_agent is starting
+type reservation struct {
+	definition string
+	agentType  state.AgentType
+}
+
+// reserveInstance checks a definition's limit, and for workers the
+// repository's worker limit, and counts agentName towards them until the
+// returned release is called, so agents spawned at the same time can't both
+// take the last place. Call release once the agent is registered, or has
+// failed to start.
+func (d *Daemon) reserveInstance(repoName, agentName string, agentType state.AgentType, definition string) (release func(), err error) {
+	if definition == "" && agentType != state.AgentTypeWorker {
+		return func() {}, nil
+	}
+	limit := 0
+	if definition != "" {
+		if limit, err = d.definitionLimit(repoName, definition); err != nil {
+			return nil, err
+		}
+	}
+	key := repoName + "/" + agentName
+
+	d.quotaMu.Lock()
+	defer d.quotaMu.Unlock()
+	if running := d.definitionInstances(repoName, definition); limit > 0 && len(running) >= limit {
+		return nil, errors.DefinitionAtLimit(definition, limit, running)
+	}
+	if agentType == state.AgentTypeWorker {
+		if err := d.checkWorkerLimit(repoName); err != nil {
+			return nil, err
+		}
+	}
+	if d.spawning == nil {
+		d.spawning = make(map[string]reservation)
+	}
+	d.spawning[key] = reservation{definition: definition, agentType: agentType}
+	return func() {
+		d.quotaMu.Lock()
+		delete(d.spawning, key)
+		d.quotaMu.Unlock()
+	}, nil
+}
+
+// handleCheckAgentQuota reports whether another agent may be started from a
+// definition, for the CLI to ask before it creates a worker or reviewer
+func (d *Daemon) handleCheckAgentQuota(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	definition, errResp, ok := getRequiredStringArg(req.Args, "definition", "agent definition name is required")
+	if !ok {
+		return errResp
+	}
+	if _, exists := d.state.GetRepo(repoName); !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+	if err := d.checkDefinitionQuota(repoName, definition); err != nil {
+		return errorResponse(err)
+	}
+	// Workers are also held to the repository's worker limit
+	isWorker := req.Args["type"] == string(state.AgentTypeWorker)
+	if isWorker {
+		d.quotaMu.Lock()
+		err := d.checkWorkerLimit(repoName)
+		d.quotaMu.Unlock()
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+
+	limit, _ := d.definitionLimit(repoName, definition)
+	d.quotaMu.Lock()
+	running := d.definitionInstances(repoName, definition)
+	d.quotaMu.Unlock()
+	data := map[string]interface{}{
+		"definition":    definition,
+		"max_instances": limit,
+		"running":       running,
+	}
+	if isWorker {
+		repo, _ := d.state.GetRepo(repoName)
+		data["max_workers"] = repo.MaxWorkers
+	}
+	return socket.Response{Success: true, Data: data}
+}
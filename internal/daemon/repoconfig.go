@@ -0,0 +1,246 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/federation"
+	"github.com/micheal-at/multiclaude/internal/repoconfig"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/workhours"
+)
+
+// repoConfigArgs converts a config file into update_repo_config arguments for
+// the settings it sets, decoded the way a socket request's are
+func repoConfigArgs(cfg *repoconfig.Config) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	setString := func(key, value string) {
+		if value != "" {
+			args[key] = value
+		}
+	}
+	setBool := func(key string, value *bool) {
+		if value != nil {
+			args[key] = *value
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil {
+			args[key] = *value
+		}
+	}
+
+	setString("target_branch", cfg.DefaultBranch)
+	setString("branch_template", cfg.BranchTemplate)
+	if mq := cfg.MergeQueue; mq != nil {
+		setBool("mq_enabled", mq.Enabled)
+		setString("mq_track_mode", mq.Track)
+		setString("mq_test_command", mq.TestCommand)
+	}
+	if ps := cfg.PRShepherd; ps != nil {
+		setBool("ps_enabled", ps.Enabled)
+		setString("ps_track_mode", ps.Track)
+	}
+	if n := cfg.Notify; n != nil {
+		setBool("notify_enabled", n.Enabled)
+		setString("notify_method", n.Method)
+		if n.To != nil {
+			args["notify_to"] = n.To
+		}
+		setString("notify_from", n.From)
+		setString("notify_smtp_addr", n.SMTP)
+		setString("notify_smtp_username", n.SMTPUser)
+		setInt("notify_digest_minutes", n.DigestMinutes)
+		if n.NeedsHuman != nil {
+			args["notify_needs_human"] = n.NeedsHuman
+		}
+		setString("notify_webhook", n.Webhook)
+	}
+	if f := cfg.Federation; f != nil {
+		switch f.Relay {
+		case "":
+		case "off":
+			args["federation_enabled"] = false
+		case federation.RelayGit:
+			args["federation_enabled"] = true
+			args["federation_relay"] = f.Relay
+		default:
+			args["federation_enabled"] = true
+			args["federation_relay"] = filepath.Clean(f.Relay)
+		}
+		setString("federation_branch", f.Branch)
+		setString("federation_peer_id", f.Peer)
+	}
+	if z := cfg.Zombie; z != nil {
+		setBool("zombie_enabled", z.Enabled)
+		setInt("zombie_stall_minutes", z.StallMinutes)
+		setString("zombie_stalled", z.Stalled)
+		setString("zombie_looping", z.Looping)
+		setString("zombie_prompt", z.Prompt)
+	}
+	if w := cfg.Worktree; w != nil {
+		setBool("worktree_lfs", w.LFS)
+		setBool("worktree_submodules", w.Submodules)
+		setBool("worktree_mirror", w.Mirror)
+	}
+	if r := cfg.Refresh; r != nil {
+		setString("refresh_strategy", r.Strategy)
+		setBool("refresh_only_clean", r.OnlyClean)
+		setBool("refresh_pause_active", r.PauseActive)
+	}
+	setString("roster", cfg.Roster)
+	setBool("draft_prs", cfg.DraftPRs)
+	if p := cfg.PRDescriptions; p != nil {
+		setBool("pr_descriptions", p.Enabled)
+		setString("pr_template", p.Template)
+	}
+	if p := cfg.PushChecks; p != nil {
+		setBool("push_rebased", p.Rebased)
+		setBool("push_signed", p.Signed)
+		setString("push_message_pattern", p.MessagePattern)
+	}
+	setString("review_dispatch", cfg.ReviewDispatch)
+	setInt("max_workers", cfg.MaxWorkers)
+	if c := cfg.CommentCommands; c != nil && c.Allow != nil {
+		args["comment_commands_allow"] = c.Allow
+	}
+	if h := cfg.SpawnHooks; h != nil {
+		setString("pre_spawn", h.PreSpawn)
+		setString("post_spawn", h.PostSpawn)
+	}
+	if b := cfg.PromptBudget; b != nil {
+		setInt("prompt_budget_total", b.Total)
+		setInt("prompt_budget_base", b.Base)
+		setInt("prompt_budget_docs", b.Docs)
+		setInt("prompt_budget_commands", b.Commands)
+		setInt("prompt_budget_custom", b.Custom)
+	}
+	if h := cfg.WorkHours; h != nil {
+		if h.Hours != "" {
+			start, end, err := workhours.ParseHours(h.Hours)
+			if err != nil {
+				return nil, fmt.Errorf("work_hours.hours: %w", err)
+			}
+			args["work_hours_start"] = start
+			args["work_hours_end"] = end
+		}
+		if h.Days != "" {
+			days, err := workhours.ParseDays(h.Days)
+			if err != nil {
+				return nil, fmt.Errorf("work_hours.days: %w", err)
+			}
+			args["work_hours_days"] = days
+		}
+		setString("work_hours_timezone", h.Timezone)
+	}
+	if cfg.Routing != nil {
+		rules := make([]map[string]interface{}, len(cfg.Routing))
+		for i, rule := range cfg.Routing {
+			rules[i] = map[string]interface{}{
+				"contains": rule.Contains,
+				"from":     rule.From,
+				"to":       rule.To,
+				"cc":       rule.CC,
+				"priority": rule.Priority,
+			}
+		}
+		args["routing_rules"] = rules
+	}
+
+	// Round-trip through JSON so numbers and lists have the types the
+	// update_repo_config handler expects from the socket
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	decoded := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// applyRepoConfigFile applies a repository's checked-in
+// .multiclaude/config.yaml, when its clone has one that changed since it was
+// last applied. Settings the file leaves out keep their current values, and
+// `multiclaude config` changes last until the file changes again. An invalid
+// file is reported and skipped.
+func (d *Daemon) applyRepoConfigFile(repoName string) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists || repo.Solo {
+		return
+	}
+	path := filepath.Join(d.paths.RepoDir(repoName), repoconfig.Path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			d.subsystemWarn("repo config", "Failed to read %s: %v", path, err)
+		}
+		return
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if hash == repo.ConfigFileHash {
+		return
+	}
+
+	cfg, err := repoconfig.Parse(data)
+	if verr, ok := err.(*repoconfig.ValidationError); ok {
+		verr.File = path
+	}
+	var args map[string]interface{}
+	if err == nil {
+		args, err = repoConfigArgs(cfg)
+	}
+	if err != nil {
+		d.subsystemWarn("repo config", "Not applying %s: %v", path, err)
+		return
+	}
+	args["name"] = repoName
+	resp := d.handleUpdateRepoConfig(socket.Request{Command: "update_repo_config", Args: args})
+	if !resp.Success {
+		d.subsystemWarn("repo config", "Not applying %s: %s", path, resp.Error)
+		return
+	}
+	if err := d.state.UpdateConfigFileHash(repoName, hash); err != nil {
+		d.subsystemWarn("repo config", "Failed to record %s as applied: %v", path, err)
+		return
+	}
+	d.logger.Info("Applied %s to repo %s", repoconfig.Path, repoName)
+}
+
+// repoConfigInterval is how often repositories' config files are checked for
+// changes
+const repoConfigInterval = 30 * time.Second
+
+// repoConfigLoop applies repositories' config files when they change, so an
+// edit or a pull that changes one takes effect without a restart
+func (d *Daemon) repoConfigLoop() {
+	defer d.wg.Done()
+	d.logger.Info("Starting repo config loop")
+
+	ticker := time.NewTicker(repoConfigInterval)
+	defer ticker.Stop()
+	d.debug.expect("repo config", repoConfigInterval, time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			d.debug.time("repo config", d.applyRepoConfigFiles)
+		case <-d.ctx.Done():
+			d.logger.Info("repo config loop stopped")
+			return
+		}
+	}
+}
+
+// applyRepoConfigFiles applies each repository's config file that changed
+func (d *Daemon) applyRepoConfigFiles() {
+	for repoName := range d.state.GetAllRepos() {
+		d.applyRepoConfigFile(repoName)
+	}
+}
@@ -0,0 +1,313 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// reviewerAvailable reports whether a review agent can take reviews: it's
+// running, not paused, and hasn't completed
+func reviewerAvailable(agent state.Agent) bool {
+	if agent.Type != state.AgentTypeReview || agent.Paused || agent.ReadyForCleanup {
+		return false
+	}
+	return agent.PID == 0 || isProcessAlive(agent.PID)
+}
+
+// availableReviewers returns the names of the review agents that can take
+// reviews, sorted so round-robin goes around them in a stable order
+func availableReviewers(agents map[string]state.Agent) []string {
+	var names []string
+	for name, agent := range agents {
+		if reviewerAvailable(agent) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pickReviewer returns who the next review goes to, or "" when no one can
+// take it. Round-robin takes the reviewer after the last one assigned;
+// least-loaded the one with the fewest reviews queued, the first by name on
+// a tie.
+func pickReviewer(dispatch state.ReviewDispatch, available []string, queue state.ReviewQueue) string {
+	if len(available) == 0 {
+		return ""
+	}
+	if dispatch.Effective() == state.ReviewDispatchLeastLoaded {
+		best := available[0]
+		for _, name := range available[1:] {
+			if queue.Depth(name) < queue.Depth(best) {
+				best = name
+			}
+		}
+		return best
+	}
+	for _, name := range available {
+		if name > queue.Last {
+			return name
+		}
+	}
+	return available[0]
+}
+
+// assignReview gives review i of the queue to the reviewer dispatch picks,
+// and returns who that is
+func assignReview(dispatch state.ReviewDispatch, available []string, queue *state.ReviewQueue, i int, now time.Time) string {
+	reviewer := pickReviewer(dispatch, available, *queue)
+	if reviewer == "" {
+		return ""
+	}
+	queue.Reviews[i].Reviewer = reviewer
+	queue.Reviews[i].AssignedAt = now
+	queue.Last = reviewer
+	return reviewer
+}
+
+// balanceReviews moves reviews off reviewers that are gone, paused or
+// crashed, and gives reviews nobody has to reviewers that can take them. A
+// review stays with a reviewer that's still there when no one else can take
+// it, so it isn't bounced around while, say, every agent is paused. It
+// returns the reviews it assigned, with the reviewer each one came from.
+func balanceReviews(dispatch state.ReviewDispatch, agents map[string]state.Agent, queue *state.ReviewQueue, now time.Time) (moved []state.ReviewAssignment, from []string) {
+	available := availableReviewers(agents)
+	for i, review := range queue.Reviews {
+		if review.Reviewer != "" && containsString(available, review.Reviewer) {
+			continue
+		}
+		_, stillThere := agents[review.Reviewer]
+		if review.Reviewer != "" && stillThere && len(available) == 0 {
+			continue
+		}
+		if assignReview(dispatch, available, queue, i, now) == "" {
+			queue.Reviews[i].Reviewer = ""
+			continue
+		}
+		moved = append(moved, queue.Reviews[i])
+		from = append(from, review.Reviewer)
+	}
+	return moved, from
+}
+
+// reviewRequestMessage asks a reviewer to review a PR it has been assigned
+func reviewRequestMessage(review state.ReviewAssignment, depth int) string {
+	return fmt.Sprintf("Review PR #%d: %s\n\nYou have %d review(s) queued; take them in order. When you've reported this one to the merge queue, run `multiclaude review done %d`.", review.PRNumber, review.PRURL, depth, review.PRNumber)
+}
+
+// handleRequestReview dispatches a PR review to one of a repository's
+// review agents and messages it the PR. With reviewer, the review is only
+// recorded as that reviewer's, for one started to review it. When no
+// reviewer can take it, nothing is queued and assigned is false, so the
+// caller can start a reviewer.
+func (d *Daemon) handleRequestReview(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	prURL, errResp, ok := getRequiredStringArg(req.Args, "pr_url", "pr_url is required")
+	if !ok {
+		return errResp
+	}
+	number, _ := req.Args["pr_number"].(float64)
+	if number <= 0 {
+		return socket.Response{Success: false, Error: "pr_number is required"}
+	}
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+	pinned, _ := req.Args["reviewer"].(string)
+
+	var review state.ReviewAssignment
+	var queued bool
+	var depth int
+	now := time.Now()
+	err := d.state.UpdateReviewQueue(repoName, func(queue *state.ReviewQueue, agents map[string]state.Agent) error {
+		i := queue.Find(int(number))
+		if i >= 0 && queue.Reviews[i].Reviewer != "" && pinned == "" {
+			review, queued = queue.Reviews[i], true
+			depth = queue.Depth(review.Reviewer)
+			return nil
+		}
+		added := i < 0
+		if added {
+			queue.Reviews = append(queue.Reviews, state.ReviewAssignment{PRNumber: int(number), PRURL: prURL, RequestedAt: now})
+			i = len(queue.Reviews) - 1
+		}
+		if pinned != "" {
+			if agent, ok := agents[pinned]; !ok || agent.Type != state.AgentTypeReview {
+				return errors.AgentNotFound("reviewer", pinned, repoName)
+			}
+			queue.Reviews[i].Reviewer = pinned
+			queue.Reviews[i].AssignedAt = now
+			queue.Last = pinned
+		} else if assignReview(repo.ReviewDispatch, availableReviewers(agents), queue, i, now) == "" {
+			// A new request isn't queued; one already waiting keeps waiting
+			if added {
+				queue.Reviews = queue.Reviews[:i]
+			}
+			return nil
+		}
+		review = queue.Reviews[i]
+		depth = queue.Depth(review.Reviewer)
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if review.Reviewer != "" && !queued && pinned == "" {
+		if _, err := d.getMessageManager().Send(repoName, "daemon", review.Reviewer, reviewRequestMessage(review, depth)); err != nil {
+			d.logger.Warn("Failed to send %s/%s its review of PR #%d: %v", repoName, review.Reviewer, review.PRNumber, err)
+		} else {
+			go d.routeMessages()
+		}
+		d.logger.WithTrace(req.TraceID).Info("Assigned review of PR #%d in %s to %s (%d queued)", review.PRNumber, repoName, review.Reviewer, depth)
+	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"assigned":       review.Reviewer != "",
+		"reviewer":       review.Reviewer,
+		"queue_depth":    depth,
+		"already_queued": queued,
+	}}
+}
+
+// handleReviewDone takes a finished review off the review queue
+func (d *Daemon) handleReviewDone(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	number, _ := req.Args["pr_number"].(float64)
+	if number <= 0 {
+		return socket.Response{Success: false, Error: "pr_number is required"}
+	}
+	if _, exists := d.state.GetRepo(repoName); !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+
+	var reviewer string
+	var remaining int
+	err := d.state.UpdateReviewQueue(repoName, func(queue *state.ReviewQueue, agents map[string]state.Agent) error {
+		i := queue.Find(int(number))
+		if i < 0 {
+			return fmt.Errorf("no review of PR #%d is queued in %s", int(number), repoName)
+		}
+		reviewer = queue.Reviews[i].Reviewer
+		queue.Reviews = append(queue.Reviews[:i], queue.Reviews[i+1:]...)
+		remaining = queue.Depth(reviewer)
+		return nil
+	})
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.WithTrace(req.TraceID).Info("Review of PR #%d in %s done (%s has %d left)", int(number), repoName, reviewer, remaining)
+	return socket.Response{Success: true, Data: map[string]interface{}{"reviewer": reviewer, "remaining": remaining}}
+}
+
+// handleReviewQueue lists a repository's queued reviews and how deep each
+// reviewer's queue is
+func (d *Daemon) handleReviewQueue(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+	repos := d.state.GetAllRepos()
+	queue := repos[repoName].ReviewQueue
+
+	reviews := make([]map[string]interface{}, 0, len(queue.Reviews))
+	for _, r := range queue.Reviews {
+		reviews = append(reviews, map[string]interface{}{
+			"pr_number":    r.PRNumber,
+			"pr_url":       r.PRURL,
+			"reviewer":     r.Reviewer,
+			"requested_at": r.RequestedAt,
+		})
+	}
+	var names []string
+	for name, agent := range repos[repoName].Agents {
+		if agent.Type == state.AgentTypeReview {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	reviewers := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		reviewers = append(reviewers, map[string]interface{}{
+			"name":      name,
+			"depth":     queue.Depth(name),
+			"available": reviewerAvailable(repos[repoName].Agents[name]),
+		})
+	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"dispatch":  string(repo.ReviewDispatch.Effective()),
+		"reviews":   reviews,
+		"reviewers": reviewers,
+	}}
+}
+
+// finishCurrentReview is called when a reviewer completes: the review it
+// was on, the oldest it has, is done. The health check that follows a
+// completion moves the rest to other reviewers.
+func (d *Daemon) finishCurrentReview(repoName, reviewer string) {
+	err := d.state.UpdateReviewQueue(repoName, func(queue *state.ReviewQueue, agents map[string]state.Agent) error {
+		for i, r := range queue.Reviews {
+			if r.Reviewer == reviewer {
+				queue.Reviews = append(queue.Reviews[:i], queue.Reviews[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		d.logger.Warn("Failed to update the review queue of %s: %v", repoName, err)
+	}
+}
+
+// rebalanceReviews moves queued reviews off reviewers that are gone, paused
+// or crashed, and messages the reviewers that take them over
+func (d *Daemon) rebalanceReviews() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if len(repo.ReviewQueue.Reviews) == 0 {
+			continue
+		}
+		var moved []state.ReviewAssignment
+		var from []string
+		var depths map[string]int
+		err := d.state.UpdateReviewQueue(repoName, func(queue *state.ReviewQueue, agents map[string]state.Agent) error {
+			moved, from = balanceReviews(repo.ReviewDispatch, agents, queue, time.Now())
+			depths = make(map[string]int)
+			for _, r := range moved {
+				depths[r.Reviewer] = queue.Depth(r.Reviewer)
+			}
+			return nil
+		})
+		if err != nil {
+			d.logger.Warn("Failed to rebalance reviews of %s: %v", repoName, err)
+			continue
+		}
+		for i, review := range moved {
+			if from[i] == "" {
+				d.logger.Info("Assigned waiting review of PR #%d in %s to %s", review.PRNumber, repoName, review.Reviewer)
+			} else {
+				d.logger.Info("Moved review of PR #%d in %s from %s to %s", review.PRNumber, repoName, from[i], review.Reviewer)
+			}
+			if _, err := d.getMessageManager().Send(repoName, "daemon", review.Reviewer, reviewRequestMessage(review, depths[review.Reviewer])); err != nil {
+				d.logger.Warn("Failed to send %s/%s its review of PR #%d: %v", repoName, review.Reviewer, review.PRNumber, err)
+			}
+		}
+		if len(moved) > 0 {
+			go d.routeMessages()
+		}
+	}
+}
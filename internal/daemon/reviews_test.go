@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestPickReviewer(t *testing.T) {
+	available := []string{"review-a", "review-b", "review-c"}
+	queue := state.ReviewQueue{
+		Reviews: []state.ReviewAssignment{{PRNumber: 1, Reviewer: "review-a"}, {PRNumber: 2, Reviewer: "review-a"}, {PRNumber: 3, Reviewer: "review-c"}},
+		Last:    "review-c",
+	}
+
+	// Round-robin goes on from the last reviewer, wrapping around
+	if got := pickReviewer(state.ReviewDispatchRoundRobin, available, queue); got != "review-a" {
+		t.Errorf("round-robin after review-c = %q, want review-a", got)
+	}
+	queue.Last = "review-a"
+	if got := pickReviewer("", available, queue); got != "review-b" {
+		t.Errorf("round-robin after review-a = %q, want review-b", got)
+	}
+	// ...and past a reviewer that has gone
+	queue.Last = "review-bb"
+	if got := pickReviewer("", available, queue); got != "review-c" {
+		t.Errorf("round-robin after a gone reviewer = %q, want review-c", got)
+	}
+
+	if got := pickReviewer(state.ReviewDispatchLeastLoaded, available, queue); got != "review-b" {
+		t.Errorf("least-loaded = %q, want review-b, which has none queued", got)
+	}
+	if got := pickReviewer(state.ReviewDispatchLeastLoaded, nil, queue); got != "" {
+		t.Errorf("pickReviewer() with no reviewers = %q", got)
+	}
+}
+
+func TestBalanceReviews(t *testing.T) {
+	agents := map[string]state.Agent{
+		"review-a": {Type: state.AgentTypeReview},
+		"review-b": {Type: state.AgentTypeReview, Paused: true},
+		"review-c": {Type: state.AgentTypeReview},
+		"calm-owl": {Type: state.AgentTypeWorker},
+	}
+	queue := &state.ReviewQueue{
+		Reviews: []state.ReviewAssignment{
+			{PRNumber: 1, Reviewer: "review-a"},
+			{PRNumber: 2, Reviewer: "review-b"}, // paused
+			{PRNumber: 3, Reviewer: "review-d"}, // gone
+			{PRNumber: 4},                       // waiting
+		},
+		Last: "review-a",
+	}
+
+	moved, from := balanceReviews(state.ReviewDispatchRoundRobin, agents, queue, time.Now())
+	if len(moved) != 3 {
+		t.Fatalf("balanceReviews() moved %d reviews, want 3: %+v", len(moved), moved)
+	}
+	want := map[int]string{1: "review-a", 2: "review-c", 3: "review-a", 4: "review-c"}
+	for _, r := range queue.Reviews {
+		if r.Reviewer != want[r.PRNumber] {
+			t.Errorf("PR #%d went to %q, want %q", r.PRNumber, r.Reviewer, want[r.PRNumber])
+		}
+	}
+	if from[0] != "review-b" || from[1] != "review-d" || from[2] != "" {
+		t.Errorf("from = %v", from)
+	}
+
+	// With no one to take them, reviews stay with a reviewer that's still
+	// there and wait when theirs is gone
+	paused := map[string]state.Agent{"review-b": {Type: state.AgentTypeReview, Paused: true}}
+	queue = &state.ReviewQueue{Reviews: []state.ReviewAssignment{{PRNumber: 2, Reviewer: "review-b"}, {PRNumber: 3, Reviewer: "review-d"}}}
+	if moved, _ := balanceReviews("", paused, queue, time.Now()); len(moved) != 0 {
+		t.Errorf("balanceReviews() moved %+v with no reviewer available", moved)
+	}
+	if queue.Reviews[0].Reviewer != "review-b" || queue.Reviews[1].Reviewer != "" {
+		t.Errorf("reviews = %+v, want #2 kept by review-b and #3 waiting", queue.Reviews)
+	}
+}
+
+func TestHandleRequestReview(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		TmuxSession: "test-session",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	request := func(number int, reviewer string) map[string]interface{} {
+		t.Helper()
+		args := map[string]interface{}{"repo": "test-repo", "pr_url": "https://github.com/test/repo/pull/1", "pr_number": float64(number)}
+		if reviewer != "" {
+			args["reviewer"] = reviewer
+		}
+		resp := d.handleRequestReview(socket.Request{Command: "request_review", Args: args})
+		if !resp.Success {
+			t.Fatalf("request_review failed: %s", resp.Error)
+		}
+		return resp.Data.(map[string]interface{})
+	}
+
+	// No reviewers: nothing is queued, so the caller starts one
+	if data := request(1, ""); data["assigned"] != false {
+		t.Errorf("request_review with no reviewers = %v", data)
+	}
+	repo, _ := d.state.GetRepo("test-repo")
+	if len(repo.ReviewQueue.Reviews) != 0 {
+		t.Errorf("queue = %+v, want it empty", repo.ReviewQueue)
+	}
+
+	for _, name := range []string{"review-1", "review-2"} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: state.AgentTypeReview, TmuxWindow: name, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+	if data := request(1, "review-1"); data["reviewer"] != "review-1" {
+		t.Errorf("pinned request_review = %v", data)
+	}
+	if data := request(2, ""); data["reviewer"] != "review-2" || data["queue_depth"] != 1 {
+		t.Errorf("request_review = %v, want review-2 with 1 queued", data)
+	}
+	if data := request(3, ""); data["reviewer"] != "review-1" || data["queue_depth"] != 2 {
+		t.Errorf("request_review = %v, want review-1 with 2 queued", data)
+	}
+	if data := request(2, ""); data["already_queued"] != true || data["reviewer"] != "review-2" {
+		t.Errorf("repeated request_review = %v, want the existing assignment", data)
+	}
+
+	resp := d.handleReviewDone(socket.Request{Command: "review_done", Args: map[string]interface{}{"repo": "test-repo", "pr_number": float64(1)}})
+	if !resp.Success || resp.Data.(map[string]interface{})["remaining"] != 1 {
+		t.Errorf("review_done = %+v", resp)
+	}
+	if resp := d.handleReviewDone(socket.Request{Command: "review_done", Args: map[string]interface{}{"repo": "test-repo", "pr_number": float64(1)}}); resp.Success {
+		t.Error("review_done of a review that isn't queued should fail")
+	}
+
+	// A reviewer that completes is done with its oldest review; the rest
+	// move to the others
+	d.finishCurrentReview("test-repo", "review-2")
+	agent, _ := d.state.GetAgent("test-repo", "review-1")
+	agent.ReadyForCleanup = true
+	if err := d.state.UpdateAgent("test-repo", "review-1", agent); err != nil {
+		t.Fatalf("UpdateAgent failed: %v", err)
+	}
+	d.rebalanceReviews()
+	repo, _ = d.state.GetRepo("test-repo")
+	if len(repo.ReviewQueue.Reviews) != 1 || repo.ReviewQueue.Reviews[0].PRNumber != 3 || repo.ReviewQueue.Reviews[0].Reviewer != "review-2" {
+		t.Errorf("queue after rebalancing = %+v, want #3 moved to review-2", repo.ReviewQueue.Reviews)
+	}
+
+	data := d.handleReviewQueue(socket.Request{Command: "review_queue", Args: map[string]interface{}{"repo": "test-repo"}}).Data.(map[string]interface{})
+	if data["dispatch"] != "round-robin" || len(data["reviews"].([]map[string]interface{})) != 1 {
+		t.Errorf("review_queue = %v", data)
+	}
+}
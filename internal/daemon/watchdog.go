@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// WatchAction is what a watchdog check did
+type WatchAction string
+
+const (
+	// WatchOK means the daemon is running and answering
+	WatchOK WatchAction = "ok"
+	// WatchStarted means the daemon wasn't running and was started
+	WatchStarted WatchAction = "started"
+	// WatchUnresponsive means the daemon is running but didn't answer a ping
+	WatchUnresponsive WatchAction = "unresponsive"
+	// WatchRestarted means an unresponsive daemon was killed and started again
+	WatchRestarted WatchAction = "restarted"
+)
+
+// pingTimeout is how long the watchdog waits for the daemon to answer a ping
+const pingTimeout = 10 * time.Second
+
+// Watchdog keeps the daemon running. It checks on it periodically and starts
+// it again if it has died, or kills and restarts it if it stops answering on
+// its socket. A restarted daemon restores agents' sessions like any other start.
+type Watchdog struct {
+	Paths    *config.Paths
+	Interval time.Duration
+	// MaxUnresponsive is how many consecutive pings a running daemon may miss
+	// before it is killed and restarted
+	MaxUnresponsive int
+	Start           func() error        // Starts a detached daemon
+	Kill            func(pid int) error // Stops a hung daemon
+	Logf            func(format string, args ...interface{})
+
+	unresponsive int
+}
+
+// NewWatchdog creates a watchdog that checks every 30 seconds
+func NewWatchdog(paths *config.Paths) *Watchdog {
+	return &Watchdog{
+		Paths:           paths,
+		Interval:        30 * time.Second,
+		MaxUnresponsive: 3,
+		Start:           RunDetached,
+		Kill:            killDaemon,
+		Logf:            func(format string, args ...interface{}) { fmt.Printf(format+"\n", args...) },
+	}
+}
+
+// Check checks on the daemon once, starting or restarting it if needed
+func (w *Watchdog) Check() (WatchAction, error) {
+	pidFile := NewPIDFile(w.Paths.DaemonPID)
+	running, pid, err := pidFile.IsRunning()
+	if err != nil {
+		return "", fmt.Errorf("failed to check daemon: %w", err)
+	}
+
+	if !running {
+		w.unresponsive = 0
+		w.Logf("%s daemon is not running; starting it", time.Now().Format(time.RFC3339))
+		if err := w.Start(); err != nil {
+			return "", fmt.Errorf("failed to start daemon: %w", err)
+		}
+		return WatchStarted, nil
+	}
+
+	client := socket.NewClient(w.Paths.DaemonSock)
+	if resp, err := client.SendTimeout(socket.Request{Command: "ping"}, pingTimeout); err == nil && resp.Success {
+		w.unresponsive = 0
+		return WatchOK, nil
+	}
+
+	w.unresponsive++
+	if w.unresponsive < w.MaxUnresponsive {
+		w.Logf("%s daemon (PID %d) did not answer a ping (%d/%d)", time.Now().Format(time.RFC3339), pid, w.unresponsive, w.MaxUnresponsive)
+		return WatchUnresponsive, nil
+	}
+
+	w.unresponsive = 0
+	w.Logf("%s daemon (PID %d) is unresponsive; restarting it", time.Now().Format(time.RFC3339), pid)
+	if err := w.Kill(pid); err != nil {
+		return "", fmt.Errorf("failed to stop unresponsive daemon (PID %d): %w", pid, err)
+	}
+	if err := pidFile.Remove(); err != nil {
+		return "", err
+	}
+	if err := w.Start(); err != nil {
+		return "", fmt.Errorf("failed to restart daemon: %w", err)
+	}
+	return WatchRestarted, nil
+}
+
+// Run checks on the daemon every Interval until ctx is cancelled. Failed
+// checks are logged and retried on the next tick.
+func (w *Watchdog) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Check(); err != nil {
+			w.Logf("%s %v", time.Now().Format(time.RFC3339), err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// killDaemon sends SIGTERM to a hung daemon, then SIGKILL if it hasn't exited
+// after a few seconds
+func killDaemon(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return nil // Already gone
+	}
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if process.Signal(syscall.Signal(0)) != nil {
+			return nil
+		}
+	}
+	return process.Signal(syscall.SIGKILL)
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+func TestWatchdogCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &config.Paths{
+		DaemonPID:  filepath.Join(tmpDir, "daemon.pid"),
+		DaemonSock: filepath.Join(tmpDir, "daemon.sock"),
+	}
+
+	var starts, kills []int
+	w := NewWatchdog(paths)
+	w.Logf = t.Logf
+	w.Start = func() error {
+		starts = append(starts, len(starts))
+		return nil
+	}
+	w.Kill = func(pid int) error {
+		kills = append(kills, pid)
+		return nil
+	}
+
+	// No daemon: start one
+	if action, err := w.Check(); err != nil || action != WatchStarted {
+		t.Fatalf("Check() with no daemon = %q, %v; want started", action, err)
+	}
+
+	// A live process that doesn't answer pings is restarted after MaxUnresponsive misses.
+	// This test process stands in for a hung daemon; Kill is faked.
+	if err := NewPIDFile(paths.DaemonPID).Write(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < w.MaxUnresponsive; i++ {
+		if action, err := w.Check(); err != nil || action != WatchUnresponsive {
+			t.Fatalf("Check() miss %d = %q, %v; want unresponsive", i, action, err)
+		}
+	}
+	if action, err := w.Check(); err != nil || action != WatchRestarted {
+		t.Fatalf("Check() after %d misses = %q, %v; want restarted", w.MaxUnresponsive, action, err)
+	}
+	if len(kills) != 1 || kills[0] != os.Getpid() {
+		t.Errorf("kills = %v, want the hung daemon's PID", kills)
+	}
+	if len(starts) != 2 {
+		t.Errorf("starts = %d, want 2", len(starts))
+	}
+	if _, err := os.Stat(paths.DaemonPID); !os.IsNotExist(err) {
+		t.Error("the hung daemon's PID file should be removed before restarting")
+	}
+
+	// A daemon that answers is left alone and resets the miss count
+	if err := NewPIDFile(paths.DaemonPID).Write(); err != nil {
+		t.Fatal(err)
+	}
+	server := socket.NewServer(paths.DaemonSock, socket.HandlerFunc(func(req socket.Request) socket.Response {
+		return socket.Response{Success: true, Data: "pong"}
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start socket server: %v", err)
+	}
+	go server.Serve()
+	defer server.Stop()
+
+	if action, err := w.Check(); err != nil || action != WatchOK {
+		t.Errorf("Check() with a healthy daemon = %q, %v; want ok", action, err)
+	}
+	if len(starts) != 2 || len(kills) != 1 {
+		t.Errorf("a healthy daemon should not be started or killed (starts=%d, kills=%d)", len(starts), len(kills))
+	}
+}
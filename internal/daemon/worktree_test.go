@@ -1,12 +1,17 @@
 package daemon
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/micheal-at/multiclaude/internal/daemonconfig"
+	"github.com/micheal-at/multiclaude/internal/mirror"
+	"github.com/micheal-at/multiclaude/internal/socket"
 	"github.com/micheal-at/multiclaude/internal/state"
 	"github.com/micheal-at/multiclaude/pkg/config"
 )
@@ -513,6 +518,65 @@ func TestCleanupOrphanedWorktrees_WithActiveWorktrees(t *testing.T) {
 	}
 }
 
+func TestMigrateWorktrees(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{TmuxSession: "mc-test-repo", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	oldPath := d.paths.AgentWorktree("test-repo", "calm-owl")
+	cmd := exec.Command("git", "worktree", "add", "-b", "work/calm-owl", oldPath, "main")
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create worktree: %v\n%s", err, out)
+	}
+	d.state.AddAgent("test-repo", "calm-owl", state.Agent{Type: state.AgentTypeWorker, WorktreePath: oldPath, TmuxWindow: "calm-owl"})
+	// Agents working in the clone itself stay where they are
+	d.state.AddAgent("test-repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor, WorktreePath: repoDir, TmuxWindow: "supervisor"})
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "worktree_dir": "scratch"}})
+	if resp.Success || !strings.Contains(resp.Error, "absolute path") {
+		t.Errorf("relative worktree_dir = %+v, want an absolute path error", resp)
+	}
+	base := filepath.Join(d.paths.Root, "scratch")
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "worktree_dir": base}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	newPath := filepath.Join(base, "test-repo", "calm-owl")
+	if got := d.paths.AgentWorktree("test-repo", "calm-owl"); got != newPath {
+		t.Errorf("AgentWorktree() = %q, want %q", got, newPath)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "migrate_worktrees", Args: map[string]interface{}{"repo": "test-repo", "dry_run": true}})
+	results, _ := resp.Data.([]map[string]interface{})
+	if !resp.Success || len(results) != 1 || results[0]["status"] != migrationPlanned || results[0]["to"] != newPath {
+		t.Fatalf("dry run = %+v, want calm-owl planned", resp)
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Errorf("dry run moved the worktree: %v", err)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "migrate_worktrees", Args: map[string]interface{}{"repo": "test-repo"}})
+	results, _ = resp.Data.([]map[string]interface{})
+	if !resp.Success || len(results) != 1 || results[0]["status"] != migrationMoved {
+		t.Fatalf("migrate_worktrees = %+v, want calm-owl moved", resp)
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "calm-owl"); agent.WorktreePath != newPath {
+		t.Errorf("agent worktree path = %q, want %q", agent.WorktreePath, newPath)
+	}
+	if _, err := os.Stat(filepath.Dir(oldPath)); !os.IsNotExist(err) {
+		t.Errorf("old worktree directory left behind: %v", err)
+	}
+
+	// Nothing left to move
+	resp = d.handleRequest(socket.Request{Command: "migrate_worktrees", Args: map[string]interface{}{"repo": "test-repo"}})
+	if results, _ := resp.Data.([]map[string]interface{}); len(results) != 0 {
+		t.Errorf("second migration = %+v, want nothing", results)
+	}
+}
+
 func TestCleanupMergedBranches_WithLocalClone(t *testing.T) {
 	d, _, cleanup := setupTestDaemonWithGitRepo(t)
 	defer cleanup()
@@ -530,3 +594,432 @@ func TestCleanupMergedBranches_WithLocalClone(t *testing.T) {
 	// Should not panic - the repo has no remote so it will skip cleanup
 	d.cleanupMergedBranches()
 }
+
+func TestRefreshWorktrees_RebasesWorkersInParallel(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	// Give the repo an origin, and a second clone to push upstream changes from
+	tmp := t.TempDir()
+	origin := filepath.Join(tmp, "origin.git")
+	git(tmp, "init", "--bare", "-b", "main", origin)
+	git(repoDir, "remote", "add", "origin", origin)
+	git(repoDir, "push", "-q", "origin", "main")
+	git(repoDir, "fetch", "-q", "origin")
+
+	repo := &state.Repository{
+		GithubURL:    "https://github.com/test/repo",
+		TmuxSession:  "test-session",
+		Agents:       make(map[string]state.Agent),
+		TargetBranch: "main",
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// More workers than refresh slots, each with a commit of its own
+	workers := daemonconfig.Default().Limits.RefreshConcurrency * 2
+	for i := 0; i < workers; i++ {
+		name := fmt.Sprintf("worker-%d", i)
+		wtPath := filepath.Join(tmp, name)
+		git(repoDir, "worktree", "add", "-q", "-b", "work/"+name, wtPath, "main")
+		git(wtPath, "config", "user.email", "test@example.com")
+		git(wtPath, "config", "user.name", "Test User")
+		if err := os.WriteFile(filepath.Join(wtPath, name+".txt"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(wtPath, "add", ".")
+		git(wtPath, "commit", "-q", "-m", name)
+
+		if err := d.state.AddAgent("test-repo", name, state.Agent{
+			Type:         state.AgentTypeWorker,
+			WorktreePath: wtPath,
+			TmuxWindow:   name,
+			CreatedAt:    time.Now(),
+		}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+
+	// And an observer, reading a detached worktree
+	observerPath := d.paths.AgentWorktree("test-repo", "observer")
+	git(repoDir, "worktree", "add", "-q", "--detach", observerPath, "main")
+	if err := d.state.AddAgent("test-repo", "observer", state.Agent{
+		Type:         state.AgentTypeObserver,
+		WorktreePath: observerPath,
+		TmuxWindow:   "observer",
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	upstream := filepath.Join(tmp, "upstream")
+	git(tmp, "clone", "-q", origin, upstream)
+	git(upstream, "config", "user.email", "test@example.com")
+	git(upstream, "config", "user.name", "Test User")
+	if err := os.WriteFile(filepath.Join(upstream, "upstream.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(upstream, "add", ".")
+	git(upstream, "commit", "-q", "-m", "upstream change")
+	git(upstream, "push", "-q", "origin", "main")
+
+	d.refreshWorktrees()
+
+	for i := 0; i < workers; i++ {
+		name := fmt.Sprintf("worker-%d", i)
+		wtPath := filepath.Join(tmp, name)
+		if _, err := os.Stat(filepath.Join(wtPath, "upstream.txt")); err != nil {
+			t.Errorf("%s was not rebased onto origin/main", name)
+		}
+		if _, err := os.Stat(filepath.Join(wtPath, name+".txt")); err != nil {
+			t.Errorf("%s lost its own commit", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(observerPath, "upstream.txt")); err != nil {
+		t.Error("the observer's worktree was not moved to origin/main")
+	}
+}
+
+func TestSyncMirror(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:      repoDir,
+		TmuxSession:    "test-session",
+		Agents:         make(map[string]state.Agent),
+		WorktreeConfig: state.WorktreeConfig{Mirror: true},
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	slots := make(chan struct{}, 1)
+	mirrorDir := d.paths.MirrorDir("test-repo")
+
+	d.syncMirror("test-repo", repo, slots)
+	if !mirror.Exists(mirrorDir) {
+		t.Fatal("syncMirror() didn't clone the mirror")
+	}
+
+	// An observer's worktree follows the default branch
+	wtPath := d.paths.MirrorWorktree("test-repo", "observer")
+	if err := mirror.AddWorktree(mirrorDir, wtPath, "main"); err != nil {
+		t.Fatalf("AddWorktree() error: %v", err)
+	}
+	repo.Agents["observer"] = state.Agent{Type: state.AgentTypeObserver, WorktreePath: wtPath}
+	os.WriteFile(filepath.Join(repoDir, "NEWS"), []byte("news\n"), 0644)
+	exec.Command("git", "-C", repoDir, "add", "NEWS").Run()
+	if err := exec.Command("git", "-C", repoDir, "commit", "-m", "News").Run(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	d.syncMirror("test-repo", repo, slots)
+	if _, err := os.Stat(filepath.Join(wtPath, "NEWS")); err != nil {
+		t.Errorf("observer worktree wasn't moved to the new commit: %v", err)
+	}
+
+	// Turned off, the mirror stays while the observer uses it
+	repo.WorktreeConfig.Mirror = false
+	d.syncMirror("test-repo", repo, slots)
+	if !mirror.Exists(mirrorDir) {
+		t.Fatal("the mirror was removed while an observer works in it")
+	}
+	delete(repo.Agents, "observer")
+	d.syncMirror("test-repo", repo, slots)
+	if mirror.Exists(mirrorDir) {
+		t.Error("the mirror was kept after it was turned off and left unused")
+	}
+	if _, err := os.Stat(d.paths.MirrorWorktreeDir("test-repo")); !os.IsNotExist(err) {
+		t.Error("the mirror worktrees were kept")
+	}
+}
+
+func TestRefreshWorktrees_Strategies(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	tmp := t.TempDir()
+	origin := filepath.Join(tmp, "origin.git")
+	git(tmp, "init", "--bare", "-b", "main", origin)
+	git(repoDir, "remote", "add", "origin", origin)
+	git(repoDir, "push", "-q", "origin", "main")
+	git(repoDir, "fetch", "-q", "origin")
+
+	// Workers merge by default; one rebases instead
+	repo := &state.Repository{
+		GithubURL:     "https://github.com/test/repo",
+		TmuxSession:   "test-session",
+		Agents:        make(map[string]state.Agent),
+		TargetBranch:  "main",
+		RefreshConfig: state.RefreshConfig{Strategy: state.RefreshMerge},
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	workers := map[string]*state.RefreshConfig{
+		"merger":  nil,
+		"rebaser": {Strategy: state.RefreshRebase},
+		"fetcher": {Strategy: state.RefreshFetch},
+		"idle":    {Strategy: state.RefreshOff},
+		"busy":    {Strategy: state.RefreshRebase, PauseActive: true},
+	}
+	for name, refresh := range workers {
+		wtPath := filepath.Join(tmp, name)
+		git(repoDir, "worktree", "add", "-q", "-b", "work/"+name, wtPath, "main")
+		git(wtPath, "config", "user.email", "test@example.com")
+		git(wtPath, "config", "user.name", "Test User")
+		if err := os.WriteFile(filepath.Join(wtPath, name+".txt"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(wtPath, "add", ".")
+		git(wtPath, "commit", "-q", "-m", name)
+
+		if err := d.state.AddAgent("test-repo", name, state.Agent{
+			Type:         state.AgentTypeWorker,
+			WorktreePath: wtPath,
+			TmuxWindow:   name,
+			CreatedAt:    time.Now(),
+			Refresh:      refresh,
+		}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+	// The busy worker is in the middle of an edit
+	if err := os.WriteFile(filepath.Join(tmp, "busy", "draft.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := filepath.Join(tmp, "upstream")
+	git(tmp, "clone", "-q", origin, upstream)
+	git(upstream, "config", "user.email", "test@example.com")
+	git(upstream, "config", "user.name", "Test User")
+	if err := os.WriteFile(filepath.Join(upstream, "upstream.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(upstream, "add", ".")
+	git(upstream, "commit", "-q", "-m", "upstream change")
+	git(upstream, "push", "-q", "origin", "main")
+
+	d.refreshWorktrees()
+	d.refreshWorktrees()
+
+	synced := func(name string) bool {
+		_, err := os.Stat(filepath.Join(tmp, name, "upstream.txt"))
+		return err == nil
+	}
+	for name, want := range map[string]bool{"merger": true, "rebaser": true, "fetcher": false, "idle": false, "busy": false} {
+		if got := synced(name); got != want {
+			t.Errorf("%s synced = %v, want %v", name, got, want)
+		}
+	}
+	if parents := strings.Fields(git(filepath.Join(tmp, "merger"), "log", "-1", "--format=%P")); len(parents) != 2 {
+		t.Errorf("merger HEAD has parents %v, want a merge commit", parents)
+	}
+	if parents := strings.Fields(git(filepath.Join(tmp, "rebaser"), "log", "-1", "--format=%P")); len(parents) != 1 {
+		t.Errorf("rebaser HEAD has parents %v, want a rebased commit", parents)
+	}
+
+	// The fetch-only worker is told it's behind once, not on every refresh
+	msgs, err := d.getMessageManager().List("test-repo", "fetcher")
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "1 commits behind origin/main") {
+		t.Errorf("fetcher messages = %+v, want one behind notice", msgs)
+	}
+}
+
+func TestHandleSyncAgent(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	tmp := t.TempDir()
+	origin := filepath.Join(tmp, "origin.git")
+	git(tmp, "init", "--bare", "-b", "main", origin)
+	git(repoDir, "remote", "add", "origin", origin)
+	git(repoDir, "push", "-q", "origin", "main")
+	git(repoDir, "fetch", "-q", "origin")
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:     "https://github.com/test/repo",
+		TmuxSession:   "test-session",
+		Agents:        make(map[string]state.Agent),
+		TargetBranch:  "main",
+		RefreshConfig: state.RefreshConfig{Strategy: state.RefreshFetch},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	// One worker adds a file of its own, the other the file upstream adds too
+	for name, file := range map[string]string{"fox": "fox.txt", "clash": "upstream.txt"} {
+		wtPath := filepath.Join(tmp, name)
+		git(repoDir, "worktree", "add", "-q", "-b", "work/"+name, wtPath, "main")
+		git(wtPath, "config", "user.email", "test@example.com")
+		git(wtPath, "config", "user.name", "Test User")
+		if err := os.WriteFile(filepath.Join(wtPath, file), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(wtPath, "add", ".")
+		git(wtPath, "commit", "-q", "-m", name)
+		if err := d.state.AddAgent("test-repo", name, state.Agent{
+			Type:         state.AgentTypeWorker,
+			WorktreePath: wtPath,
+			TmuxWindow:   name,
+			CreatedAt:    time.Now(),
+		}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+
+	upstream := filepath.Join(tmp, "upstream")
+	git(tmp, "clone", "-q", origin, upstream)
+	git(upstream, "config", "user.email", "test@example.com")
+	git(upstream, "config", "user.name", "Test User")
+	if err := os.WriteFile(filepath.Join(upstream, "upstream.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(upstream, "add", ".")
+	git(upstream, "commit", "-q", "-m", "upstream change")
+	git(upstream, "push", "-q", "origin", "main")
+
+	sync := func(agent, strategy string) map[string]interface{} {
+		t.Helper()
+		resp := d.handleSyncAgent(socket.Request{Command: "sync_agent", Args: map[string]interface{}{
+			"repo": "test-repo", "agent": agent, "strategy": strategy,
+		}})
+		if !resp.Success {
+			t.Fatalf("sync_agent %s failed: %s", agent, resp.Error)
+		}
+		return resp.Data.(map[string]interface{})
+	}
+
+	// The repo's fetch strategy only reports how far behind the branch is
+	if data := sync("fox", ""); data["status"] != "behind" || data["commits_behind"] != 1 {
+		t.Errorf("sync with fetch strategy = %+v, want behind by 1", data)
+	}
+	if data := sync("fox", "rebase"); data["status"] != "synced" || data["onto"] != "origin/main" {
+		t.Errorf("sync --strategy rebase = %+v, want synced onto origin/main", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "fox", "upstream.txt")); err != nil {
+		t.Error("fox was not rebased onto origin/main")
+	}
+	if agent, _ := d.state.GetAgent("test-repo", "fox"); agent.LastRefresh.IsZero() {
+		t.Error("LastRefresh was not recorded")
+	}
+	if data := sync("fox", "rebase"); data["status"] != "up_to_date" {
+		t.Errorf("second sync = %+v, want up_to_date", data)
+	}
+
+	// Conflicts are reported and leave the worktree as it was
+	data := sync("clash", "merge")
+	if files, _ := data["conflict_files"].([]string); data["status"] != "conflicts" || len(files) != 1 || files[0] != "upstream.txt" {
+		t.Errorf("conflicting sync = %+v, want conflicts in upstream.txt", data)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, ".git", "worktrees", "clash", "MERGE_HEAD")); err == nil {
+		t.Error("conflicting merge was not aborted")
+	}
+
+	if resp := d.handleSyncAgent(socket.Request{Command: "sync_agent", Args: map[string]interface{}{"repo": "test-repo", "agent": "missing"}}); resp.Success {
+		t.Error("sync_agent for a missing agent should fail")
+	}
+}
+
+func TestHandleSyncAgentBaseBranch(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	tmp := t.TempDir()
+	origin := filepath.Join(tmp, "origin.git")
+	git(tmp, "init", "--bare", "-b", "main", origin)
+	git(repoDir, "remote", "add", "origin", origin)
+	git(repoDir, "push", "-q", "origin", "main", "main:release/1.2")
+	git(repoDir, "fetch", "-q", "origin")
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:    "https://github.com/test/repo",
+		TmuxSession:  "test-session",
+		Agents:       make(map[string]state.Agent),
+		TargetBranch: "main",
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	wtPath := filepath.Join(tmp, "hotfix")
+	git(repoDir, "worktree", "add", "-q", "-b", "work/hotfix", wtPath, "origin/release/1.2")
+	if err := d.state.AddAgent("test-repo", "hotfix", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: wtPath,
+		TmuxWindow:   "hotfix",
+		BaseBranch:   "release/1.2",
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	// The release branch and main both move on; the worker follows only the release
+	upstream := filepath.Join(tmp, "upstream")
+	git(tmp, "clone", "-q", origin, upstream)
+	git(upstream, "config", "user.email", "test@example.com")
+	git(upstream, "config", "user.name", "Test User")
+	for branch, file := range map[string]string{"main": "main.txt", "release/1.2": "release.txt"} {
+		git(upstream, "checkout", "-q", branch)
+		if err := os.WriteFile(filepath.Join(upstream, file), []byte(branch), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(upstream, "add", ".")
+		git(upstream, "commit", "-q", "-m", "change on "+branch)
+		git(upstream, "push", "-q", "origin", branch)
+	}
+
+	resp := d.handleSyncAgent(socket.Request{Command: "sync_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "hotfix", "strategy": "rebase",
+	}})
+	if !resp.Success {
+		t.Fatalf("sync_agent failed: %s", resp.Error)
+	}
+	if data := resp.Data.(map[string]interface{}); data["onto"] != "origin/release/1.2" {
+		t.Errorf("sync = %+v, want it onto origin/release/1.2", data)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "release.txt")); err != nil {
+		t.Error("hotfix was not rebased onto the release branch")
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "main.txt")); err == nil {
+		t.Error("hotfix was rebased onto main")
+	}
+}
@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/train"
+	"github.com/micheal-at/multiclaude/internal/xref"
+)
+
+// updateXref changes a repository's cross-reference index, saving it if
+// change reports that anything changed. Errors are only logged: the index
+// is a convenience, and nothing waits on it.
+func (d *Daemon) updateXref(repoName string, change func(*xref.Index) bool) {
+	d.xrefMu.Lock()
+	defer d.xrefMu.Unlock()
+	x, err := xref.Load(d.paths.XrefFile(repoName))
+	if err != nil {
+		d.logger.Warn("Failed to load the cross-reference index of %s: %v", repoName, err)
+		return
+	}
+	if !change(x) {
+		return
+	}
+	if err := x.Save(); err != nil {
+		d.logger.Warn("Failed to save the cross-reference index of %s: %v", repoName, err)
+	}
+}
+
+// linkWorkerPRs records which of a repository's PRs are from its workers'
+// branches, from a fresh PR listing
+func (d *Daemon) linkWorkerPRs(repoName string, byBranch map[string]pullRequest) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return
+	}
+	now := time.Now()
+	d.updateXref(repoName, func(x *xref.Index) bool {
+		changed := false
+		for agentName, agent := range repo.Agents {
+			if agent.Type != state.AgentTypeWorker {
+				continue
+			}
+			branch := agent.Branch
+			if branch == "" {
+				branch = "work/" + agentName
+			}
+			if pr, ok := byBranch[branch]; ok && x.LinkPR(pr.Number, pr.URL, branch, agentName, agent.Task, now) {
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// indexMessage records a delivered message against the PRs it's about
+func (d *Daemon) indexMessage(repoKey, agentName string, msg *messages.Message) {
+	if _, isProject := state.ParseProjectKey(repoKey); isProject {
+		return
+	}
+	d.updateXref(repoKey, func(x *xref.Index) bool {
+		return x.AddMessage(xref.MessageRef{ID: msg.ID, From: msg.From, To: agentName, At: msg.Timestamp, Body: msg.Body})
+	})
+}
+
+// handleShowPR returns what's known about a PR: the worker whose branch it's
+// from and how that worker is doing or how it finished, the PR's state, its
+// place in the merge train and the messages about it
+func (d *Daemon) handleShowPR(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	number, _ := req.Args["number"].(float64)
+	if number <= 0 {
+		return socket.Response{Success: false, Error: "a PR number is required"}
+	}
+	if _, exists := d.state.GetRepo(repoName); !exists {
+		return errorResponse(errors.RepoNotFound(repoName))
+	}
+
+	d.xrefMu.Lock()
+	x, err := xref.Load(d.paths.XrefFile(repoName))
+	d.xrefMu.Unlock()
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	data := map[string]interface{}{"number": int(number), "state": "unknown", "messages": []xref.MessageRef{}}
+	indexed, found := x.PRs[int(number)]
+	if found {
+		data["url"] = indexed.URL
+		data["branch"] = indexed.Branch
+		data["agent"] = indexed.Agent
+		data["task"] = indexed.Task
+		data["history_id"] = indexed.HistoryID
+		data["first_seen"] = indexed.FirstSeen
+		if indexed.Messages != nil {
+			data["messages"] = indexed.Messages
+		}
+	}
+
+	// The PR's state, from GitHub; the index may not have seen the PR at all
+	if prs, err := d.repoPullRequests(repoName); err != nil {
+		data["state_error"] = err.Error()
+	} else {
+		for _, pr := range prs {
+			if pr.Number == int(number) {
+				found = true
+				data["state"] = pr.status()
+				data["url"] = pr.URL
+				data["branch"] = pr.HeadRefName
+				break
+			}
+		}
+	}
+	if !found {
+		return socket.Response{Success: false, Error: fmt.Sprintf("no record of PR #%d in %s", int(number), repoName)}
+	}
+
+	// The worker, while it's working, or the history entry it left
+	if indexed != nil && indexed.Agent != "" {
+		if indexed.HistoryID != "" {
+			if entry, err := d.state.FindTaskHistory(repoName, indexed.HistoryID); err == nil {
+				data["history"] = map[string]interface{}{
+					"id":             entry.ID(),
+					"status":         string(entry.Status),
+					"summary":        entry.Summary,
+					"failure_reason": entry.FailureReason,
+					"completed_at":   entry.CompletedAt,
+					"criteria":       entry.Criteria,
+				}
+			}
+		} else if agent, exists := d.state.GetAgent(repoName, indexed.Agent); exists {
+			data["worker"] = map[string]interface{}{
+				"name":              indexed.Agent,
+				"ready_for_cleanup": agent.ReadyForCleanup,
+				"created_at":        agent.CreatedAt,
+				"criteria":          agent.Criteria,
+			}
+		}
+	}
+
+	// Where the merge train has the PR
+	d.trainMu.Lock()
+	t, err := train.Load(d.paths.TrainFile(repoName))
+	d.trainMu.Unlock()
+	if err == nil {
+		position := 0
+		for _, e := range t.Entries {
+			if e.Status == train.StatusPassed {
+				position++
+			}
+			if e.PR != int(number) {
+				continue
+			}
+			entry := map[string]interface{}{
+				"status":    string(e.Status),
+				"ahead":     e.Ahead,
+				"reason":    e.Reason,
+				"tested_at": e.TestedAt,
+			}
+			if e.Status == train.StatusPassed {
+				entry["position"] = position
+			}
+			data["train"] = entry
+			break
+		}
+	}
+	return socket.Response{Success: true, Data: data}
+}
@@ -0,0 +1,289 @@
+// Package daemonconfig reads the daemon-level settings file: how often the
+// daemon's loops run, its limits and timeouts, notification muting, the log
+// level and the locale.
+// Unlike repository settings, which live in state.json and change through
+// `multiclaude config`, these are edited by hand and picked up on SIGHUP or
+// `multiclaude daemon reload`.
+package daemonconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/templates"
+	"github.com/micheal-at/multiclaude/pkg/retry"
+	"gopkg.in/yaml.v3"
+)
+
+// Levels are the log levels, from most to least verbose
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// minInterval is the shortest interval a loop may be set to
+const minInterval = 5 * time.Second
+
+// minTimeout is the shortest a command timeout may be set to
+const minTimeout = time.Second
+
+// Config is the daemon-level configuration. Zero values use the defaults.
+type Config struct {
+	LogLevel string `yaml:"log_level,omitempty"`
+	// Locale is the language of the nudges and notices typed into agents'
+	// windows, one of templates.Locales
+	Locale    string    `yaml:"locale,omitempty"`
+	Intervals Intervals `yaml:"intervals,omitempty"`
+	Limits    Limits    `yaml:"limits,omitempty"`
+	Timeouts  Timeouts  `yaml:"timeouts,omitempty"`
+	Notify    Notify    `yaml:"notify,omitempty"`
+	GRPC      GRPC      `yaml:"grpc,omitempty"`
+	HTTP      HTTP      `yaml:"http,omitempty"`
+}
+
+// Intervals are how often the daemon's periodic loops run
+type Intervals struct {
+	HealthCheck     time.Duration `yaml:"health_check,omitempty"`
+	MessageRouting  time.Duration `yaml:"message_routing,omitempty"`
+	Wake            time.Duration `yaml:"wake,omitempty"`
+	WorktreeRefresh time.Duration `yaml:"worktree_refresh,omitempty"`
+	Federation      time.Duration `yaml:"federation,omitempty"`
+	MergeTrain      time.Duration `yaml:"merge_train,omitempty"`
+	CommentCommands time.Duration `yaml:"comment_commands,omitempty"`
+}
+
+// Limits bound the daemon's resource use
+type Limits struct {
+	// RefreshConcurrency is how many worktrees are refreshed at once
+	RefreshConcurrency int `yaml:"refresh_concurrency,omitempty"`
+	// MaxLogSizeMB is the size at which agent logs are rotated
+	MaxLogSizeMB int `yaml:"max_log_size_mb,omitempty"`
+}
+
+// Timeouts bound the external commands the daemon's loops run, so that one
+// that hangs can't hold up the rest
+type Timeouts struct {
+	// Refresh bounds a repository's fetch, and each worktree's check and
+	// rebase, in the worktree refresh loop
+	Refresh time.Duration `yaml:"refresh,omitempty"`
+	// GitHub bounds each gh command
+	GitHub time.Duration `yaml:"gh,omitempty"`
+	// Tmux bounds each tmux command. Changes take effect when the daemon
+	// restarts.
+	Tmux time.Duration `yaml:"tmux,omitempty"`
+	// Git bounds each attempt of a local git command
+	Git time.Duration `yaml:"git,omitempty"`
+	// GitRemote bounds each attempt of a git command that talks to a remote,
+	// such as a fetch or push. Clones, submodule updates and LFS transfers
+	// aren't bounded.
+	GitRemote time.Duration `yaml:"git_remote,omitempty"`
+}
+
+// Notify holds settings for every repository's notifications
+type Notify struct {
+	// Muted holds back email and needs-human notifications for all repositories
+	Muted bool `yaml:"muted,omitempty"`
+}
+
+// GRPC configures the gRPC API, which the daemon serves alongside its socket
+// when enabled. Changes take effect when the daemon restarts.
+type GRPC struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Address is a loopback host:port to listen on instead of a Unix socket
+	// next to the daemon's; calls must then send the token it writes
+	Address string `yaml:"address,omitempty"`
+}
+
+// HTTP configures the daemon's HTTP server, which serves /healthz and
+// /readyz for uptime monitors. It's off unless an address is set. Changes
+// take effect when the daemon restarts.
+type HTTP struct {
+	// Address is the host:port to listen on, such as 127.0.0.1:7480
+	Address string `yaml:"address,omitempty"`
+}
+
+// Default returns the settings the daemon uses without a config file
+func Default() Config {
+	return Config{
+		LogLevel: "debug",
+		Locale:   templates.DefaultLocale,
+		Intervals: Intervals{
+			HealthCheck:     2 * time.Minute,
+			MessageRouting:  2 * time.Minute,
+			Wake:            2 * time.Minute,
+			WorktreeRefresh: 5 * time.Minute,
+			Federation:      time.Minute,
+			MergeTrain:      time.Minute,
+			CommentCommands: time.Minute,
+		},
+		Limits: Limits{
+			RefreshConcurrency: 4,
+			MaxLogSizeMB:       10,
+		},
+		Timeouts: Timeouts{
+			Refresh:   10 * time.Minute,
+			GitHub:    retry.GitHub.Timeout,
+			Tmux:      retry.Tmux.Timeout,
+			Git:       retry.Git.Timeout,
+			GitRemote: retry.GitRemote.Timeout,
+		},
+	}
+}
+
+// Load reads the config file at path and fills in defaults. A missing file
+// gives the defaults.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return Config{}, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and checks config file contents, filling in defaults
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+
+	def := Default()
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = def.LogLevel
+	} else if !validLevel(cfg.LogLevel) {
+		return Config{}, fmt.Errorf("invalid log_level %q (must be debug, info, warn or error)", cfg.LogLevel)
+	}
+	if cfg.Locale == "" {
+		cfg.Locale = def.Locale
+	} else if !templates.ValidLocale(cfg.Locale) {
+		return Config{}, fmt.Errorf("invalid locale %q (must be one of %s)", cfg.Locale, strings.Join(templates.Locales(), ", "))
+	}
+	for _, interval := range []struct {
+		name     string
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{"health_check", &cfg.Intervals.HealthCheck, def.Intervals.HealthCheck},
+		{"message_routing", &cfg.Intervals.MessageRouting, def.Intervals.MessageRouting},
+		{"wake", &cfg.Intervals.Wake, def.Intervals.Wake},
+		{"worktree_refresh", &cfg.Intervals.WorktreeRefresh, def.Intervals.WorktreeRefresh},
+		{"federation", &cfg.Intervals.Federation, def.Intervals.Federation},
+		{"merge_train", &cfg.Intervals.MergeTrain, def.Intervals.MergeTrain},
+		{"comment_commands", &cfg.Intervals.CommentCommands, def.Intervals.CommentCommands},
+	} {
+		switch {
+		case *interval.value == 0:
+			*interval.value = interval.fallback
+		case *interval.value < minInterval:
+			return Config{}, fmt.Errorf("intervals.%s must be at least %s", interval.name, minInterval)
+		}
+	}
+	if cfg.Limits.RefreshConcurrency < 0 || cfg.Limits.MaxLogSizeMB < 0 {
+		return Config{}, fmt.Errorf("limits must not be negative")
+	}
+	if cfg.Limits.RefreshConcurrency == 0 {
+		cfg.Limits.RefreshConcurrency = def.Limits.RefreshConcurrency
+	}
+	if cfg.Limits.MaxLogSizeMB == 0 {
+		cfg.Limits.MaxLogSizeMB = def.Limits.MaxLogSizeMB
+	}
+	for _, timeout := range []struct {
+		name     string
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{"refresh", &cfg.Timeouts.Refresh, def.Timeouts.Refresh},
+		{"gh", &cfg.Timeouts.GitHub, def.Timeouts.GitHub},
+		{"tmux", &cfg.Timeouts.Tmux, def.Timeouts.Tmux},
+		{"git", &cfg.Timeouts.Git, def.Timeouts.Git},
+		{"git_remote", &cfg.Timeouts.GitRemote, def.Timeouts.GitRemote},
+	} {
+		switch {
+		case *timeout.value == 0:
+			*timeout.value = timeout.fallback
+		case *timeout.value < minTimeout:
+			return Config{}, fmt.Errorf("timeouts.%s must be at least %s", timeout.name, minTimeout)
+		}
+	}
+	if cfg.GRPC.Address != "" {
+		host, _, err := net.SplitHostPort(cfg.GRPC.Address)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid grpc.address %q: %w", cfg.GRPC.Address, err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return Config{}, fmt.Errorf("invalid grpc.address %q: must be on loopback, e.g. 127.0.0.1:7443", cfg.GRPC.Address)
+		}
+	}
+	if cfg.HTTP.Address != "" {
+		if _, _, err := net.SplitHostPort(cfg.HTTP.Address); err != nil {
+			return Config{}, fmt.Errorf("invalid http.address %q: %w", cfg.HTTP.Address, err)
+		}
+	}
+	return cfg, nil
+}
+
+// validLevel reports whether level is one of Levels
+func validLevel(level string) bool {
+	for _, l := range Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Change is a setting that differs between two configs
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// String renders the change as "key: old -> new"
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// Diff lists the settings that differ from before to after, in file order
+func Diff(before, after Config) []Change {
+	var changes []Change
+	add := func(key string, old, new interface{}) {
+		o, n := fmt.Sprint(old), fmt.Sprint(new)
+		if o != n {
+			changes = append(changes, Change{Key: key, Old: o, New: n})
+		}
+	}
+	add("log_level", before.LogLevel, after.LogLevel)
+	add("locale", before.Locale, after.Locale)
+	add("intervals.health_check", before.Intervals.HealthCheck, after.Intervals.HealthCheck)
+	add("intervals.message_routing", before.Intervals.MessageRouting, after.Intervals.MessageRouting)
+	add("intervals.wake", before.Intervals.Wake, after.Intervals.Wake)
+	add("intervals.worktree_refresh", before.Intervals.WorktreeRefresh, after.Intervals.WorktreeRefresh)
+	add("intervals.federation", before.Intervals.Federation, after.Intervals.Federation)
+	add("intervals.merge_train", before.Intervals.MergeTrain, after.Intervals.MergeTrain)
+	add("intervals.comment_commands", before.Intervals.CommentCommands, after.Intervals.CommentCommands)
+	add("limits.refresh_concurrency", before.Limits.RefreshConcurrency, after.Limits.RefreshConcurrency)
+	add("limits.max_log_size_mb", before.Limits.MaxLogSizeMB, after.Limits.MaxLogSizeMB)
+	add("timeouts.refresh", before.Timeouts.Refresh, after.Timeouts.Refresh)
+	add("timeouts.gh", before.Timeouts.GitHub, after.Timeouts.GitHub)
+	add("timeouts.tmux", before.Timeouts.Tmux, after.Timeouts.Tmux)
+	add("timeouts.git", before.Timeouts.Git, after.Timeouts.Git)
+	add("timeouts.git_remote", before.Timeouts.GitRemote, after.Timeouts.GitRemote)
+	add("notify.muted", before.Notify.Muted, after.Notify.Muted)
+	add("grpc.enabled", before.GRPC.Enabled, after.GRPC.Enabled)
+	add("grpc.address", before.GRPC.Address, after.GRPC.Address)
+	add("http.address", before.HTTP.Address, after.HTTP.Address)
+	return changes
+}
@@ -0,0 +1,91 @@
+package daemonconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := Load(filepath.Join(dir, "daemon.yaml"))
+	if err != nil || cfg != Default() {
+		t.Errorf("Load() of a missing file = %+v, %v; want the defaults", cfg, err)
+	}
+
+	path := filepath.Join(dir, "daemon.yaml")
+	os.WriteFile(path, []byte("log_level: warn\nintervals:\n  wake: 30s\nlimits:\n  refresh_concurrency: 8\nnotify:\n  muted: true\n"), 0644)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.LogLevel != "warn" || cfg.Intervals.Wake != 30*time.Second || cfg.Limits.RefreshConcurrency != 8 || !cfg.Notify.Muted {
+		t.Errorf("Load() = %+v, want the file's settings", cfg)
+	}
+	if cfg, err := Parse([]byte("grpc:\n  enabled: true\n  address: localhost:7443\n")); err != nil || !cfg.GRPC.Enabled || cfg.GRPC.Address != "localhost:7443" {
+		t.Errorf("Parse() of grpc settings = %+v, %v", cfg.GRPC, err)
+	}
+	if cfg, err := Parse([]byte("http:\n  address: 0.0.0.0:7480\n")); err != nil || cfg.HTTP.Address != "0.0.0.0:7480" {
+		t.Errorf("Parse() of http settings = %+v, %v", cfg.HTTP, err)
+	}
+	if cfg, err := Parse([]byte("locale: ja\n")); err != nil || cfg.Locale != "ja" {
+		t.Errorf("Parse() of a locale = %q, %v", cfg.Locale, err)
+	}
+	if cfg, err := Parse([]byte("timeouts:\n  refresh: 3m\n")); err != nil || cfg.Timeouts.Refresh != 3*time.Minute || cfg.Timeouts.Tmux != Default().Timeouts.Tmux {
+		t.Errorf("Parse() of timeouts = %+v, %v", cfg.Timeouts, err)
+	}
+	if cfg, err := Parse([]byte("timeouts:\n  git: 30s\n  git_remote: 20m\n")); err != nil || cfg.Timeouts.Git != 30*time.Second || cfg.Timeouts.GitRemote != 20*time.Minute {
+		t.Errorf("Parse() of git timeouts = %+v, %v", cfg.Timeouts, err)
+	}
+	if _, err := Parse([]byte("timeouts:\n  git: 10ms\n")); err == nil {
+		t.Error("Parse() should reject a git timeout under a second")
+	}
+	if cfg.Intervals.HealthCheck != Default().Intervals.HealthCheck || cfg.Limits.MaxLogSizeMB != Default().Limits.MaxLogSizeMB {
+		t.Errorf("Load() = %+v, want defaults for unset settings", cfg)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, tc := range []struct{ name, data, want string }{
+		{"unknown key", "log_levle: info\n", "log_levle"},
+		{"bad level", "log_level: loud\n", "log_level"},
+		{"unknown locale", "locale: klingon\n", "en, ja"},
+		{"short interval", "intervals:\n  health_check: 1s\n", "health_check"},
+		{"bad duration", "intervals:\n  wake: soon\n", "soon"},
+		{"negative limit", "limits:\n  max_log_size_mb: -1\n", "negative"},
+		{"short timeout", "timeouts:\n  gh: 10ms\n", "timeouts.gh"},
+		{"grpc without port", "grpc:\n  address: 127.0.0.1\n", "grpc.address"},
+		{"grpc off loopback", "grpc:\n  address: 0.0.0.0:7443\n", "loopback"},
+		{"http without port", "http:\n  address: localhost\n", "http.address"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tc.data)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Parse() error = %v, want one mentioning %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := Default()
+	after := Default()
+	after.LogLevel = "info"
+	after.Intervals.Wake = 30 * time.Second
+	after.Notify.Muted = true
+	after.GRPC.Enabled = true
+
+	changes := Diff(before, after)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := "log_level: debug -> info; intervals.wake: 2m0s -> 30s; notify.muted: false -> true; grpc.enabled: false -> true"
+	if strings.Join(got, "; ") != want {
+		t.Errorf("Diff() = %s, want %s", strings.Join(got, "; "), want)
+	}
+	if len(Diff(before, before)) != 0 {
+		t.Error("Diff() of identical configs should be empty")
+	}
+}
@@ -0,0 +1,308 @@
+// Package digest summarizes what a repository's agents did over a period:
+// commits, pull requests, messages and failed tasks. It backs
+// `multiclaude digest`, a plain-text summary meant to be pasted into a standup.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/retry"
+)
+
+// SlackWebhookEnv is the environment variable holding the Slack incoming
+// webhook URL digests are posted to
+const SlackWebhookEnv = "MULTICLAUDE_SLACK_WEBHOOK"
+
+// postTimeout bounds how long posting to Slack may take
+const postTimeout = 30 * time.Second
+
+// Commit is a commit an agent made
+type Commit struct {
+	Hash    string
+	Subject string
+}
+
+// Input is the raw activity a digest is built from
+type Input struct {
+	Repo     string
+	Since    time.Time
+	Agents   map[string]state.Agent
+	History  []state.TaskHistoryEntry
+	Messages []*messages.Message // Every message in the repository's inboxes
+	Commits  map[string][]Commit // Agent name -> commits made since Since
+	PRStatus map[string]string   // Branch -> live PR status, overriding the status stored in history
+}
+
+// Task is a task an agent finished within the digest period
+type Task struct {
+	Description string
+	PR          string // "#42", the PR URL, or "" if no PR was opened
+	Status      state.TaskStatus
+	Summary     string
+	Failure     string
+}
+
+// Activity is what one agent did within the digest period
+type Activity struct {
+	Name    string
+	Type    state.AgentType
+	Running bool
+	Current string // Task the agent is working on now (running workers only)
+	Tasks   []Task
+	Commits []Commit
+	Sent    map[string]int // Recipient -> number of messages sent
+}
+
+// Failed reports whether any of the agent's tasks failed
+func (a Activity) Failed() bool {
+	for _, t := range a.Tasks {
+		if t.Status == state.TaskStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Activity) quiet() bool {
+	return len(a.Tasks) == 0 && len(a.Commits) == 0 && len(a.Sent) == 0 && a.Current == ""
+}
+
+// Digest is a summary of a repository's activity
+type Digest struct {
+	Repo       string
+	Since      time.Time
+	Activities []Activity // Agents with something to report, failures first
+	Quiet      []string   // Running agents with nothing to report
+}
+
+// Build groups raw activity by agent
+func Build(in Input) *Digest {
+	byName := make(map[string]*Activity)
+	get := func(name string) *Activity {
+		a, ok := byName[name]
+		if !ok {
+			a = &Activity{Name: name, Type: state.AgentTypeWorker, Sent: make(map[string]int)}
+			byName[name] = a
+		}
+		return a
+	}
+
+	for name, agent := range in.Agents {
+		a := get(name)
+		a.Type = agent.Type
+		a.Running = true
+		if agent.Type == state.AgentTypeWorker && !agent.ReadyForCleanup {
+			a.Current = agent.Task
+		}
+	}
+
+	for _, entry := range in.History {
+		finished := entry.CompletedAt
+		if finished.IsZero() {
+			finished = entry.CreatedAt
+		}
+		if finished.Before(in.Since) {
+			continue
+		}
+		task := Task{
+			Description: entry.Task,
+			Status:      entry.Status,
+			Summary:     entry.Summary,
+			Failure:     entry.FailureReason,
+		}
+		switch {
+		case entry.PRNumber > 0:
+			task.PR = fmt.Sprintf("#%d", entry.PRNumber)
+		case entry.PRURL != "":
+			task.PR = entry.PRURL
+		}
+		if status, ok := in.PRStatus[entry.Branch]; ok && status != "" && entry.Status != state.TaskStatusFailed {
+			task.Status = state.TaskStatus(status)
+		}
+		a := get(entry.Name)
+		a.Tasks = append(a.Tasks, task)
+	}
+
+	for name, commits := range in.Commits {
+		if len(commits) > 0 {
+			get(name).Commits = commits
+		}
+	}
+
+	for _, msg := range in.Messages {
+		if msg.Timestamp.Before(in.Since) || strings.Contains(msg.From, "/") || msg.From == "" {
+			// Cross-repo senders are reported in their own repository's digest
+			continue
+		}
+		get(msg.From).Sent[msg.To]++
+	}
+
+	d := &Digest{Repo: in.Repo, Since: in.Since}
+	for _, a := range byName {
+		if a.quiet() {
+			if a.Running {
+				d.Quiet = append(d.Quiet, a.Name)
+			}
+			continue
+		}
+		d.Activities = append(d.Activities, *a)
+	}
+	sort.Slice(d.Activities, func(i, j int) bool {
+		ai, aj := d.Activities[i], d.Activities[j]
+		if ai.Failed() != aj.Failed() {
+			return ai.Failed()
+		}
+		return ai.Name < aj.Name
+	})
+	sort.Strings(d.Quiet)
+	return d
+}
+
+// String renders the digest as plain text
+func (d *Digest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Digest for %s since %s\n", d.Repo, d.Since.Local().Format("Mon Jan 2 15:04"))
+
+	var commits, merged, open, failed, sent int
+	for _, a := range d.Activities {
+		commits += len(a.Commits)
+		for _, t := range a.Tasks {
+			switch t.Status {
+			case state.TaskStatusMerged:
+				merged++
+			case state.TaskStatusOpen:
+				open++
+			case state.TaskStatusFailed:
+				failed++
+			}
+		}
+		for _, n := range a.Sent {
+			sent += n
+		}
+	}
+	fmt.Fprintf(&b, "%s, %d PRs merged, %d PRs open, %s, %s\n",
+		plural(commits, "commit"), merged, open, plural(failed, "failed task"), plural(sent, "message"))
+
+	if len(d.Activities) == 0 {
+		b.WriteString("\nNothing happened.\n")
+	}
+	for _, a := range d.Activities {
+		b.WriteString("\n" + a.Name)
+		if a.Type != "" {
+			fmt.Fprintf(&b, " (%s)", a.Type)
+		}
+		if a.Current != "" {
+			b.WriteString(": working on " + a.Current)
+		}
+		b.WriteString("\n")
+
+		for _, t := range a.Tasks {
+			line := "  " + taskState(t) + ": " + t.Description
+			if t.Failure != "" {
+				line += " - " + t.Failure
+			} else if t.Summary != "" {
+				line += " - " + t.Summary
+			}
+			b.WriteString(line + "\n")
+		}
+		if len(a.Commits) > 0 {
+			fmt.Fprintf(&b, "  %s:\n", plural(len(a.Commits), "commit"))
+			for _, c := range a.Commits {
+				fmt.Fprintf(&b, "    %s %s\n", c.Hash, c.Subject)
+			}
+		}
+		if len(a.Sent) > 0 {
+			recipients := make([]string, 0, len(a.Sent))
+			total := 0
+			for to, n := range a.Sent {
+				recipients = append(recipients, fmt.Sprintf("%s (%d)", to, n))
+				total += n
+			}
+			sort.Strings(recipients)
+			fmt.Fprintf(&b, "  sent %s: %s\n", plural(total, "message"), strings.Join(recipients, ", "))
+		}
+	}
+
+	if len(d.Quiet) > 0 {
+		fmt.Fprintf(&b, "\nQuiet: %s\n", strings.Join(d.Quiet, ", "))
+	}
+	return b.String()
+}
+
+// taskState describes a finished task's outcome, e.g. "merged #42"
+func taskState(t Task) string {
+	status := string(t.Status)
+	if status == "" || t.Status == state.TaskStatusUnknown {
+		status = "finished"
+	}
+	if t.Status == state.TaskStatusFailed {
+		status = "FAILED"
+	}
+	if t.PR != "" && t.Status != state.TaskStatusNoPR {
+		status += " " + t.PR
+	}
+	return status
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Commits lists the non-merge commits on head made since the given time,
+// excluding those already on base. An empty base lists every commit on head.
+func Commits(dir, base, head string, since time.Time) ([]Commit, error) {
+	args := []string{"log", "--no-merges", "--since=" + since.Format(time.RFC3339), "--format=%h%x09%s", head}
+	if base != "" {
+		args = append(args, "^"+base)
+	}
+	output, err := retry.Git.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		return cmd
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w", head, err)
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		hash, subject, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		commits = append(commits, Commit{Hash: hash, Subject: subject})
+	}
+	return commits, nil
+}
+
+// PostSlack posts text to a Slack incoming webhook
+func PostSlack(webhookURL, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": "```\n" + text + "```"})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: postTimeout}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("posting to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("posting to Slack: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package digest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	old := now.Add(-48 * time.Hour)
+
+	d := Build(Input{
+		Repo:  "repo",
+		Since: since,
+		Agents: map[string]state.Agent{
+			"supervisor":  {Type: state.AgentTypeSupervisor},
+			"merge-queue": {Type: state.AgentTypeMergeQueue},
+			"calm-owl":    {Type: state.AgentTypeWorker, Task: "add dark mode"},
+		},
+		History: []state.TaskHistoryEntry{
+			{Name: "brave-fox", Task: "fix auth timeout", Branch: "work/brave-fox", PRNumber: 42, Status: state.TaskStatusOpen, CompletedAt: now},
+			{Name: "quiet-elk", Task: "migrate config", Status: state.TaskStatusFailed, FailureReason: "tests time out", CompletedAt: now},
+			{Name: "ancient", Task: "long ago", Status: state.TaskStatusMerged, CompletedAt: old},
+		},
+		Messages: []*messages.Message{
+			{From: "supervisor", To: "calm-owl", Timestamp: now},
+			{From: "supervisor", To: "calm-owl", Timestamp: now},
+			{From: "supervisor", To: "human", Timestamp: now},
+			{From: "supervisor", To: "calm-owl", Timestamp: old},
+			{From: "web/supervisor", To: "supervisor", Timestamp: now},
+		},
+		Commits: map[string][]Commit{
+			"calm-owl": {{Hash: "abc1234", Subject: "Add theme toggle"}},
+		},
+		PRStatus: map[string]string{"work/brave-fox": "merged"},
+	})
+
+	var names []string
+	for _, a := range d.Activities {
+		names = append(names, a.Name)
+	}
+	if got, want := strings.Join(names, ","), "quiet-elk,brave-fox,calm-owl,supervisor"; got != want {
+		t.Errorf("activities = %s, want %s (failures first, then by name)", got, want)
+	}
+	if got := strings.Join(d.Quiet, ","); got != "merge-queue" {
+		t.Errorf("quiet = %s, want merge-queue", got)
+	}
+
+	byName := make(map[string]Activity)
+	for _, a := range d.Activities {
+		byName[a.Name] = a
+	}
+	if task := byName["brave-fox"].Tasks[0]; task.Status != state.TaskStatusMerged || task.PR != "#42" {
+		t.Errorf("brave-fox task = %+v, want live status merged with PR #42", task)
+	}
+	if sent := byName["supervisor"].Sent; sent["calm-owl"] != 2 || sent["human"] != 1 || len(sent) != 2 {
+		t.Errorf("supervisor sent = %v, want calm-owl:2 human:1", sent)
+	}
+	if byName["calm-owl"].Current != "add dark mode" {
+		t.Errorf("calm-owl current = %q, want its task", byName["calm-owl"].Current)
+	}
+
+	text := d.String()
+	for _, want := range []string{
+		"Digest for repo since",
+		"1 commit, 1 PRs merged, 0 PRs open, 1 failed task, 3 messages",
+		"quiet-elk (worker)\n  FAILED: migrate config - tests time out",
+		"  merged #42: fix auth timeout",
+		"calm-owl (worker): working on add dark mode\n  1 commit:\n    abc1234 Add theme toggle",
+		"  sent 3 messages: calm-owl (2), human (1)",
+		"Quiet: merge-queue",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("digest missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "ancient") {
+		t.Errorf("digest includes a task finished before the period:\n%s", text)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	text := Build(Input{Repo: "repo", Since: time.Now()}).String()
+	if !strings.Contains(text, "Nothing happened.") {
+		t.Errorf("empty digest = %q, want a note that nothing happened", text)
+	}
+}
+
+func TestCommits(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-b", "main")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+	git("commit", "--allow-empty", "-m", "Initial commit")
+	git("checkout", "-b", "work/calm-owl")
+	git("commit", "--allow-empty", "-m", "Add theme toggle")
+	git("commit", "--allow-empty", "-m", "Fix contrast")
+
+	commits, err := Commits(dir, "main", "HEAD", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Commits() error: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "Fix contrast" || commits[1].Subject != "Add theme toggle" {
+		t.Errorf("Commits() = %+v, want the two branch commits, newest first", commits)
+	}
+
+	if commits, err := Commits(dir, "main", "HEAD", time.Now().Add(time.Hour)); err != nil || len(commits) != 0 {
+		t.Errorf("Commits() in the future = %+v, %v; want none", commits, err)
+	}
+	if _, err := Commits(dir, "main", "work/missing", time.Now().Add(-time.Hour)); err == nil {
+		t.Error("Commits() on a missing branch should fail")
+	}
+}
+
+func TestPostSlack(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	if err := PostSlack(server.URL, "Digest for repo\n"); err != nil {
+		t.Fatalf("PostSlack() error: %v", err)
+	}
+	if !strings.Contains(got["text"], "Digest for repo") {
+		t.Errorf("posted text = %q, want the digest", got["text"])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	if err := PostSlack(failing.URL, "x"); err == nil {
+		t.Error("PostSlack() should fail when the webhook rejects the post")
+	}
+}
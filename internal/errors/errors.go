@@ -1,9 +1,19 @@
 // Package errors provides enhanced error handling utilities for better CLI UX.
+//
+// A CLIError carries a category, a stable code, a message, a remediation hint
+// and the error it wraps. The CLI, the daemon's socket responses and the
+// tmux and worktree failures all report through it, so Format can print what
+// to do next and FormatJSON can give scripts the same fields.
 package errors
 
 import (
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/worktree"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
 )
 
 // Category represents the type of error for consistent formatting
@@ -22,11 +32,111 @@ const (
 	CategoryNotFound
 )
 
+// String returns the category's name as it appears in JSON output
+func (c Category) String() string {
+	switch c {
+	case CategoryUsage:
+		return "usage"
+	case CategoryConfig:
+		return "config"
+	case CategoryConnection:
+		return "connection"
+	case CategoryNotFound:
+		return "not_found"
+	default:
+		return "runtime"
+	}
+}
+
+// Code identifies a kind of error for scripts. Codes are stable across
+// releases; messages and remediation text are not.
+type Code string
+
+// Error codes. An error without one of these reports its category's code.
+const (
+	CodeUsage      Code = "usage_error"
+	CodeConfig     Code = "config_error"
+	CodeRuntime    Code = "runtime_error"
+	CodeConnection Code = "connection_error"
+	CodeNotFound   Code = "not_found"
+
+	CodeMissingArgument   Code = "missing_argument"
+	CodeInvalidArgument   Code = "invalid_argument"
+	CodeUnknownCommand    Code = "unknown_command"
+	CodeMultipleRepos     Code = "multiple_repos"
+	CodeInvalidPRURL      Code = "invalid_pr_url"
+	CodeNotInRepo         Code = "not_in_repo"
+	CodeNotInAgentContext Code = "not_in_agent_context"
+	CodeClaudeNotFound    Code = "claude_not_found"
+	CodeDaemonNotRunning  Code = "daemon_not_running"
+	CodeDaemonUnreachable Code = "daemon_unreachable"
+
+	CodeRepoNotFound      Code = "repo_not_found"
+	CodeAgentNotFound     Code = "agent_not_found"
+	CodeWorkspaceNotFound Code = "workspace_not_found"
+	CodeNoRepositories    Code = "no_repositories"
+	CodeNoWorkers         Code = "no_workers"
+	CodeNoWorkspaces      Code = "no_workspaces"
+	CodeNoAgents          Code = "no_agents"
+
+	CodeGitFailed           Code = "git_failed"
+	CodeTmuxFailed          Code = "tmux_failed"
+	CodeTmuxNotInstalled    Code = "tmux_not_installed"
+	CodeTmuxSessionExists   Code = "tmux_session_exists"
+	CodeTmuxSessionNotFound Code = "tmux_session_not_found"
+	CodeWorktreeFailed      Code = "worktree_failed"
+	CodeWorktreeExists      Code = "worktree_exists"
+	CodeWorktreeSetupFailed Code = "worktree_setup_failed"
+	CodeBranchExists        Code = "branch_exists"
+	CodeBranchCheckedOut    Code = "branch_checked_out"
+	CodeInvalidStartBranch  Code = "invalid_start_branch"
+	CodeDefinitionAtLimit   Code = "definition_at_limit"
+	CodeWorkerLimit         Code = "worker_limit"
+)
+
+// codeCategories gives the category of codes that can arrive without one,
+// such as in the daemon's responses
+var codeCategories = map[Code]Category{
+	CodeUsage:               CategoryUsage,
+	CodeMissingArgument:     CategoryUsage,
+	CodeInvalidArgument:     CategoryUsage,
+	CodeUnknownCommand:      CategoryUsage,
+	CodeMultipleRepos:       CategoryUsage,
+	CodeInvalidPRURL:        CategoryUsage,
+	CodeConfig:              CategoryConfig,
+	CodeNotInRepo:           CategoryConfig,
+	CodeNotInAgentContext:   CategoryConfig,
+	CodeClaudeNotFound:      CategoryConfig,
+	CodeTmuxNotInstalled:    CategoryConfig,
+	CodeConnection:          CategoryConnection,
+	CodeDaemonNotRunning:    CategoryConnection,
+	CodeDaemonUnreachable:   CategoryConnection,
+	CodeNotFound:            CategoryNotFound,
+	CodeRepoNotFound:        CategoryNotFound,
+	CodeAgentNotFound:       CategoryNotFound,
+	CodeWorkspaceNotFound:   CategoryNotFound,
+	CodeNoRepositories:      CategoryNotFound,
+	CodeNoWorkers:           CategoryNotFound,
+	CodeNoWorkspaces:        CategoryNotFound,
+	CodeNoAgents:            CategoryNotFound,
+	CodeTmuxSessionNotFound: CategoryNotFound,
+}
+
+// categoryCodes is the code of an error that has only a category
+var categoryCodes = map[Category]Code{
+	CategoryUsage:      CodeUsage,
+	CategoryConfig:     CodeConfig,
+	CategoryRuntime:    CodeRuntime,
+	CategoryConnection: CodeConnection,
+	CategoryNotFound:   CodeNotFound,
+}
+
 // CLIError represents an error with additional context for CLI display
 type CLIError struct {
 	Category   Category
+	Code       Code // Optional; ErrorCode falls back to the category's code
 	Message    string
-	Suggestion string // Optional hint for how to fix the error
+	Suggestion string // Optional hint for how to fix the error (the remediation)
 	Cause      error  // Wrapped error
 }
 
@@ -63,6 +173,100 @@ func (e *CLIError) WithSuggestion(suggestion string) *CLIError {
 	return e
 }
 
+// WithCode sets the error's code
+func (e *CLIError) WithCode(code Code) *CLIError {
+	e.Code = code
+	return e
+}
+
+// ErrorCode returns the error's code, or its category's when it has none
+func (e *CLIError) ErrorCode() Code {
+	if e.Code != "" {
+		return e.Code
+	}
+	return categoryCodes[e.Category]
+}
+
+// As returns the CLIError in err's chain, if there is one
+func As(err error) (*CLIError, bool) {
+	var cliErr *CLIError
+	if stderrors.As(err, &cliErr) {
+		return cliErr, true
+	}
+	return nil, false
+}
+
+// CodeOf returns the code of the CLIError in err's chain, CodeRuntime for
+// other errors and "" for nil
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	if cliErr, ok := As(err); ok {
+		return cliErr.ErrorCode()
+	}
+	return CodeRuntime
+}
+
+// FromDaemon converts a failed daemon response into an error: message says
+// what failed, and daemonError, code and remediation are the response's.
+// code and remediation may be empty.
+func FromDaemon(message, daemonError string, code Code, remediation string) *CLIError {
+	category, ok := codeCategories[code]
+	if !ok {
+		category = CategoryRuntime
+	}
+	return &CLIError{
+		Category:   category,
+		Code:       code,
+		Message:    message + ": " + daemonError,
+		Suggestion: remediation,
+	}
+}
+
+// Details is an error in machine-readable form
+type Details struct {
+	Category    string `json:"category"`
+	Code        Code   `json:"code"`
+	Message     string `json:"message"`
+	Cause       string `json:"cause,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// DetailsOf describes err. Errors that wrap a CLIError keep their own message
+// and take the rest from it; other errors are runtime errors.
+func DetailsOf(err error) Details {
+	cliErr, ok := As(err)
+	if !ok {
+		return Details{Category: CategoryRuntime.String(), Code: CodeRuntime, Message: err.Error()}
+	}
+	d := Details{
+		Category:    cliErr.Category.String(),
+		Code:        cliErr.ErrorCode(),
+		Message:     cliErr.Message,
+		Remediation: cliErr.Suggestion,
+	}
+	if cliErr.Cause != nil {
+		d.Cause = cliErr.Cause.Error()
+	}
+	if cliErr != err {
+		d.Message = err.Error()
+	}
+	return d
+}
+
+// FormatJSON returns err as a JSON object with an "error" key holding its
+// Details, for commands run with --json
+func FormatJSON(err error) string {
+	if err == nil {
+		return ""
+	}
+	data, _ := json.Marshal(struct {
+		Error Details `json:"error"`
+	}{DetailsOf(err)})
+	return string(data)
+}
+
 // Format returns a user-friendly formatted error message
 func Format(err error) string {
 	if err == nil {
@@ -71,12 +275,16 @@ func Format(err error) string {
 
 	var sb strings.Builder
 
-	// Check if it's a CLIError
-	if cliErr, ok := err.(*CLIError); ok {
+	// Check if it's a CLIError, or wraps one
+	if cliErr, ok := As(err); ok {
 		// Add category prefix
 		prefix := categoryPrefix(cliErr.Category)
 		sb.WriteString(prefix)
-		sb.WriteString(cliErr.Message)
+		if cliErr != err {
+			sb.WriteString(err.Error())
+		} else {
+			sb.WriteString(cliErr.Message)
+		}
 
 		// Add cause if present
 		if cliErr.Cause != nil {
@@ -122,6 +330,7 @@ func categoryPrefix(cat Category) string {
 func DaemonNotRunning() *CLIError {
 	return &CLIError{
 		Category:   CategoryConnection,
+		Code:       CodeDaemonNotRunning,
 		Message:    "daemon is not running",
 		Suggestion: "multiclaude daemon start",
 	}
@@ -131,6 +340,7 @@ func DaemonNotRunning() *CLIError {
 func DaemonCommunicationFailed(operation string, cause error) *CLIError {
 	return &CLIError{
 		Category:   CategoryConnection,
+		Code:       CodeDaemonUnreachable,
 		Message:    fmt.Sprintf("failed to communicate with daemon while %s", operation),
 		Cause:      cause,
 		Suggestion: "multiclaude daemon status",
@@ -149,6 +359,7 @@ func InvalidUsage(usage string) *CLIError {
 func NotInRepo() *CLIError {
 	return &CLIError{
 		Category:   CategoryConfig,
+		Code:       CodeNotInRepo,
 		Message:    "not in a tracked repository",
 		Suggestion: "multiclaude repo init <github-url> to track a repository, or use --repo flag",
 	}
@@ -158,6 +369,7 @@ func NotInRepo() *CLIError {
 func MultipleRepos() *CLIError {
 	return &CLIError{
 		Category:   CategoryUsage,
+		Code:       CodeMultipleRepos,
 		Message:    "multiple repositories are tracked",
 		Suggestion: "use --repo flag to specify which repository",
 	}
@@ -167,6 +379,7 @@ func MultipleRepos() *CLIError {
 func AgentNotFound(agentType, name, repo string) *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeAgentNotFound,
 		Message:    fmt.Sprintf("%s '%s' not found in repository '%s'", agentType, name, repo),
 		Suggestion: fmt.Sprintf("multiclaude worker list --repo %s", repo),
 	}
@@ -176,6 +389,7 @@ func AgentNotFound(agentType, name, repo string) *CLIError {
 func InvalidPRURL() *CLIError {
 	return &CLIError{
 		Category:   CategoryUsage,
+		Code:       CodeInvalidPRURL,
 		Message:    "invalid PR URL format",
 		Suggestion: "use format: https://github.com/owner/repo/pull/123",
 	}
@@ -185,6 +399,7 @@ func InvalidPRURL() *CLIError {
 func GitOperationFailed(operation string, cause error) *CLIError {
 	return &CLIError{
 		Category:   CategoryRuntime,
+		Code:       CodeGitFailed,
 		Message:    fmt.Sprintf("git %s failed", operation),
 		Cause:      cause,
 		Suggestion: "check git status and ensure the repository is in a clean state",
@@ -193,69 +408,84 @@ func GitOperationFailed(operation string, cause error) *CLIError {
 
 // TmuxOperationFailed creates an error for tmux operation failures with specific suggestions
 func TmuxOperationFailed(operation string, cause error) *CLIError {
-	suggestion := tmuxSuggestionForOperation(operation, cause)
+	code, suggestion := tmuxSuggestionForOperation(operation, cause)
 	return &CLIError{
 		Category:   CategoryRuntime,
+		Code:       code,
 		Message:    fmt.Sprintf("tmux %s failed", operation),
 		Cause:      cause,
 		Suggestion: suggestion,
 	}
 }
 
-// tmuxSuggestionForOperation provides specific suggestions based on the operation and error
-func tmuxSuggestionForOperation(operation string, cause error) string {
+// tmuxSuggestionForOperation provides a code and specific suggestions based on the operation and error
+func tmuxSuggestionForOperation(operation string, cause error) (Code, string) {
 	errMsg := ""
 	if cause != nil {
 		errMsg = cause.Error()
 	}
 
+	// The session is gone, e.g. after a reboot or tmux kill-server
+	var notFound *tmux.SessionNotFoundError
+	if stderrors.As(cause, &notFound) {
+		return CodeTmuxSessionNotFound, "multiclaude repair"
+	}
+
 	// tmux binary not found
 	if strings.Contains(errMsg, "executable file not found") || strings.Contains(errMsg, "not found in") {
-		return "could not find 'tmux' binary in PATH"
+		return CodeTmuxNotInstalled, "could not find 'tmux' binary in PATH"
 	}
 
 	// Session already exists
 	if strings.Contains(errMsg, "duplicate session") || strings.Contains(errMsg, "already exists") {
-		return "a tmux session with this name already exists; kill it with: tmux kill-session -t <session-name>"
+		return CodeTmuxSessionExists, "a tmux session with this name already exists; kill it with: tmux kill-session -t <session-name>"
 	}
 
 	// Default: no specific suggestion
-	return ""
+	return CodeTmuxFailed, ""
 }
 
 // WorktreeCreationFailed creates an error for worktree creation failures
 func WorktreeCreationFailed(cause error) *CLIError {
+	code, suggestion := worktreeSuggestionForError(cause)
 	return &CLIError{
 		Category:   CategoryRuntime,
+		Code:       code,
 		Message:    "failed to create git worktree",
 		Cause:      cause,
-		Suggestion: worktreeSuggestionForError(cause),
+		Suggestion: suggestion,
 	}
 }
 
-// worktreeSuggestionForError provides specific suggestions based on the git error
-func worktreeSuggestionForError(cause error) string {
+// worktreeSuggestionForError provides a code and specific suggestions based on the git error
+func worktreeSuggestionForError(cause error) (Code, string) {
 	if cause == nil {
-		return "check disk space and git repository state"
+		return CodeWorktreeFailed, "check disk space and git repository state"
 	}
 
 	errMsg := cause.Error()
 
+	// The worktree exists but a setup step after git worktree add failed
+	var setupErr *worktree.SetupError
+	if stderrors.As(cause, &setupErr) {
+		return CodeWorktreeSetupFailed, fmt.Sprintf("fix the %s step, or remove the worktree with: git worktree remove %s", setupErr.Step, setupErr.Path)
+	}
+
 	// Check more specific patterns first before "already exists"
 
 	// Worktree path already exists (check before generic "already exists")
 	if strings.Contains(errMsg, "path already exists") || strings.Contains(errMsg, "is a worktree") {
-		return "worktree directory already exists\n\nTry: multiclaude cleanup"
+		return CodeWorktreeExists, "worktree directory already exists\n\nTry: multiclaude cleanup"
 	}
 
 	// Branch already checked out in another worktree
 	if strings.Contains(errMsg, "already checked out") {
-		return "this branch is already checked out in another worktree\n\nTry: multiclaude cleanup"
+		return CodeBranchCheckedOut, "this branch is already checked out in another worktree\n\nTry: multiclaude cleanup"
 	}
 
 	// Not a valid reference (start branch doesn't exist)
 	if strings.Contains(errMsg, "not a valid reference") || strings.Contains(errMsg, "invalid reference") {
-		return "the specified start branch does not exist\n\nCheck available branches: git branch -a"
+		return CodeInvalidStartBranch, "the specified start branch does not exist\n\nCheck available branches: git branch -a"
 	}
 
 	// Branch already exists (most common case from cleanup issues)
@@ -263,17 +493,17 @@ func worktreeSuggestionForError(cause error) string {
 	if strings.Contains(errMsg, "already exists") {
 		branchName := extractQuotedValue(errMsg)
 		if branchName != "" {
-			return fmt.Sprintf("branch '%s' already exists from a previous run\n\n"+
+			return CodeBranchExists, fmt.Sprintf("branch '%s' already exists from a previous run\n\n"+
 				"To fix this:\n"+
 				"  1. Run: multiclaude cleanup\n"+
 				"  2. Or manually delete the stale branch:\n"+
 				"     git branch -D %s", branchName, branchName)
 		}
-		return "a branch with this name already exists from a previous run\n\nTry: multiclaude cleanup"
+		return CodeBranchExists, "a branch with this name already exists from a previous run\n\nTry: multiclaude cleanup"
 	}
 
 	// Default fallback
-	return "check disk space and git repository state"
+	return CodeWorktreeFailed, "check disk space and git repository state"
 }
 
 // extractQuotedValue extracts the first single-quoted value from an error message
@@ -294,6 +524,7 @@ func extractQuotedValue(errMsg string) string {
 func ClaudeNotFound(cause error) *CLIError {
 	return &CLIError{
 		Category:   CategoryConfig,
+		Code:       CodeClaudeNotFound,
 		Message:    "claude binary not found in PATH",
 		Cause:      cause,
 		Suggestion: "install Claude Code CLI: https://docs.anthropic.com/claude-code",
@@ -308,6 +539,7 @@ func MissingArgument(argName, expectedType string) *CLIError {
 	}
 	return &CLIError{
 		Category: CategoryUsage,
+		Code:     CodeMissingArgument,
 		Message:  msg,
 	}
 }
@@ -316,6 +548,7 @@ func MissingArgument(argName, expectedType string) *CLIError {
 func InvalidArgument(argName, value, expected string) *CLIError {
 	return &CLIError{
 		Category: CategoryUsage,
+		Code:     CodeInvalidArgument,
 		Message:  fmt.Sprintf("invalid value for '%s': got '%s', expected %s", argName, value, expected),
 	}
 }
@@ -324,8 +557,9 @@ func InvalidArgument(argName, value, expected string) *CLIError {
 func NotInAgentContext() *CLIError {
 	return &CLIError{
 		Category:   CategoryConfig,
+		Code:       CodeNotInAgentContext,
 		Message:    "not in a multiclaude agent directory",
-		Suggestion: "run this command from within an agent's tmux window",
+		Suggestion: "run this command from within an agent's tmux window, or name the agent with --agent <name>",
 	}
 }
 
@@ -333,6 +567,7 @@ func NotInAgentContext() *CLIError {
 func UnknownCommand(cmd string) *CLIError {
 	return &CLIError{
 		Category:   CategoryUsage,
+		Code:       CodeUnknownCommand,
 		Message:    fmt.Sprintf("unknown command: %s", cmd),
 		Suggestion: "multiclaude --help",
 	}
@@ -342,15 +577,39 @@ func UnknownCommand(cmd string) *CLIError {
 func NoRepositoriesFound() *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeNoRepositories,
 		Message:    "no repositories found",
 		Suggestion: "multiclaude repo init <github-url>",
 	}
 }
 
+// DefinitionAtLimit creates an error for when an agent definition already has
+// as many running agents as its max-instances front matter allows
+func DefinitionAtLimit(definition string, limit int, running []string) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Code:       CodeDefinitionAtLimit,
+		Message:    fmt.Sprintf("agent definition '%s' is at its limit of %d running (%s)", definition, limit, strings.Join(running, ", ")),
+		Suggestion: fmt.Sprintf("wait for one to finish, or raise max-instances in the front matter of %s.md", definition),
+	}
+}
+
+// WorkerLimitReached creates an error for when a repository already runs as
+// many workers as its max_workers setting allows
+func WorkerLimitReached(repo string, limit int, running []string) *CLIError {
+	return &CLIError{
+		Category:   CategoryRuntime,
+		Code:       CodeWorkerLimit,
+		Message:    fmt.Sprintf("repository '%s' is at its limit of %d running workers (%s)", repo, limit, strings.Join(running, ", ")),
+		Suggestion: fmt.Sprintf("wait for one to finish, or raise it with: multiclaude config %s --max-workers=<n>", repo),
+	}
+}
+
 // RepoNotFound creates an error for when a specific repository is not found
 func RepoNotFound(repo string) *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeRepoNotFound,
 		Message:    fmt.Sprintf("repository '%s' not found", repo),
 		Suggestion: "multiclaude list",
 	}
@@ -360,6 +619,7 @@ func RepoNotFound(repo string) *CLIError {
 func NoWorkersFound(repo string) *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeNoWorkers,
 		Message:    fmt.Sprintf("no workers found in repo '%s'", repo),
 		Suggestion: fmt.Sprintf("multiclaude worker create \"<task>\" --repo %s", repo),
 	}
@@ -369,6 +629,7 @@ func NoWorkersFound(repo string) *CLIError {
 func NoWorkspacesFound(repo string) *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeNoWorkspaces,
 		Message:    fmt.Sprintf("no workspaces found in repo '%s'", repo),
 		Suggestion: fmt.Sprintf("multiclaude workspace add <name> --repo %s", repo),
 	}
@@ -378,6 +639,7 @@ func NoWorkspacesFound(repo string) *CLIError {
 func NoAgentsFound(repo string) *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeNoAgents,
 		Message:    fmt.Sprintf("no agents found in repo '%s'", repo),
 		Suggestion: fmt.Sprintf("multiclaude worker list --repo %s", repo),
 	}
@@ -387,6 +649,7 @@ func NoAgentsFound(repo string) *CLIError {
 func WorkspaceNotFound(name, repo string) *CLIError {
 	return &CLIError{
 		Category:   CategoryNotFound,
+		Code:       CodeWorkspaceNotFound,
 		Message:    fmt.Sprintf("workspace '%s' not found in repo '%s'", name, repo),
 		Suggestion: fmt.Sprintf("multiclaude workspace list --repo %s", repo),
 	}
@@ -1,9 +1,14 @@
 package errors
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/worktree"
+	"github.com/micheal-at/multiclaude/pkg/tmux"
 )
 
 func TestCLIError_Error(t *testing.T) {
@@ -577,3 +582,79 @@ func TestWorkspaceNotFound(t *testing.T) {
 		t.Errorf("expected workspace list suggestion, got: %s", formatted)
 	}
 }
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  *CLIError
+		want Code
+	}{
+		{RepoNotFound("my-repo"), CodeRepoNotFound},
+		{DefinitionAtLimit("reviewer", 1, []string{"review-12"}), CodeDefinitionAtLimit},
+		{WorkerLimitReached("my-repo", 2, []string{"clever-fox", "happy-eagle"}), CodeWorkerLimit},
+		{DaemonNotRunning(), CodeDaemonNotRunning},
+		{MissingArgument("name", ""), CodeMissingArgument},
+		{New(CategoryConfig, "bad config"), CodeConfig},
+		{New(CategoryRuntime, "boom").WithCode(CodeGitFailed), CodeGitFailed},
+		{TmuxOperationFailed("create session", &tmux.SessionNotFoundError{Name: "mc-repo"}), CodeTmuxSessionNotFound},
+		{WorktreeCreationFailed(&worktree.SetupError{Path: "/wts/x", Step: "submodule update", Err: errors.New("exit status 1")}), CodeWorktreeSetupFailed},
+	}
+	for _, tt := range tests {
+		if got := tt.err.ErrorCode(); got != tt.want {
+			t.Errorf("%q: ErrorCode() = %s, want %s", tt.err.Message, got, tt.want)
+		}
+	}
+
+	if got := CodeOf(fmt.Errorf("starting worker: %w", NotInRepo())); got != CodeNotInRepo {
+		t.Errorf("CodeOf(wrapped) = %s, want %s", got, CodeNotInRepo)
+	}
+	if got := CodeOf(errors.New("plain")); got != CodeRuntime {
+		t.Errorf("CodeOf(plain) = %s, want %s", got, CodeRuntime)
+	}
+}
+
+func TestFormat_WrappedCLIError(t *testing.T) {
+	err := fmt.Errorf("starting worker: %w", NotInRepo())
+	formatted := Format(err)
+	if !strings.HasPrefix(formatted, "Configuration error: starting worker: not in a tracked repository") {
+		t.Errorf("Format(wrapped) = %q, want the wrapper's message with the category", formatted)
+	}
+	if !strings.Contains(formatted, "Try: multiclaude repo init") {
+		t.Errorf("Format(wrapped) = %q, want the suggestion", formatted)
+	}
+}
+
+func TestFromDaemon(t *testing.T) {
+	err := FromDaemon("reinit_repo failed", "repository 'x' not found", CodeRepoNotFound, "multiclaude list")
+	if err.Category != CategoryNotFound || err.Message != "reinit_repo failed: repository 'x' not found" || err.Suggestion != "multiclaude list" {
+		t.Errorf("FromDaemon() = %+v", err)
+	}
+	if err := FromDaemon("ping failed", "oops", "", ""); err.Category != CategoryRuntime || err.ErrorCode() != CodeRuntime {
+		t.Errorf("FromDaemon() without a code = %+v, want a runtime error", err)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	var got struct {
+		Error Details `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(FormatJSON(GitOperationFailed("fetch", errors.New("exit status 128")))), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Details{
+		Category:    "runtime",
+		Code:        CodeGitFailed,
+		Message:     "git fetch failed",
+		Cause:       "exit status 128",
+		Remediation: "check git status and ensure the repository is in a clean state",
+	}
+	if got.Error != want {
+		t.Errorf("FormatJSON() = %+v, want %+v", got.Error, want)
+	}
+
+	if d := DetailsOf(errors.New("plain")); d.Category != "runtime" || d.Code != CodeRuntime || d.Message != "plain" {
+		t.Errorf("DetailsOf(plain) = %+v", d)
+	}
+	if FormatJSON(nil) != "" {
+		t.Error("FormatJSON(nil) should be empty")
+	}
+}
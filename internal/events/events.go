@@ -0,0 +1,153 @@
+// Package events records what happens in the daemon (agents starting and
+// stopping, messages being delivered, tasks completing) so clients can
+// follow along with `multiclaude watch`. Events are kept in memory only; a
+// client that falls too far behind sees a gap in the sequence numbers.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	// EventRepoAdded is published when a repository starts being tracked
+	EventRepoAdded Type = "repo_added"
+	// EventRepoRemoved is published when a repository stops being tracked
+	EventRepoRemoved Type = "repo_removed"
+	// EventAgentStarted is published when an agent is registered
+	EventAgentStarted Type = "agent_started"
+	// EventAgentRestarted is published when an agent's Claude session is restarted
+	EventAgentRestarted Type = "agent_restarted"
+	// EventAgentDied is published when the health check finds an agent's window or process gone
+	EventAgentDied Type = "agent_died"
+	// EventAgentInterrupted is published when an agent is interrupted mid-turn with `multiclaude agent interrupt`
+	EventAgentInterrupted Type = "agent_interrupted"
+	// EventAgentRemoved is published when an agent is removed from state
+	EventAgentRemoved Type = "agent_removed"
+	// EventTaskCompleted is published when a worker reports its task done
+	EventTaskCompleted Type = "task_completed"
+	// EventTaskFailed is published when a worker reports its task failed
+	EventTaskFailed Type = "task_failed"
+	// EventTaskStarted is published when the daemon starts a worker on a queued task whose prerequisites are done
+	EventTaskStarted Type = "task_started"
+	// EventTaskFinished is published when a queued task is merged, completed or failed
+	EventTaskFinished Type = "task_finished"
+	// EventTrainPassed is published when a PR passes its merge train simulation and joins the train
+	EventTrainPassed Type = "train_passed"
+	// EventTrainFailed is published when a PR doesn't merge onto the merge train's tip or fails the tests there
+	EventTrainFailed Type = "train_failed"
+	// EventPRReady is published when a worker's PR is taken out of draft with `multiclaude worker ready`
+	EventPRReady Type = "pr_ready"
+	// EventCommentCommand is published when a "/multiclaude" command from a PR or issue comment is applied to a worker
+	EventCommentCommand Type = "comment_command"
+	// EventMessageDelivered is published when a message is typed into its recipient's session
+	EventMessageDelivered Type = "message_delivered"
+	// EventResourceAlert is published when an agent's processes have been hogging CPU or memory
+	EventResourceAlert Type = "resource_alert"
+	// EventNeedsHuman is published when an agent starts waiting at a permission prompt or question
+	EventNeedsHuman Type = "needs_human"
+	// EventInconsistencyFound is published when the integrity check keeps finding state out of step with tmux, worktrees, messages or prompt files
+	EventInconsistencyFound Type = "inconsistency_found"
+	// EventInconsistencyResolved is published when an inconsistency the integrity check reported is gone
+	EventInconsistencyResolved Type = "inconsistency_resolved"
+	// EventConfigReloaded is published when the daemon rereads daemon.yaml; its data maps each changed setting to "old -> new"
+	EventConfigReloaded Type = "config_reloaded"
+	// EventConfigChanged is published when a repository's settings change, from `multiclaude config` or its config file; its data lists the changed settings
+	EventConfigChanged Type = "config_changed"
+)
+
+// Event is one thing that happened. Seq increases by one per event.
+type Event struct {
+	Seq   uint64            `json:"seq"`
+	Time  time.Time         `json:"time"`
+	Type  Type              `json:"type"`
+	Repo  string            `json:"repo,omitempty"`
+	Agent string            `json:"agent,omitempty"`
+	Data  map[string]string `json:"data,omitempty"`
+	// Trace is the trace ID of the request that caused the event, if any
+	Trace string `json:"trace,omitempty"`
+}
+
+// Bus keeps the most recent events and wakes readers waiting for new ones.
+// It is safe for concurrent use.
+type Bus struct {
+	mu       sync.Mutex
+	events   []Event
+	capacity int
+	seq      uint64
+	// changed is closed and replaced on every publish
+	changed chan struct{}
+}
+
+// NewBus creates a bus that keeps the last capacity events
+func NewBus(capacity int) *Bus {
+	return &Bus{capacity: capacity, changed: make(chan struct{})}
+}
+
+// Publish records an event, filling in its sequence number and time
+func (b *Bus) Publish(typ Type, repo, agent string, data map[string]string) Event {
+	return b.PublishTraced("", typ, repo, agent, data)
+}
+
+// PublishTraced is like Publish for an event caused by a traced request
+func (b *Bus) PublishTraced(trace string, typ Type, repo, agent string, data map[string]string) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e := Event{Seq: b.seq, Time: time.Now(), Type: typ, Repo: repo, Agent: agent, Data: data, Trace: trace}
+	b.events = append(b.events, e)
+	if len(b.events) > b.capacity {
+		b.events = b.events[len(b.events)-b.capacity:]
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return e
+}
+
+// Seq returns the sequence number of the latest event
+func (b *Bus) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Since returns the kept events after seq, oldest first, and the latest
+// sequence number
+func (b *Bus) Since(seq uint64) ([]Event, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []Event
+	for _, e := range b.events {
+		if e.Seq > seq {
+			out = append(out, e)
+		}
+	}
+	return out, b.seq
+}
+
+// Wait is like Since, but when there are no events after seq it waits up to
+// timeout (or until ctx is done) for one
+func (b *Bus) Wait(ctx context.Context, seq uint64, timeout time.Duration) ([]Event, uint64) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		b.mu.Lock()
+		changed, latest := b.changed, b.seq
+		b.mu.Unlock()
+		if latest > seq {
+			return b.Since(seq)
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return b.Since(seq)
+		case <-ctx.Done():
+			return b.Since(seq)
+		}
+	}
+}
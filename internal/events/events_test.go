@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBus(t *testing.T) {
+	b := NewBus(3)
+	if evs, seq := b.Since(0); len(evs) != 0 || seq != 0 {
+		t.Errorf("Since() on an empty bus = %v, %d", evs, seq)
+	}
+
+	for _, agent := range []string{"a", "b", "c", "d"} {
+		b.Publish(EventAgentStarted, "repo", agent, nil)
+	}
+	evs, seq := b.Since(0)
+	if seq != 4 || len(evs) != 3 || evs[0].Seq != 2 || evs[0].Agent != "b" {
+		t.Errorf("Since(0) = %v, %d; want the last 3 of 4 events", evs, seq)
+	}
+	if evs, _ := b.Since(3); len(evs) != 1 || evs[0].Agent != "d" {
+		t.Errorf("Since(3) = %v, want only the fourth event", evs)
+	}
+
+	if e := b.PublishTraced("3f9a0c12d4e5", EventAgentRemoved, "repo", "d", nil); e.Trace != "3f9a0c12d4e5" || e.Seq != 5 {
+		t.Errorf("PublishTraced() = %+v", e)
+	}
+}
+
+func TestBusWait(t *testing.T) {
+	b := NewBus(10)
+	start := b.Seq()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Publish(EventMessageDelivered, "repo", "supervisor", map[string]string{"from": "calm-owl"})
+	}()
+	evs, seq := b.Wait(context.Background(), start, 5*time.Second)
+	if len(evs) != 1 || seq != 1 || evs[0].Data["from"] != "calm-owl" {
+		t.Errorf("Wait() = %v, %d; want the published event", evs, seq)
+	}
+
+	// Nothing new: returns empty once the timeout passes
+	began := time.Now()
+	if evs, _ := b.Wait(context.Background(), seq, 30*time.Millisecond); len(evs) != 0 || time.Since(began) < 30*time.Millisecond {
+		t.Errorf("Wait() with no new events = %v after %v", evs, time.Since(began))
+	}
+
+	// A cancelled context ends the wait early
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if evs, _ := b.Wait(ctx, seq, time.Hour); len(evs) != 0 {
+		t.Errorf("Wait() with a cancelled context = %v", evs)
+	}
+}
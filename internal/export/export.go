@@ -0,0 +1,333 @@
+// Package export collects what an agent did (its Claude conversation, the
+// messages it sent and received, and the changes in its worktree) into one
+// artifact that can be attached to a code review or a bug report.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/redact"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/claude"
+	"github.com/micheal-at/multiclaude/pkg/retry"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	// FormatJSONL writes one JSON record per line, for tools
+	FormatJSONL Format = "jsonl"
+	// FormatMarkdown writes a document for people
+	FormatMarkdown Format = "markdown"
+)
+
+// Formats lists the supported formats
+var Formats = []string{string(FormatJSONL), string(FormatMarkdown)}
+
+// maxLogBytes caps how much of the terminal log is used when there is no
+// session transcript
+const maxLogBytes = 256 * 1024
+
+// Source says where to find an agent's activity
+type Source struct {
+	Repo  string
+	Name  string
+	Agent state.Agent
+	// ClaudeProjectsDir is where Claude keeps session transcripts (usually ~/.claude/projects)
+	ClaudeProjectsDir string
+	// LogFile is the agent's captured terminal output, used when there is no transcript
+	LogFile string
+	// BaseRef is what the worktree diff is taken against, e.g. origin/main
+	BaseRef  string
+	Messages *messages.Manager
+	// Redactor scrubs credentials from what is collected; nil uses the built-in patterns
+	Redactor *redact.Redactor
+}
+
+// Message is a message the agent sent or received
+type Message struct {
+	Direction string `json:"direction"` // "sent" or "received"
+	*messages.Message
+}
+
+// Export is everything collected about an agent
+type Export struct {
+	Repo       string    `json:"repo"`
+	Agent      string    `json:"agent"`
+	Type       string    `json:"type"`
+	Task       string    `json:"task,omitempty"`
+	Branch     string    `json:"branch,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	ExportedAt time.Time `json:"exported_at"`
+	// TranscriptSource is the session file or terminal log the transcript came from
+	TranscriptSource string `json:"transcript_source,omitempty"`
+
+	Transcript []claude.TranscriptEntry `json:"-"`
+	Messages   []Message                `json:"-"`
+	// Status is `git status --short` in the worktree
+	Status string `json:"-"`
+	// Diff is the worktree's changes against the base, committed or not
+	Diff string `json:"-"`
+}
+
+// Collect gathers an agent's transcript, messages and changes. Parts that
+// can't be found are left empty rather than failing the export. Credentials
+// are scrubbed from all collected text.
+func Collect(src Source) (*Export, error) {
+	e := &Export{
+		Repo:       src.Repo,
+		Agent:      src.Name,
+		Type:       string(src.Agent.Type),
+		Task:       src.Agent.Task,
+		Branch:     src.Agent.Branch,
+		SessionID:  src.Agent.SessionID,
+		ExportedAt: time.Now(),
+	}
+
+	e.collectTranscript(src)
+
+	if src.Messages != nil {
+		msgs, err := collectMessages(src.Messages, src.Repo, src.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read messages: %w", err)
+		}
+		e.Messages = msgs
+	}
+
+	if src.Agent.WorktreePath != "" {
+		e.Status, e.Diff = collectChanges(src.Agent.WorktreePath, src.BaseRef)
+	}
+
+	r := src.Redactor
+	if r == nil {
+		r = redact.New()
+	}
+	e.scrub(r)
+	return e, nil
+}
+
+// collectTranscript reads the Claude session transcript, falling back to the
+// end of the terminal log
+func (e *Export) collectTranscript(src Source) {
+	if src.Agent.SessionID != "" && src.Agent.WorktreePath != "" && src.ClaudeProjectsDir != "" {
+		path := claude.SessionFile(src.ClaudeProjectsDir, src.Agent.WorktreePath, src.Agent.SessionID)
+		if entries, err := claude.ReadTranscript(path); err == nil && len(entries) > 0 {
+			e.Transcript, e.TranscriptSource = entries, path
+			return
+		}
+	}
+
+	if src.LogFile == "" {
+		return
+	}
+	data, err := os.ReadFile(src.LogFile)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	if len(data) > maxLogBytes {
+		data = data[len(data)-maxLogBytes:]
+	}
+	text := strings.TrimSpace(ansiRe.ReplaceAllString(string(data), ""))
+	if text != "" {
+		e.Transcript = []claude.TranscriptEntry{{Role: "terminal", Text: text}}
+		e.TranscriptSource = src.LogFile
+	}
+}
+
+// ansiRe matches terminal escape sequences in captured output
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07|\r`)
+
+// collectMessages returns the messages an agent received and the ones it sent
+// to other agents in the repository, oldest first
+func collectMessages(mgr *messages.Manager, repo, agent string) ([]Message, error) {
+	received, err := mgr.List(repo, agent)
+	if err != nil {
+		return nil, err
+	}
+	var out []Message
+	for _, m := range received {
+		out = append(out, Message{Direction: "received", Message: m})
+	}
+
+	inboxes, err := mgr.Inboxes(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, inbox := range inboxes {
+		if inbox == agent {
+			continue
+		}
+		msgs, err := mgr.List(repo, inbox)
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if m.From == agent {
+				out = append(out, Message{Direction: "sent", Message: m})
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out, nil
+}
+
+// collectChanges returns the worktree's status and its diff against the
+// merge base with baseRef (or against HEAD when there is no base)
+func collectChanges(worktreePath, baseRef string) (string, string) {
+	git := func(args ...string) (string, error) {
+		args = append([]string{"-C", worktreePath}, args...)
+		out, err := retry.Git.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+			return exec.CommandContext(ctx, "git", args...)
+		})
+		return string(out), err
+	}
+
+	status, err := git("status", "--short")
+	if err != nil {
+		return "", ""
+	}
+
+	base := "HEAD"
+	if baseRef != "" {
+		if mergeBase, err := git("merge-base", "HEAD", baseRef); err == nil {
+			base = strings.TrimSpace(mergeBase)
+		}
+	}
+	diff, _ := git("diff", base)
+	return status, diff
+}
+
+// scrub removes credentials from everything collected
+func (e *Export) scrub(r *redact.Redactor) {
+	for i := range e.Transcript {
+		e.Transcript[i].Text = r.Secrets(e.Transcript[i].Text)
+	}
+	for i, m := range e.Messages {
+		scrubbed := *m.Message
+		scrubbed.Body = r.Secrets(scrubbed.Body)
+		e.Messages[i].Message = &scrubbed
+	}
+	e.Diff = r.Secrets(e.Diff)
+}
+
+// Write writes the export in the given format
+func (e *Export) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSONL:
+		return e.WriteJSONL(w)
+	case FormatMarkdown:
+		return e.WriteMarkdown(w)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// WriteJSONL writes one record per line, each with a "kind": an "agent"
+// header, then "turn", "message" and finally "changes" records
+func (e *Export) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	header := struct {
+		Kind string `json:"kind"`
+		*Export
+	}{"agent", e}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, turn := range e.Transcript {
+		if err := enc.Encode(struct {
+			Kind string `json:"kind"`
+			claude.TranscriptEntry
+		}{"turn", turn}); err != nil {
+			return err
+		}
+	}
+	for _, m := range e.Messages {
+		if err := enc.Encode(struct {
+			Kind string `json:"kind"`
+			Message
+		}{"message", m}); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(map[string]string{"kind": "changes", "status": e.Status, "diff": e.Diff})
+}
+
+// WriteMarkdown writes a readable document with a section per part
+func (e *Export) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s/%s\n\n", e.Repo, e.Agent)
+	fmt.Fprintf(&b, "- **Type:** %s\n", e.Type)
+	if e.Task != "" {
+		fmt.Fprintf(&b, "- **Task:** %s\n", e.Task)
+	}
+	if e.Branch != "" {
+		fmt.Fprintf(&b, "- **Branch:** %s\n", e.Branch)
+	}
+	if e.SessionID != "" {
+		fmt.Fprintf(&b, "- **Session:** %s\n", e.SessionID)
+	}
+	fmt.Fprintf(&b, "- **Exported:** %s\n", e.ExportedAt.Format(time.RFC3339))
+
+	b.WriteString("\n## Conversation\n\n")
+	if len(e.Transcript) == 0 {
+		b.WriteString("No transcript found.\n")
+	} else {
+		fmt.Fprintf(&b, "From `%s`.\n", e.TranscriptSource)
+	}
+	for _, turn := range e.Transcript {
+		heading := turn.Role
+		if !turn.Time.IsZero() {
+			heading += " · " + turn.Time.Format("15:04:05")
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", heading, fence(turn.Text, ""))
+	}
+
+	b.WriteString("\n## Messages\n\n")
+	if len(e.Messages) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, m := range e.Messages {
+		peer := "from " + m.From
+		if m.Direction == "sent" {
+			peer = "to " + m.To
+		}
+		fmt.Fprintf(&b, "\n### %s %s · %s\n\n%s\n", m.Direction, peer, m.Timestamp.Format("2006-01-02 15:04:05"), fence(m.Body, ""))
+	}
+
+	b.WriteString("\n## Changes\n\n")
+	if e.Status == "" && e.Diff == "" {
+		b.WriteString("No uncommitted or unmerged changes.\n")
+	} else {
+		if e.Status != "" {
+			fmt.Fprintf(&b, "%s\n\n", fence(e.Status, ""))
+		}
+		if e.Diff != "" {
+			fmt.Fprintf(&b, "%s\n", fence(e.Diff, "diff"))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fence wraps text in a code fence long enough not to be closed by any fence
+// inside it
+func fence(text, lang string) string {
+	marker := "```"
+	for strings.Contains(text, marker) {
+		marker += "`"
+	}
+	return marker + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + marker
+}
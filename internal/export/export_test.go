@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/claude"
+)
+
+func setupSource(t *testing.T) Source {
+	t.Helper()
+	tmp := t.TempDir()
+
+	// A worktree with one commit and an uncommitted change
+	wt := filepath.Join(tmp, "wt")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", wt},
+		{"-C", wt, "-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(wt, "fix.go"), []byte("package fix\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", wt, "add", "fix.go").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+
+	agent := state.Agent{Type: state.AgentTypeWorker, WorktreePath: wt, SessionID: "1234-abcd", Task: "Fix the flaky test", Branch: "work/calm-owl"}
+
+	projects := filepath.Join(tmp, "projects")
+	session := claude.SessionFile(projects, wt, agent.SessionID)
+	if err := os.MkdirAll(filepath.Dir(session), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lines := `{"type":"user","timestamp":"2026-10-16T12:00:00Z","message":{"role":"user","content":"Task: fix it. API_KEY=sk-live-123"}}
+{"type":"assistant","timestamp":"2026-10-16T12:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"On it."}]}}
+`
+	if err := os.WriteFile(session, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := messages.NewManager(filepath.Join(tmp, "messages"))
+	if _, err := mgr.Send("app", "supervisor", "calm-owl", "Please fix the flaky test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Send("app", "calm-owl", "supervisor", "Done, see PR #12"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Send("app", "merge-queue", "supervisor", "Unrelated"); err != nil {
+		t.Fatal(err)
+	}
+
+	return Source{Repo: "app", Name: "calm-owl", Agent: agent, ClaudeProjectsDir: projects, BaseRef: "main", Messages: mgr}
+}
+
+func TestCollect(t *testing.T) {
+	src := setupSource(t)
+	e, err := Collect(src)
+	if err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+
+	if len(e.Transcript) != 2 || e.Transcript[1].Text != "On it." {
+		t.Errorf("Transcript = %+v", e.Transcript)
+	}
+	if strings.Contains(e.Transcript[0].Text, "sk-live-123") {
+		t.Error("transcript still contains a credential")
+	}
+	if len(e.Messages) != 2 || e.Messages[0].Direction != "received" || e.Messages[1].Direction != "sent" || e.Messages[1].To != "supervisor" {
+		t.Errorf("Messages = %+v, want one received then one sent", e.Messages)
+	}
+	if !strings.Contains(e.Status, "fix.go") || !strings.Contains(e.Diff, "+package fix") {
+		t.Errorf("Status = %q, Diff = %q; want the staged file", e.Status, e.Diff)
+	}
+}
+
+func TestCollectFallsBackToLog(t *testing.T) {
+	src := setupSource(t)
+	src.Agent.SessionID = "missing"
+	src.LogFile = filepath.Join(t.TempDir(), "calm-owl.log")
+	if err := os.WriteFile(src.LogFile, []byte("\x1b[1mRunning tests\x1b[0m\r\nok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := Collect(src)
+	if err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+	if len(e.Transcript) != 1 || e.Transcript[0].Role != "terminal" || e.Transcript[0].Text != "Running tests\nok" || e.TranscriptSource != src.LogFile {
+		t.Errorf("Transcript = %+v from %q, want the cleaned terminal log", e.Transcript, e.TranscriptSource)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	e, err := Collect(setupSource(t))
+	if err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+
+	var jsonl bytes.Buffer
+	if err := e.Write(&jsonl, FormatJSONL); err != nil {
+		t.Fatalf("Write(jsonl) failed: %v", err)
+	}
+	var kinds []string
+	for _, line := range strings.Split(strings.TrimSpace(jsonl.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		kinds = append(kinds, record["kind"].(string))
+	}
+	if got := strings.Join(kinds, ","); got != "agent,turn,turn,message,message,changes" {
+		t.Errorf("record kinds = %s", got)
+	}
+
+	var md bytes.Buffer
+	if err := e.Write(&md, FormatMarkdown); err != nil {
+		t.Fatalf("Write(markdown) failed: %v", err)
+	}
+	for _, want := range []string{"# app/calm-owl", "**Task:** Fix the flaky test", "## Conversation", "### assistant", "### sent to supervisor", "```diff"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown is missing %q:\n%s", want, md.String())
+		}
+	}
+
+	if err := e.Write(&md, "html"); err == nil {
+		t.Error("Write() accepted an unknown format")
+	}
+}
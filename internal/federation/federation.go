@@ -0,0 +1,288 @@
+// Package federation lets multiclaude daemons on different machines share a
+// repository: each daemon publishes its workers and outgoing messages to a
+// shared relay and reads its teammates' entries back.
+//
+// A relay is either a branch on the repository's origin (one JSON file per
+// peer, pushed with git plumbing so the working tree is never touched) or a
+// directory every peer can write to, such as a synced folder or a mounted
+// bucket.
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// DefaultBranch is the relay branch used when the repository doesn't name one
+const DefaultBranch = "multiclaude-federation"
+
+// RelayGit selects the git branch relay; any other relay value is a directory path
+const RelayGit = "git"
+
+// MessageTTL is how long an outgoing message stays published for peers to pick up
+const MessageTTL = 24 * time.Hour
+
+// StaleAfter is how long a peer can go without publishing before it's shown as offline
+const StaleAfter = 15 * time.Minute
+
+// Worker is a teammate's worker as published to the relay
+type Worker struct {
+	Name      string    `json:"name"`
+	Task      string    `json:"task"`
+	Branch    string    `json:"branch,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Message is a message between agents on different daemons
+type Message struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from"`     // Sending agent
+	ToPeer  string    `json:"to_peer"`  // Receiving daemon's peer ID
+	ToAgent string    `json:"to_agent"` // Receiving agent
+	Body    string    `json:"body"`
+	Time    time.Time `json:"time"`
+}
+
+// PeerStatus is everything one daemon publishes about one repository
+type PeerStatus struct {
+	Peer      string    `json:"peer"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Workers   []Worker  `json:"workers"`
+	Messages  []Message `json:"messages,omitempty"`
+}
+
+// Stale reports whether the peer hasn't published recently
+func (p PeerStatus) Stale(now time.Time) bool {
+	return now.Sub(p.UpdatedAt) > StaleAfter
+}
+
+// Relay moves peer statuses between daemons
+type Relay interface {
+	// Publish replaces this peer's entry on the relay
+	Publish(status PeerStatus) error
+	// Fetch returns every peer's entry, including this one's
+	Fetch() ([]PeerStatus, error)
+}
+
+// NewRelay returns the relay configured for a repository. namespace keeps
+// repositories apart in a shared directory; it should be the same on every
+// peer (e.g. the GitHub owner/repo).
+func NewRelay(repoPath, namespace string, cfg state.FederationConfig) (Relay, error) {
+	switch {
+	case cfg.Relay == "":
+		return nil, fmt.Errorf("no federation relay configured")
+	case cfg.Relay == RelayGit:
+		branch := cfg.Branch
+		if branch == "" {
+			branch = DefaultBranch
+		}
+		return &GitRelay{RepoPath: repoPath, Remote: "origin", Branch: branch}, nil
+	case filepath.IsAbs(cfg.Relay):
+		return &DirRelay{Dir: filepath.Join(cfg.Relay, filepath.FromSlash(namespace))}, nil
+	default:
+		return nil, fmt.Errorf("invalid federation relay %q (use %q or an absolute directory path)", cfg.Relay, RelayGit)
+	}
+}
+
+var peerIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+
+// ValidatePeerID checks that a peer ID is usable as a relay file name
+func ValidatePeerID(id string) error {
+	if !peerIDRe.MatchString(id) || strings.Contains(id, "..") {
+		return fmt.Errorf("invalid peer ID %q (use letters, digits, '.', '_', '@' and '-')", id)
+	}
+	return nil
+}
+
+// DefaultPeerID names this daemon as <user>@<host>
+func DefaultPeerID() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	host, _, _ = strings.Cut(host, ".")
+
+	id := sanitizePeerPart(name) + "@" + sanitizePeerPart(host)
+	if ValidatePeerID(id) != nil {
+		return "multiclaude@localhost"
+	}
+	return id
+}
+
+// PeerID returns the configured peer ID or the default
+func PeerID(cfg state.FederationConfig) string {
+	if cfg.PeerID != "" {
+		return cfg.PeerID
+	}
+	return DefaultPeerID()
+}
+
+func sanitizePeerPart(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() == 0 {
+		return "unknown"
+	}
+	return sb.String()
+}
+
+// ParseAddress splits a remote recipient "<agent>@<peer>". The peer part may
+// itself contain '@' (peer IDs default to user@host), so the split is at the first '@'.
+func ParseAddress(addr string) (agent, peer string, ok bool) {
+	agent, peer, ok = strings.Cut(addr, "@")
+	if !ok || agent == "" || peer == "" {
+		return "", "", false
+	}
+	return agent, peer, true
+}
+
+// Inbound returns messages for peer that were published after the given
+// per-sender cutoffs, oldest first, and the new cutoffs
+func Inbound(peers []PeerStatus, peer string, after map[string]time.Time) ([]InboundMessage, map[string]time.Time) {
+	cutoffs := make(map[string]time.Time, len(after))
+	for k, v := range after {
+		cutoffs[k] = v
+	}
+
+	var inbound []InboundMessage
+	for _, p := range peers {
+		if p.Peer == peer {
+			continue
+		}
+		for _, m := range p.Messages {
+			if m.ToPeer != peer || !m.Time.After(after[p.Peer]) {
+				continue
+			}
+			inbound = append(inbound, InboundMessage{Peer: p.Peer, Message: m})
+			if m.Time.After(cutoffs[p.Peer]) {
+				cutoffs[p.Peer] = m.Time
+			}
+		}
+	}
+
+	sort.Slice(inbound, func(i, j int) bool { return inbound[i].Time.Before(inbound[j].Time) })
+	return inbound, cutoffs
+}
+
+// InboundMessage is a message received from another peer
+type InboundMessage struct {
+	Peer string // Sending peer
+	Message
+}
+
+// SimilarWork is a teammate's worker whose task resembles a new one
+type SimilarWork struct {
+	Peer   string
+	Worker Worker
+}
+
+// FindSimilar returns teammates' workers (on peers other than self) whose tasks
+// look like task, so the same work isn't assigned twice
+func FindSimilar(task string, peers []PeerStatus, self string) []SimilarWork {
+	var similar []SimilarWork
+	for _, p := range peers {
+		if p.Peer == self {
+			continue
+		}
+		for _, w := range p.Workers {
+			if state.SimilarTasks(task, w.Task) {
+				similar = append(similar, SimilarWork{Peer: p.Peer, Worker: w})
+			}
+		}
+	}
+	return similar
+}
+
+// Store is a daemon's local federation bookkeeping for one repository: messages
+// waiting to be published and how far each peer's messages have been received
+type Store struct {
+	Outbox   []Message            `json:"outbox,omitempty"`
+	Received map[string]time.Time `json:"received,omitempty"`
+
+	path string
+}
+
+// LoadStore reads a store, returning an empty one if the file doesn't exist
+func LoadStore(path string) (*Store, error) {
+	s := &Store{path: path, Received: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read federation store: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse federation store: %w", err)
+	}
+	if s.Received == nil {
+		s.Received = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+// Save writes the store back to disk
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create federation directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal federation store: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// Queue adds an outgoing message
+func (s *Store) Queue(m Message) {
+	s.Outbox = append(s.Outbox, m)
+}
+
+// Prune drops outgoing messages older than MessageTTL
+func (s *Store) Prune(now time.Time) {
+	kept := s.Outbox[:0]
+	for _, m := range s.Outbox {
+		if now.Sub(m.Time) < MessageTTL {
+			kept = append(kept, m)
+		}
+	}
+	s.Outbox = kept
+}
+
+// writeFileAtomic writes data via a temp file and rename so readers on a shared
+// relay never see a half-written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".federation-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package federation
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestDirRelayRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	relay, err := NewRelay("/unused", "owner/repo", state.FederationConfig{Enabled: true, Relay: dir})
+	if err != nil {
+		t.Fatalf("NewRelay failed: %v", err)
+	}
+
+	peers, err := relay.Fetch()
+	if err != nil || len(peers) != 0 {
+		t.Fatalf("Fetch on empty relay = %v, %v; want no peers", peers, err)
+	}
+
+	alice := PeerStatus{Peer: "alice@laptop", UpdatedAt: time.Now(), Workers: []Worker{{Name: "calm-owl", Task: "fix login"}}}
+	bob := PeerStatus{Peer: "bob@desktop", UpdatedAt: time.Now()}
+	for _, p := range []PeerStatus{alice, bob} {
+		if err := relay.Publish(p); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", p.Peer, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "owner", "repo", "alice@laptop.json")); err != nil {
+		t.Errorf("expected peer file under namespace: %v", err)
+	}
+
+	peers, err = relay.Fetch()
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("Fetch returned %d peers, want 2", len(peers))
+	}
+	for _, p := range peers {
+		if p.Peer == "alice@laptop" && (len(p.Workers) != 1 || p.Workers[0].Name != "calm-owl") {
+			t.Errorf("alice's workers = %+v", p.Workers)
+		}
+	}
+
+	if err := relay.Publish(PeerStatus{Peer: "../escape"}); err == nil {
+		t.Error("Publish should reject an invalid peer ID")
+	}
+}
+
+func TestGitRelay(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmp := t.TempDir()
+	origin := filepath.Join(tmp, "origin.git")
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	run(tmp, "init", "--bare", "--quiet", origin)
+	run(tmp, "clone", "--quiet", origin, filepath.Join(tmp, "a"))
+	run(tmp, "clone", "--quiet", origin, filepath.Join(tmp, "b"))
+
+	cfg := state.FederationConfig{Enabled: true, Relay: RelayGit}
+	relayA, err := NewRelay(filepath.Join(tmp, "a"), "owner/repo", cfg)
+	if err != nil {
+		t.Fatalf("NewRelay failed: %v", err)
+	}
+	relayB, _ := NewRelay(filepath.Join(tmp, "b"), "owner/repo", cfg)
+
+	// No relay branch yet
+	peers, err := relayB.Fetch()
+	if err != nil || len(peers) != 0 {
+		t.Fatalf("Fetch before publish = %v, %v; want no peers", peers, err)
+	}
+
+	msg := Message{ID: "fed-1", From: "worker", ToPeer: "bob@desktop", ToAgent: "supervisor", Body: "hi", Time: time.Now()}
+	if err := relayA.Publish(PeerStatus{Peer: "alice@laptop", UpdatedAt: time.Now(), Messages: []Message{msg}}); err != nil {
+		t.Fatalf("Publish from a failed: %v", err)
+	}
+	if err := relayB.Publish(PeerStatus{Peer: "bob@desktop", UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("Publish from b failed: %v", err)
+	}
+	// Republishing replaces only the peer's own file
+	if err := relayA.Publish(PeerStatus{Peer: "alice@laptop", UpdatedAt: time.Now(), Messages: []Message{msg}, Workers: []Worker{{Name: "w1"}}}); err != nil {
+		t.Fatalf("second Publish from a failed: %v", err)
+	}
+
+	peers, err = relayB.Fetch()
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("Fetch returned %d peers, want 2: %+v", len(peers), peers)
+	}
+	inbound, _ := Inbound(peers, "bob@desktop", nil)
+	if len(inbound) != 1 || inbound[0].Body != "hi" || inbound[0].Peer != "alice@laptop" {
+		t.Errorf("Inbound = %+v, want alice's message", inbound)
+	}
+
+	// The clones' branches and working trees are untouched
+	cmd := exec.Command("git", "status", "--porcelain")
+	cmd.Dir = filepath.Join(tmp, "a")
+	if out, _ := cmd.Output(); len(out) != 0 {
+		t.Errorf("working tree changed by relay: %s", out)
+	}
+}
+
+func TestNewRelayInvalid(t *testing.T) {
+	for _, relay := range []string{"", "relative/dir"} {
+		if _, err := NewRelay("/repo", "ns", state.FederationConfig{Relay: relay}); err == nil {
+			t.Errorf("NewRelay(%q) should fail", relay)
+		}
+	}
+}
+
+func TestInboundCutoffs(t *testing.T) {
+	t0 := time.Now()
+	peers := []PeerStatus{
+		{Peer: "alice@a", Messages: []Message{
+			{ID: "2", ToPeer: "me@x", ToAgent: "supervisor", Time: t0.Add(2 * time.Second)},
+			{ID: "1", ToPeer: "me@x", ToAgent: "supervisor", Time: t0.Add(time.Second)},
+			{ID: "other", ToPeer: "carol@c", Time: t0.Add(3 * time.Second)},
+		}},
+		{Peer: "me@x", Messages: []Message{{ID: "own", ToPeer: "me@x", Time: t0}}},
+	}
+
+	inbound, cutoffs := Inbound(peers, "me@x", nil)
+	if len(inbound) != 2 || inbound[0].ID != "1" || inbound[1].ID != "2" {
+		t.Fatalf("Inbound = %+v, want messages 1 and 2 in order", inbound)
+	}
+	if !cutoffs["alice@a"].Equal(t0.Add(2 * time.Second)) {
+		t.Errorf("cutoff = %v, want time of message 2", cutoffs["alice@a"])
+	}
+
+	// Already-received messages aren't delivered again
+	inbound, _ = Inbound(peers, "me@x", cutoffs)
+	if len(inbound) != 0 {
+		t.Errorf("second Inbound = %+v, want none", inbound)
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	peers := []PeerStatus{
+		{Peer: "alice@a", Workers: []Worker{
+			{Name: "w1", Task: "Fix the login timeout on the settings page"},
+			{Name: "w2", Task: "Add dark mode"},
+		}},
+		{Peer: "me@x", Workers: []Worker{{Name: "mine", Task: "fix login timeout on settings page"}}},
+	}
+
+	similar := FindSimilar("fix login timeout on settings page", peers, "me@x")
+	if len(similar) != 1 || similar[0].Worker.Name != "w1" || similar[0].Peer != "alice@a" {
+		t.Errorf("FindSimilar = %+v, want alice's w1 only", similar)
+	}
+	if got := FindSimilar("update the changelog", peers, "me@x"); len(got) != 0 {
+		t.Errorf("FindSimilar for unrelated task = %+v, want none", got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation", "repo.json")
+	s, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore on missing file failed: %v", err)
+	}
+
+	now := time.Now()
+	s.Queue(Message{ID: "old", Time: now.Add(-MessageTTL - time.Minute)})
+	s.Queue(Message{ID: "new", Time: now})
+	s.Received["alice@a"] = now
+	s.Prune(now)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore failed: %v", err)
+	}
+	if len(loaded.Outbox) != 1 || loaded.Outbox[0].ID != "new" {
+		t.Errorf("Outbox = %+v, want only the new message", loaded.Outbox)
+	}
+	if !loaded.Received["alice@a"].Equal(now) {
+		t.Errorf("Received cutoff not persisted: %v", loaded.Received)
+	}
+}
+
+func TestParseAddressAndPeerID(t *testing.T) {
+	agent, peer, ok := ParseAddress("supervisor@alice@laptop")
+	if !ok || agent != "supervisor" || peer != "alice@laptop" {
+		t.Errorf("ParseAddress = %q, %q, %v", agent, peer, ok)
+	}
+	for _, bad := range []string{"supervisor", "@peer", "agent@"} {
+		if _, _, ok := ParseAddress(bad); ok {
+			t.Errorf("ParseAddress(%q) should fail", bad)
+		}
+	}
+
+	for _, id := range []string{"alice@laptop", "ci-runner.1"} {
+		if err := ValidatePeerID(id); err != nil {
+			t.Errorf("ValidatePeerID(%q) = %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "a/b", "..", "-x", "a b"} {
+		if err := ValidatePeerID(id); err == nil {
+			t.Errorf("ValidatePeerID(%q) should fail", id)
+		}
+	}
+	if err := ValidatePeerID(DefaultPeerID()); err != nil {
+		t.Errorf("DefaultPeerID is invalid: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+)
+
+// peerFile is the relay file name for a peer
+func peerFile(peer string) string {
+	return peer + ".json"
+}
+
+// DirRelay keeps one file per peer in a directory shared between machines
+type DirRelay struct {
+	Dir string
+}
+
+// Publish writes this peer's status file
+func (r *DirRelay) Publish(status PeerStatus) error {
+	if err := ValidatePeerID(status.Peer); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create relay directory: %w", err)
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal peer status: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(r.Dir, peerFile(status.Peer)), data)
+}
+
+// Fetch reads every peer's status file. Unreadable files are skipped so one
+// broken peer doesn't hide the others.
+func (r *DirRelay) Fetch() ([]PeerStatus, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read relay directory: %w", err)
+	}
+
+	var peers []PeerStatus
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.Dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var status PeerStatus
+		if err := json.Unmarshal(data, &status); err != nil || status.Peer == "" {
+			continue
+		}
+		peers = append(peers, status)
+	}
+	return peers, nil
+}
+
+// gitPushAttempts bounds retries when another peer pushes to the relay branch first
+const gitPushAttempts = 3
+
+// GitRelay keeps one file per peer on a dedicated branch of the repository's
+// remote. Commits are built with plumbing commands, so the clone's working tree
+// and HEAD are never touched. Peers only ever change their own file, so a
+// rejected push is resolved by rebuilding on top of the new remote tip.
+type GitRelay struct {
+	RepoPath string
+	Remote   string
+	Branch   string
+}
+
+// trackingRef is where the relay branch is fetched to, out of the way of normal branches
+func (r *GitRelay) trackingRef() string {
+	return "refs/multiclaude/federation/" + r.Branch
+}
+
+// Publish commits this peer's status file onto the relay branch and pushes it
+func (r *GitRelay) Publish(status PeerStatus) error {
+	if err := ValidatePeerID(status.Peer); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal peer status: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < gitPushAttempts; attempt++ {
+		parent, err := r.fetch()
+		if err != nil {
+			return err
+		}
+		commit, err := r.commitFile(parent, peerFile(status.Peer), data, "federation: update "+status.Peer)
+		if err != nil {
+			return err
+		}
+		if _, err := r.git(nil, "push", "--quiet", r.Remote, commit+":refs/heads/"+r.Branch); err != nil {
+			// Most likely another peer pushed first; rebuild on their commit
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to push to relay branch %s: %w", r.Branch, lastErr)
+}
+
+// Fetch reads every peer's status file from the relay branch
+func (r *GitRelay) Fetch() ([]PeerStatus, error) {
+	tip, err := r.fetch()
+	if err != nil || tip == "" {
+		return nil, err
+	}
+
+	out, err := r.git(nil, "ls-tree", tip)
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []PeerStatus
+	for _, entry := range parseTree(out) {
+		if !strings.HasSuffix(entry.name, ".json") {
+			continue
+		}
+		data, err := r.git(nil, "cat-file", "blob", entry.sha)
+		if err != nil {
+			continue
+		}
+		var status PeerStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil || status.Peer == "" {
+			continue
+		}
+		peers = append(peers, status)
+	}
+	return peers, nil
+}
+
+// fetch updates the tracking ref and returns its commit, or "" if the relay
+// branch doesn't exist yet
+func (r *GitRelay) fetch() (string, error) {
+	if _, err := r.git(nil, "fetch", "--quiet", r.Remote, "+refs/heads/"+r.Branch+":"+r.trackingRef()); err != nil {
+		// A missing branch just means no peer has published yet
+		if !strings.Contains(err.Error(), "couldn't find remote ref") {
+			return "", fmt.Errorf("failed to fetch relay branch %s: %w", r.Branch, err)
+		}
+		return "", nil
+	}
+	tip, err := r.git(nil, "rev-parse", "--verify", "--quiet", r.trackingRef())
+	if err != nil {
+		return "", nil
+	}
+	return strings.TrimSpace(tip), nil
+}
+
+// commitFile creates a commit on top of parent (if any) with file name set to data
+func (r *GitRelay) commitFile(parent, name string, data []byte, message string) (string, error) {
+	blob, err := r.git(data, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return "", err
+	}
+
+	var tree strings.Builder
+	if parent != "" {
+		out, err := r.git(nil, "ls-tree", parent)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range parseTree(out) {
+			if entry.name != name {
+				fmt.Fprintf(&tree, "%s %s %s\t%s\n", entry.mode, entry.kind, entry.sha, entry.name)
+			}
+		}
+	}
+	fmt.Fprintf(&tree, "100644 blob %s\t%s\n", strings.TrimSpace(blob), name)
+
+	treeSHA, err := r.git([]byte(tree.String()), "mktree")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"commit-tree", strings.TrimSpace(treeSHA), "-m", message}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	commit, err := r.git(nil, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+type treeEntry struct {
+	mode, kind, sha, name string
+}
+
+// parseTree parses `git ls-tree` output
+func parseTree(out string) []treeEntry {
+	var entries []treeEntry
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		meta, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 {
+			continue
+		}
+		entries = append(entries, treeEntry{mode: fields[0], kind: fields[1], sha: fields[2], name: name})
+	}
+	return entries
+}
+
+// git runs a git command in the repository with an optional stdin, using a
+// fixed identity so relay commits work on machines without git user config
+func (r *GitRelay) git(stdin []byte, args ...string) (string, error) {
+	out, err := gitcmd.Cmd{
+		Dir:  r.RepoPath,
+		Args: args,
+		Env: []string{
+			"GIT_AUTHOR_NAME=multiclaude", "GIT_AUTHOR_EMAIL=multiclaude@localhost",
+			"GIT_COMMITTER_NAME=multiclaude", "GIT_COMMITTER_EMAIL=multiclaude@localhost",
+		},
+		Stdin: stdin,
+	}.Output(context.Background())
+	return string(out), err
+}
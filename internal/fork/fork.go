@@ -2,11 +2,14 @@
 package fork
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os/exec"
 	"regexp"
 	"strings"
+
+	"github.com/micheal-at/multiclaude/pkg/retry"
 )
 
 // ForkInfo contains information about whether a repository is a fork
@@ -88,8 +91,7 @@ func DetectFork(repoPath string) (*ForkInfo, error) {
 
 // getRemoteURL returns the URL of a git remote.
 func getRemoteURL(repoPath, remoteName string) (string, error) {
-	cmd := exec.Command("git", "-C", repoPath, "remote", "get-url", remoteName)
-	output, err := cmd.Output()
+	output, err := gitCommand(repoPath, "remote", "get-url", remoteName)
 	if err != nil {
 		return "", err
 	}
@@ -121,9 +123,10 @@ func ParseGitHubURL(url string) (owner, repo string, err error) {
 // detectForkViaGitHubAPI uses the gh CLI to check if a repo is a fork.
 func detectForkViaGitHubAPI(owner, repo string) (*ForkInfo, error) {
 	// Use gh api to get repo info
-	cmd := exec.Command("gh", "api", fmt.Sprintf("repos/%s/%s", owner, repo),
-		"--jq", "{fork: .fork, parent_owner: .parent.owner.login, parent_repo: .parent.name, parent_url: .parent.clone_url}")
-	output, err := cmd.Output()
+	output, err := retry.GitHub.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "gh", "api", fmt.Sprintf("repos/%s/%s", owner, repo),
+			"--jq", "{fork: .fork, parent_owner: .parent.owner.login, parent_repo: .parent.name, parent_url: .parent.clone_url}")
+	})
 	if err != nil {
 		return nil, fmt.Errorf("gh api failed: %w", err)
 	}
@@ -158,13 +161,22 @@ func AddUpstreamRemote(repoPath, upstreamURL string) error {
 	_, err := getRemoteURL(repoPath, "upstream")
 	if err == nil {
 		// Upstream already exists - update it
-		cmd := exec.Command("git", "-C", repoPath, "remote", "set-url", "upstream", upstreamURL)
-		return cmd.Run()
+		_, err := gitCommand(repoPath, "remote", "set-url", "upstream", upstreamURL)
+		return err
 	}
 
 	// Add new upstream remote
-	cmd := exec.Command("git", "-C", repoPath, "remote", "add", "upstream", upstreamURL)
-	return cmd.Run()
+	_, err = gitCommand(repoPath, "remote", "add", "upstream", upstreamURL)
+	return err
+}
+
+// gitCommand runs git in repoPath under its retry policy and returns its
+// standard output
+func gitCommand(repoPath string, args ...string) ([]byte, error) {
+	args = append([]string{"-C", repoPath}, args...)
+	return retry.ForGit(args...).Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "git", args...)
+	})
 }
 
 // HasUpstreamRemote checks if the upstream remote is configured.
@@ -68,9 +68,7 @@ func StatusIcon(status Status) string {
 
 // ColoredStatus returns a colored status string with icon
 func ColoredStatus(status Status) string {
-	c := StatusColor(status)
-	icon := StatusIcon(status)
-	return c.Sprintf("%s %s", icon, status)
+	return StatusLabel(status, string(status))
 }
 
 // Header prints a bold header line
@@ -114,15 +112,30 @@ func TimeAgo(t time.Time) string {
 	}
 }
 
-// Truncate truncates a string to maxLen, adding "..." if truncated
+// Size formats a byte count with a binary unit, e.g. "1.5 MB"
+func Size(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// Truncate truncates a string to maxLen characters, adding "..." if truncated
 func Truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
 		return s
 	}
 	if maxLen <= 3 {
-		return s[:maxLen]
+		return string(runes[:maxLen])
 	}
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }
 
 // Table provides a simple table formatter
@@ -201,6 +214,9 @@ type ColoredTable struct {
 	rows         [][]ColoredCell
 	widths       []int
 	headerColors []*color.Color
+	// minWidths are how narrow flexible columns may get to fit the
+	// terminal; other columns have 0 and keep their width
+	minWidths []int
 }
 
 // ColoredCell represents a cell with optional color
@@ -224,14 +240,27 @@ func NewColoredTable(headers ...string) *ColoredTable {
 	widths := make([]int, len(headers))
 	headerColors := make([]*color.Color, len(headers))
 	for i, h := range headers {
-		widths[i] = len(h)
+		widths[i] = VisibleWidth(h)
 		headerColors[i] = Bold
 	}
 	return &ColoredTable{
 		headers:      headers,
 		widths:       widths,
 		headerColors: headerColors,
+		minWidths:    make([]int, len(headers)),
+	}
+}
+
+// Flexible lets the column with the given header shrink, down to minWidth
+// or the header's width, when the table is wider than the terminal. Its
+// cells are truncated to fit; the table's other columns keep their width.
+func (t *ColoredTable) Flexible(header string, minWidth int) *ColoredTable {
+	for i, h := range t.headers {
+		if h == header {
+			t.minWidths[i] = max(minWidth, VisibleWidth(h), 1)
+		}
 	}
+	return t
 }
 
 // AddRow adds a row to the colored table
@@ -241,43 +270,93 @@ func (t *ColoredTable) AddRow(cells ...ColoredCell) {
 		if i < len(cells) {
 			row[i] = cells[i]
 		}
-		if len(row[i].Text) > t.widths[i] {
-			t.widths[i] = len(row[i].Text)
+		if w := VisibleWidth(row[i].Text); w > t.widths[i] {
+			t.widths[i] = w
 		}
 	}
 	t.rows = append(t.rows, row)
 }
 
-// Print prints the colored table
+// Print prints the colored table, fitting it to the terminal's width
 func (t *ColoredTable) Print() {
+	fmt.Print(t.render(TerminalWidth()))
+}
+
+// render returns the table with its flexible columns shrunk to fit in
+// maxWidth columns, or as they are when maxWidth is 0
+func (t *ColoredTable) render(maxWidth int) string {
+	widths := t.fit(maxWidth)
+	var sb strings.Builder
+
 	// Header
 	for i, h := range t.headers {
 		if i > 0 {
-			fmt.Print("  ")
+			sb.WriteString("  ")
 		}
-		t.headerColors[i].Printf("%-*s", t.widths[i], h)
+		sb.WriteString(t.headerColors[i].Sprint(pad(h, widths[i])))
 	}
-	fmt.Println()
+	sb.WriteString("\n")
 
 	// Separator
-	Dim.Print(strings.Repeat("-", t.totalWidth()))
-	fmt.Println()
+	total := 0
+	for i, w := range widths {
+		total += w
+		if i > 0 {
+			total += 2 // spacing
+		}
+	}
+	sb.WriteString(Dim.Sprint(strings.Repeat("-", total)))
+	sb.WriteString("\n")
 
 	// Rows
 	for _, row := range t.rows {
 		for i, cell := range row {
 			if i > 0 {
-				fmt.Print("  ")
+				sb.WriteString("  ")
 			}
-			text := fmt.Sprintf("%-*s", t.widths[i], cell.Text)
+			text := cell.Text
+			if VisibleWidth(text) > widths[i] {
+				text = Truncate(StripANSI(text), widths[i])
+			}
+			text = pad(text, widths[i])
 			if cell.Color != nil {
-				cell.Color.Print(text)
-			} else {
-				fmt.Print(text)
+				text = cell.Color.Sprint(text)
+			}
+			sb.WriteString(text)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// fit returns the column widths with the flexible columns shrunk, widest
+// first, until the table fits in maxWidth or they're as narrow as they go
+func (t *ColoredTable) fit(maxWidth int) []int {
+	widths := append([]int(nil), t.widths...)
+	if maxWidth <= 0 {
+		return widths
+	}
+	for excess := t.totalWidth() - maxWidth; excess > 0; excess-- {
+		widest := -1
+		for i, w := range widths {
+			if t.minWidths[i] > 0 && w > t.minWidths[i] && (widest < 0 || w > widths[widest]) {
+				widest = i
 			}
 		}
-		fmt.Println()
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+// pad pads s with spaces to width visible columns
+func pad(s string, width int) string {
+	if n := width - VisibleWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
 	}
+	return s
 }
 
 func (t *ColoredTable) totalWidth() int {
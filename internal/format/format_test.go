@@ -111,6 +111,26 @@ func TestTruncate(t *testing.T) {
 	}
 }
 
+func TestSize(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{10 * 1024 * 1024, "10.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := Size(tt.bytes); got != tt.want {
+			t.Errorf("Size(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
+
 func TestTable(t *testing.T) {
 	table := NewTable("Name", "Age", "City")
 	table.AddRow("Alice", "30", "NYC")
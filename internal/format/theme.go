@@ -0,0 +1,67 @@
+package format
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"golang.org/x/sys/unix"
+)
+
+// colorDefault is whether color was off before any SetPlain: color turns
+// itself off when NO_COLOR is set, TERM is dumb or stdout isn't a terminal
+var colorDefault = color.NoColor
+
+// plain is whether output is plain: no color and no status icons
+var plain bool
+
+// SetPlain turns plain output on or off. Turning it off brings color back
+// only where it would have been on anyway.
+func SetPlain(on bool) {
+	plain = on
+	color.NoColor = on || colorDefault
+}
+
+// Plain reports whether output is plain
+func Plain() bool {
+	return plain
+}
+
+// StatusLabel returns label with status's icon, in status's color. Plain
+// output is label alone.
+func StatusLabel(status Status, label string) string {
+	if plain {
+		return label
+	}
+	return StatusColor(status).Sprintf("%s %s", StatusIcon(status), label)
+}
+
+// ansiPattern matches the escape sequences color wraps text in
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// StripANSI removes color escape sequences from s
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// VisibleWidth returns how many columns s takes up in a terminal: its
+// characters, not counting color escape sequences
+func VisibleWidth(s string) int {
+	return utf8.RuneCountInString(StripANSI(s))
+}
+
+// TerminalWidth returns how many columns stdout's terminal has: COLUMNS when
+// it's set, else what the terminal reports. It's 0 when stdout isn't a
+// terminal, where nothing needs to fit.
+func TerminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}
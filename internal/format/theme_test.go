@@ -0,0 +1,95 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSetPlain(t *testing.T) {
+	saved := color.NoColor
+	defer func() { SetPlain(false); color.NoColor = saved }()
+
+	SetPlain(true)
+	if !Plain() || !color.NoColor {
+		t.Fatal("SetPlain(true) left color on")
+	}
+	if got := ColoredStatus(StatusError); got != "error" {
+		t.Errorf("plain ColoredStatus(error) = %q, want no icon", got)
+	}
+	if got := StatusLabel(StatusError, "stopped"); got != "stopped" {
+		t.Errorf("plain StatusLabel() = %q, want stopped", got)
+	}
+
+	SetPlain(false)
+	if Plain() || color.NoColor != colorDefault {
+		t.Error("SetPlain(false) didn't restore the default")
+	}
+	if got := StripANSI(StatusLabel(StatusError, "stopped")); got != "✗ stopped" {
+		t.Errorf("StatusLabel() = %q, want the icon", got)
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	red := color.New(color.FgRed)
+	red.EnableColor()
+	colored := red.Sprint("stopped")
+	if colored == "stopped" {
+		t.Fatal("expected escape sequences in colored text")
+	}
+	if got := VisibleWidth(colored); got != 7 {
+		t.Errorf("VisibleWidth(colored) = %d, want 7", got)
+	}
+	if got := VisibleWidth("✓ ok"); got != 4 {
+		t.Errorf("VisibleWidth(icon) = %d, want 4", got)
+	}
+}
+
+func TestTerminalWidthColumns(t *testing.T) {
+	t.Setenv("COLUMNS", "132")
+	if got := TerminalWidth(); got != 132 {
+		t.Errorf("TerminalWidth() = %d, want 132", got)
+	}
+}
+
+func TestColoredTableFit(t *testing.T) {
+	saved := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = saved }()
+
+	task := "Implement the thing that takes a long description to explain"
+	table := NewColoredTable("NAME", "STATUS", "TASK").Flexible("TASK", 10)
+	table.AddRow(Cell("worker-1"), Cell("running"), Cell(task))
+
+	// Unlimited width shows everything
+	if out := table.render(0); !strings.Contains(out, task) {
+		t.Errorf("render(0) truncated the task:\n%s", out)
+	}
+
+	// A narrow terminal shrinks only the flexible column
+	out := table.render(40)
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if w := VisibleWidth(line); w > 40 {
+			t.Errorf("line %q is %d wide, want at most 40", line, w)
+		}
+	}
+	if !strings.Contains(out, "worker-1  running") || !strings.Contains(out, "...") {
+		t.Errorf("render(40) = \n%s", out)
+	}
+
+	// The flexible column doesn't shrink below its minimum
+	if widths := table.fit(5); widths[2] != 10 || widths[0] != 8 {
+		t.Errorf("fit(5) = %v, want TASK at its minimum of 10 and NAME unchanged", widths)
+	}
+}
+
+func TestColoredTableColoredCellWidth(t *testing.T) {
+	red := color.New(color.FgRed)
+	red.EnableColor()
+	table := NewColoredTable("STATUS")
+	table.AddRow(Cell(red.Sprint("stopped")))
+	if table.widths[0] != len("stopped") {
+		t.Errorf("width = %d, want the visible width %d", table.widths[0], len("stopped"))
+	}
+}
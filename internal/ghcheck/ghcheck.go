@@ -0,0 +1,77 @@
+// Package ghcheck tells whether the GitHub CLI can be used: whether gh is
+// installed, and logged in or given a token through GH_TOKEN or
+// GITHUB_TOKEN. Everything multiclaude does on GitHub goes through gh, so
+// without it PR tracking, the merge train and the like are turned off while
+// tmux, worktrees and messaging carry on.
+package ghcheck
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Features lists what the daemon turns off while gh can't be used
+var Features = []string{
+	"PR status",
+	"merge train",
+	"worker ready (draft PRs)",
+	"PR descriptions",
+	"comment commands",
+}
+
+// Status is the outcome of a check
+type Status struct {
+	Available bool `json:"available"`
+	// Problem says why gh can't be used, and Fix what to do about it
+	Problem   string    `json:"problem,omitempty"`
+	Fix       string    `json:"fix,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Checked reports whether the status is from a check, rather than the zero
+// value of one not yet run
+func (s Status) Checked() bool {
+	return !s.CheckedAt.IsZero()
+}
+
+// Check runs gh auth status, which succeeds when gh has a usable token,
+// whether from gh auth login or the environment
+func Check(ctx context.Context) Status {
+	status := Status{CheckedAt: time.Now()}
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		status.Problem = "gh is not installed"
+		status.Fix = "install the GitHub CLI (https://cli.github.com), then run: gh auth login"
+		return status
+	}
+
+	cmd := exec.CommandContext(ctx, path, "auth", "status")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			status.Problem = "gh auth status timed out"
+			status.Fix = "check your network connection"
+			return status
+		}
+		status.Problem = "gh is not logged in to GitHub"
+		if line := lastLine(output.String()); line != "" {
+			status.Problem += ": " + line
+		}
+		status.Fix = "run: gh auth login (or set GH_TOKEN)"
+		return status
+	}
+	status.Available = true
+	return status
+}
+
+// lastLine returns the last non-empty line of gh's output, which says what's
+// wrong with the login
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,53 @@
+package ghcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeGH puts a gh on PATH that runs script
+func fakeGH(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("not installed", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		status := Check(context.Background())
+		if status.Available || status.Problem != "gh is not installed" || !status.Checked() {
+			t.Errorf("Check() = %+v, want gh not installed", status)
+		}
+	})
+
+	t.Run("not logged in", func(t *testing.T) {
+		fakeGH(t, `echo "You are not logged into any GitHub hosts. To log in, run: gh auth login" >&2; exit 1`)
+		status := Check(context.Background())
+		if status.Available || !strings.Contains(status.Problem, "not logged in") || !strings.Contains(status.Problem, "not logged into any GitHub hosts") {
+			t.Errorf("Check() = %+v, want gh not logged in, with gh's reason", status)
+		}
+		if status.Fix == "" {
+			t.Error("Check() gave no fix")
+		}
+	})
+
+	t.Run("logged in", func(t *testing.T) {
+		fakeGH(t, `echo "Logged in to github.com account octocat"`)
+		if status := Check(context.Background()); !status.Available || status.Problem != "" {
+			t.Errorf("Check() = %+v, want available", status)
+		}
+	})
+}
+
+func TestStatusChecked(t *testing.T) {
+	if (Status{}).Checked() {
+		t.Error("the zero Status reports itself checked")
+	}
+}
@@ -0,0 +1,146 @@
+// Package gitcmd runs git the same way everywhere in multiclaude: transient
+// failures, such as another git process holding index.lock, are retried
+// under retry.ForGit, each attempt is killed when its context ends or it
+// outlasts the policy's timeout, and a failure's error names the subcommand
+// and carries what git printed about it.
+package gitcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/pkg/retry"
+)
+
+// Cmd is a git command
+type Cmd struct {
+	Dir  string // Where git runs; "" is the current directory
+	Args []string
+	// Env is added to the environment git inherits
+	Env []string
+	// Stdin, if not nil, is git's standard input on every attempt
+	Stdin []byte
+	// Progress, if not nil, receives git's standard error as git writes it.
+	// A failure's error then carries only git's last line, since the rest
+	// has been shown.
+	Progress io.Writer
+	// Unbounded lifts the policy's per-attempt timeout, for transfers that
+	// take as long as the repository's size needs. Bound them with the
+	// context instead.
+	Unbounded bool
+}
+
+// Output runs the command and returns its standard output
+func (c Cmd) Output(ctx context.Context) ([]byte, error) {
+	var stderr bytes.Buffer
+	out, err := c.policy().Output(ctx, func(ctx context.Context) *exec.Cmd {
+		cmd := c.command(ctx)
+		stderr.Reset()
+		cmd.Stderr = &stderr
+		if c.Progress != nil {
+			cmd.Stderr = io.MultiWriter(c.Progress, &stderr)
+		}
+		return cmd
+	})
+	if err != nil {
+		return out, c.error(err, stderr.String())
+	}
+	return out, nil
+}
+
+// CombinedOutput runs the command and returns its combined standard output
+// and error. A failure's error names the subcommand; what git printed about
+// it is in the output. Progress isn't used.
+func (c Cmd) CombinedOutput(ctx context.Context) ([]byte, error) {
+	out, err := c.policy().CombinedOutput(ctx, c.command)
+	if err != nil {
+		return out, c.error(err, "")
+	}
+	return out, nil
+}
+
+// policy returns the retry policy for the command
+func (c Cmd) policy() retry.Policy {
+	policy := retry.ForGit(c.Args...)
+	if c.Unbounded {
+		policy.Timeout = 0
+	}
+	return policy
+}
+
+// command builds one attempt of the command
+func (c Cmd) command(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	if c.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(c.Stdin)
+	}
+	return cmd
+}
+
+// error explains a failed run, given what git wrote to standard error
+func (c Cmd) error(err error, stderr string) error {
+	name := "git"
+	if sub := subcommand(c.Args); sub != "" {
+		name += " " + sub
+	}
+	msg := strings.TrimSpace(stderr)
+	if c.Progress != nil {
+		msg = lastLine(stderr)
+	}
+	if msg == "" {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return fmt.Errorf("%s: %w: %s", name, err, msg)
+}
+
+// subcommand returns the git subcommand in args, past options such as
+// -C <dir> and -c <name>=<value>
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-C" || arg == "-c":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return ""
+}
+
+// lastLine returns the last non-empty line of git output, whose progress
+// lines are separated by carriage returns as well as newlines
+func lastLine(output string) string {
+	lines := strings.FieldsFunc(output, func(r rune) bool { return r == '\n' || r == '\r' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// Run runs git in dir and returns its standard output, trimmed
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := Cmd{Dir: dir, Args: args}.Output(ctx)
+	return strings.TrimSpace(string(out)), err
+}
+
+// Output runs git in dir and returns its standard output
+func Output(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	return Cmd{Dir: dir, Args: args}.Output(ctx)
+}
+
+// CombinedOutput runs git in dir and returns its combined standard output
+// and error
+func CombinedOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	return Cmd{Dir: dir, Args: args}.CombinedOutput(ctx)
+}
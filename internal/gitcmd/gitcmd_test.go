@@ -0,0 +1,69 @@
+package gitcmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Run(context.Background(), dir, "init", "--quiet"); err != nil {
+		t.Fatalf("Run(init) failed: %v", err)
+	}
+	if got, err := Run(context.Background(), dir, "rev-parse", "--is-inside-work-tree"); err != nil || got != "true" {
+		t.Errorf("Run(rev-parse) = %q, %v; want trimmed output", got, err)
+	}
+
+	_, err := Run(context.Background(), dir, "-c", "user.name=x", "rev-parse", "--verify", "no-such-ref")
+	if err == nil || !strings.HasPrefix(err.Error(), "git rev-parse: ") || !strings.Contains(err.Error(), "Needed a single revision") {
+		t.Errorf("Run() error = %v, want it to name the subcommand and carry git's message", err)
+	}
+}
+
+func TestCmdOutput(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Run(context.Background(), dir, "init", "--quiet"); err != nil {
+		t.Fatalf("Run(init) failed: %v", err)
+	}
+
+	// Stdin and Env reach git
+	sha, err := Cmd{Dir: dir, Args: []string{"hash-object", "-w", "--stdin"}, Stdin: []byte("hello\n")}.Output(context.Background())
+	if err != nil {
+		t.Fatalf("hash-object failed: %v", err)
+	}
+	out, err := Cmd{Dir: dir, Args: []string{"var", "GIT_AUTHOR_IDENT"}, Env: []string{"GIT_AUTHOR_NAME=relay", "GIT_AUTHOR_EMAIL=relay@localhost"}}.Output(context.Background())
+	if err != nil || !strings.HasPrefix(string(out), "relay <relay@localhost>") {
+		t.Errorf("GIT_AUTHOR_IDENT = %q, %v; want the Env identity", out, err)
+	}
+	if blob, err := Run(context.Background(), dir, "cat-file", "blob", strings.TrimSpace(string(sha))); err != nil || blob != "hello" {
+		t.Errorf("stored blob = %q, %v; want hello", blob, err)
+	}
+
+	// Progress sees all of standard error; the error keeps its last line
+	var progress bytes.Buffer
+	_, err = Cmd{Dir: dir, Args: []string{"fetch", "--quiet", "no-such-remote"}, Progress: &progress}.Output(context.Background())
+	if err == nil || progress.Len() == 0 {
+		t.Fatalf("fetch = %v with progress %q, want a failure shown on progress", err, progress.String())
+	}
+	if last := lastLine(progress.String()); !strings.HasSuffix(err.Error(), ": "+last) {
+		t.Errorf("error = %v, want it to end with git's last line %q", err, last)
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	out, err := CombinedOutput(context.Background(), t.TempDir(), "-c", "core.pager=cat", "log")
+	if err == nil || !strings.HasPrefix(err.Error(), "git log: ") || !strings.Contains(string(out), "not a git repository") {
+		t.Errorf("CombinedOutput() = %q, %v; want git's message in the output and the subcommand in the error", out, err)
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	if got := lastLine("Receiving objects:  50%\rReceiving objects: 100%, done.\nfatal: the remote hung up\n\n"); got != "fatal: the remote hung up" {
+		t.Errorf("lastLine() = %q", got)
+	}
+	if got := lastLine(" \n"); got != "" {
+		t.Errorf("lastLine() of blank output = %q, want empty", got)
+	}
+}
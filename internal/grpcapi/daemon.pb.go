@@ -0,0 +1,1555 @@
+// The multiclaude daemon's gRPC API. It carries the same commands as the
+// JSON socket API (docs/extending/SOCKET_API.md), typed RPCs for the common
+// ones, and streams events and logs instead of long-polling for them.
+//
+// The daemon serves it when built with `-tags grpc` and daemon.yaml has
+// `grpc: {enabled: true}`. See docs/extending/GRPC_API.md. The Go code in
+// internal/grpcapi is generated from this file; run `go generate
+// ./internal/grpcapi` after changing it.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: multiclaude/v1/daemon.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CallRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// command is a socket API command name
+	Command string `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	// args are the command's arguments, as in the socket API
+	Args *structpb.Struct `protobuf:"bytes,2,opt,name=args,proto3" json:"args,omitempty"`
+	// trace_id tags the daemon's log lines and events for this call
+	TraceId       string `protobuf:"bytes,3,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallRequest) Reset() {
+	*x = CallRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallRequest) ProtoMessage() {}
+
+func (x *CallRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallRequest.ProtoReflect.Descriptor instead.
+func (*CallRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CallRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *CallRequest) GetArgs() *structpb.Struct {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *CallRequest) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+type CallResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// data is the command's result, shaped as in the socket API
+	Data          *structpb.Value `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Error         string          `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallResponse) Reset() {
+	*x = CallResponse{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallResponse) ProtoMessage() {}
+
+func (x *CallResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallResponse.ProtoReflect.Descriptor instead.
+func (*CallResponse) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CallResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CallResponse) GetData() *structpb.Value {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CallResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{2}
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Repos         int32                  `protobuf:"varint,2,opt,name=repos,proto3" json:"repos,omitempty"`
+	Agents        int32                  `protobuf:"varint,3,opt,name=agents,proto3" json:"agents,omitempty"`
+	SocketPath    string                 `protobuf:"bytes,4,opt,name=socket_path,json=socketPath,proto3" json:"socket_path,omitempty"`
+	Standby       bool                   `protobuf:"varint,5,opt,name=standby,proto3" json:"standby,omitempty"`
+	StandbyReason string                 `protobuf:"bytes,6,opt,name=standby_reason,json=standbyReason,proto3" json:"standby_reason,omitempty"`
+	// inconsistencies is how many state problems `multiclaude repair` would fix
+	Inconsistencies int32 `protobuf:"varint,7,opt,name=inconsistencies,proto3" json:"inconsistencies,omitempty"`
+	// stalled maps each loop that missed its schedule to how late it is
+	Stalled       map[string]string `protobuf:"bytes,8,rep,name=stalled,proto3" json:"stalled,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetStatusResponse) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetRepos() int32 {
+	if x != nil {
+		return x.Repos
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetAgents() int32 {
+	if x != nil {
+		return x.Agents
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetSocketPath() string {
+	if x != nil {
+		return x.SocketPath
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetStandby() bool {
+	if x != nil {
+		return x.Standby
+	}
+	return false
+}
+
+func (x *GetStatusResponse) GetStandbyReason() string {
+	if x != nil {
+		return x.StandbyReason
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetInconsistencies() int32 {
+	if x != nil {
+		return x.Inconsistencies
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetStalled() map[string]string {
+	if x != nil {
+		return x.Stalled
+	}
+	return nil
+}
+
+type ListReposRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReposRequest) Reset() {
+	*x = ListReposRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReposRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReposRequest) ProtoMessage() {}
+
+func (x *ListReposRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReposRequest.ProtoReflect.Descriptor instead.
+func (*ListReposRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{4}
+}
+
+type ListReposResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repos         []*Repo                `protobuf:"bytes,1,rep,name=repos,proto3" json:"repos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReposResponse) Reset() {
+	*x = ListReposResponse{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReposResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReposResponse) ProtoMessage() {}
+
+func (x *ListReposResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReposResponse.ProtoReflect.Descriptor instead.
+func (*ListReposResponse) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListReposResponse) GetRepos() []*Repo {
+	if x != nil {
+		return x.Repos
+	}
+	return nil
+}
+
+type Repo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	GithubUrl      string                 `protobuf:"bytes,2,opt,name=github_url,json=githubUrl,proto3" json:"github_url,omitempty"`
+	TmuxSession    string                 `protobuf:"bytes,3,opt,name=tmux_session,json=tmuxSession,proto3" json:"tmux_session,omitempty"`
+	TotalAgents    int32                  `protobuf:"varint,4,opt,name=total_agents,json=totalAgents,proto3" json:"total_agents,omitempty"`
+	WorkerCount    int32                  `protobuf:"varint,5,opt,name=worker_count,json=workerCount,proto3" json:"worker_count,omitempty"`
+	SessionHealthy bool                   `protobuf:"varint,6,opt,name=session_healthy,json=sessionHealthy,proto3" json:"session_healthy,omitempty"`
+	IsFork         bool                   `protobuf:"varint,7,opt,name=is_fork,json=isFork,proto3" json:"is_fork,omitempty"`
+	UpstreamOwner  string                 `protobuf:"bytes,8,opt,name=upstream_owner,json=upstreamOwner,proto3" json:"upstream_owner,omitempty"`
+	UpstreamRepo   string                 `protobuf:"bytes,9,opt,name=upstream_repo,json=upstreamRepo,proto3" json:"upstream_repo,omitempty"`
+	// pr_management_mode is merge-queue, pr-shepherd or none
+	PrManagementMode string `protobuf:"bytes,10,opt,name=pr_management_mode,json=prManagementMode,proto3" json:"pr_management_mode,omitempty"`
+	Solo             bool   `protobuf:"varint,11,opt,name=solo,proto3" json:"solo,omitempty"`
+	// path is a solo repository's working directory
+	Path          string `protobuf:"bytes,12,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Repo) Reset() {
+	*x = Repo{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Repo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Repo) ProtoMessage() {}
+
+func (x *Repo) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Repo.ProtoReflect.Descriptor instead.
+func (*Repo) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Repo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Repo) GetGithubUrl() string {
+	if x != nil {
+		return x.GithubUrl
+	}
+	return ""
+}
+
+func (x *Repo) GetTmuxSession() string {
+	if x != nil {
+		return x.TmuxSession
+	}
+	return ""
+}
+
+func (x *Repo) GetTotalAgents() int32 {
+	if x != nil {
+		return x.TotalAgents
+	}
+	return 0
+}
+
+func (x *Repo) GetWorkerCount() int32 {
+	if x != nil {
+		return x.WorkerCount
+	}
+	return 0
+}
+
+func (x *Repo) GetSessionHealthy() bool {
+	if x != nil {
+		return x.SessionHealthy
+	}
+	return false
+}
+
+func (x *Repo) GetIsFork() bool {
+	if x != nil {
+		return x.IsFork
+	}
+	return false
+}
+
+func (x *Repo) GetUpstreamOwner() string {
+	if x != nil {
+		return x.UpstreamOwner
+	}
+	return ""
+}
+
+func (x *Repo) GetUpstreamRepo() string {
+	if x != nil {
+		return x.UpstreamRepo
+	}
+	return ""
+}
+
+func (x *Repo) GetPrManagementMode() string {
+	if x != nil {
+		return x.PrManagementMode
+	}
+	return ""
+}
+
+func (x *Repo) GetSolo() bool {
+	if x != nil {
+		return x.Solo
+	}
+	return false
+}
+
+func (x *Repo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type ListAgentsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// repo is the repository whose agents to list
+	Repo string `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	// all lists every repository's agents instead
+	All bool `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
+	// rich adds each agent's status and branch
+	Rich          bool `protobuf:"varint,3,opt,name=rich,proto3" json:"rich,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAgentsRequest) Reset() {
+	*x = ListAgentsRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAgentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAgentsRequest) ProtoMessage() {}
+
+func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAgentsRequest.ProtoReflect.Descriptor instead.
+func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListAgentsRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *ListAgentsRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+func (x *ListAgentsRequest) GetRich() bool {
+	if x != nil {
+		return x.Rich
+	}
+	return false
+}
+
+type ListAgentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Agents        []*Agent               `protobuf:"bytes,1,rep,name=agents,proto3" json:"agents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAgentsResponse) Reset() {
+	*x = ListAgentsResponse{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAgentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAgentsResponse) ProtoMessage() {}
+
+func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAgentsResponse.ProtoReflect.Descriptor instead.
+func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListAgentsResponse) GetAgents() []*Agent {
+	if x != nil {
+		return x.Agents
+	}
+	return nil
+}
+
+type Agent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Repo  string                 `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	Name  string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// type is an agent type such as worker, supervisor or merge-queue
+	Type         string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	WorktreePath string                 `protobuf:"bytes,4,opt,name=worktree_path,json=worktreePath,proto3" json:"worktree_path,omitempty"`
+	TmuxWindow   string                 `protobuf:"bytes,5,opt,name=tmux_window,json=tmuxWindow,proto3" json:"tmux_window,omitempty"`
+	Task         string                 `protobuf:"bytes,6,opt,name=task,proto3" json:"task,omitempty"`
+	CreatedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	BaseBranch   string                 `protobuf:"bytes,8,opt,name=base_branch,json=baseBranch,proto3" json:"base_branch,omitempty"`
+	// status (running, paused, stopped or completed) and branch are set
+	// when the request asks for rich details
+	Status        string `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	Branch        string `protobuf:"bytes,10,opt,name=branch,proto3" json:"branch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Agent) Reset() {
+	*x = Agent{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Agent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Agent) ProtoMessage() {}
+
+func (x *Agent) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Agent.ProtoReflect.Descriptor instead.
+func (*Agent) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Agent) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *Agent) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Agent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Agent) GetWorktreePath() string {
+	if x != nil {
+		return x.WorktreePath
+	}
+	return ""
+}
+
+func (x *Agent) GetTmuxWindow() string {
+	if x != nil {
+		return x.TmuxWindow
+	}
+	return ""
+}
+
+func (x *Agent) GetTask() string {
+	if x != nil {
+		return x.Task
+	}
+	return ""
+}
+
+func (x *Agent) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Agent) GetBaseBranch() string {
+	if x != nil {
+		return x.BaseBranch
+	}
+	return ""
+}
+
+func (x *Agent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Agent) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+type AddTaskRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Repo  string                 `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	Task  string                 `protobuf:"bytes,2,opt,name=task,proto3" json:"task,omitempty"`
+	// after holds the IDs of tasks that must finish first
+	After         []string `protobuf:"bytes,3,rep,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddTaskRequest) Reset() {
+	*x = AddTaskRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddTaskRequest) ProtoMessage() {}
+
+func (x *AddTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddTaskRequest.ProtoReflect.Descriptor instead.
+func (*AddTaskRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AddTaskRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *AddTaskRequest) GetTask() string {
+	if x != nil {
+		return x.Task
+	}
+	return ""
+}
+
+func (x *AddTaskRequest) GetAfter() []string {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+type AddTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Task          *Task                  `protobuf:"bytes,1,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddTaskResponse) Reset() {
+	*x = AddTaskResponse{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddTaskResponse) ProtoMessage() {}
+
+func (x *AddTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddTaskResponse.ProtoReflect.Descriptor instead.
+func (*AddTaskResponse) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AddTaskResponse) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+type Task struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Repo        string                 `protobuf:"bytes,2,opt,name=repo,proto3" json:"repo,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	After       []string               `protobuf:"bytes,4,rep,name=after,proto3" json:"after,omitempty"`
+	// status is waiting, running, review, merged, completed, failed or cancelled
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Worker        string                 `protobuf:"bytes,6,opt,name=worker,proto3" json:"worker,omitempty"`
+	Branch        string                 `protobuf:"bytes,7,opt,name=branch,proto3" json:"branch,omitempty"`
+	PrUrl         string                 `protobuf:"bytes,8,opt,name=pr_url,json=prUrl,proto3" json:"pr_url,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Task) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Task) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *Task) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Task) GetAfter() []string {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+func (x *Task) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Task) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+func (x *Task) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *Task) GetPrUrl() string {
+	if x != nil {
+		return x.PrUrl
+	}
+	return ""
+}
+
+func (x *Task) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Task) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type CompleteAgentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Repo  string                 `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	Agent string                 `protobuf:"bytes,2,opt,name=agent,proto3" json:"agent,omitempty"`
+	// summary says what the agent did
+	Summary string `protobuf:"bytes,3,opt,name=summary,proto3" json:"summary,omitempty"`
+	// failure_reason gives up on the task instead, saying why
+	FailureReason string `protobuf:"bytes,4,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteAgentRequest) Reset() {
+	*x = CompleteAgentRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteAgentRequest) ProtoMessage() {}
+
+func (x *CompleteAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteAgentRequest.ProtoReflect.Descriptor instead.
+func (*CompleteAgentRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CompleteAgentRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *CompleteAgentRequest) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *CompleteAgentRequest) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *CompleteAgentRequest) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+type CompleteAgentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteAgentResponse) Reset() {
+	*x = CompleteAgentResponse{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteAgentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteAgentResponse) ProtoMessage() {}
+
+func (x *CompleteAgentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteAgentResponse.ProtoReflect.Descriptor instead.
+func (*CompleteAgentResponse) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{14}
+}
+
+type WatchEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// repo only streams events for this repository
+	Repo string `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	// since streams events after this sequence number; 0 starts with the next event
+	Since uint64 `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`
+	// backlog starts with every event the daemon still holds, ignoring since
+	Backlog       bool `protobuf:"varint,3,opt,name=backlog,proto3" json:"backlog,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *WatchEventsRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *WatchEventsRequest) GetSince() uint64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *WatchEventsRequest) GetBacklog() bool {
+	if x != nil {
+		return x.Backlog
+	}
+	return false
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	// type is an event type such as agent_started; see docs/extending/EVENT_HOOKS.md
+	Type          string            `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Repo          string            `protobuf:"bytes,4,opt,name=repo,proto3" json:"repo,omitempty"`
+	Agent         string            `protobuf:"bytes,5,opt,name=agent,proto3" json:"agent,omitempty"`
+	Data          map[string]string `protobuf:"bytes,6,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	TraceId       string            `protobuf:"bytes,7,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Event) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *Event) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *Event) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Event) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+type TailLogsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// repo and agent pick an agent's output log; both empty is the daemon log
+	Repo  string `protobuf:"bytes,1,opt,name=repo,proto3" json:"repo,omitempty"`
+	Agent string `protobuf:"bytes,2,opt,name=agent,proto3" json:"agent,omitempty"`
+	// lines is how many existing lines to send first (0 = none)
+	Lines int32 `protobuf:"varint,3,opt,name=lines,proto3" json:"lines,omitempty"`
+	// follow keeps the stream open and sends lines as they are written
+	Follow        bool `protobuf:"varint,4,opt,name=follow,proto3" json:"follow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailLogsRequest) Reset() {
+	*x = TailLogsRequest{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailLogsRequest) ProtoMessage() {}
+
+func (x *TailLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailLogsRequest.ProtoReflect.Descriptor instead.
+func (*TailLogsRequest) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TailLogsRequest) GetRepo() string {
+	if x != nil {
+		return x.Repo
+	}
+	return ""
+}
+
+func (x *TailLogsRequest) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *TailLogsRequest) GetLines() int32 {
+	if x != nil {
+		return x.Lines
+	}
+	return 0
+}
+
+func (x *TailLogsRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type LogLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          string                 `protobuf:"bytes,1,opt,name=line,proto3" json:"line,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogLine) Reset() {
+	*x = LogLine{}
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogLine) ProtoMessage() {}
+
+func (x *LogLine) ProtoReflect() protoreflect.Message {
+	mi := &file_multiclaude_v1_daemon_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogLine.ProtoReflect.Descriptor instead.
+func (*LogLine) Descriptor() ([]byte, []int) {
+	return file_multiclaude_v1_daemon_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *LogLine) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+var File_multiclaude_v1_daemon_proto protoreflect.FileDescriptor
+
+const file_multiclaude_v1_daemon_proto_rawDesc = "" +
+	"\n" +
+	"\x1bmulticlaude/v1/daemon.proto\x12\x0emulticlaude.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"o\n" +
+	"\vCallRequest\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\tR\acommand\x12+\n" +
+	"\x04args\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x04args\x12\x19\n" +
+	"\btrace_id\x18\x03 \x01(\tR\atraceId\"j\n" +
+	"\fCallResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12*\n" +
+	"\x04data\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x04data\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x12\n" +
+	"\x10GetStatusRequest\"\xe5\x02\n" +
+	"\x11GetStatusResponse\x12\x10\n" +
+	"\x03pid\x18\x01 \x01(\x05R\x03pid\x12\x14\n" +
+	"\x05repos\x18\x02 \x01(\x05R\x05repos\x12\x16\n" +
+	"\x06agents\x18\x03 \x01(\x05R\x06agents\x12\x1f\n" +
+	"\vsocket_path\x18\x04 \x01(\tR\n" +
+	"socketPath\x12\x18\n" +
+	"\astandby\x18\x05 \x01(\bR\astandby\x12%\n" +
+	"\x0estandby_reason\x18\x06 \x01(\tR\rstandbyReason\x12(\n" +
+	"\x0finconsistencies\x18\a \x01(\x05R\x0finconsistencies\x12H\n" +
+	"\astalled\x18\b \x03(\v2..multiclaude.v1.GetStatusResponse.StalledEntryR\astalled\x1a:\n" +
+	"\fStalledEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x12\n" +
+	"\x10ListReposRequest\"?\n" +
+	"\x11ListReposResponse\x12*\n" +
+	"\x05repos\x18\x01 \x03(\v2\x14.multiclaude.v1.RepoR\x05repos\"\x86\x03\n" +
+	"\x04Repo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"github_url\x18\x02 \x01(\tR\tgithubUrl\x12!\n" +
+	"\ftmux_session\x18\x03 \x01(\tR\vtmuxSession\x12!\n" +
+	"\ftotal_agents\x18\x04 \x01(\x05R\vtotalAgents\x12!\n" +
+	"\fworker_count\x18\x05 \x01(\x05R\vworkerCount\x12'\n" +
+	"\x0fsession_healthy\x18\x06 \x01(\bR\x0esessionHealthy\x12\x17\n" +
+	"\ais_fork\x18\a \x01(\bR\x06isFork\x12%\n" +
+	"\x0eupstream_owner\x18\b \x01(\tR\rupstreamOwner\x12#\n" +
+	"\rupstream_repo\x18\t \x01(\tR\fupstreamRepo\x12,\n" +
+	"\x12pr_management_mode\x18\n" +
+	" \x01(\tR\x10prManagementMode\x12\x12\n" +
+	"\x04solo\x18\v \x01(\bR\x04solo\x12\x12\n" +
+	"\x04path\x18\f \x01(\tR\x04path\"M\n" +
+	"\x11ListAgentsRequest\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x10\n" +
+	"\x03all\x18\x02 \x01(\bR\x03all\x12\x12\n" +
+	"\x04rich\x18\x03 \x01(\bR\x04rich\"C\n" +
+	"\x12ListAgentsResponse\x12-\n" +
+	"\x06agents\x18\x01 \x03(\v2\x15.multiclaude.v1.AgentR\x06agents\"\xa9\x02\n" +
+	"\x05Agent\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12#\n" +
+	"\rworktree_path\x18\x04 \x01(\tR\fworktreePath\x12\x1f\n" +
+	"\vtmux_window\x18\x05 \x01(\tR\n" +
+	"tmuxWindow\x12\x12\n" +
+	"\x04task\x18\x06 \x01(\tR\x04task\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x1f\n" +
+	"\vbase_branch\x18\b \x01(\tR\n" +
+	"baseBranch\x12\x16\n" +
+	"\x06status\x18\t \x01(\tR\x06status\x12\x16\n" +
+	"\x06branch\x18\n" +
+	" \x01(\tR\x06branch\"N\n" +
+	"\x0eAddTaskRequest\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x12\n" +
+	"\x04task\x18\x02 \x01(\tR\x04task\x12\x14\n" +
+	"\x05after\x18\x03 \x03(\tR\x05after\";\n" +
+	"\x0fAddTaskResponse\x12(\n" +
+	"\x04task\x18\x01 \x01(\v2\x14.multiclaude.v1.TaskR\x04task\"\x92\x02\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04repo\x18\x02 \x01(\tR\x04repo\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05after\x18\x04 \x03(\tR\x05after\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x16\n" +
+	"\x06worker\x18\x06 \x01(\tR\x06worker\x12\x16\n" +
+	"\x06branch\x18\a \x01(\tR\x06branch\x12\x15\n" +
+	"\x06pr_url\x18\b \x01(\tR\x05prUrl\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\x81\x01\n" +
+	"\x14CompleteAgentRequest\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x14\n" +
+	"\x05agent\x18\x02 \x01(\tR\x05agent\x12\x18\n" +
+	"\asummary\x18\x03 \x01(\tR\asummary\x12%\n" +
+	"\x0efailure_reason\x18\x04 \x01(\tR\rfailureReason\"\x17\n" +
+	"\x15CompleteAgentResponse\"X\n" +
+	"\x12WatchEventsRequest\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x14\n" +
+	"\x05since\x18\x02 \x01(\x04R\x05since\x12\x18\n" +
+	"\abacklog\x18\x03 \x01(\bR\abacklog\"\x90\x02\n" +
+	"\x05Event\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04repo\x18\x04 \x01(\tR\x04repo\x12\x14\n" +
+	"\x05agent\x18\x05 \x01(\tR\x05agent\x123\n" +
+	"\x04data\x18\x06 \x03(\v2\x1f.multiclaude.v1.Event.DataEntryR\x04data\x12\x19\n" +
+	"\btrace_id\x18\a \x01(\tR\atraceId\x1a7\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"i\n" +
+	"\x0fTailLogsRequest\x12\x12\n" +
+	"\x04repo\x18\x01 \x01(\tR\x04repo\x12\x14\n" +
+	"\x05agent\x18\x02 \x01(\tR\x05agent\x12\x14\n" +
+	"\x05lines\x18\x03 \x01(\x05R\x05lines\x12\x16\n" +
+	"\x06follow\x18\x04 \x01(\bR\x06follow\"\x1d\n" +
+	"\aLogLine\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\tR\x04line2\x82\x05\n" +
+	"\x06Daemon\x12A\n" +
+	"\x04Call\x12\x1b.multiclaude.v1.CallRequest\x1a\x1c.multiclaude.v1.CallResponse\x12P\n" +
+	"\tGetStatus\x12 .multiclaude.v1.GetStatusRequest\x1a!.multiclaude.v1.GetStatusResponse\x12P\n" +
+	"\tListRepos\x12 .multiclaude.v1.ListReposRequest\x1a!.multiclaude.v1.ListReposResponse\x12S\n" +
+	"\n" +
+	"ListAgents\x12!.multiclaude.v1.ListAgentsRequest\x1a\".multiclaude.v1.ListAgentsResponse\x12J\n" +
+	"\aAddTask\x12\x1e.multiclaude.v1.AddTaskRequest\x1a\x1f.multiclaude.v1.AddTaskResponse\x12\\\n" +
+	"\rCompleteAgent\x12$.multiclaude.v1.CompleteAgentRequest\x1a%.multiclaude.v1.CompleteAgentResponse\x12J\n" +
+	"\vWatchEvents\x12\".multiclaude.v1.WatchEventsRequest\x1a\x15.multiclaude.v1.Event0\x01\x12F\n" +
+	"\bTailLogs\x12\x1f.multiclaude.v1.TailLogsRequest\x1a\x17.multiclaude.v1.LogLine0\x01B4Z2github.com/micheal-at/multiclaude/internal/grpcapib\x06proto3"
+
+var (
+	file_multiclaude_v1_daemon_proto_rawDescOnce sync.Once
+	file_multiclaude_v1_daemon_proto_rawDescData []byte
+)
+
+func file_multiclaude_v1_daemon_proto_rawDescGZIP() []byte {
+	file_multiclaude_v1_daemon_proto_rawDescOnce.Do(func() {
+		file_multiclaude_v1_daemon_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_multiclaude_v1_daemon_proto_rawDesc), len(file_multiclaude_v1_daemon_proto_rawDesc)))
+	})
+	return file_multiclaude_v1_daemon_proto_rawDescData
+}
+
+var file_multiclaude_v1_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_multiclaude_v1_daemon_proto_goTypes = []any{
+	(*CallRequest)(nil),           // 0: multiclaude.v1.CallRequest
+	(*CallResponse)(nil),          // 1: multiclaude.v1.CallResponse
+	(*GetStatusRequest)(nil),      // 2: multiclaude.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),     // 3: multiclaude.v1.GetStatusResponse
+	(*ListReposRequest)(nil),      // 4: multiclaude.v1.ListReposRequest
+	(*ListReposResponse)(nil),     // 5: multiclaude.v1.ListReposResponse
+	(*Repo)(nil),                  // 6: multiclaude.v1.Repo
+	(*ListAgentsRequest)(nil),     // 7: multiclaude.v1.ListAgentsRequest
+	(*ListAgentsResponse)(nil),    // 8: multiclaude.v1.ListAgentsResponse
+	(*Agent)(nil),                 // 9: multiclaude.v1.Agent
+	(*AddTaskRequest)(nil),        // 10: multiclaude.v1.AddTaskRequest
+	(*AddTaskResponse)(nil),       // 11: multiclaude.v1.AddTaskResponse
+	(*Task)(nil),                  // 12: multiclaude.v1.Task
+	(*CompleteAgentRequest)(nil),  // 13: multiclaude.v1.CompleteAgentRequest
+	(*CompleteAgentResponse)(nil), // 14: multiclaude.v1.CompleteAgentResponse
+	(*WatchEventsRequest)(nil),    // 15: multiclaude.v1.WatchEventsRequest
+	(*Event)(nil),                 // 16: multiclaude.v1.Event
+	(*TailLogsRequest)(nil),       // 17: multiclaude.v1.TailLogsRequest
+	(*LogLine)(nil),               // 18: multiclaude.v1.LogLine
+	nil,                           // 19: multiclaude.v1.GetStatusResponse.StalledEntry
+	nil,                           // 20: multiclaude.v1.Event.DataEntry
+	(*structpb.Struct)(nil),       // 21: google.protobuf.Struct
+	(*structpb.Value)(nil),        // 22: google.protobuf.Value
+	(*timestamppb.Timestamp)(nil), // 23: google.protobuf.Timestamp
+}
+var file_multiclaude_v1_daemon_proto_depIdxs = []int32{
+	21, // 0: multiclaude.v1.CallRequest.args:type_name -> google.protobuf.Struct
+	22, // 1: multiclaude.v1.CallResponse.data:type_name -> google.protobuf.Value
+	19, // 2: multiclaude.v1.GetStatusResponse.stalled:type_name -> multiclaude.v1.GetStatusResponse.StalledEntry
+	6,  // 3: multiclaude.v1.ListReposResponse.repos:type_name -> multiclaude.v1.Repo
+	9,  // 4: multiclaude.v1.ListAgentsResponse.agents:type_name -> multiclaude.v1.Agent
+	23, // 5: multiclaude.v1.Agent.created_at:type_name -> google.protobuf.Timestamp
+	12, // 6: multiclaude.v1.AddTaskResponse.task:type_name -> multiclaude.v1.Task
+	23, // 7: multiclaude.v1.Task.created_at:type_name -> google.protobuf.Timestamp
+	23, // 8: multiclaude.v1.Event.time:type_name -> google.protobuf.Timestamp
+	20, // 9: multiclaude.v1.Event.data:type_name -> multiclaude.v1.Event.DataEntry
+	0,  // 10: multiclaude.v1.Daemon.Call:input_type -> multiclaude.v1.CallRequest
+	2,  // 11: multiclaude.v1.Daemon.GetStatus:input_type -> multiclaude.v1.GetStatusRequest
+	4,  // 12: multiclaude.v1.Daemon.ListRepos:input_type -> multiclaude.v1.ListReposRequest
+	7,  // 13: multiclaude.v1.Daemon.ListAgents:input_type -> multiclaude.v1.ListAgentsRequest
+	10, // 14: multiclaude.v1.Daemon.AddTask:input_type -> multiclaude.v1.AddTaskRequest
+	13, // 15: multiclaude.v1.Daemon.CompleteAgent:input_type -> multiclaude.v1.CompleteAgentRequest
+	15, // 16: multiclaude.v1.Daemon.WatchEvents:input_type -> multiclaude.v1.WatchEventsRequest
+	17, // 17: multiclaude.v1.Daemon.TailLogs:input_type -> multiclaude.v1.TailLogsRequest
+	1,  // 18: multiclaude.v1.Daemon.Call:output_type -> multiclaude.v1.CallResponse
+	3,  // 19: multiclaude.v1.Daemon.GetStatus:output_type -> multiclaude.v1.GetStatusResponse
+	5,  // 20: multiclaude.v1.Daemon.ListRepos:output_type -> multiclaude.v1.ListReposResponse
+	8,  // 21: multiclaude.v1.Daemon.ListAgents:output_type -> multiclaude.v1.ListAgentsResponse
+	11, // 22: multiclaude.v1.Daemon.AddTask:output_type -> multiclaude.v1.AddTaskResponse
+	14, // 23: multiclaude.v1.Daemon.CompleteAgent:output_type -> multiclaude.v1.CompleteAgentResponse
+	16, // 24: multiclaude.v1.Daemon.WatchEvents:output_type -> multiclaude.v1.Event
+	18, // 25: multiclaude.v1.Daemon.TailLogs:output_type -> multiclaude.v1.LogLine
+	18, // [18:26] is the sub-list for method output_type
+	10, // [10:18] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_multiclaude_v1_daemon_proto_init() }
+func file_multiclaude_v1_daemon_proto_init() {
+	if File_multiclaude_v1_daemon_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_multiclaude_v1_daemon_proto_rawDesc), len(file_multiclaude_v1_daemon_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_multiclaude_v1_daemon_proto_goTypes,
+		DependencyIndexes: file_multiclaude_v1_daemon_proto_depIdxs,
+		MessageInfos:      file_multiclaude_v1_daemon_proto_msgTypes,
+	}.Build()
+	File_multiclaude_v1_daemon_proto = out.File
+	file_multiclaude_v1_daemon_proto_goTypes = nil
+	file_multiclaude_v1_daemon_proto_depIdxs = nil
+}
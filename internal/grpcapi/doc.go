@@ -0,0 +1,13 @@
+// Package grpcapi serves the daemon's API over gRPC, for integrations in
+// languages that would rather generate a client from
+// proto/multiclaude/v1/daemon.proto than speak JSON over a Unix socket. Call
+// runs any socket API command through the same handler the socket server
+// uses, and typed RPCs wrap the common ones; WatchEvents and TailLogs stream
+// what socket clients have to poll for.
+//
+// The server is only built with the grpc build tag, so a default build
+// carries none of it. The message and service code is generated by
+// protoc-gen-go and protoc-gen-go-grpc.
+package grpcapi
+
+//go:generate protoc --proto_path=../../proto --go_out=../.. --go_opt=module=github.com/micheal-at/multiclaude --go-grpc_out=../.. --go-grpc_opt=module=github.com/micheal-at/multiclaude multiclaude/v1/daemon.proto
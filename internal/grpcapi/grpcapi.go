@@ -0,0 +1,467 @@
+//go:build grpc
+
+package grpcapi
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/events"
+	"github.com/micheal-at/multiclaude/internal/socket"
+)
+
+// eventWait is how long WatchEvents waits for events before checking
+// whether the client is still there
+const eventWait = 30 * time.Second
+
+// logPoll is how often TailLogs checks a followed log for new lines
+const logPoll = 500 * time.Millisecond
+
+// maxTailLines caps how many existing lines TailLogs sends first
+const maxTailLines = 10000
+
+// LogPath returns the log file TailLogs streams for an agent, or the
+// daemon's log when repo and agent are empty
+type LogPath func(repo, agent string) (string, error)
+
+// Server serves the API over gRPC
+type Server struct {
+	UnimplementedDaemonServer
+	handler socket.Handler
+	events  *events.Bus
+	logPath LogPath
+	token   string
+	grpc    *grpc.Server
+}
+
+// NewServer creates a server that runs commands with handler, streams
+// events from bus and finds logs with logPath. A non-empty token must be
+// sent by every call as "authorization: Bearer <token>" metadata.
+func NewServer(handler socket.Handler, bus *events.Bus, logPath LogPath, token string) *Server {
+	s := &Server{handler: handler, events: bus, logPath: logPath, token: token}
+	s.grpc = grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return next(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+			if err := s.authorize(stream.Context()); err != nil {
+				return err
+			}
+			return next(srv, stream)
+		}),
+	)
+	RegisterDaemonServer(s.grpc, s)
+	reflection.Register(s.grpc)
+	return s
+}
+
+// Serve accepts connections on listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpc.Serve(listener)
+}
+
+// Stop closes the listener and ends open calls and streams
+func (s *Server) Stop() {
+	s.grpc.Stop()
+}
+
+// authorize checks the call's token when the server requires one
+func (s *Server) authorize(ctx context.Context) error {
+	if s.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong token: send \"authorization: Bearer <token>\" with the token from the daemon's grpc-token file")
+}
+
+// Call runs a socket API command
+func (s *Server) Call(_ context.Context, req *CallRequest) (*CallResponse, error) {
+	request := socket.Request{Command: req.GetCommand(), TraceID: req.GetTraceId()}
+	if request.Command == "" {
+		return nil, status.Error(codes.InvalidArgument, "command is required")
+	}
+	if req.GetArgs() != nil {
+		request.Args = req.GetArgs().AsMap()
+	}
+
+	resp := s.handler.Handle(request)
+
+	out := &CallResponse{Success: resp.Success, Error: resp.Error}
+	if resp.Data != nil {
+		data, err := toValue(resp.Data)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unencodable response: %v", err)
+		}
+		out.Data = data
+	}
+	return out, nil
+}
+
+// toValue converts a handler's response data, which may hold structs,
+// into a protobuf Value by way of the JSON the socket API would send
+func toValue(data interface{}) (*structpb.Value, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, err
+	}
+	return structpb.NewValue(generic)
+}
+
+// GetStatus runs the status command
+func (s *Server) GetStatus(ctx context.Context, _ *GetStatusRequest) (*GetStatusResponse, error) {
+	var data struct {
+		PID             int32             `json:"pid"`
+		Repos           int32             `json:"repos"`
+		Agents          int32             `json:"agents"`
+		SocketPath      string            `json:"socket_path"`
+		Standby         bool              `json:"standby"`
+		StandbyReason   string            `json:"standby_reason"`
+		Inconsistencies int32             `json:"inconsistencies"`
+		Stalled         map[string]string `json:"stalled"`
+	}
+	if err := s.run(ctx, "status", nil, &data); err != nil {
+		return nil, err
+	}
+	return &GetStatusResponse{
+		Pid:             data.PID,
+		Repos:           data.Repos,
+		Agents:          data.Agents,
+		SocketPath:      data.SocketPath,
+		Standby:         data.Standby,
+		StandbyReason:   data.StandbyReason,
+		Inconsistencies: data.Inconsistencies,
+		Stalled:         data.Stalled,
+	}, nil
+}
+
+// ListRepos runs list_repos with its rich details, sorted by name
+func (s *Server) ListRepos(ctx context.Context, _ *ListReposRequest) (*ListReposResponse, error) {
+	var data []struct {
+		Name             string `json:"name"`
+		GithubURL        string `json:"github_url"`
+		TmuxSession      string `json:"tmux_session"`
+		TotalAgents      int32  `json:"total_agents"`
+		WorkerCount      int32  `json:"worker_count"`
+		SessionHealthy   bool   `json:"session_healthy"`
+		IsFork           bool   `json:"is_fork"`
+		UpstreamOwner    string `json:"upstream_owner"`
+		UpstreamRepo     string `json:"upstream_repo"`
+		PRManagementMode string `json:"pr_management_mode"`
+		Solo             bool   `json:"solo"`
+		Path             string `json:"path"`
+	}
+	if err := s.run(ctx, "list_repos", map[string]interface{}{"rich": true}, &data); err != nil {
+		return nil, err
+	}
+	out := &ListReposResponse{}
+	for _, r := range data {
+		out.Repos = append(out.Repos, &Repo{
+			Name:             r.Name,
+			GithubUrl:        r.GithubURL,
+			TmuxSession:      r.TmuxSession,
+			TotalAgents:      r.TotalAgents,
+			WorkerCount:      r.WorkerCount,
+			SessionHealthy:   r.SessionHealthy,
+			IsFork:           r.IsFork,
+			UpstreamOwner:    r.UpstreamOwner,
+			UpstreamRepo:     r.UpstreamRepo,
+			PrManagementMode: r.PRManagementMode,
+			Solo:             r.Solo,
+			Path:             r.Path,
+		})
+	}
+	sort.Slice(out.Repos, func(i, j int) bool { return out.Repos[i].Name < out.Repos[j].Name })
+	return out, nil
+}
+
+// ListAgents runs list_agents
+func (s *Server) ListAgents(ctx context.Context, req *ListAgentsRequest) (*ListAgentsResponse, error) {
+	args := map[string]interface{}{"rich": req.GetRich()}
+	if req.GetAll() {
+		args["all"] = true
+	} else {
+		args["repo"] = req.GetRepo()
+	}
+	var data []struct {
+		Repo         string    `json:"repo"`
+		Name         string    `json:"name"`
+		Type         string    `json:"type"`
+		WorktreePath string    `json:"worktree_path"`
+		TmuxWindow   string    `json:"tmux_window"`
+		Task         string    `json:"task"`
+		CreatedAt    time.Time `json:"created_at"`
+		BaseBranch   string    `json:"base_branch"`
+		Status       string    `json:"status"`
+		Branch       string    `json:"branch"`
+	}
+	if err := s.run(ctx, "list_agents", args, &data); err != nil {
+		return nil, err
+	}
+	out := &ListAgentsResponse{}
+	for _, a := range data {
+		out.Agents = append(out.Agents, &Agent{
+			Repo:         a.Repo,
+			Name:         a.Name,
+			Type:         a.Type,
+			WorktreePath: a.WorktreePath,
+			TmuxWindow:   a.TmuxWindow,
+			Task:         a.Task,
+			CreatedAt:    timestamp(a.CreatedAt),
+			BaseBranch:   a.BaseBranch,
+			Status:       a.Status,
+			Branch:       a.Branch,
+		})
+	}
+	return out, nil
+}
+
+// AddTask runs add_task
+func (s *Server) AddTask(ctx context.Context, req *AddTaskRequest) (*AddTaskResponse, error) {
+	args := map[string]interface{}{"repo": req.GetRepo(), "task": req.GetTask()}
+	if len(req.GetAfter()) > 0 {
+		after := make([]interface{}, len(req.GetAfter()))
+		for i, id := range req.GetAfter() {
+			after[i] = id
+		}
+		args["after"] = after
+	}
+	var data struct {
+		ID          string    `json:"id"`
+		Repo        string    `json:"repo"`
+		Description string    `json:"description"`
+		After       []string  `json:"after"`
+		Status      string    `json:"status"`
+		Worker      string    `json:"worker"`
+		Branch      string    `json:"branch"`
+		PRURL       string    `json:"pr_url"`
+		Error       string    `json:"error"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	if err := s.run(ctx, "add_task", args, &data); err != nil {
+		return nil, err
+	}
+	return &AddTaskResponse{Task: &Task{
+		Id:          data.ID,
+		Repo:        data.Repo,
+		Description: data.Description,
+		After:       data.After,
+		Status:      data.Status,
+		Worker:      data.Worker,
+		Branch:      data.Branch,
+		PrUrl:       data.PRURL,
+		Error:       data.Error,
+		CreatedAt:   timestamp(data.CreatedAt),
+	}}, nil
+}
+
+// CompleteAgent runs complete_agent
+func (s *Server) CompleteAgent(ctx context.Context, req *CompleteAgentRequest) (*CompleteAgentResponse, error) {
+	args := map[string]interface{}{"repo": req.GetRepo(), "agent": req.GetAgent()}
+	if req.GetSummary() != "" {
+		args["summary"] = req.GetSummary()
+	}
+	if req.GetFailureReason() != "" {
+		args["failure_reason"] = req.GetFailureReason()
+	}
+	if err := s.run(ctx, "complete_agent", args, nil); err != nil {
+		return nil, err
+	}
+	return &CompleteAgentResponse{}, nil
+}
+
+// run runs a socket API command for a typed RPC and decodes its data into
+// out by way of the JSON the socket API would send. A command that fails
+// is a gRPC error. The call's "trace-id" metadata, if any, tags the
+// daemon's log lines and events for it.
+func (s *Server) run(ctx context.Context, command string, args map[string]interface{}, out interface{}) error {
+	request := socket.Request{Command: command, Args: args}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get("trace-id"); len(ids) > 0 {
+		request.TraceID = ids[0]
+	}
+
+	resp := s.handler.Handle(request)
+	if !resp.Success {
+		return status.Error(statusCode(resp.Code), resp.Error)
+	}
+	if out == nil || resp.Data == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(resp.Data)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unencodable response: %v", err)
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		return status.Errorf(codes.Internal, "unexpected response: %v", err)
+	}
+	return nil
+}
+
+// statusCode maps a socket API error code onto a gRPC one
+func statusCode(code string) codes.Code {
+	switch errors.Code(code) {
+	case errors.CodeNotFound, errors.CodeRepoNotFound, errors.CodeAgentNotFound, errors.CodeWorkspaceNotFound:
+		return codes.NotFound
+	case errors.CodeUsage, errors.CodeMissingArgument, errors.CodeInvalidArgument:
+		return codes.InvalidArgument
+	case errors.CodeDefinitionAtLimit, errors.CodeWorkerLimit:
+		return codes.ResourceExhausted
+	default:
+		return codes.FailedPrecondition
+	}
+}
+
+// timestamp converts a time, leaving the zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// WatchEvents streams events until the client goes away
+func (s *Server) WatchEvents(req *WatchEventsRequest, stream grpc.ServerStreamingServer[Event]) error {
+	repo := req.GetRepo()
+	seq := req.GetSince()
+	if req.GetBacklog() {
+		seq = 0
+	} else if seq == 0 {
+		seq = s.events.Seq()
+	}
+
+	ctx := stream.Context()
+	for {
+		var evs []events.Event
+		evs, seq = s.events.Wait(ctx, seq, eventWait)
+		if ctx.Err() != nil {
+			return nil
+		}
+		for _, e := range evs {
+			if repo != "" && e.Repo != repo {
+				continue
+			}
+			if err := stream.Send(eventMessage(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventMessage converts an event to its protobuf message
+func eventMessage(e events.Event) *Event {
+	return &Event{
+		Seq:     e.Seq,
+		Time:    timestamppb.New(e.Time),
+		Type:    string(e.Type),
+		Repo:    e.Repo,
+		Agent:   e.Agent,
+		Data:    e.Data,
+		TraceId: e.Trace,
+	}
+}
+
+// TailLogs streams the last lines of a log and, when following, the lines
+// written after them until the client goes away
+func (s *Server) TailLogs(req *TailLogsRequest, stream grpc.ServerStreamingServer[LogLine]) error {
+	repo, agent := req.GetRepo(), req.GetAgent()
+	lines := int(req.GetLines())
+	if lines < 0 || lines > maxTailLines {
+		return status.Errorf(codes.InvalidArgument, "lines must be between 0 and %d", maxTailLines)
+	}
+	if (repo == "") != (agent == "") {
+		return status.Error(codes.InvalidArgument, "give both repo and agent for an agent's log, or neither for the daemon's")
+	}
+
+	path, err := s.logPath(repo, agent)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "cannot open log: %v", err)
+	}
+	defer f.Close()
+
+	send := func(line string) error {
+		return stream.Send(&LogLine{Line: line})
+	}
+
+	// Send the last lines, keeping only as many as asked for
+	reader := bufio.NewReader(f)
+	var last []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A partial last line is sent once it is finished
+			if _, seekErr := f.Seek(-int64(len(line)), io.SeekCurrent); seekErr == nil {
+				reader.Reset(f)
+			}
+			break
+		}
+		if lines > 0 {
+			last = append(last, strings.TrimRight(line, "\r\n"))
+			if len(last) > lines {
+				last = last[1:]
+			}
+		}
+	}
+	for _, line := range last {
+		if err := send(line); err != nil {
+			return err
+		}
+	}
+	if !req.GetFollow() {
+		return nil
+	}
+
+	ticker := time.NewTicker(logPoll)
+	defer ticker.Stop()
+	var partial string
+	for {
+		for {
+			chunk, err := reader.ReadString('\n')
+			partial += chunk
+			if err != nil {
+				break
+			}
+			if err := send(strings.TrimRight(partial, "\r\n")); err != nil {
+				return err
+			}
+			partial = ""
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,279 @@
+//go:build grpc
+
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/micheal-at/multiclaude/internal/errors"
+	"github.com/micheal-at/multiclaude/internal/events"
+	"github.com/micheal-at/multiclaude/internal/socket"
+)
+
+// testServer serves the API over an in-memory connection and returns a
+// client for it
+func testServer(t *testing.T, handler socket.HandlerFunc, bus *events.Bus, logPath LogPath, token string) DaemonClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(handler, bus, logPath, token)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewDaemonClient(conn)
+}
+
+// call invokes Call with a command and args
+func call(ctx context.Context, client DaemonClient, command string, args map[string]interface{}) (*CallResponse, error) {
+	req := &CallRequest{Command: command, TraceId: "trace-1"}
+	if args != nil {
+		s, err := structpb.NewStruct(args)
+		if err != nil {
+			return nil, err
+		}
+		req.Args = s
+	}
+	return client.Call(ctx, req)
+}
+
+func TestCall(t *testing.T) {
+	var got socket.Request
+	conn := testServer(t, func(req socket.Request) socket.Response {
+		got = req
+		if req.Command == "fail" {
+			return socket.Response{Success: false, Error: "no such thing"}
+		}
+		return socket.Response{Success: true, Data: []struct {
+			Name    string    `json:"name"`
+			Created time.Time `json:"created"`
+		}{{Name: "calm-owl", Created: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}}}
+	}, events.NewBus(10), nil, "")
+	ctx := context.Background()
+
+	resp, err := call(ctx, conn, "list_agents", map[string]interface{}{"repo": "my-app", "all": true})
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if got.Command != "list_agents" || got.TraceID != "trace-1" || got.Args["repo"] != "my-app" || got.Args["all"] != true {
+		t.Errorf("handler got %+v", got)
+	}
+	if !resp.GetSuccess() {
+		t.Errorf("success = false, error %q", resp.GetError())
+	}
+	list := resp.GetData().GetListValue().AsSlice()
+	if len(list) != 1 {
+		t.Fatalf("data = %v, want one agent", resp.GetData())
+	}
+	if agent := list[0].(map[string]interface{}); agent["name"] != "calm-owl" || agent["created"] != "2026-03-02T09:00:00Z" {
+		t.Errorf("data = %v, want the agent as the socket API encodes it", agent)
+	}
+
+	resp, err = call(ctx, conn, "fail", nil)
+	if err != nil || resp.GetSuccess() || resp.GetError() != "no such thing" {
+		t.Errorf("failing command = %v, %v; want the handler's error in the response", resp, err)
+	}
+
+	if _, err := call(ctx, conn, "", nil); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Call without a command error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestTypedRPCs(t *testing.T) {
+	var got []socket.Request
+	created := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	conn := testServer(t, func(req socket.Request) socket.Response {
+		got = append(got, req)
+		switch req.Command {
+		case "status":
+			return socket.Response{Success: true, Data: map[string]interface{}{"running": true, "pid": 42, "repos": 2, "agents": 5, "stalled": map[string]string{"health check": "3m late"}}}
+		case "list_repos":
+			return socket.Response{Success: true, Data: []map[string]interface{}{
+				{"name": "web", "tmux_session": "mc-web", "worker_count": 1, "pr_management_mode": "merge-queue"},
+				{"name": "api", "tmux_session": "mc-api", "is_fork": true},
+			}}
+		case "list_agents":
+			if req.Args["repo"] == "missing" {
+				return socket.Response{Success: false, Error: "repository 'missing' not found", Code: string(errors.CodeRepoNotFound)}
+			}
+			return socket.Response{Success: true, Data: []map[string]interface{}{
+				{"repo": "web", "name": "calm-owl", "type": "worker", "task": "add dark mode", "created_at": created, "status": "running"},
+			}}
+		case "add_task":
+			return socket.Response{Success: true, Data: map[string]interface{}{"id": "t-1", "repo": "web", "description": req.Args["task"], "after": req.Args["after"], "status": "waiting", "created_at": created}}
+		case "complete_agent":
+			return socket.Response{Success: true}
+		}
+		return socket.Response{Success: false, Error: "unknown command"}
+	}, events.NewBus(10), nil, "")
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "trace-id", "trace-9")
+
+	st, err := conn.GetStatus(ctx, &GetStatusRequest{})
+	if err != nil || st.GetPid() != 42 || st.GetAgents() != 5 || st.GetStalled()["health check"] != "3m late" {
+		t.Errorf("GetStatus = %v, %v", st, err)
+	}
+	if got[0].Command != "status" || got[0].TraceID != "trace-9" {
+		t.Errorf("GetStatus sent %+v, want status with the call's trace ID", got[0])
+	}
+
+	repos, err := conn.ListRepos(ctx, &ListReposRequest{})
+	if err != nil || len(repos.GetRepos()) != 2 || repos.GetRepos()[0].GetName() != "api" || !repos.GetRepos()[0].GetIsFork() || repos.GetRepos()[1].GetWorkerCount() != 1 {
+		t.Errorf("ListRepos = %v, %v; want both repos sorted by name", repos, err)
+	}
+
+	agents, err := conn.ListAgents(ctx, &ListAgentsRequest{Repo: "web", Rich: true})
+	if err != nil || len(agents.GetAgents()) != 1 {
+		t.Fatalf("ListAgents = %v, %v", agents, err)
+	}
+	if a := agents.GetAgents()[0]; a.GetName() != "calm-owl" || a.GetType() != "worker" || a.GetStatus() != "running" || !a.GetCreatedAt().AsTime().Equal(created) {
+		t.Errorf("agent = %v", a)
+	}
+	if req := got[len(got)-1]; req.Args["repo"] != "web" || req.Args["rich"] != true {
+		t.Errorf("ListAgents sent %+v", req)
+	}
+	if _, err := conn.ListAgents(ctx, &ListAgentsRequest{Repo: "missing"}); status.Code(err) != codes.NotFound || status.Convert(err).Message() != "repository 'missing' not found" {
+		t.Errorf("ListAgents of an unknown repo error = %v, want NotFound with the daemon's message", err)
+	}
+
+	added, err := conn.AddTask(ctx, &AddTaskRequest{Repo: "web", Task: "fix login", After: []string{"t-0"}})
+	if err != nil || added.GetTask().GetId() != "t-1" || added.GetTask().GetDescription() != "fix login" || len(added.GetTask().GetAfter()) != 1 {
+		t.Errorf("AddTask = %v, %v", added, err)
+	}
+
+	if _, err := conn.CompleteAgent(ctx, &CompleteAgentRequest{Repo: "web", Agent: "calm-owl", Summary: "done"}); err != nil {
+		t.Errorf("CompleteAgent failed: %v", err)
+	}
+	if req := got[len(got)-1]; req.Command != "complete_agent" || req.Args["agent"] != "calm-owl" || req.Args["summary"] != "done" || req.Args["failure_reason"] != nil {
+		t.Errorf("CompleteAgent sent %+v", req)
+	}
+}
+
+func TestToken(t *testing.T) {
+	conn := testServer(t, func(socket.Request) socket.Response { return socket.Response{Success: true} }, events.NewBus(10), nil, "s3cret")
+
+	if _, err := call(context.Background(), conn, "ping", nil); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Call without a token error = %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+	if _, err := call(ctx, conn, "ping", nil); err != nil {
+		t.Errorf("Call with the token failed: %v", err)
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	bus := events.NewBus(10)
+	bus.Publish(events.EventRepoAdded, "old-repo", "", nil)
+	conn := testServer(t, nil, bus, nil, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := conn.WatchEvents(ctx, &WatchEventsRequest{Repo: "my-app"})
+	if err != nil {
+		t.Fatalf("WatchEvents failed: %v", err)
+	}
+	// Events published once the stream is open arrive as they happen;
+	// other repositories' are left out
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		bus.Publish(events.EventAgentStarted, "other", "x", nil)
+		bus.PublishTraced("trace-2", events.EventAgentStarted, "my-app", "calm-owl", map[string]string{"type": "worker"})
+	}()
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if event.GetType() != "agent_started" || event.GetAgent() != "calm-owl" || event.GetSeq() != 3 || event.GetTraceId() != "trace-2" {
+		t.Errorf("event = %v", event)
+	}
+	if event.GetData()["type"] != "worker" {
+		t.Errorf("event data = %v", event.GetData())
+	}
+	if event.GetTime().GetSeconds() == 0 {
+		t.Error("event has no time")
+	}
+
+	backlog, err := conn.WatchEvents(ctx, &WatchEventsRequest{Backlog: true})
+	if err != nil {
+		t.Fatalf("WatchEvents failed: %v", err)
+	}
+	if event, err := backlog.Recv(); err != nil || event.GetRepo() != "old-repo" {
+		t.Errorf("first backlog event = %v, %v; want the oldest kept event", event, err)
+	}
+}
+
+func TestTailLogs(t *testing.T) {
+	dir := t.TempDir()
+	daemonLog := filepath.Join(dir, "daemon.log")
+	if err := os.WriteFile(daemonLog, []byte("one\ntwo\nthree\npart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conn := testServer(t, nil, events.NewBus(10), func(repo, agent string) (string, error) {
+		if repo == "" {
+			return daemonLog, nil
+		}
+		return filepath.Join(dir, "missing.log"), nil
+	}, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tail := func(req *TailLogsRequest) grpc.ServerStreamingClient[LogLine] {
+		t.Helper()
+		stream, err := conn.TailLogs(ctx, req)
+		if err != nil {
+			t.Fatalf("TailLogs failed: %v", err)
+		}
+		return stream
+	}
+	recv := func(stream grpc.ServerStreamingClient[LogLine]) (string, error) {
+		line, err := stream.Recv()
+		return line.GetLine(), err
+	}
+
+	stream := tail(&TailLogsRequest{Lines: 2, Follow: true})
+	for _, want := range []string{"two", "three"} {
+		if got, err := recv(stream); err != nil || got != want {
+			t.Fatalf("line = %q, %v; want %q", got, err, want)
+		}
+	}
+
+	// Lines written later arrive, including the end of the partial one
+	f, err := os.OpenFile(daemonLog, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("ial\nfour\n")
+	f.Close()
+	for _, want := range []string{"partial", "four"} {
+		if got, err := recv(stream); err != nil || got != want {
+			t.Fatalf("followed line = %q, %v; want %q", got, err, want)
+		}
+	}
+
+	missing := tail(&TailLogsRequest{Repo: "my-app", Agent: "calm-owl"})
+	if _, err := recv(missing); status.Code(err) != codes.NotFound {
+		t.Errorf("missing log error = %v, want NotFound", err)
+	}
+	halfway := tail(&TailLogsRequest{Repo: "my-app"})
+	if _, err := recv(halfway); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("repo without agent error = %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Package layout moves multiclaude's files from the legacy ~/.multiclaude
+// directory into the XDG layout: state and logs into the state directory,
+// clones and worktrees into the data directory. Git's links between clones
+// and their worktrees, and the worktree paths in the state file, are updated
+// to match.
+package layout
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// Move is a file or directory to move
+type Move struct {
+	From string
+	To   string
+}
+
+// Result is the outcome of a migration
+type Result struct {
+	Moves []Move
+	// Warnings are problems that didn't stop the migration, such as a
+	// worktree git couldn't repair
+	Warnings []string
+}
+
+// Plan returns the moves that take the files under from.Root to the layout
+// of to. The daemon's PID file and sockets are left out: they are stale once
+// the daemon has stopped and Migrate removes them.
+func Plan(from, to *config.Paths) ([]Move, error) {
+	entries, err := os.ReadDir(from.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", from.Root, err)
+	}
+
+	var moves []Move
+	for _, entry := range entries {
+		src := filepath.Join(from.Root, entry.Name())
+		var dst string
+		switch src {
+		case from.DaemonPID, from.DaemonSock, from.GRPCSock():
+			continue
+		case from.ReposDir:
+			dst = to.ReposDir
+		case from.WorktreesDir:
+			dst = to.WorktreesDir
+		default:
+			dst = filepath.Join(to.Root, entry.Name())
+		}
+		moves = append(moves, Move{From: src, To: dst})
+	}
+	return moves, nil
+}
+
+// Check returns an error for the first move whose destination is in use. An
+// empty directory, such as one a newer multiclaude created, doesn't count.
+func Check(moves []Move) error {
+	for _, m := range moves {
+		if inUse(m.To) {
+			return fmt.Errorf("%s already exists", m.To)
+		}
+	}
+	return nil
+}
+
+// inUse reports whether path exists and isn't an empty directory
+func inUse(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return true
+	}
+	entries, err := os.ReadDir(path)
+	return err != nil || len(entries) > 0
+}
+
+// Migrate moves the files under from.Root to the layout of to, rewrites the
+// worktree paths in the state file, repairs git's worktree links and removes
+// from.Root. The daemon must be stopped. If a move fails, the moves already
+// made are undone.
+func Migrate(from, to *config.Paths) (*Result, error) {
+	moves, err := Plan(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(moves); err != nil {
+		return nil, err
+	}
+
+	var done []Move
+	for _, m := range moves {
+		if err := move(m.From, m.To); err != nil {
+			undo(done)
+			return nil, fmt.Errorf("failed to move %s to %s: %w", m.From, m.To, err)
+		}
+		done = append(done, m)
+	}
+
+	for _, stale := range []string{from.DaemonPID, from.DaemonSock, from.GRPCSock()} {
+		os.Remove(stale)
+	}
+
+	result := &Result{Moves: moves}
+	if err := os.Remove(from.Root); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("could not remove %s: %v", from.Root, err))
+	}
+
+	st, err := state.Load(to.StateFile)
+	if err != nil {
+		return result, err
+	}
+	if err := rewriteState(st, from, to); err != nil {
+		return result, fmt.Errorf("failed to update worktree paths in %s: %w", to.StateFile, err)
+	}
+	result.Warnings = append(result.Warnings, repairWorktrees(st, to)...)
+	return result, nil
+}
+
+// move renames src to dst, replacing an empty directory at dst
+func move(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(dst); err == nil && info.IsDir() {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	return os.Rename(src, dst)
+}
+
+// undo moves files back, newest first
+func undo(done []Move) {
+	for i := len(done) - 1; i >= 0; i-- {
+		os.Rename(done[i].To, done[i].From)
+	}
+}
+
+// rebase returns path moved from under oldDir to under newDir, and whether
+// it was under oldDir
+func rebase(path, oldDir, newDir string) (string, bool) {
+	if path == oldDir {
+		return newDir, true
+	}
+	if strings.HasPrefix(path, oldDir+string(filepath.Separator)) {
+		return newDir + path[len(oldDir):], true
+	}
+	return path, false
+}
+
+// rebaseAll moves path along with whichever of the migrated directories it
+// is under
+func rebaseAll(path string, from, to *config.Paths) string {
+	for _, dirs := range [][2]string{
+		{from.ReposDir, to.ReposDir},
+		{from.WorktreesDir, to.WorktreesDir},
+		{from.Root, to.Root},
+	} {
+		if moved, ok := rebase(path, dirs[0], dirs[1]); ok {
+			return moved
+		}
+	}
+	return path
+}
+
+// rewriteState points agents' worktree paths, and worktree base directories
+// inside the legacy root, at their new locations
+func rewriteState(st *state.State, from, to *config.Paths) error {
+	for repoName, repo := range st.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			moved := rebaseAll(agent.WorktreePath, from, to)
+			if moved == agent.WorktreePath {
+				continue
+			}
+			agent.WorktreePath = moved
+			if err := st.UpdateAgent(repoName, agentName, agent); err != nil {
+				return err
+			}
+		}
+		if cfg := repo.WorktreeConfig; cfg.Base != "" {
+			if moved := rebaseAll(cfg.Base, from, to); moved != cfg.Base {
+				cfg.Base = moved
+				if err := st.UpdateWorktreeConfig(repoName, cfg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// repairWorktrees reconnects each clone and mirror with its moved worktrees,
+// returning a warning for each git failure
+func repairWorktrees(st *state.State, paths *config.Paths) []string {
+	repos := st.GetAllRepos()
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []string
+	for _, name := range names {
+		// Solo repositories have no clone; their worktrees branch from the
+		// directory they were started in, which didn't move
+		cloneDir := paths.RepoDir(name)
+		if repos[name].Solo && repos[name].Path != "" {
+			cloneDir = repos[name].Path
+		}
+
+		mirrorDir := paths.MirrorWorktreeDir(name)
+		clone := worktreesIn(filepath.Join(paths.WorktreesDir, name))
+		mirror := worktreesIn(mirrorDir)
+		for _, agent := range repos[name].Agents {
+			if agent.WorktreePath == cloneDir || !exists(agent.WorktreePath) {
+				continue
+			}
+			if _, ok := rebase(agent.WorktreePath, mirrorDir, mirrorDir); ok {
+				mirror = append(mirror, agent.WorktreePath)
+			} else {
+				clone = append(clone, agent.WorktreePath)
+			}
+		}
+
+		for _, repair := range []struct {
+			repoDir   string
+			worktrees []string
+		}{
+			{cloneDir, clone},
+			{paths.MirrorDir(name), mirror},
+		} {
+			if len(repair.worktrees) == 0 || !exists(repair.repoDir) {
+				continue
+			}
+			args := append([]string{"worktree", "repair"}, dedupe(repair.worktrees)...)
+			if out, err := gitcmd.CombinedOutput(context.Background(), repair.repoDir, args...); err != nil {
+				warnings = append(warnings, fmt.Sprintf("git worktree repair in %s: %v: %s", repair.repoDir, err, strings.TrimSpace(string(out))))
+			}
+		}
+	}
+	return warnings
+}
+
+// worktreesIn returns the directories in dir
+func worktreesIn(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return dirs
+}
+
+func dedupe(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	var unique []string
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,174 @@
+package layout
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.email=t@example.com", "-c", "user.name=t"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// setupLegacy creates a legacy layout with a clone of "app", a worker's
+// worktree and a state file tracking the worker
+func setupLegacy(t *testing.T) (from, to *config.Paths) {
+	t.Helper()
+	home := t.TempDir()
+	from = config.LegacyPaths(home)
+	to = config.RootPaths(filepath.Join(home, "state", "multiclaude"))
+	to.ReposDir = filepath.Join(home, "data", "multiclaude", "repos")
+	to.WorktreesDir = filepath.Join(home, "data", "multiclaude", "wts")
+	to.DaemonPID = filepath.Join(home, "run", "multiclaude", "daemon.pid")
+	to.DaemonSock = filepath.Join(home, "run", "multiclaude", "daemon.sock")
+	if err := from.EnsureDirectories(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := from.RepoDir("app")
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	git(t, repo, "commit", "-q", "--allow-empty", "-m", "init")
+	wt := from.AgentWorktree("app", "calm-owl")
+	git(t, repo, "worktree", "add", "-q", "-b", "work/calm-owl", wt)
+
+	st := state.New(from.StateFile)
+	if err := st.AddRepo("app", &state.Repository{TmuxSession: "mc-app", Agents: map[string]state.Agent{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddAgent("app", "calm-owl", state.Agent{Type: state.AgentTypeWorker, WorktreePath: wt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(from.DaemonPID, []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return from, to
+}
+
+func TestPlan(t *testing.T) {
+	from, to := setupLegacy(t)
+
+	moves, err := Plan(from, to)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, m := range moves {
+		got[m.From] = m.To
+	}
+	for src, dst := range map[string]string{
+		from.ReposDir:     to.ReposDir,
+		from.WorktreesDir: to.WorktreesDir,
+		from.StateFile:    to.StateFile,
+		from.MessagesDir:  to.MessagesDir,
+	} {
+		if got[src] != dst {
+			t.Errorf("%s moves to %q, want %q", src, got[src], dst)
+		}
+	}
+	if _, ok := got[from.DaemonPID]; ok {
+		t.Error("stale daemon.pid should not be moved")
+	}
+
+	if err := Check(moves); err != nil {
+		t.Errorf("Check() with nothing at the destinations: %v", err)
+	}
+	if err := os.MkdirAll(to.ReposDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(moves); err != nil {
+		t.Errorf("Check() with an empty destination directory: %v", err)
+	}
+	if err := os.MkdirAll(to.Root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to.StateFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(moves); err == nil || !strings.Contains(err.Error(), to.StateFile) {
+		t.Errorf("Check() with an existing state file = %v, want an error naming it", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	from, to := setupLegacy(t)
+
+	result, err := Migrate(from, to)
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Migrate() warnings: %v", result.Warnings)
+	}
+	if _, err := os.Stat(from.Root); !os.IsNotExist(err) {
+		t.Errorf("legacy root still exists: %v", err)
+	}
+
+	st, err := state.Load(to.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt := to.AgentWorktree("app", "calm-owl")
+	if agent, _ := st.GetAgent("app", "calm-owl"); agent.WorktreePath != wt {
+		t.Errorf("WorktreePath = %q, want %q", agent.WorktreePath, wt)
+	}
+
+	// Both sides of git's worktree link point at the new locations
+	if branch := strings.TrimSpace(git(t, wt, "rev-parse", "--abbrev-ref", "HEAD")); branch != "work/calm-owl" {
+		t.Errorf("worktree branch = %q", branch)
+	}
+	if list := git(t, to.RepoDir("app"), "worktree", "list", "--porcelain"); !strings.Contains(list, "worktree "+wt+"\n") {
+		t.Errorf("git worktree list doesn't show %s:\n%s", wt, list)
+	}
+}
+
+func TestMigrateUndoesMoves(t *testing.T) {
+	from, to := setupLegacy(t)
+	// A file where a directory's parent should be makes that move fail
+	if err := os.MkdirAll(filepath.Dir(filepath.Dir(to.WorktreesDir)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Dir(to.WorktreesDir), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Migrate(from, to); err == nil {
+		t.Fatal("Migrate() should fail")
+	}
+	for _, path := range []string{from.MessagesDir, from.StateFile, from.RepoDir("app"), from.AgentWorktree("app", "calm-owl")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s not restored: %v", path, err)
+		}
+	}
+}
+
+func TestRebase(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/old", "/new", true},
+		{"/old/wts/app", "/new/wts/app", true},
+		{"/older/wts", "/older/wts", false},
+		{"/elsewhere", "/elsewhere", false},
+	}
+	for _, tt := range tests {
+		got, ok := rebase(tt.path, "/old", "/new")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("rebase(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
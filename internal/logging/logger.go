@@ -1,25 +1,38 @@
 package logging
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
+// levels orders the log levels from most to least verbose
+var levels = map[string]int32{"DEBUG": 0, "INFO": 1, "WARN": 2, "ERROR": 3}
+
 // Logger provides structured logging
 type Logger struct {
 	mu     sync.Mutex
 	writer io.Writer
 	logger *log.Logger
+	trace  string // Tags every line when set, see WithTrace
+	scrub  func(string) string
+	// minLevel is shared with the loggers WithTrace returns, so SetLevel
+	// applies to them too
+	minLevel *atomic.Int32
 }
 
 // New creates a new logger that writes to the given writer
 func New(w io.Writer) *Logger {
 	return &Logger{
-		writer: w,
-		logger: log.New(w, "", log.LstdFlags),
+		writer:   w,
+		logger:   log.New(w, "", log.LstdFlags),
+		minLevel: new(atomic.Int32),
 	}
 }
 
@@ -33,6 +46,61 @@ func NewFile(path string) (*Logger, error) {
 	return New(f), nil
 }
 
+// NewTraceID returns a short random ID that ties together the log lines and
+// events caused by one user action
+func NewTraceID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// TraceTag is how a trace ID appears in log lines, e.g. "[trace=3f9a0c12d4e5]"
+func TraceTag(id string) string {
+	return "[trace=" + id + "]"
+}
+
+// WithTrace returns a logger writing to the same place whose lines are tagged
+// with a trace ID. An empty ID returns the logger itself. Closing either
+// logger closes the shared file.
+func (l *Logger) WithTrace(id string) *Logger {
+	if id == "" {
+		return l
+	}
+	return &Logger{writer: l.writer, logger: l.logger, trace: id, scrub: l.scrub, minLevel: l.minLevel}
+}
+
+// SetLevel drops messages less severe than level: "debug" (the default, which
+// logs everything), "info", "warn" or "error"
+func (l *Logger) SetLevel(level string) error {
+	n, ok := levels[strings.ToUpper(level)]
+	if !ok {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	l.minLevel.Store(n)
+	return nil
+}
+
+// SetScrubber sets a function every message passes through before it is
+// written, such as one that redacts secrets. Set it before calling WithTrace.
+func (l *Logger) SetScrubber(scrub func(string) string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scrub = scrub
+}
+
+// Scrub passes msg through the scrubber, for messages that are kept or shown
+// somewhere other than the log
+func (l *Logger) Scrub(msg string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.scrub == nil {
+		return msg
+	}
+	return l.scrub(msg)
+}
+
 // Info logs an informational message
 func (l *Logger) Info(format string, args ...interface{}) {
 	l.log("INFO", format, args...)
@@ -55,10 +123,20 @@ func (l *Logger) Debug(format string, args ...interface{}) {
 
 // log formats and writes a log message
 func (l *Logger) log(level, format string, args ...interface{}) {
+	if levels[level] < l.minLevel.Load() {
+		return
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	msg := fmt.Sprintf(format, args...)
+	if l.trace != "" {
+		msg = TraceTag(l.trace) + " " + msg
+	}
+	if l.scrub != nil {
+		msg = l.scrub(msg)
+	}
 	l.logger.Printf("[%s] %s", level, msg)
 }
 
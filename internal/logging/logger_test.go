@@ -189,3 +189,83 @@ func TestLoggerThreadSafety(t *testing.T) {
 		t.Errorf("Expected 1000 log lines, got %d", len(lines))
 	}
 }
+
+func TestLoggerWithTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf)
+
+	if logger.WithTrace("") != logger {
+		t.Error("WithTrace(\"\") should return the logger itself")
+	}
+
+	id := NewTraceID()
+	if len(id) != 12 || id == NewTraceID() {
+		t.Errorf("NewTraceID() = %q, want 12 random hex characters", id)
+	}
+
+	logger.WithTrace(id).Info("created worktree")
+	logger.Info("untraced")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if !strings.Contains(lines[0], "[INFO] "+TraceTag(id)+" created worktree") {
+		t.Errorf("traced line = %q", lines[0])
+	}
+	if strings.Contains(lines[1], "trace=") {
+		t.Errorf("untraced line = %q", lines[1])
+	}
+}
+
+func TestLoggerScrubber(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf)
+	logger.SetScrubber(func(msg string) string { return strings.ReplaceAll(msg, "hunter2", "<redacted>") })
+
+	logger.Info("password is %s", "hunter2")
+	logger.WithTrace("abc123").Warn("still hunter2")
+
+	output := buf.String()
+	if strings.Contains(output, "hunter2") {
+		t.Errorf("log output not scrubbed: %s", output)
+	}
+	if strings.Count(output, "<redacted>") != 2 {
+		t.Errorf("log output = %q, want both lines scrubbed", output)
+	}
+	if got := logger.Scrub("hunter2 again"); got != "<redacted> again" {
+		t.Errorf("Scrub() = %q", got)
+	}
+	if got := New(buf).Scrub("hunter2"); got != "hunter2" {
+		t.Errorf("Scrub() without a scrubber = %q", got)
+	}
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf)
+	traced := logger.WithTrace("abc123")
+
+	if err := logger.SetLevel("warn"); err != nil {
+		t.Fatalf("SetLevel() error: %v", err)
+	}
+	logger.Debug("debug line")
+	logger.Info("info line")
+	traced.Info("traced info line")
+	logger.Warn("warn line")
+	traced.Error("traced error line")
+
+	output := buf.String()
+	for _, dropped := range []string{"debug line", "info line"} {
+		if strings.Contains(output, dropped) {
+			t.Errorf("output contains %q below the level:\n%s", dropped, output)
+		}
+	}
+	if !strings.Contains(output, "warn line") || !strings.Contains(output, "traced error line") {
+		t.Errorf("output lost lines at or above the level:\n%s", output)
+	}
+
+	if err := logger.SetLevel("loud"); err == nil {
+		t.Error("SetLevel() accepted an unknown level")
+	}
+}
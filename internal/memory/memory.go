@@ -0,0 +1,182 @@
+// Package memory keeps each agent's persistent notes: decisions and context
+// worth carrying across restarts, respawns and daemon restarts. The daemon
+// owns the files. Agents add notes through the CLI, and the daemon adds a
+// summary when a worker finishes its task. The newest notes go into the
+// agent's prompt whenever it is started or resumed.
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxEntries is how many entries a memory file keeps; older ones are dropped
+	MaxEntries = 200
+	// MaxEntryBytes caps the text of one entry
+	MaxEntryBytes = 2000
+	// Retention is how long the memory of a removed agent is kept, so a worker
+	// restored from the trash still has it
+	Retention = 7 * 24 * time.Hour
+)
+
+// Sources of entries
+const (
+	SourceNote   = "note"
+	SourceTask   = "task"
+	SourceWorker = "worker"
+)
+
+// Entry is one note in an agent's memory
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	// About names the agent an entry is about when it isn't the memory's own
+	// agent, e.g. the worker a supervisor's entry summarizes
+	About string `json:"about,omitempty"`
+	Text  string `json:"text"`
+}
+
+// entryPrefix starts each entry's heading in a memory file
+const entryPrefix = "### "
+
+// Load reads the entries of a memory file, oldest first. A missing file has none.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	var text []string
+	flush := func() {
+		if len(entries) > 0 {
+			entries[len(entries)-1].Text = strings.TrimSpace(strings.Join(text, "\n"))
+		}
+		text = nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if e, ok := parseHeading(line); ok {
+			flush()
+			entries = append(entries, e)
+			continue
+		}
+		text = append(text, line)
+	}
+	flush()
+	return entries, nil
+}
+
+// parseHeading parses an entry heading: "### <RFC 3339 time> <source> [<about>]".
+// Requiring the timestamp keeps headings in the notes themselves from
+// being taken for entries.
+func parseHeading(line string) (Entry, bool) {
+	if !strings.HasPrefix(line, entryPrefix) {
+		return Entry{}, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, entryPrefix))
+	if len(fields) < 2 || len(fields) > 3 {
+		return Entry{}, false
+	}
+	t, err := time.Parse(time.RFC3339, fields[0])
+	if err != nil {
+		return Entry{}, false
+	}
+	e := Entry{Time: t, Source: fields[1]}
+	if len(fields) == 3 {
+		e.About = fields[2]
+	}
+	return e, true
+}
+
+// Append adds an entry to the memory of agentName in repoName, creating the
+// file if needed and dropping the oldest entries past MaxEntries
+func Append(path, repoName, agentName string, e Entry) error {
+	e.Text = strings.TrimSpace(e.Text)
+	if e.Text == "" {
+		return fmt.Errorf("note is empty")
+	}
+	if len(e.Text) > MaxEntryBytes {
+		return fmt.Errorf("note is %d bytes; keep it under %d", len(e.Text), MaxEntryBytes)
+	}
+	if strings.ContainsAny(e.Source+e.About, " \n") {
+		return fmt.Errorf("invalid source %q", e.Source)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, e)
+	if len(entries) > MaxEntries {
+		entries = entries[len(entries)-MaxEntries:]
+	}
+	return write(path, repoName, agentName, entries)
+}
+
+// write replaces a memory file with entries
+func write(path, repoName, agentName string, entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Memory of %s in %s\n\nKept by the multiclaude daemon. Add to it with `multiclaude memory add`.\n", agentName, repoName)
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n%s%s %s", entryPrefix, e.Time.UTC().Format(time.RFC3339), e.Source)
+		if e.About != "" {
+			b.WriteString(" " + e.About)
+		}
+		b.WriteString("\n\n" + e.Text + "\n")
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Clean deletes the memory files under dir (one directory per repository)
+// of agents that no longer exist and that haven't changed for Retention
+func Clean(dir string, exists func(repoName, agentName string) bool, now time.Time) ([]string, error) {
+	repos, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, repo := range repos {
+		if !repo.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dir, repo.Name()))
+		if err != nil {
+			return deleted, err
+		}
+		for _, f := range files {
+			agentName, ok := strings.CutSuffix(f.Name(), ".md")
+			if !ok || f.IsDir() || exists(repo.Name(), agentName) {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil || now.Sub(info.ModTime()) < Retention {
+				continue
+			}
+			path := filepath.Join(dir, repo.Name(), f.Name())
+			if err := os.Remove(path); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, path)
+		}
+	}
+	return deleted, nil
+}
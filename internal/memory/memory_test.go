@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my-app", "calm-owl.md")
+	if entries, err := Load(path); err != nil || entries != nil {
+		t.Fatalf("Load() of a missing file = %v, %v", entries, err)
+	}
+
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	notes := []Entry{
+		{Time: at, Source: SourceNote, Text: "The API client is generated; edit api.yaml, not client.go.\n\n### Not a heading"},
+		{Time: at.Add(time.Hour), Source: SourceWorker, About: "brave-fox", Text: "Completed: add dark mode"},
+	}
+	for _, e := range notes {
+		if err := Append(path, "my-app", "calm-owl", e); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load() = %+v, want 2 entries", entries)
+	}
+	for i, e := range entries {
+		if !e.Time.Equal(notes[i].Time) || e.Source != notes[i].Source || e.About != notes[i].About || e.Text != notes[i].Text {
+			t.Errorf("entry %d = %+v, want %+v", i, e, notes[i])
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# Memory of calm-owl in my-app") {
+		t.Errorf("memory file = %q, want a heading naming the agent", data)
+	}
+}
+
+func TestAppendRejects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calm-owl.md")
+	for _, e := range []Entry{
+		{Source: SourceNote, Text: "  \n"},
+		{Source: SourceNote, Text: strings.Repeat("x", MaxEntryBytes+1)},
+		{Source: "two words", Text: "note"},
+	} {
+		if err := Append(path, "my-app", "calm-owl", e); err == nil {
+			t.Errorf("Append(%+v) succeeded", e)
+		}
+	}
+}
+
+func TestAppendDropsOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "supervisor.md")
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < MaxEntries+5; i++ {
+		if err := Append(path, "my-app", "supervisor", Entry{Time: at.Add(time.Duration(i) * time.Minute), Source: SourceNote, Text: "note"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, _ := Load(path)
+	if len(entries) != MaxEntries || !entries[0].Time.Equal(at.Add(5*time.Minute)) {
+		t.Errorf("Load() = %d entries starting %v, want the newest %d", len(entries), entries[0].Time, MaxEntries)
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-Retention - time.Hour)
+	write := func(agent string, modified time.Time) string {
+		path := filepath.Join(dir, "my-app", agent+".md")
+		if err := Append(path, "my-app", agent, Entry{Time: modified, Source: SourceNote, Text: "note"}); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modified, modified)
+		return path
+	}
+	removedLongAgo := write("gone-owl", old)
+	removedRecently := write("fresh-fox", now)
+	stillHere := write("supervisor", old)
+
+	deleted, err := Clean(dir, func(repo, agent string) bool { return agent == "supervisor" }, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != removedLongAgo {
+		t.Errorf("Clean() deleted %v, want only %s", deleted, removedLongAgo)
+	}
+	for _, path := range []string{removedRecently, stillHere} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Clean() deleted %s", path)
+		}
+	}
+}
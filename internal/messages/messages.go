@@ -1,10 +1,10 @@
 package messages
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +20,26 @@ const (
 	StatusAcked     Status = "acked"
 )
 
+// Priority is how urgently a message is delivered. Messages without one are normal.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// rank orders priorities for delivery, most urgent first
+func (p Priority) rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	}
+	return 1
+}
+
 // Message represents a message between agents
 type Message struct {
 	ID        string     `json:"id"`
@@ -29,70 +49,133 @@ type Message struct {
 	Body      string     `json:"body"`
 	Status    Status     `json:"status"`
 	AckedAt   *time.Time `json:"acked_at,omitempty"`
+	// IdempotencyKey is the client-generated key the message was sent with, if any
+	IdempotencyKey string   `json:"idempotency_key,omitempty"`
+	Priority       Priority `json:"priority,omitempty"`
+	// Routed is set once the daemon has applied the repository's routing rules
+	Routed bool `json:"routed,omitempty"`
+	// CCOf is the original recipient when the message is a copy sent by a routing rule
+	CCOf string `json:"cc_of,omitempty"`
 }
 
-// Manager handles message filesystem operations
+// DedupWindow is how long a retried send with the same idempotency key
+// returns the original message instead of writing a new one
+const DedupWindow = 10 * time.Minute
+
+// Manager handles message operations on top of a Transport
 type Manager struct {
 	messagesRoot string
+	transport    Transport
 }
 
-// NewManager creates a new message manager
+// NewManager creates a new message manager using the default transport (the
+// file transport unless SetDefaultTransport installed another)
 func NewManager(messagesRoot string) *Manager {
-	return &Manager{messagesRoot: messagesRoot}
+	return &Manager{messagesRoot: messagesRoot, transport: newDefaultTransport(messagesRoot)}
+}
+
+// NewManagerWithTransport creates a message manager that uses the given transport
+func NewManagerWithTransport(t Transport) *Manager {
+	return &Manager{transport: t}
 }
 
-// Send creates a new message file
+// Send creates a new message
 func (m *Manager) Send(repoName, from, to, body string) (*Message, error) {
+	return m.send(repoName, from, to, body, newMessageID(), "")
+}
+
+// SendIdempotent creates a new message unless one was already sent with the
+// same idempotency key (from the same sender to the same recipient) within
+// DedupWindow, in which case it returns the original message. Clients that
+// retry a send after a timeout pass the same key on every attempt.
+//
+// The message ID is derived from the key, so the check is written ahead of
+// the message: concurrent retries write the same message rather than two. A
+// key reused after the window replaces the expired record, so retries of the
+// new send are deduplicated too; the old message moves to a fresh ID rather
+// than being lost.
+func (m *Manager) SendIdempotent(repoName, from, to, body, key string) (*Message, error) {
+	if key == "" {
+		return m.Send(repoName, from, to, body)
+	}
+
+	id := idempotentMessageID(from, to, key)
+	existing, err := m.transport.Get(repoName, to, id)
+	if err == nil {
+		if time.Since(existing.Timestamp) < DedupWindow {
+			return existing, nil
+		}
+		expired := *existing
+		expired.ID = newMessageID()
+		if err := m.transport.Put(repoName, to, &expired); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.send(repoName, from, to, body, id, key)
+}
+
+func (m *Manager) send(repoName, from, to, body, id, key string) (*Message, error) {
 	msg := &Message{
-		ID:        fmt.Sprintf("msg-%s", uuid.New().String()[:13]),
-		From:      from,
-		To:        to,
-		Timestamp: time.Now(),
-		Body:      body,
-		Status:    StatusPending,
+		ID:             id,
+		From:           from,
+		To:             to,
+		Timestamp:      time.Now(),
+		Body:           body,
+		Status:         StatusPending,
+		IdempotencyKey: key,
 	}
 
-	if err := m.write(repoName, to, msg); err != nil {
+	if err := m.transport.Put(repoName, to, msg); err != nil {
 		return nil, err
 	}
 
 	return msg, nil
 }
 
+// newMessageID returns a random message ID
+func newMessageID() string {
+	return fmt.Sprintf("msg-%s", uuid.New().String()[:13])
+}
+
+// idempotentMessageID returns the message ID for a sender, recipient and idempotency key
+func idempotentMessageID(from, to, key string) string {
+	sum := sha256.Sum256([]byte(from + "\x00" + to + "\x00" + key))
+	return "msg-" + hex.EncodeToString(sum[:])[:13]
+}
+
 // List returns all messages for an agent
 func (m *Manager) List(repoName, agentName string) ([]*Message, error) {
-	dir := m.agentDir(repoName, agentName)
+	return m.transport.List(repoName, agentName)
+}
 
-	entries, err := os.ReadDir(dir)
+// ListAll returns every message in every inbox, keyed by "<repo>/<agent>"
+func (m *Manager) ListAll() (map[string][]*Message, error) {
+	repos, err := m.transport.Repos()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []*Message{}, nil
-		}
-		return nil, fmt.Errorf("failed to read messages directory: %w", err)
+		return nil, err
 	}
 
-	var messages []*Message
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-
-		msg, err := m.read(repoName, agentName, entry.Name())
+	all := make(map[string][]*Message)
+	for _, repoName := range repos {
+		agents, err := m.transport.Inboxes(repoName)
 		if err != nil {
-			// Skip invalid messages
 			continue
 		}
-
-		messages = append(messages, msg)
+		for _, agentName := range agents {
+			msgs, err := m.List(repoName, agentName)
+			if err != nil || len(msgs) == 0 {
+				continue
+			}
+			all[repoName+"/"+agentName] = msgs
+		}
 	}
-
-	return messages, nil
+	return all, nil
 }
 
 // Get retrieves a specific message by ID
 func (m *Manager) Get(repoName, agentName, messageID string) (*Message, error) {
-	filename := messageID + ".json"
-	return m.read(repoName, agentName, filename)
+	return m.transport.Get(repoName, agentName, messageID)
 }
 
 // UpdateStatus updates the status of a message
@@ -108,7 +191,51 @@ func (m *Manager) UpdateStatus(repoName, agentName, messageID string, status Sta
 		msg.AckedAt = &now
 	}
 
-	return m.write(repoName, agentName, msg)
+	return m.transport.Put(repoName, agentName, msg)
+}
+
+// MarkRouted records that routing rules were applied to a message, along
+// with the priority they gave it (empty keeps the current one)
+func (m *Manager) MarkRouted(repoName, agentName, messageID string, priority Priority) error {
+	msg, err := m.Get(repoName, agentName, messageID)
+	if err != nil {
+		return err
+	}
+
+	msg.Routed = true
+	if priority != "" {
+		msg.Priority = priority
+	}
+	return m.transport.Put(repoName, agentName, msg)
+}
+
+// CC sends a copy of msg to another agent. The copy is already routed, so
+// rules don't apply to it again, and its ID is derived from the original's,
+// so copying the same message twice writes one copy.
+func (m *Manager) CC(repoName string, msg *Message, to string) (*Message, error) {
+	cc := &Message{
+		ID:        idempotentMessageID(msg.From, to, "cc:"+msg.ID),
+		From:      msg.From,
+		To:        to,
+		Timestamp: time.Now(),
+		Body:      msg.Body,
+		Status:    StatusPending,
+		Priority:  msg.Priority,
+		Routed:    true,
+		CCOf:      msg.To,
+	}
+	if err := m.transport.Put(repoName, to, cc); err != nil {
+		return nil, err
+	}
+	return cc, nil
+}
+
+// SortByPriority orders messages most urgent first, keeping the order of
+// messages of the same priority
+func SortByPriority(msgs []*Message) {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].Priority.rank() < msgs[j].Priority.rank()
+	})
 }
 
 // Ack marks a message as acknowledged
@@ -116,13 +243,9 @@ func (m *Manager) Ack(repoName, agentName, messageID string) error {
 	return m.UpdateStatus(repoName, agentName, messageID, StatusAcked)
 }
 
-// Delete removes a message file
+// Delete removes a message
 func (m *Manager) Delete(repoName, agentName, messageID string) error {
-	path := filepath.Join(m.agentDir(repoName, agentName), messageID+".json")
-	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete message: %w", err)
-	}
-	return nil
+	return m.transport.Delete(repoName, agentName, messageID)
 }
 
 // DeleteAcked removes all acknowledged messages for an agent
@@ -161,62 +284,42 @@ func (m *Manager) ListUnread(repoName, agentName string) ([]*Message, error) {
 	return unread, nil
 }
 
-// agentDir returns the directory path for an agent's messages
-func (m *Manager) agentDir(repoName, agentName string) string {
-	return filepath.Join(m.messagesRoot, repoName, agentName)
+// Repos returns the repositories that have at least one inbox
+func (m *Manager) Repos() ([]string, error) {
+	return m.transport.Repos()
 }
 
-// ensureAgentDir ensures the agent's message directory exists
-func (m *Manager) ensureAgentDir(repoName, agentName string) error {
-	dir := m.agentDir(repoName, agentName)
-	return os.MkdirAll(dir, 0755)
+// Inboxes returns the agents that have an inbox in a repository
+func (m *Manager) Inboxes(repoName string) ([]string, error) {
+	return m.transport.Inboxes(repoName)
 }
 
-// write writes a message to disk
-func (m *Manager) write(repoName, agentName string, msg *Message) error {
-	if err := m.ensureAgentDir(repoName, agentName); err != nil {
-		return err
-	}
-
-	data, err := json.MarshalIndent(msg, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
-	}
-
-	path := filepath.Join(m.agentDir(repoName, agentName), msg.ID+".json")
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write message file: %w", err)
-	}
-
-	return nil
+// DeleteInbox removes an agent's inbox and all its messages
+func (m *Manager) DeleteInbox(repoName, agentName string) error {
+	return m.transport.DeleteInbox(repoName, agentName)
 }
 
-// read reads a message from disk
-func (m *Manager) read(repoName, agentName, filename string) (*Message, error) {
-	path := filepath.Join(m.agentDir(repoName, agentName), filename)
-	data, err := os.ReadFile(path)
+// DeleteRepo removes every inbox in a repository
+func (m *Manager) DeleteRepo(repoName string) (int, error) {
+	agents, err := m.transport.Inboxes(repoName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read message file: %w", err)
+		return 0, err
 	}
-
-	var msg Message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		return nil, fmt.Errorf("failed to parse message: %w", err)
+	count := 0
+	for _, agentName := range agents {
+		if err := m.transport.DeleteInbox(repoName, agentName); err != nil {
+			return count, err
+		}
+		count++
 	}
-
-	return &msg, nil
+	return count, nil
 }
 
-// CleanupOrphaned removes message directories for non-existent agents
+// CleanupOrphaned removes inboxes of non-existent agents
 func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, error) {
-	repoDir := filepath.Join(m.messagesRoot, repoName)
-
-	entries, err := os.ReadDir(repoDir)
+	agents, err := m.transport.Inboxes(repoName)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to read repo messages dir: %w", err)
+		return 0, err
 	}
 
 	validAgentMap := make(map[string]bool)
@@ -225,15 +328,10 @@ func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, e
 	}
 
 	count := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		if !validAgentMap[entry.Name()] {
-			// This is an orphaned agent directory
-			path := filepath.Join(repoDir, entry.Name())
-			if err := os.RemoveAll(path); err == nil {
+	for _, agentName := range agents {
+		if !validAgentMap[agentName] {
+			// This is an orphaned inbox
+			if err := m.transport.DeleteInbox(repoName, agentName); err == nil {
 				count++
 			}
 		}
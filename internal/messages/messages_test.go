@@ -95,6 +95,44 @@ func TestListMessages(t *testing.T) {
 	}
 }
 
+func TestListAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+
+	// Missing root
+	all, err := NewManager(filepath.Join(tmpDir, "missing")).ListAll()
+	if err != nil {
+		t.Fatalf("ListAll() on missing root failed: %v", err)
+	}
+	if len(all) != 0 {
+		t.Errorf("ListAll() on missing root length = %d, want 0", len(all))
+	}
+
+	if _, err := m.Send("repo-a", "supervisor", "worker1", "one"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if _, err := m.Send("repo-a", "supervisor", "worker1", "two"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if _, err := m.Send("repo-b", "worker2", "supervisor", "three"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	all, err = m.ListAll()
+	if err != nil {
+		t.Fatalf("ListAll() failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("ListAll() inboxes = %d, want 2", len(all))
+	}
+	if len(all["repo-a/worker1"]) != 2 {
+		t.Errorf("repo-a/worker1 messages = %d, want 2", len(all["repo-a/worker1"]))
+	}
+	if len(all["repo-b/supervisor"]) != 1 {
+		t.Errorf("repo-b/supervisor messages = %d, want 1", len(all["repo-b/supervisor"]))
+	}
+}
+
 func TestGetMessage(t *testing.T) {
 	tmpDir := t.TempDir()
 	m := NewManager(tmpDir)
@@ -374,6 +412,69 @@ func TestCleanupOrphaned(t *testing.T) {
 	}
 }
 
+func TestSendIdempotent(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	first, err := m.SendIdempotent("repo", "supervisor", "worker1", "Rebase please", "key-1")
+	if err != nil {
+		t.Fatalf("SendIdempotent() failed: %v", err)
+	}
+	if first.IdempotencyKey != "key-1" {
+		t.Errorf("IdempotencyKey = %q, want key-1", first.IdempotencyKey)
+	}
+
+	// A retry returns the original message without writing a second one
+	retry, err := m.SendIdempotent("repo", "supervisor", "worker1", "Rebase please", "key-1")
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if retry.ID != first.ID || !retry.Timestamp.Equal(first.Timestamp) {
+		t.Errorf("retry = %+v, want the original message %+v", retry, first)
+	}
+	if msgs, _ := m.List("repo", "worker1"); len(msgs) != 1 {
+		t.Errorf("inbox has %d messages after retry, want 1", len(msgs))
+	}
+
+	// The key is scoped to sender and recipient
+	if other, _ := m.SendIdempotent("repo", "merge-queue", "worker1", "Rebase please", "key-1"); other.ID == first.ID {
+		t.Error("same key from another sender should be a new message")
+	}
+
+	// Without a key every send is a new message
+	m.SendIdempotent("repo", "supervisor", "worker2", "hi", "")
+	m.SendIdempotent("repo", "supervisor", "worker2", "hi", "")
+	if msgs, _ := m.List("repo", "worker2"); len(msgs) != 2 {
+		t.Errorf("sends without a key = %d messages, want 2", len(msgs))
+	}
+
+	// After the window the key starts a new message, which replaces the
+	// expired record so its own retries are deduplicated; the old message
+	// is kept under another ID
+	first.Timestamp = time.Now().Add(-DedupWindow - time.Minute)
+	if err := m.transport.Put("repo", "worker1", first); err != nil {
+		t.Fatal(err)
+	}
+	late, err := m.SendIdempotent("repo", "supervisor", "worker1", "Rebase again", "key-1")
+	if err != nil {
+		t.Fatalf("late send failed: %v", err)
+	}
+	if late.Body != "Rebase again" || !time.Now().Add(-time.Minute).Before(late.Timestamp) {
+		t.Errorf("send after the window = %+v, want a new message", late)
+	}
+	if retry, err := m.SendIdempotent("repo", "supervisor", "worker1", "Rebase again", "key-1"); err != nil || retry.ID != late.ID {
+		t.Errorf("retry of the late send = %v, %v; want the late message %s", retry, err, late.ID)
+	}
+	msgs, _ := m.List("repo", "worker1")
+	bodies := map[string]int{}
+	for _, msg := range msgs {
+		bodies[msg.Body]++
+	}
+	// (the other sender's message is there too)
+	if len(msgs) != 3 || bodies["Rebase please"] != 2 || bodies["Rebase again"] != 1 {
+		t.Errorf("inbox = %d messages %v, want the original and the late one once each", len(msgs), bodies)
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	t.Run("Send fails with invalid permissions", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -546,3 +647,59 @@ func TestErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func TestMarkRoutedAndCC(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	msg, err := m.Send("repo", "merge-queue", "worker1", "PR #12 merged")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if err := m.MarkRouted("repo", "worker1", msg.ID, PriorityHigh); err != nil {
+		t.Fatalf("MarkRouted() failed: %v", err)
+	}
+	routed, err := m.Get("repo", "worker1", msg.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !routed.Routed || routed.Priority != PriorityHigh {
+		t.Errorf("routed message = %+v, want routed with high priority", routed)
+	}
+
+	// Copying twice writes a single copy
+	for i := 0; i < 2; i++ {
+		if _, err := m.CC("repo", routed, "supervisor"); err != nil {
+			t.Fatalf("CC() failed: %v", err)
+		}
+	}
+	copies, err := m.List("repo", "supervisor")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(copies) != 1 {
+		t.Fatalf("supervisor has %d messages, want 1", len(copies))
+	}
+	cc := copies[0]
+	if cc.From != "merge-queue" || cc.CCOf != "worker1" || cc.Body != "PR #12 merged" || !cc.Routed || cc.Priority != PriorityHigh || cc.Status != StatusPending {
+		t.Errorf("copy = %+v", cc)
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	msgs := []*Message{
+		{ID: "a", Priority: PriorityLow},
+		{ID: "b"},
+		{ID: "c", Priority: PriorityHigh},
+		{ID: "d", Priority: PriorityNormal},
+		{ID: "e", Priority: PriorityHigh},
+	}
+	SortByPriority(msgs)
+
+	var got string
+	for _, msg := range msgs {
+		got += msg.ID
+	}
+	if got != "cebda" {
+		t.Errorf("order = %q, want %q", got, "cebda")
+	}
+}
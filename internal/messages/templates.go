@@ -0,0 +1,135 @@
+package messages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RepoTemplatesDir is where a repository keeps its message templates,
+// relative to the repository root. Each <name>.md file is a template sent
+// with `multiclaude message send <to> --template <name>`.
+const RepoTemplatesDir = ".multiclaude/messages"
+
+// Template is a canned message body with {{variable}} placeholders
+type Template struct {
+	Name        string
+	Description string
+	Path        string
+	// Body is the text sent, without the template's title line
+	Body string
+}
+
+// templateNameRe matches usable template names: the file name without .md
+var templateNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// templateTitleRe matches an optional "# name - Description" first line
+var templateTitleRe = regexp.MustCompile(`^#\s+\S+\s+-\s+(.+)$`)
+
+// templateVarRe matches a {{variable}} placeholder
+var templateVarRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+
+// ListTemplates returns the repository's message templates sorted by name.
+// Files that can't be used are skipped and described in the returned
+// warnings; a missing templates directory is not a problem.
+func ListTemplates(repoPath string) ([]Template, []string) {
+	dir := filepath.Join(repoPath, RepoTemplatesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []string{fmt.Sprintf("%s: %v", dir, err)}
+	}
+
+	var templates []Template
+	var warnings []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		path := filepath.Join(dir, entry.Name())
+		if !templateNameRe.MatchString(name) {
+			warnings = append(warnings, fmt.Sprintf("%s: skipped, template names use lowercase letters, digits, - and _", path))
+			continue
+		}
+		tmpl, err := readTemplate(name, path)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, warnings
+}
+
+// LoadTemplate returns the repository's message template called name
+func LoadTemplate(repoPath, name string) (Template, error) {
+	if !templateNameRe.MatchString(name) {
+		return Template{}, fmt.Errorf("invalid template name %q", name)
+	}
+	path := filepath.Join(repoPath, RepoTemplatesDir, name+".md")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Template{}, fmt.Errorf("no message template %q in %s", name, filepath.Join(repoPath, RepoTemplatesDir))
+	}
+	return readTemplate(name, path)
+}
+
+// readTemplate reads a template file. A "# name - Description" first line
+// gives the description and is left out of the body; otherwise the body's
+// first line describes it.
+func readTemplate(name, path string) (Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, fmt.Errorf("%s: %v", path, err)
+	}
+	body := strings.TrimSpace(string(data))
+	if body == "" {
+		return Template{}, fmt.Errorf("%s: skipped, the file is empty", path)
+	}
+
+	tmpl := Template{Name: name, Path: path, Body: body}
+	first, rest, _ := strings.Cut(body, "\n")
+	if m := templateTitleRe.FindStringSubmatch(strings.TrimSpace(first)); m != nil {
+		tmpl.Description = m[1]
+		tmpl.Body = strings.TrimSpace(rest)
+	} else {
+		tmpl.Description = strings.TrimSpace(first)
+	}
+	return tmpl, nil
+}
+
+// Vars returns the names of the template's variables in order of first use
+func (t Template) Vars() []string {
+	var vars []string
+	seen := make(map[string]bool)
+	for _, m := range templateVarRe.FindAllStringSubmatch(t.Body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// Render substitutes vars into the template. Every variable the template
+// uses must have a value; the error names the missing ones.
+func (t Template) Render(vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range t.Vars() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %q needs a value for: %s", t.Name, strings.Join(missing, ", "))
+	}
+	return templateVarRe.ReplaceAllStringFunc(t.Body, func(placeholder string) string {
+		return vars[templateVarRe.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
@@ -0,0 +1,74 @@
+package messages
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, repo, name, content string) {
+	t.Helper()
+	dir := filepath.Join(repo, RepoTemplatesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	repo := t.TempDir()
+	if templates, warnings := ListTemplates(repo); len(templates) != 0 || len(warnings) != 0 {
+		t.Errorf("ListTemplates() without a directory = %v, %v", templates, warnings)
+	}
+
+	writeTemplate(t, repo, "status-query.md", "Where are you on {{task}}?\n")
+	writeTemplate(t, repo, "request-changes.md", "# request-changes - Ask for changes on a PR\n\nPR #{{pr}} needs changes: {{reason}}\n")
+	writeTemplate(t, repo, "Bad Name.md", "x")
+	writeTemplate(t, repo, "empty.md", "  \n")
+	writeTemplate(t, repo, "notes.txt", "not a template")
+
+	templates, warnings := ListTemplates(repo)
+	if len(templates) != 2 || templates[0].Name != "request-changes" || templates[1].Name != "status-query" {
+		t.Fatalf("ListTemplates() = %+v, want request-changes and status-query", templates)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %v, want the bad name and the empty file", warnings)
+	}
+	if templates[0].Description != "Ask for changes on a PR" || strings.HasPrefix(templates[0].Body, "#") {
+		t.Errorf("titled template = %+v, want the title as description and out of the body", templates[0])
+	}
+	if templates[1].Description != "Where are you on {{task}}?" {
+		t.Errorf("untitled template description = %q, want its first line", templates[1].Description)
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	repo := t.TempDir()
+	writeTemplate(t, repo, "request-changes.md", "# request-changes - Ask for changes\n\nPR #{{pr}} needs changes: {{ reason }}. Ping me when #{{pr}} is ready.")
+
+	tmpl, err := LoadTemplate(repo, "request-changes")
+	if err != nil {
+		t.Fatalf("LoadTemplate() error: %v", err)
+	}
+	if got := strings.Join(tmpl.Vars(), ","); got != "pr,reason" {
+		t.Errorf("Vars() = %s, want pr,reason", got)
+	}
+
+	if _, err := tmpl.Render(map[string]string{"pr": "42"}); err == nil || !strings.Contains(err.Error(), "reason") {
+		t.Errorf("Render() with a missing variable error = %v, want it named", err)
+	}
+	got, err := tmpl.Render(map[string]string{"pr": "42", "reason": "tests fail"})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if got != "PR #42 needs changes: tests fail. Ping me when #42 is ready." {
+		t.Errorf("Render() = %q", got)
+	}
+
+	if _, err := LoadTemplate(repo, "missing"); err == nil {
+		t.Error("LoadTemplate() of a missing template should fail")
+	}
+}
@@ -0,0 +1,295 @@
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Transport stores and retrieves agent messages. Inboxes are addressed by
+// repository (or project key) and agent name. The file transport is the
+// default; integrators who need higher volume or several hosts can back
+// messages with a database table or a message broker by installing their
+// own transport with SetDefaultTransport.
+//
+// Implementations must be safe for concurrent use by multiple processes:
+// the daemon and CLI commands (run by agents) share the same inboxes.
+type Transport interface {
+	// Put creates or replaces a message in an agent's inbox
+	Put(repoName, agentName string, msg *Message) error
+	// Get returns a message from an agent's inbox
+	Get(repoName, agentName, messageID string) (*Message, error)
+	// List returns every message in an agent's inbox; a missing inbox is empty
+	List(repoName, agentName string) ([]*Message, error)
+	// Delete removes a message; deleting a missing message is not an error
+	Delete(repoName, agentName, messageID string) error
+	// Repos returns the repositories that have at least one inbox
+	Repos() ([]string, error)
+	// Inboxes returns the agents that have an inbox in a repository
+	Inboxes(repoName string) ([]string, error)
+	// DeleteInbox removes an agent's inbox and its messages
+	DeleteInbox(repoName, agentName string) error
+}
+
+// TransportFactory creates a transport. messagesRoot is the messages directory
+// under the multiclaude root, which non-file transports may ignore.
+type TransportFactory func(messagesRoot string) Transport
+
+var (
+	defaultTransportMu sync.RWMutex
+	defaultTransport   TransportFactory = func(messagesRoot string) Transport {
+		return NewFileTransport(messagesRoot)
+	}
+)
+
+// SetDefaultTransport replaces the transport used by NewManager. Call it
+// during program initialization, before any manager is created; every
+// process sharing the inboxes (daemon and CLI) must install the same one.
+func SetDefaultTransport(factory TransportFactory) {
+	defaultTransportMu.Lock()
+	defer defaultTransportMu.Unlock()
+	defaultTransport = factory
+}
+
+func newDefaultTransport(messagesRoot string) Transport {
+	defaultTransportMu.RLock()
+	defer defaultTransportMu.RUnlock()
+	return defaultTransport(messagesRoot)
+}
+
+// FileTransport keeps each message in its own JSON file under
+// <root>/<repo>/<agent>/<message-id>.json
+type FileTransport struct {
+	root string
+}
+
+// NewFileTransport creates a file transport rooted at messagesRoot
+func NewFileTransport(messagesRoot string) *FileTransport {
+	return &FileTransport{root: messagesRoot}
+}
+
+// Put writes a message to disk
+func (t *FileTransport) Put(repoName, agentName string, msg *Message) error {
+	if err := os.MkdirAll(t.agentDir(repoName, agentName), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	path := filepath.Join(t.agentDir(repoName, agentName), msg.ID+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	return nil
+}
+
+// Get reads a message from disk
+func (t *FileTransport) Get(repoName, agentName, messageID string) (*Message, error) {
+	return t.read(repoName, agentName, messageID+".json")
+}
+
+// List reads every message file in an agent's directory, skipping invalid ones
+func (t *FileTransport) List(repoName, agentName string) ([]*Message, error) {
+	entries, err := os.ReadDir(t.agentDir(repoName, agentName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Message{}, nil
+		}
+		return nil, fmt.Errorf("failed to read messages directory: %w", err)
+	}
+
+	var messages []*Message
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		msg, err := t.read(repoName, agentName, entry.Name())
+		if err != nil {
+			// Skip invalid messages
+			continue
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// Delete removes a message file
+func (t *FileTransport) Delete(repoName, agentName, messageID string) error {
+	path := filepath.Join(t.agentDir(repoName, agentName), messageID+".json")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	return nil
+}
+
+// Repos lists the repository directories under the messages root
+func (t *FileTransport) Repos() ([]string, error) {
+	repos, err := listSubdirs(t.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages root: %w", err)
+	}
+	return repos, nil
+}
+
+// Inboxes lists the agent directories in a repository's directory
+func (t *FileTransport) Inboxes(repoName string) ([]string, error) {
+	agents, err := listSubdirs(filepath.Join(t.root, repoName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repo messages dir: %w", err)
+	}
+	return agents, nil
+}
+
+// DeleteInbox removes an agent's message directory
+func (t *FileTransport) DeleteInbox(repoName, agentName string) error {
+	return os.RemoveAll(t.agentDir(repoName, agentName))
+}
+
+// agentDir returns the directory path for an agent's messages
+func (t *FileTransport) agentDir(repoName, agentName string) string {
+	return filepath.Join(t.root, repoName, agentName)
+}
+
+// read reads a message from disk
+func (t *FileTransport) read(repoName, agentName, filename string) (*Message, error) {
+	path := filepath.Join(t.agentDir(repoName, agentName), filename)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message file: %w", err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+// listSubdirs returns the names of a directory's subdirectories; a missing
+// directory has none
+func listSubdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// MemoryTransport keeps messages in memory. It only works within one process,
+// so it suits tests and serves as a reference for writing other transports.
+type MemoryTransport struct {
+	mu    sync.Mutex
+	repos map[string]map[string]map[string]Message // repo -> agent -> id -> message
+}
+
+// NewMemoryTransport creates an empty in-memory transport
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{repos: make(map[string]map[string]map[string]Message)}
+}
+
+// Put stores a copy of the message
+func (t *MemoryTransport) Put(repoName, agentName string, msg *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	agents, ok := t.repos[repoName]
+	if !ok {
+		agents = make(map[string]map[string]Message)
+		t.repos[repoName] = agents
+	}
+	inbox, ok := agents[agentName]
+	if !ok {
+		inbox = make(map[string]Message)
+		agents[agentName] = inbox
+	}
+	inbox[msg.ID] = *msg
+	return nil
+}
+
+// Get returns a copy of a message
+func (t *MemoryTransport) Get(repoName, agentName, messageID string) (*Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	msg, ok := t.repos[repoName][agentName][messageID]
+	if !ok {
+		return nil, fmt.Errorf("message %s not found", messageID)
+	}
+	return &msg, nil
+}
+
+// List returns copies of an inbox's messages, oldest first
+func (t *MemoryTransport) List(repoName, agentName string) ([]*Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	messages := []*Message{}
+	for _, msg := range t.repos[repoName][agentName] {
+		msg := msg
+		messages = append(messages, &msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Timestamp.Before(messages[j].Timestamp) })
+	return messages, nil
+}
+
+// Delete removes a message
+func (t *MemoryTransport) Delete(repoName, agentName, messageID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.repos[repoName][agentName], messageID)
+	return nil
+}
+
+// Repos returns the repositories with inboxes, sorted
+func (t *MemoryTransport) Repos() ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var repos []string
+	for repoName, agents := range t.repos {
+		if len(agents) > 0 {
+			repos = append(repos, repoName)
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// Inboxes returns a repository's inboxes, sorted
+func (t *MemoryTransport) Inboxes(repoName string) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var agents []string
+	for agentName := range t.repos[repoName] {
+		agents = append(agents, agentName)
+	}
+	sort.Strings(agents)
+	return agents, nil
+}
+
+// DeleteInbox removes an inbox
+func (t *MemoryTransport) DeleteInbox(repoName, agentName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.repos[repoName], agentName)
+	if len(t.repos[repoName]) == 0 {
+		delete(t.repos, repoName)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package messages
+
+import (
+	"testing"
+)
+
+// transports returns a fresh instance of each built-in transport
+func transports(t *testing.T) map[string]Transport {
+	return map[string]Transport{
+		"file":   NewFileTransport(t.TempDir()),
+		"memory": NewMemoryTransport(),
+	}
+}
+
+func TestTransports(t *testing.T) {
+	for name, transport := range transports(t) {
+		t.Run(name, func(t *testing.T) {
+			m := NewManagerWithTransport(transport)
+
+			msg, err := m.Send("repo", "supervisor", "worker1", "hello")
+			if err != nil {
+				t.Fatalf("Send() failed: %v", err)
+			}
+			if _, err := m.Send("repo", "supervisor", "worker2", "hi"); err != nil {
+				t.Fatalf("Send() failed: %v", err)
+			}
+
+			got, err := m.Get("repo", "worker1", msg.ID)
+			if err != nil || got.Body != "hello" {
+				t.Fatalf("Get() = %+v, %v; want the sent message", got, err)
+			}
+			if _, err := m.Get("repo", "worker1", "msg-missing"); err == nil {
+				t.Error("Get() of a missing message should fail")
+			}
+
+			// Mutating a returned message must not change the stored one
+			got.Body = "changed"
+			if again, _ := m.Get("repo", "worker1", msg.ID); again.Body != "hello" {
+				t.Errorf("stored message changed through returned copy: %q", again.Body)
+			}
+
+			if err := m.Ack("repo", "worker1", msg.ID); err != nil {
+				t.Fatalf("Ack() failed: %v", err)
+			}
+			unread, _ := m.ListUnread("repo", "worker1")
+			if len(unread) != 0 {
+				t.Errorf("ListUnread() after ack = %d messages, want 0", len(unread))
+			}
+
+			if msgs, err := m.List("repo", "nobody"); err != nil || len(msgs) != 0 {
+				t.Errorf("List() of missing inbox = %v, %v; want empty", msgs, err)
+			}
+
+			repos, _ := m.Repos()
+			if len(repos) != 1 || repos[0] != "repo" {
+				t.Errorf("Repos() = %v, want [repo]", repos)
+			}
+			inboxes, _ := m.Inboxes("repo")
+			if len(inboxes) != 2 {
+				t.Errorf("Inboxes() = %v, want worker1 and worker2", inboxes)
+			}
+
+			count, err := m.CleanupOrphaned("repo", []string{"worker2"})
+			if err != nil || count != 1 {
+				t.Errorf("CleanupOrphaned() = %d, %v; want 1", count, err)
+			}
+			all, _ := m.ListAll()
+			if len(all) != 1 || len(all["repo/worker2"]) != 1 {
+				t.Errorf("ListAll() = %v, want only worker2's message", all)
+			}
+
+			if err := m.Delete("repo", "worker2", "msg-missing"); err != nil {
+				t.Errorf("Delete() of a missing message = %v, want nil", err)
+			}
+
+			count, err = m.DeleteRepo("repo")
+			if err != nil || count != 1 {
+				t.Errorf("DeleteRepo() = %d, %v; want 1", count, err)
+			}
+			if inboxes, _ := m.Inboxes("repo"); len(inboxes) != 0 {
+				t.Errorf("Inboxes() after DeleteRepo = %v, want none", inboxes)
+			}
+		})
+	}
+}
+
+func TestSetDefaultTransport(t *testing.T) {
+	memory := NewMemoryTransport()
+	SetDefaultTransport(func(string) Transport { return memory })
+	defer SetDefaultTransport(func(root string) Transport { return NewFileTransport(root) })
+
+	dir := t.TempDir()
+	if _, err := NewManager(dir).Send("repo", "a", "b", "via default"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if msgs, _ := memory.List("repo", "b"); len(msgs) != 1 {
+		t.Errorf("default transport received %d messages, want 1", len(msgs))
+	}
+	if msgs, _ := NewManagerWithTransport(NewFileTransport(dir)).List("repo", "b"); len(msgs) != 0 {
+		t.Errorf("file transport received %d messages, want 0", len(msgs))
+	}
+}
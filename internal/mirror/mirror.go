@@ -0,0 +1,89 @@
+// Package mirror keeps a bare mirror clone of a repository for read-only
+// agents. Reviewers and observers check out detached worktrees of the mirror
+// instead of the main clone, so their git commands don't wait on, or hold,
+// the index and ref locks workers and the daemon's worktree refresh take in
+// the main clone. The mirror has its own object store and is fetched on its
+// own schedule.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+)
+
+// Exists reports whether dir holds a mirror clone
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "HEAD"))
+	return err == nil
+}
+
+// Sync brings the mirror in dir up to date with url, cloning it first if it
+// doesn't exist. The clone goes to a temporary directory first, so an
+// interrupted clone is never mistaken for a mirror. Its git commands are
+// killed when ctx is done.
+func Sync(ctx context.Context, dir, url string) error {
+	if !Exists(dir) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return fmt.Errorf("failed to create mirrors directory: %w", err)
+		}
+		tmp := dir + ".tmp"
+		os.RemoveAll(tmp)
+		if _, err := gitcmd.Run(ctx, "", "clone", "--mirror", "--quiet", url, tmp); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("failed to clone mirror: %w", err)
+		}
+		if err := os.Rename(tmp, dir); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("failed to move mirror into place: %w", err)
+		}
+		return nil
+	}
+	if _, err := gitcmd.Run(ctx, dir, "remote", "set-url", "origin", url); err != nil {
+		return fmt.Errorf("failed to set mirror URL: %w", err)
+	}
+	if _, err := gitcmd.Run(ctx, dir, "fetch", "--prune", "--quiet", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch mirror: %w", err)
+	}
+	return nil
+}
+
+// FetchRef fetches a single ref into the mirror, such as the head of a PR
+// opened since the last Sync
+func FetchRef(dir, ref string) error {
+	if _, err := gitcmd.Run(context.Background(), dir, "fetch", "--quiet", "origin", fmt.Sprintf("+%s:%s", ref, ref)); err != nil {
+		return fmt.Errorf("failed to fetch %s into mirror: %w", ref, err)
+	}
+	return nil
+}
+
+// AddWorktree checks ref out in a new detached worktree at path. Worktrees
+// stay detached: a local branch in the mirror would be pruned by the next
+// Sync.
+func AddWorktree(dir, path, ref string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create mirror worktree directory: %w", err)
+	}
+	if _, err := gitcmd.Run(context.Background(), dir, "worktree", "add", "--detach", "--quiet", path, ref); err != nil {
+		return fmt.Errorf("failed to create mirror worktree: %w", err)
+	}
+	return nil
+}
+
+// Advance moves a mirror worktree to ref. Read-only agents have no changes
+// of their own; anything that blocks the checkout is reported, not discarded.
+func Advance(ctx context.Context, path, ref string) error {
+	_, err := gitcmd.Run(ctx, path, "checkout", "--quiet", "--detach", ref)
+	return err
+}
+
+// RemoveWorktree removes a mirror worktree
+func RemoveWorktree(dir, path string) error {
+	if _, err := gitcmd.Run(context.Background(), dir, "worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("failed to remove mirror worktree: %w", err)
+	}
+	return nil
+}
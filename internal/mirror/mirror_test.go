@@ -0,0 +1,100 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run runs git in dir, failing the test on error
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes a file in work, commits it and pushes the branch
+func commit(t *testing.T, work, file, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(work, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, work, "add", file)
+	run(t, work, "commit", "-q", "-m", file)
+	run(t, work, "push", "-q", "origin", "HEAD")
+	return run(t, work, "rev-parse", "HEAD")
+}
+
+func TestMirror(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	work := filepath.Join(root, "work")
+	run(t, root, "init", "-q", "--bare", "-b", "main", origin)
+	run(t, root, "clone", "-q", origin, work)
+	run(t, work, "checkout", "-q", "-b", "main")
+	commit(t, work, "README", "one\n")
+
+	dir := filepath.Join(root, "mirrors", "repo.git")
+	if Exists(dir) {
+		t.Fatal("Exists() before the first Sync")
+	}
+	if err := Sync(context.Background(), dir, origin); err != nil {
+		t.Fatalf("Sync() clone error: %v", err)
+	}
+	if !Exists(dir) {
+		t.Fatal("Exists() = false after Sync")
+	}
+	if _, err := os.Stat(dir + ".tmp"); !os.IsNotExist(err) {
+		t.Error("the temporary clone was left behind")
+	}
+
+	wt := filepath.Join(root, "mirrors", "repo", "observer")
+	if err := AddWorktree(dir, wt, "main"); err != nil {
+		t.Fatalf("AddWorktree() error: %v", err)
+	}
+
+	// A new commit reaches the worktree after a Sync and an Advance
+	head := commit(t, work, "README", "two\n")
+	if err := Sync(context.Background(), dir, origin); err != nil {
+		t.Fatalf("Sync() fetch error: %v", err)
+	}
+	if err := Advance(context.Background(), wt, "main"); err != nil {
+		t.Fatalf("Advance() error: %v", err)
+	}
+	if got := run(t, wt, "rev-parse", "HEAD"); got != head {
+		t.Errorf("worktree HEAD = %s, want %s", got, head)
+	}
+	if data, _ := os.ReadFile(filepath.Join(wt, "README")); string(data) != "two\n" {
+		t.Errorf("README = %q, want the new content", data)
+	}
+
+	// A single ref can be fetched without a full Sync
+	run(t, work, "checkout", "-q", "-b", "feature")
+	feature := commit(t, work, "feature", "x\n")
+	run(t, work, "push", "-q", "origin", "HEAD:refs/pull/7/head")
+	if err := FetchRef(dir, "refs/pull/7/head"); err != nil {
+		t.Fatalf("FetchRef() error: %v", err)
+	}
+	if got := run(t, dir, "rev-parse", "refs/pull/7/head"); got != feature {
+		t.Errorf("refs/pull/7/head = %s, want %s", got, feature)
+	}
+
+	if err := RemoveWorktree(dir, wt); err != nil {
+		t.Fatalf("RemoveWorktree() error: %v", err)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Error("the worktree is still there")
+	}
+}
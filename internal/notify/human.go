@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/shell"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// EventNeedsHuman is an agent waiting at a permission prompt or question
+const EventNeedsHuman EventType = "needs-human"
+
+// webhookTimeout bounds how long a webhook POST may take
+const webhookTimeout = 10 * time.Second
+
+// Alert describes an agent waiting on a human
+type Alert struct {
+	Repo  string `json:"repo"`
+	Agent string `json:"agent"`
+	// Kind is what the agent is waiting at: "permission_prompt" or "question"
+	Kind string `json:"kind"`
+	// Session and Window locate the agent in tmux
+	Session string    `json:"session"`
+	Window  string    `json:"window"`
+	Time    time.Time `json:"time"`
+}
+
+// Title returns a one-line summary of the alert
+func (a Alert) Title() string {
+	return fmt.Sprintf("%s/%s needs you", a.Repo, a.Agent)
+}
+
+// Body explains what the agent is waiting on and how to get to it
+func (a Alert) Body() string {
+	what := "an answer to its question"
+	if a.Kind == "permission_prompt" {
+		what = "permission to continue"
+	}
+	return fmt.Sprintf("%s is waiting for %s. Attach with: tmux attach -t %s:%s", a.Agent, what, a.Session, a.Window)
+}
+
+// HumanNotifier announces an agent waiting on a human
+type HumanNotifier interface {
+	Notify(a Alert) error
+}
+
+// runFunc runs a command and returns its combined output
+type runFunc func(name string, args ...string) ([]byte, error)
+
+func runCommand(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// HumanNotifiers returns the notifiers a repository's config asks for
+func HumanNotifiers(config state.NotifyConfig) ([]HumanNotifier, error) {
+	notifiers := make([]HumanNotifier, 0, len(config.NeedsHuman))
+	for _, name := range config.NeedsHuman {
+		switch name {
+		case state.HumanNotifierTmux:
+			notifiers = append(notifiers, &TmuxNotifier{run: runCommand})
+		case state.HumanNotifierDesktop:
+			notifiers = append(notifiers, &DesktopNotifier{run: runCommand, lookPath: exec.LookPath})
+		case state.HumanNotifierWebhook:
+			if config.Webhook == "" {
+				return nil, fmt.Errorf("the webhook notifier requires a webhook URL")
+			}
+			notifiers = append(notifiers, &WebhookNotifier{URL: config.Webhook})
+		default:
+			return nil, fmt.Errorf("unknown human notifier %q", name)
+		}
+	}
+	return notifiers, nil
+}
+
+// TmuxNotifier shows a popup in every attached tmux client, falling back to a
+// status line message where popups aren't supported (tmux before 3.2)
+type TmuxNotifier struct {
+	run runFunc
+}
+
+// Notify implements HumanNotifier
+func (n *TmuxNotifier) Notify(a Alert) error {
+	out, err := n.run("tmux", "list-clients", "-F", "#{client_name}")
+	if err != nil {
+		return fmt.Errorf("failed to list tmux clients: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	text := a.Title() + "\n\n" + a.Body()
+	var errs []string
+	for _, client := range strings.Fields(string(out)) {
+		// Without -E the popup stays up until it is dismissed with Escape or q
+		popup := fmt.Sprintf("printf '%%s\\n' %s", shell.Quote(text))
+		if _, err := n.run("tmux", "display-popup", "-c", client, "-T", " multiclaude ", "-w", "70%", "-h", "8", popup); err == nil {
+			continue
+		}
+		if out, err := n.run("tmux", "display-message", "-c", client, "-d", "10000", a.Title()+": "+a.Body()); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v: %s", client, err, strings.TrimSpace(string(out))))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to notify tmux clients: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// DesktopNotifier shows a desktop notification with terminal-notifier (macOS)
+// or notify-send (Linux)
+type DesktopNotifier struct {
+	run      runFunc
+	lookPath func(file string) (string, error)
+}
+
+// Notify implements HumanNotifier
+func (n *DesktopNotifier) Notify(a Alert) error {
+	var name string
+	var args []string
+	if _, err := n.lookPath("terminal-notifier"); err == nil {
+		name, args = "terminal-notifier", []string{"-title", "multiclaude", "-subtitle", a.Title(), "-message", a.Body(), "-group", "multiclaude-" + a.Repo + "-" + a.Agent}
+	} else if _, err := n.lookPath("notify-send"); err == nil {
+		name, args = "notify-send", []string{"--app-name=multiclaude", "--urgency=critical", a.Title(), a.Body()}
+	} else {
+		return fmt.Errorf("desktop notifications need terminal-notifier or notify-send on PATH")
+	}
+	if out, err := n.run(name, args...); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// WebhookNotifier POSTs the alert as JSON, with "title" and "text" fields
+// added for chat services that display them
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements HumanNotifier
+func (n *WebhookNotifier) Notify(a Alert) error {
+	payload := struct {
+		Alert
+		Event string `json:"event"`
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}{a, string(EventNeedsHuman), a.Title(), a.Title() + ": " + a.Body()}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
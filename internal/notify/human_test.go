@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+var testAlert = Alert{Repo: "my-repo", Agent: "calm-owl", Kind: "permission_prompt", Session: "mc-my-repo", Window: "calm-owl"}
+
+// recordingRun records commands, failing those whose name and first
+// argument are in fail
+type recordingRun struct {
+	calls [][]string
+	out   map[string]string
+	fail  map[string]bool
+}
+
+func (r *recordingRun) run(name string, args ...string) ([]byte, error) {
+	r.calls = append(r.calls, append([]string{name}, args...))
+	key := name
+	if len(args) > 0 {
+		key += " " + args[0]
+	}
+	if r.fail[key] {
+		return []byte("unsupported"), errors.New("exit status 1")
+	}
+	return []byte(r.out[key]), nil
+}
+
+func TestHumanNotifiers(t *testing.T) {
+	notifiers, err := HumanNotifiers(state.NotifyConfig{
+		NeedsHuman: []state.HumanNotifier{state.HumanNotifierTmux, state.HumanNotifierDesktop, state.HumanNotifierWebhook},
+		Webhook:    "https://hooks.example.com/x",
+	})
+	if err != nil || len(notifiers) != 3 {
+		t.Fatalf("HumanNotifiers() = %v, %v", notifiers, err)
+	}
+	if _, err := HumanNotifiers(state.NotifyConfig{NeedsHuman: []state.HumanNotifier{state.HumanNotifierWebhook}}); err == nil {
+		t.Error("HumanNotifiers() accepted a webhook notifier without a URL")
+	}
+	if notifiers, err := HumanNotifiers(state.NotifyConfig{}); err != nil || len(notifiers) != 0 {
+		t.Errorf("HumanNotifiers(none) = %v, %v", notifiers, err)
+	}
+}
+
+func TestTmuxNotifier(t *testing.T) {
+	r := &recordingRun{
+		out:  map[string]string{"tmux list-clients": "/dev/pts/1\n/dev/pts/2\n"},
+		fail: map[string]bool{},
+	}
+	n := &TmuxNotifier{run: r.run}
+	if err := n.Notify(testAlert); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if len(r.calls) != 3 || r.calls[1][1] != "display-popup" || r.calls[2][3] != "/dev/pts/2" {
+		t.Errorf("calls = %v, want a popup per client", r.calls)
+	}
+	if popup := r.calls[1][len(r.calls[1])-1]; !strings.Contains(popup, "tmux attach -t mc-my-repo:calm-owl") {
+		t.Errorf("popup command = %q", popup)
+	}
+
+	// Old tmux without popups gets a status line message
+	r = &recordingRun{
+		out:  map[string]string{"tmux list-clients": "/dev/pts/1\n"},
+		fail: map[string]bool{"tmux display-popup": true},
+	}
+	n = &TmuxNotifier{run: r.run}
+	if err := n.Notify(testAlert); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if len(r.calls) != 3 || r.calls[2][1] != "display-message" {
+		t.Errorf("calls = %v, want a display-message fallback", r.calls)
+	}
+}
+
+func TestDesktopNotifier(t *testing.T) {
+	for _, tt := range []struct {
+		available string
+		want      string
+	}{
+		{"terminal-notifier", "terminal-notifier"},
+		{"notify-send", "notify-send"},
+		{"", ""},
+	} {
+		r := &recordingRun{}
+		n := &DesktopNotifier{run: r.run, lookPath: func(file string) (string, error) {
+			if file == tt.available {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		}}
+		err := n.Notify(testAlert)
+		if tt.want == "" {
+			if err == nil {
+				t.Error("Notify() succeeded without a notification tool")
+			}
+			continue
+		}
+		if err != nil || len(r.calls) != 1 || r.calls[0][0] != tt.want {
+			t.Errorf("with %s: Notify() = %v, calls %v", tt.available, err, r.calls)
+		}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got map[string]interface{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL}
+	if err := n.Notify(testAlert); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if got["event"] != "needs-human" || got["agent"] != "calm-owl" || got["kind"] != "permission_prompt" {
+		t.Errorf("payload = %v", got)
+	}
+	if text, _ := got["text"].(string); !strings.Contains(text, "permission to continue") {
+		t.Errorf("text = %q", text)
+	}
+
+	status = http.StatusInternalServerError
+	if err := n.Notify(testAlert); err == nil {
+		t.Error("Notify() succeeded on a server error")
+	}
+}
@@ -0,0 +1,353 @@
+// Package notify delivers email notifications for unattended runs.
+//
+// Events (supervisor escalations, agent crashes) are queued per repository and
+// sent either immediately or batched into a digest, depending on the
+// repository's state.NotifyConfig.
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// EventType identifies what triggered a notification
+type EventType string
+
+const (
+	// EventEscalation is a message an agent addressed to the human
+	EventEscalation EventType = "escalation"
+	// EventAgentCrash is an agent whose process or window died unexpectedly
+	EventAgentCrash EventType = "agent-crash"
+)
+
+// HumanRecipient is the message recipient agents use to escalate to the human
+const HumanRecipient = "human"
+
+// SMTPPasswordEnv is the environment variable holding the SMTP password
+const SMTPPasswordEnv = "MULTICLAUDE_SMTP_PASSWORD"
+
+// dialTimeout bounds how long an SMTP connection attempt may take
+const dialTimeout = 30 * time.Second
+
+// maxPending caps each repository's queue of unsent events, so a mail server
+// that stays down doesn't grow it without bound. The oldest events are
+// dropped first.
+const maxPending = 100
+
+// Event is a single notifiable occurrence
+type Event struct {
+	Type  EventType
+	Repo  string
+	Agent string
+	// Session is the repository's tmux session, for the email's attach hint
+	Session string
+	Message string
+	Time    time.Time
+}
+
+// Sender delivers a single email
+type Sender interface {
+	Send(from string, to []string, subject, body string) error
+}
+
+// SMTPSender sends mail through an SMTP server
+type SMTPSender struct {
+	Addr     string
+	Username string
+	Password string
+}
+
+// Send implements Sender
+func (s *SMTPSender) Send(from string, to []string, subject, body string) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", s.Addr, err)
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Addr, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * dialTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", addr, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(buildMessage(from, to, subject, body)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// SendmailSender pipes mail to a sendmail-compatible binary
+type SendmailSender struct {
+	Path string
+}
+
+// Send implements Sender
+func (s *SendmailSender) Send(from string, to []string, subject, body string) error {
+	path := s.Path
+	if path == "" {
+		path = "sendmail"
+	}
+
+	args := []string{"-i"}
+	if from != "" {
+		args = append(args, "-f", from)
+	}
+	args = append(args, "--")
+	args = append(args, to...)
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(buildMessage(from, to, subject, body))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// NewSender returns the Sender for a notification config
+func NewSender(config state.NotifyConfig) (Sender, error) {
+	switch config.Method {
+	case state.NotifyMethodSMTP:
+		if config.SMTPAddr == "" {
+			return nil, fmt.Errorf("smtp method requires an SMTP address")
+		}
+		return &SMTPSender{
+			Addr:     config.SMTPAddr,
+			Username: config.SMTPUsername,
+			Password: os.Getenv(SMTPPasswordEnv),
+		}, nil
+	case state.NotifyMethodSendmail, "":
+		return &SendmailSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notify method %q", config.Method)
+	}
+}
+
+// buildMessage renders a plain-text RFC 5322 message
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var sb strings.Builder
+	if from != "" {
+		sb.WriteString("From: " + from + "\r\n")
+	}
+	sb.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	sb.WriteString("Subject: " + subject + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
+
+// Dispatcher queues events per repository and sends them immediately or as
+// digests. Emails are sent in the background, so a slow or unreachable mail
+// server doesn't hold up the caller.
+type Dispatcher struct {
+	// OnError receives the failures of background sends; nil drops them
+	OnError func(error)
+
+	mu        sync.Mutex
+	pending   map[string][]Event
+	lastSent  map[string]time.Time
+	newSender func(state.NotifyConfig) (Sender, error)
+	sends     sync.WaitGroup
+}
+
+// NewDispatcher creates a Dispatcher using the configured delivery methods
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		pending:   make(map[string][]Event),
+		lastSent:  make(map[string]time.Time),
+		newSender: NewSender,
+	}
+}
+
+// NewDispatcherWithSender creates a Dispatcher that delivers every email through sender.
+// This is primarily useful for testing.
+func NewDispatcherWithSender(sender Sender) *Dispatcher {
+	d := NewDispatcher()
+	d.newSender = func(state.NotifyConfig) (Sender, error) { return sender, nil }
+	return d
+}
+
+// Notify queues an event for a repository. Events are dropped when notifications
+// are disabled. Without a digest interval the event is sent right away.
+func (d *Dispatcher) Notify(config state.NotifyConfig, event Event) {
+	if !config.Enabled || len(config.To) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	d.mu.Lock()
+	dropped := d.queue(event.Repo, append(d.pending[event.Repo], event))
+	d.mu.Unlock()
+	if dropped > 0 && d.OnError != nil {
+		d.OnError(fmt.Errorf("notification queue for %s is full: dropped %d oldest events", event.Repo, dropped))
+	}
+
+	if config.DigestMinutes <= 0 {
+		d.send(event.Repo, config)
+	}
+}
+
+// FlushDue sends digests for every repository whose digest interval has elapsed
+func (d *Dispatcher) FlushDue(configs map[string]state.NotifyConfig, now time.Time) {
+	d.mu.Lock()
+	var due []string
+	for repo, events := range d.pending {
+		if len(events) == 0 {
+			continue
+		}
+		interval := time.Duration(configs[repo].DigestMinutes) * time.Minute
+		if now.Sub(d.lastSent[repo]) >= interval {
+			due = append(due, repo)
+		}
+	}
+	d.mu.Unlock()
+
+	sort.Strings(due)
+	for _, repo := range due {
+		d.send(repo, configs[repo])
+	}
+}
+
+// Wait waits for the emails being sent to be sent, or to fail
+func (d *Dispatcher) Wait() {
+	d.sends.Wait()
+}
+
+// send flushes a repository's pending events in the background
+func (d *Dispatcher) send(repo string, config state.NotifyConfig) {
+	d.sends.Add(1)
+	go func() {
+		defer d.sends.Done()
+		if err := d.flush(repo, config); err != nil && d.OnError != nil {
+			d.OnError(err)
+		}
+	}()
+}
+
+// Pending returns the number of queued events for a repository
+func (d *Dispatcher) Pending(repo string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending[repo])
+}
+
+// flush sends all pending events for a repository as a single email
+func (d *Dispatcher) flush(repo string, config state.NotifyConfig) error {
+	d.mu.Lock()
+	events := d.pending[repo]
+	delete(d.pending, repo)
+	d.mu.Unlock()
+
+	if len(events) == 0 || !config.Enabled || len(config.To) == 0 {
+		return nil
+	}
+
+	sender, err := d.newSender(config)
+	if err != nil {
+		return fmt.Errorf("failed to configure notifications for %s: %w", repo, err)
+	}
+
+	subject, body := FormatDigest(repo, events)
+	if err := sender.Send(config.From, config.To, subject, body); err != nil {
+		// Requeue so the next flush retries
+		d.mu.Lock()
+		dropped := d.queue(repo, append(events, d.pending[repo]...))
+		d.mu.Unlock()
+		if dropped > 0 {
+			return fmt.Errorf("failed to send notification for %s: %w (queue full: dropped %d oldest events)", repo, err, dropped)
+		}
+		return fmt.Errorf("failed to send notification for %s: %w", repo, err)
+	}
+
+	d.mu.Lock()
+	d.lastSent[repo] = time.Now()
+	d.mu.Unlock()
+	return nil
+}
+
+// queue sets a repository's pending events, dropping the oldest beyond
+// maxPending, and returns how many were dropped. The caller holds d.mu.
+func (d *Dispatcher) queue(repo string, events []Event) int {
+	dropped := 0
+	if len(events) > maxPending {
+		dropped = len(events) - maxPending
+		events = events[dropped:]
+	}
+	d.pending[repo] = events
+	return dropped
+}
+
+// FormatDigest renders the subject and body for a batch of a repository's
+// events. The attach hint names the events' tmux session.
+func FormatDigest(repo string, events []Event) (subject, body string) {
+	if len(events) == 1 {
+		e := events[0]
+		subject = fmt.Sprintf("[multiclaude] %s: %s from %s", repo, e.Type, e.Agent)
+	} else {
+		subject = fmt.Sprintf("[multiclaude] %s: %d events", repo, len(events))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("multiclaude events for repository %s:\n\n", repo))
+	for _, e := range events {
+		sb.WriteString(fmt.Sprintf("[%s] %s %s\n", e.Time.Format("2006-01-02 15:04:05"), e.Type, e.Agent))
+		for _, line := range strings.Split(strings.TrimSpace(e.Message), "\n") {
+			sb.WriteString("    " + line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	if session := events[len(events)-1].Session; session != "" {
+		sb.WriteString(fmt.Sprintf("Attach with: tmux attach -t %s\n", session))
+	}
+	return subject, sb.String()
+}
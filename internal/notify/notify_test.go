@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// recordingSender captures sent emails
+type recordingSender struct {
+	sent []sentMail
+	err  error
+}
+
+type sentMail struct {
+	from    string
+	to      []string
+	subject string
+	body    string
+}
+
+func (r *recordingSender) Send(from string, to []string, subject, body string) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, sentMail{from: from, to: to, subject: subject, body: body})
+	return nil
+}
+
+func TestNotifyImmediate(t *testing.T) {
+	sender := &recordingSender{}
+	d := NewDispatcherWithSender(sender)
+
+	config := state.NotifyConfig{Enabled: true, To: []string{"me@example.com"}, From: "mc@example.com"}
+	d.Notify(config, Event{Type: EventEscalation, Repo: "my-repo", Agent: "supervisor", Session: "mc-my_repo", Message: "Need a decision on the API"})
+	d.Wait()
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sender.sent))
+	}
+	mail := sender.sent[0]
+	if mail.from != "mc@example.com" || mail.to[0] != "me@example.com" {
+		t.Errorf("mail from/to = %s/%v", mail.from, mail.to)
+	}
+	if !strings.Contains(mail.subject, "escalation from supervisor") {
+		t.Errorf("subject = %q, want escalation from supervisor", mail.subject)
+	}
+	if !strings.Contains(mail.body, "Need a decision on the API") {
+		t.Errorf("body missing message: %q", mail.body)
+	}
+	if !strings.Contains(mail.body, "tmux attach -t mc-my_repo\n") {
+		t.Errorf("body = %q, want the repository's tmux session in the attach hint", mail.body)
+	}
+}
+
+func TestNotifyDisabled(t *testing.T) {
+	sender := &recordingSender{}
+	d := NewDispatcherWithSender(sender)
+
+	tests := []state.NotifyConfig{
+		{Enabled: false, To: []string{"me@example.com"}},
+		{Enabled: true},
+	}
+	for _, config := range tests {
+		d.Notify(config, Event{Type: EventAgentCrash, Repo: "my-repo", Agent: "worker"})
+	}
+	d.Wait()
+
+	if len(sender.sent) != 0 || d.Pending("my-repo") != 0 {
+		t.Errorf("disabled notifications should be dropped, sent=%d pending=%d", len(sender.sent), d.Pending("my-repo"))
+	}
+}
+
+func TestNotifyDigest(t *testing.T) {
+	sender := &recordingSender{}
+	d := NewDispatcherWithSender(sender)
+
+	config := state.NotifyConfig{Enabled: true, To: []string{"me@example.com"}, DigestMinutes: 30}
+	configs := map[string]state.NotifyConfig{"my-repo": config}
+
+	for i := 0; i < 3; i++ {
+		event := Event{Type: EventAgentCrash, Repo: "my-repo", Agent: fmt.Sprintf("worker-%d", i), Message: "process exited"}
+		d.Notify(config, event)
+	}
+	d.Wait()
+	if len(sender.sent) != 0 {
+		t.Fatalf("digest mode sent %d emails before flush", len(sender.sent))
+	}
+
+	// First digest is due immediately (nothing sent yet)
+	d.FlushDue(configs, time.Now())
+	d.Wait()
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].subject, "3 events") {
+		t.Fatalf("want one digest with 3 events, got %+v", sender.sent)
+	}
+
+	// A new event is held until the interval elapses
+	d.Notify(config, Event{Type: EventAgentCrash, Repo: "my-repo", Agent: "worker-4"})
+	d.FlushDue(configs, time.Now())
+	d.Wait()
+	if len(sender.sent) != 1 {
+		t.Fatalf("digest sent before interval elapsed")
+	}
+	d.FlushDue(configs, time.Now().Add(31*time.Minute))
+	d.Wait()
+	if len(sender.sent) != 2 {
+		t.Fatalf("digest not sent after interval elapsed")
+	}
+}
+
+func TestNotifyRequeuesOnFailure(t *testing.T) {
+	sender := &recordingSender{err: fmt.Errorf("connection refused")}
+	d := NewDispatcherWithSender(sender)
+	var errs []error
+	d.OnError = func(err error) { errs = append(errs, err) }
+
+	config := state.NotifyConfig{Enabled: true, To: []string{"me@example.com"}}
+	d.Notify(config, Event{Type: EventAgentCrash, Repo: "my-repo", Agent: "supervisor"})
+	d.Wait()
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want the send failure reported", errs)
+	}
+	if d.Pending("my-repo") != 1 {
+		t.Errorf("failed event should be requeued, pending = %d", d.Pending("my-repo"))
+	}
+
+	sender.err = nil
+	d.FlushDue(map[string]state.NotifyConfig{"my-repo": config}, time.Now())
+	d.Wait()
+	if len(errs) != 1 {
+		t.Fatalf("FlushDue() errors: %v", errs[1:])
+	}
+	if len(sender.sent) != 1 || d.Pending("my-repo") != 0 {
+		t.Errorf("retry should send the queued event, sent=%d pending=%d", len(sender.sent), d.Pending("my-repo"))
+	}
+}
+
+func TestNotifyCapsPendingQueue(t *testing.T) {
+	sender := &recordingSender{err: fmt.Errorf("connection refused")}
+	d := NewDispatcherWithSender(sender)
+	var errs []error
+	d.OnError = func(err error) { errs = append(errs, err) }
+
+	config := state.NotifyConfig{Enabled: true, To: []string{"me@example.com"}, DigestMinutes: 30}
+	for i := 0; i < maxPending+5; i++ {
+		d.Notify(config, Event{Type: EventAgentCrash, Repo: "my-repo", Agent: fmt.Sprintf("worker-%d", i)})
+	}
+	if d.Pending("my-repo") != maxPending {
+		t.Fatalf("pending = %d, want the queue capped at %d", d.Pending("my-repo"), maxPending)
+	}
+	if len(errs) != 5 || !strings.Contains(errs[0].Error(), "dropped 1 oldest events") {
+		t.Fatalf("errors = %v, want each drop reported", errs)
+	}
+
+	// A failed send requeues without growing past the cap
+	errs = nil
+	configs := map[string]state.NotifyConfig{"my-repo": config}
+	d.FlushDue(configs, time.Now())
+	d.Wait()
+	if len(errs) != 1 || d.Pending("my-repo") != maxPending {
+		t.Fatalf("after failed send: errors = %v, pending = %d", errs, d.Pending("my-repo"))
+	}
+
+	sender.err = nil
+	d.FlushDue(configs, time.Now())
+	d.Wait()
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sender.sent))
+	}
+	body := sender.sent[0].body
+	if strings.Contains(body, "worker-4\n") || !strings.Contains(body, "worker-5\n") || !strings.Contains(body, fmt.Sprintf("worker-%d\n", maxPending+4)) {
+		t.Errorf("digest should hold the newest %d events, got %q", maxPending, body)
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	if _, err := NewSender(state.NotifyConfig{Method: state.NotifyMethodSMTP}); err == nil {
+		t.Error("NewSender() smtp without address should fail")
+	}
+
+	sender, err := NewSender(state.NotifyConfig{Method: state.NotifyMethodSMTP, SMTPAddr: "localhost:25"})
+	if err != nil {
+		t.Fatalf("NewSender() failed: %v", err)
+	}
+	if _, ok := sender.(*SMTPSender); !ok {
+		t.Errorf("NewSender(smtp) = %T, want *SMTPSender", sender)
+	}
+
+	sender, err = NewSender(state.NotifyConfig{})
+	if err != nil {
+		t.Fatalf("NewSender() failed: %v", err)
+	}
+	if _, ok := sender.(*SendmailSender); !ok {
+		t.Errorf("NewSender(default) = %T, want *SendmailSender", sender)
+	}
+}
+
+func TestSendmailSender(t *testing.T) {
+	tmpDir := t.TempDir()
+	outFile := filepath.Join(tmpDir, "mail.txt")
+	script := filepath.Join(tmpDir, "sendmail")
+	content := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\ncat >> %s\n", outFile, outFile)
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("failed to write fake sendmail: %v", err)
+	}
+
+	sender := &SendmailSender{Path: script}
+	if err := sender.Send("mc@example.com", []string{"me@example.com"}, "Test subject", "line one\nline two"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{"-f mc@example.com", "me@example.com", "Subject: Test subject", "line two"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("sendmail input missing %q:\n%s", want, out)
+		}
+	}
+}
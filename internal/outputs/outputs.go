@@ -0,0 +1,274 @@
+// Package outputs manages the agent logs in the output directory. A log is
+// live while its agent exists. When the agent is removed its log, and any
+// rotated copies, move to the repository's archive; archived and rotated logs
+// are deleted once they are older than the retention period.
+package outputs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// DefaultRetention is how long archived and rotated logs are kept
+const DefaultRetention = 7 * 24 * time.Hour
+
+// orphanGrace is how recently a log without an agent may have been written to
+// and still be left alone: agents start writing their log just before they
+// are added to state
+const orphanGrace = 5 * time.Minute
+
+// timestampFormat matches the suffix the daemon gives rotated logs
+const timestampFormat = "20060102-150405"
+
+// Kind is what stage of its lifecycle a log file is in
+type Kind string
+
+const (
+	// KindLive is the log an agent is writing to
+	KindLive Kind = "live"
+	// KindRotated is an older part of an agent's log, split off for size
+	KindRotated Kind = "rotated"
+	// KindArchived is a log of a removed agent
+	KindArchived Kind = "archived"
+)
+
+// File is a log file in the output directory
+type File struct {
+	Path    string
+	Repo    string
+	Agent   string
+	Kind    Kind
+	Size    int64
+	ModTime time.Time
+}
+
+// Scan lists the log files in the output directory, by repository and path
+func Scan(paths *config.Paths) ([]File, error) {
+	repos, err := os.ReadDir(paths.OutputDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []File
+	for _, repo := range repos {
+		if !repo.IsDir() {
+			continue
+		}
+		name := repo.Name()
+		for _, dir := range []struct {
+			path     string
+			archived bool
+		}{
+			{paths.RepoOutputDir(name), false},
+			{paths.WorkersOutputDir(name), false},
+			{paths.ArchivedOutputDir(name), true},
+		} {
+			found, err := scanDir(dir.path, name, dir.archived)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Repo != files[j].Repo {
+			return files[i].Repo < files[j].Repo
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// scanDir lists the log files directly in dir
+func scanDir(dir, repo string, archived bool) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []File
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		agent, rotated := parseName(entry.Name())
+		if agent == "" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		kind := KindLive
+		switch {
+		case archived:
+			kind = KindArchived
+		case rotated:
+			kind = KindRotated
+		}
+		files = append(files, File{
+			Path:    filepath.Join(dir, entry.Name()),
+			Repo:    repo,
+			Agent:   agent,
+			Kind:    kind,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// parseName returns the agent a log file belongs to and whether it is a
+// rotated part ("<agent>.log.<timestamp>"), or "" for other files
+func parseName(name string) (string, bool) {
+	if agent, ok := strings.CutSuffix(name, ".log"); ok && agent != "" {
+		return agent, false
+	}
+	if i := strings.LastIndex(name, ".log."); i > 0 {
+		return name[:i], true
+	}
+	return "", false
+}
+
+// Archive moves an agent's logs, live and rotated, to the repository's
+// archive and returns them with their new paths. The live log is given a
+// timestamp like a rotated one. Archived files are dated now, so the
+// retention period runs from the agent's removal.
+func Archive(paths *config.Paths, repoName, agentName string, now time.Time) ([]File, error) {
+	var logs []File
+	for _, dir := range []string{paths.RepoOutputDir(repoName), paths.WorkersOutputDir(repoName)} {
+		found, err := scanDir(dir, repoName, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			if f.Agent == agentName {
+				logs = append(logs, f)
+			}
+		}
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	archiveDir := paths.ArchivedOutputDir(repoName)
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	var archived []File
+	for _, f := range logs {
+		name := filepath.Base(f.Path)
+		if f.Kind == KindLive {
+			name += "." + now.Format(timestampFormat)
+		}
+		dest := filepath.Join(archiveDir, name)
+		if err := os.Rename(f.Path, dest); err != nil {
+			return archived, fmt.Errorf("failed to archive %s: %w", f.Path, err)
+		}
+		_ = os.Chtimes(dest, now, now)
+		f.Path, f.Kind, f.ModTime = dest, KindArchived, now
+		archived = append(archived, f)
+	}
+	return archived, nil
+}
+
+// Options controls Clean
+type Options struct {
+	// OlderThan is the age after which archived and rotated logs are deleted
+	// (default DefaultRetention)
+	OlderThan time.Duration
+	// Exists reports whether an agent is still tracked; the live logs of
+	// agents that aren't are archived
+	Exists func(repoName, agentName string) bool
+	// DryRun reports what would be done without doing it
+	DryRun bool
+	// Now is the time ages are measured from (default time.Now())
+	Now time.Time
+}
+
+// Result is what Clean did, or would do in a dry run
+type Result struct {
+	// Files is every log file before cleaning
+	Files    []File
+	Archived []File
+	Deleted  []File
+}
+
+// Freed returns the number of bytes deleted
+func (r *Result) Freed() int64 {
+	var n int64
+	for _, f := range r.Deleted {
+		n += f.Size
+	}
+	return n
+}
+
+// Clean archives the logs of agents that no longer exist and deletes
+// archived and rotated logs older than the retention period
+func Clean(paths *config.Paths, opts Options) (*Result, error) {
+	if opts.OlderThan <= 0 {
+		opts.OlderThan = DefaultRetention
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	cutoff := opts.Now.Add(-opts.OlderThan)
+
+	files, err := Scan(paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan output directory: %w", err)
+	}
+	result := &Result{Files: files}
+
+	orphans := make(map[[2]string]bool)
+	for _, f := range files {
+		switch {
+		case f.Kind == KindLive:
+			if opts.Exists != nil && !opts.Exists(f.Repo, f.Agent) && opts.Now.Sub(f.ModTime) > orphanGrace {
+				orphans[[2]string{f.Repo, f.Agent}] = true
+			}
+		case f.ModTime.Before(cutoff):
+			if !opts.DryRun {
+				if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+					return result, fmt.Errorf("failed to delete %s: %w", f.Path, err)
+				}
+			}
+			result.Deleted = append(result.Deleted, f)
+		}
+	}
+
+	for _, f := range files {
+		key := [2]string{f.Repo, f.Agent}
+		if !orphans[key] || f.Kind == KindArchived {
+			continue
+		}
+		if opts.DryRun {
+			// Rotated parts old enough to delete are counted as deleted
+			if f.Kind == KindLive || !f.ModTime.Before(cutoff) {
+				result.Archived = append(result.Archived, f)
+			}
+			continue
+		}
+		delete(orphans, key)
+		archived, err := Archive(paths, f.Repo, f.Agent, opts.Now)
+		if err != nil {
+			return result, err
+		}
+		result.Archived = append(result.Archived, archived...)
+	}
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package outputs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// writeLog creates a log file of size bytes last written at modTime
+func writeLog(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	now := time.Now()
+	writeLog(t, paths.AgentLogFile("app", "supervisor", false), 10, now)
+	writeLog(t, paths.AgentLogFile("app", "calm-owl", true), 20, now)
+	writeLog(t, paths.AgentLogFile("app", "calm-owl", true)+".20261001-120000", 30, now)
+	writeLog(t, filepath.Join(paths.ArchivedOutputDir("app"), "old-fox.log.20261002-120000"), 40, now)
+	writeLog(t, filepath.Join(paths.RepoOutputDir("app"), "notes.txt"), 50, now)
+
+	files, err := Scan(paths)
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	got := make(map[string]Kind)
+	for _, f := range files {
+		got[f.Agent+"/"+filepath.Base(f.Path)] = f.Kind
+	}
+	want := map[string]Kind{
+		"supervisor/supervisor.log":             KindLive,
+		"calm-owl/calm-owl.log":                 KindLive,
+		"calm-owl/calm-owl.log.20261001-120000": KindRotated,
+		"old-fox/old-fox.log.20261002-120000":   KindArchived,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Scan() = %v, want %v", got, want)
+	}
+	for name, kind := range want {
+		if got[name] != kind {
+			t.Errorf("%s: kind = %q, want %q", name, got[name], kind)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	old := time.Now().Add(-48 * time.Hour)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	live := paths.AgentLogFile("app", "calm-owl", true)
+	writeLog(t, live, 10, old)
+	writeLog(t, live+".20261001-120000", 10, old)
+	writeLog(t, paths.AgentLogFile("app", "quick-fox", true), 10, old)
+
+	archived, err := Archive(paths, "app", "calm-owl", now)
+	if err != nil {
+		t.Fatalf("Archive() failed: %v", err)
+	}
+	if len(archived) != 2 {
+		t.Fatalf("archived %d files, want 2", len(archived))
+	}
+	if _, err := os.Stat(live); !os.IsNotExist(err) {
+		t.Error("live log was not moved")
+	}
+	info, err := os.Stat(filepath.Join(paths.ArchivedOutputDir("app"), "calm-owl.log.20261016-120000"))
+	if err != nil {
+		t.Fatalf("live log not archived under its removal time: %v", err)
+	}
+	if !info.ModTime().Equal(now) {
+		t.Errorf("archived log dated %v, want %v", info.ModTime(), now)
+	}
+	if _, err := os.Stat(paths.AgentLogFile("app", "quick-fox", true)); err != nil {
+		t.Error("another agent's log was archived")
+	}
+
+	// Nothing to archive is not an error
+	if archived, err := Archive(paths, "app", "ghost", now); err != nil || len(archived) != 0 {
+		t.Errorf("Archive(ghost) = %v, %v", archived, err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	now := time.Now()
+	week := 8 * 24 * time.Hour
+
+	writeLog(t, paths.AgentLogFile("app", "supervisor", false), 10, now.Add(-week))
+	writeLog(t, paths.AgentLogFile("app", "supervisor", false)+".20261001-120000", 100, now.Add(-week))
+	writeLog(t, paths.AgentLogFile("app", "gone-owl", true), 20, now.Add(-time.Hour))
+	writeLog(t, paths.AgentLogFile("app", "new-fox", true), 20, now)
+	writeLog(t, filepath.Join(paths.ArchivedOutputDir("app"), "old-fox.log.20261001-120000"), 1000, now.Add(-week))
+	writeLog(t, filepath.Join(paths.ArchivedOutputDir("app"), "calm-owl.log.20261015-120000"), 1000, now.Add(-24*time.Hour))
+
+	exists := func(repo, agent string) bool { return agent == "supervisor" }
+
+	// A dry run reports without touching anything
+	result, err := Clean(paths, Options{Exists: exists, DryRun: true, Now: now})
+	if err != nil {
+		t.Fatalf("Clean(dry run) failed: %v", err)
+	}
+	if len(result.Deleted) != 2 || result.Freed() != 1100 || len(result.Archived) != 1 || result.Archived[0].Agent != "gone-owl" {
+		t.Errorf("dry run: deleted %v, archived %v", result.Deleted, result.Archived)
+	}
+	if files, _ := Scan(paths); len(files) != 6 {
+		t.Errorf("dry run changed the output directory: %d files left", len(files))
+	}
+
+	result, err = Clean(paths, Options{Exists: exists, Now: now})
+	if err != nil {
+		t.Fatalf("Clean() failed: %v", err)
+	}
+	if result.Freed() != 1100 || len(result.Archived) != 1 {
+		t.Errorf("deleted %v, archived %v", result.Deleted, result.Archived)
+	}
+
+	files, err := Scan(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := make(map[string]Kind)
+	for _, f := range files {
+		left[f.Agent] = f.Kind
+	}
+	// The supervisor is live, gone-owl is newly archived, new-fox is too new
+	// to count as orphaned and calm-owl's archive isn't old enough to delete
+	want := map[string]Kind{"supervisor": KindLive, "gone-owl": KindArchived, "new-fox": KindLive, "calm-owl": KindArchived}
+	if len(left) != len(want) {
+		t.Fatalf("left %v, want %v", left, want)
+	}
+	for agent, kind := range want {
+		if left[agent] != kind {
+			t.Errorf("%s: kind = %q, want %q", agent, left[agent], kind)
+		}
+	}
+}
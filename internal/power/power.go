@@ -0,0 +1,84 @@
+// Package power reports whether the machine is running on battery, so the
+// daemon can back off while a laptop is unplugged.
+package power
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsupported is returned on platforms where the power source can't be read
+var ErrUnsupported = errors.New("power source detection is not supported on this platform")
+
+// sysfsPowerSupply is where Linux exposes batteries and AC adapters
+const sysfsPowerSupply = "/sys/class/power_supply"
+
+// OnBattery reports whether the machine is currently running on battery power.
+// Machines without a battery report false.
+func OnBattery() (bool, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return onBatterySysfs(sysfsPowerSupply)
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		if err != nil {
+			return false, err
+		}
+		return parsePmset(string(out)), nil
+	default:
+		return false, ErrUnsupported
+	}
+}
+
+// onBatterySysfs reads a Linux power_supply directory. Any online AC or USB
+// supply means external power; otherwise a discharging battery means battery.
+func onBatterySysfs(root string) (bool, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	discharging := false
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		switch readAttr(dir, "type") {
+		case "Mains", "USB", "USB_C", "USB_PD":
+			if readAttr(dir, "online") == "1" {
+				return false, nil
+			}
+		case "Battery":
+			// Peripheral batteries (mice, headsets) don't power the machine
+			if readAttr(dir, "scope") == "Device" {
+				continue
+			}
+			if readAttr(dir, "status") == "Discharging" {
+				discharging = true
+			}
+		}
+	}
+	return discharging, nil
+}
+
+// readAttr reads a sysfs attribute, returning "" if it's missing
+func readAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// parsePmset parses `pmset -g batt`, whose first line names the power source:
+//
+//	Now drawing from 'Battery Power'
+func parsePmset(out string) bool {
+	first, _, _ := strings.Cut(out, "\n")
+	return strings.Contains(first, "'Battery Power'")
+}
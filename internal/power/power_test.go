@@ -0,0 +1,97 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSupply creates a fake /sys/class/power_supply entry
+func writeSupply(t *testing.T, root, name string, attrs map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for attr, value := range attrs {
+		if err := os.WriteFile(filepath.Join(dir, attr), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOnBatterySysfs(t *testing.T) {
+	tests := []struct {
+		name     string
+		supplies map[string]map[string]string
+		want     bool
+	}{
+		{
+			name: "no supplies (desktop)",
+			want: false,
+		},
+		{
+			name: "discharging battery, adapter offline",
+			supplies: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "0"},
+				"BAT0": {"type": "Battery", "status": "Discharging"},
+			},
+			want: true,
+		},
+		{
+			name: "adapter online",
+			supplies: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "1"},
+				"BAT0": {"type": "Battery", "status": "Charging"},
+			},
+			want: false,
+		},
+		{
+			name: "USB-C charger online while battery reports discharging",
+			supplies: map[string]map[string]string{
+				"ucsi-source-psy-1": {"type": "USB", "online": "1"},
+				"BAT0":              {"type": "Battery", "status": "Discharging"},
+			},
+			want: false,
+		},
+		{
+			name: "only a peripheral battery is discharging",
+			supplies: map[string]map[string]string{
+				"hid-mouse-battery": {"type": "Battery", "scope": "Device", "status": "Discharging"},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, attrs := range tt.supplies {
+				writeSupply(t, root, name, attrs)
+			}
+			got, err := onBatterySysfs(root)
+			if err != nil {
+				t.Fatalf("onBatterySysfs() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("onBatterySysfs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := onBatterySysfs(filepath.Join(t.TempDir(), "missing")); err != nil || got {
+		t.Errorf("onBatterySysfs(missing) = %v, %v; want false, nil", got, err)
+	}
+}
+
+func TestParsePmset(t *testing.T) {
+	battery := "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=1234)\t85%; discharging; 4:10 remaining present: true\n"
+	ac := "Now drawing from 'AC Power'\n -InternalBattery-0 (id=1234)\t100%; charged; 0:00 remaining present: true\n"
+
+	if !parsePmset(battery) {
+		t.Error("parsePmset(battery) = false, want true")
+	}
+	if parsePmset(ac) {
+		t.Error("parsePmset(ac) = true, want false")
+	}
+}
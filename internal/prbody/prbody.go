@@ -0,0 +1,157 @@
+// Package prbody writes the descriptions of workers' PRs. A description is
+// rendered from a text/template over what the daemon knows about the work: the
+// task, how the worker reported on its acceptance criteria, the commits on the
+// branch and what they changed, and Claude's summary of the diff. Repositories
+// can supply their own template; DefaultTemplate is used otherwise.
+package prbody
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// DefaultTemplate is the description used when the repository has no template
+// of its own
+const DefaultTemplate = `## Summary
+
+{{if .Summary}}{{.Summary}}{{else if .Report}}{{.Report}}{{else}}{{firstLine .Task}}{{end}}
+
+## Task
+
+{{.Task}}
+{{- if .Criteria}}
+
+## Acceptance criteria
+{{range .Criteria}}
+- [{{if eq .Status "met"}}x{{else}} {{end}}] {{.Text}}{{if eq .Status "unmet"}} (not met){{end}}{{if .Note}}: {{.Note}}{{end}}
+{{- end}}
+{{- end}}
+{{- if .Commits}}
+
+## Commits
+{{range .Commits}}
+- {{.Hash}} {{.Subject}}
+{{- end}}
+{{- end}}
+{{- if .DiffStat}}
+
+<details><summary>Files changed</summary>
+
+` + "```" + `
+{{.DiffStat}}
+` + "```" + `
+
+</details>
+{{- end}}
+
+---
+_Description written by multiclaude from {{.Agent}}'s task and commits._
+`
+
+// Commit is one commit on the PR's branch
+type Commit struct {
+	Hash    string
+	Subject string
+}
+
+// Data is what a template can use
+type Data struct {
+	Agent  string
+	Branch string
+	Base   string
+	Task   string
+	// Criteria are the task's acceptance criteria, with the worker's reports
+	Criteria []state.Criterion
+	Commits  []Commit
+	// DiffStat is git's per-file summary of the changes
+	DiffStat string
+	// Report is the worker's own summary, from `agent complete --summary`
+	Report string
+	// Summary is Claude's summary of the diff, or "" if there is none
+	Summary string
+}
+
+// Render renders a description from tmpl, or DefaultTemplate if tmpl is empty
+func Render(tmpl string, data Data) (string, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := template.New("pr").Funcs(template.FuncMap{"firstLine": firstLine}).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid PR description template: %w", err)
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render PR description: %w", err)
+	}
+	return strings.TrimSpace(b.String()) + "\n", nil
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// Changes is what a branch changed since it left its base
+type Changes struct {
+	// Head is the commit the changes go up to
+	Head     string
+	Commits  []Commit
+	DiffStat string
+	Diff     string
+}
+
+// Diff reads the commits and changes in dir's HEAD that aren't in base, such
+// as "origin/main", oldest commit first
+func Diff(ctx context.Context, dir, base string) (Changes, error) {
+	var c Changes
+	head, err := gitcmd.Run(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return c, err
+	}
+	c.Head = head
+	log, err := gitcmd.Run(ctx, dir, "log", "--reverse", "--format=%h%x09%s", base+"..HEAD")
+	if err != nil {
+		return c, err
+	}
+	for _, line := range strings.Split(log, "\n") {
+		if hash, subject, ok := strings.Cut(line, "\t"); ok {
+			c.Commits = append(c.Commits, Commit{Hash: hash, Subject: subject})
+		}
+	}
+	if c.DiffStat, err = gitcmd.Run(ctx, dir, "diff", "--stat", base+"...HEAD"); err != nil {
+		return c, err
+	}
+	if c.Diff, err = gitcmd.Run(ctx, dir, "diff", base+"...HEAD"); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// MaxDiffBytes caps how much of a diff is sent to Claude to summarize
+const MaxDiffBytes = 60000
+
+// SummaryPrompt asks Claude to summarize a diff for a PR description
+func SummaryPrompt(task, diff string) string {
+	truncated := ""
+	if len(diff) > MaxDiffBytes {
+		diff = diff[:MaxDiffBytes]
+		truncated = "\n[diff truncated]"
+	}
+	return fmt.Sprintf(`Summarize the following diff for the description of a pull request. Write 2-5 short Markdown bullet points saying what changed and why it matters to a reviewer. Output only the bullet points, with no heading or preamble.
+
+The task was: %s
+
+`+"```diff\n%s%s\n```\n", task, diff, truncated)
+}
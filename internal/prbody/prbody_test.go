@@ -0,0 +1,117 @@
+package prbody
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestRenderDefault(t *testing.T) {
+	body, err := Render("", Data{
+		Agent: "calm-owl",
+		Task:  "Fix the login bug\n\nUsers are logged out on refresh.",
+		Criteria: []state.Criterion{
+			{Text: "Tests pass", Status: state.CriterionMet},
+			{Text: "Docs updated", Status: state.CriterionUnmet, Note: "no docs for this yet"},
+		},
+		Commits:  []Commit{{Hash: "abc1234", Subject: "Keep the session cookie"}},
+		DiffStat: "auth.go | 3 ++-",
+	})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	for _, want := range []string{
+		"## Summary\n\nFix the login bug\n",
+		"Users are logged out on refresh.",
+		"- [x] Tests pass\n",
+		"- [ ] Docs updated (not met): no docs for this yet\n",
+		"- abc1234 Keep the session cookie\n",
+		"auth.go | 3 ++-",
+		"from calm-owl's task",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Render() = %q\nwant it to contain %q", body, want)
+		}
+	}
+
+	// Without Claude's summary the worker's report leads
+	body, _ = Render("", Data{Task: "Fix it", Report: "Fixed the cookie path"})
+	if !strings.HasPrefix(body, "## Summary\n\nFixed the cookie path\n") {
+		t.Errorf("Render() = %q, want the report first", body)
+	}
+
+	// Sections with nothing in them are left out, and Claude's summary leads
+	body, _ = Render("", Data{Agent: "calm-owl", Task: "Fix it", Summary: "- Fixed it"})
+	if strings.Contains(body, "Acceptance criteria") || strings.Contains(body, "Commits") || strings.Contains(body, "Files changed") {
+		t.Errorf("Render() = %q, want empty sections left out", body)
+	}
+	if !strings.HasPrefix(body, "## Summary\n\n- Fixed it\n") {
+		t.Errorf("Render() = %q, want the summary first", body)
+	}
+}
+
+func TestRenderCustom(t *testing.T) {
+	body, err := Render("{{.Agent}} on {{.Branch}}: {{firstLine .Task}}", Data{Agent: "calm-owl", Branch: "work/calm-owl", Task: "\nFix it\nproperly"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if body != "calm-owl on work/calm-owl: Fix it\n" {
+		t.Errorf("Render() = %q", body)
+	}
+
+	if _, err := Render("{{.Nope", Data{}); err == nil {
+		t.Error("Render() accepted a broken template")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	run("commit", "-q", "--allow-empty", "-m", "init")
+	run("branch", "base")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", name)
+		run("commit", "-q", "-m", "Add "+name)
+	}
+
+	changes, err := Diff(context.Background(), dir, "base")
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	if len(changes.Head) != 40 {
+		t.Errorf("Head = %q, want a commit hash", changes.Head)
+	}
+	if len(changes.Commits) != 2 || changes.Commits[0].Subject != "Add a.txt" || changes.Commits[1].Subject != "Add b.txt" {
+		t.Errorf("Commits = %+v, want both, oldest first", changes.Commits)
+	}
+	if !strings.Contains(changes.DiffStat, "2 files changed") || !strings.Contains(changes.Diff, "+b.txt") {
+		t.Errorf("DiffStat = %q, Diff = %q", changes.DiffStat, changes.Diff)
+	}
+
+	if _, err := Diff(context.Background(), dir, "missing"); err == nil {
+		t.Error("Diff() against a missing base succeeded")
+	}
+}
+
+func TestSummaryPromptTruncates(t *testing.T) {
+	prompt := SummaryPrompt("Fix it", strings.Repeat("x", MaxDiffBytes+10))
+	if !strings.Contains(prompt, "[diff truncated]") || strings.Contains(prompt, strings.Repeat("x", MaxDiffBytes+1)) {
+		t.Error("SummaryPrompt() didn't truncate a long diff")
+	}
+}
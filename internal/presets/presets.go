@@ -0,0 +1,128 @@
+// Package presets picks repository settings from what a GitHub repository
+// says about itself. Its topics choose a language preset: the merge train's
+// test command and a pre-spawn hook that fetches dependencies. Its labels
+// choose which PRs the merge queue tracks. init applies what matches, and
+// settings given explicitly win.
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/retry"
+)
+
+// Settings are what presets configure. Empty fields are left alone.
+type Settings struct {
+	TrackMode   state.TrackMode
+	TestCommand string
+	PreSpawn    string
+}
+
+// Match is a preset that applies to a repository, and why
+type Match struct {
+	Preset string // e.g. "go"
+	Reason string // e.g. `topic "golang"`
+}
+
+// language is a preset for the repositories tagged with one of its topics
+type language struct {
+	name     string
+	topics   []string
+	test     string
+	preSpawn string
+}
+
+// languages are tried in order; the first whose topic the repository has wins
+var languages = []language{
+	{"go", []string{"go", "golang"}, "go vet ./... && go test ./...", "go mod download"},
+	{"rust", []string{"rust", "rust-lang"}, "cargo test", "cargo fetch"},
+	{"node", []string{"nodejs", "node", "javascript", "typescript", "npm"}, "npm test", "npm ci"},
+	{"python", []string{"python", "python3"}, "python -m pytest", ""},
+}
+
+// labelScheme is a way of managing issues and PRs that some of a repository's
+// labels give away, and the merge-queue track mode that suits it
+type labelScheme struct {
+	name   string
+	labels []string
+	track  state.TrackMode
+}
+
+// labelSchemes are tried in order; the first with a label the repository has wins
+var labelSchemes = []labelScheme{
+	// Work is claimed by assigning it, so the merge queue takes the PRs
+	// assigned to it
+	{"assignment", []string{"assigned", "claimed", "in progress", "in-progress", "status: in progress"}, state.TrackModeAssigned},
+	// Outside contributions are triaged by people, so the merge queue takes
+	// only the PRs multiclaude opened
+	{"triage", []string{"triage", "needs-triage", "needs triage", "status: triage", "status: needs triage"}, state.TrackModeAuthor},
+}
+
+// Detect returns the settings the topics and labels call for and the presets
+// they came from. Matching ignores case.
+func Detect(topics, labels []string) (Settings, []Match) {
+	var settings Settings
+	var matches []Match
+
+	for _, lang := range languages {
+		if topic, ok := firstMatch(lang.topics, topics); ok {
+			settings.TestCommand = lang.test
+			settings.PreSpawn = lang.preSpawn
+			matches = append(matches, Match{Preset: lang.name, Reason: fmt.Sprintf("topic %q", topic)})
+			break
+		}
+	}
+
+	for _, scheme := range labelSchemes {
+		if label, ok := firstMatch(scheme.labels, labels); ok {
+			settings.TrackMode = scheme.track
+			matches = append(matches, Match{Preset: scheme.name + " labels", Reason: fmt.Sprintf("label %q", label)})
+			break
+		}
+	}
+
+	return settings, matches
+}
+
+// firstMatch returns the first of have that is one of want, ignoring case
+func firstMatch(want, have []string) (string, bool) {
+	for _, h := range have {
+		for _, w := range want {
+			if strings.EqualFold(strings.TrimSpace(h), w) {
+				return h, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Fetch returns a GitHub repository's topics and label names, using the gh CLI
+func Fetch(ctx context.Context, owner, repo string) (topics, labels []string, err error) {
+	output, err := retry.GitHub.Output(ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "gh", "api", fmt.Sprintf("repos/%s/%s/topics", owner, repo), "--jq", ".names")
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("gh api failed: %w", err)
+	}
+	if err := json.Unmarshal(output, &topics); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse gh api output: %w", err)
+	}
+
+	output, err = retry.GitHub.Output(ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "gh", "api", "--paginate", fmt.Sprintf("repos/%s/%s/labels", owner, repo), "--jq", ".[].name")
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("gh api failed: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			labels = append(labels, line)
+		}
+	}
+	return topics, labels, nil
+}
@@ -0,0 +1,66 @@
+package presets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name        string
+		topics      []string
+		labels      []string
+		want        Settings
+		wantPresets []string
+	}{
+		{"nothing to go on", nil, []string{"bug", "enhancement"}, Settings{}, nil},
+		{
+			"go topic",
+			[]string{"cli", "Golang"},
+			nil,
+			Settings{TestCommand: "go vet ./... && go test ./...", PreSpawn: "go mod download"},
+			[]string{"go"},
+		},
+		{
+			"first language listed wins",
+			[]string{"typescript", "go"},
+			nil,
+			Settings{TestCommand: "go vet ./... && go test ./...", PreSpawn: "go mod download"},
+			[]string{"go"},
+		},
+		{
+			"triage labels",
+			[]string{"python"},
+			[]string{"bug", "Needs-Triage"},
+			Settings{TrackMode: state.TrackModeAuthor, TestCommand: "python -m pytest"},
+			[]string{"python", "triage labels"},
+		},
+		{
+			"assignment beats triage",
+			nil,
+			[]string{"triage", "status: in progress"},
+			Settings{TrackMode: state.TrackModeAssigned},
+			[]string{"assignment labels"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matches := Detect(tt.topics, tt.labels)
+			if got != tt.want {
+				t.Errorf("Detect() settings = %+v, want %+v", got, tt.want)
+			}
+			var names []string
+			for _, m := range matches {
+				if m.Reason == "" {
+					t.Errorf("match %s has no reason", m.Preset)
+				}
+				names = append(names, m.Preset)
+			}
+			if !reflect.DeepEqual(names, tt.wantPresets) {
+				t.Errorf("Detect() presets = %v, want %v", names, tt.wantPresets)
+			}
+		})
+	}
+}
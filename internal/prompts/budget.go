@@ -0,0 +1,262 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// Prompt sections, in the order they are assembled
+const (
+	// SectionBase is the agent's default prompt or definition
+	SectionBase = "base"
+	// SectionDocs is the generated CLI documentation
+	SectionDocs = "docs"
+	// SectionCommands is the slash command reference
+	SectionCommands = "commands"
+	// SectionCustom is the repository-specific instructions
+	SectionCustom = "custom"
+)
+
+// Sections lists the prompt sections, in assembly order
+var Sections = []string{SectionBase, SectionDocs, SectionCommands, SectionCustom}
+
+// trimOrder is the order sections give way when the whole prompt is over
+// budget: material the agent can look up again (multiclaude --help, the
+// command files) goes before the instructions only the prompt carries
+var trimOrder = []string{SectionDocs, SectionCommands, SectionCustom, SectionBase}
+
+// DefaultBudget is used for any limit a repository leaves at zero. It leaves
+// room for every built-in prompt; only large custom instructions or docs are cut.
+var DefaultBudget = state.PromptBudget{
+	Total:    20000,
+	Base:     8000,
+	Docs:     6000,
+	Commands: 2000,
+	Custom:   8000,
+}
+
+// sectionSeparator joins non-empty sections
+const sectionSeparator = "\n\n---\n\n"
+
+// EstimateTokens estimates how many tokens text takes, at about four
+// characters per token. It is meant for budgeting, not billing.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Section is one named part of a prompt
+type Section struct {
+	Name string
+	Text string
+}
+
+// How a section was cut to fit its budget
+const (
+	// TrimSummarized keeps the section's headings and the first paragraph under each
+	TrimSummarized = "summarized"
+	// TrimTruncated keeps the start of the section
+	TrimTruncated = "truncated"
+	// TrimDropped leaves the section out
+	TrimDropped = "dropped"
+)
+
+// Trim records a section cut to fit the budget
+type Trim struct {
+	Section string
+	Method  string
+	Tokens  int // Estimated tokens before trimming
+	Kept    int // Estimated tokens after trimming
+}
+
+// Report describes an assembled prompt and what was cut from it
+type Report struct {
+	Tokens int // Estimated tokens in the assembled prompt
+	Budget int // The total budget it was assembled against
+	Trims  []Trim
+}
+
+// Trimmed reports whether any section was cut
+func (r Report) Trimmed() bool {
+	return len(r.Trims) > 0
+}
+
+func (r Report) String() string {
+	if !r.Trimmed() {
+		return fmt.Sprintf("prompt is %d tokens (budget %d)", r.Tokens, r.Budget)
+	}
+	cuts := make([]string, len(r.Trims))
+	for i, t := range r.Trims {
+		cuts[i] = fmt.Sprintf("%s %s (%d -> %d tokens)", t.Section, t.Method, t.Tokens, t.Kept)
+	}
+	return fmt.Sprintf("prompt trimmed to %d tokens (budget %d): %s", r.Tokens, r.Budget, strings.Join(cuts, ", "))
+}
+
+// ResolveBudget fills the limits a budget leaves at zero from DefaultBudget
+func ResolveBudget(b state.PromptBudget) state.PromptBudget {
+	if b.Total <= 0 {
+		b.Total = DefaultBudget.Total
+	}
+	if b.Base <= 0 {
+		b.Base = DefaultBudget.Base
+	}
+	if b.Docs <= 0 {
+		b.Docs = DefaultBudget.Docs
+	}
+	if b.Commands <= 0 {
+		b.Commands = DefaultBudget.Commands
+	}
+	if b.Custom <= 0 {
+		b.Custom = DefaultBudget.Custom
+	}
+	return b
+}
+
+// sectionLimit returns a resolved budget's limit for a section
+func sectionLimit(b state.PromptBudget, name string) int {
+	switch name {
+	case SectionBase:
+		return b.Base
+	case SectionDocs:
+		return b.Docs
+	case SectionCommands:
+		return b.Commands
+	case SectionCustom:
+		return b.Custom
+	}
+	return b.Total
+}
+
+// Assemble joins sections into a prompt that fits the budget. Each section is
+// first held to its own limit; if the whole is still over the total, sections
+// are cut further in trimOrder. A section over its limit is summarized to its
+// outline when that fits, and truncated otherwise.
+func Assemble(sections []Section, budget state.PromptBudget) (string, Report) {
+	budget = ResolveBudget(budget)
+	texts := make([]string, len(sections))
+	trims := make(map[string]*Trim)
+
+	cut := func(i, limit int) {
+		s := sections[i]
+		text, method := fit(texts[i], limit)
+		if method == "" {
+			return
+		}
+		texts[i] = text
+		t, ok := trims[s.Name]
+		if !ok {
+			t = &Trim{Section: s.Name, Tokens: EstimateTokens(s.Text)}
+			trims[s.Name] = t
+		}
+		t.Method, t.Kept = method, EstimateTokens(text)
+	}
+
+	total := 0
+	for i, s := range sections {
+		texts[i] = s.Text
+		cut(i, sectionLimit(budget, s.Name))
+		total += EstimateTokens(texts[i])
+	}
+
+	for _, name := range trimOrder {
+		for i, s := range sections {
+			if total <= budget.Total {
+				break
+			}
+			if s.Name != name || texts[i] == "" {
+				continue
+			}
+			tokens := EstimateTokens(texts[i])
+			cut(i, tokens-(total-budget.Total))
+			total += EstimateTokens(texts[i]) - tokens
+		}
+	}
+
+	var parts []string
+	for _, text := range texts {
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+
+	report := Report{Tokens: total, Budget: budget.Total}
+	for _, s := range sections {
+		if t, ok := trims[s.Name]; ok {
+			report.Trims = append(report.Trims, *t)
+			delete(trims, s.Name)
+		}
+	}
+	return strings.Join(parts, sectionSeparator), report
+}
+
+// fit cuts text to at most limit tokens, returning how it was cut ("" if it
+// already fit)
+func fit(text string, limit int) (string, string) {
+	if EstimateTokens(text) <= limit {
+		return text, ""
+	}
+	if limit <= 0 {
+		return "", TrimDropped
+	}
+	if outline := summarize(text); outline != "" && EstimateTokens(outline) <= limit {
+		return outline, TrimSummarized
+	}
+
+	marker := fmt.Sprintf("\n\n[... %d tokens trimmed to fit the prompt budget]", EstimateTokens(text))
+	keep := limit*4 - utf8.RuneCountInString(marker)
+	if keep <= 0 {
+		return "", TrimDropped
+	}
+	runes := []rune(text)
+	kept := string(runes[:keep])
+	// Prefer to end on a line boundary, unless that throws away most of the text
+	if nl := strings.LastIndexByte(kept, '\n'); nl > len(kept)/2 {
+		kept = kept[:nl]
+	}
+	return strings.TrimRight(kept, "\n") + marker, TrimTruncated
+}
+
+// summarize reduces markdown to its headings and the first paragraph under
+// each. It returns "" for text without headings.
+func summarize(text string) string {
+	var out []string
+	inFence, inParagraph, headings := false, false, 0
+	wantParagraph := true // the text before the first heading counts as a section
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			inParagraph = false
+			continue
+		}
+		if inFence {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			if len(out) > 0 {
+				out = append(out, "")
+			}
+			out = append(out, line)
+			headings++
+			wantParagraph, inParagraph = true, false
+		case trimmed == "":
+			if inParagraph {
+				wantParagraph, inParagraph = false, false
+			}
+		case wantParagraph || inParagraph:
+			if !inParagraph && len(out) > 0 {
+				out = append(out, "")
+			}
+			out = append(out, line)
+			inParagraph = true
+		}
+	}
+	if headings == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n\n[Summarized to fit the prompt budget: only headings and the first paragraph of each section are kept.]"
+}
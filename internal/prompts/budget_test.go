@@ -0,0 +1,125 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	for text, want := range map[string]int{"": 0, "abc": 1, "abcd": 1, "abcde": 2, "héllo wörld!": 3} {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+// markdown builds a document of n sections, each a heading, a short first
+// paragraph and a long second one
+func markdown(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString("## Section\n\nFirst paragraph.\n\n")
+		sb.WriteString(strings.Repeat("Details that can go. ", 20) + "\n\n")
+		sb.WriteString("```bash\n# not a heading\n```\n\n")
+	}
+	return sb.String()
+}
+
+func TestAssembleWithinBudget(t *testing.T) {
+	prompt, report := Assemble([]Section{
+		{Name: SectionBase, Text: "You are a worker."},
+		{Name: SectionDocs, Text: ""},
+		{Name: SectionCommands, Text: "## Slash Commands"},
+	}, state.PromptBudget{})
+
+	if prompt != "You are a worker.\n\n---\n\n## Slash Commands" {
+		t.Errorf("Assemble() = %q", prompt)
+	}
+	if report.Trimmed() || report.Budget != DefaultBudget.Total {
+		t.Errorf("report = %+v, want no trims against the default budget", report)
+	}
+}
+
+func TestAssembleSectionBudget(t *testing.T) {
+	docs := markdown(5)
+	prompt, report := Assemble([]Section{
+		{Name: SectionBase, Text: "You are a worker."},
+		{Name: SectionDocs, Text: docs},
+	}, state.PromptBudget{Docs: 100})
+
+	if len(report.Trims) != 1 {
+		t.Fatalf("Trims = %+v, want one", report.Trims)
+	}
+	trim := report.Trims[0]
+	if trim.Section != SectionDocs || trim.Method != TrimSummarized || trim.Tokens != EstimateTokens(docs) || trim.Kept > 100 {
+		t.Errorf("trim = %+v", trim)
+	}
+	if !strings.Contains(prompt, "First paragraph.") || strings.Contains(prompt, "Details that can go") || strings.Contains(prompt, "not a heading") {
+		t.Errorf("summary kept the wrong parts:\n%s", prompt)
+	}
+	if !strings.HasPrefix(prompt, "You are a worker.") {
+		t.Errorf("base section was changed:\n%s", prompt)
+	}
+}
+
+func TestAssembleTotalBudget(t *testing.T) {
+	base := strings.Repeat("Do the task. ", 40)
+	custom := strings.Repeat("Team rule. ", 40)
+	docs := strings.Repeat("usage line\n", 200)
+	budget := state.PromptBudget{Total: EstimateTokens(base) + EstimateTokens(custom) + 50}
+
+	prompt, report := Assemble([]Section{
+		{Name: SectionBase, Text: base},
+		{Name: SectionDocs, Text: docs},
+		{Name: SectionCustom, Text: custom},
+	}, budget)
+
+	if report.Tokens > budget.Total {
+		t.Errorf("Tokens = %d, over the budget of %d", report.Tokens, budget.Total)
+	}
+	// Docs give way first; base and custom fit once they have
+	if len(report.Trims) != 1 || report.Trims[0].Section != SectionDocs || report.Trims[0].Method != TrimTruncated {
+		t.Errorf("Trims = %+v, want docs truncated", report.Trims)
+	}
+	if !strings.Contains(prompt, base) || !strings.Contains(prompt, custom) || !strings.Contains(prompt, "tokens trimmed to fit the prompt budget") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+	if !strings.Contains(report.String(), "docs truncated") {
+		t.Errorf("String() = %q", report.String())
+	}
+
+	// A budget too small for anything but the base drops the rest
+	_, report = Assemble([]Section{
+		{Name: SectionBase, Text: base},
+		{Name: SectionDocs, Text: docs},
+	}, state.PromptBudget{Total: EstimateTokens(base)})
+	if len(report.Trims) != 1 || report.Trims[0].Method != TrimDropped || report.Tokens != EstimateTokens(base) {
+		t.Errorf("report = %+v, want docs dropped", report)
+	}
+}
+
+func TestGetPromptWithBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, ".multiclaude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	custom := markdown(20)
+	if err := os.WriteFile(filepath.Join(tmpDir, ".multiclaude", "SUPERVISOR.md"), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, report, err := GetPromptWithBudget(tmpDir, state.AgentTypeSupervisor, "", state.PromptBudget{Custom: 200})
+	if err != nil {
+		t.Fatalf("GetPromptWithBudget() failed: %v", err)
+	}
+	if len(report.Trims) != 1 || report.Trims[0].Section != SectionCustom {
+		t.Errorf("Trims = %+v, want the custom prompt cut", report.Trims)
+	}
+	if !strings.Contains(prompt, "Repository-specific instructions:") || !strings.Contains(prompt, "## Slash Commands") {
+		t.Error("prompt is missing its sections")
+	}
+}
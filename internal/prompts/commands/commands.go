@@ -13,7 +13,7 @@ import (
 
 // Embedded command templates
 //
-//go:embed refresh.md status.md workers.md messages.md
+//go:embed refresh.md status.md workers.md messages.md cli.md
 var commandFS embed.FS
 
 // CommandInfo describes a slash command
@@ -29,6 +29,7 @@ var AvailableCommands = []CommandInfo{
 	{Name: "status", Filename: "status.md", Description: "Show system status"},
 	{Name: "workers", Filename: "workers.md", Description: "List active workers"},
 	{Name: "messages", Filename: "messages.md", Description: "Check inter-agent messages"},
+	{Name: "cli", Filename: "cli.md", Description: "Show the multiclaude CLI reference"},
 }
 
 // GetCommand returns the content of a specific command template
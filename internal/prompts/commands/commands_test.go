@@ -32,6 +32,11 @@ func TestGetCommand(t *testing.T) {
 			want:    "inter-agent messages",
 			wantErr: false,
 		},
+		{
+			name:    "cli",
+			want:    "multiclaude docs",
+			wantErr: false,
+		},
 		{
 			name:    "nonexistent",
 			want:    "",
@@ -57,7 +62,7 @@ func TestGetCommand(t *testing.T) {
 }
 
 func TestAvailableCommands(t *testing.T) {
-	expectedCommands := []string{"refresh", "status", "workers", "messages"}
+	expectedCommands := []string{"refresh", "status", "workers", "messages", "cli"}
 
 	if len(AvailableCommands) != len(expectedCommands) {
 		t.Errorf("Expected %d commands, got %d", len(expectedCommands), len(AvailableCommands))
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RepoCommandsDir is where a repository keeps its own slash commands,
+// relative to the repository root. Each <name>.md file defines /<name>.
+const RepoCommandsDir = ".multiclaude/commands"
+
+// Source indicates where a slash command came from
+type Source string
+
+const (
+	// SourceBuiltin is a command embedded in multiclaude
+	SourceBuiltin Source = "builtin"
+	// SourceRepo is a command the repository defines
+	SourceRepo Source = "repo"
+	// SourceOverride is a repository command that replaces the built-in of the same name
+	SourceOverride Source = "override"
+)
+
+// Command is a slash command in a repository's effective set
+type Command struct {
+	CommandInfo
+	Content string
+	Source  Source
+	// Path is the file a repository command was read from (empty for built-ins)
+	Path string
+}
+
+// nameRe matches usable command names: the file name without .md
+var nameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// headingRe matches the title line built-in commands use: "# /name - Description"
+var headingRe = regexp.MustCompile(`^#\s+/\S+\s+-\s+(.+)$`)
+
+// Effective returns the slash commands for the repository at repoPath: the
+// built-ins, in AvailableCommands order, followed by the repository's own
+// commands sorted by name. A repository command named like a built-in
+// replaces it in place. Files that can't be used are skipped and described in
+// the returned warnings; a missing commands directory is not a problem.
+func Effective(repoPath string) ([]Command, []string) {
+	var cmds []Command
+	index := make(map[string]int)
+	for _, info := range AvailableCommands {
+		content, err := GetCommand(info.Name)
+		if err != nil {
+			continue
+		}
+		index[info.Name] = len(cmds)
+		cmds = append(cmds, Command{CommandInfo: info, Content: content, Source: SourceBuiltin})
+	}
+	if repoPath == "" {
+		return cmds, nil
+	}
+
+	dir := filepath.Join(repoPath, RepoCommandsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cmds, nil
+		}
+		return cmds, []string{fmt.Sprintf("%s: %v", dir, err)}
+	}
+
+	var warnings []string
+	var added []Command
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		if !nameRe.MatchString(name) {
+			warnings = append(warnings, fmt.Sprintf("%s: skipped, command names use lowercase letters, digits, - and _", path))
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			warnings = append(warnings, fmt.Sprintf("%s: skipped, the file is empty", path))
+			continue
+		}
+
+		cmd := Command{
+			CommandInfo: CommandInfo{Name: name, Filename: entry.Name(), Description: describe(string(content))},
+			Content:     string(content),
+			Source:      SourceRepo,
+			Path:        path,
+		}
+		if i, ok := index[name]; ok {
+			cmd.Source = SourceOverride
+			cmds[i] = cmd
+			continue
+		}
+		added = append(added, cmd)
+	}
+
+	sort.Slice(added, func(i, j int) bool { return added[i].Name < added[j].Name })
+	return append(cmds, added...), warnings
+}
+
+// describe extracts a command's description from its content: the text after
+// the dash in a "# /name - Description" title, else the first line of prose
+func describe(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+		if !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
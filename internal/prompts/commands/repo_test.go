@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEffective(t *testing.T) {
+	// Without repository commands, the effective set is the built-ins
+	cmds, warnings := Effective(t.TempDir())
+	if len(cmds) != len(AvailableCommands) || len(warnings) != 0 {
+		t.Fatalf("Effective() = %d commands, %v; want the %d built-ins", len(cmds), warnings, len(AvailableCommands))
+	}
+	for _, cmd := range cmds {
+		if cmd.Source != SourceBuiltin || cmd.Content == "" {
+			t.Errorf("built-in %q = %+v", cmd.Name, cmd)
+		}
+	}
+
+	repo := t.TempDir()
+	dir := filepath.Join(repo, RepoCommandsDir)
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"deploy.md":   "# /deploy - Deploy to staging\n\nRun make deploy.\n",
+		"lint.md":     "Run the linters and fix what they report.\n",
+		"refresh.md":  "# /refresh - Sync with the monorepo base\n\nRun ./tools/sync.\n",
+		"Bad Name.md": "# /bad\n",
+		"empty.md":    "\n",
+		"notes.txt":   "not a command",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmds, warnings = Effective(repo)
+	if len(cmds) != len(AvailableCommands)+2 {
+		t.Fatalf("Effective() returned %d commands, want built-ins plus deploy and lint", len(cmds))
+	}
+	if cmds[0].Name != "refresh" || cmds[0].Source != SourceOverride || !strings.Contains(cmds[0].Content, "tools/sync") {
+		t.Errorf("refresh = %+v, want the repository's override in the built-in's place", cmds[0])
+	}
+	deploy, lint := cmds[len(cmds)-2], cmds[len(cmds)-1]
+	if deploy.Name != "deploy" || deploy.Source != SourceRepo || deploy.Description != "Deploy to staging" || deploy.Path != filepath.Join(dir, "deploy.md") {
+		t.Errorf("deploy = %+v", deploy)
+	}
+	if lint.Name != "lint" || lint.Description != "Run the linters and fix what they report." {
+		t.Errorf("lint = %+v", lint)
+	}
+	if len(warnings) != 2 || !strings.Contains(strings.Join(warnings, "\n"), "Bad Name.md") || !strings.Contains(strings.Join(warnings, "\n"), "empty") {
+		t.Errorf("warnings = %v, want the bad name and the empty file", warnings)
+	}
+}
@@ -0,0 +1,139 @@
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fragments are the parts of an agent's prompt generated from repository
+// config, such as its PR tracking mode. Each is kept in an include file of its
+// own, and the saved prompt carries a copy between markers naming the file:
+//
+//	<!-- multiclaude:include /home/me/.multiclaude/prompts/merge-queue.d/tracking.md -->
+//	## PR Tracking Mode: ...
+//	<!-- /multiclaude:include -->
+//
+// When the config changes, only the include files it affects are rewritten and
+// only their copies replaced, rather than the whole prompt being regenerated.
+// ExpandIncludes brings every copy up to date before an agent restarts.
+
+// Fragment names
+const (
+	// FragmentTracking is the merge queue's or PR shepherd's PR tracking mode
+	FragmentTracking = "tracking"
+	// FragmentFork is the PR shepherd's fork workflow
+	FragmentFork = "fork"
+)
+
+// Fragment is a named part of a prompt generated from config
+type Fragment struct {
+	Name    string
+	Content string
+}
+
+const (
+	includeStart = "<!-- multiclaude:include "
+	includeEnd   = "<!-- /multiclaude:include -->"
+)
+
+// Include returns the copy of an include file's content that goes in a prompt
+func Include(path, content string) string {
+	return includeStart + path + " -->\n" + strings.TrimSpace(content) + "\n" + includeEnd
+}
+
+// SetInclude replaces the copy of the include file at path in a prompt, in
+// place. It reports false, leaving the prompt alone, if the prompt has none.
+func SetInclude(prompt, path, content string) (string, bool) {
+	marker := includeStart + path + " -->"
+	start := strings.Index(prompt, marker)
+	if start < 0 {
+		return prompt, false
+	}
+	end := strings.Index(prompt[start:], includeEnd)
+	if end < 0 {
+		return prompt, false
+	}
+	end += start + len(includeEnd)
+	return prompt[:start] + Include(path, content) + prompt[end:], true
+}
+
+// IncludePaths returns the include files a prompt has copies of, in order
+func IncludePaths(prompt string) []string {
+	var paths []string
+	for rest := prompt; ; {
+		start := strings.Index(rest, includeStart)
+		if start < 0 {
+			return paths
+		}
+		rest = rest[start+len(includeStart):]
+		path, _, ok := strings.Cut(rest, " -->")
+		if !ok {
+			return paths
+		}
+		paths = append(paths, path)
+	}
+}
+
+// ExpandIncludes replaces every include copy in a prompt with its file's
+// current content. Copies whose file can't be read are left as they are.
+func ExpandIncludes(prompt string) string {
+	for _, path := range IncludePaths(prompt) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		prompt, _ = SetInclude(prompt, path, string(data))
+	}
+	return prompt
+}
+
+// FragmentFile returns the include file of a fragment in an agent's
+// fragment directory
+func FragmentFile(dir, name string) string {
+	return filepath.Join(dir, name+".md")
+}
+
+// WriteFragments writes fragments to their include files in dir and returns
+// their copies for a prompt, separated by blank lines
+func WriteFragments(dir string, fragments []Fragment) (string, error) {
+	var copies []string
+	for _, f := range fragments {
+		if _, err := WriteFragment(dir, f); err != nil {
+			return "", err
+		}
+		copies = append(copies, Include(FragmentFile(dir, f.Name), f.Content))
+	}
+	return strings.Join(copies, "\n\n"), nil
+}
+
+// WriteFragment writes a fragment to its include file in dir, reporting
+// whether the file changed
+func WriteFragment(dir string, f Fragment) (bool, error) {
+	path := FragmentFile(dir, f.Name)
+	content := strings.TrimSpace(f.Content) + "\n"
+	if data, err := os.ReadFile(path); err == nil && string(data) == content {
+		return false, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create prompt fragment directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write prompt fragment: %w", err)
+	}
+	return true, nil
+}
+
+// MergeQueueFragments returns the config fragments of a merge queue's prompt
+func MergeQueueFragments(trackMode string) []Fragment {
+	return []Fragment{{Name: FragmentTracking, Content: GenerateTrackingModePrompt(trackMode)}}
+}
+
+// PRShepherdFragments returns the config fragments of a PR shepherd's prompt
+func PRShepherdFragments(trackMode, upstreamOwner, upstreamRepo string) []Fragment {
+	return []Fragment{
+		{Name: FragmentTracking, Content: GenerateTrackingModePrompt(trackMode)},
+		{Name: FragmentFork, Content: GenerateForkWorkflowPrompt(upstreamOwner, upstreamRepo, upstreamOwner)},
+	}
+}
@@ -0,0 +1,49 @@
+package prompts
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteFragmentsAndSetInclude(t *testing.T) {
+	dir := t.TempDir()
+	copies, err := WriteFragments(dir, PRShepherdFragments("author", "acme", "app"))
+	if err != nil {
+		t.Fatalf("WriteFragments() failed: %v", err)
+	}
+	prompt := copies + "\n\nYou are the PR shepherd."
+
+	tracking, fork := FragmentFile(dir, FragmentTracking), FragmentFile(dir, FragmentFork)
+	if paths := IncludePaths(prompt); len(paths) != 2 || paths[0] != tracking || paths[1] != fork {
+		t.Errorf("IncludePaths() = %v, want the tracking and fork files", paths)
+	}
+	if data, _ := os.ReadFile(fork); !strings.Contains(string(data), "acme/app") {
+		t.Errorf("fork fragment = %q, want the upstream", data)
+	}
+
+	// Rewriting one fragment replaces only its copy, in place
+	changed, err := WriteFragment(dir, Fragment{Name: FragmentTracking, Content: GenerateTrackingModePrompt("all")})
+	if err != nil || !changed {
+		t.Fatalf("WriteFragment() = %v, %v; want the file changed", changed, err)
+	}
+	updated, ok := SetInclude(prompt, tracking, GenerateTrackingModePrompt("all"))
+	if !ok || strings.Contains(updated, "Author Only") || !strings.Contains(updated, "acme/app") {
+		t.Errorf("SetInclude() = %q, %v; want only the tracking mode replaced", updated, ok)
+	}
+	if !strings.HasPrefix(updated, includeStart+tracking) || !strings.HasSuffix(updated, "You are the PR shepherd.") {
+		t.Errorf("SetInclude() moved the fragment:\n%s", updated)
+	}
+	if changed, _ := WriteFragment(dir, Fragment{Name: FragmentTracking, Content: GenerateTrackingModePrompt("all")}); changed {
+		t.Error("WriteFragment() rewrote an unchanged file")
+	}
+
+	// ExpandIncludes picks up every file's current content
+	if got := ExpandIncludes(prompt); got != updated {
+		t.Errorf("ExpandIncludes() =\n%s\nwant\n%s", got, updated)
+	}
+
+	if _, ok := SetInclude("no includes here", tracking, "x"); ok {
+		t.Error("SetInclude() should report a prompt without the include")
+	}
+}
@@ -0,0 +1,71 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/memory"
+)
+
+// Markers around the memory section of a saved prompt, so the daemon can
+// replace it as the agent's memory grows
+const (
+	memoryStart = "<!-- multiclaude:memory -->"
+	memoryEnd   = "<!-- /multiclaude:memory -->"
+)
+
+// maxMemoryPrompt is how much of an agent's memory its prompt carries. The
+// newest entries that fit are included; the rest stay in the file.
+const maxMemoryPrompt = 8000
+
+// MemorySection returns the memory section of an agent's prompt: how to add
+// to its memory, and its newest entries, oldest first. memoryFile is where the
+// full memory is kept.
+func MemorySection(entries []memory.Entry, memoryFile string) string {
+	var b strings.Builder
+	b.WriteString("## Memory\n\n")
+	b.WriteString("Your memory survives restarts, respawns and daemon restarts; your conversation may not. ")
+	b.WriteString("Save decisions, dead ends and context you'd want after a restart with `multiclaude memory add \"<note>\"`. ")
+	b.WriteString("Keep notes short and specific. Check what they say against the code before relying on them.\n\n")
+
+	var lines []string
+	size, omitted := 0, 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		line := formatMemoryEntry(entries[i])
+		if size+len(line) > maxMemoryPrompt {
+			omitted = i + 1
+			break
+		}
+		size += len(line)
+		lines = append(lines, line)
+	}
+
+	switch {
+	case len(entries) == 0:
+		b.WriteString("Nothing saved yet.")
+	default:
+		if omitted > 0 {
+			fmt.Fprintf(&b, "%d older entries are in `%s`.\n\n", omitted, memoryFile)
+		}
+		for i := len(lines) - 1; i >= 0; i-- {
+			b.WriteString(lines[i])
+		}
+	}
+	return memoryStart + "\n" + strings.TrimRight(b.String(), "\n") + "\n" + memoryEnd
+}
+
+// formatMemoryEntry formats an entry as a list item, indenting its later lines
+func formatMemoryEntry(e memory.Entry) string {
+	label := e.Source
+	if e.About != "" {
+		label += " " + e.About
+	}
+	text := strings.ReplaceAll(e.Text, "\n", "\n  ")
+	return fmt.Sprintf("- %s (%s): %s\n", e.Time.UTC().Format("2006-01-02 15:04"), label, text)
+}
+
+// SetMemorySection replaces the memory section of a prompt, appending it if
+// the prompt has none. An empty section removes it.
+func SetMemorySection(prompt, section string) string {
+	return setSection(prompt, memoryStart, memoryEnd, section)
+}
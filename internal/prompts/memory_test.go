@@ -0,0 +1,51 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/memory"
+)
+
+func TestMemorySection(t *testing.T) {
+	if section := MemorySection(nil, "/tmp/calm-owl.md"); !strings.Contains(section, "Nothing saved yet.") || !strings.Contains(section, "multiclaude memory add") {
+		t.Errorf("empty memory section = %q, want how to add to it", section)
+	}
+
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	entries := []memory.Entry{
+		{Time: at, Source: memory.SourceNote, Text: "Use the v2 API.\nv1 is going away."},
+		{Time: at.Add(time.Hour), Source: memory.SourceWorker, About: "brave-fox", Text: "Completed: add dark mode"},
+	}
+	section := MemorySection(entries, "/tmp/calm-owl.md")
+	want := "- 2026-03-02 09:00 (note): Use the v2 API.\n  v1 is going away.\n- 2026-03-02 10:00 (worker brave-fox): Completed: add dark mode\n"
+	if !strings.Contains(section, want) {
+		t.Errorf("MemorySection() = %q, want the entries oldest first:\n%s", section, want)
+	}
+
+	// Only the newest entries that fit go in; the file is pointed to for the rest
+	var many []memory.Entry
+	for i := 0; i < 100; i++ {
+		many = append(many, memory.Entry{Time: at.Add(time.Duration(i) * time.Minute), Source: memory.SourceNote, Text: strings.Repeat("x", 150)})
+	}
+	section = MemorySection(many, "/tmp/calm-owl.md")
+	if len(section) > maxMemoryPrompt+1000 || !strings.Contains(section, "older entries are in `/tmp/calm-owl.md`") || !strings.Contains(section, "10:39 (note)") {
+		t.Errorf("MemorySection() of a long memory = %d bytes, want the newest entries and a pointer to the file", len(section))
+	}
+}
+
+func TestSetMemorySection(t *testing.T) {
+	first := MemorySection(nil, "/tmp/calm-owl.md")
+	second := MemorySection([]memory.Entry{{Time: time.Now(), Source: memory.SourceNote, Text: "remember this"}}, "/tmp/calm-owl.md")
+
+	prompt := SetRosterSection("You are a worker.", RosterSection("", nil, "", "/tmp/ROSTER.md"))
+	prompt = SetMemorySection(prompt, first)
+	prompt = SetMemorySection(prompt, second)
+	if strings.Count(prompt, memoryStart) != 1 || !strings.Contains(prompt, "remember this") || strings.Contains(prompt, "Nothing saved yet.") {
+		t.Errorf("SetMemorySection() = %q, want only the new memory", prompt)
+	}
+	if !strings.Contains(prompt, rosterStart) {
+		t.Errorf("SetMemorySection() = %q, dropped the roster", prompt)
+	}
+}
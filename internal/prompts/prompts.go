@@ -34,7 +34,7 @@ const TypeReview = state.AgentTypeReview
 const TypePRShepherd = state.AgentTypePRShepherd
 
 // Embedded default prompts
-// Only supervisor and workspace are "hardcoded" - other agent types (worker, merge-queue, review)
+// Only supervisor, workspace and solo are "hardcoded" - other agent types (worker, merge-queue, review)
 // should come from configurable agent definitions in agent-templates.
 //
 //go:embed supervisor.md
@@ -43,8 +43,39 @@ var defaultSupervisorPrompt string
 //go:embed workspace.md
 var defaultWorkspacePrompt string
 
+//go:embed solo.md
+var defaultSoloPrompt string
+
+// DefaultBranchVar is the template variable for the repository's default branch.
+// Prompts, agent definitions and slash commands use it instead of hardcoding "main".
+const DefaultBranchVar = "{{DEFAULT_BRANCH}}"
+
+// BranchPatternVar is the template variable for the glob that worker branch names
+// follow (e.g. "work/*" or "mc/*/*"), derived from the repository's branch template.
+const BranchPatternVar = "{{BRANCH_PATTERN}}"
+
+// TemplateVars holds the per-repository values substituted into prompt text
+type TemplateVars struct {
+	DefaultBranch string // Empty means "main"
+	BranchPattern string // Empty means "work/*"
+}
+
+// ExpandTemplateVars substitutes template variables in prompt text.
+func ExpandTemplateVars(text string, vars TemplateVars) string {
+	if vars.DefaultBranch == "" {
+		vars.DefaultBranch = "main"
+	}
+	if vars.BranchPattern == "" {
+		vars.BranchPattern = "work/*"
+	}
+	return strings.NewReplacer(
+		DefaultBranchVar, vars.DefaultBranch,
+		BranchPatternVar, vars.BranchPattern,
+	).Replace(text)
+}
+
 // GetDefaultPrompt returns the default prompt for the given agent type.
-// Only supervisor and workspace have embedded default prompts.
+// Only supervisor, workspace and solo have embedded default prompts.
 // Worker, merge-queue, and review prompts should come from agent definitions.
 func GetDefaultPrompt(agentType state.AgentType) string {
 	switch agentType {
@@ -52,6 +83,8 @@ func GetDefaultPrompt(agentType state.AgentType) string {
 		return defaultSupervisorPrompt
 	case state.AgentTypeWorkspace:
 		return defaultWorkspacePrompt
+	case state.AgentTypeSolo:
+		return defaultSoloPrompt
 	case state.AgentTypeWorker, state.AgentTypeMergeQueue, state.AgentTypeReview:
 		// These agent types should use configurable agent definitions
 		// from ~/.multiclaude/repos/<repo>/agents/ or <repo>/.multiclaude/agents/
@@ -83,6 +116,8 @@ func LoadCustomPrompt(repoPath string, agentType state.AgentType) (string, error
 		filename = "WORKSPACE.md"
 	case state.AgentTypeReview:
 		filename = "REVIEW.md"
+	case state.AgentTypeSolo:
+		filename = "SOLO.md"
 	default:
 		return "", fmt.Errorf("unknown agent type: %s", agentType)
 	}
@@ -103,36 +138,39 @@ func LoadCustomPrompt(repoPath string, agentType state.AgentType) (string, error
 	return string(content), nil
 }
 
-// GetPrompt returns the complete prompt for an agent, combining default, custom prompts, CLI docs, and slash commands
+// CLIDocsReference returns the prompt section that points an agent to the CLI
+// reference at path, in place of the reference itself
+func CLIDocsReference(path string) string {
+	return fmt.Sprintf(`## multiclaude CLI
+
+The full multiclaude command reference is in `+"`%s`"+`. Read it (or run `+"`multiclaude docs`"+`, or the `+"`/cli`"+` slash command) before using a command you haven't used yet. It is kept current, so prefer it over what you remember.`, path)
+}
+
+// GetPrompt returns the complete prompt for an agent, combining default, custom prompts, CLI docs, and slash commands.
+// It is held to the default budget; use GetPromptWithBudget to apply a repository's budget and see what was cut.
 func GetPrompt(repoPath string, agentType state.AgentType, cliDocs string) (string, error) {
-	defaultPrompt := GetDefaultPrompt(agentType)
+	prompt, _, err := GetPromptWithBudget(repoPath, agentType, cliDocs, state.PromptBudget{})
+	return prompt, err
+}
 
+// GetPromptWithBudget is GetPrompt, assembled against budget (see Assemble)
+func GetPromptWithBudget(repoPath string, agentType state.AgentType, cliDocs string, budget state.PromptBudget) (string, Report, error) {
 	customPrompt, err := LoadCustomPrompt(repoPath, agentType)
 	if err != nil {
-		return "", err
-	}
-
-	// Build the complete prompt
-	var result string
-	result = defaultPrompt
-
-	// Add CLI documentation
-	if cliDocs != "" {
-		result += fmt.Sprintf("\n\n---\n\n%s", cliDocs)
+		return "", Report{}, err
 	}
 
-	// Add slash commands section
-	slashCommands := GetSlashCommandsPrompt()
-	if slashCommands != "" {
-		result += fmt.Sprintf("\n\n---\n\n%s", slashCommands)
+	sections := []Section{
+		{Name: SectionBase, Text: GetDefaultPrompt(agentType)},
+		{Name: SectionDocs, Text: cliDocs},
+		{Name: SectionCommands, Text: GetRepoSlashCommandsPrompt(repoPath)},
 	}
-
-	// Add custom prompt if it exists
 	if customPrompt != "" {
-		result += fmt.Sprintf("\n\n---\n\nRepository-specific instructions:\n\n%s", customPrompt)
+		sections = append(sections, Section{Name: SectionCustom, Text: "Repository-specific instructions:\n\n" + customPrompt})
 	}
 
-	return result, nil
+	prompt, report := Assemble(sections, budget)
+	return prompt, report, nil
 }
 
 // GenerateTrackingModePrompt generates prompt text explaining which PRs to track
@@ -177,6 +215,44 @@ Monitor and process all multiclaude-labeled PRs regardless of author or assignee
 	}
 }
 
+// GenerateProjectSupervisorPrompt generates the prompt for a project supervisor,
+// the supervisor-of-supervisors that coordinates work across a project's repositories.
+func GenerateProjectSupervisorPrompt(projectName string, repos []string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("You are the project supervisor for **%s**. ", projectName))
+	sb.WriteString("You coordinate work that spans several repositories. ")
+	sb.WriteString("Each repository has its own supervisor, which owns that repository's workers.\n\n")
+
+	sb.WriteString("## Member Repositories\n\n")
+	for _, repo := range repos {
+		sb.WriteString(fmt.Sprintf("- `%s` (supervisor: `%s/supervisor`)\n", repo, repo))
+	}
+
+	sb.WriteString(`
+## How You Work
+
+- Break cross-repository goals into per-repository tasks and hand them to the
+  owning repository's supervisor. Do not spawn workers yourself.
+- Track dependencies between repositories (e.g. an API change that the web
+  client needs) and make sure supervisors land them in the right order.
+- Check progress with ` + "`multiclaude project status " + projectName + "`" + `.
+
+## Messaging
+
+Address agents in member repositories as ` + "`<repo>/<agent>`" + `:
+` + "```bash" + `
+multiclaude message send ` + repos[0] + `/supervisor "Please coordinate the auth API change"
+multiclaude message list
+multiclaude message ack <id>
+` + "```" + `
+
+Agents in member repositories reach you at ` + "`@" + projectName + "`" + `.
+`)
+
+	return sb.String()
+}
+
 // GenerateForkWorkflowPrompt generates prompt text explaining fork-based workflow.
 // This is injected into all agent prompts when working in a fork.
 func GenerateForkWorkflowPrompt(upstreamOwner, upstreamRepo, forkOwner string) string {
@@ -204,19 +280,19 @@ gh pr list --repo %s/%s --author @me
 Keep your fork updated with upstream:
 `+"```bash"+`
 # Fetch upstream changes
-git fetch upstream main
+git fetch upstream {{DEFAULT_BRANCH}}
 
 # Rebase your work
-git rebase upstream/main
+git rebase upstream/{{DEFAULT_BRANCH}}
 
-# Update your fork's main
-git checkout main && git merge --ff-only upstream/main && git push origin main
+# Update your fork's {{DEFAULT_BRANCH}}
+git checkout {{DEFAULT_BRANCH}} && git merge --ff-only upstream/{{DEFAULT_BRANCH}} && git push origin {{DEFAULT_BRANCH}}
 `+"```"+`
 
 ### Important Notes
 - **You cannot merge PRs** - upstream maintainers do that
 - Create branches on your fork (origin), target upstream for PRs
-- Keep rebasing onto upstream/main to avoid conflicts
+- Keep rebasing onto upstream/{{DEFAULT_BRANCH}} to avoid conflicts
 - The pr-shepherd agent handles getting PRs ready for review
 `, upstreamOwner, upstreamRepo,
 		forkOwner, upstreamRepo,
@@ -229,17 +305,25 @@ git checkout main && git merge --ff-only upstream/main && git push origin main
 // slash commands. This can be included in agent prompts to document the available
 // commands.
 func GetSlashCommandsPrompt() string {
+	return GetRepoSlashCommandsPrompt("")
+}
+
+// GetRepoSlashCommandsPrompt is GetSlashCommandsPrompt for the repository at
+// repoPath, including the commands it defines in .multiclaude/commands (see
+// commands.Effective)
+func GetRepoSlashCommandsPrompt(repoPath string) string {
 	var builder strings.Builder
 
 	builder.WriteString("## Slash Commands\n\n")
 	builder.WriteString("The following slash commands are available for use:\n\n")
 
-	for _, cmd := range commands.AvailableCommands {
-		content, err := commands.GetCommand(cmd.Name)
-		if err != nil {
-			continue
+	cmds, _ := commands.Effective(repoPath)
+	for _, cmd := range cmds {
+		// Repository commands may leave out the "# /name - Description" title
+		if !strings.HasPrefix(strings.TrimSpace(cmd.Content), "# /") {
+			builder.WriteString("# /" + cmd.Name + "\n\n")
 		}
-		builder.WriteString(content)
+		builder.WriteString(cmd.Content)
 		builder.WriteString("\n---\n\n")
 	}
 
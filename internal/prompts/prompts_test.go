@@ -17,6 +17,7 @@ func TestGetDefaultPrompt(t *testing.T) {
 	}{
 		{"supervisor", state.AgentTypeSupervisor, false},
 		{"workspace", state.AgentTypeWorkspace, false},
+		{"solo", state.AgentTypeSolo, false},
 		// Worker, merge-queue, and review should return empty - they use configurable agent definitions
 		{"worker", state.AgentTypeWorker, true},
 		{"merge-queue", state.AgentTypeMergeQueue, true},
@@ -59,6 +60,12 @@ func TestGetDefaultPromptContent(t *testing.T) {
 		t.Error("workspace prompt should document worker spawning capabilities")
 	}
 
+	// Verify solo prompt (hardcoded - has embedded content)
+	soloPrompt := GetDefaultPrompt(state.AgentTypeSolo)
+	if !strings.Contains(soloPrompt, "multiclaude solo") || !strings.Contains(soloPrompt, "multiclaude agent complete") {
+		t.Error("solo prompt should explain where the agent came from and how to finish")
+	}
+
 	// Note: Worker, merge-queue, and review prompts are now configurable
 	// and come from agent definitions, not embedded defaults.
 	// Their content is tested via the templates package instead.
@@ -317,7 +324,7 @@ func TestGetSlashCommandsPromptContainsAllCommands(t *testing.T) {
 // TestGetSlashCommandsPromptContainsCLICommands verifies that GetSlashCommandsPrompt()
 // contains the actual CLI commands that should be run for each slash command.
 func TestGetSlashCommandsPromptContainsCLICommands(t *testing.T) {
-	prompt := GetSlashCommandsPrompt()
+	prompt := ExpandTemplateVars(GetSlashCommandsPrompt(), TemplateVars{DefaultBranch: "main"})
 
 	// Commands expected in /status
 	statusCommands := []struct {
@@ -502,3 +509,68 @@ func TestGetSlashCommandsPromptNonEmpty(t *testing.T) {
 		t.Errorf("GetSlashCommandsPrompt() seems too short (got %d bytes), expected substantial content", len(prompt))
 	}
 }
+
+func TestGenerateProjectSupervisorPrompt(t *testing.T) {
+	prompt := GenerateProjectSupervisorPrompt("platform", []string{"api", "web", "infra"})
+
+	for _, want := range []string{
+		"project supervisor for **platform**",
+		"`api/supervisor`",
+		"`web/supervisor`",
+		"`infra/supervisor`",
+		"multiclaude project status platform",
+		"`@platform`",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("GenerateProjectSupervisorPrompt() missing %q", want)
+		}
+	}
+}
+
+func TestExpandTemplateVars(t *testing.T) {
+	text := "git fetch origin " + DefaultBranchVar + " && git rebase origin/" + DefaultBranchVar
+
+	if got, want := ExpandTemplateVars(text, TemplateVars{DefaultBranch: "develop"}), "git fetch origin develop && git rebase origin/develop"; got != want {
+		t.Errorf("ExpandTemplateVars() = %q, want %q", got, want)
+	}
+	if got, want := ExpandTemplateVars(text, TemplateVars{}), "git fetch origin main && git rebase origin/main"; got != want {
+		t.Errorf("ExpandTemplateVars() with empty branch = %q, want %q", got, want)
+	}
+
+	pattern := "head branch must match " + BranchPatternVar
+	if got, want := ExpandTemplateVars(pattern, TemplateVars{BranchPattern: "mc/*/*"}), "head branch must match mc/*/*"; got != want {
+		t.Errorf("ExpandTemplateVars() = %q, want %q", got, want)
+	}
+	if got, want := ExpandTemplateVars(pattern, TemplateVars{}), "head branch must match work/*"; got != want {
+		t.Errorf("ExpandTemplateVars() with empty pattern = %q, want %q", got, want)
+	}
+
+	// Every embedded template using the variable must expand cleanly
+	for _, prompt := range []string{GetSlashCommandsPrompt(), GetDefaultPrompt(state.AgentTypeWorkspace), GenerateForkWorkflowPrompt("up", "repo", "me")} {
+		if expanded := ExpandTemplateVars(prompt, TemplateVars{DefaultBranch: "trunk"}); strings.Contains(expanded, "{{") {
+			t.Errorf("unexpanded template variable left in prompt:\n%s", expanded)
+		}
+	}
+}
+
+func TestGetRepoSlashCommandsPrompt(t *testing.T) {
+	repo := t.TempDir()
+	dir := filepath.Join(repo, ".multiclaude", "commands")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deploy.md"), []byte("Run make deploy.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := GetRepoSlashCommandsPrompt(repo)
+	if !strings.Contains(prompt, "# /deploy\n\nRun make deploy.") {
+		t.Errorf("prompt is missing the repository command:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "/refresh") {
+		t.Error("prompt is missing the built-in commands")
+	}
+	if strings.Contains(GetSlashCommandsPrompt(), "/deploy") {
+		t.Error("GetSlashCommandsPrompt() includes a repository command")
+	}
+}
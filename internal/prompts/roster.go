@@ -0,0 +1,98 @@
+package prompts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// Markers around the teammates section of a saved prompt, so the daemon can
+// replace it as agents come and go
+const (
+	rosterStart = "<!-- multiclaude:roster -->"
+	rosterEnd   = "<!-- /multiclaude:roster -->"
+)
+
+// maxRosterTask is how much of each agent's task the roster shows
+const maxRosterTask = 120
+
+// FormatRoster lists a repository's agents by name, one per line, with their
+// type and the first line of their task. The agent named self, if any, is
+// marked as such.
+func FormatRoster(agents map[string]state.Agent, self string) string {
+	names := make([]string, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		agent := agents[name]
+		fmt.Fprintf(&b, "- `%s` (%s)", name, agent.Type)
+		if name == self {
+			b.WriteString(" - you")
+		}
+		if task, _, _ := strings.Cut(strings.TrimSpace(agent.Task), "\n"); task != "" {
+			if r := []rune(task); len(r) > maxRosterTask {
+				task = string(r[:maxRosterTask]) + "..."
+			}
+			b.WriteString(": " + task)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// RosterSection returns the teammates section of an agent's prompt for the
+// repository's roster mode, or "" if the roster is off. In prompt mode it
+// lists the agents; either way it points to rosterFile, which the daemon
+// keeps current.
+func RosterSection(mode state.RosterMode, agents map[string]state.Agent, self, rosterFile string) string {
+	var b strings.Builder
+	switch mode.Effective() {
+	case state.RosterOff:
+		return ""
+	case state.RosterPrompt:
+		b.WriteString("## Teammates\n\nThe agents in this repository:\n\n")
+		b.WriteString(FormatRoster(agents, self))
+		b.WriteString("\nAgents come and go, and this list can fall behind.")
+	default:
+		b.WriteString("## Teammates\n\nAgents come and go.")
+	}
+	fmt.Fprintf(&b, " Before messaging an agent or assigning work by name, check the current list in `%s` (or run `multiclaude worker list`). Never address an agent that isn't on it.", rosterFile)
+	return rosterStart + "\n" + b.String() + "\n" + rosterEnd
+}
+
+// SetRosterSection replaces the teammates section of a prompt, appending it
+// if the prompt has none. An empty section removes it.
+func SetRosterSection(prompt, section string) string {
+	return setSection(prompt, rosterStart, rosterEnd, section)
+}
+
+// setSection replaces the part of a prompt between the markers start and
+// end, appending section if the prompt has none. An empty section removes it.
+func setSection(prompt, startMarker, endMarker, section string) string {
+	start := strings.Index(prompt, startMarker)
+	end := strings.Index(prompt, endMarker)
+	if start >= 0 && end > start {
+		before := strings.TrimRight(prompt[:start], "\n")
+		after := strings.TrimLeft(prompt[end+len(endMarker):], "\n")
+		prompt = before
+		if after != "" {
+			if prompt != "" {
+				prompt += "\n\n"
+			}
+			prompt += after
+		}
+	}
+	if section == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return section
+	}
+	return strings.TrimRight(prompt, "\n") + "\n\n" + section
+}
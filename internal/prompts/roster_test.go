@@ -0,0 +1,60 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestFormatRoster(t *testing.T) {
+	agents := map[string]state.Agent{
+		"supervisor": {Type: state.AgentTypeSupervisor},
+		"calm-owl":   {Type: state.AgentTypeWorker, Task: "add dark mode\n\nuse the existing theme hooks"},
+		"brave-fox":  {Type: state.AgentTypeWorker, Task: strings.Repeat("x", 200)},
+	}
+	got := FormatRoster(agents, "calm-owl")
+	want := "- `brave-fox` (worker): " + strings.Repeat("x", maxRosterTask) + "...\n" +
+		"- `calm-owl` (worker) - you: add dark mode\n" +
+		"- `supervisor` (supervisor)\n"
+	if got != want {
+		t.Errorf("FormatRoster() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRosterSection(t *testing.T) {
+	agents := map[string]state.Agent{"calm-owl": {Type: state.AgentTypeWorker, Task: "add dark mode"}}
+
+	section := RosterSection("", agents, "supervisor", "/tmp/ROSTER.md")
+	if !strings.Contains(section, "`calm-owl` (worker): add dark mode") || !strings.Contains(section, "/tmp/ROSTER.md") {
+		t.Errorf("prompt mode section = %q, want the agents and the file", section)
+	}
+
+	section = RosterSection(state.RosterFile, agents, "supervisor", "/tmp/ROSTER.md")
+	if strings.Contains(section, "calm-owl") || !strings.Contains(section, "/tmp/ROSTER.md") {
+		t.Errorf("file mode section = %q, want only the file", section)
+	}
+
+	if section := RosterSection(state.RosterOff, agents, "supervisor", "/tmp/ROSTER.md"); section != "" {
+		t.Errorf("off mode section = %q, want none", section)
+	}
+}
+
+func TestSetRosterSection(t *testing.T) {
+	first := RosterSection("", map[string]state.Agent{"calm-owl": {Type: state.AgentTypeWorker}}, "", "/tmp/ROSTER.md")
+	second := RosterSection("", map[string]state.Agent{"brave-fox": {Type: state.AgentTypeWorker}}, "", "/tmp/ROSTER.md")
+
+	prompt := SetRosterSection("You are a supervisor.\n", first)
+	if prompt != "You are a supervisor.\n\n"+first {
+		t.Errorf("SetRosterSection() appended = %q", prompt)
+	}
+
+	prompt = SetRosterSection(prompt, second)
+	if strings.Contains(prompt, "calm-owl") || !strings.Contains(prompt, "brave-fox") || strings.Count(prompt, rosterStart) != 1 {
+		t.Errorf("SetRosterSection() replaced = %q, want only the new roster", prompt)
+	}
+
+	if prompt = SetRosterSection(prompt, ""); prompt != "You are a supervisor." {
+		t.Errorf("SetRosterSection() removed = %q", prompt)
+	}
+}
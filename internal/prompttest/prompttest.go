@@ -0,0 +1,255 @@
+// Package prompttest renders every agent's prompt the way multiclaude
+// assembles it and checks each one for the instructions agents can't work
+// without, such as how to message other agents and which slash commands
+// exist. Rendered prompts can also be compared with golden files, so a
+// refactor that changes a prompt shows up in review instead of in an agent
+// that quietly stops doing part of its job.
+package prompttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/agents"
+	"github.com/micheal-at/multiclaude/internal/prompts"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/templates"
+)
+
+// CLIDocsPath stands in for the CLI reference file in rendered prompts, which
+// is per repository and would make golden files differ between machines
+const CLIDocsPath = "<cli-docs>"
+
+// TrackModes are the PR tracking modes prompts are rendered with for agents
+// that track PRs
+var TrackModes = []string{string(state.TrackModeAll), string(state.TrackModeAuthor), string(state.TrackModeAssigned)}
+
+// Case is one prompt to render
+type Case struct {
+	// Name identifies the case and names its golden file, e.g.
+	// "supervisor" or "merge-queue-author"
+	Name string
+	// AgentType is set for agents with a built-in prompt
+	AgentType state.AgentType
+	// Definition is the agent definition the prompt is built from, for
+	// agents that have one
+	Definition *agents.Definition
+	// TrackMode is the PR tracking mode, for agents that track PRs
+	TrackMode string
+}
+
+// Requirement is text a prompt must contain
+type Requirement struct {
+	Name string
+	Text string
+	// Own requires the text outside the slash command reference, which
+	// mentions most commands in passing
+	Own bool
+}
+
+// Requirements every prompt must meet
+var (
+	RequireMessaging     = Requirement{Name: "messaging instructions", Text: "multiclaude message", Own: true}
+	RequireSlashCommands = Requirement{Name: "slash commands", Text: "## Slash Commands"}
+	RequireCLIReference  = Requirement{Name: "CLI reference", Text: "## multiclaude CLI"}
+	RequireTrackingMode  = Requirement{Name: "tracking mode", Text: "## PR Tracking Mode"}
+)
+
+// tracksPRs reports whether agents from a definition track PRs, so their
+// prompts carry a tracking mode
+func tracksPRs(definition string) bool {
+	return definition == "merge-queue" || definition == "pr-shepherd"
+}
+
+// Cases returns a case for each built-in prompt and each definition, once per
+// tracking mode for definitions that track PRs
+func Cases(defs []agents.Definition) []Case {
+	cases := []Case{
+		{Name: "supervisor", AgentType: state.AgentTypeSupervisor},
+		{Name: "workspace", AgentType: state.AgentTypeWorkspace},
+		{Name: "solo", AgentType: state.AgentTypeSolo},
+	}
+	for i := range defs {
+		def := &defs[i]
+		if !tracksPRs(def.Name) {
+			cases = append(cases, Case{Name: def.Name, Definition: def})
+			continue
+		}
+		for _, mode := range TrackModes {
+			cases = append(cases, Case{Name: def.Name + "-" + mode, Definition: def, TrackMode: mode})
+		}
+	}
+	return cases
+}
+
+// BuiltinDefinitions returns the agent definitions multiclaude ships, as
+// copied into a new repository
+func BuiltinDefinitions() ([]agents.Definition, error) {
+	names, err := templates.ListAgentTemplates()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	var defs []agents.Definition
+	for _, name := range names {
+		content, err := templates.ReadAgentTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, agents.Definition{Name: strings.TrimSuffix(name, ".md"), Content: string(content)})
+	}
+	return defs, nil
+}
+
+// Render assembles a case's prompt for the repository at repoPath (empty for
+// none) as the CLI writes it for a new agent, with the default prompt budget
+// and template variables
+func Render(c Case, repoPath string) (string, error) {
+	docs := prompts.CLIDocsReference(CLIDocsPath)
+	var prompt string
+	if c.Definition == nil {
+		text, _, err := prompts.GetPromptWithBudget(repoPath, c.AgentType, docs, state.PromptBudget{})
+		if err != nil {
+			return "", err
+		}
+		prompt = text
+	} else {
+		prompt, _ = prompts.Assemble([]prompts.Section{
+			{Name: prompts.SectionBase, Text: c.Definition.Content},
+			{Name: prompts.SectionDocs, Text: docs},
+			{Name: prompts.SectionCommands, Text: prompts.GetRepoSlashCommandsPrompt(repoPath)},
+		}, state.PromptBudget{})
+		if c.TrackMode != "" {
+			prompt = prompts.GenerateTrackingModePrompt(c.TrackMode) + "\n\n" + prompt
+		}
+	}
+	return prompts.ExpandTemplateVars(prompt, prompts.TemplateVars{}), nil
+}
+
+// Required returns the requirements a case's prompt must meet
+func Required(c Case) []Requirement {
+	required := []Requirement{RequireMessaging, RequireSlashCommands, RequireCLIReference}
+	if c.TrackMode != "" {
+		required = append(required, RequireTrackingMode)
+	}
+	return required
+}
+
+// Missing returns the names of the requirements a prompt rendered for the
+// repository at repoPath doesn't meet
+func Missing(c Case, prompt, repoPath string) []string {
+	commands := prompts.ExpandTemplateVars(prompts.GetRepoSlashCommandsPrompt(repoPath), prompts.TemplateVars{})
+	own := strings.Replace(prompt, commands, "", 1)
+	var missing []string
+	for _, req := range Required(c) {
+		text := prompt
+		if req.Own {
+			text = own
+		}
+		if !strings.Contains(text, req.Text) {
+			missing = append(missing, req.Name)
+		}
+	}
+	return missing
+}
+
+// GoldenPath is where a case's golden file lives in dir
+func GoldenPath(dir string, c Case) string {
+	return filepath.Join(dir, c.Name+".golden.md")
+}
+
+// Golden outcomes
+const (
+	GoldenMatch   = "match"
+	GoldenDiffers = "differs"
+	GoldenMissing = "missing"
+	GoldenUpdated = "updated"
+)
+
+// Result is the outcome of testing one case
+type Result struct {
+	Case Case
+	// Missing names the requirements the prompt doesn't meet
+	Missing []string
+	// Golden is the golden file comparison, empty when there is none
+	Golden string
+	// Diff describes the first difference from the golden file
+	Diff string
+}
+
+// OK reports whether the case passed
+func (r Result) OK() bool {
+	return len(r.Missing) == 0 && (r.Golden == "" || r.Golden == GoldenMatch || r.Golden == GoldenUpdated)
+}
+
+// Run renders and checks every case. With a goldenDir it also compares each
+// prompt with its golden file, or rewrites the golden files if update is set.
+func Run(cases []Case, repoPath, goldenDir string, update bool) ([]Result, error) {
+	if update && goldenDir != "" {
+		if err := os.MkdirAll(goldenDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []Result
+	for _, c := range cases {
+		prompt, err := Render(c, repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		result := Result{Case: c, Missing: Missing(c, prompt, repoPath)}
+		if goldenDir != "" {
+			if err := compareGolden(&result, GoldenPath(goldenDir, c), prompt, update); err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Name, err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// compareGolden compares a prompt with its golden file, or rewrites it
+func compareGolden(result *Result, path, prompt string, update bool) error {
+	if update {
+		result.Golden = GoldenUpdated
+		return os.WriteFile(path, []byte(prompt), 0644)
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		result.Golden = GoldenMissing
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(want) == prompt {
+		result.Golden = GoldenMatch
+		return nil
+	}
+	result.Golden = GoldenDiffers
+	result.Diff = FirstDiff(string(want), prompt)
+	return nil
+}
+
+// FirstDiff describes the first line where got differs from want
+func FirstDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i >= len(wantLines) || i >= len(gotLines) || w != g {
+			return fmt.Sprintf("line %d: golden %q, rendered %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package prompttest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/agents"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestBuiltinPrompts guards the prompts multiclaude ships. After an
+// intended prompt change, run: go test ./internal/prompttest -update
+func TestBuiltinPrompts(t *testing.T) {
+	defs, err := BuiltinDefinitions()
+	if err != nil {
+		t.Fatalf("BuiltinDefinitions() failed: %v", err)
+	}
+	results, err := Run(Cases(defs), "", "testdata", *update)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	for _, r := range results {
+		if len(r.Missing) > 0 {
+			t.Errorf("%s: prompt is missing %v", r.Case.Name, r.Missing)
+		}
+		switch r.Golden {
+		case GoldenMissing:
+			t.Errorf("%s: no golden file; run go test ./internal/prompttest -update", r.Case.Name)
+		case GoldenDiffers:
+			t.Errorf("%s: prompt differs from %s at %s; if intended, run go test ./internal/prompttest -update", r.Case.Name, GoldenPath("testdata", r.Case), r.Diff)
+		}
+	}
+}
+
+func TestCases(t *testing.T) {
+	cases := Cases([]agents.Definition{{Name: "worker"}, {Name: "merge-queue"}})
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name)
+	}
+	want := []string{"supervisor", "workspace", "solo", "worker", "merge-queue-all", "merge-queue-author", "merge-queue-assigned"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Cases() = %v, want %v", names, want)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	def := &agents.Definition{Name: "merge-queue", Content: "You merge PRs. Say nothing to anyone."}
+	c := Case{Name: "merge-queue-all", Definition: def, TrackMode: "all"}
+	prompt, err := Render(c, "")
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if got := Missing(c, prompt, ""); !reflect.DeepEqual(got, []string{RequireMessaging.Name}) {
+		t.Errorf("Missing() = %v, want only %q", got, RequireMessaging.Name)
+	}
+
+	// Dropping the tracking mode is caught too
+	if got := Missing(c, "multiclaude message send\n## Slash Commands\n## multiclaude CLI", ""); !reflect.DeepEqual(got, []string{RequireTrackingMode.Name}) {
+		t.Errorf("Missing() = %v, want only %q", got, RequireTrackingMode.Name)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dir := t.TempDir()
+	def := &agents.Definition{Name: "helper", Content: "Use multiclaude message send to report."}
+	cases := []Case{{Name: "helper", Definition: def}}
+
+	results, err := Run(cases, "", dir, false)
+	if err != nil || results[0].Golden != GoldenMissing || results[0].OK() {
+		t.Fatalf("Run() without golden file = %+v, %v; want missing", results, err)
+	}
+
+	if results, err = Run(cases, "", dir, true); err != nil || results[0].Golden != GoldenUpdated || !results[0].OK() {
+		t.Fatalf("Run(update) = %+v, %v; want updated", results, err)
+	}
+	if results, err = Run(cases, "", dir, false); err != nil || results[0].Golden != GoldenMatch {
+		t.Fatalf("Run() after update = %+v, %v; want match", results, err)
+	}
+
+	def.Content = "Use multiclaude message send to report often."
+	results, err = Run(cases, "", dir, false)
+	if err != nil || results[0].Golden != GoldenDiffers || results[0].OK() {
+		t.Fatalf("Run() after change = %+v, %v; want differs", results, err)
+	}
+	want := `line 1: golden "Use multiclaude message send to report.", rendered "Use multiclaude message send to report often."`
+	if results[0].Diff != want {
+		t.Errorf("Diff = %q, want %q", results[0].Diff, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "helper.golden.md")); err != nil {
+		t.Errorf("golden file not written: %v", err)
+	}
+}
+
+func TestFirstDiff(t *testing.T) {
+	if got := FirstDiff("a\nb", "a\nb"); got != "" {
+		t.Errorf("FirstDiff(equal) = %q", got)
+	}
+	if got, want := FirstDiff("a\nb", "a"), `line 2: golden "b", rendered ""`; got != want {
+		t.Errorf("FirstDiff() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+// Package pushcheck checks a worker's branch against its repository's push
+// checks before the branch is pushed or its PR marked ready: that it contains
+// the latest base branch, that its commits are signed and that their subject
+// lines match a pattern. Each failure names the commit and how to fix it, so
+// the worker can put it right before CI or a reviewer finds it.
+package pushcheck
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// The checks a failure can come from
+const (
+	Rebased = "rebased"
+	Signed  = "signed"
+	Message = "message"
+)
+
+// Failure is one way a branch fails its push checks
+type Failure struct {
+	Check string
+	// Commit is the short hash of the failing commit; empty for the branch
+	Commit  string
+	Message string
+	Fix     string
+}
+
+func (f Failure) String() string {
+	if f.Commit != "" {
+		return fmt.Sprintf("%s %s: %s", f.Check, f.Commit, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", f.Check, f.Message)
+}
+
+// ValidatePattern checks that a message pattern is a valid regular expression
+func ValidatePattern(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid commit message pattern %q: %v", pattern, err)
+	}
+	return nil
+}
+
+// Run checks the branch checked out in dir against base, such as
+// "origin/main", which should have just been fetched. The commits checked are
+// those in HEAD that aren't in base.
+func Run(ctx context.Context, dir, base string, checks state.PushChecks) ([]Failure, error) {
+	var failures []Failure
+	if checks.Rebased {
+		behind, err := gitcmd.Run(ctx, dir, "rev-list", "--count", "HEAD.."+base)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := strconv.Atoi(behind); n > 0 {
+			failures = append(failures, Failure{
+				Check:   Rebased,
+				Message: fmt.Sprintf("the branch is %d commit(s) behind %s", n, base),
+				Fix:     "git rebase " + base,
+			})
+		}
+	}
+	if !checks.Signed && checks.MessagePattern == "" {
+		return failures, nil
+	}
+
+	var pattern *regexp.Regexp
+	if checks.MessagePattern != "" {
+		if err := ValidatePattern(checks.MessagePattern); err != nil {
+			return nil, err
+		}
+		pattern = regexp.MustCompile(checks.MessagePattern)
+	}
+	// %G? asks git to verify each signature only when signing is required
+	format := "%h%x09%s"
+	if checks.Signed {
+		format = "%h%x09%s%x09%G?"
+	}
+	log, err := gitcmd.Run(ctx, dir, "log", "--reverse", "--format="+format, base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(log, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		hash, subject := fields[0], fields[1]
+		if checks.Signed && len(fields) == 3 {
+			if problem := signatureProblem(fields[2]); problem != "" {
+				failures = append(failures, Failure{
+					Check:   Signed,
+					Commit:  hash,
+					Message: problem,
+					Fix:     fmt.Sprintf("git rebase --exec 'git commit --amend --no-edit -S' %s", base),
+				})
+			}
+		}
+		if pattern != nil && !pattern.MatchString(subject) {
+			failures = append(failures, Failure{
+				Check:   Message,
+				Commit:  hash,
+				Message: fmt.Sprintf("subject %q does not match %s", subject, checks.MessagePattern),
+				Fix:     "reword the commit: git commit --amend for the last one, or git rebase -i " + base,
+			})
+		}
+	}
+	return failures, nil
+}
+
+// signatureProblem describes what's wrong with a commit's signature, from
+// git's %G? code, or returns "" when it's signed. A signature git can't check
+// for want of the key still counts: the commit was signed.
+func signatureProblem(code string) string {
+	switch code {
+	case "N":
+		return "not signed"
+	case "B":
+		return "bad signature"
+	case "R":
+		return "signed with a revoked key"
+	}
+	return ""
+}
+
+// Report describes failures for the worker: one line each, then how to fix them
+func Report(failures []Failure) string {
+	var b strings.Builder
+	b.WriteString("Push checks failed:\n")
+	fixes := make(map[string]bool)
+	var fixList []string
+	for _, f := range failures {
+		fmt.Fprintf(&b, "- %s\n", f)
+		if f.Fix != "" && !fixes[f.Fix] {
+			fixes[f.Fix] = true
+			fixList = append(fixList, f.Fix)
+		}
+	}
+	if len(fixList) > 0 {
+		b.WriteString("To fix:\n")
+		for _, fix := range fixList {
+			fmt.Fprintf(&b, "- %s\n", fix)
+		}
+	}
+	b.WriteString("Then push again; a branch rewritten after it was pushed needs git push --force-with-lease.")
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package pushcheck
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	run("commit", "-q", "--allow-empty", "-m", "init")
+	run("branch", "base")
+	run("commit", "-q", "--allow-empty", "-m", "feat: add a thing")
+	run("commit", "-q", "--allow-empty", "-m", "fixed stuff")
+
+	ctx := context.Background()
+	if failures, err := Run(ctx, dir, "base", state.PushChecks{}); err != nil || len(failures) != 0 {
+		t.Errorf("Run() with no checks = %v, %v; want nothing", failures, err)
+	}
+
+	failures, err := Run(ctx, dir, "base", state.PushChecks{Rebased: true, Signed: true, MessagePattern: `^(feat|fix): `})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	var got []string
+	for _, f := range failures {
+		got = append(got, f.Check)
+	}
+	// Both commits are unsigned, and the second's subject doesn't match
+	if strings.Join(got, ",") != "signed,signed,message" {
+		t.Fatalf("Run() failures = %v", failures)
+	}
+	if !strings.Contains(failures[2].Message, `"fixed stuff"`) || failures[2].Commit == "" {
+		t.Errorf("message failure = %+v, want the commit and its subject", failures[2])
+	}
+
+	// The base moves on: the branch is behind it
+	run("checkout", "-q", "base")
+	run("commit", "-q", "--allow-empty", "-m", "upstream change")
+	run("checkout", "-q", "-")
+	failures, err = Run(ctx, dir, "base", state.PushChecks{Rebased: true})
+	if err != nil || len(failures) != 1 || failures[0].Check != Rebased || !strings.Contains(failures[0].Message, "1 commit(s) behind base") {
+		t.Errorf("Run() behind base = %v, %v", failures, err)
+	}
+
+	if _, err := Run(ctx, dir, "base", state.PushChecks{MessagePattern: "("}); err == nil {
+		t.Error("Run() with an invalid pattern should fail")
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := Report([]Failure{
+		{Check: Rebased, Message: "the branch is 2 commit(s) behind origin/main", Fix: "git rebase origin/main"},
+		{Check: Signed, Commit: "abc1234", Message: "not signed", Fix: "re-sign"},
+		{Check: Signed, Commit: "def5678", Message: "not signed", Fix: "re-sign"},
+	})
+	for _, want := range []string{"- rebased: the branch is 2", "- signed abc1234: not signed", "- git rebase origin/main\n"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report() missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Count(report, "re-sign") != 1 {
+		t.Errorf("Report() repeats a fix:\n%s", report)
+	}
+}
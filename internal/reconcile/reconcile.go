@@ -0,0 +1,488 @@
+// Package reconcile compares recorded state against live resources.
+//
+// A Plan lists every discrepancy between the agents in state.json, the tmux
+// windows that actually exist, the worktrees on disk and in git, the message
+// directories and the agents' prompt files. Each discrepancy can then be resolved individually by adopting
+// the live resource into state, deleting it, or recreating what is missing.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/notify"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/worktree"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// Kind identifies the type of discrepancy
+type Kind string
+
+const (
+	// KindMissingSession is an agent whose repository tmux session is gone
+	KindMissingSession Kind = "missing-session"
+	// KindMissingWindow is an agent whose tmux window is gone
+	KindMissingWindow Kind = "missing-window"
+	// KindMissingWorktree is an agent whose worktree directory is gone
+	KindMissingWorktree Kind = "missing-worktree"
+	// KindOrphanWindow is a tmux window with no agent in state
+	KindOrphanWindow Kind = "orphan-window"
+	// KindOrphanWorktree is a worktree directory with no agent in state
+	KindOrphanWorktree Kind = "orphan-worktree"
+	// KindOrphanMessages is a message directory with no agent in state
+	KindOrphanMessages Kind = "orphan-messages"
+	// KindUnregisteredWorktree is an agent's worktree directory that git does not know about
+	KindUnregisteredWorktree Kind = "unregistered-worktree"
+	// KindStaleRegistration is a git worktree registration whose directory is gone
+	KindStaleRegistration Kind = "stale-registration"
+	// KindMissingPrompt is an agent whose prompt file is gone
+	KindMissingPrompt Kind = "missing-prompt"
+)
+
+// Action is a resolution for a discrepancy
+type Action string
+
+const (
+	// ActionAdopt records a live resource in state
+	ActionAdopt Action = "adopt"
+	// ActionDelete removes the agent from state or the orphaned resource from disk/tmux
+	ActionDelete Action = "delete"
+	// ActionRecreate rebuilds the missing resource for an agent in state
+	ActionRecreate Action = "recreate"
+	// ActionSkip leaves the discrepancy alone
+	ActionSkip Action = "skip"
+)
+
+// Discrepancy is a single mismatch between state and live resources
+type Discrepancy struct {
+	Repo    string   `json:"repo"`
+	Agent   string   `json:"agent"`
+	Kind    Kind     `json:"kind"`
+	Detail  string   `json:"detail"`
+	Actions []Action `json:"actions"`
+}
+
+// Allows reports whether action is a valid resolution for the discrepancy
+func (d Discrepancy) Allows(action Action) bool {
+	if action == ActionSkip {
+		return true
+	}
+	for _, a := range d.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Tmux is the subset of the tmux client used for reconciliation
+type Tmux interface {
+	HasSession(ctx context.Context, name string) (bool, error)
+	ListWindows(ctx context.Context, session string) ([]string, error)
+	KillWindow(ctx context.Context, session, windowName string) error
+	GetPanePID(ctx context.Context, session, windowName string) (int, error)
+}
+
+// Env is everything needed to plan and apply a reconciliation
+type Env struct {
+	State *state.State
+	Tmux  Tmux
+	Paths *config.Paths
+
+	// RecreateAgent restarts an agent whose tmux session or window is gone.
+	// When nil (e.g. the daemon is not running) recreate is not offered for
+	// missing sessions and windows.
+	RecreateAgent func(repoName, agentName string) error
+
+	// WritePrompt regenerates an agent's prompt file. When nil, missing
+	// prompt files are reported but can only be skipped; they are also
+	// regenerated whenever the agent restarts.
+	WritePrompt func(repoName, agentName string) error
+}
+
+// PromptFile returns the path of an agent's prompt file
+func PromptFile(paths *config.Paths, agentName string) string {
+	return filepath.Join(paths.Root, "prompts", agentName+".md")
+}
+
+// Plan returns every discrepancy between state and live resources, sorted by repo and agent
+func Plan(ctx context.Context, env Env) ([]Discrepancy, error) {
+	var plan []Discrepancy
+	repos := env.State.GetAllRepos()
+
+	repoNames := make([]string, 0, len(repos))
+	for name := range repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+
+	for _, repoName := range repoNames {
+		repo := repos[repoName]
+
+		hasSession, err := env.Tmux.HasSession(ctx, repo.TmuxSession)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check session %s: %w", repo.TmuxSession, err)
+		}
+
+		windows := make(map[string]bool)
+		if hasSession {
+			names, err := env.Tmux.ListWindows(ctx, repo.TmuxSession)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list windows for %s: %w", repo.TmuxSession, err)
+			}
+			for _, name := range names {
+				windows[name] = true
+			}
+		}
+
+		// A repository that isn't a git checkout (yet) has no registrations to check
+		registered, registeredOK := env.registeredWorktrees(repoName)
+
+		agentNames := make([]string, 0, len(repo.Agents))
+		for agentName := range repo.Agents {
+			agentNames = append(agentNames, agentName)
+		}
+		sort.Strings(agentNames)
+
+		agentWindows := make(map[string]bool)
+		agentWorktrees := make(map[string]bool)
+		for _, agentName := range agentNames {
+			agent := repo.Agents[agentName]
+			agentWindows[agent.TmuxWindow] = true
+			if agent.WorktreePath != "" {
+				agentWorktrees[filepath.Clean(agent.WorktreePath)] = true
+				agentWorktrees[resolvePath(agent.WorktreePath)] = true
+			}
+
+			switch {
+			case !hasSession:
+				plan = append(plan, Discrepancy{
+					Repo:    repoName,
+					Agent:   agentName,
+					Kind:    KindMissingSession,
+					Detail:  fmt.Sprintf("tmux session %s not found", repo.TmuxSession),
+					Actions: env.windowActions(),
+				})
+			case !windows[agent.TmuxWindow]:
+				plan = append(plan, Discrepancy{
+					Repo:    repoName,
+					Agent:   agentName,
+					Kind:    KindMissingWindow,
+					Detail:  fmt.Sprintf("tmux window %s not found", agent.TmuxWindow),
+					Actions: env.windowActions(),
+				})
+			}
+
+			if hasWorktree(agent) {
+				if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
+					plan = append(plan, Discrepancy{
+						Repo:    repoName,
+						Agent:   agentName,
+						Kind:    KindMissingWorktree,
+						Detail:  fmt.Sprintf("worktree %s not found", agent.WorktreePath),
+						Actions: []Action{ActionRecreate, ActionDelete},
+					})
+				} else if err == nil && registeredOK && !registered[resolvePath(agent.WorktreePath)] {
+					plan = append(plan, Discrepancy{
+						Repo:    repoName,
+						Agent:   agentName,
+						Kind:    KindUnregisteredWorktree,
+						Detail:  fmt.Sprintf("worktree %s is not registered with git", agent.WorktreePath),
+						Actions: []Action{ActionDelete},
+					})
+				}
+			}
+
+			if _, err := os.Stat(PromptFile(env.Paths, agentName)); os.IsNotExist(err) {
+				plan = append(plan, Discrepancy{
+					Repo:    repoName,
+					Agent:   agentName,
+					Kind:    KindMissingPrompt,
+					Detail:  fmt.Sprintf("prompt file %s not found", PromptFile(env.Paths, agentName)),
+					Actions: env.promptActions(),
+				})
+			}
+		}
+
+		windowNames := make([]string, 0, len(windows))
+		for name := range windows {
+			windowNames = append(windowNames, name)
+		}
+		sort.Strings(windowNames)
+		for _, name := range windowNames {
+			if agentWindows[name] {
+				continue
+			}
+			plan = append(plan, Discrepancy{
+				Repo:    repoName,
+				Agent:   name,
+				Kind:    KindOrphanWindow,
+				Detail:  fmt.Sprintf("tmux window %s:%s has no agent in state", repo.TmuxSession, name),
+				Actions: []Action{ActionAdopt, ActionDelete},
+			})
+		}
+
+		wtDir := env.Paths.WorktreeDir(repoName)
+		for _, name := range listDirs(wtDir) {
+			path := filepath.Join(wtDir, name)
+			if agentWorktrees[path] {
+				continue
+			}
+			actions := []Action{ActionDelete}
+			if windows[name] && !agentWindows[name] {
+				// Adopting the window picks this worktree up as well
+				actions = []Action{ActionAdopt, ActionDelete}
+			}
+			plan = append(plan, Discrepancy{
+				Repo:    repoName,
+				Agent:   name,
+				Kind:    KindOrphanWorktree,
+				Detail:  fmt.Sprintf("worktree %s has no agent in state", path),
+				Actions: actions,
+			})
+		}
+
+		wtRoot := resolvePath(wtDir)
+		registeredPaths := make([]string, 0, len(registered))
+		for path := range registered {
+			registeredPaths = append(registeredPaths, path)
+		}
+		sort.Strings(registeredPaths)
+		for _, path := range registeredPaths {
+			if filepath.Dir(path) != wtRoot || agentWorktrees[path] {
+				continue
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				continue
+			}
+			plan = append(plan, Discrepancy{
+				Repo:    repoName,
+				Agent:   filepath.Base(path),
+				Kind:    KindStaleRegistration,
+				Detail:  fmt.Sprintf("git still registers missing worktree %s", path),
+				Actions: []Action{ActionDelete},
+			})
+		}
+
+		inboxes, _ := messages.NewManager(env.Paths.MessagesDir).Inboxes(repoName)
+		for _, name := range inboxes {
+			if _, ok := repo.Agents[name]; ok || name == notify.HumanRecipient {
+				continue
+			}
+			plan = append(plan, Discrepancy{
+				Repo:    repoName,
+				Agent:   name,
+				Kind:    KindOrphanMessages,
+				Detail:  fmt.Sprintf("message directory for %s has no agent in state", name),
+				Actions: []Action{ActionDelete},
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply resolves a single discrepancy with the chosen action
+func Apply(ctx context.Context, env Env, d Discrepancy, action Action) error {
+	if !d.Allows(action) {
+		return fmt.Errorf("%s is not a valid action for %s", action, d.Kind)
+	}
+	if action == ActionSkip {
+		return nil
+	}
+
+	switch d.Kind {
+	case KindMissingSession, KindMissingWindow:
+		if action == ActionDelete {
+			return env.State.RemoveAgent(d.Repo, d.Agent)
+		}
+		return env.RecreateAgent(d.Repo, d.Agent)
+
+	case KindMissingWorktree:
+		if action == ActionDelete {
+			return env.State.RemoveAgent(d.Repo, d.Agent)
+		}
+		return env.recreateWorktree(d.Repo, d.Agent)
+
+	case KindOrphanWindow:
+		if action == ActionDelete {
+			repo, ok := env.State.GetRepo(d.Repo)
+			if !ok {
+				return fmt.Errorf("repository %s not found", d.Repo)
+			}
+			return env.Tmux.KillWindow(ctx, repo.TmuxSession, d.Agent)
+		}
+		return env.adoptWindow(ctx, d.Repo, d.Agent)
+
+	case KindOrphanWorktree:
+		if action == ActionDelete {
+			return env.removeWorktree(d.Repo, d.Agent)
+		}
+		return env.adoptWindow(ctx, d.Repo, d.Agent)
+
+	case KindOrphanMessages:
+		return messages.NewManager(env.Paths.MessagesDir).DeleteInbox(d.Repo, d.Agent)
+
+	case KindUnregisteredWorktree:
+		return env.State.RemoveAgent(d.Repo, d.Agent)
+
+	case KindStaleRegistration:
+		return worktree.NewManager(env.Paths.RepoDir(d.Repo)).Prune()
+
+	case KindMissingPrompt:
+		return env.WritePrompt(d.Repo, d.Agent)
+
+	default:
+		return fmt.Errorf("unknown discrepancy kind %q", d.Kind)
+	}
+}
+
+// windowActions returns the resolutions offered for a missing session or window
+func (env Env) windowActions() []Action {
+	if env.RecreateAgent == nil {
+		return []Action{ActionDelete}
+	}
+	return []Action{ActionRecreate, ActionDelete}
+}
+
+// promptActions returns the resolutions offered for a missing prompt file
+func (env Env) promptActions() []Action {
+	if env.WritePrompt == nil {
+		return []Action{}
+	}
+	return []Action{ActionRecreate}
+}
+
+// registeredWorktrees returns the resolved paths of a repository's git
+// worktrees. ok is false if they can't be listed.
+func (env Env) registeredWorktrees(repoName string) (paths map[string]bool, ok bool) {
+	repoDir := env.Paths.RepoDir(repoName)
+	if _, err := os.Stat(filepath.Join(repoDir, ".git")); err != nil {
+		return nil, false
+	}
+	list, err := worktree.NewManager(repoDir).List()
+	if err != nil {
+		return nil, false
+	}
+	paths = make(map[string]bool, len(list))
+	for _, wt := range list {
+		paths[resolvePath(wt.Path)] = true
+	}
+	return paths, true
+}
+
+// resolvePath cleans a path and resolves its symlinks (or its parent's, if
+// it doesn't exist) so paths from state and from git compare equal
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(parent, filepath.Base(path))
+	}
+	return filepath.Clean(path)
+}
+
+// adoptWindow records an orphaned tmux window as a worker agent
+func (env Env) adoptWindow(ctx context.Context, repoName, windowName string) error {
+	repo, ok := env.State.GetRepo(repoName)
+	if !ok {
+		return fmt.Errorf("repository %s not found", repoName)
+	}
+	if agent, exists := repo.Agents[windowName]; exists {
+		if agent.TmuxWindow == windowName {
+			// Already adopted (window and worktree are offered separately)
+			return nil
+		}
+		return fmt.Errorf("agent %s already exists in state", windowName)
+	}
+
+	workDir := filepath.Join(env.Paths.WorktreeDir(repoName), windowName)
+	if _, err := os.Stat(workDir); err != nil {
+		workDir = env.Paths.RepoDir(repoName)
+	}
+
+	pid, err := env.Tmux.GetPanePID(ctx, repo.TmuxSession, windowName)
+	if err != nil {
+		return fmt.Errorf("failed to adopt window %s: %w", windowName, err)
+	}
+
+	return env.State.AddAgent(repoName, windowName, state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: workDir,
+		TmuxWindow:   windowName,
+		PID:          pid,
+		CreatedAt:    time.Now(),
+	})
+}
+
+// recreateWorktree rebuilds an agent's missing worktree on its work branch
+func (env Env) recreateWorktree(repoName, agentName string) error {
+	agent, ok := env.State.GetAgent(repoName, agentName)
+	if !ok {
+		return fmt.Errorf("agent %s not found in repository %s", agentName, repoName)
+	}
+
+	wt := worktree.NewManager(env.Paths.RepoDir(repoName))
+	if err := wt.Prune(); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+
+	branch := agent.Branch
+	if branch == "" {
+		branch = "work/" + agentName
+	}
+	exists, err := wt.BranchExists(branch)
+	if err != nil {
+		return fmt.Errorf("failed to check branch %s: %w", branch, err)
+	}
+	if exists {
+		return wt.Create(agent.WorktreePath, branch)
+	}
+	return wt.CreateNewBranch(agent.WorktreePath, branch, "HEAD")
+}
+
+// removeWorktree deletes an orphaned worktree directory and its git registration
+func (env Env) removeWorktree(repoName, name string) error {
+	path := filepath.Join(env.Paths.WorktreeDir(repoName), name)
+	wt := worktree.NewManager(env.Paths.RepoDir(repoName))
+	if err := wt.Remove(path, true); err == nil {
+		return nil
+	}
+	// Not a registered worktree (or the repo is gone) - remove the directory directly
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	_ = wt.Prune()
+	return nil
+}
+
+// hasWorktree reports whether an agent is expected to own a worktree
+func hasWorktree(agent state.Agent) bool {
+	if agent.WorktreePath == "" {
+		return false
+	}
+	return agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview
+}
+
+// listDirs returns the sorted names of subdirectories of dir
+func listDirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
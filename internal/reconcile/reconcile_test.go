@@ -0,0 +1,362 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// fakeTmux is an in-memory Tmux keyed by session name
+type fakeTmux struct {
+	sessions map[string][]string
+	killed   []string
+}
+
+func (f *fakeTmux) HasSession(ctx context.Context, name string) (bool, error) {
+	_, ok := f.sessions[name]
+	return ok, nil
+}
+
+func (f *fakeTmux) ListWindows(ctx context.Context, session string) ([]string, error) {
+	return f.sessions[session], nil
+}
+
+func (f *fakeTmux) KillWindow(ctx context.Context, session, windowName string) error {
+	f.killed = append(f.killed, session+":"+windowName)
+	return nil
+}
+
+func (f *fakeTmux) GetPanePID(ctx context.Context, session, windowName string) (int, error) {
+	return 4242, nil
+}
+
+// setupEnv creates a repo "my-repo" whose state has a supervisor and two
+// workers, while tmux has the supervisor, one worker and an unknown window.
+func setupEnv(t *testing.T) (Env, *fakeTmux) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	paths := config.NewTestPaths(tmpDir)
+	st := state.New(paths.StateFile)
+
+	if err := st.AddRepo("my-repo", &state.Repository{
+		TmuxSession: "mc-my-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	wtDir := paths.WorktreeDir("my-repo")
+	agents := map[string]state.Agent{
+		"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", WorktreePath: paths.RepoDir("my-repo")},
+		"live-fox":   {Type: state.AgentTypeWorker, TmuxWindow: "live-fox", WorktreePath: filepath.Join(wtDir, "live-fox")},
+		"gone-owl":   {Type: state.AgentTypeWorker, TmuxWindow: "gone-owl", WorktreePath: filepath.Join(wtDir, "gone-owl")},
+	}
+	for name, agent := range agents {
+		agent.CreatedAt = time.Now()
+		if err := st.AddAgent("my-repo", name, agent); err != nil {
+			t.Fatalf("AddAgent(%s) failed: %v", name, err)
+		}
+	}
+
+	for _, dir := range []string{
+		filepath.Join(wtDir, "live-fox"),
+		filepath.Join(wtDir, "stray-cat"),
+		filepath.Join(paths.MessagesDir, "my-repo", "live-fox"),
+		filepath.Join(paths.MessagesDir, "my-repo", "old-bee"),
+		filepath.Join(paths.MessagesDir, "my-repo", "human"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	for name := range agents {
+		if err := os.MkdirAll(filepath.Dir(PromptFile(paths, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(PromptFile(paths, name), []byte("prompt"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tm := &fakeTmux{sessions: map[string][]string{
+		"mc-my-repo": {"supervisor", "live-fox", "stray-cat"},
+	}}
+	return Env{State: st, Tmux: tm, Paths: paths}, tm
+}
+
+func planKeys(plan []Discrepancy) map[string]Discrepancy {
+	keys := make(map[string]Discrepancy, len(plan))
+	for _, d := range plan {
+		keys[fmt.Sprintf("%s/%s", d.Kind, d.Agent)] = d
+	}
+	return keys
+}
+
+func TestPlan(t *testing.T) {
+	env, _ := setupEnv(t)
+
+	plan, err := Plan(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+
+	keys := planKeys(plan)
+	want := []string{
+		"missing-window/gone-owl",
+		"missing-worktree/gone-owl",
+		"orphan-window/stray-cat",
+		"orphan-worktree/stray-cat",
+		"orphan-messages/old-bee",
+	}
+	for _, key := range want {
+		if _, ok := keys[key]; !ok {
+			t.Errorf("Plan() missing %s, got %+v", key, plan)
+		}
+	}
+	if len(plan) != len(want) {
+		t.Errorf("Plan() returned %d discrepancies, want %d: %+v", len(plan), len(want), plan)
+	}
+
+	// Without a recreate hook, missing windows can only be deleted
+	if d := keys["missing-window/gone-owl"]; d.Allows(ActionRecreate) || !d.Allows(ActionDelete) {
+		t.Errorf("missing-window actions = %v, want delete only", d.Actions)
+	}
+	// Orphan worktrees with a matching window can be adopted
+	if d := keys["orphan-worktree/stray-cat"]; !d.Allows(ActionAdopt) {
+		t.Errorf("orphan-worktree actions = %v, want adopt allowed", d.Actions)
+	}
+}
+
+func TestPlanPromptsAndRegistrations(t *testing.T) {
+	env, tm := setupEnv(t)
+	tm.sessions["mc-my-repo"] = []string{"supervisor", "live-fox", "gone-owl"}
+
+	// Make the repository a git checkout: live-fox's worktree is a plain
+	// directory git doesn't know about, and gone-owl's registration is stale
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-C", env.Paths.RepoDir("my-repo"), "-c", "user.email=t@example.com", "-c", "user.name=t"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.MkdirAll(env.Paths.RepoDir("my-repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q", "-b", "main")
+	git("commit", "-q", "--allow-empty", "-m", "init")
+	wtDir := env.Paths.WorktreeDir("my-repo")
+	git("worktree", "add", "-q", "-b", "work/ghost-elk", filepath.Join(wtDir, "ghost-elk"))
+	if err := os.RemoveAll(filepath.Join(wtDir, "ghost-elk")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(PromptFile(env.Paths, "live-fox")); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := Plan(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	keys := planKeys(plan)
+	for _, key := range []string{"unregistered-worktree/live-fox", "stale-registration/ghost-elk", "missing-prompt/live-fox"} {
+		if _, ok := keys[key]; !ok {
+			t.Errorf("Plan() missing %s, got %+v", key, plan)
+		}
+	}
+	// Without a prompt hook, missing prompts can only be skipped
+	if d := keys["missing-prompt/live-fox"]; len(d.Actions) != 0 {
+		t.Errorf("missing-prompt actions = %v, want none", d.Actions)
+	}
+
+	if err := Apply(context.Background(), env, keys["stale-registration/ghost-elk"], ActionDelete); err != nil {
+		t.Fatalf("Apply(stale-registration) failed: %v", err)
+	}
+	var written []string
+	env.WritePrompt = func(repoName, agentName string) error {
+		written = append(written, repoName+"/"+agentName)
+		return nil
+	}
+	plan, err = Plan(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	keys = planKeys(plan)
+	if _, ok := keys["stale-registration/ghost-elk"]; ok {
+		t.Error("stale registration should be pruned")
+	}
+	if err := Apply(context.Background(), env, keys["missing-prompt/live-fox"], ActionRecreate); err != nil {
+		t.Fatalf("Apply(missing-prompt) failed: %v", err)
+	}
+	if len(written) != 1 || written[0] != "my-repo/live-fox" {
+		t.Errorf("written = %v, want [my-repo/live-fox]", written)
+	}
+}
+
+func TestPlanMissingSession(t *testing.T) {
+	env, tm := setupEnv(t)
+	delete(tm.sessions, "mc-my-repo")
+	env.RecreateAgent = func(repoName, agentName string) error { return nil }
+
+	plan, err := Plan(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+
+	count := 0
+	for _, d := range plan {
+		if d.Kind == KindMissingSession {
+			count++
+			if !d.Allows(ActionRecreate) {
+				t.Errorf("missing-session for %s should allow recreate", d.Agent)
+			}
+		}
+		if d.Kind == KindOrphanWindow {
+			t.Errorf("no orphan windows expected without a session, got %+v", d)
+		}
+	}
+	if count != 3 {
+		t.Errorf("got %d missing-session discrepancies, want 3", count)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		action Action
+		check  func(t *testing.T, env Env, tm *fakeTmux)
+	}{
+		{
+			name:   "delete missing window removes agent",
+			key:    "missing-window/gone-owl",
+			action: ActionDelete,
+			check: func(t *testing.T, env Env, tm *fakeTmux) {
+				if _, ok := env.State.GetAgent("my-repo", "gone-owl"); ok {
+					t.Error("agent should be removed from state")
+				}
+			},
+		},
+		{
+			name:   "adopt orphan window adds worker",
+			key:    "orphan-window/stray-cat",
+			action: ActionAdopt,
+			check: func(t *testing.T, env Env, tm *fakeTmux) {
+				agent, ok := env.State.GetAgent("my-repo", "stray-cat")
+				if !ok {
+					t.Fatal("agent should be adopted into state")
+				}
+				if agent.Type != state.AgentTypeWorker || agent.PID != 4242 {
+					t.Errorf("adopted agent = %+v", agent)
+				}
+				if agent.WorktreePath != filepath.Join(env.Paths.WorktreeDir("my-repo"), "stray-cat") {
+					t.Errorf("adopted worktree = %s, want existing worktree dir", agent.WorktreePath)
+				}
+			},
+		},
+		{
+			name:   "delete orphan window kills it",
+			key:    "orphan-window/stray-cat",
+			action: ActionDelete,
+			check: func(t *testing.T, env Env, tm *fakeTmux) {
+				if len(tm.killed) != 1 || tm.killed[0] != "mc-my-repo:stray-cat" {
+					t.Errorf("killed = %v", tm.killed)
+				}
+			},
+		},
+		{
+			name:   "delete orphan messages removes dir",
+			key:    "orphan-messages/old-bee",
+			action: ActionDelete,
+			check: func(t *testing.T, env Env, tm *fakeTmux) {
+				if _, err := os.Stat(filepath.Join(env.Paths.MessagesDir, "my-repo", "old-bee")); !os.IsNotExist(err) {
+					t.Error("message dir should be removed")
+				}
+			},
+		},
+		{
+			name:   "delete orphan worktree removes dir",
+			key:    "orphan-worktree/stray-cat",
+			action: ActionDelete,
+			check: func(t *testing.T, env Env, tm *fakeTmux) {
+				if _, err := os.Stat(filepath.Join(env.Paths.WorktreeDir("my-repo"), "stray-cat")); !os.IsNotExist(err) {
+					t.Error("worktree dir should be removed")
+				}
+			},
+		},
+		{
+			name:   "skip changes nothing",
+			key:    "missing-window/gone-owl",
+			action: ActionSkip,
+			check: func(t *testing.T, env Env, tm *fakeTmux) {
+				if _, ok := env.State.GetAgent("my-repo", "gone-owl"); !ok {
+					t.Error("skipped agent should remain in state")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, tm := setupEnv(t)
+			plan, err := Plan(context.Background(), env)
+			if err != nil {
+				t.Fatalf("Plan() failed: %v", err)
+			}
+			d, ok := planKeys(plan)[tt.key]
+			if !ok {
+				t.Fatalf("plan missing %s", tt.key)
+			}
+			if err := Apply(context.Background(), env, d, tt.action); err != nil {
+				t.Fatalf("Apply() failed: %v", err)
+			}
+			tt.check(t, env, tm)
+		})
+	}
+}
+
+func TestApplyRejectsDisallowedAction(t *testing.T) {
+	env, _ := setupEnv(t)
+	plan, err := Plan(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+
+	d := planKeys(plan)["missing-window/gone-owl"]
+	if err := Apply(context.Background(), env, d, ActionRecreate); err == nil {
+		t.Error("Apply() should reject recreate when no recreate hook is set")
+	}
+	d = planKeys(plan)["orphan-messages/old-bee"]
+	if err := Apply(context.Background(), env, d, ActionAdopt); err == nil {
+		t.Error("Apply() should reject adopt for orphan messages")
+	}
+}
+
+func TestApplyRecreateUsesHook(t *testing.T) {
+	env, _ := setupEnv(t)
+	var recreated []string
+	env.RecreateAgent = func(repoName, agentName string) error {
+		recreated = append(recreated, repoName+"/"+agentName)
+		return nil
+	}
+
+	plan, err := Plan(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if err := Apply(context.Background(), env, planKeys(plan)["missing-window/gone-owl"], ActionRecreate); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if len(recreated) != 1 || recreated[0] != "my-repo/gone-owl" {
+		t.Errorf("recreated = %v, want [my-repo/gone-owl]", recreated)
+	}
+}
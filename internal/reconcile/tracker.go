@@ -0,0 +1,112 @@
+package reconcile
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConfirmChecks is how many checks in a row must find a discrepancy before it
+// counts as an inconsistency. Creating or removing an agent briefly leaves
+// state and live resources out of step (the worktree and window exist before
+// the agent is recorded), so a single sighting is not reported.
+const ConfirmChecks = 2
+
+// Inconsistency is a discrepancy the tracker has seen
+type Inconsistency struct {
+	Discrepancy
+	FirstSeen time.Time `json:"first_seen"`
+	// Checks is how many checks in a row have found it
+	Checks int `json:"checks"`
+}
+
+// Confirmed reports whether the discrepancy has been seen often enough to report
+func (i Inconsistency) Confirmed() bool {
+	return i.Checks >= ConfirmChecks
+}
+
+// Tracker follows discrepancies across periodic checks, so the daemon can
+// report the ones that persist and notice when they are resolved. It is safe
+// for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	current map[string]*Inconsistency
+	checked time.Time
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{current: make(map[string]*Inconsistency)}
+}
+
+// key identifies a discrepancy across checks
+func key(d Discrepancy) string {
+	return d.Repo + "/" + d.Agent + "/" + string(d.Kind)
+}
+
+// Observe records the result of a check. It returns the discrepancies that
+// were confirmed by this check and the confirmed ones that are now gone.
+func (t *Tracker) Observe(plan []Discrepancy, now time.Time) (found, resolved []Inconsistency) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[string]bool, len(plan))
+	for _, d := range plan {
+		k := key(d)
+		seen[k] = true
+		inc, ok := t.current[k]
+		if !ok {
+			inc = &Inconsistency{FirstSeen: now}
+			t.current[k] = inc
+		}
+		// Details and actions can change between checks
+		inc.Discrepancy = d
+		inc.Checks++
+		if inc.Checks == ConfirmChecks {
+			found = append(found, *inc)
+		}
+	}
+	for k, inc := range t.current {
+		if seen[k] {
+			continue
+		}
+		if inc.Confirmed() {
+			resolved = append(resolved, *inc)
+		}
+		delete(t.current, k)
+	}
+	t.checked = now
+
+	sortInconsistencies(found)
+	sortInconsistencies(resolved)
+	return found, resolved
+}
+
+// Current returns the discrepancies found by the latest check, sorted by repo
+// and agent, and when that check ran. Unless all is set, only confirmed ones
+// are returned.
+func (t *Tracker) Current(all bool) ([]Inconsistency, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := make([]Inconsistency, 0, len(t.current))
+	for _, inc := range t.current {
+		if all || inc.Confirmed() {
+			list = append(list, *inc)
+		}
+	}
+	sortInconsistencies(list)
+	return list, t.checked
+}
+
+func sortInconsistencies(list []Inconsistency) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Repo != list[j].Repo {
+			return list[i].Repo < list[j].Repo
+		}
+		if list[i].Agent != list[j].Agent {
+			return list[i].Agent < list[j].Agent
+		}
+		return list[i].Kind < list[j].Kind
+	})
+}
@@ -0,0 +1,51 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	start := time.Now()
+	orphan := Discrepancy{Repo: "app", Agent: "stray-cat", Kind: KindOrphanWindow}
+	prompt := Discrepancy{Repo: "app", Agent: "calm-owl", Kind: KindMissingPrompt}
+
+	// A single sighting is not reported
+	found, resolved := tr.Observe([]Discrepancy{orphan}, start)
+	if len(found) != 0 || len(resolved) != 0 {
+		t.Fatalf("first check found %v, resolved %v", found, resolved)
+	}
+	if list, _ := tr.Current(false); len(list) != 0 {
+		t.Errorf("Current(false) = %v after one check", list)
+	}
+	if list, _ := tr.Current(true); len(list) != 1 {
+		t.Errorf("Current(true) = %v, want the unconfirmed orphan", list)
+	}
+
+	found, _ = tr.Observe([]Discrepancy{orphan, prompt}, start.Add(time.Minute))
+	if len(found) != 1 || found[0].Agent != "stray-cat" || !found[0].FirstSeen.Equal(start) {
+		t.Fatalf("second check found %+v, want stray-cat first seen at the start", found)
+	}
+
+	// Found once, not on every later check
+	found, _ = tr.Observe([]Discrepancy{orphan, prompt}, start.Add(2*time.Minute))
+	if len(found) != 1 || found[0].Agent != "calm-owl" {
+		t.Fatalf("third check found %+v, want calm-owl", found)
+	}
+	list, checked := tr.Current(false)
+	if len(list) != 2 || list[0].Agent != "calm-owl" || !checked.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("Current(false) = %+v at %v", list, checked)
+	}
+
+	found, resolved = tr.Observe([]Discrepancy{prompt}, start.Add(3*time.Minute))
+	if len(found) != 0 || len(resolved) != 1 || resolved[0].Agent != "stray-cat" {
+		t.Errorf("fourth check found %+v, resolved %+v; want stray-cat resolved", found, resolved)
+	}
+
+	// Unconfirmed discrepancies disappear without being reported as resolved
+	tr.Observe([]Discrepancy{prompt, orphan}, start.Add(4*time.Minute))
+	if _, resolved := tr.Observe([]Discrepancy{prompt}, start.Add(5*time.Minute)); len(resolved) != 0 {
+		t.Errorf("resolved %+v, want nothing for an unconfirmed discrepancy", resolved)
+	}
+}
@@ -0,0 +1,458 @@
+// Package repoconfig reads and validates .multiclaude/config.yaml, the
+// repository settings a team checks in next to its code. The file format is
+// described by a JSON Schema (see Schema) so CI can reject a malformed file
+// before it breaks agent spawning at runtime.
+package repoconfig
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/branchname"
+	"github.com/micheal-at/multiclaude/internal/federation"
+	"github.com/micheal-at/multiclaude/internal/pushcheck"
+	"gopkg.in/yaml.v3"
+)
+
+// Path is where the config file lives, relative to the repository root
+const Path = ".multiclaude/config.yaml"
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Schema returns the JSON Schema for the config file
+func Schema() []byte {
+	return schemaJSON
+}
+
+// Config is a parsed config file. Unset fields are nil or empty and leave the
+// corresponding repository setting alone.
+type Config struct {
+	DefaultBranch   string            `yaml:"default_branch,omitempty"`
+	BranchTemplate  string            `yaml:"branch_template,omitempty"`
+	MergeQueue      *AgentConfig      `yaml:"merge_queue,omitempty"`
+	PRShepherd      *AgentConfig      `yaml:"pr_shepherd,omitempty"`
+	Notify          *NotifyConfig     `yaml:"notify,omitempty"`
+	Federation      *FederationConfig `yaml:"federation,omitempty"`
+	Zombie          *ZombieConfig     `yaml:"zombie,omitempty"`
+	Worktree        *WorktreeConfig   `yaml:"worktree,omitempty"`
+	Refresh         *RefreshConfig    `yaml:"refresh,omitempty"`
+	Roster          string            `yaml:"roster,omitempty"`
+	DraftPRs        *bool             `yaml:"draft_prs,omitempty"`
+	PRDescriptions  *PRDescriptions   `yaml:"pr_descriptions,omitempty"`
+	PushChecks      *PushChecks       `yaml:"push_checks,omitempty"`
+	ReviewDispatch  string            `yaml:"review_dispatch,omitempty"`
+	MaxWorkers      *int              `yaml:"max_workers,omitempty"`
+	CommentCommands *CommentCommands  `yaml:"comment_commands,omitempty"`
+	SpawnHooks      *SpawnHooks       `yaml:"spawn_hooks,omitempty"`
+	PromptBudget    *PromptBudget     `yaml:"prompt_budget,omitempty"`
+	WorkHours       *WorkHours        `yaml:"work_hours,omitempty"`
+	Routing         []RoutingRule     `yaml:"routing,omitempty"`
+}
+
+// AgentConfig configures the merge queue or PR shepherd agent
+type AgentConfig struct {
+	Enabled *bool  `yaml:"enabled,omitempty"`
+	Track   string `yaml:"track,omitempty"`
+	// TestCommand is the merge train's test command; merge queue only
+	TestCommand string `yaml:"test_command,omitempty"`
+}
+
+// NotifyConfig configures email notifications and alerts for agents
+// waiting on a human
+type NotifyConfig struct {
+	Enabled       *bool    `yaml:"enabled,omitempty"`
+	Method        string   `yaml:"method,omitempty"`
+	To            []string `yaml:"to,omitempty"`
+	From          string   `yaml:"from,omitempty"`
+	SMTP          string   `yaml:"smtp,omitempty"`
+	SMTPUser      string   `yaml:"smtp_user,omitempty"`
+	DigestMinutes *int     `yaml:"digest_minutes,omitempty"`
+	NeedsHuman    []string `yaml:"needs_human,omitempty"`
+	Webhook       string   `yaml:"webhook,omitempty"`
+}
+
+// FederationConfig configures team federation
+type FederationConfig struct {
+	Relay  string `yaml:"relay,omitempty"`
+	Branch string `yaml:"branch,omitempty"`
+	Peer   string `yaml:"peer,omitempty"`
+}
+
+// ZombieConfig configures zombie agent detection
+type ZombieConfig struct {
+	Enabled      *bool  `yaml:"enabled,omitempty"`
+	StallMinutes *int   `yaml:"stall_minutes,omitempty"`
+	Stalled      string `yaml:"stalled,omitempty"`
+	Looping      string `yaml:"looping,omitempty"`
+	Prompt       string `yaml:"prompt,omitempty"`
+}
+
+// WorktreeConfig configures setup steps run in new worktrees
+type WorktreeConfig struct {
+	LFS        *bool `yaml:"lfs,omitempty"`
+	Submodules *bool `yaml:"submodules,omitempty"`
+	Mirror     *bool `yaml:"mirror,omitempty"`
+}
+
+// RefreshConfig configures how the daemon keeps worker branches up to date
+type RefreshConfig struct {
+	Strategy    string `yaml:"strategy,omitempty"`
+	OnlyClean   *bool  `yaml:"only_clean,omitempty"`
+	PauseActive *bool  `yaml:"pause_active,omitempty"`
+}
+
+// SpawnHooks configures shell commands run around starting each worker
+type SpawnHooks struct {
+	PreSpawn  string `yaml:"pre_spawn,omitempty"`
+	PostSpawn string `yaml:"post_spawn,omitempty"`
+}
+
+// PRDescriptions configures the PR descriptions the daemon writes for workers
+type PRDescriptions struct {
+	Enabled  *bool  `yaml:"enabled,omitempty"`
+	Template string `yaml:"template,omitempty"`
+}
+
+// PushChecks configures what workers' branches must pass before they're pushed
+type PushChecks struct {
+	Rebased        *bool  `yaml:"rebased,omitempty"`
+	Signed         *bool  `yaml:"signed,omitempty"`
+	MessagePattern string `yaml:"message_pattern,omitempty"`
+}
+
+// CommentCommands configures who may steer workers from GitHub comments
+type CommentCommands struct {
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// PromptBudget configures the token limits for agent prompts
+type PromptBudget struct {
+	Total    *int `yaml:"total,omitempty"`
+	Base     *int `yaml:"base,omitempty"`
+	Docs     *int `yaml:"docs,omitempty"`
+	Commands *int `yaml:"commands,omitempty"`
+	Custom   *int `yaml:"custom,omitempty"`
+}
+
+// WorkHours configures when the daemon keeps the repository's agents busy
+type WorkHours struct {
+	Hours    string `yaml:"hours,omitempty"`
+	Days     string `yaml:"days,omitempty"`
+	Timezone string `yaml:"timezone,omitempty"`
+}
+
+// RoutingRule copies or reprioritizes the messages it matches
+type RoutingRule struct {
+	Contains string   `yaml:"contains,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	To       string   `yaml:"to,omitempty"`
+	CC       []string `yaml:"cc,omitempty"`
+	Priority string   `yaml:"priority,omitempty"`
+}
+
+// Issue is a problem found in a config file. Line and Column are 1-based;
+// zero means the position is unknown.
+type Issue struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", i.Line, i.Column)
+	}
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// ValidationError lists every issue found in a config file
+type ValidationError struct {
+	File   string
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+		if e.File != "" {
+			lines[i] = e.File + ":" + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Load reads and validates a config file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(data)
+	if verr, ok := err.(*ValidationError); ok {
+		verr.File = path
+	}
+	return cfg, err
+}
+
+// Parse validates config file contents and decodes them. Invalid contents
+// return a *ValidationError.
+func Parse(data []byte) (*Config, error) {
+	if issues := Validate(data); len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, &ValidationError{Issues: []Issue{syntaxIssue(err)}}
+	}
+	return &cfg, nil
+}
+
+// Validate checks config file contents against the schema and returns every
+// issue found, in file order
+func Validate(data []byte) []Issue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Issue{syntaxIssue(err)}
+	}
+	if len(doc.Content) == 0 {
+		// An empty file sets nothing
+		return nil
+	}
+
+	var issues []Issue
+	rootSchema.validate(doc.Content[0], "", &issues)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// checks are validations the schema can't express, keyed by field path
+var checks = map[string]func(string) error{
+	"branch_template":             branchname.Validate,
+	"federation.peer":             federation.ValidatePeerID,
+	"push_checks.message_pattern": pushcheck.ValidatePattern,
+}
+
+// yamlLineRe extracts the line number from yaml.v3 syntax errors
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func syntaxIssue(err error) Issue {
+	msg := err.Error()
+	if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Issue{Line: line, Column: 1, Message: m[2]}
+	}
+	return Issue{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+// schema is the subset of JSON Schema used by schema.json
+type schema struct {
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []string           `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *int               `json:"minimum"`
+
+	pattern *regexp.Regexp
+}
+
+var rootSchema = mustLoadSchema()
+
+func mustLoadSchema() *schema {
+	var s schema
+	if err := json.Unmarshal(schemaJSON, &s); err != nil {
+		panic(fmt.Sprintf("repoconfig: invalid schema.json: %v", err))
+	}
+	s.compile()
+	return &s
+}
+
+func (s *schema) compile() {
+	if s.Pattern != "" {
+		s.pattern = regexp.MustCompile(s.Pattern)
+	}
+	for _, prop := range s.Properties {
+		prop.compile()
+	}
+	if s.Items != nil {
+		s.Items.compile()
+	}
+}
+
+// validate checks a node against the schema, appending any issues
+func (s *schema) validate(n *yaml.Node, path string, issues *[]Issue) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	report := func(node *yaml.Node, format string, args ...interface{}) {
+		*issues = append(*issues, Issue{Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	want := map[string]string{"object": "mapping", "array": "list", "string": "string", "boolean": "boolean (true or false)", "integer": "whole number"}[s.Type]
+	if got := nodeType(n); got != s.Type {
+		if got == "null" {
+			report(n, "has no value (expected a %s)", want)
+		} else if s.Type == "string" && n.Kind == yaml.ScalarNode {
+			report(n, "expected a string, got %s %q (quote it)", nodeTypeName(got), n.Value)
+		} else {
+			report(n, "expected a %s, got %s", want, nodeTypeName(got))
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			if seen[key.Value] {
+				*issues = append(*issues, Issue{Line: key.Line, Column: key.Column, Path: childPath, Message: "duplicate key"})
+				continue
+			}
+			seen[key.Value] = true
+
+			prop, ok := s.Properties[key.Value]
+			if !ok {
+				msg := "unknown key"
+				if suggestion := closest(key.Value, s.Properties); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				*issues = append(*issues, Issue{Line: key.Line, Column: key.Column, Path: childPath, Message: msg})
+				continue
+			}
+			prop.validate(value, childPath, issues)
+		}
+
+	case "array":
+		for i, item := range n.Content {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), issues)
+		}
+
+	case "string":
+		if len(s.Enum) > 0 && !contains(s.Enum, n.Value) {
+			report(n, "%q is not one of %s", n.Value, strings.Join(s.Enum, ", "))
+			return
+		}
+		if s.pattern != nil && !s.pattern.MatchString(n.Value) {
+			report(n, "%q does not match %s", n.Value, s.Pattern)
+			return
+		}
+		if check := checks[path]; check != nil {
+			if err := check(n.Value); err != nil {
+				report(n, "%v", err)
+			}
+		}
+
+	case "integer":
+		v, err := strconv.Atoi(n.Value)
+		if err != nil {
+			report(n, "%q is not a whole number", n.Value)
+		} else if s.Minimum != nil && v < *s.Minimum {
+			report(n, "%d is less than the minimum of %d", v, *s.Minimum)
+		}
+	}
+}
+
+// nodeType returns the JSON Schema type of a YAML node
+func nodeType(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch n.ShortTag() {
+	case "!!str":
+		return "string"
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!null":
+		return "null"
+	}
+	return n.ShortTag()
+}
+
+func nodeTypeName(t string) string {
+	switch t {
+	case "object":
+		return "a mapping"
+	case "array":
+		return "a list"
+	case "integer", "number":
+		return "a number"
+	case "boolean":
+		return "a boolean"
+	case "string":
+		return "a string"
+	}
+	return t
+}
+
+// closest suggests the known key nearest to an unknown one, if any is close
+func closest(key string, props map[string]*schema) string {
+	best, bestDist := "", 3
+	for name := range props {
+		if d := editDistance(strings.ToLower(key), name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package repoconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseValid(t *testing.T) {
+	data := `
+default_branch: develop
+branch_template: "mc/{agent}/{task-slug}"
+merge_queue:
+  enabled: true
+  track: author
+pr_shepherd:
+  enabled: false
+notify:
+  enabled: true
+  method: smtp
+  to: [me@example.com, team@example.com]
+  smtp: smtp.example.com:587
+  digest_minutes: 60
+federation:
+  relay: git
+  peer: alice@laptop
+zombie:
+  stall_minutes: 90
+  looping: escalate
+worktree:
+  submodules: true
+prompt_budget:
+  total: 30000
+  custom: 12000
+work_hours:
+  hours: "07:00-22:00"
+  days: mon-fri
+push_checks:
+  rebased: true
+  message_pattern: "^(feat|fix): "
+review_dispatch: least-loaded
+routing:
+  - contains: URGENT
+    cc: [workspace]
+  - from: review
+    priority: high
+`
+	cfg, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.DefaultBranch != "develop" || cfg.MergeQueue.Track != "author" || !*cfg.MergeQueue.Enabled {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if *cfg.PRShepherd.Enabled || cfg.PRShepherd.Track != "" {
+		t.Errorf("PRShepherd = %+v", cfg.PRShepherd)
+	}
+	if len(cfg.Notify.To) != 2 || *cfg.Notify.DigestMinutes != 60 {
+		t.Errorf("Notify = %+v", cfg.Notify)
+	}
+	if cfg.Federation.Peer != "alice@laptop" {
+		t.Errorf("Federation = %+v", cfg.Federation)
+	}
+	if *cfg.Zombie.StallMinutes != 90 || cfg.Zombie.Looping != "escalate" || cfg.Zombie.Enabled != nil {
+		t.Errorf("Zombie = %+v", cfg.Zombie)
+	}
+	if !*cfg.Worktree.Submodules || cfg.Worktree.LFS != nil {
+		t.Errorf("Worktree = %+v", cfg.Worktree)
+	}
+	if *cfg.PromptBudget.Total != 30000 || *cfg.PromptBudget.Custom != 12000 || cfg.PromptBudget.Docs != nil {
+		t.Errorf("PromptBudget = %+v", cfg.PromptBudget)
+	}
+	if cfg.ReviewDispatch != "least-loaded" {
+		t.Errorf("ReviewDispatch = %q", cfg.ReviewDispatch)
+	}
+	if cfg.WorkHours.Hours != "07:00-22:00" || cfg.WorkHours.Days != "mon-fri" || cfg.WorkHours.Timezone != "" {
+		t.Errorf("WorkHours = %+v", cfg.WorkHours)
+	}
+	if !*cfg.PushChecks.Rebased || cfg.PushChecks.Signed != nil || cfg.PushChecks.MessagePattern != "^(feat|fix): " {
+		t.Errorf("PushChecks = %+v", cfg.PushChecks)
+	}
+	if len(cfg.Routing) != 2 || cfg.Routing[0].Contains != "URGENT" || cfg.Routing[0].CC[0] != "workspace" || cfg.Routing[1].Priority != "high" {
+		t.Errorf("Routing = %+v", cfg.Routing)
+	}
+
+	if cfg, err := Parse(nil); err != nil || cfg.MergeQueue != nil {
+		t.Errorf("Parse(empty) = %+v, %v; want empty config", cfg, err)
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string // issue strings, in order
+	}{
+		{
+			name: "unknown keys with suggestions",
+			data: "merge_queu:\n  enabled: true\nnotify:\n  enabeld: true\n",
+			want: []string{
+				`1:1: merge_queu: unknown key (did you mean "merge_queue"?)`,
+				`4:3: notify.enabeld: unknown key (did you mean "enabled"?)`,
+			},
+		},
+		{
+			name: "enum and type errors",
+			data: "merge_queue:\n  enabled: yes\n  track: everyone\nnotify:\n  digest_minutes: -5\n",
+			want: []string{
+				`2:12: merge_queue.enabled: expected a boolean (true or false), got a string`,
+				`3:10: merge_queue.track: "everyone" is not one of all, author, assigned`,
+				`5:19: notify.digest_minutes: -5 is less than the minimum of 0`,
+			},
+		},
+		{
+			name: "unquoted number for a string",
+			data: "default_branch: 1.0\n",
+			want: []string{`1:17: default_branch: expected a string, got a number "1.0" (quote it)`},
+		},
+		{
+			name: "semantic checks",
+			data: "branch_template: \"mc/{agent}/{ticket}\"\nfederation:\n  relay: relative/dir\n  peer: \"a..b\"\n",
+			want: []string{
+				`1:18: branch_template: unknown placeholder {ticket} in branch template (use {agent}, {task-slug}, {date} or {user})`,
+				`3:10: federation.relay: "relative/dir" does not match ^(off|git|/.*)$`,
+				`4:9: federation.peer: invalid peer ID "a..b" (use letters, digits, '.', '_', '@' and '-')`,
+			},
+		},
+		{
+			name: "list items and empty sections",
+			data: "notify:\n  to:\n    - me@example.com\n    - not-an-address\npr_shepherd:\n",
+			want: []string{
+				`4:7: notify.to[1]: "not-an-address" does not match ^[^@\s]+@[^@\s]+$`,
+				`5:13: pr_shepherd: has no value (expected a mapping)`,
+			},
+		},
+		{
+			name: "push checks",
+			data: "push_checks:\n  signed: true\n  message_pattern: \"^(feat|fix\"\n",
+			want: []string{
+				"3:20: push_checks.message_pattern: invalid commit message pattern \"^(feat|fix\": error parsing regexp: missing closing ): `^(feat|fix`",
+			},
+		},
+		{
+			name: "routing rules",
+			data: "routing:\n  - from: review\n    priority: urgent\n  - contain: URGENT\n",
+			want: []string{
+				`3:15: routing[0].priority: "urgent" is not one of high, normal, low`,
+				`4:5: routing[1].contain: unknown key (did you mean "contains"?)`,
+			},
+		},
+		{
+			name: "syntax error",
+			data: "merge_queue:\n  enabled: true\n track: all\n",
+			want: []string{`2:1: did not find expected key`},
+		},
+		{
+			name: "not a mapping",
+			data: "- merge_queue\n",
+			want: []string{`1:1: expected a mapping, got a list`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate([]byte(tt.data))
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Validate issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLoadReportsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("merge_queue:\n  track: nobody\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(path)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Load error = %v, want *ValidationError", err)
+	}
+	if want := path + `:2:10: merge_queue.track: "nobody" is not one of all, author, assigned`; verr.Error() != want {
+		t.Errorf("Error() = %q, want %q", verr.Error(), want)
+	}
+}
+
+func TestSchemaIsValidJSON(t *testing.T) {
+	var s map[string]interface{}
+	if err := json.Unmarshal(Schema(), &s); err != nil {
+		t.Fatalf("schema.json is invalid: %v", err)
+	}
+	// Every top-level key in Config must be described by the schema
+	props := s["properties"].(map[string]interface{})
+	for _, key := range []string{"default_branch", "branch_template", "merge_queue", "pr_shepherd", "notify", "federation", "zombie", "worktree", "prompt_budget", "work_hours", "routing"} {
+		if _, ok := props[key]; !ok {
+			t.Errorf("schema is missing %q", key)
+		}
+	}
+}
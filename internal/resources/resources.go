@@ -0,0 +1,274 @@
+// Package resources measures the CPU and memory use of agents' process trees,
+// so the daemon can show them and notice runaway tooling, such as a test run
+// that spins every core or leaks memory until the machine swaps.
+//
+// CPU use is measured as the CPU time a tree's processes used between two
+// samples, divided by the time between them, so it reflects what the tree is
+// doing now rather than its average since it started.
+package resources
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// Alert thresholds: a tree is hot while it uses HotCPUFraction of all cores
+// or HotMemoryFraction of physical memory, and an alert is sent once it has
+// been hot for AlertAfter samples in a row
+const (
+	HotCPUFraction    = 0.8
+	HotMemoryFraction = 0.5
+	AlertAfter        = 3
+)
+
+// avgWeight is the weight of the newest sample in the moving CPU average
+const avgWeight = 0.2
+
+// Process is one row of the process table
+type Process struct {
+	PID     int
+	PPID    int
+	RSS     int64         // Resident memory in bytes
+	CPUTime time.Duration // CPU time used since the process started
+	Command string
+}
+
+// Table is a snapshot of the process table
+type Table struct {
+	procs    map[int]Process
+	children map[int][]int
+}
+
+// Snapshot reads the process table with ps, so it works on Linux and macOS
+func Snapshot() (*Table, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "rss=", "-o", "time=", "-o", "comm=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	return ParseTable(string(output)), nil
+}
+
+// ParseTable parses ps output with pid, ppid, rss (KB), CPU time and command
+// columns. Rows that don't parse are skipped.
+func ParseTable(output string) *Table {
+	t := &Table{procs: make(map[int]Process), children: make(map[int][]int)}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		rss, err3 := strconv.ParseInt(fields[2], 10, 64)
+		cpu, err4 := parseCPUTime(fields[3])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		t.procs[pid] = Process{PID: pid, PPID: ppid, RSS: rss * 1024, CPUTime: cpu, Command: strings.Join(fields[4:], " ")}
+		t.children[ppid] = append(t.children[ppid], pid)
+	}
+	return t
+}
+
+// parseCPUTime parses ps's cumulative CPU time: "[dd-][hh:]mm:ss[.ss]"
+func parseCPUTime(s string) (time.Duration, error) {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU time %q", s)
+		}
+		days, s = n, rest
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid CPU time %q", s)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU time %q", s)
+	}
+	total := float64(days*86400) + seconds
+	for i, unit := range []float64{60, 3600}[:len(parts)-1] {
+		n, err := strconv.Atoi(parts[len(parts)-2-i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU time %q", s)
+		}
+		total += float64(n) * unit
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// Tree returns the process rootPID and its descendants, or nothing if it has exited
+func (t *Table) Tree(rootPID int) []Process {
+	if _, ok := t.procs[rootPID]; !ok {
+		return nil
+	}
+	var tree []Process
+	queue := []int{rootPID}
+	seen := make(map[int]bool)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		tree = append(tree, t.procs[pid])
+		queue = append(queue, t.children[pid]...)
+	}
+	return tree
+}
+
+// Usage is what one process tree used at a sample
+type Usage struct {
+	CPU        float64 // Percent of one core since the previous sample (400 is four busy cores)
+	RSS        int64   // Resident memory in bytes
+	Procs      int
+	Busiest    Process // The process that used the most CPU
+	BusiestCPU float64
+}
+
+// Sampler turns successive snapshots into CPU use per process tree
+type Sampler struct {
+	mu   sync.Mutex
+	prev map[int]time.Duration
+	at   time.Time
+}
+
+// NewSampler creates a sampler with no previous sample
+func NewSampler() *Sampler {
+	return &Sampler{}
+}
+
+// Sample measures the trees under roots, keyed by any name, in a snapshot
+// taken at now. Trees whose root has exited are left out. CPU use needs a
+// previous sample, so the first call reports cpuKnown false and no CPU.
+func (s *Sampler) Sample(t *Table, roots map[string]int, now time.Time) (usage map[string]Usage, cpuKnown bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := now.Sub(s.at)
+	cpuKnown = s.prev != nil && elapsed > 0
+
+	usage = make(map[string]Usage, len(roots))
+	for key, root := range roots {
+		tree := t.Tree(root)
+		if len(tree) == 0 {
+			continue
+		}
+		var u Usage
+		for _, p := range tree {
+			u.RSS += p.RSS
+			u.Procs++
+			if !cpuKnown {
+				continue
+			}
+			// A process missing from the previous sample started since
+			used := p.CPUTime - s.prev[p.PID]
+			if used < 0 {
+				used = 0 // The PID was reused
+			}
+			cpu := 100 * used.Seconds() / elapsed.Seconds()
+			u.CPU += cpu
+			if cpu > u.BusiestCPU || u.Busiest.PID == 0 {
+				u.Busiest, u.BusiestCPU = p, cpu
+			}
+		}
+		usage[key] = u
+	}
+
+	s.prev = make(map[int]time.Duration, len(t.procs))
+	for pid, p := range t.procs {
+		s.prev[pid] = p.CPUTime
+	}
+	s.at = now
+	return usage, cpuKnown
+}
+
+// Machine is the capacity alerts are measured against
+type Machine struct {
+	CPUs   int
+	Memory int64 // Physical memory in bytes, 0 if unknown
+}
+
+// DetectMachine returns this machine's core count and physical memory
+func DetectMachine() Machine {
+	return Machine{CPUs: runtime.NumCPU(), Memory: physicalMemory()}
+}
+
+// physicalMemory reads the total memory from /proc/meminfo (Linux) or
+// sysctl (macOS), returning 0 if neither works
+func physicalMemory() int64 {
+	if data, err := os.ReadFile("/proc/meminfo"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if rest, ok := strings.CutPrefix(line, "MemTotal:"); ok {
+				kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+				if err == nil {
+					return kb * 1024
+				}
+			}
+		}
+	}
+	if out, err := exec.Command("sysctl", "-n", "hw.memsize").Output(); err == nil {
+		if n, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// hot returns why a tree's usage is hogging the machine, or ""
+func (m Machine) hot(u Usage, cpuKnown bool) string {
+	if cpuKnown && m.CPUs > 0 && u.CPU >= HotCPUFraction*100*float64(m.CPUs) {
+		return fmt.Sprintf("using %.0f%% CPU (%d cores), busiest: %s (PID %d) at %.0f%%", u.CPU, m.CPUs, u.Busiest.Command, u.Busiest.PID, u.BusiestCPU)
+	}
+	if m.Memory > 0 && float64(u.RSS) >= HotMemoryFraction*float64(m.Memory) {
+		return fmt.Sprintf("using %d MB of memory (%.0f%% of the machine) across %d processes", u.RSS>>20, 100*float64(u.RSS)/float64(m.Memory), u.Procs)
+	}
+	return ""
+}
+
+// Record folds a sample into an agent's rolling stats. It returns an alert
+// describing the problem when the agent has been hot for AlertAfter samples
+// in a row; one alert is sent per hot streak.
+func Record(stats state.ResourceStats, u Usage, cpuKnown bool, m Machine, now time.Time) (state.ResourceStats, string) {
+	if cpuKnown {
+		if stats.SampledAt.IsZero() {
+			stats.AvgCPU = u.CPU
+		} else {
+			stats.AvgCPU = avgWeight*u.CPU + (1-avgWeight)*stats.AvgCPU
+		}
+		stats.CPU = u.CPU
+		if u.CPU > stats.PeakCPU {
+			stats.PeakCPU = u.CPU
+		}
+		stats.Busiest = u.Busiest.Command
+	}
+	stats.RSS = u.RSS
+	if u.RSS > stats.PeakRSS {
+		stats.PeakRSS = u.RSS
+	}
+	stats.Procs = u.Procs
+	stats.SampledAt = now
+
+	reason := m.hot(u, cpuKnown)
+	if reason == "" {
+		stats.HotSamples, stats.Alerted = 0, false
+		return stats, ""
+	}
+	stats.HotSamples++
+	if stats.HotSamples < AlertAfter || stats.Alerted {
+		return stats, ""
+	}
+	stats.Alerted = true
+	return stats, reason
+}
@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestParseCPUTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"00:00:07", 7 * time.Second},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"2-00:00:01", 48*time.Hour + time.Second},
+		{"3:04.50", 3*time.Minute + 4500*time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUTime(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseCPUTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "12", "a:b", "x-00:01"} {
+		if _, err := parseCPUTime(bad); err == nil {
+			t.Errorf("parseCPUTime(%q) should fail", bad)
+		}
+	}
+}
+
+// table builds ps output for a pane shell (100) running claude (101), which
+// runs go test (102), next to an unrelated process (200)
+func table(claudeCPU, testCPU string) *Table {
+	return ParseTable(strings.Join([]string{
+		"  100     1   2048 00:00:01 bash",
+		"  101   100 204800 " + claudeCPU + " claude",
+		"  102   101 102400 " + testCPU + " go test ./...",
+		"  200     1   4096 00:09:00 Xorg",
+		"garbage",
+	}, "\n"))
+}
+
+func TestSample(t *testing.T) {
+	s := NewSampler()
+	start := time.Now()
+	roots := map[string]int{"app/calm-owl": 100, "app/gone": 999}
+
+	usage, cpuKnown := s.Sample(table("00:00:10", "00:00:00"), roots, start)
+	if cpuKnown {
+		t.Error("first sample reported CPU")
+	}
+	u, ok := usage["app/calm-owl"]
+	if !ok || u.Procs != 3 || u.RSS != (2048+204800+102400)*1024 {
+		t.Errorf("first sample = %+v, want 3 processes and their memory", u)
+	}
+	if _, ok := usage["app/gone"]; ok {
+		t.Error("an exited tree was sampled")
+	}
+
+	// Over 10s claude used 1s and go test 30s: three cores
+	usage, cpuKnown = s.Sample(table("00:00:11", "00:00:30"), roots, start.Add(10*time.Second))
+	u = usage["app/calm-owl"]
+	if !cpuKnown || u.CPU < 309 || u.CPU > 311 {
+		t.Errorf("CPU = %.1f (known %v), want 310", u.CPU, cpuKnown)
+	}
+	if u.Busiest.Command != "go test ./..." || u.BusiestCPU < 299 || u.BusiestCPU > 301 {
+		t.Errorf("busiest = %q at %.1f, want go test at 300", u.Busiest.Command, u.BusiestCPU)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	m := Machine{CPUs: 4, Memory: 1 << 30}
+	now := time.Now()
+	hot := Usage{CPU: 390, RSS: 1 << 20, Procs: 5, Busiest: Process{PID: 7, Command: "go test"}, BusiestCPU: 380}
+	cool := Usage{CPU: 20, RSS: 2 << 20, Procs: 2}
+
+	var stats state.ResourceStats
+	var alerts []string
+	for i := 0; i < AlertAfter+2; i++ {
+		var alert string
+		stats, alert = Record(stats, hot, true, m, now)
+		if alert != "" {
+			alerts = append(alerts, alert)
+		}
+	}
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "go test (PID 7)") {
+		t.Fatalf("alerts = %q, want one naming the busiest process", alerts)
+	}
+	if stats.PeakCPU != 390 || stats.AvgCPU != 390 || stats.Busiest != "go test" {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// Cooling down ends the streak and moves the average
+	stats, alert := Record(stats, cool, true, m, now)
+	if alert != "" || stats.HotSamples != 0 || stats.Alerted {
+		t.Errorf("after cooling: alert %q, stats %+v", alert, stats)
+	}
+	if stats.CPU != 20 || stats.AvgCPU >= 390 || stats.PeakRSS != 2<<20 {
+		t.Errorf("after cooling: stats %+v", stats)
+	}
+
+	// Memory alone can make an agent hot, even before CPU is known
+	big := Usage{RSS: 600 << 20, Procs: 3}
+	stats = state.ResourceStats{}
+	for i := 0; i < AlertAfter; i++ {
+		stats, alert = Record(stats, big, false, m, now)
+	}
+	if !strings.Contains(alert, "memory") {
+		t.Errorf("memory alert = %q", alert)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	table, err := Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() failed: %v", err)
+	}
+	tree := table.Tree(os.Getpid())
+	if len(tree) == 0 || tree[0].RSS <= 0 {
+		t.Errorf("Tree(self) = %+v, want this process with its memory", tree)
+	}
+}
@@ -0,0 +1,129 @@
+// Package routing applies a repository's message routing rules: copying
+// the messages a rule matches to more agents (such as every merge queue
+// message to the supervisor) and giving them a priority. The daemon applies
+// the rules once to each message, when it routes the message.
+package routing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// Priorities are the priorities a rule may set
+var Priorities = []messages.Priority{messages.PriorityHigh, messages.PriorityNormal, messages.PriorityLow}
+
+// Validate checks routing rules as stored in state
+func Validate(rules []state.RoutingRule) error {
+	for i, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("routing rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule state.RoutingRule) error {
+	if rule.Contains == "" && rule.From == "" && rule.To == "" {
+		return fmt.Errorf("a rule needs at least one of contains, from or to")
+	}
+	if len(rule.CC) == 0 && rule.Priority == "" {
+		return fmt.Errorf("a rule needs cc, a priority or both")
+	}
+	for _, name := range append([]string{rule.From, rule.To}, rule.CC...) {
+		if strings.ContainsAny(name, " \t\n/") {
+			return fmt.Errorf("invalid agent name %q", name)
+		}
+	}
+	for _, name := range rule.CC {
+		if name == "" {
+			return fmt.Errorf("cc has an empty agent name")
+		}
+	}
+	if rule.Priority != "" && !validPriority(messages.Priority(rule.Priority)) {
+		return fmt.Errorf("invalid priority %q: must be high, normal or low", rule.Priority)
+	}
+	return nil
+}
+
+func validPriority(p messages.Priority) bool {
+	for _, valid := range Priorities {
+		if p == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether a rule applies to a message. senderType is the
+// type of the sending agent, if it is one.
+func Matches(rule state.RoutingRule, msg *messages.Message, senderType string) bool {
+	if rule.Contains != "" && !strings.Contains(msg.Body, rule.Contains) {
+		return false
+	}
+	if rule.From != "" && rule.From != msg.From && rule.From != senderType {
+		return false
+	}
+	if rule.To != "" && rule.To != msg.To {
+		return false
+	}
+	return true
+}
+
+// Result is what the rules matching a message do to it
+type Result struct {
+	// CC are the agents that also get a copy, without the message's sender
+	// and recipient
+	CC []string
+	// Priority is the priority of the first matching rule that sets one
+	Priority messages.Priority
+}
+
+// Apply works out what rules do to a message. Every matching rule adds its
+// copies; the first one with a priority sets it.
+func Apply(rules []state.RoutingRule, msg *messages.Message, senderType string) Result {
+	var result Result
+	seen := map[string]bool{msg.From: true, msg.To: true}
+	for _, rule := range rules {
+		if !Matches(rule, msg, senderType) {
+			continue
+		}
+		for _, name := range rule.CC {
+			if !seen[name] {
+				seen[name] = true
+				result.CC = append(result.CC, name)
+			}
+		}
+		if result.Priority == "" && rule.Priority != "" {
+			result.Priority = messages.Priority(rule.Priority)
+		}
+	}
+	return result
+}
+
+// Describe renders a rule on one line, e.g.
+// `from merge-queue containing "URGENT": cc supervisor; priority high`
+func Describe(rule state.RoutingRule) string {
+	var when []string
+	if rule.From != "" {
+		when = append(when, "from "+rule.From)
+	}
+	if rule.To != "" {
+		when = append(when, "to "+rule.To)
+	}
+	if rule.Contains != "" {
+		when = append(when, "containing "+strconv.Quote(rule.Contains))
+	}
+
+	var then []string
+	if len(rule.CC) > 0 {
+		then = append(then, "cc "+strings.Join(rule.CC, ", "))
+	}
+	if rule.Priority != "" {
+		then = append(then, "priority "+rule.Priority)
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(when, " "), strings.Join(then, "; "))
+}
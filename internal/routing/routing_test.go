@@ -0,0 +1,90 @@
+package routing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []state.RoutingRule{
+		{Contains: "URGENT", CC: []string{"workspace"}},
+		{From: "merge-queue", CC: []string{"supervisor"}},
+		{From: "review", Priority: "high"},
+	}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	tests := []struct {
+		rule state.RoutingRule
+		want string
+	}{
+		{state.RoutingRule{CC: []string{"supervisor"}}, "at least one of"},
+		{state.RoutingRule{From: "review"}, "needs cc"},
+		{state.RoutingRule{From: "review", Priority: "urgent"}, "invalid priority"},
+		{state.RoutingRule{From: "review", CC: []string{""}}, "empty agent name"},
+		{state.RoutingRule{To: "a b", Priority: "low"}, "invalid agent name"},
+	}
+	for _, tt := range tests {
+		err := Validate(append(valid, tt.rule))
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "rule 4") {
+			t.Errorf("Validate(%+v) = %v, want an error about %q in rule 4", tt.rule, err, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	rules := []state.RoutingRule{
+		{Contains: "URGENT", CC: []string{"workspace"}},
+		{From: "merge-queue", CC: []string{"supervisor", "workspace"}},
+		{From: "review", Priority: "high"},
+		{To: "supervisor", Priority: "low"},
+	}
+
+	tests := []struct {
+		name       string
+		msg        messages.Message
+		senderType string
+		want       Result
+	}{
+		{
+			name: "no match",
+			msg:  messages.Message{From: "calm-owl", To: "supervisor", Body: "done"},
+			want: Result{Priority: messages.PriorityLow},
+		},
+		{
+			name: "contains is case-sensitive",
+			msg:  messages.Message{From: "calm-owl", To: "merge-queue", Body: "urgent: tests fail"},
+		},
+		{
+			name: "copies are not duplicated or sent to the recipient",
+			msg:  messages.Message{From: "merge-queue", To: "workspace", Body: "URGENT: main is red"},
+			want: Result{CC: []string{"supervisor"}},
+		},
+		{
+			name:       "from matches the sender's type",
+			msg:        messages.Message{From: "review-12", To: "supervisor", Body: "LGTM"},
+			senderType: "review",
+			want:       Result{Priority: messages.PriorityHigh},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Apply(rules, &tt.msg, tt.senderType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	rule := state.RoutingRule{From: "merge-queue", Contains: "URGENT", CC: []string{"supervisor", "workspace"}, Priority: "high"}
+	want := `from merge-queue containing "URGENT": cc supervisor, workspace; priority high`
+	if got := Describe(rule); got != want {
+		t.Errorf("Describe() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+// Package scaffold generates a starter .multiclaude directory for a
+// repository: a config file, agent definitions, slash commands, message
+// templates and hooks, from templates embedded in the binary.
+package scaffold
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/prompts/commands"
+	"github.com/micheal-at/multiclaude/internal/repoconfig"
+	"github.com/micheal-at/multiclaude/internal/templates"
+)
+
+//go:embed all:files
+var files embed.FS
+
+// Dir is the directory the scaffold creates, relative to the repository root
+const Dir = ".multiclaude"
+
+// Variant selects how much the scaffold generates
+type Variant string
+
+const (
+	// Minimal is a short config file and the worker definition
+	Minimal Variant = "minimal"
+	// Standard adds every built-in agent definition, an example slash
+	// command, example message templates and a README
+	Standard Variant = "standard"
+	// Full lists every config setting and adds example hooks
+	Full Variant = "full"
+)
+
+// File is a file the scaffold generates
+type File struct {
+	Path    string // Relative to the repository root
+	Content []byte
+}
+
+// Files returns the files a variant generates, in the order they are written
+func Files(v Variant) ([]File, error) {
+	var out []File
+	add := func(path, embedded string) error {
+		content, err := files.ReadFile(embedded)
+		if err != nil {
+			return fmt.Errorf("failed to read scaffold template %s: %w", embedded, err)
+		}
+		out = append(out, File{Path: path, Content: content})
+		return nil
+	}
+	addAgent := func(name string) error {
+		content, err := templates.ReadAgentTemplate(name)
+		if err != nil {
+			return err
+		}
+		out = append(out, File{Path: filepath.Join(Dir, "agents", name), Content: content})
+		return nil
+	}
+
+	config := "files/config.minimal.yaml"
+	if v == Full {
+		config = "files/config.full.yaml"
+	}
+	if err := add(repoconfig.Path, config); err != nil {
+		return nil, err
+	}
+
+	switch v {
+	case Minimal:
+		if err := addAgent("worker.md"); err != nil {
+			return nil, err
+		}
+		return out, nil
+	case Standard, Full:
+	default:
+		return nil, fmt.Errorf("unknown scaffold variant %q", v)
+	}
+
+	agentNames, err := templates.ListAgentTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range agentNames {
+		if err := addAgent(name); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(filepath.Join(commands.RepoCommandsDir, "check.md"), "files/commands/check.md"); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"request-changes.md", "review-request.md", "status-query.md"} {
+		if err := add(filepath.Join(messages.RepoTemplatesDir, name), "files/messages/"+name); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(filepath.Join(Dir, "README.md"), "files/README.md"); err != nil {
+		return nil, err
+	}
+	if v == Full {
+		if err := add(filepath.Join(Dir, "hooks.json"), "files/hooks.json"); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Result lists what Write did, by path relative to the repository root
+type Result struct {
+	Written []string
+	// Skipped files already existed and were left alone
+	Skipped []string
+}
+
+// Write generates a variant's files under repoPath. Files that already exist
+// are skipped unless overwrite is set, so running it again only fills in
+// what's missing.
+func Write(repoPath string, v Variant, overwrite bool) (*Result, error) {
+	scaffold, err := Files(v)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	for _, f := range scaffold {
+		path := filepath.Join(repoPath, f.Path)
+		if _, err := os.Stat(path); err == nil && !overwrite {
+			result.Skipped = append(result.Skipped, f.Path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return result, fmt.Errorf("failed to create %s: %w", filepath.Dir(f.Path), err)
+		}
+		if err := os.WriteFile(path, f.Content, 0644); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+		result.Written = append(result.Written, f.Path)
+	}
+	return result, nil
+}
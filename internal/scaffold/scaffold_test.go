@@ -0,0 +1,106 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/prompts/commands"
+	"github.com/micheal-at/multiclaude/internal/repoconfig"
+)
+
+// commentedSettingRe matches a commented-out setting: "#key: value" or an
+// indented "#  key: value", unlike explanatory comments ("# text")
+var commentedSettingRe = regexp.MustCompile(`(?m)^#(\S|  )`)
+
+func TestFiles(t *testing.T) {
+	counts := make(map[Variant]int)
+	for _, v := range []Variant{Minimal, Standard, Full} {
+		scaffold, err := Files(v)
+		if err != nil {
+			t.Fatalf("Files(%s) failed: %v", v, err)
+		}
+		counts[v] = len(scaffold)
+
+		for _, f := range scaffold {
+			switch filepath.Ext(f.Path) {
+			case ".yaml":
+				if issues := repoconfig.Validate(f.Content); len(issues) > 0 {
+					t.Errorf("%s %s is invalid: %v", v, f.Path, issues)
+				}
+				// Every example setting must be valid once uncommented
+				uncommented := commentedSettingRe.ReplaceAll(f.Content, []byte("$1"))
+				if issues := repoconfig.Validate(uncommented); len(issues) > 0 {
+					t.Errorf("%s %s examples are invalid: %v", v, f.Path, issues)
+				}
+			case ".json":
+				if !json.Valid(f.Content) {
+					t.Errorf("%s %s is not valid JSON", v, f.Path)
+				}
+			}
+		}
+	}
+	if counts[Minimal] != 2 || counts[Standard] <= counts[Minimal] || counts[Full] != counts[Standard]+1 {
+		t.Errorf("file counts = %v, want minimal < standard < full", counts)
+	}
+
+	if _, err := Files("huge"); err == nil {
+		t.Error("Files() accepted an unknown variant")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	repo := t.TempDir()
+	configPath := filepath.Join(repo, repoconfig.Path)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte("default_branch: develop\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Write(repo, Full, false)
+	if err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != repoconfig.Path {
+		t.Errorf("Skipped = %v, want the existing config", result.Skipped)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != "default_branch: develop\n" {
+		t.Errorf("existing config was overwritten: %q", data)
+	}
+
+	// The example command is picked up as a repository command
+	cmds, warnings := commands.Effective(repo)
+	if len(warnings) > 0 {
+		t.Errorf("commands.Effective() warnings: %v", warnings)
+	}
+	found := false
+	for _, cmd := range cmds {
+		if cmd.Name == "check" && cmd.Source == commands.SourceRepo {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("the scaffolded /check command is not a repository command")
+	}
+
+	// So are the example message templates
+	if templates, warnings := messages.ListTemplates(repo); len(templates) != 3 || len(warnings) > 0 {
+		t.Errorf("messages.ListTemplates() = %d templates, warnings %v; want the 3 examples", len(templates), warnings)
+	}
+
+	result, err = Write(repo, Full, true)
+	if err != nil {
+		t.Fatalf("Write(overwrite) failed: %v", err)
+	}
+	if len(result.Skipped) != 0 {
+		t.Errorf("Skipped = %v with overwrite", result.Skipped)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) == "default_branch: develop\n" {
+		t.Error("config was not overwritten")
+	}
+}
@@ -0,0 +1,265 @@
+// Package service installs the daemon as a per-user service (a systemd user
+// unit on Linux, a launchd agent on macOS) so it starts at login and is
+// restarted after it crashes.
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupported is returned on platforms without a supported service manager
+var ErrUnsupported = errors.New("installing a service is only supported on Linux (systemd) and macOS (launchd); use 'multiclaude daemon watch' instead")
+
+// The service managers a service can be installed with
+const (
+	Systemd = "systemd"
+	Launchd = "launchd"
+)
+
+const (
+	// SystemdUnit is the systemd user unit name
+	SystemdUnit = "multiclaude.service"
+	// LaunchdLabel is the launchd agent label
+	LaunchdLabel = "com.multiclaude.daemon"
+)
+
+// Service is a service definition for the daemon
+type Service struct {
+	Manager string     // Systemd or Launchd
+	Path    string     // Where the definition file is installed
+	Content string     // The definition file
+	Enable  [][]string // Commands that load and start the service
+	Disable [][]string // Commands that stop and unload the service
+	Status  []string   // Command that shows whether the service is running and why not
+}
+
+// Options describe how the daemon is run by the service
+type Options struct {
+	Executable string // Absolute path to the multiclaude binary
+	LogFile    string // Daemon log file (stdout and stderr are appended to it)
+	PATH       string // PATH for the daemon, which runs tmux, git, gh and claude
+	// Env holds extra KEY=value variables, such as the directory layout
+	// variables, so the daemon finds the same paths as the CLI
+	Env []string
+}
+
+// DefaultManager returns the service manager of goos
+func DefaultManager(goos string) (string, error) {
+	switch goos {
+	case "linux":
+		return Systemd, nil
+	case "darwin":
+		return Launchd, nil
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+// For returns the service definition for goos, rooted at the user's home directory
+func For(goos, home string, opts Options) (*Service, error) {
+	manager, err := DefaultManager(goos)
+	if err != nil {
+		return nil, err
+	}
+	return ForManager(manager, home, opts)
+}
+
+// ForManager returns the service definition for manager, rooted at the
+// user's home directory
+func ForManager(manager, home string, opts Options) (*Service, error) {
+	switch manager {
+	case Systemd:
+		return &Service{
+			Manager: Systemd,
+			Path:    filepath.Join(home, ".config", "systemd", "user", SystemdUnit),
+			Content: systemdUnit(opts),
+			Enable: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+				{"systemctl", "--user", "enable", "--now", SystemdUnit},
+			},
+			Disable: [][]string{
+				{"systemctl", "--user", "disable", "--now", SystemdUnit},
+			},
+			Status: []string{"systemctl", "--user", "status", SystemdUnit},
+		}, nil
+	case Launchd:
+		path := filepath.Join(home, "Library", "LaunchAgents", LaunchdLabel+".plist")
+		return &Service{
+			Manager: Launchd,
+			Path:    path,
+			Content: launchdPlist(opts),
+			Enable:  [][]string{{"launchctl", "load", "-w", path}},
+			Disable: [][]string{{"launchctl", "unload", "-w", path}},
+			Status:  []string{"launchctl", "list", LaunchdLabel},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown service manager %q: must be %s or %s", manager, Systemd, Launchd)
+	}
+}
+
+// Available checks that the service's manager can be run here
+func (s *Service) Available() error {
+	if _, err := exec.LookPath(s.Enable[0][0]); err != nil {
+		return fmt.Errorf("%s is not available: %s not found", s.Manager, s.Enable[0][0])
+	}
+	return nil
+}
+
+// SearchPath returns the PATH for the daemon: base, with each of dirs added
+// in front that isn't on it already. The service manager's own PATH is
+// minimal, so the directories of the binary and the tools the daemon runs
+// are passed on explicitly.
+func SearchPath(base string, dirs ...string) string {
+	seen := make(map[string]bool)
+	var entries []string
+	for _, dir := range filepath.SplitList(base) {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			entries = append(entries, dir)
+		}
+	}
+	var front []string
+	for _, dir := range dirs {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			front = append(front, dir)
+		}
+	}
+	return strings.Join(append(front, entries...), string(os.PathListSeparator))
+}
+
+// systemdUnit restarts the daemon when it exits with an error or is killed by
+// a signal; `multiclaude daemon stop` exits cleanly and is left stopped
+func systemdUnit(opts Options) string {
+	return fmt.Sprintf(`[Unit]
+Description=multiclaude daemon
+After=network-online.target
+
+[Service]
+Type=simple
+ExecStart=%s daemon _run
+Restart=on-failure
+RestartSec=5
+Environment=%s
+%sStandardOutput=append:%s
+StandardError=append:%s
+
+[Install]
+WantedBy=default.target
+`, systemdQuote(opts.Executable), systemdQuote("PATH="+opts.PATH), systemdEnv(opts.Env), opts.LogFile, opts.LogFile)
+}
+
+// systemdEnv returns an Environment= line for each extra variable
+func systemdEnv(env []string) string {
+	var b strings.Builder
+	for _, kv := range env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(kv))
+	}
+	return b.String()
+}
+
+// systemdQuote quotes a value for a unit file if it contains spaces
+func systemdQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// launchdPlist keeps the daemon alive unless it exits cleanly, like the systemd unit
+func launchdPlist(opts Options) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>daemon</string>
+		<string>_run</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>10</integer>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>PATH</key>
+		<string>%s</string>
+%s	</dict>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, LaunchdLabel, xmlEscape(opts.Executable), xmlEscape(opts.PATH), launchdEnv(opts.Env), xmlEscape(opts.LogFile), xmlEscape(opts.LogFile))
+}
+
+// launchdEnv returns an EnvironmentVariables entry for each extra variable
+func launchdEnv(env []string) string {
+	var b strings.Builder
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlEscape(key), xmlEscape(value))
+	}
+	return b.String()
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Installed reports whether the service definition file exists
+func (s *Service) Installed() bool {
+	_, err := os.Stat(s.Path)
+	return err == nil
+}
+
+// Install writes the service definition and runs the enable commands
+func (s *Service) Install() error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.Path, []byte(s.Content), 0644); err != nil {
+		return err
+	}
+	return run(s.Enable)
+}
+
+// Uninstall runs the disable commands and removes the service definition
+func (s *Service) Uninstall() error {
+	if err := run(s.Disable); err != nil {
+		return err
+	}
+	if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func run(cmds [][]string) error {
+	for _, args := range cmds {
+		cmd := exec.Command(args[0], args[1:]...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
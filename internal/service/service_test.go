@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	opts := Options{
+		Executable: "/home/me/go/bin/multiclaude",
+		LogFile:    "/home/me/.multiclaude/daemon.log",
+		PATH:       "/home/me/go/bin:/usr/local/bin:/usr/bin",
+	}
+
+	t.Run("linux", func(t *testing.T) {
+		s, err := For("linux", "/home/me", opts)
+		if err != nil {
+			t.Fatalf("For() error: %v", err)
+		}
+		if s.Path != filepath.Join("/home/me", ".config", "systemd", "user", "multiclaude.service") {
+			t.Errorf("Path = %q", s.Path)
+		}
+		for _, want := range []string{
+			"ExecStart=/home/me/go/bin/multiclaude daemon _run\n",
+			"Restart=on-failure\n",
+			"Environment=PATH=/home/me/go/bin:/usr/local/bin:/usr/bin\n",
+			"StandardOutput=append:/home/me/.multiclaude/daemon.log\n",
+			"WantedBy=default.target\n",
+		} {
+			if !strings.Contains(s.Content, want) {
+				t.Errorf("unit missing %q:\n%s", want, s.Content)
+			}
+		}
+		if got := strings.Join(s.Enable[len(s.Enable)-1], " "); got != "systemctl --user enable --now multiclaude.service" {
+			t.Errorf("enable command = %q", got)
+		}
+	})
+
+	t.Run("darwin", func(t *testing.T) {
+		s, err := For("darwin", "/Users/me", Options{Executable: "/opt/mc & co/multiclaude", LogFile: "/tmp/d.log", PATH: "/usr/bin"})
+		if err != nil {
+			t.Fatalf("For() error: %v", err)
+		}
+		if s.Path != filepath.Join("/Users/me", "Library", "LaunchAgents", "com.multiclaude.daemon.plist") {
+			t.Errorf("Path = %q", s.Path)
+		}
+		if err := xml.Unmarshal([]byte(s.Content), new(interface{})); err != nil {
+			t.Errorf("plist is not valid XML: %v\n%s", err, s.Content)
+		}
+		for _, want := range []string{"<string>/opt/mc &amp; co/multiclaude</string>", "<key>SuccessfulExit</key>", "<string>_run</string>"} {
+			if !strings.Contains(s.Content, want) {
+				t.Errorf("plist missing %q:\n%s", want, s.Content)
+			}
+		}
+	})
+
+	t.Run("extra environment", func(t *testing.T) {
+		env := []string{"XDG_STATE_HOME=/home/me/state", "MULTICLAUDE_HOME=/home/me/my mc"}
+		s, _ := For("linux", "/home/me", Options{Executable: "/usr/bin/multiclaude", LogFile: "/tmp/d.log", PATH: "/usr/bin", Env: env})
+		for _, want := range []string{"Environment=XDG_STATE_HOME=/home/me/state\n", `Environment="MULTICLAUDE_HOME=/home/me/my mc"` + "\n"} {
+			if !strings.Contains(s.Content, want) {
+				t.Errorf("unit missing %q:\n%s", want, s.Content)
+			}
+		}
+
+		s, _ = For("darwin", "/Users/me", Options{Executable: "/usr/bin/multiclaude", LogFile: "/tmp/d.log", PATH: "/usr/bin", Env: env})
+		if err := xml.Unmarshal([]byte(s.Content), new(interface{})); err != nil {
+			t.Errorf("plist is not valid XML: %v\n%s", err, s.Content)
+		}
+		if !strings.Contains(s.Content, "<key>MULTICLAUDE_HOME</key>\n\t\t<string>/home/me/my mc</string>") {
+			t.Errorf("plist missing MULTICLAUDE_HOME:\n%s", s.Content)
+		}
+	})
+
+	t.Run("quotes paths with spaces", func(t *testing.T) {
+		s, _ := For("linux", "/home/me", Options{Executable: "/home/me/my tools/multiclaude", LogFile: "/tmp/d.log", PATH: "/usr/bin"})
+		if !strings.Contains(s.Content, `ExecStart="/home/me/my tools/multiclaude" daemon _run`) {
+			t.Errorf("ExecStart not quoted:\n%s", s.Content)
+		}
+	})
+
+	if _, err := For("windows", `C:\Users\me`, opts); err != ErrUnsupported {
+		t.Errorf("For(windows) error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestForManager(t *testing.T) {
+	opts := Options{Executable: "/usr/local/bin/multiclaude", LogFile: "/tmp/d.log", PATH: "/usr/bin"}
+
+	// Either manager can be chosen whatever the platform
+	s, err := ForManager(Launchd, "/home/me", opts)
+	if err != nil {
+		t.Fatalf("ForManager(launchd) error: %v", err)
+	}
+	if s.Manager != Launchd || !strings.HasSuffix(s.Path, "com.multiclaude.daemon.plist") {
+		t.Errorf("ForManager(launchd) = %s at %s", s.Manager, s.Path)
+	}
+	if got := strings.Join(s.Status, " "); got != "launchctl list com.multiclaude.daemon" {
+		t.Errorf("status command = %q", got)
+	}
+
+	if s, _ := For("linux", "/home/me", opts); s.Manager != Systemd {
+		t.Errorf("For(linux) manager = %q, want systemd", s.Manager)
+	}
+	if _, err := ForManager("runit", "/home/me", opts); err == nil {
+		t.Error("ForManager(runit) should fail")
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	got := SearchPath("/usr/bin:/bin:/usr/bin", "/home/me/go/bin", "/usr/bin", "/opt/homebrew/bin", "/home/me/go/bin")
+	if want := "/home/me/go/bin:/opt/homebrew/bin:/usr/bin:/bin"; got != want {
+		t.Errorf("SearchPath() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,16 @@
+// Package shell builds command lines for a POSIX shell: the commands agents
+// run in their tmux windows, popups and the commands multiclaude suggests.
+package shell
+
+import "strings"
+
+// Quote quotes s for a POSIX shell. Words made only of characters the shell
+// leaves alone are returned as they are, so suggested commands stay readable.
+func Quote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@=+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,36 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"my-repo", "my-repo"},
+		{"/usr/bin/multiclaude", "/usr/bin/multiclaude"},
+		{"", "''"},
+		{"mc root", "'mc root'"},
+		{"it's-me", `'it'\''s-me'`},
+		{"$HOME", "'$HOME'"},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	for _, s := range []string{"plain", "two words", "it's", `"double" $x; rm -rf /`, "line\nbreak", ""} {
+		out, err := exec.Command("sh", "-c", "printf %s "+Quote(s)).Output()
+		if err != nil {
+			t.Fatalf("sh failed for %q: %v", s, err)
+		}
+		if string(out) != s {
+			t.Errorf("sh read Quote(%q) as %q", s, out)
+		}
+	}
+}
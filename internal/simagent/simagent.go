@@ -0,0 +1,218 @@
+// Package simagent is the scripted stand-in for Claude that runs in an
+// agent's tmux pane when MULTICLAUDE_TEST_MODE=sim. It behaves the way a
+// well-mannered agent does, without calling the API: it reads and acks the
+// messages routed to it, prints canned output, and a worker commits a file for
+// its task and completes. That is enough for end-to-end tests of message
+// routing, worktree refresh and cleanup.
+package simagent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/shell"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// EnvTestMode keeps Claude from starting. With "1" agents' windows are left
+// with just a shell; with "sim" they run the simulator.
+const EnvTestMode = "MULTICLAUDE_TEST_MODE"
+
+// Skip reports whether agents' windows are left with just a shell
+func Skip() bool {
+	return os.Getenv(EnvTestMode) == "1"
+}
+
+// Enabled reports whether agents' windows run the simulator instead of Claude
+func Enabled() bool {
+	return os.Getenv(EnvTestMode) == "sim"
+}
+
+// Command returns the shell command that runs the simulator as an agent. The
+// simulator is the multiclaude binary itself, given the layout environment
+// that finds the daemon's paths.
+func Command(binary string, paths *config.Paths, repo, agent string) string {
+	var env []string
+	for _, assignment := range paths.Env() {
+		name, value, _ := strings.Cut(assignment, "=")
+		env = append(env, name+"="+shell.Quote(value))
+	}
+	return fmt.Sprintf("%s %s agent _simulate --repo %s --agent %s",
+		strings.Join(env, " "), shell.Quote(binary), shell.Quote(repo), shell.Quote(agent))
+}
+
+// Inbox is the agent's mailbox, as messages.Manager provides it
+type Inbox interface {
+	List(repoName, agentName string) ([]*messages.Message, error)
+	Ack(repoName, agentName, messageID string) error
+}
+
+// Simulator plays one agent
+type Simulator struct {
+	Repo  string
+	Agent string
+
+	// Lookup returns the agent's state. The simulator usually starts before
+	// the agent is registered, so it waits for Lookup to find it.
+	Lookup func() (state.Agent, bool)
+	Inbox  Inbox
+	// Complete marks the agent complete, as `multiclaude agent complete` does
+	Complete func(summary string) error
+
+	// Input is what's typed into the pane; each line is echoed as received
+	Input io.Reader
+	Out   io.Writer
+
+	// Poll is how often the inbox is checked
+	Poll time.Duration
+	// RegisterTimeout is how long to wait for the agent to be registered
+	RegisterTimeout time.Duration
+
+	outMu sync.Mutex
+}
+
+// Run plays the agent until ctx is done. Workers and review agents finish
+// their task once and then keep answering messages until they're cleaned up.
+func (s *Simulator) Run(ctx context.Context) error {
+	agent, err := s.waitForAgent(ctx)
+	if err != nil {
+		return err
+	}
+	s.say("%s %s started (simulated, %s=sim)", agent.Type, s.Agent, EnvTestMode)
+	if agent.Task != "" {
+		s.say("Task: %s", agent.Task)
+	}
+
+	if s.Input != nil {
+		go s.echoInput()
+	}
+
+	s.drainInbox()
+	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview {
+		if err := s.finish(agent); err != nil {
+			s.say("Could not finish: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(s.Poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.drainInbox()
+		}
+	}
+}
+
+// waitForAgent waits for the agent to appear in state
+func (s *Simulator) waitForAgent(ctx context.Context) (state.Agent, error) {
+	deadline := time.Now().Add(s.RegisterTimeout)
+	for {
+		if agent, ok := s.Lookup(); ok {
+			return agent, nil
+		}
+		if time.Now().After(deadline) {
+			return state.Agent{}, fmt.Errorf("agent %s was not registered in repository %s within %s", s.Agent, s.Repo, s.RegisterTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return state.Agent{}, ctx.Err()
+		case <-time.After(s.Poll):
+		}
+	}
+}
+
+// drainInbox reads and acks every message not acked yet
+func (s *Simulator) drainInbox() {
+	msgs, err := s.Inbox.List(s.Repo, s.Agent)
+	if err != nil {
+		s.say("Could not list messages: %v", err)
+		return
+	}
+	for _, msg := range msgs {
+		if msg.Status == messages.StatusAcked {
+			continue
+		}
+		s.say("Message from %s: %s", msg.From, msg.Body)
+		if err := s.Inbox.Ack(s.Repo, s.Agent, msg.ID); err != nil {
+			s.say("Could not ack %s: %v", msg.ID, err)
+			continue
+		}
+		s.say("Acked %s", msg.ID)
+	}
+}
+
+// finish does a worker's or reviewer's task and completes. Workers commit a
+// file naming their task first.
+func (s *Simulator) finish(agent state.Agent) error {
+	summary := "Simulated review"
+	if agent.Type == state.AgentTypeWorker {
+		commit, err := s.commit(agent)
+		if err != nil {
+			return err
+		}
+		s.say("Committed %s", commit)
+		summary = fmt.Sprintf("Simulated %q in %s", agent.Task, commit)
+	}
+	if err := s.Complete(summary); err != nil {
+		return fmt.Errorf("failed to complete: %w", err)
+	}
+	s.say("Completed: %s", summary)
+	return nil
+}
+
+// commit writes a file for the task to the agent's worktree and commits it,
+// returning the commit's short hash
+func (s *Simulator) commit(agent state.Agent) (string, error) {
+	if agent.WorktreePath == "" {
+		return "", fmt.Errorf("agent has no worktree")
+	}
+	file := "SIMULATED-" + s.Agent + ".md"
+	content := fmt.Sprintf("# %s\n\n%s\n", s.Agent, agent.Task)
+	if err := os.WriteFile(filepath.Join(agent.WorktreePath, file), []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", file, err)
+	}
+
+	subject := strings.SplitN(agent.Task, "\n", 2)[0]
+	if subject == "" {
+		subject = "simulated work"
+	}
+	if _, err := gitcmd.Run(context.Background(), agent.WorktreePath, "add", file); err != nil {
+		return "", err
+	}
+	if _, err := gitcmd.Run(context.Background(), agent.WorktreePath, "-c", "user.name=multiclaude-sim", "-c", "user.email=sim@multiclaude.invalid",
+		"commit", "-m", "Simulate: "+subject); err != nil {
+		return "", err
+	}
+	return gitcmd.Run(context.Background(), agent.WorktreePath, "rev-parse", "--short", "HEAD")
+}
+
+// echoInput reports what's typed into the pane, such as routed messages and
+// nudges, the way Claude would show them
+func (s *Simulator) echoInput() {
+	scanner := bufio.NewScanner(s.Input)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			s.say("Received: %s", line)
+		}
+	}
+}
+
+// say prints a line of the simulator's canned output
+func (s *Simulator) say(format string, args ...interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.Out, "[sim] "+format+"\n", args...)
+}
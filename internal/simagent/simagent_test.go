@@ -0,0 +1,158 @@
+package simagent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+func TestCommand(t *testing.T) {
+	got := Command("/usr/bin/multiclaude", config.RootPaths("/tmp/mc root"), "my-repo", "it's-me")
+	want := `MULTICLAUDE_HOME='/tmp/mc root' XDG_STATE_HOME='' XDG_DATA_HOME='' XDG_RUNTIME_DIR='' /usr/bin/multiclaude agent _simulate --repo my-repo --agent 'it'\''s-me'`
+	if got != want {
+		t.Errorf("Command() = %s\nwant %s", got, want)
+	}
+}
+
+func TestCommandXDG(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(config.HomeEnv, "")
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(home, "run"))
+	paths := config.XDGPaths(home)
+
+	// The pane's shell has its own environment; the command sets the layout
+	t.Setenv(config.HomeEnv, "/elsewhere")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	// A stand-in for multiclaude that prints the layout it was given
+	binary := filepath.Join(home, "multiclaude")
+	script := "#!/bin/sh\n"
+	for _, env := range config.LayoutEnv {
+		script += "echo " + env + "=$" + env + "\n"
+	}
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("sh", "-c", Command(binary, paths, "my-repo", "worker")).Output()
+	if err != nil {
+		t.Fatalf("running Command() failed: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		t.Setenv(key, value)
+	}
+	got, err := config.DefaultPaths()
+	if err != nil {
+		t.Fatalf("DefaultPaths() failed: %v", err)
+	}
+	if got.Root != paths.Root || got.DaemonSock != paths.DaemonSock || got.ReposDir != paths.ReposDir {
+		t.Errorf("the simulator would use root %s, socket %s and repos %s; want %s, %s and %s",
+			got.Root, got.DaemonSock, got.ReposDir, paths.Root, paths.DaemonSock, paths.ReposDir)
+	}
+}
+
+func TestSimulatorWorker(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	inbox := messages.NewManager(t.TempDir())
+	if _, err := inbox.Send("repo", "supervisor", "calm-owl", "how's it going?"); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	// The agent is registered a moment after the simulator starts
+	var mu sync.Mutex
+	registered := false
+	completed := make(chan string, 1)
+	var out bytes.Buffer
+	sim := &Simulator{
+		Repo:  "repo",
+		Agent: "calm-owl",
+		Lookup: func() (state.Agent, bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			agent := state.Agent{Type: state.AgentTypeWorker, WorktreePath: dir, Task: "Fix the login bug"}
+			return agent, registered
+		},
+		Inbox:           inbox,
+		Complete:        func(summary string) error { completed <- summary; return nil },
+		Out:             &out,
+		Poll:            10 * time.Millisecond,
+		RegisterTimeout: 5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sim.Run(ctx) }()
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	registered = true
+	mu.Unlock()
+
+	var summary string
+	select {
+	case summary = <-completed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker never completed")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	cmd := exec.Command("git", "log", "-1", "--format=%s", "--name-only")
+	cmd.Dir = dir
+	log, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	if !strings.Contains(string(log), "Simulate: Fix the login bug") || !strings.Contains(string(log), "SIMULATED-calm-owl.md") {
+		t.Errorf("last commit = %q, want the simulated task", log)
+	}
+	if !strings.Contains(summary, "Fix the login bug") {
+		t.Errorf("summary = %q, want the task", summary)
+	}
+
+	msgs, _ := inbox.List("repo", "calm-owl")
+	if len(msgs) != 1 || msgs[0].Status != messages.StatusAcked {
+		t.Errorf("messages = %+v, want the one message acked", msgs)
+	}
+	if !strings.Contains(out.String(), "[sim] Message from supervisor: how's it going?") {
+		t.Errorf("output = %q, want the message shown", out.String())
+	}
+}
+
+func TestSimulatorNotRegistered(t *testing.T) {
+	sim := &Simulator{
+		Repo:            "repo",
+		Agent:           "ghost",
+		Lookup:          func() (state.Agent, bool) { return state.Agent{}, false },
+		Out:             &bytes.Buffer{},
+		Poll:            5 * time.Millisecond,
+		RegisterTimeout: 20 * time.Millisecond,
+	}
+	if err := sim.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("Run() = %v, want a registration timeout", err)
+	}
+}
@@ -1,17 +1,56 @@
 package socket
 
 import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// Transport selects how the daemon listens for requests
+type Transport string
+
+const (
+	// TransportUnix listens on a Unix socket at the socket path (the default)
+	TransportUnix Transport = "unix"
+	// TransportTCP listens on a random TCP loopback port. The socket path
+	// becomes a regular file holding the address and an access token, which
+	// clients read to connect, so only users who can read it can connect.
+	TransportTCP Transport = "tcp"
+	// TransportAuto uses a Unix socket, falling back to TCP loopback when
+	// the socket can't be created (e.g. on Windows filesystems under WSL)
+	TransportAuto Transport = "auto"
+)
+
+// ParseTransport parses a transport name; empty means TransportUnix
+func ParseTransport(s string) (Transport, error) {
+	switch t := Transport(s); t {
+	case "":
+		return TransportUnix, nil
+	case TransportUnix, TransportTCP, TransportAuto:
+		return t, nil
+	}
+	return "", fmt.Errorf("invalid transport %q: must be 'unix', 'tcp' or 'auto'", s)
+}
+
 // Request represents a request sent to the daemon
 type Request struct {
 	Command string                 `json:"command"`
 	Args    map[string]interface{} `json:"args,omitempty"`
+	// Token authenticates requests over TCP; clients fill it in
+	Token string `json:"token,omitempty"`
+	// TraceID ties the request to the user action it is part of, for the
+	// daemon's log and events (see Client.SetTraceID)
+	TraceID string `json:"trace_id,omitempty"`
 }
 
 // Response represents a response from the daemon
@@ -19,11 +58,17 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// Code and Remediation optionally classify a failure and say how to
+	// fix it; codes are those of internal/errors, e.g. "repo_not_found"
+	Code        string `json:"code,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
 }
 
-// Client connects to the daemon via Unix socket
+// Client connects to the daemon via its Unix socket, or via TCP loopback when
+// the socket path holds a TCP address (see TransportTCP)
 type Client struct {
 	socketPath string
+	traceID    string
 }
 
 // NewClient creates a new socket client
@@ -31,13 +76,45 @@ func NewClient(socketPath string) *Client {
 	return &Client{socketPath: socketPath}
 }
 
+// SetTraceID tags the client's requests that don't have a trace ID of their own
+func (c *Client) SetTraceID(id string) {
+	c.traceID = id
+}
+
+// dial connects to the daemon and returns the token to send with requests
+func (c *Client) dial(timeout time.Duration) (net.Conn, string, error) {
+	network, address, token := "unix", c.socketPath, ""
+	if info, err := os.Stat(c.socketPath); err == nil && info.Mode().IsRegular() {
+		var err error
+		if address, token, err = ReadTCPAddress(c.socketPath); err != nil {
+			return nil, "", fmt.Errorf("failed to connect to daemon: %w", err)
+		}
+		network = "tcp"
+	}
+	var conn net.Conn
+	var err error
+	if timeout > 0 {
+		conn, err = net.DialTimeout(network, address, timeout)
+	} else {
+		conn, err = net.Dial(network, address)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	return conn, token, nil
+}
+
 // Send sends a request to the daemon and returns the response
 func (c *Client) Send(req Request) (*Response, error) {
-	conn, err := net.Dial("unix", c.socketPath)
+	conn, token, err := c.dial(0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+		return nil, err
 	}
 	defer conn.Close()
+	req.Token = token
+	if req.TraceID == "" {
+		req.TraceID = c.traceID
+	}
 
 	// Send request
 	if err := json.NewEncoder(conn).Encode(req); err != nil {
@@ -53,11 +130,109 @@ func (c *Client) Send(req Request) (*Response, error) {
 	return &resp, nil
 }
 
-// Server listens on a Unix socket for requests
+// SendTimeout is like Send but gives up if the daemon hasn't answered within timeout
+func (c *Client) SendTimeout(req Request, timeout time.Duration) (*Response, error) {
+	conn, token, err := c.dial(timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	req.Token = token
+	if req.TraceID == "" {
+		req.TraceID = c.traceID
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// Server listens on a Unix socket (or TCP loopback, see Transport) for requests
 type Server struct {
 	socketPath string
+	transport  Transport
 	listener   net.Listener
 	handler    Handler
+	token      string // Required in requests when listening on TCP
+	limits     Limits
+	stats      serverStats
+}
+
+// Limits bounds what clients can hold on a server. Zero fields mean no limit.
+type Limits struct {
+	// MaxConnections is how many connections are served at once; clients
+	// beyond it are told the server is busy and disconnected
+	MaxConnections int
+	// MaxRequestBytes is the largest request accepted
+	MaxRequestBytes int64
+	// ReadTimeout is how long a client has to send its request
+	ReadTimeout time.Duration
+	// WriteTimeout is how long a client has to read the response
+	WriteTimeout time.Duration
+}
+
+// DefaultLimits are the limits servers start with. The timeouts cover
+// sending the request and reading the response, not the handler, so
+// long-polling commands aren't cut short.
+var DefaultLimits = Limits{
+	MaxConnections:  128,
+	MaxRequestBytes: 8 << 20,
+	ReadTimeout:     30 * time.Second,
+	WriteTimeout:    30 * time.Second,
+}
+
+// rejectTimeout bounds how long telling a rejected client why can take
+const rejectTimeout = time.Second
+
+// Stats counts the connections a server has handled
+type Stats struct {
+	// Accepted is every connection, including rejected ones
+	Accepted uint64 `json:"accepted"`
+	// Active is the connections being served now
+	Active int64 `json:"active"`
+	// Rejected arrived while MaxConnections were being served
+	Rejected uint64 `json:"rejected"`
+	// TooLarge sent a request over MaxRequestBytes
+	TooLarge uint64 `json:"too_large"`
+	// TimedOut didn't send their request or read the response in time
+	TimedOut uint64 `json:"timed_out"`
+}
+
+// serverStats holds the counters behind Stats
+type serverStats struct {
+	accepted, rejected, tooLarge, timedOut atomic.Uint64
+	active                                 atomic.Int64
+}
+
+// errRequestTooLarge is returned by the reader of a request over MaxRequestBytes
+var errRequestTooLarge = errors.New("request too large")
+
+// limitedReader fails reads past its limit with errRequestTooLarge
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errRequestTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
 }
 
 // Handler processes requests
@@ -78,6 +253,28 @@ func NewServer(socketPath string, handler Handler) *Server {
 	return &Server{
 		socketPath: socketPath,
 		handler:    handler,
+		limits:     DefaultLimits,
+	}
+}
+
+// SetTransport selects how Start listens; the default is TransportUnix
+func (s *Server) SetTransport(t Transport) {
+	s.transport = t
+}
+
+// SetLimits replaces DefaultLimits; call it before Serve
+func (s *Server) SetLimits(l Limits) {
+	s.limits = l
+}
+
+// Stats returns the server's connection counters
+func (s *Server) Stats() Stats {
+	return Stats{
+		Accepted: s.stats.accepted.Load(),
+		Active:   s.stats.active.Load(),
+		Rejected: s.stats.rejected.Load(),
+		TooLarge: s.stats.tooLarge.Load(),
+		TimedOut: s.stats.timedOut.Load(),
 	}
 }
 
@@ -88,8 +285,15 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to remove stale socket: %w", err)
 	}
 
+	if s.transport == TransportTCP {
+		return s.startTCP()
+	}
+
 	listener, err := net.Listen("unix", s.socketPath)
 	if err != nil {
+		if s.transport == TransportAuto {
+			return s.startTCP()
+		}
 		return fmt.Errorf("failed to listen on socket: %w", err)
 	}
 
@@ -103,18 +307,106 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// startTCP listens on a random loopback port and writes its address and a
+// fresh access token to the socket path for clients to read
+func (s *Server) startTCP() error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate access token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen on TCP loopback: %w", err)
+	}
+	content := listener.Addr().String() + "\n" + token + "\n"
+	if err := os.WriteFile(s.socketPath, []byte(content), 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to write daemon address: %w", err)
+	}
+
+	s.listener = listener
+	s.token = token
+	return nil
+}
+
+// Address describes where the server is listening, e.g. "unix:/home/me/.multiclaude/daemon.sock"
+// or "tcp:127.0.0.1:41234"
+func (s *Server) Address() string {
+	if s.listener == nil {
+		return ""
+	}
+	addr := s.listener.Addr()
+	return addr.Network() + ":" + addr.String()
+}
+
+// ReadTCPAddress reads the TCP address and access token a daemon listening on
+// TCP loopback wrote to its socket path
+func ReadTCPAddress(path string) (address, token string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	var lines []string
+	for scanner.Scan() && len(lines) < 2 {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	if len(lines) < 2 || lines[0] == "" || lines[1] == "" {
+		return "", "", fmt.Errorf("%s is not a daemon socket or address file", path)
+	}
+	return lines[0], lines[1], nil
+}
+
 // Serve accepts and handles connections
 func (s *Server) Serve() error {
+	var slots chan struct{}
+	if s.limits.MaxConnections > 0 {
+		slots = make(chan struct{}, s.limits.MaxConnections)
+	}
+
 	for {
 		conn, err := s.listener.Accept()
 		if err != nil {
 			return fmt.Errorf("failed to accept connection: %w", err)
 		}
+		s.stats.accepted.Add(1)
 
-		go s.handleConnection(conn)
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			default:
+				s.stats.rejected.Add(1)
+				go s.reject(conn)
+				continue
+			}
+		}
+
+		go func() {
+			s.stats.active.Add(1)
+			defer func() {
+				s.stats.active.Add(-1)
+				if slots != nil {
+					<-slots
+				}
+			}()
+			s.handleConnection(conn)
+		}()
 	}
 }
 
+// reject tells a client over the connection limit to retry, and disconnects it
+func (s *Server) reject(conn net.Conn) {
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(rejectTimeout))
+	json.NewEncoder(conn).Encode(Response{
+		Success: false,
+		Error:   fmt.Sprintf("daemon is busy: %d connections are already open, try again", s.limits.MaxConnections),
+	})
+}
+
 // Stop stops the server
 func (s *Server) Stop() error {
 	if s.listener != nil {
@@ -135,21 +427,52 @@ func (s *Server) Stop() error {
 func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
+	var r io.Reader = conn
+	if s.limits.MaxRequestBytes > 0 {
+		r = &limitedReader{r: conn, n: s.limits.MaxRequestBytes}
+	}
+	if s.limits.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.limits.ReadTimeout))
+	}
+
 	var req Request
-	if err := json.NewDecoder(conn).Decode(&req); err != nil {
-		if err != io.EOF {
-			resp := Response{
-				Success: false,
-				Error:   fmt.Sprintf("failed to decode request: %v", err),
-			}
-			json.NewEncoder(conn).Encode(resp)
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		var netErr net.Error
+		switch {
+		case err == io.EOF:
+		case errors.Is(err, errRequestTooLarge):
+			s.stats.tooLarge.Add(1)
+			s.respond(conn, Response{Success: false, Error: fmt.Sprintf("request is larger than %d bytes", s.limits.MaxRequestBytes)})
+		case errors.As(err, &netErr) && netErr.Timeout():
+			s.stats.timedOut.Add(1)
+			s.respond(conn, Response{Success: false, Error: fmt.Sprintf("no request received within %s", s.limits.ReadTimeout)})
+		default:
+			s.respond(conn, Response{Success: false, Error: fmt.Sprintf("failed to decode request: %v", err)})
 		}
 		return
 	}
+	// The handler may take as long as it needs
+	conn.SetReadDeadline(time.Time{})
 
-	resp := s.handler.Handle(req)
-	if err := json.NewEncoder(conn).Encode(resp); err != nil {
-		// Can't send error response at this point
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.token)) != 1 {
+		s.respond(conn, Response{Success: false, Error: "invalid or missing access token"})
 		return
 	}
+	req.Token = ""
+
+	s.respond(conn, s.handler.Handle(req))
+}
+
+// respond writes a response, giving up on clients that don't read it in time
+func (s *Server) respond(conn net.Conn, resp Response) {
+	if s.limits.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(s.limits.WriteTimeout))
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		// Can't send an error response at this point
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			s.stats.timedOut.Add(1)
+		}
+	}
 }
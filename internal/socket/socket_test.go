@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -346,3 +347,241 @@ func TestServerStopRemovesSocket(t *testing.T) {
 		t.Error("Socket file should be removed after Stop()")
 	}
 }
+
+func TestServerTCPTransport(t *testing.T) {
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "daemon.sock")
+
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response {
+		return Response{Success: true, Data: req.Command}
+	}))
+	server.SetTransport(TransportTCP)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	if !strings.HasPrefix(server.Address(), "tcp:127.0.0.1:") {
+		t.Errorf("Address() = %q, want a TCP loopback address", server.Address())
+	}
+	info, err := os.Stat(sockPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("address file: %v, %v; want a 0600 file", info, err)
+	}
+
+	// Clients find the daemon through the address file
+	resp, err := NewClient(sockPath).Send(Request{Command: "ping"})
+	if err != nil || !resp.Success || resp.Data != "ping" {
+		t.Fatalf("Send() = %+v, %v", resp, err)
+	}
+
+	// Requests without the token are rejected
+	address, _, err := ReadTCPAddress(sockPath)
+	if err != nil {
+		t.Fatalf("ReadTCPAddress() failed: %v", err)
+	}
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer conn.Close()
+	json.NewEncoder(conn).Encode(Request{Command: "ping"})
+	var rejected Response
+	if err := json.NewDecoder(conn).Decode(&rejected); err != nil || rejected.Success {
+		t.Errorf("request without a token = %+v, %v; want rejected", rejected, err)
+	}
+
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if _, err := os.Stat(sockPath); !os.IsNotExist(err) {
+		t.Error("Stop() should remove the address file")
+	}
+}
+
+func TestServerAutoTransportFallsBack(t *testing.T) {
+	// Unix socket paths are limited to about 100 bytes, so this one can't be a socket
+	dir := filepath.Join(t.TempDir(), strings.Repeat("d", 120))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sockPath := filepath.Join(dir, "daemon.sock")
+
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response { return Response{Success: true} }))
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Skip("this platform allows long Unix socket paths")
+	}
+
+	server.SetTransport(TransportAuto)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() with auto transport failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	if !strings.HasPrefix(server.Address(), "tcp:") {
+		t.Errorf("Address() = %q, want the TCP fallback", server.Address())
+	}
+	if resp, err := NewClient(sockPath).Send(Request{Command: "ping"}); err != nil || !resp.Success {
+		t.Errorf("Send() = %+v, %v", resp, err)
+	}
+}
+
+func TestParseTransport(t *testing.T) {
+	for in, want := range map[string]Transport{"": TransportUnix, "unix": TransportUnix, "tcp": TransportTCP, "auto": TransportAuto} {
+		if got, err := ParseTransport(in); err != nil || got != want {
+			t.Errorf("ParseTransport(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseTransport("pipe"); err == nil {
+		t.Error("ParseTransport(pipe) should fail")
+	}
+}
+
+// startLimitedServer serves handler with limits and returns the socket path
+func startLimitedServer(t *testing.T, limits Limits, handler Handler) (*Server, string) {
+	t.Helper()
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(sockPath, handler)
+	server.SetLimits(limits)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+	go server.Serve()
+	return server, sockPath
+}
+
+// readResponse reads one response from a raw connection
+func readResponse(t *testing.T, conn net.Conn) Response {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp
+}
+
+func TestServerReadTimeout(t *testing.T) {
+	server, sockPath := startLimitedServer(t, Limits{ReadTimeout: 100 * time.Millisecond}, HandlerFunc(func(req Request) Response {
+		return Response{Success: true}
+	}))
+
+	// A client that connects and sends half a request is let go
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(`{"command":`))
+
+	resp := readResponse(t, conn)
+	if resp.Success || !strings.Contains(resp.Error, "no request received") {
+		t.Errorf("response = %+v, want a timeout error", resp)
+	}
+	if stats := server.Stats(); stats.TimedOut != 1 {
+		t.Errorf("TimedOut = %d, want 1", stats.TimedOut)
+	}
+
+	// The timeout doesn't cover the handler
+	_, sockPath = startLimitedServer(t, Limits{ReadTimeout: 50 * time.Millisecond, WriteTimeout: 50 * time.Millisecond}, HandlerFunc(func(req Request) Response {
+		time.Sleep(200 * time.Millisecond)
+		return Response{Success: true}
+	}))
+	if resp, err := NewClient(sockPath).Send(Request{Command: "slow"}); err != nil || !resp.Success {
+		t.Errorf("slow handler: resp = %+v, err = %v", resp, err)
+	}
+}
+
+func TestServerMaxRequestBytes(t *testing.T) {
+	server, sockPath := startLimitedServer(t, Limits{MaxRequestBytes: 256}, HandlerFunc(func(req Request) Response {
+		return Response{Success: true}
+	}))
+	client := NewClient(sockPath)
+
+	if resp, err := client.Send(Request{Command: "small"}); err != nil || !resp.Success {
+		t.Fatalf("small request: resp = %+v, err = %v", resp, err)
+	}
+
+	resp, err := client.Send(Request{Command: "big", Args: map[string]interface{}{"body": strings.Repeat("x", 1024)}})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "larger than 256 bytes") {
+		t.Errorf("response = %+v, want a size error", resp)
+	}
+	if stats := server.Stats(); stats.TooLarge != 1 || stats.Accepted != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestServerMaxConnections(t *testing.T) {
+	release := make(chan struct{})
+	server, sockPath := startLimitedServer(t, Limits{MaxConnections: 1}, HandlerFunc(func(req Request) Response {
+		<-release
+		return Response{Success: true}
+	}))
+
+	// Hold the only slot
+	held := make(chan *Response, 1)
+	go func() {
+		resp, _ := NewClient(sockPath).Send(Request{Command: "hold"})
+		held <- resp
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Stats().Active != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := NewClient(sockPath).Send(Request{Command: "extra"})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "daemon is busy") {
+		t.Errorf("response = %+v, want a busy error", resp)
+	}
+
+	close(release)
+	if resp := <-held; resp == nil || !resp.Success {
+		t.Errorf("held request: resp = %+v", resp)
+	}
+	stats := server.Stats()
+	if stats.Rejected != 1 || stats.Accepted != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// The slot is free again once the held connection is done
+	for server.Stats().Active != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if resp, err := NewClient(sockPath).Send(Request{Command: "after"}); err != nil || !resp.Success {
+		t.Errorf("after release: resp = %+v, err = %v", resp, err)
+	}
+}
+
+func TestClientTraceID(t *testing.T) {
+	got := make(chan string, 2)
+	_, sockPath := startLimitedServer(t, DefaultLimits, HandlerFunc(func(req Request) Response {
+		got <- req.TraceID
+		return Response{Success: true}
+	}))
+
+	client := NewClient(sockPath)
+	client.SetTraceID("3f9a0c12d4e5")
+	if _, err := client.Send(Request{Command: "traced"}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if _, err := client.SendTimeout(Request{Command: "own", TraceID: "abc"}, time.Second); err != nil {
+		t.Fatalf("SendTimeout() failed: %v", err)
+	}
+
+	if id := <-got; id != "3f9a0c12d4e5" {
+		t.Errorf("trace ID = %q, want the client's", id)
+	}
+	if id := <-got; id != "abc" {
+		t.Errorf("trace ID = %q, want the request's own", id)
+	}
+}
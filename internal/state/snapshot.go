@@ -0,0 +1,376 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotIDFormat is the time layout used for snapshot IDs and file names
+const SnapshotIDFormat = "20060102-150405"
+
+// snapshotPrefix and snapshotExt frame the snapshot ID in file names
+const (
+	snapshotPrefix = "state-"
+	snapshotExt    = ".json"
+)
+
+// MessageIndexEntry records a message's identity and status at snapshot time
+type MessageIndexEntry struct {
+	ID     string `json:"id"`
+	From   string `json:"from"`
+	Status string `json:"status"`
+}
+
+// Snapshot is a point-in-time copy of state.json plus the message index
+type Snapshot struct {
+	ID        string          `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Reason    string          `json:"reason,omitempty"`
+	Checksum  string          `json:"checksum"`
+	State     json.RawMessage `json:"state"`
+	// Messages maps "<repo>/<agent>" to the messages in that inbox
+	Messages map[string][]MessageIndexEntry `json:"messages,omitempty"`
+}
+
+// SnapshotInfo describes a snapshot on disk without loading it
+type SnapshotInfo struct {
+	ID   string
+	Time time.Time
+	Path string
+}
+
+// TakeSnapshot captures the current state and the given message index
+func (s *State) TakeSnapshot(reason string, msgIndex map[string][]MessageIndexEntry) (*Snapshot, error) {
+	s.mu.RLock()
+	data, err := json.Marshal(s)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	for key := range msgIndex {
+		entries := msgIndex[key]
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	}
+
+	index, err := json.Marshal(msgIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message index: %w", err)
+	}
+	sum := sha256.Sum256(append(data, index...))
+
+	now := time.Now()
+	return &Snapshot{
+		ID:        now.Format(SnapshotIDFormat),
+		Timestamp: now,
+		Reason:    reason,
+		Checksum:  hex.EncodeToString(sum[:]),
+		State:     data,
+		Messages:  msgIndex,
+	}, nil
+}
+
+// ParseState decodes the state held in a snapshot. The result is detached
+// from any file and must not be saved.
+func (snap *Snapshot) ParseState() (*State, error) {
+	var s State
+	if err := json.Unmarshal(snap.State, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", snap.ID, err)
+	}
+	if s.Repos == nil {
+		s.Repos = make(map[string]*Repository)
+	}
+	if s.Projects == nil {
+		s.Projects = make(map[string]*Project)
+	}
+	return &s, nil
+}
+
+// Restore replaces the current state with the contents of a snapshot and saves it
+func (s *State) Restore(snap *Snapshot) error {
+	restored, err := snap.ParseState()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Repos = restored.Repos
+	s.CurrentRepo = restored.CurrentRepo
+	s.Projects = restored.Projects
+	return s.saveUnlocked()
+}
+
+// WriteSnapshot stores a snapshot in dir and returns its path. If a snapshot
+// with the same ID exists, the ID is moved forward a second at a time so
+// snapshots taken in quick succession don't overwrite each other.
+func WriteSnapshot(dir string, snap *Snapshot) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	path := filepath.Join(dir, snapshotPrefix+snap.ID+snapshotExt)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		snap.Timestamp = snap.Timestamp.Add(time.Second)
+		snap.ID = snap.Timestamp.Format(SnapshotIDFormat)
+		path = filepath.Join(dir, snapshotPrefix+snap.ID+snapshotExt)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	if err := atomicWrite(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadSnapshot reads a snapshot from disk
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", filepath.Base(path), err)
+	}
+	return &snap, nil
+}
+
+// ListSnapshots returns the snapshots in dir, oldest first
+func ListSnapshots(dir string) ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var snapshots []SnapshotInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotExt)
+		t, err := time.ParseInLocation(SnapshotIDFormat, id, time.Local)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, SnapshotInfo{ID: id, Time: t, Path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots and returns how many were removed
+func PruneSnapshots(dir string, keep int) (int, error) {
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(snapshots) <= keep {
+		return 0, nil
+	}
+
+	removed := 0
+	for _, snap := range snapshots[:len(snapshots)-keep] {
+		if err := os.Remove(snap.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove snapshot %s: %w", snap.ID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// snapshotTimeLayouts are the accepted formats for --to timestamps
+var snapshotTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FindSnapshot resolves a snapshot ID or timestamp to a snapshot. A timestamp
+// selects the newest snapshot taken at or before that time.
+func FindSnapshot(dir, to string) (SnapshotInfo, error) {
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	if len(snapshots) == 0 {
+		return SnapshotInfo{}, fmt.Errorf("no snapshots found")
+	}
+
+	for _, snap := range snapshots {
+		if snap.ID == to {
+			return snap, nil
+		}
+	}
+
+	var target time.Time
+	for _, layout := range append([]string{SnapshotIDFormat}, snapshotTimeLayouts...) {
+		if t, err := time.ParseInLocation(layout, to, time.Local); err == nil {
+			target = t
+			break
+		}
+	}
+	if target.IsZero() {
+		return SnapshotInfo{}, fmt.Errorf("invalid timestamp %q: use a snapshot ID (%s) or a time like 2006-01-02 15:04", to, SnapshotIDFormat)
+	}
+
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].Time.After(target) {
+			return snapshots[i], nil
+		}
+	}
+	return SnapshotInfo{}, fmt.Errorf("no snapshot at or before %s (oldest is %s)", to, snapshots[0].ID)
+}
+
+// DiffSnapshots describes how state and messages changed from a to b, one line per change.
+// Lines start with "+" (added), "-" (removed) or "~" (changed).
+func DiffSnapshots(a, b *Snapshot) ([]string, error) {
+	stateA, err := a.ParseState()
+	if err != nil {
+		return nil, err
+	}
+	stateB, err := b.ParseState()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, repoName := range unionKeys(stateA.Repos, stateB.Repos) {
+		repoA, inA := stateA.Repos[repoName]
+		repoB, inB := stateB.Repos[repoName]
+		switch {
+		case !inA:
+			lines = append(lines, fmt.Sprintf("+ repo %s (%d agents)", repoName, len(repoB.Agents)))
+			continue
+		case !inB:
+			lines = append(lines, fmt.Sprintf("- repo %s (%d agents)", repoName, len(repoA.Agents)))
+			continue
+		}
+
+		for _, agentName := range unionKeys(repoA.Agents, repoB.Agents) {
+			agentA, inA := repoA.Agents[agentName]
+			agentB, inB := repoB.Agents[agentName]
+			switch {
+			case !inA:
+				lines = append(lines, fmt.Sprintf("+ agent %s/%s (%s)", repoName, agentName, agentB.Type))
+			case !inB:
+				lines = append(lines, fmt.Sprintf("- agent %s/%s (%s)", repoName, agentName, agentA.Type))
+			default:
+				for _, change := range diffAgent(agentA, agentB) {
+					lines = append(lines, fmt.Sprintf("~ agent %s/%s: %s", repoName, agentName, change))
+				}
+			}
+		}
+
+		if len(repoA.TaskHistory) != len(repoB.TaskHistory) {
+			lines = append(lines, fmt.Sprintf("~ repo %s: task history %d -> %d entries", repoName, len(repoA.TaskHistory), len(repoB.TaskHistory)))
+		}
+	}
+
+	for _, projectName := range unionKeys(stateA.Projects, stateB.Projects) {
+		_, inA := stateA.Projects[projectName]
+		_, inB := stateB.Projects[projectName]
+		if !inA {
+			lines = append(lines, fmt.Sprintf("+ project %s", projectName))
+		} else if !inB {
+			lines = append(lines, fmt.Sprintf("- project %s", projectName))
+		}
+	}
+
+	if stateA.CurrentRepo != stateB.CurrentRepo {
+		lines = append(lines, fmt.Sprintf("~ current repo: %q -> %q", stateA.CurrentRepo, stateB.CurrentRepo))
+	}
+
+	for _, inbox := range unionKeys(a.Messages, b.Messages) {
+		msgsA := indexByID(a.Messages[inbox])
+		msgsB := indexByID(b.Messages[inbox])
+		for _, id := range unionKeys(msgsA, msgsB) {
+			msgA, inA := msgsA[id]
+			msgB, inB := msgsB[id]
+			switch {
+			case !inA:
+				lines = append(lines, fmt.Sprintf("+ message %s %s from %s (%s)", inbox, id, msgB.From, msgB.Status))
+			case !inB:
+				lines = append(lines, fmt.Sprintf("- message %s %s from %s (%s)", inbox, id, msgA.From, msgA.Status))
+			case msgA.Status != msgB.Status:
+				lines = append(lines, fmt.Sprintf("~ message %s %s: %s -> %s", inbox, id, msgA.Status, msgB.Status))
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// diffAgent lists the notable field changes between two versions of an agent
+func diffAgent(a, b Agent) []string {
+	var changes []string
+	if a.Type != b.Type {
+		changes = append(changes, fmt.Sprintf("type %s -> %s", a.Type, b.Type))
+	}
+	if a.TmuxWindow != b.TmuxWindow {
+		changes = append(changes, fmt.Sprintf("window %s -> %s", a.TmuxWindow, b.TmuxWindow))
+	}
+	if a.WorktreePath != b.WorktreePath {
+		changes = append(changes, fmt.Sprintf("worktree %s -> %s", a.WorktreePath, b.WorktreePath))
+	}
+	if a.PID != b.PID {
+		changes = append(changes, fmt.Sprintf("pid %d -> %d", a.PID, b.PID))
+	}
+	if a.Task != b.Task {
+		changes = append(changes, "task changed")
+	}
+	if a.ReadyForCleanup != b.ReadyForCleanup {
+		changes = append(changes, fmt.Sprintf("ready_for_cleanup %v -> %v", a.ReadyForCleanup, b.ReadyForCleanup))
+	}
+	return changes
+}
+
+// indexByID maps message index entries by ID
+func indexByID(entries []MessageIndexEntry) map[string]MessageIndexEntry {
+	m := make(map[string]MessageIndexEntry, len(entries))
+	for _, e := range entries {
+		m[e.ID] = e
+	}
+	return m
+}
+
+// unionKeys returns the sorted union of two maps' keys
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,264 @@
+package state
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSnapshotTestState(t *testing.T) *State {
+	t.Helper()
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("repo", &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-repo",
+		Agents:      make(map[string]Agent),
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	for _, name := range []string{"supervisor", "happy-eagle"} {
+		agentType := AgentTypeWorker
+		if name == "supervisor" {
+			agentType = AgentTypeSupervisor
+		}
+		if err := s.AddAgent("repo", name, Agent{Type: agentType, TmuxWindow: name}); err != nil {
+			t.Fatalf("AddAgent(%s) failed: %v", name, err)
+		}
+	}
+	return s
+}
+
+// writeSnapshotAt takes a snapshot of s and stores it under the given time
+func writeSnapshotAt(t *testing.T, s *State, dir string, at time.Time) *Snapshot {
+	t.Helper()
+	snap, err := s.TakeSnapshot("test", nil)
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+	snap.ID = at.Format(SnapshotIDFormat)
+	snap.Timestamp = at
+	if _, err := WriteSnapshot(dir, snap); err != nil {
+		t.Fatalf("WriteSnapshot() failed: %v", err)
+	}
+	return snap
+}
+
+func TestTakeSnapshotChecksum(t *testing.T) {
+	s := newSnapshotTestState(t)
+
+	a, err := s.TakeSnapshot("periodic", nil)
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+	b, err := s.TakeSnapshot("periodic", nil)
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+	if a.Checksum != b.Checksum {
+		t.Error("checksum changed without a state change")
+	}
+
+	if err := s.RemoveAgent("repo", "happy-eagle"); err != nil {
+		t.Fatalf("RemoveAgent() failed: %v", err)
+	}
+	c, err := s.TakeSnapshot("periodic", nil)
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+	if a.Checksum == c.Checksum {
+		t.Error("checksum unchanged after removing an agent")
+	}
+
+	d, err := s.TakeSnapshot("periodic", map[string][]MessageIndexEntry{
+		"repo/supervisor": {{ID: "msg-1", From: "happy-eagle", Status: "pending"}},
+	})
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+	if c.Checksum == d.Checksum {
+		t.Error("checksum unchanged after adding a message")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	s := newSnapshotTestState(t)
+	dir := t.TempDir()
+
+	snap := writeSnapshotAt(t, s, dir, time.Now())
+
+	if err := s.RemoveAgent("repo", "happy-eagle"); err != nil {
+		t.Fatalf("RemoveAgent() failed: %v", err)
+	}
+
+	info, err := FindSnapshot(dir, snap.ID)
+	if err != nil {
+		t.Fatalf("FindSnapshot() failed: %v", err)
+	}
+	loaded, err := LoadSnapshot(info.Path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot() failed: %v", err)
+	}
+	if err := s.Restore(loaded); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+
+	if _, exists := s.GetAgent("repo", "happy-eagle"); !exists {
+		t.Error("agent not restored in memory")
+	}
+
+	reloaded, err := Load(s.path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if _, exists := reloaded.GetAgent("repo", "happy-eagle"); !exists {
+		t.Error("restored agent not saved to disk")
+	}
+}
+
+func TestListAndPruneSnapshots(t *testing.T) {
+	s := newSnapshotTestState(t)
+	dir := t.TempDir()
+
+	// Missing directory is not an error
+	snapshots, err := ListSnapshots(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("ListSnapshots() on missing dir failed: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("ListSnapshots() on missing dir = %d, want 0", len(snapshots))
+	}
+
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	for i := 4; i >= 0; i-- {
+		writeSnapshotAt(t, s, dir, base.Add(time.Duration(i)*time.Minute))
+	}
+
+	snapshots, err = ListSnapshots(dir)
+	if err != nil {
+		t.Fatalf("ListSnapshots() failed: %v", err)
+	}
+	if len(snapshots) != 5 {
+		t.Fatalf("ListSnapshots() = %d, want 5", len(snapshots))
+	}
+	for i := 1; i < len(snapshots); i++ {
+		if !snapshots[i-1].Time.Before(snapshots[i].Time) {
+			t.Errorf("snapshots not sorted oldest first: %s before %s", snapshots[i-1].ID, snapshots[i].ID)
+		}
+	}
+
+	removed, err := PruneSnapshots(dir, 3)
+	if err != nil {
+		t.Fatalf("PruneSnapshots() failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("PruneSnapshots() removed %d, want 2", removed)
+	}
+
+	snapshots, err = ListSnapshots(dir)
+	if err != nil {
+		t.Fatalf("ListSnapshots() failed: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("ListSnapshots() after prune = %d, want 3", len(snapshots))
+	}
+	if want := base.Add(2 * time.Minute).Format(SnapshotIDFormat); snapshots[0].ID != want {
+		t.Errorf("oldest kept snapshot = %s, want %s", snapshots[0].ID, want)
+	}
+}
+
+func TestFindSnapshot(t *testing.T) {
+	s := newSnapshotTestState(t)
+	dir := t.TempDir()
+
+	if _, err := FindSnapshot(dir, "20261016-120000"); err == nil {
+		t.Error("FindSnapshot() with no snapshots should fail")
+	}
+
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	writeSnapshotAt(t, s, dir, base)
+	writeSnapshotAt(t, s, dir, base.Add(10*time.Minute))
+
+	tests := []struct {
+		name    string
+		to      string
+		want    string
+		wantErr bool
+	}{
+		{name: "exact ID", to: "20261016-121000", want: "20261016-121000"},
+		{name: "timestamp between snapshots", to: "2026-10-16 12:05", want: "20261016-120000"},
+		{name: "timestamp after newest", to: "2026-10-16T13:00:00", want: "20261016-121000"},
+		{name: "timestamp before oldest", to: "2026-10-16 11:00", wantErr: true},
+		{name: "garbage", to: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := FindSnapshot(dir, tt.to)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("FindSnapshot(%q) = %s, want error", tt.to, info.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindSnapshot(%q) failed: %v", tt.to, err)
+			}
+			if info.ID != tt.want {
+				t.Errorf("FindSnapshot(%q) = %s, want %s", tt.to, info.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	s := newSnapshotTestState(t)
+
+	before, err := s.TakeSnapshot("test", map[string][]MessageIndexEntry{
+		"repo/supervisor": {{ID: "msg-1", From: "happy-eagle", Status: "pending"}},
+	})
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+
+	if err := s.RemoveAgent("repo", "happy-eagle"); err != nil {
+		t.Fatalf("RemoveAgent() failed: %v", err)
+	}
+	if err := s.AddAgent("repo", "calm-owl", Agent{Type: AgentTypeWorker, TmuxWindow: "calm-owl"}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	if err := s.UpdateAgentPID("repo", "supervisor", 4242); err != nil {
+		t.Fatalf("UpdateAgentPID() failed: %v", err)
+	}
+
+	after, err := s.TakeSnapshot("test", map[string][]MessageIndexEntry{
+		"repo/supervisor": {{ID: "msg-1", From: "happy-eagle", Status: "acked"}},
+	})
+	if err != nil {
+		t.Fatalf("TakeSnapshot() failed: %v", err)
+	}
+
+	lines, err := DiffSnapshots(before, after)
+	if err != nil {
+		t.Fatalf("DiffSnapshots() failed: %v", err)
+	}
+	joined := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"+ agent repo/calm-owl (worker)",
+		"- agent repo/happy-eagle (worker)",
+		"~ agent repo/supervisor: pid 0 -> 4242",
+		"~ message repo/supervisor msg-1: pending -> acked",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("diff missing %q, got:\n%s", want, joined)
+		}
+	}
+
+	same, err := DiffSnapshots(after, after)
+	if err != nil {
+		t.Fatalf("DiffSnapshots() failed: %v", err)
+	}
+	if len(same) != 0 {
+		t.Errorf("DiffSnapshots() of identical snapshots = %v, want none", same)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,21 +21,29 @@ const (
 	AgentTypeWorkspace         AgentType = "workspace"
 	AgentTypeReview            AgentType = "review"
 	AgentTypeGenericPersistent AgentType = "generic-persistent"
+	AgentTypeObserver          AgentType = "observer"
+	AgentTypeSolo              AgentType = "solo"
 )
 
 // IsPersistent returns true if this agent type represents a persistent agent
 // that should be auto-restarted when dead. Persistent agents include supervisor,
-// merge-queue, pr-shepherd, workspace, and generic-persistent. Transient agents
-// (worker, review) are not auto-restarted.
+// merge-queue, pr-shepherd, workspace, generic-persistent and observer. Transient
+// agents (worker, review, solo) are not auto-restarted.
 func (t AgentType) IsPersistent() bool {
 	switch t {
-	case AgentTypeSupervisor, AgentTypeMergeQueue, AgentTypePRShepherd, AgentTypeWorkspace, AgentTypeGenericPersistent:
+	case AgentTypeSupervisor, AgentTypeMergeQueue, AgentTypePRShepherd, AgentTypeWorkspace, AgentTypeGenericPersistent, AgentTypeObserver:
 		return true
 	default:
 		return false
 	}
 }
 
+// IsReadOnly returns true if this agent type may read the repository but not
+// edit files. Observer agents are read-only.
+func (t AgentType) IsReadOnly() bool {
+	return t == AgentTypeObserver
+}
+
 // TrackMode defines which PRs the merge queue should track
 type TrackMode string
 
@@ -68,6 +77,9 @@ type MergeQueueConfig struct {
 	Enabled bool `json:"enabled"`
 	// TrackMode determines which PRs to track: "all", "author", or "assigned" (default: "all")
 	TrackMode TrackMode `json:"track_mode"`
+	// TestCommand, when set, is run on each open PR merged onto the merge
+	// train's tip before the merge queue may merge it
+	TestCommand string `json:"test_command,omitempty"`
 }
 
 // DefaultMergeQueueConfig returns the default merge queue configuration
@@ -108,6 +120,385 @@ type ForkConfig struct {
 	ForceForkMode bool `json:"force_fork_mode,omitempty"`
 }
 
+// NotifyMethod is how email notifications are delivered
+type NotifyMethod string
+
+const (
+	// NotifyMethodSMTP sends mail through an SMTP server
+	NotifyMethodSMTP NotifyMethod = "smtp"
+	// NotifyMethodSendmail pipes mail to the local sendmail binary
+	NotifyMethodSendmail NotifyMethod = "sendmail"
+)
+
+// ParseNotifyMethod converts a string to a NotifyMethod, returning an error if invalid
+func ParseNotifyMethod(s string) (NotifyMethod, error) {
+	switch s {
+	case string(NotifyMethodSMTP):
+		return NotifyMethodSMTP, nil
+	case string(NotifyMethodSendmail):
+		return NotifyMethodSendmail, nil
+	default:
+		return "", fmt.Errorf("invalid notify method: %s (must be 'smtp' or 'sendmail')", s)
+	}
+}
+
+// HumanNotifier is a way to announce an agent waiting on a human at a
+// permission prompt or question
+type HumanNotifier string
+
+const (
+	// HumanNotifierTmux shows a popup in every attached tmux client
+	HumanNotifierTmux HumanNotifier = "tmux"
+	// HumanNotifierDesktop shows a desktop notification (terminal-notifier or notify-send)
+	HumanNotifierDesktop HumanNotifier = "desktop"
+	// HumanNotifierWebhook POSTs the alert as JSON to NotifyConfig.Webhook
+	HumanNotifierWebhook HumanNotifier = "webhook"
+)
+
+// ParseHumanNotifier converts a string to a HumanNotifier, returning an error if invalid
+func ParseHumanNotifier(s string) (HumanNotifier, error) {
+	switch s {
+	case string(HumanNotifierTmux), string(HumanNotifierDesktop), string(HumanNotifierWebhook):
+		return HumanNotifier(s), nil
+	default:
+		return "", fmt.Errorf("invalid human notifier: %s (must be 'tmux', 'desktop' or 'webhook')", s)
+	}
+}
+
+// NotifyConfig holds email notification configuration for a repository.
+// Notifications cover supervisor escalations and agent crashes.
+type NotifyConfig struct {
+	// Enabled determines whether notifications are sent for this repository
+	Enabled bool `json:"enabled"`
+	// Method is the delivery method (defaults to sendmail)
+	Method NotifyMethod `json:"method,omitempty"`
+	// To lists the recipient addresses
+	To []string `json:"to,omitempty"`
+	// From is the sender address
+	From string `json:"from,omitempty"`
+	// SMTPAddr is the SMTP server as host:port (smtp method only)
+	SMTPAddr string `json:"smtp_addr,omitempty"`
+	// SMTPUsername is the SMTP auth user; the password is read from
+	// MULTICLAUDE_SMTP_PASSWORD so it never lands in state.json
+	SMTPUsername string `json:"smtp_username,omitempty"`
+	// DigestMinutes batches events into one email per interval (0 sends immediately)
+	DigestMinutes int `json:"digest_minutes,omitempty"`
+	// NeedsHuman lists the notifiers for agents waiting at a permission
+	// prompt or question. They work whether or not email is enabled; when it
+	// is, these alerts are emailed too.
+	NeedsHuman []HumanNotifier `json:"needs_human,omitempty"`
+	// Webhook is the URL the webhook notifier POSTs to
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// FederationConfig holds team federation settings for a repository.
+// Federated daemons share worker status and messages through a relay.
+type FederationConfig struct {
+	// Enabled determines whether this daemon syncs with the relay
+	Enabled bool `json:"enabled"`
+	// Relay is "git" (a branch on origin) or an absolute path to a shared directory
+	Relay string `json:"relay,omitempty"`
+	// Branch is the git relay branch (defaults to multiclaude-federation)
+	Branch string `json:"branch,omitempty"`
+	// PeerID names this daemon to teammates (defaults to <user>@<host>)
+	PeerID string `json:"peer_id,omitempty"`
+}
+
+// ZombieAction is how the daemon remediates an agent that is alive but stuck
+type ZombieAction string
+
+const (
+	// ZombieActionNudge sends the agent a message asking it to report what is blocking it
+	ZombieActionNudge ZombieAction = "nudge"
+	// ZombieActionRestart stops the stuck Claude process and resumes its session
+	ZombieActionRestart ZombieAction = "restart"
+	// ZombieActionEscalate messages the human and the supervisor
+	ZombieActionEscalate ZombieAction = "escalate"
+	// ZombieActionIgnore leaves the agent alone
+	ZombieActionIgnore ZombieAction = "ignore"
+)
+
+// ParseZombieAction converts a string to a ZombieAction, returning an error if invalid
+func ParseZombieAction(s string) (ZombieAction, error) {
+	switch ZombieAction(s) {
+	case ZombieActionNudge, ZombieActionRestart, ZombieActionEscalate, ZombieActionIgnore:
+		return ZombieAction(s), nil
+	default:
+		return "", fmt.Errorf("invalid zombie action: %s (must be 'nudge', 'restart', 'escalate' or 'ignore')", s)
+	}
+}
+
+// ZombieConfig holds zombie agent detection settings for a repository.
+// Detection is on by default; empty actions use the defaults in package zombie.
+type ZombieConfig struct {
+	// Disabled turns zombie detection off for this repository
+	Disabled bool `json:"disabled,omitempty"`
+	// StallMinutes is how long an agent may show no new output before it counts as stalled (default 120)
+	StallMinutes int `json:"stall_minutes,omitempty"`
+	// Stalled is the remediation for agents with no new output (default nudge)
+	Stalled ZombieAction `json:"stalled,omitempty"`
+	// Looping is the remediation for agents repeating the same output (default restart)
+	Looping ZombieAction `json:"looping,omitempty"`
+	// PermissionPrompt is the remediation for agents waiting at a permission prompt (default escalate)
+	PermissionPrompt ZombieAction `json:"permission_prompt,omitempty"`
+}
+
+// WorktreeConfig holds setup steps run in each new worktree of a repository,
+// for repositories whose checkouts are incomplete without them, and where
+// read-only agents get their worktrees
+type WorktreeConfig struct {
+	// LFS runs `git lfs pull` to download Git LFS objects
+	LFS bool `json:"lfs,omitempty"`
+	// Submodules runs `git submodule update --init --recursive`
+	Submodules bool `json:"submodules,omitempty"`
+	// Mirror keeps a bare mirror clone that reviewers and observers check
+	// out from, instead of the main clone
+	Mirror bool `json:"mirror,omitempty"`
+	// Base is the directory the repository's agent worktrees are created
+	// under, as <base>/<repo>/<agent>, e.g. on a faster disk (empty means
+	// ~/.multiclaude/wts)
+	Base string `json:"base,omitempty"`
+}
+
+// SpawnHooks are shell commands the daemon runs around starting a worker, to
+// register it with outside trackers or provision what it needs. They run in
+// the worker's worktree with MULTICLAUDE_AGENT, MULTICLAUDE_REPO,
+// MULTICLAUDE_WORKTREE, MULTICLAUDE_BRANCH and MULTICLAUDE_TASK set.
+type SpawnHooks struct {
+	// PreSpawn runs before Claude starts; if it fails the worker isn't started
+	PreSpawn string `json:"pre_spawn,omitempty"`
+	// PostSpawn runs once the worker is running; failures are only logged
+	PostSpawn string `json:"post_spawn,omitempty"`
+}
+
+// CommentCommands lets people steer a repository's workers from GitHub with
+// "/multiclaude <verb>" lines in PR and issue comments
+type CommentCommands struct {
+	// Allow lists the GitHub logins whose commands are followed; empty turns
+	// comment commands off
+	Allow []string `json:"allow,omitempty"`
+}
+
+// PRDescriptions has the daemon write the descriptions of workers' PRs, from
+// their task, acceptance criteria, commits and a summary of the diff (see
+// package prbody), when they complete or mark their PR ready
+type PRDescriptions struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Template is a text/template file, relative to the repository root and
+	// read from the worker's branch; empty uses the built-in template
+	Template string `json:"template,omitempty"`
+}
+
+// PushChecks are what a worker's branch must pass before it's pushed or its
+// PR marked ready (see package pushcheck). The zero value checks nothing.
+type PushChecks struct {
+	// Rebased requires the branch to contain the latest base branch
+	Rebased bool `json:"rebased,omitempty"`
+	// Signed requires every commit on the branch to be signed
+	Signed bool `json:"signed,omitempty"`
+	// MessagePattern is a regular expression every commit's subject line
+	// must match; empty allows any
+	MessagePattern string `json:"message_pattern,omitempty"`
+}
+
+// Enabled reports whether any check is on
+func (p PushChecks) Enabled() bool {
+	return p.Rebased || p.Signed || p.MessagePattern != ""
+}
+
+// WorkHours limit when a repository's agents are kept busy. Outside them the
+// daemon stops nudging agents, starting queued tasks and spawning workers,
+// and carries on when they start again. See package workhours.
+type WorkHours struct {
+	// Start and End are HH:MM; an End before Start runs past midnight. Both
+	// empty means agents always work.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// Days are the days a working window may start on (sun, mon, ...); empty is every day
+	Days []string `json:"days,omitempty"`
+	// Timezone is the IANA zone of the hours; empty is the daemon's local time
+	Timezone string `json:"timezone,omitempty"`
+	// Override is "on" or "off" to work or not whatever the hours say, until
+	// OverrideUntil (zero for no end)
+	Override      string    `json:"override,omitempty"`
+	OverrideUntil time.Time `json:"override_until,omitempty"`
+}
+
+// RoutingRule copies or reprioritizes the messages it matches when the
+// daemon routes them. Every match condition that is set must hold. See
+// package routing.
+type RoutingRule struct {
+	// Contains matches messages whose body contains the text (case-sensitive)
+	Contains string `json:"contains,omitempty"`
+	// From matches messages sent by an agent of this name or type
+	From string `json:"from,omitempty"`
+	// To matches messages addressed to this agent
+	To string `json:"to,omitempty"`
+	// CC are agents that also get a copy of matching messages
+	CC []string `json:"cc,omitempty"`
+	// Priority is set on matching messages: "high", "normal" or "low"
+	Priority string `json:"priority,omitempty"`
+}
+
+// RefreshStrategy is how the daemon's worktree refresh brings a worker's
+// branch up to date with the default branch
+type RefreshStrategy string
+
+const (
+	// RefreshRebase rebases the branch onto the default branch (the default)
+	RefreshRebase RefreshStrategy = "rebase"
+	// RefreshMerge merges the default branch into the branch, leaving the agent's commits alone
+	RefreshMerge RefreshStrategy = "merge"
+	// RefreshFetch only fetches, and tells the agent how far behind it is
+	RefreshFetch RefreshStrategy = "fetch"
+	// RefreshOff leaves the worktree alone
+	RefreshOff RefreshStrategy = "off"
+)
+
+// ParseRefreshStrategy converts a string to a RefreshStrategy, returning an error if invalid
+func ParseRefreshStrategy(s string) (RefreshStrategy, error) {
+	switch RefreshStrategy(s) {
+	case RefreshRebase, RefreshMerge, RefreshFetch, RefreshOff:
+		return RefreshStrategy(s), nil
+	default:
+		return "", fmt.Errorf("invalid refresh strategy: %s (must be 'rebase', 'merge', 'fetch' or 'off')", s)
+	}
+}
+
+// RefreshConfig controls how the daemon's worktree refresh treats worker
+// worktrees. The zero value rebases, stashing uncommitted changes around
+// the rebase, whatever the agent is doing.
+type RefreshConfig struct {
+	// Strategy is how branches are brought up to date; empty means rebase
+	Strategy RefreshStrategy `json:"strategy,omitempty"`
+	// OnlyClean skips worktrees with uncommitted changes instead of stashing them
+	OnlyClean bool `json:"only_clean,omitempty"`
+	// PauseActive skips worktrees whose agent is producing output or editing files
+	PauseActive bool `json:"pause_active,omitempty"`
+}
+
+// EffectiveStrategy returns the strategy, with the default filled in
+func (c RefreshConfig) EffectiveStrategy() RefreshStrategy {
+	if c.Strategy == "" {
+		return RefreshRebase
+	}
+	return c.Strategy
+}
+
+// RosterMode is how agents learn who their teammates are
+type RosterMode string
+
+const (
+	// RosterPrompt lists the teammates in each agent's prompt, kept current as
+	// agents come and go, and in the roster file (the default)
+	RosterPrompt RosterMode = "prompt"
+	// RosterFile only keeps the roster file, which prompts point to
+	RosterFile RosterMode = "file"
+	// RosterOff keeps neither
+	RosterOff RosterMode = "off"
+)
+
+// ParseRosterMode converts a string to a RosterMode, returning an error if invalid
+func ParseRosterMode(s string) (RosterMode, error) {
+	switch RosterMode(s) {
+	case RosterPrompt, RosterFile, RosterOff:
+		return RosterMode(s), nil
+	default:
+		return "", fmt.Errorf("invalid roster mode: %s (must be 'prompt', 'file' or 'off')", s)
+	}
+}
+
+// Effective returns the mode, with the default filled in
+func (m RosterMode) Effective() RosterMode {
+	if m == "" {
+		return RosterPrompt
+	}
+	return m
+}
+
+// ReviewDispatch is how review requests are shared among a repository's
+// review agents
+type ReviewDispatch string
+
+const (
+	// ReviewDispatchRoundRobin gives each reviewer the next request in turn
+	// (the default)
+	ReviewDispatchRoundRobin ReviewDispatch = "round-robin"
+	// ReviewDispatchLeastLoaded gives each request to the reviewer with the
+	// fewest reviews queued
+	ReviewDispatchLeastLoaded ReviewDispatch = "least-loaded"
+)
+
+// ParseReviewDispatch converts a string to a ReviewDispatch, returning an error if invalid
+func ParseReviewDispatch(s string) (ReviewDispatch, error) {
+	switch ReviewDispatch(s) {
+	case ReviewDispatchRoundRobin, ReviewDispatchLeastLoaded:
+		return ReviewDispatch(s), nil
+	default:
+		return "", fmt.Errorf("invalid review dispatch: %s (must be 'round-robin' or 'least-loaded')", s)
+	}
+}
+
+// Effective returns the dispatch, with the default filled in
+func (r ReviewDispatch) Effective() ReviewDispatch {
+	if r == "" {
+		return ReviewDispatchRoundRobin
+	}
+	return r
+}
+
+// ReviewAssignment is a PR review requested of a repository's reviewers
+type ReviewAssignment struct {
+	PRNumber    int       `json:"pr_number"`
+	PRURL       string    `json:"pr_url"`
+	Reviewer    string    `json:"reviewer,omitempty"` // Empty while no reviewer can take it
+	RequestedAt time.Time `json:"requested_at"`
+	AssignedAt  time.Time `json:"assigned_at,omitempty"`
+}
+
+// ReviewQueue holds the reviews requested of a repository's reviewers until
+// they're done
+type ReviewQueue struct {
+	Reviews []ReviewAssignment `json:"reviews,omitempty"`
+	Last    string             `json:"last,omitempty"` // Reviewer round-robin dispatch assigned to last
+}
+
+// Depth returns how many reviews are queued for reviewer
+func (q ReviewQueue) Depth(reviewer string) int {
+	n := 0
+	for _, r := range q.Reviews {
+		if r.Reviewer == reviewer {
+			n++
+		}
+	}
+	return n
+}
+
+// Find returns the index of the review of PR number, or -1
+func (q ReviewQueue) Find(number int) int {
+	for i, r := range q.Reviews {
+		if r.PRNumber == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// PromptBudget holds the token limits agent prompts are assembled against.
+// Zero limits use the defaults in package prompts.
+type PromptBudget struct {
+	// Total limits the whole prompt
+	Total int `json:"total,omitempty"`
+	// Base limits the agent's default prompt or definition
+	Base int `json:"base,omitempty"`
+	// Docs limits the generated CLI documentation
+	Docs int `json:"docs,omitempty"`
+	// Commands limits the slash command reference
+	Commands int `json:"commands,omitempty"`
+	// Custom limits the repository-specific instructions
+	Custom int `json:"custom,omitempty"`
+}
+
 // TaskStatus represents the status of a completed task
 type TaskStatus string
 
@@ -126,33 +517,101 @@ const (
 	TaskStatusUnknown TaskStatus = "unknown"
 )
 
+// CriterionStatus is a worker's report on one acceptance criterion
+type CriterionStatus string
+
+const (
+	// CriterionPending means the worker hasn't reported on the criterion yet
+	CriterionPending CriterionStatus = "pending"
+	// CriterionMet means the worker reports the criterion is satisfied
+	CriterionMet CriterionStatus = "met"
+	// CriterionUnmet means the worker reports the criterion is not satisfied
+	CriterionUnmet CriterionStatus = "unmet"
+)
+
+// Criterion is an acceptance criterion a worker's task must satisfy
+type Criterion struct {
+	Text   string          `json:"text"`
+	Status CriterionStatus `json:"status"`
+	Note   string          `json:"note,omitempty"` // Worker's explanation, e.g. why it wasn't met
+}
+
 // TaskHistoryEntry represents a completed task in the history
 type TaskHistoryEntry struct {
-	Name          string     `json:"name"`                     // Worker name
-	Task          string     `json:"task"`                     // Task description
-	Branch        string     `json:"branch"`                   // Git branch
-	PRURL         string     `json:"pr_url,omitempty"`         // Pull request URL if created
-	PRNumber      int        `json:"pr_number,omitempty"`      // PR number for quick lookup
-	Status        TaskStatus `json:"status"`                   // Current status
-	Summary       string     `json:"summary,omitempty"`        // Brief summary of what was accomplished
-	FailureReason string     `json:"failure_reason,omitempty"` // Why the task failed (if applicable)
-	CreatedAt     time.Time  `json:"created_at"`               // When the task was started
-	CompletedAt   time.Time  `json:"completed_at,omitempty"`   // When the task was completed
+	Name          string      `json:"name"`                     // Worker name
+	Task          string      `json:"task"`                     // Task description
+	Branch        string      `json:"branch"`                   // Git branch
+	PRURL         string      `json:"pr_url,omitempty"`         // Pull request URL if created
+	PRNumber      int         `json:"pr_number,omitempty"`      // PR number for quick lookup
+	Status        TaskStatus  `json:"status"`                   // Current status
+	Summary       string      `json:"summary,omitempty"`        // Brief summary of what was accomplished
+	FailureReason string      `json:"failure_reason,omitempty"` // Why the task failed (if applicable)
+	CreatedAt     time.Time   `json:"created_at"`               // When the task was started
+	CompletedAt   time.Time   `json:"completed_at,omitempty"`   // When the task was completed
+	RetryOf       string      `json:"retry_of,omitempty"`       // History ID of the attempt this task retried
+	RetriedBy     string      `json:"retried_by,omitempty"`     // Name of the worker that retried this task
+	Criteria      []Criterion `json:"criteria,omitempty"`       // Acceptance criteria and the worker's report on each
+	Conversation  string      `json:"conversation,omitempty"`   // Claude's summary of the worker's session: what was done, decisions and follow-ups
+}
+
+// ID returns the entry's history ID: the worker name plus its completion time.
+// Worker names can be reused, so the timestamp keeps IDs unique.
+func (e TaskHistoryEntry) ID() string {
+	if e.CompletedAt.IsZero() {
+		return e.Name
+	}
+	return e.Name + "-" + e.CompletedAt.Format("20060102150405")
 }
 
 // Agent represents an agent's state
 type Agent struct {
-	Type            AgentType `json:"type"`
-	WorktreePath    string    `json:"worktree_path"`
-	TmuxWindow      string    `json:"tmux_window"`
-	SessionID       string    `json:"session_id"`
-	PID             int       `json:"pid"`
-	Task            string    `json:"task,omitempty"`           // Only for workers
-	Summary         string    `json:"summary,omitempty"`        // Brief summary of work done (workers only)
-	FailureReason   string    `json:"failure_reason,omitempty"` // Why the task failed (workers only)
-	CreatedAt       time.Time `json:"created_at"`
-	LastNudge       time.Time `json:"last_nudge,omitempty"`
-	ReadyForCleanup bool      `json:"ready_for_cleanup,omitempty"` // Only for workers
+	Type            AgentType      `json:"type"`
+	WorktreePath    string         `json:"worktree_path"`
+	Branch          string         `json:"branch,omitempty"`      // Work branch the agent was created on (workers only)
+	BaseBranch      string         `json:"base_branch,omitempty"` // Branch the work branch was created from and is rebased onto, when not the default (workers only)
+	TmuxWindow      string         `json:"tmux_window"`
+	SessionID       string         `json:"session_id"`
+	PID             int            `json:"pid"`
+	Task            string         `json:"task,omitempty"`           // Only for workers
+	Summary         string         `json:"summary,omitempty"`        // Brief summary of work done (workers only)
+	FailureReason   string         `json:"failure_reason,omitempty"` // Why the task failed (workers only)
+	CreatedAt       time.Time      `json:"created_at"`
+	LastNudge       time.Time      `json:"last_nudge,omitempty"`
+	ReadyForCleanup bool           `json:"ready_for_cleanup,omitempty"` // Only for workers
+	RetryOf         string         `json:"retry_of,omitempty"`          // History ID of the task this worker retries (workers only)
+	Paused          bool           `json:"paused,omitempty"`            // Stopped (SIGSTOP) while the daemon is in standby
+	Criteria        []Criterion    `json:"criteria,omitempty"`          // Acceptance criteria for the task (workers only)
+	Capabilities    []string       `json:"capabilities,omitempty"`      // Capabilities declared by the agent's definition
+	Definition      string         `json:"definition,omitempty"`        // Agent definition it was started from, for max-instances limits
+	Adopted         bool           `json:"adopted,omitempty"`           // Started outside multiclaude and adopted; its window and directory are the user's
+	Resources       *ResourceStats `json:"resources,omitempty"`         // CPU and memory use of the agent's processes
+	Refresh         *RefreshConfig `json:"refresh,omitempty"`           // Overrides the repository's refresh config (workers only)
+	LastRefresh     time.Time      `json:"last_refresh,omitempty"`      // When the worktree was last brought up to date with the default branch (workers only)
+	Editor          *EditorSession `json:"editor,omitempty"`            // Editor the worktree was opened in with `workspace connect --editor` (workspaces only)
+	DescribedHead   string         `json:"described_head,omitempty"`    // Commit the PR description was last written for (workers only)
+}
+
+// EditorSession is an editor window opened on an agent's worktree
+type EditorSession struct {
+	Editor   string    `json:"editor"`           // vscode or jetbrains
+	PID      int       `json:"pid"`              // The editor process, which runs while the window is open
+	Remote   string    `json:"remote,omitempty"` // SSH host the editor connects through, if any
+	OpenedAt time.Time `json:"opened_at"`
+}
+
+// ResourceStats summarizes the CPU and memory use of an agent's process tree,
+// sampled by the daemon's health check (see package resources)
+type ResourceStats struct {
+	CPU        float64   `json:"cpu"`                   // Percent of one core over the last interval
+	AvgCPU     float64   `json:"avg_cpu"`               // Moving average of CPU
+	PeakCPU    float64   `json:"peak_cpu"`              // Highest CPU seen
+	RSS        int64     `json:"rss"`                   // Resident memory in bytes
+	PeakRSS    int64     `json:"peak_rss"`              // Highest RSS seen
+	Procs      int       `json:"procs"`                 // Processes in the tree
+	Busiest    string    `json:"busiest,omitempty"`     // Command that used the most CPU in the last interval
+	HotSamples int       `json:"hot_samples,omitempty"` // Consecutive samples over the alert threshold
+	Alerted    bool      `json:"alerted,omitempty"`     // An alert was sent for the current hot streak
+	SampledAt  time.Time `json:"sampled_at"`
 }
 
 // Repository represents a tracked repository's state
@@ -164,13 +623,93 @@ type Repository struct {
 	MergeQueueConfig MergeQueueConfig   `json:"merge_queue_config,omitempty"`
 	PRShepherdConfig PRShepherdConfig   `json:"pr_shepherd_config,omitempty"`
 	ForkConfig       ForkConfig         `json:"fork_config,omitempty"`
-	TargetBranch     string             `json:"target_branch,omitempty"` // Default branch for PRs (usually "main")
+	TargetBranch     string             `json:"target_branch,omitempty"`   // Default branch: base for workers, rebase target, PR target
+	BranchTemplate   string             `json:"branch_template,omitempty"` // Worker branch naming template (empty means "work/{agent}")
+	NotifyConfig     NotifyConfig       `json:"notify_config,omitempty"`
+	FederationConfig FederationConfig   `json:"federation_config,omitempty"`
+	ZombieConfig     ZombieConfig       `json:"zombie_config,omitempty"`
+	WorktreeConfig   WorktreeConfig     `json:"worktree_config,omitempty"`
+	RefreshConfig    RefreshConfig      `json:"refresh_config,omitempty"`
+	PromptBudget     PromptBudget       `json:"prompt_budget,omitempty"`
+	Roster           RosterMode         `json:"roster,omitempty"` // How agents learn their teammates (empty means "prompt")
+	SpawnHooks       SpawnHooks         `json:"spawn_hooks,omitempty"`
+	WorkHours        WorkHours          `json:"work_hours,omitempty"`
+	RoutingRules     []RoutingRule      `json:"routing_rules,omitempty"`
+	DraftPRs         bool               `json:"draft_prs,omitempty"` // Workers open draft PRs early and mark them ready when done
+	CommentCommands  CommentCommands    `json:"comment_commands,omitempty"`
+	PRDescriptions   PRDescriptions     `json:"pr_descriptions,omitempty"`
+	PushChecks       PushChecks         `json:"push_checks,omitempty"`
+	ReviewDispatch   ReviewDispatch     `json:"review_dispatch,omitempty"` // How review requests are shared among reviewers (empty means "round-robin")
+	MaxWorkers       int                `json:"max_workers,omitempty"`     // Most workers that may run at once (0 means no limit)
+	ReviewQueue      ReviewQueue        `json:"review_queue,omitempty"`
+	ClaudeConfigDir  string             `json:"claude_config_dir,omitempty"` // CLAUDE_CONFIG_DIR for the repo's agents (empty means ~/.claude)
+	ConfigFileHash   string             `json:"config_file_hash,omitempty"`  // SHA-256 of the .multiclaude/config.yaml last applied
+	// Solo repositories hold agents started with `multiclaude solo`: no clone,
+	// supervisor or merge queue, and removed once their last agent is gone
+	Solo bool   `json:"solo,omitempty"`
+	Path string `json:"path,omitempty"` // Solo only: the directory the agents work in (or branch worktrees from)
+}
+
+// projectKeyPrefix marks a project (rather than a repository) in message addressing
+const projectKeyPrefix = "@"
+
+// ValidateProjectName checks that a project name can name its directory, its
+// tmux session and its message address: letters, digits, '-' and '_', not
+// starting with '-'. Dots, colons and slashes would escape the projects
+// directory or confuse tmux targets.
+func ValidateProjectName(name string) error {
+	if name == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("project name %q cannot start with '-'", name)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("project name %q can only contain letters, digits, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// ProjectKey returns the key used to address a project's supervisor in place
+// of a repository name, e.g. in the messages directory ("@platform").
+func ProjectKey(projectName string) string {
+	return projectKeyPrefix + projectName
+}
+
+// ParseProjectKey returns the project name for a key created by ProjectKey
+func ParseProjectKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, projectKeyPrefix) || len(key) == len(projectKeyPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, projectKeyPrefix), true
+}
+
+// Project groups several tracked repositories under a project supervisor
+type Project struct {
+	Repos       []string  `json:"repos"`
+	TmuxSession string    `json:"tmux_session"`
+	SessionID   string    `json:"session_id,omitempty"`
+	PID         int       `json:"pid,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// HasRepo returns true if the repository is a member of the project
+func (p *Project) HasRepo(repoName string) bool {
+	for _, r := range p.Repos {
+		if r == repoName {
+			return true
+		}
+	}
+	return false
 }
 
 // State represents the entire daemon state
 type State struct {
 	Repos       map[string]*Repository `json:"repos"`
 	CurrentRepo string                 `json:"current_repo,omitempty"`
+	Projects    map[string]*Project    `json:"projects,omitempty"`
 	mu          sync.RWMutex
 	path        string
 }
@@ -178,8 +717,9 @@ type State struct {
 // New creates a new empty state
 func New(path string) *State {
 	return &State{
-		Repos: make(map[string]*Repository),
-		path:  path,
+		Repos:    make(map[string]*Repository),
+		Projects: make(map[string]*Project),
+		path:     path,
 	}
 }
 
@@ -205,6 +745,9 @@ func Load(path string) (*State, error) {
 	if s.Repos == nil {
 		s.Repos = make(map[string]*Repository)
 	}
+	if s.Projects == nil {
+		s.Projects = make(map[string]*Project)
+	}
 
 	return &s, nil
 }
@@ -257,6 +800,21 @@ func (s *State) Save() error {
 	return atomicWrite(s.path, data)
 }
 
+// Check reports whether the state can be saved: its lock is free and a file
+// can be written next to the state file. A save that fails would otherwise
+// go unnoticed until the daemon restarts without its changes.
+func (s *State) Check() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	probe, err := os.CreateTemp(filepath.Dir(s.path), ".state-check-*.tmp")
+	if err != nil {
+		return fmt.Errorf("state directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
 // AddRepo adds a new repository to the state
 func (s *State) AddRepo(name string, repo *Repository) error {
 	s.mu.Lock()
@@ -293,6 +851,17 @@ func (s *State) RemoveRepo(name string) error {
 	}
 
 	delete(s.Repos, name)
+
+	// Drop the repository from any projects it belonged to
+	for _, project := range s.Projects {
+		kept := project.Repos[:0]
+		for _, r := range project.Repos {
+			if r != name {
+				kept = append(kept, r)
+			}
+		}
+		project.Repos = kept
+	}
 	return s.saveUnlocked()
 }
 
@@ -368,9 +937,43 @@ func (s *State) GetAllRepos() map[string]*Repository {
 			PRShepherdConfig: repo.PRShepherdConfig,
 			ForkConfig:       repo.ForkConfig,
 			TargetBranch:     repo.TargetBranch,
+			BranchTemplate:   repo.BranchTemplate,
+			NotifyConfig:     repo.NotifyConfig,
+			FederationConfig: repo.FederationConfig,
+			ZombieConfig:     repo.ZombieConfig,
+			WorktreeConfig:   repo.WorktreeConfig,
+			RefreshConfig:    repo.RefreshConfig,
+			PromptBudget:     repo.PromptBudget,
+			Roster:           repo.Roster,
+			SpawnHooks:       repo.SpawnHooks,
+			WorkHours:        repo.WorkHours,
+			RoutingRules:     copyRoutingRules(repo.RoutingRules),
+			DraftPRs:         repo.DraftPRs,
+			CommentCommands:  CommentCommands{Allow: append([]string(nil), repo.CommentCommands.Allow...)},
+			PRDescriptions:   repo.PRDescriptions,
+			PushChecks:       repo.PushChecks,
+			ReviewDispatch:   repo.ReviewDispatch,
+			MaxWorkers:       repo.MaxWorkers,
+			ReviewQueue:      ReviewQueue{Reviews: append([]ReviewAssignment(nil), repo.ReviewQueue.Reviews...), Last: repo.ReviewQueue.Last},
+			ClaudeConfigDir:  repo.ClaudeConfigDir,
+			ConfigFileHash:   repo.ConfigFileHash,
+			Solo:             repo.Solo,
+			Path:             repo.Path,
 		}
+		repoCopy.NotifyConfig.To = append([]string(nil), repo.NotifyConfig.To...)
+		repoCopy.WorkHours.Days = append([]string(nil), repo.WorkHours.Days...)
 		// Copy agents
 		for agentName, agent := range repo.Agents {
+			agent.Criteria = append([]Criterion(nil), agent.Criteria...)
+			agent.Capabilities = append([]string(nil), agent.Capabilities...)
+			if agent.Refresh != nil {
+				refresh := *agent.Refresh
+				agent.Refresh = &refresh
+			}
+			if agent.Editor != nil {
+				editor := *agent.Editor
+				agent.Editor = &editor
+			}
 			repoCopy.Agents[agentName] = agent
 		}
 		// Copy task history
@@ -439,6 +1042,71 @@ func (s *State) UpdateAgentPID(repoName, agentName string, pid int) error {
 	return s.saveUnlocked()
 }
 
+// UpdateAgentLastRefresh records when an agent's worktree was last brought up
+// to date with the default branch
+func (s *State) UpdateAgentLastRefresh(repoName, agentName string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.LastRefresh = at
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentDescribedHead records the commit an agent's PR description was
+// last written for
+func (s *State) UpdateAgentDescribedHead(repoName, agentName, head string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.DescribedHead = head
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// UpdateAgentResources records the resource stats of a repository's agents
+// in one save. Agents that no longer exist are skipped.
+func (s *State) UpdateAgentResources(repoName string, stats map[string]ResourceStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for agentName, st := range stats {
+		agent, exists := repo.Agents[agentName]
+		if !exists {
+			continue
+		}
+		st := st
+		agent.Resources = &st
+		repo.Agents[agentName] = agent
+	}
+	return s.saveUnlocked()
+}
+
 // RemoveAgent removes an agent from a repository
 func (s *State) RemoveAgent(repoName, agentName string) error {
 	s.mu.Lock()
@@ -573,22 +1241,23 @@ func (s *State) UpdateForkConfig(repoName string, config ForkConfig) error {
 	return s.saveUnlocked()
 }
 
-// IsForkMode returns true if the repository should operate in fork mode.
-// This is true if the repository is detected as a fork OR if force_fork_mode is enabled.
-func (s *State) IsForkMode(repoName string) bool {
+// GetNotifyConfig returns the notification config for a repository
+func (s *State) GetNotifyConfig(repoName string) (NotifyConfig, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	repo, exists := s.Repos[repoName]
 	if !exists {
-		return false
+		return NotifyConfig{}, fmt.Errorf("repository %q not found", repoName)
 	}
 
-	return repo.ForkConfig.IsFork || repo.ForkConfig.ForceForkMode
+	config := repo.NotifyConfig
+	config.To = append([]string(nil), repo.NotifyConfig.To...)
+	return config, nil
 }
 
-// AddTaskHistory adds a completed task to the repository's history
-func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
+// UpdateNotifyConfig updates the notification config for a repository
+func (s *State) UpdateNotifyConfig(repoName string, config NotifyConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -597,41 +1266,25 @@ func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
 		return fmt.Errorf("repository %q not found", repoName)
 	}
 
-	repo.TaskHistory = append(repo.TaskHistory, entry)
+	repo.NotifyConfig = config
 	return s.saveUnlocked()
 }
 
-// GetTaskHistory returns the task history for a repository, optionally limited to N entries
-func (s *State) GetTaskHistory(repoName string, limit int) ([]TaskHistoryEntry, error) {
+// GetFederationConfig returns the federation config for a repository
+func (s *State) GetFederationConfig(repoName string) (FederationConfig, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	repo, exists := s.Repos[repoName]
 	if !exists {
-		return nil, fmt.Errorf("repository %q not found", repoName)
-	}
-
-	history := repo.TaskHistory
-	if history == nil {
-		return []TaskHistoryEntry{}, nil
-	}
-
-	// Return most recent first
-	result := make([]TaskHistoryEntry, len(history))
-	for i, entry := range history {
-		result[len(history)-1-i] = entry
+		return FederationConfig{}, fmt.Errorf("repository %q not found", repoName)
 	}
 
-	// Apply limit if specified
-	if limit > 0 && len(result) > limit {
-		result = result[:limit]
-	}
-
-	return result, nil
+	return repo.FederationConfig, nil
 }
 
-// UpdateTaskHistoryStatus updates the status and PR info for a task by name
-func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskStatus, prURL string, prNumber int) error {
+// UpdateFederationConfig updates the federation config for a repository
+func (s *State) UpdateFederationConfig(repoName string, config FederationConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -640,25 +1293,25 @@ func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskSt
 		return fmt.Errorf("repository %q not found", repoName)
 	}
 
-	// Find the most recent entry with this name and update it
-	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
-		if repo.TaskHistory[i].Name == taskName {
-			repo.TaskHistory[i].Status = status
-			if prURL != "" {
-				repo.TaskHistory[i].PRURL = prURL
-			}
-			if prNumber > 0 {
-				repo.TaskHistory[i].PRNumber = prNumber
-			}
-			return s.saveUnlocked()
-		}
+	repo.FederationConfig = config
+	return s.saveUnlocked()
+}
+
+// GetZombieConfig returns the zombie detection config for a repository
+func (s *State) GetZombieConfig(repoName string) (ZombieConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return ZombieConfig{}, fmt.Errorf("repository %q not found", repoName)
 	}
 
-	return fmt.Errorf("task %q not found in history", taskName)
+	return repo.ZombieConfig, nil
 }
 
-// UpdateTaskHistorySummary updates the summary and failure reason for a task by name
-func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureReason string) error {
+// UpdateZombieConfig updates the zombie detection config for a repository
+func (s *State) UpdateZombieConfig(repoName string, config ZombieConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -667,15 +1320,525 @@ func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureRea
 		return fmt.Errorf("repository %q not found", repoName)
 	}
 
-	// Find the most recent entry with this name and update it
-	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
-		if repo.TaskHistory[i].Name == taskName {
-			if summary != "" {
-				repo.TaskHistory[i].Summary = summary
-			}
-			if failureReason != "" {
-				repo.TaskHistory[i].FailureReason = failureReason
-				// Also update status to failed if a failure reason is provided
+	repo.ZombieConfig = config
+	return s.saveUnlocked()
+}
+
+// GetWorktreeConfig returns the worktree setup config for a repository
+func (s *State) GetWorktreeConfig(repoName string) (WorktreeConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return WorktreeConfig{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.WorktreeConfig, nil
+}
+
+// UpdateWorktreeConfig updates the worktree setup config for a repository
+func (s *State) UpdateWorktreeConfig(repoName string, config WorktreeConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.WorktreeConfig = config
+	return s.saveUnlocked()
+}
+
+// GetRefreshConfig returns the worktree refresh config for a repository
+func (s *State) GetRefreshConfig(repoName string) (RefreshConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return RefreshConfig{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.RefreshConfig, nil
+}
+
+// UpdateRefreshConfig updates the worktree refresh config for a repository
+func (s *State) UpdateRefreshConfig(repoName string, config RefreshConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.RefreshConfig = config
+	return s.saveUnlocked()
+}
+
+// GetRosterMode returns how a repository's agents learn their teammates
+func (s *State) GetRosterMode(repoName string) (RosterMode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return "", fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.Roster, nil
+}
+
+// UpdateRosterMode updates how a repository's agents learn their teammates
+func (s *State) UpdateRosterMode(repoName string, mode RosterMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.Roster = mode
+	return s.saveUnlocked()
+}
+
+// UpdateDraftPRs sets whether a repository's workers open draft PRs
+func (s *State) UpdateDraftPRs(repoName string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.DraftPRs = enabled
+	return s.saveUnlocked()
+}
+
+// UpdatePRDescriptions sets how the daemon writes a repository's worker PR
+// descriptions
+func (s *State) UpdatePRDescriptions(repoName string, cfg PRDescriptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.PRDescriptions = cfg
+	return s.saveUnlocked()
+}
+
+// UpdatePushChecks sets what a repository's worker branches must pass before
+// they're pushed
+func (s *State) UpdatePushChecks(repoName string, checks PushChecks) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.PushChecks = checks
+	return s.saveUnlocked()
+}
+
+// UpdateReviewDispatch sets how review requests are shared among a
+// repository's reviewers
+func (s *State) UpdateReviewDispatch(repoName string, dispatch ReviewDispatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.ReviewDispatch = dispatch
+	return s.saveUnlocked()
+}
+
+// UpdateReviewQueue changes a repository's review queue with update, which
+// sees the queue and the repository's agents as they are under the lock, so
+// assignments can't race. Nothing is saved when update fails.
+func (s *State) UpdateReviewQueue(repoName string, update func(queue *ReviewQueue, agents map[string]Agent) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	queue := ReviewQueue{Reviews: append([]ReviewAssignment(nil), repo.ReviewQueue.Reviews...), Last: repo.ReviewQueue.Last}
+	if err := update(&queue, repo.Agents); err != nil {
+		return err
+	}
+	repo.ReviewQueue = queue
+	return s.saveUnlocked()
+}
+
+// UpdateClaudeConfigDir sets the Claude config directory a repository's
+// agents run with, or clears it when dir is empty
+func (s *State) UpdateClaudeConfigDir(repoName, dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.ClaudeConfigDir = dir
+	return s.saveUnlocked()
+}
+
+// GetSpawnHooks returns the commands run around starting a repository's workers
+func (s *State) GetSpawnHooks(repoName string) (SpawnHooks, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return SpawnHooks{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.SpawnHooks, nil
+}
+
+// UpdateSpawnHooks updates the commands run around starting a repository's workers
+func (s *State) UpdateSpawnHooks(repoName string, hooks SpawnHooks) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.SpawnHooks = hooks
+	return s.saveUnlocked()
+}
+
+// UpdateCommentCommands updates who may steer a repository's workers from
+// GitHub comments
+func (s *State) UpdateCommentCommands(repoName string, cfg CommentCommands) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.CommentCommands = cfg
+	return s.saveUnlocked()
+}
+
+// GetWorkHours returns when a repository's agents are kept busy
+func (s *State) GetWorkHours(repoName string) (WorkHours, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return WorkHours{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	hours := repo.WorkHours
+	hours.Days = append([]string(nil), hours.Days...)
+	return hours, nil
+}
+
+// UpdateWorkHours updates when a repository's agents are kept busy
+func (s *State) UpdateWorkHours(repoName string, hours WorkHours) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.WorkHours = hours
+	return s.saveUnlocked()
+}
+
+// GetRoutingRules returns the message routing rules of a repository
+func (s *State) GetRoutingRules(repoName string) ([]RoutingRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+	return copyRoutingRules(repo.RoutingRules), nil
+}
+
+// UpdateRoutingRules replaces the message routing rules of a repository
+func (s *State) UpdateRoutingRules(repoName string, rules []RoutingRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.RoutingRules = copyRoutingRules(rules)
+	return s.saveUnlocked()
+}
+
+// UpdateMaxWorkers sets how many workers a repository may run at once; 0
+// means no limit
+func (s *State) UpdateMaxWorkers(repoName string, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.MaxWorkers = limit
+	return s.saveUnlocked()
+}
+
+// UpdateConfigFileHash records the hash of the config file last applied to a
+// repository
+func (s *State) UpdateConfigFileHash(repoName, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.ConfigFileHash = hash
+	return s.saveUnlocked()
+}
+
+// copyRoutingRules deep-copies routing rules
+func copyRoutingRules(rules []RoutingRule) []RoutingRule {
+	if rules == nil {
+		return nil
+	}
+	copied := make([]RoutingRule, len(rules))
+	for i, rule := range rules {
+		rule.CC = append([]string(nil), rule.CC...)
+		copied[i] = rule
+	}
+	return copied
+}
+
+// GetPromptBudget returns the prompt budget for a repository
+func (s *State) GetPromptBudget(repoName string) (PromptBudget, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return PromptBudget{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.PromptBudget, nil
+}
+
+// UpdatePromptBudget updates the prompt budget for a repository
+func (s *State) UpdatePromptBudget(repoName string, budget PromptBudget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.PromptBudget = budget
+	return s.saveUnlocked()
+}
+
+// GetTargetBranch returns the recorded default branch for a repository.
+// It is empty for repositories added before the branch was recorded.
+func (s *State) GetTargetBranch(repoName string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return "", fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.TargetBranch, nil
+}
+
+// UpdateTargetBranch sets the default branch for a repository
+func (s *State) UpdateTargetBranch(repoName, branch string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TargetBranch = branch
+	return s.saveUnlocked()
+}
+
+// UpdateGithubURL sets the URL a repository is cloned from
+func (s *State) UpdateGithubURL(repoName, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.GithubURL = url
+	return s.saveUnlocked()
+}
+
+// GetBranchTemplate returns the worker branch naming template for a repository.
+// It is empty when the repository uses the default scheme.
+func (s *State) GetBranchTemplate(repoName string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return "", fmt.Errorf("repository %q not found", repoName)
+	}
+
+	return repo.BranchTemplate, nil
+}
+
+// UpdateBranchTemplate sets the worker branch naming template for a repository
+func (s *State) UpdateBranchTemplate(repoName, template string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.BranchTemplate = template
+	return s.saveUnlocked()
+}
+
+// IsForkMode returns true if the repository should operate in fork mode.
+// This is true if the repository is detected as a fork OR if force_fork_mode is enabled.
+func (s *State) IsForkMode(repoName string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return false
+	}
+
+	return repo.ForkConfig.IsFork || repo.ForkConfig.ForceForkMode
+}
+
+// AddTaskHistory adds a completed task to the repository's history
+func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TaskHistory = append(repo.TaskHistory, entry)
+	return s.saveUnlocked()
+}
+
+// GetTaskHistory returns the task history for a repository, optionally limited to N entries
+func (s *State) GetTaskHistory(repoName string, limit int) ([]TaskHistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	history := repo.TaskHistory
+	if history == nil {
+		return []TaskHistoryEntry{}, nil
+	}
+
+	// Return most recent first
+	result := make([]TaskHistoryEntry, len(history))
+	for i, entry := range history {
+		result[len(history)-1-i] = entry
+	}
+
+	// Apply limit if specified
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// UpdateTaskHistoryStatus updates the status and PR info for a task by name
+func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskStatus, prURL string, prNumber int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	// Find the most recent entry with this name and update it
+	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+		if repo.TaskHistory[i].Name == taskName {
+			repo.TaskHistory[i].Status = status
+			if prURL != "" {
+				repo.TaskHistory[i].PRURL = prURL
+			}
+			if prNumber > 0 {
+				repo.TaskHistory[i].PRNumber = prNumber
+			}
+			return s.saveUnlocked()
+		}
+	}
+
+	return fmt.Errorf("task %q not found in history", taskName)
+}
+
+// UpdateTaskHistorySummary updates the summary and failure reason for a task by name
+func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureReason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	// Find the most recent entry with this name and update it
+	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+		if repo.TaskHistory[i].Name == taskName {
+			if summary != "" {
+				repo.TaskHistory[i].Summary = summary
+			}
+			if failureReason != "" {
+				repo.TaskHistory[i].FailureReason = failureReason
+				// Also update status to failed if a failure reason is provided
 				repo.TaskHistory[i].Status = TaskStatusFailed
 			}
 			return s.saveUnlocked()
@@ -685,6 +1848,227 @@ func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureRea
 	return fmt.Errorf("task %q not found in history", taskName)
 }
 
+// UpdateTaskHistoryConversation sets the summary of the worker's session on
+// a task history entry, found by ID or, failing that, worker name
+func (s *State) UpdateTaskHistoryConversation(repoName, ref, summary string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+	i := findTaskHistoryIndex(repo.TaskHistory, ref)
+	if i < 0 {
+		return fmt.Errorf("task %q not found in history", ref)
+	}
+	repo.TaskHistory[i].Conversation = summary
+	return s.saveUnlocked()
+}
+
+// FindTaskHistory looks up a task history entry by history ID, or by worker name
+// (most recent entry with that name)
+func (s *State) FindTaskHistory(repoName, ref string) (TaskHistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return TaskHistoryEntry{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	i := findTaskHistoryIndex(repo.TaskHistory, ref)
+	if i < 0 {
+		return TaskHistoryEntry{}, fmt.Errorf("task %q not found in history", ref)
+	}
+	return repo.TaskHistory[i], nil
+}
+
+// similarTaskThreshold is the share of significant words two task
+// descriptions must have in common to be flagged as possible duplicates
+const similarTaskThreshold = 0.6
+
+// SimilarTasks reports whether two task descriptions look like the same task:
+// most of their words longer than two characters, ignoring case, are shared
+func SimilarTasks(a, b string) bool {
+	wordsA, wordsB := taskWords(a), taskWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return false
+	}
+	shared := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			shared++
+		}
+	}
+	return float64(shared)/float64(len(wordsA)+len(wordsB)-shared) >= similarTaskThreshold
+}
+
+// taskWords returns the lowercased words of a task longer than two characters
+func taskWords(task string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(task), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9')
+	}) {
+		if len(w) > 2 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// SimilarTaskHistory returns the repository's tasks finished since since
+// whose descriptions look like task, most recent first, so a task that was
+// already attempted isn't handed out again blind
+func (s *State) SimilarTaskHistory(repoName, task string, since time.Time) []TaskHistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil
+	}
+
+	var similar []TaskHistoryEntry
+	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+		entry := repo.TaskHistory[i]
+		if entry.CompletedAt.Before(since) || !SimilarTasks(task, entry.Task) {
+			continue
+		}
+		entry.Criteria = append([]Criterion(nil), entry.Criteria...)
+		similar = append(similar, entry)
+	}
+	return similar
+}
+
+// MarkTaskHistoryRetried records which worker is retrying a task from the history
+func (s *State) MarkTaskHistoryRetried(repoName, ref, workerName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	i := findTaskHistoryIndex(repo.TaskHistory, ref)
+	if i < 0 {
+		return fmt.Errorf("task %q not found in history", ref)
+	}
+	repo.TaskHistory[i].RetriedBy = workerName
+	return s.saveUnlocked()
+}
+
+// findTaskHistoryIndex returns the index of the entry with the given history ID,
+// falling back to the most recent entry for a worker name, or -1
+func findTaskHistoryIndex(history []TaskHistoryEntry, ref string) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].ID() == ref {
+			return i
+		}
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Name == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddProject adds a new project grouping existing repositories
+func (s *State) AddProject(name string, project *Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ValidateProjectName(name); err != nil {
+		return err
+	}
+	if _, exists := s.Projects[name]; exists {
+		return fmt.Errorf("project %q already exists", name)
+	}
+	if len(project.Repos) == 0 {
+		return fmt.Errorf("project %q must contain at least one repository", name)
+	}
+	for _, repoName := range project.Repos {
+		if _, exists := s.Repos[repoName]; !exists {
+			return fmt.Errorf("repository %q not found", repoName)
+		}
+	}
+
+	if s.Projects == nil {
+		s.Projects = make(map[string]*Project)
+	}
+	s.Projects[name] = project
+	return s.saveUnlocked()
+}
+
+// GetProject returns a copy of a project by name
+func (s *State) GetProject(name string) (Project, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	project, exists := s.Projects[name]
+	if !exists {
+		return Project{}, false
+	}
+	projectCopy := *project
+	projectCopy.Repos = append([]string(nil), project.Repos...)
+	return projectCopy, true
+}
+
+// UpdateProjectSupervisor records the session and PID of a project's supervisor
+func (s *State) UpdateProjectSupervisor(name, sessionID string, pid int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project, exists := s.Projects[name]
+	if !exists {
+		return fmt.Errorf("project %q not found", name)
+	}
+
+	project.SessionID = sessionID
+	project.PID = pid
+	return s.saveUnlocked()
+}
+
+// RemoveProject removes a project from the state. Member repositories are not affected.
+func (s *State) RemoveProject(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.Projects[name]; !exists {
+		return fmt.Errorf("project %q not found", name)
+	}
+
+	delete(s.Projects, name)
+	return s.saveUnlocked()
+}
+
+// ListProjects returns all project names
+func (s *State) ListProjects() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := make([]string, 0, len(s.Projects))
+	for name := range s.Projects {
+		projects = append(projects, name)
+	}
+	return projects
+}
+
+// ReposShareProject returns true if both repositories belong to a common project
+func (s *State) ReposShareProject(repoA, repoB string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, project := range s.Projects {
+		if project.HasRepo(repoA) && project.HasRepo(repoB) {
+			return true
+		}
+	}
+	return false
+}
+
 // saveUnlocked saves state without acquiring lock (caller must hold lock)
 func (s *State) saveUnlocked() error {
 	data, err := json.MarshalIndent(s, "", "  ")
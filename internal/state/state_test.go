@@ -508,6 +508,22 @@ func TestStateAtomicSave(t *testing.T) {
 	}
 }
 
+func TestCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(filepath.Join(tmpDir, "state.json"))
+	if err := s.Check(); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+		t.Errorf("Check() left %d files behind", len(entries))
+	}
+
+	gone := New(filepath.Join(tmpDir, "missing", "state.json"))
+	if err := gone.Check(); err == nil {
+		t.Error("Check() of a state in a missing directory should fail")
+	}
+}
+
 func TestSave(t *testing.T) {
 	tmpDir := t.TempDir()
 	statePath := filepath.Join(tmpDir, "state.json")
@@ -1574,6 +1590,29 @@ func TestUpdateTaskHistorySummary(t *testing.T) {
 	}
 }
 
+func TestUpdateTaskHistoryConversation(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	entry := TaskHistoryEntry{Name: "worker-1", Task: "Test task", CreatedAt: time.Now(), CompletedAt: time.Now()}
+	if err := s.AddTaskHistory("test-repo", entry); err != nil {
+		t.Fatalf("AddTaskHistory() failed: %v", err)
+	}
+
+	if err := s.UpdateTaskHistoryConversation("test-repo", entry.ID(), "## Done\n- Fixed it"); err != nil {
+		t.Fatalf("UpdateTaskHistoryConversation() failed: %v", err)
+	}
+	history, _ := s.GetTaskHistory("test-repo", 10)
+	if history[0].Conversation != "## Done\n- Fixed it" {
+		t.Errorf("Conversation = %q", history[0].Conversation)
+	}
+
+	if err := s.UpdateTaskHistoryConversation("test-repo", "no-such-entry", "x"); err == nil {
+		t.Error("UpdateTaskHistoryConversation() of an unknown entry should fail")
+	}
+}
+
 func TestAgentTypeIsPersistent(t *testing.T) {
 	tests := []struct {
 		agentType  AgentType
@@ -1584,6 +1623,7 @@ func TestAgentTypeIsPersistent(t *testing.T) {
 		{AgentTypeMergeQueue, true},
 		{AgentTypeWorkspace, true},
 		{AgentTypeGenericPersistent, true},
+		{AgentTypeObserver, true},
 		// Transient agents should return false
 		{AgentTypeWorker, false},
 		{AgentTypeReview, false},
@@ -1602,6 +1642,17 @@ func TestAgentTypeIsPersistent(t *testing.T) {
 	}
 }
 
+func TestAgentTypeIsReadOnly(t *testing.T) {
+	if !AgentTypeObserver.IsReadOnly() {
+		t.Error("observer should be read-only")
+	}
+	for _, agentType := range []AgentType{AgentTypeSupervisor, AgentTypeWorker, AgentTypeMergeQueue, AgentTypeGenericPersistent} {
+		if agentType.IsReadOnly() {
+			t.Errorf("%s should not be read-only", agentType)
+		}
+	}
+}
+
 func TestDefaultPRShepherdConfig(t *testing.T) {
 	config := DefaultPRShepherdConfig()
 
@@ -2206,3 +2257,513 @@ func TestGetTaskHistoryNoLimit(t *testing.T) {
 		t.Errorf("GetTaskHistory() with limit=0 returned %d entries, want 5", len(history))
 	}
 }
+
+func TestProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	for _, name := range []string{"api", "web", "infra"} {
+		repo := &Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Agents:      make(map[string]Agent),
+		}
+		if err := s.AddRepo(name, repo); err != nil {
+			t.Fatalf("AddRepo(%s) failed: %v", name, err)
+		}
+	}
+
+	if err := s.AddProject("platform", &Project{Repos: []string{"api", "missing"}}); err == nil {
+		t.Error("AddProject() with unknown repo should fail")
+	}
+	if err := s.AddProject("empty", &Project{}); err == nil {
+		t.Error("AddProject() without repos should fail")
+	}
+
+	project := &Project{
+		Repos:       []string{"api", "web"},
+		TmuxSession: "mc-project-platform",
+		CreatedAt:   time.Now(),
+	}
+	if err := s.AddProject("platform", project); err != nil {
+		t.Fatalf("AddProject() failed: %v", err)
+	}
+	if err := s.AddProject("platform", project); err == nil {
+		t.Error("AddProject() duplicate should fail")
+	}
+
+	if !s.ReposShareProject("api", "web") {
+		t.Error("ReposShareProject(api, web) = false, want true")
+	}
+	if s.ReposShareProject("api", "infra") {
+		t.Error("ReposShareProject(api, infra) = true, want false")
+	}
+
+	if err := s.UpdateProjectSupervisor("platform", "session-1", 1234); err != nil {
+		t.Fatalf("UpdateProjectSupervisor() failed: %v", err)
+	}
+
+	// Reload from disk to verify persistence
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	got, ok := loaded.GetProject("platform")
+	if !ok {
+		t.Fatal("GetProject() after reload: project not found")
+	}
+	if len(got.Repos) != 2 || got.SessionID != "session-1" || got.PID != 1234 {
+		t.Errorf("GetProject() = %+v, want 2 repos with session-1/1234", got)
+	}
+
+	// Removing a repo drops it from the project
+	if err := loaded.RemoveRepo("web"); err != nil {
+		t.Fatalf("RemoveRepo() failed: %v", err)
+	}
+	got, _ = loaded.GetProject("platform")
+	if got.HasRepo("web") {
+		t.Error("project still contains removed repo")
+	}
+
+	if err := loaded.RemoveProject("platform"); err != nil {
+		t.Fatalf("RemoveProject() failed: %v", err)
+	}
+	if len(loaded.ListProjects()) != 0 {
+		t.Errorf("ListProjects() = %v, want empty", loaded.ListProjects())
+	}
+	if err := loaded.RemoveProject("platform"); err == nil {
+		t.Error("RemoveProject() of missing project should fail")
+	}
+}
+
+func TestProjectKey(t *testing.T) {
+	key := ProjectKey("platform")
+	if key != "@platform" {
+		t.Errorf("ProjectKey() = %q, want %q", key, "@platform")
+	}
+	if name, ok := ParseProjectKey(key); !ok || name != "platform" {
+		t.Errorf("ParseProjectKey(%q) = %q, %v", key, name, ok)
+	}
+	for _, key := range []string{"platform", "@", ""} {
+		if _, ok := ParseProjectKey(key); ok {
+			t.Errorf("ParseProjectKey(%q) ok = true, want false", key)
+		}
+	}
+}
+
+func TestValidateProjectName(t *testing.T) {
+	for _, name := range []string{"platform", "web-team", "team_2", "A1"} {
+		if err := ValidateProjectName(name); err != nil {
+			t.Errorf("ValidateProjectName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x", "a:b", "a.b", "a/b", "a b", "-x"} {
+		if err := ValidateProjectName(name); err == nil {
+			t.Errorf("ValidateProjectName(%q) = nil, want error", name)
+		}
+	}
+}
+
+func TestNotifyConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	config, err := s.GetNotifyConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetNotifyConfig() failed: %v", err)
+	}
+	if config.Enabled {
+		t.Error("notifications should be disabled by default")
+	}
+
+	config = NotifyConfig{
+		Enabled:       true,
+		Method:        NotifyMethodSMTP,
+		To:            []string{"me@example.com"},
+		From:          "multiclaude@example.com",
+		SMTPAddr:      "smtp.example.com:587",
+		DigestMinutes: 30,
+	}
+	if err := s.UpdateNotifyConfig("test-repo", config); err != nil {
+		t.Fatalf("UpdateNotifyConfig() failed: %v", err)
+	}
+
+	// Snapshot must not share the recipient slice with state
+	repos := s.GetAllRepos()
+	repos["test-repo"].NotifyConfig.To[0] = "modified"
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	got, err := loaded.GetNotifyConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetNotifyConfig() failed: %v", err)
+	}
+	if !got.Enabled || got.Method != NotifyMethodSMTP || got.DigestMinutes != 30 {
+		t.Errorf("GetNotifyConfig() = %+v, want persisted config", got)
+	}
+	if len(got.To) != 1 || got.To[0] != "me@example.com" {
+		t.Errorf("GetNotifyConfig().To = %v, want [me@example.com]", got.To)
+	}
+
+	if err := s.UpdateNotifyConfig("missing", config); err == nil {
+		t.Error("UpdateNotifyConfig() on missing repo should fail")
+	}
+}
+
+func TestParseNotifyMethod(t *testing.T) {
+	for _, tt := range []struct {
+		input   string
+		want    NotifyMethod
+		wantErr bool
+	}{
+		{"smtp", NotifyMethodSMTP, false},
+		{"sendmail", NotifyMethodSendmail, false},
+		{"pigeon", "", true},
+	} {
+		got, err := ParseNotifyMethod(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseNotifyMethod(%q) = %q, %v", tt.input, got, err)
+		}
+	}
+}
+
+func TestTargetBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:    "https://github.com/test/repo",
+		TmuxSession:  "mc-test",
+		Agents:       make(map[string]Agent),
+		TargetBranch: "master",
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	branch, err := s.GetTargetBranch("test-repo")
+	if err != nil {
+		t.Fatalf("GetTargetBranch() failed: %v", err)
+	}
+	if branch != "master" {
+		t.Errorf("GetTargetBranch() = %q, want master", branch)
+	}
+
+	if err := s.UpdateTargetBranch("test-repo", "develop"); err != nil {
+		t.Fatalf("UpdateTargetBranch() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if branch, _ := loaded.GetTargetBranch("test-repo"); branch != "develop" {
+		t.Errorf("GetTargetBranch() after reload = %q, want develop", branch)
+	}
+
+	if _, err := s.GetTargetBranch("missing"); err == nil {
+		t.Error("GetTargetBranch() on missing repo should fail")
+	}
+	if err := s.UpdateTargetBranch("missing", "main"); err == nil {
+		t.Error("UpdateTargetBranch() on missing repo should fail")
+	}
+}
+
+func TestBranchTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if tmpl, err := s.GetBranchTemplate("test-repo"); err != nil || tmpl != "" {
+		t.Errorf("GetBranchTemplate() = %q, %v; want empty", tmpl, err)
+	}
+
+	if err := s.UpdateBranchTemplate("test-repo", "mc/{agent}/{task-slug}"); err != nil {
+		t.Fatalf("UpdateBranchTemplate() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if tmpl, _ := loaded.GetBranchTemplate("test-repo"); tmpl != "mc/{agent}/{task-slug}" {
+		t.Errorf("GetBranchTemplate() after reload = %q, want mc/{agent}/{task-slug}", tmpl)
+	}
+	if repos := loaded.GetAllRepos(); repos["test-repo"].BranchTemplate != "mc/{agent}/{task-slug}" {
+		t.Errorf("GetAllRepos() BranchTemplate = %q, want it copied", repos["test-repo"].BranchTemplate)
+	}
+
+	if _, err := s.GetBranchTemplate("missing"); err == nil {
+		t.Error("GetBranchTemplate() on missing repo should fail")
+	}
+	if err := s.UpdateBranchTemplate("missing", "x/{agent}"); err == nil {
+		t.Error("UpdateBranchTemplate() on missing repo should fail")
+	}
+}
+
+func TestFindTaskHistoryAndRetryLink(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	first := TaskHistoryEntry{
+		Name:        "happy-eagle",
+		Task:        "Fix the login bug",
+		Status:      TaskStatusFailed,
+		CompletedAt: time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC),
+	}
+	second := TaskHistoryEntry{
+		Name:        "happy-eagle",
+		Task:        "Add OAuth",
+		CompletedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+	}
+	for _, e := range []TaskHistoryEntry{first, second} {
+		if err := s.AddTaskHistory("test-repo", e); err != nil {
+			t.Fatalf("AddTaskHistory() failed: %v", err)
+		}
+	}
+
+	if first.ID() != "happy-eagle-20261015090000" {
+		t.Errorf("ID() = %q, want happy-eagle-20261015090000", first.ID())
+	}
+
+	// By history ID
+	entry, err := s.FindTaskHistory("test-repo", first.ID())
+	if err != nil {
+		t.Fatalf("FindTaskHistory() failed: %v", err)
+	}
+	if entry.Task != "Fix the login bug" {
+		t.Errorf("FindTaskHistory(id) task = %q, want the first attempt", entry.Task)
+	}
+
+	// By worker name: most recent entry wins
+	entry, err = s.FindTaskHistory("test-repo", "happy-eagle")
+	if err != nil {
+		t.Fatalf("FindTaskHistory() failed: %v", err)
+	}
+	if entry.Task != "Add OAuth" {
+		t.Errorf("FindTaskHistory(name) task = %q, want the most recent entry", entry.Task)
+	}
+
+	if _, err := s.FindTaskHistory("test-repo", "nope"); err == nil {
+		t.Error("FindTaskHistory() with unknown ref should fail")
+	}
+	if _, err := s.FindTaskHistory("missing", "happy-eagle"); err == nil {
+		t.Error("FindTaskHistory() on missing repo should fail")
+	}
+
+	if err := s.MarkTaskHistoryRetried("test-repo", first.ID(), "calm-owl"); err != nil {
+		t.Fatalf("MarkTaskHistoryRetried() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	entry, _ = loaded.FindTaskHistory("test-repo", first.ID())
+	if entry.RetriedBy != "calm-owl" {
+		t.Errorf("RetriedBy = %q after reload, want calm-owl", entry.RetriedBy)
+	}
+	if err := s.MarkTaskHistoryRetried("test-repo", "nope", "calm-owl"); err == nil {
+		t.Error("MarkTaskHistoryRetried() with unknown ref should fail")
+	}
+}
+
+func TestSimilarTaskHistory(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{TmuxSession: "mc-test", Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	now := time.Now()
+	for _, e := range []TaskHistoryEntry{
+		{Name: "old-owl", Task: "Fix the flaky login test", Status: TaskStatusFailed, CompletedAt: now.Add(-60 * 24 * time.Hour)},
+		{Name: "calm-owl", Task: "Fix the flaky login test", Status: TaskStatusFailed, FailureReason: "blocked on auth mock", CompletedAt: now.Add(-2 * time.Hour)},
+		{Name: "brave-fox", Task: "Add dark mode", Status: TaskStatusMerged, CompletedAt: now.Add(-time.Hour)},
+		{Name: "swift-elk", Task: "fix flaky LOGIN test!", Status: TaskStatusClosed, CompletedAt: now.Add(-time.Minute)},
+	} {
+		if err := s.AddTaskHistory("test-repo", e); err != nil {
+			t.Fatalf("AddTaskHistory() failed: %v", err)
+		}
+	}
+
+	similar := s.SimilarTaskHistory("test-repo", "Fix the flaky login test again", now.Add(-30*24*time.Hour))
+	if len(similar) != 2 || similar[0].Name != "swift-elk" || similar[1].Name != "calm-owl" {
+		t.Fatalf("SimilarTaskHistory() = %+v, want swift-elk then calm-owl", similar)
+	}
+	if got := s.SimilarTaskHistory("test-repo", "Write release notes", time.Time{}); len(got) != 0 {
+		t.Errorf("SimilarTaskHistory() for an unrelated task = %+v, want none", got)
+	}
+	if got := s.SimilarTaskHistory("missing", "Fix the flaky login test", time.Time{}); got != nil {
+		t.Errorf("SimilarTaskHistory() on a missing repo = %+v, want nil", got)
+	}
+}
+
+func TestSimilarTasks(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Fix the login bug", "fix the LOGIN bug", true},
+		{"Add OAuth to the login page", "Add OAuth to the signup page", true},
+		{"Fix the login bug", "Add dark mode", false},
+		{"a b", "a b", false}, // No words long enough to compare
+	}
+	for _, tt := range tests {
+		if got := SimilarTasks(tt.a, tt.b); got != tt.want {
+			t.Errorf("SimilarTasks(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFederationConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if cfg, err := s.GetFederationConfig("test-repo"); err != nil || cfg.Enabled {
+		t.Errorf("GetFederationConfig() = %+v, %v; want disabled", cfg, err)
+	}
+
+	want := FederationConfig{Enabled: true, Relay: "git", PeerID: "alice@laptop"}
+	if err := s.UpdateFederationConfig("test-repo", want); err != nil {
+		t.Fatalf("UpdateFederationConfig() failed: %v", err)
+	}
+	if err := s.UpdateFederationConfig("missing", want); err == nil {
+		t.Error("UpdateFederationConfig() should fail for an unknown repo")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg, _ := loaded.GetFederationConfig("test-repo"); cfg != want {
+		t.Errorf("GetFederationConfig() after reload = %+v, want %+v", cfg, want)
+	}
+	if repos := loaded.GetAllRepos(); repos["test-repo"].FederationConfig != want {
+		t.Errorf("GetAllRepos() FederationConfig = %+v, want it copied", repos["test-repo"].FederationConfig)
+	}
+}
+
+func TestUpdateGithubURL(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{GithubURL: "https://github.com/old/repo", Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.UpdateGithubURL("test-repo", "https://github.com/new/repo"); err != nil {
+		t.Fatalf("UpdateGithubURL() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if repo, _ := loaded.GetRepo("test-repo"); repo.GithubURL != "https://github.com/new/repo" {
+		t.Errorf("GithubURL after reload = %q", repo.GithubURL)
+	}
+
+	if err := s.UpdateGithubURL("missing", "https://github.com/x/y"); err == nil {
+		t.Error("UpdateGithubURL() on missing repo should fail")
+	}
+}
+
+func TestReviewQueue(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: map[string]Agent{"review-1": {Type: AgentTypeReview}}}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	err := s.UpdateReviewQueue("test-repo", func(queue *ReviewQueue, agents map[string]Agent) error {
+		if _, ok := agents["review-1"]; !ok {
+			t.Error("update didn't see the repository's agents")
+		}
+		queue.Reviews = append(queue.Reviews, ReviewAssignment{PRNumber: 42, Reviewer: "review-1"}, ReviewAssignment{PRNumber: 43})
+		queue.Last = "review-1"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateReviewQueue() failed: %v", err)
+	}
+
+	// A failed update changes nothing
+	err = s.UpdateReviewQueue("test-repo", func(queue *ReviewQueue, agents map[string]Agent) error {
+		queue.Reviews = nil
+		return fmt.Errorf("no")
+	})
+	if err == nil {
+		t.Error("UpdateReviewQueue() should return update's error")
+	}
+	if err := s.UpdateReviewQueue("missing", func(*ReviewQueue, map[string]Agent) error { return nil }); err == nil {
+		t.Error("UpdateReviewQueue() on missing repo should fail")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	queue := loaded.GetAllRepos()["test-repo"].ReviewQueue
+	if len(queue.Reviews) != 2 || queue.Last != "review-1" {
+		t.Fatalf("ReviewQueue after reload = %+v", queue)
+	}
+	if queue.Depth("review-1") != 1 || queue.Depth("") != 1 || queue.Depth("review-2") != 0 {
+		t.Errorf("Depth() = %d, %d, %d; want 1, 1, 0", queue.Depth("review-1"), queue.Depth(""), queue.Depth("review-2"))
+	}
+	if queue.Find(43) != 1 || queue.Find(7) != -1 {
+		t.Errorf("Find() = %d, %d; want 1, -1", queue.Find(43), queue.Find(7))
+	}
+}
+
+func TestParseReviewDispatch(t *testing.T) {
+	for _, s := range []string{"round-robin", "least-loaded"} {
+		if got, err := ParseReviewDispatch(s); err != nil || string(got) != s {
+			t.Errorf("ParseReviewDispatch(%q) = %q, %v", s, got, err)
+		}
+	}
+	if _, err := ParseReviewDispatch("random"); err == nil {
+		t.Error("ParseReviewDispatch(random) should fail")
+	}
+	if got := ReviewDispatch("").Effective(); got != ReviewDispatchRoundRobin {
+		t.Errorf("Effective() = %q, want round-robin", got)
+	}
+}
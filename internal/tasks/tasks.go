@@ -0,0 +1,345 @@
+// Package tasks holds queued tasks and the dependencies between them. A task
+// added with `multiclaude task add "B" --after <id>` waits until every task
+// it comes after is merged or completed; the daemon then starts a worker on
+// it and follows the worker through to its PR. Prerequisites must already
+// exist when a task is added, so the dependencies always form a DAG.
+package tasks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status is where a task is in its lifecycle
+type Status string
+
+const (
+	// StatusWaiting means the task is held until its prerequisites are done
+	StatusWaiting Status = "waiting"
+	// StatusRunning means a worker is working on the task
+	StatusRunning Status = "running"
+	// StatusReview means the worker finished and its PR is open
+	StatusReview Status = "review"
+	// StatusMerged means the task's PR was merged
+	StatusMerged Status = "merged"
+	// StatusCompleted means the worker finished without opening a PR
+	StatusCompleted Status = "completed"
+	// StatusFailed means the worker failed, disappeared or its PR was closed
+	StatusFailed Status = "failed"
+	// StatusCancelled means the task was cancelled before it started
+	StatusCancelled Status = "cancelled"
+)
+
+// TTL is how long finished tasks are kept once nothing waits on them
+const TTL = 7 * 24 * time.Hour
+
+var (
+	// ErrNotFound is returned for an unknown task ID
+	ErrNotFound = errors.New("task not found")
+	// ErrStarted is returned when cancelling a task that already has a worker
+	ErrStarted = errors.New("task already started")
+)
+
+// Task is a unit of work for a worker, optionally coming after other tasks
+type Task struct {
+	ID          string `json:"id"`
+	Repo        string `json:"repo"`
+	Description string `json:"description"`
+	// After lists the IDs of the tasks that must be done before this one starts
+	After  []string `json:"after,omitempty"`
+	Status Status   `json:"status"`
+	// Worker is the worker started on the task, and Branch its branch
+	Worker    string     `json:"worker,omitempty"`
+	Branch    string     `json:"branch,omitempty"`
+	PRURL     string     `json:"pr_url,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+}
+
+// Done reports whether the task counts as done for the tasks that come after it
+func (t *Task) Done() bool {
+	return t.Status == StatusMerged || t.Status == StatusCompleted
+}
+
+// Finished reports whether nothing more will happen to the task
+func (t *Task) Finished() bool {
+	return t.Done() || t.Status == StatusFailed || t.Status == StatusCancelled
+}
+
+// Finish records the task's final status
+func (t *Task) Finish(status Status, reason string, now time.Time) {
+	t.Status = status
+	t.Error = reason
+	t.DoneAt = &now
+}
+
+// Store holds the daemon's tasks. It is not safe for concurrent use; the
+// daemon loads, changes and saves it under a lock.
+type Store struct {
+	Tasks map[string]*Task `json:"tasks"`
+
+	path string
+}
+
+// Load reads a store, returning an empty one if the file doesn't exist
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Tasks: make(map[string]*Task)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse tasks: %w", err)
+	}
+	if s.Tasks == nil {
+		s.Tasks = make(map[string]*Task)
+	}
+	return s, nil
+}
+
+// Save writes the store back to disk
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create tasks directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tasks: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tasks: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write tasks: %w", err)
+	}
+	return nil
+}
+
+// Add queues a task. Every prerequisite must be a task in the same repository.
+func (s *Store) Add(repo, description string, after []string, now time.Time) (*Task, error) {
+	seen := make(map[string]bool, len(after))
+	var prereqs []string
+	for _, id := range after {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		prereq, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if prereq.Repo != repo {
+			return nil, fmt.Errorf("task %s belongs to repository '%s', not '%s'", id, prereq.Repo, repo)
+		}
+		prereqs = append(prereqs, id)
+	}
+
+	t := &Task{
+		ID:          newID(),
+		Repo:        repo,
+		Description: description,
+		After:       prereqs,
+		Status:      StatusWaiting,
+		CreatedAt:   now,
+	}
+	s.Tasks[t.ID] = t
+	return t, nil
+}
+
+// Get returns a task by ID
+func (s *Store) Get(id string) (*Task, error) {
+	t, ok := s.Tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return t, nil
+}
+
+// Cancel cancels a task that hasn't started. Tasks after it stay blocked.
+func (s *Store) Cancel(id string, now time.Time) (*Task, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != StatusWaiting {
+		return nil, fmt.Errorf("%w: %s is %s", ErrStarted, id, t.Status)
+	}
+	t.Finish(StatusCancelled, "", now)
+	return t, nil
+}
+
+// List returns a repository's tasks, or every task if repo is empty, oldest first
+func (s *Store) List(repo string) []*Task {
+	var list []*Task
+	for _, t := range s.Tasks {
+		if repo == "" || t.Repo == repo {
+			list = append(list, t)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Ready returns the waiting tasks whose prerequisites are all done, oldest first
+func (s *Store) Ready() []*Task {
+	var ready []*Task
+	for _, t := range s.List("") {
+		if t.Status == StatusWaiting && len(s.Pending(t)) == 0 && len(s.Blocking(t)) == 0 {
+			ready = append(ready, t)
+		}
+	}
+	return ready
+}
+
+// Pending returns the IDs of a task's prerequisites that aren't done yet
+func (s *Store) Pending(t *Task) []string {
+	var pending []string
+	for _, id := range t.After {
+		if prereq, ok := s.Tasks[id]; !ok || !prereq.Done() {
+			pending = append(pending, id)
+		}
+	}
+	return pending
+}
+
+// Blocking returns the IDs of a task's prerequisites that failed, were
+// cancelled or were pruned. The task can't start until it is cancelled or
+// the prerequisite is retried as a new task.
+func (s *Store) Blocking(t *Task) []string {
+	var blocking []string
+	for _, id := range t.After {
+		prereq, ok := s.Tasks[id]
+		if !ok || prereq.Status == StatusFailed || prereq.Status == StatusCancelled {
+			blocking = append(blocking, id)
+		}
+	}
+	return blocking
+}
+
+// Prune drops tasks that finished more than TTL ago, unless a task that
+// hasn't finished comes after them
+func (s *Store) Prune(now time.Time) {
+	needed := make(map[string]bool)
+	for _, t := range s.Tasks {
+		if !t.Finished() {
+			for _, id := range t.After {
+				needed[id] = true
+			}
+		}
+	}
+	for id, t := range s.Tasks {
+		if t.Finished() && t.DoneAt != nil && now.Sub(*t.DoneAt) > TTL && !needed[id] {
+			delete(s.Tasks, id)
+		}
+	}
+}
+
+// Render draws tasks as a tree per task with no prerequisites among them,
+// each task under the tasks it comes after. A task with several
+// prerequisites appears under each; after the first time it is drawn, its
+// own dependents are not repeated.
+func Render(list []*Task) string {
+	byID := make(map[string]*Task, len(list))
+	for _, t := range list {
+		byID[t.ID] = t
+	}
+	children := make(map[string][]*Task)
+	var roots []*Task
+	for _, t := range list {
+		isRoot := true
+		for _, id := range t.After {
+			if _, ok := byID[id]; ok {
+				children[id] = append(children[id], t)
+				isRoot = false
+			}
+		}
+		if isRoot {
+			roots = append(roots, t)
+		}
+	}
+
+	var b strings.Builder
+	drawn := make(map[string]bool)
+	var draw func(t *Task, prefix, branch, indent string)
+	draw = func(t *Task, prefix, branch, indent string) {
+		fmt.Fprintf(&b, "%s%s%s  %-9s  %s", prefix, branch, t.ID, t.Status, firstLine(t.Description))
+		if drawn[t.ID] && len(children[t.ID]) > 0 {
+			b.WriteString("  (see above)\n")
+			return
+		}
+		b.WriteString("\n")
+		drawn[t.ID] = true
+		kids := children[t.ID]
+		for i, kid := range kids {
+			if i == len(kids)-1 {
+				draw(kid, prefix+indent, "└─ ", "   ")
+			} else {
+				draw(kid, prefix+indent, "├─ ", "│  ")
+			}
+		}
+	}
+	for _, root := range roots {
+		draw(root, "", "", "")
+	}
+	return b.String()
+}
+
+// DOT returns tasks as a Graphviz digraph, with an edge from each
+// prerequisite to the task that comes after it
+func DOT(list []*Task) string {
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n  rankdir=LR;\n  node [shape=box];\n")
+	known := make(map[string]bool, len(list))
+	for _, t := range list {
+		known[t.ID] = true
+		label := fmt.Sprintf("%s\\n%s\\n%s", t.ID, t.Status, firstLine(t.Description))
+		fmt.Fprintf(&b, "  %q [label=\"%s\"];\n", t.ID, strings.ReplaceAll(label, `"`, `\"`))
+	}
+	for _, t := range list {
+		for _, id := range t.After {
+			if known[id] {
+				fmt.Fprintf(&b, "  %q -> %q;\n", id, t.ID)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// firstLine returns the first line of a description, shortened for display
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if len([]rune(s)) > 60 {
+		s = string([]rune(s)[:57]) + "..."
+	}
+	return s
+}
+
+// newID returns a short random task ID
+func newID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("t-%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return "t-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() on a missing file error: %v", err)
+	}
+
+	now := time.Now()
+	a, err := s.Add("repo", "Add the schema", nil, now)
+	if err != nil || !strings.HasPrefix(a.ID, "t-") || a.Status != StatusWaiting {
+		t.Fatalf("Add() = %+v, %v, want a waiting t- task", a, err)
+	}
+	b, err := s.Add("repo", "Build the API", []string{a.ID, a.ID}, now.Add(time.Second))
+	if err != nil || len(b.After) != 1 {
+		t.Fatalf("Add(after) = %+v, %v, want one prerequisite", b, err)
+	}
+	if _, err := s.Add("repo", "x", []string{"t-missing"}, now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Add() with an unknown prerequisite error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Add("other", "x", []string{a.ID}, now); err == nil {
+		t.Error("Add() accepted a prerequisite from another repository")
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if s, err = Load(path); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	a, b = s.Tasks[a.ID], s.Tasks[b.ID]
+
+	if ready := s.Ready(); len(ready) != 1 || ready[0].ID != a.ID {
+		t.Errorf("Ready() = %v, want only the task without prerequisites", ready)
+	}
+	a.Status = StatusReview
+	if ready := s.Ready(); len(ready) != 0 {
+		t.Errorf("Ready() = %v while the prerequisite is in review", ready)
+	}
+	a.Finish(StatusMerged, "", now)
+	if ready := s.Ready(); len(ready) != 1 || ready[0].ID != b.ID {
+		t.Errorf("Ready() = %v, want the task whose prerequisite merged", ready)
+	}
+
+	c, _ := s.Add("repo", "Wire the UI", []string{b.ID}, now.Add(2*time.Second))
+	if _, err := s.Cancel(b.ID, now); err != nil {
+		t.Fatalf("Cancel() error: %v", err)
+	}
+	if blocking := s.Blocking(c); len(blocking) != 1 || blocking[0] != b.ID {
+		t.Errorf("Blocking() = %v, want the cancelled prerequisite", blocking)
+	}
+	if ready := s.Ready(); len(ready) != 0 {
+		t.Errorf("Ready() = %v, want nothing behind a cancelled task", ready)
+	}
+	if _, err := s.Cancel(a.ID, now); !errors.Is(err, ErrStarted) {
+		t.Errorf("Cancel() on a merged task error = %v, want ErrStarted", err)
+	}
+
+	// Finished tasks are kept while an unfinished task comes after them
+	s.Prune(now.Add(TTL + time.Minute))
+	if _, kept := s.Tasks[b.ID]; !kept || len(s.Tasks) != 2 {
+		t.Errorf("Prune() kept %v, want the cancelled prerequisite and its dependent", s.List(""))
+	}
+	s.Cancel(c.ID, now)
+	s.Prune(now.Add(TTL + time.Minute))
+	if len(s.Tasks) != 0 {
+		t.Errorf("Prune() kept %d tasks, want none", len(s.Tasks))
+	}
+}
+
+func TestRender(t *testing.T) {
+	now := time.Now()
+	list := []*Task{
+		{ID: "t-a", Description: "Add the schema", Status: StatusMerged, CreatedAt: now},
+		{ID: "t-b", Description: "Build the API", After: []string{"t-a"}, Status: StatusRunning, CreatedAt: now},
+		{ID: "t-c", Description: "Write the docs", After: []string{"t-a"}, Status: StatusWaiting, CreatedAt: now},
+		{ID: "t-d", Description: "Wire the UI\nwith details", After: []string{"t-b", "t-c"}, Status: StatusWaiting, CreatedAt: now},
+		{ID: "t-e", Description: "Ship it", After: []string{"t-d"}, Status: StatusWaiting, CreatedAt: now},
+	}
+
+	want := strings.Join([]string{
+		"t-a  merged     Add the schema",
+		"├─ t-b  running    Build the API",
+		"│  └─ t-d  waiting    Wire the UI",
+		"│     └─ t-e  waiting    Ship it",
+		"└─ t-c  waiting    Write the docs",
+		"   └─ t-d  waiting    Wire the UI  (see above)",
+		"",
+	}, "\n")
+	if got := Render(list); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+
+	dot := DOT(list)
+	for _, edge := range []string{`"t-a" -> "t-b"`, `"t-b" -> "t-d"`, `"t-c" -> "t-d"`, `"t-d" -> "t-e"`} {
+		if !strings.Contains(dot, edge) {
+			t.Errorf("DOT() is missing edge %s:\n%s", edge, dot)
+		}
+	}
+}
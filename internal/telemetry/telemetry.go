@@ -0,0 +1,160 @@
+// Package telemetry records, for users who opt in, which CLI commands run, how
+// long they take and whether they fail, for `multiclaude stats` to summarize.
+// Nothing leaves the machine and no arguments or output are kept: each run is
+// one line in a local file naming the command, its duration and outcome.
+//
+// The file's existence is the opt-in. Lines are appended with O_APPEND, so
+// the many CLI processes agents run at once don't lose each other's records.
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Event is one command run
+type Event struct {
+	Command string    `json:"cmd"`
+	At      time.Time `json:"at"`
+	// DurationMS is how long the command took, in milliseconds
+	DurationMS int64 `json:"ms"`
+	Failed     bool  `json:"failed,omitempty"`
+}
+
+// Enabled reports whether telemetry is on
+func Enabled(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Enable turns telemetry on, keeping anything already recorded
+func Enable(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create telemetry directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to enable telemetry: %w", err)
+	}
+	return f.Close()
+}
+
+// Disable turns telemetry off and deletes what was recorded
+func Disable(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to disable telemetry: %w", err)
+	}
+	return nil
+}
+
+// Record appends a command run, doing nothing if telemetry is off
+func Record(path, command string, duration time.Duration, failed bool, at time.Time) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open telemetry file: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(Event{Command: command, At: at, DurationMS: duration.Milliseconds(), Failed: failed})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry event: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write telemetry event: %w", err)
+	}
+	return nil
+}
+
+// Load reads every recorded run. Lines that don't parse, such as one cut
+// short by a crash, are skipped.
+func Load(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read telemetry: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Command == "" {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read telemetry: %w", err)
+	}
+	return events, nil
+}
+
+// CommandStats summarizes the runs of one command
+type CommandStats struct {
+	Command  string        `json:"command"`
+	Runs     int           `json:"runs"`
+	Failures int           `json:"failures"`
+	Mean     time.Duration `json:"mean_ns"`
+	Max      time.Duration `json:"max_ns"`
+	LastRun  time.Time     `json:"last_run"`
+}
+
+// FailureRate returns the fraction of runs that failed
+func (s CommandStats) FailureRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Runs)
+}
+
+// Summarize totals the runs at or after since by command, most used first
+func Summarize(events []Event, since time.Time) []CommandStats {
+	byCommand := make(map[string]*CommandStats)
+	totals := make(map[string]time.Duration)
+	for _, e := range events {
+		if e.At.Before(since) {
+			continue
+		}
+		s, ok := byCommand[e.Command]
+		if !ok {
+			s = &CommandStats{Command: e.Command}
+			byCommand[e.Command] = s
+		}
+		d := time.Duration(e.DurationMS) * time.Millisecond
+		s.Runs++
+		if e.Failed {
+			s.Failures++
+		}
+		if d > s.Max {
+			s.Max = d
+		}
+		if e.At.After(s.LastRun) {
+			s.LastRun = e.At
+		}
+		totals[e.Command] += d
+	}
+
+	stats := make([]CommandStats, 0, len(byCommand))
+	for name, s := range byCommand {
+		s.Mean = totals[name] / time.Duration(s.Runs)
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Runs != stats[j].Runs {
+			return stats[i].Runs > stats[j].Runs
+		}
+		return stats[i].Command < stats[j].Command
+	})
+	return stats
+}
@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordOnlyWhenEnabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	if err := Record(path, "status", time.Second, false, now); err != nil {
+		t.Fatalf("Record() while off failed: %v", err)
+	}
+	if Enabled(path) {
+		t.Fatal("Record() turned telemetry on")
+	}
+
+	if err := Enable(path); err != nil {
+		t.Fatalf("Enable() failed: %v", err)
+	}
+	Record(path, "status", 100*time.Millisecond, false, now)
+	Record(path, "worker create", 3*time.Second, true, now)
+	Record(path, "status", 300*time.Millisecond, false, now.Add(time.Hour))
+
+	// A truncated line is skipped
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"cmd":"sta`)
+	f.Close()
+
+	events, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Load() = %d events, want 3", len(events))
+	}
+
+	if err := Enable(path); err != nil {
+		t.Fatalf("Enable() again failed: %v", err)
+	}
+	if events, _ := Load(path); len(events) != 3 {
+		t.Errorf("Enable() on an enabled file lost events: %d left", len(events))
+	}
+
+	if err := Disable(path); err != nil {
+		t.Fatalf("Disable() failed: %v", err)
+	}
+	if Enabled(path) {
+		t.Error("Disable() left telemetry on")
+	}
+	if err := Disable(path); err != nil {
+		t.Errorf("Disable() when off failed: %v", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Command: "status", At: now.Add(-48 * time.Hour), DurationMS: 9000},
+		{Command: "status", At: now, DurationMS: 100},
+		{Command: "status", At: now.Add(time.Minute), DurationMS: 300},
+		{Command: "worker create", At: now, DurationMS: 3000, Failed: true},
+		{Command: "attach", At: now, DurationMS: 50},
+	}
+
+	stats := Summarize(events, now.Add(-24*time.Hour))
+	if len(stats) != 3 {
+		t.Fatalf("Summarize() = %+v, want 3 commands", stats)
+	}
+	status := stats[0]
+	if status.Command != "status" || status.Runs != 2 || status.Mean != 200*time.Millisecond ||
+		status.Max != 300*time.Millisecond || !status.LastRun.Equal(now.Add(time.Minute)) {
+		t.Errorf("status stats = %+v", status)
+	}
+	// Ties are broken by name
+	if stats[1].Command != "attach" || stats[2].Command != "worker create" {
+		t.Errorf("order = %s, %s; want attach, worker create", stats[1].Command, stats[2].Command)
+	}
+	if rate := stats[2].FailureRate(); rate != 1 {
+		t.Errorf("FailureRate() = %v, want 1", rate)
+	}
+}
@@ -0,0 +1,123 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the locale used when none is set, and for texts a locale
+// doesn't have
+const DefaultLocale = "en"
+
+// The nudges, notices and other operator-facing texts, one file per locale
+//
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+var (
+	catalogsOnce sync.Once
+	catalogs     map[string]map[string]string
+	catalogsErr  error
+)
+
+// loadCatalogs parses the embedded locale files once
+func loadCatalogs() (map[string]map[string]string, error) {
+	catalogsOnce.Do(func() {
+		entries, err := localeFiles.ReadDir("locales")
+		if err != nil {
+			catalogsErr = fmt.Errorf("failed to read locales: %w", err)
+			return
+		}
+		catalogs = make(map[string]map[string]string, len(entries))
+		for _, entry := range entries {
+			data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+			if err != nil {
+				catalogsErr = fmt.Errorf("failed to read locale %s: %w", entry.Name(), err)
+				return
+			}
+			texts := map[string]string{}
+			if err := yaml.Unmarshal(data, &texts); err != nil {
+				catalogsErr = fmt.Errorf("invalid locale %s: %w", entry.Name(), err)
+				return
+			}
+			catalogs[strings.TrimSuffix(entry.Name(), ".yaml")] = texts
+		}
+	})
+	return catalogs, catalogsErr
+}
+
+// Locales returns the available locales, sorted
+func Locales() []string {
+	all, _ := loadCatalogs()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidLocale reports whether locale is one of Locales. "" is valid and
+// means DefaultLocale.
+func ValidLocale(locale string) bool {
+	if locale == "" {
+		return true
+	}
+	all, _ := loadCatalogs()
+	_, ok := all[locale]
+	return ok
+}
+
+// Text returns the text for key in locale, filled in from data. It falls
+// back to English when the locale or the key is unknown, and to the key
+// itself when English doesn't have it either, so a missing text shows up
+// rather than leaving a blank.
+func Text(locale, key string, data interface{}) string {
+	all, err := loadCatalogs()
+	if err != nil {
+		return key
+	}
+	text, ok := all[locale][key]
+	if !ok {
+		if text, ok = all[DefaultLocale][key]; !ok {
+			return key
+		}
+	}
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New(key).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return text
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return text
+	}
+	return out.String()
+}
+
+// Markers returns what every locale's nudges start with, for telling what
+// the daemon typed into a window from what the agent printed there
+func Markers() []string {
+	all, _ := loadCatalogs()
+	seen := map[string]bool{}
+	var markers []string
+	for _, name := range Locales() {
+		for _, key := range []string{"status_check", "health_check"} {
+			if marker := all[name][key]; marker != "" && !seen[marker] {
+				seen[marker] = true
+				markers = append(markers, marker)
+			}
+		}
+	}
+	return markers
+}
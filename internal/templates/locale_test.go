@@ -0,0 +1,69 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocales(t *testing.T) {
+	locales := Locales()
+	if len(locales) < 2 || locales[0] != "en" || locales[1] != "ja" {
+		t.Fatalf("Locales() = %v, want en and ja", locales)
+	}
+	if !ValidLocale("") || !ValidLocale("ja") || ValidLocale("xx") {
+		t.Error("ValidLocale() should take \"\", en and ja, and nothing else")
+	}
+
+	all, err := loadCatalogs()
+	if err != nil {
+		t.Fatalf("loadCatalogs() error: %v", err)
+	}
+	for _, locale := range locales {
+		texts := all[locale]
+		// Every locale translates everything, so none falls back to English
+		for key := range all[DefaultLocale] {
+			if texts[key] == "" {
+				t.Errorf("%s has no %s", locale, key)
+			}
+		}
+		// ...and its nudges can be told from the agent's output
+		for key, text := range texts {
+			if !strings.HasPrefix(key, "nudge.") {
+				continue
+			}
+			if !strings.HasPrefix(text, texts["status_check"]) && !strings.HasPrefix(text, texts["health_check"]) {
+				t.Errorf("%s %s = %q, want it to start with a status or health check marker", locale, key, text)
+			}
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	if got := Text("ja", "nudge.wake.worker", nil); got != "状況確認: 進捗はいかがですか?" {
+		t.Errorf("Text(ja) = %q", got)
+	}
+	if got := Text("xx", "nudge.wake.worker", nil); got != "Status check: Update on your progress?" {
+		t.Errorf("Text() of an unknown locale = %q, want English", got)
+	}
+	if got := Text("en", "no.such.key", nil); got != "no.such.key" {
+		t.Errorf("Text() of an unknown key = %q, want the key", got)
+	}
+
+	motd := Text("ja", "motd", map[string]string{"Agent": "calm-owl", "Type": "worker", "Repo": "my-app"})
+	if !strings.Contains(motd, "my-app の calm-owl (worker)") || !strings.Contains(motd, "multiclaude agent restart calm-owl") {
+		t.Errorf("Text(motd) = %q", motd)
+	}
+}
+
+func TestMarkers(t *testing.T) {
+	markers := Markers()
+	for _, want := range []string{"Status check:", "Health check:", "状況確認:", "ヘルスチェック:"} {
+		found := false
+		for _, m := range markers {
+			found = found || m == want
+		}
+		if !found {
+			t.Errorf("Markers() = %v, want %q", markers, want)
+		}
+	}
+}
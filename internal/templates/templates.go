@@ -1,5 +1,6 @@
 // Package templates provides embedded agent templates that are copied to
-// per-repository agent directories during initialization.
+// per-repository agent directories during initialization, and the localized
+// nudges and notices the daemon types into agents' windows.
 package templates
 
 import (
@@ -82,3 +83,13 @@ func ListAgentTemplates() ([]string, error) {
 
 	return templates, nil
 }
+
+// ReadAgentTemplate returns the contents of an agent template, by file name
+// as returned by ListAgentTemplates
+func ReadAgentTemplate(name string) ([]byte, error) {
+	content, err := agentTemplates.ReadFile("agent-templates/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent template %s: %w", name, err)
+	}
+	return content, nil
+}
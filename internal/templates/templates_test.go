@@ -18,6 +18,7 @@ func TestListAgentTemplates(t *testing.T) {
 		"pr-shepherd.md": true,
 		"worker.md":      true,
 		"reviewer.md":    true,
+		"observer.md":    true,
 	}
 
 	if len(templates) != len(expected) {
@@ -52,7 +53,7 @@ func TestCopyAgentTemplates(t *testing.T) {
 	}
 
 	// Verify all expected files exist and have content
-	expectedFiles := []string{"merge-queue.md", "pr-shepherd.md", "worker.md", "reviewer.md"}
+	expectedFiles := []string{"merge-queue.md", "pr-shepherd.md", "worker.md", "reviewer.md", "observer.md"}
 	for _, filename := range expectedFiles {
 		path := filepath.Join(destDir, filename)
 		info, err := os.Stat(path)
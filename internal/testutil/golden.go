@@ -0,0 +1,83 @@
+package testutil
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// update is the -update flag of test binaries that use golden files. Test
+// packages that import testutil get it, and mustn't define their own.
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// packageDir is the test's package directory, where go test starts test
+// binaries, kept in case a test changes directory before comparing
+var packageDir, _ = os.Getwd()
+
+// Golden compares got with testdata/<name>.golden in the test's package, or
+// rewrites the file when the test runs with -update
+func Golden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join(packageDir, "testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Errorf("no golden file %s; run the test with -update to write it:\n%s", path, got)
+		return
+	}
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if string(want) != got {
+		t.Errorf("differs from %s at %s; if intended, run the test with -update\ngot:\n%s", path, firstDiff(string(want), got), got)
+	}
+}
+
+// firstDiff describes the first line where got differs from want
+func firstDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i >= len(wantLines) || i >= len(gotLines) || w != g {
+			return fmt.Sprintf("line %d: golden %q, got %q", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+// pollInterval is how often Eventually checks its condition
+const pollInterval = 20 * time.Millisecond
+
+// Eventually waits for cond to hold, failing the test with what it was
+// waiting for if it doesn't within the timeout. Use it in place of sleeping
+// for as long as something usually takes.
+func Eventually(t testing.TB, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(pollInterval)
+	}
+}
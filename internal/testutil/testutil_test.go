@@ -0,0 +1,58 @@
+package testutil
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestTmuxCommand(t *testing.T) {
+	c := TmuxCommand{"-L", "x", "set-buffer", "--", "two words\nand a line"}
+	if c.Name() != "set-buffer" {
+		t.Errorf("Name() = %q, want set-buffer", c.Name())
+	}
+	if got, want := c.String(), `tmux -L x set-buffer -- "two words\nand a line"`; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if (TmuxCommand{"-V"}).Name() != "-V" {
+		t.Error("Name() of tmux -V isn't -V")
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	commands := []TmuxCommand{{"send-keys", "b"}, {"send-keys", "a"}}
+	if got := Transcript(commands, false); got != "tmux send-keys b\ntmux send-keys a\n" {
+		t.Errorf("Transcript() = %q", got)
+	}
+	if got := Transcript(commands, true); got != "tmux send-keys a\ntmux send-keys b\n" {
+		t.Errorf("sorted Transcript() = %q", got)
+	}
+}
+
+func TestRecordTmux(t *testing.T) {
+	rec := RecordTmux(t)
+	rec.Replace("/tmp/work", "<work>")
+	for _, args := range [][]string{{"-V"}, {"-L", "testutil-none", "has-session", "-t", "/tmp/work/x y"}} {
+		// has-session fails without a server; it's recorded all the same
+		exec.Command("tmux", args...).Run()
+	}
+
+	want := []TmuxCommand{{"-V"}, {"-L", "testutil-none", "has-session", "-t", "<work>/x y"}}
+	if got := rec.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands() = %q, want %q", got, want)
+	}
+	if got := rec.Mutations(); len(got) != 0 {
+		t.Errorf("Mutations() = %q, want none", got)
+	}
+
+	rec.Reset()
+	if got := rec.Commands(); got != nil {
+		t.Errorf("Commands() after Reset() = %q, want none", got)
+	}
+}
+
+func TestTrimPane(t *testing.T) {
+	if got := trimPane("a  \nb\t\n\n\n"); got != "a\nb\n" {
+		t.Errorf("trimPane() = %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+// Package testutil makes integration tests of multiclaude's tmux flows
+// reproducible. A TmuxRecorder puts a tmux on PATH that logs each command
+// before running the real one, and takes pane snapshots once a pane has
+// settled, so a test can compare what a flow did with golden files in its
+// testdata directory instead of sleeping and hoping the timing holds.
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/shell"
+)
+
+// Separators in the recorder's log: one record per command, one unit per
+// argument. Arguments can hold newlines (pasted text), but not these.
+const (
+	recordSep = "\x1e"
+	unitSep   = "\x1f"
+)
+
+// queries are the tmux commands that only look, which health checks and
+// waits issue on their own schedule. Mutations leaves them out.
+var queries = map[string]bool{
+	"-V":               true,
+	"capture-pane":     true,
+	"display-message":  true,
+	"has-session":      true,
+	"list-clients":     true,
+	"list-panes":       true,
+	"list-sessions":    true,
+	"list-windows":     true,
+	"show-buffer":      true,
+	"show-environment": true,
+	"show-options":     true,
+}
+
+// TmuxCommand is one recorded tmux invocation's arguments
+type TmuxCommand []string
+
+// Name returns the tmux command, such as "new-window", after tmux's own
+// options
+func (c TmuxCommand) Name() string {
+	for i := 0; i < len(c); i++ {
+		switch arg := c[i]; {
+		case globalOptionsWithValues[arg]:
+			i++
+		case !strings.HasPrefix(arg, "-") || arg == "-V":
+			return arg
+		}
+	}
+	return ""
+}
+
+// globalOptionsWithValues are the options tmux takes before the command that
+// have a value
+var globalOptionsWithValues = map[string]bool{"-L": true, "-S": true, "-f": true, "-c": true, "-T": true}
+
+// String renders the command on one line, quoting arguments with spaces and
+// escaping newlines, for golden files
+func (c TmuxCommand) String() string {
+	parts := make([]string, len(c))
+	for i, arg := range c {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(arg) + `"`
+		}
+		parts[i] = arg
+	}
+	return "tmux " + strings.Join(parts, " ")
+}
+
+// TmuxRecorder records the tmux commands the test process and everything it
+// starts run, for the rest of the test
+type TmuxRecorder struct {
+	t        testing.TB
+	tmuxPath string
+	log      string
+	replace  [][2]string
+}
+
+// RecordTmux puts a recording tmux first on PATH for the rest of the test.
+// The test fails if tmux isn't installed. Like t.Setenv, it can't be used in
+// parallel tests.
+func RecordTmux(t testing.TB) *TmuxRecorder {
+	t.Helper()
+	tmuxPath, err := exec.LookPath("tmux")
+	if err != nil {
+		t.Fatal("tmux is required for this test but not available")
+	}
+	dir := t.TempDir()
+	r := &TmuxRecorder{t: t, tmuxPath: tmuxPath, log: filepath.Join(dir, "tmux.log")}
+
+	// One printf per command, so commands run at once don't interleave
+	script := `#!/bin/sh
+rec=""
+for arg in "$@"; do rec="$rec$arg` + unitSep + `"; done
+printf '%s` + recordSep + `' "$rec" >> ` + shell.Quote(r.log) + `
+exec ` + shell.Quote(tmuxPath) + ` "$@"
+`
+	if err := os.WriteFile(filepath.Join(dir, "tmux"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write the recording tmux: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return r
+}
+
+// Replace has Commands and Snapshot show old as new, for text that differs
+// between runs, such as temporary directories. Replacements apply in the
+// order they were added.
+func (r *TmuxRecorder) Replace(old, new string) {
+	r.replace = append(r.replace, [2]string{old, new})
+}
+
+// normalize applies the replacements
+func (r *TmuxRecorder) normalize(s string) string {
+	for _, rep := range r.replace {
+		s = strings.ReplaceAll(s, rep[0], rep[1])
+	}
+	return s
+}
+
+// Reset forgets the commands recorded so far, so the next ones are a step's
+func (r *TmuxRecorder) Reset() {
+	r.t.Helper()
+	if err := os.Remove(r.log); err != nil && !os.IsNotExist(err) {
+		r.t.Fatalf("Failed to reset the tmux log: %v", err)
+	}
+}
+
+// Commands returns the commands recorded since the start or the last Reset,
+// in the order they ran, normalized
+func (r *TmuxRecorder) Commands() []TmuxCommand {
+	r.t.Helper()
+	data, err := os.ReadFile(r.log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		r.t.Fatalf("Failed to read the tmux log: %v", err)
+	}
+	var commands []TmuxCommand
+	for _, record := range strings.Split(string(data), recordSep) {
+		if record == "" {
+			continue
+		}
+		args := strings.Split(strings.TrimSuffix(record, unitSep), unitSep)
+		for i := range args {
+			args[i] = r.normalize(args[i])
+		}
+		commands = append(commands, TmuxCommand(args))
+	}
+	return commands
+}
+
+// Mutations returns the recorded commands that change something, leaving out
+// queries whose number depends on timing
+func (r *TmuxRecorder) Mutations() []TmuxCommand {
+	r.t.Helper()
+	var mutations []TmuxCommand
+	for _, c := range r.Commands() {
+		if !queries[c.Name()] {
+			mutations = append(mutations, c)
+		}
+	}
+	return mutations
+}
+
+// Transcript renders commands one per line, for a golden file. With sorted,
+// the lines are sorted, for flows whose commands race with each other.
+func Transcript(commands []TmuxCommand, sorted bool) string {
+	lines := make([]string, len(commands))
+	for i, c := range commands {
+		lines[i] = c.String()
+	}
+	if sorted {
+		sort.Strings(lines)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Snapshot captures a pane's visible content once it has stopped changing,
+// normalized and with trailing blank space trimmed. target is a tmux target
+// such as "mc-repo:worker". The test fails if the pane doesn't settle within
+// the timeout.
+func (r *TmuxRecorder) Snapshot(target string, timeout time.Duration) string {
+	r.t.Helper()
+	capture := func() string {
+		// The real tmux, so snapshots don't show up among the commands
+		out, err := exec.Command(r.tmuxPath, "capture-pane", "-p", "-t", target).Output()
+		if err != nil {
+			r.t.Fatalf("Failed to capture pane %s: %v", target, err)
+		}
+		return trimPane(string(out))
+	}
+
+	deadline := time.Now().Add(timeout)
+	last := capture()
+	for {
+		time.Sleep(settleInterval)
+		current := capture()
+		if current == last {
+			return r.normalize(current)
+		}
+		if time.Now().After(deadline) {
+			r.t.Fatalf("Pane %s still changing after %s", target, timeout)
+		}
+		last = current
+	}
+}
+
+// settleInterval is how long a pane must stay the same to count as settled
+const settleInterval = 200 * time.Millisecond
+
+// trimPane trims each line's trailing spaces and the blank lines at the end
+// of a captured pane
+func trimPane(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
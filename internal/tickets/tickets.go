@@ -0,0 +1,191 @@
+// Package tickets correlates questions and answers between agents. An agent
+// asks another a question with `multiclaude ask`; the daemon records a ticket
+// and delivers the question as a message naming the ticket, and the target
+// replies with `multiclaude answer <ticket>`. The asker either waits for the
+// answer or gets it later as a message.
+package tickets
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Status is where a ticket is in its lifecycle
+type Status string
+
+const (
+	// StatusPending means the question hasn't been answered and the deadline hasn't passed
+	StatusPending Status = "pending"
+	// StatusAnswered means the target replied
+	StatusAnswered Status = "answered"
+	// StatusExpired means the deadline passed without an answer
+	StatusExpired Status = "expired"
+)
+
+// TTL is how long answered and expired tickets are kept
+const TTL = 24 * time.Hour
+
+var (
+	// ErrNotFound is returned for an unknown ticket ID
+	ErrNotFound = errors.New("ticket not found")
+	// ErrAnswered is returned when a ticket already has an answer
+	ErrAnswered = errors.New("ticket was already answered")
+)
+
+// Ticket is a question one agent asked another
+type Ticket struct {
+	ID       string    `json:"id"`
+	Repo     string    `json:"repo"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Question string    `json:"question"`
+	AskedAt  time.Time `json:"asked_at"`
+	Deadline time.Time `json:"deadline"`
+	// Notify sends the answer to the asker as a message; set when the asker
+	// isn't waiting for it
+	Notify     bool       `json:"notify,omitempty"`
+	Answer     string     `json:"answer,omitempty"`
+	AnsweredBy string     `json:"answered_by,omitempty"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+}
+
+// Status returns the ticket's status at a point in time
+func (t *Ticket) Status(now time.Time) Status {
+	switch {
+	case t.AnsweredAt != nil:
+		return StatusAnswered
+	case now.After(t.Deadline):
+		return StatusExpired
+	default:
+		return StatusPending
+	}
+}
+
+// Store holds the daemon's tickets. It is not safe for concurrent use; the
+// daemon loads, changes and saves it under a lock.
+type Store struct {
+	Tickets map[string]*Ticket `json:"tickets"`
+
+	path string
+}
+
+// Load reads a store, returning an empty one if the file doesn't exist
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Tickets: make(map[string]*Ticket)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read tickets: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse tickets: %w", err)
+	}
+	if s.Tickets == nil {
+		s.Tickets = make(map[string]*Ticket)
+	}
+	return s, nil
+}
+
+// Save writes the store back to disk
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create tickets directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tickets: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tickets: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write tickets: %w", err)
+	}
+	return nil
+}
+
+// Open records a new question and returns its ticket
+func (s *Store) Open(repo, from, to, question string, timeout time.Duration, notify bool, now time.Time) *Ticket {
+	t := &Ticket{
+		ID:       newID(),
+		Repo:     repo,
+		From:     from,
+		To:       to,
+		Question: question,
+		AskedAt:  now,
+		Deadline: now.Add(timeout),
+		Notify:   notify,
+	}
+	s.Tickets[t.ID] = t
+	return t
+}
+
+// Get returns a ticket by ID
+func (s *Store) Get(id string) (*Ticket, error) {
+	t, ok := s.Tickets[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return t, nil
+}
+
+// Answer records the answer to a ticket. Late answers (after the deadline)
+// are recorded too; the caller decides how to deliver them.
+func (s *Store) Answer(id, answer, by string, now time.Time) (*Ticket, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.AnsweredAt != nil {
+		return nil, fmt.Errorf("%w by %s", ErrAnswered, t.AnsweredBy)
+	}
+	t.Answer = answer
+	t.AnsweredBy = by
+	t.AnsweredAt = &now
+	return t, nil
+}
+
+// Pending returns the unanswered, unexpired tickets addressed to an agent, oldest first
+func (s *Store) Pending(repo, to string, now time.Time) []*Ticket {
+	var pending []*Ticket
+	for _, t := range s.Tickets {
+		if t.Repo == repo && t.To == to && t.Status(now) == StatusPending {
+			pending = append(pending, t)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].AskedAt.Before(pending[j].AskedAt) })
+	return pending
+}
+
+// Prune drops tickets that were answered or expired more than TTL ago
+func (s *Store) Prune(now time.Time) {
+	for id, t := range s.Tickets {
+		done := t.Deadline
+		if t.AnsweredAt != nil {
+			done = *t.AnsweredAt
+		}
+		if now.Sub(done) > TTL {
+			delete(s.Tickets, id)
+		}
+	}
+}
+
+// newID returns a short random ticket ID
+func newID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("tk-%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return "tk-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,60 @@
+package tickets
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tickets.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() on a missing file error: %v", err)
+	}
+
+	now := time.Now()
+	tk := s.Open("repo", "supervisor", "calm-owl", "Which DB driver?", 10*time.Minute, false, now)
+	if !strings.HasPrefix(tk.ID, "tk-") || tk.Status(now) != StatusPending {
+		t.Errorf("Open() = %+v, want a pending tk- ticket", tk)
+	}
+	if tk.Status(now.Add(11*time.Minute)) != StatusExpired {
+		t.Error("a ticket past its deadline should be expired")
+	}
+	other := s.Open("repo", "supervisor", "calm-owl", "Any blockers?", time.Minute, true, now.Add(time.Second))
+	if pending := s.Pending("repo", "calm-owl", now.Add(2*time.Second)); len(pending) != 2 || pending[0] != tk {
+		t.Errorf("Pending() = %v, want both tickets, oldest first", pending)
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	s, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	answered, err := s.Answer(tk.ID, "pgx", "calm-owl", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Answer() error: %v", err)
+	}
+	if answered.Answer != "pgx" || answered.Status(now) != StatusAnswered || answered.Question != "Which DB driver?" {
+		t.Errorf("answered ticket = %+v", answered)
+	}
+	if _, err := s.Answer(tk.ID, "sqlx", "calm-owl", now); !errors.Is(err, ErrAnswered) {
+		t.Errorf("second Answer() error = %v, want ErrAnswered", err)
+	}
+	if _, err := s.Answer("tk-missing", "x", "calm-owl", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Answer() on an unknown ticket error = %v, want ErrNotFound", err)
+	}
+	if pending := s.Pending("repo", "calm-owl", now.Add(2*time.Second)); len(pending) != 1 || pending[0].ID != other.ID {
+		t.Errorf("Pending() after an answer = %v, want only the unanswered ticket", pending)
+	}
+
+	s.Prune(now.Add(TTL + 2*time.Minute))
+	if len(s.Tickets) != 0 {
+		t.Errorf("Prune() kept %d tickets, want none", len(s.Tickets))
+	}
+}
@@ -0,0 +1,351 @@
+// Package train simulates merges before the merge queue makes them. The
+// daemon keeps a train per repository: open PRs that, merged in order on top
+// of the default branch, pass the repository's test command. Each new PR is
+// merged onto the train's tip in a throwaway worktree and tested there, so a
+// PR that conflicts with or breaks one ahead of it is caught before either
+// is merged, rather than after.
+package train
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status is the result of a PR's simulation
+type Status string
+
+const (
+	// StatusPassed means the PR merged onto the train's tip and the tests passed; it is in the train
+	StatusPassed Status = "passed"
+	// StatusFailed means the PR didn't merge cleanly or the tests failed
+	StatusFailed Status = "failed"
+)
+
+// PR is an open pull request as the train sees it
+type PR struct {
+	Number int
+	Branch string
+	// Head is the commit the PR points at
+	Head string
+	// State is "open", "merged" or "closed"
+	State string
+}
+
+// Entry is a simulated PR. Failed entries stay until the PR's head changes,
+// so a broken PR isn't tested again until its worker pushes a fix.
+type Entry struct {
+	PR     int    `json:"pr"`
+	Branch string `json:"branch"`
+	Head   string `json:"head"`
+	Status Status `json:"status"`
+	// Ahead lists the train's PRs the entry was merged on top of
+	Ahead  []int  `json:"ahead,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Log is the path of the simulation's output
+	Log      string    `json:"log,omitempty"`
+	TestedAt time.Time `json:"tested_at"`
+}
+
+// Train is a repository's simulated PRs. It is not safe for concurrent use;
+// the daemon loads, changes and saves it under a lock.
+type Train struct {
+	Entries []Entry `json:"entries"`
+
+	path string
+}
+
+// Load reads a train, returning an empty one if the file doesn't exist
+func Load(path string) (*Train, error) {
+	t := &Train{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, fmt.Errorf("failed to read merge train: %w", err)
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("failed to parse merge train: %w", err)
+	}
+	return t, nil
+}
+
+// Save writes the train back to disk
+func (t *Train) Save() error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("failed to create merge train directory: %w", err)
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal merge train: %w", err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write merge train: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write merge train: %w", err)
+	}
+	return nil
+}
+
+// Passed returns the PRs in the train, in the order they should be merged
+func (t *Train) Passed() []Entry {
+	var passed []Entry
+	for _, e := range t.Entries {
+		if e.Status == StatusPassed {
+			passed = append(passed, e)
+		}
+	}
+	return passed
+}
+
+// Sync drops entries whose PR was merged, closed or pushed to since it was
+// tested. Passed entries that were tested on top of a PR that left the train
+// without being merged, or behind one that was merged out of order, no
+// longer match what will be merged; they are dropped too, to be tested
+// again, and returned.
+func (t *Train) Sync(prs []PR) (requeued []Entry) {
+	byNumber := make(map[int]PR, len(prs))
+	for _, pr := range prs {
+		byNumber[pr.Number] = pr
+	}
+
+	var kept []Entry
+	invalid := false
+	for _, e := range t.Entries {
+		pr, ok := byNumber[e.PR]
+		current := ok && pr.State == "open" && pr.Head == e.Head
+		if e.Status != StatusPassed {
+			if current {
+				kept = append(kept, e)
+			}
+			continue
+		}
+		switch {
+		case current && invalid:
+			requeued = append(requeued, e)
+		case current:
+			kept = append(kept, e)
+		case ok && pr.State == "merged":
+			// The train's PRs ahead of it never ran on top of it
+			var rest []Entry
+			for _, k := range kept {
+				if k.Status == StatusPassed {
+					requeued = append(requeued, k)
+				} else {
+					rest = append(rest, k)
+				}
+			}
+			kept = rest
+		default:
+			// Everything tested on top of this PR is stale
+			invalid = true
+		}
+	}
+	t.Entries = kept
+	return requeued
+}
+
+// Next returns the lowest-numbered open PR that hasn't been simulated at its
+// current head, or nil if there is none
+func (t *Train) Next(prs []PR) *PR {
+	simulated := make(map[int]bool, len(t.Entries))
+	for _, e := range t.Entries {
+		simulated[e.PR] = true
+	}
+	var next *PR
+	for i := range prs {
+		pr := &prs[i]
+		if pr.State != "open" || simulated[pr.Number] {
+			continue
+		}
+		if next == nil || pr.Number < next.Number {
+			next = pr
+		}
+	}
+	return next
+}
+
+// Record adds a simulated PR to the end of the train
+func (t *Train) Record(e Entry) {
+	for i, existing := range t.Entries {
+		if existing.PR == e.PR {
+			t.Entries = append(t.Entries[:i], t.Entries[i+1:]...)
+			break
+		}
+	}
+	t.Entries = append(t.Entries, e)
+}
+
+// Requeue drops a PR from the train, with the passed PRs after it, which were
+// tested on top of it, so they are tested again. It returns the dropped entries.
+func (t *Train) Requeue(pr int) []Entry {
+	var kept, requeued []Entry
+	found := false
+	for _, e := range t.Entries {
+		found = found || e.PR == pr
+		if found && e.Status == StatusPassed {
+			requeued = append(requeued, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	t.Entries = kept
+	return requeued
+}
+
+// Failure is a simulation that failed because of the PR: it didn't merge
+// cleanly or the tests failed. Other errors mean the simulation couldn't run.
+type Failure struct {
+	Reason string
+}
+
+func (f *Failure) Error() string {
+	return f.Reason
+}
+
+// IsFailure reports whether err is a Failure
+func IsFailure(err error) bool {
+	var f *Failure
+	return errors.As(err, &f)
+}
+
+// StaleError is a simulation that couldn't run because a PR in the train no
+// longer merges onto the base branch, which has moved since it was tested
+type StaleError struct {
+	PR int
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("PR #%d in the train no longer merges cleanly", e.PR)
+}
+
+// Simulation merges PRs onto a repository's default branch in a throwaway
+// worktree and runs the test command there
+type Simulation struct {
+	RepoDir string
+	Remote  string
+	Base    string
+	// Command is run with sh -c in the worktree
+	Command string
+	Timeout time.Duration
+	// Log receives the output of the merges and the test command
+	Log io.Writer
+}
+
+// Run merges the heads of the train's PRs and then the candidate onto the
+// remote's base branch and runs the test command. It returns a *Failure when
+// the candidate doesn't merge or the tests fail.
+func (s Simulation) Run(ctx context.Context, ahead []Entry, candidate PR) error {
+	refspecs := []string{fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", s.Base, s.Remote, s.Base)}
+	for _, branch := range append(branches(ahead), candidate.Branch) {
+		refspecs = append(refspecs, fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, s.Remote, branch))
+	}
+	if err := s.git(ctx, s.RepoDir, append([]string{"fetch", s.Remote}, refspecs...)...); err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "multiclaude-train-")
+	if err != nil {
+		return fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := s.git(ctx, s.RepoDir, "worktree", "add", "--detach", dir, s.Remote+"/"+s.Base); err != nil {
+		return fmt.Errorf("failed to create worktree: %w", err)
+	}
+	defer func() {
+		// A fresh context, so the worktree goes even when ctx is cancelled
+		s.git(context.Background(), s.RepoDir, "worktree", "remove", "--force", dir)
+		s.git(context.Background(), s.RepoDir, "worktree", "prune")
+	}()
+
+	for _, e := range ahead {
+		if err := s.merge(ctx, dir, e.Head, e.PR); err != nil {
+			return &StaleError{PR: e.PR}
+		}
+	}
+	if err := s.merge(ctx, dir, candidate.Head, candidate.Number); err != nil {
+		return &Failure{Reason: fmt.Sprintf("it does not merge cleanly onto %s", s.tip(ahead))}
+	}
+
+	fmt.Fprintf(s.Log, "$ %s\n", s.Command)
+	testCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(testCtx, "sh", "-c", s.Command)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = s.Log, s.Log
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if testCtx.Err() == context.DeadlineExceeded {
+			return &Failure{Reason: fmt.Sprintf("the tests timed out after %s on %s", s.Timeout, s.tip(ahead))}
+		}
+		return &Failure{Reason: fmt.Sprintf("the tests failed (%v) on %s", err, s.tip(ahead))}
+	}
+	return nil
+}
+
+// tip describes what a PR was merged onto
+func (s Simulation) tip(ahead []Entry) string {
+	tip := s.Remote + "/" + s.Base
+	if len(ahead) == 0 {
+		return tip
+	}
+	prs := make([]string, len(ahead))
+	for i, e := range ahead {
+		prs[i] = fmt.Sprintf("#%d", e.PR)
+	}
+	return tip + " + " + strings.Join(prs, ", ")
+}
+
+// merge merges a PR's head into the worktree, aborting on a conflict
+func (s Simulation) merge(ctx context.Context, dir, head string, number int) error {
+	err := s.git(ctx, dir, "-c", "user.name=multiclaude", "-c", "user.email=multiclaude@localhost",
+		"merge", "--no-ff", "--no-edit", "-m", fmt.Sprintf("Merge PR #%d", number), head)
+	if err != nil {
+		s.git(ctx, dir, "merge", "--abort")
+	}
+	return err
+}
+
+// git runs git in dir, logging the command and its output
+func (s Simulation) git(ctx context.Context, dir string, args ...string) error {
+	fmt.Fprintf(s.Log, "$ git %s\n", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = s.Log, s.Log
+	return cmd.Run()
+}
+
+// branches returns the branches of entries
+func branches(entries []Entry) []string {
+	list := make([]string, len(entries))
+	for i, e := range entries {
+		list[i] = e.Branch
+	}
+	return list
+}
+
+// Tail returns the last n lines of a log file
+func Tail(path string, n int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
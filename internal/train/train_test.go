@@ -0,0 +1,168 @@
+package train
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSync(t *testing.T) {
+	tr := &Train{Entries: []Entry{
+		{PR: 1, Head: "a1", Status: StatusPassed},
+		{PR: 2, Head: "b1", Status: StatusFailed},
+		{PR: 3, Head: "c1", Status: StatusPassed},
+		{PR: 4, Head: "d1", Status: StatusPassed},
+	}}
+
+	// #1 merged in order: the rest stay
+	requeued := tr.Sync([]PR{
+		{Number: 1, Head: "a1", State: "merged"},
+		{Number: 2, Head: "b1", State: "open"},
+		{Number: 3, Head: "c1", State: "open"},
+		{Number: 4, Head: "d1", State: "open"},
+	})
+	if len(requeued) != 0 || len(tr.Entries) != 3 || tr.Entries[0].PR != 2 {
+		t.Fatalf("after an in-order merge: entries %+v, requeued %+v", tr.Entries, requeued)
+	}
+
+	// #3 was pushed to: #4, tested on top of it, goes back in the queue.
+	// #2's failure stands until its own head changes.
+	requeued = tr.Sync([]PR{
+		{Number: 2, Head: "b1", State: "open"},
+		{Number: 3, Head: "c2", State: "open"},
+		{Number: 4, Head: "d1", State: "open"},
+	})
+	if len(requeued) != 1 || requeued[0].PR != 4 || len(tr.Entries) != 1 || tr.Entries[0].PR != 2 {
+		t.Fatalf("after a push: entries %+v, requeued %+v", tr.Entries, requeued)
+	}
+
+	// Merging out of order invalidates the PRs ahead of the merged one
+	tr = &Train{Entries: []Entry{
+		{PR: 5, Head: "e1", Status: StatusPassed},
+		{PR: 6, Head: "f1", Status: StatusPassed},
+	}}
+	requeued = tr.Sync([]PR{
+		{Number: 5, Head: "e1", State: "open"},
+		{Number: 6, Head: "f1", State: "merged"},
+	})
+	if len(requeued) != 1 || requeued[0].PR != 5 || len(tr.Entries) != 0 {
+		t.Fatalf("after an out-of-order merge: entries %+v, requeued %+v", tr.Entries, requeued)
+	}
+
+	tr = &Train{Entries: []Entry{
+		{PR: 7, Head: "g1", Status: StatusPassed},
+		{PR: 8, Head: "h1", Status: StatusPassed},
+		{PR: 9, Head: "i1", Status: StatusFailed},
+	}}
+	if requeued := tr.Requeue(7); len(requeued) != 2 || len(tr.Entries) != 1 || tr.Entries[0].PR != 9 {
+		t.Errorf("Requeue() = %+v, entries %+v", requeued, tr.Entries)
+	}
+}
+
+func TestNextAndRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trains", "repo.json")
+	tr, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() on a missing file error: %v", err)
+	}
+	prs := []PR{
+		{Number: 12, Head: "b", State: "open"},
+		{Number: 10, Head: "a", State: "open"},
+		{Number: 9, Head: "z", State: "closed"},
+	}
+	if next := tr.Next(prs); next == nil || next.Number != 10 {
+		t.Fatalf("Next() = %+v, want the oldest open PR", next)
+	}
+	tr.Record(Entry{PR: 10, Head: "a", Status: StatusPassed, TestedAt: time.Now()})
+	if err := tr.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if tr, err = Load(path); err != nil || len(tr.Passed()) != 1 {
+		t.Fatalf("Load() = %+v, %v", tr, err)
+	}
+	if next := tr.Next(prs); next == nil || next.Number != 12 {
+		t.Errorf("Next() = %+v, want the PR not yet simulated", next)
+	}
+	tr.Record(Entry{PR: 10, Head: "a2", Status: StatusFailed})
+	if len(tr.Entries) != 1 || tr.Entries[0].Status != StatusFailed {
+		t.Errorf("Record() of a simulated PR = %+v, want it replaced", tr.Entries)
+	}
+}
+
+// git runs git in dir, failing the test on error
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// pushBranch commits a file on a new branch off main and pushes it, returning its head
+func pushBranch(t *testing.T, work, branch, file, content string) string {
+	t.Helper()
+	git(t, work, "checkout", "-q", "-b", branch, "main")
+	if err := os.WriteFile(filepath.Join(work, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "add", file)
+	git(t, work, "commit", "-q", "-m", branch)
+	git(t, work, "push", "-q", "origin", branch)
+	return git(t, work, "rev-parse", "HEAD")
+}
+
+func TestSimulationRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	work := filepath.Join(root, "work")
+	clone := filepath.Join(root, "clone")
+	git(t, root, "init", "-q", "--bare", "-b", "main", origin)
+	git(t, root, "clone", "-q", origin, work)
+	git(t, work, "checkout", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(work, "value"), []byte("1\n"), 0644)
+	git(t, work, "add", "value")
+	git(t, work, "commit", "-q", "-m", "initial")
+	git(t, work, "push", "-q", "origin", "main")
+	git(t, root, "clone", "-q", origin, clone)
+
+	// #1 adds a file the tests need; #2 changes the value #3 changes too
+	head1 := pushBranch(t, work, "work/one", "ready", "yes\n")
+	head2 := pushBranch(t, work, "work/two", "value", "2\n")
+	head3 := pushBranch(t, work, "work/three", "value", "3\n")
+
+	var log bytes.Buffer
+	sim := Simulation{RepoDir: clone, Remote: "origin", Base: "main", Command: "test -f ready", Timeout: time.Minute, Log: &log}
+	ctx := context.Background()
+
+	err := sim.Run(ctx, nil, PR{Number: 2, Branch: "work/two", Head: head2})
+	if !IsFailure(err) || !strings.Contains(err.Error(), "tests failed") {
+		t.Errorf("Run(#2 alone) = %v, want a test failure", err)
+	}
+	ahead := []Entry{{PR: 1, Branch: "work/one", Head: head1, Status: StatusPassed}}
+	if err := sim.Run(ctx, ahead, PR{Number: 2, Branch: "work/two", Head: head2}); err != nil {
+		t.Errorf("Run(#2 after #1) = %v\n%s", err, log.String())
+	}
+
+	ahead = append(ahead, Entry{PR: 2, Branch: "work/two", Head: head2, Status: StatusPassed})
+	err = sim.Run(ctx, ahead, PR{Number: 3, Branch: "work/three", Head: head3})
+	if !IsFailure(err) || !strings.Contains(err.Error(), "does not merge cleanly onto origin/main + #1, #2") {
+		t.Errorf("Run(#3 after #1, #2) = %v, want a merge conflict", err)
+	}
+
+	// The throwaway worktrees are gone
+	if list := git(t, clone, "worktree", "list"); strings.Count(list, "\n") != 0 {
+		t.Errorf("worktrees left behind:\n%s", list)
+	}
+}
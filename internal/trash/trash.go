@@ -0,0 +1,220 @@
+// Package trash keeps removed workers restorable for a while. Removing a
+// worker leaves its branch in place and writes a tombstone recording the
+// worker's state, the commit its branch pointed to, its uncommitted changes,
+// and its final diff and messages. A worker can be restored from its
+// tombstone until the tombstone expires.
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/export"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+	"github.com/micheal-at/multiclaude/pkg/retry"
+)
+
+// DefaultRetention is how long tombstones are kept
+const DefaultRetention = 7 * 24 * time.Hour
+
+// Tombstone is what is kept of a removed worker
+type Tombstone struct {
+	Repo   string      `json:"repo"`
+	Name   string      `json:"name"`
+	Agent  state.Agent `json:"agent"`
+	Branch string      `json:"branch"`
+	// Head is the commit the branch pointed to, used if the branch is gone
+	Head string `json:"head"`
+	// Changes are the uncommitted changes, as a binary patch against Head
+	Changes string `json:"changes,omitempty"`
+	// Diff is everything the worker changed against the base branch, with
+	// credentials scrubbed
+	Diff      string           `json:"diff,omitempty"`
+	Messages  []export.Message `json:"messages,omitempty"`
+	DeletedAt time.Time        `json:"deleted_at"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// Capture records a worker before its worktree is removed. The worktree's
+// index is updated to include untracked files, so it must not be used
+// afterwards.
+func Capture(src export.Source, retention time.Duration, now time.Time) (*Tombstone, error) {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	wt := src.Agent.WorktreePath
+	git := func(args ...string) (string, error) {
+		args = append([]string{"-C", wt}, args...)
+		out, err := retry.Git.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+			return exec.CommandContext(ctx, "git", args...)
+		})
+		return string(out), err
+	}
+
+	head, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the worktree's HEAD: %w", err)
+	}
+	branch := src.Agent.Branch
+	if branch == "" {
+		if out, err := git("rev-parse", "--abbrev-ref", "HEAD"); err == nil && strings.TrimSpace(out) != "HEAD" {
+			branch = strings.TrimSpace(out)
+		}
+	}
+
+	// Record the final diff and messages before staging changes the status
+	e, err := export.Collect(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := git("add", "-A"); err != nil {
+		return nil, fmt.Errorf("failed to stage uncommitted changes: %w", err)
+	}
+	changes, err := git("diff", "--cached", "--binary", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to save uncommitted changes: %w", err)
+	}
+
+	return &Tombstone{
+		Repo:      src.Repo,
+		Name:      src.Name,
+		Agent:     src.Agent,
+		Branch:    branch,
+		Head:      strings.TrimSpace(head),
+		Changes:   changes,
+		Diff:      e.Diff,
+		Messages:  e.Messages,
+		DeletedAt: now,
+		ExpiresAt: now.Add(retention),
+	}, nil
+}
+
+// Restore re-applies the worker's uncommitted changes to a worktree checked
+// out at its branch
+func (t *Tombstone) Restore(worktreePath string) error {
+	if t.Changes == "" {
+		return nil
+	}
+	out, err := retry.Git.CombinedOutput(context.Background(), func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "git", "-C", worktreePath, "apply", "--whitespace=nowarn", "-")
+		cmd.Stdin = strings.NewReader(t.Changes)
+		return cmd
+	})
+	if err != nil {
+		return fmt.Errorf("failed to re-apply uncommitted changes: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// path returns the file a worker's tombstone is kept in
+func path(paths *config.Paths, repoName, name string) string {
+	return filepath.Join(paths.RepoTrashDir(repoName), name+".json")
+}
+
+// Save writes a tombstone, replacing any earlier one for the same worker
+func Save(paths *config.Paths, t *Tombstone) error {
+	dir := paths.RepoTrashDir(t.Repo)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tombstone: %w", err)
+	}
+	tmp := path(paths, t.Repo, t.Name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tombstone: %w", err)
+	}
+	return os.Rename(tmp, path(paths, t.Repo, t.Name))
+}
+
+// Load reads a worker's tombstone. The error wraps os.ErrNotExist if the
+// worker isn't in the trash.
+func Load(paths *config.Paths, repoName, name string) (*Tombstone, error) {
+	data, err := os.ReadFile(path(paths, repoName, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("worker '%s' is not in the trash of %s: %w", name, repoName, os.ErrNotExist)
+		}
+		return nil, err
+	}
+	var t Tombstone
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse tombstone of %s: %w", name, err)
+	}
+	return &t, nil
+}
+
+// List returns a repository's tombstones, most recently removed first
+func List(paths *config.Paths, repoName string) ([]*Tombstone, error) {
+	entries, err := os.ReadDir(paths.RepoTrashDir(repoName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var tombstones []*Tombstone
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		t, err := Load(paths, repoName, name)
+		if err != nil {
+			continue
+		}
+		tombstones = append(tombstones, t)
+	}
+	sort.Slice(tombstones, func(i, j int) bool { return tombstones[i].DeletedAt.After(tombstones[j].DeletedAt) })
+	return tombstones, nil
+}
+
+// Remove deletes a worker's tombstone
+func Remove(paths *config.Paths, repoName, name string) error {
+	if err := os.Remove(path(paths, repoName, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Expire deletes the tombstones, of every repository, that expired before now
+// and returns them. Their branches are left for 'multiclaude cleanup'.
+func Expire(paths *config.Paths, now time.Time) ([]*Tombstone, error) {
+	repos, err := os.ReadDir(paths.TrashDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var expired []*Tombstone
+	for _, repo := range repos {
+		if !repo.IsDir() {
+			continue
+		}
+		tombstones, err := List(paths, repo.Name())
+		if err != nil {
+			return expired, err
+		}
+		for _, t := range tombstones {
+			if !t.ExpiresAt.Before(now) {
+				continue
+			}
+			if err := Remove(paths, t.Repo, t.Name); err != nil {
+				return expired, fmt.Errorf("failed to delete tombstone of %s/%s: %w", t.Repo, t.Name, err)
+			}
+			expired = append(expired, t)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,125 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/export"
+	"github.com/micheal-at/multiclaude/internal/messages"
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.email=t@example.com", "-c", "user.name=t"}, args...)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// setupWorker creates a worktree on work/calm-owl with one commit, a modified
+// file and an untracked file, and a message the worker sent
+func setupWorker(t *testing.T) export.Source {
+	t.Helper()
+	tmp := t.TempDir()
+	wt := filepath.Join(tmp, "wt")
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", wt).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(wt, "a.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, wt, "add", "a.txt")
+	git(t, wt, "commit", "-q", "-m", "init")
+	git(t, wt, "checkout", "-q", "-b", "work/calm-owl")
+	if err := os.WriteFile(filepath.Join(wt, "a.txt"), []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := messages.NewManager(filepath.Join(tmp, "messages"))
+	if _, err := mgr.Send("app", "calm-owl", "supervisor", "Halfway there"); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := state.Agent{Type: state.AgentTypeWorker, WorktreePath: wt, Task: "Fix the flaky test", Branch: "work/calm-owl"}
+	return export.Source{Repo: "app", Name: "calm-owl", Agent: agent, BaseRef: "main", Messages: mgr}
+}
+
+func TestCaptureAndRestore(t *testing.T) {
+	src := setupWorker(t)
+	wt := src.Agent.WorktreePath
+	now := time.Now()
+
+	tomb, err := Capture(src, 0, now)
+	if err != nil {
+		t.Fatalf("Capture() failed: %v", err)
+	}
+	if tomb.Branch != "work/calm-owl" || tomb.Head == "" || !tomb.ExpiresAt.Equal(now.Add(DefaultRetention)) {
+		t.Errorf("tombstone = branch %q, head %q, expires %v", tomb.Branch, tomb.Head, tomb.ExpiresAt)
+	}
+	if len(tomb.Messages) != 1 || tomb.Messages[0].Body != "Halfway there" {
+		t.Errorf("Messages = %+v, want the message the worker sent", tomb.Messages)
+	}
+
+	// A fresh checkout of the branch gets the uncommitted work back
+	git(t, wt, "reset", "-q", "--hard")
+	git(t, wt, "clean", "-q", "-fd")
+	if err := tomb.Restore(wt); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	for file, want := range map[string]string{"a.txt": "two\n", "new.txt": "new\n"} {
+		got, err := os.ReadFile(filepath.Join(wt, file))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", file, got, err, want)
+		}
+	}
+}
+
+func TestSaveLoadExpire(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	now := time.Now()
+
+	if _, err := Load(paths, "app", "calm-owl"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load(missing) = %v, want a not-exist error", err)
+	}
+
+	old := &Tombstone{Repo: "app", Name: "old-fox", DeletedAt: now.Add(-8 * 24 * time.Hour), ExpiresAt: now.Add(-24 * time.Hour)}
+	recent := &Tombstone{Repo: "app", Name: "calm-owl", Branch: "work/calm-owl", Changes: "patch", DeletedAt: now, ExpiresAt: now.Add(DefaultRetention)}
+	for _, tomb := range []*Tombstone{old, recent} {
+		if err := Save(paths, tomb); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	loaded, err := Load(paths, "app", "calm-owl")
+	if err != nil || loaded.Branch != "work/calm-owl" || loaded.Changes != "patch" {
+		t.Fatalf("Load() = %+v, %v", loaded, err)
+	}
+	list, err := List(paths, "app")
+	if err != nil || len(list) != 2 || list[0].Name != "calm-owl" {
+		t.Fatalf("List() = %v, %v; want newest first", list, err)
+	}
+
+	expired, err := Expire(paths, now)
+	if err != nil || len(expired) != 1 || expired[0].Name != "old-fox" {
+		t.Fatalf("Expire() = %v, %v; want old-fox", expired, err)
+	}
+	if list, _ := List(paths, "app"); len(list) != 1 {
+		t.Errorf("%d tombstones left, want 1", len(list))
+	}
+
+	if err := Remove(paths, "app", "calm-owl"); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if err := Remove(paths, "app", "calm-owl"); err != nil {
+		t.Errorf("removing a missing tombstone failed: %v", err)
+	}
+}
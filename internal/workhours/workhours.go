@@ -0,0 +1,240 @@
+// Package workhours decides whether a repository is inside its work hours,
+// the window in which the daemon keeps its agents busy. Outside it the
+// daemon holds back nudges, queued tasks and new workers, for cost control
+// and to stay clear of rate limits overnight, and picks up again on its own
+// when the window opens.
+package workhours
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// Days are the day names work hours use, in time.Weekday order
+var Days = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Overrides
+const (
+	On  = "on"
+	Off = "off"
+)
+
+// ParseHours parses a window such as "07:00-22:00". The end may be before
+// the start for a window that runs past midnight.
+func ParseHours(spec string) (start, end string, err error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return "", "", fmt.Errorf("invalid work hours %q: use HH:MM-HH:MM, e.g. 07:00-22:00", spec)
+	}
+	start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+	for _, t := range []string{start, end} {
+		if _, err := clock(t); err != nil {
+			return "", "", err
+		}
+	}
+	if start == end {
+		return "", "", fmt.Errorf("invalid work hours %q: start and end are the same", spec)
+	}
+	return start, end, nil
+}
+
+// ParseDays parses working days such as "mon-fri", "mon,wed,fri" or
+// "sat-sun". Ranges may wrap around the week, e.g. "fri-mon".
+func ParseDays(spec string) ([]string, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := day(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = day(to); err != nil {
+				return nil, err
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			seen[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	var days []string
+	for d := range Days {
+		if seen[d] {
+			days = append(days, Days[d])
+		}
+	}
+	return days, nil
+}
+
+// Validate checks work hours as stored in state
+func Validate(h state.WorkHours) error {
+	if (h.Start == "") != (h.End == "") {
+		return fmt.Errorf("work hours need both a start and an end")
+	}
+	if h.Start != "" {
+		if _, _, err := ParseHours(h.Start + "-" + h.End); err != nil {
+			return err
+		}
+	}
+	for _, d := range h.Days {
+		if _, err := day(d); err != nil {
+			return err
+		}
+	}
+	if _, err := location(h.Timezone); err != nil {
+		return err
+	}
+	if h.Override != "" && h.Override != On && h.Override != Off {
+		return fmt.Errorf("invalid work hours override %q: must be %q or %q", h.Override, On, Off)
+	}
+	return nil
+}
+
+// Status reports whether a repository's agents should be working at now,
+// and when that next changes (zero if it doesn't). An override that hasn't
+// expired wins over the hours.
+func Status(h state.WorkHours, now time.Time) (working bool, until time.Time) {
+	if overridden(h, now) {
+		return h.Override == On, h.OverrideUntil
+	}
+	return Scheduled(h, now)
+}
+
+// Scheduled is Status without the override: what the hours alone say
+func Scheduled(h state.WorkHours, now time.Time) (working bool, until time.Time) {
+	if h.Start == "" {
+		return true, time.Time{}
+	}
+	working = inWindow(h, now)
+	for _, boundary := range boundaries(h, now) {
+		if boundary.After(now) && inWindow(h, boundary) != working {
+			return working, boundary
+		}
+	}
+	return working, time.Time{}
+}
+
+// OverrideUntil returns when an override set at now should end by default:
+// when the hours would have switched to it anyway, or never if they won't
+func OverrideUntil(h state.WorkHours, now time.Time) time.Time {
+	_, until := Scheduled(h, now)
+	return until
+}
+
+// Describe renders the hours for display, e.g. "07:00-22:00 mon-fri (Europe/Berlin)"
+func Describe(h state.WorkHours) string {
+	if h.Start == "" {
+		return "always"
+	}
+	desc := h.Start + "-" + h.End
+	if len(h.Days) > 0 && len(h.Days) < len(Days) {
+		desc += " " + strings.Join(h.Days, ",")
+	}
+	if h.Timezone != "" {
+		desc += " (" + h.Timezone + ")"
+	}
+	return desc
+}
+
+// overridden reports whether the override is in effect at now
+func overridden(h state.WorkHours, now time.Time) bool {
+	return h.Override != "" && (h.OverrideUntil.IsZero() || now.Before(h.OverrideUntil))
+}
+
+// inWindow reports whether t falls in a working window. A window belongs to
+// the day it starts on, so one running past midnight carries on into the
+// next day even if that isn't a working day.
+func inWindow(h state.WorkHours, t time.Time) bool {
+	loc, err := location(h.Timezone)
+	if err != nil {
+		return true // Validate rejects bad zones; don't stop work over one
+	}
+	t = t.In(loc)
+	start, _ := clock(h.Start)
+	end, _ := clock(h.End)
+	minute := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return minute >= start && minute < end && workDay(h, t.Weekday())
+	}
+	// Past midnight: in the evening part of today's window, or the morning
+	// part of yesterday's
+	return (minute >= start && workDay(h, t.Weekday())) ||
+		(minute < end && workDay(h, (t.Weekday()+6)%7))
+}
+
+// boundaries returns the starts and ends of windows over the week from now,
+// in order, as candidates for the next change
+func boundaries(h state.WorkHours, now time.Time) []time.Time {
+	loc, err := location(h.Timezone)
+	if err != nil {
+		return nil
+	}
+	now = now.In(loc)
+	start, _ := clock(h.Start)
+	end, _ := clock(h.End)
+	var times []time.Time
+	first, second := start, end
+	if first > second {
+		first, second = second, first
+	}
+	for offset := 0; offset <= 8; offset++ {
+		for _, minute := range []int{first, second} {
+			times = append(times, time.Date(now.Year(), now.Month(), now.Day()+offset, minute/60, minute%60, 0, 0, loc))
+		}
+	}
+	return times
+}
+
+// workDay reports whether a window may start on d
+func workDay(h state.WorkHours, d time.Weekday) bool {
+	if len(h.Days) == 0 {
+		return true
+	}
+	for _, name := range h.Days {
+		if name == Days[d] {
+			return true
+		}
+	}
+	return false
+}
+
+// clock parses HH:MM into minutes after midnight
+func clock(t string) (int, error) {
+	parsed, err := time.Parse("15:04", t)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: use HH:MM on a 24-hour clock", t)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// day parses a day name into its index in Days
+func day(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, d := range Days {
+		if name == d {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q: use %s", name, strings.Join(Days, ", "))
+}
+
+// location loads a timezone, the daemon's local one if name is empty
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: use an IANA name such as Europe/Berlin", name)
+	}
+	return loc, nil
+}
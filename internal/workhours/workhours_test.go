@@ -0,0 +1,153 @@
+package workhours
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+// at returns a time in UTC on the week of Monday 2026-03-02
+func at(weekday time.Weekday, clock string) time.Time {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		panic(err)
+	}
+	return time.Date(2026, 3, 1+int(weekday), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func TestParseHours(t *testing.T) {
+	if start, end, err := ParseHours(" 22:00 - 07:00 "); err != nil || start != "22:00" || end != "07:00" {
+		t.Errorf("ParseHours() = %q, %q, %v", start, end, err)
+	}
+	for _, spec := range []string{"9-17", "07:00", "07:00-24:00", "08:00-08:00"} {
+		if _, _, err := ParseHours(spec); err == nil {
+			t.Errorf("ParseHours(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"mon-fri", "mon,tue,wed,thu,fri"},
+		{"Sat, sun", "sun,sat"},
+		{"fri-mon", "sun,mon,fri,sat"},
+		{"wed", "wed"},
+	}
+	for _, tt := range tests {
+		days, err := ParseDays(tt.spec)
+		if err != nil || strings.Join(days, ",") != tt.want {
+			t.Errorf("ParseDays(%q) = %v, %v, want %s", tt.spec, days, err, tt.want)
+		}
+	}
+	if _, err := ParseDays("monday"); err == nil {
+		t.Error("ParseDays(\"monday\") succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := state.WorkHours{Start: "07:00", End: "22:00", Days: []string{"mon"}, Timezone: "UTC", Override: On}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Validate(%+v) = %v", valid, err)
+	}
+	for _, h := range []state.WorkHours{
+		{Start: "07:00"},
+		{Start: "07:00", End: "7pm"},
+		{Start: "07:00", End: "22:00", Days: []string{"someday"}},
+		{Start: "07:00", End: "22:00", Timezone: "Mars/Olympus"},
+		{Override: "maybe"},
+	} {
+		if err := Validate(h); err == nil {
+			t.Errorf("Validate(%+v) succeeded", h)
+		}
+	}
+}
+
+func TestScheduled(t *testing.T) {
+	weekdays := state.WorkHours{Start: "07:00", End: "22:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Timezone: "UTC"}
+	overnight := state.WorkHours{Start: "22:00", End: "06:00", Days: []string{"fri"}, Timezone: "UTC"}
+
+	tests := []struct {
+		name    string
+		hours   state.WorkHours
+		now     time.Time
+		working bool
+		until   time.Time
+	}{
+		{"always", state.WorkHours{}, at(time.Sunday, "03:00"), true, time.Time{}},
+		{"inside", weekdays, at(time.Monday, "12:00"), true, at(time.Monday, "22:00")},
+		{"before start", weekdays, at(time.Tuesday, "06:59"), false, at(time.Tuesday, "07:00")},
+		{"friday night waits for monday", weekdays, at(time.Friday, "22:00"), false, at(time.Monday, "07:00").AddDate(0, 0, 7)},
+		{"weekend", weekdays, at(time.Saturday, "12:00"), false, at(time.Monday, "07:00").AddDate(0, 0, 7)},
+		{"overnight evening", overnight, at(time.Friday, "23:00"), true, at(time.Saturday, "06:00")},
+		{"overnight runs into saturday", overnight, at(time.Saturday, "05:00"), true, at(time.Saturday, "06:00")},
+		{"overnight not from thursday", overnight, at(time.Friday, "05:00"), false, at(time.Friday, "22:00")},
+	}
+	for _, tt := range tests {
+		working, until := Scheduled(tt.hours, tt.now)
+		if working != tt.working || !until.Equal(tt.until) {
+			t.Errorf("%s: Scheduled() = %v until %v, want %v until %v", tt.name, working, until, tt.working, tt.until)
+		}
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	hours := state.WorkHours{Start: "09:00", End: "17:00", Timezone: "Asia/Tokyo"}
+	// 01:00 UTC is 10:00 in Tokyo
+	if working, _ := Status(hours, at(time.Wednesday, "01:00")); !working {
+		t.Error("01:00 UTC isn't inside 09:00-17:00 Tokyo time")
+	}
+	if working, _ := Status(hours, at(time.Wednesday, "12:00")); working {
+		t.Error("12:00 UTC is inside 09:00-17:00 Tokyo time")
+	}
+}
+
+func TestOverride(t *testing.T) {
+	hours := state.WorkHours{Start: "07:00", End: "22:00", Timezone: "UTC"}
+	now := at(time.Wednesday, "23:00")
+
+	// By default an override lasts until the hours would have switched anyway
+	hours.Override = On
+	hours.OverrideUntil = OverrideUntil(hours, now)
+	if !hours.OverrideUntil.Equal(at(time.Thursday, "07:00")) {
+		t.Errorf("OverrideUntil() = %v, want the next start", hours.OverrideUntil)
+	}
+	if working, until := Status(hours, now); !working || !until.Equal(hours.OverrideUntil) {
+		t.Errorf("Status() with override = %v until %v, want working", working, until)
+	}
+
+	// Once it expires the hours apply again
+	if working, _ := Status(hours, at(time.Thursday, "12:00")); !working {
+		t.Error("Status() after the override ended isn't following the hours")
+	}
+	hours.Override = Off
+	if working, _ := Status(hours, at(time.Thursday, "12:00")); !working {
+		t.Error("Status() honors an expired off override")
+	}
+
+	// An override without an end lasts until it is cleared
+	hours.OverrideUntil = time.Time{}
+	if working, until := Status(hours, at(time.Thursday, "12:00")); working || !until.IsZero() {
+		t.Errorf("Status() with an open-ended off override = %v until %v", working, until)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		hours state.WorkHours
+		want  string
+	}{
+		{state.WorkHours{}, "always"},
+		{state.WorkHours{Start: "07:00", End: "22:00"}, "07:00-22:00"},
+		{state.WorkHours{Start: "07:00", End: "22:00", Days: []string{"mon", "tue"}, Timezone: "Europe/Berlin"}, "07:00-22:00 mon,tue (Europe/Berlin)"},
+	}
+	for _, tt := range tests {
+		if got := Describe(tt.hours); got != tt.want {
+			t.Errorf("Describe(%+v) = %q, want %q", tt.hours, got, tt.want)
+		}
+	}
+}
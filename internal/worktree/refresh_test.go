@@ -1,11 +1,15 @@
 package worktree
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // createTestRepoWithRemote creates a test repo with an origin remote
@@ -180,7 +184,7 @@ func TestRefreshWorktree_DetachedHead(t *testing.T) {
 	}
 
 	// RefreshWorktree should skip detached HEAD
-	result := RefreshWorktree(wtPath, "origin", "main")
+	result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 	if !result.Skipped {
 		t.Error("Expected RefreshWorktree to skip detached HEAD")
 	}
@@ -194,7 +198,7 @@ func TestRefreshWorktree_OnMainBranch(t *testing.T) {
 	defer cleanup()
 
 	// RefreshWorktree should skip if on main branch
-	result := RefreshWorktree(repoPath, "origin", "main")
+	result := RefreshWorktree(context.Background(), repoPath, "origin", "main")
 	if !result.Skipped {
 		t.Error("Expected RefreshWorktree to skip main branch")
 	}
@@ -227,7 +231,7 @@ func TestRefreshWorktree_MidRebase(t *testing.T) {
 	}
 
 	// RefreshWorktree should skip mid-rebase
-	result := RefreshWorktree(wtPath, "origin", "main")
+	result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 	if !result.Skipped {
 		t.Error("Expected RefreshWorktree to skip mid-rebase state")
 	}
@@ -260,7 +264,7 @@ func TestRefreshWorktree_MidMerge(t *testing.T) {
 	}
 
 	// RefreshWorktree should skip mid-merge
-	result := RefreshWorktree(wtPath, "origin", "main")
+	result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 	if !result.Skipped {
 		t.Error("Expected RefreshWorktree to skip mid-merge state")
 	}
@@ -271,7 +275,7 @@ func TestRefreshWorktree_MidMerge(t *testing.T) {
 
 func TestRefreshWorktree_NonExistentPath(t *testing.T) {
 	// RefreshWorktree should return error for non-existent path
-	result := RefreshWorktree("/nonexistent/path", "origin", "main")
+	result := RefreshWorktree(context.Background(), "/nonexistent/path", "origin", "main")
 	if result.Error == nil {
 		t.Error("Expected error for non-existent path")
 	}
@@ -290,7 +294,7 @@ func TestGetWorktreeState_UpToDate(t *testing.T) {
 	}
 
 	// Get worktree state
-	state, err := GetWorktreeState(wtPath, "origin", "main")
+	state, err := GetWorktreeState(context.Background(), wtPath, "origin", "main")
 	if err != nil {
 		t.Fatalf("GetWorktreeState() failed: %v", err)
 	}
@@ -324,7 +328,7 @@ func TestGetWorktreeState_DetachedHead(t *testing.T) {
 	}
 
 	// GetWorktreeState should indicate can't refresh
-	state, err := GetWorktreeState(wtPath, "origin", "main")
+	state, err := GetWorktreeState(context.Background(), wtPath, "origin", "main")
 	if err != nil {
 		t.Fatalf("GetWorktreeState() failed: %v", err)
 	}
@@ -342,7 +346,7 @@ func TestGetWorktreeState_OnMainBranch(t *testing.T) {
 	defer cleanup()
 
 	// GetWorktreeState for main branch
-	state, err := GetWorktreeState(repoPath, "origin", "main")
+	state, err := GetWorktreeState(context.Background(), repoPath, "origin", "main")
 	if err != nil {
 		t.Fatalf("GetWorktreeState() failed: %v", err)
 	}
@@ -451,7 +455,7 @@ func TestIsBehindMain_UpToDate(t *testing.T) {
 	}
 
 	// Should not be behind initially
-	behind, count, err := IsBehindMain(wtPath, "origin", "main")
+	behind, count, err := IsBehindMain(context.Background(), wtPath, "origin", "main")
 	if err != nil {
 		t.Fatalf("IsBehindMain() failed: %v", err)
 	}
@@ -486,7 +490,7 @@ func TestIsBehindMain_ActuallyBehind(t *testing.T) {
 	}
 
 	// Now the worktree should be behind main
-	behind, count, err := IsBehindMain(wtPath, "origin", "main")
+	behind, count, err := IsBehindMain(context.Background(), wtPath, "origin", "main")
 	if err != nil {
 		t.Fatalf("IsBehindMain() failed: %v", err)
 	}
@@ -511,7 +515,7 @@ func TestRefreshWorktreeWithDefaults_NoRemote(t *testing.T) {
 	}
 
 	// RefreshWorktreeWithDefaults should fail without remote
-	result := manager.RefreshWorktreeWithDefaults(wtPath)
+	result := manager.RefreshWorktreeWithDefaults(context.Background(), wtPath)
 	if result.Error == nil {
 		t.Error("Expected error when no remote configured")
 	}
@@ -536,7 +540,7 @@ func TestRefreshWorktree_WithUncommittedChanges(t *testing.T) {
 	}
 
 	// RefreshWorktree should handle uncommitted changes (stash and restore)
-	result := RefreshWorktree(wtPath, "origin", "main")
+	result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 	// Since there's nothing new on main, this might skip or succeed
 	// The key is it shouldn't lose the uncommitted changes
 	if result.Error != nil && !strings.Contains(result.Error.Error(), "fetch") {
@@ -605,7 +609,7 @@ func TestGetWorktreeState_WithMidRebaseApply(t *testing.T) {
 	}
 
 	// GetWorktreeState should detect mid-rebase
-	state, err := GetWorktreeState(wtPath, "origin", "main")
+	state, err := GetWorktreeState(context.Background(), wtPath, "origin", "main")
 	if err != nil {
 		t.Fatalf("GetWorktreeState() failed: %v", err)
 	}
@@ -617,3 +621,155 @@ func TestGetWorktreeState_WithMidRebaseApply(t *testing.T) {
 		t.Errorf("Expected mid-rebase reason, got: %s", state.RefreshReason)
 	}
 }
+
+func TestRefreshWorktreeWithOptions_Merge(t *testing.T) {
+	repoPath, cleanup := createTestRepoWithRemote(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-merge")
+	if err := manager.CreateNewBranch(wtPath, "feature-branch", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "feature.txt"), []byte("feature\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exec.Command("git", "-C", wtPath, "add", "feature.txt").Run()
+	if err := exec.Command("git", "-C", wtPath, "commit", "-m", "Feature").Run(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	out, _ := exec.Command("git", "-C", wtPath, "rev-parse", "HEAD").Output()
+	featureCommit := strings.TrimSpace(string(out))
+	addCommitToRemote(t, repoPath, "remote-change")
+
+	result := RefreshWorktreeWithOptions(context.Background(), wtPath, "origin", "main", RefreshOptions{Merge: true})
+	if result.Error != nil || result.Skipped || !result.Merged {
+		t.Fatalf("RefreshWorktreeWithOptions(merge) = %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "remote-change.txt")); err != nil {
+		t.Error("the default branch's change wasn't merged in")
+	}
+	// The agent's own commit is kept as is, not rewritten
+	if err := exec.Command("git", "-C", wtPath, "merge-base", "--is-ancestor", featureCommit, "HEAD").Run(); err != nil {
+		t.Error("the branch's commit was rewritten by a merge refresh")
+	}
+}
+
+func TestRefreshWorktreeWithOptions_OnlyClean(t *testing.T) {
+	repoPath, cleanup := createTestRepoWithRemote(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-only-clean")
+	if err := manager.CreateNewBranch(wtPath, "feature-branch", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	addCommitToRemote(t, repoPath, "remote-change")
+
+	before := time.Now().Add(-time.Second)
+	if last, err := LastEdit(wtPath); err != nil || !last.IsZero() {
+		t.Errorf("LastEdit() of a clean worktree = %v, %v", last, err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "wip.txt"), []byte("half done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := LastEdit(wtPath); err != nil || last.Before(before) {
+		t.Errorf("LastEdit() = %v, %v, want the time wip.txt was written", last, err)
+	}
+
+	result := RefreshWorktreeWithOptions(context.Background(), wtPath, "origin", "main", RefreshOptions{OnlyClean: true})
+	if !result.Skipped || result.SkipReason != "uncommitted changes" || result.WasStashed {
+		t.Errorf("RefreshWorktreeWithOptions(only clean) = %+v, want skipped", result)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "remote-change.txt")); !os.IsNotExist(err) {
+		t.Error("a worktree with uncommitted changes was refreshed")
+	}
+}
+
+func TestRefreshWorktreeCancelled(t *testing.T) {
+	repoPath, cleanup := createTestRepoWithRemote(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-cancelled")
+	if err := manager.CreateNewBranch(wtPath, "feature-branch", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	addCommitToRemote(t, repoPath, "remote-change")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetWorktreeState(ctx, wtPath, "origin", "main"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetWorktreeState() with a cancelled context = %v, want context.Canceled", err)
+	}
+	result := RefreshWorktree(ctx, wtPath, "origin", "main")
+	if !errors.Is(result.Error, context.Canceled) {
+		t.Errorf("RefreshWorktree() with a cancelled context = %+v, want context.Canceled", result)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "remote-change.txt")); !os.IsNotExist(err) {
+		t.Error("a cancelled refresh changed the worktree")
+	}
+}
+
+func TestRefreshWorktreeWithOptions_ConcurrentStashes(t *testing.T) {
+	repoPath, cleanup := createTestRepoWithRemote(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	names := []string{"wt-one", "wt-two", "wt-three", "wt-four"}
+	for _, name := range names {
+		if err := manager.CreateNewBranch(filepath.Join(repoPath, name), name, "main"); err != nil {
+			t.Fatalf("Failed to create worktree: %v", err)
+		}
+		// Each worktree has its own edit to a tracked file and its own
+		// untracked file
+		if err := os.WriteFile(filepath.Join(repoPath, name, "README.md"), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repoPath, name, name+".txt"), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addCommitToRemote(t, repoPath, "remote-change")
+	if err := manager.FetchRemote(context.Background(), "origin"); err != nil {
+		t.Fatalf("FetchRemote() failed: %v", err)
+	}
+
+	results := make([]RefreshResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = RefreshWorktreeWithOptions(context.Background(), filepath.Join(repoPath, name), "origin", "main", RefreshOptions{NoFetch: true})
+		}(i, name)
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		wtPath := filepath.Join(repoPath, name)
+		if results[i].Error != nil || !results[i].WasStashed || !results[i].StashRestored {
+			t.Errorf("refresh of %s = %+v", name, results[i])
+		}
+		if _, err := os.Stat(filepath.Join(wtPath, "remote-change.txt")); err != nil {
+			t.Errorf("%s wasn't rebased onto the fetched default branch", name)
+		}
+		readme, _ := os.ReadFile(filepath.Join(wtPath, "README.md"))
+		if string(readme) != "# "+name+"\n" {
+			t.Errorf("%s/README.md = %q, want its own change back", name, readme)
+		}
+		for _, other := range names {
+			_, err := os.Stat(filepath.Join(wtPath, other+".txt"))
+			if other == name && err != nil {
+				t.Errorf("%s lost its untracked file", name)
+			}
+			if other != name && err == nil {
+				t.Errorf("%s got %s's untracked file", name, other)
+			}
+		}
+	}
+	// Nothing is left behind on the shared stash stack
+	if out, _ := exec.Command("git", "-C", repoPath, "stash", "list").Output(); len(out) > 0 {
+		t.Errorf("stash list = %q, want empty", out)
+	}
+}
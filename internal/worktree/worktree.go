@@ -1,16 +1,61 @@
 package worktree
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/gitcmd"
 )
 
 // Manager handles git worktree operations
 type Manager struct {
-	repoPath string
+	repoPath      string
+	defaultBranch string
+	setup         Setup
+}
+
+// Setup configures extra steps run in each worktree the manager creates, for
+// repositories whose checkouts are incomplete without them
+type Setup struct {
+	// LFS downloads Git LFS objects (git lfs pull)
+	LFS bool
+	// Submodules checks out submodules (git submodule update --init --recursive)
+	Submodules bool
+	// Progress is called as each step starts and finishes; nil reports nothing
+	Progress func(msg string)
+}
+
+// SetupError is returned by Create and CreateNewBranch when the worktree was
+// created but a setup step failed. The worktree is left in place.
+type SetupError struct {
+	Path string
+	Step string
+	Err  error
+}
+
+func (e *SetupError) Error() string {
+	return fmt.Sprintf("worktree %s was created but %s failed: %v", e.Path, e.Step, e.Err)
+}
+
+func (e *SetupError) Unwrap() error {
+	return e.Err
+}
+
+// IsSetupError reports whether err means the worktree was created but its setup failed
+func IsSetupError(err error) bool {
+	var setupErr *SetupError
+	return errors.As(err, &setupErr)
 }
 
 // NewManager creates a new worktree manager for a repository
@@ -18,14 +63,30 @@ func NewManager(repoPath string) *Manager {
 	return &Manager{repoPath: repoPath}
 }
 
+// SetDefaultBranch makes GetDefaultBranch return branch instead of detecting it.
+// Use it with the branch recorded for the repository; an empty branch restores detection.
+func (m *Manager) SetDefaultBranch(branch string) {
+	m.defaultBranch = branch
+}
+
+// SetSetup makes Create and CreateNewBranch run setup steps in new worktrees
+func (m *Manager) SetSetup(setup Setup) {
+	m.setup = setup
+}
+
 // runGit runs a git command in the repository directory and returns output.
-// If the command fails, the error includes the command output for debugging.
+// Transient failures, such as another git process holding index.lock, are
+// retried. If the command fails, the error includes the command output for
+// debugging.
 func (m *Manager) runGit(args ...string) ([]byte, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = m.repoPath
-	output, err := cmd.CombinedOutput()
+	return m.runGitContext(context.Background(), args...)
+}
+
+// runGitContext is runGit, killing the command when ctx is done
+func (m *Manager) runGitContext(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := gitcmd.CombinedOutput(ctx, m.repoPath, args...)
 	if err != nil {
-		return output, fmt.Errorf("git %s: %w\nOutput: %s", args[0], err, output)
+		return output, fmt.Errorf("%w\nOutput: %s", err, output)
 	}
 	return output, nil
 }
@@ -48,16 +109,74 @@ func resolvePathWithSymlinks(path string) (string, error) {
 	return evalPath, nil
 }
 
-// Create creates a new git worktree
+// Create creates a new git worktree and runs the manager's setup steps in it
 func (m *Manager) Create(path, branch string) error {
-	_, err := m.runGit("worktree", "add", path, branch)
-	return err
+	if _, err := m.runGit("worktree", "add", path, branch); err != nil {
+		return err
+	}
+	return m.runSetup(path)
 }
 
-// CreateNewBranch creates a new worktree with a new branch
+// CreateNewBranch creates a new worktree with a new branch and runs the
+// manager's setup steps in it
 func (m *Manager) CreateNewBranch(path, newBranch, startPoint string) error {
-	_, err := m.runGit("worktree", "add", "-b", newBranch, path, startPoint)
-	return err
+	if _, err := m.runGit("worktree", "add", "-b", newBranch, path, startPoint); err != nil {
+		return err
+	}
+	return m.runSetup(path)
+}
+
+// CreateDetached creates a worktree with a detached HEAD at ref, for agents
+// that read the repository and never commit, and runs the manager's setup
+// steps in it
+func (m *Manager) CreateDetached(path, ref string) error {
+	if _, err := m.runGit("worktree", "add", "--detach", path, ref); err != nil {
+		return err
+	}
+	return m.runSetup(path)
+}
+
+// CheckoutDetached moves a detached worktree to ref. Anything that blocks the
+// checkout is reported, not discarded.
+func CheckoutDetached(ctx context.Context, path, ref string) error {
+	if output, err := gitcmd.CombinedOutput(ctx, path, "checkout", "--quiet", "--detach", ref); err != nil {
+		return fmt.Errorf("failed to check out %s: %w\nOutput: %s", ref, err, output)
+	}
+	return nil
+}
+
+// runSetup runs the configured setup steps in a new worktree
+func (m *Manager) runSetup(path string) error {
+	steps := []struct {
+		enabled bool
+		name    string
+		args    []string
+	}{
+		{m.setup.Submodules, "submodule update", []string{"submodule", "update", "--init", "--recursive"}},
+		{m.setup.LFS, "git lfs pull", []string{"lfs", "pull"}},
+	}
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		m.progress(fmt.Sprintf("Running %s in %s...", step.name, path))
+		start := time.Now()
+		if output, err := gitcmd.CombinedOutput(context.Background(), path, step.args...); err != nil {
+			if msg := strings.TrimSpace(string(output)); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			m.progress(fmt.Sprintf("%s failed: %v", step.name, err))
+			return &SetupError{Path: path, Step: step.name, Err: err}
+		}
+		m.progress(fmt.Sprintf("Finished %s (%s)", step.name, time.Since(start).Round(100*time.Millisecond)))
+	}
+	return nil
+}
+
+func (m *Manager) progress(msg string) {
+	if m.setup.Progress != nil {
+		m.setup.Progress(msg)
+	}
 }
 
 // Remove removes a git worktree
@@ -70,11 +189,92 @@ func (m *Manager) Remove(path string, force bool) error {
 	return err
 }
 
+// Move moves a worktree to newPath, which must not exist yet, and repairs
+// git's links to it. Unlike git worktree move it works across filesystems,
+// by copying the worktree and removing the original.
+func (m *Manager) Move(oldPath, newPath string) error {
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("%s already exists", newPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		if !errors.Is(err, syscall.EXDEV) {
+			return err
+		}
+		if err := copyTree(oldPath, newPath); err != nil {
+			os.RemoveAll(newPath)
+			return fmt.Errorf("failed to copy worktree: %w", err)
+		}
+		if err := os.RemoveAll(oldPath); err != nil {
+			return fmt.Errorf("copied worktree to %s but failed to remove the original: %w", newPath, err)
+		}
+	}
+
+	_, err := m.runGit("worktree", "repair", newPath)
+	return err
+}
+
+// copyTree copies a directory tree, keeping file modes, modification times
+// and symlinks
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chtimes(target, info.ModTime(), info.ModTime())
+		default:
+			// Sockets, pipes and devices don't belong in a worktree
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
 // List returns a list of all worktrees
 func (m *Manager) List() ([]WorktreeInfo, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = m.repoPath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), m.repoPath, "worktree", "list", "--porcelain")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
@@ -115,9 +315,11 @@ func (m *Manager) Prune() error {
 
 // HasUncommittedChanges checks if a worktree has uncommitted changes
 func HasUncommittedChanges(path string) (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = path
-	output, err := cmd.Output()
+	return hasUncommittedChanges(context.Background(), path)
+}
+
+func hasUncommittedChanges(ctx context.Context, path string) (bool, error) {
+	output, err := gitcmd.Output(ctx, path, "status", "--porcelain")
 	if err != nil {
 		return false, fmt.Errorf("failed to check git status: %w", err)
 	}
@@ -125,28 +327,48 @@ func HasUncommittedChanges(path string) (bool, error) {
 	return len(strings.TrimSpace(string(output))) > 0, nil
 }
 
+// LastEdit returns when the most recently modified file with uncommitted
+// changes in a worktree was written, or the zero time if there are none
+func LastEdit(path string) (time.Time, error) {
+	output, err := gitcmd.Output(context.Background(), path, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to check git status: %w", err)
+	}
+
+	var last time.Time
+	entries := strings.Split(string(output), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// Renames and copies are followed by the original path
+			i++
+		}
+		if info, err := os.Stat(filepath.Join(path, entry[3:])); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
 // HasUnpushedCommits checks if a worktree has unpushed commits
 func HasUnpushedCommits(path string) (bool, error) {
 	// First verify this is a valid git repository
-	verifyCmd := exec.Command("git", "rev-parse", "--git-dir")
-	verifyCmd.Dir = path
-	if err := verifyCmd.Run(); err != nil {
+	if _, err := gitcmd.Output(context.Background(), path, "rev-parse", "--git-dir"); err != nil {
 		return false, fmt.Errorf("not a git repository: %w", err)
 	}
 
 	// Check if there's a tracking branch
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
-	cmd.Dir = path
-	if err := cmd.Run(); err != nil {
+	if _, err := gitcmd.Output(context.Background(), path, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"); err != nil {
 		// No tracking branch, so no unpushed commits
 		// This is a valid state (branch has no upstream configured)
 		return false, nil
 	}
 
 	// Check for commits ahead of upstream
-	cmd = exec.Command("git", "rev-list", "--count", "@{u}..")
-	cmd.Dir = path
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), path, "rev-list", "--count", "@{u}..")
 	if err != nil {
 		return false, fmt.Errorf("failed to check unpushed commits: %w", err)
 	}
@@ -157,9 +379,7 @@ func HasUnpushedCommits(path string) (bool, error) {
 
 // GetCurrentBranch returns the current branch name for a worktree
 func GetCurrentBranch(path string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = path
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), path, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
@@ -172,6 +392,122 @@ type WorktreeInfo struct {
 	Path   string
 	Commit string
 	Branch string
+	// Status is the worktree's git status, filled in by ListWithStatus
+	Status *Status
+}
+
+// ListWithStatus returns all worktrees with their git status. Worktrees
+// whose status can't be read, such as ones whose directory is gone, have
+// no Status.
+func (m *Manager) ListWithStatus() ([]WorktreeInfo, error) {
+	worktrees, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range worktrees {
+		if status, err := m.Status(worktrees[i].Path); err == nil {
+			worktrees[i].Status = &status
+		}
+	}
+	return worktrees, nil
+}
+
+// Status is a worktree's git status: where it stands against its tracking
+// branch, what's changed in it and its last commit
+type Status struct {
+	Branch     string  `json:"branch"` // Checked-out branch, "HEAD" when detached
+	Detached   bool    `json:"detached,omitempty"`
+	Upstream   string  `json:"upstream,omitempty"` // Tracking branch, empty if there is none
+	Ahead      int     `json:"ahead"`              // Commits not on the tracking branch yet
+	Behind     int     `json:"behind"`             // Commits on the tracking branch not here yet
+	Staged     int     `json:"staged"`             // Files with staged changes
+	Unstaged   int     `json:"unstaged"`           // Tracked files with unstaged changes
+	Untracked  int     `json:"untracked"`          // Untracked files, not counting ignored ones
+	Conflicted int     `json:"conflicted"`         // Files with unresolved merge conflicts
+	LastCommit *Commit `json:"last_commit,omitempty"`
+}
+
+// Dirty reports whether the worktree has uncommitted changes
+func (s Status) Dirty() bool {
+	return s.Staged+s.Unstaged+s.Untracked+s.Conflicted > 0
+}
+
+// Commit is a commit's metadata
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Time    time.Time `json:"time"`
+	Subject string    `json:"subject"`
+}
+
+// Status returns the git status of the worktree at path. It takes two git
+// commands, so callers showing several worktrees should cache it.
+func (m *Manager) Status(path string) (Status, error) {
+	output, err := gitcmd.Output(context.Background(), path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to check git status: %w", err)
+	}
+	status := parseStatus(string(output))
+
+	// A branch without commits has no last commit
+	if output, err := gitcmd.Output(context.Background(), path, "log", "-1", "--format=%H%x00%an%x00%ct%x00%s"); err == nil {
+		status.LastCommit = parseLastCommit(string(output))
+	}
+	return status, nil
+}
+
+// parseStatus parses the output of `git status --porcelain=v2 --branch`
+func parseStatus(output string) Status {
+	var status Status
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "#":
+			switch {
+			case fields[1] == "branch.head" && len(fields) == 3:
+				status.Branch = fields[2]
+				if status.Branch == "(detached)" {
+					status.Branch, status.Detached = "HEAD", true
+				}
+			case fields[1] == "branch.upstream" && len(fields) == 3:
+				status.Upstream = fields[2]
+			case fields[1] == "branch.ab" && len(fields) == 4:
+				fmt.Sscanf(fields[2], "+%d", &status.Ahead)
+				fmt.Sscanf(fields[3], "-%d", &status.Behind)
+			}
+		case "1", "2":
+			// fields[1] is XY: the staged and unstaged state, "." if unchanged
+			if len(fields[1]) == 2 {
+				if fields[1][0] != '.' {
+					status.Staged++
+				}
+				if fields[1][1] != '.' {
+					status.Unstaged++
+				}
+			}
+		case "u":
+			status.Conflicted++
+		case "?":
+			status.Untracked++
+		}
+	}
+	return status
+}
+
+// parseLastCommit parses `git log -1 --format=%H%x00%an%x00%ct%x00%s` output
+func parseLastCommit(output string) *Commit {
+	parts := strings.SplitN(strings.TrimSuffix(output, "\n"), "\x00", 4)
+	if len(parts) != 4 {
+		return nil
+	}
+	commit := &Commit{Hash: parts[0], Author: parts[1], Subject: parts[3]}
+	if unix, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+		commit.Time = time.Unix(unix, 0)
+	}
+	return commit
 }
 
 // parseWorktreeList parses the output of `git worktree list --porcelain`
@@ -215,12 +551,11 @@ func parseWorktreeList(output string) []WorktreeInfo {
 
 // BranchExists checks if a branch exists in the repository
 func (m *Manager) BranchExists(branchName string) (bool, error) {
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
-	cmd.Dir = m.repoPath
-	err := cmd.Run()
+	_, err := gitcmd.Output(context.Background(), m.repoPath, "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
 	if err != nil {
 		// Exit code 1 means branch doesn't exist
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
 			return false, nil
 		}
 		return false, fmt.Errorf("failed to check branch existence: %w", err)
@@ -242,9 +577,7 @@ func (m *Manager) DeleteBranch(branchName string) error {
 
 // ListBranchesWithPrefix lists all branches that start with the given prefix
 func (m *Manager) ListBranchesWithPrefix(prefix string) ([]string, error) {
-	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads/"+prefix)
-	cmd.Dir = m.repoPath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), m.repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads/"+prefix)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list branches: %w", err)
 	}
@@ -374,28 +707,27 @@ To fix this, you can either:
 // It prefers "upstream" if it exists, otherwise falls back to "origin"
 func (m *Manager) GetUpstreamRemote() (string, error) {
 	// Check if "upstream" remote exists
-	cmd := exec.Command("git", "remote", "get-url", "upstream")
-	cmd.Dir = m.repoPath
-	if err := cmd.Run(); err == nil {
+	if _, err := gitcmd.Output(context.Background(), m.repoPath, "remote", "get-url", "upstream"); err == nil {
 		return "upstream", nil
 	}
 
 	// Fall back to "origin"
-	cmd = exec.Command("git", "remote", "get-url", "origin")
-	cmd.Dir = m.repoPath
-	if err := cmd.Run(); err == nil {
+	if _, err := gitcmd.Output(context.Background(), m.repoPath, "remote", "get-url", "origin"); err == nil {
 		return "origin", nil
 	}
 
 	return "", fmt.Errorf("no upstream or origin remote found")
 }
 
-// GetDefaultBranch returns the default branch name for a remote (e.g., "main" or "master")
+// GetDefaultBranch returns the default branch name for a remote (e.g., "main" or "master").
+// If a default branch was set with SetDefaultBranch, it is returned as is.
 func (m *Manager) GetDefaultBranch(remote string) (string, error) {
+	if m.defaultBranch != "" {
+		return m.defaultBranch, nil
+	}
+
 	// Try to get the default branch from the remote's HEAD
-	cmd := exec.Command("git", "symbolic-ref", fmt.Sprintf("refs/remotes/%s/HEAD", remote))
-	cmd.Dir = m.repoPath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), m.repoPath, "symbolic-ref", fmt.Sprintf("refs/remotes/%s/HEAD", remote))
 	if err == nil {
 		// Output is like "refs/remotes/origin/main" - extract the branch name
 		refPath := strings.TrimSpace(string(output))
@@ -407,9 +739,7 @@ func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 
 	// Fallback: check for common branch names
 	for _, branch := range []string{"main", "master"} {
-		cmd := exec.Command("git", "rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s", remote, branch))
-		cmd.Dir = m.repoPath
-		if err := cmd.Run(); err == nil {
+		if _, err := gitcmd.Output(context.Background(), m.repoPath, "rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s", remote, branch)); err == nil {
 			return branch, nil
 		}
 	}
@@ -417,9 +747,37 @@ func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 	return "", fmt.Errorf("could not determine default branch for remote %s", remote)
 }
 
-// FetchRemote fetches updates from a remote
-func (m *Manager) FetchRemote(remote string) error {
-	_, err := m.runGit("fetch", remote)
+// DetectRemoteDefaultBranch asks the remote which branch its HEAD points to.
+// Unlike GetDefaultBranch it works before the remote has been fetched, so it
+// is used when a repository is first cloned. Falls back to GetDefaultBranch
+// if the remote can't be reached.
+func (m *Manager) DetectRemoteDefaultBranch(remote string) (string, error) {
+	output, err := gitcmd.Output(context.Background(), m.repoPath, "ls-remote", "--symref", remote, "HEAD")
+	if err == nil {
+		if branch := parseSymrefHead(string(output)); branch != "" {
+			return branch, nil
+		}
+	}
+
+	return m.GetDefaultBranch(remote)
+}
+
+// parseSymrefHead extracts the branch from `git ls-remote --symref <remote> HEAD`
+// output, whose first line looks like "ref: refs/heads/main\tHEAD"
+func parseSymrefHead(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+			return strings.TrimPrefix(fields[1], "refs/heads/")
+		}
+	}
+	return ""
+}
+
+// FetchRemote fetches updates from a remote. The fetch is killed when ctx is
+// done.
+func (m *Manager) FetchRemote(ctx context.Context, remote string) error {
+	_, err := m.runGitContext(ctx, "fetch", remote)
 	return err
 }
 
@@ -428,6 +786,14 @@ func (m *Manager) FetchRemote(remote string) error {
 // The branchPrefix filters which branches to check (e.g., "multiclaude/" or "work/").
 // Returns a list of branch names that can be safely deleted.
 func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, error) {
+	return m.findMergedUpstreamBranches(func(branch string) bool {
+		return branchPrefix == "" || strings.HasPrefix(branch, branchPrefix)
+	})
+}
+
+// findMergedUpstreamBranches fetches the upstream remote once and lists the
+// local branches merged into its default branch for which include returns true
+func (m *Manager) findMergedUpstreamBranches(include func(string) bool) ([]string, error) {
 	// Get the upstream remote name
 	remote, err := m.GetUpstreamRemote()
 	if err != nil {
@@ -435,7 +801,7 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 	}
 
 	// Fetch from upstream to get the latest state
-	if err := m.FetchRemote(remote); err != nil {
+	if err := m.FetchRemote(context.Background(), remote); err != nil {
 		return nil, fmt.Errorf("failed to fetch from upstream: %w", err)
 	}
 
@@ -447,14 +813,12 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 
 	// Get branches merged into upstream's default branch
 	upstreamRef := fmt.Sprintf("%s/%s", remote, defaultBranch)
-	cmd := exec.Command("git", "branch", "--merged", upstreamRef, "--format=%(refname:short)")
-	cmd.Dir = m.repoPath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(context.Background(), m.repoPath, "branch", "--merged", upstreamRef, "--format=%(refname:short)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list merged branches: %w", err)
 	}
 
-	// Filter branches by prefix
+	// Filter branches
 	var mergedBranches []string
 	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
 		branch := strings.TrimSpace(line)
@@ -462,11 +826,10 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 			continue
 		}
 		// Skip the default branches themselves
-		if branch == "main" || branch == "master" {
+		if branch == defaultBranch || branch == "main" || branch == "master" {
 			continue
 		}
-		// Only include branches matching the prefix
-		if branchPrefix != "" && !strings.HasPrefix(branch, branchPrefix) {
+		if !include(branch) {
 			continue
 		}
 		mergedBranches = append(mergedBranches, branch)
@@ -485,14 +848,48 @@ func (m *Manager) DeleteRemoteBranch(remote, branchName string) error {
 // If deleteRemote is true, it also deletes the corresponding remote branches from origin.
 // Returns the list of deleted branch names.
 func (m *Manager) CleanupMergedBranches(branchPrefix string, deleteRemote bool) ([]string, error) {
-	// Find merged branches
-	mergedBranches, err := m.FindMergedUpstreamBranches(branchPrefix)
+	return m.CleanupMergedBranchesMatching(branchPrefix, nil, deleteRemote)
+}
+
+// CleanupMergedBranchesMatching is like CleanupMergedBranches but only deletes
+// branches for which match returns true. A nil match accepts every branch.
+func (m *Manager) CleanupMergedBranchesMatching(branchPrefix string, match func(string) bool, deleteRemote bool) ([]string, error) {
+	result, err := m.CleanupMergedBranchesFiltered(func(branch string) bool {
+		if branchPrefix != "" && !strings.HasPrefix(branch, branchPrefix) {
+			return false
+		}
+		return match == nil || match(branch)
+	}, deleteRemote)
 	if err != nil {
 		return nil, err
 	}
+	return result.Deleted, nil
+}
+
+// MergedCleanupResult reports what a merged-branch cleanup did
+type MergedCleanupResult struct {
+	Deleted       []string // Local branches deleted
+	RemoteDeleted []string // Deleted branches that were also removed from origin
+	CheckedOut    []string // Merged branches kept because a worktree has them checked out
+}
+
+// gitBatchSize bounds how many branches one git invocation deletes, keeping
+// command lines well under OS argument limits
+const gitBatchSize = 100
 
+// CleanupMergedBranchesFiltered deletes the local branches merged upstream for
+// which include returns true, using a constant number of git invocations: one
+// fetch, one merged-branch listing, and batched deletes. If deleteRemote is true,
+// deleted branches that exist on origin are removed there in one batched push.
+func (m *Manager) CleanupMergedBranchesFiltered(include func(string) bool, deleteRemote bool) (*MergedCleanupResult, error) {
+	result := &MergedCleanupResult{}
+
+	mergedBranches, err := m.findMergedUpstreamBranches(include)
+	if err != nil {
+		return nil, err
+	}
 	if len(mergedBranches) == 0 {
-		return nil, nil
+		return result, nil
 	}
 
 	// Get worktrees to avoid deleting branches that are still checked out
@@ -508,28 +905,96 @@ func (m *Manager) CleanupMergedBranches(branchPrefix string, deleteRemote bool)
 		}
 	}
 
-	var deleted []string
+	var candidates []string
 	for _, branch := range mergedBranches {
-		// Skip branches that are currently checked out in worktrees
 		if activeBranches[branch] {
+			result.CheckedOut = append(result.CheckedOut, branch)
 			continue
 		}
+		candidates = append(candidates, branch)
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
 
-		// Delete local branch
-		if err := m.DeleteBranch(branch); err != nil {
-			// Log but continue with other branches
-			continue
+	// Delete in batches. A failure on one branch doesn't stop git deleting the
+	// others, so what was deleted is read back from the refs afterwards.
+	for _, batch := range batches(candidates, gitBatchSize) {
+		_, _ = m.runGit(append([]string{"branch", "-D"}, batch...)...)
+	}
+	remaining, err := m.listRefs("refs/heads/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	for _, branch := range candidates {
+		if !remaining[branch] {
+			result.Deleted = append(result.Deleted, branch)
+		}
+	}
+
+	if !deleteRemote || len(result.Deleted) == 0 {
+		return result, nil
+	}
+
+	// Only push deletions for branches origin is known to have (the fork, in fork mode)
+	onOrigin, err := m.listRefs("refs/remotes/origin/")
+	if err != nil {
+		return result, nil
+	}
+	var remoteCandidates []string
+	for _, branch := range result.Deleted {
+		if onOrigin[branch] {
+			remoteCandidates = append(remoteCandidates, branch)
 		}
-		deleted = append(deleted, branch)
+	}
+	if len(remoteCandidates) == 0 {
+		return result, nil
+	}
+	for _, batch := range batches(remoteCandidates, gitBatchSize) {
+		_, _ = m.runGit(append([]string{"push", "origin", "--delete"}, batch...)...)
+	}
+	// Successful deletions also drop the remote-tracking refs
+	stillOnOrigin, err := m.listRefs("refs/remotes/origin/")
+	if err != nil {
+		return result, nil
+	}
+	for _, branch := range remoteCandidates {
+		if !stillOnOrigin[branch] {
+			result.RemoteDeleted = append(result.RemoteDeleted, branch)
+		}
+	}
+
+	return result, nil
+}
+
+// listRefs returns the names under a ref namespace (e.g. "refs/heads/"), with
+// the namespace stripped
+func (m *Manager) listRefs(namespace string) (map[string]bool, error) {
+	output, err := gitcmd.Output(context.Background(), m.repoPath, "for-each-ref", "--format=%(refname)", namespace)
+	if err != nil {
+		return nil, err
+	}
 
-		// Delete remote branch if requested
-		if deleteRemote {
-			// Try to delete from origin (the fork)
-			_ = m.DeleteRemoteBranch("origin", branch)
+	refs := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if name := strings.TrimPrefix(line, namespace); name != "" && name != line {
+			refs[name] = true
 		}
 	}
+	return refs, nil
+}
 
-	return deleted, nil
+// batches splits items into consecutive slices of at most size items
+func batches(items []string, size int) [][]string {
+	var out [][]string
+	for len(items) > size {
+		out = append(out, items[:size])
+		items = items[size:]
+	}
+	if len(items) > 0 {
+		out = append(out, items)
+	}
+	return out
 }
 
 // CleanupOrphanedResult contains the result of a cleanup operation
@@ -617,22 +1082,22 @@ type WorktreeState struct {
 	RefreshReason  string
 }
 
-// GetWorktreeState checks the current state of a worktree and whether it can be safely refreshed
-func GetWorktreeState(worktreePath string, remote string, mainBranch string) (WorktreeState, error) {
+// UpToDate is the RefreshReason of a worktree that isn't behind the main branch
+const UpToDate = "already up to date"
+
+// GetWorktreeState checks the current state of a worktree and whether it can
+// be safely refreshed. Its git commands are killed when ctx is done.
+func GetWorktreeState(ctx context.Context, worktreePath string, remote string, mainBranch string) (WorktreeState, error) {
 	state := WorktreeState{
 		Path:       worktreePath,
 		CanRefresh: true,
 	}
 
 	// Get current branch (or detect detached HEAD)
-	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
-	cmd.Dir = worktreePath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(ctx, worktreePath, "symbolic-ref", "--short", "HEAD")
 	if err != nil {
 		// Check if it's detached HEAD (different error than not being a git repo)
-		cmd2 := exec.Command("git", "rev-parse", "--verify", "HEAD")
-		cmd2.Dir = worktreePath
-		if err2 := cmd2.Run(); err2 != nil {
+		if _, err2 := gitcmd.Output(ctx, worktreePath, "rev-parse", "--verify", "HEAD"); err2 != nil {
 			return state, fmt.Errorf("not a git repository or invalid state: %w", err)
 		}
 		state.IsDetachedHEAD = true
@@ -669,7 +1134,7 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	}
 
 	// Check for uncommitted changes
-	hasChanges, err := HasUncommittedChanges(worktreePath)
+	hasChanges, err := hasUncommittedChanges(ctx, worktreePath)
 	if err == nil {
 		state.HasUncommitted = hasChanges
 	}
@@ -687,9 +1152,7 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	}
 
 	// Check commits behind/ahead of remote main
-	cmd = exec.Command("git", "rev-list", "--left-right", "--count", fmt.Sprintf("%s/%s...HEAD", remote, mainBranch))
-	cmd.Dir = worktreePath
-	output, err = cmd.Output()
+	output, err = gitcmd.Output(ctx, worktreePath, "rev-list", "--left-right", "--count", fmt.Sprintf("%s/%s...HEAD", remote, mainBranch))
 	if err != nil {
 		// If we can't check, assume we can't safely auto-refresh
 		state.CanRefresh = false
@@ -707,15 +1170,15 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	// If not behind, no need to refresh
 	if state.CommitsBehind == 0 {
 		state.CanRefresh = false
-		state.RefreshReason = "already up to date"
+		state.RefreshReason = UpToDate
 	}
 
 	return state, nil
 }
 
 // IsBehindMain checks if a worktree is behind the remote main branch
-func IsBehindMain(worktreePath string, remote string, mainBranch string) (bool, int, error) {
-	state, err := GetWorktreeState(worktreePath, remote, mainBranch)
+func IsBehindMain(ctx context.Context, worktreePath string, remote string, mainBranch string) (bool, int, error) {
+	state, err := GetWorktreeState(ctx, worktreePath, remote, mainBranch)
 	if err != nil {
 		return false, 0, err
 	}
@@ -727,6 +1190,7 @@ type RefreshResult struct {
 	WorktreePath   string
 	Branch         string
 	CommitsRebased int
+	Merged         bool // The default branch was merged in rather than rebased onto
 	WasStashed     bool
 	StashRestored  bool
 	HasConflicts   bool
@@ -736,10 +1200,31 @@ type RefreshResult struct {
 	SkipReason     string
 }
 
+// RefreshOptions changes how RefreshWorktreeWithOptions brings a branch up to date
+type RefreshOptions struct {
+	// Merge merges the main branch in instead of rebasing onto it
+	Merge bool
+	// OnlyClean skips worktrees with uncommitted changes instead of stashing them
+	OnlyClean bool
+	// NoFetch skips the fetch, for callers that have just fetched the
+	// repository for all of its worktrees
+	NoFetch bool
+}
+
 // RefreshWorktree syncs a worktree with the latest changes from the main branch.
 // It fetches from the remote, stashes any uncommitted changes, rebases onto main,
 // and restores the stash. Returns detailed results about what happened.
-func RefreshWorktree(worktreePath string, remote string, mainBranch string) RefreshResult {
+//
+// If ctx is done partway through, the git command running is killed, an
+// interrupted rebase or merge is aborted and the stash is restored, so the
+// worktree is left as it was found.
+func RefreshWorktree(ctx context.Context, worktreePath string, remote string, mainBranch string) RefreshResult {
+	return RefreshWorktreeWithOptions(ctx, worktreePath, remote, mainBranch, RefreshOptions{})
+}
+
+// RefreshWorktreeWithOptions is RefreshWorktree with a choice of merging
+// instead of rebasing, and of leaving worktrees with uncommitted changes alone
+func RefreshWorktreeWithOptions(ctx context.Context, worktreePath string, remote string, mainBranch string, opts RefreshOptions) RefreshResult {
 	result := RefreshResult{
 		WorktreePath: worktreePath,
 	}
@@ -772,14 +1257,10 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Get current branch (also detects detached HEAD)
-	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
-	cmd.Dir = worktreePath
-	output, err := cmd.Output()
+	output, err := gitcmd.Output(ctx, worktreePath, "symbolic-ref", "--short", "HEAD")
 	if err != nil {
 		// Check if it's detached HEAD vs not a git repo
-		cmd2 := exec.Command("git", "rev-parse", "--verify", "HEAD")
-		cmd2.Dir = worktreePath
-		if cmd2.Run() == nil {
+		if _, err := gitcmd.Output(ctx, worktreePath, "rev-parse", "--verify", "HEAD"); err == nil {
 			result.Skipped = true
 			result.SkipReason = "detached HEAD (checkout a branch first)"
 			return result
@@ -798,65 +1279,70 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Fetch latest from remote
-	cmd = exec.Command("git", "fetch", remote, mainBranch)
-	cmd.Dir = worktreePath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		result.Error = fmt.Errorf("failed to fetch from %s: %w\nOutput: %s", remote, err, output)
-		return result
+	if !opts.NoFetch {
+		output, err = gitcmd.CombinedOutput(ctx, worktreePath, "fetch", remote, mainBranch)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to fetch from %s: %w\nOutput: %s", remote, err, output)
+			return result
+		}
 	}
 
 	// Check for uncommitted changes
-	hasChanges, err := HasUncommittedChanges(worktreePath)
+	hasChanges, err := hasUncommittedChanges(ctx, worktreePath)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to check for uncommitted changes: %w", err)
 		return result
 	}
 
+	if hasChanges && opts.OnlyClean {
+		result.Skipped = true
+		result.SkipReason = "uncommitted changes"
+		return result
+	}
+
 	// Stash if there are uncommitted changes (including untracked files)
-	stashName := ""
+	var stash string
 	if hasChanges {
-		stashName = fmt.Sprintf("refresh-stash-%d", os.Getpid())
-		cmd = exec.Command("git", "stash", "push", "--include-untracked", "-m", stashName)
-		cmd.Dir = worktreePath
-		if output, err := cmd.CombinedOutput(); err != nil {
-			result.Error = fmt.Errorf("failed to stash changes: %w\nOutput: %s", err, output)
+		if stash, err = stashChanges(ctx, worktreePath); err != nil {
+			result.Error = err
 			return result
 		}
 		result.WasStashed = true
 	}
 
 	// Get current commit count before rebase
-	cmd = exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s/%s..HEAD", remote, mainBranch))
-	cmd.Dir = worktreePath
-	countOutput, _ := cmd.Output()
+	countOutput, _ := gitcmd.Output(ctx, worktreePath, "rev-list", "--count", fmt.Sprintf("%s/%s..HEAD", remote, mainBranch))
 	commitsBefore := strings.TrimSpace(string(countOutput))
 
-	// Rebase onto main
-	cmd = exec.Command("git", "rebase", fmt.Sprintf("%s/%s", remote, mainBranch))
-	cmd.Dir = worktreePath
-	rebaseOutput, rebaseErr := cmd.CombinedOutput()
+	// Rebase onto main, or merge it in
+	verb := "rebase"
+	args := []string{"rebase", fmt.Sprintf("%s/%s", remote, mainBranch)}
+	if opts.Merge {
+		verb = "merge"
+		args = []string{"merge", "--no-edit", fmt.Sprintf("%s/%s", remote, mainBranch)}
+	}
+	rebaseOutput, rebaseErr := gitcmd.CombinedOutput(ctx, worktreePath, args...)
 
+	// Cleanup runs even once ctx is done, so that a refresh that is cut off
+	// doesn't leave the worktree mid-rebase with its changes stashed
+	cleanupCtx := context.WithoutCancel(ctx)
 	if rebaseErr != nil {
 		// Check if there are conflicts
-		cmd = exec.Command("git", "diff", "--name-only", "--diff-filter=U")
-		cmd.Dir = worktreePath
-		conflictOutput, _ := cmd.Output()
+		conflictOutput, _ := gitcmd.Output(cleanupCtx, worktreePath, "diff", "--name-only", "--diff-filter=U")
 		conflictFiles := strings.Split(strings.TrimSpace(string(conflictOutput)), "\n")
 		if len(conflictFiles) > 0 && conflictFiles[0] != "" {
 			result.HasConflicts = true
 			result.ConflictFiles = conflictFiles
-			// Abort the rebase to leave the worktree in a clean state
-			abortCmd := exec.Command("git", "rebase", "--abort")
-			abortCmd.Dir = worktreePath
-			abortCmd.Run()
 		}
-		result.Error = fmt.Errorf("rebase failed: %w\nOutput: %s", rebaseErr, rebaseOutput)
+		if result.HasConflicts || ctx.Err() != nil {
+			// Abort the rebase or merge to leave the worktree in a clean state
+			_, _ = gitcmd.Output(cleanupCtx, worktreePath, verb, "--abort")
+		}
+		result.Error = fmt.Errorf("%s failed: %w\nOutput: %s", verb, rebaseErr, rebaseOutput)
 
 		// Restore stash if we stashed
 		if result.WasStashed {
-			popCmd := exec.Command("git", "stash", "pop")
-			popCmd.Dir = worktreePath
-			if popCmd.Run() == nil {
+			if err := restoreStash(cleanupCtx, worktreePath, stash); err == nil {
 				result.StashRestored = true
 			}
 		}
@@ -865,17 +1351,16 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 
 	// Calculate commits rebased (commits that were ahead of main)
 	// This is an approximation based on the output
-	if commitsBefore != "" && commitsBefore != "0" {
+	result.Merged = opts.Merge
+	if !opts.Merge && commitsBefore != "" && commitsBefore != "0" {
 		fmt.Sscanf(commitsBefore, "%d", &result.CommitsRebased)
 	}
 
 	// Restore stash if we stashed
 	if result.WasStashed {
-		cmd = exec.Command("git", "stash", "pop")
-		cmd.Dir = worktreePath
-		if err := cmd.Run(); err != nil {
-			// Stash pop might fail if there are conflicts
-			result.Error = fmt.Errorf("stash pop failed (manual resolution may be needed): %w", err)
+		if err := restoreStash(cleanupCtx, worktreePath, stash); err != nil {
+			// Applying might fail if there are conflicts
+			result.Error = err
 		} else {
 			result.StashRestored = true
 		}
@@ -884,8 +1369,65 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	return result
 }
 
+// stashLocks serialize stashing in the worktrees of each clone: they share
+// one stash stack, keyed by the clone's common git directory
+var stashLocks sync.Map
+
+// stashChanges stashes a worktree's uncommitted changes, untracked files
+// included, and returns the stash commit. The commit is taken off the stash
+// stack straight away, which every worktree of the clone shares, so changes
+// stashed in one worktree can't be popped in another.
+func stashChanges(ctx context.Context, worktreePath string) (string, error) {
+	commonDir, err := gitcmd.Output(ctx, worktreePath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the git directory: %w", err)
+	}
+	key := strings.TrimSpace(string(commonDir))
+	if !filepath.IsAbs(key) {
+		key = filepath.Join(worktreePath, key)
+	}
+	lock, _ := stashLocks.LoadOrStore(filepath.Clean(key), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	name := fmt.Sprintf("refresh-stash-%d-%d", os.Getpid(), time.Now().UnixNano())
+	if output, err := gitcmd.CombinedOutput(ctx, worktreePath, "stash", "push", "--include-untracked", "-m", name); err != nil {
+		return "", fmt.Errorf("failed to stash changes: %w\nOutput: %s", err, output)
+	}
+	// Find the entry by its name, in case something outside multiclaude
+	// stashed in the meantime
+	list, err := gitcmd.Output(ctx, worktreePath, "stash", "list", "--format=%gd %H %gs")
+	if err != nil {
+		return "", fmt.Errorf("failed to list stashes: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(list)), "\n") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) == 3 && strings.HasSuffix(fields[2], name) {
+			if _, err := gitcmd.Output(ctx, worktreePath, "stash", "drop", fields[0]); err != nil {
+				return "", fmt.Errorf("failed to take %s off the stash stack: %w", name, err)
+			}
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("stashed changes as %s but can't find the stash", name)
+}
+
+// restoreStash applies a stash commit from stashChanges. If it doesn't
+// apply cleanly, it is put back on the stash stack, so the changes can be
+// recovered by hand.
+func restoreStash(ctx context.Context, worktreePath, stash string) error {
+	output, err := gitcmd.CombinedOutput(ctx, worktreePath, "stash", "apply", "--index", stash)
+	if err == nil {
+		return nil
+	}
+	if _, storeErr := gitcmd.Output(ctx, worktreePath, "stash", "store", "-m", "multiclaude refresh: changes that didn't apply", stash); storeErr != nil {
+		return fmt.Errorf("stash apply failed and the changes (commit %s) couldn't be put back on the stash stack: %w\nOutput: %s", stash, err, output)
+	}
+	return fmt.Errorf("stash apply failed (manual resolution may be needed; the changes are in the stash list): %w\nOutput: %s", err, output)
+}
+
 // RefreshWorktreeWithDefaults refreshes a worktree using the repository's default remote and branch
-func (m *Manager) RefreshWorktreeWithDefaults(worktreePath string) RefreshResult {
+func (m *Manager) RefreshWorktreeWithDefaults(ctx context.Context, worktreePath string) RefreshResult {
 	// Get the upstream remote
 	remote, err := m.GetUpstreamRemote()
 	if err != nil {
@@ -904,5 +1446,5 @@ func (m *Manager) RefreshWorktreeWithDefaults(worktreePath string) RefreshResult
 		}
 	}
 
-	return RefreshWorktree(worktreePath, remote, mainBranch)
+	return RefreshWorktree(ctx, worktreePath, remote, mainBranch)
 }
@@ -1,13 +1,13 @@
 package worktree
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 )
 
 // TestMain ensures git is available
@@ -180,6 +180,63 @@ func TestCreateWorktreeNewBranch(t *testing.T) {
 	}
 }
 
+func TestCreateWorktreeWithSubmodules(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	libPath, cleanupLib := createTestRepo(t)
+	defer cleanupLib()
+
+	// Local submodule URLs need the file protocol, which newer git versions disallow by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	for _, args := range [][]string{
+		{"submodule", "add", libPath, "lib"},
+		{"commit", "-m", "Add lib submodule"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", args[0], err, output)
+		}
+	}
+
+	// Without setup the submodule directory is empty
+	manager := NewManager(repoPath)
+	plainPath := filepath.Join(repoPath, "wt-plain")
+	if err := manager.CreateNewBranch(plainPath, "plain", "main"); err != nil {
+		t.Fatalf("CreateNewBranch() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(plainPath, "lib", "README.md")); !os.IsNotExist(err) {
+		t.Errorf("expected an empty submodule without setup, stat error = %v", err)
+	}
+
+	var progress []string
+	manager.SetSetup(Setup{Submodules: true, Progress: func(msg string) { progress = append(progress, msg) }})
+	wtPath := filepath.Join(repoPath, "wt-sub")
+	if err := manager.CreateNewBranch(wtPath, "with-sub", "main"); err != nil {
+		t.Fatalf("CreateNewBranch() with submodules error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "README.md")); err != nil {
+		t.Errorf("submodule not checked out: %v", err)
+	}
+	if len(progress) != 2 || !strings.HasPrefix(progress[0], "Running submodule update") || !strings.HasPrefix(progress[1], "Finished submodule update") {
+		t.Errorf("progress = %q", progress)
+	}
+
+	// A failing step leaves the worktree in place and reports a SetupError
+	os.RemoveAll(libPath)
+	failPath := filepath.Join(repoPath, "wt-fail")
+	err := manager.CreateNewBranch(failPath, "fail", "main")
+	if !IsSetupError(err) {
+		t.Fatalf("CreateNewBranch() with a missing submodule error = %v, want a SetupError", err)
+	}
+	if exists, _ := manager.Exists(failPath); !exists {
+		t.Error("worktree should be kept when setup fails")
+	}
+}
+
 func TestRemoveWorktree(t *testing.T) {
 	repoPath, cleanup := createTestRepo(t)
 	defer cleanup()
@@ -212,6 +269,67 @@ func TestRemoveWorktree(t *testing.T) {
 	}
 }
 
+func TestMoveWorktree(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	oldPath := filepath.Join(repoPath, "wts", "calm-owl")
+	if err := manager.CreateNewBranch(oldPath, "work/calm-owl", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	// Uncommitted work moves with the worktree
+	if err := os.WriteFile(filepath.Join(oldPath, "notes.txt"), []byte("wip\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	newPath := filepath.Join(repoPath, "scratch", "calm-owl")
+	if err := manager.Move(oldPath, newPath); err != nil {
+		t.Fatalf("Move() failed: %v", err)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Error("old worktree still exists after Move()")
+	}
+	if exists, err := manager.Exists(newPath); err != nil || !exists {
+		t.Errorf("moved worktree not registered in git: exists=%v, err=%v", exists, err)
+	}
+	if branch, err := GetCurrentBranch(newPath); err != nil || branch != "work/calm-owl" {
+		t.Errorf("GetCurrentBranch() after Move() = %q, %v", branch, err)
+	}
+	if dirty, err := HasUncommittedChanges(newPath); err != nil || !dirty {
+		t.Errorf("uncommitted work lost in Move(): dirty=%v, err=%v", dirty, err)
+	}
+
+	if err := manager.Move(newPath, repoPath); err == nil {
+		t.Error("Move() onto an existing path should fail")
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("bin/run.sh", filepath.Join(src, "run")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("copyTree() failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "bin", "run.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("copied file = %v, %v; want mode 0755", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "run")); err != nil || link != "bin/run.sh" {
+		t.Errorf("copied symlink = %q, %v", link, err)
+	}
+}
+
 func TestRemoveWorktreeForce(t *testing.T) {
 	repoPath, cleanup := createTestRepo(t)
 	defer cleanup()
@@ -723,35 +841,14 @@ func TestConcurrentWorktreeOperations(t *testing.T) {
 		createBranch(t, repoPath, fmt.Sprintf("branch-%d", i))
 	}
 
-	// Create worktrees with staggered starts and retry logic to handle
-	// transient git race conditions (e.g., "failed to read .git/worktrees/*/commondir")
+	// Manager retries transient git race conditions (e.g., "failed to read
+	// .git/worktrees/*/commondir") itself
 	done := make(chan error, numWorktrees)
 	for i := 0; i < numWorktrees; i++ {
 		i := i // capture loop variable
 		go func() {
 			wtPath := filepath.Join(repoPath, fmt.Sprintf("wt-%d", i))
-			branchName := fmt.Sprintf("branch-%d", i)
-
-			// Retry with exponential backoff for transient git race conditions
-			var lastErr error
-			for attempt := 0; attempt < 5; attempt++ {
-				if attempt > 0 {
-					// Exponential backoff: 50ms, 100ms, 200ms, 400ms
-					backoff := time.Duration(50<<attempt) * time.Millisecond
-					time.Sleep(backoff)
-				}
-				lastErr = manager.Create(wtPath, branchName)
-				if lastErr == nil {
-					done <- nil
-					return
-				}
-				// Only retry on race condition errors, not on permanent failures
-				if !strings.Contains(lastErr.Error(), "commondir") &&
-					!strings.Contains(lastErr.Error(), "index.lock") {
-					break
-				}
-			}
-			done <- lastErr
+			done <- manager.Create(wtPath, fmt.Sprintf("branch-%d", i))
 		}()
 	}
 
@@ -1186,6 +1283,72 @@ func TestGetDefaultBranch(t *testing.T) {
 	})
 }
 
+func TestSetDefaultBranch(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	manager.SetDefaultBranch("develop")
+
+	// No remote exists, so the branch can only come from the override
+	branch, err := manager.GetDefaultBranch("origin")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if branch != "develop" {
+		t.Errorf("Expected 'develop', got %s", branch)
+	}
+
+	manager.SetDefaultBranch("")
+	if _, err := manager.GetDefaultBranch("origin"); err == nil {
+		t.Error("Expected detection error after clearing the override")
+	}
+}
+
+func TestDetectRemoteDefaultBranch(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	// Rename main to trunk so detection can't fall back to common names
+	cmd := exec.Command("git", "branch", "-m", "main", "trunk")
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to rename branch: %v\n%s", err, output)
+	}
+
+	// Point origin at the repo itself without fetching
+	cmd = exec.Command("git", "remote", "add", "origin", repoPath)
+	cmd.Dir = repoPath
+	cmd.Run()
+
+	manager := NewManager(repoPath)
+	branch, err := manager.DetectRemoteDefaultBranch("origin")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if branch != "trunk" {
+		t.Errorf("Expected 'trunk', got %s", branch)
+	}
+}
+
+func TestParseSymrefHead(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"ref: refs/heads/main\tHEAD\n1234abcd\tHEAD\n", "main"},
+		{"ref: refs/heads/release/2.x\tHEAD\n", "release/2.x"},
+		{"1234abcd\tHEAD\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseSymrefHead(tt.output); got != tt.want {
+			t.Errorf("parseSymrefHead(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
 func TestFindMergedUpstreamBranches(t *testing.T) {
 	t.Run("finds merged branches", func(t *testing.T) {
 		repoPath, cleanup := createTestRepo(t)
@@ -1890,7 +2053,7 @@ func TestRefreshWorktree(t *testing.T) {
 		cmd.Run()
 
 		// Refresh the worktree
-		result := RefreshWorktree(wtPath, "origin", "main")
+		result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 
 		if result.Error != nil {
 			t.Errorf("Unexpected error: %v", result.Error)
@@ -1911,7 +2074,7 @@ func TestRefreshWorktree(t *testing.T) {
 		defer cleanup()
 
 		// Test refreshing the main repo (which is on main branch)
-		result := RefreshWorktree(repoPath, "origin", "main")
+		result := RefreshWorktree(context.Background(), repoPath, "origin", "main")
 
 		if !result.Skipped {
 			t.Error("Should have skipped refresh for main branch")
@@ -1950,7 +2113,7 @@ func TestRefreshWorktree(t *testing.T) {
 		}
 
 		// Refresh the worktree
-		result := RefreshWorktree(wtPath, "origin", "main")
+		result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 
 		if result.Error != nil {
 			t.Errorf("Unexpected error: %v", result.Error)
@@ -1982,7 +2145,7 @@ func TestRefreshWorktree(t *testing.T) {
 		defer manager.Remove(wtPath, true)
 
 		// Try to refresh with non-existent remote
-		result := RefreshWorktree(wtPath, "nonexistent", "main")
+		result := RefreshWorktree(context.Background(), wtPath, "nonexistent", "main")
 
 		if result.Error == nil {
 			t.Error("Expected error for non-existent remote")
@@ -2014,7 +2177,7 @@ func TestRefreshWorktreeWithDefaults(t *testing.T) {
 		defer manager.Remove(wtPath, true)
 
 		// Refresh using defaults
-		result := manager.RefreshWorktreeWithDefaults(wtPath)
+		result := manager.RefreshWorktreeWithDefaults(context.Background(), wtPath)
 
 		if result.Error != nil {
 			t.Errorf("Unexpected error: %v", result.Error)
@@ -2038,7 +2201,7 @@ func TestRefreshWorktreeWithDefaults(t *testing.T) {
 		defer manager.Remove(wtPath, true)
 
 		// Refresh using defaults should fail
-		result := manager.RefreshWorktreeWithDefaults(wtPath)
+		result := manager.RefreshWorktreeWithDefaults(context.Background(), wtPath)
 
 		if result.Error == nil {
 			t.Error("Expected error when no remote exists")
@@ -2069,7 +2232,7 @@ func TestGetWorktreeState(t *testing.T) {
 		}
 		defer manager.Remove(wtPath, true)
 
-		state, err := GetWorktreeState(wtPath, "origin", "main")
+		state, err := GetWorktreeState(context.Background(), wtPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2099,7 +2262,7 @@ func TestGetWorktreeState(t *testing.T) {
 			t.Fatalf("Failed to detach HEAD: %v", err)
 		}
 
-		state, err := GetWorktreeState(repoPath, "origin", "main")
+		state, err := GetWorktreeState(context.Background(), repoPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2157,7 +2320,7 @@ func TestGetWorktreeState(t *testing.T) {
 		cmd.Dir = wtPath
 		cmd.Run() // Will fail, leaving us mid-rebase
 
-		state, err := GetWorktreeState(wtPath, "origin", "main")
+		state, err := GetWorktreeState(context.Background(), wtPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2191,7 +2354,7 @@ func TestGetWorktreeState(t *testing.T) {
 		cmd.Dir = repoPath
 		cmd.Run()
 
-		state, err := GetWorktreeState(repoPath, "origin", "main")
+		state, err := GetWorktreeState(context.Background(), repoPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2246,7 +2409,7 @@ func TestGetWorktreeState(t *testing.T) {
 		cmd.Dir = wtPath
 		cmd.Run()
 
-		state, err := GetWorktreeState(wtPath, "origin", "main")
+		state, err := GetWorktreeState(context.Background(), wtPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2272,7 +2435,7 @@ func TestRefreshWorktreeEdgeCases(t *testing.T) {
 			t.Fatalf("Failed to detach HEAD: %v", err)
 		}
 
-		result := RefreshWorktree(repoPath, "origin", "main")
+		result := RefreshWorktree(context.Background(), repoPath, "origin", "main")
 
 		if !result.Skipped {
 			t.Error("Should have skipped refresh for detached HEAD")
@@ -2324,7 +2487,7 @@ func TestRefreshWorktreeEdgeCases(t *testing.T) {
 		cmd.Dir = wtPath
 		cmd.Run() // Will fail, leaving us mid-rebase
 
-		result := RefreshWorktree(wtPath, "origin", "main")
+		result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 
 		if !result.Skipped {
 			t.Error("Should have skipped refresh for mid-rebase")
@@ -2381,7 +2544,7 @@ func TestRefreshWorktreeEdgeCases(t *testing.T) {
 		cmd.Dir = wtPath
 		cmd.Run() // Will fail, leaving us mid-merge
 
-		result := RefreshWorktree(wtPath, "origin", "main")
+		result := RefreshWorktree(context.Background(), wtPath, "origin", "main")
 
 		if !result.Skipped {
 			t.Error("Should have skipped refresh for mid-merge")
@@ -2437,7 +2600,7 @@ func TestIsBehindMain(t *testing.T) {
 		cmd.Dir = wtPath
 		cmd.Run()
 
-		isBehind, count, err := IsBehindMain(wtPath, "origin", "main")
+		isBehind, count, err := IsBehindMain(context.Background(), wtPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2472,7 +2635,7 @@ func TestIsBehindMain(t *testing.T) {
 		}
 		defer manager.Remove(wtPath, true)
 
-		isBehind, count, err := IsBehindMain(wtPath, "origin", "main")
+		isBehind, count, err := IsBehindMain(context.Background(), wtPath, "origin", "main")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2989,3 +3152,178 @@ func TestCleanupMergedBranchesWithRemoteDeletion(t *testing.T) {
 		}
 	})
 }
+
+func TestCleanupMergedBranchesFiltered(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	remoteDir := t.TempDir()
+	cmd := exec.Command("git", "init", "--bare", "-b", "main")
+	cmd.Dir = remoteDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	git("remote", "add", "origin", remoteDir)
+	git("push", "-q", "-u", "origin", "main")
+
+	// More merged branches than one delete batch, created in a single git call
+	count := gitBatchSize + 20
+	var refs strings.Builder
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&refs, "create refs/heads/work/merged-%03d HEAD\n", i)
+	}
+	fmt.Fprintf(&refs, "create refs/heads/other/merged HEAD\n")
+	cmd = exec.Command("git", "update-ref", "--stdin")
+	cmd.Dir = repoPath
+	cmd.Stdin = strings.NewReader(refs.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("update-ref failed: %v\n%s", err, out)
+	}
+	git("push", "-q", "origin", "work/merged-000", "work/merged-001")
+	git("fetch", "-q", "origin")
+
+	// One merged branch is still checked out in a worktree
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	git("worktree", "add", "-q", wtPath, "work/merged-002")
+
+	result, err := manager.CleanupMergedBranchesFiltered(func(branch string) bool {
+		return strings.HasPrefix(branch, "work/")
+	}, true)
+	if err != nil {
+		t.Fatalf("CleanupMergedBranchesFiltered failed: %v", err)
+	}
+
+	if len(result.Deleted) != count-1 {
+		t.Errorf("deleted %d branches, want %d", len(result.Deleted), count-1)
+	}
+	if len(result.CheckedOut) != 1 || result.CheckedOut[0] != "work/merged-002" {
+		t.Errorf("CheckedOut = %v, want [work/merged-002]", result.CheckedOut)
+	}
+	if len(result.RemoteDeleted) != 2 {
+		t.Errorf("RemoteDeleted = %v, want the 2 pushed branches", result.RemoteDeleted)
+	}
+
+	remaining, _ := manager.ListBranchesWithPrefix("")
+	want := map[string]bool{"main": true, "work/merged-002": true, "other/merged": true}
+	if len(remaining) != len(want) {
+		t.Errorf("remaining branches = %v, want main, work/merged-002 and other/merged", remaining)
+	}
+	for _, b := range remaining {
+		if !want[b] {
+			t.Errorf("unexpected remaining branch %s", b)
+		}
+	}
+
+	out, _ := exec.Command("git", "--git-dir", remoteDir, "branch", "--list", "work/*").Output()
+	if len(strings.TrimSpace(string(out))) != 0 {
+		t.Errorf("remote branches not deleted: %s", out)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	output := `# branch.oid 1a2b3c
+# branch.head work/calm-owl
+# branch.upstream origin/work/calm-owl
+# branch.ab +2 -1
+1 M. N... 100644 100644 100644 aaa bbb staged.go
+1 MM N... 100644 100644 100644 aaa bbb both.go
+1 .M N... 100644 100644 100644 aaa bbb unstaged.go
+2 R. N... 100644 100644 100644 aaa bbb R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 aaa bbb ccc conflict.go
+? notes.txt
+! build/
+`
+	got := parseStatus(output)
+	want := Status{Branch: "work/calm-owl", Upstream: "origin/work/calm-owl", Ahead: 2, Behind: 1, Staged: 3, Unstaged: 2, Untracked: 1, Conflicted: 1}
+	if got != want {
+		t.Errorf("parseStatus() = %+v, want %+v", got, want)
+	}
+	if !got.Dirty() {
+		t.Error("Dirty() = false with changed files")
+	}
+
+	detached := parseStatus("# branch.oid 1a2b3c\n# branch.head (detached)\n")
+	if detached.Branch != "HEAD" || !detached.Detached || detached.Dirty() {
+		t.Errorf("parseStatus() detached = %+v", detached)
+	}
+}
+
+func TestWorktreeStatus(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	manager := NewManager(repoPath)
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	remoteDir := t.TempDir()
+	git(remoteDir, "init", "--bare")
+	git(repoPath, "remote", "add", "origin", remoteDir)
+
+	wtPath := filepath.Join(repoPath, "wt-status")
+	if err := manager.CreateNewBranch(wtPath, "work/status", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	defer manager.Remove(wtPath, true)
+	git(wtPath, "push", "-u", "origin", "work/status")
+
+	// One commit ahead, one staged file, one modified file and one untracked file
+	os.WriteFile(filepath.Join(wtPath, "feature.txt"), []byte("feature"), 0644)
+	git(wtPath, "add", "feature.txt")
+	git(wtPath, "commit", "-m", "Add feature")
+	os.WriteFile(filepath.Join(wtPath, "staged.txt"), []byte("staged"), 0644)
+	git(wtPath, "add", "staged.txt")
+	os.WriteFile(filepath.Join(wtPath, "README.md"), []byte("changed"), 0644)
+	os.WriteFile(filepath.Join(wtPath, "scratch.txt"), []byte("scratch"), 0644)
+
+	status, err := manager.Status(wtPath)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Branch != "work/status" || status.Upstream != "origin/work/status" || status.Ahead != 1 || status.Behind != 0 {
+		t.Errorf("Status() branch = %+v, want work/status one ahead of origin", status)
+	}
+	if status.Staged != 1 || status.Unstaged != 1 || status.Untracked != 1 || status.Conflicted != 0 {
+		t.Errorf("Status() files = %+v, want one staged, one unstaged and one untracked", status)
+	}
+	if status.LastCommit == nil || status.LastCommit.Subject != "Add feature" || status.LastCommit.Author != "Test User" || status.LastCommit.Time.IsZero() {
+		t.Errorf("LastCommit = %+v, want the feature commit", status.LastCommit)
+	}
+
+	worktrees, err := manager.ListWithStatus()
+	if err != nil {
+		t.Fatalf("ListWithStatus() failed: %v", err)
+	}
+	found := false
+	for _, wt := range worktrees {
+		if wt.Branch == "work/status" {
+			found = true
+			if wt.Status == nil || wt.Status.Ahead != 1 {
+				t.Errorf("ListWithStatus() status = %+v, want the worktree's status", wt.Status)
+			}
+		}
+	}
+	if !found {
+		t.Error("ListWithStatus() is missing the worktree")
+	}
+
+	if _, err := manager.Status(filepath.Join(repoPath, "missing")); err == nil {
+		t.Error("Status() of a missing directory should fail")
+	}
+}
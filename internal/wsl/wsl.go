@@ -0,0 +1,111 @@
+// Package wsl supports running multiclaude inside the Windows Subsystem for
+// Linux. multiclaude itself (daemon, tmux, git, claude) runs in the Linux
+// distribution; this package detects that situation and translates paths
+// between the Linux side (/mnt/c/Users/me, /home/me) and the forms Windows
+// tools understand (C:\Users\me, \\wsl.localhost\Ubuntu\home\me).
+package wsl
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// osReleaseFile reports the kernel release; WSL kernels mention Microsoft
+const osReleaseFile = "/proc/sys/kernel/osrelease"
+
+// Detect reports whether the process is running under WSL
+func Detect() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" || os.Getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	release, err := os.ReadFile(osReleaseFile)
+	if err != nil {
+		return false
+	}
+	return IsWSLKernel(string(release))
+}
+
+// IsWSLKernel reports whether a kernel release string is a WSL kernel,
+// e.g. "5.15.153.1-microsoft-standard-WSL2"
+func IsWSLKernel(release string) bool {
+	release = strings.ToLower(release)
+	return strings.Contains(release, "microsoft") || strings.Contains(release, "wsl")
+}
+
+// Distro returns the name of the WSL distribution, e.g. "Ubuntu", or "" if unknown
+func Distro() string {
+	return os.Getenv("WSL_DISTRO_NAME")
+}
+
+// drivePathRe matches a Windows drive path: C:\Users or C:/Users
+var drivePathRe = regexp.MustCompile(`^([A-Za-z]):(?:[\\/](.*))?$`)
+
+// mountPathRe matches a Windows drive mounted in WSL: /mnt/c/Users
+var mountPathRe = regexp.MustCompile(`^/mnt/([a-z])(?:/(.*))?$`)
+
+// uncPrefixes are the UNC hosts Windows uses for WSL distributions
+var uncPrefixes = []string{`\\wsl.localhost\`, `\\wsl$\`}
+
+// IsWindowsPath reports whether p is a Windows drive or WSL UNC path
+func IsWindowsPath(p string) bool {
+	if drivePathRe.MatchString(p) {
+		return true
+	}
+	for _, prefix := range uncPrefixes {
+		if hasPrefixFold(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToLinux translates a Windows path to its location inside WSL:
+// C:\Users\me becomes /mnt/c/Users/me and \\wsl.localhost\Ubuntu\home\me
+// becomes /home/me. Other paths are returned unchanged.
+func ToLinux(p string) string {
+	if m := drivePathRe.FindStringSubmatch(p); m != nil {
+		linux := "/mnt/" + strings.ToLower(m[1])
+		if rest := strings.ReplaceAll(m[2], `\`, "/"); rest != "" {
+			linux = path.Join(linux, rest)
+		}
+		return linux
+	}
+	for _, prefix := range uncPrefixes {
+		if !hasPrefixFold(p, prefix) {
+			continue
+		}
+		// Drop the distribution name: \\wsl$\Ubuntu\home\me -> \home\me
+		rest := p[len(prefix):]
+		if i := strings.IndexByte(rest, '\\'); i >= 0 {
+			rest = rest[i:]
+		} else {
+			rest = ""
+		}
+		return path.Join("/", strings.ReplaceAll(rest, `\`, "/"))
+	}
+	return p
+}
+
+// ToWindows translates an absolute WSL path to the form Windows tools use:
+// /mnt/c/Users/me becomes C:\Users\me, and paths inside the distribution
+// become \\wsl.localhost\<distro>\... Relative paths are returned unchanged,
+// as are distribution paths when the distribution is unknown.
+func ToWindows(p, distro string) string {
+	if !path.IsAbs(p) {
+		return p
+	}
+	p = path.Clean(p)
+	if m := mountPathRe.FindStringSubmatch(p); m != nil {
+		return strings.ToUpper(m[1]) + `:\` + strings.ReplaceAll(m[2], "/", `\`)
+	}
+	if distro == "" {
+		return p
+	}
+	return uncPrefixes[0] + distro + strings.ReplaceAll(p, "/", `\`)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
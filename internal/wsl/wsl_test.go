@@ -0,0 +1,61 @@
+package wsl
+
+import "testing"
+
+func TestIsWSLKernel(t *testing.T) {
+	for release, want := range map[string]bool{
+		"5.15.153.1-microsoft-standard-WSL2\n": true,
+		"4.4.0-19041-Microsoft":                true,
+		"6.8.0-45-generic":                     false,
+	} {
+		if got := IsWSLKernel(release); got != want {
+			t.Errorf("IsWSLKernel(%q) = %v, want %v", release, got, want)
+		}
+	}
+}
+
+func TestToLinux(t *testing.T) {
+	tests := map[string]string{
+		`C:\Users\me\task.md`:                    "/mnt/c/Users/me/task.md",
+		`d:/work/repo`:                           "/mnt/d/work/repo",
+		`C:\`:                                    "/mnt/c",
+		`E:`:                                     "/mnt/e",
+		`\\wsl.localhost\Ubuntu\home\me\task.md`: "/home/me/task.md",
+		`\\WSL$\Ubuntu-22.04\tmp`:                "/tmp",
+		`\\wsl$\Ubuntu`:                          "/",
+		"/home/me/task.md":                       "/home/me/task.md",
+		"task.md":                                "task.md",
+	}
+	for in, want := range tests {
+		if got := ToLinux(in); got != want {
+			t.Errorf("ToLinux(%q) = %q, want %q", in, got, want)
+		}
+		if isWindows := in != want; IsWindowsPath(in) != isWindows {
+			t.Errorf("IsWindowsPath(%q) = %v, want %v", in, !isWindows, isWindows)
+		}
+	}
+}
+
+func TestToWindows(t *testing.T) {
+	tests := []struct {
+		path, distro, want string
+	}{
+		{"/mnt/c/Users/me/report.md", "Ubuntu", `C:\Users\me\report.md`},
+		{"/mnt/d", "Ubuntu", `D:\`},
+		{"/home/me/.multiclaude/daemon.log", "Ubuntu", `\\wsl.localhost\Ubuntu\home\me\.multiclaude\daemon.log`},
+		{"/home/me/.multiclaude", "", "/home/me/.multiclaude"},
+		{"relative/path", "Ubuntu", "relative/path"},
+	}
+	for _, tt := range tests {
+		if got := ToWindows(tt.path, tt.distro); got != tt.want {
+			t.Errorf("ToWindows(%q, %q) = %q, want %q", tt.path, tt.distro, got, tt.want)
+		}
+	}
+
+	// Translating back recovers the WSL path
+	for _, p := range []string{"/mnt/c/Users/me/report.md", "/home/me/.multiclaude/daemon.log"} {
+		if back := ToLinux(ToWindows(p, "Ubuntu")); back != p {
+			t.Errorf("ToLinux(ToWindows(%q)) = %q", p, back)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package xref keeps a repository's cross-reference index: for each PR, the
+// worker whose branch it's from, the task history entry the work was
+// recorded as, and the messages about it. These only exist implicitly
+// elsewhere, and the daemon deletes what they're inferred from (workers,
+// acked messages) as it goes, so the daemon writes links down as it sees
+// them: PRs when it lists them, history entries when workers finish and
+// messages as it delivers them.
+package xref
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MaxMessages is how many messages are kept per PR; older ones are dropped
+const MaxMessages = 50
+
+// maxBody is how much of a message's body is kept
+const maxBody = 500
+
+// PR is what's known about one pull request
+type PR struct {
+	Number int    `json:"number"`
+	URL    string `json:"url,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// Agent is the worker whose branch the PR is from
+	Agent string `json:"agent,omitempty"`
+	Task  string `json:"task,omitempty"`
+	// HistoryID is the task history entry recorded when the worker finished;
+	// empty while it's still working
+	HistoryID string       `json:"history_id,omitempty"`
+	Messages  []MessageRef `json:"messages,omitempty"`
+	FirstSeen time.Time    `json:"first_seen"`
+}
+
+// MessageRef is a delivered message that mentions a PR, or passed to or
+// from the PR's worker while it was working
+type MessageRef struct {
+	ID   string    `json:"id"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+	Body string    `json:"body"`
+}
+
+// Index is a repository's cross-references. It is not safe for concurrent
+// use; the daemon loads, changes and saves it under a lock.
+type Index struct {
+	PRs map[int]*PR `json:"prs"`
+
+	path string
+}
+
+// Load reads an index, returning an empty one if the file doesn't exist
+func Load(path string) (*Index, error) {
+	x := &Index{PRs: make(map[int]*PR), path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return x, nil
+		}
+		return nil, fmt.Errorf("failed to read cross-reference index: %w", err)
+	}
+	if err := json.Unmarshal(data, x); err != nil {
+		return nil, fmt.Errorf("failed to parse cross-reference index: %w", err)
+	}
+	if x.PRs == nil {
+		x.PRs = make(map[int]*PR)
+	}
+	return x, nil
+}
+
+// Save writes the index back to disk
+func (x *Index) Save() error {
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cross-reference directory: %w", err)
+	}
+	data, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cross-reference index: %w", err)
+	}
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cross-reference index: %w", err)
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cross-reference index: %w", err)
+	}
+	return nil
+}
+
+// LinkPR records that a PR is from a worker's branch. It reports whether
+// anything changed. A PR whose worker has finished is left alone, since a
+// later worker with the same name can have the same branch.
+func (x *Index) LinkPR(number int, url, branch, agent, task string, now time.Time) bool {
+	pr, ok := x.PRs[number]
+	if !ok {
+		pr = &PR{Number: number, FirstSeen: now}
+		x.PRs[number] = pr
+	} else if pr.HistoryID != "" || (pr.Agent == agent && pr.URL == url && pr.Branch == branch) {
+		return false
+	}
+	pr.URL, pr.Branch, pr.Agent, pr.Task = url, branch, agent, task
+	return true
+}
+
+// LinkHistory records the task history entry a worker's work was recorded
+// as on the PR from its branch, and returns that PR, if there is one
+func (x *Index) LinkHistory(agent, branch, historyID string) *PR {
+	pr := x.working(agent)
+	if pr == nil || pr.Branch != branch {
+		return nil
+	}
+	pr.HistoryID = historyID
+	return pr
+}
+
+// working returns the PR of a worker that is still working, the newest if
+// it has several. Finished workers' PRs are left out, since a later worker
+// can have the same name.
+func (x *Index) working(agent string) *PR {
+	var found *PR
+	for _, pr := range x.PRs {
+		if pr.Agent == agent && pr.HistoryID == "" && (found == nil || pr.Number > found.Number) {
+			found = pr
+		}
+	}
+	return found
+}
+
+// AddMessage links a delivered message to the PRs it mentions and the PR of
+// the working worker it's to or from. A PR mentioned before the daemon has
+// listed it, as when a worker announces one it just opened, is added with
+// just its number. It reports whether anything changed.
+func (x *Index) AddMessage(m MessageRef) bool {
+	if len(m.Body) > maxBody {
+		m.Body = m.Body[:maxBody] + "…"
+	}
+	targets := make(map[int]*PR)
+	for _, number := range Mentions(m.Body) {
+		pr, ok := x.PRs[number]
+		if !ok {
+			pr = &PR{Number: number, FirstSeen: m.At}
+			x.PRs[number] = pr
+		}
+		targets[number] = pr
+	}
+	for _, agent := range []string{m.From, m.To} {
+		if pr := x.working(agent); pr != nil {
+			targets[pr.Number] = pr
+		}
+	}
+
+	changed := false
+	for _, pr := range targets {
+		if pr.hasMessage(m.ID) {
+			continue
+		}
+		pr.Messages = append(pr.Messages, m)
+		if len(pr.Messages) > MaxMessages {
+			pr.Messages = pr.Messages[len(pr.Messages)-MaxMessages:]
+		}
+		changed = true
+	}
+	return changed
+}
+
+func (pr *PR) hasMessage(id string) bool {
+	for _, m := range pr.Messages {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionPattern matches the ways agents refer to PRs: "PR #42", "PR 42",
+// "pull request #42" and PR URLs
+var mentionPattern = regexp.MustCompile(`(?i)(?:\bPR\s*#?|\bpull request\s*#?|/pull/)(\d+)\b`)
+
+// Mentions returns the PR numbers a message body refers to, in order, once each
+func Mentions(body string) []int {
+	seen := make(map[int]bool)
+	var numbers []int
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || seen[n] {
+			continue
+		}
+		seen[n] = true
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
@@ -0,0 +1,100 @@
+package xref
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMentions(t *testing.T) {
+	body := "PR #42 is ready (https://github.com/o/r/pull/42); see also pr 7, pull request #8, issue #9 and PR#10"
+	if got := Mentions(body); !reflect.DeepEqual(got, []int{42, 7, 8, 10}) {
+		t.Errorf("Mentions() = %v, want [42 7 8 10]", got)
+	}
+	if got := Mentions("APR 3 and SPR #4"); got != nil {
+		t.Errorf("Mentions() = %v, want none", got)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xref", "repo.json")
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	x, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of a missing index failed: %v", err)
+	}
+
+	// A worker announces its PR before the daemon has listed it
+	if !x.AddMessage(MessageRef{ID: "m1", From: "calm-owl", To: "supervisor", At: now, Body: "Opened PR #42"}) {
+		t.Fatal("AddMessage() mentioning a PR changed nothing")
+	}
+	if !x.LinkPR(42, "https://github.com/o/r/pull/42", "work/calm-owl", "calm-owl", "Fix login", now) {
+		t.Fatal("LinkPR() of a new link changed nothing")
+	}
+	if x.LinkPR(42, "https://github.com/o/r/pull/42", "work/calm-owl", "calm-owl", "Fix login", now) {
+		t.Error("LinkPR() of the same link reported a change")
+	}
+
+	// Messages to and from the working worker are linked without a mention
+	x.AddMessage(MessageRef{ID: "m2", From: "supervisor", To: "calm-owl", At: now, Body: "How's it going?"})
+	x.AddMessage(MessageRef{ID: "m3", From: "supervisor", To: "eager-fox", At: now, Body: "Unrelated"})
+	if x.AddMessage(MessageRef{ID: "m2", From: "supervisor", To: "calm-owl", At: now, Body: "How's it going?"}) {
+		t.Error("AddMessage() of a message already linked reported a change")
+	}
+	if got := messageIDs(x.PRs[42]); !reflect.DeepEqual(got, []string{"m1", "m2"}) {
+		t.Errorf("PR #42 messages = %v, want [m1 m2]", got)
+	}
+
+	if pr := x.LinkHistory("calm-owl", "work/other", "calm-owl-1"); pr != nil {
+		t.Error("LinkHistory() linked a PR from another branch")
+	}
+	if pr := x.LinkHistory("calm-owl", "work/calm-owl", "calm-owl-20260302090000"); pr == nil || pr.Number != 42 {
+		t.Fatalf("LinkHistory() = %+v, want PR #42", pr)
+	}
+
+	// Once the worker has finished, a new worker with its name gets nothing of it
+	x.AddMessage(MessageRef{ID: "m4", From: "supervisor", To: "calm-owl", At: now, Body: "New task"})
+	if x.LinkPR(42, "https://github.com/o/r/pull/42", "work/calm-owl", "calm-owl-2", "", now) {
+		t.Error("LinkPR() relinked a finished worker's PR")
+	}
+	if len(x.PRs[42].Messages) != 2 {
+		t.Errorf("PR #42 messages = %v, want the finished worker's only", messageIDs(x.PRs[42]))
+	}
+
+	if err := x.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if pr := loaded.PRs[42]; pr == nil || pr.Agent != "calm-owl" || pr.HistoryID != "calm-owl-20260302090000" || len(pr.Messages) != 2 {
+		t.Errorf("loaded PR #42 = %+v", pr)
+	}
+}
+
+func TestAddMessageLimits(t *testing.T) {
+	x, _ := Load(filepath.Join(t.TempDir(), "repo.json"))
+	x.LinkPR(1, "", "work/a", "a", "", time.Now())
+	for i := 0; i < MaxMessages+5; i++ {
+		x.AddMessage(MessageRef{ID: string(rune('A' + i)), From: "a", To: "supervisor", Body: strings.Repeat("x", maxBody+10)})
+	}
+	msgs := x.PRs[1].Messages
+	if len(msgs) != MaxMessages || msgs[0].ID != string(rune('A'+5)) {
+		t.Errorf("kept %d messages starting at %q, want the latest %d", len(msgs), msgs[0].ID, MaxMessages)
+	}
+	if !strings.HasSuffix(msgs[0].Body, "…") || len(msgs[0].Body) > maxBody+len("…") {
+		t.Errorf("body kept at %d bytes, want it cut at %d", len(msgs[0].Body), maxBody)
+	}
+}
+
+func messageIDs(pr *PR) []string {
+	var ids []string
+	for _, m := range pr.Messages {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
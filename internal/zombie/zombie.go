@@ -0,0 +1,375 @@
+// Package zombie detects agents whose Claude process is alive but stuck. The
+// daemon's PID checks catch agents that crashed; a zombie still has a live
+// process but has stopped making progress: its window has shown no new output
+// for hours, it keeps printing the same thing, or it is sitting at an
+// interactive permission prompt that nobody will answer.
+//
+// Detection works on captures of the agent's tmux pane. Text the daemon types
+// into the window itself (wake-up status checks and delivered messages) is
+// ignored, so a stuck agent that keeps receiving messages still counts as
+// having produced no output.
+package zombie
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/templates"
+)
+
+// Kind is a way an agent can be stuck
+type Kind string
+
+const (
+	// Stalled means the pane has shown no new output for StallMinutes
+	Stalled Kind = "stalled"
+	// Looping means the agent keeps printing the same lines within one turn
+	Looping Kind = "looping"
+	// PermissionPrompt means the agent is waiting at an interactive permission prompt
+	PermissionPrompt Kind = "permission_prompt"
+	// Question means the agent has asked a multiple-choice question and is
+	// waiting for an answer. It is reported by Waiting, not Classify.
+	Question Kind = "question"
+)
+
+const (
+	// HistoryLines is how much scrollback to capture for classification
+	HistoryLines = 200
+	// DefaultStallMinutes is how long a pane may go unchanged before the agent counts as stalled
+	DefaultStallMinutes = 120
+	// PromptMinutes is how long a permission prompt may sit unanswered
+	PromptMinutes = 5
+)
+
+// loopPeriod is the longest block of lines checked for repetition, and
+// loopRepeats is how many times a block must repeat in a row. Single lines
+// must repeat twice as often, since a few identical lines in a row are common.
+const (
+	loopPeriod  = 10
+	loopRepeats = 3
+)
+
+// Action returns the remediation configured for a kind, applying defaults:
+// stalled agents are nudged, looping agents are restarted, and permission
+// prompts are escalated to a human
+func Action(cfg state.ZombieConfig, kind Kind) state.ZombieAction {
+	var action state.ZombieAction
+	switch kind {
+	case Stalled:
+		action = cfg.Stalled
+		if action == "" {
+			action = state.ZombieActionNudge
+		}
+	case Looping:
+		action = cfg.Looping
+		if action == "" {
+			action = state.ZombieActionRestart
+		}
+	case PermissionPrompt:
+		action = cfg.PermissionPrompt
+		if action == "" {
+			action = state.ZombieActionEscalate
+		}
+	}
+	return action
+}
+
+// StallAfter returns how long a pane may go unchanged before the agent counts as stalled
+func StallAfter(cfg state.ZombieConfig) time.Duration {
+	if cfg.StallMinutes > 0 {
+		return time.Duration(cfg.StallMinutes) * time.Minute
+	}
+	return DefaultStallMinutes * time.Minute
+}
+
+// Describe explains a kind in a sentence, for logs and escalations
+func Describe(kind Kind, cfg state.ZombieConfig) string {
+	switch kind {
+	case Stalled:
+		return fmt.Sprintf("no new output for %s", StallAfter(cfg))
+	case Looping:
+		return "repeating the same output"
+	case PermissionPrompt:
+		return "waiting at a permission prompt"
+	}
+	return string(kind)
+}
+
+// injectedMarkers identify lines the daemon typed into the window: messages
+// and, in every locale, nudges
+var injectedMarkers = append([]string{"📨 Message from"}, templates.Markers()...)
+
+// Lines returns the meaningful lines of a pane capture: box drawing, digits
+// (timers, counters) and symbols (spinners) are stripped, and blank lines and
+// lines the daemon typed are dropped
+func Lines(pane string) []string {
+	return lines(pane, false)
+}
+
+// lines normalizes a pane capture. With keepInjected, lines the daemon typed
+// are kept, so messages it delivered still mark the start of a turn.
+func lines(pane string, keepInjected bool) []string {
+	var result []string
+	for _, raw := range strings.Split(pane, "\n") {
+		if !keepInjected && isInjected(raw) {
+			continue
+		}
+		line := strings.Join(strings.Fields(strings.Map(normalizeRune, raw)), " ")
+		if line == "" {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func isInjected(line string) bool {
+	for _, marker := range injectedMarkers {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeRune(r rune) rune {
+	switch {
+	case r == '>':
+		return r
+	case unicode.IsDigit(r), unicode.IsSymbol(r):
+		return -1
+	case unicode.IsLetter(r), unicode.IsPunct(r), unicode.IsSpace(r):
+		return r
+	}
+	return -1
+}
+
+// promptQuestionRe and promptOptionRe match Claude's permission prompt, e.g.
+// "Do you want to make this edit to main.go?" followed by "❯ 1. Yes"
+var (
+	promptQuestionRe = regexp.MustCompile(`Do you want to `)
+	promptOptionRe   = regexp.MustCompile(`^\W*1\. Yes`)
+)
+
+// promptTail is how many lines from the bottom of the pane a permission prompt may start
+const promptTail = 20
+
+// AtPermissionPrompt reports whether a pane capture ends at a permission prompt
+func AtPermissionPrompt(pane string) bool {
+	lines := strings.Split(strings.TrimRight(pane, "\n "), "\n")
+	if len(lines) > promptTail {
+		lines = lines[len(lines)-promptTail:]
+	}
+	question := -1
+	for i, line := range lines {
+		line = strings.TrimSpace(strings.Trim(line, "│ "))
+		if promptQuestionRe.MatchString(line) {
+			question = i
+		} else if question >= 0 && promptOptionRe.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// questionFooterRe matches the key hints under Claude's multiple-choice
+// questions, e.g. "Enter to select · ↑/↓ to navigate · Esc to cancel"
+var questionFooterRe = regexp.MustCompile(`Enter to select`)
+
+// AtQuestion reports whether a pane capture ends at a multiple-choice question
+func AtQuestion(pane string) bool {
+	lines := strings.Split(strings.TrimRight(pane, "\n "), "\n")
+	if len(lines) > promptTail {
+		lines = lines[len(lines)-promptTail:]
+	}
+	for _, line := range lines {
+		if questionFooterRe.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Waiting returns what a pane capture shows the agent waiting on a human
+// for: PermissionPrompt, Question, or "" if it isn't waiting
+func Waiting(pane string) Kind {
+	switch {
+	case AtPermissionPrompt(pane):
+		return PermissionPrompt
+	case AtQuestion(pane):
+		return Question
+	}
+	return ""
+}
+
+// IsLooping reports whether the current turn of a pane capture (the lines
+// after the last input, which start with ">") contains a block of lines
+// repeated several times in a row
+func IsLooping(pane string) bool {
+	lines := lines(pane, true)
+	start := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, ">") && strings.TrimSpace(strings.TrimPrefix(line, ">")) != "" {
+			start = i + 1
+		}
+	}
+	turn := lines[start:]
+
+	for period := 1; period <= loopPeriod; period++ {
+		repeats := loopRepeats
+		if period == 1 {
+			repeats = 2 * loopRepeats
+		}
+		run := 0 // lines matching the line one period earlier, in a row
+		for i := period; i < len(turn); i++ {
+			if turn[i] == turn[i-period] && !strings.HasPrefix(turn[i], ">") {
+				run++
+				if run >= period*(repeats-1) {
+					return true
+				}
+			} else {
+				run = 0
+			}
+		}
+	}
+	return false
+}
+
+// Classify returns how an agent is stuck, given a capture of its pane and how
+// long the pane has gone without new output, or "" if it looks healthy
+func Classify(pane string, unchanged time.Duration, cfg state.ZombieConfig) Kind {
+	if AtPermissionPrompt(pane) && unchanged >= PromptMinutes*time.Minute {
+		return PermissionPrompt
+	}
+	if IsLooping(pane) {
+		return Looping
+	}
+	if unchanged >= StallAfter(cfg) {
+		return Stalled
+	}
+	return ""
+}
+
+// Tracker remembers each agent's pane between health checks, so the daemon
+// can tell how long it has gone without output and remediate each episode
+// once. If a remediation doesn't help, the agent is escalated to a human after
+// another stall period.
+type Tracker struct {
+	mu     sync.Mutex
+	agents map[string]*agentState
+	// waiting is what each agent was waiting on at its last capture
+	waiting map[string]Kind
+}
+
+type agentState struct {
+	fingerprint [sha256.Size]byte
+	changed     time.Time
+	kind        Kind      // The current episode, "" when healthy
+	handled     time.Time // When the episode was last remediated
+	escalated   bool
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{agents: make(map[string]*agentState), waiting: make(map[string]Kind)}
+}
+
+// Observe records a capture of an agent's pane. It returns the agent's zombie
+// kind ("" if healthy) and the remediation to apply now ("" if none is due).
+// key identifies the agent, e.g. "repo/agent".
+func (t *Tracker) Observe(key, pane string, now time.Time, cfg state.ZombieConfig) (Kind, state.ZombieAction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a := t.record(key, pane, now)
+	kind := Classify(pane, now.Sub(a.changed), cfg)
+	if kind != a.kind {
+		// A new episode, or the agent recovered
+		a.kind = kind
+		a.handled = time.Time{}
+		a.escalated = false
+	}
+	if kind == "" {
+		return "", ""
+	}
+
+	action := Action(cfg, kind)
+	switch {
+	case action == state.ZombieActionIgnore || a.escalated:
+		return kind, ""
+	case a.handled.IsZero():
+		a.handled = now
+		a.escalated = action == state.ZombieActionEscalate
+		return kind, action
+	case now.Sub(a.handled) >= StallAfter(cfg):
+		// The remediation didn't help
+		a.escalated = true
+		return kind, state.ZombieActionEscalate
+	}
+	return kind, ""
+}
+
+// Record notes a capture of an agent's pane without classifying it, so
+// LastOutput stays current while zombie detection is off
+func (t *Tracker) Record(key, pane string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record(key, pane, now)
+}
+
+// record updates an agent's fingerprint; t.mu must be held
+func (t *Tracker) record(key, pane string, now time.Time) *agentState {
+	fingerprint := sha256.Sum256([]byte(strings.Join(Lines(pane), "\n")))
+	a, ok := t.agents[key]
+	if !ok {
+		a = &agentState{fingerprint: fingerprint, changed: now}
+		t.agents[key] = a
+	} else if a.fingerprint != fingerprint {
+		a.fingerprint = fingerprint
+		a.changed = now
+	}
+	return a
+}
+
+// LastOutput returns when an agent's pane last changed, as of its latest
+// capture. ok is false if the agent hasn't been captured yet.
+func (t *Tracker) LastOutput(key string) (at time.Time, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a, ok := t.agents[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	return a.changed, true
+}
+
+// WaitingOnHuman records what a capture of an agent's pane shows it waiting
+// on (see Waiting). started is true when the wait is new since the previous
+// capture, so each wait is announced once however long it lasts.
+func (t *Tracker) WaitingOnHuman(key, pane string) (kind Kind, started bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kind = Waiting(pane)
+	started = kind != "" && t.waiting[key] != kind
+	if kind == "" {
+		delete(t.waiting, key)
+	} else {
+		t.waiting[key] = kind
+	}
+	return kind, started
+}
+
+// Forget drops an agent's history, e.g. after it was restarted or removed
+func (t *Tracker) Forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.agents, key)
+	delete(t.waiting, key)
+}
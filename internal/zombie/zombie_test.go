@@ -0,0 +1,233 @@
+package zombie
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/state"
+)
+
+const idlePane = `> Fix the login timeout
+
+⏺ I'll look at the auth handler first.
+
+⏺ Read(internal/auth/handler.go)
+  ⎿  Read 120 lines
+
+⏺ The timeout is hardcoded to 5s. Raising it and adding a test.
+
+╭──────────────────────────────────────────╮
+│ >                                        │
+╰──────────────────────────────────────────╯
+  ? for shortcuts
+`
+
+const promptPane = `⏺ Update(internal/auth/handler.go)
+
+╭──────────────────────────────────────────╮
+│ Edit file                                │
+│                                          │
+│ Do you want to make this edit to         │
+│ handler.go?                              │
+│ ❯ 1. Yes                                 │
+│   2. Yes, and don't ask again            │
+│   3. No, and tell Claude what to do      │
+╰──────────────────────────────────────────╯
+`
+
+const questionPane = `⏺ Before adding the cache I need a decision.
+
+ ☐ Storage
+
+Which database should the cache use?
+
+❯ 1. Redis
+     Fast, needs a new service
+  2. Postgres
+     Already deployed
+  3. Type something.
+
+Enter to select · ↑/↓ to navigate · Esc to cancel
+`
+
+// loopPane repeats the same failing tool call within one turn
+var loopPane = "> Run the tests\n" + strings.Repeat(`⏺ Bash(go test ./internal/auth)
+  ⎿  Error: build cache is locked (attempt 1)
+⏺ Let me try that again.
+`, 4) + idlePane[strings.Index(idlePane, "╭"):]
+
+func TestLines(t *testing.T) {
+	lines := Lines("✻ Thinking… (12s · esc to interrupt)\n\n> Status check: Update on your progress?\n│ 📨 Message from supervisor: hi │\n⏺ Done in 3 steps\n")
+	if got := strings.Join(lines, "|"); got != "Thinking… (s · esc to interrupt)|Done in steps" {
+		t.Errorf("Lines() = %q", got)
+	}
+}
+
+func TestAtPermissionPrompt(t *testing.T) {
+	if !AtPermissionPrompt(promptPane) {
+		t.Error("AtPermissionPrompt() = false for a permission prompt")
+	}
+	if AtPermissionPrompt(idlePane) {
+		t.Error("AtPermissionPrompt() = true for an idle pane")
+	}
+	// A prompt that was answered and scrolled up is not pending
+	if AtPermissionPrompt(promptPane + strings.Repeat("⏺ more work\n", promptTail)) {
+		t.Error("AtPermissionPrompt() = true for a prompt scrolled out of the tail")
+	}
+}
+
+func TestIsLooping(t *testing.T) {
+	if !IsLooping(loopPane) {
+		t.Error("IsLooping() = false for a repeated block")
+	}
+	if IsLooping(idlePane) {
+		t.Error("IsLooping() = true for an idle pane")
+	}
+
+	// The same answer to each status check is not a loop
+	var turns strings.Builder
+	for i := 0; i < 5; i++ {
+		turns.WriteString("> Status check: Review open PRs and check CI status.\n⏺ Bash(gh pr list)\n  ⎿  []\n⏺ No open PRs.\n")
+	}
+	if IsLooping(turns.String()) {
+		t.Error("IsLooping() = true for the same answer in separate turns")
+	}
+
+	// A few identical lines in a row are fine, many are not
+	if IsLooping("> go\n" + strings.Repeat("PASS\n", 3)) {
+		t.Error("IsLooping() = true for 3 identical lines")
+	}
+	if !IsLooping("> go\n" + strings.Repeat("⏺ Retrying request\n", 6)) {
+		t.Error("IsLooping() = false for 6 identical lines")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cfg := state.ZombieConfig{StallMinutes: 60}
+	tests := []struct {
+		name      string
+		pane      string
+		unchanged time.Duration
+		want      Kind
+	}{
+		{"active", idlePane, time.Minute, ""},
+		{"stalled", idlePane, 61 * time.Minute, Stalled},
+		{"new prompt", promptPane, time.Minute, ""},
+		{"waiting at prompt", promptPane, 6 * time.Minute, PermissionPrompt},
+		{"looping", loopPane, 0, Looping},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.pane, tt.unchanged, cfg); got != tt.want {
+			t.Errorf("%s: Classify() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAction(t *testing.T) {
+	var defaults state.ZombieConfig
+	if Action(defaults, Stalled) != state.ZombieActionNudge || Action(defaults, Looping) != state.ZombieActionRestart || Action(defaults, PermissionPrompt) != state.ZombieActionEscalate {
+		t.Error("unexpected default actions")
+	}
+	if got := Action(state.ZombieConfig{Looping: state.ZombieActionIgnore}, Looping); got != state.ZombieActionIgnore {
+		t.Errorf("Action() = %q, want the configured action", got)
+	}
+}
+
+func TestWaiting(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		pane string
+		want Kind
+	}{
+		{"permission prompt", promptPane, PermissionPrompt},
+		{"question", questionPane, Question},
+		{"idle", idlePane, ""},
+		{"answered question", questionPane + strings.Repeat("⏺ more work\n", promptTail), ""},
+	} {
+		if got := Waiting(tt.pane); got != tt.want {
+			t.Errorf("%s: Waiting() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerWaitingOnHuman(t *testing.T) {
+	tr := NewTracker()
+	check := func(pane string, wantKind Kind, wantStarted bool) {
+		t.Helper()
+		kind, started := tr.WaitingOnHuman("repo/calm-owl", pane)
+		if kind != wantKind || started != wantStarted {
+			t.Errorf("WaitingOnHuman() = %q, %v; want %q, %v", kind, started, wantKind, wantStarted)
+		}
+	}
+
+	check(idlePane, "", false)
+	check(promptPane, PermissionPrompt, true)
+	check(promptPane, PermissionPrompt, false) // Announced once
+	check(questionPane, Question, true)        // A different wait
+	check(idlePane, "", false)
+	check(questionPane, Question, true) // Asked again
+
+	tr.Forget("repo/calm-owl")
+	check(questionPane, Question, true)
+}
+
+func TestTracker(t *testing.T) {
+	cfg := state.ZombieConfig{StallMinutes: 30}
+	tr := NewTracker()
+	start := time.Now()
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	observe := func(minutes int, pane string, wantKind Kind, wantAction state.ZombieAction) {
+		t.Helper()
+		kind, action := tr.Observe("repo/calm-owl", pane, at(minutes), cfg)
+		if kind != wantKind || action != wantAction {
+			t.Errorf("at %dm: Observe() = %q, %q; want %q, %q", minutes, kind, action, wantKind, wantAction)
+		}
+	}
+
+	observe(0, idlePane, "", "")
+	// Daemon-typed text doesn't count as output
+	observe(10, idlePane+"> Status check: Update on your progress?\n", "", "")
+	observe(30, idlePane, Stalled, state.ZombieActionNudge)
+	observe(40, idlePane, Stalled, "") // Already nudged
+	observe(60, idlePane, Stalled, state.ZombieActionEscalate)
+	observe(120, idlePane, Stalled, "") // Escalated once
+
+	// New output ends the episode
+	observe(122, idlePane+"⏺ Back to work\n", "", "")
+	observe(152, idlePane+"⏺ Back to work\n", Stalled, state.ZombieActionNudge)
+
+	// Forgotten agents start over
+	tr.Forget("repo/calm-owl")
+	observe(200, idlePane, "", "")
+
+	// Ignored kinds are reported but never remediated
+	cfg.Looping = state.ZombieActionIgnore
+	observe(201, loopPane, Looping, "")
+	observe(300, loopPane, Looping, "")
+}
+
+func TestTrackerLastOutput(t *testing.T) {
+	tr := NewTracker()
+	if _, ok := tr.LastOutput("repo/calm-owl"); ok {
+		t.Fatal("LastOutput() before any capture: ok = true")
+	}
+	start := time.Now()
+	tr.Record("repo/calm-owl", idlePane, start)
+	tr.Record("repo/calm-owl", idlePane, start.Add(time.Minute))
+	if at, ok := tr.LastOutput("repo/calm-owl"); !ok || !at.Equal(start) {
+		t.Errorf("LastOutput() = %v, %v; want the first capture, the pane hasn't changed", at, ok)
+	}
+	tr.Record("repo/calm-owl", idlePane+"⏺ Editing\n", start.Add(2*time.Minute))
+	if at, _ := tr.LastOutput("repo/calm-owl"); !at.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("LastOutput() = %v, want the capture with new output", at)
+	}
+}
+
+func TestLinesDropsLocalizedNudges(t *testing.T) {
+	lines := Lines("> 状況確認: 進捗はいかがですか?\n> ヘルスチェック: 同じ出力を繰り返しています。\n⏺ Done\n")
+	if len(lines) != 1 || lines[0] != "Done" {
+		t.Errorf("Lines() = %q, want only the agent's output", lines)
+	}
+}
@@ -0,0 +1,54 @@
+package claude
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ConfigDirEnv points Claude at a config directory other than ~/.claude:
+// its settings, credentials and session history. Separate directories let
+// instances use different accounts or organizations.
+const ConfigDirEnv = "CLAUDE_CONFIG_DIR"
+
+// ProjectsDir returns where Claude keeps session history when run with
+// configDir as its config directory, or with ~/.claude when it's empty
+func ProjectsDir(configDir string) (string, error) {
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configDir = filepath.Join(home, ".claude")
+	}
+	return filepath.Join(configDir, "projects"), nil
+}
+
+// ValidateConfigDir checks that dir can be given to Claude as its config
+// directory: an absolute path to a directory Claude has been logged in with.
+// Claude keeps credentials in .credentials.json there, except on macOS,
+// where they're in the keychain and .claude.json records the login.
+func ValidateConfigDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("claude config directory %q is not an absolute path", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("claude config directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("claude config directory %s is not a directory", dir)
+	}
+
+	credentials := []string{".credentials.json"}
+	if runtime.GOOS == "darwin" {
+		credentials = append(credentials, ".claude.json")
+	}
+	for _, name := range credentials {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no Claude credentials in %s; log in with: %s=%s claude, then /login", dir, ConfigDirEnv, dir)
+}
@@ -0,0 +1,42 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := ValidateConfigDir("relative/dir"); err == nil {
+		t.Error("ValidateConfigDir() accepted a relative path")
+	}
+	if err := ValidateConfigDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("ValidateConfigDir() accepted a missing directory")
+	}
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0644)
+	if err := ValidateConfigDir(file); err == nil {
+		t.Error("ValidateConfigDir() accepted a file")
+	}
+
+	err := ValidateConfigDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "CLAUDE_CONFIG_DIR="+dir+" claude") {
+		t.Errorf("ValidateConfigDir() of a directory without credentials = %v, want how to log in", err)
+	}
+	os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte("{}"), 0600)
+	if err := ValidateConfigDir(dir); err != nil {
+		t.Errorf("ValidateConfigDir() = %v, want nil with credentials", err)
+	}
+}
+
+func TestProjectsDir(t *testing.T) {
+	if got, _ := ProjectsDir("/home/me/.claude-work"); got != "/home/me/.claude-work/projects" {
+		t.Errorf("ProjectsDir() = %q", got)
+	}
+	home, _ := os.UserHomeDir()
+	if got, _ := ProjectsDir(""); got != filepath.Join(home, ".claude", "projects") {
+		t.Errorf("ProjectsDir(\"\") = %q, want ~/.claude/projects", got)
+	}
+}
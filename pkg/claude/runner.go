@@ -40,6 +40,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -203,6 +204,44 @@ type Config struct {
 	// This is useful for showing restart instructions or other information.
 	// If empty, no MOTD is displayed.
 	MOTD string
+
+	// ReadOnly starts the instance with ReadOnlyArgs instead of skipping
+	// permission checks: without the file-editing tools, and with Bash limited
+	// to the commands in ReadOnlyAllowedTools.
+	ReadOnly bool
+
+	// ConfigDir is exported as CLAUDE_CONFIG_DIR, so the instance uses that
+	// directory's settings and credentials: another account or organization.
+	// If empty, Claude uses ~/.claude. See ValidateConfigDir.
+	ConfigDir string
+}
+
+// FileEditTools are the tools that modify files
+var FileEditTools = []string{"Edit", "MultiEdit", "Write", "NotebookEdit"}
+
+// ReadOnlyAllowedTools are the tools a read-only agent may use: reading
+// files, and Bash commands that look at the repository, its PRs and the other
+// agents. Sending messages is the one thing it may change.
+var ReadOnlyAllowedTools = []string{
+	"Read", "Grep", "Glob", "LS",
+	"Bash(git log:*)", "Bash(git show:*)", "Bash(git diff:*)", "Bash(git status:*)", "Bash(git branch:*)",
+	"Bash(gh pr list:*)", "Bash(gh pr view:*)", "Bash(gh pr checks:*)", "Bash(gh run list:*)", "Bash(gh run view:*)",
+	"Bash(multiclaude digest:*)", "Bash(multiclaude history:*)", "Bash(multiclaude status:*)",
+	"Bash(multiclaude worker list:*)", "Bash(multiclaude workspace list:*)",
+	"Bash(multiclaude message list:*)", "Bash(multiclaude message read:*)",
+	"Bash(multiclaude message ack:*)", "Bash(multiclaude message send:*)",
+}
+
+// ReadOnlyArgs are the arguments that start Claude as a read-only agent, in
+// place of --dangerously-skip-permissions. Claude doesn't ask about tools
+// outside ReadOnlyAllowedTools; it refuses them, and the file-editing tools
+// are removed outright.
+func ReadOnlyArgs() []string {
+	return []string{
+		"--permission-mode", "dontAsk",
+		"--allowedTools", strings.Join(ReadOnlyAllowedTools, ","),
+		"--disallowedTools", strings.Join(FileEditTools, ","),
+	}
 }
 
 // StartResult contains information about a started Claude instance.
@@ -297,9 +336,10 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 		cmd = fmt.Sprintf("cd %q && ", cfg.WorkDir)
 	}
 
-	// Note: CLAUDE_CONFIG_DIR and CLAUDE_CODE_OAUTH_TOKEN are not used because
-	// Claude Code only reads credentials from ~/.claude/.credentials.json
-	// regardless of CLAUDE_CONFIG_DIR setting. Slash commands go in ~/.claude/commands/.
+	// Export the config directory for this command only
+	if cfg.ConfigDir != "" {
+		cmd += fmt.Sprintf("%s=%q ", ConfigDirEnv, cfg.ConfigDir)
+	}
 
 	cmd += r.BinaryPath
 
@@ -310,8 +350,12 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 		cmd += fmt.Sprintf(" --session-id %s", sessionID)
 	}
 
-	// Add skip permissions flag
-	if r.SkipPermissions {
+	// Add skip permissions flag, or the read-only restrictions
+	if cfg.ReadOnly {
+		for _, arg := range ReadOnlyArgs() {
+			cmd += fmt.Sprintf(" %q", arg)
+		}
+	} else if r.SkipPermissions {
 		cmd += " --dangerously-skip-permissions"
 	}
 
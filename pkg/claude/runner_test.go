@@ -514,6 +514,33 @@ func TestBuildCommand(t *testing.T) {
 				"/path/to/claude",
 			},
 		},
+		{
+			name: "read-only",
+			config: Config{
+				SessionID: "test-session",
+				ReadOnly:  true,
+			},
+			contains: []string{
+				`"--permission-mode" "dontAsk"`,
+				`"--disallowedTools" "Edit,MultiEdit,Write,NotebookEdit"`,
+				`"Read,Grep,Glob,LS,Bash(git log:*),`,
+			},
+			excludes: []string{
+				"--dangerously-skip-permissions",
+				`"Bash"`,
+				"Bash,",
+			},
+		},
+		{
+			name: "not read-only",
+			config: Config{
+				SessionID: "test-session",
+			},
+			excludes: []string{
+				"--disallowedTools",
+				"--allowedTools",
+			},
+		},
 		{
 			name: "with workdir excludes CLAUDE_CONFIG_DIR",
 			config: Config{
@@ -528,6 +555,17 @@ func TestBuildCommand(t *testing.T) {
 				"CLAUDE_CONFIG_DIR",
 			},
 		},
+		{
+			name: "with config dir",
+			config: Config{
+				SessionID: "test-session-id",
+				WorkDir:   "/path/to/workdir",
+				ConfigDir: "/home/me/.claude-work",
+			},
+			contains: []string{
+				"cd \"/path/to/workdir\" && CLAUDE_CONFIG_DIR=\"/home/me/.claude-work\" /path/to/claude --session-id",
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,118 @@
+package claude
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FindProcess returns the PID and command line of the first Claude process at
+// or below rootPID in the process tree, e.g. under a tmux pane's shell. It
+// reads the process table with ps, so it works on Linux and macOS.
+func FindProcess(rootPID int) (int, []string, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "args=").Output()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	pid, args := findProcess(string(output), rootPID)
+	if pid == 0 {
+		return 0, nil, fmt.Errorf("no Claude process found under PID %d", rootPID)
+	}
+	return pid, args, nil
+}
+
+// findProcess searches a process table (pid, ppid and args per line)
+// breadth-first from rootPID for a Claude process
+func findProcess(table string, rootPID int) (int, []string) {
+	children := make(map[int][]int)
+	commands := make(map[int][]string)
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], pid)
+		commands[pid] = fields[2:]
+	}
+
+	queue := []int{rootPID}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if isClaudeCommand(commands[pid]) {
+			return pid, commands[pid]
+		}
+		queue = append(queue, children[pid]...)
+	}
+	return 0, nil
+}
+
+// isClaudeCommand reports whether a command line runs Claude Code: the claude
+// binary itself, or node running the claude-code package
+func isClaudeCommand(args []string) bool {
+	for i, arg := range args {
+		if i > 1 {
+			break
+		}
+		if filepath.Base(arg) == "claude" || strings.Contains(arg, "claude-code") {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionIDFromArgs returns the session ID from a Claude command line
+// (--session-id, --resume or -r), or "" if it has none
+func SessionIDFromArgs(args []string) string {
+	for i, arg := range args {
+		for _, flag := range []string{"--session-id", "--resume", "-r"} {
+			if arg == flag && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				return args[i+1]
+			}
+			if id, ok := strings.CutPrefix(arg, flag+"="); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// projectDirRe matches the characters Claude replaces with "-" when naming a
+// working directory's folder under ~/.claude/projects
+var projectDirRe = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// LatestSessionID returns the ID of the session Claude most recently wrote for
+// a working directory, looking in projectsDir (usually ~/.claude/projects), or
+// "" if there is none. It identifies sessions started without --session-id.
+func LatestSessionID(projectsDir, workDir string) string {
+	dir := filepath.Join(projectsDir, projectDirRe.ReplaceAllString(workDir, "-"))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var latest string
+	var latestMod time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestMod) {
+			latest, latestMod = strings.TrimSuffix(entry.Name(), ".jsonl"), info.ModTime()
+		}
+	}
+	return latest
+}
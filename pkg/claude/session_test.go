@@ -0,0 +1,68 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindProcess(t *testing.T) {
+	table := `    1     0 /sbin/init
+  100     1 tmux new-session -d -s work
+  200   100 -bash
+  300   200 node /usr/lib/node_modules/@anthropic-ai/claude-code/cli.js --session-id 1234-abcd --dangerously-skip-permissions
+  201   100 -zsh
+  301   201 vim notes.md
+`
+	pid, args := findProcess(table, 200)
+	if pid != 300 || SessionIDFromArgs(args) != "1234-abcd" {
+		t.Errorf("findProcess(200) = %d, %v; want the node claude-code process", pid, args)
+	}
+	if pid, _ := findProcess(table, 201); pid != 0 {
+		t.Errorf("findProcess(201) = %d, want none under a pane running vim", pid)
+	}
+}
+
+func TestSessionIDFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"/usr/local/bin/claude", "--session-id", "abc"}, "abc"},
+		{[]string{"claude", "--resume=def"}, "def"},
+		{[]string{"claude", "--dangerously-skip-permissions", "-r", "ghi"}, "ghi"},
+		{[]string{"claude", "--resume"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := SessionIDFromArgs(tt.args); got != tt.want {
+			t.Errorf("SessionIDFromArgs(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestLatestSessionID(t *testing.T) {
+	projects := t.TempDir()
+	dir := filepath.Join(projects, "-home-me-my-app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"old.jsonl": time.Hour, "new.jsonl": 0, "notes.txt": -time.Hour} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := LatestSessionID(projects, "/home/me/my.app"); got != "new" {
+		t.Errorf("LatestSessionID() = %q, want the newest session", got)
+	}
+	if got := LatestSessionID(projects, "/elsewhere"); got != "" {
+		t.Errorf("LatestSessionID() = %q for a directory without sessions", got)
+	}
+}
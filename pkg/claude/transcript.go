@@ -0,0 +1,115 @@
+package claude
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SessionFile returns the transcript Claude writes for a session started in
+// workDir, under projectsDir (usually ~/.claude/projects)
+func SessionFile(projectsDir, workDir, sessionID string) string {
+	return filepath.Join(projectsDir, projectDirRe.ReplaceAllString(workDir, "-"), sessionID+".jsonl")
+}
+
+// TranscriptEntry is one turn of a Claude session: something the user (or
+// multiclaude, or a tool result) said, or the assistant's reply
+type TranscriptEntry struct {
+	Time time.Time `json:"time"`
+	Role string    `json:"role"`
+	Text string    `json:"text"`
+}
+
+// maxToolResult caps how much of each tool result a transcript keeps
+const maxToolResult = 2000
+
+// sessionLine is the part of a session file line ReadTranscript uses
+type sessionLine struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// contentBlock is one block of a message's content
+type contentBlock struct {
+	Type    string          `json:"type"`
+	Text    string          `json:"text"`
+	Name    string          `json:"name"`
+	Input   json.RawMessage `json:"input"`
+	Content json.RawMessage `json:"content"`
+}
+
+// ReadTranscript reads the user and assistant turns from a Claude session
+// file. Tool calls are shown as "[tool: Name] input" and tool results are
+// shortened. Lines it doesn't understand (summaries, metadata) are skipped.
+func ReadTranscript(path string) ([]TranscriptEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []TranscriptEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line sessionLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Type != "user" && line.Type != "assistant" {
+			continue
+		}
+		role := line.Message.Role
+		if role == "" {
+			role = line.Type
+		}
+		if text := contentText(line.Message.Content); text != "" {
+			entries = append(entries, TranscriptEntry{Time: line.Timestamp, Role: role, Text: text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// contentText flattens message content, which is either a string or a list
+// of blocks, to text
+func contentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, strings.TrimSpace(b.Text))
+		case "tool_use":
+			parts = append(parts, fmt.Sprintf("[tool: %s] %s", b.Name, string(b.Input)))
+		case "tool_result":
+			result := contentText(b.Content)
+			if len(result) > maxToolResult {
+				result = result[:maxToolResult] + fmt.Sprintf("\n[... %d more bytes]", len(result)-maxToolResult)
+			}
+			parts = append(parts, "[tool result] "+result)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
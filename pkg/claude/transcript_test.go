@@ -0,0 +1,50 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionFile(t *testing.T) {
+	got := SessionFile("/home/me/.claude/projects", "/home/me/.multiclaude/wts/app/calm-owl", "1234-abcd")
+	want := "/home/me/.claude/projects/-home-me--multiclaude-wts-app-calm-owl/1234-abcd.jsonl"
+	if got != want {
+		t.Errorf("SessionFile() = %q, want %q", got, want)
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	session := `{"type":"summary","summary":"Fix the flaky test"}
+{"type":"user","timestamp":"2026-10-16T12:00:00Z","message":{"role":"user","content":"Task: fix the flaky test"}}
+{"type":"assistant","timestamp":"2026-10-16T12:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Looking at it."},{"type":"tool_use","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","timestamp":"2026-10-16T12:00:09Z","message":{"role":"user","content":[{"type":"tool_result","content":"` + strings.Repeat("x", 3000) + `"}]}}
+not json
+`
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte(session), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadTranscript(path)
+	if err != nil {
+		t.Fatalf("ReadTranscript() failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("ReadTranscript() = %d entries, want 3: %+v", len(entries), entries)
+	}
+	if entries[0].Role != "user" || entries[0].Text != "Task: fix the flaky test" || entries[0].Time.IsZero() {
+		t.Errorf("entries[0] = %+v", entries[0])
+	}
+	if entries[1].Text != "Looking at it.\n[tool: Bash] {\"command\":\"go test ./...\"}" {
+		t.Errorf("entries[1].Text = %q", entries[1].Text)
+	}
+	if !strings.HasPrefix(entries[2].Text, "[tool result] xxx") || !strings.HasSuffix(entries[2].Text, "[... 1000 more bytes]") {
+		t.Errorf("tool result was not shortened: %q...", entries[2].Text[:40])
+	}
+
+	if _, err := ReadTranscript(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("ReadTranscript() of a missing file succeeded")
+	}
+}
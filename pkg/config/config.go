@@ -3,13 +3,36 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
+// Layout names how multiclaude's files are arranged on disk
+type Layout string
+
+const (
+	// LayoutLegacy keeps everything under ~/.multiclaude
+	LayoutLegacy Layout = "legacy"
+	// LayoutXDG follows the XDG base directory spec: state and logs in
+	// $XDG_STATE_HOME/multiclaude, clones and worktrees in
+	// $XDG_DATA_HOME/multiclaude and the socket in $XDG_RUNTIME_DIR/multiclaude
+	LayoutXDG Layout = "xdg"
+	// LayoutCustom keeps everything under $MULTICLAUDE_HOME
+	LayoutCustom Layout = "custom"
+)
+
+// HomeEnv overrides the layout, keeping everything in the directory it names
+const HomeEnv = "MULTICLAUDE_HOME"
+
+// LayoutEnv lists the environment variables that decide where multiclaude's
+// files are, which a daemon started by a service manager must see too
+var LayoutEnv = []string{HomeEnv, "XDG_STATE_HOME", "XDG_DATA_HOME", "XDG_RUNTIME_DIR"}
+
 // Paths holds all the directory and file paths used by multiclaude
 type Paths struct {
-	Root            string // $HOME/.multiclaude/
+	Layout          Layout // How the paths were chosen; empty for paths built by hand
+	Root            string // $HOME/.multiclaude/ or $XDG_STATE_HOME/multiclaude/
 	DaemonPID       string // daemon.pid
 	DaemonSock      string // daemon.sock
 	DaemonLog       string // daemon.log
@@ -19,17 +42,69 @@ type Paths struct {
 	MessagesDir     string // messages/
 	OutputDir       string // output/
 	ClaudeConfigDir string // claude-config/
+
+	// WorktreeBase, if set, returns the worktree base directory configured
+	// for a repository, or "" to keep its worktrees under WorktreesDir
+	WorktreeBase func(repoName string) string
 }
 
-// DefaultPaths returns the default paths for multiclaude
+// DefaultPaths returns the default paths for multiclaude: everything under
+// $MULTICLAUDE_HOME if it is set, the legacy ~/.multiclaude if it exists, and
+// XDG base directories otherwise
 func DefaultPaths() (*Paths, error) {
+	if root := os.Getenv(HomeEnv); root != "" {
+		paths := RootPaths(root)
+		paths.Layout = LayoutCustom
+		return paths, nil
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
+	if legacy := LegacyPaths(home); exists(legacy.Root) {
+		return legacy, nil
+	}
+	return XDGPaths(home), nil
+}
+
+// LegacyPaths returns the paths of the legacy layout, under ~/.multiclaude
+func LegacyPaths(home string) *Paths {
+	paths := RootPaths(filepath.Join(home, ".multiclaude"))
+	paths.Layout = LayoutLegacy
+	return paths
+}
+
+// XDGPaths returns the paths of the XDG layout. The socket and PID file stay
+// with the state when XDG_RUNTIME_DIR isn't set.
+func XDGPaths(home string) *Paths {
+	stateDir := filepath.Join(xdgDir("XDG_STATE_HOME", home, ".local", "state"), "multiclaude")
+	dataDir := filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local", "share"), "multiclaude")
+	runtimeDir := stateDir
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		runtimeDir = filepath.Join(dir, "multiclaude")
+	}
 
-	root := filepath.Join(home, ".multiclaude")
+	paths := RootPaths(stateDir)
+	paths.Layout = LayoutXDG
+	paths.DaemonPID = filepath.Join(runtimeDir, "daemon.pid")
+	paths.DaemonSock = filepath.Join(runtimeDir, "daemon.sock")
+	paths.ReposDir = filepath.Join(dataDir, "repos")
+	paths.WorktreesDir = filepath.Join(dataDir, "wts")
+	return paths
+}
 
+// xdgDir returns an XDG base directory, or its default under home when the
+// variable is unset or, as the spec requires, not an absolute path
+func xdgDir(env, home string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
+
+// RootPaths returns paths with everything under root
+func RootPaths(root string) *Paths {
 	return &Paths{
 		Root:            root,
 		DaemonPID:       filepath.Join(root, "daemon.pid"),
@@ -41,13 +116,40 @@ func DefaultPaths() (*Paths, error) {
 		MessagesDir:     filepath.Join(root, "messages"),
 		OutputDir:       filepath.Join(root, "output"),
 		ClaudeConfigDir: filepath.Join(root, "claude-config"),
-	}, nil
+	}
+}
+
+// Env returns the layout environment under which DefaultPaths finds these
+// paths, for processes multiclaude starts that must use the same files, such
+// as the agent simulator. Every variable in LayoutEnv is set, some to "", so
+// the caller's own settings don't interfere. Paths built by hand are taken to
+// be under Root.
+func (p *Paths) Env() []string {
+	if p.Layout != LayoutXDG {
+		return []string{HomeEnv + "=" + p.Root, "XDG_STATE_HOME=", "XDG_DATA_HOME=", "XDG_RUNTIME_DIR="}
+	}
+	runtimeDir := ""
+	if dir := filepath.Dir(p.DaemonSock); dir != p.Root {
+		runtimeDir = filepath.Dir(dir)
+	}
+	return []string{
+		HomeEnv + "=",
+		"XDG_STATE_HOME=" + filepath.Dir(p.Root),
+		"XDG_DATA_HOME=" + filepath.Dir(filepath.Dir(p.ReposDir)),
+		"XDG_RUNTIME_DIR=" + runtimeDir,
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
 // EnsureDirectories creates all necessary directories if they don't exist
 func (p *Paths) EnsureDirectories() error {
 	dirs := []string{
 		p.Root,
+		filepath.Dir(p.DaemonSock),
 		p.ReposDir,
 		p.WorktreesDir,
 		p.MessagesDir,
@@ -69,14 +171,167 @@ func (p *Paths) RepoDir(repoName string) string {
 	return filepath.Join(p.ReposDir, repoName)
 }
 
+// ProjectDir returns the working directory for a project's supervisor
+func (p *Paths) ProjectDir(projectName string) string {
+	return filepath.Join(p.Root, "projects", projectName)
+}
+
+// SnapshotsDir returns the directory holding timestamped state snapshots
+func (p *Paths) SnapshotsDir() string {
+	return filepath.Join(p.Root, "snapshots")
+}
+
+// DebugDir returns the directory `multiclaude daemon debug --pprof` writes
+// profiles to
+func (p *Paths) DebugDir() string {
+	return filepath.Join(p.Root, "debug")
+}
+
+// FederationFile returns the file holding a repository's federation outbox and
+// receive cursors
+func (p *Paths) FederationFile(repoName string) string {
+	return filepath.Join(p.Root, "federation", repoName+".json")
+}
+
+// CommentsFile returns the file holding how far the daemon has read a
+// repository's GitHub comments for commands
+func (p *Paths) CommentsFile(repoName string) string {
+	return filepath.Join(p.Root, "comments", repoName+".json")
+}
+
+// XrefFile returns the file holding a repository's cross-reference index of
+// PRs, workers, task history entries and messages
+func (p *Paths) XrefFile(repoName string) string {
+	return filepath.Join(p.Root, "xref", repoName+".json")
+}
+
+// CLIDocsFile returns the generated CLI reference that a repository's agent
+// prompts point to
+func (p *Paths) CLIDocsFile(repoName string) string {
+	return filepath.Join(p.Root, "docs", repoName, "CLI.md")
+}
+
+// PromptFragmentsDir returns the directory of an agent's prompt fragments:
+// the include files holding the parts of its prompt generated from config
+func (p *Paths) PromptFragmentsDir(agentName string) string {
+	return filepath.Join(p.Root, "prompts", agentName+".d")
+}
+
+// RosterFile returns the list of a repository's agents that the daemon keeps
+// current for agent prompts to point to
+func (p *Paths) RosterFile(repoName string) string {
+	return filepath.Join(p.Root, "docs", repoName, "ROSTER.md")
+}
+
+// RedactFile returns the file of extra secret patterns scrubbed from the
+// daemon log, bug reports and exports
+func (p *Paths) RedactFile() string {
+	return filepath.Join(p.Root, "redact.txt")
+}
+
+// DaemonConfigFile returns the daemon's settings file, reread on SIGHUP
+func (p *Paths) DaemonConfigFile() string {
+	return filepath.Join(p.Root, "daemon.yaml")
+}
+
+// MemoryDir returns the directory holding agents' memory files
+func (p *Paths) MemoryDir() string {
+	return filepath.Join(p.Root, "memory")
+}
+
+// MemoryFile returns the file holding an agent's persistent notes
+func (p *Paths) MemoryFile(repoName, agentName string) string {
+	return filepath.Join(p.MemoryDir(), repoName, agentName+".md")
+}
+
+// GRPCSock returns the Unix socket the daemon serves its gRPC API on, next to
+// the daemon socket
+func (p *Paths) GRPCSock() string {
+	return filepath.Join(filepath.Dir(p.DaemonSock), "daemon-grpc.sock")
+}
+
+// GRPCTokenFile returns the file holding the token gRPC calls must send when
+// the API listens on TCP
+func (p *Paths) GRPCTokenFile() string {
+	return filepath.Join(p.Root, "grpc-token")
+}
+
+// TrashDir returns the directory holding tombstones of removed workers
+func (p *Paths) TrashDir() string {
+	return filepath.Join(p.Root, "trash")
+}
+
+// RepoTrashDir returns the directory holding tombstones of a repository's
+// removed workers
+func (p *Paths) RepoTrashDir(repoName string) string {
+	return filepath.Join(p.TrashDir(), repoName)
+}
+
+// TicketsFile returns the file holding open ask/answer tickets
+func (p *Paths) TicketsFile() string {
+	return filepath.Join(p.Root, "tickets.json")
+}
+
+// TelemetryFile returns the file of opt-in command usage records
+func (p *Paths) TelemetryFile() string {
+	return filepath.Join(p.Root, "telemetry.jsonl")
+}
+
+// TasksFile returns the file holding queued tasks and their dependencies
+func (p *Paths) TasksFile() string {
+	return filepath.Join(p.Root, "tasks.json")
+}
+
+// TrainsDir returns the directory holding the repositories' merge trains
+func (p *Paths) TrainsDir() string {
+	return filepath.Join(p.Root, "trains")
+}
+
+// TrainFile returns the file holding a repository's merge train
+func (p *Paths) TrainFile(repoName string) string {
+	return filepath.Join(p.TrainsDir(), repoName+".json")
+}
+
+// TrainLogFile returns the log of a PR's latest merge train simulation
+func (p *Paths) TrainLogFile(repoName string, pr int) string {
+	return filepath.Join(p.TrainsDir(), repoName, fmt.Sprintf("pr-%d.log", pr))
+}
+
+// MirrorsDir returns the directory holding the repositories' mirror clones
+func (p *Paths) MirrorsDir() string {
+	return filepath.Join(p.Root, "mirrors")
+}
+
+// MirrorDir returns the bare mirror clone of a repository
+func (p *Paths) MirrorDir(repoName string) string {
+	return filepath.Join(p.MirrorsDir(), repoName+".git")
+}
+
+// MirrorWorktreeDir returns the directory holding the worktrees read-only
+// agents check out from a repository's mirror
+func (p *Paths) MirrorWorktreeDir(repoName string) string {
+	return filepath.Join(p.MirrorsDir(), repoName)
+}
+
+// MirrorWorktree returns a read-only agent's worktree of a repository's mirror
+func (p *Paths) MirrorWorktree(repoName, agentName string) string {
+	return filepath.Join(p.MirrorWorktreeDir(repoName), agentName)
+}
+
 // RepoAgentsDir returns the path for a repository's agent definitions
 // These are the per-repo agent templates that define configurable agents
 func (p *Paths) RepoAgentsDir(repoName string) string {
 	return filepath.Join(p.ReposDir, repoName, "agents")
 }
 
-// WorktreeDir returns the path for a repository's worktrees
+// WorktreeDir returns the path for a repository's worktrees, under the
+// repository's worktree base directory if one is configured
 func (p *Paths) WorktreeDir(repoName string) string {
+	if p.WorktreeBase != nil {
+		if base := p.WorktreeBase(repoName); base != "" {
+			return filepath.Join(base, repoName)
+		}
+	}
 	return filepath.Join(p.WorktreesDir, repoName)
 }
 
@@ -105,6 +360,12 @@ func (p *Paths) WorkersOutputDir(repoName string) string {
 	return filepath.Join(p.RepoOutputDir(repoName), "workers")
 }
 
+// ArchivedOutputDir returns the path a repository's logs are moved to when
+// their agents are removed
+func (p *Paths) ArchivedOutputDir(repoName string) string {
+	return filepath.Join(p.RepoOutputDir(repoName), "archive")
+}
+
 // AgentLogFile returns the path to an agent's log file
 func (p *Paths) AgentLogFile(repoName, agentName string, isWorker bool) string {
 	if isWorker {
@@ -127,16 +388,5 @@ func (p *Paths) AgentCommandsDir(repoName, agentName string) string {
 // NewTestPaths creates a Paths instance for testing with all paths under tmpDir.
 // This eliminates duplicate test setup code and ensures consistent path configuration.
 func NewTestPaths(tmpDir string) *Paths {
-	return &Paths{
-		Root:            tmpDir,
-		DaemonPID:       filepath.Join(tmpDir, "daemon.pid"),
-		DaemonSock:      filepath.Join(tmpDir, "daemon.sock"),
-		DaemonLog:       filepath.Join(tmpDir, "daemon.log"),
-		StateFile:       filepath.Join(tmpDir, "state.json"),
-		ReposDir:        filepath.Join(tmpDir, "repos"),
-		WorktreesDir:    filepath.Join(tmpDir, "wts"),
-		MessagesDir:     filepath.Join(tmpDir, "messages"),
-		OutputDir:       filepath.Join(tmpDir, "output"),
-		ClaudeConfigDir: filepath.Join(tmpDir, "claude-config"),
-	}
+	return RootPaths(tmpDir)
 }
@@ -7,46 +7,134 @@ import (
 	"testing"
 )
 
+// setLayoutEnv points the layout environment at a fresh home directory
+func setLayoutEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range LayoutEnv {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
 func TestDefaultPaths(t *testing.T) {
+	home := setLayoutEnv(t)
+
+	// Without ~/.multiclaude, XDG defaults
 	paths, err := DefaultPaths()
 	if err != nil {
 		t.Fatalf("DefaultPaths() failed: %v", err)
 	}
+	if paths.Layout != LayoutXDG {
+		t.Errorf("Layout = %q, want %q", paths.Layout, LayoutXDG)
+	}
+	stateDir := filepath.Join(home, ".local", "state", "multiclaude")
+	dataDir := filepath.Join(home, ".local", "share", "multiclaude")
+	for name, got := range map[string][2]string{
+		"Root":         {paths.Root, stateDir},
+		"StateFile":    {paths.StateFile, filepath.Join(stateDir, "state.json")},
+		"DaemonLog":    {paths.DaemonLog, filepath.Join(stateDir, "daemon.log")},
+		"DaemonSock":   {paths.DaemonSock, filepath.Join(stateDir, "daemon.sock")},
+		"ReposDir":     {paths.ReposDir, filepath.Join(dataDir, "repos")},
+		"WorktreesDir": {paths.WorktreesDir, filepath.Join(dataDir, "wts")},
+		"MessagesDir":  {paths.MessagesDir, filepath.Join(stateDir, "messages")},
+	} {
+		if got[0] != got[1] {
+			t.Errorf("%s = %q, want %q", name, got[0], got[1])
+		}
+	}
 
-	home, err := os.UserHomeDir()
+	// An existing ~/.multiclaude keeps the legacy layout
+	legacyRoot := filepath.Join(home, ".multiclaude")
+	if err := os.MkdirAll(legacyRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	paths, err = DefaultPaths()
 	if err != nil {
-		t.Fatalf("UserHomeDir() failed: %v", err)
+		t.Fatalf("DefaultPaths() failed: %v", err)
 	}
-
-	expected := filepath.Join(home, ".multiclaude")
-	if paths.Root != expected {
-		t.Errorf("Root = %q, want %q", paths.Root, expected)
+	if paths.Layout != LayoutLegacy || paths.Root != legacyRoot {
+		t.Errorf("DefaultPaths() with ~/.multiclaude = %q at %q, want legacy at %q", paths.Layout, paths.Root, legacyRoot)
 	}
-
-	// Test that all paths are under the root
-	if !strings.HasPrefix(paths.DaemonPID, paths.Root) {
-		t.Errorf("DaemonPID not under Root: %s", paths.DaemonPID)
+	for _, path := range []string{paths.DaemonPID, paths.DaemonSock, paths.DaemonLog, paths.StateFile, paths.ReposDir, paths.WorktreesDir, paths.MessagesDir, paths.ClaudeConfigDir} {
+		if !strings.HasPrefix(path, paths.Root) {
+			t.Errorf("%s not under Root %s", path, paths.Root)
+		}
 	}
-	if !strings.HasPrefix(paths.DaemonSock, paths.Root) {
-		t.Errorf("DaemonSock not under Root: %s", paths.DaemonSock)
+
+	// MULTICLAUDE_HOME wins over both
+	custom := filepath.Join(home, "mc")
+	t.Setenv(HomeEnv, custom)
+	paths, err = DefaultPaths()
+	if err != nil {
+		t.Fatalf("DefaultPaths() failed: %v", err)
 	}
-	if !strings.HasPrefix(paths.DaemonLog, paths.Root) {
-		t.Errorf("DaemonLog not under Root: %s", paths.DaemonLog)
+	if paths.Layout != LayoutCustom || paths.Root != custom || paths.ReposDir != filepath.Join(custom, "repos") {
+		t.Errorf("DefaultPaths() with %s = %+v", HomeEnv, paths)
 	}
-	if !strings.HasPrefix(paths.StateFile, paths.Root) {
-		t.Errorf("StateFile not under Root: %s", paths.StateFile)
+}
+
+func TestXDGPaths(t *testing.T) {
+	home := setLayoutEnv(t)
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	t.Setenv("XDG_DATA_HOME", "relative/data") // ignored, as the spec says
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	paths := XDGPaths(home)
+	if paths.Root != "/xdg/state/multiclaude" {
+		t.Errorf("Root = %q", paths.Root)
 	}
-	if !strings.HasPrefix(paths.ReposDir, paths.Root) {
-		t.Errorf("ReposDir not under Root: %s", paths.ReposDir)
+	if paths.ReposDir != filepath.Join(home, ".local", "share", "multiclaude", "repos") {
+		t.Errorf("ReposDir = %q", paths.ReposDir)
 	}
-	if !strings.HasPrefix(paths.WorktreesDir, paths.Root) {
-		t.Errorf("WorktreesDir not under Root: %s", paths.WorktreesDir)
+	if paths.DaemonSock != "/run/user/1000/multiclaude/daemon.sock" || paths.DaemonPID != "/run/user/1000/multiclaude/daemon.pid" {
+		t.Errorf("DaemonSock = %q, DaemonPID = %q", paths.DaemonSock, paths.DaemonPID)
 	}
-	if !strings.HasPrefix(paths.MessagesDir, paths.Root) {
-		t.Errorf("MessagesDir not under Root: %s", paths.MessagesDir)
+	if paths.GRPCSock() != "/run/user/1000/multiclaude/daemon-grpc.sock" {
+		t.Errorf("GRPCSock() = %q", paths.GRPCSock())
 	}
-	if !strings.HasPrefix(paths.ClaudeConfigDir, paths.Root) {
-		t.Errorf("ClaudeConfigDir not under Root: %s", paths.ClaudeConfigDir)
+}
+
+func TestPathsEnv(t *testing.T) {
+	home := setLayoutEnv(t)
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	xdg := XDGPaths(home)
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	xdgRuntime := XDGPaths(home)
+
+	for name, want := range map[string]*Paths{
+		"xdg":             xdg,
+		"xdg runtime dir": xdgRuntime,
+		"legacy":          LegacyPaths(home),
+		"custom":          RootPaths("/srv/multiclaude"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Whatever the environment was, Env() sets it to find the paths
+			t.Setenv(HomeEnv, "/elsewhere")
+			t.Setenv("XDG_DATA_HOME", "/elsewhere/data")
+			t.Setenv("XDG_RUNTIME_DIR", "/elsewhere/run")
+			for _, assignment := range want.Env() {
+				key, value, _ := strings.Cut(assignment, "=")
+				t.Setenv(key, value)
+			}
+			got, err := DefaultPaths()
+			if err != nil {
+				t.Fatalf("DefaultPaths() failed: %v", err)
+			}
+			for field, paths := range map[string][2]string{
+				"Root":         {got.Root, want.Root},
+				"StateFile":    {got.StateFile, want.StateFile},
+				"MessagesDir":  {got.MessagesDir, want.MessagesDir},
+				"DaemonSock":   {got.DaemonSock, want.DaemonSock},
+				"ReposDir":     {got.ReposDir, want.ReposDir},
+				"WorktreesDir": {got.WorktreesDir, want.WorktreesDir},
+			} {
+				if paths[0] != paths[1] {
+					t.Errorf("%s = %q under Env(), want %q", field, paths[0], paths[1])
+				}
+			}
+		})
 	}
 }
 
@@ -98,6 +186,66 @@ func TestRepoPaths(t *testing.T) {
 		t.Errorf("RepoDir() = %q, want %q", repoDir, expected)
 	}
 
+	projectDir := paths.ProjectDir("platform")
+	expected = filepath.Join(tmpDir, "projects", "platform")
+	if projectDir != expected {
+		t.Errorf("ProjectDir() = %q, want %q", projectDir, expected)
+	}
+
+	if got := paths.SnapshotsDir(); got != filepath.Join(tmpDir, "snapshots") {
+		t.Errorf("SnapshotsDir() = %q, want %q", got, filepath.Join(tmpDir, "snapshots"))
+	}
+
+	if got := paths.DebugDir(); got != filepath.Join(tmpDir, "debug") {
+		t.Errorf("DebugDir() = %q, want %q", got, filepath.Join(tmpDir, "debug"))
+	}
+
+	if got := paths.FederationFile(repoName); got != filepath.Join(tmpDir, "federation", repoName+".json") {
+		t.Errorf("FederationFile() = %q, want %q", got, filepath.Join(tmpDir, "federation", repoName+".json"))
+	}
+	if got := paths.CommentsFile(repoName); got != filepath.Join(tmpDir, "comments", repoName+".json") {
+		t.Errorf("CommentsFile() = %q", got)
+	}
+	if got := paths.XrefFile(repoName); got != filepath.Join(tmpDir, "xref", repoName+".json") {
+		t.Errorf("XrefFile() = %q", got)
+	}
+	if got := paths.PromptFragmentsDir("merge-queue"); got != filepath.Join(tmpDir, "prompts", "merge-queue.d") {
+		t.Errorf("PromptFragmentsDir() = %q", got)
+	}
+	if got := paths.CLIDocsFile(repoName); got != filepath.Join(tmpDir, "docs", repoName, "CLI.md") {
+		t.Errorf("CLIDocsFile() = %q, want %q", got, filepath.Join(tmpDir, "docs", repoName, "CLI.md"))
+	}
+	if got := paths.RosterFile(repoName); got != filepath.Join(tmpDir, "docs", repoName, "ROSTER.md") {
+		t.Errorf("RosterFile() = %q", got)
+	}
+	if got := paths.MemoryFile(repoName, "calm-owl"); got != filepath.Join(tmpDir, "memory", repoName, "calm-owl.md") {
+		t.Errorf("MemoryFile() = %q", got)
+	}
+	if got := paths.RedactFile(); got != filepath.Join(tmpDir, "redact.txt") {
+		t.Errorf("RedactFile() = %q", got)
+	}
+	if got := paths.DaemonConfigFile(); got != filepath.Join(tmpDir, "daemon.yaml") {
+		t.Errorf("DaemonConfigFile() = %q", got)
+	}
+	if got := paths.TelemetryFile(); got != filepath.Join(tmpDir, "telemetry.jsonl") {
+		t.Errorf("TelemetryFile() = %q", got)
+	}
+	if got := paths.TicketsFile(); got != filepath.Join(tmpDir, "tickets.json") {
+		t.Errorf("TicketsFile() = %q, want %q", got, filepath.Join(tmpDir, "tickets.json"))
+	}
+	if got := paths.TrainLogFile("my-repo", 42); got != filepath.Join(tmpDir, "trains", "my-repo", "pr-42.log") {
+		t.Errorf("TrainLogFile() = %q", got)
+	}
+	if got := paths.MirrorDir("my-repo"); got != filepath.Join(tmpDir, "mirrors", "my-repo.git") {
+		t.Errorf("MirrorDir() = %q", got)
+	}
+	if got := paths.MirrorWorktree("my-repo", "review-12"); got != filepath.Join(tmpDir, "mirrors", "my-repo", "review-12") {
+		t.Errorf("MirrorWorktree() = %q", got)
+	}
+	if got := paths.TasksFile(); got != filepath.Join(tmpDir, "tasks.json") {
+		t.Errorf("TasksFile() = %q, want %q", got, filepath.Join(tmpDir, "tasks.json"))
+	}
+
 	wtDir := paths.WorktreeDir(repoName)
 	expected = filepath.Join(tmpDir, "wts", repoName)
 	if wtDir != expected {
@@ -111,6 +259,20 @@ func TestRepoPaths(t *testing.T) {
 		t.Errorf("AgentWorktree() = %q, want %q", agentWT, expected)
 	}
 
+	// A configured worktree base moves the repository's worktrees
+	paths.WorktreeBase = func(repo string) string {
+		if repo == repoName {
+			return "/scratch/wts"
+		}
+		return ""
+	}
+	if got := paths.AgentWorktree(repoName, agentName); got != filepath.Join("/scratch/wts", repoName, agentName) {
+		t.Errorf("AgentWorktree() with a worktree base = %q", got)
+	}
+	if got := paths.WorktreeDir("other"); got != filepath.Join(tmpDir, "wts", "other") {
+		t.Errorf("WorktreeDir() without a worktree base = %q", got)
+	}
+
 	repoMsgDir := paths.RepoMessagesDir(repoName)
 	expected = filepath.Join(tmpDir, "messages", repoName)
 	if repoMsgDir != expected {
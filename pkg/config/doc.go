@@ -2,7 +2,7 @@ package config
 
 // PathDoc describes a single path for documentation purposes
 type PathDoc struct {
-	Path        string // Relative path from ~/.multiclaude/
+	Path        string // Relative path from the root, ~/.multiclaude/ in the legacy layout
 	Description string // What this path is used for
 	Type        string // "file" or "directory"
 	Notes       string // Additional implementation notes
@@ -28,7 +28,7 @@ func DirectoryDocs() []PathDoc {
 			Path:        "daemon.log",
 			Description: "Append-only log of daemon activity",
 			Type:        "file",
-			Notes:       "Useful for debugging daemon issues. Check this when agents behave unexpectedly.",
+			Notes:       "Useful for debugging daemon issues. Check this when agents behave unexpectedly. CLI commands also log their steps here, tagged [trace=<id>] like the daemon lines they cause.",
 		},
 		{
 			Path:        "state.json",
@@ -58,7 +58,7 @@ func DirectoryDocs() []PathDoc {
 			Path:        "wts/<repo-name>/",
 			Description: "Worktrees directory for a specific repository",
 			Type:        "directory",
-			Notes:       "Contains subdirectories for each agent working on this repo.",
+			Notes:       "Contains subdirectories for each agent working on this repo. Moves to <base>/<repo-name>/ when the repo has a worktree base directory (multiclaude config --worktree-dir).",
 		},
 		{
 			Path:        "wts/<repo-name>/<agent-name>/",
@@ -84,11 +84,137 @@ func DirectoryDocs() []PathDoc {
 			Type:        "directory",
 			Notes:       "Contains msg-<uuid>.json files addressed to this agent.",
 		},
+		{
+			Path:        "output/<repo-name>/",
+			Description: "Captured terminal output of a repository's agents",
+			Type:        "directory",
+			Notes:       "<agent-name>.log for system agents, workers/<agent-name>.log for workers and review agents. The daemon rotates logs over 10MB to <agent-name>.log.<YYYYMMDD-HHMMSS>.",
+		},
+		{
+			Path:        "output/<repo-name>/archive/",
+			Description: "Logs of agents that have been removed",
+			Type:        "directory",
+			Notes:       "Moved here when an agent is cleaned up. The daemon deletes archived and rotated logs after 7 days; 'multiclaude cleanup --outputs' does it on demand.",
+		},
 		{
 			Path:        "prompts/",
 			Description: "Generated prompt files for agents",
 			Type:        "directory",
-			Notes:       "Created on-demand. Contains <agent-name>.md prompt files.",
+			Notes:       "Created on-demand. Contains <agent-name>.md prompt files, and <agent-name>.d/ with the include files of the parts generated from repo config (e.g. tracking.md); the daemon rewrites those and their copies in the prompt when the config changes.",
+		},
+		{
+			Path:        "projects/<project-name>/",
+			Description: "Working directory for a project supervisor",
+			Type:        "directory",
+			Notes:       "Created by 'multiclaude project create'. Project supervisor messages live in messages/@<project-name>/.",
+		},
+		{
+			Path:        "snapshots/",
+			Description: "Timestamped copies of state.json and the message index",
+			Type:        "directory",
+			Notes:       "Files are named state-<YYYYMMDD-HHMMSS>.json. The daemon keeps the newest 50. Restore with 'multiclaude state rollback --to <id>'.",
+		},
+		{
+			Path:        "trash/<repo-name>/<worker-name>.json",
+			Description: "Tombstone of a removed worker",
+			Type:        "file",
+			Notes:       "Written by 'multiclaude worker rm'. Holds the worker's state, branch head, uncommitted changes, final diff and messages so 'multiclaude worker undelete' can restore it. The daemon deletes tombstones after 7 days.",
+		},
+		{
+			Path:        "federation/<repo-name>.json",
+			Description: "Federation outbox and receive cursors for a repository",
+			Type:        "file",
+			Notes:       "Only present when federation is enabled. Holds messages queued for teammates' daemons (kept 24h) and the time of the last message received from each peer.",
+		},
+		{
+			Path:        "tickets.json",
+			Description: "Questions asked with 'multiclaude ask' and their answers",
+			Type:        "file",
+			Notes:       "Each ticket records who asked whom, the deadline and the answer. Answered and expired tickets are kept 24h.",
+		},
+		{
+			Path:        "xref/<repo-name>.json",
+			Description: "A repository's cross-reference index: each PR's worker, task history entry and related messages",
+			Type:        "file",
+			Notes:       "Written by the daemon as it lists PRs, records finished workers and delivers messages, since workers and acked messages are deleted. Keeps the latest 50 messages per PR. Read by 'multiclaude show pr'.",
+		},
+		{
+			Path:        "trains/<repo-name>.json",
+			Description: "A repository's merge train: open PRs simulated on top of each other",
+			Type:        "file",
+			Notes:       "Only present when the merge queue has a test command. Each entry records the PR's head, whether it passed, the PRs it was merged on top of and, for failures, why.",
+		},
+		{
+			Path:        "trains/<repo-name>/pr-<number>.log",
+			Description: "Output of a PR's latest merge train simulation",
+			Type:        "file",
+			Notes:       "The merges and the test command's output. Workers whose PR fails get the last lines in a message.",
+		},
+		{
+			Path:        "mirrors/<repo-name>.git/",
+			Description: "Bare mirror clone of a repository, for read-only agents",
+			Type:        "directory",
+			Notes:       "Only present with --worktree-mirror. The daemon fetches it on every worktree refresh, so reviewers and observers don't contend with workers for the main clone's locks.",
+		},
+		{
+			Path:        "mirrors/<repo-name>/<agent-name>/",
+			Description: "A reviewer's or observer's detached worktree of the mirror",
+			Type:        "directory",
+			Notes:       "Observers' worktrees follow the default branch; reviewers' are checked out at the PR. Removed with the agent.",
+		},
+		{
+			Path:        "redact.txt",
+			Description: "Extra secret patterns, one regular expression per line",
+			Type:        "file",
+			Notes:       "Optional. Scrubbed, along with the built-in patterns (GitHub, AWS, Slack and API keys, bearer headers, private keys), from daemon.log as it is written and from bug reports and agent exports. A pattern with a capture group redacts only the group. Lines starting with # are comments.",
+		},
+		{
+			Path:        "daemon.yaml",
+			Description: "Daemon settings: loop intervals, limits, notification muting, log level and the gRPC API",
+			Type:        "file",
+			Notes:       "Optional; missing settings use the defaults. Reread on SIGHUP or 'multiclaude daemon reload' without restarting agents, except the grpc and http settings, which apply on restart. An invalid file is reported and the current settings are kept.",
+		},
+		{
+			Path:        "daemon-grpc.sock",
+			Description: "Unix socket for the daemon's gRPC API",
+			Type:        "file",
+			Notes:       "Only present when daemon.yaml enables grpc without an address. Created with mode 0600 and removed when the daemon stops.",
+		},
+		{
+			Path:        "grpc-token",
+			Description: "Token gRPC calls must send when the API listens on TCP",
+			Type:        "file",
+			Notes:       "Only present when daemon.yaml sets grpc.address. Regenerated each time the daemon starts; mode 0600. Send it as 'authorization: Bearer <token>' metadata.",
+		},
+		{
+			Path:        "memory/<repo-name>/<agent-name>.md",
+			Description: "An agent's memory: notes it saved with 'multiclaude memory add' and summaries of finished tasks",
+			Type:        "file",
+			Notes:       "Kept by the daemon across restarts; the newest entries go into the agent's prompt when it starts or resumes. The supervisor's gains a summary of each worker that completes. Holds at most 200 entries. Deleted 7 days after the agent is removed.",
+		},
+		{
+			Path:        "telemetry.jsonl",
+			Description: "Opt-in command usage: one line per CLI command run, with its duration and whether it failed",
+			Type:        "file",
+			Notes:       "Only present after 'multiclaude stats enable'; 'multiclaude stats disable' deletes it. Holds no arguments or output and is never sent anywhere. Summarized by 'multiclaude stats'.",
+		},
+		{
+			Path:        "tasks.json",
+			Description: "Tasks queued with 'multiclaude task add' and their dependencies",
+			Type:        "file",
+			Notes:       "Each task records the tasks it comes after, its status and the worker started on it. The daemon starts a task once its prerequisites are merged or completed. Finished tasks are kept 7 days once nothing waits on them.",
+		},
+		{
+			Path:        "docs/<repo-name>/CLI.md",
+			Description: "Generated CLI reference that agent prompts point to",
+			Type:        "file",
+			Notes:       "Rewritten whenever the CLI writes a prompt for the repository, so running agents see new commands without new prompts. Agents can also print it with /cli.",
+		},
+		{
+			Path:        "docs/<repo-name>/ROSTER.md",
+			Description: "The repository's current agents, their types and tasks",
+			Type:        "file",
+			Notes:       "Rewritten by the daemon as agents are added and removed; agent prompts point to it. Absent when the repository's roster is off.",
 		},
 	}
 }
@@ -121,6 +247,11 @@ func StateDocs() []StateFieldDoc {
 		{Field: "repos.<name>.agents.<name>.created_at", Type: "time.Time", Description: "When the agent was created"},
 		{Field: "repos.<name>.agents.<name>.last_nudge", Type: "time.Time", Description: "Last time agent was nudged (omitempty)"},
 		{Field: "repos.<name>.agents.<name>.ready_for_cleanup", Type: "bool", Description: "Whether worker is ready to be cleaned up (workers only, omitempty)"},
+
+		// Project fields
+		{Field: "projects", Type: "map[string]*Project", Description: "Map of project name to project state (omitempty)"},
+		{Field: "projects.<name>.repos", Type: "[]string", Description: "Names of the member repositories"},
+		{Field: "projects.<name>.tmux_session", Type: "string", Description: "Name of the tmux session for the project supervisor"},
 	}
 }
 
@@ -141,5 +272,9 @@ func MessageDocs() []MessageFieldDoc {
 		{Field: "body", Type: "string", Description: "Message content (markdown text)"},
 		{Field: "status", Type: "string", Description: "Message status: pending, delivered, read, or acked"},
 		{Field: "acked_at", Type: "time.Time", Description: "When the message was acknowledged (omitempty)"},
+		{Field: "idempotency_key", Type: "string", Description: "Client-generated key; retries with the same key within 10 minutes return this message (omitempty)"},
+		{Field: "priority", Type: "string", Description: "Priority set by a routing rule: high, normal or low; high ones are delivered first (omitempty)"},
+		{Field: "routed", Type: "bool", Description: "Whether the daemon has applied the repository's routing rules (omitempty)"},
+		{Field: "cc_of", Type: "string", Description: "Original recipient, on a copy sent by a routing rule (omitempty)"},
 	}
 }
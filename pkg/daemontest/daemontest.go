@@ -0,0 +1,431 @@
+package daemontest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+	"github.com/micheal-at/multiclaude/pkg/config"
+)
+
+// Repo is a repository tracked by the fake daemon
+type Repo struct {
+	GithubURL   string
+	TmuxSession string
+	Agents      map[string]Agent
+}
+
+// Agent is an agent tracked by the fake daemon
+type Agent struct {
+	// Type is the agent type, e.g. "worker" or "supervisor"
+	Type            string
+	WorktreePath    string
+	TmuxWindow      string
+	Task            string
+	CreatedAt       time.Time
+	ReadyForCleanup bool
+	Summary         string
+	FailureReason   string
+}
+
+// Request is a request the fake daemon received
+type Request struct {
+	Command string
+	Args    map[string]interface{}
+}
+
+// HandlerFunc answers a command. The returned data becomes the response's
+// data; an error fails the request with the error's message.
+type HandlerFunc func(args map[string]interface{}) (interface{}, error)
+
+// Daemon is an in-memory daemon serving the socket API
+type Daemon struct {
+	paths  *config.Paths
+	server *socket.Server
+
+	mu       sync.Mutex
+	repos    map[string]*Repo
+	handlers map[string]HandlerFunc
+	requests []Request
+}
+
+// New starts a fake daemon for the duration of a test. It listens on a Unix
+// socket in a fresh directory and writes a PID file, so the CLI sees it as a
+// running daemon.
+func New(t testing.TB) *Daemon {
+	t.Helper()
+
+	root := t.TempDir()
+	// Unix socket paths are limited to about 100 bytes, which test temp
+	// directories can exceed
+	sockDir, err := os.MkdirTemp("", "mcd")
+	if err != nil {
+		t.Fatalf("daemontest: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sockDir) })
+
+	paths := config.NewTestPaths(root)
+	paths.DaemonSock = filepath.Join(sockDir, "daemon.sock")
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("daemontest: %v", err)
+	}
+	if err := os.WriteFile(paths.DaemonPID, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatalf("daemontest: %v", err)
+	}
+
+	d := &Daemon{
+		paths:    paths,
+		repos:    make(map[string]*Repo),
+		handlers: make(map[string]HandlerFunc),
+	}
+	d.server = socket.NewServer(paths.DaemonSock, socket.HandlerFunc(d.handle))
+	if err := d.server.Start(); err != nil {
+		t.Fatalf("daemontest: failed to start server: %v", err)
+	}
+	go d.server.Serve()
+	t.Cleanup(func() { d.server.Stop() })
+
+	return d
+}
+
+// Paths returns multiclaude paths under a temporary root, with the daemon
+// socket and PID file pointing at this daemon
+func (d *Daemon) Paths() *config.Paths {
+	return d.paths
+}
+
+// SocketPath returns the path of the daemon's Unix socket
+func (d *Daemon) SocketPath() string {
+	return d.paths.DaemonSock
+}
+
+// Handle answers a command with fn instead of the built-in behavior
+func (d *Daemon) Handle(command string, fn HandlerFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[command] = fn
+}
+
+// Requests returns the requests received so far, oldest first
+func (d *Daemon) Requests() []Request {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Request(nil), d.requests...)
+}
+
+// AddRepo adds a repository, replacing any with the same name
+func (d *Daemon) AddRepo(name string, repo Repo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	agents := make(map[string]Agent, len(repo.Agents))
+	for agentName, agent := range repo.Agents {
+		agents[agentName] = agent
+	}
+	repo.Agents = agents
+	d.repos[name] = &repo
+}
+
+// AddAgent adds an agent to a repository, adding the repository if needed
+func (d *Daemon) AddAgent(repoName, agentName string, agent Agent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	repo, ok := d.repos[repoName]
+	if !ok {
+		repo = &Repo{Agents: make(map[string]Agent)}
+		d.repos[repoName] = repo
+	}
+	repo.Agents[agentName] = agent
+}
+
+// Repo returns a copy of a repository
+func (d *Daemon) Repo(name string) (Repo, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	repo, ok := d.repos[name]
+	if !ok {
+		return Repo{}, false
+	}
+	copied := *repo
+	copied.Agents = make(map[string]Agent, len(repo.Agents))
+	for agentName, agent := range repo.Agents {
+		copied.Agents[agentName] = agent
+	}
+	return copied, true
+}
+
+// Agent returns an agent of a repository
+func (d *Daemon) Agent(repoName, agentName string) (Agent, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	repo, ok := d.repos[repoName]
+	if !ok {
+		return Agent{}, false
+	}
+	agent, ok := repo.Agents[agentName]
+	return agent, ok
+}
+
+// handle records a request and answers it with its handler or the built-in
+// command
+func (d *Daemon) handle(req socket.Request) socket.Response {
+	d.mu.Lock()
+	d.requests = append(d.requests, Request{Command: req.Command, Args: req.Args})
+	fn, ok := d.handlers[req.Command]
+	d.mu.Unlock()
+
+	if ok {
+		data, err := fn(req.Args)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		return socket.Response{Success: true, Data: data}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	data, err := d.builtin(req)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// builtin answers the built-in commands from memory. The caller holds d.mu.
+func (d *Daemon) builtin(req socket.Request) (interface{}, error) {
+	switch req.Command {
+	case "ping":
+		return "pong", nil
+
+	case "status":
+		agents := 0
+		for _, repo := range d.repos {
+			agents += len(repo.Agents)
+		}
+		return map[string]interface{}{
+			"running":         true,
+			"pid":             os.Getpid(),
+			"repos":           len(d.repos),
+			"agents":          agents,
+			"socket_path":     d.paths.DaemonSock,
+			"standby":         false,
+			"standby_reason":  "",
+			"inconsistencies": 0,
+		}, nil
+
+	case "list_repos":
+		return d.listRepos(req.Args), nil
+
+	case "add_repo":
+		name, err := requiredArg(req.Args, "name")
+		if err != nil {
+			return nil, err
+		}
+		session, err := requiredArg(req.Args, "tmux_session")
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := d.repos[name]; exists {
+			return nil, fmt.Errorf("repository %q already exists", name)
+		}
+		githubURL, _ := req.Args["github_url"].(string)
+		d.repos[name] = &Repo{GithubURL: githubURL, TmuxSession: session, Agents: make(map[string]Agent)}
+		return nil, nil
+
+	case "remove_repo":
+		name, err := requiredArg(req.Args, "name")
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := d.repos[name]; !exists {
+			return nil, fmt.Errorf("repository %q not found", name)
+		}
+		delete(d.repos, name)
+		return nil, nil
+
+	case "add_agent":
+		return nil, d.addAgent(req.Args)
+
+	case "remove_agent":
+		repo, agentName, err := d.agentArgs(req.Args)
+		if err != nil {
+			return nil, err
+		}
+		delete(repo.Agents, agentName)
+		return nil, nil
+
+	case "list_agents":
+		return d.listAgents(req.Args)
+
+	case "complete_agent":
+		repo, agentName, err := d.agentArgs(req.Args)
+		if err != nil {
+			return nil, err
+		}
+		agent := repo.Agents[agentName]
+		agent.ReadyForCleanup = true
+		agent.Summary, _ = req.Args["summary"].(string)
+		agent.FailureReason, _ = req.Args["failure_reason"].(string)
+		repo.Agents[agentName] = agent
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown command: %q. Run 'multiclaude --help' for available commands", req.Command)
+	}
+}
+
+// listRepos answers list_repos, with names or with details when rich is set
+func (d *Daemon) listRepos(args map[string]interface{}) interface{} {
+	names := d.repoNames()
+	if rich, _ := args["rich"].(bool); !rich {
+		return names
+	}
+
+	details := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		repo := d.repos[name]
+		workers := 0
+		for _, agent := range repo.Agents {
+			if agent.Type == "worker" {
+				workers++
+			}
+		}
+		details = append(details, map[string]interface{}{
+			"name":               name,
+			"github_url":         repo.GithubURL,
+			"tmux_session":       repo.TmuxSession,
+			"total_agents":       len(repo.Agents),
+			"worker_count":       workers,
+			"session_healthy":    true,
+			"is_fork":            false,
+			"upstream_owner":     "",
+			"upstream_repo":      "",
+			"pr_management_mode": "merge-queue",
+			"solo":               false,
+			"path":               "",
+		})
+	}
+	return details
+}
+
+// addAgent answers add_agent
+func (d *Daemon) addAgent(args map[string]interface{}) error {
+	values := make(map[string]string)
+	for _, key := range []string{"repo", "agent", "type", "worktree_path", "tmux_window"} {
+		value, err := requiredArg(args, key)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	repo, ok := d.repos[values["repo"]]
+	if !ok {
+		return fmt.Errorf("repository %q not found", values["repo"])
+	}
+	task, _ := args["task"].(string)
+	repo.Agents[values["agent"]] = Agent{
+		Type:         values["type"],
+		WorktreePath: values["worktree_path"],
+		TmuxWindow:   values["tmux_window"],
+		Task:         task,
+		CreatedAt:    time.Now(),
+	}
+	return nil
+}
+
+// listAgents answers list_agents for one repository, or every one with all.
+// rich adds each agent's status.
+func (d *Daemon) listAgents(args map[string]interface{}) (interface{}, error) {
+	rich, _ := args["rich"].(bool)
+	repoNames := d.repoNames()
+	if all, _ := args["all"].(bool); !all {
+		name, err := requiredArg(args, "repo")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := d.repos[name]; !ok {
+			return nil, fmt.Errorf("repository %q not found", name)
+		}
+		repoNames = []string{name}
+	}
+
+	details := make([]map[string]interface{}, 0)
+	for _, repoName := range repoNames {
+		repo := d.repos[repoName]
+		agentNames := make([]string, 0, len(repo.Agents))
+		for name := range repo.Agents {
+			agentNames = append(agentNames, name)
+		}
+		sort.Strings(agentNames)
+		for _, name := range agentNames {
+			agent := repo.Agents[name]
+			detail := map[string]interface{}{
+				"repo":          repoName,
+				"name":          name,
+				"type":          agent.Type,
+				"worktree_path": agent.WorktreePath,
+				"tmux_window":   agent.TmuxWindow,
+				"task":          agent.Task,
+				"created_at":    agent.CreatedAt,
+				"capabilities":  []string(nil),
+				"adopted":       false,
+			}
+			if rich {
+				status := "running"
+				if agent.ReadyForCleanup {
+					status = "completed"
+				}
+				detail["status"] = status
+				detail["branch"] = ""
+				detail["messages_total"] = 0
+				detail["messages_pending"] = 0
+			}
+			details = append(details, detail)
+		}
+	}
+	return details, nil
+}
+
+// agentArgs finds the agent a request names by its repo and agent arguments
+func (d *Daemon) agentArgs(args map[string]interface{}) (*Repo, string, error) {
+	repoName, err := requiredArg(args, "repo")
+	if err != nil {
+		return nil, "", err
+	}
+	agentName, err := requiredArg(args, "agent")
+	if err != nil {
+		return nil, "", err
+	}
+	repo, ok := d.repos[repoName]
+	if !ok {
+		return nil, "", fmt.Errorf("repository %q not found", repoName)
+	}
+	if _, ok := repo.Agents[agentName]; !ok {
+		return nil, "", fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+	return repo, agentName, nil
+}
+
+// repoNames returns the repository names in order
+func (d *Daemon) repoNames() []string {
+	names := make([]string, 0, len(d.repos))
+	for name := range d.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requiredArg returns a non-empty string argument
+func requiredArg(args map[string]interface{}, key string) (string, error) {
+	value, _ := args[key].(string)
+	if value == "" {
+		return "", fmt.Errorf("missing '%s'", key)
+	}
+	return value, nil
+}
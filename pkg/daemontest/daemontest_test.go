@@ -0,0 +1,141 @@
+package daemontest
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/micheal-at/multiclaude/internal/socket"
+)
+
+func send(t *testing.T, d *Daemon, command string, args map[string]interface{}) *socket.Response {
+	t.Helper()
+	resp, err := socket.NewClient(d.SocketPath()).Send(socket.Request{Command: command, Args: args})
+	if err != nil {
+		t.Fatalf("%s: %v", command, err)
+	}
+	return resp
+}
+
+func TestBuiltinCommands(t *testing.T) {
+	d := New(t)
+
+	if resp := send(t, d, "ping", nil); !resp.Success || resp.Data != "pong" {
+		t.Errorf("ping = %+v, want pong", resp)
+	}
+
+	if resp := send(t, d, "add_repo", map[string]interface{}{"name": "api", "github_url": "https://github.com/acme/api", "tmux_session": "mc-api"}); !resp.Success {
+		t.Fatalf("add_repo failed: %s", resp.Error)
+	}
+	if resp := send(t, d, "add_repo", map[string]interface{}{"name": "api", "tmux_session": "mc-api"}); resp.Success {
+		t.Error("add_repo of an existing repository should fail")
+	}
+	if resp := send(t, d, "add_agent", map[string]interface{}{"repo": "api", "agent": "calm-owl", "type": "worker", "worktree_path": "/tmp/wt", "tmux_window": "calm-owl", "task": "Fix it"}); !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+	if resp := send(t, d, "add_agent", map[string]interface{}{"repo": "api", "agent": "x"}); resp.Success || !strings.Contains(resp.Error, "type") {
+		t.Errorf("add_agent without a type = %+v, want a missing argument error", resp)
+	}
+
+	resp := send(t, d, "list_agents", map[string]interface{}{"repo": "api", "rich": true})
+	agents, _ := resp.Data.([]interface{})
+	if len(agents) != 1 {
+		t.Fatalf("list_agents = %+v, want one agent", resp)
+	}
+	agent := agents[0].(map[string]interface{})
+	if agent["name"] != "calm-owl" || agent["task"] != "Fix it" || agent["status"] != "running" {
+		t.Errorf("list_agents agent = %v", agent)
+	}
+
+	if resp := send(t, d, "complete_agent", map[string]interface{}{"repo": "api", "agent": "calm-owl", "summary": "Fixed"}); !resp.Success {
+		t.Fatalf("complete_agent failed: %s", resp.Error)
+	}
+	if got, _ := d.Agent("api", "calm-owl"); !got.ReadyForCleanup || got.Summary != "Fixed" {
+		t.Errorf("completed agent = %+v", got)
+	}
+
+	resp = send(t, d, "status", nil)
+	status, _ := resp.Data.(map[string]interface{})
+	if status["repos"] != float64(1) || status["agents"] != float64(1) {
+		t.Errorf("status = %v, want 1 repo and 1 agent", status)
+	}
+
+	if resp := send(t, d, "remove_agent", map[string]interface{}{"repo": "api", "agent": "calm-owl"}); !resp.Success {
+		t.Errorf("remove_agent failed: %s", resp.Error)
+	}
+	if resp := send(t, d, "remove_repo", map[string]interface{}{"name": "api"}); !resp.Success {
+		t.Errorf("remove_repo failed: %s", resp.Error)
+	}
+	if resp := send(t, d, "list_repos", nil); !resp.Success || len(resp.Data.([]interface{})) != 0 {
+		t.Errorf("list_repos after remove = %+v, want none", resp)
+	}
+}
+
+func TestListAll(t *testing.T) {
+	d := New(t)
+	d.AddRepo("web", Repo{TmuxSession: "mc-web"})
+	d.AddAgent("web", "supervisor", Agent{Type: "supervisor"})
+	d.AddAgent("api", "calm-owl", Agent{Type: "worker"})
+
+	resp := send(t, d, "list_repos", nil)
+	if !reflect.DeepEqual(resp.Data, []interface{}{"api", "web"}) {
+		t.Errorf("list_repos = %v, want [api web]", resp.Data)
+	}
+
+	resp = send(t, d, "list_repos", map[string]interface{}{"rich": true})
+	repos, _ := resp.Data.([]interface{})
+	if len(repos) != 2 || repos[0].(map[string]interface{})["worker_count"] != float64(1) {
+		t.Errorf("list_repos rich = %v", resp.Data)
+	}
+
+	resp = send(t, d, "list_agents", map[string]interface{}{"all": true})
+	var names []string
+	for _, a := range resp.Data.([]interface{}) {
+		agent := a.(map[string]interface{})
+		names = append(names, agent["repo"].(string)+"/"+agent["name"].(string))
+	}
+	if want := []string{"api/calm-owl", "web/supervisor"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("list_agents all = %v, want %v", names, want)
+	}
+}
+
+func TestHandleAndRequests(t *testing.T) {
+	d := New(t)
+	d.Handle("trigger_cleanup", func(args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("cleanup is disabled")
+	})
+	d.Handle("ping", func(args map[string]interface{}) (interface{}, error) {
+		return "stubbed", nil
+	})
+
+	if resp := send(t, d, "trigger_cleanup", map[string]interface{}{"dry_run": true}); resp.Success || resp.Error != "cleanup is disabled" {
+		t.Errorf("trigger_cleanup = %+v, want the handler's error", resp)
+	}
+	if resp := send(t, d, "ping", nil); resp.Data != "stubbed" {
+		t.Errorf("ping = %+v, want the handler's data", resp)
+	}
+	if resp := send(t, d, "no_such_command", nil); resp.Success || !strings.Contains(resp.Error, "unknown command") {
+		t.Errorf("no_such_command = %+v, want an unknown command error", resp)
+	}
+
+	requests := d.Requests()
+	if len(requests) != 3 || requests[0].Command != "trigger_cleanup" || requests[0].Args["dry_run"] != true {
+		t.Errorf("Requests() = %+v", requests)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	d := New(t)
+	paths := d.Paths()
+	if paths.DaemonSock != d.SocketPath() {
+		t.Errorf("Paths().DaemonSock = %q, want %q", paths.DaemonSock, d.SocketPath())
+	}
+	if _, err := os.Stat(paths.DaemonPID); err != nil {
+		t.Errorf("PID file not written: %v", err)
+	}
+	if _, err := os.Stat(paths.MessagesDir); err != nil {
+		t.Errorf("directories not created: %v", err)
+	}
+}
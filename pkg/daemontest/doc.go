@@ -0,0 +1,39 @@
+// Package daemontest provides an in-memory multiclaude daemon for tests.
+//
+// A [Daemon] serves the daemon's socket API from memory: no tmux, no git and
+// no state file. It answers the commands the CLI uses to track repositories
+// and agents, records every request, and lets a test stub any other command
+// with [Daemon.Handle]. Tests of the CLI, and of tools that talk to the
+// daemon socket, can use it instead of starting a real daemon.
+//
+// # Installation
+//
+//	go get github.com/micheal-at/multiclaude/pkg/daemontest
+//
+// # Example Usage
+//
+//	func TestListRepos(t *testing.T) {
+//	    d := daemontest.New(t)
+//	    d.AddRepo("api", daemontest.Repo{GithubURL: "https://github.com/acme/api"})
+//	    d.AddAgent("api", "calm-owl", daemontest.Agent{Type: "worker", Task: "Fix the flaky test"})
+//
+//	    // Point a client at d.SocketPath(), or for the multiclaude CLI:
+//	    //   cli.NewWithPaths(d.Paths()).Execute([]string{"work", "list", "--repo", "api"})
+//
+//	    d.Handle("trigger_cleanup", func(args map[string]interface{}) (interface{}, error) {
+//	        return nil, errors.New("cleanup is disabled")
+//	    })
+//
+//	    if got := d.Requests(); len(got) != 0 {
+//	        t.Errorf("unexpected requests: %v", got)
+//	    }
+//	}
+//
+// # Built-in Commands
+//
+// ping, status, list_repos, add_repo, remove_repo, add_agent, remove_agent,
+// list_agents and complete_agent work against the in-memory repositories and
+// agents, with the response shapes of the real daemon. Any other command
+// fails with the daemon's unknown command error unless a handler is
+// registered for it. Handlers also replace built-in commands.
+package daemontest
@@ -0,0 +1,36 @@
+// Package retry reruns commands that fail for reasons that go away on their
+// own: a tmux server that is busy or restarting, a git lock held by another
+// git process, a dropped connection to a remote.
+//
+// A [Policy] describes one class of operation: how many attempts it gets,
+// how long to wait between them, and which error output marks a failure as
+// transient. Waits back off exponentially with jitter. Failures that don't
+// match are returned at once, so a missing branch or a merge conflict isn't
+// retried. Each attempt gets its own context, bounded by the policy's
+// Timeout, so a command that hangs is killed rather than blocking its
+// caller forever.
+//
+// # Installation
+//
+//	go get github.com/micheal-at/multiclaude/pkg/retry
+//
+// # Example Usage
+//
+//	out, err := retry.Git.CombinedOutput(ctx, func(ctx context.Context) *exec.Cmd {
+//	    cmd := exec.CommandContext(ctx, "git", "worktree", "add", path, branch)
+//	    cmd.Dir = repoPath
+//	    return cmd
+//	})
+//
+//	// A stricter policy for one caller
+//	policy := retry.GitRemote
+//	policy.Attempts = 5
+//	policy.Timeout = 10 * time.Minute
+//	err = policy.Do(ctx, func() (bool, error) {
+//	    err := push()
+//	    return errors.Is(err, errRejected), err
+//	})
+//
+// The [Tmux], [Git], [GitRemote] and [GitHub] policies are what multiclaude uses; the
+// tmux client takes its policy with [github.com/micheal-at/multiclaude/pkg/tmux.WithRetry].
+package retry
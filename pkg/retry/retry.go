@@ -0,0 +1,276 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy says how one class of operation is retried
+type Policy struct {
+	// Attempts is how many times the operation runs in all; below 2 it runs
+	// once
+	Attempts int
+	// Delay is the wait before the first retry. It doubles for each retry
+	// after that, up to MaxDelay, and each wait is jittered by up to a
+	// quarter either way so that callers contending for the same lock
+	// spread out.
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// Transient lists text in a failed command's error output that marks the
+	// failure as worth retrying. Other failures are returned at once.
+	Transient []string
+	// Timeout bounds each attempt of a command run with Output or
+	// CombinedOutput; zero means no limit. An attempt that times out is
+	// killed and not retried.
+	Timeout time.Duration
+}
+
+// Policies for the operation classes multiclaude runs
+var (
+	// Tmux covers tmux commands, which fail while the server is starting,
+	// exiting or too busy to accept a connection
+	Tmux = Policy{
+		Attempts: 3,
+		Delay:    100 * time.Millisecond,
+		MaxDelay: time.Second,
+		Timeout:  30 * time.Second,
+		Transient: []string{
+			"server exited unexpectedly",
+			"lost server",
+			"server busy",
+			"Resource temporarily unavailable",
+		},
+	}
+
+	// Git covers local git commands, which fail while another git process
+	// holds a lock in the same repository, or while a worktree is being
+	// added or removed next to them
+	Git = Policy{
+		Attempts:  5,
+		Delay:     100 * time.Millisecond,
+		MaxDelay:  2 * time.Second,
+		Transient: gitTransient,
+		Timeout:   2 * time.Minute,
+	}
+
+	// GitRemote covers git commands that talk to a remote, which also fail
+	// on dropped connections
+	GitRemote = Policy{
+		Attempts: 3,
+		Delay:    2 * time.Second,
+		MaxDelay: 15 * time.Second,
+		Timeout:  5 * time.Minute,
+		Transient: append([]string{
+			"Could not resolve host",
+			"Connection reset",
+			"Connection timed out",
+			"Operation timed out",
+			"The remote end hung up unexpectedly",
+			"early EOF",
+			"RPC failed",
+			"HTTP 502",
+			"HTTP 503",
+			"HTTP 504",
+		}, gitTransient...),
+	}
+
+	// GitHub covers the gh CLI, whose API calls fail while GitHub is
+	// overloaded or the connection drops
+	GitHub = Policy{
+		Attempts: 3,
+		Delay:    time.Second,
+		MaxDelay: 10 * time.Second,
+		Timeout:  time.Minute,
+		Transient: []string{
+			"HTTP 502",
+			"HTTP 503",
+			"HTTP 504",
+			"connection reset by peer",
+			"i/o timeout",
+			"TLS handshake timeout",
+		},
+	}
+)
+
+var gitTransient = []string{
+	"index.lock",
+	".lock': File exists",
+	"could not lock config file",
+	"commondir",
+}
+
+// IsTransient reports whether a failed command's output matches one of the
+// policy's transient errors
+func (p Policy) IsTransient(output []byte) bool {
+	for _, text := range p.Transient {
+		if bytes.Contains(output, []byte(text)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Do runs op until it succeeds, fails with an error it doesn't report as
+// transient, runs out of attempts or ctx is done. It returns op's last error,
+// or ctx's error if ctx is done while waiting to retry.
+func (p Policy) Do(ctx context.Context, op func() (transient bool, err error)) error {
+	delay := p.Delay
+	for attempt := 1; ; attempt++ {
+		transient, err := op()
+		if err == nil || !transient || attempt >= p.Attempts {
+			return err
+		}
+
+		timer := time.NewTimer(jitter(delay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if delay *= 2; p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+// jitter spreads d by up to a quarter either way
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d*3/4 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// Output runs the command newCmd builds, building a fresh one for each
+// attempt with a context that ends when ctx does or the attempt times out,
+// and returns its standard output. Failures whose standard error matches the
+// policy are retried; a command that sets its own Stderr gets a copy of it.
+func (p Policy) Output(ctx context.Context, newCmd func(context.Context) *exec.Cmd) ([]byte, error) {
+	var output []byte
+	err := p.Do(ctx, func() (bool, error) {
+		attemptCtx, cancel := p.attempt(ctx)
+		defer cancel()
+		cmd := newCmd(attemptCtx)
+		var stderr bytes.Buffer
+		if cmd.Stderr == nil {
+			cmd.Stderr = &stderr
+		} else {
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, &stderr)
+		}
+		var err error
+		output, err = cmd.Output()
+		if err != nil && attemptCtx.Err() != nil {
+			return false, p.contextError(ctx, err)
+		}
+		return err != nil && p.IsTransient(stderr.Bytes()), err
+	})
+	return output, err
+}
+
+// CombinedOutput runs the command newCmd builds, building a fresh one for
+// each attempt as Output does, and returns its combined standard output and
+// error. Failures whose output matches the policy are retried.
+func (p Policy) CombinedOutput(ctx context.Context, newCmd func(context.Context) *exec.Cmd) ([]byte, error) {
+	var output []byte
+	err := p.Do(ctx, func() (bool, error) {
+		attemptCtx, cancel := p.attempt(ctx)
+		defer cancel()
+		var err error
+		output, err = newCmd(attemptCtx).CombinedOutput()
+		if err != nil && attemptCtx.Err() != nil {
+			return false, p.contextError(ctx, err)
+		}
+		return err != nil && p.IsTransient(output), err
+	})
+	return output, err
+}
+
+// attempt returns the context for one attempt, bounded by the policy's
+// timeout
+func (p Policy) attempt(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.Timeout)
+}
+
+// contextError explains a command that failed because its context ended:
+// ctx itself ending, or the attempt timing out. Both wrap the context's
+// error, so callers can match context.DeadlineExceeded.
+func (p Policy) contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w (%v)", ctxErr, err)
+	}
+	return fmt.Errorf("timed out after %s: %w", p.Timeout, context.DeadlineExceeded)
+}
+
+// remoteGitCommands are the git subcommands that talk to a remote
+var remoteGitCommands = map[string]bool{
+	"fetch":     true,
+	"ls-remote": true,
+	"pull":      true,
+	"push":      true,
+}
+
+// transferGitCommands are the git subcommands that transfer as much as the
+// repository's size needs: clones, submodule updates and LFS transfers
+var transferGitCommands = map[string]bool{
+	"clone":     true,
+	"lfs":       true,
+	"submodule": true,
+}
+
+// gitTimeouts override Git's and GitRemote's timeouts in the policies ForGit
+// returns; see SetGitTimeouts
+var gitTimeouts struct {
+	sync.RWMutex
+	local, remote time.Duration
+}
+
+// SetGitTimeouts sets the timeouts of the policies ForGit returns: local for
+// local git commands and remote for those that talk to a remote. 0 keeps the
+// Git or GitRemote default.
+func SetGitTimeouts(local, remote time.Duration) {
+	gitTimeouts.Lock()
+	defer gitTimeouts.Unlock()
+	gitTimeouts.local, gitTimeouts.remote = local, remote
+}
+
+// ForGit returns the policy for a git command, given its arguments:
+// GitRemote for commands that talk to a remote and Git for the rest, with
+// the timeouts SetGitTimeouts set. Clones, submodule updates and LFS
+// transfers take as long as the repository's size needs, so they get
+// GitRemote without its timeout; bound them with the context instead.
+func ForGit(args ...string) Policy {
+	gitTimeouts.RLock()
+	local, remote := gitTimeouts.local, gitTimeouts.remote
+	gitTimeouts.RUnlock()
+
+	policy, timeout := Git, local
+	for i := 0; i < len(args); i++ {
+		if arg := args[i]; arg == "-C" || arg == "-c" {
+			i++
+			continue
+		} else if strings.HasPrefix(arg, "-") {
+			continue
+		} else if transferGitCommands[arg] {
+			policy, timeout = GitRemote, 0
+			policy.Timeout = 0
+		} else if remoteGitCommands[arg] {
+			policy, timeout = GitRemote, remote
+		}
+		break
+	}
+	if timeout > 0 {
+		policy.Timeout = timeout
+	}
+	return policy
+}
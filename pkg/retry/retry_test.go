@@ -0,0 +1,179 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var fast = Policy{Attempts: 3, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Transient: []string{"busy"}}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	errBusy := errors.New("busy")
+	errGone := errors.New("gone")
+
+	tests := []struct {
+		name      string
+		failures  []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"succeeds at once", nil, 1, nil},
+		{"retries transient failures", []error{errBusy, errBusy}, 3, nil},
+		{"gives up after the attempts", []error{errBusy, errBusy, errBusy, errBusy}, 3, errBusy},
+		{"returns other failures at once", []error{errGone, errBusy}, 1, errGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := fast.Do(ctx, func() (bool, error) {
+				calls++
+				if calls > len(tt.failures) {
+					return false, nil
+				}
+				err := tt.failures[calls-1]
+				return err == errBusy, err
+			})
+			if err != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("Do() = %v after %d calls, want %v after %d", err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := Policy{Attempts: 3, Delay: time.Hour}
+
+	calls := 0
+	err := slow.Do(ctx, func() (bool, error) {
+		calls++
+		cancel()
+		return true, errors.New("busy")
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(100 * time.Millisecond); d < 75*time.Millisecond || d > 125*time.Millisecond {
+			t.Fatalf("jitter(100ms) = %v, want within a quarter", d)
+		}
+	}
+	if d := jitter(0); d != 0 {
+		t.Errorf("jitter(0) = %v", d)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	// The command fails with a lock error until it has run twice
+	counter := filepath.Join(t.TempDir(), "runs")
+	script := `echo x >> "$1"; if [ $(wc -l < "$1") -lt 3 ]; then echo "fatal: Unable to create 'index.lock': File exists." >&2; exit 128; fi; echo done`
+	newCmd := func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", script, "sh", counter)
+	}
+
+	out, err := Git.Output(context.Background(), newCmd)
+	if err != nil || string(out) != "done\n" {
+		t.Errorf("Output() = %q, %v, want done after retrying", out, err)
+	}
+
+	// A command with its own Stderr is still retried, and sees every attempt's
+	counter = filepath.Join(t.TempDir(), "runs")
+	var stderr bytes.Buffer
+	out, err = Git.Output(context.Background(), func(ctx context.Context) *exec.Cmd {
+		cmd := newCmd(ctx)
+		cmd.Stderr = &stderr
+		return cmd
+	})
+	if err != nil || string(out) != "done\n" || strings.Count(stderr.String(), "index.lock") != 2 {
+		t.Errorf("Output() with Stderr = %q, %v, stderr %q; want done after retrying", out, err, stderr.String())
+	}
+
+	once := Git
+	once.Attempts = 1
+	if _, err := once.CombinedOutput(context.Background(), func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", "echo 'index.lock' >&2; exit 1")
+	}); err == nil {
+		t.Error("CombinedOutput() with one attempt should fail")
+	}
+}
+
+func TestOutputTimeout(t *testing.T) {
+	hang := func(ctx context.Context) *exec.Cmd { return exec.CommandContext(ctx, "sleep", "10") }
+
+	short := fast
+	short.Timeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err := short.CombinedOutput(context.Background(), hang)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CombinedOutput() = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("CombinedOutput() took %v; a timed-out attempt should not be retried", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := fast.Output(ctx, hang); !errors.Is(err, context.Canceled) {
+		t.Errorf("Output() = %v, want context.Canceled", err)
+	}
+}
+
+func TestForGit(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"fetch", "origin"}, "remote"},
+		{[]string{"-C", "/repo", "push", "origin", "main"}, "remote"},
+		{[]string{"-c", "user.name=x", "--no-pager", "ls-remote", "origin"}, "remote"},
+		{[]string{"worktree", "add", "/wt", "main"}, "local"},
+		{[]string{"-C", "fetch", "status"}, "local"},
+		{nil, "local"},
+		{[]string{"clone", "--mirror", "url", "dir"}, "unbounded"},
+		{[]string{"submodule", "update", "--init", "--recursive"}, "unbounded"},
+		{[]string{"-C", "/wt", "lfs", "pull"}, "unbounded"},
+	}
+	for _, tt := range tests {
+		got := "local"
+		switch policy := ForGit(tt.args...); {
+		case reflect.DeepEqual(policy, GitRemote):
+			got = "remote"
+		case policy.Timeout == 0 && policy.Attempts == GitRemote.Attempts:
+			got = "unbounded"
+		}
+		if got != tt.want {
+			t.Errorf("ForGit(%v) is the %s policy, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSetGitTimeouts(t *testing.T) {
+	SetGitTimeouts(time.Minute, 20*time.Minute)
+	defer SetGitTimeouts(0, 0)
+
+	if got := ForGit("status").Timeout; got != time.Minute {
+		t.Errorf("local timeout = %s, want 1m", got)
+	}
+	if got := ForGit("fetch", "origin").Timeout; got != 20*time.Minute {
+		t.Errorf("remote timeout = %s, want 20m", got)
+	}
+	if got := ForGit("lfs", "pull").Timeout; got != 0 {
+		t.Errorf("lfs timeout = %s, want none", got)
+	}
+
+	SetGitTimeouts(0, 0)
+	if got := ForGit("status").Timeout; got != Git.Timeout {
+		t.Errorf("default local timeout = %s, want %s", got, Git.Timeout)
+	}
+}
@@ -49,6 +49,8 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/micheal-at/multiclaude/pkg/retry"
 )
 
 // Client wraps tmux operations for programmatic control of tmux sessions,
@@ -57,6 +59,8 @@ type Client struct {
 	// tmuxPath allows overriding the default "tmux" binary path.
 	// If empty, "tmux" is used (relies on PATH).
 	tmuxPath string
+	// retry says how commands that fail transiently are retried
+	retry retry.Policy
 }
 
 // ClientOption is a functional option for configuring a Client.
@@ -69,10 +73,20 @@ func WithTmuxPath(path string) ClientOption {
 	}
 }
 
+// WithRetry sets how commands that fail transiently, such as while the tmux
+// server is starting, are retried. The default is [retry.Tmux]; a policy
+// with one attempt turns retrying off.
+func WithRetry(policy retry.Policy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
 // NewClient creates a new tmux client with the given options.
 func NewClient(opts ...ClientOption) *Client {
 	c := &Client{
 		tmuxPath: "tmux",
+		retry:    retry.Tmux,
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -85,10 +99,25 @@ func (c *Client) tmuxCmd(ctx context.Context, args ...string) *exec.Cmd {
 	return exec.CommandContext(ctx, c.tmuxPath, args...)
 }
 
+// output runs a tmux command, retrying transient failures and killing
+// attempts that outlast the retry policy's timeout, and returns its standard
+// output.
+func (c *Client) output(ctx context.Context, args ...string) ([]byte, error) {
+	return c.retry.Output(ctx, func(ctx context.Context) *exec.Cmd {
+		return c.tmuxCmd(ctx, args...)
+	})
+}
+
+// run runs a tmux command, retrying transient failures.
+func (c *Client) run(ctx context.Context, args ...string) error {
+	_, err := c.output(ctx, args...)
+	return err
+}
+
 // wrapCommandError wraps an error from a tmux command, checking for context cancellation first.
 // If err is nil, returns nil. If context is cancelled, returns context error.
 // Otherwise, wraps in CommandError with the given operation and target information.
-func (c *Client) wrapCommandError(ctx context.Context, err error, op, session, window string) error {
+func (c *Client) wrapCommandError(ctx context.Context, err error, op string, target Target) error {
 	if err == nil {
 		return nil
 	}
@@ -97,8 +126,9 @@ func (c *Client) wrapCommandError(ctx context.Context, err error, op, session, w
 	}
 	return &CommandError{
 		Op:      op,
-		Session: session,
-		Window:  window,
+		Session: target.Session,
+		Window:  target.Window,
+		Pane:    target.Pane,
 		Err:     err,
 	}
 }
@@ -116,8 +146,7 @@ func (c *Client) IsTmuxAvailable() bool {
 
 // HasSession checks if a tmux session with the given name exists.
 func (c *Client) HasSession(ctx context.Context, name string) (bool, error) {
-	cmd := c.tmuxCmd(ctx, "has-session", "-t", name)
-	err := cmd.Run()
+	err := c.run(ctx, "has-session", "-t", name)
 	if err != nil {
 		if ctx.Err() != nil {
 			return false, ctx.Err()
@@ -141,20 +170,17 @@ func (c *Client) CreateSession(ctx context.Context, name string, detached bool)
 		args = append(args, "-d")
 	}
 
-	cmd := c.tmuxCmd(ctx, args...)
-	return c.wrapCommandError(ctx, cmd.Run(), "new-session", name, "")
+	return c.wrapCommandError(ctx, c.run(ctx, args...), "new-session", Target{Session: name})
 }
 
 // KillSession terminates a tmux session.
 func (c *Client) KillSession(ctx context.Context, name string) error {
-	cmd := c.tmuxCmd(ctx, "kill-session", "-t", name)
-	return c.wrapCommandError(ctx, cmd.Run(), "kill-session", name, "")
+	return c.wrapCommandError(ctx, c.run(ctx, "kill-session", "-t", name), "kill-session", Target{Session: name})
 }
 
 // ListSessions returns a list of all tmux session names.
 func (c *Client) ListSessions(ctx context.Context) ([]string, error) {
-	cmd := c.tmuxCmd(ctx, "list-sessions", "-F", "#{session_name}")
-	output, err := cmd.Output()
+	output, err := c.output(ctx, "list-sessions", "-F", "#{session_name}")
 	if err != nil {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
@@ -182,16 +208,14 @@ func (c *Client) ListSessions(ctx context.Context) ([]string, error) {
 // CreateWindow creates a new window in the specified session.
 func (c *Client) CreateWindow(ctx context.Context, session, windowName string) error {
 	target := fmt.Sprintf("%s:", session)
-	cmd := c.tmuxCmd(ctx, "new-window", "-t", target, "-n", windowName)
-	return c.wrapCommandError(ctx, cmd.Run(), "new-window", session, windowName)
+	return c.wrapCommandError(ctx, c.run(ctx, "new-window", "-t", target, "-n", windowName), "new-window", WindowTarget(session, windowName))
 }
 
 // HasWindow checks if a window with the given name exists in the session.
 // Uses exact matching via tmux format strings.
 func (c *Client) HasWindow(ctx context.Context, session, windowName string) (bool, error) {
 	// Use -F to get just the window names, one per line
-	cmd := c.tmuxCmd(ctx, "list-windows", "-t", session, "-F", "#{window_name}")
-	output, err := cmd.Output()
+	output, err := c.output(ctx, "list-windows", "-t", session, "-F", "#{window_name}")
 	if err != nil {
 		if ctx.Err() != nil {
 			return false, ctx.Err()
@@ -212,14 +236,12 @@ func (c *Client) HasWindow(ctx context.Context, session, windowName string) (boo
 // KillWindow terminates a specific window in a session.
 func (c *Client) KillWindow(ctx context.Context, session, windowName string) error {
 	target := fmt.Sprintf("%s:%s", session, windowName)
-	cmd := c.tmuxCmd(ctx, "kill-window", "-t", target)
-	return c.wrapCommandError(ctx, cmd.Run(), "kill-window", session, windowName)
+	return c.wrapCommandError(ctx, c.run(ctx, "kill-window", "-t", target), "kill-window", WindowTarget(session, windowName))
 }
 
 // ListWindows returns a list of window names in the specified session.
 func (c *Client) ListWindows(ctx context.Context, session string) ([]string, error) {
-	cmd := c.tmuxCmd(ctx, "list-windows", "-t", session, "-F", "#{window_name}")
-	output, err := cmd.Output()
+	output, err := c.output(ctx, "list-windows", "-t", session, "-F", "#{window_name}")
 	if err != nil {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
@@ -234,6 +256,70 @@ func (c *Client) ListWindows(ctx context.Context, session string) ([]string, err
 	return windows, nil
 }
 
+// RenameWindow renames a window in the specified session.
+func (c *Client) RenameWindow(ctx context.Context, session, windowName, newName string) error {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	return c.wrapCommandError(ctx, c.run(ctx, "rename-window", "-t", target, newName), "rename-window", WindowTarget(session, windowName))
+}
+
+// LinkWindow links a window into another session without moving it: the same
+// window (and the processes in it) then belongs to both sessions.
+func (c *Client) LinkWindow(ctx context.Context, session, windowName, targetSession string) error {
+	source := fmt.Sprintf("%s:%s", session, windowName)
+	return c.wrapCommandError(ctx, c.run(ctx, "link-window", "-d", "-s", source, "-t", targetSession+":"), "link-window", WindowTarget(session, windowName))
+}
+
+// UnlinkWindow removes a window from a session it was linked into. It fails,
+// leaving the window alone, if the session is the only one holding the window.
+func (c *Client) UnlinkWindow(ctx context.Context, session, windowName string) error {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	return c.wrapCommandError(ctx, c.run(ctx, "unlink-window", "-t", target), "unlink-window", WindowTarget(session, windowName))
+}
+
+// =============================================================================
+// Pane Management
+// =============================================================================
+
+// ListPanes returns the panes of a window in index order.
+func (c *Client) ListPanes(ctx context.Context, session, windowName string) ([]Pane, error) {
+	target := WindowTarget(session, windowName)
+	output, err := c.output(ctx, "list-panes", "-t", target.String(), "-F", "#{pane_index}\t#{pane_id}\t#{pane_pid}\t#{pane_active}")
+	if err != nil {
+		return nil, c.wrapCommandError(ctx, err, "list-panes", target)
+	}
+
+	var panes []Pane
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		var pane Pane
+		if _, err := fmt.Sscanf(fields[0]+" "+fields[2], "%d %d", &pane.Index, &pane.PID); err != nil {
+			return nil, &CommandError{Op: "parse-panes", Session: session, Window: windowName, Err: err}
+		}
+		pane.ID = fields[1]
+		pane.Active = fields[3] == "1"
+		panes = append(panes, pane)
+	}
+	return panes, nil
+}
+
+// SplitWindow splits a pane in two and returns a target for the new pane, by
+// its ID. The new pane goes below the split pane, or beside it if horizontal
+// is set. The split pane stays active.
+func (c *Client) SplitWindow(ctx context.Context, target Target, horizontal bool) (Target, error) {
+	direction := "-v"
+	if horizontal {
+		direction = "-h"
+	}
+	output, err := c.output(ctx, "split-window", direction, "-d", "-t", target.String(), "-P", "-F", "#{pane_id}")
+	if err != nil {
+		return Target{}, c.wrapCommandError(ctx, err, "split-window", target)
+	}
+	return PaneIDTarget(strings.TrimSpace(string(output))), nil
+}
+
 // =============================================================================
 // Text Input - The Key Differentiator
 // =============================================================================
@@ -241,15 +327,12 @@ func (c *Client) ListWindows(ctx context.Context, session string) ([]string, err
 // SendKeys sends text to a window followed by Enter (C-m).
 // This is equivalent to typing the text and pressing Enter.
 func (c *Client) SendKeys(ctx context.Context, session, windowName, text string) error {
-	target := fmt.Sprintf("%s:%s", session, windowName)
-	cmd := c.tmuxCmd(ctx, "send-keys", "-t", target, text, "C-m")
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		return &CommandError{Op: "send-keys", Session: session, Window: windowName, Err: err}
-	}
-	return nil
+	return c.SendKeysAt(ctx, WindowTarget(session, windowName), text)
+}
+
+// SendKeysAt sends text to a pane followed by Enter (C-m).
+func (c *Client) SendKeysAt(ctx context.Context, target Target, text string) error {
+	return c.wrapCommandError(ctx, c.run(ctx, "send-keys", "-t", target.String(), text, "C-m"), "send-keys", target)
 }
 
 // SendKeysLiteral sends text to a window without pressing Enter.
@@ -262,54 +345,48 @@ func (c *Client) SendKeys(ctx context.Context, session, windowName, text string)
 // handles multiline text when interacting with CLI applications that might
 // interpret newlines as command submission.
 func (c *Client) SendKeysLiteral(ctx context.Context, session, windowName, text string) error {
-	target := fmt.Sprintf("%s:%s", session, windowName)
+	return c.SendKeysLiteralAt(ctx, WindowTarget(session, windowName), text)
+}
 
+// SendKeysLiteralAt sends text to a pane without pressing Enter, like
+// SendKeysLiteral.
+func (c *Client) SendKeysLiteralAt(ctx context.Context, target Target, text string) error {
 	// For multiline text, use paste buffer to avoid triggering processing on each line
 	if strings.Contains(text, "\n") {
 		// Set the buffer with the text
-		setCmd := c.tmuxCmd(ctx, "set-buffer", text)
-		if err := setCmd.Run(); err != nil {
-			if ctx.Err() != nil {
-				return ctx.Err()
-			}
-			return &CommandError{Op: "set-buffer", Session: session, Window: windowName, Err: err}
+		if err := c.run(ctx, "set-buffer", text); err != nil {
+			return c.wrapCommandError(ctx, err, "set-buffer", target)
 		}
 
 		// Paste the buffer to the target
-		pasteCmd := c.tmuxCmd(ctx, "paste-buffer", "-t", target)
-		if err := pasteCmd.Run(); err != nil {
-			if ctx.Err() != nil {
-				return ctx.Err()
-			}
-			return &CommandError{Op: "paste-buffer", Session: session, Window: windowName, Err: err}
-		}
-		return nil
+		return c.wrapCommandError(ctx, c.run(ctx, "paste-buffer", "-t", target.String()), "paste-buffer", target)
 	}
 
 	// No newlines, send the text using send-keys with literal mode
-	cmd := c.tmuxCmd(ctx, "send-keys", "-t", target, "-l", text)
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		return &CommandError{Op: "send-keys", Session: session, Window: windowName, Err: err}
-	}
-	return nil
+	return c.wrapCommandError(ctx, c.run(ctx, "send-keys", "-t", target.String(), "-l", text), "send-keys", target)
 }
 
 // SendEnter sends just the Enter key (C-m) to a window.
 // Useful when you want to send text with SendKeysLiteral and then
 // separately trigger command execution.
 func (c *Client) SendEnter(ctx context.Context, session, windowName string) error {
-	target := fmt.Sprintf("%s:%s", session, windowName)
-	cmd := c.tmuxCmd(ctx, "send-keys", "-t", target, "C-m")
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		return &CommandError{Op: "send-keys", Session: session, Window: windowName, Err: err}
-	}
-	return nil
+	return c.SendEnterAt(ctx, WindowTarget(session, windowName))
+}
+
+// SendEnterAt sends just the Enter key (C-m) to a pane.
+func (c *Client) SendEnterAt(ctx context.Context, target Target) error {
+	return c.wrapCommandError(ctx, c.run(ctx, "send-keys", "-t", target.String(), "C-m"), "send-keys", target)
+}
+
+// SendKey sends a single named key, such as "Escape" or "C-c", to a window.
+// Unlike SendKeysLiteral, the key is interpreted by tmux, not typed out.
+func (c *Client) SendKey(ctx context.Context, session, windowName, key string) error {
+	return c.SendKeyAt(ctx, WindowTarget(session, windowName), key)
+}
+
+// SendKeyAt sends a single named key to a pane.
+func (c *Client) SendKeyAt(ctx context.Context, target Target, key string) error {
+	return c.wrapCommandError(ctx, c.run(ctx, "send-keys", "-t", target.String(), key), "send-keys", target)
 }
 
 // SendKeysLiteralWithEnter sends text + Enter atomically using shell command chaining.
@@ -317,52 +394,85 @@ func (c *Client) SendEnter(ctx context.Context, session, windowName string) erro
 // Uses sh -c with && to chain tmux commands in a single shell execution.
 // This approach works reliably for both single-line and multiline messages.
 func (c *Client) SendKeysLiteralWithEnter(ctx context.Context, session, windowName, text string) error {
-	target := fmt.Sprintf("%s:%s", session, windowName)
+	return c.SendKeysLiteralWithEnterAt(ctx, WindowTarget(session, windowName), text)
+}
 
+// SendKeysLiteralWithEnterAt sends text + Enter to a pane atomically, like
+// SendKeysLiteralWithEnter.
+func (c *Client) SendKeysLiteralWithEnterAt(ctx context.Context, target Target, text string) error {
 	// Use sh -c to chain tmux commands atomically with &&
-	// The text is passed as $1 to avoid shell escaping issues with special characters
+	// The text and target are passed as $1 and $2 to avoid shell escaping issues with special characters
 	// Commands: set-buffer (load text) -> paste-buffer (insert to pane) -> send-keys Enter (submit)
-	cmdStr := fmt.Sprintf("%s set-buffer -- \"$1\" && %s paste-buffer -t %s && %s send-keys -t %s Enter",
-		c.tmuxPath, c.tmuxPath, target, c.tmuxPath, target)
-	cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr, "sh", text)
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		return &CommandError{Op: "send-keys-atomic", Session: session, Window: windowName, Err: err}
-	}
-	return nil
+	// This isn't retried: a failure after the paste would paste the text twice
+	cmdStr := fmt.Sprintf("%s set-buffer -- \"$1\" && %s paste-buffer -t \"$2\" && %s send-keys -t \"$2\" Enter",
+		c.tmuxPath, c.tmuxPath, c.tmuxPath)
+	once := c.retry
+	once.Attempts = 1
+	_, err := once.Output(ctx, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", cmdStr, "sh", text, target.String())
+	})
+	return c.wrapCommandError(ctx, err, "send-keys-atomic", target)
 }
 
 // =============================================================================
 // Process Monitoring - Another Differentiator
 // =============================================================================
 
-// GetPanePID gets the PID of the process running in the first pane of a window.
+// GetPanePID gets the PID of the process running in the active pane of a window.
 // This allows monitoring whether the process in a tmux pane is still alive.
 func (c *Client) GetPanePID(ctx context.Context, session, windowName string) (int, error) {
-	target := fmt.Sprintf("%s:%s", session, windowName)
-	cmd := c.tmuxCmd(ctx, "display-message", "-t", target, "-p", "#{pane_pid}")
-	output, err := cmd.Output()
+	return c.GetPanePIDAt(ctx, WindowTarget(session, windowName))
+}
+
+// GetPanePIDAt gets the PID of the process running in a pane.
+func (c *Client) GetPanePIDAt(ctx context.Context, target Target) (int, error) {
+	output, err := c.output(ctx, "display-message", "-t", target.String(), "-p", "#{pane_pid}")
 	if err != nil {
-		if ctx.Err() != nil {
-			return 0, ctx.Err()
-		}
-		return 0, &CommandError{Op: "display-message", Session: session, Window: windowName, Err: err}
+		return 0, c.wrapCommandError(ctx, err, "display-message", target)
 	}
 
 	var pid int
 	if _, err := fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &pid); err != nil {
-		return 0, &CommandError{Op: "parse-pid", Session: session, Window: windowName, Err: err}
+		return 0, c.wrapCommandError(ctx, err, "parse-pid", target)
 	}
 
 	return pid, nil
 }
 
+// GetPanePath gets the current working directory of the active pane of a window.
+func (c *Client) GetPanePath(ctx context.Context, session, windowName string) (string, error) {
+	return c.GetPanePathAt(ctx, WindowTarget(session, windowName))
+}
+
+// GetPanePathAt gets the current working directory of a pane.
+func (c *Client) GetPanePathAt(ctx context.Context, target Target) (string, error) {
+	output, err := c.output(ctx, "display-message", "-t", target.String(), "-p", "#{pane_current_path}")
+	if err != nil {
+		return "", c.wrapCommandError(ctx, err, "display-message", target)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // =============================================================================
 // Output Capture - Third Differentiator
 // =============================================================================
 
+// CapturePane returns the visible contents of a window's pane plus up to
+// historyLines lines of scrollback.
+func (c *Client) CapturePane(ctx context.Context, session, windowName string, historyLines int) (string, error) {
+	return c.CapturePaneAt(ctx, WindowTarget(session, windowName), historyLines)
+}
+
+// CapturePaneAt returns the visible contents of a pane plus up to
+// historyLines lines of scrollback.
+func (c *Client) CapturePaneAt(ctx context.Context, target Target, historyLines int) (string, error) {
+	output, err := c.output(ctx, "capture-pane", "-p", "-J", "-t", target.String(), "-S", fmt.Sprintf("-%d", historyLines))
+	if err != nil {
+		return "", c.wrapCommandError(ctx, err, "capture-pane", target)
+	}
+	return string(output), nil
+}
+
 // StartPipePane starts capturing pane output to a file.
 // The output is appended to the file, so it persists across restarts.
 //
@@ -372,24 +482,24 @@ func (c *Client) GetPanePID(ctx context.Context, session, windowName string) (in
 //	// ... run commands in the pane ...
 //	client.StopPipePane(ctx, "my-session", "my-window")
 func (c *Client) StartPipePane(ctx context.Context, session, windowName, outputFile string) error {
-	target := fmt.Sprintf("%s:%s", session, windowName)
+	return c.StartPipePaneAt(ctx, WindowTarget(session, windowName), outputFile)
+}
+
+// StartPipePaneAt starts capturing a pane's output to a file.
+func (c *Client) StartPipePaneAt(ctx context.Context, target Target, outputFile string) error {
 	// Use -o to open a pipe (output only, not input)
 	// cat >> appends to the file so output is preserved
-	cmd := c.tmuxCmd(ctx, "pipe-pane", "-o", "-t", target, fmt.Sprintf("cat >> '%s'", outputFile))
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		return &CommandError{Op: "pipe-pane", Session: session, Window: windowName, Err: err}
-	}
-	return nil
+	return c.wrapCommandError(ctx, c.run(ctx, "pipe-pane", "-o", "-t", target.String(), fmt.Sprintf("cat >> '%s'", outputFile)), "pipe-pane", target)
 }
 
 // StopPipePane stops the pipe-pane for a window.
 // After calling this, output is no longer captured to the file.
 func (c *Client) StopPipePane(ctx context.Context, session, windowName string) error {
-	target := fmt.Sprintf("%s:%s", session, windowName)
+	return c.StopPipePaneAt(ctx, WindowTarget(session, windowName))
+}
+
+// StopPipePaneAt stops the pipe-pane for a pane.
+func (c *Client) StopPipePaneAt(ctx context.Context, target Target) error {
 	// Running pipe-pane with no command stops any existing pipe
-	cmd := c.tmuxCmd(ctx, "pipe-pane", "-t", target)
-	return c.wrapCommandError(ctx, cmd.Run(), "pipe-pane-stop", session, windowName)
+	return c.wrapCommandError(ctx, c.run(ctx, "pipe-pane", "-t", target.String()), "pipe-pane-stop", target)
 }
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/micheal-at/multiclaude/pkg/retry"
 )
 
 // canCreateSessions indicates whether the environment supports creating tmux sessions.
@@ -150,6 +153,28 @@ func TestNewClientWithOptions(t *testing.T) {
 	}
 }
 
+func TestRetry(t *testing.T) {
+	// A fake tmux whose server is unavailable for its first run
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "tmux")
+	script := "#!/bin/sh\nif [ ! -f " + dir + "/ran ]; then touch " + dir + "/ran; echo 'server exited unexpectedly' >&2; exit 1; fi\necho supervisor\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err := NewClient(WithTmuxPath(fake)).ListWindows(context.Background(), "mc-app")
+	if err != nil || len(windows) != 1 || windows[0] != "supervisor" {
+		t.Errorf("ListWindows() = %v, %v, want [supervisor] after a retry", windows, err)
+	}
+
+	os.Remove(filepath.Join(dir, "ran"))
+	noRetry := retry.Tmux
+	noRetry.Attempts = 1
+	if _, err := NewClient(WithTmuxPath(fake), WithRetry(noRetry)).ListWindows(context.Background(), "mc-app"); err == nil {
+		t.Error("ListWindows() without retries should fail")
+	}
+}
+
 func TestIsTmuxAvailable(t *testing.T) {
 	client := NewClient()
 	if !client.IsTmuxAvailable() {
@@ -504,6 +529,24 @@ func TestSendEnter(t *testing.T) {
 	}
 }
 
+func TestSendKey(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, sessionName)
+
+	windowName := "test-window"
+	if err := client.CreateWindow(ctx, sessionName, windowName); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	for _, key := range []string{"Escape", "C-c"} {
+		if err := client.SendKey(ctx, sessionName, windowName, key); err != nil {
+			t.Fatalf("Failed to send %s: %v", key, err)
+		}
+	}
+}
+
 func TestSendKeysLiteralWithEnter(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -1273,3 +1316,171 @@ func TestStopPipePaneContextCancellation(t *testing.T) {
 		t.Errorf("Expected context.Canceled, got %v", err)
 	}
 }
+
+func TestCapturePane(t *testing.T) {
+	skipIfCannotCreateSessions(t)
+	ctx := context.Background()
+	client := NewClient()
+	session := uniqueSessionName()
+	window := "capture"
+
+	cmd := exec.Command("tmux", "new-session", "-d", "-s", session, "-n", window)
+	if err := cmd.Run(); err != nil {
+		t.Skipf("tmux session creation failed (intermittent CI issue): %v", err)
+	}
+	defer client.KillSession(ctx, session)
+
+	if err := client.SendKeys(ctx, session, window, "echo capture-marker-$((40+2))"); err != nil {
+		t.Fatalf("Failed to send keys: %v", err)
+	}
+
+	var content string
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		var err error
+		content, err = client.CapturePane(ctx, session, window, 100)
+		if err != nil {
+			t.Fatalf("CapturePane failed: %v", err)
+		}
+		if strings.Contains(content, "capture-marker-42") {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("CapturePane output missing command output:\n%s", content)
+}
+
+func TestCapturePaneErrors(t *testing.T) {
+	client := NewClient()
+
+	if _, err := client.CapturePane(context.Background(), "nonexistent-session", "window", 10); err == nil {
+		t.Error("CapturePane on non-existent session should fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.CapturePane(ctx, "session", "window", 10); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLinkWindow(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	source := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, source)
+	target := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, target)
+
+	if err := client.CreateWindow(ctx, source, "manual"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	if err := client.RenameWindow(ctx, source, "manual", "adopted"); err != nil {
+		t.Fatalf("RenameWindow failed: %v", err)
+	}
+	if err := client.LinkWindow(ctx, source, "adopted", target); err != nil {
+		t.Fatalf("LinkWindow failed: %v", err)
+	}
+	for _, session := range []string{source, target} {
+		if exists, err := client.HasWindow(ctx, session, "adopted"); err != nil || !exists {
+			t.Errorf("HasWindow(%s) = %v, %v after linking; want true", session, exists, err)
+		}
+	}
+
+	if path, err := client.GetPanePath(ctx, target, "adopted"); err != nil || path == "" {
+		t.Errorf("GetPanePath() = %q, %v", path, err)
+	}
+
+	// Unlinking leaves the window in its original session
+	if err := client.UnlinkWindow(ctx, target, "adopted"); err != nil {
+		t.Fatalf("UnlinkWindow failed: %v", err)
+	}
+	if exists, _ := client.HasWindow(ctx, target, "adopted"); exists {
+		t.Error("window should be gone from the session it was unlinked from")
+	}
+	if exists, _ := client.HasWindow(ctx, source, "adopted"); !exists {
+		t.Error("window should still be in its original session")
+	}
+	if err := client.UnlinkWindow(ctx, source, "adopted"); err == nil {
+		t.Error("UnlinkWindow should refuse to unlink a window's only session")
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{WindowTarget("mc-app", "supervisor"), "mc-app:supervisor"},
+		{PaneTarget("mc-app", "supervisor", 1), "mc-app:supervisor.1"},
+		{PaneIDTarget("%12"), "%12"},
+		{Target{Session: "mc-app", Window: "supervisor", Pane: "%12"}, "%12"},
+	}
+	for _, tt := range tests {
+		if got := tt.target.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+
+	paneErr := &CommandError{Op: "send-keys", Session: "sess", Window: "win", Pane: "1", Err: fmt.Errorf("error")}
+	if want := "tmux send-keys failed for sess:win.1: error"; paneErr.Error() != want {
+		t.Errorf("Expected %q, got %q", want, paneErr.Error())
+	}
+	idErr := &CommandError{Op: "capture-pane", Pane: "%12", Err: fmt.Errorf("error")}
+	if want := "tmux capture-pane failed for pane %12: error"; idErr.Error() != want {
+		t.Errorf("Expected %q, got %q", want, idErr.Error())
+	}
+}
+
+func TestPanes(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	session := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, session)
+	window := "panes"
+	if err := client.CreateWindow(ctx, session, window); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	split, err := client.SplitWindow(ctx, WindowTarget(session, window), true)
+	if err != nil {
+		t.Fatalf("SplitWindow failed: %v", err)
+	}
+	panes, err := client.ListPanes(ctx, session, window)
+	if err != nil {
+		t.Fatalf("ListPanes failed: %v", err)
+	}
+	if len(panes) != 2 || panes[1].ID != split.Pane || !panes[0].Active || panes[1].Active {
+		t.Fatalf("ListPanes() = %+v, want the original active pane and %s", panes, split.Pane)
+	}
+
+	if pid, err := client.GetPanePIDAt(ctx, split); err != nil || pid != panes[1].PID {
+		t.Errorf("GetPanePIDAt() = %d, %v, want %d", pid, err, panes[1].PID)
+	}
+	if pid, err := client.GetPanePIDAt(ctx, PaneTarget(session, window, panes[1].Index)); err != nil || pid != panes[1].PID {
+		t.Errorf("GetPanePIDAt() by index = %d, %v, want %d", pid, err, panes[1].PID)
+	}
+
+	// Keys sent to the new pane don't reach the window's active pane
+	if err := client.SendKeysAt(ctx, split, "echo pane-marker-$((40+2))"); err != nil {
+		t.Fatalf("SendKeysAt failed: %v", err)
+	}
+	var content string
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(content, "pane-marker-42") {
+		time.Sleep(50 * time.Millisecond)
+		if content, err = client.CapturePaneAt(ctx, split, 100); err != nil {
+			t.Fatalf("CapturePaneAt failed: %v", err)
+		}
+	}
+	if !strings.Contains(content, "pane-marker-42") {
+		t.Errorf("split pane missing command output:\n%s", content)
+	}
+	if active, _ := client.CapturePane(ctx, session, window, 100); strings.Contains(active, "pane-marker") {
+		t.Errorf("active pane received keys sent to the split pane:\n%s", active)
+	}
+
+	if _, err := client.CapturePaneAt(ctx, PaneIDTarget("%999999"), 10); err == nil {
+		t.Error("CapturePaneAt on a non-existent pane should fail")
+	}
+}
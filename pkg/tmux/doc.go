@@ -72,6 +72,16 @@
 //	    log.Printf("Process PID: %d", pid)
 //	}
 //
+// # Targeting Panes
+//
+// Methods taking a session and window act on the window's active pane. Each
+// of the text input, process monitoring and output capture methods has an
+// ...At variant, such as [Client.SendKeysAt] and [Client.CapturePaneAt], that
+// takes a [Target] instead, so windows split into several panes can address
+// each one by index ([PaneTarget]) or by ID ([PaneIDTarget]). Use
+// [Client.ListPanes] to find a window's panes and [Client.SplitWindow] to add
+// one.
+//
 // # The Paste-Buffer Technique
 //
 // When sending multiline text to a CLI application, naive approaches using
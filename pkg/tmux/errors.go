@@ -38,12 +38,17 @@ type CommandError struct {
 	Op      string // Operation that failed (e.g., "create-session", "send-keys")
 	Session string // Session name, if applicable
 	Window  string // Window name, if applicable
+	Pane    string // Pane index or ID, if applicable
 	Err     error  // Underlying error
 }
 
 func (e *CommandError) Error() string {
+	target := Target{Session: e.Session, Window: e.Window, Pane: e.Pane}
+	if target.isPaneID() {
+		return fmt.Sprintf("tmux %s failed for pane %s: %v", e.Op, e.Pane, e.Err)
+	}
 	if e.Window != "" {
-		return fmt.Sprintf("tmux %s failed for %s:%s: %v", e.Op, e.Session, e.Window, e.Err)
+		return fmt.Sprintf("tmux %s failed for %s: %v", e.Op, target, e.Err)
 	}
 	if e.Session != "" {
 		return fmt.Sprintf("tmux %s failed for session %s: %v", e.Op, e.Session, e.Err)
@@ -0,0 +1,64 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Target addresses a pane. With no Pane it means the window's active pane,
+// which is what the window-based methods such as SendKeys use.
+type Target struct {
+	Session string
+	Window  string
+	// Pane is a pane index within the window, such as "1", or a pane ID
+	// such as "%12". Pane IDs are unique across the tmux server, so Session
+	// and Window are ignored for them.
+	Pane string
+}
+
+// WindowTarget targets the active pane of a window.
+func WindowTarget(session, window string) Target {
+	return Target{Session: session, Window: window}
+}
+
+// PaneTarget targets a pane of a window by its index.
+func PaneTarget(session, window string, index int) Target {
+	return Target{Session: session, Window: window, Pane: fmt.Sprint(index)}
+}
+
+// PaneIDTarget targets a pane by its ID, such as "%12", which stays the same
+// when panes are added, removed or moved to another window.
+func PaneIDTarget(id string) Target {
+	return Target{Pane: id}
+}
+
+// isPaneID reports whether the target is a pane ID
+func (t Target) isPaneID() bool {
+	return strings.HasPrefix(t.Pane, "%")
+}
+
+// String returns the target in tmux's -t syntax: session:window,
+// session:window.pane or a pane ID.
+func (t Target) String() string {
+	switch {
+	case t.isPaneID():
+		return t.Pane
+	case t.Pane != "":
+		return fmt.Sprintf("%s:%s.%s", t.Session, t.Window, t.Pane)
+	default:
+		return fmt.Sprintf("%s:%s", t.Session, t.Window)
+	}
+}
+
+// Pane is a pane of a window
+type Pane struct {
+	Index  int
+	ID     string // Pane ID, such as "%12"
+	PID    int    // PID of the process running in the pane
+	Active bool   // Whether this is the window's active pane
+}
+
+// Target returns a target for the pane by its ID.
+func (p Pane) Target() Target {
+	return PaneIDTarget(p.ID)
+}
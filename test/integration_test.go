@@ -5,13 +5,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/micheal-at/multiclaude/internal/cli"
 	"github.com/micheal-at/multiclaude/internal/daemon"
+	"github.com/micheal-at/multiclaude/internal/messages"
 	"github.com/micheal-at/multiclaude/internal/socket"
 	"github.com/micheal-at/multiclaude/internal/state"
+	"github.com/micheal-at/multiclaude/internal/testutil"
+	"github.com/micheal-at/multiclaude/internal/trash"
 	"github.com/micheal-at/multiclaude/internal/worktree"
 	"github.com/micheal-at/multiclaude/pkg/config"
 	"github.com/micheal-at/multiclaude/pkg/tmux"
@@ -77,7 +81,7 @@ func setupIntegrationTest(t *testing.T, repoName string) (*cli.CLI, *daemon.Daem
 	}
 
 	// Wait for daemon to be ready
-	time.Sleep(100 * time.Millisecond)
+	waitForDaemon(t, paths)
 
 	// Create tmux session
 	tmuxSession := "mc-" + repoName
@@ -100,6 +104,16 @@ func setupIntegrationTest(t *testing.T, repoName string) (*cli.CLI, *daemon.Daem
 	return c, d, tmuxSession, cleanup
 }
 
+// waitForDaemon waits until the daemon answers on its socket
+func waitForDaemon(t *testing.T, paths *config.Paths) {
+	t.Helper()
+	client := socket.NewClient(paths.DaemonSock)
+	testutil.Eventually(t, 5*time.Second, "the daemon to answer", func() bool {
+		resp, err := client.Send(socket.Request{Command: "ping"})
+		return err == nil && resp.Success
+	})
+}
+
 // setupTestGitRepo creates a test git repository with initial commit
 func setupTestGitRepo(t *testing.T, repoPath string) {
 	t.Helper()
@@ -155,13 +169,17 @@ func TestWorkerCreationIntegration(t *testing.T) {
 		t.Fatalf("Failed to change to repo dir: %v", err)
 	}
 
-	// Create worker via CLI with explicit name and repo flag
+	// Create worker via CLI with explicit name and repo flag, recording what
+	// it does in tmux
+	rec := testutil.RecordTmux(t)
+	rec.Replace(paths.Root, "<root>")
 	workerName := "test-worker"
 	taskDescription := "Implement feature X for testing"
 	err := c.Execute([]string{"work", taskDescription, "--name", workerName, "--repo", repoName})
 	if err != nil {
 		t.Fatalf("Worker creation failed: %v", err)
 	}
+	testutil.Golden(t, "worker-creation-tmux", testutil.Transcript(rec.Mutations(), false))
 
 	// Verification 1: Worker appears in state
 	agent, exists := d.GetState().GetAgent(repoName, workerName)
@@ -339,7 +357,7 @@ func TestRepoInitializationIntegration(t *testing.T) {
 	}
 	defer d.Stop()
 
-	time.Sleep(100 * time.Millisecond)
+	waitForDaemon(t, paths)
 
 	// Create CLI
 	c := cli.NewWithPaths(paths)
@@ -481,7 +499,7 @@ func TestRepoInitializationWithMergeQueueDisabled(t *testing.T) {
 	d, _ := daemon.New(paths)
 	d.Start()
 	defer d.Stop()
-	time.Sleep(100 * time.Millisecond)
+	waitForDaemon(t, paths)
 
 	c := cli.NewWithPaths(paths)
 
@@ -513,6 +531,106 @@ func TestRepoInitializationWithMergeQueueDisabled(t *testing.T) {
 	}
 }
 
+func TestRepoInitializationSkipAgents(t *testing.T) {
+	os.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	defer os.Unsetenv("MULTICLAUDE_TEST_MODE")
+
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	// Create temp directory
+	tmpDir, err := os.MkdirTemp("", "repo-init-skip-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpDir, _ = filepath.EvalSymlinks(tmpDir)
+
+	paths := &config.Paths{
+		Root:            tmpDir,
+		DaemonPID:       filepath.Join(tmpDir, "daemon.pid"),
+		DaemonSock:      filepath.Join(tmpDir, "daemon.sock"),
+		DaemonLog:       filepath.Join(tmpDir, "daemon.log"),
+		StateFile:       filepath.Join(tmpDir, "state.json"),
+		ReposDir:        filepath.Join(tmpDir, "repos"),
+		WorktreesDir:    filepath.Join(tmpDir, "wts"),
+		MessagesDir:     filepath.Join(tmpDir, "messages"),
+		OutputDir:       filepath.Join(tmpDir, "output"),
+		ClaudeConfigDir: filepath.Join(tmpDir, "claude-config"),
+	}
+
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	os.MkdirAll(filepath.Join(tmpDir, "prompts"), 0755)
+
+	// Create bare repo for cloning
+	remoteRepoPath := filepath.Join(tmpDir, "remote-repo.git")
+	exec.Command("git", "init", "--bare", remoteRepoPath).Run()
+
+	sourceRepo := filepath.Join(tmpDir, "source-repo")
+	setupTestGitRepo(t, sourceRepo)
+	cmd := exec.Command("git", "remote", "add", "origin", remoteRepoPath)
+	cmd.Dir = sourceRepo
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to add remote: %v", err)
+	}
+	cmd = exec.Command("git", "branch", "-M", "main")
+	cmd.Dir = sourceRepo
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to rename branch: %v", err)
+	}
+	cmd = exec.Command("git", "push", "-u", "origin", "main")
+	cmd.Dir = sourceRepo
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	// Update bare repo HEAD to point to main (git init --bare defaults to master/main based on config)
+	cmd = exec.Command("git", "symbolic-ref", "HEAD", "refs/heads/main")
+	cmd.Dir = remoteRepoPath
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to update bare repo HEAD: %v", err)
+	}
+
+	d, _ := daemon.New(paths)
+	d.Start()
+	defer d.Stop()
+	waitForDaemon(t, paths)
+
+	c := cli.NewWithPaths(paths)
+
+	repoName := "skip-repo"
+	err = c.Execute([]string{"init", remoteRepoPath, repoName, "--skip-agents"})
+	if err != nil {
+		t.Fatalf("Repo initialization failed: %v", err)
+	}
+
+	tmuxSession := "mc-" + repoName
+	defer tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	// The repo is cloned and tracked, with its session, but has no agents
+	if _, err := os.Stat(filepath.Join(paths.RepoDir(repoName), ".git")); err != nil {
+		t.Errorf("Repository was not cloned: %v", err)
+	}
+	repo, exists := d.GetState().GetRepo(repoName)
+	if !exists {
+		t.Fatal("Repository should be tracked")
+	}
+	if len(repo.Agents) != 0 {
+		t.Errorf("--skip-agents registered %d agents", len(repo.Agents))
+	}
+	if hasSession, _ := tmuxClient.HasSession(context.Background(), tmuxSession); !hasSession {
+		t.Error("Tmux session should exist so the daemon doesn't restore agents into it")
+	}
+	if _, err := os.Stat(paths.AgentWorktree(repoName, "default")); !os.IsNotExist(err) {
+		t.Error("--skip-agents created the default workspace")
+	}
+}
+
 // TestDaemonCommunicationRoundTrip tests that CLI->daemon->CLI communication works correctly.
 // This tests the exact flow that was broken by the provider bug.
 func TestDaemonCommunicationRoundTrip(t *testing.T) {
@@ -918,3 +1036,121 @@ func TestWorkerRemovalCleanup(t *testing.T) {
 	// Note: Worktree cleanup happens asynchronously via daemon health check
 	// So we don't verify worktree is gone here
 }
+
+// TestWorkerTrashAndUndelete tests that a removed worker can be restored from
+// the trash with its branch and uncommitted changes
+func TestWorkerTrashAndUndelete(t *testing.T) {
+	repoName := "trash-test"
+	c, d, tmuxSession, cleanup := setupIntegrationTest(t, repoName)
+	defer cleanup()
+
+	paths := d.GetPaths()
+	repoPath := paths.RepoDir(repoName)
+	setupTestGitRepo(t, repoPath)
+
+	repo := &state.Repository{
+		GithubURL:        "https://github.com/test/repo",
+		TmuxSession:      tmuxSession,
+		Agents:           make(map[string]state.Agent),
+		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+	}
+	d.GetState().AddRepo(repoName, repo)
+
+	workerName := "trashed-worker"
+	if err := c.Execute([]string{"work", "Task to restore", "--name", workerName, "--repo", repoName}); err != nil {
+		t.Fatalf("Worker creation failed: %v", err)
+	}
+	agent, _ := d.GetState().GetAgent(repoName, workerName)
+	wtPath := paths.AgentWorktree(repoName, workerName)
+	if err := os.WriteFile(filepath.Join(wtPath, "wip.txt"), []byte("half done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Execute([]string{"work", "rm", workerName, "--repo", repoName}); err != nil {
+		t.Fatalf("Worker removal failed: %v", err)
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Error("Worktree should be removed")
+	}
+	if _, err := trash.Load(paths, repoName, workerName); err != nil {
+		t.Fatalf("Worker should be in the trash: %v", err)
+	}
+	if exists, _ := worktree.NewManager(repoPath).BranchExists(agent.Branch); !exists {
+		t.Errorf("Branch %s should be kept", agent.Branch)
+	}
+
+	if err := c.Execute([]string{"work", "undelete", workerName, "--repo", repoName}); err != nil {
+		t.Fatalf("Undelete failed: %v", err)
+	}
+	restored, exists := d.GetState().GetAgent(repoName, workerName)
+	if !exists || restored.Task != "Task to restore" || restored.Branch != agent.Branch {
+		t.Errorf("Restored worker = %+v, exists %v; want the same task and branch", restored, exists)
+	}
+	if data, err := os.ReadFile(filepath.Join(wtPath, "wip.txt")); err != nil || string(data) != "half done\n" {
+		t.Errorf("Uncommitted file = %q, %v; want it re-applied", data, err)
+	}
+	if _, err := trash.Load(paths, repoName, workerName); err == nil {
+		t.Error("Restored worker should leave the trash")
+	}
+}
+
+// TestMessageDeliveryIntegration checks what delivering a message does in
+// tmux, and what the recipient sees, against golden files in testdata. After
+// an intended change, run: go test ./test -run TestMessageDeliveryIntegration -update
+func TestMessageDeliveryIntegration(t *testing.T) {
+	repoName := "message-delivery-test"
+	c, d, tmuxSession, cleanup := setupIntegrationTest(t, repoName)
+	defer cleanup()
+
+	paths := d.GetPaths()
+	repoPath := paths.RepoDir(repoName)
+	setupTestGitRepo(t, repoPath)
+	if err := d.GetState().AddRepo(repoName, &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: tmuxSession,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	workerName := "test-worker"
+	if err := c.Execute([]string{"work", "Implement feature X for testing", "--name", workerName, "--repo", repoName}); err != nil {
+		t.Fatalf("Worker creation failed: %v", err)
+	}
+
+	// The worker's window has a shell, whose prompt differs between
+	// machines; cat shows just what's typed into it
+	target := tmuxSession + ":" + workerName
+	tmuxCmd := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("tmux", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("tmux %v failed: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	tmuxCmd("respawn-pane", "-k", "-t", target, "cat")
+	testutil.Eventually(t, 5*time.Second, "cat to start in the worker's window", func() bool {
+		return tmuxCmd("display-message", "-p", "-t", target, "#{pane_current_command}") == "cat"
+	})
+	tmuxCmd("send-keys", "-R", "-t", target)
+	tmuxCmd("clear-history", "-t", target)
+
+	rec := testutil.RecordTmux(t)
+	rec.Replace(paths.Root, "<root>")
+	msgMgr := messages.NewManager(paths.MessagesDir)
+	msg, err := msgMgr.Send(repoName, "supervisor", workerName, "Please rebase onto main")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	d.TriggerMessageRouting()
+
+	delivered, err := msgMgr.Get(repoName, workerName, msg.ID)
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if delivered.Status != messages.StatusDelivered {
+		t.Errorf("Message status = %s, want delivered", delivered.Status)
+	}
+	testutil.Golden(t, "message-delivery-tmux", testutil.Transcript(rec.Mutations(), false))
+	testutil.Golden(t, "message-delivery-pane", rec.Snapshot(target, 5*time.Second))
+}